@@ -0,0 +1,319 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const accessCmdUsage = `Usage:
+    kes access <command>
+
+Commands:
+    request                  Request temporary access to a policy.
+    approve                  Approve an access request.
+    deny                     Deny an access request.
+    ls                       List pending access requests.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func accessCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, accessCmdUsage) }
+
+	subCmds := commands{
+		"request": requestAccessCmd,
+		"approve": approveAccessCmd,
+		"deny":    denyAccessCmd,
+		"ls":      lsAccessCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes access --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not an access command. See 'kes access --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const requestAccessCmdUsage = `Usage:
+    kes access request [options] <policy>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --duration <DURATION>
+                             Duration of the policy assignment once the
+                             request has been approved. (default: 1h)
+        --reason <text>      Justification shown to approvers.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Requests to be assigned to the policy for a limited duration and prints
+the ID of the access request. The request has no effect until another
+identity approves it. Once approved, the policy assignment expires
+automatically after the requested duration, at most 24h. Identities
+that are already assigned to a policy cannot request access.
+
+Pending access requests are discarded after 24h and when the server
+restarts.
+
+Examples:
+    $ kes access request --duration 30m --reason "INC-1234" my-policy
+`
+
+func requestAccessCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, requestAccessCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		duration           = time.Hour
+		reason             string
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.DurationVar(&duration, "duration", duration, "Duration of the policy assignment")
+	cmd.StringVar(&reason, "reason", "", "Justification shown to approvers")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes access request --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no policy specified. See 'kes access request --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes access request --help'")
+	case duration <= 0:
+		cli.Fatal("'--duration' must be positive. See 'kes access request --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	policy := cmd.Arg(0)
+	client := newClient(insecureSkipVerify)
+	id, err := kesclient.RequestAccess(ctx, client, enclaveName, policy, duration, reason)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to request access to policy %q: %v", policy, err)
+	}
+	fmt.Println(id)
+}
+
+const approveAccessCmdUsage = `Usage:
+    kes access approve [options] <id>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Approves the access requests and assigns the requesting identities to
+the requested policies. An identity cannot approve its own request.
+
+Examples:
+    $ kes access approve 3a7c0e51b0a2f6d8c4e1f9a7b5d3c2e1
+`
+
+func approveAccessCmd(args []string) {
+	accessDecisionCmd(args, approveAccessCmdUsage, "approve", kesclient.ApproveAccess)
+}
+
+const denyAccessCmdUsage = `Usage:
+    kes access deny [options] <id>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes access deny 3a7c0e51b0a2f6d8c4e1f9a7b5d3c2e1
+`
+
+func denyAccessCmd(args []string) {
+	accessDecisionCmd(args, denyAccessCmdUsage, "deny", kesclient.DenyAccess)
+}
+
+// accessDecisionCmd approves or denies the access requests
+// specified by args using the given kesclient function.
+func accessDecisionCmd(args []string, usage, command string, fn func(context.Context, *kes.Client, string, string) error) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes access %s --help'", err, command)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatalf("no access request specified. See 'kes access %s --help'", command)
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	for _, id := range cmd.Args() {
+		if err := fn(ctx, client, enclaveName, id); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to %s access request %q: %v", command, id, err)
+		}
+	}
+}
+
+const lsAccessCmdUsage = `Usage:
+    kes access ls [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print access requests in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes access ls
+`
+
+func lsAccessCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsAccessCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print access requests in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes access ls --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes access ls --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	requests, err := kesclient.ListAccessRequests(ctx, client, enclaveName)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list access requests: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, req := range requests {
+			if err = encoder.Encode(req); err != nil {
+				cli.Fatal(err)
+			}
+		}
+		return
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	headerStyle := tui.NewStyle()
+	dateStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorDate tui.Color = "#5f8700"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		dateStyle = dateStyle.Foreground(ColorDate)
+	}
+
+	fmt.Println(
+		headerStyle.Render(fmt.Sprintf("%-19s", "Date Requested")),
+		headerStyle.Render(fmt.Sprintf("%-32s", "ID")),
+		headerStyle.Render(fmt.Sprintf("%-10s", "Duration")),
+		headerStyle.Render(fmt.Sprintf("%-20s", "Policy")),
+		headerStyle.Render("Identity"),
+	)
+	for _, req := range requests {
+		year, month, day := req.RequestedAt.Local().Date()
+		hour, min, sec := req.RequestedAt.Local().Clock()
+
+		fmt.Printf("%s %-32s %-10s %-20s %s\n",
+			dateStyle.Render(fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)),
+			req.ID,
+			req.Duration,
+			req.Policy,
+			req.Identity,
+		)
+		if req.Reason != "" {
+			fmt.Printf("%-19s %s\n", "", req.Reason)
+		}
+	}
+}
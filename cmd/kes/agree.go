@@ -0,0 +1,125 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const agreeKeyCmdUsage = `Usage:
+    kes key agree [options] <name> [<public-key>]
+
+Options:
+    --curve <CURVE>          The curve: X25519 or P-256. (default: X25519,
+                             P-256 in FIPS mode)
+    --salt <HEX>             Hex-encoded HKDF salt.
+    --info <TEXT>            HKDF info, e.g. a protocol identifier.
+    --length <N>             Length of the shared secret in bytes, at most
+                             64. (default: 32)
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Performs an (EC)DH key agreement between the static key pair of the key
+<name> and the base64-encoded <public-key>. It prints the server's public
+key and the shared secret derived via HKDF-SHA256, both base64-encoded.
+The key pair is derived from the key such that the server's public key
+never changes. Without a <public-key>, it only prints the server's public
+key.
+
+X25519 public keys are 32 bytes long. P-256 public keys are uncompressed
+points of 65 bytes.
+
+Examples:
+    $ kes key agree my-key
+    $ kes key agree --info my-protocol my-key BDYMbnUo8SUtSc0CiDwRD/...
+`
+
+func agreeKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, agreeKeyCmdUsage) }
+
+	var (
+		params             kesclient.KeyAgreementParams
+		salt               string
+		info               string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&params.Curve, "curve", "", "The curve: X25519 or P-256")
+	cmd.StringVar(&salt, "salt", "", "Hex-encoded HKDF salt")
+	cmd.StringVar(&info, "info", "", "HKDF info")
+	cmd.IntVar(&params.Length, "length", 0, "Length of the shared secret in bytes")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key agree --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key agree --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key agree --help'")
+	}
+	var publicKey []byte
+	if cmd.NArg() == 2 {
+		b, err := base64.StdEncoding.DecodeString(cmd.Arg(1))
+		if err != nil {
+			cli.Fatalf("invalid public key: %v. See 'kes key agree --help'", err)
+		}
+		publicKey = b
+	}
+	if salt != "" {
+		b, err := hex.DecodeString(salt)
+		if err != nil {
+			cli.Fatalf("invalid salt '%s': not hex-encoded", salt)
+		}
+		params.Salt = b
+	}
+	params.Info = []byte(info)
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	serverPublicKey, sharedSecret, err := kesclient.AgreeKey(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0), publicKey, &params)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to perform key agreement: %v", err)
+	}
+
+	if isTerm(os.Stdout) {
+		fmt.Printf("\npublic key:    %s\n", base64.StdEncoding.EncodeToString(serverPublicKey))
+		if len(sharedSecret) > 0 {
+			fmt.Printf("shared secret: %s\n", base64.StdEncoding.EncodeToString(sharedSecret))
+		}
+	} else {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			PublicKey    []byte `json:"public_key"`
+			SharedSecret []byte `json:"shared_secret,omitempty"`
+		}{PublicKey: serverPublicKey, SharedSecret: sharedSecret})
+	}
+}
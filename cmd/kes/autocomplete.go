@@ -28,70 +28,132 @@ func complete(cmd string) bool {
 		return false
 	}
 
-	completion := map[string][]string{
-		cmd:             {"server", "init", "enclave", "key", "policy", "identity", "log", "status", "metric", "update"},
-		cmd + " server": {"--config", "--addr", "--auth"},
-		cmd + " init":   {"--config", "--force"},
-		cmd + " log":    {"--audit", "--error", "--json", "--insecure"},
-		cmd + " status": {"--short", "--api", "--json", "--color", "--insecure"},
-		cmd + " metric": {"--rate", "--insecure"},
-		cmd + " update": {"--downgrade", "--output", "--os", "--arch", "--minisign-key", "--insecure"},
-
-		cmd + " enclave":        {"create", "info", "rm"},
-		cmd + " enclave create": {"--insecure"},
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	if strings.HasSuffix(line, " ") {
+		fields = append(fields, "")
+	}
+	for _, candidate := range completeArgs(cmd, fields[1:]) {
+		fmt.Println(candidate)
+	}
+	return true
+}
+
+// completionTable returns the subcommands and flags
+// of all commands of the given binary name.
+func completionTable(cmd string) map[string][]string {
+	return map[string][]string{
+		cmd:                 {"server", "init", "enclave", "key", "policy", "identity", "ca", "ssh", "token", "cert", "random", "tokenize", "detokenize", "access", "cluster", "log", "status", "metric", "bench", "top", "doctor", "fsck", "cache", "operator", "gitops", "migrate-ciphertext", "bundle", "update", "completion", "man"},
+		cmd + " server":     {"--config", "--addr", "--ip-stack", "--auth", "--ui", "--bootstrap", "--metrics-addr", "--metrics-tls", "--metrics-identities", "--metrics-enclave-label", "--metrics-key-prefix", "--scim-addr", "--scim-enclave", "--scim-default-policy", "--signed-requests", "--clock-skew", "--max-requests", "--max-enclave-requests", "--max-body-bytes", "--watchdog-dump", "--watchdog-goroutines", "--watchdog-memory", "--watchdog-blocked", "--authorizer", "--log-level", "--log-format", "--audit-decisions", "--ca-max-client-ttl", "--ca-max-server-ttl", "--ca-crl-ttl", "--ca-max-ssh-ttl", "--max-token-ttl", "--key-algorithms", "--default-key-algorithm", "--min-key-size", "--reserved-prefix", "--max-name-length", "--extended-names"},
+		cmd + " init":       {"--config", "--yes", "--force"},
+		cmd + " log":        {"--audit", "--error", "--security", "--json", "--level", "--identity", "--path", "--status", "--enclave", "--insecure"},
+		cmd + " status":     {"--short", "--api", "--json", "--output", "--color", "--insecure"},
+		cmd + " metric":     {"--rate", "--insecure"},
+		cmd + " bench":      {"--concurrency", "--duration", "--op", "--size", "--enclave", "--json", "--color", "--insecure"},
+		cmd + " top":        {"--interval", "--insecure", "--color"},
+		cmd + " doctor":     {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " fsck":       {"--quarantine", "--insecure", "--json", "--color"},
+		cmd + " operator":   {"--namespace", "--interval", "--kube-api", "--print-crds", "--insecure"},
+		cmd + " gitops":     {"--branch", "--path", "--dir", "--interval", "--once", "--prune", "--dry-run", "--allowed-signers", "--skip-verify-commits", "--insecure"},
+		cmd + " update":     {"--downgrade", "--output", "--os", "--arch", "--minisign-key", "--insecure"},
+		cmd + " completion": {"bash", "zsh", "fish", "powershell"},
+		cmd + " man":        {},
+
+		cmd + " bundle":         {"create", "inspect"},
+		cmd + " bundle create":  {"--from", "--key", "--sign"},
+		cmd + " bundle inspect": {"--key", "--verify"},
+
+		cmd + " cache":       {"stats", "flush"},
+		cmd + " cache stats": {"--insecure", "--enclave", "--json", "--color"},
+		cmd + " cache flush": {"--insecure", "--enclave"},
+
+		cmd + " cluster":        {"status", "nodes"},
+		cmd + " cluster status": {"--insecure", "--json", "--color"},
+		cmd + " cluster nodes":  {"--insecure", "--json", "--color"},
+
+		cmd + " enclave":        {"create", "info", "ls", "rm"},
+		cmd + " enclave create": {"--insecure", "--key-algorithms", "--default-key-algorithm", "--min-key-size"},
 		cmd + " enclave info":   {"--insecure", "--json", "--color"},
+		cmd + " enclave ls":     {"--insecure", "--json", "--color"},
 		cmd + " enclave rm":     {"--insecure"},
 
-		cmd + " key":         {"create", "import", "info", "ls", "rm", "encrypt", "decrypt", "dek"},
-		cmd + " key create":  {"--enclave", "--insecure"},
-		cmd + " key import":  {"--enclave", "--insecure"},
-		cmd + " key info":    {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " key ls":      {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " key rm":      {"--enclave", "--insecure"},
-		cmd + " key encrypt": {"--enclave", "--insecure"},
-		cmd + " key decrypt": {"--enclave", "--insecure"},
-		cmd + " key dek":     {"--enclave", "--insecure"},
-
-		cmd + " policy":        {"create", "assign", "info", "ls", "rm", "show"},
-		cmd + " policy create": {"--enclave", "--insecure"},
-		cmd + " policy assign": {"--enclave", "--insecure"},
-		cmd + " policy info":   {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " key":           {"create", "import", "register", "info", "ls", "rm", "verify", "hold", "release", "disable", "enable", "allowlist", "encrypt", "decrypt", "dek", "hash", "fpe-encrypt", "fpe-decrypt", "agree", "encrypt-file", "decrypt-file"},
+		cmd + " key create":    {"--algorithm", "--enclave", "--insecure", "--retention"},
+		cmd + " key import":    {"--enclave", "--insecure"},
+		cmd + " key register":  {"--enclave", "--insecure"},
+		cmd + " key info":      {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " key ls":        {"--enclave", "--insecure", "--json", "--output", "--color"},
+		cmd + " key rm":        {"--pattern", "--dry-run", "--yes", "--color", "--enclave", "--insecure"},
+		cmd + " key verify":    {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " key hold":      {"--enclave", "--insecure"},
+		cmd + " key release":   {"--enclave", "--insecure"},
+		cmd + " key disable":   {"--enclave", "--insecure"},
+		cmd + " key enable":    {"--enclave", "--insecure"},
+		cmd + " key allowlist": {"--identity", "--policy", "--clear", "--enclave", "--insecure"},
+		cmd + " key encrypt":   {"--envelope", "--enclave", "--insecure"},
+		cmd + " key decrypt":   {"--enclave", "--insecure"},
+		cmd + " key dek":       {"--envelope", "--enclave", "--insecure"},
+		cmd + " key hash":      {"--enclave", "--insecure"},
+
+		cmd + " key encrypt-file": {"--enclave", "--insecure", "--out"},
+		cmd + " key decrypt-file": {"--enclave", "--insecure", "--out"},
+		cmd + " key fpe-encrypt":  {"--mode", "--alphabet", "--radix", "--tweak", "--enclave", "--insecure"},
+		cmd + " key fpe-decrypt":  {"--mode", "--alphabet", "--radix", "--tweak", "--enclave", "--insecure"},
+		cmd + " key agree":        {"--curve", "--salt", "--info", "--length", "--enclave", "--insecure"},
+
+		cmd + " policy":        {"create", "assign", "check", "edit", "import", "info", "ls", "rm", "show"},
+		cmd + " policy create": {"--enclave", "--insecure", "--retention"},
+		cmd + " policy assign": {"--expiry", "--enclave", "--insecure"},
+		cmd + " policy check":  {"--policy", "--api", "--json", "--color"},
+		cmd + " policy edit":   {"--enclave", "--insecure", "--json", "--yes", "--color"},
+		cmd + " policy import": {"--enclave", "--insecure", "--dry-run", "--json", "--color"},
+		cmd + " policy info":   {"--enclave", "--insecure", "--json", "--output", "--color"},
 		cmd + " policy ls":     {"--enclave", "--insecure", "--json", "--color"},
 		cmd + " policy rm":     {"--enclave", "--insecure"},
 		cmd + " policy show":   {"--enclave", "--insecure", "--json"},
 
-		cmd + " identity":      {"new", "of", "info", "ls", "rm"},
-		cmd + " identity new":  {"--key", "--cert", "--force", "--ip", "--dns", "--expiry", "--encrypt"},
-		cmd + " identity of":   {},
-		cmd + " identity info": {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " identity ls":   {"--enclave", "--insecure", "--json", "--color"},
-		cmd + " identity rm":   {"--enclave", "--insecure"},
-	}
+		cmd + " identity":        {"new", "of", "info", "import", "ls", "rm", "rotate"},
+		cmd + " identity new":    {"--key", "--cert", "--force", "--ip", "--dns", "--expiry", "--encrypt"},
+		cmd + " identity of":     {},
+		cmd + " identity info":   {"--enclave", "--insecure", "--json", "--color"},
+		cmd + " identity import": {"--enclave", "--insecure", "--dry-run", "--json", "--color"},
+		cmd + " identity ls":     {"--enclave", "--insecure", "--json", "--output", "--color"},
+		cmd + " identity rm":     {"--enclave", "--insecure"},
+		cmd + " identity rotate": {"--key", "--cert", "--ttl", "--enclave", "--insecure"},
 
-	fields := strings.Fields(line)
-	cmds := make([]string, 0, len(fields))
-	for _, field := range fields {
-		if !strings.HasPrefix(field, "-") {
-			cmds = append(cmds, field)
-		}
-	}
-	line = strings.Join(cmds, " ")
+		cmd + " ca":        {"cert", "crl", "issue", "revoke"},
+		cmd + " ca cert":   {"--insecure"},
+		cmd + " ca crl":    {"--insecure"},
+		cmd + " ca issue":  {"--key", "--cert", "--force", "--server", "--ip", "--dns", "--ttl", "--insecure"},
+		cmd + " ca revoke": {"--insecure"},
 
-	var match string
-	for key := range completion {
-		if strings.HasPrefix(line, key) && len(key) > len(match) {
-			match = key
-		}
-	}
-	if candidates, ok := completion[match]; ok {
-		line = strings.TrimSpace(strings.TrimPrefix(line, match))
-		for _, candidate := range candidates {
-			if strings.HasPrefix(candidate, line) {
-				fmt.Println(candidate)
-			}
-		}
+		cmd + " ssh":      {"ca", "sign"},
+		cmd + " ssh ca":   {"--enclave", "--insecure"},
+		cmd + " ssh sign": {"--principal", "--host", "--ttl", "--out", "--enclave", "--insecure"},
+
+		cmd + " token":           {"designate", "sign", "jwks"},
+		cmd + " token designate": {"--revoke", "--enclave", "--insecure"},
+		cmd + " token sign":      {"--claim", "--claims", "--ttl", "--json", "--enclave", "--insecure"},
+		cmd + " token jwks":      {"--enclave", "--insecure"},
+
+		cmd + " cert":       {"ca", "issue"},
+		cmd + " cert ca":    {"--enclave", "--insecure"},
+		cmd + " cert issue": {"--key", "--cert", "--force", "--ip", "--dns", "--ttl", "--enclave", "--insecure"},
+
+		cmd + " random":     {"--enclave", "--insecure"},
+		cmd + " tokenize":   {"--enclave", "--insecure"},
+		cmd + " detokenize": {"--enclave", "--insecure"},
+
+		cmd + " migrate-ciphertext": {"--dry-run", "--out", "--enclave", "--insecure"},
+
+		cmd + " access":         {"request", "approve", "deny", "ls"},
+		cmd + " access request": {"--duration", "--reason", "--enclave", "--insecure"},
+		cmd + " access approve": {"--enclave", "--insecure"},
+		cmd + " access deny":    {"--enclave", "--insecure"},
+		cmd + " access ls":      {"--enclave", "--insecure", "--json", "--color"},
 	}
-	return true
 }
 
 func installAutoCompletion() {
@@ -0,0 +1,373 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const benchCmdUsage = `Usage:
+    kes bench [options] [<key>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -c, --concurrency <n>    Number of concurrent clients. (default: #CPUs)
+    -d, --duration <t>       Duration of the benchmark. (default: 10s)
+        --op <op>,...        Operations to benchmark. Requests are distributed
+                             evenly across all operations.
+                             Possible values: *encrypt*, decrypt, generate, list.
+        --size <bytes>       Size of the plaintext used for encrypt and decrypt
+                             operations. (default: 32)
+        --json               Print the benchmark results in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+If no key name is specified, a temporary key is created before and
+deleted after the benchmark. Otherwise, the specified key has to exist.
+
+Examples:
+    $ kes bench
+    $ kes bench --op encrypt,decrypt -c 32 -d 1m my-key
+`
+
+func benchCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, benchCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		concurrency        int
+		duration           time.Duration
+		ops                []string
+		size               int
+		jsonFlag           bool
+		colorFlag          colorOption
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.IntVarP(&concurrency, "concurrency", "c", runtime.NumCPU(), "Number of concurrent clients")
+	cmd.DurationVarP(&duration, "duration", "d", 10*time.Second, "Duration of the benchmark")
+	cmd.StringSliceVar(&ops, "op", []string{benchEncrypt}, "Operations to benchmark")
+	cmd.IntVar(&size, "size", 32, "Size of the plaintext used for encrypt and decrypt operations")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the benchmark results in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes bench --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes bench --help'")
+	}
+	if concurrency <= 0 {
+		cli.Fatal("concurrency must be greater than 0. See 'kes bench --help'")
+	}
+	if duration <= 0 {
+		cli.Fatal("duration must be greater than 0. See 'kes bench --help'")
+	}
+	if size < 0 {
+		cli.Fatal("plaintext size must not be negative. See 'kes bench --help'")
+	}
+	for i, op := range ops {
+		ops[i] = strings.ToLower(strings.TrimSpace(op))
+		switch ops[i] {
+		case benchEncrypt, benchDecrypt, benchGenerate, benchList:
+		default:
+			cli.Fatalf("invalid operation %q. See 'kes bench --help'", op)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	keyName := cmd.Arg(0)
+	temporary := keyName == ""
+	if temporary {
+		var random [8]byte
+		if _, err := rand.Read(random[:]); err != nil {
+			cli.Fatal(err)
+		}
+		keyName = "kes-bench-" + hex.EncodeToString(random[:])
+		if err := enclave.CreateKey(ctx, keyName); err != nil {
+			cli.Fatalf("failed to create key %q: %v", keyName, err)
+		}
+	}
+
+	// The benchmark returns all errors such that a temporary
+	// key gets deleted before exiting - even on failure.
+	err := benchmark(ctx, enclave, keyName, ops, size, concurrency, duration, jsonFlag, colorFlag)
+	if temporary {
+		// Use a new context since ctx may have been canceled already.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := enclave.DeleteKey(ctx, keyName); err != nil {
+			cli.Printf("failed to delete key %q: %v\n", keyName, err)
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		os.Exit(1)
+	}
+	if err != nil {
+		cli.Fatal(err)
+	}
+}
+
+// benchmark runs the operations with the given key and prints
+// the results. It returns instead of exiting on errors such
+// that the caller can clean up.
+func benchmark(ctx context.Context, enclave *kes.Enclave, keyName string, ops []string, size, concurrency int, duration time.Duration, jsonFlag bool, colorFlag colorOption) error {
+	plaintext := make([]byte, size)
+	if _, err := rand.Read(plaintext); err != nil {
+		return err
+	}
+	ciphertext, err := enclave.Encrypt(ctx, keyName, plaintext, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		return fmt.Errorf("failed to encrypt with key %q: %v", keyName, err)
+	}
+
+	ops = dedup(ops)
+	run := func(ctx context.Context, op string) error {
+		switch op {
+		case benchEncrypt:
+			_, err := enclave.Encrypt(ctx, keyName, plaintext, nil)
+			return err
+		case benchDecrypt:
+			_, err := enclave.Decrypt(ctx, keyName, ciphertext, nil)
+			return err
+		case benchGenerate:
+			_, err := enclave.GenerateKey(ctx, keyName, nil)
+			return err
+		case benchList:
+			iter, err := enclave.ListKeys(ctx, "*")
+			if err != nil {
+				return err
+			}
+			for iter.Next() {
+			}
+			return iter.Close()
+		default:
+			return fmt.Errorf("unknown operation %q", op)
+		}
+	}
+
+	if !jsonFlag && isTerm(os.Stderr) {
+		fmt.Fprintf(os.Stderr, "Benchmarking %s with %d clients for %v...\n", strings.Join(ops, ", "), concurrency, duration)
+	}
+	results := runBenchmark(ctx, ops, concurrency, duration, run)
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(results)
+	}
+
+	header := tui.NewStyle()
+	errStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorErr = tui.Color("#ff0000")
+		header = header.Faint(true).Underline(true).UnderlineSpaces(false)
+		errStyle = errStyle.Foreground(ColorErr)
+	}
+	fmt.Println(
+		header.Render(fmt.Sprintf("%-10s", "Operation")),
+		header.Render(fmt.Sprintf("%10s", "Requests")),
+		header.Render(fmt.Sprintf("%8s", "Errors")),
+		header.Render(fmt.Sprintf("%10s", "Req/s")),
+		header.Render(fmt.Sprintf("%9s", "Avg")),
+		header.Render(fmt.Sprintf("%9s", "P50")),
+		header.Render(fmt.Sprintf("%9s", "P90")),
+		header.Render(fmt.Sprintf("%9s", "P99")),
+		header.Render(fmt.Sprintf("%9s", "Max")),
+	)
+	for _, r := range results {
+		errCount := fmt.Sprintf("%8d", r.Errors)
+		if r.Errors > 0 {
+			errCount = errStyle.Render(errCount)
+		}
+		fmt.Println(
+			fmt.Sprintf("%-10s", r.Operation),
+			fmt.Sprintf("%10d", r.Requests),
+			errCount,
+			fmt.Sprintf("%10.1f", r.Throughput),
+			fmt.Sprintf("%9s", r.Avg.Round(10*time.Microsecond)),
+			fmt.Sprintf("%9s", r.P50.Round(10*time.Microsecond)),
+			fmt.Sprintf("%9s", r.P90.Round(10*time.Microsecond)),
+			fmt.Sprintf("%9s", r.P99.Round(10*time.Microsecond)),
+			fmt.Sprintf("%9s", r.Max.Round(10*time.Microsecond)),
+		)
+	}
+	return nil
+}
+
+// Operations supported by 'kes bench'.
+const (
+	benchEncrypt  = "encrypt"
+	benchDecrypt  = "decrypt"
+	benchGenerate = "generate"
+	benchList     = "list"
+)
+
+// benchResult summarizes the requests of one
+// benchmarked operation.
+type benchResult struct {
+	Operation  string        `json:"operation"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	Duration   time.Duration `json:"duration"`
+	Throughput float64       `json:"throughput"` // Successful requests per second
+	Avg        time.Duration `json:"avg"`
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+}
+
+// runBenchmark calls run from n concurrent workers until the
+// duration d has elapsed or the ctx gets canceled. Each worker
+// cycles through the given operations.
+//
+// It returns one benchResult per operation, in the order
+// of ops.
+func runBenchmark(ctx context.Context, ops []string, n int, d time.Duration, run func(context.Context, string) error) []benchResult {
+	type Sample struct {
+		Latencies map[string][]time.Duration
+		Errors    map[string]int
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		samples = make([]Sample, n)
+		start   = time.Now()
+	)
+	for i := range samples {
+		samples[i] = Sample{
+			Latencies: map[string][]time.Duration{},
+			Errors:    map[string]int{},
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			sample := &samples[i]
+			for j := i; ctx.Err() == nil; j++ {
+				op := ops[j%len(ops)]
+				reqStart := time.Now()
+				if err := run(ctx, op); err != nil {
+					if ctx.Err() != nil { // Don't count requests aborted at the end of the benchmark
+						return
+					}
+					sample.Errors[op]++
+					continue
+				}
+				sample.Latencies[op] = append(sample.Latencies[op], time.Since(reqStart))
+			}
+		}(i)
+	}
+
+	// Measure the elapsed time once the benchmark ends and not once
+	// all workers have returned. Requests that are still in flight
+	// get canceled and may take a while to return - e.g. due to
+	// client-side retries.
+	<-ctx.Done()
+	elapsed := time.Since(start)
+	wg.Wait()
+
+	results := make([]benchResult, 0, len(ops))
+	for _, op := range ops {
+		var (
+			latencies []time.Duration
+			errs      int
+		)
+		for _, s := range samples {
+			latencies = append(latencies, s.Latencies[op]...)
+			errs += s.Errors[op]
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		result := benchResult{
+			Operation:  op,
+			Requests:   len(latencies) + errs,
+			Errors:     errs,
+			Duration:   elapsed,
+			Throughput: float64(len(latencies)) / elapsed.Seconds(),
+			P50:        percentile(latencies, 50),
+			P90:        percentile(latencies, 90),
+			P99:        percentile(latencies, 99),
+		}
+		if len(latencies) > 0 {
+			var sum time.Duration
+			for _, l := range latencies {
+				sum += l
+			}
+			result.Avg = sum / time.Duration(len(latencies))
+			result.Max = latencies[len(latencies)-1]
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// percentile returns the p-th percentile of the
+// sorted latencies using the nearest-rank method.
+func percentile(latencies []time.Duration, p int) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	rank := (p*len(latencies) + 99) / 100 // ceil(p/100 * N)
+	if rank < 1 {
+		rank = 1
+	}
+	return latencies[rank-1]
+}
+
+// dedup removes duplicate entries from s
+// while preserving the order of s.
+func dedup(s []string) []string {
+	seen := make(map[string]bool, len(s))
+	out := s[:0]
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
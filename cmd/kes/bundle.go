@@ -0,0 +1,277 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/minio/kes/edge"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/keystore/bundle"
+	flag "github.com/spf13/pflag"
+)
+
+const bundleCmdUsage = `Usage:
+    kes bundle <command>
+
+Commands:
+    create                   Create a signed and encrypted key bundle.
+    inspect                  Verify a key bundle and print its content.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func bundleCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, bundleCmdUsage) }
+
+	subCmds := commands{
+		"create":  createBundleCmd,
+		"inspect": inspectBundleCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes bundle --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a bundle command. See 'kes bundle --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const createBundleCmdUsage = `Usage:
+    kes bundle create [options] <file> [<pattern>]
+
+Options:
+    --from <PATH>            Path to the KES config file of the keystore
+                             and policies to bundle.
+    --key <HEX>              Hex-encoded 256 bit key used to encrypt the
+                             bundle. Defaults to $KES_BUNDLE_KEY.
+    --sign <PATH>            Path to the PEM-encoded Ed25519 private key
+                             used to sign the bundle.
+
+    -h, --help               Print command line options.
+
+Creates a bundle file containing all keys that match the pattern and
+all policies of the KES config file. The bundle is encrypted with the
+bundle key and signed with the Ed25519 private key.
+
+A KES server provisioned with the bundle serves its keys and policies
+without any network connectivity. Use the 'bundle' keystore to point
+the server to the bundle file, the bundle key and the Ed25519 public
+key.
+
+Examples:
+    $ openssl genpkey -algorithm ed25519 -out bundle.key
+    $ openssl pkey -in bundle.key -pubout -out bundle.pub
+    $ export KES_BUNDLE_KEY=$(openssl rand -hex 32)
+    $ kes bundle create --from config.yml --sign bundle.key site.bundle
+`
+
+func createBundleCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, createBundleCmdUsage) }
+
+	var (
+		fromPath string
+		keyFlag  string
+		signPath string
+	)
+	cmd.StringVar(&fromPath, "from", "", "Path to the KES config file of the keystore and policies")
+	cmd.StringVar(&keyFlag, "key", "", "Hex-encoded 256 bit bundle key")
+	cmd.StringVar(&signPath, "sign", "", "Path to the Ed25519 private key")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes bundle create --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no bundle file specified. See 'kes bundle create --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes bundle create --help'")
+	case fromPath == "":
+		cli.Fatal("no config file specified. Use '--from' to specify a config file")
+	case signPath == "":
+		cli.Fatal("no signing key specified. Use '--sign' to specify an Ed25519 private key")
+	}
+	if keyFlag == "" {
+		keyFlag = os.Getenv("KES_BUNDLE_KEY")
+	}
+	if keyFlag == "" {
+		cli.Fatal("no bundle key specified. Use '--key' or $KES_BUNDLE_KEY to specify a bundle key")
+	}
+	key, err := bundle.ParseKey(keyFlag)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	signer, err := bundle.ReadPrivateKey(signPath)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	pattern := cmd.Arg(1)
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	file, err := os.Open(fromPath)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	config, err := edge.ReadServerConfigYAML(file)
+	file.Close()
+	if err != nil {
+		cli.Fatalf("failed to read '--from' config file: %v", err)
+	}
+	store, err := config.KeyStore.Connect(ctx)
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	b := &bundle.Bundle{
+		CreatedAt: time.Now().UTC(),
+		Keys:      map[string][]byte{},
+		Policies:  make(map[string]bundle.Policy, len(config.Policies)),
+	}
+	iter, err := store.List(ctx)
+	if err != nil {
+		cli.Fatalf("failed to list keys: %v", err)
+	}
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if ok, _ := filepath.Match(pattern, name); !ok {
+			continue
+		}
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			iter.Close()
+			cli.Fatalf("failed to fetch key '%s': %v", name, err)
+		}
+		b.Keys[name] = value
+	}
+	if err = iter.Close(); err != nil {
+		cli.Fatalf("failed to list keys: %v", err)
+	}
+	for name, policy := range config.Policies {
+		b.Policies[name] = bundle.Policy{
+			Allow:      policy.Allow,
+			Deny:       policy.Deny,
+			Identities: policy.Identities,
+		}
+	}
+
+	data, err := bundle.Seal(b, key, signer)
+	if err != nil {
+		cli.Fatalf("failed to create bundle: %v", err)
+	}
+	if err = os.WriteFile(cmd.Arg(0), data, 0o600); err != nil {
+		cli.Fatal(err)
+	}
+	fmt.Printf("Bundled %d keys and %d policies into '%s'\n", len(b.Keys), len(b.Policies), cmd.Arg(0))
+}
+
+const inspectBundleCmdUsage = `Usage:
+    kes bundle inspect [options] <file>
+
+Options:
+    --key <HEX>              Hex-encoded 256 bit key used to decrypt the
+                             bundle. Defaults to $KES_BUNDLE_KEY.
+    --verify <PATH>          Path to the PEM-encoded Ed25519 public key
+                             used to verify the bundle signature.
+
+    -h, --help               Print command line options.
+
+Verifies the signature of the bundle, decrypts it and prints the
+names of its keys and policies.
+
+Examples:
+    $ kes bundle inspect --verify bundle.pub site.bundle
+`
+
+func inspectBundleCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, inspectBundleCmdUsage) }
+
+	var (
+		keyFlag    string
+		verifyPath string
+	)
+	cmd.StringVar(&keyFlag, "key", "", "Hex-encoded 256 bit bundle key")
+	cmd.StringVar(&verifyPath, "verify", "", "Path to the Ed25519 public key")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes bundle inspect --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no bundle file specified. See 'kes bundle inspect --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes bundle inspect --help'")
+	case verifyPath == "":
+		cli.Fatal("no public key specified. Use '--verify' to specify an Ed25519 public key")
+	}
+	if keyFlag == "" {
+		keyFlag = os.Getenv("KES_BUNDLE_KEY")
+	}
+	if keyFlag == "" {
+		cli.Fatal("no bundle key specified. Use '--key' or $KES_BUNDLE_KEY to specify a bundle key")
+	}
+
+	b, err := (&edge.BundleKeyStore{
+		Path:      cmd.Arg(0),
+		Key:       keyFlag,
+		PublicKey: verifyPath,
+	}).Open()
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	keys := make([]string, 0, len(b.Keys))
+	for name := range b.Keys {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	policies := make([]string, 0, len(b.Policies))
+	for name := range b.Policies {
+		policies = append(policies, name)
+	}
+	sort.Strings(policies)
+
+	fmt.Printf("Created: %s\n", b.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("Keys (%d):\n", len(keys))
+	for _, name := range keys {
+		fmt.Println("  " + name)
+	}
+	fmt.Printf("Policies (%d):\n", len(policies))
+	for _, name := range policies {
+		fmt.Println("  " + name)
+	}
+}
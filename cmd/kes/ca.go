@@ -0,0 +1,374 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const caCmdUsage = `Usage:
+    kes ca <command>
+
+Commands:
+    cert                     Print the CA certificate.
+    crl                      Print the certificate revocation list.
+    issue                    Issue a server or client certificate.
+    revoke                   Revoke a certificate.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func caCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, caCmdUsage) }
+
+	subCmds := commands{
+		"cert":   certCACmd,
+		"crl":    crlCACmd,
+		"issue":  issueCACmd,
+		"revoke": revokeCACmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ca --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a ca command. See 'kes ca --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const certCACmdUsage = `Usage:
+    kes ca cert [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Prints the PEM-encoded certificate of the built-in CA of the server.
+Clients and servers that should accept certificates issued by the
+built-in CA have to trust this certificate.
+
+Examples:
+    $ kes ca cert > ca.crt
+`
+
+func certCACmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, certCACmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ca cert --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes ca cert --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	cert, err := kesclient.CACertificate(ctx, newClient(insecureSkipVerify))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch CA certificate: %v", err)
+	}
+	fmt.Print(string(cert))
+}
+
+const crlCACmdUsage = `Usage:
+    kes ca crl [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Prints the PEM-encoded certificate revocation list (CRL) of the built-in CA
+of the server. The CRL is also available, DER-encoded, under /v1/ca/crl.
+
+Examples:
+    $ kes ca crl > ca.crl
+`
+
+func crlCACmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, crlCACmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ca crl --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes ca crl --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	crl, err := kesclient.RevocationList(ctx, newClient(insecureSkipVerify))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch certificate revocation list: %v", err)
+	}
+	fmt.Print(string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})))
+}
+
+const issueCACmdUsage = `Usage:
+    kes ca issue [options] <subject>
+
+Options:
+    --key <PATH>             Path to the private key. (default: ./private.key)
+    --cert <PATH>            Path to the certificate. (default: ./public.crt)
+    -f, --force              Overwrite an existing private key and/or certificate.
+
+    --server                 Issue a server instead of a client certificate.
+    --ip <IP>                Add <IP> as subject alternative name. (SAN)
+    --dns <DOMAIN>           Add <DOMAIN> as subject alternative name. (SAN)
+    --ttl <DURATION>         Duration until the certificate expires. Defaults to
+                             24h for client and to the max. lifetime for server
+                             certificates.
+
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Generates a new private key and requests a certificate for it from the built-in
+CA of the server. Server certificates require at least one DNS name or IP
+address. Only the system admin can issue certificates.
+
+Examples:
+    $ kes ca issue --server --dns kes-1.local --key kes-1.key --cert kes-1.crt kes-1
+    $ kes ca issue --ttl 72h --key client.key --cert client.crt client
+`
+
+func issueCACmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, issueCACmdUsage) }
+
+	var (
+		keyPath            string
+		certPath           string
+		forceFlag          bool
+		serverFlag         bool
+		IPs                []net.IP
+		domains            []string
+		ttl                time.Duration
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&keyPath, "key", "private.key", "Path to the private key")
+	cmd.StringVar(&certPath, "cert", "public.crt", "Path to the certificate")
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Overwrite an existing private key and/or certificate")
+	cmd.BoolVar(&serverFlag, "server", false, "Issue a server instead of a client certificate")
+	cmd.IPSliceVar(&IPs, "ip", []net.IP{}, "Add <IP> as subject alternative name")
+	cmd.StringSliceVar(&domains, "dns", []string{}, "Add <DOMAIN> as subject alternative name")
+	cmd.DurationVar(&ttl, "ttl", 0, "Duration until the certificate expires")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ca issue --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no subject specified. See 'kes ca issue --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes ca issue --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid '--ttl': must not be negative")
+	}
+	if !serverFlag && (len(IPs) > 0 || len(domains) > 0) {
+		cli.Fatal("'--ip' and '--dns' require '--server'. See 'kes ca issue --help'")
+	}
+	if serverFlag && len(IPs) == 0 && len(domains) == 0 {
+		cli.Fatal("server certificate requires at least one '--ip' or '--dns'. See 'kes ca issue --help'")
+	}
+	if !forceFlag {
+		if _, err := os.Stat(keyPath); err == nil {
+			cli.Fatal("private key already exists. Use --force to overwrite it")
+		}
+		if _, err := os.Stat(certPath); err == nil {
+			cli.Fatal("certificate already exists. Use --force to overwrite it")
+		}
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		cli.Fatalf("failed to generate private key: %v", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: cmd.Arg(0)},
+	}, priv)
+	if err != nil {
+		cli.Fatalf("failed to create certificate request: %v", err)
+	}
+	ips := make([]string, 0, len(IPs))
+	for _, ip := range IPs {
+		ips = append(ips, ip.String())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	cert, err := kesclient.IssueCACertificate(ctx, newClient(insecureSkipVerify), &kesclient.CertificateRequest{
+		CSR:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+		Server:      serverFlag,
+		DNSNames:    domains,
+		IPAddresses: ips,
+		TTL:         ttl,
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to issue certificate: %v", err)
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		cli.Fatalf("failed to encode private key: %v", err)
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}), 0o600); err != nil {
+		cli.Fatalf("failed to write private key: %v", err)
+	}
+	if err = os.WriteFile(certPath, []byte(cert.Certificate), 0o644); err != nil {
+		os.Remove(keyPath)
+		cli.Fatalf("failed to write certificate: %v", err)
+	}
+
+	year, month, day := cert.ExpiresAt.Local().Date()
+	hour, min, sec := cert.ExpiresAt.Local().Clock()
+	var buffer strings.Builder
+	fmt.Fprintf(&buffer, "Identity:      %s\n", cert.Identity)
+	fmt.Fprintf(&buffer, "Serial Number: %s\n", cert.SerialNumber)
+	fmt.Fprintf(&buffer, "Expires At:    %04d-%02d-%02d %02d:%02d:%02d\n", year, month, day, hour, min, sec)
+	fmt.Fprintf(&buffer, "Private Key:   %s\n", keyPath)
+	fmt.Fprintf(&buffer, "Certificate:   %s", certPath)
+	cli.Println(buffer.String())
+}
+
+const revokeCACmdUsage = `Usage:
+    kes ca revoke [options] <serial-number>
+    kes ca revoke [options] <certificate>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Revokes a certificate issued by the built-in CA of the server. The certificate
+is either specified by its hex-encoded serial number or by the path to the
+certificate file. Revoked certificates are listed in the certificate revocation
+list and rejected by the server. Only the system admin can revoke certificates.
+
+Examples:
+    $ kes ca revoke 5c:1f:9a:02:7e:4b:0d:31:a8:67:c2:19:5e:f0:33:8d
+    $ kes ca revoke client.crt
+`
+
+func revokeCACmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, revokeCACmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ca revoke --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no certificate specified. See 'kes ca revoke --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes ca revoke --help'")
+	}
+
+	var serialNumber *big.Int
+	if _, err := os.Stat(cmd.Arg(0)); err == nil {
+		cert, err := readCertificate(cmd.Arg(0))
+		if err != nil {
+			cli.Fatalf("failed to read certificate '%s': %v", cmd.Arg(0), err)
+		}
+		serialNumber = cert.SerialNumber
+	} else {
+		var ok bool
+		if serialNumber, ok = new(big.Int).SetString(strings.ReplaceAll(cmd.Arg(0), ":", ""), 16); !ok {
+			cli.Fatalf("invalid serial number '%s'. See 'kes ca revoke --help'", cmd.Arg(0))
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	if err := kesclient.RevokeCertificate(ctx, newClient(insecureSkipVerify), serialNumber); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to revoke certificate: %v", err)
+	}
+}
+
+// readCertificate reads and parses the first
+// PEM-encoded certificate of the given file.
+func readCertificate(filename string) (*x509.Certificate, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM-encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
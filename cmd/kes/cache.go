@@ -0,0 +1,219 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const cacheCmdUsage = `Usage:
+    kes cache <command>
+
+Commands:
+    stats                    Print the cache statistics of a server.
+    flush                    Remove entries from the caches of a server.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func cacheCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, cacheCmdUsage) }
+
+	subCmds := commands{
+		"stats": statsCacheCmd,
+		"flush": flushCacheCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cache --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a cache command. See 'kes cache --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const statsCacheCmdUsage = `Usage:
+    kes cache stats [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Print the cache statistics of this key namespace
+                             of a KES edge server.
+        --json               Print the cache statistics in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Prints the number of cached keys, secrets, policies and identities, and
+how many keys have been served from the cache (hits) or fetched from the
+keystore (misses). A stateful server reports the cache statistics of all
+its enclaves. Only the system admin can fetch the cache statistics of a
+stateful server.
+
+Examples:
+    $ kes cache stats
+`
+
+func statsCacheCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, statsCacheCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		enclaveName        string
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the cache statistics in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cache stats --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes cache stats --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+	stats, err := kesclient.ReadCacheStats(ctx, newClient(insecureSkipVerify), enclaveName)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch cache statistics: %v", err)
+	}
+
+	if jsonFlag {
+		if err = json.NewEncoder(os.Stdout).Encode(stats); err != nil {
+			cli.Fatalf("failed to fetch cache statistics: %v", err)
+		}
+		return
+	}
+
+	var faint tui.Style
+	if colorFlag.Colorize() {
+		faint = faint.Faint(true).Bold(true)
+	}
+	if stats.Enclaves > 0 {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Enclaves")), stats.Enclaves)
+	}
+	if stats.OfflineKeys > 0 {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Keys")), stats.Keys, fmt.Sprintf("(%d offline)", stats.OfflineKeys))
+	} else {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Keys")), stats.Keys)
+	}
+	if stats.Enclaves > 0 {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Secrets")), stats.Secrets)
+	}
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Policies")), stats.Policies)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Identities")), stats.Identities)
+
+	var hitRate float64
+	if total := stats.KeyHits + stats.KeyMisses; total > 0 {
+		hitRate = 100 * float64(stats.KeyHits) / float64(total)
+	}
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Hits")), stats.KeyHits, fmt.Sprintf("(%.1f%%)", hitRate))
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Misses")), stats.KeyMisses)
+}
+
+const flushCacheCmdUsage = `Usage:
+    kes cache flush [options] [<pattern>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Flush the caches of this key namespace of a KES
+                             edge server.
+
+    -h, --help               Print command line options.
+
+Removes all cached keys whose names match the glob pattern from the
+caches of a server. Without a pattern, it removes all cached keys,
+policies and identities. Subsequent requests fetch flushed entries from
+the keystore again. Use it to force a refresh after modifying the
+keystore out-of-band.
+
+A stateful server flushes the caches of all its enclaves. Only the
+system admin can flush the caches of a stateful server. In a cluster,
+each node has its own caches.
+
+Examples:
+    $ kes cache flush
+    $ kes cache flush 'my-app-*'
+`
+
+func flushCacheCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, flushCacheCmdUsage) }
+
+	var (
+		enclaveName        string
+		insecureSkipVerify bool
+	)
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cache flush --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes cache flush --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+	n, err := kesclient.FlushCache(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to flush cache: %v", err)
+	}
+	fmt.Printf("Flushed %d keys\n", n)
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const certCmdUsage = `Usage:
+    kes cert <command>
+
+Commands:
+    ca                       Print the CA certificate of a key.
+    issue                    Issue a certificate for a certificate profile.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func certCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, certCmdUsage) }
+
+	subCmds := commands{
+		"ca":    caCertCmd,
+		"issue": issueCertCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cert --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a cert command. See 'kes cert --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const caCertCmdUsage = `Usage:
+    kes cert ca [options] <key>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Prints the certificate of the CA derived from the KES key <key>. The CA
+certificate does not change as long as the key exists. TLS clients and
+servers trust certificates issued for a certificate profile if the CA
+certificate of the profile's key is part of their root CAs.
+
+Examples:
+    $ kes cert ca my-ca > ca.crt
+`
+
+func caCertCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, caCertCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cert ca --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes cert ca --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes cert ca --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	cert, err := kesclient.ProfileCA(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch CA certificate: %v", err)
+	}
+	fmt.Print(string(cert))
+}
+
+const issueCertCmdUsage = `Usage:
+    kes cert issue [options] <profile>
+
+Options:
+    --key <PATH>             Path to the private key. (default: ./private.key)
+    --cert <PATH>            Path to the certificate. (default: ./public.crt)
+    -f, --force              Overwrite an existing private key and/or certificate.
+
+    --ip <IP>                Add <IP> as subject alternative name. (SAN)
+    --dns <DOMAIN>           Add <DOMAIN> as subject alternative name. (SAN)
+    --ttl <DURATION>         Duration until the certificate expires. (default: 24h)
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Generates a new private key and requests a certificate for it for the certificate
+profile <profile>. Certificate profiles are defined by the policy of the identity
+and specify the KES key acting as CA, the allowed DNS names and IP ranges, the
+usage and the max. lifetime of certificates. For example:
+
+    {
+      "allow": ["/v1/cert/issue/web"],
+      "certificates": {
+        "web": {
+          "key": "my-ca",
+          "dns_names": ["*.svc.cluster.local"],
+          "ip_ranges": ["10.0.0.0/8"],
+          "usage": ["server", "client"],
+          "max_ttl": "72h"
+        }
+      }
+    }
+
+Examples:
+    $ kes cert issue --dns web.svc.cluster.local --key web.key --cert web.crt web
+`
+
+func issueCertCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, issueCertCmdUsage) }
+
+	var (
+		keyPath            string
+		certPath           string
+		forceFlag          bool
+		IPs                []net.IP
+		domains            []string
+		ttl                time.Duration
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&keyPath, "key", "private.key", "Path to the private key")
+	cmd.StringVar(&certPath, "cert", "public.crt", "Path to the certificate")
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Overwrite an existing private key and/or certificate")
+	cmd.IPSliceVar(&IPs, "ip", []net.IP{}, "Add <IP> as subject alternative name")
+	cmd.StringSliceVar(&domains, "dns", []string{}, "Add <DOMAIN> as subject alternative name")
+	cmd.DurationVar(&ttl, "ttl", 0, "Duration until the certificate expires")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cert issue --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no certificate profile specified. See 'kes cert issue --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes cert issue --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid '--ttl': must not be negative")
+	}
+	if len(IPs) == 0 && len(domains) == 0 {
+		cli.Fatal("certificate requires at least one '--ip' or '--dns'. See 'kes cert issue --help'")
+	}
+	if !forceFlag {
+		if _, err := os.Stat(keyPath); err == nil {
+			cli.Fatal("private key already exists. Use --force to overwrite it")
+		}
+		if _, err := os.Stat(certPath); err == nil {
+			cli.Fatal("certificate already exists. Use --force to overwrite it")
+		}
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		cli.Fatalf("failed to generate private key: %v", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, priv)
+	if err != nil {
+		cli.Fatalf("failed to create certificate request: %v", err)
+	}
+	ips := make([]string, 0, len(IPs))
+	for _, ip := range IPs {
+		ips = append(ips, ip.String())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	cert, err := kesclient.IssueProfileCertificate(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0), &kesclient.ProfileCertificateRequest{
+		CSR:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+		DNSNames:    domains,
+		IPAddresses: ips,
+		TTL:         ttl,
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to issue certificate: %v", err)
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		cli.Fatalf("failed to encode private key: %v", err)
+	}
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}), 0o600); err != nil {
+		cli.Fatalf("failed to write private key: %v", err)
+	}
+	if err = os.WriteFile(certPath, []byte(cert.Certificate), 0o644); err != nil {
+		os.Remove(keyPath)
+		cli.Fatalf("failed to write certificate: %v", err)
+	}
+
+	year, month, day := cert.ExpiresAt.Local().Date()
+	hour, min, sec := cert.ExpiresAt.Local().Clock()
+	var buffer strings.Builder
+	fmt.Fprintf(&buffer, "Serial Number: %s\n", cert.SerialNumber)
+	fmt.Fprintf(&buffer, "Expires At:    %04d-%02d-%02d %02d:%02d:%02d\n", year, month, day, hour, min, sec)
+	fmt.Fprintf(&buffer, "Private Key:   %s\n", keyPath)
+	fmt.Fprintf(&buffer, "Certificate:   %s", certPath)
+	cli.Println(buffer.String())
+}
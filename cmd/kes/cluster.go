@@ -0,0 +1,243 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const clusterCmdUsage = `Usage:
+    kes cluster <command>
+
+Commands:
+    status                   Print the cluster status of a server.
+    nodes                    List all cluster nodes.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func clusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, clusterCmdUsage) }
+
+	subCmds := commands{
+		"status": statusClusterCmd,
+		"nodes":  nodesClusterCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a cluster command. See 'kes cluster --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const statusClusterCmdUsage = `Usage:
+    kes cluster status [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the cluster status in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Prints the ID, role, version, supported internal API wire versions and
+replication lag of the server. The replication lag of a follower is the
+time its data lags behind the data of the leader. Only the system admin
+can fetch the cluster status.
+
+Examples:
+    $ kes cluster status
+`
+
+func statusClusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, statusClusterCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the cluster status in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster status --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes cluster status --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	status, err := kesclient.ClusterStatus(ctx, newClient(insecureSkipVerify))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch cluster status: %v", err)
+	}
+
+	if jsonFlag {
+		if err = json.NewEncoder(os.Stdout).Encode(status); err != nil {
+			cli.Fatalf("failed to fetch cluster status: %v", err)
+		}
+		return
+	}
+
+	var faint, idStyle tui.Style
+	if colorFlag.Colorize() {
+		const ColorID tui.Color = "#2e42d1"
+		faint = faint.Faint(true).Bold(true)
+		idStyle = idStyle.Foreground(ColorID)
+	}
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Node")), idStyle.Render(status.ID))
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Endpoint")), status.Endpoint)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Role")), status.Role)
+	if status.Leader != "" {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Leader")), status.Leader)
+	}
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Version")), status.Version)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Wire")), fmt.Sprintf("v%d - v%d", status.MinWireVersion, status.MaxWireVersion))
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Lag")), fmtReplicationLag(status.ReplicationLag))
+	if status.Err != "" {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Error")), status.Err)
+	}
+}
+
+const nodesClusterCmdUsage = `Usage:
+    kes cluster nodes [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print cluster nodes in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Lists the ID, endpoint, role, version and replication lag of all nodes
+of the server's cluster. The server fetches the status of the other nodes
+on behalf of the client. Only the system admin can list cluster nodes.
+
+Examples:
+    $ kes cluster nodes
+`
+
+func nodesClusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, nodesClusterCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print cluster nodes in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster nodes --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes cluster nodes --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	nodes, err := kesclient.ClusterNodes(ctx, newClient(insecureSkipVerify))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list cluster nodes: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, node := range nodes {
+			if err = encoder.Encode(node); err != nil {
+				cli.Fatal(err)
+			}
+		}
+		return
+	}
+
+	headerStyle := tui.NewStyle()
+	errStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorErr tui.Color = "#ac0000"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		errStyle = errStyle.Foreground(ColorErr)
+	}
+
+	fmt.Println(
+		headerStyle.Render(fmt.Sprintf("%-20s", "Node")),
+		headerStyle.Render(fmt.Sprintf("%-30s", "Endpoint")),
+		headerStyle.Render(fmt.Sprintf("%-10s", "Role")),
+		headerStyle.Render(fmt.Sprintf("%-20s", "Version")),
+		headerStyle.Render("Lag"),
+	)
+	for _, node := range nodes {
+		if node.Err != "" {
+			fmt.Printf("%-20s %-30s %s\n", "-", node.Endpoint, errStyle.Render(node.Err))
+			continue
+		}
+		fmt.Printf("%-20s %-30s %-10s %-20s %s\n", node.ID, node.Endpoint, node.Role, node.Version, fmtReplicationLag(node.ReplicationLag))
+	}
+}
+
+// fmtReplicationLag returns a human-readable
+// representation of the replication lag.
+func fmtReplicationLag(lag time.Duration) string {
+	if lag < 0 {
+		return "unknown"
+	}
+	return lag.Truncate(time.Second).String()
+}
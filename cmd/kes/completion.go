@@ -0,0 +1,315 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const completionCmdUsage = `Usage:
+    kes completion <shell>
+
+Shells:
+    bash                     Print the bash completion script.
+    zsh                      Print the zsh completion script.
+    fish                     Print the fish completion script.
+    powershell               Print the PowerShell completion script.
+
+Options:
+    -h, --help               Print command line options.
+
+Prints a shell completion script. Besides commands and flags, it completes
+the names of keys, policies, identities and enclaves by querying the server
+specified by the KES_SERVER environment variable. Names are only completed
+when the KES_API_KEY or the KES_CLIENT_CERT and KES_CLIENT_KEY environment
+variables are set and the private key is not password-protected.
+
+Examples:
+    $ source <(kes completion bash)
+    $ kes completion zsh > "${fpath[1]}/_kes"
+    $ kes completion fish > ~/.config/fish/completions/kes.fish
+    PS> kes completion powershell | Out-String | Invoke-Expression
+`
+
+func completionCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, completionCmdUsage) }
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes completion --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no shell specified. See 'kes completion --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes completion --help'")
+	}
+
+	name := filepath.Base(os.Args[0])
+	var script string
+	switch shell := cmd.Arg(0); shell {
+	case "bash":
+		script = fmt.Sprintf(bashCompletion, name)
+	case "zsh":
+		script = fmt.Sprintf(zshCompletion, name)
+	case "fish":
+		script = fmt.Sprintf(fishCompletion, name)
+	case "powershell":
+		script = fmt.Sprintf(powershellCompletion, name)
+	default:
+		cli.Fatalf("'%s' is not a supported shell. See 'kes completion --help'", shell)
+	}
+	fmt.Print(strings.ReplaceAll(script, "{{kes}}", name))
+}
+
+// The completion scripts call the hidden '__complete'
+// command with all words of the command line. The last
+// word is the one being completed and may be empty.
+const (
+	bashCompletion = `# bash completion for %s
+_{{kes}}_completion() {
+    local IFS=$'\n'
+    COMPREPLY=( $({{kes}} __complete "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null) )
+}
+complete -o default -F _{{kes}}_completion {{kes}}
+`
+	zshCompletion = `#compdef %s
+_{{kes}}() {
+    local -a candidates
+    candidates=("${(@f)$({{kes}} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -a candidates
+}
+compdef _{{kes}} {{kes}}
+`
+	fishCompletion = `# fish completion for %s
+complete -c {{kes}} -f -a '({{kes}} __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`
+	powershellCompletion = `# PowerShell completion for %s
+Register-ArgumentCompleter -Native -CommandName '{{kes}}' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '' }
+    & '{{kes}}' __complete @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+)
+
+// completeCmd prints the completion candidates for
+// the given command line arguments, one per line.
+func completeCmd(args []string) {
+	for _, candidate := range completeArgs(filepath.Base(os.Args[0]), args[1:]) {
+		fmt.Println(candidate)
+	}
+}
+
+// nameCompletion describes the positional arguments of
+// commands that refer to server resources. The last kind
+// applies to all remaining positional arguments if the
+// command accepts an arbitrary number of arguments.
+var nameCompletion = map[string]struct {
+	Kinds    []string
+	Variadic bool
+}{
+	"enclave info": {Kinds: []string{"enclave"}},
+	"enclave rm":   {Kinds: []string{"enclave"}, Variadic: true},
+
+	"key info":    {Kinds: []string{"key"}},
+	"key rm":      {Kinds: []string{"key"}, Variadic: true},
+	"key verify":  {Kinds: []string{"key"}, Variadic: true},
+	"key encrypt": {Kinds: []string{"key"}},
+	"key decrypt": {Kinds: []string{"key"}},
+	"key dek":     {Kinds: []string{"key"}},
+
+	"key encrypt-file": {Kinds: []string{"key"}},
+
+	"policy edit":   {Kinds: []string{"policy"}},
+	"policy info":   {Kinds: []string{"policy"}},
+	"policy show":   {Kinds: []string{"policy"}},
+	"policy rm":     {Kinds: []string{"policy"}, Variadic: true},
+	"policy assign": {Kinds: []string{"policy", "identity"}, Variadic: true},
+
+	"identity info": {Kinds: []string{"identity"}},
+	"identity rm":   {Kinds: []string{"identity"}, Variadic: true},
+}
+
+// completeArgs returns the completion candidates for the
+// command line arguments of the binary cmd. The last
+// argument is the word being completed.
+func completeArgs(cmd string, args []string) []string {
+	var current string
+	if len(args) > 0 {
+		current, args = args[len(args)-1], args[:len(args)-1]
+	}
+
+	var (
+		words       = []string{cmd}
+		enclaveName = os.Getenv("KES_ENCLAVE")
+		insecure    bool
+	)
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-e" || arg == "--enclave":
+			if i == len(args)-1 {
+				return completeNames("enclave", "", current, insecure)
+			}
+			i++
+			enclaveName = args[i]
+		case strings.HasPrefix(arg, "--enclave="):
+			enclaveName = strings.TrimPrefix(arg, "--enclave=")
+		case arg == "-k" || arg == "--insecure":
+			insecure = true
+		case strings.HasPrefix(arg, "-"):
+		default:
+			words = append(words, arg)
+		}
+	}
+
+	// Find the longest sequence of words that is a command.
+	// The remaining words are positional arguments.
+	table := completionTable(cmd)
+	n := len(words)
+	for ; n > 1; n-- {
+		if _, ok := table[strings.Join(words[:n], " ")]; ok {
+			break
+		}
+	}
+	name := strings.Join(words[:n], " ")
+	positional := words[n:]
+
+	var candidates []string
+	if !strings.HasPrefix(current, "-") {
+		if c, ok := nameCompletion[strings.TrimPrefix(name, cmd+" ")]; ok {
+			switch {
+			case len(positional) < len(c.Kinds):
+				return completeNames(c.Kinds[len(positional)], enclaveName, current, insecure)
+			case c.Variadic:
+				return completeNames(c.Kinds[len(c.Kinds)-1], enclaveName, current, insecure)
+			default:
+				return nil
+			}
+		}
+		if len(positional) > 0 {
+			return nil
+		}
+	}
+	for _, candidate := range table[name] {
+		if strings.HasPrefix(candidate, current) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// completeNames returns the names of the server resources
+// of the given kind that start with prefix. It returns no
+// names if the server cannot be reached within a short
+// time frame.
+func completeNames(kind, enclaveName, prefix string, insecure bool) []string {
+	client, ok := completionClient(insecure)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var (
+		names   []string
+		pattern = prefix + "*"
+		enclave = client.Enclave(enclaveName)
+	)
+	switch kind {
+	case "enclave":
+		iter, err := kesclient.ListEnclaves(ctx, client, pattern)
+		if err != nil {
+			return nil
+		}
+		defer iter.Close()
+		for iter.Next() {
+			names = append(names, iter.Value().Name)
+		}
+	case "key":
+		iter, err := enclave.ListKeys(ctx, pattern)
+		if err != nil {
+			return nil
+		}
+		defer iter.Close()
+		for iter.Next() {
+			names = append(names, iter.Name())
+		}
+	case "policy":
+		iter, err := enclave.ListPolicies(ctx, pattern)
+		if err != nil {
+			return nil
+		}
+		defer iter.Close()
+		for iter.Next() {
+			names = append(names, iter.Name())
+		}
+	case "identity":
+		iter, err := enclave.ListIdentities(ctx, pattern)
+		if err != nil {
+			return nil
+		}
+		defer iter.Close()
+		for iter.Next() {
+			names = append(names, iter.Identity().String())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionClient returns a new client if the client
+// credentials are available without user interaction.
+// In contrast to newClient, it never prompts for a
+// password or exits the program.
+func completionClient(insecureSkipVerify bool) (*kes.Client, bool) {
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	if apiKey, ok := os.LookupEnv("KES_API_KEY"); ok {
+		key, err := kes.ParseAPIKey(apiKey)
+		if err != nil {
+			return nil, false
+		}
+		if cert, err = kes.GenerateCertificate(key); err != nil {
+			return nil, false
+		}
+	} else {
+		certPath, keyPath := os.Getenv("KES_CLIENT_CERT"), os.Getenv("KES_CLIENT_KEY")
+		if certPath == "" || keyPath == "" {
+			return nil, false
+		}
+		if cert, err = tls.LoadX509KeyPair(certPath, keyPath); err != nil {
+			return nil, false
+		}
+	}
+	client, err := kesclient.NewClient(serverEndpoints(), &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: insecureSkipVerify,
+	})
+	if err != nil {
+		return nil, false
+	}
+	return client, true
+}
@@ -0,0 +1,492 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const doctorCmdUsage = `Usage:
+    kes doctor [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print findings in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Diagnoses common problems of the connection to the KES server specified
+by the KES_SERVER environment variable. It checks the client configuration,
+the client and server certificates, the server reachability, the clock skew
+between client and server, the identity and policy of the client and the
+health of the server's key store backend.
+
+Each finding is either ok, a warning or a failure. Warnings and failures
+contain a hint on how to resolve them. The command exits with a non-zero
+exit code if there is at least one failure.
+
+Examples:
+    $ kes doctor
+    $ kes doctor --json | jq 'select(.level != "ok")'
+`
+
+func doctorCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, doctorCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		jsonFlag           bool
+		colorFlag          colorOption
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print findings in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes doctor --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes doctor --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	d := &doctor{json: jsonFlag}
+	if colorFlag.Colorize() {
+		const (
+			Red    tui.Color = "#d70000"
+			Yellow tui.Color = "#d7af00"
+			Green  tui.Color = "#00a700"
+		)
+		d.okStyle = tui.NewStyle().Foreground(Green)
+		d.warnStyle = tui.NewStyle().Foreground(Yellow)
+		d.failStyle = tui.NewStyle().Foreground(Red)
+		d.faintStyle = tui.NewStyle().Faint(true)
+	}
+
+	if d.checkConfig() {
+		client := newClient(insecureSkipVerify)
+		if d.checkConnection(client, insecureSkipVerify) {
+			d.checkServer(ctx, client, enclaveName)
+		}
+	}
+	d.summarize()
+}
+
+// Levels of doctor findings.
+const (
+	levelOK   = "ok"
+	levelWarn = "warn"
+	levelFail = "fail"
+)
+
+// A finding is the result of a doctor check.
+type finding struct {
+	Check   string `json:"check"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// doctor runs diagnostic checks and
+// prints their findings.
+type doctor struct {
+	json     bool
+	findings []finding
+
+	okStyle, warnStyle, failStyle, faintStyle tui.Style
+}
+
+func (d *doctor) ok(check, format string, a ...any) {
+	d.report(finding{Check: check, Level: levelOK, Message: fmt.Sprintf(format, a...)})
+}
+
+func (d *doctor) warn(check, hint, format string, a ...any) {
+	d.report(finding{Check: check, Level: levelWarn, Message: fmt.Sprintf(format, a...), Hint: hint})
+}
+
+func (d *doctor) fail(check, hint, format string, a ...any) {
+	d.report(finding{Check: check, Level: levelFail, Message: fmt.Sprintf(format, a...), Hint: hint})
+}
+
+// report prints the finding immediately
+// such that slow checks show progress.
+func (d *doctor) report(f finding) {
+	d.findings = append(d.findings, f)
+	if d.json {
+		json.NewEncoder(os.Stdout).Encode(f)
+		return
+	}
+
+	var symbol string
+	switch f.Level {
+	case levelOK:
+		symbol = d.okStyle.Render("✔")
+	case levelWarn:
+		symbol = d.warnStyle.Render("!")
+	default:
+		symbol = d.failStyle.Render("✖")
+	}
+	fmt.Printf("%s %-20s %s\n", symbol, f.Check, f.Message)
+	if f.Hint != "" {
+		fmt.Printf("  %-20s %s\n", "", d.faintStyle.Render("→ "+f.Hint))
+	}
+}
+
+// summarize prints the number of warnings and
+// failures and exits with a non-zero exit code
+// if any check failed.
+func (d *doctor) summarize() {
+	var warnings, failures int
+	for _, f := range d.findings {
+		switch f.Level {
+		case levelWarn:
+			warnings++
+		case levelFail:
+			failures++
+		}
+	}
+	if !d.json {
+		fmt.Println()
+		if warnings == 0 && failures == 0 {
+			fmt.Println(d.okStyle.Render("No problems found."))
+		} else {
+			fmt.Printf("Found %d warning(s) and %d failure(s).\n", warnings, failures)
+		}
+	}
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkConfig checks the client configuration and reports
+// whether it is complete enough to create a client.
+func (d *doctor) checkConfig() bool {
+	const Check = "configuration"
+
+	endpoints := serverEndpoints()
+	if _, ok := os.LookupEnv("KES_SERVER"); !ok {
+		d.warn(Check, "Set KES_SERVER to the address of your KES server, e.g. https://kes.example.com:7373", "KES_SERVER is not set, using %s", endpoints[0])
+	}
+	for _, addr := range endpoints {
+		if kesclient.IsDiscoveryEndpoint(addr) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			servers, err := kesclient.LookupEndpoints(ctx, addr)
+			cancel()
+			if err != nil {
+				d.fail(Check, "Check that the DNS SRV record _kes._tcp.<host> lists the KES servers", "%v", err)
+				return false
+			}
+			d.ok(Check, "Discovered %s via %s", strings.Join(servers, ", "), addr)
+			continue
+		}
+
+		endpoint, err := url.Parse(addr)
+		switch {
+		case err != nil:
+			d.fail(Check, "Set KES_SERVER to an URL like https://kes.example.com:7373", "KES_SERVER '%s' is not a valid URL: %v", addr, err)
+			return false
+		case endpoint.Scheme != "https":
+			d.fail(Check, "KES only accepts TLS connections. Use an https:// URL", "KES_SERVER '%s' does not use https", addr)
+			return false
+		case endpoint.Host == "":
+			d.fail(Check, "Set KES_SERVER to an URL like https://kes.example.com:7373", "KES_SERVER '%s' does not contain a host", addr)
+			return false
+		}
+	}
+
+	apiKey, hasAPIKey := os.LookupEnv("KES_API_KEY")
+	certPath, hasCert := os.LookupEnv("KES_CLIENT_CERT")
+	keyPath, hasKey := os.LookupEnv("KES_CLIENT_KEY")
+	switch {
+	case hasAPIKey && (hasCert || hasKey):
+		d.fail(Check, "Unset either KES_API_KEY or KES_CLIENT_CERT and KES_CLIENT_KEY", "Both, an API key and a client certificate, are set")
+		return false
+	case hasAPIKey:
+		key, err := kes.ParseAPIKey(apiKey)
+		if err != nil {
+			d.fail(Check, "Generate a new API key with 'kes identity new'", "KES_API_KEY is invalid: %v", err)
+			return false
+		}
+		d.ok(Check, "Using API key of identity %s", key.Identity())
+		return true
+	case !hasCert || strings.TrimSpace(certPath) == "":
+		d.fail(Check, "Set KES_API_KEY or KES_CLIENT_CERT and KES_CLIENT_KEY", "No client credentials")
+		return false
+	case !hasKey || strings.TrimSpace(keyPath) == "":
+		d.fail(Check, "Set KES_CLIENT_KEY to the private key of the client certificate", "KES_CLIENT_KEY is not set")
+		return false
+	}
+	d.ok(Check, "Using server %s", strings.Join(endpoints, ", "))
+
+	const CertCheck = "client certificate"
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		d.fail(CertCheck, "Set KES_CLIENT_CERT to a readable PEM-encoded certificate file", "Failed to read '%s': %v", certPath, err)
+		return false
+	}
+	var block *pem.Block
+	for rest := certPEM; ; {
+		if block, rest = pem.Decode(rest); block == nil || block.Type == "CERTIFICATE" {
+			break
+		}
+	}
+	if block == nil {
+		d.fail(CertCheck, "Set KES_CLIENT_CERT to a PEM-encoded certificate file", "'%s' does not contain a PEM-encoded certificate", certPath)
+		return false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		d.fail(CertCheck, "Set KES_CLIENT_CERT to a valid certificate", "Failed to parse '%s': %v", certPath, err)
+		return false
+	}
+	d.checkValidity(CertCheck, cert, "Issue a new client certificate, e.g. with 'kes identity new'")
+	if len(cert.ExtKeyUsage) > 0 && !hasExtKeyUsage(cert, x509.ExtKeyUsageClientAuth) {
+		d.fail(CertCheck, "Issue a client certificate with the 'Client Authentication' extended key usage", "Certificate is not valid for client authentication")
+	}
+
+	const KeyCheck = "client key"
+	stat, err := os.Stat(keyPath)
+	if err != nil {
+		d.fail(KeyCheck, "Set KES_CLIENT_KEY to a readable PEM-encoded private key file", "Failed to access '%s': %v", keyPath, err)
+		return false
+	}
+	if runtime.GOOS != "windows" && stat.Mode().Perm()&0o077 != 0 {
+		d.warn(KeyCheck, fmt.Sprintf("Restrict the access with 'chmod 600 %s'", keyPath), "Private key '%s' is accessible by other users (%v)", keyPath, stat.Mode().Perm())
+	} else {
+		d.ok(KeyCheck, "Private key '%s' is only accessible by its owner", keyPath)
+	}
+	return true
+}
+
+// checkConnection checks whether the server endpoints are
+// reachable and whether their certificates are valid and
+// trusted. It reports whether requests can be sent to at
+// least one of them.
+func (d *doctor) checkConnection(client *kes.Client, insecureSkipVerify bool) bool {
+	endpoints := serverEndpoints()
+	transport := client.HTTPClient.Transport
+	if balancer, ok := transport.(*kesclient.Balancer); ok {
+		transport = balancer.Transport()
+
+		endpoints = endpoints[:0]
+		for _, status := range balancer.Status() {
+			endpoints = append(endpoints, status.Endpoint)
+		}
+	}
+	config := &tls.Config{}
+	if transport, ok := transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig
+	}
+
+	var reachable bool
+	for _, addr := range endpoints {
+		endpoint, _ := url.Parse(addr)
+		if d.checkEndpoint(endpoint, config.Clone(), insecureSkipVerify) {
+			reachable = true
+		}
+	}
+	return reachable
+}
+
+// checkEndpoint checks whether the endpoint is reachable and
+// whether its certificate is valid and trusted.
+func (d *doctor) checkEndpoint(endpoint *url.URL, config *tls.Config, insecureSkipVerify bool) bool {
+	const Check = "connection"
+
+	host, port := endpoint.Hostname(), endpoint.Port()
+	if port == "" {
+		port = "443"
+	}
+	config.ServerName = host
+	config.InsecureSkipVerify = true // We verify the certificate chain below to report the error
+
+	start := time.Now()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", net.JoinHostPort(host, port), config)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			d.fail(Check, "Check that the server is running and that no firewall blocks the connection", "Connecting to %s timed out", endpoint.Host)
+		} else {
+			d.fail(Check, "Check that the server is running and listening on the address of KES_SERVER", "Failed to connect to %s: %v", endpoint.Host, err)
+		}
+		return false
+	}
+	state := conn.ConnectionState()
+	conn.Close()
+	d.ok(Check, "Connected to %s in %v using %s", endpoint.Host, time.Since(start).Round(time.Millisecond), tls.VersionName(state.Version))
+
+	const CertCheck = "server certificate"
+	if len(state.PeerCertificates) == 0 {
+		d.fail(CertCheck, "Configure a TLS certificate for the server", "Server did not send a certificate")
+		return true
+	}
+	leaf := state.PeerCertificates[0]
+	d.checkValidity(CertCheck, leaf, "Renew the server certificate")
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+		Roots:         config.RootCAs,
+	})
+	switch {
+	case err == nil:
+		d.ok(CertCheck, "Certificate is trusted and valid for '%s'", host)
+	case insecureSkipVerify:
+		d.warn(CertCheck, "Add the issuing CA to the system trust store to avoid --insecure", "Certificate is not trusted: %v", err)
+	default:
+		d.fail(CertCheck, "Add the issuing CA to the system trust store or use --insecure for testing", "Certificate is not trusted: %v", err)
+		return false // All further requests would fail with the same error
+	}
+	return true
+}
+
+// checkServer checks the clock skew, the client identity
+// and the server and key store status.
+func (d *doctor) checkServer(ctx context.Context, client *kes.Client, enclaveName string) {
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	const IdentityCheck = "identity"
+	info, policy, err := client.Enclave(enclaveName).DescribeSelf(ctx)
+	switch {
+	case errors.Is(err, context.Canceled):
+		os.Exit(1)
+	case errors.Is(err, kes.ErrEnclaveNotFound):
+		d.fail(IdentityCheck, "Check the enclave name or unset KES_ENCLAVE", "Enclave '%s' does not exist", enclaveName)
+	case err != nil:
+		d.fail(IdentityCheck, "Ask an admin to assign a policy to your identity with 'kes policy assign'", "Failed to describe identity: %v", err)
+	case info.IsAdmin:
+		d.ok(IdentityCheck, "Identity %s is an admin", info.Identity)
+	case info.Policy == "":
+		d.fail(IdentityCheck, "Ask an admin to assign a policy to your identity with 'kes policy assign'", "Identity %s has no policy", info.Identity)
+	case policy == nil || len(policy.Allow) == 0:
+		d.warn(IdentityCheck, fmt.Sprintf("Add allow rules to policy '%s' with 'kes policy edit %s'", info.Policy, info.Policy), "Policy '%s' of identity %s does not allow any API", info.Policy, info.Identity)
+	default:
+		d.ok(IdentityCheck, "Identity %s has policy '%s' with %d allow and %d deny rule(s)", info.Identity, info.Policy, len(policy.Allow), len(policy.Deny))
+	}
+
+	const (
+		StatusCheck   = "status"
+		ClockCheck    = "clock"
+		KeyStoreCheck = "key store"
+		VersionCheck  = "version"
+	)
+	start := time.Now()
+	status, err := kesclient.Status(ctx, client)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		if kesclient.Code(err) == kesclient.CodeNotAllowed {
+			d.warn(StatusCheck, "Allow '/v1/status' in the policy of your identity to check the server health", "Not allowed to access the server status")
+		} else {
+			d.fail(StatusCheck, "Check the server error log with 'kes log --error'", "Failed to fetch server status: %v", err)
+		}
+		return
+	}
+	end := time.Now()
+	d.ok(StatusCheck, "Server is up for %v", status.UpTime)
+
+	if !status.Date.IsZero() {
+		// The server time has a resolution of one second.
+		// Compare it to the local time halfway through the
+		// request and tolerate the resolution error.
+		skew := status.Date.Sub(start.Add(end.Sub(start) / 2).Truncate(time.Second))
+		if skew < 0 {
+			skew = -skew
+		}
+		switch {
+		case skew > 5*time.Minute:
+			d.fail(ClockCheck, "Synchronize the client and server clocks, e.g. with NTP", "Clock skew between client and server is %v", skew)
+		case skew > 30*time.Second:
+			d.warn(ClockCheck, "Synchronize the client and server clocks, e.g. with NTP", "Clock skew between client and server is %v", skew)
+		default:
+			d.ok(ClockCheck, "Client and server clocks are in sync")
+		}
+	}
+
+	switch {
+	case status.KeyStoreUnreachable:
+		d.fail(KeyStoreCheck, "Check the network connection between the KES server and its key store", "Key store is not reachable")
+	case status.KeyStoreUnavailable:
+		d.fail(KeyStoreCheck, "Check the key store health and the server error log with 'kes log --error'", "Key store is not available")
+	case status.KeyStoreLatency > time.Second:
+		d.warn(KeyStoreCheck, "Check the load and network latency of the key store", "Key store latency is %v", status.KeyStoreLatency)
+	default:
+		d.ok(KeyStoreCheck, "Key store is available (latency %v)", status.KeyStoreLatency)
+	}
+
+	if version := sys.BinaryInfo().Version; status.Version != version {
+		d.warn(VersionCheck, "Update the client or the server with 'kes update' to the same version", "Server version %s differs from client version %s", status.Version, version)
+	} else {
+		d.ok(VersionCheck, "Client and server run version %s", version)
+	}
+}
+
+// checkValidity checks whether the certificate is valid
+// now and does not expire within the next 30 days.
+func (d *doctor) checkValidity(check string, cert *x509.Certificate, hint string) {
+	const ExpiryWarning = 30 * 24 * time.Hour
+
+	now := time.Now()
+	switch {
+	case now.Before(cert.NotBefore):
+		d.fail(check, "Check the system clock or wait until the certificate becomes valid", "Certificate is not valid before %v", cert.NotBefore.Local().Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		d.fail(check, hint, "Certificate expired at %v", cert.NotAfter.Local().Format(time.RFC3339))
+	case cert.NotAfter.Sub(now) < ExpiryWarning:
+		d.warn(check, hint, "Certificate expires in %v", cert.NotAfter.Sub(now).Round(time.Hour))
+	default:
+		d.ok(check, "Certificate is valid until %v", cert.NotAfter.Local().Format(time.RFC3339))
+	}
+}
+
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage || u == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
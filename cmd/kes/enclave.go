@@ -11,10 +11,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 
 	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
 	flag "github.com/spf13/pflag"
 )
 
@@ -24,6 +27,7 @@ const enclaveCmdUsage = `Usage:
 Commands:
     create                   Create a new enclave.
     info                     Get information about an enclave. 
+    ls                       List enclaves.
     rm                       Delete an enclave.
 
 Options:
@@ -37,6 +41,7 @@ func enclaveCmd(args []string) {
 	subCmds := commands{
 		"create": createEnclaveCmd,
 		"info":   describeEnclaveCmd,
+		"ls":     lsEnclaveCmd,
 		"rm":     deleteEnclaveCmd,
 	}
 
@@ -67,24 +72,47 @@ const createEnclaveCmdUsage = `Usage:
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
+
+        --key-algorithms <ALGORITHMS>
+                             Comma-separated list of algorithms allowed for new
+                             keys within the enclave. Valid algorithms are:
+                             AES256-GCM_SHA256, XCHACHA20-POLY1305, AES256-GCM-SIV
+        --default-key-algorithm <ALGORITHM>
+                             The algorithm of new keys created without an
+                             explicit algorithm.
+        --min-key-size <BITS>
+                             The min. key size of new keys in bits.
+
     -h, --help               Print command line options.
 
 Examples:
     $ kes enclave create tenant-1 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
+    $ kes enclave create --key-algorithms AES256-GCM_SHA256 tenant-2 5f2f4ef3e0e340a07fc330f58ef0a1c4d661e564ab10795f9231f75fcfe572f1
 `
 
 func createEnclaveCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, createEnclaveCmdUsage) }
 
-	var insecureSkipVerify bool
+	var (
+		insecureSkipVerify bool
+		keyAlgorithms      []string
+		defaultAlgorithm   string
+		minKeySize         int
+	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringSliceVar(&keyAlgorithms, "key-algorithms", nil, "Algorithms allowed for new keys")
+	cmd.StringVar(&defaultAlgorithm, "default-key-algorithm", "", "The algorithm of new keys created without an explicit algorithm")
+	cmd.IntVar(&minKeySize, "min-key-size", 0, "The min. key size of new keys in bits")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes enclave create --help'", err)
 	}
+	if minKeySize < 0 {
+		cli.Fatalf("invalid min. key size '%d'. See 'kes enclave create --help'", minKeySize)
+	}
 
 	switch {
 	case cmd.NArg() == 0:
@@ -100,8 +128,17 @@ func createEnclaveCmd(args []string) {
 
 	name := cmd.Arg(0)
 	admin := cmd.Arg(1)
+	var opts kesclient.CreateEnclaveOptions
+	if len(keyAlgorithms) > 0 || defaultAlgorithm != "" || minKeySize > 0 {
+		opts.KeyPolicy = &kesclient.KeyPolicy{
+			DefaultAlgorithm: defaultAlgorithm,
+			Algorithms:       keyAlgorithms,
+			MinKeySize:       minKeySize,
+		}
+	}
+
 	client := newClient(insecureSkipVerify)
-	if err := client.CreateEnclave(ctx, name, kes.Identity(admin)); err != nil {
+	if err := kesclient.CreateEnclave(ctx, client, name, kes.Identity(admin), &opts); err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
@@ -158,7 +195,7 @@ func describeEnclaveCmd(args []string) {
 		name = cmd.Arg(0)
 	}
 	client := newClient(insecureSkipVerify)
-	info, err := client.DescribeEnclave(ctx, name)
+	info, err := kesclient.DescribeEnclave(ctx, client, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
@@ -186,6 +223,30 @@ func describeEnclaveCmd(args []string) {
 		faint.Render(fmt.Sprintf("%-11s", "Enclave")),
 		enclaveStyle.Render(info.Name),
 	)
+	fmt.Println(
+		faint.Render(fmt.Sprintf("%-11s", "Admin")),
+		info.Admin,
+	)
+	if info.KeyPolicy != nil {
+		if len(info.KeyPolicy.Algorithms) > 0 {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "Algorithms")),
+				strings.Join(info.KeyPolicy.Algorithms, ", "),
+			)
+		}
+		if info.KeyPolicy.DefaultAlgorithm != "" {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "Default")),
+				info.KeyPolicy.DefaultAlgorithm,
+			)
+		}
+		if info.KeyPolicy.MinKeySize > 0 {
+			fmt.Println(
+				faint.Render(fmt.Sprintf("%-11s", "Min. Size")),
+				fmt.Sprintf("%d bits", info.KeyPolicy.MinKeySize),
+			)
+		}
+	}
 	fmt.Println(
 		faint.Render(fmt.Sprintf("%-11s", "Created At")),
 		fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec),
@@ -196,6 +257,116 @@ func describeEnclaveCmd(args []string) {
 	)
 }
 
+const lsEnclaveCmdUsage = `Usage:
+    kes enclave ls [options] [<pattern>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print enclaves in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes enclave ls
+    $ kes enclave ls 'tenant-*'
+`
+
+func lsEnclaveCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsEnclaveCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print enclaves in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes enclave ls --help'", err)
+	}
+
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes enclave ls --help'")
+	}
+
+	pattern := "*"
+	if cmd.NArg() == 1 {
+		pattern = cmd.Arg(0)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	iter, err := kesclient.ListEnclaves(ctx, newClient(insecureSkipVerify), pattern)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list enclaves: %v", err)
+	}
+	defer iter.Close()
+
+	var enclaves []kesclient.EnclaveInfo
+	for iter.Next() {
+		enclaves = append(enclaves, iter.Value())
+	}
+	if err = iter.Close(); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		var listErr *kesclient.ListError
+		if errors.As(err, &listErr) {
+			cli.Fatalf("failed to list all enclaves: %v", err)
+		}
+		cli.Fatalf("failed to list enclaves: %v", err)
+	}
+	sort.Slice(enclaves, func(i, j int) bool {
+		return strings.Compare(enclaves[i].Name, enclaves[j].Name) < 0
+	})
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, enclave := range enclaves {
+			if err = encoder.Encode(enclave); err != nil {
+				cli.Fatal(err)
+			}
+		}
+		return
+	}
+	if len(enclaves) == 0 {
+		return
+	}
+
+	headerStyle := tui.NewStyle()
+	dateStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const ColorDate tui.Color = "#5f8700"
+		headerStyle = headerStyle.Underline(true).Bold(true)
+		dateStyle = dateStyle.Foreground(ColorDate)
+	}
+
+	fmt.Println(
+		headerStyle.Render(fmt.Sprintf("%-19s", "Date Created")),
+		headerStyle.Render("Enclave"),
+	)
+	for _, enclave := range enclaves {
+		year, month, day := enclave.CreatedAt.Local().Date()
+		hour, min, sec := enclave.CreatedAt.Local().Clock()
+		date := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec)
+		fmt.Printf("%s %s\n", dateStyle.Render(date), enclave.Name)
+	}
+}
+
 const deleteEnclaveCmdUsage = `Usage:
     kes enclave rm [options] <name>...
 
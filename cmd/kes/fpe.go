@@ -0,0 +1,159 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const fpeEncryptKeyCmdUsage = `Usage:
+    kes key fpe-encrypt [options] <name> <plaintext>
+
+Options:
+    --mode <MODE>            The FPE mode: FF1 or FF3-1. (default: FF1)
+    --alphabet <CHARS>       The characters of the plaintext.
+    --radix <N>              The number of characters, if no alphabet is
+                             specified. Digits and lower case letters are
+                             used as alphabet. (default: 10)
+    --tweak <HEX>            Hex-encoded tweak. FF3-1 tweaks must be 7 bytes.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Encrypts the plaintext such that the ciphertext has the same length and
+consists of the same alphabet, e.g. to tokenize credit card or social
+security numbers. The same key, parameters and plaintext always produce
+the same ciphertext. Decrypting requires the same parameters.
+
+Examples:
+    $ kes key fpe-encrypt my-key 4111111111111111
+    $ kes key fpe-encrypt --mode FF3-1 --tweak 0a0b0c0d0e0f10 my-key 123456789
+`
+
+func fpeEncryptKeyCmd(args []string) {
+	fpeCmd(args, "fpe-encrypt", fpeEncryptKeyCmdUsage, false)
+}
+
+const fpeDecryptKeyCmdUsage = `Usage:
+    kes key fpe-decrypt [options] <name> <ciphertext>
+
+Options:
+    --mode <MODE>            The FPE mode: FF1 or FF3-1. (default: FF1)
+    --alphabet <CHARS>       The characters of the ciphertext.
+    --radix <N>              The number of characters, if no alphabet is
+                             specified. Digits and lower case letters are
+                             used as alphabet. (default: 10)
+    --tweak <HEX>            Hex-encoded tweak. FF3-1 tweaks must be 7 bytes.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Decrypts a ciphertext produced by 'kes key fpe-encrypt' with the same
+parameters.
+
+Examples:
+    $ kes key fpe-decrypt my-key 5923904128571349
+`
+
+func fpeDecryptKeyCmd(args []string) {
+	fpeCmd(args, "fpe-decrypt", fpeDecryptKeyCmdUsage, true)
+}
+
+// fpeCmd implements the 'kes key fpe-encrypt' and
+// 'kes key fpe-decrypt' commands.
+func fpeCmd(args []string, name, usage string, decrypt bool) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	var (
+		params             kesclient.FPEParams
+		tweak              string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&params.Mode, "mode", "", "The FPE mode: FF1 or FF3-1")
+	cmd.StringVar(&params.Alphabet, "alphabet", "", "The characters of the text")
+	cmd.IntVar(&params.Radix, "radix", 0, "The number of characters, if no alphabet is specified")
+	cmd.StringVar(&tweak, "tweak", "", "Hex-encoded tweak")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key %s --help'", err, name)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatalf("no key name specified. See 'kes key %s --help'", name)
+	case cmd.NArg() == 1 && decrypt:
+		cli.Fatalf("no ciphertext specified. See 'kes key %s --help'", name)
+	case cmd.NArg() == 1:
+		cli.Fatalf("no plaintext specified. See 'kes key %s --help'", name)
+	case cmd.NArg() > 2:
+		cli.Fatalf("too many arguments. See 'kes key %s --help'", name)
+	}
+	if tweak != "" {
+		b, err := hex.DecodeString(tweak)
+		if err != nil {
+			cli.Fatalf("invalid tweak '%s': not hex-encoded", tweak)
+		}
+		params.Tweak = b
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	var (
+		keyName = cmd.Arg(0)
+		text    string
+		err     error
+	)
+	if decrypt {
+		text, err = kesclient.FPEDecrypt(ctx, newClient(insecureSkipVerify), enclaveName, keyName, cmd.Arg(1), &params)
+	} else {
+		text, err = kesclient.FPEEncrypt(ctx, newClient(insecureSkipVerify), enclaveName, keyName, cmd.Arg(1), &params)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		if decrypt {
+			cli.Fatalf("failed to decrypt ciphertext: %v", err)
+		}
+		cli.Fatalf("failed to encrypt plaintext: %v", err)
+	}
+
+	switch {
+	case isTerm(os.Stdout):
+		fmt.Println(text)
+	case decrypt:
+		json.NewEncoder(os.Stdout).Encode(struct {
+			Plaintext string `json:"plaintext"`
+		}{Plaintext: text})
+	default:
+		json.NewEncoder(os.Stdout).Encode(struct {
+			Ciphertext string `json:"ciphertext"`
+		}{Ciphertext: text})
+	}
+}
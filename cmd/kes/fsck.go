@@ -0,0 +1,184 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/sys/fs"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const fsckCmdUsage = `Usage:
+    kes fsck [options] [<path>]
+
+Options:
+    -q, --quarantine         Move corrupted entries into the quarantine
+                             directory of the server state.
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the check report in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Checks the integrity of the state of a stateful KES server. It verifies
+that every enclave, key, secret, policy and identity can be decrypted
+under the server's root key and decoded, and that keys match their
+stored key check values.
+
+Without a path, the server at $KES_SERVER checks its own state. It blocks
+all write requests during the check such that the check sees a consistent
+snapshot. Only the system admin can check the server state.
+
+With a path, kes fsck checks the state directory at the path directly. The
+root key is unsealed via $KES_UNSEAL_KEY, like when starting the server.
+The server must not be running or the path must point to a snapshot of
+the state directory.
+
+With --quarantine, corrupted entries are moved to 'quarantine/<time>/'
+within the state directory. An enclave that cannot be decrypted is
+quarantined as a whole. Quarantined entries are no longer accessible but
+can be inspected or restored manually.
+
+The command exits with a non-zero exit code if any entry is corrupted.
+
+Examples:
+    $ kes fsck
+    $ kes fsck --quarantine
+    $ kes fsck /var/lib/kes
+`
+
+func fsckCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, fsckCmdUsage) }
+
+	var (
+		quarantine         bool
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVarP(&quarantine, "quarantine", "q", false, "Move corrupted entries into the quarantine directory")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the check report in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes fsck --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes fsck --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	var (
+		report *kesclient.FsckReport
+		err    error
+	)
+	if cmd.NArg() == 1 {
+		report, err = fsckPath(ctx, cmd.Arg(0), quarantine)
+	} else {
+		report, err = kesclient.Fsck(ctx, newClient(insecureSkipVerify), quarantine)
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to check server state: %v", err)
+	}
+
+	if jsonFlag {
+		if err = json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			cli.Fatalf("failed to check server state: %v", err)
+		}
+	} else {
+		printFsckReport(report, colorFlag.Colorize())
+	}
+	if len(report.Corrupted) > 0 {
+		os.Exit(1)
+	}
+}
+
+// fsckPath checks the state directory at the given path.
+func fsckPath(ctx context.Context, path string, quarantine bool) (*kesclient.FsckReport, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	vault, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := vault.Check(ctx, quarantine)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &kesclient.FsckReport{
+		Enclaves:   r.Enclaves,
+		Keys:       r.Keys,
+		Secrets:    r.Secrets,
+		Policies:   r.Policies,
+		Identities: r.Identities,
+	}
+	for _, entry := range r.Corrupted {
+		report.Corrupted = append(report.Corrupted, kesclient.CorruptEntry{
+			Enclave:    entry.Enclave,
+			Kind:       entry.Kind,
+			Name:       entry.Name,
+			Path:       entry.Path,
+			Err:        entry.Err.Error(),
+			Quarantine: entry.Quarantine,
+		})
+	}
+	return report, nil
+}
+
+func printFsckReport(report *kesclient.FsckReport, colorize bool) {
+	var faint, okStyle, errStyle tui.Style
+	if colorize {
+		const (
+			ColorOK  tui.Color = "#00a700"
+			ColorErr tui.Color = "#ac0000"
+		)
+		faint = faint.Faint(true).Bold(true)
+		okStyle = okStyle.Foreground(ColorOK)
+		errStyle = errStyle.Foreground(ColorErr)
+	}
+
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Enclaves")), report.Enclaves)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Keys")), report.Keys)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Secrets")), report.Secrets)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Policies")), report.Policies)
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Identities")), report.Identities)
+	if len(report.Corrupted) == 0 {
+		fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Status")), okStyle.Render("No corrupted entries"))
+		return
+	}
+	fmt.Println(faint.Render(fmt.Sprintf("%-11s", "Status")), errStyle.Render(fmt.Sprintf("%d corrupted entries", len(report.Corrupted))))
+	fmt.Println()
+	for _, entry := range report.Corrupted {
+		fmt.Printf("%s %s %q in enclave %q: %s\n", errStyle.Render("✖"), entry.Kind, entry.Name, entry.Enclave, entry.Err)
+		fmt.Printf("  %s\n", entry.Path)
+		if entry.Quarantine != "" {
+			fmt.Printf("  quarantined to %s\n", entry.Quarantine)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
@@ -13,6 +14,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -27,63 +29,120 @@ import (
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/edge"
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/cli"
-	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/log"
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/internal/watchdog"
 )
 
 type gatewayConfig struct {
 	Address     string
-	ConfigFile  string
+	Network     string
+	ConfigFiles []string
 	PrivateKey  string
 	Certificate string
 	TLSAuth     string
+	UI          bool
+	MetricsAddr string
+	MetricsTLS  bool
+	LogLevel    log.Level
+	LogJSON     bool
+
+	// AuditDecisions controls for which requests audit
+	// events contain the policy decision.
+	AuditDecisions audit.DecisionLevel
+
+	MetricsIdentities int
+
+	// MetricsKeyLabels controls the optional
+	// labels of the key operation metrics.
+	MetricsKeyLabels metric.KeyLabels
+
+	// Admission, if not nil, limits the number and
+	// size of requests handled concurrently.
+	Admission *api.Admission
+
+	// Replay, if not nil, requires signed requests
+	// on the metrics listener.
+	Replay *api.ReplayGuard
+
+	// Watchdog, if not nil, writes diagnostics dumps
+	// once a watchdog threshold has been crossed.
+	Watchdog *watchdog.Config
 }
 
+// startGateway starts one edge server per config file. All
+// edge servers run in the same process and share the metrics,
+// the metrics listener and the admission control.
 func startGateway(cliConfig gatewayConfig) {
+	startTime := time.Now()
+
 	var mlock bool
 	if runtime.GOOS == "linux" {
 		mlock = mlockall() == nil
 	}
 
-	if isTerm(os.Stderr) {
+	log.Default().SetLevel(cliConfig.LogLevel)
+	log.Default().SetJSON(cliConfig.LogJSON)
+	if isTerm(os.Stderr) && !cliConfig.LogJSON {
 		style := tui.NewStyle().Foreground(tui.Color("#ac0000")) // red
 		log.Default().SetPrefix(style.Render("Error: "))
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancelCtx := serverContext()
 	defer cancelCtx()
 
-	config, err := loadGatewayConfig(cliConfig)
-	if err != nil {
+	metrics := metric.New()
+	metrics.SetIdentityLabels(cliConfig.MetricsIdentities)
+	if err := metrics.SetKeyLabels(cliConfig.MetricsKeyLabels); err != nil {
 		cli.Fatal(err)
 	}
-	tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
-	if err != nil {
-		cli.Fatal(err)
-	}
-	gwConfig, err := newGatewayConfig(ctx, config, tlsConfig)
-	if err != nil {
-		cli.Fatal(err)
+	if cliConfig.Watchdog != nil {
+		go watchdog.Run(ctx, cliConfig.Watchdog)
 	}
 
-	buffer, err := gatewayMessage(config, tlsConfig, mlock)
-	if err != nil {
-		cli.Fatal(err)
+	gateways := make([]*gateway, 0, len(cliConfig.ConfigFiles))
+	for _, filename := range cliConfig.ConfigFiles {
+		gw, err := newGateway(ctx, filename, cliConfig, metrics, startTime)
+		if err != nil {
+			if len(cliConfig.ConfigFiles) > 1 {
+				cli.Fatalf("%s: %v", filename, err)
+			}
+			cli.Fatal(err)
+		}
+		defer gw.Close()
+		gateways = append(gateways, gw)
+
+		buffer, err := gatewayMessage(gw.config, cliConfig, gw.tlsConfig, mlock)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		cli.Println(buffer.String())
 	}
-	cli.Println(buffer.String())
 
-	server := https.NewServer(&https.Config{
-		Addr:      config.Addr,
-		Handler:   api.NewEdgeRouter(gwConfig),
-		TLSConfig: tlsConfig,
-	})
+	servers := make([]*https.Server, 0, len(gateways))
+	for _, gw := range gateways {
+		servers = append(servers, gw.server)
+	}
+	var metricsServer *https.Server
+	if cliConfig.MetricsAddr != "" {
+		metricsServer = startMetricsServer(ctx, cliConfig.MetricsAddr, cliConfig.Network, metricsTLSConfig(cliConfig, gateways[0].TLSConfig()), gatewayMetricsConfig(metrics, gateways, cliConfig.Replay))
+	}
+	updateMetricsTLS := func() {
+		if metricsServer == nil || !cliConfig.MetricsTLS {
+			return
+		}
+		if err := metricsServer.UpdateTLS(metricsTLSConfig(cliConfig, gateways[0].TLSConfig())); err != nil {
+			log.Printf("failed to update metrics TLS configuration: %v", err)
+		}
+	}
+	handoverOnSignal(ctx, cancelCtx, append(servers, metricsServer)...)
 	go func(ctx context.Context) {
 		if runtime.GOOS == "windows" {
 			return
@@ -99,37 +158,26 @@ func startGateway(cliConfig gatewayConfig) {
 				return
 			case <-sighup:
 				cli.Println("SIGHUP signal received. Reloading configuration...")
-				config, err := loadGatewayConfig(cliConfig)
-				if err != nil {
-					log.Printf("failed to read server config: %v", err)
-					continue
-				}
-				tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
-				if err != nil {
-					log.Printf("failed to initialize TLS config: %v", err)
-					continue
-				}
-				gwConfig, err := newGatewayConfig(ctx, config, tlsConfig)
-				if err != nil {
-					log.Printf("failed to initialize server API: %v", err)
-					continue
-				}
-				err = server.Update(&https.Config{
-					Addr:      config.Addr,
-					Handler:   api.NewEdgeRouter(gwConfig),
-					TLSConfig: tlsConfig,
-				})
-				if err != nil {
-					log.Printf("failed to update server configuration: %v", err)
-					continue
-				}
-				buffer, err := gatewayMessage(config, tlsConfig, mlock)
-				if err != nil {
-					log.Print(err)
-					cli.Println("Reloading configuration after SIGHUP signal completed.")
-				} else {
-					cli.Println(buffer.String())
+				setServiceState(serviceReloading)
+				for _, gw := range gateways {
+					if err := gw.Reload(ctx); err != nil {
+						if len(gateways) > 1 {
+							log.Printf("%s: %v", gw.filename, err)
+						} else {
+							log.Print(err)
+						}
+						continue
+					}
+					buffer, err := gatewayMessage(gw.config, cliConfig, gw.TLSConfig(), mlock)
+					if err != nil {
+						log.Print(err)
+						cli.Println("Reloading configuration after SIGHUP signal completed.")
+					} else {
+						cli.Println(buffer.String())
+					}
 				}
+				updateMetricsTLS()
+				setServiceState(serviceReady)
 			}
 		}
 	}(ctx)
@@ -141,22 +189,346 @@ func startGateway(cliConfig gatewayConfig) {
 		for {
 			select {
 			case <-ctx.Done():
+				return
 			case <-ticker.C:
-				tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
-				if err != nil {
-					log.Printf("failed to reload TLS configuration: %v", err)
-					continue
-				}
-				if err = server.UpdateTLS(tlsConfig); err != nil {
-					log.Printf("failed to update TLS configuration: %v", err)
+				for _, gw := range gateways {
+					if err := gw.UpdateTLS(); err != nil {
+						log.Print(err)
+					}
 				}
+				updateMetricsTLS()
 			}
 		}
 	}(ctx)
 
-	if err := server.Start(ctx); err != nil && err != http.ErrServerClosed {
-		cli.Fatal(err)
+	notifyHandover(ctx, servers...)
+	defer notifyService(ctx, append(servers, metricsServer)...)()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *https.Server) {
+			defer wg.Done()
+			if err := server.Start(ctx); err != nil && err != http.ErrServerClosed {
+				cli.Fatal(err)
+			}
+		}(server)
+	}
+	wg.Wait()
+}
+
+// gateway is an edge server that serves the KES API as
+// specified by one config file. It reloads its config
+// file on SIGHUP.
+type gateway struct {
+	filename  string
+	cliConfig gatewayConfig
+	metrics   *metric.Metrics
+	startTime time.Time
+
+	server    *https.Server
+	persister *key.Persister
+	events    gatewayEvents // Shared across config reloads to keep subscribers connected
+
+	reloadLock sync.Mutex // Serializes config and TLS reloads
+	auditFile  *auditLogFile
+	statsd     *metric.StatsD
+	heartbeat  *api.Heartbeat
+
+	lock      sync.Mutex
+	config    *edge.ServerConfig
+	tlsConfig *tls.Config
+	rConfig   *api.EdgeRouterConfig
+}
+
+// newGateway returns a new gateway for the given config
+// file. The gateway records its metrics in the given
+// metrics. It does not start its server.
+func newGateway(ctx context.Context, filename string, cliConfig gatewayConfig, metrics *metric.Metrics, startTime time.Time) (*gateway, error) {
+	config, err := loadGatewayConfig(filename, cliConfig)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig(config, cliConfig.TLSAuth)
+	if err != nil {
+		return nil, err
+	}
+	rConfig, err := newGatewayConfig(ctx, config, tlsConfig, metrics)
+	if err != nil {
+		return nil, err
+	}
+	rConfig.ErrorLog.SetLevel(cliConfig.LogLevel)
+	rConfig.ErrorLog.SetJSON(cliConfig.LogJSON)
+
+	g := &gateway{
+		filename:  filename,
+		cliConfig: cliConfig,
+		metrics:   metrics,
+		startTime: startTime,
+		events:    gatewayEvents{},
+		config:    config,
+		tlsConfig: tlsConfig,
+		rConfig:   rConfig,
+	}
+	if g.persister, err = newCachePersister(config, tlsConfig); err != nil {
+		return nil, err
+	}
+	if g.auditFile, err = openAuditFile(config, rConfig); err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %v", err)
+	}
+	if g.statsd, err = startStatsD(config, rConfig); err != nil {
+		g.Close()
+		return nil, fmt.Errorf("failed to start StatsD exporter: %v", err)
+	}
+	if g.heartbeat, err = startHeartbeat(config, rConfig, startTime); err != nil {
+		g.Close()
+		return nil, err
+	}
+	if g.persister != nil {
+		restoreCache(g.persister, config, rConfig)
+		interval := config.Cache.Persist.Interval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		go g.persister.Run(ctx, interval)
+	}
+	setGatewayOptions(rConfig, cliConfig, g.events)
+
+	g.server = https.NewServer(&https.Config{
+		Addr:          config.Addr,
+		Network:       cliConfig.Network,
+		Handler:       api.NewEdgeRouter(rConfig),
+		TLSConfig:     tlsConfig,
+		OnAuthFailure: reportAuthFailure(rConfig.AuditLog, rConfig.Metrics),
+	})
+	return g, nil
+}
+
+// TLSConfig returns the current TLS configuration of
+// the gateway.
+func (g *gateway) TLSConfig() *tls.Config {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.tlsConfig
+}
+
+// Ready returns an error if the gateway cannot serve
+// requests because its keystore is not reachable.
+func (g *gateway) Ready(ctx context.Context) error {
+	g.lock.Lock()
+	rConfig := g.rConfig
+	g.lock.Unlock()
+
+	_, err := rConfig.Keys.Status(ctx)
+	return err
+}
+
+// Reload reads the gateway's config file again and updates
+// its server. If the new config cannot be applied, the
+// gateway keeps serving requests with its current config
+// and publishes a config.reload_failed event.
+func (g *gateway) Reload(ctx context.Context) error {
+	g.reloadLock.Lock()
+	defer g.reloadLock.Unlock()
+
+	if err := g.reload(ctx); err != nil {
+		g.lock.Lock()
+		config := g.config
+		g.lock.Unlock()
+
+		publishConfigEvent(config, api.EventConfigReloadFailed, g.events)
+		return err
+	}
+	return nil
+}
+
+// reload applies the gateway's config file. The caller
+// must hold the reloadLock.
+func (g *gateway) reload(ctx context.Context) error {
+	config, err := loadGatewayConfig(g.filename, g.cliConfig)
+	if err != nil {
+		return fmt.Errorf("failed to read server config: %v", err)
+	}
+	tlsConfig, err := newTLSConfig(config, g.cliConfig.TLSAuth)
+	if err != nil {
+		return fmt.Errorf("failed to initialize TLS config: %v", err)
+	}
+	rConfig, err := newGatewayConfig(ctx, config, tlsConfig, g.metrics)
+	if err != nil {
+		return fmt.Errorf("failed to initialize server API: %v", err)
+	}
+	rConfig.ErrorLog.SetLevel(g.cliConfig.LogLevel)
+	rConfig.ErrorLog.SetJSON(g.cliConfig.LogJSON)
+	if g.persister != nil { // Changes of the persistence config require a restart
+		restoreCache(g.persister, config, rConfig)
+	}
+	auditFile, err := openAuditFile(config, rConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %v", err)
+	}
+	statsd, err := startStatsD(config, rConfig)
+	if err != nil {
+		if auditFile != nil {
+			auditFile.Close()
+		}
+		return fmt.Errorf("failed to start StatsD exporter: %v", err)
+	}
+	heartbeat, err := startHeartbeat(config, rConfig, g.startTime)
+	if err != nil {
+		if auditFile != nil {
+			auditFile.Close()
+		}
+		if statsd != nil {
+			statsd.Close()
+		}
+		return err
+	}
+	setGatewayOptions(rConfig, g.cliConfig, g.events)
+	err = g.server.Update(&https.Config{
+		Addr:          config.Addr,
+		Network:       g.cliConfig.Network,
+		Handler:       api.NewEdgeRouter(rConfig),
+		TLSConfig:     tlsConfig,
+		OnAuthFailure: reportAuthFailure(rConfig.AuditLog, rConfig.Metrics),
+	})
+	if err != nil {
+		if auditFile != nil {
+			auditFile.Close()
+		}
+		if statsd != nil {
+			statsd.Close()
+		}
+		if heartbeat != nil {
+			heartbeat.Close()
+		}
+		return fmt.Errorf("failed to update server configuration: %v", err)
+	}
+
+	if g.auditFile != nil {
+		g.auditFile.Close()
+	}
+	if g.statsd != nil {
+		g.statsd.Close()
+	}
+	if g.heartbeat != nil {
+		g.heartbeat.Close()
+	}
+	g.auditFile, g.statsd, g.heartbeat = auditFile, statsd, heartbeat
+
+	g.lock.Lock()
+	oldConfig := g.config
+	g.config, g.tlsConfig, g.rConfig = config, tlsConfig, rConfig
+	g.lock.Unlock()
+
+	publishConfigChanges(oldConfig, config, rConfig, g.events)
+	return nil
+}
+
+// publishConfigChanges logs an audit event that records the
+// policy fingerprints of the old and new config and how the
+// policies and identities defined by the config have changed.
+// It also publishes one event per change to the event stream
+// of the affected namespace, followed by a config.reloaded
+// event with the new fingerprint.
+func publishConfigChanges(old, new *edge.ServerConfig, rConfig *api.EdgeRouterConfig, events gatewayEvents) {
+	fingerprint := new.PolicyFingerprint()
+	diff := edge.DiffPolicies(old, new)
+
+	changes := make([]audit.ConfigChange, 0, len(diff))
+	for _, c := range diff {
+		changes = append(changes, audit.ConfigChange{
+			Namespace:          c.Namespace,
+			AdminChanged:       c.AdminChanged,
+			AddedPolicies:      c.AddedPolicies,
+			RemovedPolicies:    c.RemovedPolicies,
+			ModifiedPolicies:   c.ModifiedPolicies,
+			AssignedIdentities: c.AssignedIdentities,
+			RemovedIdentities:  c.RemovedIdentities,
+		})
+
+		namespace := c.Namespace
+		if namespace == "" {
+			namespace = sys.DefaultEnclaveName
+		}
+		stream := events.Stream(namespace)
+		for _, name := range c.AddedPolicies {
+			stream.PublishConfig(namespace, api.EventPolicyWritten, name)
+		}
+		for _, name := range c.ModifiedPolicies {
+			stream.PublishConfig(namespace, api.EventPolicyWritten, name)
+		}
+		for _, name := range c.RemovedPolicies {
+			stream.PublishConfig(namespace, api.EventPolicyDeleted, name)
+		}
+		for _, identity := range c.AssignedIdentities {
+			stream.PublishConfig(namespace, api.EventIdentityAssigned, identity.String())
+		}
+		for _, identity := range c.RemovedIdentities {
+			stream.PublishConfig(namespace, api.EventIdentityDeleted, identity.String())
+		}
+	}
+	audit.LogConfigReload(rConfig.AuditLog, fingerprint, old.PolicyFingerprint(), changes)
+
+	publishConfigEvent(new, api.EventConfigReloaded, events)
+}
+
+// publishConfigEvent publishes an event of the given type,
+// named by the policy fingerprint of the config, to the
+// event streams of all namespaces of the config.
+func publishConfigEvent(config *edge.ServerConfig, typ api.EventType, events gatewayEvents) {
+	fingerprint := config.PolicyFingerprint()
+	events.Stream(sys.DefaultEnclaveName).PublishConfig(sys.DefaultEnclaveName, typ, fingerprint)
+	for name := range config.Namespaces {
+		events.Stream(name).PublishConfig(name, typ, fingerprint)
+	}
+}
+
+// UpdateTLS reloads the gateway's TLS private key and
+// certificate.
+func (g *gateway) UpdateTLS() error {
+	g.reloadLock.Lock()
+	defer g.reloadLock.Unlock()
+
+	g.lock.Lock()
+	config := g.config
+	g.lock.Unlock()
+
+	tlsConfig, err := newTLSConfig(config, g.cliConfig.TLSAuth)
+	if err != nil {
+		return fmt.Errorf("failed to reload TLS configuration: %v", err)
+	}
+	if err = g.server.UpdateTLS(tlsConfig); err != nil {
+		return fmt.Errorf("failed to update TLS configuration: %v", err)
 	}
+
+	g.lock.Lock()
+	g.tlsConfig = tlsConfig
+	g.lock.Unlock()
+	return nil
+}
+
+// Close persists the gateway's key cache, if enabled,
+// and stops its audit log file, StatsD exporter and
+// heartbeat.
+func (g *gateway) Close() error {
+	g.reloadLock.Lock()
+	defer g.reloadLock.Unlock()
+
+	if g.persister != nil {
+		if err := g.persister.Persist(); err != nil {
+			log.Printf("failed to persist key cache: %v", err)
+		}
+	}
+	if g.auditFile != nil {
+		g.auditFile.Close()
+	}
+	if g.statsd != nil {
+		g.statsd.Close()
+	}
+	if g.heartbeat != nil {
+		g.heartbeat.Close()
+	}
+	return nil
 }
 
 func description(config *edge.ServerConfig) (kind string, endpoint []string, err error) {
@@ -193,12 +565,69 @@ func description(config *edge.ServerConfig) (kind string, endpoint []string, err
 	case *edge.AzureKeyVaultKeyStore:
 		kind = "Azure KeyVault"
 		endpoint = []string{kms.Endpoint}
+	case *edge.PluginKeyStore:
+		kind = "Plugin"
+		if kms.Path != "" {
+			endpoint = []string{kms.Path}
+		} else {
+			endpoint = []string{kms.Socket}
+		}
+	case *edge.BundleKeyStore:
+		kind = "Offline Bundle"
+		if abs, err := filepath.Abs(kms.Path); err == nil {
+			endpoint = []string{abs}
+		} else {
+			endpoint = []string{kms.Path}
+		}
+	case *edge.ProxyKeyStore:
+		kind = "Proxy"
+		endpoint = []string{kms.KMS}
 	default:
 		return "", nil, fmt.Errorf("unknown KMS backend %T", kms)
 	}
 	return kind, endpoint, nil
 }
 
+// wrappingDescription returns the kind of external KMS
+// and the key that wraps all keys, if any.
+func wrappingDescription(config *edge.ServerConfig) (kind, key string) {
+	switch kms := config.KeyWrapping.(type) {
+	case *edge.AWSKMSKeyWrapping:
+		return "AWS KMS", kms.Key
+	case *edge.GCPKMSKeyWrapping:
+		return "GCP Cloud KMS", kms.Key
+	case *edge.AzureKeyVaultKeyWrapping:
+		return "Azure KeyVault", kms.Key
+	case *edge.KESKeyWrapping:
+		return "KES", kms.Key
+	default:
+		return "", ""
+	}
+}
+
+// addBundlePolicies adds the policies of the offline key
+// bundle to the policies of the given ServerConfig.
+func addBundlePolicies(config *edge.ServerConfig, keystore *edge.BundleKeyStore) error {
+	bundle, err := keystore.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open key bundle: %v", err)
+	}
+	if len(bundle.Policies) > 0 && config.Policies == nil {
+		config.Policies = make(map[string]edge.Policy, len(bundle.Policies))
+	}
+	for name, policy := range bundle.Policies {
+		if _, ok := config.Policies[name]; ok {
+			return fmt.Errorf("policy %q is defined in the config file and the key bundle", name)
+		}
+		config.Policies[name] = edge.Policy{
+			Allow:      policy.Allow,
+			Deny:       policy.Deny,
+			Identities: policy.Identities,
+		}
+	}
+	return nil
+}
+
 // policySetFromConfig returns an in-memory PolicySet
 // from the given ServerConfig.
 func policySetFromConfig(config *edge.ServerConfig) (auth.PolicySet, error) {
@@ -412,8 +841,8 @@ func (i *identityIterator) Identity() kes.Identity { return i.current }
 
 func (i *identityIterator) Close() error { return nil }
 
-func loadGatewayConfig(gConfig gatewayConfig) (*edge.ServerConfig, error) {
-	file, err := os.Open(gConfig.ConfigFile)
+func loadGatewayConfig(filename string, gConfig gatewayConfig) (*edge.ServerConfig, error) {
+	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -500,7 +929,7 @@ func newTLSConfig(config *edge.ServerConfig, auth string) (*tls.Config, error) {
 	}, nil
 }
 
-func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig *tls.Config) (*api.EdgeRouterConfig, error) {
+func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig *tls.Config, metrics *metric.Metrics) (*api.EdgeRouterConfig, error) {
 	rConfig := &api.EdgeRouterConfig{}
 
 	if config.Log.Error {
@@ -530,49 +959,255 @@ func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig
 		}
 	}
 
-	rConfig.APIConfig = make(map[string]api.Config, len(config.API.Paths))
-	for k, v := range config.API.Paths {
-		k = strings.TrimSpace(k) // Ensure that the API path starts with a '/'
-		if !strings.HasPrefix(k, "/") {
-			k = "/" + k
+	rConfig.APIConfig = map[string]api.Config{}
+	if config.API != nil {
+		for k, v := range config.API.Paths {
+			k = strings.TrimSpace(k) // Ensure that the API path starts with a '/'
+			if !strings.HasPrefix(k, "/") {
+				k = "/" + k
+			}
+
+			if _, ok := rConfig.APIConfig[k]; ok {
+				return nil, fmt.Errorf("ambiguous API configuration for '%s'", k)
+			}
+			rConfig.APIConfig[k] = api.Config{
+				Timeout:          v.Timeout,
+				MaxBody:          v.MaxBody,
+				MaxInFlight:      v.MaxInFlight,
+				InsecureSkipAuth: v.InsecureSkipAuth,
+				Deprecated:       v.Deprecated,
+				Sunset:           v.Sunset,
+				Successor:        v.Successor,
+			}
 		}
+	}
 
-		if _, ok := rConfig.APIConfig[k]; ok {
-			return nil, fmt.Errorf("ambiguous API configuration for '%s'", k)
+	var err error
+	if bundle, ok := config.KeyStore.(*edge.BundleKeyStore); ok {
+		if err = addBundlePolicies(config, bundle); err != nil {
+			return nil, err
 		}
-		rConfig.APIConfig[k] = api.Config{
-			Timeout:          v.Timeout,
-			InsecureSkipAuth: v.InsecureSkipAuth,
+	}
+	policies, err := policySetFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := identitySetFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	rConfig.Policies = auth.NewPolicyCache(policies, config.Cache.ExpiryPolicy)
+	rConfig.Identities = auth.NewIdentityCache(identities, config.Cache.ExpiryPolicy)
+
+	if config.Authorizer != nil {
+		if rConfig.Authorizer, err = newAuthorizer(config.Authorizer); err != nil {
+			return nil, err
 		}
 	}
 
-	var err error
-	rConfig.Policies, err = policySetFromConfig(config)
+	if rConfig.KeyPolicy, err = newKeyPolicy(config.KeyPolicy); err != nil {
+		return nil, err
+	}
+	if config.Names != nil {
+		rConfig.Names = api.NameRules{
+			MaxLength: config.Names.MaxLength,
+			Extended:  config.Names.Extended,
+		}
+		if err = rConfig.Names.Validate(); err != nil {
+			return nil, err
+		}
+		rConfig.ReservedPrefixes = api.ReservedPrefixes(config.Names.Reserved)
+		if err = rConfig.ReservedPrefixes.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.ExternalKMS) > 0 {
+		rConfig.ExternalKMS = make(map[string]key.ExternalKMS, len(config.ExternalKMS))
+		for name, kms := range config.ExternalKMS {
+			rConfig.ExternalKMS[name] = &externalKMS{
+				config: kms,
+				keys:   map[string]key.Wrapper{},
+			}
+		}
+	}
+	if config.KeyWrapping != nil {
+		if rConfig.KeyWrapper, err = config.KeyWrapping.Connect(ctx); err != nil {
+			return nil, fmt.Errorf("failed to connect to key wrapping KMS: %v", err)
+		}
+	}
+	if rConfig.Keys, err = newKeyCache(ctx, config, config.KeyStore, config.Keys, rConfig.KeyWrapper, rConfig.KeyPolicy); err != nil {
+		return nil, err
+	}
+
+	if len(config.Cache.Warmup) > 0 {
+		timeout := config.Cache.WarmupTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		warmupCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, err = rConfig.Keys.Warmup(warmupCtx, config.Cache.Warmup)
+		cancel()
+		if err != nil { // Keys that could not be fetched are loaded on first use
+			log.Printf("failed to warm up key cache: %v", err)
+		}
+	}
+
+	rConfig.Idempotency = api.NewIdempotencyCache(0)
+	rConfig.Metrics = metrics
+	rConfig.AuditLog.Add(rConfig.Metrics.AuditEventCounter())
+	rConfig.ErrorLog.Add(rConfig.Metrics.ErrorEventCounter())
+
+	if len(config.Namespaces) > 0 {
+		rConfig.Namespaces = make(map[string]*api.EdgeRouterConfig, len(config.Namespaces))
+		for name, namespace := range config.Namespaces {
+			nsConfig, err := newNamespaceConfig(ctx, config, rConfig, namespace)
+			if err != nil {
+				return nil, fmt.Errorf("namespace '%s': %v", name, err)
+			}
+			rConfig.Namespaces[name] = nsConfig
+		}
+	}
+	return rConfig, nil
+}
+
+// gatewayEvents contains the event streams of the
+// default namespace and all other key namespaces.
+type gatewayEvents map[string]*api.EventStream
+
+// Stream returns the event stream of the given
+// namespace.
+func (e gatewayEvents) Stream(namespace string) *api.EventStream {
+	stream, ok := e[namespace]
+	if !ok {
+		stream = api.NewEventStream()
+		e[namespace] = stream
+	}
+	return stream
+}
+
+// setGatewayOptions applies the command line options and
+// event streams to the router config and all its namespaces.
+// Each namespace has its own event stream such that clients
+// only receive events of their namespace.
+func setGatewayOptions(rConfig *api.EdgeRouterConfig, cliConfig gatewayConfig, events gatewayEvents) {
+	rConfig.Events = events.Stream(sys.DefaultEnclaveName)
+	rConfig.UI = cliConfig.UI
+	rConfig.Admission = cliConfig.Admission
+	rConfig.AuditDecisions = cliConfig.AuditDecisions
+	for name, nsConfig := range rConfig.Namespaces {
+		nsConfig.Events = events.Stream(name)
+		nsConfig.UI = cliConfig.UI
+		nsConfig.Admission = cliConfig.Admission
+		nsConfig.AuditDecisions = cliConfig.AuditDecisions
+	}
+}
+
+// newNamespaceConfig returns the router configuration of
+// the given key namespace. The namespace uses its own
+// keystore, policies and identities and shares everything
+// else, like the logs and metrics, with the router config.
+// Its key policy, if any, replaces the one of the router
+// config.
+func newNamespaceConfig(ctx context.Context, config *edge.ServerConfig, rConfig *api.EdgeRouterConfig, namespace *edge.Namespace) (*api.EdgeRouterConfig, error) {
+	nsServerConfig := *config
+	nsServerConfig.Policies = namespace.Policies
+
+	nsConfig := *rConfig
+	policies, err := policySetFromConfig(&nsServerConfig)
 	if err != nil {
 		return nil, err
 	}
-	rConfig.Identities, err = identitySetFromConfig(config)
+	identities, err := identitySetFromConfig(&nsServerConfig)
+	if err != nil {
+		return nil, err
+	}
+	nsConfig.Policies = auth.NewPolicyCache(policies, config.Cache.ExpiryPolicy)
+	nsConfig.Identities = auth.NewIdentityCache(identities, config.Cache.ExpiryPolicy)
+	if namespace.KeyPolicy != nil {
+		if nsConfig.KeyPolicy, err = newKeyPolicy(namespace.KeyPolicy); err != nil {
+			return nil, err
+		}
+	}
+	if nsConfig.Keys, err = newKeyCache(ctx, config, namespace.KeyStore, namespace.Keys, rConfig.KeyWrapper, nsConfig.KeyPolicy); err != nil {
+		return nil, err
+	}
+	nsConfig.Idempotency = api.NewIdempotencyCache(0)
+	nsConfig.Namespaces = nil
+	return &nsConfig, nil
+}
+
+// externalKMS implements key.ExternalKMS. It connects
+// to each key at the external KMS once and reuses the
+// connection for all subsequent operations.
+type externalKMS struct {
+	config edge.ExternalKMS
+
+	lock sync.Mutex
+	keys map[string]key.Wrapper
+}
+
+func (e *externalKMS) Key(ctx context.Context, keyID string) (key.Wrapper, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if w, ok := e.keys[keyID]; ok {
+		return w, nil
+	}
+	w, err := e.config.ConnectKey(ctx, keyID)
 	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external key '%s': %v", keyID, err)
+	}
+	e.keys[keyID] = w
+	return w, nil
+}
+
+// newKeyPolicy returns the key.AlgorithmPolicy of the given
+// key policy config. It returns nil if config is nil.
+func newKeyPolicy(config *edge.KeyPolicyConfig) (*key.AlgorithmPolicy, error) {
+	if config == nil {
+		return nil, nil
+	}
+	policy, err := key.ParseAlgorithmPolicy(config.DefaultAlgorithm, config.Algorithms, config.MinKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key policy: %v", err)
+	}
+	if err = policy.Validate(); err != nil {
 		return nil, err
 	}
+	return policy, nil
+}
 
-	conn, err := config.KeyStore.Connect(ctx)
+// newKeyCache connects to the keystore and returns a key
+// cache for it. It wraps all keys with the wrapper, if not
+// nil, and creates all keys that don't exist yet with an
+// algorithm allowed by the key policy.
+func newKeyCache(ctx context.Context, config *edge.ServerConfig, keystore edge.KeyStore, keys []edge.Key, wrapper key.Wrapper, keyPolicy *key.AlgorithmPolicy) (*key.Cache, error) {
+	conn, err := keystore.Connect(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if wrapper != nil {
+		conn = key.NewWrappedStore(conn, wrapper, &key.WrappedStoreConfig{
+			AllowUnwrapped: config.AllowUnwrappedKeys,
+		})
+	}
+	if config.Breaker != nil {
+		conn = key.NewBreaker(conn, &key.BreakerConfig{
+			Threshold: config.Breaker.Threshold,
+			Cooldown:  config.Breaker.Cooldown,
+		})
+	}
 	store := key.Store{Conn: conn}
-	rConfig.Keys = key.NewCache(store, &key.CacheConfig{
+	cache := key.NewCache(store, &key.CacheConfig{
 		Expiry:        config.Cache.Expiry,
 		ExpiryUnused:  config.Cache.ExpiryUnused,
 		ExpiryOffline: config.Cache.ExpiryOffline,
 	})
 
-	for _, k := range config.Keys {
-		var algorithm kes.KeyAlgorithm
-		if fips.Enabled || cpu.HasAESGCM() {
-			algorithm = kes.AES256_GCM_SHA256
-		} else {
-			algorithm = kes.XCHACHA20_POLY1305
+	for _, k := range keys {
+		algorithm, err := key.SelectAlgorithm(kes.KeyAlgorithmUndefined, keyPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create key '%s': %v", k.Name, err)
 		}
 
 		key, err := key.Random(algorithm, config.Admin)
@@ -583,16 +1218,256 @@ func newGatewayConfig(ctx context.Context, config *edge.ServerConfig, tlsConfig
 			return nil, fmt.Errorf("failed to create key '%s': %v", k.Name, err)
 		}
 	}
+	return cache, nil
+}
 
-	rConfig.Metrics = metric.New()
-	rConfig.AuditLog.Add(rConfig.Metrics.AuditEventCounter())
-	rConfig.ErrorLog.Add(rConfig.Metrics.ErrorEventCounter())
-	return rConfig, nil
+// newCachePersister returns a new key.Persister that persists
+// the key cache encrypted on disk. It returns nil if cache
+// persistence is not configured.
+//
+// If the persistence config does not specify a key, the key
+// is derived from the server's TLS private key.
+func newCachePersister(config *edge.ServerConfig, tlsConfig *tls.Config) (*key.Persister, error) {
+	if config.Cache.Persist == nil {
+		return nil, nil
+	}
+
+	var sealKey []byte
+	if config.Cache.Persist.Key != "" {
+		b, err := hex.DecodeString(config.Cache.Persist.Key)
+		if err != nil || len(b) != 32 {
+			return nil, errors.New("invalid cache persistence key: key must be a hex-encoded 256 bit key")
+		}
+		sealKey = b
+	} else {
+		if len(tlsConfig.Certificates) == 0 {
+			return nil, errors.New("failed to derive cache persistence key: no TLS private key")
+		}
+		privateKey, err := x509.MarshalPKCS8PrivateKey(tlsConfig.Certificates[0].PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive cache persistence key: %v", err)
+		}
+		mac := hmac.New(sha256.New, privateKey)
+		mac.Write([]byte("kes: key cache persistence"))
+		sealKey = mac.Sum(nil)
+	}
+	return key.NewPersister(config.Cache.Persist.Path, sealKey)
+}
+
+// restoreCache restores the persisted keys into the
+// key cache of the router config and persists this
+// cache from now on.
+func restoreCache(persister *key.Persister, config *edge.ServerConfig, rConfig *api.EdgeRouterConfig) {
+	keys, err := persister.Load(config.Cache.ExpiryOffline)
+	if err != nil {
+		log.Printf("failed to restore key cache: %v", err)
+	} else {
+		rConfig.Keys.Restore(keys)
+	}
+	persister.SetCache(rConfig.Keys)
+}
+
+// newAuthorizer returns a new auth.Authorizer that consults
+// the external authorization service of the given config.
+func newAuthorizer(config *edge.AuthorizerConfig) (auth.Authorizer, error) {
+	tlsConfig, err := clientTLSConfig(config.Certificate, config.PrivateKey, config.CAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize authorizer TLS config: %v", err)
+	}
+	return auth.NewHTTPAuthorizer(&auth.HTTPAuthorizerConfig{
+		Endpoint: config.Endpoint,
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     tlsConfig,
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 16,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		CacheExpiry: config.CacheExpiry,
+	}), nil
+}
+
+// clientTLSConfig returns a new TLS client configuration with
+// the optional client certificate and root CA certificates.
+func clientTLSConfig(certFile, keyFile, caPath string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: fips.TLSCiphers(),
+	}
+	if certFile != "" || keyFile != "" {
+		certificate, err := https.CertificateFromFile(certFile, keyFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+	if caPath != "" {
+		rootCAs, err := https.CertPoolFromFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA certificates: %v", err)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return tlsConfig, nil
+}
+
+// gatewayMetricsConfig returns the metrics listener
+// configuration for the given gateways. The gateways
+// are ready once all their keystores are reachable.
+func gatewayMetricsConfig(metrics *metric.Metrics, gateways []*gateway, replay *api.ReplayGuard) *api.MetricsConfig {
+	return &api.MetricsConfig{
+		Metrics: metrics,
+		Ready: func(ctx context.Context) error {
+			for _, gw := range gateways {
+				if err := gw.Ready(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Token:  os.Getenv("KES_METRICS_TOKEN"),
+		Replay: replay,
+	}
 }
 
-func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool) (*cli.Buffer, error) {
+// metricsTLSConfig returns the TLS configuration of
+// the metrics listener or nil if the listener serves
+// plaintext HTTP. The metrics listener does not request
+// client certificates.
+func metricsTLSConfig(cliConfig gatewayConfig, tlsConfig *tls.Config) *tls.Config {
+	if !cliConfig.MetricsTLS {
+		return nil
+	}
+	c := tlsConfig.Clone()
+	c.ClientAuth = tls.NoClientCert
+	c.ClientCAs = nil
+	return c
+}
+
+// auditLogFile is an audit log file that is optionally
+// synced to a central audit log service.
+type auditLogFile struct {
+	*log.File
+
+	syncer *audit.Syncer
+}
+
+// Close stops syncing and closes the audit log file.
+func (f *auditLogFile) Close() error {
+	if f.syncer != nil {
+		f.syncer.Close()
+	}
+	return f.File.Close()
+}
+
+// openAuditFile opens the audit log file, if configured,
+// and adds it as output to the router's audit log. It
+// returns nil if no audit log file is configured.
+//
+// If audit log sync is configured, the audit log file
+// is synced to the audit log service until it is closed.
+func openAuditFile(config *edge.ServerConfig, rConfig *api.EdgeRouterConfig) (*auditLogFile, error) {
+	if config.Log.AuditFile == nil {
+		return nil, nil
+	}
+
+	var client *http.Client
+	if sync := config.Log.AuditFile.Sync; sync != nil {
+		tlsConfig, err := clientTLSConfig(sync.Certificate, sync.PrivateKey, sync.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize audit sync TLS config: %v", err)
+		}
+		client = &http.Client{
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				TLSClientConfig:   tlsConfig,
+				ForceAttemptHTTP2: true,
+				IdleConnTimeout:   90 * time.Second,
+			},
+		}
+	}
+
+	file, err := log.OpenFile(config.Log.AuditFile.Path, log.FileConfig{
+		MaxSize:  config.Log.AuditFile.MaxSize,
+		Interval: config.Log.AuditFile.Rotate,
+		MaxFiles: config.Log.AuditFile.MaxFiles,
+		MaxAge:   config.Log.AuditFile.MaxAge,
+		Compress: config.Log.AuditFile.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rConfig.AuditLog.Add(file)
+
+	f := &auditLogFile{File: file}
+	if sync := config.Log.AuditFile.Sync; sync != nil {
+		f.syncer = audit.NewSyncer(file, &audit.SyncConfig{
+			Endpoint: sync.Endpoint,
+			Client:   client,
+			Interval: sync.Interval,
+		})
+	}
+	return f, nil
+}
+
+// startHeartbeat starts sending heartbeats with the
+// health of the server, if configured. It returns nil
+// if no heartbeat is configured.
+func startHeartbeat(config *edge.ServerConfig, rConfig *api.EdgeRouterConfig, startTime time.Time) (*api.Heartbeat, error) {
+	if config.Heartbeat == nil {
+		return nil, nil
+	}
+
+	tlsConfig, err := clientTLSConfig(config.Heartbeat.Certificate, config.Heartbeat.PrivateKey, config.Heartbeat.CAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize heartbeat TLS config: %v", err)
+	}
+	node := config.Heartbeat.Node
+	if node == "" {
+		if node, err = os.Hostname(); err != nil {
+			node = config.Addr
+		}
+	}
+	return api.NewHeartbeat(rConfig, &api.HeartbeatConfig{
+		Endpoint:  config.Heartbeat.Endpoint,
+		Node:      node,
+		StartTime: startTime,
+		Interval:  config.Heartbeat.Interval,
+		Client: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				TLSClientConfig:   tlsConfig,
+				ForceAttemptHTTP2: true,
+				IdleConnTimeout:   90 * time.Second,
+			},
+		},
+	}), nil
+}
+
+// startStatsD starts pushing the router's metrics to the
+// StatsD agent, if configured. It returns nil if no StatsD
+// exporter is configured.
+func startStatsD(config *edge.ServerConfig, rConfig *api.EdgeRouterConfig) (*metric.StatsD, error) {
+	if config.Metrics == nil || config.Metrics.StatsD == nil {
+		return nil, nil
+	}
+	return metric.NewStatsD(rConfig.Metrics, &metric.StatsDConfig{
+		Endpoint:  config.Metrics.StatsD.Endpoint,
+		Prefix:    config.Metrics.StatsD.Prefix,
+		Interval:  config.Metrics.StatsD.Interval,
+		DogStatsD: config.Metrics.StatsD.DogStatsD,
+		Tags:      config.Metrics.StatsD.Tags,
+	})
+}
+
+func gatewayMessage(config *edge.ServerConfig, cliConfig gatewayConfig, tlsConfig *tls.Config, mlock bool) (*cli.Buffer, error) {
 	ip, port := serverAddr(config.Addr)
-	ifaceIPs := listeningOnV4(ip)
+	ifaceIPs := listeningOn(ip, cliConfig.Network)
 	if len(ifaceIPs) == 0 {
 		return nil, errors.New("failed to listen on network interfaces")
 	}
@@ -619,9 +1494,12 @@ func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool
 	for _, endpoint := range kmsEndpoints[1:] {
 		buffer.Sprintf("%-12s", " ").Sprint(strings.Repeat(" ", len(kmsKind))).Sprintf("  %s\n", endpoint)
 	}
-	buffer.Stylef(item, "%-12s", "Endpoints").Sprintf("https://%s:%s\n", ifaceIPs[0], port)
+	if kind, key := wrappingDescription(config); kind != "" {
+		buffer.Stylef(item, "%-12s", "Key Wrap").Sprintf("%s: %s\n", kind, key)
+	}
+	buffer.Stylef(item, "%-12s", "Endpoints").Sprintf("https://%s\n", net.JoinHostPort(ifaceIPs[0].String(), port))
 	for _, ifaceIP := range ifaceIPs[1:] {
-		buffer.Sprintf("%-12s", " ").Sprintf("https://%s:%s\n", ifaceIP, port)
+		buffer.Sprintf("%-12s", " ").Sprintf("https://%s\n", net.JoinHostPort(ifaceIP.String(), port))
 	}
 	buffer.Sprintln()
 	if r, err := hex.DecodeString(config.Admin.String()); err == nil && len(r) == sha256.Size {
@@ -632,6 +1510,9 @@ func gatewayMessage(config *edge.ServerConfig, tlsConfig *tls.Config, mlock bool
 	if tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert {
 		buffer.Stylef(item, "%-12s", "Mutual TLS").Sprint("on").Styleln(faint, "Verify client certificates")
 	}
+	if cliConfig.MetricsAddr != "" {
+		buffer.Stylef(item, "%-12s", "Metrics").Sprintln(metricsEndpoint(cliConfig.MetricsAddr, cliConfig.MetricsTLS))
+	}
 	switch {
 	case runtime.GOOS == "linux" && mlock:
 		buffer.Stylef(item, "%-12s", "Mem Lock").Stylef(green, "%-22s", "on").Styleln(faint, "RAM pages will not be swapped to disk")
@@ -0,0 +1,161 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/gitops"
+	flag "github.com/spf13/pflag"
+)
+
+const gitopsCmdUsage = `Usage:
+    kes gitops [options] <repository>
+
+Options:
+        --branch <name>          The branch to sync. (default: remote HEAD)
+        --path <dir>             Directory within the repository that contains
+                                 the policy files. (default: repository root)
+        --dir <dir>              Local working directory of the repository.
+                                 (default: $HOME/.kes/gitops)
+        --interval <time>        Time between syncs. (default: 1m)
+        --once                   Sync once and exit.
+        --prune                  Delete policies and identities that are not
+                                 defined in the repository.
+        --dry-run                Only report drift. Don't modify the server.
+        --allowed-signers <file> SSH allowed signers file used to verify
+                                 commit signatures.
+        --skip-verify-commits    Don't verify commit signatures.
+
+    -k, --insecure               Skip TLS certificate validation.
+    -h, --help                   Print command line options.
+
+Syncs the policies and identity assignments defined in a Git repository to
+the KES server specified by the KES_SERVER environment variable. It pulls
+the repository periodically, verifies the signature of the latest commit
+and creates, updates and, with --prune, deletes policies and identities
+such that the server matches the repository. Commits without a valid
+signature are not synced. Neither are commits that are not descendants of
+the previously synced commit, such that rewinding the branch does not roll
+back the server. To sync a rewritten branch, remove the --dir directory.
+
+The repository contains YAML files, one per enclave, that define the
+policies and their assigned identities:
+
+    enclave: tenant-1
+    policy:
+      my-app:
+        allow:
+        - /v1/key/encrypt/my-app*
+        identities:
+        - 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+
+Files without an enclave refer to the default enclave. Every difference
+between the server and the repository is reported as drift, e.g. policies
+modified by calling the KES API directly.
+
+Examples:
+    $ kes gitops --path policies https://git.example.com/kes-policies.git
+    $ kes gitops --once --dry-run git@git.example.com:kes-policies.git
+`
+
+func gitopsCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, gitopsCmdUsage) }
+
+	var (
+		branchFlag         string
+		pathFlag           string
+		dirFlag            string
+		intervalFlag       time.Duration
+		onceFlag           bool
+		pruneFlag          bool
+		dryRunFlag         bool
+		allowedSignersFlag string
+		skipVerifyFlag     bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&branchFlag, "branch", "", "The branch to sync")
+	cmd.StringVar(&pathFlag, "path", "", "Directory within the repository that contains the policy files")
+	cmd.StringVar(&dirFlag, "dir", "", "Local working directory of the repository")
+	cmd.DurationVar(&intervalFlag, "interval", 1*time.Minute, "Time between syncs")
+	cmd.BoolVar(&onceFlag, "once", false, "Sync once and exit")
+	cmd.BoolVar(&pruneFlag, "prune", false, "Delete policies and identities that are not defined in the repository")
+	cmd.BoolVar(&dryRunFlag, "dry-run", false, "Only report drift")
+	cmd.StringVar(&allowedSignersFlag, "allowed-signers", "", "SSH allowed signers file used to verify commit signatures")
+	cmd.BoolVar(&skipVerifyFlag, "skip-verify-commits", false, "Don't verify commit signatures")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes gitops --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no repository specified. See 'kes gitops --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes gitops --help'")
+	}
+	if intervalFlag <= 0 {
+		cli.Fatal("invalid sync interval: interval must be positive")
+	}
+	if skipVerifyFlag && allowedSignersFlag != "" {
+		cli.Fatal("'--skip-verify-commits' and '--allowed-signers' cannot be used together")
+	}
+	if dirFlag == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			cli.Fatalf("failed to determine working directory: %v. Use --dir", err)
+		}
+		dirFlag = filepath.Join(home, ".kes", "gitops")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	syncer := &gitops.Syncer{
+		Repository: &gitops.Repository{
+			URL:            cmd.Arg(0),
+			Branch:         branchFlag,
+			Dir:            dirFlag,
+			AllowedSigners: allowedSignersFlag,
+			SkipVerify:     skipVerifyFlag,
+		},
+		Server: gitops.NewServer(newClient(insecureSkipVerify)),
+		Path:   pathFlag,
+		Prune:  pruneFlag,
+		DryRun: dryRunFlag,
+	}
+	if onceFlag {
+		report, err := syncer.Sync(ctx)
+		if report != nil {
+			for _, drift := range report.Drift {
+				fmt.Println(drift)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatal(err)
+		}
+		cli.Println(fmt.Sprintf("Synced commit %s: %d drift(s) detected, %d resolved", report.Commit, len(report.Drift), report.Applied))
+		return
+	}
+
+	cli.Println(fmt.Sprintf("Syncing policies from '%s' every %v", cmd.Arg(0), intervalFlag))
+	if err := syncer.Run(ctx, intervalFlag); err != nil && !errors.Is(err, context.Canceled) {
+		cli.Fatal(err)
+	}
+}
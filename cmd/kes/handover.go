@@ -0,0 +1,194 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/log"
+)
+
+// envHandoverFD is the env. variable that contains the file
+// descriptor a new server process writes to once it listens
+// for incoming connections.
+const envHandoverFD = "KES_HANDOVER_FD"
+
+const (
+	// handoverTimeout is the max. amount of time the current
+	// server process waits for a new server process to listen
+	// on the inherited sockets.
+	handoverTimeout = 30 * time.Second
+
+	// handoverGracePeriod is the max. amount of time the current
+	// server process waits for active requests to complete after
+	// a new server process has taken over.
+	handoverGracePeriod = 30 * time.Second
+)
+
+// handoverOnSignal hands the listening sockets of the given
+// servers over to a new server process, started from the
+// current executable with the same arguments, whenever the
+// SIGUSR2 signal is received. Once the new server process
+// listens, it cancels the ctx such that the servers stop
+// accepting new connections and shut down gracefully.
+//
+// If the new server process fails to start, the servers
+// keep serving requests.
+func handoverOnSignal(ctx context.Context, cancel context.CancelFunc, servers ...*https.Server) {
+	sigusr2 := make(chan os.Signal, 1)
+	signal.Notify(sigusr2, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(sigusr2)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigusr2:
+				cli.Println("SIGUSR2 signal received. Handing over to new server process...")
+				pid, err := handover(servers...)
+				if err != nil {
+					log.Printf("failed to hand over to new server process: %v", err)
+					continue
+				}
+				for _, server := range servers {
+					if server != nil {
+						server.SetShutdownTimeout(handoverGracePeriod)
+					}
+				}
+				cli.Println(fmt.Sprintf("Server process %d has taken over. Shutting down...", pid))
+				cancel()
+				return
+			}
+		}
+	}()
+}
+
+// handover starts a new server process that inherits the
+// listening sockets of the given servers. It waits until
+// the new process listens for incoming connections and
+// returns its process ID.
+func handover(servers ...*https.Server) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var (
+		files     []*os.File
+		listeners []string
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		f, err := server.ListenerFile()
+		if err != nil {
+			w.Close()
+			return 0, err
+		}
+		listeners = append(listeners, server.Addr()+"="+strconv.Itoa(3+len(files))) // Extra files start at fd 3
+		files = append(files, f)
+	}
+	files = append(files, w)
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, https.EnvListeners+"=") && !strings.HasPrefix(e, envHandoverFD+"=") {
+			env = append(env, e)
+		}
+	}
+	env = append(env, https.EnvListeners+"="+strings.Join(listeners, ","))
+	env = append(env, envHandoverFD+"="+strconv.Itoa(3+len(files)-1))
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = env
+	cmd.ExtraFiles = files
+	if err = cmd.Start(); err != nil {
+		return 0, err
+	}
+	w.Close() // The new process holds the only remaining write end of the pipe.
+
+	ready := make(chan error, 1)
+	go func() {
+		var b [1]byte
+		if _, err := r.Read(b[:]); err != nil {
+			ready <- errors.New("new server process exited before listening")
+			return
+		}
+		ready <- nil
+	}()
+
+	timer := time.NewTimer(handoverTimeout)
+	defer timer.Stop()
+	select {
+	case err = <-ready:
+	case <-timer.C:
+		err = fmt.Errorf("new server process did not listen within %v", handoverTimeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+	return pid, nil
+}
+
+// notifyHandover notifies the parent server process, if this
+// process has been started by a handover, once all servers
+// listen for incoming connections.
+func notifyHandover(ctx context.Context, servers ...*https.Server) {
+	s := os.Getenv(envHandoverFD)
+	if s == "" {
+		return
+	}
+	os.Unsetenv(envHandoverFD)
+
+	fd, err := strconv.Atoi(s)
+	if err != nil || fd < 3 {
+		log.Printf("invalid handover file descriptor '%s'", s)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "handover")
+	go func() {
+		defer f.Close()
+
+		for _, server := range servers {
+			select {
+			case <-ctx.Done():
+				return
+			case <-server.Listening():
+			}
+		}
+		f.Write([]byte{1})
+	}()
+}
@@ -0,0 +1,18 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+
+	"github.com/minio/kes/internal/https"
+)
+
+// handoverOnSignal does nothing on windows since
+// there is no SIGUSR2 signal.
+func handoverOnSignal(context.Context, context.CancelFunc, ...*https.Server) {}
+
+// notifyHandover does nothing on windows.
+func notifyHandover(context.Context, ...*https.Server) {}
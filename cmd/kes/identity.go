@@ -7,9 +7,12 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
@@ -25,6 +28,7 @@ import (
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/kesclient"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -36,8 +40,10 @@ Commands:
     new                      Create a new KES identity.
     of                       Compute a KES identity from a certificate.
     info                     Get information about a KES identity.
+    import                   Assign policies to identities from a file.
     ls                       List KES identities.
     rm                       Remove a KES identity.
+    rotate                   Rotate the client certificate.
 
 Options:
     -h, --help               Print command line options.
@@ -48,11 +54,13 @@ func identityCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, identityCmdUsage) }
 
 	subCmds := commands{
-		"new":  newIdentityCmd,
-		"of":   ofIdentityCmd,
-		"info": infoIdentityCmd,
-		"ls":   lsIdentityCmd,
-		"rm":   rmIdentityCmd,
+		"new":    newIdentityCmd,
+		"of":     ofIdentityCmd,
+		"info":   infoIdentityCmd,
+		"import": importIdentityCmd,
+		"ls":     lsIdentityCmd,
+		"rm":     rmIdentityCmd,
+		"rotate": rotateIdentityCmd,
 	}
 
 	if len(args) < 2 {
@@ -294,23 +302,10 @@ func ofIdentityCmd(args []string) {
 		}
 		identity = key.Identity()
 	} else {
-		filename := cmd.Arg(0)
-		pemBlock, err := os.ReadFile(filename)
-		if err != nil {
+		var err error
+		if identity, err = certificateIdentity(cmd.Arg(0)); err != nil {
 			cli.Fatal(err)
 		}
-		pemBlock, err = https.FilterPEM(pemBlock, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
-		if err != nil {
-			cli.Fatalf("failed to parse certificate in '%s': %v", filename, err)
-		}
-
-		next, _ := pem.Decode(pemBlock)
-		cert, err := x509.ParseCertificate(next.Bytes)
-		if err != nil {
-			cli.Fatalf("failed to parse certificate in '%s': %v", filename, err)
-		}
-		h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
-		identity = kes.Identity(hex.EncodeToString(h[:]))
 	}
 	if isTerm(os.Stdout) {
 		var buffer strings.Builder
@@ -323,6 +318,30 @@ func ofIdentityCmd(args []string) {
 	}
 }
 
+// certificateIdentity returns the identity of the
+// first certificate in the named PEM file.
+func certificateIdentity(filename string) (kes.Identity, error) {
+	pemBlock, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	pemBlock, err = https.FilterPEM(pemBlock, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate in '%s': %v", filename, err)
+	}
+
+	next, _ := pem.Decode(pemBlock)
+	if next == nil {
+		return "", fmt.Errorf("failed to parse certificate in '%s': no certificate found", filename)
+	}
+	cert, err := x509.ParseCertificate(next.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate in '%s': %v", filename, err)
+	}
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return kes.Identity(hex.EncodeToString(h[:])), nil
+}
+
 const infoIdentityCmdUsage = `Usage:
     kes identity info [options] [<identity>]
 
@@ -469,7 +488,9 @@ const lsIdentityCmdUsage = `Usage:
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
-        --json               Print identities in JSON format.
+        --json               Print identities in JSON format. Same as '--output json'.
+    -o, --output <format>    Print output in the given format: table, json or
+                             yaml. Defaults to $KES_OUTPUT or table.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -495,6 +516,7 @@ func lsIdentityCmd(args []string) {
 		enclaveName        string
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.VarP(&outputFlag, "output", "o", "Print output in the given format")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
@@ -504,6 +526,9 @@ func lsIdentityCmd(args []string) {
 		}
 		cli.Fatalf("%v. See 'kes identity ls --help'", err)
 	}
+	if err := outputFlag.Parse(jsonFlag); err != nil {
+		cli.Fatalf("%v. See 'kes identity ls --help'", err)
+	}
 
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes identity ls --help'")
@@ -527,7 +552,7 @@ func lsIdentityCmd(args []string) {
 	}
 	defer identities.Close()
 
-	if jsonFlag {
+	if outputFlag.Format() == "json" {
 		if _, err = identities.WriteTo(os.Stdout); err != nil {
 			cli.Fatal(err)
 		}
@@ -539,10 +564,20 @@ func lsIdentityCmd(args []string) {
 		if err != nil {
 			cli.Fatalf("failed to list identities: %v", err)
 		}
+		sort.Slice(sortedInfos, func(i, j int) bool {
+			return strings.Compare(sortedInfos[i].Policy, sortedInfos[j].Policy) < 0
+		})
+
+		if !outputFlag.IsTable() {
+			if sortedInfos == nil {
+				sortedInfos = []kes.IdentityInfo{}
+			}
+			if err = outputFlag.Encode(os.Stdout, sortedInfos); err != nil {
+				cli.Fatal(err)
+			}
+			return
+		}
 		if len(sortedInfos) > 0 {
-			sort.Slice(sortedInfos, func(i, j int) bool {
-				return strings.Compare(sortedInfos[i].Policy, sortedInfos[j].Policy) < 0
-			})
 
 			headerStyle := tui.NewStyle()
 			dateStyle := tui.NewStyle()
@@ -622,3 +657,128 @@ func rmIdentityCmd(args []string) {
 		}
 	}
 }
+
+const rotateIdentityCmdUsage = `Usage:
+    kes identity rotate [options]
+
+Options:
+    --key <PATH>             Path to the client private key. (default: $KES_CLIENT_KEY)
+    --cert <PATH>            Path to the client certificate. (default: $KES_CLIENT_CERT)
+    --ttl <DURATION>         Duration until the new certificate expires.
+                             (default: 24h, max: 168h)
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Rotates the client certificate of the current identity. It requests a
+short-lived client certificate for the current private key from the KES
+server. The certificate does not outlive the identity's policy assignment.
+The new certificate replaces the file at --cert. The identity does not
+change.
+
+Examples:
+    $ kes identity rotate
+    $ kes identity rotate --ttl 72h
+    $ kes identity rotate --key client.key --cert client.crt
+`
+
+func rotateIdentityCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rotateIdentityCmdUsage) }
+
+	var (
+		keyPath            string
+		certPath           string
+		ttl                time.Duration
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&keyPath, "key", os.Getenv("KES_CLIENT_KEY"), "Path to the client private key")
+	cmd.StringVar(&certPath, "cert", os.Getenv("KES_CLIENT_CERT"), "Path to the client certificate")
+	cmd.DurationVar(&ttl, "ttl", 0, "Duration until the new certificate expires")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes identity rotate --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes identity rotate --help'")
+	}
+	if keyPath == "" {
+		cli.Fatal("no private key specified. Set the '--key' flag or KES_CLIENT_KEY")
+	}
+	if certPath == "" {
+		cli.Fatal("no certificate specified. Set the '--cert' flag or KES_CLIENT_CERT")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid '--ttl': must not be negative")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	certPem, err := os.ReadFile(certPath)
+	if err != nil {
+		cli.Fatalf("failed to read certificate: %v", err)
+	}
+	keyPem, err := os.ReadFile(keyPath)
+	if err != nil {
+		cli.Fatalf("failed to read private key: %v", err)
+	}
+	current, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		cli.Fatalf("failed to load private key or certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(current.Certificate[0])
+	if err != nil {
+		cli.Fatalf("failed to load certificate: %v", err)
+	}
+
+	priv, ok := current.PrivateKey.(crypto.Signer)
+	if !ok {
+		cli.Fatal("failed to load private key: not a signing key")
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: leaf.Subject.CommonName},
+	}, priv)
+	if err != nil {
+		cli.Fatalf("failed to create certificate request: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	client := newClientWithCertificate(current, insecureSkipVerify)
+	cert, err := kesclient.IssueCertificate(ctx, client, enclaveName, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}), ttl)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to rotate certificate: %v", err)
+	}
+
+	// Write the new certificate to a temporary file first
+	// such that an error does not leave a partially written
+	// certificate behind.
+	if err = os.WriteFile(certPath+".tmp", []byte(cert.Certificate), 0o644); err != nil {
+		cli.Fatalf("failed to write certificate: %v", err)
+	}
+	if err = os.Rename(certPath+".tmp", certPath); err != nil {
+		os.Remove(certPath + ".tmp")
+		cli.Fatalf("failed to write certificate: %v", err)
+	}
+
+	year, month, day := cert.ExpiresAt.Local().Date()
+	hour, min, sec := cert.ExpiresAt.Local().Clock()
+	var buffer strings.Builder
+	fmt.Fprintf(&buffer, "Identity:    %s\n", cert.Identity)
+	fmt.Fprintf(&buffer, "Expires At:  %04d-%02d-%02d %02d:%02d:%02d\n", year, month, day, hour, min, sec)
+	fmt.Fprintf(&buffer, "Private Key: %s\n", keyPath)
+	fmt.Fprintf(&buffer, "Certificate: %s", certPath)
+	cli.Println(buffer.String())
+}
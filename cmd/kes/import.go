@@ -0,0 +1,464 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// readImportFile reads the records of a CSV, JSON or YAML
+// file. The format is detected by the file extension. JSON
+// and YAML files must contain a list of objects. CSV files
+// must start with a header row naming the columns. Each
+// CSV row is converted to a record by fromCSV.
+func readImportFile[T any](filename string, fromCSV func(row map[string]string) (T, error)) ([]T, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %v", filename, err)
+		}
+	case ".yml", ".yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(&records); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse '%s': %v", filename, err)
+		}
+	case ".csv":
+		reader := csv.NewReader(bytes.NewReader(b))
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse '%s': %v", filename, err)
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		header := rows[0]
+		for i := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+		}
+		for i, row := range rows[1:] {
+			columns := make(map[string]string, len(header))
+			for j, name := range header {
+				columns[name] = strings.TrimSpace(row[j])
+			}
+			record, err := fromCSV(columns)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse '%s': row %d: %v", filename, i+2, err)
+			}
+			records = append(records, record)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported file format '%s': use a .csv, .json or .yaml file", ext)
+	}
+	return records, nil
+}
+
+// splitRules splits a list of policy rules
+// separated by ';' within a CSV column.
+func splitRules(s string) []string {
+	var rules []string
+	for _, rule := range strings.Split(s, ";") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// importResult is the outcome of importing one record.
+type importResult struct {
+	Row    int    `json:"row"`
+	Name   string `json:"name"`
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// importReport prints the result of each
+// imported record as soon as it is known.
+type importReport struct {
+	json     bool
+	failures int
+
+	ok, failed tui.Style
+}
+
+func newImportReport(jsonFlag, colorize bool) *importReport {
+	r := &importReport{json: jsonFlag}
+	if colorize {
+		r.ok = tui.NewStyle().Foreground(tui.Color("#00a700"))
+		r.failed = tui.NewStyle().Foreground(tui.Color("#d70000"))
+	}
+	return r
+}
+
+// Add prints the result of the record at row. If err is
+// not nil, the record could not be imported.
+func (r *importReport) Add(row int, name, action string, err error) {
+	result := importResult{Row: row, Name: name, Action: action}
+	if err != nil {
+		result.Action = ""
+		result.Error = err.Error()
+		r.failures++
+	}
+	if r.json {
+		json.NewEncoder(os.Stdout).Encode(result)
+		return
+	}
+	if err != nil {
+		fmt.Printf("%s %4d  %s: %v\n", r.failed.Render("✖"), row, name, err)
+	} else {
+		fmt.Printf("%s %4d  %s: %s\n", r.ok.Render("✔"), row, name, action)
+	}
+}
+
+// Close prints a summary and exits with a non-zero
+// exit code if any record could not be imported.
+func (r *importReport) Close(n int, dryRun bool) {
+	if !r.json {
+		switch {
+		case dryRun:
+			fmt.Printf("\nDry run: %d of %d record(s) would be imported.\n", n-r.failures, n)
+		default:
+			fmt.Printf("\nImported %d of %d record(s).\n", n-r.failures, n)
+		}
+	}
+	if r.failures > 0 {
+		os.Exit(1)
+	}
+}
+
+const importPolicyCmdUsage = `Usage:
+    kes policy import [options] <file>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --dry-run            Validate the file and show what would be changed
+                             without changing anything.
+        --json               Print the result of each policy in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Creates or replaces all policies listed in <file>. The file is either a
+JSON or YAML file containing a list of policies, or a CSV file with the
+columns 'name', 'allow' and 'deny'. Multiple rules within a CSV column are
+separated by ';'.
+
+    - name: my-app
+      allow:
+      - /v1/key/generate/my-app-*
+      - /v1/key/decrypt/my-app-*
+      deny: []
+
+The result of each policy is shown. Failed policies do not prevent the
+remaining ones from being imported, but cause a non-zero exit code.
+
+Examples:
+    $ kes policy import --dry-run ./policies.yml
+    $ kes policy import ./policies.csv
+`
+
+// policyRecord is a policy within an import file.
+type policyRecord struct {
+	Name  string   `json:"name" yaml:"name"`
+	Allow []string `json:"allow" yaml:"allow"`
+	Deny  []string `json:"deny" yaml:"deny"`
+}
+
+func importPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, importPolicyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		dryRunFlag         bool
+		jsonFlag           bool
+		colorFlag          colorOption
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&dryRunFlag, "dry-run", false, "Show what would be changed without changing anything")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print results in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy import --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no import file specified. See 'kes policy import --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes policy import --help'")
+	}
+
+	policies, err := readImportFile(cmd.Arg(0), func(row map[string]string) (policyRecord, error) {
+		return policyRecord{
+			Name:  row["name"],
+			Allow: splitRules(row["allow"]),
+			Deny:  splitRules(row["deny"]),
+		}, nil
+	})
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	var (
+		enclave = newEnclave(enclaveName, insecureSkipVerify)
+		report  = newImportReport(jsonFlag, colorFlag.Colorize())
+		seen    = make(map[string]int, len(policies))
+	)
+	for i, policy := range policies {
+		row := i + 1
+		if policy.Name == "" {
+			report.Add(row, policy.Name, "", errors.New("no policy name"))
+			continue
+		}
+		if prev, ok := seen[policy.Name]; ok {
+			report.Add(row, policy.Name, "", fmt.Errorf("duplicate of record %d", prev))
+			continue
+		}
+		seen[policy.Name] = row
+		if err := validatePolicyRules(policy.Allow, policy.Deny); err != nil {
+			report.Add(row, policy.Name, "", err)
+			continue
+		}
+
+		action := "created"
+		if _, err := enclave.DescribePolicy(ctx, policy.Name); err == nil {
+			action = "updated"
+		} else if !errors.Is(err, kes.ErrPolicyNotFound) {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			report.Add(row, policy.Name, "", err)
+			continue
+		}
+		if dryRunFlag {
+			report.Add(row, policy.Name, "would be "+action, nil)
+			continue
+		}
+		err := enclave.SetPolicy(ctx, policy.Name, &kes.Policy{
+			Allow: policy.Allow,
+			Deny:  policy.Deny,
+		})
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		report.Add(row, policy.Name, action, err)
+	}
+	report.Close(len(policies), dryRunFlag)
+}
+
+const importIdentityCmdUsage = `Usage:
+    kes identity import [options] <file>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --dry-run            Validate the file and show what would be changed
+                             without changing anything.
+        --json               Print the result of each identity in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Assigns policies to all identities listed in <file>. The file is either
+a JSON or YAML file containing a list of identities, or a CSV file with
+the columns 'identity', 'certificate' and 'policy'. Each identity is
+specified either by its 'identity' or by the path of its 'certificate'.
+Relative certificate paths are relative to the directory of <file>.
+
+    - identity: 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+      policy: my-app
+    - certificate: ./certs/my-other-app.crt
+      policy: my-other-app
+
+The result of each identity is shown. Failed identities do not prevent the
+remaining ones from being imported, but cause a non-zero exit code.
+
+Examples:
+    $ kes identity import --dry-run ./identities.yml
+    $ kes identity import ./identities.csv
+`
+
+// identityRecord is an identity within an import file.
+type identityRecord struct {
+	Identity    kes.Identity `json:"identity" yaml:"identity"`
+	Certificate string       `json:"certificate" yaml:"certificate"`
+	Policy      string       `json:"policy" yaml:"policy"`
+}
+
+func importIdentityCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, importIdentityCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		dryRunFlag         bool
+		jsonFlag           bool
+		colorFlag          colorOption
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&dryRunFlag, "dry-run", false, "Show what would be changed without changing anything")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print results in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes identity import --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no import file specified. See 'kes identity import --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes identity import --help'")
+	}
+
+	filename := cmd.Arg(0)
+	identities, err := readImportFile(filename, func(row map[string]string) (identityRecord, error) {
+		return identityRecord{
+			Identity:    kes.Identity(row["identity"]),
+			Certificate: row["certificate"],
+			Policy:      row["policy"],
+		}, nil
+	})
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	var (
+		enclave  = newEnclave(enclaveName, insecureSkipVerify)
+		report   = newImportReport(jsonFlag, colorFlag.Colorize())
+		seen     = make(map[kes.Identity]int, len(identities))
+		policies = map[string]error{}
+	)
+	for i, record := range identities {
+		row := i + 1
+		identity := record.Identity
+		switch {
+		case !identity.IsUnknown() && record.Certificate != "":
+			report.Add(row, identity.String(), "", errors.New("both, identity and certificate, specified"))
+			continue
+		case record.Certificate != "":
+			path := record.Certificate
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(filename), path)
+			}
+			if identity, err = certificateIdentity(path); err != nil {
+				report.Add(row, record.Certificate, "", err)
+				continue
+			}
+		case identity.IsUnknown():
+			report.Add(row, "", "", errors.New("no identity or certificate"))
+			continue
+		}
+		if b, err := hex.DecodeString(identity.String()); err != nil || len(b) != sha256.Size {
+			report.Add(row, identity.String(), "", errors.New("invalid identity: must be a hex-encoded SHA-256 hash"))
+			continue
+		}
+		if prev, ok := seen[identity]; ok {
+			report.Add(row, identity.String(), "", fmt.Errorf("duplicate of record %d", prev))
+			continue
+		}
+		seen[identity] = row
+		if record.Policy == "" {
+			report.Add(row, identity.String(), "", errors.New("no policy"))
+			continue
+		}
+
+		// Check that the policy exists. Otherwise, a dry run
+		// would report identities that cannot be imported as
+		// ok.
+		err, ok := policies[record.Policy]
+		if !ok {
+			_, err = enclave.DescribePolicy(ctx, record.Policy)
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			policies[record.Policy] = err
+		}
+		if err != nil {
+			report.Add(row, identity.String(), "", fmt.Errorf("policy '%s': %v", record.Policy, err))
+			continue
+		}
+
+		action := fmt.Sprintf("assigned policy '%s'", record.Policy)
+		info, err := enclave.DescribeIdentity(ctx, identity)
+		switch {
+		case err == nil && info.IsAdmin:
+			report.Add(row, identity.String(), "", errors.New("identity is an admin"))
+			continue
+		case err == nil && info.Policy == record.Policy:
+			report.Add(row, identity.String(), "unchanged", nil)
+			continue
+		case err == nil && info.Policy != "":
+			action = fmt.Sprintf("reassigned from policy '%s' to '%s'", info.Policy, record.Policy)
+		case err != nil && !errors.Is(err, kes.ErrIdentityNotFound):
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			report.Add(row, identity.String(), "", err)
+			continue
+		}
+		if dryRunFlag {
+			report.Add(row, identity.String(), "would be "+action, nil)
+			continue
+		}
+		err = enclave.AssignPolicy(ctx, record.Policy, identity)
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		report.Add(row, identity.String(), action, err)
+	}
+	report.Close(len(identities), dryRunFlag)
+}
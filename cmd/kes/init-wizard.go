@@ -0,0 +1,276 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+)
+
+// initWizard prompts for the essential server settings and
+// writes a complete, ready-to-use KES setup into dir:
+//   - init.yml:                       the init configuration
+//   - server.key, server.crt:         the server TLS key and certificate
+//   - root.key, root.crt:             the system admin identity
+//   - enclave-admin.key, ...crt:      the admin identity of the default enclave
+//   - unseal.env:                     the unseal key as environment file
+//   - kes.service:                    a systemd unit (optional)
+//
+// It returns the init configuration and the
+// directory the vault should be initialized in.
+func initWizard(dir string, in io.Reader, acceptDefaults bool) (*cli.InitConfig, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	hostname, _ := os.Hostname()
+	binary, _ := os.Executable()
+	if binary == "" {
+		binary = "/usr/local/bin/kes"
+	}
+
+	var (
+		reader = bufio.NewReader(in)
+		ask    = func(question, value string) string {
+			if acceptDefaults {
+				return value
+			}
+			if value != "" {
+				fmt.Fprintf(os.Stderr, "%s [%s]: ", question, value)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: ", question)
+			}
+			line, _ := reader.ReadString('\n')
+			if line = strings.TrimSpace(line); line != "" {
+				return line
+			}
+			return value
+		}
+	)
+
+	address := ask("Server address", "0.0.0.0:7373")
+	if _, _, err = net.SplitHostPort(address); err != nil {
+		return nil, "", fmt.Errorf("invalid server address '%s': %v", address, err)
+	}
+	defaultDomains := "localhost"
+	if hostname != "" && hostname != "localhost" {
+		defaultDomains = hostname + ",localhost"
+	}
+	domains := splitList(ask("Server DNS names (comma-separated)", defaultDomains))
+	var IPs []net.IP
+	for _, s := range splitList(ask("Server IP addresses (comma-separated)", "127.0.0.1")) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, "", fmt.Errorf("invalid IP address '%s'", s)
+		}
+		IPs = append(IPs, ip)
+	}
+	if len(domains) == 0 && len(IPs) == 0 {
+		return nil, "", errors.New("server certificate requires at least one DNS name or IP address")
+	}
+	expiry, err := time.ParseDuration(ask("Certificate validity", "8760h"))
+	if err != nil || expiry <= 0 {
+		return nil, "", fmt.Errorf("invalid certificate validity: %v", err)
+	}
+	systemd := strings.ToLower(ask("Create systemd unit (yes/no)", "yes"))
+	if systemd != "yes" && systemd != "y" && systemd != "no" && systemd != "n" {
+		return nil, "", fmt.Errorf("invalid answer '%s': must be 'yes' or 'no'", systemd)
+	}
+	if systemd == "yes" || systemd == "y" {
+		binary = ask("Path to the KES binary", binary)
+	}
+
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return nil, "", err
+	}
+	_, err = writeIdentity(dir, "server", expiry, func(cert *x509.Certificate) {
+		cert.Subject.CommonName = "kes-server"
+		cert.DNSNames = domains
+		cert.IPAddresses = IPs
+		cert.ExtKeyUsage = append(cert.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create server certificate: %v", err)
+	}
+	rootIdentity, err := writeIdentity(dir, "root", expiry, func(cert *x509.Certificate) {
+		cert.Subject.CommonName = "root"
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create root identity: %v", err)
+	}
+	enclaveAdmin, err := writeIdentity(dir, "enclave-admin", expiry, func(cert *x509.Certificate) {
+		cert.Subject.CommonName = "enclave-admin"
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create enclave admin identity: %v", err)
+	}
+
+	var unsealKey [32]byte
+	if _, err = rand.Read(unsealKey[:]); err != nil {
+		return nil, "", err
+	}
+	const UnsealEnv = "KES_UNSEAL_KEY"
+	unseal := UnsealEnv + "=" + base64.StdEncoding.EncodeToString(unsealKey[:]) + "\n"
+	if err = os.WriteFile(filepath.Join(dir, "unseal.env"), []byte(unseal), 0o600); err != nil {
+		return nil, "", err
+	}
+	if err = os.Setenv(UnsealEnv, base64.StdEncoding.EncodeToString(unsealKey[:])); err != nil {
+		return nil, "", err
+	}
+
+	settings := wizardSettings{
+		Dir:          dir,
+		Binary:       binary,
+		Address:      address,
+		RootIdentity: rootIdentity,
+		EnclaveAdmin: enclaveAdmin,
+		UnsealEnv:    UnsealEnv,
+	}
+	if err = writeTemplate(filepath.Join(dir, "init.yml"), initConfigTemplate, settings, 0o600); err != nil {
+		return nil, "", err
+	}
+	if systemd == "yes" || systemd == "y" {
+		if err = writeTemplate(filepath.Join(dir, "kes.service"), systemdUnitTemplate, settings, 0o644); err != nil {
+			return nil, "", err
+		}
+	}
+
+	config, err := cli.ReadInitConfig(filepath.Join(dir, "init.yml"))
+	if err != nil {
+		return nil, "", err
+	}
+	return config, filepath.Join(dir, "data"), nil
+}
+
+// wizardSettings are the values used to render
+// the init config and systemd unit templates.
+type wizardSettings struct {
+	Dir          string
+	Binary       string
+	Address      string
+	RootIdentity kes.Identity
+	EnclaveAdmin kes.Identity
+	UnsealEnv    string
+}
+
+var initConfigTemplate = template.Must(template.New("init.yml").Parse(`# Generated by 'kes init'.
+version: v1
+address: {{ .Address }}
+
+system:
+  admin:
+    identity: {{ .RootIdentity }} # {{ .Dir }}/root.crt
+
+tls:
+  key:  {{ .Dir }}/server.key
+  cert: {{ .Dir }}/server.crt
+
+unseal:
+  environment:
+    name: {{ .UnsealEnv }}
+
+enclave:
+  default:
+    admin:
+      identity: {{ .EnclaveAdmin }} # {{ .Dir }}/enclave-admin.crt
+`))
+
+var systemdUnitTemplate = template.Must(template.New("kes.service").Parse(`# Generated by 'kes init'. Install it with:
+#   sudo cp {{ .Dir }}/kes.service /etc/systemd/system/
+#   sudo systemctl enable --now kes
+[Unit]
+Description=KES
+Documentation=https://github.com/minio/kes/wiki
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=all
+WatchdogSec=30s
+EnvironmentFile={{ .Dir }}/unseal.env
+ExecStart={{ .Binary }} server {{ .Dir }}/data
+Restart=always
+LimitNOFILE=65536
+AmbientCapabilities=CAP_IPC_LOCK
+NoNewPrivileges=yes
+PrivateTmp=yes
+ProtectSystem=full
+ProtectHome=read-only
+ReadWritePaths={{ .Dir }}
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// writeIdentity generates a new private key and certificate
+// and writes them to dir/<name>.key and dir/<name>.crt. It
+// returns the identity of the certificate.
+func writeIdentity(dir, name string, expiry time.Duration, option kes.CertificateOption) (kes.Identity, error) {
+	key, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		return "", err
+	}
+	cert, err := kes.GenerateCertificate(key, option, func(cert *x509.Certificate) {
+		now := time.Now()
+		cert.NotBefore, cert.NotAfter = now, now.Add(expiry)
+	})
+	if err != nil {
+		return "", err
+	}
+	privBytes, err := x509.MarshalPKCS8PrivateKey(key.Private())
+	if err != nil {
+		return "", err
+	}
+
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	if err = os.WriteFile(filepath.Join(dir, name+".key"), keyPem, 0o600); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filepath.Join(dir, name+".crt"), certPem, 0o644); err != nil {
+		return "", err
+	}
+	return key.Identity(), nil
+}
+
+func writeTemplate(filename string, tmpl *template.Template, data any, perm os.FileMode) error {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if err = tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitList splits a comma-separated list and
+// removes empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
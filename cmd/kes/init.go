@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
@@ -16,6 +17,7 @@ import (
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/internal/sys/fs"
 	flag "github.com/spf13/pflag"
@@ -27,11 +29,21 @@ const initCmdUsage = `Usage:
 
 Options:
     --config <PATH>          Path to the initial configuration file.
+    -y, --yes                Accept the default answers of the setup wizard.
     -f, --force              Overwrite any existing data.
     -h, --help               Print command line options.
 
+Initializes a stateful KES server within <PATH> from the given config file.
+
+Without --config, 'kes init' runs an interactive setup wizard instead. It
+generates the server TLS private key and certificate, a root identity for
+the system admin, an admin identity for the default enclave, an unseal key,
+the init configuration and a systemd unit, and writes everything to <PATH>.
+The server data is stored in <PATH>/data.
+
 Examples:
    $ kes init --config init.yml ~/kes
+   $ kes init ~/kes
 `
 
 func initCmd(args []string) {
@@ -40,9 +52,11 @@ func initCmd(args []string) {
 
 	var (
 		forceFlag  bool
+		yesFlag    bool
 		configFlag string
 	)
 	cmd.BoolVarP(&forceFlag, "force", "f", false, "Overwrite any existing data")
+	cmd.BoolVarP(&yesFlag, "yes", "y", false, "Accept the default answers of the setup wizard")
 	cmd.StringVar(&configFlag, "config", "", "Path to the initial configuration file")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -69,14 +83,103 @@ func initCmd(args []string) {
 		}
 	}
 
-	config, err := cli.ReadInitConfig(configFlag)
-	if err != nil {
-		cli.Fatalf("failed to read init config: %v", err)
+	if configFlag != "" && yesFlag {
+		cli.Fatal("'--yes' cannot be used with '--config'. See 'kes init --help'")
+	}
+
+	var (
+		config    *cli.InitConfig
+		vaultPath = path
+		err       error
+	)
+	if configFlag != "" {
+		config, err = cli.ReadInitConfig(configFlag)
+		if err != nil {
+			cli.Fatalf("failed to read init config: %v", err)
+		}
+	} else {
+		config, vaultPath, err = initWizard(path, os.Stdin, yesFlag)
+		if err != nil {
+			cli.Fatalf("failed to setup '%s': %v", path, err)
+		}
+	}
+	if err = initVault(vaultPath, config); err != nil {
+		cli.Fatal(err)
+	}
+
+	width := 70
+	if isTerm(os.Stdout) {
+		w, _, err := term.GetSize(int(os.Stdout.Fd()))
+		if err == nil {
+			width = w - 2
+		}
+	}
+	{
+		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
+		item := tui.NewStyle().Bold(true)
+		cli.Println(header.Render("TLS:"))
+		cli.Println(item.Render("  · Private Key: "), config.TLS.PrivateKey.Value())
+		cli.Println(item.Render("  · Certificate: "), config.TLS.Certificate.Value())
+		cli.Println()
+	}
+	{
+		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
+		item := tui.NewStyle().Bold(true)
+		cli.Println(header.Render("System:"))
+		cli.Println(item.Render("  · Identity: "), config.System.Admin.Identity.Value())
+		cli.Println()
 	}
+	{
+		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
+		item := tui.NewStyle().Bold(true)
+		cli.Println(header.Render("Unseal:"))
+		cli.Println(item.Render("  · Environment: "), config.Unseal.Environment.Name)
+		cli.Println()
+	}
+	if configFlag == "" {
+		dir, _ := filepath.Abs(path)
+		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
+		item := tui.NewStyle().Bold(true)
+		cli.Println(header.Render("Next Steps:"))
+		cli.Println(item.Render("  · Start the server: "), fmt.Sprintf("set -a && . %s && kes server %s", filepath.Join(dir, "unseal.env"), vaultPath))
+		if _, err := os.Stat(filepath.Join(dir, "kes.service")); err == nil {
+			cli.Println(item.Render("  · Install service:  "), fmt.Sprintf("sudo cp %s /etc/systemd/system/ && sudo systemctl enable --now kes", filepath.Join(dir, "kes.service")))
+		}
+		cli.Println(item.Render("  · Connect as admin: "), fmt.Sprintf("export KES_CLIENT_CERT=%s KES_CLIENT_KEY=%s", filepath.Join(dir, "enclave-admin.crt"), filepath.Join(dir, "enclave-admin.key")))
+		cli.Println()
+		cli.Println("  Keep unseal.env and the private keys secret and back them up.")
+		cli.Println("  Without the unseal key, the server data cannot be decrypted.")
+		cli.Println()
+	}
+	{
+		border := tui.NewStyle().Border(tui.RoundedBorder(), true, true, true, true).BorderForeground(tui.Color("#007700")).Width(width).Align(tui.Center)
+		bold := tui.NewStyle().Bold(true)
+		cli.Println(border.Render(fmt.Sprintf(
+			"Initialized KES %s in %s",
+			bold.Render(sys.BinaryInfo().Version),
+			bold.Render(cmd.Arg(0)),
+		)))
+	}
+}
+
+// initVault initializes a stateful KES deployment within the
+// given path. It provisions the system admin as well as the
+// enclaves, policies and identities of the init config.
+//
+// The '.init' file is written last. If initVault fails, the
+// path does not contain an '.init' file.
+func initVault(path string, config *cli.InitConfig) error {
 	if config.System.Admin.Identity.Value().IsUnknown() {
-		cli.Fatal("invalid configuration: system identity cannot be empty")
+		return errors.New("invalid configuration: system identity cannot be empty")
+	}
+	if len(config.Enclave) == 0 {
+		return errors.New("no enclave configuration specified")
 	}
 	for enclaveName, enclave := range config.Enclave {
+		if enclave.Admin.Identity.Value().IsUnknown() {
+			return fmt.Errorf("failed to create enclave '%s': no admin identity", enclaveName)
+		}
+
 		identities := map[kes.Identity]string{}
 		for policyName, policy := range enclave.Policy {
 			for _, identity := range policy.Identity {
@@ -84,21 +187,21 @@ func initCmd(args []string) {
 					continue
 				}
 				if identity.Value() == config.System.Admin.Identity.Value() {
-					cli.Fatalf("invalid policy assignment in enclave '%s': cannot assign '%s' to identity '%s': identity is equal to system admin",
+					return fmt.Errorf("invalid policy assignment in enclave '%s': cannot assign '%s' to identity '%s': identity is equal to system admin",
 						enclaveName,
 						policyName,
 						identity.Value(),
 					)
 				}
 				if identity.Value() == enclave.Admin.Identity.Value() {
-					cli.Fatalf("invalid policy assignment in enclave '%s': cannot assign '%s' to identity '%s': identity is equal to enclave admin",
+					return fmt.Errorf("invalid policy assignment in enclave '%s': cannot assign '%s' to identity '%s': identity is equal to enclave admin",
 						enclaveName,
 						policyName,
 						identity.Value(),
 					)
 				}
 				if name, ok := identities[identity.Value()]; ok {
-					cli.Fatalf(
+					return fmt.Errorf(
 						"invalid policy assignment in enclave '%s': '%s' and '%s' are assigned to identity '%v'",
 						enclaveName,
 						policyName,
@@ -111,13 +214,13 @@ func initCmd(args []string) {
 		}
 	}
 
-	if _, err = https.CertificateFromFile(config.TLS.Certificate.Value(), config.TLS.PrivateKey.Value(), config.TLS.Password.Value()); err != nil {
-		cli.Fatalf("failed to load TLS certificate: %v", err)
+	if _, err := https.CertificateFromFile(config.TLS.Certificate.Value(), config.TLS.PrivateKey.Value(), config.TLS.Password.Value()); err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
 	}
 
 	sealer, err := sys.SealFromEnvironment(config.Unseal.Environment.Name)
 	if err != nil {
-		cli.Fatalf("failed to create sealer: %v", err)
+		return fmt.Errorf("failed to create sealer: %v", err)
 	}
 	init := &fs.InitConfig{
 		Address:           config.Address,
@@ -125,31 +228,38 @@ func initCmd(args []string) {
 		Certificate:       config.TLS.Certificate,
 		Password:          config.TLS.Password,
 		VerifyClientCerts: config.TLS.Client.VerifyCerts,
+		ProxyIdentities:   config.TLS.Proxy.Identity,
+		ProxyClientCert:   config.TLS.Proxy.Header.ClientCert,
+		Leader:            config.Cluster.Leader,
+		LeaderCA:          config.Cluster.CA,
+		NodeID:            config.Cluster.ID,
+		Nodes:             config.Cluster.Nodes,
 	}
 	seal := &fs.SealConfig{
 		SysAdmin: config.System.Admin.Identity.Value(),
 		Sealer:   sealer,
 	}
-	vault, _, err := fs.Init(cmd.Arg(0), init, seal)
+	vault, _, err := fs.Init(path, seal)
 	if err != nil {
-		cli.Fatalf("failed to initialize FS Vault: %v", err)
+		return fmt.Errorf("failed to initialize FS Vault: %v", err)
 	}
 
-	if len(config.Enclave) == 0 {
-		cli.Fatal("no enclave configuration specified")
-	}
 	for name, enclave := range config.Enclave {
-		if enclave.Admin.Identity.Value().IsUnknown() {
-			cli.Fatalf("failed to create enclave '%s': no admin identity", name)
+		keyPolicy, err := key.ParseAlgorithmPolicy(enclave.KeyPolicy.DefaultAlgorithm, enclave.KeyPolicy.Algorithms, enclave.KeyPolicy.MinKeySize)
+		if err != nil {
+			return fmt.Errorf("invalid key policy of enclave '%s': %v", name, err)
 		}
-		_, err = vault.CreateEnclave(context.Background(), name, enclave.Admin.Identity.Value())
+		if keyPolicy.IsZero() {
+			keyPolicy = nil
+		}
+		_, err = vault.CreateEnclave(context.Background(), name, enclave.Admin.Identity.Value(), keyPolicy)
 		if err != nil {
-			cli.Fatalf("failed to create enclave '%s': %v", name, err)
+			return fmt.Errorf("failed to create enclave '%s': %v", name, err)
 		}
 
 		enc, err := vault.GetEnclave(context.Background(), name)
 		if err != nil {
-			cli.Fatalf("failed to init enclave '%s': %v", name, err)
+			return fmt.Errorf("failed to init enclave '%s': %v", name, err)
 		}
 		for policyName, policy := range enclave.Policy {
 			err = enc.SetPolicy(context.Background(), policyName, auth.Policy{
@@ -159,52 +269,17 @@ func initCmd(args []string) {
 				CreatedBy: config.System.Admin.Identity.Value(),
 			})
 			if err != nil {
-				cli.Fatalf("failed to init enclave '%s': failed to create policy '%s': %v", name, policyName, err)
+				return fmt.Errorf("failed to init enclave '%s': failed to create policy '%s': %v", name, policyName, err)
 			}
 			for _, identity := range policy.Identity {
 				if err = enc.AssignPolicy(context.Background(), policyName, identity.Value()); err != nil {
-					cli.Fatalf("failed to init enclave '%s': failed to assign policy '%s' to identity '%v': %v", name, policyName, identity.Value(), err)
+					return fmt.Errorf("failed to init enclave '%s': failed to assign policy '%s' to identity '%v': %v", name, policyName, identity.Value(), err)
 				}
 			}
 		}
 	}
-
-	width := 70
-	if isTerm(os.Stdout) {
-		w, _, err := term.GetSize(int(os.Stdout.Fd()))
-		if err == nil {
-			width = w - 2
-		}
-	}
-	{
-		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
-		item := tui.NewStyle().Bold(true)
-		cli.Println(header.Render("TLS:"))
-		cli.Println(item.Render("  · Private Key: "), config.TLS.PrivateKey.Value())
-		cli.Println(item.Render("  · Certificate: "), config.TLS.Certificate.Value())
-		cli.Println()
-	}
-	{
-		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
-		item := tui.NewStyle().Bold(true)
-		cli.Println(header.Render("System:"))
-		cli.Println(item.Render("  · Identity: "), config.System.Admin.Identity.Value())
-		cli.Println()
-	}
-	{
-		header := tui.NewStyle().Underline(true).Bold(true).Foreground(tui.Color("#D1BD2E"))
-		item := tui.NewStyle().Bold(true)
-		cli.Println(header.Render("Unseal:"))
-		cli.Println(item.Render("  · Environment: "), config.Unseal.Environment.Name)
-		cli.Println()
-	}
-	{
-		border := tui.NewStyle().Border(tui.RoundedBorder(), true, true, true, true).BorderForeground(tui.Color("#007700")).Width(width).Align(tui.Center)
-		bold := tui.NewStyle().Bold(true)
-		cli.Println(border.Render(fmt.Sprintf(
-			"Initialized KES %s in %s",
-			bold.Render(sys.BinaryInfo().Version),
-			bold.Render(cmd.Arg(0)),
-		)))
+	if err = fs.WriteInitConfig(filepath.Join(path, ".init"), init); err != nil {
+		return fmt.Errorf("failed to write init config: %v", err)
 	}
+	return nil
 }
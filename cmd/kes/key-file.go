@@ -0,0 +1,233 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const encryptFileCmdUsage = `Usage:
+    kes key encrypt-file [options] <name> [<file>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -o, --out <path>         Write the encrypted data to <path> instead of
+                             standard output.
+
+    -h, --help               Print command line options.
+
+Encrypts the content of <file>, or standard input if no <file> or '-'
+is specified, using envelope encryption. A new data encryption key is
+generated with the key <name> for every file. The data is encrypted
+locally and the data encryption key, encrypted with <name>, is stored
+in the header of the encrypted data. Hence, the encrypted data can
+only be decrypted with 'kes key decrypt-file' as long as the key <name>
+exists.
+
+Examples:
+    $ kes key encrypt-file my-key config.yml -o config.yml.enc
+    $ tar -cz ./data | kes key encrypt-file my-key > data.tar.gz.enc
+`
+
+func encryptFileCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, encryptFileCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		outPath            string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.StringVarP(&outPath, "out", "o", "", "Write the encrypted data to the file")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key encrypt-file --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key encrypt-file --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key encrypt-file --help'")
+	}
+	if outPath == "" && isTerm(os.Stdout) {
+		cli.Fatal("refusing to write encrypted data to a terminal. See 'kes key encrypt-file --help'")
+	}
+
+	name := cmd.Arg(0)
+	in, err := openInput(cmd.Arg(1))
+	if err != nil {
+		cli.Fatal(err)
+	}
+	defer in.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	dek, err := enclave.GenerateKey(ctx, name, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to generate data encryption key: %v", err)
+	}
+	header := kesclient.StreamHeader{
+		KeyName:    name,
+		WrappedKey: dek.Ciphertext,
+	}
+	err = writeOutput(outPath, 0o644, func(out io.Writer) error {
+		_, err := kesclient.EncryptStream(out, in, header, dek.Plaintext)
+		return err
+	})
+	if err != nil {
+		cli.Fatalf("failed to encrypt data: %v", err)
+	}
+}
+
+const decryptFileCmdUsage = `Usage:
+    kes key decrypt-file [options] [<file>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+    -o, --out <path>         Write the decrypted data to <path> instead of
+                             standard output.
+
+    -h, --help               Print command line options.
+
+Decrypts the content of <file>, or standard input if no <file> or '-'
+is specified, that has been encrypted with 'kes key encrypt-file'.
+The data encryption key is decrypted with the key whose name is stored
+in the header of the encrypted data.
+
+When writing to standard output, decrypted data is written as soon as
+it has been verified. If the encrypted data turns out to be modified
+or truncated, the command fails and all data written so far must be
+discarded. With --out, the file is only created once all data has been
+decrypted successfully.
+
+Examples:
+    $ kes key decrypt-file config.yml.enc -o config.yml
+    $ kes key decrypt-file < data.tar.gz.enc | tar -xz
+`
+
+func decryptFileCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, decryptFileCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		outPath            string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.StringVarP(&outPath, "out", "o", "", "Write the decrypted data to the file")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key decrypt-file --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes key decrypt-file --help'")
+	}
+
+	in, err := openInput(cmd.Arg(0))
+	if err != nil {
+		cli.Fatal(err)
+	}
+	defer in.Close()
+
+	header, err := kesclient.ReadStreamHeader(in)
+	if err != nil {
+		if errors.Is(err, kesclient.ErrStreamFormat) {
+			cli.Fatal("failed to decrypt data: not encrypted with 'kes key encrypt-file'")
+		}
+		cli.Fatalf("failed to decrypt data: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	key, err := enclave.Decrypt(ctx, header.KeyName, header.WrappedKey, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to decrypt data encryption key with '%s': %v", header.KeyName, err)
+	}
+	err = writeOutput(outPath, 0o600, func(out io.Writer) error {
+		_, err := kesclient.DecryptStream(out, in, header, key)
+		return err
+	})
+	if err != nil {
+		cli.Fatalf("failed to decrypt data: %v", err)
+	}
+}
+
+// openInput opens the named file, or returns
+// standard input if name is empty or "-".
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open '%s': %v", name, err)
+	}
+	return f, nil
+}
+
+// writeOutput calls write with standard output if
+// path is empty. Otherwise, it calls write with a
+// temporary file that replaces the file at path
+// once write returns without an error. Hence, path
+// either contains the complete output or remains
+// unchanged.
+func writeOutput(path string, perm os.FileMode, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err = write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -14,10 +15,12 @@ import (
 	"os/signal"
 	"sort"
 	"strings"
+	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
 	flag "github.com/spf13/pflag"
 )
 
@@ -27,13 +30,26 @@ const keyCmdUsage = `Usage:
 Commands:
     create                   Create a new crypto key.
     import                   Import a crypto key.
+    register                 Register a key held by an external KMS.
     info                     Get information about a crypto key. 
     ls                       List crypto keys.
     rm                       Delete a crypto key.
+    verify                   Verify the integrity of a crypto key.
+    hold                     Place a legal hold on a crypto key.
+    release                  Release the legal hold of a crypto key.
+    disable                  Disable a crypto key.
+    enable                   Enable a disabled crypto key.
+    allowlist                Restrict the identities that can use a crypto key.
 
     encrypt                  Encrypt a message.
     decrypt                  Decrypt an encrypted message.
     dek                      Generate a new data encryption key.
+    hash                     Compute keyed hashes of messages.
+    fpe-encrypt              Encrypt a value preserving its format.
+    fpe-decrypt              Decrypt a format-preserving ciphertext.
+    agree                    Perform an ECDH key agreement.
+    encrypt-file             Encrypt a file.
+    decrypt-file             Decrypt an encrypted file.
 
 Options:
     -h, --help               Print command line options.
@@ -44,15 +60,32 @@ func keyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, keyCmdUsage) }
 
 	subCmds := commands{
-		"create": createKeyCmd,
-		"import": importKeyCmd,
-		"info":   describeKeyCmd,
-		"ls":     lsKeyCmd,
-		"rm":     rmKeyCmd,
+		"create":   createKeyCmd,
+		"import":   importKeyCmd,
+		"register": registerKeyCmd,
+		"info":     describeKeyCmd,
+		"ls":       lsKeyCmd,
+		"rm":       rmKeyCmd,
+		"verify":   verifyKeyCmd,
+
+		"hold":    holdKeyCmd,
+		"release": releaseKeyCmd,
+		"disable": disableKeyCmd,
+		"enable":  enableKeyCmd,
+
+		"allowlist": allowlistKeyCmd,
 
 		"encrypt": encryptKeyCmd,
 		"decrypt": decryptKeyCmd,
 		"dek":     dekCmd,
+		"hash":    hashKeyCmd,
+
+		"fpe-encrypt": fpeEncryptKeyCmd,
+		"fpe-decrypt": fpeDecryptKeyCmd,
+		"agree":       agreeKeyCmd,
+
+		"encrypt-file": encryptFileCmd,
+		"decrypt-file": decryptFileCmd,
 	}
 
 	if len(args) < 2 {
@@ -83,12 +116,28 @@ const createKeyCmdUsage = `Usage:
 Options:
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
+    -a, --algorithm <alg>    Create keys for the given algorithm:
+                               AES256-GCM_SHA256
+                               XCHACHA20-POLY1305
+                               AES256-GCM-SIV
+                             Defaults to the server's choice. In FIPS mode,
+                             only AES256-GCM_SHA256 is supported.
+        --retention <DUR>    Make the keys immutable for the given retention
+                             period, e.g. 8760h. An immutable key cannot be
+                             deleted, not even by an admin.
 
     -h, --help               Print command line options.
 
+AES256-GCM-SIV keys are resistant against nonce reuse. XCHACHA20-POLY1305 keys
+use long random nonces and are fast on CPUs without AES instructions. The
+algorithm of a key cannot be changed. Ciphertexts can only be decrypted with
+keys of the same algorithm.
+
 Examples:
     $ kes key create my-key
     $ kes key create my-key1 my-key2
+    $ kes key create --algorithm AES256-GCM-SIV my-key
+    $ kes key create --retention 8760h my-key
 `
 
 func createKeyCmd(args []string) {
@@ -98,9 +147,13 @@ func createKeyCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		algorithm          string
+		retention          time.Duration
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.StringVarP(&algorithm, "algorithm", "a", "", "Create keys for the given algorithm")
+	cmd.DurationVar(&retention, "retention", 0, "Make the keys immutable for the given retention period")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -111,13 +164,23 @@ func createKeyCmd(args []string) {
 	if cmd.NArg() == 0 {
 		cli.Fatal("no key name specified. See 'kes key create --help'")
 	}
+	if retention < 0 {
+		cli.Fatal("invalid retention period: must not be negative. See 'kes key create --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
 
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	client := newClient(insecureSkipVerify)
+	opts := &kesclient.CreateKeyOptions{
+		Algorithm: algorithm,
+		Retention: retention,
+	}
 	for _, name := range cmd.Args() {
-		if err := enclave.CreateKey(ctx, name); err != nil {
+		if err := kesclient.CreateKey(ctx, client, enclaveName, name, opts); err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
 			}
@@ -182,6 +245,66 @@ func importKeyCmd(args []string) {
 	}
 }
 
+const registerKeyCmdUsage = `Usage:
+    kes key register [options] <name> <kms> <key-id>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key register my-key aws arn:aws:kms:us-east-1:123456789012:key/my-key
+`
+
+func registerKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, registerKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key register --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key register --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no external KMS specified. See 'kes key register --help'")
+	case cmd.NArg() == 2:
+		cli.Fatal("no external key ID specified. See 'kes key register --help'")
+	case cmd.NArg() > 3:
+		cli.Fatal("too many arguments. See 'kes key register --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+	name := cmd.Arg(0)
+	ext := kesclient.ExternalKey{
+		KMS:   cmd.Arg(1),
+		KeyID: cmd.Arg(2),
+	}
+	if err := kesclient.RegisterKey(ctx, newClient(insecureSkipVerify), enclaveName, name, ext); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to register %q: %v", name, err)
+	}
+}
+
 const describeKeyCmdUsage = `Usage:
     kes key info [options] <name>
 
@@ -232,9 +355,11 @@ func describeKeyCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 	name := cmd.Arg(0)
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	info, err := enclave.DescribeKey(ctx, name)
+	info, err := kesclient.DescribeKey(ctx, newClient(insecureSkipVerify), enclaveName, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
@@ -267,12 +392,24 @@ func describeKeyCmd(args []string) {
 			info.ID,
 		)
 	}
-	if info.Algorithm != kes.KeyAlgorithmUndefined {
+	if info.Algorithm != "" {
 		fmt.Println(
 			faint.Render(fmt.Sprintf("%-11s", "Algorithm")),
 			info.Algorithm,
 		)
 	}
+	if info.External != nil {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "External")),
+			info.External.KMS+": "+info.External.KeyID,
+		)
+	}
+	if info.Fingerprint != "" {
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Fingerprint")),
+			info.Fingerprint,
+		)
+	}
 	fmt.Println(
 		faint.Render(fmt.Sprintf("%-11s", "Created At")),
 		fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec),
@@ -293,9 +430,15 @@ func describeKeyCmd(args []string) {
 const lsKeyCmdUsage = `Usage:
     kes key ls [options] [<pattern>]
 
+A pattern ending with a '/', like 'tenants/acme/', lists all keys
+with this prefix - including keys within further segments, like
+'tenants/acme/dev/my-key'. Otherwise, a '*' does not match a '/'.
+
 Options:
     -k, --insecure           Skip TLS certificate validation.
-        --json               Print keys in JSON format. 
+        --json               Print keys in JSON format. Same as '--output json'.
+    -o, --output <format>    Print output in the given format: table, json or
+                             yaml. Defaults to $KES_OUTPUT or table.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -308,6 +451,7 @@ Options:
 Examples:
     $ kes key ls
     $ kes key ls 'my-key*'
+    $ kes key ls tenants/acme/
 `
 
 func lsKeyCmd(args []string) {
@@ -321,6 +465,7 @@ func lsKeyCmd(args []string) {
 		enclaveName        string
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.VarP(&outputFlag, "output", "o", "Print output in the given format")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
@@ -330,6 +475,9 @@ func lsKeyCmd(args []string) {
 		}
 		cli.Fatalf("%v. See 'kes key ls --help'", err)
 	}
+	if err := outputFlag.Parse(jsonFlag); err != nil {
+		cli.Fatalf("%v. See 'kes key ls --help'", err)
+	}
 
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes key ls --help'")
@@ -343,36 +491,51 @@ func lsKeyCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	iterator, err := enclave.ListKeys(ctx, pattern)
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+	iter, err := kesclient.ListKeys(ctx, newClient(insecureSkipVerify), enclaveName, pattern)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
 		cli.Fatalf("failed to list keys: %v", err)
 	}
-	defer iterator.Close()
+	defer iter.Close()
 
-	if jsonFlag {
-		if _, err = iterator.WriteTo(os.Stdout); err != nil {
-			cli.Fatal(err)
+	var keys []kesclient.KeyInfo
+	for iter.Next() {
+		keys = append(keys, iter.Value())
+	}
+	if err = iter.Close(); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
 		}
-		if err = iterator.Close(); err != nil {
-			cli.Fatal(err)
+		cli.Fatalf("failed to list keys: %v", err)
+	}
+
+	if outputFlag.Format() == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, key := range keys {
+			if err = encoder.Encode(key); err != nil {
+				cli.Fatal(err)
+			}
 		}
 	} else {
-		keys, err := iterator.Values(0)
-		if err != nil {
-			cli.Fatalf("failed to list keys: %v", err)
-		}
-		if err = iterator.Close(); err != nil {
-			cli.Fatalf("failed to list keys: %v", err)
-		}
+		sort.Slice(keys, func(i, j int) bool {
+			return strings.Compare(keys[i].Name, keys[j].Name) < 0
+		})
 
+		if !outputFlag.IsTable() {
+			if keys == nil {
+				keys = []kesclient.KeyInfo{}
+			}
+			if err = outputFlag.Encode(os.Stdout, keys); err != nil {
+				cli.Fatal(err)
+			}
+			return
+		}
 		if len(keys) > 0 {
-			sort.Slice(keys, func(i, j int) bool {
-				return strings.Compare(keys[i].Name, keys[j].Name) < 0
-			})
 
 			headerStyle := tui.NewStyle()
 			dateStyle := tui.NewStyle()
@@ -404,16 +567,34 @@ func lsKeyCmd(args []string) {
 
 const rmKeyCmdUsage = `Usage:
     kes key rm [options] <name>...
+    kes key rm [options] --pattern <pattern>
 
 Options:
+        --pattern <pattern>  Remove all keys that match the glob pattern.
+        --dry-run            Only show which keys would be removed.
+    -y, --yes                Remove the keys without asking for confirmation.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
     -k, --insecure           Skip X.509 certificate validation during TLS handshake.
     -e, --enclave <name>     Operate within the specified enclave.
 
     -h, --help               Show list of command-line options.
 
+With --pattern, the command first shows which keys match the pattern
+and would be removed. Immutable keys, keys under legal hold and keys
+the identity is not allowed to delete are shown but not removed. After
+confirmation, the command removes the keys unless the matching keys
+have changed in the meantime. It exits with a non-zero exit code if
+any matching key could not be removed.
+
 Examples:
     $ kes key rm my-key
     $ kes key rm my-key1 my-key2
+    $ kes key rm --pattern 'tmp-*' --dry-run
+    $ kes key rm --pattern 'tmp-*' --yes
 `
 
 func rmKeyCmd(args []string) {
@@ -421,9 +602,17 @@ func rmKeyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmKeyCmdUsage) }
 
 	var (
+		pattern            string
+		dryRunFlag         bool
+		yesFlag            bool
+		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.StringVar(&pattern, "pattern", "", "Remove all keys that match the glob pattern")
+	cmd.BoolVar(&dryRunFlag, "dry-run", false, "Only show which keys would be removed")
+	cmd.BoolVarP(&yesFlag, "yes", "y", false, "Remove the keys without asking for confirmation")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
@@ -432,13 +621,33 @@ func rmKeyCmd(args []string) {
 		}
 		cli.Fatalf("%v. See 'kes key rm --help'", err)
 	}
-	if cmd.NArg() == 0 {
-		cli.Fatal("no key name specified. See 'kes key rm --help'")
+	if cmd.Changed("pattern") {
+		if cmd.NArg() > 0 {
+			cli.Fatal("'--pattern' cannot be used with key names. See 'kes key rm --help'")
+		}
+		if pattern == "" {
+			cli.Fatal("no pattern specified. See 'kes key rm --help'")
+		}
+	} else {
+		if dryRunFlag || yesFlag {
+			cli.Fatal("'--dry-run' and '--yes' require '--pattern'. See 'kes key rm --help'")
+		}
+		if cmd.NArg() == 0 {
+			cli.Fatal("no key name specified. See 'kes key rm --help'")
+		}
 	}
 
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	if pattern != "" {
+		if enclaveName == "" {
+			enclaveName = os.Getenv("KES_ENCLAVE")
+		}
+		rmKeyPattern(ctx, newClient(insecureSkipVerify), enclaveName, pattern, dryRunFlag, yesFlag, colorFlag.Colorize())
+		return
+	}
+
 	enclave := newEnclave(enclaveName, insecureSkipVerify)
 	for _, name := range cmd.Args() {
 		if err := enclave.DeleteKey(ctx, name); err != nil {
@@ -450,17 +659,391 @@ func rmKeyCmd(args []string) {
 	}
 }
 
+// rmKeyPattern removes all keys that match the pattern.
+// It shows which keys would be removed first and, unless
+// dryRun is true, removes them after confirmation.
+func rmKeyPattern(ctx context.Context, client *kes.Client, enclave, pattern string, dryRun, yes, colorize bool) {
+	var okStyle, errStyle tui.Style
+	if colorize {
+		const (
+			ColorOK  tui.Color = "#00a700"
+			ColorErr tui.Color = "#ac0000"
+		)
+		okStyle = okStyle.Foreground(ColorOK)
+		errStyle = errStyle.Foreground(ColorErr)
+	}
+	printResults := func(result *kesclient.DeleteKeysResult, status string) (failed int) {
+		for _, key := range result.Keys {
+			switch {
+			case key.Err != "":
+				failed++
+				fmt.Printf("%-30s %s\n", key.Name, errStyle.Render(key.Err))
+			case key.Deleted || result.DryRun:
+				fmt.Printf("%-30s %s  %s\n", key.Name, okStyle.Render(fmt.Sprintf("%-16s", status)), key.Fingerprint)
+			}
+		}
+		return failed
+	}
+
+	preview, err := kesclient.PreviewDeleteKeys(ctx, client, enclave, pattern)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list keys matching '%s': %v", pattern, err)
+	}
+	if len(preview.Keys) == 0 {
+		cli.Printf("No keys match '%s'.\n", pattern)
+		return
+	}
+	failed := printResults(preview, "would be removed")
+	if dryRun {
+		return
+	}
+	if failed == len(preview.Keys) {
+		os.Exit(1)
+	}
+
+	question := fmt.Sprintf("Remove %d keys matching '%s'?", len(preview.Keys)-failed, pattern)
+	if !yes && !confirm(bufio.NewReader(os.Stdin), question) {
+		cli.Println("No keys removed.")
+		return
+	}
+	result, err := kesclient.DeleteKeys(ctx, client, enclave, pattern, preview.Confirm)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		if kesclient.Code(err) == kesclient.CodePreconditionFailed {
+			cli.Fatalf("keys matching '%s' have changed since the preview. No keys removed. Run 'kes key rm --pattern' again", pattern)
+		}
+		cli.Fatalf("failed to remove keys matching '%s': %v", pattern, err)
+	}
+	fmt.Println()
+	if printResults(result, "removed") > 0 {
+		os.Exit(1)
+	}
+}
+
+const holdKeyCmdUsage = `Usage:
+    kes key hold [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Places a legal hold on the keys. A key under legal hold cannot be
+deleted until the hold is released with 'kes key release'.
+
+Examples:
+    $ kes key hold my-key
+`
+
+func holdKeyCmd(args []string) {
+	keyStateCmd(args, holdKeyCmdUsage, "hold", kesclient.HoldKey)
+}
+
+const releaseKeyCmdUsage = `Usage:
+    kes key release [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Releases the legal hold placed on the keys.
+
+Examples:
+    $ kes key release my-key
+`
+
+func releaseKeyCmd(args []string) {
+	keyStateCmd(args, releaseKeyCmdUsage, "release", kesclient.ReleaseKey)
+}
+
+const disableKeyCmdUsage = `Usage:
+    kes key disable [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Disables the keys without deleting them. A disabled key cannot be
+used for cryptographic operations until it is enabled again with
+'kes key enable', e.g. after an incident investigation.
+
+Examples:
+    $ kes key disable my-key
+`
+
+func disableKeyCmd(args []string) {
+	keyStateCmd(args, disableKeyCmdUsage, "disable", kesclient.DisableKey)
+}
+
+const enableKeyCmdUsage = `Usage:
+    kes key enable [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Enables the disabled keys.
+
+Examples:
+    $ kes key enable my-key
+`
+
+func enableKeyCmd(args []string) {
+	keyStateCmd(args, enableKeyCmdUsage, "enable", kesclient.EnableKey)
+}
+
+const allowlistKeyCmdUsage = `Usage:
+    kes key allowlist [options] <name>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --identity <id>      Allow the identity to use the key. May be
+                             specified multiple times.
+        --policy <name>      Allow all identities assigned to the policy to
+                             use the key. May be specified multiple times.
+        --clear              Remove the allowlist of the key.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Restricts the identities that can use the key for cryptographic operations.
+The allowlist is checked in addition to the policy of the requesting identity.
+An identity can only use the key if it is on the allowlist or assigned to
+one of its policies. Setting an allowlist replaces the previous one.
+
+Examples:
+    $ kes key allowlist --identity 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22 my-key
+    $ kes key allowlist --policy my-app my-key
+    $ kes key allowlist --clear my-key
+`
+
+func allowlistKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, allowlistKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		identities         []string
+		policies           []string
+		clearAllowlist     bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringSliceVar(&identities, "identity", []string{}, "Allow the identity to use the key")
+	cmd.StringSliceVar(&policies, "policy", []string{}, "Allow all identities assigned to the policy to use the key")
+	cmd.BoolVar(&clearAllowlist, "clear", false, "Remove the allowlist of the key")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key allowlist --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key allowlist --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key allowlist --help'")
+	case clearAllowlist && (len(identities) > 0 || len(policies) > 0):
+		cli.Fatal("'--clear' cannot be combined with '--identity' or '--policy'. See 'kes key allowlist --help'")
+	case !clearAllowlist && len(identities) == 0 && len(policies) == 0:
+		cli.Fatal("no identity or policy specified. See 'kes key allowlist --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ids := make([]kes.Identity, 0, len(identities))
+	for _, id := range identities {
+		ids = append(ids, kes.Identity(id))
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	client := newClient(insecureSkipVerify)
+	if err := kesclient.SetKeyAllowlist(ctx, client, enclaveName, name, ids, policies); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to set allowlist of key %q: %v", name, err)
+	}
+}
+
+// keyStateCmd changes the state of the keys specified by
+// args, e.g. places a legal hold on them, using the given
+// kesclient function.
+func keyStateCmd(args []string, usage, command string, fn func(context.Context, *kes.Client, string, string) error) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key %s --help'", err, command)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatalf("no key name specified. See 'kes key %s --help'", command)
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	for _, name := range cmd.Args() {
+		if err := fn(ctx, client, enclaveName, name); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to %s key %q: %v", command, name, err)
+		}
+	}
+}
+
+const verifyKeyCmdUsage = `Usage:
+    kes key verify [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the key checks in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Verifies the integrity of stored keys. The server reads each key from
+the key store, bypassing its key cache, and compares the key check value
+(KCV) of the key material with the check value stored along with the key.
+
+A key is either intact, corrupted or unverified. Keys created by servers
+without support for key check values are unverified. The command exits
+with a non-zero exit code if any key is corrupted.
+
+Examples:
+    $ kes key verify my-key
+    $ kes key verify my-key1 my-key2
+`
+
+func verifyKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, verifyKeyCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the key checks in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key verify --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes key verify --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	var okStyle, errStyle tui.Style
+	if colorFlag.Colorize() {
+		const (
+			ColorOK  tui.Color = "#00a700"
+			ColorErr tui.Color = "#ac0000"
+		)
+		okStyle = okStyle.Foreground(ColorOK)
+		errStyle = errStyle.Foreground(ColorErr)
+	}
+
+	client := newClient(insecureSkipVerify)
+	encoder := json.NewEncoder(os.Stdout)
+	var corrupted bool
+	for _, name := range cmd.Args() {
+		check, err := kesclient.VerifyKey(ctx, client, enclaveName, name)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to verify key %q: %v", name, err)
+		}
+		if check.Status == kesclient.KeyCorrupted {
+			corrupted = true
+		}
+
+		if jsonFlag {
+			if err = encoder.Encode(check); err != nil {
+				cli.Fatal(err)
+			}
+			continue
+		}
+		switch check.Status {
+		case kesclient.KeyIntact:
+			fmt.Printf("%-30s %-12s KCV %s\n", check.Name, okStyle.Render(fmt.Sprintf("%-10s", check.Status)), check.CheckValue)
+		case kesclient.KeyCorrupted:
+			fmt.Printf("%-30s %-12s KCV %s - stored KCV %s\n", check.Name, errStyle.Render(fmt.Sprintf("%-10s", check.Status)), check.CheckValue, check.StoredCheckValue)
+		default:
+			fmt.Printf("%-30s %-12s KCV %s\n", check.Name, fmt.Sprintf("%-10s", check.Status), check.CheckValue)
+		}
+	}
+	if corrupted {
+		os.Exit(1)
+	}
+}
+
 const encryptKeyCmdUsage = `Usage:
     kes key encrypt [options] <name> <message>
 
 Options:
+        --envelope           Return a versioned ciphertext envelope.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
     -h, --help               Print command line options.
 
+With --envelope, the ciphertext is a versioned envelope that contains the
+key name, key ID, algorithm, nonce and a hash of the associated data. It is
+a MessagePack map that can be parsed in any language. Servers decrypt both,
+envelopes and default ciphertexts.
+
 Examples:
     $ kes key encrypt my-key "Hello World"
+    $ kes key encrypt --envelope my-key "Hello World"
 `
 
 func encryptKeyCmd(args []string) {
@@ -468,9 +1051,11 @@ func encryptKeyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprintf(os.Stderr, encryptKeyCmdUsage) }
 
 	var (
+		envelope           bool
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.BoolVar(&envelope, "envelope", false, "Return a versioned ciphertext envelope")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
@@ -495,8 +1080,18 @@ func encryptKeyCmd(args []string) {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
 
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	ciphertext, err := enclave.Encrypt(ctx, name, []byte(message), nil)
+	var (
+		ciphertext []byte
+		err        error
+	)
+	if envelope {
+		if enclaveName == "" {
+			enclaveName = os.Getenv("KES_ENCLAVE")
+		}
+		ciphertext, err = kesclient.EncryptEnvelope(ctx, newClient(insecureSkipVerify), enclaveName, name, []byte(message), nil)
+	} else {
+		ciphertext, err = newEnclave(enclaveName, insecureSkipVerify).Encrypt(ctx, name, []byte(message), nil)
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
@@ -511,6 +1106,84 @@ func encryptKeyCmd(args []string) {
 	}
 }
 
+const hashKeyCmdUsage = `Usage:
+    kes key hash [options] <name> <message>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Computes the HMAC-SHA256 of each message with a hashing key derived from
+the named key and prints the base64-encoded hashes in the order of the
+messages. The same key and message always produce the same hash, e.g. to
+look up or deduplicate values without storing them in plaintext.
+
+Examples:
+    $ kes key hash my-key "alice@example.com" "bob@example.com"
+`
+
+func hashKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, hashKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key hash --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key hash --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no message specified. See 'kes key hash --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	name := cmd.Arg(0)
+	messages := make([][]byte, 0, cmd.NArg()-1)
+	for _, message := range cmd.Args()[1:] {
+		messages = append(messages, []byte(message))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	hashes, err := kesclient.HashKey(ctx, newClient(insecureSkipVerify), enclaveName, name, messages)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to hash messages: %v", err)
+	}
+
+	if isTerm(os.Stdout) {
+		for _, hash := range hashes {
+			fmt.Println(base64.StdEncoding.EncodeToString(hash))
+		}
+	} else {
+		type Hash struct {
+			Hash []byte `json:"hash"`
+		}
+		response := make([]Hash, 0, len(hashes))
+		for _, hash := range hashes {
+			response = append(response, Hash{Hash: hash})
+		}
+		json.NewEncoder(os.Stdout).Encode(response)
+	}
+}
+
 const decryptKeyCmdUsage = `Usage:
     kes key decrypt [options] <name> <ciphertext> [<context>]
 
@@ -588,6 +1261,7 @@ const dekCmdUsage = `Usage:
     kes key dek <name> [<context>]
 
 Options:
+        --envelope           Return the encrypted key as ciphertext envelope.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
 
@@ -595,6 +1269,7 @@ Options:
 
 Examples:
     $ kes key dek my-key
+    $ kes key dek --envelope my-key
 `
 
 func dekCmd(args []string) {
@@ -602,9 +1277,11 @@ func dekCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, dekCmdUsage) }
 
 	var (
+		envelope           bool
 		insecureSkipVerify bool
 		enclaveName        string
 	)
+	cmd.BoolVar(&envelope, "envelope", false, "Return the encrypted key as versioned ciphertext envelope")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
@@ -634,8 +1311,18 @@ func dekCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	key, err := enclave.GenerateKey(ctx, name, associatedData)
+	var (
+		key kes.DEK
+		err error
+	)
+	if envelope {
+		if enclaveName == "" {
+			enclaveName = os.Getenv("KES_ENCLAVE")
+		}
+		key, err = kesclient.GenerateKeyEnvelope(ctx, newClient(insecureSkipVerify), enclaveName, name, associatedData)
+	} else {
+		key, err = newEnclave(enclaveName, insecureSkipVerify).GenerateKey(ctx, name, associatedData)
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
@@ -6,8 +6,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,6 +20,7 @@ import (
 	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
 
 	flag "github.com/spf13/pflag"
 )
@@ -27,14 +31,38 @@ const logCmdUsage = `Usage:
 Options:
     --audit                  Print audit logs. (default)
     --error                  Print error logs.
+    --security               Print security events, like auth failures,
+                             denied requests and admin actions.
     --json                   Print log events as JSON.
+    --level <level>          Set the level of the server's error log instead
+                             of printing log events. Valid levels are: debug,
+                             info, warn and error.
+
+    --identity <identity>    Only print audit events of this identity.
+    --path <prefix>          Only print audit events of API paths with this
+                             prefix, e.g. '/v1/key/'.
+    --status <code>          Only print audit events with this status code,
+                             e.g. '403', or status class, e.g. '4xx'.
+    -e, --enclave <name>     Only print audit events of this enclave.
 
     -k, --insecure           Skip TLS certificate validation.
     -h, --help               Print command line options.
 
+The audit event filters are applied by the server. It only sends
+events that match all specified filters. They cannot be applied to
+security events.
+
+The server only logs errors with a level equal to or higher than its
+log level. The default level is info. At the debug level, it logs every
+request with its request ID, enclave and identity. A log level set with
+--level is reset once the server restarts or reloads its config.
+
 Examples:
     $ kes log
     $ kes log --error
+    $ kes log --security --json
+    $ kes log --enclave tenant-1 --status 4xx
+    $ kes log --level debug
 `
 
 func logCmd(args []string) {
@@ -44,12 +72,22 @@ func logCmd(args []string) {
 	var (
 		auditFlag          bool
 		errorFlag          bool
+		securityFlag       bool
 		jsonFlag           bool
+		identityFlag       string
+		levelFlag          string
+		filter             kesclient.AuditFilter
 		insecureSkipVerify bool
 	)
 	cmd.BoolVar(&auditFlag, "audit", true, "Print audit logs")
 	cmd.BoolVar(&errorFlag, "error", false, "Print error logs")
+	cmd.BoolVar(&securityFlag, "security", false, "Print security events")
 	cmd.BoolVar(&jsonFlag, "json", false, "Print log events as JSON")
+	cmd.StringVar(&levelFlag, "level", "", "Set the level of the server's error log")
+	cmd.StringVar(&identityFlag, "identity", "", "Only print audit events of this identity")
+	cmd.StringVar(&filter.Path, "path", "", "Only print audit events of API paths with this prefix")
+	cmd.StringVar(&filter.Status, "status", "", "Only print audit events with this status code or class")
+	cmd.StringVarP(&filter.Enclave, "enclave", "e", "", "Only print audit events of this enclave")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -60,20 +98,38 @@ func logCmd(args []string) {
 	if cmd.NArg() > 0 {
 		cli.Fatal("too many arguments. See 'kes key import --help'")
 	}
-	if auditFlag && errorFlag && cmd.Changed("audit") {
-		cli.Fatal("cannot display audit and error logs at the same time")
+	if errorFlag && securityFlag {
+		cli.Fatal("cannot display error logs and security events at the same time")
+	}
+	if auditFlag && (errorFlag || securityFlag) && cmd.Changed("audit") {
+		cli.Fatal("cannot display audit and error logs or security events at the same time")
 	}
-	if auditFlag && errorFlag { // Unset (default) audit flag if error flag has been set
+	if auditFlag && (errorFlag || securityFlag) { // Unset (default) audit flag if error or security flag has been set
 		auditFlag = !auditFlag
 	}
+	filter.Identity = kes.Identity(identityFlag)
+	if errorFlag && filter != (kesclient.AuditFilter{}) {
+		cli.Fatal("audit event filters cannot be applied to error logs")
+	}
+	if securityFlag && filter != (kesclient.AuditFilter{}) {
+		cli.Fatal("audit event filters cannot be applied to security events")
+	}
 
 	client := newClient(insecureSkipVerify)
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	if cmd.Changed("level") {
+		if cmd.Changed("audit") || cmd.Changed("error") || cmd.Changed("security") || cmd.Changed("json") || filter != (kesclient.AuditFilter{}) {
+			cli.Fatal("cannot set the log level and print log events at the same time")
+		}
+		setLogLevel(ctx, client, levelFlag)
+		return
+	}
+
 	switch {
 	case auditFlag:
-		stream, err := client.AuditLog(ctx)
+		stream, err := kesclient.AuditLog(ctx, client, filter)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
@@ -106,6 +162,23 @@ func logCmd(args []string) {
 		} else {
 			printErrorLog(stream)
 		}
+	case securityFlag:
+		stream, err := kesclient.SecurityLog(ctx, client)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to connect to security log: %v", err)
+		}
+		defer stream.Close()
+
+		if jsonFlag {
+			if _, err = io.Copy(os.Stdout, stream); err != nil && !errors.Is(err, context.Canceled) {
+				cli.Fatal(err)
+			}
+		} else {
+			printSecurityLog(stream)
+		}
 	default:
 		cmd.Usage()
 		os.Exit(2)
@@ -175,6 +248,95 @@ func printAuditLog(stream *kes.AuditStream) {
 	}
 }
 
+func printSecurityLog(stream io.Reader) {
+	typeStyle := tui.NewStyle().Foreground(tui.Color("#ff0000")).Width(12)
+	const (
+		header = "Time        Type            Identity                IP                 Details"
+		format = "%02d:%02d:%02d    %s    %-20.20s    %-15s    %s\n"
+	)
+
+	if isTerm(os.Stdout) {
+		fmt.Println(tui.NewStyle().Bold(true).Underline(true).Render(header))
+	} else {
+		fmt.Println(header)
+	}
+	decoder := json.NewDecoder(stream)
+	for {
+		var event struct {
+			Timestamp time.Time `json:"time"`
+			Security  string    `json:"security"`
+			Request   *struct {
+				IP       net.IP       `json:"ip"`
+				APIPath  string       `json:"path"`
+				Identity kes.Identity `json:"identity"`
+			} `json:"request"`
+			Response struct {
+				StatusCode int `json:"code"`
+			} `json:"response"`
+			AuthFailure *struct {
+				IP       net.IP       `json:"ip"`
+				Identity kes.Identity `json:"identity"`
+				Reason   string       `json:"reason"`
+			} `json:"auth_failure"`
+			Config *struct {
+				Fingerprint string `json:"fingerprint"`
+			} `json:"config"`
+		}
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+				return
+			}
+			cli.Fatal(err)
+		}
+
+		var (
+			identity kes.Identity
+			ip       net.IP
+			details  string
+		)
+		switch {
+		case event.AuthFailure != nil:
+			identity, ip, details = event.AuthFailure.Identity, event.AuthFailure.IP, "TLS handshake: "+event.AuthFailure.Reason
+		case event.Config != nil:
+			details = "config reloaded: " + event.Config.Fingerprint
+		case event.Request != nil:
+			identity, ip = event.Request.Identity, event.Request.IP
+			details = strconv.Itoa(event.Response.StatusCode) + " " + event.Request.APIPath
+		}
+		ipAddr := "<unknown>"
+		if len(ip) > 0 {
+			ipAddr = ip.String()
+		}
+
+		hour, min, sec := event.Timestamp.Clock()
+		fmt.Printf(format, hour, min, sec, typeStyle.Render(event.Security), identity, ipAddr, details)
+	}
+}
+
+func setLogLevel(ctx context.Context, client *kes.Client, level string) {
+	oldLevel, err := kesclient.LogLevel(ctx, client)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to set log level: %v", err)
+	}
+	if err = kesclient.SetLogLevel(ctx, client, level); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to set log level: %v", err)
+	}
+	newLevel, err := kesclient.LogLevel(ctx, client)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to set log level: %v", err)
+	}
+	fmt.Printf("Changed log level from %s to %s\n", oldLevel, newLevel)
+}
+
 func printErrorLog(stream *kes.ErrorStream) {
 	for stream.Next() {
 		fmt.Println(stream.Event().Message)
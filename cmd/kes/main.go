@@ -18,6 +18,7 @@ import (
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kesclient"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -36,15 +37,40 @@ Commands:
     secret                   Manage KES secrets.
     policy                   Manage KES policies.
     identity                 Manage KES identities.
+    ca                       Manage the built-in certificate authority.
+    ssh                      Sign SSH certificates.
+    token                    Sign JWTs.
+    cert                     Issue certificates for certificate profiles.
+    random                   Generate random bytes.
+    tokenize                 Replace sensitive values by tokens.
+    detokenize               Reveal the values of tokens.
+    access                   Request and approve temporary access.
+    cluster                  Monitor KES cluster nodes.
 
     log                      Print error and audit log events.
     status                   Print server status.
     metric                   Print server metrics.
+    bench                    Benchmark a server.
+    top                      Show a live dashboard of server metrics.
+    doctor                   Diagnose client and server problems.
+    fsck                     Check the integrity of the server state.
+    cache                    Inspect and flush server caches.
+
+    operator                 Reconcile Kubernetes custom resources.
+    gitops                   Sync policies and identities from a Git repository.
 
     migrate                  Migrate KMS data.
+    migrate-ciphertext       Migrate ciphertexts to the envelope format.
+    bundle                   Create offline key bundles.
     update                   Update KES binary.
 
+    completion               Print a shell completion script.
+    man                      Generate man pages.
+
 Options:
+    -o, --output <format>    Print output in the given format, if supported
+                             by the command: table, json or yaml. Defaults
+                             to $KES_OUTPUT or table.
     -v, --version            Print version information.
         --auto-completion    Install auto-completion for this shell.
     -h, --help               Print command line options.
@@ -62,26 +88,55 @@ func main() {
 		"server": serverCmd,
 		"init":   initCmd,
 
-		"enclave":  enclaveCmd,
-		"key":      keyCmd,
-		"secret":   secretCmd,
-		"policy":   policyCmd,
-		"identity": identityCmd,
+		"enclave":    enclaveCmd,
+		"key":        keyCmd,
+		"secret":     secretCmd,
+		"policy":     policyCmd,
+		"identity":   identityCmd,
+		"ca":         caCmd,
+		"ssh":        sshCmd,
+		"token":      tokenCmd,
+		"cert":       certCmd,
+		"random":     randomCmd,
+		"tokenize":   tokenizeCmd,
+		"detokenize": detokenizeCmd,
+		"access":     accessCmd,
+		"cluster":    clusterCmd,
 
 		"log":    logCmd,
 		"status": statusCmd,
 		"metric": metricCmd,
-
-		"migrate": migrateCmd,
-		"update":  updateCmd,
+		"bench":  benchCmd,
+		"top":    topCmd,
+		"doctor": doctorCmd,
+		"fsck":   fsckCmd,
+		"cache":  cacheCmd,
+
+		"operator": operatorCmd,
+		"gitops":   gitopsCmd,
+
+		"migrate":            migrateCmd,
+		"migrate-ciphertext": migrateCiphertextCmd,
+		"bundle":             bundleCmd,
+		"update":             updateCmd,
+
+		"completion": completionCmd,
+		"man":        manCmd,
+		"__complete": completeCmd,
 	}
 
-	if len(os.Args) < 2 {
+	// The output format is a global option that may
+	// precede the command, e.g. 'kes -o json key ls'.
+	args, err := parseOutputOption(os.Args[1:])
+	if err != nil {
+		cli.Fatalf("%v. See 'kes --help'", err)
+	}
+	if len(args) == 0 {
 		cmd.Usage()
 		os.Exit(2)
 	}
-	if subCmd, ok := subCmds[os.Args[1]]; ok {
-		subCmd(os.Args[1:])
+	if subCmd, ok := subCmds[args[0]]; ok {
+		subCmd(args)
 		return
 	}
 
@@ -91,15 +146,15 @@ func main() {
 	)
 	cmd.BoolVarP(&showVersion, "version", "v", false, "Print version information.")
 	cmd.BoolVar(&autoCompletion, "auto-completion", false, "Install auto-completion for this shell")
-	if err := cmd.Parse(os.Args); err != nil {
+	if err := cmd.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes --help'", err)
 
 	}
-	if cmd.NArg() > 1 {
-		cli.Fatalf("%q is not a kes command. See 'kes --help'", cmd.Arg(1))
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a kes command. See 'kes --help'", cmd.Arg(0))
 	}
 	if showVersion {
 		buildInfo := sys.BinaryInfo()
@@ -114,10 +169,13 @@ func main() {
 	os.Exit(2)
 }
 
+// newClient returns a new client for the server endpoints
+// specified by the KES_SERVER environment variable. Multiple
+// endpoints are separated by commas. Requests are distributed
+// over all of them and fail over to healthy endpoints. Endpoints
+// like https+srv://example.com are discovered via DNS SRV.
 func newClient(insecureSkipVerify bool) *kes.Client {
-	const DefaultServer = "https://127.0.0.1:7373"
 	const (
-		EnvServer     = "KES_SERVER"
 		EnvAPIKey     = "KES_API_KEY"
 		EnvClientKey  = "KES_CLIENT_KEY"
 		EnvClientCert = "KES_CLIENT_CERT"
@@ -138,15 +196,7 @@ func newClient(insecureSkipVerify bool) *kes.Client {
 		if err != nil {
 			cli.Fatalf("failed to generate client certificate from API key: %v", err)
 		}
-
-		addr := DefaultServer
-		if env, ok := os.LookupEnv(EnvServer); ok {
-			addr = env
-		}
-		return kes.NewClientWithConfig(addr, &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			InsecureSkipVerify: insecureSkipVerify,
-		})
+		return newClientWithCertificate(cert, insecureSkipVerify)
 	}
 
 	certPath, ok := os.LookupEnv(EnvClientCert)
@@ -206,15 +256,40 @@ func newClient(insecureSkipVerify bool) *kes.Client {
 	if err != nil {
 		cli.Fatalf("failed to load TLS private key or certificate: %v", err)
 	}
+	return newClientWithCertificate(cert, insecureSkipVerify)
+}
 
-	addr := DefaultServer
-	if env, ok := os.LookupEnv(EnvServer); ok {
-		addr = env
-	}
-	return kes.NewClientWithConfig(addr, &tls.Config{
+func newClientWithCertificate(cert tls.Certificate, insecureSkipVerify bool) *kes.Client {
+	client, err := kesclient.NewClient(serverEndpoints(), &tls.Config{
 		Certificates:       []tls.Certificate{cert},
 		InsecureSkipVerify: insecureSkipVerify,
 	})
+	if err != nil {
+		cli.Fatalf("invalid KES_SERVER: %v", err)
+	}
+	return client
+}
+
+// serverEndpoints returns the comma-separated server
+// endpoints of the KES_SERVER environment variable or
+// the default endpoint if KES_SERVER is not set.
+func serverEndpoints() []string {
+	const DefaultServer = "https://127.0.0.1:7373"
+
+	env, ok := os.LookupEnv("KES_SERVER")
+	if !ok {
+		return []string{DefaultServer}
+	}
+	var endpoints []string
+	for _, endpoint := range strings.Split(env, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		return []string{env}
+	}
+	return endpoints
 }
 
 func newEnclave(name string, insecureSkipVerify bool) *kes.Enclave {
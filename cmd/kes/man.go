@@ -0,0 +1,285 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/sys"
+	flag "github.com/spf13/pflag"
+)
+
+const manCmdUsage = `Usage:
+    kes man [options] <PATH>
+
+Options:
+    -h, --help               Print command line options.
+
+Generates man pages for the kes command and all its subcommands and
+writes them into the directory <PATH>. The man page of a subcommand
+is named after the command, e.g. 'kes-key-ls.1' for 'kes key ls'.
+
+Examples:
+    $ kes man /usr/local/share/man/man1
+    $ man kes-key-ls
+`
+
+// manPages contains the name and usage text
+// of all commands with a man page.
+var manPages = []struct {
+	Name  string
+	Usage string
+}{
+	{Name: "kes", Usage: usage},
+	{Name: "kes server", Usage: serverCmdUsage},
+	{Name: "kes init", Usage: initCmdUsage},
+
+	{Name: "kes cluster", Usage: clusterCmdUsage},
+	{Name: "kes cluster status", Usage: statusClusterCmdUsage},
+	{Name: "kes cluster nodes", Usage: nodesClusterCmdUsage},
+
+	{Name: "kes enclave", Usage: enclaveCmdUsage},
+	{Name: "kes enclave create", Usage: createEnclaveCmdUsage},
+	{Name: "kes enclave info", Usage: describeEnclaveCmdUsage},
+	{Name: "kes enclave ls", Usage: lsEnclaveCmdUsage},
+	{Name: "kes enclave rm", Usage: deleteEnclaveCmdUsage},
+
+	{Name: "kes key", Usage: keyCmdUsage},
+	{Name: "kes key create", Usage: createKeyCmdUsage},
+	{Name: "kes key import", Usage: importKeyCmdUsage},
+	{Name: "kes key register", Usage: registerKeyCmdUsage},
+	{Name: "kes key info", Usage: describeKeyCmdUsage},
+	{Name: "kes key ls", Usage: lsKeyCmdUsage},
+	{Name: "kes key rm", Usage: rmKeyCmdUsage},
+	{Name: "kes key verify", Usage: verifyKeyCmdUsage},
+	{Name: "kes key encrypt", Usage: encryptKeyCmdUsage},
+	{Name: "kes key decrypt", Usage: decryptKeyCmdUsage},
+	{Name: "kes key dek", Usage: dekCmdUsage},
+	{Name: "kes key hash", Usage: hashKeyCmdUsage},
+	{Name: "kes key fpe-encrypt", Usage: fpeEncryptKeyCmdUsage},
+	{Name: "kes key fpe-decrypt", Usage: fpeDecryptKeyCmdUsage},
+	{Name: "kes key agree", Usage: agreeKeyCmdUsage},
+	{Name: "kes key encrypt-file", Usage: encryptFileCmdUsage},
+	{Name: "kes key decrypt-file", Usage: decryptFileCmdUsage},
+
+	{Name: "kes secret", Usage: secretCmdUsage},
+	{Name: "kes secret create", Usage: createSecretCmdUsage},
+	{Name: "kes secret update", Usage: updateSecretCmdUsage},
+	{Name: "kes secret rotation", Usage: rotationSecretCmdUsage},
+	{Name: "kes secret rotate", Usage: rotateSecretCmdUsage},
+	{Name: "kes secret info", Usage: describeSecretCmdUsage},
+	{Name: "kes secret show", Usage: showSecretCmdUsage},
+	{Name: "kes secret ls", Usage: lsSecretCmdUsage},
+	{Name: "kes secret rm", Usage: deleteSecretCmdUsage},
+
+	{Name: "kes policy", Usage: policyCmdUsage},
+	{Name: "kes policy create", Usage: createPolicyCmdUsage},
+	{Name: "kes policy assign", Usage: assignPolicyCmdUsage},
+	{Name: "kes policy check", Usage: checkPolicyCmdUsage},
+	{Name: "kes policy edit", Usage: editPolicyCmdUsage},
+	{Name: "kes policy import", Usage: importPolicyCmdUsage},
+	{Name: "kes policy info", Usage: infoPolicyCmdUsage},
+	{Name: "kes policy ls", Usage: lsPolicyCmdUsage},
+	{Name: "kes policy rm", Usage: rmPolicyCmdUsage},
+	{Name: "kes policy show", Usage: showPolicyCmdUsage},
+
+	{Name: "kes identity", Usage: identityCmdUsage},
+	{Name: "kes identity new", Usage: newIdentityCmdUsage},
+	{Name: "kes identity of", Usage: ofIdentityCmdUsage},
+	{Name: "kes identity info", Usage: infoIdentityCmdUsage},
+	{Name: "kes identity import", Usage: importIdentityCmdUsage},
+	{Name: "kes identity ls", Usage: lsIdentityCmdUsage},
+	{Name: "kes identity rm", Usage: rmIdentityCmdUsage},
+	{Name: "kes identity rotate", Usage: rotateIdentityCmdUsage},
+
+	{Name: "kes ca", Usage: caCmdUsage},
+	{Name: "kes ca cert", Usage: certCACmdUsage},
+	{Name: "kes ca crl", Usage: crlCACmdUsage},
+	{Name: "kes ca issue", Usage: issueCACmdUsage},
+	{Name: "kes ca revoke", Usage: revokeCACmdUsage},
+
+	{Name: "kes ssh", Usage: sshCmdUsage},
+	{Name: "kes ssh ca", Usage: caSSHCmdUsage},
+	{Name: "kes ssh sign", Usage: signSSHCmdUsage},
+	{Name: "kes token", Usage: tokenCmdUsage},
+	{Name: "kes token designate", Usage: designateTokenCmdUsage},
+	{Name: "kes token sign", Usage: signTokenCmdUsage},
+	{Name: "kes token jwks", Usage: jwksTokenCmdUsage},
+	{Name: "kes cert", Usage: certCmdUsage},
+	{Name: "kes cert ca", Usage: caCertCmdUsage},
+	{Name: "kes cert issue", Usage: issueCertCmdUsage},
+	{Name: "kes random", Usage: randomCmdUsage},
+	{Name: "kes tokenize", Usage: tokenizeCmdUsage},
+	{Name: "kes detokenize", Usage: detokenizeCmdUsage},
+
+	{Name: "kes access", Usage: accessCmdUsage},
+	{Name: "kes access request", Usage: requestAccessCmdUsage},
+	{Name: "kes access approve", Usage: approveAccessCmdUsage},
+	{Name: "kes access deny", Usage: denyAccessCmdUsage},
+	{Name: "kes access ls", Usage: lsAccessCmdUsage},
+
+	{Name: "kes log", Usage: logCmdUsage},
+	{Name: "kes status", Usage: statusCmdUsage},
+	{Name: "kes metric", Usage: metricCmdUsage},
+	{Name: "kes bench", Usage: benchCmdUsage},
+	{Name: "kes top", Usage: topCmdUsage},
+	{Name: "kes doctor", Usage: doctorCmdUsage},
+	{Name: "kes fsck", Usage: fsckCmdUsage},
+	{Name: "kes cache", Usage: cacheCmdUsage},
+	{Name: "kes cache stats", Usage: statsCacheCmdUsage},
+	{Name: "kes cache flush", Usage: flushCacheCmdUsage},
+	{Name: "kes operator", Usage: operatorCmdUsage},
+	{Name: "kes gitops", Usage: gitopsCmdUsage},
+	{Name: "kes migrate", Usage: migrateCmdUsage},
+	{Name: "kes migrate-ciphertext", Usage: migrateCiphertextCmdUsage},
+	{Name: "kes bundle", Usage: bundleCmdUsage},
+	{Name: "kes bundle create", Usage: createBundleCmdUsage},
+	{Name: "kes bundle inspect", Usage: inspectBundleCmdUsage},
+	{Name: "kes update", Usage: updateCmdUsage},
+	{Name: "kes completion", Usage: completionCmdUsage},
+	{Name: "kes man", Usage: manCmdUsage},
+}
+
+func manCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, manCmdUsage) }
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes man --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no path specified. See 'kes man --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes man --help'")
+	}
+
+	dir := cmd.Arg(0)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		cli.Fatalf("failed to create '%s': %v", dir, err)
+	}
+	var (
+		date    = time.Now().UTC().Format("2006-01-02")
+		version = sys.BinaryInfo().Version
+	)
+	for _, page := range manPages {
+		filename := filepath.Join(dir, strings.ReplaceAll(page.Name, " ", "-")+".1")
+		if err := os.WriteFile(filename, manPage(page.Name, page.Usage, date, version), 0o644); err != nil {
+			cli.Fatalf("failed to write '%s': %v", filename, err)
+		}
+	}
+	cli.Printf("Generated %d man pages in '%s'\n", len(manPages), dir)
+}
+
+// manPage renders the usage text of the named command
+// as roff man page. Each section of the usage text,
+// like "Options:", becomes a man page section. Text
+// outside any section becomes the DESCRIPTION.
+func manPage(name, usage, date, version string) []byte {
+	title := strings.ToUpper(strings.ReplaceAll(name, " ", "-"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %q 1 %q %q \"KES Manual\"\n", title, date, "KES "+version)
+	b.WriteString(".SH NAME\n")
+	if summary := manSummary(name); summary != "" {
+		fmt.Fprintf(&b, "%s \\- %s\n", roffEscape(strings.ReplaceAll(name, " ", "-")), roffEscape(summary))
+	} else {
+		fmt.Fprintf(&b, "%s\n", roffEscape(strings.ReplaceAll(name, " ", "-")))
+	}
+
+	var section string
+	scanner := bufio.NewScanner(strings.NewReader(usage))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " ")
+		switch {
+		case line != "" && !strings.HasPrefix(line, " ") && strings.HasSuffix(line, ":") && !strings.Contains(line, ". "):
+			heading := strings.ToUpper(strings.TrimSuffix(line, ":"))
+			if heading == "USAGE" {
+				heading = "SYNOPSIS"
+			}
+			if section != "" {
+				b.WriteString(".fi\n")
+			}
+			fmt.Fprintf(&b, ".SH %s\n.nf\n", heading)
+			section = heading
+		case line != "" && !strings.HasPrefix(line, " ") && section != "DESCRIPTION":
+			if section != "" {
+				b.WriteString(".fi\n")
+			}
+			b.WriteString(".SH DESCRIPTION\n.nf\n")
+			section = "DESCRIPTION"
+			b.WriteString(roffEscape(line) + "\n")
+		default:
+			if section == "" {
+				continue
+			}
+			b.WriteString(roffEscape(line) + "\n")
+		}
+	}
+	if section != "" {
+		b.WriteString(".fi\n")
+	}
+
+	var related []string
+	for _, page := range manPages {
+		if page.Name != name && (parentCmd(page.Name) == name || parentCmd(name) == page.Name) {
+			related = append(related, roffEscape(strings.ReplaceAll(page.Name, " ", "-"))+"(1)")
+		}
+	}
+	if len(related) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		b.WriteString(strings.Join(related, ", ") + "\n")
+	}
+	return []byte(b.String())
+}
+
+// manSummary returns the one-line description of the
+// named command as listed in its parent's usage text.
+func manSummary(name string) string {
+	parent := parentCmd(name)
+	if parent == "" {
+		return "Key Encryption Service"
+	}
+	sub := strings.TrimPrefix(name, parent+" ")
+	for _, page := range manPages {
+		if page.Name != parent {
+			continue
+		}
+		for _, line := range strings.Split(page.Usage, "\n") {
+			if fields := strings.Fields(line); len(fields) > 1 && fields[0] == sub {
+				return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), sub))
+			}
+		}
+	}
+	return ""
+}
+
+// parentCmd returns the parent command of
+// the named command, e.g. "kes key" for
+// "kes key ls", or the empty string.
+func parentCmd(name string) string {
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// roffEscape escapes roff control characters.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
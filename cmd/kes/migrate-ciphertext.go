@@ -0,0 +1,206 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const migrateCiphertextCmdUsage = `Usage:
+    kes migrate-ciphertext [options] <name> [<file>]
+
+Options:
+        --dry-run            Only detect the ciphertext formats.
+    -o, --out <path>         Write the migrated ciphertexts to <path>.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Migrates ciphertexts produced with the key <name> to the current ciphertext
+envelope format. It reads one base64-encoded ciphertext per line, optionally
+followed by a space and its base64-encoded context, from <file> or standard
+input. For each line, it writes the base64-encoded ciphertext envelope and
+the context, if any, to standard output or the --out file, in the same order.
+
+The server decrypts and re-encrypts each ciphertext. The plaintext never leaves
+the server. Legacy ciphertexts use the JSON format of early KES releases or the
+binary format that is still the default. Both remain decryptable. Migrating is
+recommended before upgrading from old KES releases, or when ciphertexts must be
+parsed by other tools. Use --dry-run to count the ciphertexts per format first.
+
+Examples:
+    $ kes migrate-ciphertext --dry-run my-key ciphertexts.txt
+    $ kes migrate-ciphertext my-key ciphertexts.txt --out envelopes.txt
+`
+
+func migrateCiphertextCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, migrateCiphertextCmdUsage) }
+
+	var (
+		dryRun             bool
+		outPath            string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&dryRun, "dry-run", false, "Only detect the ciphertext formats")
+	cmd.StringVarP(&outPath, "out", "o", "", "Write the migrated ciphertexts to the file")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes migrate-ciphertext --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes migrate-ciphertext --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes migrate-ciphertext --help'")
+	}
+	if dryRun && outPath != "" {
+		cli.Fatal("mutually exclusive options '--dry-run' and '--out' specified")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	var in io.Reader = os.Stdin
+	if cmd.NArg() == 2 && cmd.Arg(1) != "-" {
+		f, err := os.Open(cmd.Arg(1))
+		if err != nil {
+			cli.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	ciphertexts, err := readCiphertexts(in)
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	formats := map[string]int{}
+	if dryRun {
+		for i, c := range ciphertexts {
+			format, err := key.CiphertextFormat(c.Ciphertext)
+			if err != nil {
+				cli.Fatalf("line %d: invalid ciphertext", i+1)
+			}
+			formats[format]++
+		}
+		printCiphertextFormats(formats, "Found")
+		if n := formats[key.FormatJSON] + formats[key.FormatBinary]; n > 0 {
+			fmt.Fprintf(os.Stderr, "\n%d ciphertexts use a legacy format. Run the command without --dry-run to migrate them.\n", n)
+		}
+		return
+	}
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.OpenFile(outPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	const BatchSize = 100 // Keep requests well below the server's body limit
+	client := newClient(insecureSkipVerify)
+	for i := 0; i < len(ciphertexts); i += BatchSize {
+		batch := ciphertexts[i:]
+		if len(batch) > BatchSize {
+			batch = batch[:BatchSize]
+		}
+		results, err := kesclient.RewrapKey(ctx, client, enclaveName, cmd.Arg(0), batch)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to migrate ciphertexts %d-%d: %v", i+1, i+len(batch), err)
+		}
+		if len(results) != len(batch) {
+			cli.Fatalf("failed to migrate ciphertexts %d-%d: server returned %d ciphertexts", i+1, i+len(batch), len(results))
+		}
+		for j, result := range results {
+			formats[result.Format]++
+			w.WriteString(base64.StdEncoding.EncodeToString(result.Ciphertext))
+			if len(batch[j].Context) > 0 {
+				w.WriteString(" " + base64.StdEncoding.EncodeToString(batch[j].Context))
+			}
+			w.WriteByte('\n')
+		}
+	}
+	if err = w.Flush(); err != nil {
+		cli.Fatal(err)
+	}
+	printCiphertextFormats(formats, "Migrated")
+}
+
+// readCiphertexts reads one base64-encoded ciphertext per
+// line, optionally followed by its base64-encoded context.
+// It ignores empty lines.
+func readCiphertexts(r io.Reader) ([]kes.CCP, error) {
+	var (
+		ciphertexts []kes.CCP
+		scanner     = bufio.NewScanner(r)
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: too many fields", line)
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid ciphertext: %v", line, err)
+		}
+		var context []byte
+		if len(fields) == 2 {
+			if context, err = base64.StdEncoding.DecodeString(fields[1]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid context: %v", line, err)
+			}
+		}
+		ciphertexts = append(ciphertexts, kes.CCP{Ciphertext: ciphertext, Context: context})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ciphertexts, nil
+}
+
+// printCiphertextFormats prints the number of ciphertexts
+// per format to STDERR such that STDOUT only contains the
+// migrated ciphertexts.
+func printCiphertextFormats(formats map[string]int, verb string) {
+	total := formats[key.FormatJSON] + formats[key.FormatBinary] + formats[key.FormatEnvelope]
+	fmt.Fprintf(os.Stderr, "%s %d ciphertexts:\n", verb, total)
+	fmt.Fprintf(os.Stderr, "  %-10s %d (legacy)\n", key.FormatJSON, formats[key.FormatJSON])
+	fmt.Fprintf(os.Stderr, "  %-10s %d (legacy)\n", key.FormatBinary, formats[key.FormatBinary])
+	fmt.Fprintf(os.Stderr, "  %-10s %d\n", key.FormatEnvelope, formats[key.FormatEnvelope])
+}
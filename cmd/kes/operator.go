@@ -0,0 +1,115 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/operator"
+	flag "github.com/spf13/pflag"
+)
+
+const operatorCmdUsage = `Usage:
+    kes operator [options]
+
+Options:
+    -n, --namespace <name>   The Kubernetes namespace to watch. Defaults to
+                             the namespace of the operator's service account.
+        --interval <time>    Time between reconciliations. (default: 30s)
+        --kube-api <URL>     URL of the Kubernetes API server, e.g. of a
+                             'kubectl proxy'. If omitted, the in-cluster
+                             service account is used.
+        --print-crds         Print the custom resource definitions and exit.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -h, --help               Print command line options.
+
+Reconciles KESKey, KESPolicy and KESIdentity custom resources against the
+KES server specified by the KES_SERVER environment variable. The operator
+authenticates with the client certificate or API key of the KES_CLIENT_CERT
+and KES_CLIENT_KEY or KES_API_KEY environment variables.
+
+The outcome of each reconciliation is reported as Ready status condition of
+the custom resource. Keys are only deleted from the KES server when their
+resource sets 'deletionPolicy: Delete'.
+
+Examples:
+    $ kes operator --print-crds | kubectl apply -f -
+    $ kes operator --namespace kes --interval 1m
+`
+
+func operatorCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, operatorCmdUsage) }
+
+	var (
+		namespaceFlag      string
+		intervalFlag       time.Duration
+		kubeAPIFlag        string
+		printCRDsFlag      bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVarP(&namespaceFlag, "namespace", "n", "", "The Kubernetes namespace to watch")
+	cmd.DurationVar(&intervalFlag, "interval", 30*time.Second, "Time between reconciliations")
+	cmd.StringVar(&kubeAPIFlag, "kube-api", "", "URL of the Kubernetes API server")
+	cmd.BoolVar(&printCRDsFlag, "print-crds", false, "Print the custom resource definitions and exit")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes operator --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes operator --help'")
+	}
+
+	if printCRDsFlag {
+		os.Stdout.Write(operator.CRDs)
+		return
+	}
+	if intervalFlag <= 0 {
+		cli.Fatal("invalid reconciliation interval: interval must be positive")
+	}
+
+	var (
+		kube      *operator.Kube
+		namespace string
+	)
+	if kubeAPIFlag != "" {
+		kube = &operator.Kube{URL: kubeAPIFlag}
+	} else {
+		var err error
+		if kube, namespace, err = operator.InCluster(); err != nil {
+			cli.Fatalf("failed to connect to Kubernetes: %v. Use --kube-api", err)
+		}
+	}
+	if namespaceFlag != "" {
+		namespace = namespaceFlag
+	}
+	if namespace == "" {
+		cli.Fatal("no namespace specified. See 'kes operator --help'")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	reconciler := &operator.Reconciler{
+		Kube:      kube,
+		Server:    operator.NewServer(newClient(insecureSkipVerify)),
+		Namespace: namespace,
+	}
+	cli.Println(fmt.Sprintf("Reconciling KES resources in namespace '%s' every %v", namespace, intervalFlag))
+	if err := reconciler.Run(ctx, intervalFlag); err != nil && !errors.Is(err, context.Canceled) {
+		cli.Fatal(err)
+	}
+}
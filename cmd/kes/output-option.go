@@ -0,0 +1,165 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFlag is the global output format. It can be
+// set before the command, e.g. 'kes -o json key ls',
+// or as option of any command that supports it.
+var outputFlag outputOption
+
+// outputOption is a CLI Flag that controls
+// the output format of a command. It can be
+// set to one of the following values:
+//
+//	· table (default)
+//	· json
+//	· yaml
+//
+// If not set explicitly, the format is read
+// from the KES_OUTPUT environment variable.
+type outputOption struct {
+	value string
+}
+
+var _ flag.Value = (*outputOption)(nil)
+
+// Format returns the output format. It is
+// either "table", "json" or "yaml".
+func (o *outputOption) Format() string {
+	if o.value != "" {
+		return o.value
+	}
+	if value := strings.ToLower(os.Getenv("KES_OUTPUT")); value != "" {
+		return value
+	}
+	return "table"
+}
+
+// IsTable reports whether the output format
+// is the human-readable table format.
+func (o *outputOption) IsTable() bool { return o.Format() == "table" }
+
+// Parse completes the output format once the command
+// flags have been parsed. The --json flag sets the format
+// to JSON. Parse returns an error if --json conflicts with
+// the format set by --output or if no format has been set
+// and KES_OUTPUT contains an invalid format.
+func (o *outputOption) Parse(jsonFlag bool) error {
+	if jsonFlag {
+		if o.value != "" && o.value != "json" {
+			return fmt.Errorf("'--json' conflicts with '--output %s'", o.value)
+		}
+		o.value = "json"
+	}
+	if o.value == "" {
+		switch value := os.Getenv("KES_OUTPUT"); strings.ToLower(value) {
+		case "", "table", "json", "yaml":
+		default:
+			return fmt.Errorf("invalid output format '%s' in KES_OUTPUT: must be table, json or yaml", value)
+		}
+	}
+	return nil
+}
+
+// Encode writes v to w using the output format.
+// JSON output is indented if w is a terminal.
+func (o *outputOption) Encode(w io.Writer, v any) error {
+	switch o.Format() {
+	case "yaml":
+		// Encode v as JSON first such that the YAML
+		// output uses the same field names and field
+		// order as the JSON output.
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var node yaml.Node
+		if err = yaml.Unmarshal(b, &node); err != nil {
+			return err
+		}
+		blockStyle(&node)
+
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(&node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		encoder := json.NewEncoder(w)
+		if f, ok := w.(*os.File); ok && isTerm(f) {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(v)
+	}
+}
+
+func (o *outputOption) String() string { return o.value }
+
+// Set sets the output format. It returns an error if
+// a different output format has been set already, e.g.
+// by 'kes -o json key ls -o yaml'.
+func (o *outputOption) Set(value string) error {
+	switch v := strings.ToLower(value); v {
+	case "table", "json", "yaml":
+		if o.value != "" && o.value != v {
+			return fmt.Errorf("conflicting output formats '%s' and '%s'", o.value, v)
+		}
+		o.value = v
+		return nil
+	default:
+		return errors.New("invalid output format")
+	}
+}
+
+func (o *outputOption) Type() string { return "output format" }
+
+// parseOutputOption parses the global output options
+// preceding the command, like '-o json' or '--output=json',
+// and returns the remaining arguments.
+func parseOutputOption(args []string) ([]string, error) {
+	for len(args) > 0 {
+		var value string
+		switch arg := args[0]; {
+		case arg == "-o" || arg == "--output":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			value, args = args[1], args[2:]
+		case strings.HasPrefix(arg, "--output="):
+			value, args = strings.TrimPrefix(arg, "--output="), args[1:]
+		case strings.HasPrefix(arg, "-o") && !strings.HasPrefix(arg, "--"):
+			value, args = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "="), args[1:]
+		default:
+			return args, nil
+		}
+		if err := outputFlag.Set(value); err != nil {
+			return nil, fmt.Errorf("invalid argument %q for \"-o, --output\" flag: %v", value, err)
+		}
+	}
+	return args, nil
+}
+
+// blockStyle resets the style of the node and all
+// its children such that the YAML encoder uses its
+// default block style instead of the JSON flow style.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const checkPolicyCmdUsage = `Usage:
+    kes policy check [options] --policy <file> --api <path>...
+
+Options:
+        --policy <file>      Policy file in JSON or YAML format.
+        --api <path>         API path to evaluate, e.g. /v1/key/decrypt/my-key.
+                             May be specified multiple times.
+        --json               Print results in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Evaluates a policy file locally, without contacting a server, and shows
+whether the policy allows requests to each API path. The policy file has
+the same format as for 'kes policy create' and is evaluated exactly like
+the server does: a request is allowed if no deny rule and at least one
+allow rule matches its path.
+
+The command exits with a non-zero exit code if any API is denied. Hence,
+it can be used to validate policy changes in CI pipelines.
+
+Examples:
+    $ kes policy check --policy ./my-app.json --api /v1/key/decrypt/my-key
+    $ kes policy check --policy ./my-app.yml --api /v1/key/create/app-1 --api /v1/key/delete/app-1
+`
+
+func checkPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, checkPolicyCmdUsage) }
+
+	var (
+		policyFile string
+		apiPaths   []string
+		jsonFlag   bool
+		colorFlag  colorOption
+	)
+	cmd.StringVar(&policyFile, "policy", "", "Policy file in JSON or YAML format")
+	cmd.StringArrayVar(&apiPaths, "api", nil, "API path to evaluate")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print results in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy check --help'", err)
+	}
+	switch {
+	case cmd.NArg() > 0:
+		cli.Fatal("too many arguments. See 'kes policy check --help'")
+	case policyFile == "":
+		cli.Fatal("no policy file specified. See 'kes policy check --help'")
+	case len(apiPaths) == 0:
+		cli.Fatal("no API path specified. See 'kes policy check --help'")
+	}
+
+	b, err := os.ReadFile(policyFile)
+	if err != nil {
+		cli.Fatalf("failed to read '%s': %v", policyFile, err)
+	}
+	p, err := decodePolicy(b, strings.EqualFold(filepath.Ext(policyFile), ".json"))
+	if err != nil {
+		cli.Fatalf("invalid policy '%s': %v", policyFile, err)
+	}
+	policy := &auth.Policy{
+		Allow: p.Allow,
+		Deny:  p.Deny,
+	}
+
+	type Result struct {
+		API     string `json:"api"`
+		Allowed bool   `json:"allowed"`
+		Rule    string `json:"rule,omitempty"`
+	}
+	var (
+		results = make([]Result, 0, len(apiPaths))
+		denied  bool
+	)
+	for _, apiPath := range apiPaths {
+		u, err := url.Parse(apiPath)
+		if err != nil || !strings.HasPrefix(u.Path, "/") {
+			cli.Fatalf("invalid API path '%s': must start with '/'. See 'kes policy check --help'", apiPath)
+		}
+		allowed, rule := policy.Match(u.Path)
+		results = append(results, Result{
+			API:     u.Path,
+			Allowed: allowed,
+			Rule:    rule,
+		})
+		denied = denied || !allowed
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		for _, result := range results {
+			encoder.Encode(result)
+		}
+	} else {
+		var allowStyle, denyStyle, faint tui.Style
+		if colorFlag.Colorize() {
+			allowStyle = allowStyle.Foreground(tui.Color("#00a700"))
+			denyStyle = denyStyle.Foreground(tui.Color("#d70000"))
+			faint = faint.Faint(true)
+		}
+		for _, result := range results {
+			switch {
+			case result.Allowed:
+				fmt.Printf("%s %s %s\n", allowStyle.Render("allow"), result.API, faint.Render("(allow rule '"+result.Rule+"')"))
+			case result.Rule != "":
+				fmt.Printf("%s  %s %s\n", denyStyle.Render("deny"), result.API, faint.Render("(deny rule '"+result.Rule+"')"))
+			default:
+				fmt.Printf("%s  %s %s\n", denyStyle.Render("deny"), result.API, faint.Render("(no allow rule matches)"))
+			}
+		}
+	}
+	if denied {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const editPolicyCmdUsage = `Usage:
+    kes policy edit [options] <name>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --json               Edit the policy in JSON instead of YAML format.
+    -y, --yes                Apply the changes without asking for confirmation.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Opens the policy in the editor specified by the VISUAL or EDITOR
+environment variable. Once the editor exits, the edited policy is
+validated, the changes are shown and, after confirmation, written
+back to the server. If the policy has been modified by someone else
+in the meantime, no changes are applied. If the policy does not
+exist, a new policy is created.
+
+Examples:
+    $ kes policy edit my-policy
+    $ EDITOR=nano kes policy edit --json my-policy
+`
+
+func editPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, editPolicyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		jsonFlag           bool
+		yesFlag            bool
+		colorFlag          colorOption
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", false, "Edit the policy in JSON format")
+	cmd.BoolVarP(&yesFlag, "yes", "y", false, "Apply the changes without asking for confirmation")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy edit --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no policy name specified. See 'kes policy edit --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes policy edit --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	client := newClient(insecureSkipVerify)
+	policy, etag, err := kesclient.ReadPolicy(ctx, client, enclaveName, name)
+	if err != nil && !errors.Is(err, kes.ErrPolicyNotFound) {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to read policy '%s': %v", name, err)
+	}
+	exists := err == nil
+	if !exists {
+		policy = &kesclient.Policy{}
+	}
+
+	original, err := encodePolicy(policy, jsonFlag)
+	if err != nil {
+		cli.Fatalf("failed to encode policy '%s': %v", name, err)
+	}
+	pattern := "kes-policy-*.yml"
+	if jsonFlag {
+		pattern = "kes-policy-*.json"
+	}
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		cli.Fatalf("failed to create temporary file: %v", err)
+	}
+	filename := file.Name()
+	defer os.Remove(filename)
+	if _, err = file.Write(original); err != nil {
+		file.Close()
+		cli.Fatalf("failed to write '%s': %v", filename, err)
+	}
+	if err = file.Close(); err != nil {
+		cli.Fatalf("failed to write '%s': %v", filename, err)
+	}
+
+	var (
+		edited *kesclient.Policy
+		stdin  = bufio.NewReader(os.Stdin)
+	)
+	for {
+		if err = runEditor(filename); err != nil {
+			cli.Fatal(err)
+		}
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			cli.Fatalf("failed to read '%s': %v", filename, err)
+		}
+		if bytes.Equal(bytes.TrimSpace(b), bytes.TrimSpace(original)) {
+			cli.Println("Edit cancelled, no changes made.")
+			return
+		}
+		if edited, err = decodePolicy(b, jsonFlag); err == nil {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "Error: invalid policy: %v\n", err)
+		if !confirm(stdin, "Edit again?") {
+			os.Exit(1)
+		}
+	}
+
+	changes := diffPolicy(policy, edited)
+	if len(changes) == 0 {
+		cli.Println("Edit cancelled, no changes made.")
+		return
+	}
+	var added, removed tui.Style
+	if colorFlag.Colorize() {
+		const (
+			Red   tui.Color = "#d70000"
+			Green tui.Color = "#00a700"
+		)
+		added = added.Foreground(Green)
+		removed = removed.Foreground(Red)
+	}
+	for _, change := range changes {
+		if strings.HasPrefix(change, "+") {
+			fmt.Println(added.Render(change))
+		} else {
+			fmt.Println(removed.Render(change))
+		}
+	}
+	if !yesFlag && !confirm(stdin, fmt.Sprintf("Apply changes to policy '%s'?", name)) {
+		cli.Println("Edit cancelled, no changes made.")
+		return
+	}
+
+	cond := kesclient.Precondition{IfMatch: etag}
+	if !exists {
+		cond = kesclient.Precondition{IfNoneMatch: "*"}
+	}
+	if _, err = kesclient.WritePolicy(ctx, client, enclaveName, name, edited, cond); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		if kesclient.Code(err) == kesclient.CodePreconditionFailed {
+			cli.Fatalf("policy '%s' has been modified concurrently. No changes applied. Run 'kes policy edit %s' again", name, name)
+		}
+		cli.Fatalf("failed to write policy '%s': %v", name, err)
+	}
+}
+
+// runEditor opens the file in the editor specified by the
+// VISUAL or EDITOR environment variable and waits until
+// the editor exits.
+func runEditor(filename string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may contain arguments, like "code --wait".
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], filename)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor '%s': %v", editor, err)
+	}
+	return nil
+}
+
+// confirm asks the question and reports whether
+// the user answered with yes.
+func confirm(in *bufio.Reader, question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, _ := in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// policyFile is the representation of a
+// policy that is presented to the editor.
+type policyFile struct {
+	Allow []string `json:"allow" yaml:"allow"`
+	Deny  []string `json:"deny" yaml:"deny"`
+
+	Certificates   map[string]kesclient.CertificateProfile `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+	MaxRandomBytes int                                     `json:"max_random_bytes,omitempty" yaml:"max_random_bytes,omitempty"`
+	Tokens         *kesclient.TokenProfile                 `json:"tokens,omitempty" yaml:"tokens,omitempty"`
+	MaxSSHTTL      string                                  `json:"max_ssh_ttl,omitempty" yaml:"max_ssh_ttl,omitempty"`
+}
+
+// encodePolicy encodes the policy as YAML or,
+// if asJSON is true, as JSON document.
+func encodePolicy(policy *kesclient.Policy, asJSON bool) ([]byte, error) {
+	file := policyFile{
+		Allow:          policy.Allow,
+		Deny:           policy.Deny,
+		Certificates:   policy.Certificates,
+		MaxRandomBytes: policy.MaxRandomBytes,
+		Tokens:         policy.Tokens,
+		MaxSSHTTL:      policy.MaxSSHTTL,
+	}
+	if file.Allow == nil {
+		file.Allow = []string{}
+	}
+	if file.Deny == nil {
+		file.Deny = []string{}
+	}
+	if asJSON {
+		b, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+	return yaml.Marshal(file)
+}
+
+// decodePolicy decodes and validates a YAML or, if
+// asJSON is true, JSON encoded policy. It rejects
+// unknown fields and malformed rules.
+func decodePolicy(b []byte, asJSON bool) (*kesclient.Policy, error) {
+	var file policyFile
+	if asJSON {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validatePolicyRules(file.Allow, file.Deny); err != nil {
+		return nil, err
+	}
+	for name, profile := range file.Certificates {
+		if profile.Key == "" {
+			return nil, fmt.Errorf("invalid certificate profile '%s': no CA key specified", name)
+		}
+		if profile.MaxTTL != "" {
+			if _, err := time.ParseDuration(profile.MaxTTL); err != nil {
+				return nil, fmt.Errorf("invalid certificate profile '%s': invalid max. TTL '%s'", name, profile.MaxTTL)
+			}
+		}
+	}
+	if file.MaxRandomBytes < 0 {
+		return nil, fmt.Errorf("invalid max. random bytes '%d'", file.MaxRandomBytes)
+	}
+	if file.Tokens != nil && file.Tokens.MaxTTL != "" {
+		if _, err := time.ParseDuration(file.Tokens.MaxTTL); err != nil {
+			return nil, fmt.Errorf("invalid token profile: invalid max. TTL '%s'", file.Tokens.MaxTTL)
+		}
+	}
+	if file.MaxSSHTTL != "" {
+		if ttl, err := time.ParseDuration(file.MaxSSHTTL); err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid max. SSH TTL '%s'", file.MaxSSHTTL)
+		}
+	}
+	return &kesclient.Policy{
+		Allow:          file.Allow,
+		Deny:           file.Deny,
+		Certificates:   file.Certificates,
+		MaxRandomBytes: file.MaxRandomBytes,
+		Tokens:         file.Tokens,
+		MaxSSHTTL:      file.MaxSSHTTL,
+	}, nil
+}
+
+// validatePolicyRules returns an error if any
+// allow or deny rule is empty or not a valid
+// glob pattern.
+func validatePolicyRules(allow, deny []string) error {
+	for _, rules := range [][]string{allow, deny} {
+		for _, rule := range rules {
+			if strings.TrimSpace(rule) == "" {
+				return errors.New("empty policy rule")
+			}
+			if _, err := path.Match(rule, ""); err != nil {
+				return fmt.Errorf("invalid policy rule '%s': %v", rule, err)
+			}
+		}
+	}
+	return nil
+}
+
+// diffPolicy returns the rules and certificate profiles that
+// have been added to or removed from the policy, prefixed
+// with '+' or '-'. A modified profile is reported as removed
+// and added. A changed random bytes limit, token profile or
+// SSH certificate lifetime is prefixed with '~'.
+func diffPolicy(old, new *kesclient.Policy) []string {
+	var changes []string
+	diff := func(kind string, old, new []string) {
+		for _, rule := range old {
+			if !containsRule(new, rule) {
+				changes = append(changes, "- "+kind+": "+rule)
+			}
+		}
+		for _, rule := range new {
+			if !containsRule(old, rule) {
+				changes = append(changes, "+ "+kind+": "+rule)
+			}
+		}
+	}
+	diff("allow", old.Allow, new.Allow)
+	diff("deny", old.Deny, new.Deny)
+	diff("certificate", profileRules(old.Certificates), profileRules(new.Certificates))
+	if old.MaxRandomBytes != new.MaxRandomBytes {
+		changes = append(changes, fmt.Sprintf("~ max_random_bytes: %d -> %d", old.MaxRandomBytes, new.MaxRandomBytes))
+	}
+	if oldTokens, newTokens := tokenRule(old.Tokens), tokenRule(new.Tokens); oldTokens != newTokens {
+		changes = append(changes, fmt.Sprintf("~ tokens: %s -> %s", oldTokens, newTokens))
+	}
+	if old.MaxSSHTTL != new.MaxSSHTTL {
+		changes = append(changes, fmt.Sprintf("~ max_ssh_ttl: %q -> %q", old.MaxSSHTTL, new.MaxSSHTTL))
+	}
+	return changes
+}
+
+// tokenRule returns the token profile as JSON
+// rule or "none" if the profile is nil.
+func tokenRule(profile *kesclient.TokenProfile) string {
+	if profile == nil {
+		return "none"
+	}
+	b, _ := json.Marshal(profile)
+	return string(b)
+}
+
+// profileRules returns the certificate profiles as
+// sorted list of '<name> <JSON>' rules.
+func profileRules(profiles map[string]kesclient.CertificateProfile) []string {
+	rules := make([]string, 0, len(profiles))
+	for name, profile := range profiles {
+		b, _ := json.Marshal(profile)
+		rules = append(rules, name+" "+string(b))
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func containsRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -18,6 +18,7 @@ import (
 	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
 	flag "github.com/spf13/pflag"
 )
 
@@ -27,6 +28,9 @@ const policyCmdUsage = `Usage:
 Commands:
     create                   Create a new policy.
     assign                   Assign a policy to identities.
+    check                    Evaluate a policy file locally.
+    edit                     Edit a policy in an editor.
+    import                   Import policies from a file.
     info                     Get information about a policy.
     ls                       List policies.
     rm                       Remove a policy.
@@ -43,6 +47,9 @@ func policyCmd(args []string) {
 	subCmds := commands{
 		"create": createPolicyCmd,
 		"assign": assignPolicyCmd,
+		"check":  checkPolicyCmd,
+		"edit":   editPolicyCmd,
+		"import": importPolicyCmd,
 		"info":   infoPolicyCmd,
 		"ls":     lsPolicyCmd,
 		"rm":     rmPolicyCmd,
@@ -75,11 +82,15 @@ const createPolicyCmdUsage = `Usage:
 Options:
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
+        --retention <DUR>    Make the policy immutable for the given retention
+                             period, e.g. 8760h. An immutable policy cannot be
+                             modified or deleted, not even by an admin.
 
     -h, --help               Print command line options.
 
 Examples:
     $ kes policy add my-policy ./policy.json
+    $ kes policy add --retention 8760h my-policy ./policy.json
 `
 
 func createPolicyCmd(args []string) {
@@ -89,9 +100,11 @@ func createPolicyCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		retention          time.Duration
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.DurationVar(&retention, "retention", 0, "Make the policy immutable for the given retention period")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -107,6 +120,12 @@ func createPolicyCmd(args []string) {
 	case cmd.NArg() > 2:
 		cli.Fatal("too many arguments. See 'kes policy create --help'")
 	}
+	if retention < 0 {
+		cli.Fatal("invalid retention period: must not be negative. See 'kes policy create --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 
 	name := cmd.Arg(0)
 	filename := cmd.Arg(1)
@@ -115,7 +134,7 @@ func createPolicyCmd(args []string) {
 		cli.Fatalf("failed to read %q: %v", filename, err)
 	}
 
-	var policy kes.Policy
+	var policy kesclient.Policy
 	if err = json.Unmarshal(b, &policy); err != nil {
 		cli.Fatalf("failed to read %q: %v", filename, err)
 	}
@@ -123,8 +142,13 @@ func createPolicyCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	if err := enclave.SetPolicy(ctx, name, &policy); err != nil {
+	client := newClient(insecureSkipVerify)
+	if retention > 0 {
+		err = kesclient.WriteImmutablePolicy(ctx, client, enclaveName, name, &policy, retention)
+	} else {
+		_, err = kesclient.WritePolicy(ctx, client, enclaveName, name, &policy, kesclient.Precondition{})
+	}
+	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
@@ -137,12 +161,22 @@ const assignPolicyCmdUsage = `Usage:
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
+        --expiry <duration>  Assign the policy only for the given duration.
+                             Once expired, the identities are no longer
+                             allowed to perform any operation.
     -e, --enclave <name>     Operate within the specified enclave.
 
     -h, --help               Print command line options.
 
+The built-in 'enclave-admin' policy delegates the management of keys,
+secrets, policies and identities within an enclave to an identity
+without granting it system admin privileges. Only the system admin
+and the enclave admin can assign it.
+
 Examples:
     $ kes policy assign my-policy 032dc24c353f1baf782660635ade933c601095ba462a44d1484a511c4271e212
+    $ kes policy assign -e tenant-1 enclave-admin 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+    $ kes policy assign --expiry 4h my-policy 032dc24c353f1baf782660635ade933c601095ba462a44d1484a511c4271e212
 `
 
 func assignPolicyCmd(args []string) {
@@ -151,9 +185,11 @@ func assignPolicyCmd(args []string) {
 
 	var (
 		insecureSkipVerify bool
+		expiry             time.Duration
 		enclaveName        string
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.DurationVar(&expiry, "expiry", 0, "Assign the policy only for the given duration")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -168,15 +204,32 @@ func assignPolicyCmd(args []string) {
 	if cmd.NArg() == 1 {
 		cli.Fatal("no identity specified. See 'kes policy assign --help'")
 	}
+	if expiry < 0 {
+		cli.Fatalf("invalid expiry '%v'. See 'kes policy assign --help'", expiry)
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 
 	policy := cmd.Arg(0)
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	client := newClient(insecureSkipVerify)
+	enclave := client.Enclave(enclaveName)
 
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	var expiresAt time.Time
+	if expiry > 0 {
+		expiresAt = time.Now().Add(expiry)
+	}
 	for _, identity := range cmd.Args()[1:] { // cmd.Arg(0) is the policy
-		if err := enclave.AssignPolicy(ctx, policy, kes.Identity(identity)); err != nil {
+		var err error
+		if expiry > 0 {
+			err = kesclient.AssignPolicyUntil(ctx, client, enclaveName, policy, kes.Identity(identity), expiresAt)
+		} else {
+			err = enclave.AssignPolicy(ctx, policy, kes.Identity(identity))
+		}
+		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
 			}
@@ -343,7 +396,9 @@ const infoPolicyCmdUsage = `Usage:
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
-        --json               Print policy in JSON format.
+        --json               Print policy in JSON format. Same as '--output json'.
+    -o, --output <format>    Print output in the given format: table, json or
+                             yaml. Defaults to $KES_OUTPUT or table.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -368,6 +423,7 @@ func infoPolicyCmd(args []string) {
 		enclaveName        string
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print policy in JSON format.")
+	cmd.VarP(&outputFlag, "output", "o", "Print output in the given format")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
@@ -380,6 +436,9 @@ func infoPolicyCmd(args []string) {
 	if cmd.NArg() == 0 {
 		cli.Fatal("no policy name specified. See 'kes policy show --help'")
 	}
+	if err := outputFlag.Parse(jsonFlag); err != nil {
+		cli.Fatalf("%v. See 'kes policy show --help'", err)
+	}
 
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
@@ -393,12 +452,8 @@ func infoPolicyCmd(args []string) {
 		}
 		cli.Fatal(err)
 	}
-	if jsonFlag {
-		encoder := json.NewEncoder(os.Stdout)
-		if isTerm(os.Stdout) {
-			encoder.SetIndent("", "  ")
-		}
-		if err = encoder.Encode(info); err != nil {
+	if !outputFlag.IsTable() {
+		if err = outputFlag.Encode(os.Stdout, info); err != nil {
 			cli.Fatal(err)
 		}
 	} else {
@@ -459,13 +514,15 @@ func showPolicyCmd(args []string) {
 		cli.Fatal("no policy name specified. See 'kes policy show --help'")
 	}
 
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 	name := cmd.Arg(0)
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
 
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
-	policy, err := enclave.GetPolicy(ctx, name)
+	policy, _, err := kesclient.ReadPolicy(ctx, newClient(insecureSkipVerify), enclaveName, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
@@ -473,23 +530,11 @@ func showPolicyCmd(args []string) {
 		cli.Fatalf("failed to show policy '%s': %v", name, err)
 	}
 	if !isTerm(os.Stdout) || jsonFlag {
-		type Response struct {
-			Allow     []string     `json:"allow,omitempty"`
-			Deny      []string     `json:"deny,omitempty"`
-			CreatedAt time.Time    `json:"created_at,omitempty"`
-			CreatedBy kes.Identity `json:"created_by,omitempty"`
-		}
 		encoder := json.NewEncoder(os.Stdout)
 		if isTerm(os.Stdout) {
 			encoder.SetIndent("", "  ")
 		}
-		err = encoder.Encode(Response{
-			Allow:     policy.Allow,
-			Deny:      policy.Deny,
-			CreatedAt: policy.Info.CreatedAt,
-			CreatedBy: policy.Info.CreatedBy,
-		})
-		if err != nil {
+		if err = encoder.Encode(policy); err != nil {
 			cli.Fatalf("failed to show policy '%s': %v", name, err)
 		}
 	} else {
@@ -515,16 +560,47 @@ func showPolicyCmd(args []string) {
 				fmt.Println("  · " + rule)
 			}
 		}
+		if len(policy.Certificates) > 0 {
+			if len(policy.Allow) > 0 || len(policy.Deny) > 0 {
+				fmt.Println()
+			}
+			header := tui.NewStyle().Bold(true).Foreground(Cyan)
+			fmt.Println(header.Render("Certificates:"))
+			for _, rule := range profileRules(policy.Certificates) {
+				fmt.Println("  · " + rule)
+			}
+		}
+		if policy.MaxRandomBytes > 0 {
+			if len(policy.Allow) > 0 || len(policy.Deny) > 0 || len(policy.Certificates) > 0 {
+				fmt.Println()
+			}
+			header := tui.NewStyle().Bold(true).Foreground(Cyan)
+			fmt.Println(header.Render("Max. random bytes:"), policy.MaxRandomBytes)
+		}
+		if policy.Tokens != nil {
+			if len(policy.Allow) > 0 || len(policy.Deny) > 0 || len(policy.Certificates) > 0 || policy.MaxRandomBytes > 0 {
+				fmt.Println()
+			}
+			header := tui.NewStyle().Bold(true).Foreground(Cyan)
+			fmt.Println(header.Render("Tokens:"), tokenRule(policy.Tokens))
+		}
+		if policy.MaxSSHTTL != "" {
+			if len(policy.Allow) > 0 || len(policy.Deny) > 0 || len(policy.Certificates) > 0 || policy.MaxRandomBytes > 0 || policy.Tokens != nil {
+				fmt.Println()
+			}
+			header := tui.NewStyle().Bold(true).Foreground(Cyan)
+			fmt.Println(header.Render("Max. SSH TTL:"), policy.MaxSSHTTL)
+		}
 
 		fmt.Println()
 		header := tui.NewStyle().Bold(true).Foreground(Cyan)
-		if !policy.Info.CreatedAt.IsZero() {
-			year, month, day := policy.Info.CreatedAt.Local().Date()
-			hour, min, sec := policy.Info.CreatedAt.Local().Clock()
+		if !policy.CreatedAt.IsZero() {
+			year, month, day := policy.CreatedAt.Local().Date()
+			hour, min, sec := policy.CreatedAt.Local().Clock()
 			fmt.Printf("\n%s %04d-%02d-%02d %02d:%02d:%02d\n", header.Render("Created at:"), year, month, day, hour, min, sec)
 		}
-		if !policy.Info.CreatedBy.IsUnknown() {
-			fmt.Println(header.Render("Created by:"), policy.Info.CreatedBy)
+		if !policy.CreatedBy.IsUnknown() {
+			fmt.Println(header.Render("Created by:"), policy.CreatedBy)
 		} else {
 			fmt.Println(header.Render("Created by:"), "<unknown>")
 		}
@@ -0,0 +1,90 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const randomCmdUsage = `Usage:
+    kes random [options] [<length>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Prints <length> cryptographically secure random bytes, base64-encoded,
+generated by the server. By default, 32 bytes. The policy of the identity
+limits the length. Without a 'max_random_bytes' policy setting, at most
+1024 bytes can be requested at once.
+
+Examples:
+    $ kes random
+    $ kes random 64
+`
+
+func randomCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, randomCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes random --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes random --help'")
+	}
+
+	length := 32
+	if cmd.NArg() == 1 {
+		n, err := strconv.Atoi(cmd.Arg(0))
+		if err != nil || n <= 0 {
+			cli.Fatalf("invalid length '%s'. See 'kes random --help'", cmd.Arg(0))
+		}
+		length = n
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	b, err := kesclient.RandomBytes(ctx, newClient(insecureSkipVerify), enclaveName, length)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to generate random bytes: %v", err)
+	}
+	if isTerm(os.Stdout) {
+		fmt.Println(base64.StdEncoding.EncodeToString(b))
+	} else {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			Bytes []byte `json:"bytes"`
+		}{Bytes: b})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/secret"
+	"github.com/minio/kes/kesclient"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -31,6 +33,9 @@ const secretCmdUsage = `Usage:
 
 Commands:
     create                   Create a new secret.
+    update                   Update the value of a secret.
+    rotation                 Set the rotation schedule of a secret.
+    rotate                   Rotate a secret immediately.
     info                     Get information about a secret. 
     show                     Display a secret.
     ls                       List secrets.
@@ -45,11 +50,14 @@ func secretCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, secretCmdUsage) }
 
 	subCmds := commands{
-		"create": createSecretCmd,
-		"info":   describeSecretCmd,
-		"show":   showSecretCmd,
-		"ls":     lsSecretCmd,
-		"rm":     deleteSecretCmd,
+		"create":   createSecretCmd,
+		"update":   updateSecretCmd,
+		"rotation": rotationSecretCmd,
+		"rotate":   rotateSecretCmd,
+		"info":     describeSecretCmd,
+		"show":     showSecretCmd,
+		"ls":       lsSecretCmd,
+		"rm":       deleteSecretCmd,
 	}
 
 	if len(args) < 2 {
@@ -120,42 +128,118 @@ func createSecretCmd(args []string) {
 		cli.Fatal("too many arguments. See 'kes secret create --help'")
 	}
 
-	var value []byte
+	value := readSecretValue(cmd, filename)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	name := cmd.Arg(0)
+	enclave := newEnclave(enclaveName, insecureSkipVerify)
+	if err := enclave.CreateSecret(ctx, name, value, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to create secret %q: %v", name, err)
+	}
+}
+
+const updateSecretCmdUsage = `Usage:
+    kes secret update [options] <name> <value>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --file <name>        Use the file content as secret value.
+
+    -h, --help               Print command line options.
+
+Replaces the value of an existing secret and prints its new
+version. Each update increments the version of the secret.
+
+Examples:
+    $ kes secret update my-secret
+      Enter secret:
+
+    $ kes secret update my-secret password456
+    $ kes secret update my-secret --file password.txt
+`
+
+func updateSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, updateSecretCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		filename           string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.StringVar(&filename, "file", "", "Use the file content as secret value")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret update --help'", err)
+	}
+
+	switch n := cmd.NArg(); {
+	case n == 0:
+		cli.Fatal("no secret name specified. See 'kes secret update --help'")
+	case n == 2 && filename != "":
+		cli.Fatalf("cannot read from '%s' when a secret value is specified. See 'kes secret update --help'", filename)
+	case n > 2:
+		cli.Fatal("too many arguments. See 'kes secret update --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	value := readSecretValue(cmd, filename)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	name := cmd.Arg(0)
+	version, err := kesclient.UpdateSecret(ctx, newClient(insecureSkipVerify), enclaveName, name, value)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to update secret %q: %v", name, err)
+	}
+	cli.Printf("Updated secret %q to version %d\n", name, version)
+}
+
+// readSecretValue returns the secret value specified as second
+// argument of cmd, read from the file or entered interactively.
+func readSecretValue(cmd *flag.FlagSet, filename string) []byte {
 	switch {
 	case cmd.NArg() == 2:
-		value = []byte(cmd.Arg(1))
+		return []byte(cmd.Arg(1))
 	case filename != "":
 		file, err := os.Open(filename)
 		if err != nil {
-			cli.Fatalf("failed to read '%s': %v", err)
+			cli.Fatalf("failed to read '%s': %v", filename, err)
 		}
 		defer file.Close()
 
 		var buffer bytes.Buffer
-		if _, err = io.Copy(&buffer, mem.LimitReader(os.Stdin, secret.MaxSize)); err != nil {
-			cli.Fatalf("failed to read '%s': %v", err)
+		if _, err = io.Copy(&buffer, mem.LimitReader(file, secret.MaxValueSize+1)); err != nil {
+			cli.Fatalf("failed to read '%s': %v", filename, err)
 		}
-		value = buffer.Bytes()
+		if buffer.Len() > int(secret.MaxValueSize) {
+			cli.Fatalf("failed to read '%s': secret value must not exceed %s", filename, mem.FormatSize(secret.MaxValueSize, 'B', -1))
+		}
+		return buffer.Bytes()
 	default:
 		fmt.Print("Enter secret: ")
-		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
 		fmt.Println()
 		if err != nil {
 			cli.Fatalf("failed to read secret input: %v", err)
 		}
-		value = secret
-	}
-
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
-	defer cancel()
-
-	name := cmd.Arg(0)
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	if err := enclave.CreateSecret(ctx, name, value, nil); err != nil {
-		if errors.Is(err, context.Canceled) {
-			os.Exit(1)
-		}
-		cli.Fatalf("failed to create secret %q: %v", name, err)
+		return value
 	}
 }
 
@@ -209,14 +293,16 @@ func describeSecretCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 	name := cmd.Arg(0)
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	info, err := enclave.DescribeSecret(ctx, name)
+	info, err := kesclient.DescribeSecret(ctx, newClient(insecureSkipVerify), enclaveName, name)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
-		cli.Fatalf("failed to describe keys: %v", err)
+		cli.Fatalf("failed to describe secret %q: %v", name, err)
 	}
 	if jsonFlag {
 		if err = json.NewEncoder(os.Stdout).Encode(info); err != nil {
@@ -242,6 +328,20 @@ func describeSecretCmd(args []string) {
 		faint.Render(fmt.Sprintf("%-11s", "Type")),
 		info.Type.String(),
 	)
+	fmt.Println(
+		faint.Render(fmt.Sprintf("%-11s", "Version")),
+		info.Version,
+	)
+	if len(info.Versions) > 1 {
+		versions := make([]string, 0, len(info.Versions))
+		for _, v := range info.Versions {
+			versions = append(versions, strconv.FormatUint(v.Version, 10))
+		}
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Versions")),
+			strings.Join(versions, ", "),
+		)
+	}
 	fmt.Println(
 		faint.Render(fmt.Sprintf("%-11s", "Created At")),
 		fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec),
@@ -257,6 +357,160 @@ func describeSecretCmd(args []string) {
 			info.CreatedBy,
 		)
 	}
+	if info.Rotation != nil {
+		year, month, day := info.Rotation.NextRotation.Local().Date()
+		hour, min, sec := info.Rotation.NextRotation.Local().Clock()
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Rotation")),
+			fmt.Sprintf("every %v, next at %04d-%02d-%02d %02d:%02d:%02d", info.Rotation.Interval, year, month, day, hour, min, sec),
+		)
+		fmt.Println(
+			faint.Render(fmt.Sprintf("%-11s", "Endpoint")),
+			info.Rotation.Endpoint,
+		)
+	}
+}
+
+const rotationSecretCmdUsage = `Usage:
+    kes secret rotation [options] <name>
+
+Options:
+    --endpoint <URL>         The https URL of the rotation webhook or a
+                             postgres:// or mysql:// database URL.
+    --interval <DURATION>    The time between two rotations. (min: 1m)
+    --disable                Disable the rotation of the secret.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Sets the rotation schedule of a secret. Once the interval has passed since
+the secret has been modified last, the server sends a POST request with the
+enclave, name and current version of the secret as JSON to the webhook. The
+webhook has to respond with a JSON object containing the new value, e.g.
+{"bytes":"<base64>"}, that the server stores as new version of the secret.
+
+If the endpoint is a PostgreSQL or MySQL database URL, the secret value must
+be the current password of the database user in the URL. The URL must not
+contain the password. The server connects as this user, changes its password
+to a new random password and stores it as new version of the secret. Database
+connections use TLS unless the URL specifies otherwise, e.g. '?sslmode=disable'
+for PostgreSQL or '?tls=false' for MySQL.
+
+Within a cluster, only the leader rotates secrets.
+
+Examples:
+    $ kes secret rotation --endpoint https://rotate.example.com/db --interval 24h my-secret
+    $ kes secret rotation --endpoint postgres://app@db.example.com:5432/app --interval 720h db-app
+    $ kes secret rotation --disable my-secret
+`
+
+func rotationSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rotationSecretCmdUsage) }
+
+	var (
+		endpoint           string
+		interval           time.Duration
+		disableFlag        bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringVar(&endpoint, "endpoint", "", "The rotation webhook or database URL")
+	cmd.DurationVar(&interval, "interval", 0, "The time between two rotations")
+	cmd.BoolVar(&disableFlag, "disable", false, "Disable the rotation of the secret")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret rotation --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no secret name specified. See 'kes secret rotation --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes secret rotation --help'")
+	case disableFlag && (endpoint != "" || interval != 0):
+		cli.Fatal("'--disable' cannot be combined with '--endpoint' or '--interval'. See 'kes secret rotation --help'")
+	case !disableFlag && (endpoint == "" || interval == 0):
+		cli.Fatal("'--endpoint' and '--interval' are required. See 'kes secret rotation --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	name := cmd.Arg(0)
+	if err := kesclient.SetSecretRotation(ctx, newClient(insecureSkipVerify), enclaveName, name, endpoint, interval); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to set rotation of secret %q: %v", name, err)
+	}
+}
+
+const rotateSecretCmdUsage = `Usage:
+    kes secret rotate [options] <name>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Rotates a secret immediately by invoking its rotation webhook, or changing
+the database password, and prints the new version of the secret. The secret must have a rotation schedule.
+See 'kes secret rotation --help'.
+
+Examples:
+    $ kes secret rotate my-secret
+`
+
+func rotateSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rotateSecretCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret rotate --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no secret name specified. See 'kes secret rotate --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes secret rotate --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	name := cmd.Arg(0)
+	version, err := kesclient.RotateSecret(ctx, newClient(insecureSkipVerify), enclaveName, name)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to rotate secret %q: %v", name, err)
+	}
+	cli.Printf("Rotated secret %q to version %d\n", name, version)
 }
 
 const showSecretCmdUsage = `Usage:
@@ -264,6 +518,8 @@ const showSecretCmdUsage = `Usage:
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
+        --version <N>        Display the version N instead of the current
+                             version of the secret.
     -p, --plain              Print the raw secret without any styling.
         --json               Print the secret in JSON format. 
         --color <when>       Specify when to use colored output. The automatic
@@ -277,6 +533,7 @@ Options:
 
 Examples:
     $ kes secret show my-secret
+    $ kes secret show --version 2 my-secret
 `
 
 func showSecretCmd(args []string) {
@@ -284,6 +541,7 @@ func showSecretCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, showSecretCmdUsage) }
 
 	var (
+		version            uint64
 		plainFlag          bool
 		jsonFlag           bool
 		colorFlag          colorOption
@@ -294,6 +552,7 @@ func showSecretCmd(args []string) {
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.BoolVarP(&plainFlag, "plain", "p", false, "Print the raw secret without any styling")
+	cmd.Uint64Var(&version, "version", 0, "Display the version N of the secret")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -312,14 +571,16 @@ func showSecretCmd(args []string) {
 	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancelCtx()
 
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
 	name := cmd.Arg(0)
-	enclave := newEnclave(enclaveName, insecureSkipVerify)
-	secret, info, err := enclave.ReadSecret(ctx, name)
+	secret, info, err := kesclient.ReadSecretVersion(ctx, newClient(insecureSkipVerify), enclaveName, name, version)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
-		cli.Fatalf("failed to describe keys: %v", err)
+		cli.Fatalf("failed to read secret %q: %v", name, err)
 	}
 	if plainFlag {
 		fmt.Println(string(secret))
@@ -329,12 +590,14 @@ func showSecretCmd(args []string) {
 		type JSON struct {
 			Bytes     []byte       `json:"bytes"`
 			Name      string       `json:"name"`
+			Version   uint64       `json:"version"`
 			CreatedAt time.Time    `json:"created_at,omitempty"`
 			CreatedBy kes.Identity `json:"created_by,omitempty"`
 		}
 		err = json.NewEncoder(os.Stdout).Encode(JSON{
 			Bytes:     secret,
 			Name:      info.Name,
+			Version:   info.Version,
 			CreatedAt: info.CreatedAt,
 			CreatedBy: info.CreatedBy,
 		})
@@ -361,6 +624,10 @@ func showSecretCmd(args []string) {
 		faint.Render(fmt.Sprintf("%-11s", "Type")),
 		info.Type.String(),
 	)
+	fmt.Println(
+		faint.Render(fmt.Sprintf("%-11s", "Version")),
+		info.Version,
+	)
 	fmt.Println(
 		faint.Render(fmt.Sprintf("%-11s", "Created At")),
 		fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, min, sec),
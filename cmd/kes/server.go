@@ -14,24 +14,26 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
 	"time"
 
+	"aead.dev/mem"
 	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/log"
 	xlog "github.com/minio/kes/internal/log"
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/internal/sys/fs"
+	"github.com/minio/kes/internal/watchdog"
 	flag "github.com/spf13/pflag"
 )
 
@@ -40,13 +42,18 @@ const serverCmdUsage = `Usage:
 
 Options:
     --addr <IP:PORT>         The address of the server (default: 0.0.0.0:7373)
-    --config <PATH>          Path to the server configuration file
+    --config <PATH>          Path to the server configuration file. Specify it
+                             multiple times to run multiple edge servers
 
     --key <PATH>             Path to the TLS private key. It takes precedence over
                              the config file
     --cert <PATH>            Path to the TLS certificate. It takes precedence over
                              the config file
 
+    --ip-stack {dual|ipv4|ipv6}
+                             The IP versions the server listens on if its address
+                             is unspecified, e.g. 0.0.0.0 or [::]. (default: dual)
+
     --auth {on|off}          Controls how the server handles mTLS authentication.
                              By default, the server requires a client certificate
                              and verifies that certificate has been issued by a
@@ -55,26 +62,404 @@ Options:
                                 Require and verify      : --auth=on (default)
                                 Require but don't verify: --auth=off
 
+    --ui                     Serve the web console under /ui/. Browsers authenticate
+                             with a client certificate, like any other client
+
+    --metrics-addr <IP:PORT> Serve the metrics and health APIs on a separate
+                             listener that does not require client certificates
+    --metrics-tls            Serve the metrics listener over TLS with the server
+                             certificate instead of plaintext HTTP
+    --metrics-identities <N> Export the request metrics of the N identities that
+                             sent the most requests with an identity label.
+                             (default: 0)
+    --metrics-enclave-label  Export the key operation metrics with an enclave
+                             label. Only for stateful servers
+    --metrics-key-prefix <PREFIX>
+                             Export the key operation metrics with a key_prefix
+                             label. May be specified multiple times
+
+    --scim-addr <IP:PORT>    Serve the SCIM provisioning API on a separate TLS
+                             listener. Only for stateful servers
+    --scim-enclave <NAME>    The enclave of identities provisioned via SCIM.
+                             (default: default)
+    --scim-default-policy <NAME>
+                             The policy of SCIM users that are not member of any
+                             group. (default: scim-user)
+    --signed-requests        Require clients of the metrics and SCIM listeners
+                             to sign requests with their bearer token and reject
+                             replayed requests
+    --clock-skew <DURATION>  The max. difference between the timestamp of signed
+                             requests and the server clock. (default: 5m)
+
+    --max-requests <N>       The max. number of requests handled concurrently.
+                             Further requests are rejected with 503 Service
+                             Unavailable. (default: unlimited)
+    --max-body-bytes <SIZE>  The max. aggregate size of the request bodies of
+                             all requests handled concurrently, e.g. 64MiB.
+                             Further requests are rejected with 429 Too Many
+                             Requests. (default: unlimited)
+    --max-enclave-requests <N>
+                             The max. number of requests handled concurrently per
+                             enclave and identity. Up to N further requests wait
+                             for a free slot. (default: unlimited)
+
+    --watchdog-dump <TARGET> Write diagnostics dumps to a directory or an S3
+                             bucket, like s3://<BUCKET>/<PREFIX>
+    --watchdog-goroutines <N>
+                             Write a dump when there are more than N goroutines
+    --watchdog-memory <SIZE> Write a dump when the memory usage (RSS) exceeds
+                             SIZE, e.g. 2GiB
+    --watchdog-blocked <N>   Write a dump when N request handlers are still
+                             running after their request has timed out
+
+    --log-level <level>      The level of the error log. The server only logs
+                             errors with this or a higher level. Valid levels are:
+                             debug, info (default), warn and error
+    --log-format <format>    The format of the error log. Valid formats are:
+                             text (default) and json
+    --audit-decisions <level>
+                             Include the policy and rule that allowed or denied
+                             a request in its audit event. Valid levels are:
+                             off (default), denied and all
+
+    --authorizer <URL>       URL of an external authorization service. Requests
+                             that pass the policy checks must also be allowed
+                             by the service. Only for stateful servers
+
+    --ca-max-client-ttl <DURATION>
+                             The max. lifetime of client certificates issued by
+                             the built-in CA. (default: 168h)
+    --ca-max-server-ttl <DURATION>
+                             The max. lifetime of server certificates issued by
+                             the built-in CA. (default: 2160h)
+    --ca-crl-ttl <DURATION>  The time after which clients should fetch a new
+                             certificate revocation list. (default: 24h)
+    --ca-max-ssh-ttl <DURATION>
+                             The max. lifetime of SSH certificates. (default: 24h)
+    --max-token-ttl <DURATION>
+                             The max. lifetime of JWTs. (default: 24h)
+
+    --key-algorithms <ALGORITHMS>
+                             Comma-separated list of algorithms allowed for new
+                             keys. (default: all supported algorithms)
+    --default-key-algorithm <ALGORITHM>
+                             The algorithm of new keys created without an explicit
+                             algorithm. (default: depends on the CPU)
+    --min-key-size <BITS>    The min. key size of new keys in bits. (default: 0)
+    --reserved-prefix <PREFIX>=<POLICIES>
+                             Reserve a key and policy name prefix, like 'sys/',
+                             for the comma-separated policies. Only admins and
+                             identities assigned to one of the policies can create
+                             keys and policies within the prefix. Can be specified
+                             multiple times. Only for stateful servers
+    --max-name-length <BYTES>
+                             The max. length of key, policy and secret names
+                             in bytes, at most 255. (default: 80)
+    --extended-names         Allow '.' characters in names, like 'key.v2'.
+
+    --bootstrap <PATH>       Path to an init configuration file. If the <PATH>
+                             argument has not been initialized yet, the server
+                             initializes it with the system admin, enclaves,
+                             policies and identities of the init configuration.
+                             Otherwise, the file is ignored
+
     -h, --help               Show list of command-line options
 
 Starts a KES server. The server address can be specified in the config file but
 may be overwritten by the --addr flag. If omitted the IP defaults to 0.0.0.0 and
 the PORT to 7373.
 
+A gateway runs one edge server per --config file within the same process. Each
+edge server listens on the address of its config file and uses its own keystore,
+policies and logs. All edge servers share the metrics, the metrics listener and
+the limits of --max-requests and --max-body-bytes. On SIGHUP, each edge server
+reloads its config file. An edge server whose config file cannot be reloaded
+keeps serving requests with its current config and publishes a
+'config.reload_failed' event with the fingerprint of the current policy
+definitions. Once reloaded, it logs an audit
+event with the fingerprints of the previous and new policy definitions and the
+added, removed or modified policies and identities, and publishes corresponding
+events, e.g. 'policy.written', followed by a 'config.reloaded' event.
+
+By default, a server listening on an unspecified address, like 0.0.0.0:7373 or
+[::]:7373, accepts IPv4 and IPv6 connections. With --ip-stack=ipv6, it only accepts
+IPv6 connections, e.g. on IPv6-only hosts, and with --ip-stack=ipv4 only IPv4
+connections. The option applies to the metrics listener as well.
+
 The client TLS verification can be disabled by setting --auth=off. The server then
 accepts arbitrary client certificates but still maps them to policies. So, it disables
 authentication but not authorization.
 
+A stateful server runs as cluster follower if the 'cluster.leader' field of its
+init configuration contains the endpoint of the leader. A follower serves read
+requests itself but forwards write requests, like creating or deleting keys and
+writing policies, to the leader and returns the leader's response. The follower
+authenticates with its TLS certificate and passes the client certificate to the
+leader. Hence, the leader must list the follower identity as TLS proxy in the
+'tls.proxy.identity' field. The follower's data has to be kept in sync with the
+leader, e.g. by replicating the leader's data directory.
+
+The 'cluster.nodes' field lists the endpoints of all other cluster nodes and
+'cluster.id' the ID of the server itself, which defaults to the hostname. A
+server reports the status of all nodes, including their replication lag, to
+the system admin. It fetches the status of other nodes on behalf of the admin.
+Hence, all nodes must list each other as TLS proxy. The replication lag is the
+time since the most recent change of the leader's data directory that is not
+reflected by the node's data directory yet.
+
+Cluster nodes negotiate the version of the internal API with every request they
+send to each other. A node rejects requests of nodes that do not support any
+common wire version. Hence, during a rolling upgrade, requests between nodes
+with incompatible versions fail instead of modifying the state of the cluster.
+
+With --metrics-addr, the server serves /v1/metrics, /v1/health/live and
+/v1/health/ready on a separate listener, e.g. for Prometheus scrapers that
+cannot present a client certificate. The listener serves plaintext HTTP unless
+--metrics-tls is set. If the env. variable KES_METRICS_TOKEN is set, clients
+have to send its value as bearer token. The ready API returns 503 if the server
+cannot serve requests, e.g. because the keystore is not reachable.
+
+With --scim-addr, identity providers, like Okta or Azure AD, provision the
+identities of an enclave via SCIM 2.0 at https://<IP:PORT>/scim/v2. The listener
+uses the server certificate but does not require client certificates. Instead,
+identity providers have to send the value of the env. variable KES_SCIM_TOKEN as
+bearer token. SCIM users are identities and SCIM groups are policies. Creating
+a user assigns the identity to the --scim-default-policy, adding it to a group
+assigns it to the group's policy and removing it from the group assigns it to
+the default policy again. Deactivating a user lets its policy assignment expire
+and deleting a user deletes the identity. Policies are not created or deleted
+via SCIM. Instead, identity providers link their groups to existing policies by
+name. The enclave admin policy and admin identities cannot be provisioned. Within
+a cluster, only the leader serves the SCIM API.
+
+With --signed-requests, clients of the metrics and SCIM listeners don't send
+their bearer token. Instead, they sign each request with the token and send a
+timestamp, a random nonce and the signature as X-Kes-Timestamp, X-Kes-Nonce and
+X-Kes-Signature headers. The signature is the hex-encoded HMAC-SHA256, keyed by
+the token, of the request method, URI, timestamp, nonce and hex-encoded SHA-256
+body hash, separated by newlines. The server rejects requests with a timestamp
+that differs more than --clock-skew from its clock and requests with a nonce it
+has already seen. Hence, captured requests cannot be replayed.
+
+The server tracks the number of requests, errors and failures per client
+identity for up to 1000 identities. The system admin can list the identities
+that sent the most requests with 'kes top'. With --metrics-identities, the
+metrics API also exports the statistics of the top N identities with an
+'identity' label.
+
+The server counts the successful generate, encrypt and decrypt operations
+of each key. With --metrics-enclave-label, the metric has an 'enclave' label.
+With --metrics-key-prefix, it has a 'key_prefix' label that contains the
+longest of the given prefixes the key name starts with, or is empty if there
+is no such prefix. For example, --metrics-key-prefix tenants/acme/ and
+--metrics-key-prefix tenants/umbrella/ break key usage down by tenant. Each
+label multiplies the number of exported time series. Hence, there should be
+few enclaves and at most 100 prefixes.
+
+The request latency histogram is also exported as native histogram to
+scrapers that accept the protobuf format. Requests that carry a W3C
+'traceparent' header of a sampled trace are recorded with their trace
+ID as exemplar, which scrapers receive in the OpenMetrics format.
+
+With --max-requests and --max-body-bytes, the server sheds load instead
+of running out of memory during request bursts. Each request reserves the
+size of its body, or the max. body size of the API if the client does not
+send a content length. Log and event streams are not limited.
+
+With --watchdog-dump and at least one of --watchdog-goroutines,
+--watchdog-memory and --watchdog-blocked, the server checks its goroutines,
+memory usage and request handlers every 10 seconds. Once a threshold is
+crossed, it writes a goroutine dump, a heap profile and a summary to the
+target, at most once every 15 minutes. An S3 target URL may specify the
+region and, for S3-compatible object stores, the endpoint, like:
+s3://<BUCKET>/<PREFIX>?region=<REGION>&endpoint=<URL>. The S3 credentials
+are read from the AWS environment variables or the EC2 instance metadata.
+
+With --max-enclave-requests, a stateful server isolates enclaves, and a
+gateway its key namespaces, from each other. A burst of requests for one
+enclave only occupies the enclave's own request slots and cannot starve
+other enclaves. The slots are kept per identity, such that clients without
+access to an enclave cannot starve its identities. Requests wait for a free slot
+until the API times out and are rejected with 503 Service Unavailable if too
+many requests are waiting already.
+
+With --authorizer, a stateful server layers an external entitlement system on
+top of its policies. Once a request of an identity, that is not an admin, has
+passed the policy checks, the server sends the identity, policy, enclave and
+request method and path as JSON to the authorization service. The service has
+to respond with {"allow": true} to allow the request. The server authenticates
+with its TLS certificate and caches decisions for 30 seconds. Requests are
+rejected with 503 if the service is not available. Gateways configure the
+service in the 'authorizer' section of their config file.
+
+A stateful server runs a built-in certificate authority (CA) whose private key
+is kept within the vault and only accessible while the vault is unsealed. The
+system admin can issue server and client certificates with 'kes ca issue' and
+revoke them with 'kes ca revoke'. Identities can renew their own certificate
+with 'kes identity rotate'. The CA certificate and its certificate revocation
+list (CRL) are available to any client, regardless of its policy, under
+/v1/ca/cert and /v1/ca/crl. With --auth=on, the server accepts client certificates issued by
+the built-in CA in addition to the system CAs and rejects revoked certificates.
+
+A stateful server can also act as SSH certificate authority. Any key of an
+enclave can sign SSH user and host certificates with an Ed25519 CA key derived
+from the key. The policy of an identity controls which principals it may request
+certificates for. For example, the policy path '/v1/ssh/sign/my-ca/user/alice'
+allows signing user certificates for the principal 'alice' with the key 'my-ca'.
+SSH certificates expire after at most the policy's max_ssh_ttl or --ca-max-ssh-ttl,
+whichever is smaller, and not after the policy assignment of the requesting
+identity.
+
+Similarly, a key designated with 'kes token designate' can sign JWTs with an
+Ed25519 key derived from it, such that services can mint verifiable tokens
+without holding a private key. The 'sub' claim is always the requesting identity.
+The 'tokens' profile of a policy lists the audiences and custom claims identities
+may request. String claims may contain the template variables {{identity}},
+{{policy}}, {{enclave}} and {{key}}. Tokens expire after at most the profile's
+max_ttl or --max-token-ttl, whichever is smaller, and not after the policy
+assignment of the requesting identity.
+The public keys of all designated keys of an enclave are available to any client,
+regardless of its policy, as JWKS under /.well-known/jwks.json?enclave=<name>.
+
+Workloads can obtain X.509 certificates with 'kes cert issue'. The certificate
+profiles of a policy define which key acts as CA and which DNS names, IP ranges,
+usages and lifetimes an identity may request. Without a max_ttl, certificates
+expire after at most --ca-max-server-ttl and never after the policy assignment
+of the requesting identity.
+
+Identities can request random bytes, with 'kes random', up to the limit set
+by the 'max_random_bytes' field of their policy, 1024 bytes by default and at
+most 65536 bytes.
+
+Keys use AES256-GCM_SHA256 or, on CPUs without AES instructions, XCHACHA20-POLY1305
+unless 'kes key create --algorithm' chooses one. AES256-GCM-SIV (RFC 8452) keys
+tolerate nonce reuse. A key only decrypts ciphertexts produced by its algorithm.
+In FIPS mode, only AES256-GCM_SHA256 keys can be created and used.
+
+With --key-algorithms, --default-key-algorithm and --min-key-size, a stateful
+server restricts the algorithms of new keys in all enclaves. Each enclave may
+restrict them further with its own key policy, set by 'kes enclave create'.
+Gateways configure the 'key_policy' section of their config file, globally or
+per namespace. Requests to create or import a key with an algorithm that
+violates a policy are rejected with 400 Bad Request. The policies do not
+affect existing keys.
+
+Encrypt and generate requests with the query parameter envelope=true return
+a versioned ciphertext envelope, a MessagePack map containing the key name,
+key ID, algorithm, nonce and a SHA-256 hash of the context. Decrypt accepts
+envelopes as well as the default ciphertext format. Existing ciphertexts,
+including legacy JSON ciphertexts, can be re-wrapped into envelopes via the
+/v1/key/rewrap/<name> API, e.g. with 'kes migrate-ciphertext'.
+
+Each key also acts as static X25519 and P-256 key pair for key agreements. With
+'kes key agree', the server performs ECDH with a caller-supplied public key and
+returns a shared secret derived via HKDF-SHA256. The raw ECDH output and the
+private key never leave the server. In FIPS mode, only P-256 is available.
+
+Sensitive values, like card numbers, can be replaced by random tokens with
+'kes tokenize'. Tokens belong to a scope and are stored encrypted within the
+enclave. Revealing the values with 'kes detokenize' is a separate API, such
+that policies can allow /v1/tokenize/<scope> without /v1/detokenize/<scope>.
+
+Secrets keep up to 10 versions. A secret with a rotation schedule, set with 'kes
+secret rotation', gets a new version from its https webhook once the rotation
+interval has passed. A secret holding a PostgreSQL or MySQL password can be
+rotated by the server itself by using a postgres:// or mysql:// URL of the
+database user as rotation endpoint. Within a cluster, only the leader rotates
+secrets. The server uses the system root CAs to verify the webhook's and the
+database's TLS certificate.
+
+With --log-format=json, the server writes each error log entry as JSON object
+containing the time, level, message and, for requests, the component, request
+ID, enclave and identity. The level can be changed at runtime with
+'kes log --level'.
+
+A stateful server can be bootstrapped non-interactively with --bootstrap.
+The bootstrap is performed once, at the first start. Subsequent starts
+with the same flag leave the existing data unchanged. A bootstrap that
+fails is started over at the next start. Hence, the flag can
+be specified unconditionally, e.g. in a container entrypoint. The server
+refuses to bootstrap a non-empty directory without '.init' file.
+
+The server reports its lifecycle to the service manager. Under systemd, with
+Type=notify or Type=notify-reload, it notifies systemd once it is ready, while
+reloading its config on SIGHUP and when stopping, and sends watchdog keep-alives
+if WatchdogSec is set. With NotifyAccess=all, systemd tracks the new process
+after a SIGUSR2 handover. On Windows, the server can run as Windows service. It
+reports itself as running once ready, stops when the service is stopped and
+writes its output to the Windows event log with the source 'KES'.
+
+On SIGUSR2, the server starts the current 'kes' binary with the same arguments
+and hands its listening sockets over to the new process. Once the new process
+listens, the old one stops accepting connections and exits after completing
+active requests. Hence, the binary can be upgraded without refusing any
+connections. If the new process fails to start, the old one keeps serving.
+
 Examples:
     $ kes server --config config.yml --auth =off
+    $ kes server --config site-a.yml --config site-b.yml
+    $ kes server --bootstrap /etc/kes/init.yml /var/lib/kes
 `
 
 type serverConfig struct {
 	Address     string
+	Network     string
 	ConfigPath  string
 	PrivateKey  string
 	Certificate string
 	TLSAuth     string
+	UI          bool
+	Bootstrap   string
+	Authorizer  string
+	MetricsAddr string
+	MetricsTLS  bool
+	LogLevel    log.Level
+	LogJSON     bool
+
+	// SCIMAddr, if not empty, is the address of
+	// the SCIM listener that provisions identities
+	// of the SCIMEnclave.
+	SCIMAddr          string
+	SCIMEnclave       string
+	SCIMDefaultPolicy string
+
+	// AuditDecisions controls for which requests audit
+	// events contain the policy decision.
+	AuditDecisions audit.DecisionLevel
+
+	MetricsIdentities int
+
+	// MetricsKeyLabels controls the optional
+	// labels of the key operation metrics.
+	MetricsKeyLabels metric.KeyLabels
+
+	// Admission, if not nil, limits the number and
+	// size of requests handled concurrently.
+	Admission *api.Admission
+
+	// Replay, if not nil, requires signed requests on
+	// the metrics and SCIM listeners.
+	Replay *api.ReplayGuard
+
+	// Watchdog, if not nil, writes diagnostics dumps
+	// once a watchdog threshold has been crossed.
+	Watchdog *watchdog.Config
+
+	// CA controls the lifetimes of certificates
+	// issued by the built-in certificate authority.
+	CA api.CAConfig
+
+	// KeyPolicy, if not nil, restricts the algorithms
+	// of new keys in all enclaves.
+	KeyPolicy *key.AlgorithmPolicy
+
+	// ReservedPrefixes restricts which identities can create
+	// keys and policies with a reserved name prefix.
+	ReservedPrefixes api.ReservedPrefixes
+
+	// Names controls which key, policy and
+	// secret names the server accepts.
+	Names api.NameRules
 }
 
 func serverCmd(args []string) {
@@ -82,17 +467,87 @@ func serverCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, serverCmdUsage) }
 
 	var (
-		addrFlag     string
-		configFlag   string
-		tlsKeyFlag   string
-		tlsCertFlag  string
-		mtlsAuthFlag string
+		addrFlag      string
+		ipStackFlag   string
+		configFlags   []string
+		tlsKeyFlag    string
+		tlsCertFlag   string
+		mtlsAuthFlag  string
+		uiFlag        bool
+		bootstrapFlag string
+		metricsAddr   string
+		metricsTLS    bool
+		metricsIDs    int
+		metricsEnclv  bool
+		metricsPrefix []string
+		scimAddr      string
+		scimEnclave   string
+		scimPolicy    string
+		signedReqs    bool
+		clockSkew     time.Duration
+		maxRequests   int64
+		maxEnclaveReq int64
+		maxBodyFlag   string
+		watchdogDump  string
+		watchdogGos   int
+		watchdogMem   string
+		watchdogBlock int
+		authzFlag     string
+		logLevelFlag  string
+		logFormatFlag string
+		decisionsFlag string
+		caClientTTL   time.Duration
+		caServerTTL   time.Duration
+		caCRLTTL      time.Duration
+		caSSHTTL      time.Duration
+		tokenTTL      time.Duration
+		keyAlgsFlag   []string
+		defaultKeyAlg string
+		minKeySize    int
+		reservedFlags []string
+		maxNameLength int
+		extendedNames bool
 	)
 	cmd.StringVar(&addrFlag, "addr", "", "The address of the server")
-	cmd.StringVar(&configFlag, "config", "", "Path to the server configuration file")
+	cmd.StringVar(&ipStackFlag, "ip-stack", "dual", "The IP versions the server listens on")
+	cmd.StringArrayVar(&configFlags, "config", nil, "Path to the server configuration file")
 	cmd.StringVar(&tlsKeyFlag, "key", "", "Path to the TLS private key")
 	cmd.StringVar(&tlsCertFlag, "cert", "", "Path to the TLS certificate")
 	cmd.StringVar(&mtlsAuthFlag, "auth", "", "Controls how the server handles mTLS authentication")
+	cmd.BoolVar(&uiFlag, "ui", false, "Serve the web console")
+	cmd.StringVar(&bootstrapFlag, "bootstrap", "", "Path to an init configuration file")
+	cmd.StringVar(&metricsAddr, "metrics-addr", "", "Serve the metrics and health APIs on a separate listener")
+	cmd.BoolVar(&metricsTLS, "metrics-tls", false, "Serve the metrics listener over TLS")
+	cmd.IntVar(&metricsIDs, "metrics-identities", 0, "Export the request metrics of the top N identities")
+	cmd.BoolVar(&metricsEnclv, "metrics-enclave-label", false, "Export the key operation metrics with an enclave label")
+	cmd.StringArrayVar(&metricsPrefix, "metrics-key-prefix", nil, "Export the key operation metrics with a key prefix label")
+	cmd.StringVar(&scimAddr, "scim-addr", "", "Serve the SCIM provisioning API on a separate listener")
+	cmd.StringVar(&scimEnclave, "scim-enclave", "", "The enclave of identities provisioned via SCIM")
+	cmd.StringVar(&scimPolicy, "scim-default-policy", "", "The policy of SCIM users that are not member of any group")
+	cmd.BoolVar(&signedReqs, "signed-requests", false, "Require signed requests on the metrics and SCIM listeners")
+	cmd.DurationVar(&clockSkew, "clock-skew", api.DefaultClockSkew, "The max. clock skew of signed requests")
+	cmd.Int64Var(&maxRequests, "max-requests", 0, "The max. number of requests handled concurrently")
+	cmd.Int64Var(&maxEnclaveReq, "max-enclave-requests", 0, "The max. number of requests handled concurrently per enclave and identity")
+	cmd.StringVar(&maxBodyFlag, "max-body-bytes", "", "The max. aggregate size of request bodies handled concurrently")
+	cmd.StringVar(&watchdogDump, "watchdog-dump", "", "Write diagnostics dumps to a directory or an S3 bucket")
+	cmd.IntVar(&watchdogGos, "watchdog-goroutines", 0, "Write a dump when there are more than N goroutines")
+	cmd.StringVar(&watchdogMem, "watchdog-memory", "", "Write a dump when the memory usage exceeds SIZE")
+	cmd.IntVar(&watchdogBlock, "watchdog-blocked", 0, "Write a dump when N request handlers are blocked")
+	cmd.StringVar(&authzFlag, "authorizer", "", "URL of an external authorization service")
+	cmd.StringVar(&logLevelFlag, "log-level", "info", "The level of the error log")
+	cmd.StringVar(&logFormatFlag, "log-format", "text", "The format of the error log")
+	cmd.StringVar(&decisionsFlag, "audit-decisions", "off", "Include policy decisions in audit events")
+	cmd.DurationVar(&caClientTTL, "ca-max-client-ttl", 0, "The max. lifetime of client certificates issued by the built-in CA")
+	cmd.DurationVar(&caServerTTL, "ca-max-server-ttl", 0, "The max. lifetime of server certificates issued by the built-in CA")
+	cmd.DurationVar(&caCRLTTL, "ca-crl-ttl", 0, "The time after which clients should fetch a new CRL")
+	cmd.DurationVar(&caSSHTTL, "ca-max-ssh-ttl", 0, "The max. lifetime of SSH certificates")
+	cmd.DurationVar(&tokenTTL, "max-token-ttl", 0, "The max. lifetime of JWTs")
+	cmd.StringSliceVar(&keyAlgsFlag, "key-algorithms", nil, "Algorithms allowed for new keys")
+	cmd.StringVar(&defaultKeyAlg, "default-key-algorithm", "", "The algorithm of new keys created without an explicit algorithm")
+	cmd.IntVar(&minKeySize, "min-key-size", 0, "The min. key size of new keys in bits")
+	cmd.StringArrayVar(&reservedFlags, "reserved-prefix", nil, "Reserve a key and policy name prefix for the given policies")
+	cmd.IntVar(&maxNameLength, "max-name-length", 0, "The max. length of names in bytes")
+	cmd.BoolVar(&extendedNames, "extended-names", false, "Allow '.' characters in names")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -103,21 +558,246 @@ func serverCmd(args []string) {
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes server --help'")
 	}
+	network, err := listenNetwork(ipStackFlag)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes server --help'", err)
+	}
+	logLevel, err := log.ParseLevel(logLevelFlag)
+	if err != nil {
+		cli.Fatalf("invalid log level '%s'. See 'kes server --help'", logLevelFlag)
+	}
+	auditDecisions, err := audit.ParseDecisionLevel(decisionsFlag)
+	if err != nil {
+		cli.Fatalf("invalid audit decision level '%s'. See 'kes server --help'", decisionsFlag)
+	}
+	if metricsTLS && metricsAddr == "" {
+		cli.Fatal("--metrics-tls requires --metrics-addr. See 'kes server --help'")
+	}
+	if (scimEnclave != "" || scimPolicy != "") && scimAddr == "" {
+		cli.Fatal("--scim-enclave and --scim-default-policy require --scim-addr. See 'kes server --help'")
+	}
+	if scimAddr != "" && os.Getenv("KES_SCIM_TOKEN") == "" {
+		cli.Fatal("--scim-addr requires the env. variable KES_SCIM_TOKEN. See 'kes server --help'")
+	}
+	if cmd.Changed("clock-skew") && !signedReqs {
+		cli.Fatal("--clock-skew requires --signed-requests. See 'kes server --help'")
+	}
+	if clockSkew <= 0 {
+		cli.Fatalf("invalid clock skew '%v'. See 'kes server --help'", clockSkew)
+	}
+	if signedReqs && (metricsAddr == "" || os.Getenv("KES_METRICS_TOKEN") == "") && scimAddr == "" {
+		cli.Fatal("--signed-requests requires --metrics-addr with the env. variable KES_METRICS_TOKEN or --scim-addr. See 'kes server --help'")
+	}
+	var replay *api.ReplayGuard
+	if signedReqs {
+		replay = api.NewReplayGuard(&api.ReplayConfig{ClockSkew: clockSkew})
+	}
+	if metricsIDs < 0 || metricsIDs > metric.MaxIdentities {
+		cli.Fatalf("--metrics-identities must be between 0 and %d. See 'kes server --help'", metric.MaxIdentities)
+	}
+	metricsKeyLabels := metric.KeyLabels{
+		Enclave:  metricsEnclv,
+		Prefixes: metricsPrefix,
+	}
+	if err = metricsKeyLabels.Validate(); err != nil {
+		cli.Fatalf("%v. See 'kes server --help'", err)
+	}
+	if maxRequests < 0 {
+		cli.Fatalf("invalid max. requests '%d'. See 'kes server --help'", maxRequests)
+	}
+	if maxEnclaveReq < 0 {
+		cli.Fatalf("invalid max. enclave requests '%d'. See 'kes server --help'", maxEnclaveReq)
+	}
+	var maxBodyBytes mem.Size
+	if maxBodyFlag != "" {
+		if maxBodyBytes, err = mem.ParseSize(maxBodyFlag); err != nil || maxBodyBytes < 0 {
+			cli.Fatalf("invalid max. body bytes '%s'. See 'kes server --help'", maxBodyFlag)
+		}
+	}
+	var admission *api.Admission
+	if maxRequests > 0 || maxBodyBytes > 0 || maxEnclaveReq > 0 {
+		admission = api.NewAdmission(&api.AdmissionConfig{
+			MaxRequests:        maxRequests,
+			MaxBodyBytes:       int64(maxBodyBytes),
+			MaxEnclaveRequests: maxEnclaveReq,
+		})
+	}
+
+	var watchdogConfig *watchdog.Config
+	if watchdogDump != "" || watchdogGos != 0 || watchdogMem != "" || watchdogBlock != 0 {
+		var maxMemory mem.Size
+		if watchdogMem != "" {
+			if maxMemory, err = mem.ParseSize(watchdogMem); err != nil || maxMemory < 0 {
+				cli.Fatalf("invalid watchdog memory threshold '%s'. See 'kes server --help'", watchdogMem)
+			}
+		}
+		if watchdogDump == "" {
+			cli.Fatal("--watchdog-goroutines, --watchdog-memory and --watchdog-blocked require --watchdog-dump. See 'kes server --help'")
+		}
+		target, err := watchdog.ParseTarget(watchdogDump)
+		if err != nil {
+			cli.Fatalf("%v. See 'kes server --help'", err)
+		}
+		watchdogConfig = &watchdog.Config{
+			Target:          target,
+			MaxGoroutines:   watchdogGos,
+			MaxMemory:       int64(maxMemory),
+			MaxBlocked:      watchdogBlock,
+			BlockedHandlers: api.BlockedHandlers,
+		}
+		if err = watchdogConfig.Validate(); err != nil {
+			cli.Fatalf("%v. See 'kes server --help'", err)
+		}
+	}
+
+	if caClientTTL < 0 || caServerTTL < 0 || caCRLTTL < 0 || caSSHTTL < 0 {
+		cli.Fatal("CA lifetimes must not be negative. See 'kes server --help'")
+	}
+	if tokenTTL < 0 {
+		cli.Fatal("token lifetime must not be negative. See 'kes server --help'")
+	}
+
+	keyPolicy, err := key.ParseAlgorithmPolicy(defaultKeyAlg, keyAlgsFlag, minKeySize)
+	if err != nil {
+		cli.Fatalf("invalid key policy: %v. See 'kes server --help'", err)
+	}
+	if err = keyPolicy.Validate(); err != nil {
+		cli.Fatalf("%v. See 'kes server --help'", err)
+	}
+	if keyPolicy.IsZero() {
+		keyPolicy = nil
+	}
+	var reservedPrefixes api.ReservedPrefixes
+	if len(reservedFlags) > 0 {
+		reservedPrefixes = make(api.ReservedPrefixes, len(reservedFlags))
+		for _, reserved := range reservedFlags {
+			prefix, policies, ok := strings.Cut(reserved, "=")
+			if !ok || policies == "" {
+				cli.Fatalf("invalid reserved prefix '%s': expected <PREFIX>=<POLICIES>. See 'kes server --help'", reserved)
+			}
+			if _, ok = reservedPrefixes[prefix]; ok {
+				cli.Fatalf("reserved prefix '%s' is specified multiple times. See 'kes server --help'", prefix)
+			}
+			reservedPrefixes[prefix] = strings.Split(policies, ",")
+		}
+		if err = reservedPrefixes.Validate(); err != nil {
+			cli.Fatalf("%v. See 'kes server --help'", err)
+		}
+	}
+	if maxNameLength < 0 {
+		cli.Fatalf("invalid max. name length '%d': must not be negative. See 'kes server --help'", maxNameLength)
+	}
+	names := api.NameRules{
+		MaxLength: maxNameLength,
+		Extended:  extendedNames,
+	}
+	if err = names.Validate(); err != nil {
+		cli.Fatalf("%v. See 'kes server --help'", err)
+	}
+
+	var logJSON bool
+	switch strings.ToLower(logFormatFlag) {
+	case "text":
+	case "json":
+		logJSON = true
+	default:
+		cli.Fatalf("invalid log format '%s'. See 'kes server --help'", logFormatFlag)
+	}
+
 	if cmd.NArg() == 0 {
+		if bootstrapFlag != "" {
+			cli.Fatal("--bootstrap requires a <PATH> argument. See 'kes server --help'")
+		}
+		if authzFlag != "" {
+			cli.Fatal("--authorizer requires a <PATH> argument. Use the 'authorizer' section of the config file instead. See 'kes server --help'")
+		}
+		if caClientTTL > 0 || caServerTTL > 0 || caCRLTTL > 0 || caSSHTTL > 0 || tokenTTL > 0 {
+			cli.Fatal("--ca-max-client-ttl, --ca-max-server-ttl, --ca-crl-ttl, --ca-max-ssh-ttl and --max-token-ttl require a <PATH> argument. See 'kes server --help'")
+		}
+		if scimAddr != "" {
+			cli.Fatal("--scim-addr requires a <PATH> argument. See 'kes server --help'")
+		}
+		if metricsEnclv {
+			cli.Fatal("--metrics-enclave-label requires a <PATH> argument. See 'kes server --help'")
+		}
+		if keyPolicy != nil {
+			cli.Fatal("--key-algorithms, --default-key-algorithm and --min-key-size require a <PATH> argument. Use the 'key_policy' section of the config file instead. See 'kes server --help'")
+		}
+		if reservedPrefixes != nil {
+			cli.Fatal("--reserved-prefix requires a <PATH> argument. Use the 'names' section of the config file instead. See 'kes server --help'")
+		}
+		if names != (api.NameRules{}) {
+			cli.Fatal("--max-name-length and --extended-names require a <PATH> argument. Use the 'names' section of the config file instead. See 'kes server --help'")
+		}
+		if len(configFlags) == 0 {
+			cli.Fatal("no config file specified. See 'kes server --help'")
+		}
+		if len(configFlags) > 1 && addrFlag != "" {
+			cli.Fatal("--addr cannot be used with multiple config files. Specify the address in each config file instead. See 'kes server --help'")
+		}
 		startGateway(gatewayConfig{
 			Address:     addrFlag,
-			ConfigFile:  configFlag,
+			Network:     network,
+			ConfigFiles: configFlags,
 			PrivateKey:  tlsKeyFlag,
 			Certificate: tlsCertFlag,
 			TLSAuth:     mtlsAuthFlag,
+			UI:          uiFlag,
+			MetricsAddr: metricsAddr,
+			MetricsTLS:  metricsTLS,
+			LogLevel:    logLevel,
+			LogJSON:     logJSON,
+
+			AuditDecisions:    auditDecisions,
+			MetricsIdentities: metricsIDs,
+			MetricsKeyLabels:  metricsKeyLabels,
+			Admission:         admission,
+			Replay:            replay,
+			Watchdog:          watchdogConfig,
 		})
 	} else {
+		if len(configFlags) > 1 {
+			cli.Fatal("--config can only be specified once for a stateful server. See 'kes server --help'")
+		}
+		var configFlag string
+		if len(configFlags) == 1 {
+			configFlag = configFlags[0]
+		}
 		config := serverConfig{
 			Address:     addrFlag,
+			Network:     network,
 			ConfigPath:  configFlag,
 			PrivateKey:  tlsKeyFlag,
 			Certificate: tlsCertFlag,
 			TLSAuth:     mtlsAuthFlag,
+			UI:          uiFlag,
+			Bootstrap:   bootstrapFlag,
+			Authorizer:  authzFlag,
+			MetricsAddr: metricsAddr,
+			MetricsTLS:  metricsTLS,
+			LogLevel:    logLevel,
+			LogJSON:     logJSON,
+
+			SCIMAddr:          scimAddr,
+			SCIMEnclave:       scimEnclave,
+			SCIMDefaultPolicy: scimPolicy,
+
+			AuditDecisions:    auditDecisions,
+			MetricsIdentities: metricsIDs,
+			MetricsKeyLabels:  metricsKeyLabels,
+			Admission:         admission,
+			Replay:            replay,
+			Watchdog:          watchdogConfig,
+			CA: api.CAConfig{
+				MaxClientTTL: caClientTTL,
+				MaxServerTTL: caServerTTL,
+				CRLTTL:       caCRLTTL,
+				MaxSSHTTL:    caSSHTTL,
+				MaxTokenTTL:  tokenTTL,
+			},
+			KeyPolicy:        keyPolicy,
+			ReservedPrefixes: reservedPrefixes,
+			Names:            names,
 		}
 		startServer(cmd.Arg(0), config)
 	}
@@ -129,9 +809,25 @@ func startServer(path string, sConfig serverConfig) {
 		mlock = mlockall() == nil
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancelCtx := serverContext()
 	defer cancelCtx()
 
+	if sConfig.Bootstrap != "" {
+		err := fs.Bootstrap(path, func() error {
+			config, err := cli.ReadInitConfig(sConfig.Bootstrap)
+			if err != nil {
+				return fmt.Errorf("failed to read bootstrap config: %v", err)
+			}
+			return initVault(path, config)
+		})
+		if errors.Is(err, os.ErrExist) {
+			cli.Fatalf("failed to bootstrap '%s': directory is not empty and has no '.init' file", path)
+		}
+		if err != nil {
+			cli.Fatalf("failed to bootstrap '%s': %v", path, err)
+		}
+	}
+
 	init, err := fs.ReadInitConfig(filepath.Join(path, ".init"))
 	if err != nil {
 		cli.Fatalf("failed to initialize vault: %v", err)
@@ -140,7 +836,7 @@ func startServer(path string, sConfig serverConfig) {
 		init.Address.Set(sConfig.Address)
 	}
 	if sConfig.PrivateKey != "" {
-		init.Address.Set(sConfig.PrivateKey)
+		init.PrivateKey.Set(sConfig.PrivateKey)
 	}
 	if sConfig.Certificate != "" {
 		init.Certificate.Set(sConfig.Certificate)
@@ -163,7 +859,9 @@ func startServer(path string, sConfig serverConfig) {
 	}
 
 	auditLog := xlog.New(ioutil.Discard, "", 0)
-	if isTerm(os.Stderr) {
+	log.Default().SetLevel(sConfig.LogLevel)
+	log.Default().SetJSON(sConfig.LogJSON)
+	if isTerm(os.Stderr) && !sConfig.LogJSON {
 		style := tui.NewStyle().Foreground(tui.Color("#ac0000")) // red
 		log.Default().SetPrefix(style.Render("Error: "))
 	}
@@ -184,10 +882,15 @@ func startServer(path string, sConfig serverConfig) {
 		clientAuth = tls.RequireAndVerifyClientCert
 	}
 
+	certHeader := init.ProxyClientCert.Value()
+	if certHeader == "" {
+		certHeader = api.DefaultForwardCertHeader
+	}
+
 	var proxy *auth.TLSProxy
 	if len(init.ProxyIdentities) != 0 {
 		proxy = &auth.TLSProxy{
-			CertHeader: http.CanonicalHeaderKey(init.ProxyClientCert.Value()),
+			CertHeader: http.CanonicalHeaderKey(certHeader),
 		}
 		if clientAuth == tls.RequireAndVerifyClientCert || clientAuth == tls.VerifyClientCertIfGiven {
 			proxy.VerifyOptions = new(x509.VerifyOptions)
@@ -199,32 +902,179 @@ func startServer(path string, sConfig serverConfig) {
 		}
 	}
 
+	var (
+		transport http.RoundTripper
+		forwarder *api.Forwarder
+	)
+	if init.Leader.Value() != "" || len(init.Nodes) > 0 {
+		var rootCAs *x509.CertPool
+		if init.LeaderCA.Value() != "" {
+			if rootCAs, err = https.CertPoolFromFile(init.LeaderCA.Value()); err != nil {
+				cli.Fatalf("failed to load cluster CA certificates: %v", err)
+			}
+		}
+		transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{certificate},
+				RootCAs:      rootCAs,
+				CipherSuites: fips.TLSCiphers(),
+			},
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     90 * time.Second,
+		}
+	}
+	var authorizer auth.Authorizer
+	if sConfig.Authorizer != "" {
+		authorizer = auth.NewHTTPAuthorizer(&auth.HTTPAuthorizerConfig{
+			Endpoint: sConfig.Authorizer,
+			Client: &http.Client{
+				Timeout: 5 * time.Second,
+				Transport: &http.Transport{
+					Proxy: http.ProxyFromEnvironment,
+					TLSClientConfig: &tls.Config{
+						MinVersion:   tls.VersionTLS12,
+						Certificates: []tls.Certificate{certificate},
+						CipherSuites: fips.TLSCiphers(),
+					},
+					ForceAttemptHTTP2:   true,
+					MaxIdleConnsPerHost: 16,
+					IdleConnTimeout:     90 * time.Second,
+				},
+			},
+		})
+	}
+	if leader := init.Leader.Value(); leader != "" {
+		if forwarder, err = api.NewForwarder(leader, transport, certHeader); err != nil {
+			cli.Fatalf("invalid cluster config: %v", err)
+		}
+		if sConfig.SCIMAddr != "" {
+			cli.Fatal("--scim-addr is not supported by cluster followers. Serve the SCIM API on the leader instead")
+		}
+	}
+
+	nodeID := init.NodeID.Value()
+	if nodeID == "" {
+		if nodeID, err = os.Hostname(); err != nil {
+			nodeID = init.Address.Value()
+		}
+	}
+	nodes := make([]string, 0, len(init.Nodes))
+	for _, node := range init.Nodes {
+		nodes = append(nodes, node.Value())
+	}
+	cluster, err := api.NewCluster(&api.ClusterConfig{
+		ID:         nodeID,
+		Leader:     init.Leader.Value(),
+		Nodes:      nodes,
+		Transport:  transport,
+		CertHeader: certHeader,
+		ModTime:    func() (time.Time, error) { return fs.ModTime(path) },
+	})
+	if err != nil {
+		cli.Fatalf("invalid cluster config: %v", err)
+	}
+
 	vault, err := fs.Open(path)
 	if err != nil {
 		cli.Fatalf("failed to initialize vault: %v", err)
 	}
 
+	// The TLS stack only requires a client certificate. It gets
+	// verified by verifyPeer since it may have been issued by the
+	// vault's CA which is only accessible once the vault is unsealed.
+	verifyPeer := verifyClientCertificate(vault, clientAuth == tls.RequireAndVerifyClientCert)
+
 	metrics := metric.New()
+	metrics.SetIdentityLabels(sConfig.MetricsIdentities)
+	if err = metrics.SetKeyLabels(sConfig.MetricsKeyLabels); err != nil {
+		cli.Fatal(err)
+	}
+	if sConfig.Watchdog != nil {
+		go watchdog.Run(ctx, sConfig.Watchdog)
+	}
 	log.Default().Add(metrics.ErrorEventCounter())
 	auditLog.Add(metrics.AuditEventCounter())
 
+	events := api.NewEventStream()
 	server := https.NewServer(&https.Config{
-		Addr: init.Address.Value(),
+		Addr:    init.Address.Value(),
+		Network: sConfig.Network,
 		Handler: api.NewRouter(&api.RouterConfig{
-			Vault:    vault,
-			Proxy:    proxy,
-			AuditLog: auditLog,
-			ErrorLog: log.Default(),
-			Metrics:  metrics,
+			Vault:       vault,
+			Proxy:       proxy,
+			Idempotency: api.NewIdempotencyCache(0),
+			Events:      events,
+			Forwarder:   forwarder,
+			Cluster:     cluster,
+			UI:          sConfig.UI,
+			Admission:   sConfig.Admission,
+			Authorizer:  authorizer,
+			CA:          sConfig.CA,
+			KeyPolicy:   sConfig.KeyPolicy,
+			AuditLog:    auditLog,
+			ErrorLog:    log.Default(),
+			Metrics:     metrics,
+
+			ReservedPrefixes: sConfig.ReservedPrefixes,
+			Names:            sConfig.Names,
+			AuditDecisions:   sConfig.AuditDecisions,
 		}),
 		TLSConfig: &tls.Config{
+			MinVersion:            tls.VersionTLS12,
+			Certificates:          []tls.Certificate{certificate},
+			CipherSuites:          fips.TLSCiphers(),
+			CurvePreferences:      fips.TLSCurveIDs(),
+			ClientAuth:            tls.RequireAnyClientCert,
+			VerifyPeerCertificate: verifyPeer,
+		},
+		OnAuthFailure: reportAuthFailure(auditLog, metrics),
+	})
+	var metricsServer *https.Server
+	if sConfig.MetricsAddr != "" {
+		var metricsTLS *tls.Config
+		if sConfig.MetricsTLS {
+			metricsTLS = &tls.Config{
+				MinVersion:       tls.VersionTLS12,
+				Certificates:     []tls.Certificate{certificate},
+				CipherSuites:     fips.TLSCiphers(),
+				CurvePreferences: fips.TLSCurveIDs(),
+			}
+		}
+		metricsServer = startMetricsServer(ctx, sConfig.MetricsAddr, sConfig.Network, metricsTLS, &api.MetricsConfig{
+			Metrics: metrics,
+			Ready: func(ctx context.Context) error {
+				_, err := vault.Admin(ctx)
+				return err
+			},
+			Token:  os.Getenv("KES_METRICS_TOKEN"),
+			Replay: sConfig.Replay,
+		})
+	}
+	var scimServer *https.Server
+	if sConfig.SCIMAddr != "" {
+		scimServer = startSCIMServer(ctx, sConfig.SCIMAddr, sConfig.Network, &tls.Config{
 			MinVersion:       tls.VersionTLS12,
 			Certificates:     []tls.Certificate{certificate},
 			CipherSuites:     fips.TLSCiphers(),
 			CurvePreferences: fips.TLSCurveIDs(),
-			ClientAuth:       clientAuth,
-		},
-	})
+		}, &api.SCIMConfig{
+			Vault:         vault,
+			Enclave:       sConfig.SCIMEnclave,
+			DefaultPolicy: sConfig.SCIMDefaultPolicy,
+			Token:         os.Getenv("KES_SCIM_TOKEN"),
+			Replay:        sConfig.Replay,
+			Events:        events,
+			AuditLog:      auditLog,
+		})
+	}
+	if forwarder == nil { // Within a cluster, only the leader rotates secrets
+		rotation := api.NewSecretRotation(vault, &api.SecretRotationConfig{})
+		defer rotation.Close()
+	}
+	handoverOnSignal(ctx, cancelCtx, server, metricsServer, scimServer)
 	go func(ctx context.Context) {
 		ticker := time.NewTicker(15 * time.Minute)
 		defer ticker.Stop()
@@ -244,21 +1094,38 @@ func startServer(path string, sConfig serverConfig) {
 					xlog.Print("failed to load TLS certificate: certificate does not contain any DNS or IP address as SAN")
 				}
 				c := &tls.Config{
-					MinVersion:       tls.VersionTLS12,
-					Certificates:     []tls.Certificate{certificate},
-					CipherSuites:     fips.TLSCiphers(),
-					CurvePreferences: fips.TLSCurveIDs(),
-					ClientAuth:       clientAuth,
+					MinVersion:            tls.VersionTLS12,
+					Certificates:          []tls.Certificate{certificate},
+					CipherSuites:          fips.TLSCiphers(),
+					CurvePreferences:      fips.TLSCurveIDs(),
+					ClientAuth:            tls.RequireAnyClientCert,
+					VerifyPeerCertificate: verifyPeer,
 				}
 				if err = server.UpdateTLS(c); err != nil {
 					log.Printf("failed to update TLS configuration: %v", err)
 				}
+				if metricsServer != nil && sConfig.MetricsTLS {
+					c = c.Clone()
+					c.ClientAuth = tls.NoClientCert
+					c.VerifyPeerCertificate = nil
+					if err = metricsServer.UpdateTLS(c); err != nil {
+						log.Printf("failed to update metrics TLS configuration: %v", err)
+					}
+				}
+				if scimServer != nil {
+					c := c.Clone()
+					c.ClientAuth = tls.NoClientCert
+					c.VerifyPeerCertificate = nil
+					if err = scimServer.UpdateTLS(c); err != nil {
+						log.Printf("failed to update SCIM TLS configuration: %v", err)
+					}
+				}
 			}
 		}
 	}(ctx)
 
 	ip, port := serverAddr(init.Address.Value())
-	ifaceIPs := listeningOnV4(ip)
+	ifaceIPs := listeningOn(ip, sConfig.Network)
 	if len(ifaceIPs) == 0 {
 		cli.Fatal("failed to listen on network interfaces")
 	}
@@ -277,14 +1144,26 @@ func startServer(path string, sConfig serverConfig) {
 	buffer.Stylef(item, "%-12s", "License").Sprintf("%-22s", "GNU AGPLv3").Styleln(faint, "https://www.gnu.org/licenses/agpl-3.0.html")
 	buffer.Stylef(item, "%-12s", "Version").Sprintf("%-22s", sys.BinaryInfo().Version).Stylef(faint, "%s/%s\n", runtime.GOOS, runtime.GOARCH)
 	buffer.Sprintln()
-	buffer.Stylef(item, "%-12s", "Endpoints").Sprintf("https://%s:%s\n", ifaceIPs[0], port)
+	buffer.Stylef(item, "%-12s", "Endpoints").Sprintf("https://%s\n", net.JoinHostPort(ifaceIPs[0].String(), port))
 	for _, ifaceIP := range ifaceIPs[1:] {
-		buffer.Sprintf("%-12s", " ").Sprintf("https://%s:%s\n", ifaceIP, port)
+		buffer.Sprintf("%-12s", " ").Sprintf("https://%s\n", net.JoinHostPort(ifaceIP.String(), port))
 	}
 	buffer.Sprintln()
 	if clientAuth == tls.RequireAndVerifyClientCert {
 		buffer.Stylef(item, "%-12s", "Mutual TLS").Sprint("on").Styleln(faint, "Verify client certificates")
 	}
+	if sConfig.MetricsAddr != "" {
+		buffer.Stylef(item, "%-12s", "Metrics").Sprintln(metricsEndpoint(sConfig.MetricsAddr, sConfig.MetricsTLS))
+	}
+	if sConfig.SCIMAddr != "" {
+		buffer.Stylef(item, "%-12s", "SCIM").Sprintln("https://" + sConfig.SCIMAddr + api.SCIMPath)
+	}
+	switch cluster.Role() {
+	case api.RoleFollower:
+		buffer.Stylef(item, "%-12s", "Cluster").Sprintf("%-22s", cluster.Role()).Styleln(faint, "Forward write requests to "+forwarder.Leader())
+	case api.RoleLeader:
+		buffer.Stylef(item, "%-12s", "Cluster").Sprintf("%-22s", cluster.Role()).Styleln(faint, "Node ID "+cluster.ID())
+	}
 	switch {
 	case runtime.GOOS == "linux" && mlock:
 		buffer.Stylef(item, "%-12s", "Mem Lock").Stylef(green, "%-22s", "on").Styleln(faint, "RAM pages will not be swapped to disk")
@@ -295,45 +1174,187 @@ func startServer(path string, sConfig serverConfig) {
 	}
 	cli.Println(buffer.String())
 
+	notifyHandover(ctx, server)
+	defer notifyService(ctx, server, metricsServer, scimServer)()
 	if err := server.Start(ctx); err != http.ErrServerClosed {
 		cli.Fatalf("failed to start server: %v", err)
 	}
 }
 
-// listeningOnV4 returns a list of the system IPv4 interface
-// addresses an TCP/IP listener with the given IP is listening
-// on.
+// verifyClientCertificate returns a function that verifies client
+// certificates during the TLS handshake. It rejects certificates
+// that have been revoked by the vault's certificate authority. If
+// verify is true, it also rejects certificates that have not been
+// issued by a system CA or the vault's certificate authority.
+func verifyClientCertificate(vault *sys.Vault, verify bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("tls: client did not provide a certificate")
+		}
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// While the vault is sealed, the issuer is not accessible.
+		// Any request, besides unsealing the vault, fails anyway.
+		issuer, err := vault.Issuer(ctx)
+		if err == nil && issuer.IsRevoked(certs[0]) {
+			return https.ErrCertificateRevoked
+		}
+		if !verify {
+			return nil
+		}
+
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if issuer != nil {
+			roots.AddCert(issuer.Certificate())
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err = certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		return err
+	}
+}
+
+// reportAuthFailure returns a function that records failed
+// TLS client authentications as audit events and metrics.
+func reportAuthFailure(auditLog *log.Logger, metrics *metric.Metrics) func(https.AuthFailure) {
+	return func(failure https.AuthFailure) {
+		var ip net.IP
+		if addr, ok := failure.RemoteAddr.(*net.TCPAddr); ok {
+			ip = addr.IP
+		}
+		audit.LogAuthFailure(auditLog, audit.AuthFailure{
+			IP:         ip,
+			ServerName: failure.ServerName,
+			Identity:   failure.Identity,
+			Reason:     failure.Reason,
+			Error:      failure.Err.Error(),
+		})
+		metrics.CountAuthFailure(failure.Reason)
+	}
+}
+
+// startMetricsServer starts a metrics listener at the given
+// address and network in a separate goroutine. The listener
+// serves plaintext HTTP if tlsConfig is nil. It exits if the
+// listener fails.
+func startMetricsServer(ctx context.Context, addr, network string, tlsConfig *tls.Config, config *api.MetricsConfig) *https.Server {
+	server := https.NewServer(&https.Config{
+		Addr:      addr,
+		Network:   network,
+		Handler:   api.NewMetricsHandler(config),
+		TLSConfig: tlsConfig,
+	})
+	go func() {
+		if err := server.Start(ctx); err != http.ErrServerClosed {
+			cli.Fatalf("failed to start metrics listener: %v", err)
+		}
+	}()
+	return server
+}
+
+// startSCIMServer starts a SCIM listener at the given address
+// and network in a separate goroutine. It exits if the listener
+// fails.
+func startSCIMServer(ctx context.Context, addr, network string, tlsConfig *tls.Config, config *api.SCIMConfig) *https.Server {
+	server := https.NewServer(&https.Config{
+		Addr:      addr,
+		Network:   network,
+		Handler:   api.NewSCIMHandler(config),
+		TLSConfig: tlsConfig,
+	})
+	go func() {
+		if err := server.Start(ctx); err != http.ErrServerClosed {
+			cli.Fatalf("failed to start SCIM listener: %v", err)
+		}
+	}()
+	return server
+}
+
+// metricsEndpoint returns the URL of the metrics listener.
+func metricsEndpoint(addr string, tls bool) string {
+	if tls {
+		return "https://" + addr
+	}
+	return "http://" + addr
+}
+
+// listeningOn returns a list of the system IP interface
+// addresses a TCP/IP listener with the given IP and network
+// is listening on.
 //
 // In particular, a TCP/IP listener listening on the pseudo
-// address 0.0.0.0 listens on all network interfaces while
-// a listener on a specific IP only listens on the network
-// interface with that IP address.
-func listeningOnV4(ip net.IP) []net.IP {
+// address 0.0.0.0 or :: listens on all network interfaces
+// while a listener on a specific IP only listens on the
+// network interface with that IP address. A "tcp" listener
+// listens on the IPv4 and IPv6 addresses of all interfaces,
+// a "tcp4" listener only on the IPv4 addresses and a "tcp6"
+// listener only on the IPv6 addresses.
+func listeningOn(ip net.IP, network string) []net.IP {
 	if !ip.IsUnspecified() {
 		return []net.IP{ip}
 	}
-	// We listen on the pseudo-address: 0.0.0.0
-	// The TCP/IP listener is listening on all available
-	// network interfaces.
 	interfaces, err := net.InterfaceAddrs()
 	if err != nil {
 		return []net.IP{}
 	}
 
-	var ip4Addr []net.IP
+	var ip4Addr, ip6Addr []net.IP
 	for _, iface := range interfaces {
 		var ip net.IP
 		switch addr := iface.(type) {
 		case *net.IPNet:
-			ip = addr.IP.To4()
+			ip = addr.IP
 		case *net.IPAddr:
-			ip = addr.IP.To4()
+			ip = addr.IP
 		}
-		if ip != nil {
-			ip4Addr = append(ip4Addr, ip)
+		switch {
+		case ip == nil:
+		case ip.To4() != nil:
+			if network != "tcp6" {
+				ip4Addr = append(ip4Addr, ip.To4())
+			}
+		case ip.IsLinkLocalUnicast(): // Link-local IPv6 addresses require a zone
+		default:
+			if network != "tcp4" {
+				ip6Addr = append(ip6Addr, ip)
+			}
 		}
 	}
-	return ip4Addr
+	return append(ip4Addr, ip6Addr...)
+}
+
+// listenNetwork returns the network of the server listeners
+// for the given --ip-stack option.
+func listenNetwork(ipStack string) (string, error) {
+	switch strings.ToLower(ipStack) {
+	case "", "dual":
+		return "tcp", nil
+	case "ipv4":
+		return "tcp4", nil
+	case "ipv6":
+		return "tcp6", nil
+	default:
+		return "", fmt.Errorf("invalid IP stack '%s'", ipStack)
+	}
 }
 
 // serverAddr takes an address string <IP>:<port> and
@@ -0,0 +1,64 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+
+	"github.com/minio/kes/internal/https"
+)
+
+// serviceState is a lifecycle state of the server process
+// that is reported to the service manager, e.g. systemd or
+// the Windows service control manager.
+type serviceState int
+
+const (
+	// serviceReady indicates that the server listens for
+	// incoming connections.
+	serviceReady serviceState = iota
+
+	// serviceReloading indicates that the server reloads its
+	// configuration. Once reloaded, the server reports that
+	// it is ready again.
+	serviceReloading
+
+	// serviceStopping indicates that the server is shutting
+	// down.
+	serviceStopping
+)
+
+// notifyService reports to the service manager, if any, that
+// the server is ready once all given servers listen for incoming
+// connections, and that it is stopping once the ctx is canceled.
+//
+// The returned function reports that the server is stopping, if
+// not reported already. It should be called before the server
+// process exits.
+func notifyService(ctx context.Context, servers ...*https.Server) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer setServiceState(serviceStopping)
+
+		for _, server := range servers {
+			if server == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-server.Listening():
+			}
+		}
+		setServiceState(serviceReady)
+		<-ctx.Done()
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minio/kes/internal/log"
+	"golang.org/x/sys/unix"
+)
+
+// serverContext returns a context that is canceled once the
+// server process receives SIGINT or SIGTERM.
+func serverContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+var startWatchdog sync.Once
+
+// setServiceState reports the given state to systemd using the
+// sd_notify protocol if the server process has been started by
+// systemd with Type=notify or Type=notify-reload. Otherwise, it
+// does nothing.
+//
+// Once the server is ready, it also sends watchdog keep-alive
+// messages if the systemd watchdog is enabled by WatchdogSec.
+//
+// Ref: https://www.freedesktop.org/software/systemd/man/sd_notify.html
+func setServiceState(state serviceState) {
+	var err error
+	switch state {
+	case serviceReady:
+		// A new process started by a handover reports its PID
+		// such that systemd tracks it as the main process. This
+		// requires NotifyAccess=all.
+		err = sdNotify("READY=1\nMAINPID=" + strconv.Itoa(os.Getpid()))
+		startWatchdog.Do(sdWatchdog)
+	case serviceReloading:
+		var now unix.Timespec
+		if err = unix.ClockGettime(unix.CLOCK_MONOTONIC, &now); err == nil {
+			err = sdNotify("RELOADING=1\nMONOTONIC_USEC=" + strconv.FormatInt(now.Nano()/1000, 10))
+		}
+	case serviceStopping:
+		err = sdNotify("STOPPING=1")
+	}
+	if err != nil {
+		log.Printf("failed to notify systemd: %v", err)
+	}
+}
+
+// sdWatchdog sends keep-alive messages to systemd at half of
+// the watchdog interval, if the watchdog is enabled for the
+// server process.
+func sdWatchdog() {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				log.Printf("failed to notify systemd watchdog: %v", err)
+			}
+		}
+	}()
+}
+
+// sdNotify sends the state to the systemd notification socket.
+// It does nothing if the NOTIFY_SOCKET env. variable is not set.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
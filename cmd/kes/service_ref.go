@@ -0,0 +1,24 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+)
+
+// serverContext returns a context that is canceled once the
+// server process receives SIGINT or SIGTERM.
+func serverContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// setServiceState does nothing. We only support systemd
+// and the Windows service control manager at the moment.
+func setServiceState(serviceState) {}
@@ -0,0 +1,154 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minio/kes/internal/log"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventSource is the event log source of the KES server
+// when running as Windows service.
+const eventSource = "KES"
+
+// service is the Windows service handler if the server
+// process runs as Windows service. Otherwise, it is nil.
+var service *windowsService
+
+// serverContext returns a context that is canceled once the
+// server process receives an interrupt or, if the process has
+// been started by the Windows service control manager, the
+// service is stopped.
+//
+// When running as Windows service, the server writes its output
+// to the Windows event log. The returned cancel function waits
+// until the service has reported that it has stopped.
+func serverContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if isService, err := svc.IsWindowsService(); err != nil || !isService {
+		return ctx, cancel
+	}
+
+	if elog, err := eventlog.Open(eventSource); err == nil {
+		log.Default().Remove(os.Stderr)
+		os.Stdout = eventLogPipe(elog.Info)
+		os.Stderr = eventLogPipe(elog.Error)
+		log.Default().Add(os.Stderr)
+	}
+
+	service = &windowsService{
+		ready:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(service.done)
+		if err := svc.Run(eventSource, service); err != nil {
+			log.Printf("failed to run as Windows service: %v", err)
+		}
+		cancel()
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-service.stop:
+			cancel()
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		service.Stopped()
+		<-service.done
+	}
+}
+
+// setServiceState reports the given state to the Windows
+// service control manager if the server process runs as
+// Windows service. Otherwise, it does nothing.
+func setServiceState(state serviceState) {
+	if service == nil {
+		return
+	}
+	if state == serviceReady {
+		service.readyOnce.Do(func() { close(service.ready) })
+	}
+}
+
+// windowsService is a svc.Handler that reports the server
+// as running once it listens for incoming connections and
+// stops the server when requested by the service control
+// manager.
+type windowsService struct {
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	stop     chan struct{} // Closed when the service manager stops the service
+	stopOnce sync.Once
+
+	stopped     chan struct{} // Closed once the server has shut down
+	stoppedOnce sync.Once
+	done        chan struct{} // Closed once svc.Run returns
+}
+
+// Stopped reports that the server has shut down.
+func (s *windowsService) Stopped() {
+	s.stoppedOnce.Do(func() { close(s.stopped) })
+}
+
+// Execute implements the svc.Handler interface.
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const Accepts = svc.AcceptStop | svc.AcceptShutdown
+
+	changes <- svc.Status{State: svc.StartPending, WaitHint: uint32((30 * time.Second).Milliseconds())}
+	ready := s.ready
+	for {
+		select {
+		case <-ready:
+			changes <- svc.Status{State: svc.Running, Accepts: Accepts}
+			ready = nil
+		case <-s.stopped:
+			changes <- svc.Status{State: svc.StopPending}
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				changes <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending, WaitHint: uint32((30 * time.Second).Milliseconds())}
+				s.stopOnce.Do(func() { close(s.stop) })
+			}
+		}
+	}
+}
+
+// eventLogPipe returns a file that writes each line written
+// to it as event using the given event log function.
+func eventLogPipe(report func(eid uint32, msg string) error) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return os.Stderr
+	}
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				report(1, line)
+			}
+		}
+		io.Copy(io.Discard, r)
+	}()
+	return w
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const sshCmdUsage = `Usage:
+    kes ssh <command>
+
+Commands:
+    ca                       Print the public key of an SSH CA.
+    sign                     Sign an SSH public key.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func sshCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, sshCmdUsage) }
+
+	subCmds := commands{
+		"ca":   caSSHCmd,
+		"sign": signSSHCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ssh --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a ssh command. See 'kes ssh --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const caSSHCmdUsage = `Usage:
+    kes ssh ca [options] <key>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Prints the public key of the SSH CA derived from the KES key <key> in
+authorized_keys format. SSH servers trust user certificates signed by
+the CA if its public key is listed in TrustedUserCAKeys. SSH clients
+trust host certificates signed by the CA if its public key is listed
+as @cert-authority in their known_hosts file.
+
+Examples:
+    $ kes ssh ca my-ca > /etc/ssh/kes_ca.pub
+`
+
+func caSSHCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, caSSHCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ssh ca --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes ssh ca --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes ssh ca --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	publicKey, err := kesclient.SSHPublicKey(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch SSH CA public key: %v", err)
+	}
+	fmt.Print(string(publicKey))
+}
+
+const signSSHCmdUsage = `Usage:
+    kes ssh sign [options] <key> <public-key-file>
+
+Options:
+    --principal <NAME>       Add <NAME> as user or host name the certificate is
+                             valid for. Can be specified multiple times.
+    --host                   Sign a host instead of a user certificate.
+    --ttl <DURATION>         Duration until the certificate expires.
+                             (default: 1h)
+    -o, --out <PATH>         Path to the certificate. (default: the path of the
+                             public key with the suffix '-cert.pub')
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Signs the SSH public key at <public-key-file> with the SSH CA derived from the
+KES key <key>. The policy of the identity must allow each principal. For example,
+the policy path '/v1/ssh/sign/my-ca/user/alice' allows signing user certificates
+for 'alice' with the key 'my-ca'.
+
+Examples:
+    $ kes ssh sign --principal alice my-ca ~/.ssh/id_ed25519.pub
+    $ kes ssh sign --host --principal host-1.example.com my-ca /etc/ssh/ssh_host_ed25519_key.pub
+`
+
+func signSSHCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, signSSHCmdUsage) }
+
+	var (
+		principals         []string
+		hostFlag           bool
+		ttl                time.Duration
+		outPath            string
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringArrayVar(&principals, "principal", nil, "Add <NAME> as user or host name the certificate is valid for")
+	cmd.BoolVar(&hostFlag, "host", false, "Sign a host instead of a user certificate")
+	cmd.DurationVar(&ttl, "ttl", 0, "Duration until the certificate expires")
+	cmd.StringVarP(&outPath, "out", "o", "", "Path to the certificate")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ssh sign --help'", err)
+	}
+	if cmd.NArg() < 2 {
+		cli.Fatal("no key name or public key specified. See 'kes ssh sign --help'")
+	}
+	if cmd.NArg() > 2 {
+		cli.Fatal("too many arguments. See 'kes ssh sign --help'")
+	}
+	if len(principals) == 0 {
+		cli.Fatal("no principal specified. Set the '--principal' flag")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid '--ttl': must not be negative")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	keyName, publicKeyPath := cmd.Arg(0), cmd.Arg(1)
+	if outPath == "" {
+		outPath = strings.TrimSuffix(publicKeyPath, ".pub") + "-cert.pub"
+	}
+	publicKey, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		cli.Fatalf("failed to read public key: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	cert, err := kesclient.SignSSHKey(ctx, newClient(insecureSkipVerify), enclaveName, keyName, &kesclient.SSHCertificateRequest{
+		PublicKey:  publicKey,
+		Host:       hostFlag,
+		Principals: principals,
+		TTL:        ttl,
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to sign SSH public key: %v", err)
+	}
+	if err = os.WriteFile(outPath, []byte(cert.Certificate), 0o644); err != nil {
+		cli.Fatalf("failed to write certificate: %v", err)
+	}
+
+	year, month, day := cert.ExpiresAt.Local().Date()
+	hour, min, sec := cert.ExpiresAt.Local().Clock()
+	var buffer strings.Builder
+	fmt.Fprintf(&buffer, "Serial Number: %d\n", cert.SerialNumber)
+	fmt.Fprintf(&buffer, "Principals:    %s\n", strings.Join(principals, ", "))
+	fmt.Fprintf(&buffer, "Expires At:    %04d-%02d-%02d %02d:%02d:%02d\n", year, month, day, hour, min, sec)
+	fmt.Fprintf(&buffer, "Certificate:   %s", outPath)
+	cli.Println(buffer.String())
+}
@@ -30,7 +30,10 @@ Options:
     -k, --insecure           Skip TLS certificate validation.
     -s, --short              Print status information in a short summary format.
         --api                List all server APIs.
-        --json               Print status information in JSON format.
+        --json               Print status information in JSON format. Same as
+                             '--output json'.
+    -o, --output <format>    Print output in the given format: table, json or
+                             yaml. Defaults to $KES_OUTPUT or table.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -52,6 +55,7 @@ func statusCmd(args []string) {
 		insecureSkipVerify bool
 	)
 	cmd.BoolVar(&jsonFlag, "json", false, "Print status information in JSON format")
+	cmd.VarP(&outputFlag, "output", "o", "Print output in the given format")
 	cmd.BoolVar(&apiFlag, "api", false, "List all server APIs")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&shortFlag, "short", "s", false, "Print status information in a short summary format")
@@ -65,6 +69,9 @@ func statusCmd(args []string) {
 	if cmd.NArg() > 0 {
 		cli.Fatal("too many arguments. See 'kes status --help'")
 	}
+	if err := outputFlag.Parse(jsonFlag); err != nil {
+		cli.Fatalf("%v. See 'kes status --help'", err)
+	}
 
 	client := newClient(insecureSkipVerify)
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
@@ -88,7 +95,8 @@ func statusCmd(args []string) {
 		}
 	}
 
-	if jsonFlag {
+	switch outputFlag.Format() {
+	case "json":
 		encoder := json.NewEncoder(os.Stdout)
 		if isTerm(os.Stdout) && !shortFlag {
 			encoder.SetIndent("", "  ")
@@ -103,6 +111,16 @@ func statusCmd(args []string) {
 			}
 		}
 		return
+	case "yaml":
+		if apiFlag {
+			err = outputFlag.Encode(os.Stdout, APIs)
+		} else {
+			err = outputFlag.Encode(os.Stdout, status)
+		}
+		if err != nil {
+			cli.Fatal(err)
+		}
+		return
 	}
 
 	faint := tui.NewStyle()
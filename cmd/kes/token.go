@@ -0,0 +1,285 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const tokenCmdUsage = `Usage:
+    kes token <command>
+
+Commands:
+    designate                Designate a key as JWT signing key.
+    sign                     Sign a JWT.
+    jwks                     Print the JWT signing keys of an enclave.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func tokenCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, tokenCmdUsage) }
+
+	subCmds := commands{
+		"designate": designateTokenCmd,
+		"sign":      signTokenCmd,
+		"jwks":      jwksTokenCmd,
+	}
+
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes token --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a token command. See 'kes token --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const designateTokenCmdUsage = `Usage:
+    kes token designate [options] <key>
+
+Options:
+    --revoke                 Revoke the designation instead.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Designates the KES key <key> as JWT signing key. Tokens are signed with an
+Ed25519 key derived from <key>. Its public key is published as part of the
+enclave's JSON Web Key Set (JWKS) until the designation is revoked.
+
+Examples:
+    $ kes token designate my-jwt-key
+    $ kes token designate --revoke my-jwt-key
+`
+
+func designateTokenCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, designateTokenCmdUsage) }
+
+	var (
+		revokeFlag         bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVar(&revokeFlag, "revoke", false, "Revoke the designation instead")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes token designate --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes token designate --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes token designate --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	if err := kesclient.DesignateTokenKey(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0), !revokeFlag); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to designate '%s': %v", cmd.Arg(0), err)
+	}
+}
+
+const signTokenCmdUsage = `Usage:
+    kes token sign [options] <key>
+
+Options:
+    --claim <NAME=VALUE>     Add the string claim <NAME> with the value <VALUE>.
+                             Can be specified multiple times.
+    --claims <PATH>          Path to a JSON file containing additional claims.
+    --ttl <DURATION>         Duration until the token expires. (default: 15m)
+    --json                   Print the token, its key ID and expiry as JSON.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Signs a JWT with the KES key <key>, which must be designated as JWT signing
+key. The server sets the 'sub', 'iat', 'nbf', 'exp' and 'jti' claims. The 'sub'
+claim is the identity of the client. The 'aud' claim, custom claims and the
+token lifetime are restricted by the token profile of the client's policy.
+String claims may contain the template variables {{identity}}, {{policy}},
+{{enclave}} and {{key}}.
+
+Examples:
+    $ kes token sign --claim aud=billing my-jwt-key
+    $ kes token sign --claims claims.json --ttl 5m my-jwt-key
+`
+
+func signTokenCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, signTokenCmdUsage) }
+
+	var (
+		claimFlags         []string
+		claimsPath         string
+		ttl                time.Duration
+		jsonFlag           bool
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.StringArrayVar(&claimFlags, "claim", nil, "Add the string claim <NAME> with the value <VALUE>")
+	cmd.StringVar(&claimsPath, "claims", "", "Path to a JSON file containing additional claims")
+	cmd.DurationVar(&ttl, "ttl", 0, "Duration until the token expires")
+	cmd.BoolVar(&jsonFlag, "json", false, "Print the token, its key ID and expiry as JSON")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes token sign --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes token sign --help'")
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes token sign --help'")
+	}
+	if ttl < 0 {
+		cli.Fatal("invalid '--ttl': must not be negative")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	claims := map[string]any{}
+	if claimsPath != "" {
+		file, err := os.ReadFile(claimsPath)
+		if err != nil {
+			cli.Fatalf("failed to read claims: %v", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(file))
+		decoder.UseNumber()
+		if err = decoder.Decode(&claims); err != nil {
+			cli.Fatalf("failed to read claims: %v", err)
+		}
+	}
+	for _, claim := range claimFlags {
+		name, value, ok := strings.Cut(claim, "=")
+		if !ok || name == "" {
+			cli.Fatalf("invalid claim '%s': must be <NAME>=<VALUE>", claim)
+		}
+		claims[name] = value
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	token, err := kesclient.SignToken(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0), claims, ttl)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to sign token: %v", err)
+	}
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(token)
+		return
+	}
+	fmt.Println(token.Token)
+}
+
+const jwksTokenCmdUsage = `Usage:
+    kes token jwks [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Prints the JSON Web Key Set (JWKS) containing the public keys of all JWT
+signing keys of the enclave. Services verify tokens signed by KES using
+this key set. It is also available under /.well-known/jwks.json.
+
+Examples:
+    $ kes token jwks > jwks.json
+    $ kes token jwks --enclave tenant-1
+`
+
+func jwksTokenCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, jwksTokenCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes token jwks --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes token jwks --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	jwks, err := kesclient.TokenKeySet(ctx, newClient(insecureSkipVerify), enclaveName)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch JWKS: %v", err)
+	}
+	var buffer bytes.Buffer
+	if err = json.Indent(&buffer, jwks, "", "  "); err != nil {
+		cli.Fatalf("failed to fetch JWKS: %v", err)
+	}
+	fmt.Print(buffer.String())
+}
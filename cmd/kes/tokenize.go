@@ -0,0 +1,149 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const tokenizeCmdUsage = `Usage:
+    kes tokenize [options] <scope> <value>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Replaces each value by a random token within the tokenization <scope> and
+prints the tokens, one per line. The values are stored encrypted by the
+server. Tokenizing the same value twice produces two different tokens.
+
+Examples:
+    $ kes tokenize card-numbers 4111111111111111
+    $ kes tokenize card-numbers 4111111111111111 5500000000000004
+`
+
+func tokenizeCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, tokenizeCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes tokenize --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no scope specified. See 'kes tokenize --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no value specified. See 'kes tokenize --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	scope := cmd.Arg(0)
+	values := make([][]byte, 0, cmd.NArg()-1)
+	for _, value := range cmd.Args()[1:] {
+		values = append(values, []byte(value))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	tokens, err := kesclient.Tokenize(ctx, newClient(insecureSkipVerify), enclaveName, scope, values)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to tokenize values: %v", err)
+	}
+	if isTerm(os.Stdout) {
+		for _, token := range tokens {
+			fmt.Println(token)
+		}
+	} else {
+		json.NewEncoder(os.Stdout).Encode(tokens)
+	}
+}
+
+const detokenizeCmdUsage = `Usage:
+    kes detokenize [options] <scope> <token>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+
+    -h, --help               Print command line options.
+
+Prints the values replaced by the tokens within the tokenization <scope>,
+one per line. Detokenizing requires access to /v1/detokenize/<scope>, which
+is separate from the permission to tokenize values.
+
+Examples:
+    $ kes detokenize card-numbers 8f2c3c6bd3b8f5c1e6a7b4d2c9e0f1a3
+`
+
+func detokenizeCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, detokenizeCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes detokenize --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no scope specified. See 'kes detokenize --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no token specified. See 'kes detokenize --help'")
+	}
+	if enclaveName == "" {
+		enclaveName = os.Getenv("KES_ENCLAVE")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	values, err := kesclient.Detokenize(ctx, newClient(insecureSkipVerify), enclaveName, cmd.Arg(0), cmd.Args()[1:])
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to detokenize tokens: %v", err)
+	}
+	if isTerm(os.Stdout) {
+		for _, value := range values {
+			fmt.Println(string(value))
+		}
+	} else {
+		json.NewEncoder(os.Stdout).Encode(values)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesclient"
+	flag "github.com/spf13/pflag"
+)
+
+const topCmdUsage = `Usage:
+    kes top [options]
+
+Options:
+    -n, --interval <duration> Refresh interval. (default: 2s)
+    -k, --insecure           Skip TLS certificate validation.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Shows a live dashboard of the server's request rates, error rates,
+response latency percentiles and key store health. Rates and latency
+percentiles refer to the requests within the last refresh interval.
+
+If the client identity is allowed to fetch the request statistics per
+identity, the dashboard also shows the identities that sent the most
+requests since the server started.
+
+If the output is not a terminal, one JSON object per refresh interval
+is printed instead.
+
+Examples:
+    $ kes top
+    $ kes top --interval 5s
+    $ kes top | jq .request_rate
+`
+
+// topIdentities is the number of identities
+// shown by kes top.
+const topIdentities = 5
+
+func topCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, topCmdUsage) }
+
+	var (
+		interval           time.Duration
+		insecureSkipVerify bool
+		colorFlag          colorOption
+	)
+	cmd.DurationVarP(&interval, "interval", "n", 2*time.Second, "Refresh interval")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes top --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes top --help'")
+	}
+	if interval < 100*time.Millisecond {
+		cli.Fatal("refresh interval must be at least 100ms. See 'kes top --help'")
+	}
+
+	client := newClient(insecureSkipVerify)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	// Fetch the initial metrics such that the first
+	// sample already shows rates and percentiles.
+	prev, err := client.Metrics(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch metrics: %v", err)
+	}
+	prevTime := time.Now()
+
+	var (
+		terminal = isTerm(os.Stdout)
+		history  []float64
+		view     = newTopView(client.Endpoints[0], interval, colorFlag.Colorize())
+		encoder  = json.NewEncoder(os.Stdout)
+		ticker   = time.NewTicker(interval)
+	)
+	defer ticker.Stop()
+	if terminal {
+		const (
+			AltScreen  = "\x1b[?1049h"
+			MainScreen = "\x1b[?1049l"
+			HideCursor = "\x1b[?25l"
+			ShowCursor = "\x1b[?25h"
+		)
+		fmt.Print(AltScreen, HideCursor)
+		defer fmt.Print(ShowCursor, MainScreen)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var sample topSample
+		metric, err := client.Metrics(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			sample.Err = err
+		} else {
+			now := time.Now()
+			sample = newTopSample(&prev, &metric, now.Sub(prevTime))
+			prev, prevTime = metric, now
+		}
+		if status, err := kesclient.Status(ctx, client); err == nil {
+			sample.Status = status
+		} else if errors.Is(err, context.Canceled) {
+			return
+		}
+		if identities, err := kesclient.TopIdentities(ctx, client, topIdentities); err == nil {
+			sample.Identities = identities
+		} else if errors.Is(err, context.Canceled) {
+			return
+		}
+
+		if !terminal {
+			encoder.Encode(sample)
+			continue
+		}
+		if sample.Err == nil {
+			history = append(history, sample.RequestRate)
+			if len(history) > 60 {
+				history = history[len(history)-60:]
+			}
+		}
+		view.Draw(&sample, history)
+	}
+}
+
+// A topSample contains the server metrics
+// of one refresh interval.
+type topSample struct {
+	Time time.Time `json:"time"`
+
+	RequestRate float64 `json:"request_rate"` // Requests per second
+	ErrorRate   float64 `json:"error_rate"`   // Requests with a 4xx response per second
+	FailureRate float64 `json:"failure_rate"` // Requests with a 5xx response per second
+	Active      uint64  `json:"active"`
+
+	P50 time.Duration `json:"latency_p50"`
+	P90 time.Duration `json:"latency_p90"`
+	P99 time.Duration `json:"latency_p99"`
+
+	Identities []kesclient.IdentityStat `json:"identities,omitempty"`
+
+	Metric kes.Metric              `json:"-"`
+	Status *kesclient.ServerStatus `json:"-"`
+	Err    error                   `json:"-"`
+}
+
+// MarshalJSON adds the key store health
+// to the JSON representation of s.
+func (s topSample) MarshalJSON() ([]byte, error) {
+	type Sample topSample
+	type JSON struct {
+		Sample
+		KeyStoreAvailable *bool         `json:"keystore_available,omitempty"`
+		KeyStoreLatency   time.Duration `json:"keystore_latency,omitempty"`
+		Error             string        `json:"error,omitempty"`
+	}
+	v := JSON{Sample: Sample(s)}
+	if s.Status != nil {
+		available := !s.Status.KeyStoreUnavailable && !s.Status.KeyStoreUnreachable
+		v.KeyStoreAvailable = &available
+		v.KeyStoreLatency = s.Status.KeyStoreLatency
+	}
+	if s.Err != nil {
+		v.Error = s.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// newTopSample computes the rates and latency percentiles
+// of the requests handled between the prev and cur metrics.
+func newTopSample(prev, cur *kes.Metric, elapsed time.Duration) topSample {
+	rate := func(prev, cur uint64) float64 {
+		if cur < prev || elapsed <= 0 { // Server restarted
+			return 0
+		}
+		return float64(cur-prev) / elapsed.Seconds()
+	}
+	return topSample{
+		Time:        time.Now().UTC(),
+		RequestRate: rate(prev.RequestN(), cur.RequestN()),
+		ErrorRate:   rate(prev.RequestErr, cur.RequestErr),
+		FailureRate: rate(prev.RequestFail, cur.RequestFail),
+		Active:      cur.RequestActive,
+		P50:         latencyPercentile(prev.LatencyHistogram, cur.LatencyHistogram, 0.50),
+		P90:         latencyPercentile(prev.LatencyHistogram, cur.LatencyHistogram, 0.90),
+		P99:         latencyPercentile(prev.LatencyHistogram, cur.LatencyHistogram, 0.99),
+		Metric:      *cur,
+	}
+}
+
+// latencyPercentile returns the upper bound of the latency
+// bucket that contains the p-th percentile of requests
+// handled between the prev and cur cumulative latency
+// histograms. It returns 0 if there were no requests.
+func latencyPercentile(prev, cur map[time.Duration]uint64, p float64) time.Duration {
+	buckets := make([]time.Duration, 0, len(cur))
+	for l := range cur {
+		buckets = append(buckets, l)
+	}
+	if len(buckets) == 0 {
+		return 0
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	delta := func(l time.Duration) uint64 {
+		if cur[l] < prev[l] { // Server restarted
+			return cur[l]
+		}
+		return cur[l] - prev[l]
+	}
+	total := delta(buckets[len(buckets)-1])
+	if total == 0 {
+		return 0
+	}
+	for _, l := range buckets {
+		if float64(delta(l)) >= p*float64(total) {
+			return l
+		}
+	}
+	return buckets[len(buckets)-1]
+}
+
+// topView renders the kes top dashboard.
+type topView struct {
+	endpoint string
+	interval time.Duration
+
+	header, faint, green, yellow, red tui.Style
+}
+
+func newTopView(endpoint string, interval time.Duration, colorize bool) *topView {
+	v := &topView{
+		endpoint: endpoint,
+		interval: interval,
+	}
+	if colorize {
+		v.header = tui.NewStyle().Bold(true)
+		v.faint = tui.NewStyle().Faint(true)
+		v.green = tui.NewStyle().Foreground(tui.Color("#00a700"))
+		v.yellow = tui.NewStyle().Foreground(tui.Color("#d7af00"))
+		v.red = tui.NewStyle().Foreground(tui.Color("#d70000"))
+	}
+	return v
+}
+
+// Draw clears the screen and renders the sample
+// and the history of request rates.
+func (v *topView) Draw(s *topSample, history []float64) {
+	const ClearScreen = "\x1b[H\x1b[2J"
+
+	var b strings.Builder
+	b.WriteString(ClearScreen)
+	fmt.Fprintf(&b, "%s %s\n", v.header.Render("KES top"), v.faint.Render(fmt.Sprintf("- %s - every %v - %s", v.endpoint, v.interval, time.Now().Format("15:04:05"))))
+	b.WriteString("\n")
+
+	if s.Err != nil {
+		fmt.Fprintf(&b, "%s %v\n", v.red.Render("Failed to fetch metrics:"), s.Err)
+	} else {
+		m := &s.Metric
+		share := func(n uint64) string {
+			if m.RequestN() == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(m.RequestN()))
+		}
+		fmt.Fprintln(&b, v.header.Render(fmt.Sprintf("%-16s %14s %12s %10s", "Requests", "Total", "Rate", "Share")))
+		fmt.Fprintf(&b, "  %-14s %14d %12s %10s\n", v.green.Render(fmt.Sprintf("%-14s", "Success")), m.RequestOK, fmt.Sprintf("%.1f/s", s.RequestRate-s.ErrorRate-s.FailureRate), share(m.RequestOK))
+		fmt.Fprintf(&b, "  %-14s %14d %12s %10s\n", v.yellow.Render(fmt.Sprintf("%-14s", "Error [4xx]")), m.RequestErr, fmt.Sprintf("%.1f/s", s.ErrorRate), share(m.RequestErr))
+		fmt.Fprintf(&b, "  %-14s %14d %12s %10s\n", v.red.Render(fmt.Sprintf("%-14s", "Failure [5xx]")), m.RequestFail, fmt.Sprintf("%.1f/s", s.FailureRate), share(m.RequestFail))
+		fmt.Fprintf(&b, "  %-14s %14d\n", "Active", m.RequestActive)
+		b.WriteString("\n")
+
+		percentile := func(d time.Duration) string {
+			if d == 0 {
+				return "-"
+			}
+			return d.String()
+		}
+		fmt.Fprintln(&b, v.header.Render(fmt.Sprintf("%-16s %14s %12s %10s", "Latency", "p50", "p90", "p99")))
+		fmt.Fprintf(&b, "  %-14s %14s %12s %10s\n", "", percentile(s.P50), percentile(s.P90), percentile(s.P99))
+		b.WriteString("\n")
+
+		fmt.Fprintln(&b, v.header.Render("Requests/s"))
+		fmt.Fprintf(&b, "  %s %s\n", sparkline(history), v.faint.Render(fmt.Sprintf("%.1f/s", s.RequestRate)))
+		b.WriteString("\n")
+
+		fmt.Fprintln(&b, v.header.Render("System"))
+		fmt.Fprintf(&b, "  UpTime %v   Heap %s   Stack %s   CPUs %d/%d   Threads %d\n",
+			m.UpTime,
+			mem.FormatSize(mem.Size(m.HeapAlloc), 'D', 1),
+			mem.FormatSize(mem.Size(m.StackAlloc), 'D', 1),
+			m.UsableCPUs, m.CPUs,
+			m.Threads,
+		)
+		fmt.Fprintf(&b, "  Audit events %d   Error events %d\n", m.AuditEvents, m.ErrorEvents)
+		b.WriteString("\n")
+	}
+
+	if len(s.Identities) > 0 {
+		fmt.Fprintln(&b, v.header.Render(fmt.Sprintf("%-18s %12s %12s %10s", "Top Identities", "Requests", "Errors", "Failures")))
+		for _, stat := range s.Identities {
+			identity := stat.Identity.String()
+			if len(identity) > 16 {
+				identity = identity[:16] + "…"
+			}
+			fmt.Fprintf(&b, "  %-17s %12d %12d %10d\n", identity, stat.Requests, stat.Errors, stat.Failures)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintln(&b, v.header.Render("Key Store"))
+	switch {
+	case s.Status == nil:
+		fmt.Fprintf(&b, "  %s\n", v.faint.Render("unknown - server status not accessible"))
+	case s.Status.KeyStoreUnreachable:
+		fmt.Fprintf(&b, "  %s\n", v.red.Render("unreachable"))
+	case s.Status.KeyStoreUnavailable:
+		fmt.Fprintf(&b, "  %s\n", v.red.Render("unavailable"))
+	default:
+		fmt.Fprintf(&b, "  %s   latency %v   version %s\n", v.green.Render("available"), s.Status.KeyStoreLatency, s.Status.Version)
+	}
+	b.WriteString("\n" + v.faint.Render("Press Ctrl-C to quit.") + "\n")
+	fmt.Print(b.String())
+}
+
+// sparkline renders the values as a
+// sequence of unicode block characters.
+func sparkline(values []float64) string {
+	const Bars = "▁▂▃▄▅▆▇█"
+	bars := []rune(Bars)
+
+	var peak float64
+	for _, v := range values {
+		if v > peak {
+			peak = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if peak > 0 {
+			i = int(v / peak * float64(len(bars)-1))
+		}
+		b.WriteRune(bars[i])
+	}
+	return b.String()
+}
@@ -6,6 +6,7 @@ package edge
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -47,6 +48,16 @@ func TestReadServerConfigYAML_CustomAPI(t *testing.T) {
 		MetricsPath     = "/v1/metrics"
 		MetricsTimeout  = 22 * time.Second
 		MetricsSkipAuth = true
+		BulkPath        = "/v1/key/bulk/decrypt/"
+		BulkSuccessor   = "/v1/key/decrypt/"
+		BulkMaxBody     = 4 << 20
+		CreatePath      = "/v1/key/create/"
+		CreateMaxBody   = 4 << 10
+		CreateInFlight  = 50
+	)
+	var (
+		BulkDeprecated = time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+		BulkSunset     = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
 	)
 
 	file, err := os.Open(Filename)
@@ -80,6 +91,215 @@ func TestReadServerConfigYAML_CustomAPI(t *testing.T) {
 	if api.InsecureSkipAuth != MetricsSkipAuth {
 		t.Fatalf("Invalid API config: invalid skip_auth for '%s': got '%v' - want '%v'", StatusPath, api.InsecureSkipAuth, MetricsSkipAuth)
 	}
+
+	api, ok = config.API.Paths[BulkPath]
+	if !ok {
+		t.Fatalf("Invalid API config: missing API '%s'", BulkPath)
+	}
+	if !api.Deprecated.Equal(BulkDeprecated) {
+		t.Fatalf("Invalid API config: invalid deprecated for '%s': got '%v' - want '%v'", BulkPath, api.Deprecated, BulkDeprecated)
+	}
+	if !api.Sunset.Equal(BulkSunset) {
+		t.Fatalf("Invalid API config: invalid sunset for '%s': got '%v' - want '%v'", BulkPath, api.Sunset, BulkSunset)
+	}
+	if api.Successor != BulkSuccessor {
+		t.Fatalf("Invalid API config: invalid successor for '%s': got '%v' - want '%v'", BulkPath, api.Successor, BulkSuccessor)
+	}
+	if api.MaxBody != BulkMaxBody {
+		t.Fatalf("Invalid API config: invalid max_body for '%s': got '%v' - want '%v'", BulkPath, api.MaxBody, BulkMaxBody)
+	}
+
+	api, ok = config.API.Paths[CreatePath]
+	if !ok {
+		t.Fatalf("Invalid API config: missing API '%s'", CreatePath)
+	}
+	if api.MaxBody != CreateMaxBody {
+		t.Fatalf("Invalid API config: invalid max_body for '%s': got '%v' - want '%v'", CreatePath, api.MaxBody, CreateMaxBody)
+	}
+	if api.MaxInFlight != CreateInFlight {
+		t.Fatalf("Invalid API config: invalid max_in_flight for '%s': got '%v' - want '%v'", CreatePath, api.MaxInFlight, CreateInFlight)
+	}
+}
+
+func TestReadServerConfigYAML_AuditFile(t *testing.T) {
+	const (
+		Filename = "./testdata/audit-file.yml"
+
+		Path     = "/var/log/kes/audit.log"
+		MaxSize  = 64 << 20
+		Rotate   = 24 * time.Hour
+		MaxFiles = 7
+		MaxAge   = 7 * 24 * time.Hour
+	)
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	if !config.Log.Audit {
+		t.Fatalf("Invalid log config: audit logging to STDOUT is disabled")
+	}
+	auditFile := config.Log.AuditFile
+	if auditFile == nil {
+		t.Fatalf("Invalid log config: missing audit file config")
+	}
+	if auditFile.Path != Path {
+		t.Fatalf("Invalid audit file config: got path '%s' - want path '%s'", auditFile.Path, Path)
+	}
+	if auditFile.MaxSize != MaxSize {
+		t.Fatalf("Invalid audit file config: got max size '%d' - want max size '%d'", auditFile.MaxSize, MaxSize)
+	}
+	if auditFile.Rotate != Rotate {
+		t.Fatalf("Invalid audit file config: got rotate '%v' - want rotate '%v'", auditFile.Rotate, Rotate)
+	}
+	if auditFile.MaxFiles != MaxFiles {
+		t.Fatalf("Invalid audit file config: got max files '%d' - want max files '%d'", auditFile.MaxFiles, MaxFiles)
+	}
+	if auditFile.MaxAge != MaxAge {
+		t.Fatalf("Invalid audit file config: got max age '%v' - want max age '%v'", auditFile.MaxAge, MaxAge)
+	}
+	if !auditFile.Compress {
+		t.Fatalf("Invalid audit file config: compression is disabled")
+	}
+}
+
+func TestReadServerConfigYAML_Heartbeat(t *testing.T) {
+	const (
+		Filename = "./testdata/heartbeat.yml"
+
+		Endpoint = "https://monitoring.example.com/v1/heartbeat"
+		Node     = "kes-edge-1"
+		Interval = 30 * time.Second
+		CAPath   = "./monitoring-ca.crt"
+	)
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	heartbeat := config.Heartbeat
+	if heartbeat == nil {
+		t.Fatalf("Invalid config: missing heartbeat config")
+	}
+	if heartbeat.Endpoint != Endpoint {
+		t.Fatalf("Invalid heartbeat config: got endpoint '%s' - want endpoint '%s'", heartbeat.Endpoint, Endpoint)
+	}
+	if heartbeat.Node != Node {
+		t.Fatalf("Invalid heartbeat config: got node '%s' - want node '%s'", heartbeat.Node, Node)
+	}
+	if heartbeat.Interval != Interval {
+		t.Fatalf("Invalid heartbeat config: got interval '%v' - want interval '%v'", heartbeat.Interval, Interval)
+	}
+	if heartbeat.CAPath != CAPath {
+		t.Fatalf("Invalid heartbeat config: got CA path '%s' - want CA path '%s'", heartbeat.CAPath, CAPath)
+	}
+}
+
+func TestReadServerConfigYAML_Namespace(t *testing.T) {
+	const (
+		Filename = "./testdata/namespace.yml"
+
+		Namespace = "app-1"
+		Path      = "/tmp/kes/app-1"
+		Policy    = "app"
+		Identity  = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		Key       = "app-1-key"
+
+		KeyAlgorithm = "AES256-GCM-SIV"
+		MinKeySize   = 256
+	)
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	namespace, ok := config.Namespaces[Namespace]
+	if !ok {
+		t.Fatalf("Invalid config: missing namespace '%s'", Namespace)
+	}
+	fs, ok := namespace.KeyStore.(*FSKeyStore)
+	if !ok {
+		var want *FSKeyStore
+		t.Fatalf("Invalid keystore: got type '%T' - want type '%T'", namespace.KeyStore, want)
+	}
+	if fs.Path != Path {
+		t.Fatalf("Invalid keystore: got path '%s' - want path '%s'", fs.Path, Path)
+	}
+	policy, ok := namespace.Policies[Policy]
+	if !ok {
+		t.Fatalf("Invalid namespace config: missing policy '%s'", Policy)
+	}
+	if len(policy.Identities) != 1 || policy.Identities[0] != Identity {
+		t.Fatalf("Invalid policy identities: got '%v' - want '%v'", policy.Identities, []string{Identity})
+	}
+	if len(namespace.Keys) != 1 || namespace.Keys[0].Name != Key {
+		t.Fatalf("Invalid namespace keys: got '%v' - want '%v'", namespace.Keys, []string{Key})
+	}
+	if config.KeyPolicy == nil || len(config.KeyPolicy.Algorithms) != 2 {
+		t.Fatalf("Invalid key policy: got '%+v' - want 2 algorithms", config.KeyPolicy)
+	}
+	if namespace.KeyPolicy == nil || namespace.KeyPolicy.DefaultAlgorithm != KeyAlgorithm || namespace.KeyPolicy.MinKeySize != MinKeySize {
+		t.Fatalf("Invalid namespace key policy: got '%+v' - want default algorithm '%s' and min. key size %d", namespace.KeyPolicy, KeyAlgorithm, MinKeySize)
+	}
+}
+
+func TestReadServerConfigYAML_StatsD(t *testing.T) {
+	const (
+		Filename = "./testdata/statsd.yml"
+
+		Endpoint = "127.0.0.1:8125"
+		Prefix   = "kes."
+		Interval = 30 * time.Second
+	)
+	Tags := []string{"env:prod", "region:eu-west-1"}
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	if config.Metrics == nil || config.Metrics.StatsD == nil {
+		t.Fatalf("Invalid metrics config: missing statsd config")
+	}
+	statsd := config.Metrics.StatsD
+	if statsd.Endpoint != Endpoint {
+		t.Fatalf("Invalid statsd config: got endpoint '%s' - want endpoint '%s'", statsd.Endpoint, Endpoint)
+	}
+	if statsd.Prefix != Prefix {
+		t.Fatalf("Invalid statsd config: got prefix '%s' - want prefix '%s'", statsd.Prefix, Prefix)
+	}
+	if statsd.Interval != Interval {
+		t.Fatalf("Invalid statsd config: got interval '%v' - want interval '%v'", statsd.Interval, Interval)
+	}
+	if !statsd.DogStatsD {
+		t.Fatalf("Invalid statsd config: dogstatsd is disabled")
+	}
+	if !reflect.DeepEqual(statsd.Tags, Tags) {
+		t.Fatalf("Invalid statsd config: got tags '%v' - want tags '%v'", statsd.Tags, Tags)
+	}
 }
 
 func TestReadServerConfigYAML_VaultWithAppRole(t *testing.T) {
@@ -322,3 +542,199 @@ func TestReadServerConfigYAML_AWS_NoCredentials(t *testing.T) {
 		t.Fatalf("Invalid secret key: got '%s' - want '%s'", aws.SessionToken, SessionToken)
 	}
 }
+
+func TestReadServerConfigYAML_Plugin(t *testing.T) {
+	const (
+		Filename = "./testdata/plugin.yml"
+
+		PluginPath = "/usr/local/bin/kes-plugin-hsm"
+	)
+	PluginArgs := []string{"--slot", "1"}
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	plugin, ok := config.KeyStore.(*PluginKeyStore)
+	if !ok {
+		var want *PluginKeyStore
+		t.Fatalf("Invalid keystore: got type '%T' - want type '%T'", config.KeyStore, want)
+	}
+	if plugin.Path != PluginPath {
+		t.Fatalf("Invalid keystore: got path '%s' - want path '%s'", plugin.Path, PluginPath)
+	}
+	if len(plugin.Args) != len(PluginArgs) || plugin.Args[0] != PluginArgs[0] || plugin.Args[1] != PluginArgs[1] {
+		t.Fatalf("Invalid keystore: got args '%v' - want args '%v'", plugin.Args, PluginArgs)
+	}
+}
+
+func TestReadServerConfigYAML_Bundle(t *testing.T) {
+	const (
+		Filename = "./testdata/bundle.yml"
+
+		BundlePath    = "/etc/kes/site.bundle"
+		PublicKey     = "/etc/kes/bundle.pub"
+		AuditEndpoint = "https://audit.example.com/v1/kes"
+		AuditInterval = 10 * time.Minute
+	)
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	bundle, ok := config.KeyStore.(*BundleKeyStore)
+	if !ok {
+		var want *BundleKeyStore
+		t.Fatalf("Invalid keystore: got type '%T' - want type '%T'", config.KeyStore, want)
+	}
+	if bundle.Path != BundlePath {
+		t.Fatalf("Invalid keystore: got path '%s' - want path '%s'", bundle.Path, BundlePath)
+	}
+	if bundle.PublicKey != PublicKey {
+		t.Fatalf("Invalid keystore: got public key '%s' - want public key '%s'", bundle.PublicKey, PublicKey)
+	}
+
+	sync := config.Log.AuditFile.Sync
+	if sync == nil {
+		t.Fatal("Invalid log config: no audit sync config")
+	}
+	if sync.Endpoint != AuditEndpoint {
+		t.Fatalf("Invalid audit sync config: got endpoint '%s' - want endpoint '%s'", sync.Endpoint, AuditEndpoint)
+	}
+	if sync.Interval != AuditInterval {
+		t.Fatalf("Invalid audit sync config: got interval '%v' - want interval '%v'", sync.Interval, AuditInterval)
+	}
+}
+
+func TestReadServerConfigYAML_Proxy(t *testing.T) {
+	const (
+		Filename = "./testdata/proxy.yml"
+
+		KMS       = "aws-prod"
+		KeyPrefix = "alias/"
+		Region    = "us-east-2"
+	)
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	proxy, ok := config.KeyStore.(*ProxyKeyStore)
+	if !ok {
+		var want *ProxyKeyStore
+		t.Fatalf("Invalid keystore: got type '%T' - want type '%T'", config.KeyStore, want)
+	}
+	if proxy.KMS != KMS {
+		t.Fatalf("Invalid keystore: got KMS '%s' - want KMS '%s'", proxy.KMS, KMS)
+	}
+	if proxy.KeyPrefix != KeyPrefix {
+		t.Fatalf("Invalid keystore: got key prefix '%s' - want key prefix '%s'", proxy.KeyPrefix, KeyPrefix)
+	}
+	aws, ok := proxy.ExternalKMS.(*AWSKMSKeyWrapping)
+	if !ok {
+		var want *AWSKMSKeyWrapping
+		t.Fatalf("Invalid external KMS: got type '%T' - want type '%T'", proxy.ExternalKMS, want)
+	}
+	if aws.Region != Region {
+		t.Fatalf("Invalid external KMS: got region '%s' - want region '%s'", aws.Region, Region)
+	}
+	if _, ok = config.ExternalKMS[KMS]; !ok {
+		t.Fatalf("Invalid config: external KMS '%s' not found", KMS)
+	}
+}
+
+func TestReadServerConfigYAML_Cache(t *testing.T) {
+	const (
+		Filename = "./testdata/cache.yml"
+
+		Timeout         = 10 * time.Second
+		PersistPath     = "/var/lib/kes/cache"
+		PersistInterval = 30 * time.Second
+		ExpiryPolicy    = 5 * time.Minute
+	)
+	Keys := []string{"my-key", "minio-*"}
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+	if len(config.Cache.Warmup) != len(Keys) || config.Cache.Warmup[0] != Keys[0] || config.Cache.Warmup[1] != Keys[1] {
+		t.Fatalf("Invalid cache config: got warmup keys '%v' - want '%v'", config.Cache.Warmup, Keys)
+	}
+	if config.Cache.WarmupTimeout != Timeout {
+		t.Fatalf("Invalid cache config: got warmup timeout '%v' - want '%v'", config.Cache.WarmupTimeout, Timeout)
+	}
+	if config.Cache.ExpiryPolicy != ExpiryPolicy {
+		t.Fatalf("Invalid cache config: got policy expiry '%v' - want '%v'", config.Cache.ExpiryPolicy, ExpiryPolicy)
+	}
+	if config.Cache.Persist == nil {
+		t.Fatal("Invalid cache config: no persist config")
+	}
+	if config.Cache.Persist.Path != PersistPath {
+		t.Fatalf("Invalid cache config: got persist path '%s' - want '%s'", config.Cache.Persist.Path, PersistPath)
+	}
+	if config.Cache.Persist.Interval != PersistInterval {
+		t.Fatalf("Invalid cache config: got persist interval '%v' - want '%v'", config.Cache.Persist.Interval, PersistInterval)
+	}
+}
+
+func TestReadServerConfigYAML_Wrapping(t *testing.T) {
+	const (
+		Filename = "./testdata/wrapping.yml"
+
+		Region = "us-east-2"
+		Key    = "alias/kes-wrapping"
+	)
+
+	file, err := os.Open(Filename)
+	if err != nil {
+		t.Fatalf("Failed to access file '%s': %v", Filename, err)
+	}
+
+	config, err := ReadServerConfigYAML(file)
+	if err != nil {
+		t.Fatalf("Failed to read file '%s': %v", Filename, err)
+	}
+
+	if _, ok := config.KeyStore.(*FSKeyStore); !ok {
+		var want *FSKeyStore
+		t.Fatalf("Invalid keystore: got type '%T' - want type '%T'", config.KeyStore, want)
+	}
+	wrapping, ok := config.KeyWrapping.(*AWSKMSKeyWrapping)
+	if !ok {
+		var want *AWSKMSKeyWrapping
+		t.Fatalf("Invalid keystore wrapping: got type '%T' - want type '%T'", config.KeyWrapping, want)
+	}
+	if wrapping.Region != Region {
+		t.Fatalf("Invalid keystore wrapping: got region '%s' - want region '%s'", wrapping.Region, Region)
+	}
+	if wrapping.Key != Key {
+		t.Fatalf("Invalid keystore wrapping: got key '%s' - want key '%s'", wrapping.Key, Key)
+	}
+	if !config.AllowUnwrappedKeys {
+		t.Fatalf("Invalid keystore wrapping: got allow unwrapped '%v' - want '%v'", config.AllowUnwrappedKeys, true)
+	}
+}
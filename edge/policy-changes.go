@@ -0,0 +1,173 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+)
+
+// PolicyChanges describes how the policies and identity
+// assignments of a key namespace differ between two
+// ServerConfigs.
+type PolicyChanges struct {
+	// Namespace is the name of the key namespace. It is
+	// empty for the top-level policies.
+	Namespace string
+
+	// AdminChanged reports whether the admin identity
+	// has changed. It is only set for the top-level
+	// policies.
+	AdminChanged bool
+
+	AddedPolicies    []string // Policies that did not exist before
+	RemovedPolicies  []string // Policies that no longer exist
+	ModifiedPolicies []string // Policies with modified allow or deny rules
+
+	// AssignedIdentities are identities that either
+	// have not been assigned to any policy before or
+	// have been assigned to another policy.
+	AssignedIdentities []kes.Identity
+
+	// RemovedIdentities are identities that are no
+	// longer assigned to any policy.
+	RemovedIdentities []kes.Identity
+}
+
+// IsEmpty reports whether there are no changes.
+func (c *PolicyChanges) IsEmpty() bool {
+	return !c.AdminChanged &&
+		len(c.AddedPolicies) == 0 && len(c.RemovedPolicies) == 0 && len(c.ModifiedPolicies) == 0 &&
+		len(c.AssignedIdentities) == 0 && len(c.RemovedIdentities) == 0
+}
+
+// DiffPolicies compares the policies and identity assignments
+// of the old and the new ServerConfig. It returns the changes
+// of the top-level policies, if any, followed by the changes
+// of each key namespace, sorted by namespace name. Namespaces
+// without changes are omitted.
+func DiffPolicies(old, new *ServerConfig) []PolicyChanges {
+	var changes []PolicyChanges
+	top := diffPolicies(old.Policies, new.Policies)
+	top.AdminChanged = old.Admin != new.Admin
+	if !top.IsEmpty() {
+		changes = append(changes, top)
+	}
+
+	names := make([]string, 0, len(old.Namespaces)+len(new.Namespaces))
+	for name := range old.Namespaces {
+		names = append(names, name)
+	}
+	for name := range new.Namespaces {
+		if _, ok := old.Namespaces[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var oldPolicies, newPolicies map[string]Policy
+		if ns, ok := old.Namespaces[name]; ok && ns != nil {
+			oldPolicies = ns.Policies
+		}
+		if ns, ok := new.Namespaces[name]; ok && ns != nil {
+			newPolicies = ns.Policies
+		}
+		if c := diffPolicies(oldPolicies, newPolicies); !c.IsEmpty() {
+			c.Namespace = name
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// PolicyFingerprint returns a hex-encoded SHA-256 fingerprint
+// of the admin identity, the policies and the identity
+// assignments of the ServerConfig and all its namespaces.
+//
+// Two ServerConfigs have the same fingerprint if and only if
+// they define the same admin, policies and assignments. In
+// particular, the order of policies, namespaces and assigned
+// identities does not affect the fingerprint.
+func (c *ServerConfig) PolicyFingerprint() string {
+	type policy struct {
+		Allow      []string       `json:"allow"`
+		Deny       []string       `json:"deny"`
+		Identities []kes.Identity `json:"identities"`
+	}
+	canonical := func(policies map[string]Policy) map[string]policy {
+		m := make(map[string]policy, len(policies))
+		for name, p := range policies {
+			identities := append(make([]kes.Identity, 0, len(p.Identities)), p.Identities...)
+			sort.Slice(identities, func(i, j int) bool { return identities[i] < identities[j] })
+			m[name] = policy{Allow: p.Allow, Deny: p.Deny, Identities: identities}
+		}
+		return m
+	}
+
+	namespaces := make(map[string]map[string]policy, len(c.Namespaces))
+	for name, ns := range c.Namespaces {
+		if ns != nil {
+			namespaces[name] = canonical(ns.Policies)
+		}
+	}
+	// JSON encodes map keys in sorted order. Hence,
+	// the encoding does not depend on the map order.
+	b, _ := json.Marshal(struct {
+		Admin      kes.Identity                 `json:"admin"`
+		Policies   map[string]policy            `json:"policies"`
+		Namespaces map[string]map[string]policy `json:"namespaces"`
+	}{
+		Admin:      c.Admin,
+		Policies:   canonical(c.Policies),
+		Namespaces: namespaces,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// diffPolicies compares the old and new policies and
+// their identity assignments.
+func diffPolicies(old, new map[string]Policy) PolicyChanges {
+	var c PolicyChanges
+	for name, policy := range new {
+		oldPolicy, ok := old[name]
+		switch {
+		case !ok:
+			c.AddedPolicies = append(c.AddedPolicies, name)
+		case !auth.EqualPatterns(oldPolicy.Allow, policy.Allow) || !auth.EqualPatterns(oldPolicy.Deny, policy.Deny):
+			c.ModifiedPolicies = append(c.ModifiedPolicies, name)
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			c.RemovedPolicies = append(c.RemovedPolicies, name)
+		}
+	}
+
+	identities := func(p Policy) []kes.Identity { return p.Identities }
+	oldRoles, newRoles := auth.IdentityRoles(old, identities), auth.IdentityRoles(new, identities)
+	for identity, policy := range newRoles {
+		if oldPolicy, ok := oldRoles[identity]; !ok || oldPolicy != policy {
+			c.AssignedIdentities = append(c.AssignedIdentities, identity)
+		}
+	}
+	for identity := range oldRoles {
+		if _, ok := newRoles[identity]; !ok {
+			c.RemovedIdentities = append(c.RemovedIdentities, identity)
+		}
+	}
+
+	sort.Strings(c.AddedPolicies)
+	sort.Strings(c.RemovedPolicies)
+	sort.Strings(c.ModifiedPolicies)
+	sort.Slice(c.AssignedIdentities, func(i, j int) bool { return c.AssignedIdentities[i] < c.AssignedIdentities[j] })
+	sort.Slice(c.RemovedIdentities, func(i, j int) bool { return c.RemovedIdentities[i] < c.RemovedIdentities[j] })
+	return c
+}
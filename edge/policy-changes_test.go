@@ -0,0 +1,91 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package edge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	const (
+		Identity1 kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		Identity2 kes.Identity = "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		Identity3 kes.Identity = "5ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	)
+	old := &ServerConfig{
+		Admin: "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		Policies: map[string]Policy{
+			"my-app":   {Allow: []string{"/v1/key/encrypt/my-app*"}, Identities: []kes.Identity{Identity1}},
+			"my-admin": {Allow: []string{"/v1/key/*"}, Identities: []kes.Identity{Identity2}},
+			"old":      {Allow: []string{"/v1/status"}, Identities: []kes.Identity{Identity3}},
+		},
+		Namespaces: map[string]*Namespace{
+			"tenant-1": {Policies: map[string]Policy{"ns": {Allow: []string{"/v1/key/*"}}}},
+			"tenant-2": {Policies: map[string]Policy{"ns": {Allow: []string{"/v1/key/*"}}}},
+		},
+	}
+	new := &ServerConfig{
+		Admin: old.Admin,
+		Policies: map[string]Policy{
+			"my-app":   {Allow: []string{"/v1/key/encrypt/my-app*"}, Identities: []kes.Identity{Identity2}},
+			"my-admin": {Allow: []string{"/v1/key/*"}, Deny: []string{"/v1/key/delete/*"}},
+			"new":      {Allow: []string{"/v1/status"}},
+		},
+		Namespaces: map[string]*Namespace{
+			"tenant-1": {Policies: map[string]Policy{"ns": {Allow: []string{"/v1/key/*"}}}},
+			"tenant-3": {Policies: map[string]Policy{"ns": {Allow: []string{"/v1/key/*"}}}},
+		},
+	}
+
+	want := []PolicyChanges{
+		{
+			AddedPolicies:      []string{"new"},
+			RemovedPolicies:    []string{"old"},
+			ModifiedPolicies:   []string{"my-admin"},
+			AssignedIdentities: []kes.Identity{Identity2},
+			RemovedIdentities:  []kes.Identity{Identity1, Identity3},
+		},
+		{Namespace: "tenant-2", RemovedPolicies: []string{"ns"}},
+		{Namespace: "tenant-3", AddedPolicies: []string{"ns"}},
+	}
+	if changes := DiffPolicies(old, new); !reflect.DeepEqual(changes, want) {
+		t.Fatalf("Invalid policy changes: got '%+v' - want '%+v'", changes, want)
+	}
+	if changes := DiffPolicies(old, old); len(changes) != 0 {
+		t.Fatalf("Invalid policy changes: got '%+v' - want none", changes)
+	}
+}
+
+func TestPolicyFingerprint(t *testing.T) {
+	const (
+		Identity1 kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		Identity2 kes.Identity = "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	)
+	config := &ServerConfig{
+		Policies: map[string]Policy{
+			"my-app": {Allow: []string{"/v1/key/*"}, Identities: []kes.Identity{Identity1, Identity2}},
+		},
+	}
+	reordered := &ServerConfig{
+		Policies: map[string]Policy{
+			"my-app": {Allow: []string{"/v1/key/*"}, Identities: []kes.Identity{Identity2, Identity1}},
+		},
+	}
+	modified := &ServerConfig{
+		Policies: map[string]Policy{
+			"my-app": {Allow: []string{"/v1/key/*"}, Identities: []kes.Identity{Identity1}},
+		},
+	}
+
+	if a, b := config.PolicyFingerprint(), reordered.PolicyFingerprint(); a != b {
+		t.Fatalf("Fingerprints differ: got '%s' and '%s'", a, b)
+	}
+	if a, b := config.PolicyFingerprint(), modified.PolicyFingerprint(); a == b {
+		t.Fatalf("Fingerprints are equal: got '%s'", a)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"aead.dev/mem"
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
 	"gopkg.in/yaml.v3"
 )
 
@@ -44,146 +46,334 @@ type yml struct {
 		Identities []env[kes.Identity] `yaml:"identities"`
 	} `yaml:"policy"`
 
+	Authorizer struct {
+		Endpoint env[string]        `yaml:"endpoint"`
+		Expiry   env[time.Duration] `yaml:"expiry"`
+		TLS      struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"authorizer"`
+
+	KeyPolicy ymlKeyPolicy `yaml:"key_policy"`
+
+	Names struct {
+		MaxLength env[int]                 `yaml:"max_length"`
+		Extended  env[bool]                `yaml:"extended"`
+		Reserved  map[string][]env[string] `yaml:"reserved"`
+	} `yaml:"names"`
+
+	ExternalKMS map[string]*ymlKeyWrapping `yaml:"external_kms"`
+
 	Cache struct {
 		Expiry struct {
 			Any     env[time.Duration] `yaml:"any"`
 			Unused  env[time.Duration] `yaml:"unused"`
 			Offline env[time.Duration] `yaml:"offline"`
+			Policy  env[time.Duration] `yaml:"policy"`
 		} `yaml:"expiry"`
+		Warmup struct {
+			Keys    []env[string]      `yaml:"keys"`
+			Timeout env[time.Duration] `yaml:"timeout"`
+		} `yaml:"warmup"`
+		Persist struct {
+			Path     env[string]        `yaml:"path"`
+			Key      env[string]        `yaml:"key"`
+			Interval env[time.Duration] `yaml:"interval"`
+		} `yaml:"persist"`
 	} `yaml:"cache"`
 
 	API struct {
 		Paths map[string]struct {
 			InsecureSkipAuth env[bool]          `yaml:"skip_auth"`
 			Timeout          env[time.Duration] `yaml:"timeout"`
+			MaxBody          env[string]        `yaml:"max_body"`
+			MaxInFlight      env[int]           `yaml:"max_in_flight"`
+			Deprecated       env[time.Time]     `yaml:"deprecated"`
+			Sunset           env[time.Time]     `yaml:"sunset"`
+			Successor        env[string]        `yaml:"successor"`
 		} `yaml:",inline"`
 	} `yaml:"api"`
 
 	Log struct {
 		Error env[string] `yaml:"error"`
 		Audit env[string] `yaml:"audit"`
+
+		AuditFile struct {
+			Path     env[string]        `yaml:"path"`
+			MaxSize  env[string]        `yaml:"max_size"`
+			Rotate   env[time.Duration] `yaml:"rotate"`
+			MaxFiles env[int]           `yaml:"max_files"`
+			MaxAge   env[time.Duration] `yaml:"max_age"`
+			Compress env[bool]          `yaml:"compress"`
+		} `yaml:"audit_file"`
+
+		AuditSync struct {
+			Endpoint env[string]        `yaml:"endpoint"`
+			Interval env[time.Duration] `yaml:"interval"`
+			TLS      struct {
+				PrivateKey  env[string] `yaml:"key"`
+				Certificate env[string] `yaml:"cert"`
+				CAPath      env[string] `yaml:"ca"`
+			} `yaml:"tls"`
+		} `yaml:"audit_sync"`
 	} `yaml:"log"`
 
+	Heartbeat struct {
+		Endpoint env[string]        `yaml:"endpoint"`
+		Node     env[string]        `yaml:"node"`
+		Interval env[time.Duration] `yaml:"interval"`
+		TLS      struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"heartbeat"`
+
+	Metrics struct {
+		StatsD struct {
+			Endpoint  env[string]        `yaml:"endpoint"`
+			Prefix    env[string]        `yaml:"prefix"`
+			Interval  env[time.Duration] `yaml:"interval"`
+			DogStatsD env[bool]          `yaml:"dogstatsd"`
+			Tags      []env[string]      `yaml:"tags"`
+		} `yaml:"statsd"`
+	} `yaml:"metrics"`
+
 	Keys []struct {
 		Name env[string] `yaml:"name"`
 	} `yaml:"keys"`
 
-	KeyStore struct {
-		FS *struct {
-			Path env[string] `yaml:"path"`
-		}
-		KES *struct {
-			Endpoint []env[string] `yaml:"endpoint"`
-			Enclave  env[string]   `yaml:"enclave"`
-			TLS      struct {
-				Certificate env[string] `yaml:"cert"`
-				PrivateKey  env[string] `yaml:"key"`
-				CAPath      env[string] `yaml:"ca"`
+	KeyStore ymlKeyStore `yaml:"keystore"`
+
+	Namespaces map[string]struct {
+		Policies map[string]struct {
+			Allow      []string            `yaml:"allow"`
+			Deny       []string            `yaml:"deny"`
+			Identities []env[kes.Identity] `yaml:"identities"`
+		} `yaml:"policy"`
+
+		Keys []struct {
+			Name env[string] `yaml:"name"`
+		} `yaml:"keys"`
+
+		KeyStore ymlKeyStore `yaml:"keystore"`
+
+		KeyPolicy ymlKeyPolicy `yaml:"key_policy"`
+	} `yaml:"namespace"`
+}
+
+// ymlKeyPolicy is the YAML representation of a key
+// algorithm policy.
+type ymlKeyPolicy struct {
+	DefaultAlgorithm env[string]   `yaml:"default_algorithm"`
+	Algorithms       []env[string] `yaml:"algorithms"`
+	MinKeySize       env[int]      `yaml:"min_key_size"`
+}
+
+// ymlKeyWrapping is the YAML representation of an external
+// KMS that wraps keys, or holds the keys of external keys.
+type ymlKeyWrapping struct {
+	AWS *struct {
+		KMS *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Region   env[string] `yaml:"region"`
+			Key      env[string] `yaml:"key"`
+
+			Login struct {
+				AccessKey    env[string] `yaml:"accesskey"`
+				SecretKey    env[string] `yaml:"secretkey"`
+				SessionToken env[string] `yaml:"token"`
+			} `yaml:"credentials"`
+		} `yaml:"kms"`
+	} `yaml:"aws"`
+
+	GCP *struct {
+		KMS *struct {
+			Endpoint    env[string]   `yaml:"endpoint"`
+			Key         env[string]   `yaml:"key"`
+			Scopes      []env[string] `yaml:"scopes"`
+			Credentials struct {
+				Client   env[string] `yaml:"client_email"`
+				ClientID env[string] `yaml:"client_id"`
+				KeyID    env[string] `yaml:"private_key_id"`
+				Key      env[string] `yaml:"private_key"`
+			} `yaml:"credentials"`
+		} `yaml:"kms"`
+	} `yaml:"gcp"`
+
+	Azure *struct {
+		KeyVault *struct {
+			Endpoint    env[string] `yaml:"endpoint"`
+			Key         env[string] `yaml:"key"`
+			Credentials *struct {
+				TenantID env[string] `yaml:"tenant_id"`
+				ClientID env[string] `yaml:"client_id"`
+				Secret   env[string] `yaml:"client_secret"`
+			} `yaml:"credentials"`
+			ManagedIdentity *struct {
+				ClientID env[string] `yaml:"client_id"`
+			} `yaml:"managed_identity"`
+		} `yaml:"keyvault"`
+	} `yaml:"azure"`
+
+	KES *struct {
+		Endpoint []env[string] `yaml:"endpoint"`
+		Enclave  env[string]   `yaml:"enclave"`
+		Key      env[string]   `yaml:"key"`
+		TLS      struct {
+			Certificate env[string] `yaml:"cert"`
+			PrivateKey  env[string] `yaml:"key"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"kes"`
+
+	// AllowUnwrapped is only valid for keystore wrapping.
+	AllowUnwrapped env[bool] `yaml:"allow_unwrapped"`
+}
+
+// ymlKeyStore is the YAML representation of a keystore
+// configuration.
+type ymlKeyStore struct {
+	Breaker struct {
+		Threshold env[int]           `yaml:"threshold"`
+		Cooldown  env[time.Duration] `yaml:"cooldown"`
+	} `yaml:"breaker"`
+
+	Wrapping *ymlKeyWrapping `yaml:"wrapping"`
+
+	FS *struct {
+		Path env[string] `yaml:"path"`
+	}
+	KES *struct {
+		Endpoint []env[string] `yaml:"endpoint"`
+		Enclave  env[string]   `yaml:"enclave"`
+		TLS      struct {
+			Certificate env[string] `yaml:"cert"`
+			PrivateKey  env[string] `yaml:"key"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"kes"`
+
+	Vault *struct {
+		Endpoint   env[string] `yaml:"endpoint"`
+		Engine     env[string] `yaml:"engine"`
+		APIVersion env[string] `yaml:"version"`
+		Namespace  env[string] `yaml:"namespace"`
+		Prefix     env[string] `yaml:"prefix"`
+
+		AppRole *struct {
+			Engine env[string] `yaml:"engine"`
+			ID     env[string] `yaml:"id"`
+			Secret env[string] `yaml:"secret"`
+		} `yaml:"approle"`
+
+		Kubernetes *struct {
+			Engine env[string] `yaml:"engine"`
+			Role   env[string] `yaml:"role"`
+			JWT    env[string] `yaml:"jwt"` // Can be either a JWT or a path to a file containing a JWT
+		} `yaml:"kubernetes"`
+
+		TLS struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+
+		Status struct {
+			Ping env[time.Duration] `yaml:"ping"`
+		} `yaml:"status"`
+	} `yaml:"vault"`
+
+	Fortanix *struct {
+		SDKMS *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			GroupID  env[string] `yaml:"group_id"`
+
+			Login struct {
+				APIKey env[string] `yaml:"key"`
+			} `yaml:"credentials"`
+
+			TLS struct {
+				CAPath env[string] `yaml:"ca"`
 			} `yaml:"tls"`
-		} `yaml:"kes"`
-
-		Vault *struct {
-			Endpoint   env[string] `yaml:"endpoint"`
-			Engine     env[string] `yaml:"engine"`
-			APIVersion env[string] `yaml:"version"`
-			Namespace  env[string] `yaml:"namespace"`
-			Prefix     env[string] `yaml:"prefix"`
-
-			AppRole *struct {
-				Engine env[string] `yaml:"engine"`
-				ID     env[string] `yaml:"id"`
-				Secret env[string] `yaml:"secret"`
-			} `yaml:"approle"`
-
-			Kubernetes *struct {
-				Engine env[string] `yaml:"engine"`
-				Role   env[string] `yaml:"role"`
-				JWT    env[string] `yaml:"jwt"` // Can be either a JWT or a path to a file containing a JWT
-			} `yaml:"kubernetes"`
+		} `yaml:"sdkms"`
+	} `yaml:"fortanix"`
+
+	Gemalto *struct {
+		KeySecure *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+
+			Login struct {
+				Token  env[string] `yaml:"token"`
+				Domain env[string] `yaml:"domain"`
+			} `yaml:"credentials"`
 
 			TLS struct {
-				PrivateKey  env[string] `yaml:"key"`
-				Certificate env[string] `yaml:"cert"`
-				CAPath      env[string] `yaml:"ca"`
+				CAPath env[string] `yaml:"ca"`
 			} `yaml:"tls"`
+		} `yaml:"keysecure"`
+	} `yaml:"gemalto"`
+
+	GCP *struct {
+		SecretManager *struct {
+			ProjectID   env[string]   `yaml:"project_id"`
+			Endpoint    env[string]   `yaml:"endpoint"`
+			Scopes      []env[string] `yaml:"scopes"`
+			Credentials struct {
+				Client   env[string] `yaml:"client_email"`
+				ClientID env[string] `yaml:"client_id"`
+				KeyID    env[string] `yaml:"private_key_id"`
+				Key      env[string] `yaml:"private_key"`
+			} `yaml:"credentials"`
+		} `yaml:"secretmanager"`
+	} `yaml:"gcp"`
+
+	AWS *struct {
+		SecretsManager *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Region   env[string] `yaml:"region"`
+			KmsKey   env[string] ` yaml:"kmskey"`
+
+			Login struct {
+				AccessKey    env[string] `yaml:"accesskey"`
+				SecretKey    env[string] `yaml:"secretkey"`
+				SessionToken env[string] `yaml:"token"`
+			} `yaml:"credentials"`
+		} `yaml:"secretsmanager"`
+	} `yaml:"aws"`
+
+	Azure *struct {
+		KeyVault *struct {
+			Endpoint    env[string] `yaml:"endpoint"`
+			Credentials *struct {
+				TenantID env[string] `yaml:"tenant_id"`
+				ClientID env[string] `yaml:"client_id"`
+				Secret   env[string] `yaml:"client_secret"`
+			} `yaml:"credentials"`
+			ManagedIdentity *struct {
+				ClientID env[string] `yaml:"client_id"`
+			} `yaml:"managed_identity"`
+		} `yaml:"keyvault"`
+	} `yaml:"azure"`
+
+	Plugin *struct {
+		Path   env[string]   `yaml:"path"`
+		Args   []env[string] `yaml:"args"`
+		Socket env[string]   `yaml:"socket"`
+	} `yaml:"plugin"`
+
+	Bundle *struct {
+		Path      env[string] `yaml:"path"`
+		Key       env[string] `yaml:"key"`
+		PublicKey env[string] `yaml:"public_key"`
+	} `yaml:"bundle"`
 
-			Status struct {
-				Ping env[time.Duration] `yaml:"ping"`
-			} `yaml:"status"`
-		} `yaml:"vault"`
-
-		Fortanix *struct {
-			SDKMS *struct {
-				Endpoint env[string] `yaml:"endpoint"`
-				GroupID  env[string] `yaml:"group_id"`
-
-				Login struct {
-					APIKey env[string] `yaml:"key"`
-				} `yaml:"credentials"`
-
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"sdkms"`
-		} `yaml:"fortanix"`
-
-		Gemalto *struct {
-			KeySecure *struct {
-				Endpoint env[string] `yaml:"endpoint"`
-
-				Login struct {
-					Token  env[string] `yaml:"token"`
-					Domain env[string] `yaml:"domain"`
-				} `yaml:"credentials"`
-
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"keysecure"`
-		} `yaml:"gemalto"`
-
-		GCP *struct {
-			SecretManager *struct {
-				ProjectID   env[string]   `yaml:"project_id"`
-				Endpoint    env[string]   `yaml:"endpoint"`
-				Scopes      []env[string] `yaml:"scopes"`
-				Credentials struct {
-					Client   env[string] `yaml:"client_email"`
-					ClientID env[string] `yaml:"client_id"`
-					KeyID    env[string] `yaml:"private_key_id"`
-					Key      env[string] `yaml:"private_key"`
-				} `yaml:"credentials"`
-			} `yaml:"secretmanager"`
-		} `yaml:"gcp"`
-
-		AWS *struct {
-			SecretsManager *struct {
-				Endpoint env[string] `yaml:"endpoint"`
-				Region   env[string] `yaml:"region"`
-				KmsKey   env[string] ` yaml:"kmskey"`
-
-				Login struct {
-					AccessKey    env[string] `yaml:"accesskey"`
-					SecretKey    env[string] `yaml:"secretkey"`
-					SessionToken env[string] `yaml:"token"`
-				} `yaml:"credentials"`
-			} `yaml:"secretsmanager"`
-		} `yaml:"aws"`
-
-		Azure *struct {
-			KeyVault *struct {
-				Endpoint    env[string] `yaml:"endpoint"`
-				Credentials *struct {
-					TenantID env[string] `yaml:"tenant_id"`
-					ClientID env[string] `yaml:"client_id"`
-					Secret   env[string] `yaml:"client_secret"`
-				} `yaml:"credentials"`
-				ManagedIdentity *struct {
-					ClientID env[string] `yaml:"client_id"`
-				} `yaml:"managed_identity"`
-			} `yaml:"keyvault"`
-		} `yaml:"azure"`
-	} `yaml:"keystore"`
+	Proxy *struct {
+		KMS       env[string] `yaml:"kms"`
+		KeyPrefix env[string] `yaml:"key_prefix"`
+	} `yaml:"proxy"`
 }
 
 func findVersion(root *yaml.Node) (string, error) {
@@ -249,6 +439,11 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 		}
 	}
 
+	namespaces, err := ymlToNamespaces(y)
+	if err != nil {
+		return nil, err
+	}
+
 	if y.Cache.Expiry.Any.Value < 0 {
 		return nil, fmt.Errorf("edge: invalid cache expiry '%v'", y.Cache.Expiry.Any.Value)
 	}
@@ -258,6 +453,24 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 	if y.Cache.Expiry.Offline.Value < 0 {
 		return nil, fmt.Errorf("edge: invalid offline cache expiry '%v'", y.Cache.Expiry.Offline.Value)
 	}
+	if y.Cache.Warmup.Timeout.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid cache warmup timeout '%v'", y.Cache.Warmup.Timeout.Value)
+	}
+	if y.Cache.Persist.Path.Value == "" && y.Cache.Persist.Key.Value != "" {
+		return nil, errors.New("edge: invalid cache persist config: no path specified")
+	}
+	if y.Cache.Persist.Path.Value != "" && y.Cache.Expiry.Offline.Value == 0 {
+		return nil, errors.New("edge: invalid cache persist config: offline cache is disabled")
+	}
+	if y.Cache.Persist.Interval.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid cache persist interval '%v'", y.Cache.Persist.Interval.Value)
+	}
+	var warmup []string
+	for _, key := range y.Cache.Warmup.Keys {
+		if name := strings.TrimSpace(key.Value); name != "" {
+			warmup = append(warmup, name)
+		}
+	}
 
 	if v := strings.ToLower(strings.TrimSpace(y.Log.Error.Value)); v != "on" && v != "off" && v != "" {
 		return nil, fmt.Errorf("edge: invalid error log config '%v'", y.Log.Error.Value)
@@ -265,11 +478,66 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 	if v := strings.ToLower(strings.TrimSpace(y.Log.Audit.Value)); v != "on" && v != "off" && v != "" {
 		return nil, fmt.Errorf("edge: invalid audit log config '%v'", y.Log.Audit.Value)
 	}
+	var auditFileSize mem.Size
+	if v := strings.TrimSpace(y.Log.AuditFile.MaxSize.Value); v != "" {
+		size, err := mem.ParseSize(v)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("edge: invalid audit file max size '%v'", y.Log.AuditFile.MaxSize.Value)
+		}
+		auditFileSize = size
+	}
+	if y.Log.AuditFile.Rotate.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid audit file rotation '%v'", y.Log.AuditFile.Rotate.Value)
+	}
+	if y.Log.AuditFile.MaxFiles.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid audit file max files '%d'", y.Log.AuditFile.MaxFiles.Value)
+	}
+	if y.Log.AuditFile.MaxAge.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid audit file max age '%v'", y.Log.AuditFile.MaxAge.Value)
+	}
+
+	if y.Log.AuditSync.Endpoint.Value != "" && strings.TrimSpace(y.Log.AuditFile.Path.Value) == "" {
+		return nil, errors.New("edge: invalid audit sync config: no audit file specified")
+	}
+	if y.Log.AuditSync.Interval.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid audit sync interval '%v'", y.Log.AuditSync.Interval.Value)
+	}
+	if y.Heartbeat.Interval.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid heartbeat interval '%v'", y.Heartbeat.Interval.Value)
+	}
+	if (y.Heartbeat.TLS.PrivateKey.Value == "") != (y.Heartbeat.TLS.Certificate.Value == "") {
+		return nil, errors.New("edge: invalid heartbeat config: TLS private key and certificate must be specified together")
+	}
+	if y.Metrics.StatsD.Interval.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid statsd interval '%v'", y.Metrics.StatsD.Interval.Value)
+	}
+	for _, tag := range y.Metrics.StatsD.Tags {
+		if k, _, ok := strings.Cut(tag.Value, ":"); !ok || k == "" || strings.ContainsAny(tag.Value, "|,#\n") {
+			return nil, fmt.Errorf("edge: invalid statsd tag '%s'", tag.Value)
+		}
+	}
+	if (y.Log.AuditSync.TLS.PrivateKey.Value == "") != (y.Log.AuditSync.TLS.Certificate.Value == "") {
+		return nil, errors.New("edge: invalid audit sync config: TLS private key and certificate must be specified together")
+	}
 
 	for path, api := range y.API.Paths {
 		if api.Timeout.Value < 0 {
 			return nil, fmt.Errorf("edge: invalid timeout '%d' for API '%s'", api.Timeout.Value, path)
 		}
+		if v := strings.TrimSpace(api.MaxBody.Value); v != "" {
+			if size, err := mem.ParseSize(v); err != nil || size < 0 {
+				return nil, fmt.Errorf("edge: invalid max. body size '%v' for API '%s'", api.MaxBody.Value, path)
+			}
+		}
+		if api.MaxInFlight.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid max. in-flight requests '%d' for API '%s'", api.MaxInFlight.Value, path)
+		}
+		if !api.Sunset.Value.IsZero() && api.Sunset.Value.Before(api.Deprecated.Value) {
+			return nil, fmt.Errorf("edge: invalid sunset for API '%s': sunset '%v' is before deprecation '%v'", path, api.Sunset.Value, api.Deprecated.Value)
+		}
+		if successor := api.Successor.Value; successor != "" && !strings.HasPrefix(successor, "/") {
+			return nil, fmt.Errorf("edge: invalid successor '%s' for API '%s': successor must be an API path", successor, path)
+		}
 	}
 
 	if len(y.Keys) > 0 {
@@ -282,10 +550,58 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 		}
 	}
 
+	if y.Authorizer.Endpoint.Value == "" && (y.Authorizer.TLS.PrivateKey.Value != "" || y.Authorizer.TLS.Certificate.Value != "") {
+		return nil, errors.New("edge: invalid authorizer config: no endpoint specified")
+	}
+	if (y.Authorizer.TLS.PrivateKey.Value == "") != (y.Authorizer.TLS.Certificate.Value == "") {
+		return nil, errors.New("edge: invalid authorizer config: TLS private key and certificate must be specified together")
+	}
+
+	keyPolicy, err := ymlToKeyPolicy(&y.KeyPolicy)
+	if err != nil {
+		return nil, err
+	}
+	var names *NameConfig
+	if len(y.Names.Reserved) > 0 || y.Names.MaxLength.Value != 0 || y.Names.Extended.Value {
+		if y.Names.MaxLength.Value < 0 {
+			return nil, fmt.Errorf("edge: invalid max. name length '%d': must not be negative", y.Names.MaxLength.Value)
+		}
+		names = &NameConfig{
+			MaxLength: y.Names.MaxLength.Value,
+			Extended:  y.Names.Extended.Value,
+			Reserved:  make(map[string][]string, len(y.Names.Reserved)),
+		}
+		for prefix, policies := range y.Names.Reserved {
+			if len(policies) == 0 {
+				return nil, fmt.Errorf("edge: invalid reserved prefix '%s': no policy specified", prefix)
+			}
+			for _, policy := range policies {
+				names.Reserved[prefix] = append(names.Reserved[prefix], policy.Value)
+			}
+		}
+	}
+	externalKMS, err := ymlToExternalKMS(y)
+	if err != nil {
+		return nil, err
+	}
+
 	keystore, err := ymlToKeyStore(y)
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := keystore.(*ProxyKeyStore); ok && len(y.Keys) > 0 {
+		return nil, errors.New("edge: invalid key config: keys of an external KMS cannot be created")
+	}
+	wrapping, err := ymlToKeyWrapping(y)
+	if err != nil {
+		return nil, err
+	}
+	if y.KeyStore.Breaker.Threshold.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid keystore breaker threshold '%d'", y.KeyStore.Breaker.Threshold.Value)
+	}
+	if y.KeyStore.Breaker.Cooldown.Value < 0 {
+		return nil, fmt.Errorf("edge: invalid keystore breaker cooldown '%v'", y.KeyStore.Breaker.Cooldown.Value)
+	}
 
 	c := &ServerConfig{
 		Addr:  y.Addr.Value,
@@ -301,12 +617,89 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 			Expiry:        y.Cache.Expiry.Any.Value,
 			ExpiryUnused:  y.Cache.Expiry.Unused.Value,
 			ExpiryOffline: y.Cache.Expiry.Offline.Value,
+			ExpiryPolicy:  y.Cache.Expiry.Policy.Value,
+			Warmup:        warmup,
+			WarmupTimeout: y.Cache.Warmup.Timeout.Value,
 		},
 		Log: &LogConfig{
 			Error: strings.TrimSpace(strings.ToLower(y.Log.Error.Value)) != "off", // default is "on" behavior
-			Audit: strings.TrimSpace(strings.ToLower(y.Log.Audit.Value)) != "on",  // default is "off" behavior
+			Audit: strings.TrimSpace(strings.ToLower(y.Log.Audit.Value)) == "on",  // default is "off" behavior
 		},
-		KeyStore: keystore,
+		KeyStore:    keystore,
+		KeyWrapping: wrapping,
+		KeyPolicy:   keyPolicy,
+		Names:       names,
+		ExternalKMS: externalKMS,
+		Namespaces:  namespaces,
+	}
+	if wrapping != nil {
+		c.AllowUnwrappedKeys = y.KeyStore.Wrapping.AllowUnwrapped.Value
+	}
+	if y.Cache.Persist.Path.Value != "" {
+		c.Cache.Persist = &CachePersistConfig{
+			Path:     y.Cache.Persist.Path.Value,
+			Key:      y.Cache.Persist.Key.Value,
+			Interval: y.Cache.Persist.Interval.Value,
+		}
+	}
+	if y.KeyStore.Breaker.Threshold.Value > 0 {
+		c.Breaker = &BreakerConfig{
+			Threshold: y.KeyStore.Breaker.Threshold.Value,
+			Cooldown:  y.KeyStore.Breaker.Cooldown.Value,
+		}
+	}
+	if y.Authorizer.Endpoint.Value != "" {
+		c.Authorizer = &AuthorizerConfig{
+			Endpoint:    y.Authorizer.Endpoint.Value,
+			CacheExpiry: y.Authorizer.Expiry.Value,
+			PrivateKey:  y.Authorizer.TLS.PrivateKey.Value,
+			Certificate: y.Authorizer.TLS.Certificate.Value,
+			CAPath:      y.Authorizer.TLS.CAPath.Value,
+		}
+	}
+	if path := strings.TrimSpace(y.Log.AuditFile.Path.Value); path != "" {
+		c.Log.AuditFile = &AuditFileConfig{
+			Path:     path,
+			MaxSize:  int64(auditFileSize),
+			Rotate:   y.Log.AuditFile.Rotate.Value,
+			MaxFiles: y.Log.AuditFile.MaxFiles.Value,
+			MaxAge:   y.Log.AuditFile.MaxAge.Value,
+			Compress: y.Log.AuditFile.Compress.Value,
+		}
+		if y.Log.AuditSync.Endpoint.Value != "" {
+			c.Log.AuditFile.Sync = &AuditSyncConfig{
+				Endpoint:    y.Log.AuditSync.Endpoint.Value,
+				Interval:    y.Log.AuditSync.Interval.Value,
+				PrivateKey:  y.Log.AuditSync.TLS.PrivateKey.Value,
+				Certificate: y.Log.AuditSync.TLS.Certificate.Value,
+				CAPath:      y.Log.AuditSync.TLS.CAPath.Value,
+			}
+		}
+	}
+	if endpoint := strings.TrimSpace(y.Heartbeat.Endpoint.Value); endpoint != "" {
+		c.Heartbeat = &HeartbeatConfig{
+			Endpoint:    endpoint,
+			Node:        y.Heartbeat.Node.Value,
+			Interval:    y.Heartbeat.Interval.Value,
+			PrivateKey:  y.Heartbeat.TLS.PrivateKey.Value,
+			Certificate: y.Heartbeat.TLS.Certificate.Value,
+			CAPath:      y.Heartbeat.TLS.CAPath.Value,
+		}
+	}
+	if endpoint := strings.TrimSpace(y.Metrics.StatsD.Endpoint.Value); endpoint != "" {
+		tags := make([]string, 0, len(y.Metrics.StatsD.Tags))
+		for _, tag := range y.Metrics.StatsD.Tags {
+			tags = append(tags, tag.Value)
+		}
+		c.Metrics = &MetricsConfig{
+			StatsD: &StatsDConfig{
+				Endpoint:  endpoint,
+				Prefix:    y.Metrics.StatsD.Prefix.Value,
+				Interval:  y.Metrics.StatsD.Interval.Value,
+				DogStatsD: y.Metrics.StatsD.DogStatsD.Value,
+				Tags:      tags,
+			},
+		}
 	}
 	if len(y.TLS.Proxy.Identities) > 0 {
 		c.TLS.Proxies = make([]kes.Identity, 0, len(y.TLS.Proxy.Identities))
@@ -331,9 +724,18 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 	if len(y.API.Paths) > 0 {
 		paths := make(map[string]APIPathConfig, len(y.API.Paths))
 		for path, api := range y.API.Paths {
+			var maxBody mem.Size
+			if v := strings.TrimSpace(api.MaxBody.Value); v != "" {
+				maxBody, _ = mem.ParseSize(v) // Validated before
+			}
 			paths[path] = APIPathConfig{
 				InsecureSkipAuth: api.InsecureSkipAuth.Value,
 				Timeout:          api.Timeout.Value,
+				MaxBody:          int64(maxBody),
+				MaxInFlight:      api.MaxInFlight.Value,
+				Deprecated:       api.Deprecated.Value,
+				Sunset:           api.Sunset.Value,
+				Successor:        api.Successor.Value,
 			}
 		}
 		c.API = &APIConfig{
@@ -354,6 +756,119 @@ func ymlToServerConfig(y *yml) (*ServerConfig, error) {
 	return c, nil
 }
 
+// ymlToNamespaces returns the namespace configurations
+// of the YAML config, if any.
+func ymlToNamespaces(y *yml) (map[string]*Namespace, error) {
+	if len(y.Namespaces) == 0 {
+		return nil, nil
+	}
+
+	namespaces := make(map[string]*Namespace, len(y.Namespaces))
+	for name, ns := range y.Namespaces {
+		if err := verifyNamespace(name); err != nil {
+			return nil, err
+		}
+		if ns.KeyStore.Wrapping != nil || ns.KeyStore.Breaker.Threshold.Value != 0 {
+			return nil, fmt.Errorf("edge: invalid namespace '%s': key wrapping and circuit breaker apply to all namespaces and must be configured in the top-level keystore", name)
+		}
+
+		keystore, err := ymlToKeyStore(&yml{KeyStore: ns.KeyStore, ExternalKMS: y.ExternalKMS})
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid namespace '%s': %v", name, err)
+		}
+		if keystore == nil {
+			return nil, fmt.Errorf("edge: invalid namespace '%s': no keystore specified", name)
+		}
+		if _, ok := keystore.(*ProxyKeyStore); ok && len(ns.Keys) > 0 {
+			return nil, fmt.Errorf("edge: invalid namespace '%s': keys of an external KMS cannot be created", name)
+		}
+
+		keyPolicy, err := ymlToKeyPolicy(&ns.KeyPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("edge: invalid namespace '%s': %v", name, err)
+		}
+
+		namespace := &Namespace{
+			KeyStore:  keystore,
+			KeyPolicy: keyPolicy,
+			Policies:  make(map[string]Policy, len(ns.Policies)),
+		}
+		for policyName, policy := range ns.Policies {
+			identities := make([]kes.Identity, 0, len(policy.Identities))
+			for _, identity := range policy.Identities {
+				if identity.Value == y.Admin.Identity.Value {
+					return nil, fmt.Errorf("edge: invalid policy '%s' of namespace '%s': identity '%s' is already admin", policyName, name, identity.Value)
+				}
+				for _, proxy := range y.TLS.Proxy.Identities {
+					if identity.Value == proxy.Value {
+						return nil, fmt.Errorf("edge: invalid policy '%s' of namespace '%s': identity '%s' is already a TLS proxy", policyName, name, identity.Value)
+					}
+				}
+				identities = append(identities, identity.Value)
+			}
+			namespace.Policies[policyName] = Policy{
+				Allow:      policy.Allow,
+				Deny:       policy.Deny,
+				Identities: identities,
+			}
+		}
+		for _, key := range ns.Keys {
+			namespace.Keys = append(namespace.Keys, Key{Name: key.Name.Value})
+		}
+		namespaces[name] = namespace
+	}
+	return namespaces, nil
+}
+
+// ymlToKeyPolicy returns the key policy of the YAML config.
+// It returns nil if the YAML config does not restrict the
+// algorithms of new keys.
+func ymlToKeyPolicy(y *ymlKeyPolicy) (*KeyPolicyConfig, error) {
+	algorithms := make([]string, 0, len(y.Algorithms))
+	for _, a := range y.Algorithms {
+		algorithms = append(algorithms, a.Value)
+	}
+	policy, err := key.ParseAlgorithmPolicy(y.DefaultAlgorithm.Value, algorithms, y.MinKeySize.Value)
+	if err != nil {
+		return nil, fmt.Errorf("edge: invalid key policy: %v", err)
+	}
+	if err = policy.Validate(); err != nil {
+		return nil, fmt.Errorf("edge: %v", err)
+	}
+	if policy.IsZero() {
+		return nil, nil
+	}
+	return &KeyPolicyConfig{
+		DefaultAlgorithm: y.DefaultAlgorithm.Value,
+		Algorithms:       algorithms,
+		MinKeySize:       y.MinKeySize.Value,
+	}, nil
+}
+
+// verifyNamespace returns an error if name is not a valid
+// namespace name. Valid names consist of letters, digits,
+// '-', '_' and '.' and must not be "default" since the
+// top-level keystore is the default namespace.
+func verifyNamespace(name string) error {
+	if name == "" {
+		return errors.New("edge: invalid namespace: empty namespace name")
+	}
+	if name == "default" {
+		return errors.New("edge: invalid namespace 'default': the top-level keystore is the default namespace")
+	}
+	if len(name) > 80 {
+		return fmt.Errorf("edge: invalid namespace '%s': name is too long", name)
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return fmt.Errorf("edge: invalid namespace '%s': name contains invalid character '%c'", name, c)
+		}
+	}
+	return nil
+}
+
 func ymlToKeyStore(y *yml) (KeyStore, error) {
 	var keystore KeyStore
 
@@ -596,12 +1111,260 @@ func ymlToKeyStore(y *yml) (KeyStore, error) {
 		keystore = s
 	}
 
+	// Keystore Plugin
+	if y.KeyStore.Plugin != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.KeyStore.Plugin.Path.Value == "" && y.KeyStore.Plugin.Socket.Value == "" {
+			return nil, errors.New("edge: invalid plugin keystore: no plugin path or socket specified")
+		}
+		if y.KeyStore.Plugin.Path.Value != "" && y.KeyStore.Plugin.Socket.Value != "" {
+			return nil, errors.New("edge: invalid plugin keystore: plugin path and socket specified")
+		}
+		args := make([]string, 0, len(y.KeyStore.Plugin.Args))
+		for _, arg := range y.KeyStore.Plugin.Args {
+			args = append(args, arg.Value)
+		}
+		keystore = &PluginKeyStore{
+			Path:   y.KeyStore.Plugin.Path.Value,
+			Args:   args,
+			Socket: y.KeyStore.Plugin.Socket.Value,
+		}
+	}
+
+	// Offline Key Bundle
+	if y.KeyStore.Bundle != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		if y.KeyStore.Bundle.Path.Value == "" {
+			return nil, errors.New("edge: invalid bundle keystore: no bundle path specified")
+		}
+		if y.KeyStore.Bundle.Key.Value == "" {
+			return nil, errors.New("edge: invalid bundle keystore: no bundle key specified")
+		}
+		if y.KeyStore.Bundle.PublicKey.Value == "" {
+			return nil, errors.New("edge: invalid bundle keystore: no public key specified")
+		}
+		keystore = &BundleKeyStore{
+			Path:      y.KeyStore.Bundle.Path.Value,
+			Key:       y.KeyStore.Bundle.Key.Value,
+			PublicKey: y.KeyStore.Bundle.PublicKey.Value,
+		}
+	}
+
+	// Proxy to an external KMS
+	if y.KeyStore.Proxy != nil {
+		if keystore != nil {
+			return nil, errors.New("edge: invalid keystore config: more than once keystore specified")
+		}
+		name := y.KeyStore.Proxy.KMS.Value
+		if name == "" {
+			return nil, errors.New("edge: invalid proxy keystore: no external KMS specified")
+		}
+		w, ok := y.ExternalKMS[name]
+		if !ok || w == nil {
+			return nil, fmt.Errorf("edge: invalid proxy keystore: external KMS '%s' does not exist", name)
+		}
+		kms, err := ymlToKMS(w, "external KMS '"+name+"'", false)
+		if err != nil {
+			return nil, err
+		}
+		keystore = &ProxyKeyStore{
+			KMS:         name,
+			KeyPrefix:   y.KeyStore.Proxy.KeyPrefix.Value,
+			ExternalKMS: kms.(ExternalKMS),
+		}
+	}
+
 	if keystore == nil {
 		return nil, errors.New("edge: no keystore specified")
 	}
 	return keystore, nil
 }
 
+func ymlToKeyWrapping(y *yml) (KeyWrapping, error) {
+	w := y.KeyStore.Wrapping
+	if w == nil {
+		return nil, nil
+	}
+	if y.KeyStore.Bundle != nil {
+		return nil, errors.New("edge: invalid keystore wrapping config: keys of an offline bundle cannot be wrapped")
+	}
+	if y.KeyStore.Proxy != nil {
+		return nil, errors.New("edge: invalid keystore wrapping config: keys of an external KMS cannot be wrapped")
+	}
+	return ymlToKMS(w, "keystore wrapping", true)
+}
+
+// ymlToExternalKMS returns the external KMS configurations,
+// by name, of the YAML config, if any.
+func ymlToExternalKMS(y *yml) (map[string]ExternalKMS, error) {
+	if len(y.ExternalKMS) == 0 {
+		return nil, nil
+	}
+
+	kms := make(map[string]ExternalKMS, len(y.ExternalKMS))
+	for name, w := range y.ExternalKMS {
+		if name == "" {
+			return nil, errors.New("edge: invalid external KMS: empty name")
+		}
+		if w == nil {
+			return nil, fmt.Errorf("edge: invalid external KMS '%s': no KMS specified", name)
+		}
+		if w.AllowUnwrapped.Value {
+			return nil, fmt.Errorf("edge: invalid external KMS '%s': 'allow_unwrapped' is only valid for keystore wrapping", name)
+		}
+		wrapping, err := ymlToKMS(w, "external KMS '"+name+"'", false)
+		if err != nil {
+			return nil, err
+		}
+		kms[name] = wrapping.(ExternalKMS)
+	}
+	return kms, nil
+}
+
+// ymlToKMS returns the configuration of the KMS specified in w.
+// The kind describes the purpose of the KMS in error messages.
+// If requireKey is true, the KMS configuration has to specify
+// a key. Otherwise, it must not specify one since the keys are
+// specified by external keys.
+func ymlToKMS(w *ymlKeyWrapping, kind string, requireKey bool) (KeyWrapping, error) {
+	var wrapping KeyWrapping
+
+	// AWS-KMS
+	if w.AWS != nil && w.AWS.KMS != nil {
+		if w.AWS.KMS.Region.Value == "" {
+			return nil, fmt.Errorf("edge: invalid AWS KMS %s: no region specified", kind)
+		}
+		if err := verifyKMSKey(w.AWS.KMS.Key.Value, requireKey); err != nil {
+			return nil, fmt.Errorf("edge: invalid AWS KMS %s: %v", kind, err)
+		}
+		wrapping = &AWSKMSKeyWrapping{
+			Endpoint:     w.AWS.KMS.Endpoint.Value,
+			Region:       w.AWS.KMS.Region.Value,
+			Key:          w.AWS.KMS.Key.Value,
+			AccessKey:    w.AWS.KMS.Login.AccessKey.Value,
+			SecretKey:    w.AWS.KMS.Login.SecretKey.Value,
+			SessionToken: w.AWS.KMS.Login.SessionToken.Value,
+		}
+	}
+
+	// GCP Cloud KMS
+	if w.GCP != nil && w.GCP.KMS != nil {
+		if wrapping != nil {
+			return nil, fmt.Errorf("edge: invalid %s config: more than one KMS specified", kind)
+		}
+		if err := verifyKMSKey(w.GCP.KMS.Key.Value, requireKey); err != nil {
+			return nil, fmt.Errorf("edge: invalid GCP KMS %s: %v", kind, err)
+		}
+		scopes := make([]string, 0, len(w.GCP.KMS.Scopes))
+		for _, scope := range w.GCP.KMS.Scopes {
+			if scope.Value != "" {
+				scopes = append(scopes, scope.Value)
+			}
+		}
+		wrapping = &GCPKMSKeyWrapping{
+			Endpoint:    w.GCP.KMS.Endpoint.Value,
+			Key:         w.GCP.KMS.Key.Value,
+			Scopes:      scopes,
+			ClientEmail: w.GCP.KMS.Credentials.Client.Value,
+			ClientID:    w.GCP.KMS.Credentials.ClientID.Value,
+			KeyID:       w.GCP.KMS.Credentials.KeyID.Value,
+			PrivateKey:  w.GCP.KMS.Credentials.Key.Value,
+		}
+	}
+
+	// Azure KeyVault
+	if w.Azure != nil && w.Azure.KeyVault != nil {
+		if wrapping != nil {
+			return nil, fmt.Errorf("edge: invalid %s config: more than one KMS specified", kind)
+		}
+		if w.Azure.KeyVault.Endpoint.Value == "" {
+			return nil, fmt.Errorf("edge: invalid Azure keyvault %s: no endpoint specified", kind)
+		}
+		if err := verifyKMSKey(w.Azure.KeyVault.Key.Value, requireKey); err != nil {
+			return nil, fmt.Errorf("edge: invalid Azure keyvault %s: %v", kind, err)
+		}
+		if w.Azure.KeyVault.Credentials == nil && w.Azure.KeyVault.ManagedIdentity == nil {
+			return nil, fmt.Errorf("edge: invalid Azure keyvault %s: no authentication method specified", kind)
+		}
+		if w.Azure.KeyVault.Credentials != nil && w.Azure.KeyVault.ManagedIdentity != nil {
+			return nil, fmt.Errorf("edge: invalid Azure keyvault %s: more than one authentication method specified", kind)
+		}
+		s := &AzureKeyVaultKeyWrapping{
+			Endpoint: w.Azure.KeyVault.Endpoint.Value,
+			Key:      w.Azure.KeyVault.Key.Value,
+		}
+		if w.Azure.KeyVault.Credentials != nil {
+			s.TenantID = w.Azure.KeyVault.Credentials.TenantID.Value
+			s.ClientID = w.Azure.KeyVault.Credentials.ClientID.Value
+			s.ClientSecret = w.Azure.KeyVault.Credentials.Secret.Value
+			if s.TenantID == "" || s.ClientID == "" || s.ClientSecret == "" {
+				return nil, fmt.Errorf("edge: invalid Azure keyvault %s: tenant ID, client ID and client secret must be specified", kind)
+			}
+		}
+		if w.Azure.KeyVault.ManagedIdentity != nil {
+			s.ManagedIdentityClientID = w.Azure.KeyVault.ManagedIdentity.ClientID.Value
+			if s.ManagedIdentityClientID == "" {
+				return nil, fmt.Errorf("edge: invalid Azure keyvault %s: no client ID specified", kind)
+			}
+		}
+		wrapping = s
+	}
+
+	// KES
+	if w.KES != nil {
+		if wrapping != nil {
+			return nil, fmt.Errorf("edge: invalid %s config: more than one KMS specified", kind)
+		}
+		endpoints := make([]string, 0, len(w.KES.Endpoint))
+		for _, endpoint := range w.KES.Endpoint {
+			if e := strings.TrimSpace(endpoint.Value); e != "" {
+				endpoints = append(endpoints, e)
+			}
+		}
+		if len(endpoints) == 0 {
+			return nil, fmt.Errorf("edge: invalid KES %s: no endpoint specified", kind)
+		}
+		if err := verifyKMSKey(w.KES.Key.Value, requireKey); err != nil {
+			return nil, fmt.Errorf("edge: invalid KES %s: %v", kind, err)
+		}
+		if w.KES.TLS.Certificate.Value == "" {
+			return nil, fmt.Errorf("edge: invalid KES %s: no TLS certificate specified", kind)
+		}
+		if w.KES.TLS.PrivateKey.Value == "" {
+			return nil, fmt.Errorf("edge: invalid KES %s: no TLS private key specified", kind)
+		}
+		wrapping = &KESKeyWrapping{
+			Endpoints:       endpoints,
+			Enclave:         w.KES.Enclave.Value,
+			Key:             w.KES.Key.Value,
+			CertificateFile: w.KES.TLS.Certificate.Value,
+			PrivateKeyFile:  w.KES.TLS.PrivateKey.Value,
+			CAPath:          w.KES.TLS.CAPath.Value,
+		}
+	}
+
+	if wrapping == nil {
+		return nil, fmt.Errorf("edge: invalid %s config: no KMS specified", kind)
+	}
+	return wrapping, nil
+}
+
+// verifyKMSKey returns an error if no key is specified
+// but required or a key is specified but not required.
+func verifyKMSKey(key string, requireKey bool) error {
+	if requireKey && key == "" {
+		return errors.New("no key specified")
+	}
+	if !requireKey && key != "" {
+		return errors.New("key must not be specified since external keys specify their key IDs")
+	}
+	return nil
+}
+
 type env[T any] struct {
 	Var   string
 	Value T
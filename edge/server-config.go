@@ -7,19 +7,24 @@ package edge
 import (
 	"context"
 	"errors"
+	"os"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/keystore/aws"
 	"github.com/minio/kes/internal/keystore/azure"
+	"github.com/minio/kes/internal/keystore/bundle"
 	"github.com/minio/kes/internal/keystore/fortanix"
 	"github.com/minio/kes/internal/keystore/fs"
 	"github.com/minio/kes/internal/keystore/gcp"
 	"github.com/minio/kes/internal/keystore/gemalto"
 	kesstore "github.com/minio/kes/internal/keystore/kes"
+	"github.com/minio/kes/internal/keystore/proxy"
 	"github.com/minio/kes/internal/keystore/vault"
 	"github.com/minio/kes/kms"
 	"github.com/minio/kes/kv"
+	"github.com/minio/kes/kv/plugin"
 )
 
 // ServerConfig is a structure that holds configuration
@@ -66,6 +71,58 @@ type ServerConfig struct {
 	// encryption and decryption.
 	KeyStore KeyStore
 
+	// KeyWrapping contains the configuration of an external
+	// KMS that wraps all keys before they are stored at the
+	// KeyStore. If nil, keys are stored as they are.
+	KeyWrapping KeyWrapping
+
+	// AllowUnwrappedKeys controls whether keys that have been
+	// stored before KeyWrapping has been enabled are used as
+	// they are. Otherwise, such keys cannot be used once
+	// KeyWrapping is enabled. New keys are always wrapped.
+	AllowUnwrappedKeys bool
+
+	// Breaker contains the circuit breaker configuration
+	// for the keystore. If nil, the KES server does not
+	// use a circuit breaker.
+	Breaker *BreakerConfig
+
+	// Authorizer contains the configuration of an external
+	// authorization service that authorizes requests after
+	// the built-in policy checks. If nil, requests are only
+	// authorized by policies.
+	Authorizer *AuthorizerConfig
+
+	// KeyPolicy restricts the algorithms of new keys. If nil,
+	// new keys may use any supported algorithm.
+	KeyPolicy *KeyPolicyConfig
+
+	// Names contains the key name configuration. If nil,
+	// the default name rules apply and no key name prefix
+	// is reserved.
+	Names *NameConfig
+
+	// ExternalKMS contains the external KMS, by name, that
+	// hold the keys referenced by external keys. Clients
+	// register external keys via the /v1/key/register API.
+	ExternalKMS map[string]ExternalKMS
+
+	// Namespaces contains additional key namespaces served
+	// by the same server. Each namespace has its own keystore,
+	// policies and identities. Clients select a namespace via
+	// the enclave query parameter. The top-level keystore,
+	// policies and identities form the default namespace.
+	Namespaces map[string]*Namespace
+
+	// Heartbeat contains the configuration for sending
+	// periodic health reports to an external endpoint.
+	// If nil, the KES server does not send heartbeats.
+	Heartbeat *HeartbeatConfig
+
+	// Metrics contains the metrics export configuration.
+	// If nil, metrics are only exposed via the metrics API.
+	Metrics *MetricsConfig
+
 	_ [0]int // force usage of struct composite literals with field names
 }
 
@@ -132,6 +189,136 @@ type CacheConfig struct {
 	// cache expiry periods apply.
 	ExpiryOffline time.Duration
 
+	// ExpiryPolicy is the time period after which cached
+	// policies and identities are discarded. Policy and
+	// identity changes made via the API take effect
+	// immediately. If 0, it defaults to 1 minute. If < 0,
+	// policies and identities are not cached.
+	ExpiryPolicy time.Duration
+
+	// Warmup is a list of key names or glob patterns, like
+	// "my-app-*". The KES server fetches all matching keys
+	// from the keystore and caches them during startup,
+	// before it starts serving requests.
+	Warmup []string
+
+	// WarmupTimeout is the max. amount of time the KES server
+	// spends fetching the Warmup keys. If <= 0, defaults to
+	// 30 seconds.
+	WarmupTimeout time.Duration
+
+	// Persist is the cache persistence configuration. If nil,
+	// cached keys are not persisted across restarts.
+	Persist *CachePersistConfig
+
+	_ [0]int
+}
+
+// CachePersistConfig is a structure that holds the configuration
+// for persisting the key cache encrypted on disk.
+//
+// Persisted keys are restored into the offline cache on startup.
+// Hence, a KES server can serve requests after a restart while
+// the keystore is not available.
+type CachePersistConfig struct {
+	// Path is the path of the file the cached
+	// keys are written to.
+	Path string
+
+	// Key is the optional hex-encoded 256 bit key used to
+	// encrypt the file. If empty, the key is derived from
+	// the server's TLS private key.
+	Key string
+
+	// Interval is the time period between writing the cached
+	// keys to the file. If <= 0, defaults to 1 minute.
+	Interval time.Duration
+
+	_ [0]int
+}
+
+// AuthorizerConfig is a structure that holds the external
+// authorization service configuration for a KES server.
+type AuthorizerConfig struct {
+	// Endpoint is the URL of the authorization service.
+	Endpoint string
+
+	// CacheExpiry is the time period the KES server caches
+	// the decisions of the authorization service. If 0, a
+	// default expiry period is used. If < 0, decisions are
+	// not cached.
+	CacheExpiry time.Duration
+
+	// PrivateKey is an optional path to a TLS private
+	// key used to authenticate to the authorization
+	// service.
+	PrivateKey string
+
+	// Certificate is an optional path to a TLS certificate
+	// used to authenticate to the authorization service.
+	Certificate string
+
+	// CAPath is an optional path to the root CA certificate(s)
+	// for verifying the TLS certificate of the authorization
+	// service. If empty, the OS default root CA set is used.
+	CAPath string
+
+	_ [0]int
+}
+
+// KeyPolicyConfig is a structure that holds the algorithm
+// policy for new keys of a KES server.
+type KeyPolicyConfig struct {
+	// DefaultAlgorithm is the algorithm of new keys created
+	// without an explicit algorithm. If empty, the KES server
+	// picks the first supported algorithm the policy allows.
+	DefaultAlgorithm string
+
+	// Algorithms are the algorithms allowed for new keys.
+	// If empty, all supported algorithms are allowed.
+	Algorithms []string
+
+	// MinKeySize is the min. size of new keys in bits.
+	MinKeySize int
+
+	_ [0]int
+}
+
+// NameConfig is a structure that holds the key name
+// configuration of a KES server.
+type NameConfig struct {
+	// MaxLength is the max. length of key and policy
+	// names in bytes. If 0, it defaults to 80 bytes.
+	MaxLength int
+
+	// Extended enables the extended name character set.
+	// Names may also contain '.' characters, like
+	// "tenants/acme/key.v2".
+	Extended bool
+
+	// Reserved maps reserved key name prefixes, like "sys/",
+	// to the policies whose identities can create keys with
+	// such a prefix. Other identities, except the admin,
+	// cannot create keys within a reserved prefix.
+	Reserved map[string][]string
+
+	_ [0]int
+}
+
+// BreakerConfig is a structure that holds the keystore
+// circuit breaker configuration for a KES server.
+type BreakerConfig struct {
+	// Threshold is the number of consecutive failed keystore
+	// operations after which the KES server stops sending
+	// requests to the keystore and rejects requests with
+	// 503 Service Unavailable.
+	Threshold int
+
+	// Cooldown is the time period after which the KES server
+	// probes whether the keystore has recovered. If 0, a
+	// default cooldown period is used.
+	Cooldown time.Duration
+
 	_ [0]int
 }
 
@@ -146,6 +333,138 @@ type LogConfig struct {
 	// It does not en/disable audit logging in general.
 	Audit bool
 
+	// AuditFile is the audit log file configuration. If nil,
+	// the KES server does not write audit events to a file.
+	AuditFile *AuditFileConfig
+
+	_ [0]int
+}
+
+// AuditFileConfig is a structure that holds the configuration
+// of a local audit log file.
+type AuditFileConfig struct {
+	// Path is the path of the audit log file.
+	Path string
+
+	// MaxSize is the max. size of the audit log file in
+	// bytes. Once exceeded, the file gets rotated. If <= 0,
+	// the file is not rotated based on its size.
+	MaxSize int64
+
+	// Rotate is the max. amount of time the KES server writes
+	// audit events to the same file before rotating it. If <= 0,
+	// the file is not rotated based on time.
+	Rotate time.Duration
+
+	// MaxFiles is the max. number of rotated files to retain.
+	// If <= 0, rotated files are not removed based on their
+	// number.
+	MaxFiles int
+
+	// MaxAge is the max. amount of time rotated files are
+	// retained. If <= 0, rotated files are not removed based
+	// on their age.
+	MaxAge time.Duration
+
+	// Compress determines whether rotated files are
+	// gzip compressed.
+	Compress bool
+
+	// Sync is the audit log sync configuration. If nil,
+	// audit log files are not synced to a central audit
+	// log service.
+	Sync *AuditSyncConfig
+
+	_ [0]int
+}
+
+// AuditSyncConfig is a structure that holds the configuration
+// for syncing audit log files to a central audit log service.
+type AuditSyncConfig struct {
+	// Endpoint is the URL of the audit log service.
+	Endpoint string
+
+	// Interval is the time period between two sync
+	// attempts. If <= 0, defaults to 5 minutes.
+	Interval time.Duration
+
+	// PrivateKey is an optional path to a TLS private
+	// key used to authenticate to the audit log service.
+	PrivateKey string
+
+	// Certificate is an optional path to a TLS certificate
+	// used to authenticate to the audit log service.
+	Certificate string
+
+	// CAPath is an optional path to the root CA
+	// certificate(s) for verifying the TLS certificate
+	// of the audit log service.
+	CAPath string
+
+	_ [0]int
+}
+
+// HeartbeatConfig is a structure that holds the configuration
+// of periodic health reports sent to an external endpoint.
+type HeartbeatConfig struct {
+	// Endpoint is the URL that receives the heartbeats.
+	Endpoint string
+
+	// Node is the server name included in every heartbeat.
+	// If empty, defaults to the hostname.
+	Node string
+
+	// Interval is the time period between two heartbeats.
+	// If <= 0, defaults to 1 minute.
+	Interval time.Duration
+
+	// PrivateKey is an optional path to a TLS private
+	// key used to authenticate to the endpoint.
+	PrivateKey string
+
+	// Certificate is an optional path to a TLS certificate
+	// used to authenticate to the endpoint.
+	Certificate string
+
+	// CAPath is an optional path to the root CA
+	// certificate(s) for verifying the TLS certificate
+	// of the endpoint.
+	CAPath string
+
+	_ [0]int
+}
+
+// MetricsConfig is a structure that holds the metrics
+// export configuration for a KES server.
+type MetricsConfig struct {
+	// StatsD is the StatsD exporter configuration. If nil,
+	// the KES server does not push metrics to StatsD.
+	StatsD *StatsDConfig
+
+	_ [0]int
+}
+
+// StatsDConfig is a structure that holds the configuration
+// of a StatsD or DogStatsD metrics exporter.
+type StatsDConfig struct {
+	// Endpoint is the UDP address of the StatsD agent.
+	Endpoint string
+
+	// Prefix is prepended to all metric names.
+	Prefix string
+
+	// Interval is the time period between two metric
+	// pushes. If <= 0, defaults to 10 seconds.
+	Interval time.Duration
+
+	// DogStatsD determines whether metric labels are
+	// sent as DogStatsD tags.
+	DogStatsD bool
+
+	// Tags are "key:value" DogStatsD tags attached to
+	// all metrics.
+	Tags []string
+
 	_ [0]int
 }
 
@@ -167,6 +486,17 @@ type APIPathConfig struct {
 	// zero the API default is used.
 	Timeout time.Duration
 
+	// MaxBody is the max. size of request bodies in bytes.
+	// If MaxBody is zero the API default is used.
+	MaxBody int64
+
+	// MaxInFlight is the max. number of requests the API
+	// handles concurrently. Further requests are rejected
+	// with 503 Service Unavailable. If MaxInFlight is zero
+	// the number of concurrent requests is not limited
+	// per API.
+	MaxInFlight int
+
 	// InsecureSkipAuth controls whether the API verifies
 	// client identities. If InsecureSkipAuth is true,
 	// the API accepts requests from arbitrary identities.
@@ -177,6 +507,21 @@ type APIPathConfig struct {
 	// like metrics.
 	InsecureSkipAuth bool
 
+	// Deprecated is the point in time from which on the
+	// API is deprecated. Responses of deprecated APIs
+	// contain a Deprecation header and calls are counted
+	// separately. If zero, the API default is used.
+	Deprecated time.Time
+
+	// Sunset is the point in time after which the API may
+	// be removed or disabled. If zero, the API default is
+	// used.
+	Sunset time.Time
+
+	// Successor is the path of the API that replaces a
+	// deprecated API, if any.
+	Successor string
+
 	_ [0]int
 }
 
@@ -205,6 +550,28 @@ type Policy struct {
 	_ [0]int
 }
 
+// Namespace is a structure that holds the configuration
+// of a key namespace of a KES edge server.
+type Namespace struct {
+	// Policies contains the policy definitions and
+	// statical identity assignments of the namespace.
+	Policies map[string]Policy
+
+	// Keys contains pre-defined keys that the KES server
+	// creates within the namespace, if they don't exist.
+	Keys []Key
+
+	// KeyStore is the keystore of the namespace.
+	KeyStore KeyStore
+
+	// KeyPolicy restricts the algorithms of new keys within
+	// the namespace. If not nil, it replaces the top-level
+	// key policy.
+	KeyPolicy *KeyPolicyConfig
+
+	_ [0]int
+}
+
 // Key is a structure defining a cryptographic key
 // that the KES server will create or ensure exists
 // before startup.
@@ -639,6 +1006,356 @@ func (s *AzureKeyVaultKeyStore) Connect(ctx context.Context) (kv.Store[string, [
 	}
 }
 
+// PluginKeyStore is a structure containing the
+// configuration for an external keystore plugin.
+type PluginKeyStore struct {
+	// Path is the path of the plugin executable.
+	// If not empty, the KES server starts the
+	// plugin process.
+	Path string
+
+	// Args are the command line arguments passed
+	// to the plugin executable.
+	Args []string
+
+	// Socket is the path of the Unix socket of
+	// an already running plugin.
+	Socket string
+
+	_ [0]int
+}
+
+// Connect returns a kv.Store that stores key-value pairs at a keystore plugin.
+func (s *PluginKeyStore) Connect(ctx context.Context) (kv.Store[string, []byte], error) {
+	client, err := plugin.Connect(ctx, &plugin.Config{
+		Path:   s.Path,
+		Args:   s.Args,
+		Socket: s.Socket,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// BundleKeyStore is a structure containing the
+// configuration for an offline key bundle.
+//
+// A KES server using a BundleKeyStore serves the
+// keys and policies of a signed and encrypted
+// bundle without any network connectivity. The
+// keys cannot be modified.
+type BundleKeyStore struct {
+	// Path is the path of the bundle file.
+	Path string
+
+	// Key is the hex-encoded 256 bit key
+	// used to decrypt the bundle.
+	Key string
+
+	// PublicKey is the path of the PEM-encoded
+	// Ed25519 public key used to verify the
+	// bundle signature.
+	PublicKey string
+
+	_ [0]int
+}
+
+// Open reads, verifies and decrypts the bundle.
+func (s *BundleKeyStore) Open() (*bundle.Bundle, error) {
+	key, err := bundle.ParseKey(s.Key)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := bundle.ReadPublicKey(s.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Open(data, key, publicKey)
+}
+
+// Connect returns a read-only kv.Store that serves the keys of the bundle.
+func (s *BundleKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	b, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+	return bundle.NewStore(b), nil
+}
+
+// ProxyKeyStore is a structure containing the
+// configuration for proxying all keys to an external
+// KMS.
+//
+// A KES server using a ProxyKeyStore holds no key
+// material. Each key refers to the key at the external
+// KMS with the same name, prefixed with KeyPrefix, and
+// all cryptographic operations are delegated to the
+// external KMS. The keys cannot be modified.
+type ProxyKeyStore struct {
+	// KMS is the name of the external KMS.
+	KMS string
+
+	// KeyPrefix is prepended to key names to
+	// obtain the key IDs at the external KMS,
+	// e.g. "alias/" for AWS KMS.
+	KeyPrefix string
+
+	// ExternalKMS is the configuration of the
+	// external KMS.
+	ExternalKMS ExternalKMS
+
+	_ [0]int
+}
+
+// Connect returns a read-only kv.Store that serves
+// references to the keys of the external KMS.
+func (s *ProxyKeyStore) Connect(context.Context) (kv.Store[string, []byte], error) {
+	return proxy.NewStore(s.KMS, s.KeyPrefix, externalKMSConn{s.ExternalKMS}), nil
+}
+
+// externalKMSConn adapts an ExternalKMS to a key.ExternalKMS.
+type externalKMSConn struct {
+	kms ExternalKMS
+}
+
+func (c externalKMSConn) Key(ctx context.Context, keyID string) (key.Wrapper, error) {
+	return c.kms.ConnectKey(ctx, keyID)
+}
+
+// KeyWrapping is a KES key wrapping configuration.
+//
+// Concrete instances implement Connect to return a
+// key.Wrapper that en/decrypts keys with a key at
+// an external KMS. Hence, neither the keystore nor
+// the external KMS alone suffices to recover keys.
+type KeyWrapping interface {
+	// Connect establishes and returns a new connection
+	// to the external KMS.
+	Connect(ctx context.Context) (key.Wrapper, error)
+}
+
+// ExternalKMS is an external KMS configuration. Its keys
+// can be registered as external keys.
+//
+// Concrete instances implement ConnectKey to return a
+// connection to a concrete key at the external KMS.
+type ExternalKMS interface {
+	// ConnectKey establishes and returns a new connection
+	// to the key with the given ID at the external KMS.
+	ConnectKey(ctx context.Context, keyID string) (key.Wrapper, error)
+}
+
+// AWSKMSKeyWrapping is a structure containing the
+// configuration for wrapping keys with an AWS-KMS key.
+type AWSKMSKeyWrapping struct {
+	// Endpoint is the AWS-KMS endpoint. If empty,
+	// the endpoint is derived from the region.
+	Endpoint string
+
+	// Region is the AWS region of the AWS-KMS key.
+	Region string
+
+	// Key is the ID, ARN or alias of the AWS-KMS key.
+	Key string
+
+	// AccessKey is the access key for authenticating to AWS.
+	AccessKey string
+
+	// SecretKey is the secret key for authenticating to AWS.
+	SecretKey string
+
+	// SessionToken is an optional session token for authenticating
+	// to AWS.
+	SessionToken string
+
+	_ [0]int
+}
+
+// Connect returns a key.Wrapper that wraps keys with an AWS-KMS key.
+func (s *AWSKMSKeyWrapping) Connect(ctx context.Context) (key.Wrapper, error) {
+	return aws.ConnectKMS(ctx, &aws.KMSConfig{
+		Addr:   s.Endpoint,
+		Region: s.Region,
+		KeyID:  s.Key,
+		Login: aws.Credentials{
+			AccessKey:    s.AccessKey,
+			SecretKey:    s.SecretKey,
+			SessionToken: s.SessionToken,
+		},
+	})
+}
+
+// ConnectKey returns a new connection to the key with
+// the given ID at the KMS. It ignores s.Key.
+func (s *AWSKMSKeyWrapping) ConnectKey(ctx context.Context, keyID string) (key.Wrapper, error) {
+	c := *s
+	c.Key = keyID
+	return c.Connect(ctx)
+}
+
+// GCPKMSKeyWrapping is a structure containing the
+// configuration for wrapping keys with a GCP Cloud
+// KMS key.
+type GCPKMSKeyWrapping struct {
+	// Endpoint is the GCP Cloud KMS endpoint. If
+	// empty, the default endpoint is used.
+	Endpoint string
+
+	// Key is the resource name of the Cloud KMS key:
+	//  projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>
+	Key string
+
+	// Scopes are GCP OAuth2 scopes for accessing GCP APIs.
+	// If empty, the cloudkms scope is used.
+	Scopes []string
+
+	// ClientEmail is the Client email of the GCP service account
+	// used to access the Cloud KMS.
+	ClientEmail string
+
+	// ClientID is the client ID of the GCP service account used
+	// to access the Cloud KMS.
+	ClientID string
+
+	// KeyID is the private key ID of the GCP service account
+	// used to access the Cloud KMS.
+	KeyID string
+
+	// PrivateKey is the private key of the GCP service account
+	// used to access the Cloud KMS.
+	PrivateKey string
+
+	_ [0]int
+}
+
+// Connect returns a key.Wrapper that wraps keys with a GCP Cloud KMS key.
+func (s *GCPKMSKeyWrapping) Connect(ctx context.Context) (key.Wrapper, error) {
+	return gcp.ConnectKMS(ctx, &gcp.KMSConfig{
+		Endpoint: s.Endpoint,
+		Key:      s.Key,
+		Scopes:   s.Scopes,
+		Credentials: gcp.Credentials{
+			ClientID: s.ClientID,
+			Client:   s.ClientEmail,
+			KeyID:    s.KeyID,
+			Key:      s.PrivateKey,
+		},
+	})
+}
+
+// ConnectKey returns a new connection to the key with
+// the given ID at the KMS. It ignores s.Key.
+func (s *GCPKMSKeyWrapping) ConnectKey(ctx context.Context, keyID string) (key.Wrapper, error) {
+	c := *s
+	c.Key = keyID
+	return c.Connect(ctx)
+}
+
+// AzureKeyVaultKeyWrapping is a structure containing the
+// configuration for wrapping keys with an Azure KeyVault
+// RSA key.
+type AzureKeyVaultKeyWrapping struct {
+	// Endpoint is the Azure KeyVault endpoint.
+	Endpoint string
+
+	// Key is the name of the KeyVault RSA key.
+	Key string
+
+	// TenantID is the ID of the Azure KeyVault tenant.
+	TenantID string
+
+	// ClientID is the ID of the client accessing
+	// Azure KeyVault.
+	ClientID string
+
+	// ClientSecret is the client secret accessing the
+	// Azure KeyVault.
+	ClientSecret string
+
+	// ManagedIdentityClientID is the client ID of the
+	// Azure managed identity that access the KeyVault.
+	ManagedIdentityClientID string
+
+	_ [0]int
+}
+
+// Connect returns a key.Wrapper that wraps keys with an Azure KeyVault key.
+func (s *AzureKeyVaultKeyWrapping) Connect(ctx context.Context) (key.Wrapper, error) {
+	if s.ManagedIdentityClientID != "" {
+		return azure.ConnectKMSWithIdentity(ctx, s.Endpoint, s.Key, azure.ManagedIdentity{
+			ClientID: s.ManagedIdentityClientID,
+		})
+	}
+	return azure.ConnectKMSWithCredentials(ctx, s.Endpoint, s.Key, azure.Credentials{
+		TenantID: s.TenantID,
+		ClientID: s.ClientID,
+		Secret:   s.ClientSecret,
+	})
+}
+
+// ConnectKey returns a new connection to the key with
+// the given ID at the KMS. It ignores s.Key.
+func (s *AzureKeyVaultKeyWrapping) ConnectKey(ctx context.Context, keyID string) (key.Wrapper, error) {
+	c := *s
+	c.Key = keyID
+	return c.Connect(ctx)
+}
+
+// KESKeyWrapping is a structure containing the configuration
+// for wrapping keys with a key at a KES server, e.g. a KES
+// server that uses a hardware security module as keystore.
+type KESKeyWrapping struct {
+	// Endpoints is a set of KES server endpoints.
+	Endpoints []string
+
+	// Enclave is an optional enclave name. If empty,
+	// the default enclave name will be used.
+	Enclave string
+
+	// Key is the name of the key at the KES server.
+	Key string
+
+	// CertificateFile is a path to a mTLS client
+	// certificate file used to authenticate to
+	// the KES server.
+	CertificateFile string
+
+	// PrivateKeyFile is a path to a mTLS private
+	// key used to authenticate to the KES server.
+	PrivateKeyFile string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the KES server.
+	CAPath string
+
+	_ [0]int
+}
+
+// Connect returns a key.Wrapper that wraps keys with a key at a KES server.
+func (s *KESKeyWrapping) Connect(ctx context.Context) (key.Wrapper, error) {
+	return kesstore.ConnectKMS(ctx, &kesstore.Config{
+		Endpoints:   s.Endpoints,
+		Enclave:     s.Enclave,
+		Certificate: s.CertificateFile,
+		PrivateKey:  s.PrivateKeyFile,
+		CAPath:      s.CAPath,
+	}, s.Key)
+}
+
+// ConnectKey returns a new connection to the key with
+// the given ID at the KMS. It ignores s.Key.
+func (s *KESKeyWrapping) ConnectKey(ctx context.Context, keyID string) (key.Wrapper, error) {
+	c := *s
+	c.Key = keyID
+	return c.Connect(ctx)
+}
+
 func wrap(conn kms.Conn, err error) (kv.Store[string, []byte], error) {
 	if err != nil {
 		return nil, err
@@ -691,6 +1408,14 @@ func (s *store) List(ctx context.Context) (kv.Iter[string], error) {
 	return &iter{iter: i}, nil
 }
 
+func (s *store) ListPrefix(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	i, err := kms.ListPrefix(ctx, s.conn, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &iter{iter: i}, nil
+}
+
 type iter struct {
 	iter kms.Iter
 }
@@ -704,4 +1429,4 @@ func (i *iter) Next() (string, bool) {
 	return "", false
 }
 
-func (i *iter) Close() error { return i.Close() }
+func (i *iter) Close() error { return i.iter.Close() }
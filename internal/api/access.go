@@ -0,0 +1,246 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// requestAccess files a request to assign the request
+// identity to a policy for a limited duration.
+//
+// Any identity may request access, even if it is not
+// assigned to any policy or its assignment has expired.
+// The request has no effect until it gets approved.
+func requestAccess(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/access/request/"
+		MaxBody     = int64(1 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = false
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Duration time.Duration `json:"duration"`
+		Reason   string        `json:"reason,omitempty"`
+	}
+	type Response struct {
+		ID string `json:"id"`
+	}
+	// Identities without policy assignment can request access.
+	// Hence, replaying an outcome only requires the enclave to
+	// exist.
+	verify := func(r *http.Request) error {
+		_, err := enclaveFromRequest(config.Vault, r)
+		return err
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		if name == auth.EnclaveAdminPolicy {
+			return kes.NewError(http.StatusBadRequest, "cannot request access to policy '"+auth.EnclaveAdminPolicy+"'")
+		}
+		identity := auth.Identify(r)
+		admin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if admin == identity {
+			return kes.NewError(http.StatusBadRequest, "system admin cannot request access")
+		}
+		access, err := enclave.RequestAccess(r.Context(), identity, name, req.Duration, req.Reason)
+		if err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventAccessRequested, access.ID)
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{ID: access.ID})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(verify, handler)))),
+	}
+}
+
+func approveAccess(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/access/approve/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		access, err := enclave.GetAccessRequest(name)
+		if err != nil {
+			return err
+		}
+		if _, err = enclave.GetPolicy(r.Context(), access.Policy); err != nil {
+			return err
+		}
+		enclaveAdmin, err := isEnclaveAdmin(r.Context(), enclave, access.Identity)
+		if err != nil {
+			return err
+		}
+		if enclaveAdmin {
+			if err = verifyEnclaveOwner(config.Vault, enclave, r); err != nil {
+				return err
+			}
+		}
+		if _, err = enclave.ApproveAccessRequest(r.Context(), name, auth.Identify(r)); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventAccessApproved, name)
+		config.Events.Publish(r, EventIdentityAssigned, access.Identity.String())
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func denyAccess(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/access/deny/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if _, err = enclave.DenyAccessRequest(name); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventAccessDenied, name)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func listAccess(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/access/list"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		ID          string        `json:"id"`
+		Identity    kes.Identity  `json:"identity"`
+		Policy      string        `json:"policy"`
+		Duration    time.Duration `json:"duration"`
+		Reason      string        `json:"reason,omitempty"`
+		RequestedAt time.Time     `json:"requested_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		requests := enclave.ListAccessRequests()
+		responses := make([]Response, 0, len(requests))
+		for _, req := range requests {
+			responses = append(responses, Response{
+				ID:          req.ID,
+				Identity:    req.Identity,
+				Policy:      req.Policy,
+				Duration:    req.Duration,
+				Reason:      req.Reason,
+				RequestedAt: req.RequestedAt,
+			})
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+)
+
+var (
+	errTooManyRequests = kes.NewError(http.StatusServiceUnavailable, "service unavailable: too many concurrent requests")
+	errTooManyBytes    = kes.NewError(http.StatusTooManyRequests, "too many requests: request body memory limit exceeded")
+	errEnclaveBusy     = kes.NewError(http.StatusServiceUnavailable, "service unavailable: too many concurrent requests for enclave")
+)
+
+// AdmissionConfig is a structure containing
+// the request admission limits.
+type AdmissionConfig struct {
+	// MaxRequests is the max. number of requests
+	// handled concurrently. If <= 0, the number of
+	// concurrent requests is not limited.
+	MaxRequests int64
+
+	// MaxBodyBytes is the max. aggregate size of the
+	// request bodies of all requests handled concurrently.
+	// If <= 0, the aggregate size is not limited.
+	MaxBodyBytes int64
+
+	// MaxEnclaveRequests is the max. number of requests
+	// handled concurrently per enclave and identity. Up
+	// to the same number of further requests wait for a
+	// free slot. If <= 0, the number of concurrent requests
+	// per enclave is not limited.
+	MaxEnclaveRequests int64
+}
+
+// Admission controls whether a request is handled
+// or rejected based on the number and size of the
+// requests currently in flight.
+//
+// A request reserves the size of its body, as
+// specified by its content length, or the max.
+// body size of the API, if smaller or no content
+// length has been sent. A request is rejected with
+// 429 Too Many Requests if its reservation would
+// exceed the MaxBodyBytes limit. Hence, a burst of
+// large requests cannot exhaust the server memory.
+//
+// A request is rejected with 503 Service Unavailable
+// if the server is already handling MaxRequests
+// requests.
+//
+// Each enclave handles at most MaxEnclaveRequests
+// requests of one identity concurrently. Further
+// requests wait until a slot becomes free, the API
+// timeout elapses or the client cancels the request.
+// Requests are rejected with 503 Service Unavailable
+// if MaxEnclaveRequests requests are already waiting.
+// Hence, a burst of requests for one enclave cannot
+// starve all other enclaves. Waiting requests do not
+// count towards MaxRequests.
+//
+// Admission happens before the request is authenticated.
+// Therefore, the slots are keyed by the enclave and the
+// identity of the client certificate, which the client
+// has proven to own during the TLS handshake. Clients
+// without access to an enclave cannot occupy the slots
+// of the enclave's identities.
+//
+// APIs without a timeout, like log or event streams,
+// are not subject to admission control since their
+// requests are long-lived.
+type Admission struct {
+	maxRequests        int64
+	maxBodyBytes       int64
+	maxEnclaveRequests int64
+
+	requests  int64 // Number of requests in flight. Modified atomically.
+	bodyBytes int64 // Reserved body bytes of requests in flight. Modified atomically.
+
+	enclaveLock sync.Mutex
+	enclaves    map[enclaveKey]*enclaveSlots
+}
+
+// enclaveKey identifies the requests of one
+// identity to one enclave.
+type enclaveKey struct {
+	Enclave  string
+	Identity kes.Identity
+}
+
+// enclaveSlots limits the number of concurrent
+// requests of one identity to one enclave.
+type enclaveSlots struct {
+	slots chan struct{}
+	refs  int64 // Number of requests in flight or waiting. Guarded by Admission.enclaveLock
+}
+
+// NewAdmission returns a new Admission with the given limits.
+func NewAdmission(config *AdmissionConfig) *Admission {
+	return &Admission{
+		maxRequests:        config.MaxRequests,
+		maxBodyBytes:       config.MaxBodyBytes,
+		maxEnclaveRequests: config.MaxEnclaveRequests,
+		enclaves:           map[enclaveKey]*enclaveSlots{},
+	}
+}
+
+// InFlight returns the number of requests and the
+// reserved request body bytes currently in flight.
+func (a *Admission) InFlight() (requests, bodyBytes int64) {
+	return atomic.LoadInt64(&a.requests), atomic.LoadInt64(&a.bodyBytes)
+}
+
+// admit returns a handler that invokes h if the request
+// is admitted by the admission and otherwise rejects it.
+// If admission is nil, admit returns h.
+func admit(admission *Admission, api API, h http.Handler) http.Handler {
+	if admission == nil || api.Timeout <= 0 {
+		return h
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if admission.maxEnclaveRequests > 0 {
+			release, err := admission.acquireEnclave(r, api.Timeout)
+			if err != nil {
+				w.Header().Set("Retry-After", "1")
+				return err
+			}
+			defer release()
+		}
+		if n := atomic.AddInt64(&admission.requests, 1); admission.maxRequests > 0 && n > admission.maxRequests {
+			atomic.AddInt64(&admission.requests, -1)
+			w.Header().Set("Retry-After", "1")
+			return errTooManyRequests
+		}
+		defer atomic.AddInt64(&admission.requests, -1)
+
+		if size := bodyReservation(r, api.MaxBody); admission.maxBodyBytes > 0 && size > 0 {
+			if n := atomic.AddInt64(&admission.bodyBytes, size); n > admission.maxBodyBytes {
+				atomic.AddInt64(&admission.bodyBytes, -size)
+				w.Header().Set("Retry-After", "1")
+				return errTooManyBytes
+			}
+			defer atomic.AddInt64(&admission.bodyBytes, -size)
+		}
+		h.ServeHTTP(w, r)
+		return nil
+	}
+	return handler
+}
+
+// acquireEnclave waits up to timeout for a free slot of
+// the request's enclave and identity and returns a function
+// that releases the slot. It returns an error if too many
+// requests are waiting already, if no slot becomes free
+// in time or if the request is canceled while waiting.
+func (a *Admission) acquireEnclave(r *http.Request, timeout time.Duration) (func(), error) {
+	name := enclaveKey{
+		Enclave:  enclaveName(r),
+		Identity: auth.Identify(r),
+	}
+
+	a.enclaveLock.Lock()
+	enclave, ok := a.enclaves[name]
+	if !ok {
+		enclave = &enclaveSlots{slots: make(chan struct{}, a.maxEnclaveRequests)}
+		a.enclaves[name] = enclave
+	}
+	if enclave.refs >= 2*a.maxEnclaveRequests {
+		a.enclaveLock.Unlock()
+		return nil, errEnclaveBusy
+	}
+	enclave.refs++
+	a.enclaveLock.Unlock()
+
+	// Remove the slots once there are no more requests
+	// such that requests for arbitrary enclave names or
+	// identities do not accumulate memory.
+	unref := func() {
+		a.enclaveLock.Lock()
+		defer a.enclaveLock.Unlock()
+		if enclave.refs--; enclave.refs == 0 {
+			delete(a.enclaves, name)
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case enclave.slots <- struct{}{}:
+		return func() {
+			<-enclave.slots
+			unref()
+		}, nil
+	case <-timer.C:
+		unref()
+		return nil, errEnclaveBusy
+	case <-r.Context().Done():
+		unref()
+		return nil, errEnclaveBusy
+	}
+}
+
+// bodyReservation returns the number of bytes the
+// request body of r may occupy at most.
+func bodyReservation(r *http.Request, maxBody int64) int64 {
+	if r.ContentLength >= 0 && r.ContentLength < maxBody {
+		return r.ContentLength
+	}
+	return maxBody
+}
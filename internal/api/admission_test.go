@@ -0,0 +1,255 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	var (
+		admission = NewAdmission(&AdmissionConfig{MaxRequests: 1, MaxBodyBytes: 16})
+		started   = make(chan struct{})
+		unblock   = make(chan struct{})
+	)
+	api := API{
+		Method:  http.MethodPost,
+		Path:    "/v1/test",
+		MaxBody: 1 << 20,
+		Timeout: 10 * time.Second,
+	}
+	api.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("block") {
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(admit(admission, api, api))
+	defer server.Close()
+
+	send := func(query, body string) int {
+		resp, err := http.Post(server.URL+"/v1/test"+query, "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	waitReleased := func() {
+		for i := 0; ; i++ { // Handlers release their admission after sending the response
+			requests, bodyBytes := admission.InFlight()
+			if requests == 0 && bodyBytes == 0 {
+				return
+			}
+			if i == 100 {
+				t.Fatalf("Admission has not been released: %d requests and %d body bytes in flight", requests, bodyBytes)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if status := send("", strings.Repeat("a", 32)); status != http.StatusTooManyRequests {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusTooManyRequests)
+	}
+	if status := send("", "Hello World"); status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+
+	waitReleased()
+
+	done := make(chan int, 1)
+	go func() { done <- send("?block", "") }()
+	<-started
+	if status := send("", ""); status != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusServiceUnavailable)
+	}
+	close(unblock)
+	if status := <-done; status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+	waitReleased()
+}
+
+func TestAdmissionEnclave(t *testing.T) {
+	var (
+		admission = NewAdmission(&AdmissionConfig{MaxEnclaveRequests: 1})
+		started   = make(chan struct{})
+		unblock   = make(chan struct{})
+	)
+	api := API{
+		Method:  http.MethodGet,
+		Path:    "/v1/test",
+		Timeout: 10 * time.Second,
+	}
+	api.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("block") {
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(admit(admission, api, api))
+	defer server.Close()
+
+	send := func(query string) int {
+		resp, err := http.Get(server.URL + "/v1/test" + query)
+		if err != nil {
+			t.Errorf("Failed to send request: %v", err)
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	waiting := func(enclave string, refs int64) {
+		for i := 0; ; i++ {
+			admission.enclaveLock.Lock()
+			var n int64
+			if slots, ok := admission.enclaves[enclaveKey{Enclave: enclave}]; ok {
+				n = slots.refs
+			}
+			admission.enclaveLock.Unlock()
+			if n == refs {
+				return
+			}
+			if i == 100 {
+				t.Fatalf("Invalid number of requests for enclave '%s': got '%d' - want '%d'", enclave, n, refs)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	blocked := make(chan int, 1)
+	go func() { blocked <- send("?enclave=tenant-a&block") }()
+	<-started
+
+	queued := make(chan int, 1)
+	go func() { queued <- send("?enclave=tenant-a") }()
+	waiting("tenant-a", 2)
+
+	if status := send("?enclave=tenant-a"); status != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusServiceUnavailable)
+	}
+	if status := send("?enclave=tenant-b"); status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+
+	close(unblock)
+	if status := <-blocked; status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+	if status := <-queued; status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+	waiting("tenant-a", 0)
+	if n := len(admission.enclaves); n != 0 {
+		t.Fatalf("Idle enclaves have not been removed: got '%d' - want '0'", n)
+	}
+}
+
+func TestAdmissionEnclaveIdentity(t *testing.T) {
+	var (
+		admission = NewAdmission(&AdmissionConfig{MaxEnclaveRequests: 1})
+		started   = make(chan struct{})
+		unblock   = make(chan struct{})
+	)
+	api := API{
+		Method:  http.MethodGet,
+		Path:    "/v1/test",
+		Timeout: 10 * time.Second,
+	}
+	api.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("block") {
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewUnstartedServer(admit(admission, api, api))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	_, alice, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, bob, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	clients := map[string]*http.Client{}
+	for name, priv := range map[string]ed25519.PrivateKey{"alice": alice, "bob": bob} {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = []tls.Certificate{{
+			Certificate: [][]byte{selfSigned(t, priv).Raw},
+			PrivateKey:  priv,
+		}}
+		clients[name] = &http.Client{Transport: transport}
+	}
+	send := func(client, query string) int {
+		resp, err := clients[client].Get(server.URL + "/v1/test" + query)
+		if err != nil {
+			t.Errorf("Failed to send request: %v", err)
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	waiting := func(priv ed25519.PrivateKey, refs int64) {
+		key := enclaveKey{Enclave: "tenant-a", Identity: identityOf(priv)}
+		for i := 0; ; i++ {
+			admission.enclaveLock.Lock()
+			var n int64
+			if slots, ok := admission.enclaves[key]; ok {
+				n = slots.refs
+			}
+			admission.enclaveLock.Unlock()
+			if n == refs {
+				return
+			}
+			if i == 100 {
+				t.Fatalf("Invalid number of requests for identity '%s': got '%d' - want '%d'", key.Identity, n, refs)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// A client that occupies all slots of one identity
+	// does not block other identities of the same enclave.
+	blocked := make(chan int, 1)
+	go func() { blocked <- send("alice", "?enclave=tenant-a&block") }()
+	<-started
+
+	queued := make(chan int, 1)
+	go func() { queued <- send("alice", "?enclave=tenant-a") }()
+	waiting(alice, 2)
+
+	if status := send("alice", "?enclave=tenant-a"); status != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusServiceUnavailable)
+	}
+	if status := send("bob", "?enclave=tenant-a"); status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+
+	close(unblock)
+	if status := <-blocked; status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+	if status := <-queued; status != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", status, http.StatusOK)
+	}
+	waiting(alice, 0)
+	if n := len(admission.enclaves); n != 0 {
+		t.Fatalf("Idle enclaves have not been removed: got '%d' - want '0'", n)
+	}
+}
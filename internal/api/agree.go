@@ -0,0 +1,127 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/key"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Limits of the shared secret derived by agreeKey.
+const (
+	defaultSharedSecretLength = 32
+	maxSharedSecretLength     = 64
+)
+
+// agreeKey performs an (EC)DH key agreement between a static key
+// pair, derived from an enclave key, and the caller's public key.
+// The raw shared secret never leaves the server. Instead, it
+// returns a secret derived from it via HKDF-SHA256.
+//
+// Without a caller public key, it only returns the server's
+// public key, such that peers can perform the agreement, too.
+func agreeKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/agree/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Curve     string `json:"curve"`      // optional
+		PublicKey []byte `json:"public_key"` // optional
+		Salt      []byte `json:"salt"`       // optional
+		Info      []byte `json:"info"`       // optional
+		Length    int    `json:"length"`     // optional
+	}
+	type Response struct {
+		Curve        string `json:"curve"`
+		PublicKey    []byte `json:"public_key"`
+		SharedSecret []byte `json:"shared_secret,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		k, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, k); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if req.Curve == "" {
+			req.Curve = key.DefaultCurve()
+		}
+		if req.Length == 0 {
+			req.Length = defaultSharedSecretLength
+		}
+		if req.Length < 0 || req.Length > maxSharedSecretLength {
+			return kes.NewError(http.StatusBadRequest, "invalid shared secret length")
+		}
+
+		publicKey, err := k.AgreementPublicKey(req.Curve)
+		if err != nil {
+			return err
+		}
+		var sharedSecret []byte
+		if len(req.PublicKey) > 0 {
+			secret, err := k.Agree(req.Curve, req.PublicKey)
+			if err != nil {
+				return err
+			}
+			sharedSecret = make([]byte, req.Length)
+			if _, err = io.ReadFull(hkdf.New(sha256.New, secret, req.Salt, req.Info), sharedSecret); err != nil {
+				return err
+			}
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Curve:        req.Curve,
+			PublicKey:    publicKey,
+			SharedSecret: sharedSecret,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
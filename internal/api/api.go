@@ -5,14 +5,17 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/sys"
 )
 
@@ -24,6 +27,18 @@ type Config struct {
 	// is used.
 	Timeout time.Duration
 
+	// MaxBody is the max. size of request bodies in
+	// bytes. If MaxBody <= 0 the API default is used.
+	MaxBody int64
+
+	// MaxInFlight is the max. number of requests the
+	// API handles concurrently. Further requests are
+	// rejected with 503 Service Unavailable. If
+	// MaxInFlight <= 0 the number of concurrent
+	// requests is only limited by the admission
+	// control, if any.
+	MaxInFlight int
+
 	// InsecureSkipAuth controls whether the API verifies
 	// client identities. If InsecureSkipAuth is true,
 	// the API accepts requests from arbitrary identities.
@@ -33,6 +48,20 @@ type Config struct {
 	// cases for APIs that don't expose sensitive information,
 	// like metrics.
 	InsecureSkipAuth bool
+
+	// Deprecated is the point in time from which on the
+	// API is deprecated. If set, API responses contain a
+	// Deprecation header.
+	Deprecated time.Time
+
+	// Sunset is the point in time after which the API
+	// may be removed. If set, API responses contain a
+	// Sunset header.
+	Sunset time.Time
+
+	// Successor is the path of the API that replaces a
+	// deprecated API, if any.
+	Successor string
 }
 
 // API describes a KES server API.
@@ -43,6 +72,14 @@ type API struct {
 	Timeout time.Duration // The duration after which an API request times out. 0 means no timeout
 	Verify  bool          // Whether the API verifies the client identity
 
+	ContentType string // The content type of successful responses. Empty means no response body
+	Request     any    // A value of the JSON request body type, if any. Describes the API, e.g. in its OpenAPI document
+	Response    any    // A value of the JSON response body type, if any. For streams, the type of a single message
+
+	Deprecated time.Time // The time since the API is deprecated. Zero means not deprecated
+	Sunset     time.Time // The time after which the API may be removed. Zero means no removal is planned
+	Successor  string    // The path of the API that replaces a deprecated API, if any
+
 	// Handler implements the API.
 	//
 	// When invoked by the API's ServeHTTP method, the handler
@@ -51,6 +88,10 @@ type API struct {
 	//  - the API path being a prefix of the request URL.
 	//  - the request body being limited to the API's MaxBody size.
 	//  - the request timing out after the duration specified for the API.
+	//  - the request context having a deadline shortly before the request
+	//    times out, if the API specifies a timeout. Handlers should pass
+	//    the request context to backends such that they give up before
+	//    the request times out.
 	Handler http.Handler
 
 	_ [0]int
@@ -71,7 +112,8 @@ func (a API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, a.MaxBody)
 
 	if a.Timeout > 0 {
-		switch err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(a.Timeout)); {
+		deadline := time.Now().Add(a.Timeout)
+		switch err := http.NewResponseController(w).SetWriteDeadline(deadline); {
 		case errors.Is(err, http.ErrNotSupported):
 			Fail(w, errors.New("internal error: HTTP connection does not accept a timeout"))
 			return
@@ -79,127 +121,270 @@ func (a API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			Fail(w, fmt.Errorf("internal error: %v", err))
 			return
 		}
+
+		// The request context expires before the write deadline
+		// such that there is time left to send an error response
+		// when a backend does not respond in time.
+		ctx, cancel := context.WithDeadline(r.Context(), deadline.Add(-responseReserve(a.Timeout)))
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		id := activeHandlers.Add(deadline)
+		defer activeHandlers.Remove(id)
 	}
 	a.Handler.ServeHTTP(w, r)
 }
 
+// responseReserve returns the amount of time, out of the
+// given API timeout, reserved for sending the response.
+func responseReserve(timeout time.Duration) time.Duration {
+	const MaxReserve = 1 * time.Second
+	if reserve := timeout / 10; reserve < MaxReserve {
+		return reserve
+	}
+	return MaxReserve
+}
+
 // nameFromRequest strips the API path from the request URL, verifies
-// that the remaining path is a valid name, via verifyName, and returns
-// the remaining path.
+// that the remaining path is a valid name, with respect to the request's
+// NameRules, and returns the remaining path.
 func nameFromRequest(r *http.Request, apiPath string) (string, error) {
 	name := strings.TrimPrefix(r.URL.Path, apiPath)
 	if len(name) == len(r.URL.Path) {
 		return "", fmt.Errorf("api: patch mismatch: received '%s' - expected '%s'", r.URL.Path, apiPath)
 	}
-	if err := verifyName(name); err != nil {
+	if err := nameRulesFromContext(r.Context()).verifyName(name); err != nil {
 		return "", err
 	}
 	return name, nil
 }
 
-// patternFromRequest strips the API path from the request URL, verifies
-// that the remaining path is a valid pattern, via verifyPattern, and returns
-// the remaining path.
-func patternFromRequest(r *http.Request, apiPath string) (string, error) {
-	pattern := strings.TrimPrefix(r.URL.Path, apiPath)
-	if len(pattern) == len(r.URL.Path) {
-		return "", fmt.Errorf("api: patch mismatch: received '%s' - expected '%s'", r.URL.Path, apiPath)
+// retentionFromRequest parses the optional 'retention' query
+// parameter of the request. A key or policy created with a
+// retention period is immutable until the period ends. It
+// returns the zero time if the request has no retention period.
+func retentionFromRequest(r *http.Request) (time.Time, error) {
+	s := r.URL.Query().Get("retention")
+	if s == "" {
+		return time.Time{}, nil
 	}
-	if err := verifyPattern(pattern); err != nil {
-		return "", err
+	retention, err := time.ParseDuration(s)
+	if err != nil || retention <= 0 {
+		return time.Time{}, kes.NewError(http.StatusBadRequest, "invalid retention parameter '"+s+"'")
 	}
-	return pattern, nil
+	return time.Now().UTC().Add(retention), nil
 }
 
-// verifyName reports whether the name is valid.
-//
-// A valid name must only contain numbers (0-9),
-// letters (a-z and A-Z) and '-' as well as '_'
-// characters.
-func verifyName(name string) error {
-	const MaxLength = 80 // Some arbitrary but reasonable limit
-
-	if name == "" {
-		return kes.NewError(http.StatusBadRequest, "invalid argument: name is empty")
-	}
-	if len(name) > MaxLength {
-		return kes.NewError(http.StatusBadRequest, "invalid argument: name is too long")
-	}
-	for _, r := range name { // Valid characters are: [ 0-9 , A-Z , a-z , - , _ ]
-		switch {
-		case r >= '0' && r <= '9':
-		case r >= 'A' && r <= 'Z':
-		case r >= 'a' && r <= 'z':
-		case r == '-':
-		case r == '_':
-		default:
-			return kes.NewError(http.StatusBadRequest, "invalid argument: name contains invalid character")
+// algorithmFromRequest returns the key algorithm selected by the
+// request's 'algorithm' query parameter. Without one, it returns
+// kes.KeyAlgorithmUndefined.
+func algorithmFromRequest(r *http.Request) (kes.KeyAlgorithm, error) {
+	s := r.URL.Query().Get("algorithm")
+	if s == "" {
+		return kes.KeyAlgorithmUndefined, nil
+	}
+	algorithm, err := key.ParseAlgorithm(s)
+	if err != nil {
+		return kes.KeyAlgorithmUndefined, err
+	}
+	if algorithm == kes.KeyAlgorithmUndefined {
+		return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "invalid algorithm '"+s+"'")
+	}
+	return algorithm, nil
+}
+
+// verifyImportAlgorithm returns an error if the algorithm of
+// an imported key is not supported or not allowed by any of
+// the key policies. Keys without an algorithm are only allowed
+// if no policy restricts the algorithms.
+func verifyImportAlgorithm(algorithm kes.KeyAlgorithm, policies ...*key.AlgorithmPolicy) error {
+	if algorithm != kes.KeyAlgorithmUndefined && !key.Supported(algorithm) {
+		return kes.NewError(http.StatusBadRequest, "unsupported algorithm '"+key.AlgorithmName(algorithm)+"'")
+	}
+	for _, policy := range policies {
+		if err := policy.Verify(algorithm); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// verifyPattern reports whether the pattern is valid.
-//
-// A valid pattern must only contain numbers (0-9),
-// letters (a-z and A-Z) and '-', '_' as well as '*'
-// characters.
-func verifyPattern(pattern string) error {
-	const MaxLength = 80 // Some arbitrary but reasonable limit
-
-	if pattern == "" {
-		return kes.NewError(http.StatusBadRequest, "invalid argument: pattern is empty")
-	}
-	if len(pattern) > MaxLength {
-		return kes.NewError(http.StatusBadRequest, "invalid argument: pattern is too long")
-	}
-	for _, r := range pattern { // Valid characters are: [ 0-9 , A-Z , a-z , - , _ , * ]
-		switch {
-		case r >= '0' && r <= '9':
-		case r >= 'A' && r <= 'Z':
-		case r >= 'a' && r <= 'z':
-		case r == '-':
-		case r == '_':
-		case r == '*':
-		default:
-			return kes.NewError(http.StatusBadRequest, "invalid argument: pattern contains invalid character")
+// wrapKey encrypts the plaintext with the named key. If the
+// request sets the 'envelope' query parameter to true, it returns
+// a versioned ciphertext envelope instead of the default binary
+// ciphertext. Decryption accepts both.
+func wrapKey(r *http.Request, name string, k *key.Key, plaintext, associatedData []byte) ([]byte, error) {
+	if s := r.URL.Query().Get("envelope"); s != "" {
+		envelope, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid envelope parameter '"+s+"'")
+		}
+		if envelope {
+			return k.WrapEnvelope(name, plaintext, associatedData)
 		}
 	}
-	return nil
+	return k.Wrap(plaintext, associatedData)
+}
+
+// edgeWrapKey is like wrapKey but encrypts the plaintext
+// with a key held by an external KMS if the named key
+// references one. Ciphertexts of external keys are never
+// ciphertext envelopes.
+func edgeWrapKey(r *http.Request, config *EdgeRouterConfig, name string, k *key.Key, plaintext, associatedData []byte) ([]byte, error) {
+	if k.External() == nil {
+		return wrapKey(r, name, k, plaintext, associatedData)
+	}
+	if s := r.URL.Query().Get("envelope"); s != "" {
+		return nil, kes.NewError(http.StatusBadRequest, "key is held by an external KMS: ciphertext envelopes are not supported")
+	}
+	w, err := externalKey(r.Context(), config, k)
+	if err != nil {
+		return nil, err
+	}
+	return k.WrapExternal(r.Context(), w, plaintext, associatedData)
+}
+
+// edgeUnwrapKey decrypts the ciphertext with the key or,
+// if the key references one, with a key held by an
+// external KMS.
+func edgeUnwrapKey(ctx context.Context, config *EdgeRouterConfig, k *key.Key, ciphertext, associatedData []byte) ([]byte, error) {
+	if k.External() == nil {
+		return k.Unwrap(ciphertext, associatedData)
+	}
+	w, err := externalKey(ctx, config, k)
+	if err != nil {
+		return nil, err
+	}
+	return k.UnwrapExternal(ctx, w, ciphertext, associatedData)
+}
+
+// externalKey returns a Wrapper for the key at the
+// external KMS referenced by k.
+func externalKey(ctx context.Context, config *EdgeRouterConfig, k *key.Key) (key.Wrapper, error) {
+	ext := k.External()
+	kms, ok := config.ExternalKMS[ext.KMS]
+	if !ok {
+		return nil, kes.NewError(http.StatusServiceUnavailable, "external KMS '"+ext.KMS+"' is not configured")
+	}
+	return kms.Key(ctx, ext.KeyID)
+}
+
+// patternFromRequest strips the API path from the request URL, verifies
+// that the remaining path is a valid pattern, with respect to the request's
+// NameRules, and returns the remaining path.
+func patternFromRequest(r *http.Request, apiPath string) (string, error) {
+	pattern := strings.TrimPrefix(r.URL.Path, apiPath)
+	if len(pattern) == len(r.URL.Path) {
+		return "", fmt.Errorf("api: patch mismatch: received '%s' - expected '%s'", r.URL.Path, apiPath)
+	}
+	if err := nameRulesFromContext(r.Context()).verifyPattern(pattern); err != nil {
+		return "", err
+	}
+	return pattern, nil
 }
 
 // enclaveFromRequest parses the enclave name from the request URL
 // and returns the corresponding enclave present at the vault.
 func enclaveFromRequest(vault *sys.Vault, req *http.Request) (*sys.Enclave, error) {
-	name := req.URL.Query().Get("enclave")
-	if name == "" {
-		name = sys.DefaultEnclaveName
-	}
-	if err := verifyName(name); err != nil {
+	name := enclaveName(req)
+	if err := verifyEnclaveName(name); err != nil {
 		return nil, err
 	}
 	return vault.GetEnclave(req.Context(), name)
 }
 
-// Sync calls f while holding the given lock and
-// releases the lock once f has been finished.
-//
-// Sync returns the error returned by f, if  any.
-func Sync(locker sync.Locker, f func() error) error {
-	locker.Lock()
-	defer locker.Unlock()
+// verifyEnclaveRequest verifies the request against the policies
+// and identities of the enclave specified by the request URL.
+func verifyEnclaveRequest(vault *sys.Vault, req *http.Request) error {
+	enclave, err := enclaveFromRequest(vault, req)
+	if err != nil {
+		return err
+	}
+	return enclave.VerifyRequest(req)
+}
+
+// enclaveVerifier returns a function that verifies requests
+// like verifyEnclaveRequest.
+func enclaveVerifier(vault *sys.Vault) func(*http.Request) error {
+	return func(req *http.Request) error { return verifyEnclaveRequest(vault, req) }
+}
+
+// sysAdminVerifier returns a function that returns
+// kes.ErrNotAllowed if the request identity is not
+// the system admin.
+func sysAdminVerifier(vault *sys.Vault) func(*http.Request) error {
+	return func(req *http.Request) error {
+		admin, err := vault.Admin(req.Context())
+		if err != nil {
+			return err
+		}
+		if auth.Identify(req) != admin {
+			return kes.ErrNotAllowed
+		}
+		return nil
+	}
+}
 
-	return f()
+// edgeVerifier returns a function that verifies requests
+// against the policies and identities of the edge server.
+func edgeVerifier(config *EdgeRouterConfig) func(*http.Request) error {
+	return func(req *http.Request) error {
+		return auth.VerifyRequest(req, config.Policies, config.Identities)
+	}
 }
 
-// VSync calls f while holding the given lock and
-// releases the lock once f has been finished.
-//
-// VSync returns the result of f and its error
-// if  any.
-func VSync[V any](locker sync.Locker, f func() (V, error)) (V, error) {
-	locker.Lock()
-	defer locker.Unlock()
+// verifyEnclaveOwner returns kes.ErrNotAllowed if the request
+// identity is neither the system admin nor the admin of the
+// enclave. It prevents identities assigned to the built-in
+// enclave admin policy from granting their privileges to other
+// identities or revoking them from other enclave admins.
+func verifyEnclaveOwner(vault *sys.Vault, enclave *sys.Enclave, req *http.Request) error {
+	identity := auth.Identify(req)
+	admin, err := vault.Admin(req.Context())
+	if err != nil {
+		return err
+	}
+	if identity == admin {
+		return nil
+	}
+	if admin, err = enclave.Admin(req.Context()); err != nil {
+		return err
+	}
+	if identity == admin {
+		return nil
+	}
+	return kes.ErrNotAllowed
+}
 
-	return f()
+// verifyKeyAccess returns kes.ErrNotAllowed if the key carries
+// an allowlist that neither contains the request identity nor
+// the policy the identity is assigned to.
+func verifyKeyAccess(enclave *sys.Enclave, req *http.Request, k key.Key) error {
+	allowlist := k.Allowlist()
+	if allowlist.IsZero() {
+		return nil
+	}
+
+	identity := auth.Identify(req)
+	info, err := enclave.GetIdentity(req.Context(), identity)
+	if err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+		return err
+	}
+	if !allowlist.Allows(identity, info.Policy) {
+		return kes.ErrNotAllowed
+	}
+	return nil
+}
+
+// isEnclaveAdmin reports whether the given identity is assigned
+// to the built-in enclave admin policy of the enclave.
+func isEnclaveAdmin(ctx context.Context, enclave *sys.Enclave, identity kes.Identity) (bool, error) {
+	info, err := enclave.GetIdentity(ctx, identity)
+	if errors.Is(err, kes.ErrIdentityNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.Policy == auth.EnclaveAdminPolicy, nil
 }
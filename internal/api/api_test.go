@@ -5,10 +5,16 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/minio/kes/kesclient"
 )
 
 func TestVerifyName(t *testing.T) {
@@ -55,6 +61,48 @@ func TestNameFromRequest(t *testing.T) {
 	}
 }
 
+func TestAPIDeadline(t *testing.T) {
+	const Timeout = 500 * time.Millisecond
+
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			return errors.New("request context has no deadline")
+		}
+		if d := time.Until(deadline); d > Timeout-responseReserve(Timeout) {
+			return errors.New("request deadline exceeds API timeout")
+		}
+		<-r.Context().Done() // Simulate a backend that does not respond in time
+		return errors.New("backend did not respond")
+	}
+	server := httptest.NewServer(API{
+		Method:  http.MethodGet,
+		Path:    "/v1/test",
+		Timeout: Timeout,
+		Handler: handler,
+	})
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/test")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", resp.StatusCode, http.StatusGatewayTimeout)
+	}
+	var response struct {
+		Code kesclient.ErrorCode `json:"code"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Code != kesclient.CodeDeadlineExceeded {
+		t.Fatalf("Invalid error code: got '%s' - want '%s'", response.Code, kesclient.CodeDeadlineExceeded)
+	}
+}
+
 func TestPatternFromRequest(t *testing.T) {
 	for i, test := range patternFromRequestTests {
 		url, err := url.Parse(test.URL)
@@ -97,6 +145,11 @@ var (
 		{Name: "hel<lo", ShouldFail: true},                // 13
 		{Name: "Εmacs", ShouldFail: true},                 // 14 - greek Ε
 		{Name: strings.Repeat("a", 81), ShouldFail: true}, // 15
+
+		{Name: "sys/my-key"},                 // 16
+		{Name: "/my-key", ShouldFail: true},  // 17
+		{Name: "sys/a/b"},                    // 18
+		{Name: "sys//key", ShouldFail: true}, // 19
 	}
 
 	verifyPatternTests = []struct {
@@ -117,12 +170,19 @@ var (
 
 		{Pattern: "", ShouldFail: true},                      // 11
 		{Pattern: "my.key", ShouldFail: true},                // 12
-		{Pattern: "key/", ShouldFail: true},                  // 13
+		{Pattern: "key/"},                                    // 13
 		{Pattern: "", ShouldFail: true},                      // 14
 		{Pattern: "☰", ShouldFail: true},                     // 15
 		{Pattern: "hel<lo", ShouldFail: true},                // 16
 		{Pattern: "Εmacs", ShouldFail: true},                 // 17 - greek Ε
 		{Pattern: strings.Repeat("a", 81), ShouldFail: true}, // 18
+
+		{Pattern: "sys/*"},                   // 19
+		{Pattern: "*/*"},                     // 20
+		{Pattern: "/*", ShouldFail: true},    // 21
+		{Pattern: "*/*/*"},                   // 22
+		{Pattern: "/", ShouldFail: true},     // 23
+		{Pattern: "key//", ShouldFail: true}, // 24
 	}
 
 	nameFromRequestTests = []struct {
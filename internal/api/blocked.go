@@ -0,0 +1,70 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// BlockedHandlers returns the number of API handlers, of all
+// routers, that are still running although their request has
+// timed out.
+//
+// Handlers should give up once the request context expires.
+// A handler that does not return in time is usually blocked,
+// for example, by a backend that does not respect the request
+// context. APIs without a timeout, like log and event streams,
+// are never considered blocked.
+func BlockedHandlers() int { return activeHandlers.Blocked() }
+
+// activeHandlers tracks the deadlines of all
+// API handlers with a timeout that are running.
+var activeHandlers = handlerDeadlines{
+	deadlines: map[uint64]time.Time{},
+}
+
+type handlerDeadlines struct {
+	lock      sync.Mutex
+	next      uint64
+	deadlines map[uint64]time.Time
+}
+
+// Add adds a handler with the given deadline and
+// returns an ID that has to be passed to Remove
+// once the handler returns.
+func (h *handlerDeadlines) Add(deadline time.Time) uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.next++
+	h.deadlines[h.next] = deadline
+	return h.next
+}
+
+// Remove removes the handler with the given ID.
+func (h *handlerDeadlines) Remove(id uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.deadlines, id)
+}
+
+// Blocked returns the number of handlers
+// whose deadline has been exceeded.
+func (h *handlerDeadlines) Blocked() int {
+	now := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var n int
+	for _, deadline := range h.deadlines {
+		if now.After(deadline) {
+			n++
+		}
+	}
+	return n
+}
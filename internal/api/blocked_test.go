@@ -0,0 +1,28 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandlerDeadlines(t *testing.T) {
+	handlers := handlerDeadlines{
+		deadlines: map[uint64]time.Time{},
+	}
+	a := handlers.Add(time.Now().Add(-time.Second))
+	b := handlers.Add(time.Now().Add(-time.Minute))
+	handlers.Add(time.Now().Add(time.Hour))
+	if n := handlers.Blocked(); n != 2 {
+		t.Fatalf("Invalid number of blocked handlers: got %d - want %d", n, 2)
+	}
+
+	handlers.Remove(a)
+	handlers.Remove(b)
+	if n := handlers.Blocked(); n != 0 {
+		t.Fatalf("Invalid number of blocked handlers: got %d - want %d", n, 0)
+	}
+}
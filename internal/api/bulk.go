@@ -0,0 +1,285 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/kesclient"
+)
+
+// MaxBulkDeleteKeys is the max. number of keys a single
+// bulk delete request can delete.
+const MaxBulkDeleteKeys = 1000
+
+var errBulkDeleteChanged = &kesclient.Error{
+	Code:    kesclient.CodePreconditionFailed,
+	Status:  http.StatusPreconditionFailed,
+	Message: "keys matching the pattern have changed since the dry run",
+}
+
+func bulkDeleteKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodDelete
+		APIPath     = "/v1/key/delete-bulk/"
+		MaxBody     = 0
+		Timeout     = 60 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		pattern, err := patternFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		return bulkDelete(w, r, pattern, bulkKeyStore{
+			List: func(ctx context.Context) ([]string, error) {
+				iterator, err := enclave.ListKeys(ctx)
+				if err != nil {
+					return nil, err
+				}
+				defer iterator.Close()
+
+				var names []string
+				for iterator.Next() {
+					names = append(names, iterator.Name())
+				}
+				return names, iterator.Close()
+			},
+			Get:    enclave.GetKey,
+			Delete: enclave.DeleteKey,
+			Verify: enclave.VerifyRequest,
+		}, func(name string) { config.Events.Publish(r, EventKeyDeleted, name) })
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    bulkDeleteResponse{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeBulkDeleteKey(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodDelete
+		APIPath = "/v1/key/delete-bulk/"
+		MaxBody int64
+		Timeout = 60 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		pattern, err := patternFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		return bulkDelete(w, r, pattern, bulkKeyStore{
+			List: func(ctx context.Context) ([]string, error) {
+				iterator, err := config.Keys.ListPrefix(ctx, patternPrefix(pattern))
+				if err != nil {
+					return nil, err
+				}
+				defer iterator.Close()
+
+				var names []string
+				for {
+					name, ok := iterator.Next()
+					if !ok {
+						break
+					}
+					names = append(names, name)
+				}
+				return names, iterator.Close()
+			},
+			Get:    config.Keys.Get,
+			Delete: config.Keys.Delete,
+			Verify: func(r *http.Request) error {
+				return auth.VerifyRequest(r, config.Policies, config.Identities)
+			},
+		}, func(name string) { config.Events.Publish(r, EventKeyDeleted, name) })
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/json",
+		Response:    bulkDeleteResponse{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// bulkKeyStore provides the operations of a
+// key store required for deleting keys in bulk.
+type bulkKeyStore struct {
+	List   func(context.Context) ([]string, error)
+	Get    func(context.Context, string) (key.Key, error)
+	Delete func(context.Context, string) error
+
+	// Verify verifies that the request identity
+	// is allowed to send the request.
+	Verify func(*http.Request) error
+}
+
+// bulkDeleteResponse is the response of the bulk delete APIs.
+type bulkDeleteResponse struct {
+	DryRun  bool               `json:"dry_run"`
+	Confirm string             `json:"confirm,omitempty"`
+	Keys    []bulkDeleteResult `json:"keys"`
+}
+
+// bulkDeleteResult is the result of deleting a single key.
+type bulkDeleteResult struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Deleted     bool   `json:"deleted"`
+	Err         string `json:"error,omitempty"`
+}
+
+// bulkDelete deletes all keys whose names match the pattern
+// and writes the per-key results to w.
+//
+// A client has to send a dry run, with the "dry-run" query
+// parameter, first. The dry run deletes nothing but returns
+// the keys that would be deleted and a confirmation token.
+// The token is a hash of the names and fingerprints of these
+// keys. To delete them, the client sends the token as "confirm"
+// query parameter. If the matching keys have changed since the
+// dry run, e.g. because a key has been created or recreated,
+// no key gets deleted.
+//
+// A key is only deleted if the request identity would also
+// be allowed to delete it via the /v1/key/delete/ API.
+func bulkDelete(w http.ResponseWriter, r *http.Request, pattern string, store bulkKeyStore, onDelete func(string)) error {
+
+	if pattern == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: pattern is empty")
+	}
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dry-run"))
+	if _, ok := query["dry-run"]; ok && query.Get("dry-run") == "" {
+		dryRun = true
+	}
+	confirm := query.Get("confirm")
+	if dryRun && confirm != "" {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: 'dry-run' and 'confirm' cannot be used together")
+	}
+	if !dryRun && confirm == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: send a dry run first and confirm the deletion with its token")
+	}
+
+	names, err := store.List(r.Context())
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var (
+		results = []bulkDeleteResult{}
+		token   = sha256.New()
+	)
+	for _, name := range names {
+		if !matchPattern(pattern, name) {
+			continue
+		}
+		if len(results) == MaxBulkDeleteKeys {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: more than "+strconv.Itoa(MaxBulkDeleteKeys)+" keys match the pattern")
+		}
+
+		result := bulkDeleteResult{Name: name}
+		if err = verifyKeyDeletion(r, name, store.Verify); err != nil {
+			result.Err = err.Error()
+			results = append(results, result)
+			continue
+		}
+		k, err := store.Get(r.Context(), name)
+		if err != nil {
+			result.Err = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.Fingerprint = k.Fingerprint()
+		switch {
+		case k.Immutable():
+			result.Err = key.ErrImmutable.Error()
+		case k.LegalHold() != nil:
+			result.Err = key.ErrLegalHold.Error()
+		default:
+			token.Write([]byte(name))
+			token.Write([]byte{0})
+			token.Write([]byte(result.Fingerprint))
+			token.Write([]byte{'\n'})
+		}
+		results = append(results, result)
+	}
+	sum := hex.EncodeToString(token.Sum(nil)[:16])
+
+	if !dryRun {
+		if sum != confirm {
+			return errBulkDeleteChanged
+		}
+		for i := range results {
+			if results[i].Err != "" {
+				continue
+			}
+			if err = store.Delete(r.Context(), results[i].Name); err != nil {
+				results[i].Err = err.Error()
+				continue
+			}
+			results[i].Deleted = true
+			onDelete(results[i].Name)
+		}
+		sum = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bulkDeleteResponse{
+		DryRun:  dryRun,
+		Confirm: sum,
+		Keys:    results,
+	})
+	return nil
+}
+
+// verifyKeyDeletion verifies that the identity of r is
+// allowed to delete the named key via the /v1/key/delete/
+// API. The policy decision is not recorded for r.
+func verifyKeyDeletion(r *http.Request, name string, verify func(*http.Request) error) error {
+	req := r.Clone(auth.WithDecisionRecorder(r.Context()))
+	req.URL.Path = "/v1/key/delete/" + name
+	req.URL.RawPath = ""
+	return verify(req)
+}
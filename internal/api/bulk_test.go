@@ -0,0 +1,144 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestBulkDelete(t *testing.T) {
+	type Response struct {
+		DryRun  bool   `json:"dry_run"`
+		Confirm string `json:"confirm"`
+		Keys    []struct {
+			Name    string `json:"name"`
+			Deleted bool   `json:"deleted"`
+			Err     string `json:"error"`
+		} `json:"keys"`
+	}
+
+	keys := map[string]key.Key{}
+	create := func(name string) {
+		t.Helper()
+		k, err := key.Random(kes.AES256_GCM_SHA256, "")
+		if err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+		keys[name] = k
+	}
+	create("tmp-1")
+	create("tmp-2")
+	create("tmp-immutable")
+	create("tmp-forbidden")
+	create("my-key")
+	k := keys["tmp-immutable"]
+	k.SetRetainUntil(time.Now().Add(time.Hour))
+	keys["tmp-immutable"] = k
+
+	var deleted []string
+	store := bulkKeyStore{
+		List: func(context.Context) ([]string, error) {
+			names := make([]string, 0, len(keys))
+			for name := range keys {
+				names = append(names, name)
+			}
+			return names, nil
+		},
+		Get: func(_ context.Context, name string) (key.Key, error) {
+			k, ok := keys[name]
+			if !ok {
+				return key.Key{}, kes.ErrKeyNotFound
+			}
+			return k, nil
+		},
+		Delete: func(_ context.Context, name string) error {
+			delete(keys, name)
+			return nil
+		},
+		Verify: func(r *http.Request) error {
+			if r.URL.Path == "/v1/key/delete/tmp-forbidden" {
+				return kes.ErrNotAllowed
+			}
+			return nil
+		},
+	}
+	send := func(query string) (*Response, error) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "/v1/key/delete-bulk/tmp-*?"+query, nil)
+		if err := bulkDelete(w, r, "tmp-*", store, func(name string) { deleted = append(deleted, name) }); err != nil {
+			return nil, err
+		}
+		var resp Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return &resp, nil
+	}
+
+	if _, err := send(""); err == nil {
+		t.Fatal("Bulk delete without dry run succeeded")
+	}
+	if _, err := send("dry-run&confirm=abc"); err == nil {
+		t.Fatal("Bulk delete with dry run and confirmation succeeded")
+	}
+
+	preview, err := send("dry-run")
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if !preview.DryRun || preview.Confirm == "" {
+		t.Fatalf("Invalid dry run: got dry_run '%v' and confirm '%s'", preview.DryRun, preview.Confirm)
+	}
+	if len(preview.Keys) != 4 {
+		t.Fatalf("Invalid dry run: got %d keys - want %d", len(preview.Keys), 4)
+	}
+	if len(keys) != 5 || len(deleted) != 0 {
+		t.Fatal("Dry run deleted keys")
+	}
+
+	create("tmp-3")
+	if _, err = send("confirm=" + preview.Confirm); !errors.Is(err, errBulkDeleteChanged) {
+		t.Fatalf("Bulk delete after keys have changed: got '%v' - want '%v'", err, errBulkDeleteChanged)
+	}
+	if len(keys) != 6 {
+		t.Fatal("Bulk delete deleted keys although keys have changed")
+	}
+
+	if preview, err = send("dry-run=true"); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	result, err := send("confirm=" + preview.Confirm)
+	if err != nil {
+		t.Fatalf("Bulk delete failed: %v", err)
+	}
+	for _, k := range result.Keys {
+		switch k.Name {
+		case "tmp-immutable", "tmp-forbidden":
+			if k.Deleted || k.Err == "" {
+				t.Fatalf("Key '%s': got deleted '%v' and error '%s' - want not deleted", k.Name, k.Deleted, k.Err)
+			}
+		default:
+			if !k.Deleted {
+				t.Fatalf("Key '%s' has not been deleted: %s", k.Name, k.Err)
+			}
+		}
+	}
+	if len(deleted) != 3 {
+		t.Fatalf("Invalid deleted keys: got '%v' - want 3 keys", deleted)
+	}
+	if _, ok := keys["my-key"]; !ok {
+		t.Fatal("Bulk delete deleted key not matching the pattern")
+	}
+}
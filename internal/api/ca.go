@@ -0,0 +1,316 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/pki"
+)
+
+// CAConfig controls the lifetimes of certificates and tokens
+// issued by the built-in certificate authority and signing
+// services of a stateful server.
+type CAConfig struct {
+	// MaxClientTTL is the max. lifetime of client certificates.
+	// If <= 0, it defaults to 7 days.
+	MaxClientTTL time.Duration
+
+	// MaxServerTTL is the max. lifetime of server certificates.
+	// If <= 0, it defaults to 90 days.
+	MaxServerTTL time.Duration
+
+	// CRLTTL is the time after which relying parties should
+	// fetch a new certificate revocation list. If <= 0, it
+	// defaults to 24 hours.
+	CRLTTL time.Duration
+
+	// MaxSSHTTL is the max. lifetime of SSH certificates.
+	// If <= 0, it defaults to 24 hours.
+	MaxSSHTTL time.Duration
+
+	// MaxTokenTTL is the max. lifetime of JWTs.
+	// If <= 0, it defaults to 24 hours.
+	MaxTokenTTL time.Duration
+}
+
+func (c *CAConfig) maxClientTTL() time.Duration {
+	if c.MaxClientTTL <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return c.MaxClientTTL
+}
+
+func (c *CAConfig) maxServerTTL() time.Duration {
+	if c.MaxServerTTL <= 0 {
+		return 90 * 24 * time.Hour
+	}
+	return c.MaxServerTTL
+}
+
+func (c *CAConfig) crlTTL() time.Duration {
+	if c.CRLTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return c.CRLTTL
+}
+
+func (c *CAConfig) maxSSHTTL() time.Duration {
+	if c.MaxSSHTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return c.MaxSSHTTL
+}
+
+func (c *CAConfig) maxTokenTTL() time.Duration {
+	if c.MaxTokenTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return c.MaxTokenTTL
+}
+
+// defaultClientTTL is the lifetime of client certificates
+// if the client does not request a specific lifetime.
+const defaultClientTTL = 24 * time.Hour
+
+func caCertificate(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/ca/cert"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = false
+		ContentType = "application/x-pem-file"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		issuer, err := config.Vault.Issuer(r.Context())
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: issuer.Certificate().Raw})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func caRevocationList(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/ca/crl"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = false
+		ContentType = "application/pkix-crl"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		issuer, err := config.Vault.Issuer(r.Context())
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		crl, err := issuer.RevocationList(now, now.Add(config.CA.crlTTL()))
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(crl)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func caIssue(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/ca/issue"
+		MaxBody     = int64(16 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		CSR         string        `json:"csr"`
+		Type        string        `json:"type"` // "server" or "client"
+		DNSNames    []string      `json:"dns_names"`
+		IPAddresses []string      `json:"ip_addresses"`
+		TTL         time.Duration `json:"ttl"` // optional
+	}
+	type Response struct {
+		Identity     kes.Identity `json:"identity"`
+		SerialNumber string       `json:"serial_number"`
+		Certificate  string       `json:"certificate"`
+		CA           string       `json:"ca"`
+		ExpiresAt    time.Time    `json:"expires_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		csr, err := pki.ParseCertificateRequest([]byte(req.CSR))
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		ips := make([]net.IP, 0, len(req.IPAddresses))
+		for _, s := range req.IPAddresses {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid IP address '%s'", s))
+			}
+			ips = append(ips, ip)
+		}
+
+		var maxTTL, ttl time.Duration
+		switch req.Type {
+		case "server":
+			maxTTL, ttl = config.CA.maxServerTTL(), config.CA.maxServerTTL()
+		case "client":
+			maxTTL, ttl = config.CA.maxClientTTL(), defaultClientTTL
+			if ttl > maxTTL {
+				ttl = maxTTL
+			}
+		default:
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid certificate type '%s': must be 'server' or 'client'", req.Type))
+		}
+		if req.TTL < 0 || req.TTL > maxTTL {
+			return kes.NewError(http.StatusBadRequest, "invalid ttl: must not exceed "+maxTTL.String())
+		}
+		if req.TTL > 0 {
+			ttl = req.TTL
+		}
+
+		issuer, err := config.Vault.Issuer(r.Context())
+		if err != nil {
+			return err
+		}
+		var issued *x509.Certificate
+		if req.Type == "server" {
+			issued, err = issuer.IssueServerCertificate(csr, req.DNSNames, ips, time.Now().Add(ttl))
+		} else {
+			issued, err = issuer.IssueClientCertificate(csr, time.Now().Add(ttl))
+		}
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		h := sha256.Sum256(issued.RawSubjectPublicKeyInfo)
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Identity:     kes.Identity(hex.EncodeToString(h[:])),
+			SerialNumber: fmt.Sprintf("%x", issued.SerialNumber),
+			Certificate:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issued.Raw})),
+			CA:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Certificate().Raw})),
+			ExpiresAt:    issued.NotAfter,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(sysAdminVerifier(config.Vault), handler)))),
+	}
+}
+
+func caRevoke(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/ca/revoke/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		serialNumber, ok := parseSerialNumber(name)
+		if !ok {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid serial number '%s'", name))
+		}
+		if err = config.Vault.RevokeCertificate(r.Context(), serialNumber); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(sysAdminVerifier(config.Vault), handler)))),
+	}
+}
+
+// parseSerialNumber parses a hex-encoded certificate serial
+// number. The bytes of the serial number may be separated by
+// colons, as printed by OpenSSL.
+func parseSerialNumber(s string) (*big.Int, bool) {
+	serialNumber, ok := new(big.Int).SetString(strings.ReplaceAll(s, ":", ""), 16)
+	if !ok || serialNumber.Sign() <= 0 {
+		return nil, false
+	}
+	return serialNumber, true
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/kesclient"
+)
+
+func cacheStats(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/admin/cache/stats"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if auth.Identify(r) != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		stats, err := config.Vault.CacheStats()
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(kesclient.CacheStats{
+			Enclaves:   stats.Enclaves,
+			Keys:       stats.Keys,
+			Secrets:    stats.Secrets,
+			Policies:   stats.Policies,
+			Identities: stats.Identities,
+			KeyHits:    stats.KeyHits,
+			KeyMisses:  stats.KeyMisses,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    kesclient.CacheStats{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeCacheStats(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/admin/cache/stats"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		keys := config.Keys.Stats()
+		stats := kesclient.CacheStats{
+			Keys:        keys.Entries,
+			OfflineKeys: keys.OfflineEntries,
+			KeyHits:     keys.Hits,
+			KeyMisses:   keys.Misses,
+		}
+		if policies, ok := config.Policies.(*auth.PolicyCache); ok {
+			stats.Policies = policies.Len()
+		}
+		if identities, ok := config.Identities.(*auth.IdentityCache); ok {
+			stats.Identities = identities.Len()
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(stats)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    kesclient.CacheStats{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func flushCache(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/admin/cache/flush"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if auth.Identify(r) != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		n, err := config.Vault.FlushCache(r.URL.Query().Get("key"))
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(flushCacheResponse{Keys: n})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    flushCacheResponse{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeFlushCache(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodPost
+		APIPath     = "/v1/admin/cache/flush"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		pattern := r.URL.Query().Get("key")
+		n, err := config.Keys.Flush(pattern)
+		if err != nil {
+			return err
+		}
+		if pattern == "" {
+			if policies, ok := config.Policies.(*auth.PolicyCache); ok {
+				policies.Invalidate()
+			}
+			if identities, ok := config.Identities.(*auth.IdentityCache); ok {
+				identities.Invalidate()
+			}
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(flushCacheResponse{Keys: n})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    flushCacheResponse{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// flushCacheResponse is the response of a cache flush.
+// It contains the number of flushed keys.
+type flushCacheResponse struct {
+	Keys int `json:"keys"`
+}
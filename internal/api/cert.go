@@ -0,0 +1,258 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/pki"
+)
+
+// certSigningKey is the purpose of the signing key derived
+// from a KES key to sign X.509 certificates.
+const certSigningKey = "x509"
+
+// defaultCertTTL is the lifetime of X.509 certificates if
+// the client does not request a specific lifetime.
+const defaultCertTTL = 24 * time.Hour
+
+func certCA(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/cert/ca/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/x-pem-file"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		issuer, err := certIssuer(name, &key)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: issuer.Certificate().Raw})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func certIssue(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/cert/issue/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+
+		MaxNames = 100
+	)
+	type Request struct {
+		CSR         string        `json:"csr"`
+		DNSNames    []string      `json:"dns_names"`
+		IPAddresses []string      `json:"ip_addresses"`
+		TTL         time.Duration `json:"ttl"` // optional
+	}
+	type Response struct {
+		Identity     kes.Identity `json:"identity"`
+		SerialNumber string       `json:"serial_number"`
+		Certificate  string       `json:"certificate"`
+		CA           string       `json:"ca"`
+		ExpiresAt    time.Time    `json:"expires_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(req.DNSNames) == 0 && len(req.IPAddresses) == 0 {
+			return kes.NewError(http.StatusBadRequest, "no DNS name or IP address specified")
+		}
+		if len(req.DNSNames)+len(req.IPAddresses) > MaxNames {
+			return kes.NewError(http.StatusBadRequest, "too many DNS names and IP addresses")
+		}
+		csr, err := pki.ParseCertificateRequest([]byte(req.CSR))
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		// The certificate profile is defined by the policy
+		// the requesting identity is assigned to.
+		identity := auth.Identify(r)
+		info, err := enclave.GetIdentity(r.Context(), identity)
+		if err != nil {
+			return err
+		}
+		var profile auth.CertificateProfile
+		if info.Policy != "" {
+			policy, err := enclave.GetPolicy(r.Context(), info.Policy)
+			if err != nil {
+				return err
+			}
+			profile = policy.Certificates[name]
+		}
+		if profile.Key == "" {
+			return kes.NewError(http.StatusNotFound, fmt.Sprintf("certificate profile '%s' does not exist", name))
+		}
+
+		for _, dnsName := range req.DNSNames {
+			if dnsName == "" {
+				return kes.NewError(http.StatusBadRequest, "invalid DNS name: empty name")
+			}
+			if strings.Contains(dnsName, "*") {
+				return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid DNS name '%s': wildcard certificates are not supported", dnsName))
+			}
+			if !profile.AllowsDNSName(dnsName) {
+				return kes.NewError(http.StatusForbidden, fmt.Sprintf("not authorized: DNS name '%s' is not allowed", dnsName))
+			}
+		}
+		ips := make([]net.IP, 0, len(req.IPAddresses))
+		for _, s := range req.IPAddresses {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid IP address '%s'", s))
+			}
+			if !profile.AllowsIP(ip) {
+				return kes.NewError(http.StatusForbidden, fmt.Sprintf("not authorized: IP address '%s' is not allowed", s))
+			}
+			ips = append(ips, ip)
+		}
+
+		maxTTL := profile.MaxTTL
+		if maxTTL <= 0 {
+			maxTTL = config.CA.maxServerTTL()
+		}
+		if req.TTL < 0 || req.TTL > maxTTL {
+			return kes.NewError(http.StatusBadRequest, "invalid ttl: must not exceed "+maxTTL.String())
+		}
+		if req.TTL == 0 {
+			req.TTL = defaultCertTTL
+			if req.TTL > maxTTL {
+				req.TTL = maxTTL
+			}
+		}
+		expiresAt := time.Now().Add(req.TTL)
+		if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(expiresAt) {
+			expiresAt = info.ExpiresAt // The certificate must not outlive the identity's policy assignment
+		}
+
+		usage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		if len(profile.Usage) > 0 {
+			usage = usage[:0]
+			for _, u := range profile.Usage {
+				switch u {
+				case auth.UsageServer:
+					usage = append(usage, x509.ExtKeyUsageServerAuth)
+				case auth.UsageClient:
+					usage = append(usage, x509.ExtKeyUsageClientAuth)
+				}
+			}
+		}
+
+		key, err := enclave.GetKey(r.Context(), profile.Key)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+		issuer, err := certIssuer(profile.Key, &key)
+		if err != nil {
+			return err
+		}
+		cert, err := issuer.IssueCertificate(csr, req.DNSNames, ips, usage, expiresAt)
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Identity:     kes.Identity(hex.EncodeToString(h[:])),
+			SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+			Certificate:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			CA:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Certificate().Raw})),
+			ExpiresAt:    cert.NotAfter,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// certIssuer returns the certificate authority derived from
+// the key with the given name. Its CA certificate does not
+// change as long as the key exists.
+func certIssuer(name string, k *key.Key) (*pki.Issuer, error) {
+	priv, err := k.SigningKey(certSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return pki.DeriveIssuer(name, priv, k.CreatedAt())
+}
@@ -0,0 +1,377 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/sys"
+)
+
+// Roles of a KES server within a cluster.
+const (
+	RoleStandalone = "standalone"
+	RoleLeader     = "leader"
+	RoleFollower   = "follower"
+)
+
+// ClusterConfig is a structure containing the
+// cluster configuration of a KES server.
+type ClusterConfig struct {
+	// ID is the ID of the server within the cluster.
+	ID string
+
+	// Leader is the endpoint of the cluster leader.
+	// It is empty if the server is the leader or not
+	// part of a cluster.
+	Leader string
+
+	// Nodes are the endpoints of all other cluster
+	// nodes. The Leader is added if not present.
+	Nodes []string
+
+	// Transport is used to fetch the status of
+	// other cluster nodes.
+	Transport http.RoundTripper
+
+	// CertHeader is the HTTP header that carries the
+	// client certificate when fetching the status of
+	// other cluster nodes. If empty, it defaults to
+	// DefaultForwardCertHeader.
+	CertHeader string
+
+	// ModTime returns the point in time when the
+	// server state has been modified most recently.
+	ModTime func() (time.Time, error)
+}
+
+// NodeStatus describes the state of a cluster node.
+type NodeStatus struct {
+	ID       string        `json:"id,omitempty"`
+	Endpoint string        `json:"endpoint,omitempty"`
+	Role     string        `json:"role,omitempty"`
+	Leader   string        `json:"leader,omitempty"`
+	Version  string        `json:"version,omitempty"`
+	Commit   string        `json:"commit,omitempty"`
+	UpTime   time.Duration `json:"uptime,omitempty"`
+	ModTime  time.Time     `json:"modified_at"`
+
+	MinWireVersion int `json:"wire_version_min,omitempty"`
+	MaxWireVersion int `json:"wire_version_max,omitempty"`
+
+	// ReplicationLag is the time the node state lags
+	// behind the leader state. It is nil if unknown.
+	ReplicationLag *time.Duration `json:"replication_lag,omitempty"`
+
+	// Err is the error that occurred when fetching
+	// the status of the node, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// NewCluster returns a new Cluster from the given
+// configuration.
+func NewCluster(config *ClusterConfig) (*Cluster, error) {
+	c := &Cluster{
+		id:         config.ID,
+		role:       RoleStandalone,
+		leader:     config.Leader,
+		certHeader: http.CanonicalHeaderKey(config.CertHeader),
+		client:     http.Client{Transport: config.Transport},
+		modTime:    config.ModTime,
+		startTime:  time.Now(),
+	}
+	if c.certHeader == "" {
+		c.certHeader = DefaultForwardCertHeader
+	}
+
+	endpoints := config.Nodes
+	if config.Leader != "" {
+		c.role = RoleFollower
+		endpoints = append([]string{config.Leader}, endpoints...)
+	} else if len(config.Nodes) > 0 {
+		c.role = RoleLeader
+	}
+	seen := map[string]bool{}
+	for _, endpoint := range endpoints {
+		node, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("api: invalid cluster node '%s': %v", endpoint, err)
+		}
+		if node.Scheme != "https" || node.Host == "" {
+			return nil, fmt.Errorf("api: invalid cluster node '%s': must be an https URL", endpoint)
+		}
+		if !seen[node.String()] {
+			seen[node.String()] = true
+			c.nodes = append(c.nodes, node)
+		}
+	}
+	return c, nil
+}
+
+// A Cluster reports the status of a KES server and
+// the other nodes of the cluster it belongs to.
+//
+// It fetches the status of other nodes on behalf of
+// the client. Hence, all cluster nodes have to accept
+// each other as TLS proxy.
+type Cluster struct {
+	id         string
+	role       string
+	leader     string
+	nodes      []*url.URL
+	certHeader string
+	client     http.Client
+	modTime    func() (time.Time, error)
+	startTime  time.Time
+}
+
+// ID returns the ID of the server within the cluster.
+func (c *Cluster) ID() string { return c.id }
+
+// Role returns the role of the server within the cluster.
+func (c *Cluster) Role() string { return c.role }
+
+// Status returns the status of the server itself. The
+// client certificate is used to fetch the leader status
+// when computing the replication lag of a follower.
+func (c *Cluster) Status(ctx context.Context, cert *x509.Certificate) NodeStatus {
+	status := c.status()
+	if status.Err != "" {
+		return status
+	}
+
+	switch c.role {
+	case RoleStandalone, RoleLeader:
+		var lag time.Duration
+		status.ReplicationLag = &lag
+	case RoleFollower:
+		leader, err := c.fetch(ctx, c.nodes[0], cert)
+		if err == nil && leader.Role == RoleLeader {
+			status.ReplicationLag = replicationLag(leader.ModTime, status.ModTime)
+		}
+	}
+	return status
+}
+
+// Nodes returns the status of all cluster nodes. The first
+// NodeStatus is the status of the server itself. The status
+// of other nodes is fetched on behalf of the client.
+//
+// Nodes that cannot be reached are reported with an error.
+func (c *Cluster) Nodes(ctx context.Context, cert *x509.Certificate) []NodeStatus {
+	nodes := make([]NodeStatus, 1+len(c.nodes))
+	nodes[0] = c.status()
+
+	var wg sync.WaitGroup
+	for i, node := range c.nodes {
+		wg.Add(1)
+		go func(i int, node *url.URL) {
+			defer wg.Done()
+
+			status, err := c.fetch(ctx, node, cert)
+			if err != nil {
+				status = NodeStatus{Err: err.Error()}
+			}
+			status.Endpoint = node.String()
+			nodes[i+1] = status
+		}(i, node)
+	}
+	wg.Wait()
+
+	var (
+		leaderModTime time.Time
+		hasLeader     bool
+	)
+	for _, node := range nodes {
+		if node.Err == "" && (node.Role == RoleLeader || node.Role == RoleStandalone) {
+			leaderModTime, hasLeader = node.ModTime, true
+			break
+		}
+	}
+	for i := range nodes {
+		nodes[i].ReplicationLag = nil
+		if hasLeader && nodes[i].Err == "" {
+			nodes[i].ReplicationLag = replicationLag(leaderModTime, nodes[i].ModTime)
+		}
+	}
+	return nodes
+}
+
+// status returns the status of the server itself
+// without the replication lag.
+func (c *Cluster) status() NodeStatus {
+	status := NodeStatus{
+		ID:      c.id,
+		Role:    c.role,
+		Leader:  c.leader,
+		Version: sys.BinaryInfo().Version,
+		Commit:  sys.BinaryInfo().CommitID,
+		UpTime:  time.Since(c.startTime).Round(time.Second),
+
+		MinWireVersion: MinWireVersion,
+		MaxWireVersion: MaxWireVersion,
+	}
+	if c.modTime != nil {
+		modTime, err := c.modTime()
+		if err != nil {
+			status.Err = fmt.Sprintf("failed to read state: %v", err)
+		}
+		status.ModTime = modTime
+	}
+	return status
+}
+
+// fetch fetches the status of the given cluster
+// node on behalf of the client.
+func (c *Cluster) fetch(ctx context.Context, node *url.URL, cert *x509.Certificate) (NodeStatus, error) {
+	const (
+		Timeout = 5 * time.Second
+		MaxBody = 1 * mem.MiB
+	)
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, node.String()+"/v1/cluster/status", nil)
+	if err != nil {
+		return NodeStatus{}, err
+	}
+	if cert != nil {
+		setClientCert(req.Header, c.certHeader, cert)
+	}
+	req.Header.Set(HeaderWireVersion, wireVersions())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return NodeStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	if err = verifyWireVersion(resp); err != nil {
+		return NodeStatus{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var response struct {
+			Message string `json:"message"`
+		}
+		if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&response); err != nil || response.Message == "" {
+			response.Message = http.StatusText(resp.StatusCode)
+		}
+		return NodeStatus{}, kes.NewError(resp.StatusCode, response.Message)
+	}
+
+	var status NodeStatus
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&status); err != nil {
+		return NodeStatus{}, err
+	}
+	return status, nil
+}
+
+// replicationLag returns the time the node state lags
+// behind the leader state. It is zero if the node state
+// is as recent as the leader state.
+func replicationLag(leader, node time.Time) *time.Duration {
+	var lag time.Duration
+	if leader.After(node) {
+		lag = leader.Sub(node)
+	}
+	return &lag
+}
+
+func clusterStatus(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/cluster/status"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		status := config.Cluster.Status(r.Context(), clientCert(r))
+		status.Endpoint = "https://" + r.Host
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(status)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    NodeStatus{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func clusterNodes(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/cluster/nodes"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		nodes := config.Cluster.Nodes(r.Context(), clientCert(r))
+		nodes[0].Endpoint = "https://" + r.Host
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(nodes)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    []NodeStatus{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// clientCert returns the client certificate of
+// the request or nil if there is none.
+func clientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
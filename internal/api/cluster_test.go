@@ -0,0 +1,125 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestClusterNodes(t *testing.T) {
+	leaderModTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	leader := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/cluster/status" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if _, err := url.QueryUnescape(r.Header.Get(DefaultForwardCertHeader)); err != nil || r.Header.Get(DefaultForwardCertHeader) == "" {
+			t.Errorf("Request does not contain a valid client certificate header: %v", err)
+		}
+		json.NewEncoder(w).Encode(NodeStatus{
+			ID:      "node-0",
+			Role:    RoleLeader,
+			ModTime: leaderModTime,
+		})
+	}))
+	defer leader.Close()
+
+	unreachable := httptest.NewTLSServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	cluster, err := NewCluster(&ClusterConfig{
+		ID:        "node-1",
+		Leader:    leader.URL,
+		Nodes:     []string{leader.URL, unreachable.URL},
+		Transport: leader.Client().Transport,
+		ModTime:   func() (time.Time, error) { return leaderModTime.Add(-5 * time.Second), nil },
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cluster: %v", err)
+	}
+
+	nodes := cluster.Nodes(context.Background(), leader.Certificate())
+	if len(nodes) != 3 {
+		t.Fatalf("Invalid number of nodes: got %d - want %d", len(nodes), 3)
+	}
+	if nodes[0].ID != "node-1" || nodes[0].Role != RoleFollower || nodes[0].Leader != leader.URL {
+		t.Fatalf("Invalid status of the node itself: got '%+v'", nodes[0])
+	}
+	if nodes[0].ReplicationLag == nil || *nodes[0].ReplicationLag != 5*time.Second {
+		t.Fatalf("Invalid replication lag of the node itself: got '%v' - want '%v'", nodes[0].ReplicationLag, 5*time.Second)
+	}
+	if nodes[1].ID != "node-0" || nodes[1].Role != RoleLeader || nodes[1].Endpoint != leader.URL {
+		t.Fatalf("Invalid status of the leader: got '%+v'", nodes[1])
+	}
+	if nodes[1].ReplicationLag == nil || *nodes[1].ReplicationLag != 0 {
+		t.Fatalf("Invalid replication lag of the leader: got '%v' - want '%v'", nodes[1].ReplicationLag, 0)
+	}
+	if nodes[2].Err == "" || nodes[2].ReplicationLag != nil {
+		t.Fatalf("Unreachable node is not reported as unreachable: got '%+v'", nodes[2])
+	}
+
+	status := cluster.Status(context.Background(), leader.Certificate())
+	if status.ReplicationLag == nil || *status.ReplicationLag != 5*time.Second {
+		t.Fatalf("Invalid replication lag: got '%v' - want '%v'", status.ReplicationLag, 5*time.Second)
+	}
+}
+
+func TestNewCluster(t *testing.T) {
+	for i, test := range newClusterTests {
+		cluster, err := NewCluster(&test.Config)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to create cluster: %v", i, err)
+		}
+		if err == nil && cluster.role != test.Role {
+			t.Fatalf("Test %d: got role '%s' - want '%s'", i, cluster.role, test.Role)
+		}
+		if err == nil && len(cluster.nodes) != test.Nodes {
+			t.Fatalf("Test %d: got %d nodes - want %d", i, len(cluster.nodes), test.Nodes)
+		}
+	}
+}
+
+var newClusterTests = []struct {
+	Config     ClusterConfig
+	Role       string
+	Nodes      int
+	ShouldFail bool
+}{
+	{ // 0
+		Config: ClusterConfig{},
+		Role:   RoleStandalone,
+	},
+	{ // 1
+		Config: ClusterConfig{Nodes: []string{"https://kes-1:7373", "https://kes-2:7373"}},
+		Role:   RoleLeader,
+		Nodes:  2,
+	},
+	{ // 2
+		Config: ClusterConfig{Leader: "https://kes-0:7373", Nodes: []string{"https://kes-0:7373/", "https://kes-2:7373"}},
+		Role:   RoleFollower,
+		Nodes:  2,
+	},
+	{ // 3
+		Config: ClusterConfig{Leader: "https://kes-0:7373"},
+		Role:   RoleFollower,
+		Nodes:  1,
+	},
+	{ // 4
+		Config:     ClusterConfig{Nodes: []string{"http://kes-1:7373"}},
+		ShouldFail: true,
+	},
+	{ // 5
+		Config:     ClusterConfig{Leader: "kes-0:7373"},
+		ShouldFail: true,
+	},
+}
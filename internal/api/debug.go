@@ -0,0 +1,205 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// MaxProfileDuration is the max. duration of
+// a CPU profile or runtime trace.
+const MaxProfileDuration = 5 * time.Minute
+
+func debugProfile(config *RouterConfig) API {
+	const (
+		Method  = http.MethodGet
+		APIPath = "/v1/debug/pprof/"
+		MaxBody = 0
+		Timeout = 0 * time.Second // No timeout - CPU profiles and traces are limited by MaxProfileDuration
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		// Profiles contain information about the entire server
+		// and not just one enclave. Heap profiles may even contain
+		// key material of any enclave. Hence, only the system admin
+		// can take profiles.
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+		return serveProfile(w, r, strings.TrimPrefix(r.URL.Path, APIPath))
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/octet-stream",
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeDebugProfile(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/v1/debug/pprof/"
+		MaxBody int64
+		Timeout = 0 * time.Second // No timeout - CPU profiles and traces are limited by MaxProfileDuration
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		// Heap profiles may contain key material. Hence,
+		// only the admin can take profiles.
+		admin, err := config.Identities.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != admin {
+			return kes.ErrNotAllowed
+		}
+		return serveProfile(w, r, strings.TrimPrefix(r.URL.Path, APIPath))
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/octet-stream",
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// serveProfile writes the profile with the given name to w.
+// It serves the same profiles as the net/http/pprof package
+// without registering any handler on http.DefaultServeMux:
+//   - profile: a CPU profile. The 'seconds' query parameter
+//     controls its duration. (default: 30s)
+//   - trace: a runtime execution trace. The 'seconds' query
+//     parameter controls its duration. (default: 1s)
+//   - any runtime/pprof profile, like heap, allocs, goroutine,
+//     block, mutex or threadcreate. The 'debug' query parameter
+//     selects the text format instead of the protobuf format,
+//     and, for the heap profile, the 'gc' query parameter runs a
+//     garbage collection before taking the profile.
+//
+// Only one CPU profile and one trace can be taken at a time.
+// Profiles, in particular heap profiles, may contain secret
+// data, like key material, and must only be served to the
+// admin.
+func serveProfile(w http.ResponseWriter, r *http.Request, name string) error {
+	switch name {
+	case "profile", "trace":
+		duration := 30 * time.Second
+		if name == "trace" {
+			duration = 1 * time.Second
+		}
+		if s := r.URL.Query().Get("seconds"); s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > MaxProfileDuration {
+				return kes.NewError(http.StatusBadRequest, "invalid argument: seconds must be between 1 and "+strconv.Itoa(int(MaxProfileDuration.Seconds())))
+			}
+			duration = time.Duration(seconds) * time.Second
+		}
+
+		// The profile is written to w by a separate goroutine
+		// once profiling has started. Hence, the response headers
+		// are sent by the first write and not before profiling
+		// has started successfully.
+		pw := &profileWriter{ResponseWriter: w, name: name}
+		stop := pprof.StopCPUProfile
+		if name == "profile" {
+			if err := pprof.StartCPUProfile(pw); err != nil {
+				return kes.NewError(http.StatusConflict, "CPU profile is already in progress")
+			}
+		} else {
+			if err := trace.Start(pw); err != nil {
+				return kes.NewError(http.StatusConflict, "trace is already in progress")
+			}
+			stop = trace.Stop
+		}
+
+		timer := time.NewTimer(duration)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+		}
+		stop() // Waits until the profile has been written
+		if !pw.written {
+			pw.writeHeader()
+		}
+		return nil
+	default:
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			return kes.NewError(http.StatusNotFound, "profile '"+name+"' not found")
+		}
+
+		var debug int
+		if s := r.URL.Query().Get("debug"); s != "" {
+			var err error
+			if debug, err = strconv.Atoi(s); err != nil || debug < 0 || debug > 2 {
+				return kes.NewError(http.StatusBadRequest, "invalid argument: debug must be 0, 1 or 2")
+			}
+		}
+		if name == "heap" && r.URL.Query().Get("gc") != "" {
+			runtime.GC()
+		}
+
+		if debug > 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			profile.WriteTo(w, debug)
+			return nil
+		}
+		pw := &profileWriter{ResponseWriter: w, name: name}
+		pw.writeHeader()
+		profile.WriteTo(pw, debug)
+		return nil
+	}
+}
+
+// profileWriter is an http.ResponseWriter that sends
+// the response headers of a binary profile on the first
+// write.
+type profileWriter struct {
+	http.ResponseWriter
+	name    string // The profile name, used as file name
+	written bool
+}
+
+func (w *profileWriter) Write(p []byte) (int, error) {
+	if !w.written {
+		w.writeHeader()
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *profileWriter) writeHeader() {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+w.name+`"`)
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+	w.written = true
+}
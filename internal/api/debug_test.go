@@ -0,0 +1,128 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kes/internal/sys"
+)
+
+func TestDebugProfile(t *testing.T) {
+	const EnclaveAdmin kes.Identity = "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	ctx := context.Background()
+
+	_, admin, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, oncall, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, identityOf(admin))
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	vault := sys.NewVault(sys.NewVaultFS(t.TempDir(), rootKey))
+	if _, err = vault.CreateEnclave(ctx, sys.DefaultEnclaveName, EnclaveAdmin, nil); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, sys.DefaultEnclaveName)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.SetPolicy(ctx, "my-oncall", auth.Policy{Allow: []string{"/v1/debug/pprof/*"}}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	if err = enclave.AssignPolicy(ctx, "my-oncall", identityOf(oncall)); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+
+	api := debugProfile(&RouterConfig{
+		Vault:    vault,
+		Metrics:  metric.New(),
+		AuditLog: log.New(io.Discard, "", 0),
+	})
+	profile := func(priv ed25519.PrivateKey) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/debug/pprof/goroutine?debug=1", nil)
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{selfSigned(t, priv)}}
+		resp := httptest.NewRecorder()
+		api.Handler.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// Heap profiles may contain key material of any enclave.
+	// Hence, a policy of the default enclave must not grant
+	// access to profiles.
+	if status := profile(oncall); status != http.StatusForbidden {
+		t.Fatalf("Profile of policy identity: got status '%d' - want '%d'", status, http.StatusForbidden)
+	}
+	if status := profile(admin); status != http.StatusOK {
+		t.Fatalf("Profile of system admin: got status '%d' - want '%d'", status, http.StatusOK)
+	}
+}
+
+func TestServeProfile(t *testing.T) {
+	for i, test := range serveProfileTests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/debug/pprof/"+test.Name+test.Query, nil)
+		err := serveProfile(w, r, test.Name)
+		if test.Status != http.StatusOK {
+			if err == nil {
+				t.Fatalf("Test %d: should have failed", i)
+			}
+			if status := statusCode(err); status != test.Status {
+				t.Fatalf("Test %d: got status '%d' - want '%d'", i, status, test.Status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: failed to serve profile: %v", i, err)
+		}
+		if w.Code != http.StatusOK {
+			t.Fatalf("Test %d: got status '%d' - want '%d'", i, w.Code, http.StatusOK)
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, test.ContentType) {
+			t.Fatalf("Test %d: got content type '%s' - want '%s'", i, contentType, test.ContentType)
+		}
+		if w.Body.Len() == 0 {
+			t.Fatalf("Test %d: profile is empty", i)
+		}
+	}
+}
+
+var serveProfileTests = []struct {
+	Name        string
+	Query       string
+	Status      int
+	ContentType string
+}{
+	{Name: "heap", Status: http.StatusOK, ContentType: "application/octet-stream"},                       // 0
+	{Name: "heap", Query: "?gc=1", Status: http.StatusOK, ContentType: "application/octet-stream"},       // 1
+	{Name: "goroutine", Query: "?debug=2", Status: http.StatusOK, ContentType: "text/plain"},             // 2
+	{Name: "trace", Query: "?seconds=1", Status: http.StatusOK, ContentType: "application/octet-stream"}, // 3
+	{Name: "goroutine", Query: "?debug=3", Status: http.StatusBadRequest},                                // 4
+	{Name: "profile", Query: "?seconds=0", Status: http.StatusBadRequest},                                // 5
+	{Name: "profile", Query: "?seconds=301", Status: http.StatusBadRequest},                              // 6
+	{Name: "trace", Query: "?seconds=1s", Status: http.StatusBadRequest},                                 // 7
+	{Name: "unknown", Status: http.StatusNotFound},                                                       // 8
+	{Name: "", Status: http.StatusNotFound},                                                              // 9
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+)
+
+// Legacy reports whether the API is deprecated or
+// scheduled for removal.
+func (a API) Legacy() bool { return !a.Deprecated.IsZero() || !a.Sunset.IsZero() }
+
+// deprecate sets the deprecation of the API according
+// to the given configuration, if any.
+func deprecate(a *API, config Config) {
+	if !config.Deprecated.IsZero() {
+		a.Deprecated = config.Deprecated
+	}
+	if !config.Sunset.IsZero() {
+		a.Sunset = config.Sunset
+	}
+	if config.Successor != "" {
+		a.Successor = config.Successor
+	}
+}
+
+// trackUsage returns a handler that counts the requests to
+// the API per API version and invokes h.
+//
+// If the API is deprecated or scheduled for removal, it sets
+// the Deprecation (RFC 9745), Sunset (RFC 8594) and successor
+// Link response headers and logs a warning the first time an
+// identity calls the API. Hence, operators can identify the
+// clients that must be upgraded before the API gets removed.
+func trackUsage(metrics *metric.Metrics, logger *log.Logger, a API, h http.Handler) http.Handler {
+	version := apiVersion(a.Path)
+	if !a.Legacy() {
+		if metrics == nil {
+			return h
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			metrics.CountAPI(a.Path, version, false)
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	var (
+		lock    sync.Mutex
+		callers = map[kes.Identity]struct{}{}
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if metrics != nil {
+			metrics.CountAPI(a.Path, version, true)
+		}
+		if !a.Deprecated.IsZero() {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(a.Deprecated.Unix(), 10))
+		}
+		if !a.Sunset.IsZero() {
+			w.Header().Set("Sunset", a.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if a.Successor != "" {
+			w.Header().Add("Link", "<"+a.Successor+">; rel=\"successor-version\"")
+		}
+
+		if logger != nil && logger.Enabled(log.LevelWarn) {
+			identity := auth.Identify(r)
+
+			// Once the set of known callers is full, no further
+			// warnings are logged to bound memory and log volume.
+			lock.Lock()
+			_, seen := callers[identity]
+			if !seen {
+				if seen = len(callers) >= metric.MaxIdentities; !seen {
+					callers[identity] = struct{}{}
+				}
+			}
+			lock.Unlock()
+
+			if !seen {
+				var note string
+				if !a.Sunset.IsZero() {
+					note += " - removed after " + a.Sunset.UTC().Format(http.TimeFormat)
+				}
+				if a.Successor != "" {
+					note += " - use " + a.Successor + " instead"
+				}
+				fields := log.Fields{Component: "api", Identity: identity.String()}
+				logger.Logf(log.LevelWarn, fields, "%s %s: deprecated API called%s", a.Method, a.Path, note)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// apiVersion returns the API version of the given API
+// path, e.g. "v1" for "/v1/key/create/", or "none" if
+// the path is not versioned.
+func apiVersion(path string) string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if len(version) < 2 || version[0] != 'v' {
+		return "none"
+	}
+	if _, err := strconv.ParseUint(version[1:], 10, 32); err != nil {
+		return "none"
+	}
+	return version
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/metric"
+)
+
+var apiVersionTests = []struct {
+	Path    string
+	Version string
+}{
+	{Path: "/v1/key/create/", Version: "v1"}, // 0
+	{Path: "/v2/key/create/", Version: "v2"}, // 1
+	{Path: "/v1/status", Version: "v1"},      // 2
+	{Path: "/version", Version: "none"},      // 3
+	{Path: "/ui/", Version: "none"},          // 4
+	{Path: "/v/key", Version: "none"},        // 5
+}
+
+func TestAPIVersion(t *testing.T) {
+	for i, test := range apiVersionTests {
+		if version := apiVersion(test.Path); version != test.Version {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, version, test.Version)
+		}
+	}
+}
+
+func TestTrackUsage(t *testing.T) {
+	const (
+		Deprecation = "@1685577600"
+		Sunset      = "Mon, 01 Jan 2024 00:00:00 GMT"
+		Link        = `</v1/key/decrypt/>; rel="successor-version"`
+	)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	a := API{Method: http.MethodPost, Path: "/v1/key/bulk/decrypt/"}
+	resp := httptest.NewRecorder()
+	trackUsage(metric.New(), nil, a, ok).ServeHTTP(resp, httptest.NewRequest(a.Method, a.Path, nil))
+	if h := resp.Header().Get("Deprecation"); h != "" {
+		t.Fatalf("API is not deprecated but response contains Deprecation header '%s'", h)
+	}
+
+	deprecate(&a, Config{
+		Deprecated: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Successor:  "/v1/key/decrypt/",
+	})
+	resp = httptest.NewRecorder()
+	trackUsage(metric.New(), nil, a, ok).ServeHTTP(resp, httptest.NewRequest(a.Method, a.Path, nil))
+	if h := resp.Header().Get("Deprecation"); h != Deprecation {
+		t.Fatalf("Invalid Deprecation header: got '%s' - want '%s'", h, Deprecation)
+	}
+	if h := resp.Header().Get("Sunset"); h != Sunset {
+		t.Fatalf("Invalid Sunset header: got '%s' - want '%s'", h, Sunset)
+	}
+	if h := resp.Header().Get("Link"); h != Link {
+		t.Fatalf("Invalid Link header: got '%s' - want '%s'", h, Link)
+	}
+}
@@ -13,7 +13,7 @@ import (
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
-	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/internal/key"
 )
 
 func createEnclave(config *RouterConfig) API {
@@ -25,41 +25,40 @@ func createEnclave(config *RouterConfig) API {
 		Verify  = true
 	)
 	type Request struct {
-		Admin kes.Identity `json:"admin"`
+		Admin     kes.Identity         `json:"admin"`
+		KeyPolicy *key.AlgorithmPolicy `json:"key_policy"` // optional
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
+		if err = verifyEnclaveName(name); err != nil {
+			return err
+		}
 
-		if err = Sync(config.Vault.Locker(), func() error {
-			sysAdmin, err := config.Vault.Admin(r.Context())
-			if err != nil {
-				return err
-			}
-			if identity := auth.Identify(r); identity != sysAdmin {
-				return kes.ErrNotAllowed
-			}
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
 
-			var req Request
-			if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-				return err
-			}
-			if err = verifyName(req.Admin.String()); err != nil {
-				return err
-			}
-			if req.Admin.IsUnknown() {
-				return kes.NewError(http.StatusBadRequest, "identity is unknown")
-			}
-			if req.Admin == sysAdmin {
-				return kes.NewError(http.StatusBadRequest, "admin identity cannot be system admin")
-			}
-			if _, err = config.Vault.CreateEnclave(r.Context(), name, req.Admin); err != nil {
-				return err
-			}
-			return nil
-		}); err != nil {
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		if err = verifyName(req.Admin.String()); err != nil {
+			return err
+		}
+		if req.Admin.IsUnknown() {
+			return kes.NewError(http.StatusBadRequest, "identity is unknown")
+		}
+		if req.Admin == sysAdmin {
+			return kes.NewError(http.StatusBadRequest, "admin identity cannot be system admin")
+		}
+		if _, err = config.Vault.CreateEnclave(r.Context(), name, req.Admin, req.KeyPolicy); err != nil {
 			return err
 		}
 
@@ -72,7 +71,8 @@ func createEnclave(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(sysAdminVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -86,9 +86,11 @@ func describeEnclave(config *RouterConfig) API {
 		ContentType = "application/json"
 	)
 	type Response struct {
-		Name      string       `json:"name"`
-		CreatedAt time.Time    `json:"created_at"`
-		CreatedBy kes.Identity `json:"created_by"`
+		Name      string               `json:"name"`
+		Admin     kes.Identity         `json:"admin"`
+		CreatedAt time.Time            `json:"created_at"`
+		CreatedBy kes.Identity         `json:"created_by"`
+		KeyPolicy *key.AlgorithmPolicy `json:"key_policy,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -96,16 +98,23 @@ func describeEnclave(config *RouterConfig) API {
 			return err
 		}
 
-		info, err := VSync(config.Vault.RLocker(), func() (sys.EnclaveInfo, error) {
-			sysAdmin, err := config.Vault.Admin(r.Context())
-			if err != nil {
-				return sys.EnclaveInfo{}, err
-			}
-			if identity := auth.Identify(r); identity != sysAdmin {
-				return sys.EnclaveInfo{}, kes.ErrNotAllowed
-			}
-			return config.Vault.GetEnclaveInfo(r.Context(), name)
-		})
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+		info, err := config.Vault.GetEnclaveInfo(r.Context(), name)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := config.Vault.GetEnclave(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		admin, err := enclave.Admin(r.Context())
 		if err != nil {
 			return err
 		}
@@ -114,18 +123,110 @@ func describeEnclave(config *RouterConfig) API {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
 			Name:      info.Name,
+			Admin:     admin,
 			CreatedAt: info.CreatedAt,
 			CreatedBy: info.CreatedBy,
+			KeyPolicy: info.KeyPolicy,
 		})
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func listEnclave(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/enclave/list/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/x-ndjson"
+	)
+	type Response struct {
+		Name      string       `json:"name,omitempty"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+
+		Err string `json:"error,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		pattern, err := patternFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		hasWritten, err := func() (bool, error) {
+			iterator, err := config.Vault.ListEnclaves(r.Context())
+			if err != nil {
+				return false, err
+			}
+			defer iterator.Close()
+
+			var hasWritten bool
+			encoder := json.NewEncoder(w)
+			for iterator.Next() {
+				if !matchPattern(pattern, iterator.Name()) || iterator.Name() == "" {
+					continue
+				}
+				info, err := config.Vault.GetEnclaveInfo(r.Context(), iterator.Name())
+				if err != nil {
+					return hasWritten, err
+				}
+				if !hasWritten {
+					hasWritten = true
+					w.Header().Set("Content-Type", ContentType)
+					w.WriteHeader(http.StatusOK)
+				}
+
+				err = encoder.Encode(Response{
+					Name:      info.Name,
+					CreatedAt: info.CreatedAt,
+					CreatedBy: info.CreatedBy,
+				})
+				if err != nil {
+					return hasWritten, err
+				}
+			}
+			return hasWritten, iterator.Close()
+		}()
+		if err != nil {
+			if hasWritten {
+				json.NewEncoder(w).Encode(Response{Err: err.Error()})
+				return nil
+			}
+			return err
+		}
+		if !hasWritten {
+			w.WriteHeader(http.StatusOK)
+		}
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -143,16 +244,14 @@ func deleteEnclave(config *RouterConfig) API {
 			return err
 		}
 
-		if err = Sync(config.Vault.Locker(), func() error {
-			sysAdmin, err := config.Vault.Admin(r.Context())
-			if err != nil {
-				return err
-			}
-			if identity := auth.Identify(r); identity != sysAdmin {
-				return kes.ErrNotAllowed
-			}
-			return config.Vault.DeleteEnclave(r.Context(), name)
-		}); err != nil {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+		if err = config.Vault.DeleteEnclave(r.Context(), name); err != nil {
 			return err
 		}
 
@@ -165,6 +264,6 @@ func deleteEnclave(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(sysAdminVerifier(config.Vault), handler)))),
 	}
 }
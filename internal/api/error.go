@@ -5,9 +5,10 @@
 package api
 
 import (
-	"fmt"
-	"io"
+	"encoding/json"
 	"net/http"
+
+	"github.com/minio/kes/kesclient"
 )
 
 // StatusCode is an interface implemented by types
@@ -18,22 +19,33 @@ type StatusCode interface {
 	Status() int
 }
 
+// errDeadlineExceeded is sent to clients when a request
+// does not complete within its API timeout, e.g. because
+// the key store does not respond in time.
+var errDeadlineExceeded = &kesclient.Error{
+	Code:    kesclient.CodeDeadlineExceeded,
+	Status:  http.StatusGatewayTimeout,
+	Message: "gateway timeout: request deadline exceeded",
+}
+
 // Fail sends an error response to the w.
 //
-// If error implements the StatusCode interface, Fail
-// sends the response with the returned status code.
+// If error implements the StatusCode interface or is
+// a *kesclient.Error, Fail sends the response with the
+// error's status code.
 // Otherwise, Fail sends a HTTP 500 status code
 // (internal server error).
 //
+// The response body contains the error message and
+// the error's kesclient.ErrorCode such that clients
+// can branch on the error without matching the message.
+//
 // If err is nil, Fail sends the HTTP 500 status code
 // and an empty response body.
 //
 // Fail returns an error if writing to w fails.
 func Fail(w http.ResponseWriter, err error) error {
-	status := http.StatusInternalServerError
-	if s, ok := err.(StatusCode); ok {
-		status = s.Status()
-	}
+	status := statusCode(err)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -46,14 +58,29 @@ func Fail(w http.ResponseWriter, err error) error {
 	}
 	w.WriteHeader(status)
 
-	const (
-		emptyMsg = `{}`
-		format   = `{"message":"%v"}`
-	)
 	if err == nil {
-		_, err = io.WriteString(w, emptyMsg)
-	} else {
-		_, err = io.WriteString(w, fmt.Sprintf(format, err))
+		_, err = w.Write([]byte(`{}`))
+		return err
+	}
+
+	type Response struct {
+		Message string              `json:"message"`
+		Code    kesclient.ErrorCode `json:"code"`
+	}
+	return json.NewEncoder(w).Encode(Response{
+		Message: err.Error(),
+		Code:    kesclient.Code(err),
+	})
+}
+
+// statusCode returns the HTTP status code
+// Fail sends for the given error.
+func statusCode(err error) int {
+	if s, ok := err.(StatusCode); ok {
+		return s.Status()
+	}
+	if e, ok := err.(*kesclient.Error); ok {
+		return e.Status
 	}
-	return err
+	return http.StatusInternalServerError
 }
@@ -0,0 +1,118 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/kesclient"
+)
+
+var errPreconditionFailed = &kesclient.Error{
+	Code:    kesclient.CodePreconditionFailed,
+	Status:  http.StatusPreconditionFailed,
+	Message: "precondition failed: resource has been modified",
+}
+
+// policyETag returns the strong entity tag of the policy.
+// It changes whenever the policy gets written.
+func policyETag(policy auth.Policy) string {
+	type ETag struct {
+		Allow     []string     `json:"allow"`
+		Deny      []string     `json:"deny"`
+		CreatedAt time.Time    `json:"created_at"`
+		CreatedBy kes.Identity `json:"created_by"`
+
+		Certificates   map[string]auth.CertificateProfile `json:"certificates,omitempty"`
+		MaxRandomBytes int                                `json:"max_random_bytes,omitempty"`
+		Tokens         *auth.TokenProfile                 `json:"tokens,omitempty"`
+		MaxSSHTTL      time.Duration                      `json:"max_ssh_ttl,omitempty"`
+	}
+	b, _ := json.Marshal(ETag{
+		Allow:     policy.Allow,
+		Deny:      policy.Deny,
+		CreatedAt: policy.CreatedAt.UTC(),
+		CreatedBy: policy.CreatedBy,
+
+		Certificates:   policy.Certificates,
+		MaxRandomBytes: policy.MaxRandomBytes,
+		Tokens:         policy.Tokens,
+		MaxSSHTTL:      policy.MaxSSHTTL,
+	})
+	return etag(b)
+}
+
+// keyETag returns the strong entity tag of the key's metadata.
+func keyETag(k key.Key) string {
+	type ETag struct {
+		ID        string       `json:"id"`
+		Algorithm string       `json:"algorithm"`
+		CreatedAt time.Time    `json:"created_at"`
+		CreatedBy kes.Identity `json:"created_by"`
+	}
+	b, _ := json.Marshal(ETag{
+		ID:        k.ID(),
+		Algorithm: key.AlgorithmName(k.Algorithm()),
+		CreatedAt: k.CreatedAt().UTC(),
+		CreatedBy: k.CreatedBy(),
+	})
+	return etag(b)
+}
+
+func etag(b []byte) string {
+	h := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// checkPreconditions evaluates the If-Match and If-None-Match
+// request headers against the entity tag of the current resource,
+// as described by RFC 9110. If the resource does not exist, exists
+// is false and the entity tag is ignored.
+//
+// It returns a precondition failed error if the request should
+// not be processed.
+func checkPreconditions(r *http.Request, etag string, exists bool) error {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !exists || !matchETag(ifMatch, etag, false) {
+			return errPreconditionFailed
+		}
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if exists && matchETag(ifNoneMatch, etag, true) {
+			return errPreconditionFailed
+		}
+	}
+	return nil
+}
+
+// matchETag reports whether the etag matches any entity tag of
+// the comma-separated list. A '*' matches any entity tag. Weak
+// entity tags only match if weak comparison is used.
+func matchETag(list, etag string, weak bool) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
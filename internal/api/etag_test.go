@@ -0,0 +1,52 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	const ETag = `"0123456789abcdef"`
+	for i, test := range checkPreconditionsTests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/policy/write/my-policy", nil)
+		if test.IfMatch != "" {
+			req.Header.Set("If-Match", test.IfMatch)
+		}
+		if test.IfNoneMatch != "" {
+			req.Header.Set("If-None-Match", test.IfNoneMatch)
+		}
+
+		err := checkPreconditions(req, ETag, test.Exists)
+		if failed := errors.Is(err, errPreconditionFailed); failed != test.Failed {
+			t.Fatalf("Test %d: got precondition failed '%v' - want '%v'", i, failed, test.Failed)
+		}
+	}
+}
+
+var checkPreconditionsTests = []struct {
+	IfMatch     string
+	IfNoneMatch string
+	Exists      bool
+	Failed      bool
+}{
+	{Exists: true},  // 0
+	{Exists: false}, // 1
+	{IfMatch: `"0123456789abcdef"`, Exists: true},                                 // 2
+	{IfMatch: `"0123456789abcdef"`, Exists: false, Failed: true},                  // 3
+	{IfMatch: `"fedcba9876543210"`, Exists: true, Failed: true},                   // 4
+	{IfMatch: `"fedcba9876543210", "0123456789abcdef"`, Exists: true},             // 5
+	{IfMatch: `W/"0123456789abcdef"`, Exists: true, Failed: true},                 // 6 - weak tags never match If-Match
+	{IfMatch: `*`, Exists: true},                                                  // 7
+	{IfMatch: `*`, Exists: false, Failed: true},                                   // 8
+	{IfNoneMatch: `*`, Exists: false},                                             // 9
+	{IfNoneMatch: `*`, Exists: true, Failed: true},                                // 10
+	{IfNoneMatch: `W/"0123456789abcdef"`, Exists: true, Failed: true},             // 11
+	{IfNoneMatch: `"fedcba9876543210"`, Exists: true},                             // 12
+	{IfMatch: `"0123456789abcdef"`, IfNoneMatch: `*`, Exists: true, Failed: true}, // 13
+}
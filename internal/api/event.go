@@ -0,0 +1,272 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/sys"
+)
+
+// An EventType describes the kind of configuration
+// change an Event reports.
+type EventType string
+
+// Configuration change event types.
+const (
+	EventKeyCreated         EventType = "key.created"
+	EventKeyDeleted         EventType = "key.deleted"
+	EventKeyHeld            EventType = "key.held"
+	EventKeyReleased        EventType = "key.released"
+	EventKeyDisabled        EventType = "key.disabled"
+	EventKeyEnabled         EventType = "key.enabled"
+	EventKeyAllowlistSet    EventType = "key.allowlist.set"
+	EventKeyTokenSigningSet EventType = "key.token_signing.set"
+	EventPolicyWritten      EventType = "policy.written"
+	EventPolicyDeleted      EventType = "policy.deleted"
+	EventIdentityAssigned   EventType = "identity.assigned"
+	EventIdentityDeleted    EventType = "identity.deleted"
+	EventAccessRequested    EventType = "access.requested"
+	EventAccessApproved     EventType = "access.approved"
+	EventAccessDenied       EventType = "access.denied"
+	EventConfigReloaded     EventType = "config.reloaded"
+	EventConfigReloadFailed EventType = "config.reload_failed"
+)
+
+// An Event reports a change of a key, policy or identity.
+type Event struct {
+	ID       uint64       `json:"id"`
+	Type     EventType    `json:"type"`
+	Name     string       `json:"name"`
+	Enclave  string       `json:"enclave"`
+	Identity kes.Identity `json:"identity"`
+	Time     time.Time    `json:"time"`
+}
+
+// NewEventStream returns a new EventStream without subscribers.
+func NewEventStream() *EventStream {
+	return &EventStream{
+		subscribers: map[*eventSubscriber]struct{}{},
+	}
+}
+
+// An EventStream broadcasts configuration change events
+// to its subscribers.
+//
+// Subscribers only receive events of the enclave they
+// subscribed to. A subscriber that does not keep up with
+// the event rate gets unsubscribed, such that it notices
+// the gap and can resynchronize, instead of missing events
+// silently.
+type EventStream struct {
+	lock        sync.Mutex
+	seq         uint64
+	subscribers map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	enclave string
+	events  chan Event
+}
+
+// Publish sends a new event of the given type for the named
+// key, policy or identity to all subscribers of the request's
+// enclave. The event is attributed to the request identity.
+//
+// If s is nil, Publish does nothing.
+func (s *EventStream) Publish(r *http.Request, typ EventType, name string) {
+	s.publish(enclaveName(r), auth.Identify(r), typ, name)
+}
+
+// PublishConfig sends a new event of the given type for the
+// named policy or identity to all subscribers of the enclave.
+// In contrast to Publish, the event is not attributed to any
+// identity since it is caused by a change of the server config
+// or by an identity provider via SCIM.
+//
+// If s is nil, PublishConfig does nothing.
+func (s *EventStream) PublishConfig(enclave string, typ EventType, name string) {
+	s.publish(enclave, "", typ, name)
+}
+
+func (s *EventStream) publish(enclave string, identity kes.Identity, typ EventType, name string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.seq++
+	event := Event{
+		ID:       s.seq,
+		Type:     typ,
+		Name:     name,
+		Enclave:  enclave,
+		Identity: identity,
+		Time:     time.Now().UTC(),
+	}
+	for sub := range s.subscribers {
+		if sub.enclave != event.Enclave {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe returns a channel that receives all subsequent
+// events of the given enclave and a function that cancels the
+// subscription. The channel gets closed when the subscription
+// is canceled or the subscriber falls behind.
+func (s *EventStream) Subscribe(enclave string) (<-chan Event, func()) {
+	const BufferSize = 256
+
+	sub := &eventSubscriber{
+		enclave: enclave,
+		events:  make(chan Event, BufferSize),
+	}
+	s.lock.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.lock.Unlock()
+
+	return sub.events, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if _, ok := s.subscribers[sub]; ok {
+			delete(s.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// enclaveName returns the enclave name specified by the request
+// URL or the default enclave name if the request does not
+// specify an enclave.
+func enclaveName(r *http.Request) string {
+	if name := r.URL.Query().Get("enclave"); name != "" {
+		return name
+	}
+	return sys.DefaultEnclaveName
+}
+
+// serveEvents streams the events of the request's enclave to
+// the client as server-sent events until the client closes
+// the connection or falls behind.
+func serveEvents(w http.ResponseWriter, r *http.Request, stream *EventStream) {
+	const (
+		ContentType       = "text/event-stream"
+		KeepAliveInterval = 30 * time.Second
+	)
+	if stream == nil {
+		Fail(w, kes.NewError(http.StatusNotImplemented, "not implemented"))
+		return
+	}
+	events, cancel := stream.Subscribe(enclaveName(r))
+	defer cancel()
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	out := https.FlushOnWrite(w)
+	io.WriteString(out, ": connected\n\n")
+
+	ticker := time.NewTicker(KeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return // Client is too slow. It has to reconnect
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if _, err = fmt.Fprintf(out, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(out, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func events(config *RouterConfig) API {
+	const (
+		Method  = http.MethodGet
+		APIPath = "/v1/events"
+		MaxBody = 0
+		Timeout = 0 * time.Second // No timeout
+		Verify  = true
+	)
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
+			Fail(w, err)
+			return
+		}
+		serveEvents(w, r, config.Events)
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "text/event-stream",
+		Response:    Event{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
+	}
+}
+
+func edgeEvents(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/v1/events"
+		MaxBody int64
+		Timeout = 0 * time.Second // No timeout
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			Fail(w, err)
+			return
+		}
+		serveEvents(w, r, config.Events)
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "text/event-stream",
+		Response:    Event{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
+	}
+}
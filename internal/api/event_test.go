@@ -0,0 +1,53 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStream(t *testing.T) {
+	stream := NewEventStream()
+
+	events, cancel := stream.Subscribe("default")
+	defer cancel()
+	tenant, cancelTenant := stream.Subscribe("tenant-1")
+	defer cancelTenant()
+
+	stream.Publish(httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil), EventKeyCreated, "my-key")
+	stream.Publish(httptest.NewRequest(http.MethodDelete, "/v1/policy/delete/my-policy?enclave=tenant-1", nil), EventPolicyDeleted, "my-policy")
+
+	if event := <-events; event.Type != EventKeyCreated || event.Name != "my-key" || event.Enclave != "default" {
+		t.Fatalf("Test 0: got event '%+v' - want '%s' for 'my-key' in enclave 'default'", event, EventKeyCreated)
+	}
+	if event := <-tenant; event.Type != EventPolicyDeleted || event.Name != "my-policy" || event.Enclave != "tenant-1" {
+		t.Fatalf("Test 1: got event '%+v' - want '%s' for 'my-policy' in enclave 'tenant-1'", event, EventPolicyDeleted)
+	}
+	if n := len(events); n != 0 {
+		t.Fatalf("Test 2: subscriber received %d events of another enclave", n)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("Test 3: event channel is not closed after canceling the subscription")
+	}
+	cancel() // Canceling twice must not panic
+}
+
+func TestEventStreamSlowSubscriber(t *testing.T) {
+	stream := NewEventStream()
+
+	events, cancel := stream.Subscribe("default")
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil)
+	for i := 0; i <= cap(events); i++ {
+		stream.Publish(req, EventKeyCreated, "my-key")
+	}
+	for range events { // Channel must get closed once the subscriber falls behind
+	}
+}
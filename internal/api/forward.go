@@ -0,0 +1,181 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// HeaderForwarded is the HTTP request header set by a
+// follower when forwarding a request to the leader.
+const HeaderForwarded = "Kes-Forwarded"
+
+// DefaultForwardCertHeader is the HTTP request header that
+// carries the client certificate of forwarded requests if
+// no other header is specified.
+const DefaultForwardCertHeader = "X-Tls-Client-Certificate"
+
+var errForwardLoop = kes.NewError(http.StatusLoopDetected, "forwarding loop: request has already been forwarded by another node")
+
+// writeAPIs are the API paths of requests that
+// modify the server state and, therefore, are
+// forwarded to the cluster leader.
+var writeAPIs = []string{
+	"/v1/key/create/",
+	"/v1/key/import/",
+	"/v1/key/delete/",
+	"/v1/key/delete-bulk/",
+	"/v1/key/hold/",
+	"/v1/key/release/",
+	"/v1/key/disable/",
+	"/v1/key/enable/",
+	"/v1/key/allowlist/",
+	"/v1/token/designate/",
+	"/v1/secret/create/",
+	"/v1/secret/update/",
+	"/v1/secret/rotation/",
+	"/v1/secret/rotate/",
+	"/v1/secret/delete/",
+	"/v1/tokenize/",
+	"/v1/policy/write/",
+	"/v1/policy/delete/",
+	"/v1/policy/assign/",
+	"/v1/identity/delete/",
+	"/v1/identity/self/csr",
+	"/v1/ca/issue",
+	"/v1/ca/revoke/",
+	"/v1/access/request/",
+	"/v1/access/approve/",
+	"/v1/access/deny/",
+	"/v1/access/list", // Pending access requests only exist on the leader
+	"/v1/enclave/create/",
+	"/v1/enclave/delete/",
+}
+
+// NewForwarder returns a new Forwarder that forwards requests
+// to the leader endpoint using the given transport. The transport
+// has to authenticate as the follower, usually with the follower's
+// TLS certificate.
+//
+// The client certificate of a forwarded request is sent to
+// the leader within certHeader. If certHeader is empty, it
+// defaults to DefaultForwardCertHeader.
+func NewForwarder(leader string, transport http.RoundTripper, certHeader string) (*Forwarder, error) {
+	endpoint, err := url.Parse(leader)
+	if err != nil {
+		return nil, fmt.Errorf("api: invalid leader endpoint '%s': %v", leader, err)
+	}
+	if endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("api: invalid leader endpoint '%s': must be an https URL", leader)
+	}
+	if certHeader == "" {
+		certHeader = DefaultForwardCertHeader
+	}
+
+	f := &Forwarder{
+		leader:     endpoint,
+		certHeader: http.CanonicalHeaderKey(certHeader),
+	}
+	f.proxy = &httputil.ReverseProxy{
+		Director:       f.direct,
+		Transport:      transport,
+		ModifyResponse: verifyWireVersion,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if _, ok := err.(kes.Error); ok {
+				Fail(w, err)
+				return
+			}
+			Fail(w, kes.NewError(http.StatusBadGateway, "bad gateway: failed to forward request to cluster leader"))
+		},
+	}
+	return f, nil
+}
+
+// A Forwarder forwards write requests received by a
+// follower to the leader of a KES cluster.
+//
+// The leader has to accept the follower as TLS proxy.
+// The Forwarder sends the certificate of the client
+// that made the request to the leader such that the
+// leader authenticates and authorizes the client, not
+// the follower.
+type Forwarder struct {
+	leader     *url.URL
+	certHeader string
+	proxy      *httputil.ReverseProxy
+}
+
+// Leader returns the leader endpoint.
+func (f *Forwarder) Leader() string { return f.leader.String() }
+
+// ServeHTTP forwards the request to the leader
+// and writes the leader's response to w.
+//
+// It rejects requests that have been forwarded by
+// another node already to prevent forwarding loops.
+func (f *Forwarder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(HeaderForwarded) != "" {
+		Fail(w, errForwardLoop)
+		return
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		Fail(w, kes.NewError(http.StatusBadRequest, "no client certificate is present"))
+		return
+	}
+	f.proxy.ServeHTTP(w, r)
+}
+
+// direct rewrites the request such that it
+// gets sent to the leader on behalf of the
+// client.
+func (f *Forwarder) direct(r *http.Request) {
+	r.URL.Scheme = f.leader.Scheme
+	r.URL.Host = f.leader.Host
+	r.Host = ""
+
+	setClientCert(r.Header, f.certHeader, r.TLS.PeerCertificates[0])
+	r.Header.Set(HeaderForwarded, "true")
+	r.Header.Set(HeaderWireVersion, wireVersions())
+}
+
+// setClientCert sets the header field to the PEM-encoded
+// and URL-escaped client certificate, as expected by a KES
+// server that accepts the sender as TLS proxy.
+func setClientCert(header http.Header, field string, cert *x509.Certificate) {
+	block := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Raw,
+	})
+	header.Set(field, url.QueryEscape(string(block)))
+}
+
+// forwardWrites replaces the handlers of all write APIs
+// with a handler that forwards requests to the leader.
+func forwardWrites(config *RouterConfig, apis []API) {
+	for i, a := range apis {
+		if isWriteAPI(a.Path) {
+			apis[i].Handler = config.Metrics.Count(config.Metrics.Latency(config.Forwarder))
+		}
+	}
+}
+
+// isWriteAPI reports whether the API
+// path belongs to a write API.
+func isWriteAPI(path string) bool {
+	for _, p := range writeAPIs {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
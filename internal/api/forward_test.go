@@ -0,0 +1,113 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestForwarder(t *testing.T) {
+	leader := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderForwarded) == "" {
+			t.Errorf("Forwarded request does not contain the '%s' header", HeaderForwarded)
+		}
+		cert, err := url.QueryUnescape(r.Header.Get(DefaultForwardCertHeader))
+		if err != nil {
+			t.Errorf("Invalid client certificate header: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, r.URL.Path+"\n"+cert)
+	}))
+	defer leader.Close()
+
+	forwarder, err := NewForwarder(leader.URL, leader.Client().Transport, "")
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leader.Certificate()},
+	}
+	resp := httptest.NewRecorder()
+	forwarder.ServeHTTP(resp, req)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("Invalid response status: got '%d' - want '%d'", resp.Code, http.StatusCreated)
+	}
+	const Prefix = "/v1/key/create/my-key\n-----BEGIN CERTIFICATE-----"
+	if body := resp.Body.String(); len(body) < len(Prefix) || body[:len(Prefix)] != Prefix {
+		t.Fatalf("Invalid response body: got '%s'", body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leader.Certificate()},
+	}
+	req.Header.Set(HeaderForwarded, "true")
+	resp = httptest.NewRecorder()
+	forwarder.ServeHTTP(resp, req)
+	if resp.Code != http.StatusLoopDetected {
+		t.Fatalf("Forwarding loop: got '%d' - want '%d'", resp.Code, http.StatusLoopDetected)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil)
+	resp = httptest.NewRecorder()
+	forwarder.ServeHTTP(resp, req)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("Request without client certificate: got '%d' - want '%d'", resp.Code, http.StatusBadRequest)
+	}
+}
+
+func TestNewForwarder(t *testing.T) {
+	for i, test := range newForwarderTests {
+		_, err := NewForwarder(test.Leader, http.DefaultTransport, "")
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to create forwarder: %v", i, err)
+		}
+	}
+}
+
+func TestIsWriteAPI(t *testing.T) {
+	for i, test := range isWriteAPITests {
+		if ok := isWriteAPI(test.Path); ok != test.Write {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, ok, test.Write)
+		}
+	}
+}
+
+var newForwarderTests = []struct {
+	Leader     string
+	ShouldFail bool
+}{
+	{Leader: "https://127.0.0.1:7373"},                  // 0
+	{Leader: "https://kes-0.local"},                     // 1
+	{Leader: "http://127.0.0.1:7373", ShouldFail: true}, // 2
+	{Leader: "https://", ShouldFail: true},              // 3
+	{Leader: "127.0.0.1:7373", ShouldFail: true},        // 4
+}
+
+var isWriteAPITests = []struct {
+	Path  string
+	Write bool
+}{
+	{Path: "/v1/key/create/*", Write: true},     // 0
+	{Path: "/v1/key/delete/*", Write: true},     // 1
+	{Path: "/v1/policy/write/*", Write: true},   // 2
+	{Path: "/v1/enclave/create/*", Write: true}, // 3
+	{Path: "/v1/key/encrypt/*"},                 // 4
+	{Path: "/v1/key/list/*"},                    // 5
+	{Path: "/v1/policy/describe/*"},             // 6
+	{Path: "/v1/status"},                        // 7
+	{Path: "/v1/key/hold/*", Write: true},       // 8
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/fpe"
+	"github.com/minio/kes/internal/key"
+)
+
+// fpeRadixAlphabet is the alphabet of radix-N strings
+// for a radix N <= 36 if no explicit alphabet is given.
+const fpeRadixAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// fpeParams are the parameters of a format-preserving
+// encryption or decryption request.
+type fpeParams struct {
+	Mode     string `json:"mode"`     // optional, FF1 by default
+	Alphabet string `json:"alphabet"` // optional
+	Radix    int    `json:"radix"`    // optional, 10 by default
+	Tweak    []byte `json:"tweak"`    // optional
+}
+
+func fpeEncryptKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/fpe/encrypt/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		fpeParams
+		Plaintext string `json:"plaintext"`
+	}
+	type Response struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		mode, alphabet, err := req.parse()
+		if err != nil {
+			return err
+		}
+
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+		ciphertext, err := fpeCrypt(&key, mode, alphabet, req.Tweak, req.Plaintext, false)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Ciphertext: ciphertext,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func fpeDecryptKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/fpe/decrypt/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		fpeParams
+		Ciphertext string `json:"ciphertext"`
+	}
+	type Response struct {
+		Plaintext string `json:"plaintext"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		mode, alphabet, err := req.parse()
+		if err != nil {
+			return err
+		}
+
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+		plaintext, err := fpeCrypt(&key, mode, alphabet, req.Tweak, req.Ciphertext, true)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Plaintext: plaintext,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// parse returns the FPE mode and alphabet of the request
+// parameters. An explicit alphabet takes precedence over
+// the radix.
+func (p *fpeParams) parse() (fpe.Mode, *fpe.Alphabet, error) {
+	var mode fpe.Mode
+	switch strings.ToUpper(p.Mode) {
+	case "", string(fpe.FF1):
+		mode = fpe.FF1
+	case string(fpe.FF31):
+		mode = fpe.FF31
+	default:
+		return "", nil, kes.NewError(http.StatusBadRequest, "unsupported FPE mode '"+p.Mode+"'")
+	}
+
+	alphabet := p.Alphabet
+	switch {
+	case alphabet != "" && p.Radix != 0 && p.Radix != len([]rune(alphabet)):
+		return "", nil, kes.NewError(http.StatusBadRequest, "radix does not match the alphabet")
+	case alphabet == "" && p.Radix == 0:
+		alphabet = fpe.Digits
+	case alphabet == "":
+		if p.Radix < 2 || p.Radix > len(fpeRadixAlphabet) {
+			return "", nil, kes.NewError(http.StatusBadRequest, "invalid radix: must be between 2 and 36 unless an alphabet is specified")
+		}
+		alphabet = fpeRadixAlphabet[:p.Radix]
+	}
+	a, err := fpe.NewAlphabet(alphabet)
+	if err != nil {
+		return "", nil, kes.NewError(http.StatusBadRequest, strings.TrimPrefix(err.Error(), "fpe: "))
+	}
+	return mode, a, nil
+}
+
+// fpeCrypt encrypts or decrypts the text with an FPE key
+// derived from the given key.
+func fpeCrypt(k *key.Key, mode fpe.Mode, alphabet *fpe.Alphabet, tweak []byte, text string, decrypt bool) (string, error) {
+	fpeKey, err := k.FPEKey()
+	if err != nil {
+		return "", err
+	}
+	var result string
+	if decrypt {
+		result, err = fpe.Decrypt(mode, fpeKey, alphabet, tweak, text)
+	} else {
+		result, err = fpe.Encrypt(mode, fpeKey, alphabet, tweak, text)
+	}
+	if err != nil {
+		return "", kes.NewError(http.StatusBadRequest, strings.TrimPrefix(err.Error(), "fpe: "))
+	}
+	return result, nil
+}
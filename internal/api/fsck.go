@@ -0,0 +1,95 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+func fsck(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/fsck"
+		MaxBody     = 0
+		Timeout     = 5 * time.Minute
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type CorruptEntry struct {
+		Enclave    string `json:"enclave"`
+		Kind       string `json:"kind"`
+		Name       string `json:"name"`
+		Path       string `json:"path"`
+		Err        string `json:"error"`
+		Quarantine string `json:"quarantine,omitempty"`
+	}
+	type Response struct {
+		Enclaves   int            `json:"enclaves"`
+		Keys       int            `json:"keys"`
+		Secrets    int            `json:"secrets"`
+		Policies   int            `json:"policies"`
+		Identities int            `json:"identities"`
+		Corrupted  []CorruptEntry `json:"corrupted,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if identity := auth.Identify(r); identity != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		var quarantine bool
+		if s := r.URL.Query().Get("quarantine"); s != "" {
+			if quarantine, err = strconv.ParseBool(s); err != nil {
+				return kes.NewError(http.StatusBadRequest, "invalid quarantine parameter '"+s+"'")
+			}
+		}
+		report, err := config.Vault.Check(r.Context(), quarantine)
+		if err != nil {
+			return err
+		}
+
+		response := Response{
+			Enclaves:   report.Enclaves,
+			Keys:       report.Keys,
+			Secrets:    report.Secrets,
+			Policies:   report.Policies,
+			Identities: report.Identities,
+		}
+		for _, entry := range report.Corrupted {
+			response.Corrupted = append(response.Corrupted, CorruptEntry{
+				Enclave:    entry.Enclave,
+				Kind:       entry.Kind,
+				Name:       entry.Name,
+				Path:       entry.Path,
+				Err:        entry.Err.Error(),
+				Quarantine: entry.Quarantine,
+			})
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
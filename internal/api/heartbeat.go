@@ -0,0 +1,200 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kms"
+	"github.com/minio/kes/kv"
+)
+
+// HeartbeatConfig is a structure containing the
+// configuration of a Heartbeat.
+type HeartbeatConfig struct {
+	// Endpoint is the URL that receives the heartbeats.
+	Endpoint string
+
+	// Node is the name of the server that is included
+	// in every heartbeat. For example, the hostname.
+	Node string
+
+	// StartTime is the time the server has been started.
+	// It is used to compute the server's uptime.
+	StartTime time.Time
+
+	// Client is the HTTP client used to send heartbeats.
+	// If nil, a client with a 15 second timeout is used.
+	Client *http.Client
+
+	// Interval is the time period between two heartbeats.
+	// If <= 0, defaults to 1 minute.
+	Interval time.Duration
+}
+
+// Heartbeat health states.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// Heartbeat periodically sends the health of a KES
+// edge server as JSON to an external monitoring
+// endpoint. It allows fleet monitoring to detect
+// servers that have stopped sending heartbeats or
+// whose backends have become unavailable.
+type Heartbeat struct {
+	config   *EdgeRouterConfig
+	endpoint string
+	node     string
+	start    time.Time
+	client   *http.Client
+
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// NewHeartbeat returns a new Heartbeat that reports the
+// health of the edge server with the given router config
+// in the background until the Heartbeat is closed.
+//
+// The first heartbeat is sent immediately.
+func NewHeartbeat(config *EdgeRouterConfig, hbConfig *HeartbeatConfig) *Heartbeat {
+	h := &Heartbeat{
+		config:   config,
+		endpoint: hbConfig.Endpoint,
+		node:     hbConfig.Node,
+		start:    hbConfig.StartTime,
+		client:   hbConfig.Client,
+	}
+	if h.client == nil {
+		h.client = &http.Client{Timeout: 15 * time.Second}
+	}
+	if h.start.IsZero() {
+		h.start = time.Now()
+	}
+	interval := hbConfig.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.stop = cancel
+	h.done.Add(1)
+	go func() {
+		defer h.done.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := h.Send(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("api: failed to send heartbeat to '%s': %v", h.endpoint, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return h
+}
+
+// Send checks the health of the edge server and sends
+// it to the heartbeat endpoint.
+func (h *Heartbeat) Send(ctx context.Context) error {
+	type Response struct {
+		Node    string        `json:"node,omitempty"`
+		Time    time.Time     `json:"time"`
+		Status  string        `json:"status"`
+		Version string        `json:"version"`
+		OS      string        `json:"os"`
+		Arch    string        `json:"arch"`
+		UpTime  time.Duration `json:"uptime"`
+
+		KeyStoreLatency     int64 `json:"keystore_latency,omitempty"`
+		KeyStoreUnavailable bool  `json:"keystore_unavailable,omitempty"`
+		KeyStoreUnreachable bool  `json:"keystore_unreachable,omitempty"`
+
+		KeyWrapperLatency     int64 `json:"keywrapper_latency,omitempty"`
+		KeyWrapperUnavailable bool  `json:"keywrapper_unavailable,omitempty"`
+		KeyWrapperUnreachable bool  `json:"keywrapper_unreachable,omitempty"`
+	}
+	response := Response{
+		Node:    h.node,
+		Time:    time.Now().UTC(),
+		Status:  HealthOK,
+		Version: sys.BinaryInfo().Version,
+		OS:      runtime.GOOS,
+		Arch:    runtime.GOARCH,
+		UpTime:  time.Since(h.start).Round(time.Second),
+	}
+
+	statusCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if state, err := h.config.Keys.Status(statusCtx); err != nil {
+		response.Status = HealthDegraded
+		response.KeyStoreUnavailable = true
+		_, response.KeyStoreUnreachable = kms.IsUnreachable(err)
+	} else {
+		response.KeyStoreLatency = latencyMillis(state.Latency)
+	}
+	if h.config.KeyWrapper != nil {
+		if state, err := h.config.KeyWrapper.Status(statusCtx); err != nil {
+			response.Status = HealthDegraded
+			response.KeyWrapperUnavailable = true
+			_, response.KeyWrapperUnreachable = kv.IsUnreachable(err)
+		} else {
+			response.KeyWrapperLatency = latencyMillis(state.Latency)
+		}
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("heartbeat endpoint responded with '%s'", resp.Status)
+	}
+	return nil
+}
+
+// Close stops sending heartbeats.
+func (h *Heartbeat) Close() error {
+	h.stop()
+	h.done.Wait()
+	return nil
+}
+
+// latencyMillis returns the latency in milliseconds. It
+// returns at least 1 such that an available backend
+// always reports a latency.
+func latencyMillis(latency time.Duration) int64 {
+	latency = latency.Round(time.Millisecond)
+	if latency == 0 {
+		latency = 1 * time.Millisecond
+	}
+	return latency.Milliseconds()
+}
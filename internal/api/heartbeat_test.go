@@ -0,0 +1,72 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestHeartbeat(t *testing.T) {
+	type Heartbeat struct {
+		Node            string `json:"node"`
+		Status          string `json:"status"`
+		Version         string `json:"version"`
+		KeyStoreLatency int64  `json:"keystore_latency"`
+	}
+	heartbeats := make(chan Heartbeat, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var heartbeat Heartbeat
+		if err := json.NewDecoder(r.Body).Decode(&heartbeat); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		select {
+		case heartbeats <- heartbeat:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	cache := key.NewCache(key.Store{Conn: &mem.Store{}}, &key.CacheConfig{})
+	defer cache.Stop()
+
+	heartbeat := NewHeartbeat(&EdgeRouterConfig{Keys: cache}, &HeartbeatConfig{
+		Endpoint: server.URL,
+		Node:     "kes-1",
+		Interval: time.Hour,
+	})
+	defer heartbeat.Close()
+
+	select {
+	case hb := <-heartbeats:
+		if hb.Node != "kes-1" {
+			t.Fatalf("Invalid heartbeat: got node '%s' - want '%s'", hb.Node, "kes-1")
+		}
+		if hb.Status != HealthOK {
+			t.Fatalf("Invalid heartbeat: got status '%s' - want '%s'", hb.Status, HealthOK)
+		}
+		if hb.KeyStoreLatency <= 0 {
+			t.Fatalf("Invalid heartbeat: got keystore latency '%d' - want > 0", hb.KeyStoreLatency)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No heartbeat received")
+	}
+
+	if err := heartbeat.Send(context.Background()); err != nil {
+		t.Fatalf("Failed to send heartbeat: %v", err)
+	}
+	server.Close()
+	if err := heartbeat.Send(context.Background()); err == nil {
+		t.Fatal("Sending heartbeat to unavailable endpoint succeeded")
+	}
+}
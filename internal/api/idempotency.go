@@ -0,0 +1,251 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/kesclient"
+)
+
+const (
+	// HeaderIdempotencyKey is the HTTP request header that
+	// carries a client-chosen, unique idempotency key.
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplayed is the HTTP response header
+	// set when a response is a replay of a cached outcome.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+var (
+	errIdempotencyKeyReused = &kesclient.Error{
+		Code:    kesclient.CodeIdempotencyKeyReused,
+		Status:  http.StatusUnprocessableEntity,
+		Message: "idempotency key has already been used for a different request",
+	}
+	errIdempotencyKeyInUse = &kesclient.Error{
+		Code:    kesclient.CodeIdempotencyKeyInUse,
+		Status:  http.StatusConflict,
+		Message: "a request with the same idempotency key is in progress",
+	}
+)
+
+// NewIdempotencyCache returns a new IdempotencyCache that
+// caches request outcomes for the given window. If window
+// is <= 0, it defaults to 5 minutes.
+func NewIdempotencyCache(window time.Duration) *IdempotencyCache {
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+	return &IdempotencyCache{
+		window:  window,
+		entries: map[idempotencyID]*idempotencyEntry{},
+	}
+}
+
+// An IdempotencyCache caches the outcome of mutating API
+// requests that carry an Idempotency-Key header.
+//
+// When a client retries a request with the same idempotency
+// key, e.g. after a timeout, the cache replays the outcome of
+// the original request instead of executing it again. Hence,
+// a retried key creation does not fail with "key already exists"
+// and a retried deletion does not delete twice.
+//
+// Idempotency keys are scoped to the client identity. Outcomes
+// of requests that failed with a server error (5xx) are not
+// cached such that the client can retry them.
+type IdempotencyCache struct {
+	window time.Duration
+
+	lock    sync.Mutex
+	entries map[idempotencyID]*idempotencyEntry
+}
+
+type idempotencyID struct {
+	Identity kes.Identity
+	Key      string
+}
+
+type idempotencyEntry struct {
+	fingerprint [sha256.Size]byte
+	done        bool // Whether the request has been processed
+	expiresAt   time.Time
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Handle returns a HandlerFunc that replays the cached outcome
+// of requests with a known idempotency key and calls h for all
+// others. Requests without an Idempotency-Key header are passed
+// to h as is.
+//
+// Before replaying an outcome, Handle verifies the request
+// with verify, which should perform the same authorization
+// checks as h. Otherwise, an identity could still obtain the
+// outcome, e.g. a response containing key material, after its
+// policy has been changed or its assignment been removed.
+//
+// If c is nil, Handle returns h.
+func (c *IdempotencyCache) Handle(verify func(*http.Request) error, h HandlerFunc) HandlerFunc {
+	if c == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) error {
+		const (
+			MaxKeyLength   = 255
+			MaxEntries     = 100000
+			MaxCachedBytes = 1 << 20
+		)
+
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return h(w, r)
+		}
+		if len(key) > MaxKeyLength {
+			return kes.NewError(http.StatusBadRequest, "invalid idempotency key: key is too long")
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+				return kes.NewError(http.StatusRequestEntityTooLarge, "request body too large")
+			}
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.New()
+		io.WriteString(fingerprint, r.Method)
+		fingerprint.Write([]byte{0})
+		io.WriteString(fingerprint, r.URL.RequestURI())
+		fingerprint.Write([]byte{0})
+		fingerprint.Write(body)
+
+		id := idempotencyID{Identity: auth.Identify(r), Key: key}
+		entry := &idempotencyEntry{}
+		fingerprint.Sum(entry.fingerprint[:0])
+
+		now := time.Now()
+		c.lock.Lock()
+		if e, ok := c.entries[id]; ok && (!e.done || now.Before(e.expiresAt)) {
+			var (
+				reused = e.fingerprint != entry.fingerprint
+				done   = e.done
+				status = e.status
+				header = e.header
+				body   = e.body
+			)
+			c.lock.Unlock()
+
+			if reused {
+				return errIdempotencyKeyReused
+			}
+			if !done {
+				return errIdempotencyKeyInUse
+			}
+			if err = verify(r); err != nil {
+				return err
+			}
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(HeaderIdempotentReplayed, "true")
+			w.WriteHeader(status)
+			w.Write(body)
+			return nil
+		}
+		if len(c.entries) >= MaxEntries {
+			for k, e := range c.entries {
+				if e.done && now.After(e.expiresAt) {
+					delete(c.entries, k)
+				}
+			}
+		}
+		if len(c.entries) >= MaxEntries {
+			c.lock.Unlock()
+			return h(w, r)
+		}
+		c.entries[id] = entry
+		c.lock.Unlock()
+
+		rw := &recordingResponseWriter{ResponseWriter: w, limit: MaxCachedBytes}
+		if err = h(rw, r); err != nil {
+			Fail(rw, err)
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if rw.status == 0 || rw.overflow || !isCacheable(rw.status) {
+			delete(c.entries, id)
+			return nil
+		}
+		entry.done = true
+		entry.expiresAt = time.Now().Add(c.window)
+		entry.status = rw.status
+		entry.header = w.Header().Clone()
+		entry.body = rw.body.Bytes()
+		return nil
+	}
+}
+
+// isCacheable reports whether the outcome of a request with
+// the given response status code can be replayed. It returns
+// false for server errors and status codes indicating that
+// the client should retry.
+func isCacheable(status int) bool {
+	return status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+}
+
+// recordingResponseWriter is an http.ResponseWriter that records
+// the response status code and up to limit bytes of the response
+// body while writing the response.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+
+	limit    int
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.overflow {
+		if w.body.Len()+len(p) > w.limit {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+//
+// This method is implemented for http.ResponseController.
+func (w *recordingResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,121 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	var (
+		calls  int
+		denied bool
+	)
+	verify := func(*http.Request) error {
+		if denied {
+			return kes.ErrNotAllowed
+		}
+		return nil
+	}
+	handler := NewIdempotencyCache(0).Handle(verify, func(w http.ResponseWriter, r *http.Request) error {
+		calls++
+		if calls > 1 {
+			return kes.ErrKeyExists
+		}
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	for i, test := range idempotencyCacheTests {
+		req := httptest.NewRequest(test.Method, test.URL, strings.NewReader(test.Body))
+		if test.Key != "" {
+			req.Header.Set(HeaderIdempotencyKey, test.Key)
+		}
+		denied = test.Denied
+
+		resp := httptest.NewRecorder()
+		HandlerFunc(handler).ServeHTTP(resp, req)
+		if resp.Code != test.Status {
+			t.Fatalf("Test %d: got status %d - want %d: %s", i, resp.Code, test.Status, resp.Body)
+		}
+		if replayed := resp.Header().Get(HeaderIdempotentReplayed) == "true"; replayed != test.Replayed {
+			t.Fatalf("Test %d: got replayed '%v' - want '%v'", i, replayed, test.Replayed)
+		}
+	}
+}
+
+var idempotencyCacheTests = []struct {
+	Method   string
+	URL      string
+	Body     string
+	Key      string
+	Status   int
+	Replayed bool
+	Denied   bool
+}{
+	{ // 0
+		Method: http.MethodPost,
+		URL:    "/v1/key/create/my-key",
+		Key:    "1",
+		Status: http.StatusOK,
+	},
+	{ // 1 - retry gets the original outcome
+		Method:   http.MethodPost,
+		URL:      "/v1/key/create/my-key",
+		Key:      "1",
+		Status:   http.StatusOK,
+		Replayed: true,
+	},
+	{ // 2 - same key but different request
+		Method: http.MethodPost,
+		URL:    "/v1/key/create/my-key-2",
+		Key:    "1",
+		Status: http.StatusUnprocessableEntity,
+	},
+	{ // 3 - same key but different body
+		Method: http.MethodPost,
+		URL:    "/v1/key/create/my-key",
+		Body:   `{}`,
+		Key:    "1",
+		Status: http.StatusUnprocessableEntity,
+	},
+	{ // 4 - no idempotency key
+		Method: http.MethodPost,
+		URL:    "/v1/key/create/my-key",
+		Status: http.StatusBadRequest,
+	},
+	{ // 5 - new idempotency key
+		Method: http.MethodPost,
+		URL:    "/v1/key/create/my-key",
+		Key:    "2",
+		Status: http.StatusBadRequest,
+	},
+	{ // 6
+		Method:   http.MethodPost,
+		URL:      "/v1/key/create/my-key",
+		Key:      "2",
+		Status:   http.StatusBadRequest,
+		Replayed: true,
+	},
+	{ // 7 - retry is no longer authorized
+		Method: http.MethodPost,
+		URL:    "/v1/key/create/my-key",
+		Key:    "1",
+		Status: http.StatusForbidden,
+		Denied: true,
+	},
+	{ // 8
+		Method:   http.MethodPost,
+		URL:      "/v1/key/create/my-key",
+		Key:      "1",
+		Status:   http.StatusOK,
+		Replayed: true,
+	},
+}
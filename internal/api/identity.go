@@ -5,14 +5,19 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"net/http"
-	"path"
 	"time"
 
+	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/pki"
 )
 
 func describeIdentity(config *RouterConfig) API {
@@ -29,6 +34,9 @@ func describeIdentity(config *RouterConfig) API {
 		Policy    string       `json:"policy"`
 		CreatedAt time.Time    `json:"created_at,omitempty"`
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
+
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		Expired   bool       `json:"expired,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -36,39 +44,42 @@ func describeIdentity(config *RouterConfig) API {
 			return err
 		}
 
-		info, err := VSync(config.Vault.RLocker(), func() (auth.IdentityInfo, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return auth.IdentityInfo{}, err
-			}
-			return VSync(enclave.RLocker(), func() (auth.IdentityInfo, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return auth.IdentityInfo{}, err
-				}
-				return enclave.GetIdentity(r.Context(), kes.Identity(name))
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		info, err := enclave.GetIdentity(r.Context(), kes.Identity(name))
 		if err != nil {
 			return err
 		}
 
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
+		response := Response{
 			IsAdmin:   info.IsAdmin,
 			Policy:    info.Policy,
 			CreatedAt: info.CreatedAt,
 			CreatedBy: info.CreatedBy,
-		})
+			Expired:   info.Expired(),
+		}
+		if !info.ExpiresAt.IsZero() {
+			response.ExpiresAt = &info.ExpiresAt
+		}
+		json.NewEncoder(w).Encode(response)
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -117,12 +128,14 @@ func edgeDescribeIdentity(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -147,59 +160,161 @@ func selfDescribeIdentity(config *RouterConfig) API {
 		PolicyName string       `json:"policy_name,omitempty"`
 		CreatedAt  time.Time    `json:"created_at,omitempty"`
 		CreatedBy  kes.Identity `json:"created_by,omitempty"`
+		ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
 
 		Policy InlinePolicy `json:"policy"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
-		response, err := VSync(config.Vault.RLocker(), func() (Response, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return Response{}, err
-			}
-			return VSync(enclave.RLocker(), func() (Response, error) {
-				identity := auth.Identify(r)
-				info, err := enclave.GetIdentity(r.Context(), identity)
-				if err != nil {
-					return Response{}, err
-				}
-				policy := auth.Policy{}
-				if !info.IsAdmin {
-					policy, err = enclave.GetPolicy(r.Context(), info.Policy)
-					if err != nil {
-						return Response{}, err
-					}
-				}
-				return Response{
-					Identity:   identity,
-					PolicyName: info.Policy,
-					IsAdmin:    info.IsAdmin,
-					CreatedAt:  info.CreatedAt,
-					CreatedBy:  info.CreatedBy,
-					Policy: InlinePolicy{
-						Allow:     policy.Allow,
-						Deny:      policy.Deny,
-						CreatedAt: policy.CreatedAt,
-						CreatedBy: policy.CreatedBy,
-					},
-				}, nil
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
 		if err != nil {
 			return err
 		}
 
+		identity := auth.Identify(r)
+		info, err := enclave.GetIdentity(r.Context(), identity)
+		if err != nil {
+			return err
+		}
+		policy := auth.Policy{}
+		if !info.IsAdmin {
+			policy, err = enclave.GetPolicy(r.Context(), info.Policy)
+			if err != nil {
+				return err
+			}
+		}
+
+		response := Response{
+			Identity:   identity,
+			PolicyName: info.Policy,
+			IsAdmin:    info.IsAdmin,
+			CreatedAt:  info.CreatedAt,
+			CreatedBy:  info.CreatedBy,
+			Policy: InlinePolicy{
+				Allow:     policy.Allow,
+				Deny:      policy.Deny,
+				CreatedAt: policy.CreatedAt,
+				CreatedBy: policy.CreatedBy,
+			},
+		}
+		if !info.ExpiresAt.IsZero() {
+			response.ExpiresAt = &info.ExpiresAt
+		}
+
 		w.Header().Add("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(response)
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func selfIssueCertificate(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/identity/self/csr"
+		MaxBody     = int64(16 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		CSR string        `json:"csr"`
+		TTL time.Duration `json:"ttl"` // optional
+	}
+	type Response struct {
+		Identity     kes.Identity `json:"identity"`
+		SerialNumber string       `json:"serial_number"`
+		Certificate  string       `json:"certificate"`
+		CA           string       `json:"ca"`
+		ExpiresAt    time.Time    `json:"expires_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		maxTTL := config.CA.maxClientTTL()
+		if req.TTL < 0 || req.TTL > maxTTL {
+			return kes.NewError(http.StatusBadRequest, "invalid ttl: must not exceed "+maxTTL.String())
+		}
+		if req.TTL == 0 {
+			req.TTL = defaultClientTTL
+			if req.TTL > maxTTL {
+				req.TTL = maxTTL
+			}
+		}
+		csr, err := pki.ParseCertificateRequest([]byte(req.CSR))
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		self := auth.Identify(r)
+		info, err := enclave.GetIdentity(r.Context(), self)
+		if err != nil {
+			return err
+		}
+		expiresAt := time.Now().Add(req.TTL)
+		if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(expiresAt) {
+			expiresAt = info.ExpiresAt // The certificate must not outlive the identity's policy assignment
+		}
+
+		// The certificate is bound to the requesting identity. Otherwise,
+		// any identity could create new identities with its own policy,
+		// which in turn could create new identities, and so on. Deleting
+		// the requesting identity would not revoke any of them.
+		h := sha256.Sum256(csr.RawSubjectPublicKeyInfo)
+		identity := kes.Identity(hex.EncodeToString(h[:]))
+		if identity != self {
+			return kes.NewError(http.StatusBadRequest, "certificate request does not contain the public key of the requesting identity")
+		}
+
+		issuer, err := config.Vault.Issuer(r.Context())
+		if err != nil {
+			return err
+		}
+		cert, err := issuer.IssueClientCertificate(csr, expiresAt)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Identity:     identity,
+			SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+			Certificate:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			CA:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Certificate().Raw})),
+			ExpiresAt:    cert.NotAfter,
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -264,12 +379,14 @@ func edgeSelfDescribeIdentity(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -287,30 +404,36 @@ func deleteIdentity(config *RouterConfig) API {
 			return err
 		}
 
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		admin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+
+		identity := kes.Identity(name)
+		if admin == identity {
+			return kes.NewError(http.StatusBadRequest, "cannot delete system admin")
+		}
+		enclaveAdmin, err := isEnclaveAdmin(r.Context(), enclave, identity)
+		if err != nil {
+			return err
+		}
+		if enclaveAdmin {
+			if err = verifyEnclaveOwner(config.Vault, enclave, r); err != nil {
 				return err
 			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-				admin, err := config.Vault.Admin(r.Context())
-				if err != nil {
-					return err
-				}
-
-				identity := kes.Identity(name)
-				if admin == identity {
-					return kes.NewError(http.StatusBadRequest, "cannot delete system admin")
-				}
-				return enclave.DeleteIdentity(r.Context(), identity)
-			})
-		}); err != nil {
+		}
+		if err = enclave.DeleteIdentity(r.Context(), identity); err != nil {
 			return err
 		}
 
+		config.Events.Publish(r, EventIdentityDeleted, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -320,7 +443,7 @@ func deleteIdentity(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -339,6 +462,8 @@ func listIdentity(config *RouterConfig) API {
 		Policy    string       `json:"policy"`
 		CreatedAt time.Time    `json:"created_at,omitempty"`
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
+		ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+		Expired   bool         `json:"expired,omitempty"`
 
 		Err string `json:"error,omitempty"`
 	}
@@ -348,51 +473,55 @@ func listIdentity(config *RouterConfig) API {
 			return err
 		}
 
-		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		hasWritten, err := func() (bool, error) {
+			iterator, err := enclave.ListIdentities(r.Context())
 			if err != nil {
 				return false, err
 			}
-			return VSync(enclave.RLocker(), func() (bool, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return false, err
+			defer iterator.Close()
+
+			var hasWritten bool
+			encoder := json.NewEncoder(w)
+			for iterator.Next() {
+				if !matchPattern(pattern, iterator.Identity().String()) {
+					continue
 				}
-				iterator, err := enclave.ListIdentities(r.Context())
+				info, err := enclave.GetIdentity(r.Context(), iterator.Identity())
 				if err != nil {
-					return false, err
+					return hasWritten, err
 				}
-				defer iterator.Close()
-
-				var hasWritten bool
-				encoder := json.NewEncoder(w)
-				for iterator.Next() {
-					if ok, _ := path.Match(pattern, iterator.Identity().String()); !ok {
-						continue
-					}
-					info, err := enclave.GetIdentity(r.Context(), iterator.Identity())
-					if err != nil {
-						return hasWritten, err
-					}
-					if !hasWritten {
-						hasWritten = true
-						w.Header().Set("Content-Type", ContentType)
-						w.WriteHeader(http.StatusOK)
-					}
-
-					err = encoder.Encode(Response{
-						Identity:  iterator.Identity(),
-						IsAdmin:   info.IsAdmin,
-						Policy:    info.Policy,
-						CreatedAt: info.CreatedAt,
-						CreatedBy: info.CreatedBy,
-					})
-					if err != nil {
-						return hasWritten, err
-					}
+				if !hasWritten {
+					hasWritten = true
+					w.Header().Set("Content-Type", ContentType)
+					w.WriteHeader(http.StatusOK)
 				}
-				return hasWritten, iterator.Close()
-			})
-		})
+
+				response := Response{
+					Identity:  iterator.Identity(),
+					IsAdmin:   info.IsAdmin,
+					Policy:    info.Policy,
+					CreatedAt: info.CreatedAt,
+					CreatedBy: info.CreatedBy,
+					Expired:   info.Expired(),
+				}
+				if !info.ExpiresAt.IsZero() {
+					response.ExpiresAt = &info.ExpiresAt
+				}
+				err = encoder.Encode(response)
+				if err != nil {
+					return hasWritten, err
+				}
+			}
+			return hasWritten, iterator.Close()
+		}()
 		if err != nil {
 			if hasWritten {
 				json.NewEncoder(w).Encode(Response{Err: err.Error()})
@@ -406,12 +535,14 @@ func listIdentity(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -458,7 +589,7 @@ func edgeListIdentity(config *EdgeRouterConfig) API {
 			hasWritten bool
 		)
 		for iterator.Next() {
-			if ok, _ := path.Match(pattern, iterator.Identity().String()); !ok {
+			if !matchPattern(pattern, iterator.Identity().String()) {
 				continue
 			}
 			if !hasWritten {
@@ -496,11 +627,13 @@ func edgeListIdentity(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
@@ -0,0 +1,167 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kes/internal/sys"
+)
+
+func TestSelfIssueCertificate(t *testing.T) {
+	const (
+		Admin        kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		EnclaveAdmin kes.Identity = "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	vault := sys.NewVault(sys.NewVaultFS(t.TempDir(), rootKey))
+	if _, err = vault.CreateEnclave(ctx, sys.DefaultEnclaveName, EnclaveAdmin, nil); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, sys.DefaultEnclaveName)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.SetPolicy(ctx, "my-app", auth.Policy{Allow: []string{"/v1/identity/self/csr"}}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	api := selfIssueCertificate(&RouterConfig{
+		Vault:    vault,
+		Metrics:  metric.New(),
+		AuditLog: log.New(io.Discard, "", 0),
+	})
+	issue := func(priv, next ed25519.PrivateKey) *httptest.ResponseRecorder {
+		t.Helper()
+		csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "my-app"}}, next)
+		if err != nil {
+			t.Fatalf("Failed to create certificate request: %v", err)
+		}
+		body, _ := json.Marshal(map[string]string{
+			"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/identity/self/csr", strings.NewReader(string(body)))
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{selfSigned(t, priv)}}
+		resp := httptest.NewRecorder()
+		api.Handler.ServeHTTP(resp, req)
+		return resp
+	}
+	expiryOf := func(resp *httptest.ResponseRecorder) time.Time {
+		t.Helper()
+		var cert struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&cert); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return cert.ExpiresAt
+	}
+
+	// A certificate for the requesting identity expires
+	// after the default TTL if the assignment is permanent.
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.AssignPolicy(ctx, "my-app", identityOf(priv)); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	resp := issue(priv, priv)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Failed to issue certificate: got status '%d' - want '%d': %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	if expiresAt := expiryOf(resp); expiresAt.Before(time.Now().Add(defaultClientTTL - time.Minute)) {
+		t.Fatalf("Certificate expires too early: got '%v' - want '%v'", expiresAt, time.Now().Add(defaultClientTTL))
+	}
+
+	// A certificate request for another public key must be
+	// rejected. Otherwise, any identity could create new
+	// identities with its policy.
+	_, next, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if resp = issue(priv, next); resp.Code != http.StatusBadRequest {
+		t.Fatalf("Issuing certificate for new public key: got status '%d' - want '%d'", resp.Code, http.StatusBadRequest)
+	}
+	if _, err = enclave.GetIdentity(ctx, identityOf(next)); !errors.Is(err, kes.ErrIdentityNotFound) {
+		t.Fatalf("Identity of new public key: got '%v' - want '%v'", err, kes.ErrIdentityNotFound)
+	}
+
+	// A certificate must not outlive a temporary
+	// policy assignment.
+	_, priv, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err = enclave.AssignPolicyUntil(ctx, "my-app", identityOf(priv), expiresAt); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	resp = issue(priv, priv)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Failed to issue certificate: got status '%d' - want '%d': %s", resp.Code, http.StatusOK, resp.Body)
+	}
+	if got := expiryOf(resp); got.After(expiresAt) {
+		t.Fatalf("Certificate outlives policy assignment: got '%v' - want '%v'", got, expiresAt)
+	}
+}
+
+// identityOf returns the identity of the private key.
+func identityOf(priv ed25519.PrivateKey) kes.Identity {
+	spki, _ := x509.MarshalPKIXPublicKey(priv.Public())
+	h := sha256.Sum256(spki)
+	return kes.Identity(hex.EncodeToString(h[:]))
+}
+
+// selfSigned returns a self-signed client
+// certificate for the private key.
+func selfSigned(t *testing.T, priv ed25519.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "my-app"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
@@ -6,17 +6,16 @@ package api
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
-	"path"
 	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
-	"github.com/minio/kes/internal/cpu"
-	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/key"
 )
 
@@ -33,32 +32,37 @@ func createKey(config *RouterConfig) API {
 		if err != nil {
 			return err
 		}
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-
-				var algorithm kes.KeyAlgorithm
-				if fips.Enabled || cpu.HasAESGCM() {
-					algorithm = kes.AES256_GCM_SHA256
-				} else {
-					algorithm = kes.XCHACHA20_POLY1305
-				}
+		retainUntil, err := retentionFromRequest(r)
+		if err != nil {
+			return err
+		}
+		algorithm, err := algorithmFromRequest(r)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = config.ReservedPrefixes.verify(r, name, enclave.VerifyRequest); err != nil {
+			return err
+		}
+		if algorithm, err = key.SelectAlgorithm(algorithm, enclave.KeyPolicy(), config.KeyPolicy); err != nil {
+			return err
+		}
 
-				key, err := key.Random(algorithm, auth.Identify(r))
-				if err != nil {
-					return err
-				}
-				return enclave.CreateKey(r.Context(), name, key)
-			})
-		}); err != nil {
+		key, err := key.Random(algorithm, auth.Identify(r))
+		if err != nil {
+			return err
+		}
+		key.SetRetainUntil(retainUntil)
+		if err = enclave.CreateKey(r.Context(), name, key); err != nil {
 			return err
 		}
+		config.Events.Publish(r, EventKeyCreated, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -68,7 +72,7 @@ func createKey(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(config.ReservedPrefixes.verifier(APIPath, enclaveVerifier(config.Vault)), handler)))),
 	}
 }
 
@@ -90,25 +94,36 @@ func edgeCreateKey(config *EdgeRouterConfig) API {
 		if err != nil {
 			return err
 		}
+		retainUntil, err := retentionFromRequest(r)
+		if err != nil {
+			return err
+		}
+		algorithm, err := algorithmFromRequest(r)
+		if err != nil {
+			return err
+		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
-
-		var algorithm kes.KeyAlgorithm
-		if fips.Enabled || cpu.HasAESGCM() {
-			algorithm = kes.AES256_GCM_SHA256
-		} else {
-			algorithm = kes.XCHACHA20_POLY1305
+		if err = config.ReservedPrefixes.verify(r, name, func(r *http.Request) error {
+			return auth.VerifyRequest(r, config.Policies, config.Identities)
+		}); err != nil {
+			return err
+		}
+		if algorithm, err = key.SelectAlgorithm(algorithm, config.KeyPolicy); err != nil {
+			return err
 		}
 
 		key, err := key.Random(algorithm, auth.Identify(r))
 		if err != nil {
 			return err
 		}
+		key.SetRetainUntil(retainUntil)
 		if err = config.Keys.Create(r.Context(), name, key); err != nil {
 			return err
 		}
 
+		config.Events.Publish(r, EventKeyCreated, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -118,7 +133,7 @@ func edgeCreateKey(config *EdgeRouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(config.ReservedPrefixes.verifier(APIPath, edgeVerifier(config)), handler)))),
 	}
 }
 
@@ -131,40 +146,52 @@ func importKey(config *RouterConfig) API {
 		Verify  = true
 	)
 	type Request struct {
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm"`
+		Bytes     []byte `json:"bytes"`
+		Algorithm string `json:"algorithm"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		if err := Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
+		retainUntil, err := retentionFromRequest(r)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = config.ReservedPrefixes.verify(r, name, enclave.VerifyRequest); err != nil {
+			return err
+		}
 
-				var req Request
-				if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-					return kes.NewError(http.StatusBadRequest, err.Error())
-				}
-				if len(req.Bytes) != key.Len(req.Algorithm) {
-					return kes.NewError(http.StatusBadRequest, "invalid key size")
-				}
-				key, err := key.New(req.Algorithm, req.Bytes, auth.Identify(r))
-				if err != nil {
-					return err
-				}
-				return enclave.CreateKey(r.Context(), name, key)
-			})
-		}); err != nil {
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		algorithm, err := key.ParseAlgorithm(req.Algorithm)
+		if err != nil {
+			return err
+		}
+		if err = verifyImportAlgorithm(algorithm, enclave.KeyPolicy(), config.KeyPolicy); err != nil {
+			return err
+		}
+		if len(req.Bytes) != key.Len(algorithm) {
+			return kes.NewError(http.StatusBadRequest, "invalid key size")
+		}
+		key, err := key.New(algorithm, req.Bytes, auth.Identify(r))
+		if err != nil {
 			return err
 		}
+		key.SetRetainUntil(retainUntil)
+		if err = enclave.CreateKey(r.Context(), name, key); err != nil {
+			return err
+		}
+		config.Events.Publish(r, EventKeyCreated, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -174,7 +201,8 @@ func importKey(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(config.ReservedPrefixes.verifier(APIPath, enclaveVerifier(config.Vault)), handler)))),
 	}
 }
 
@@ -192,33 +220,51 @@ func edgeImportKey(config *EdgeRouterConfig) API {
 		}
 	}
 	type Request struct {
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm"`
+		Bytes     []byte `json:"bytes"`
+		Algorithm string `json:"algorithm"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
+		retainUntil, err := retentionFromRequest(r)
+		if err != nil {
+			return err
+		}
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
 			return err
 		}
+		if err = config.ReservedPrefixes.verify(r, name, func(r *http.Request) error {
+			return auth.VerifyRequest(r, config.Policies, config.Identities)
+		}); err != nil {
+			return err
+		}
 
 		var req Request
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			return kes.NewError(http.StatusBadRequest, err.Error())
 		}
-		if len(req.Bytes) != key.Len(req.Algorithm) {
+		algorithm, err := key.ParseAlgorithm(req.Algorithm)
+		if err != nil {
+			return err
+		}
+		if err = verifyImportAlgorithm(algorithm, config.KeyPolicy); err != nil {
+			return err
+		}
+		if len(req.Bytes) != key.Len(algorithm) {
 			return kes.NewError(http.StatusBadRequest, "invalid key size")
 		}
-		key, err := key.New(req.Algorithm, req.Bytes, auth.Identify(r))
+		key, err := key.New(algorithm, req.Bytes, auth.Identify(r))
 		if err != nil {
 			return err
 		}
+		key.SetRetainUntil(retainUntil)
 		if err = config.Keys.Create(r.Context(), name, key); err != nil {
 			return err
 		}
 
+		config.Events.Publish(r, EventKeyCreated, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -228,7 +274,8 @@ func edgeImportKey(config *EdgeRouterConfig) API {
 		MaxBody: int64(MaxBody),
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(config.ReservedPrefixes.verifier(APIPath, edgeVerifier(config)), handler)))),
 	}
 }
 
@@ -242,51 +289,139 @@ func describeKey(config *RouterConfig) API {
 		ContentType = "application/json"
 	)
 	type Response struct {
-		Name      string           `json:"name"`
-		ID        string           `json:"id,omitempty"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Name      string       `json:"name"`
+		ID        string       `json:"id,omitempty"`
+		Algorithm string       `json:"algorithm,omitempty"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+
+		Fingerprint string         `json:"fingerprint,omitempty"`
+		RetainUntil *time.Time     `json:"retain_until,omitempty"`
+		LegalHold   *key.LegalHold `json:"legal_hold,omitempty"`
+		Disabled    bool           `json:"disabled,omitempty"`
+		Allowlist   *key.Allowlist `json:"allowlist,omitempty"`
+
+		TokenSigning bool `json:"token_signing,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return key.Key{}, err
-			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
-				}
-				return enclave.GetKey(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		k, err := enclave.GetKey(r.Context(), name)
 		if err != nil {
 			return err
 		}
 
 		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("ETag", keyETag(k))
+		w.WriteHeader(http.StatusOK)
+		response := Response{
+			Name:        name,
+			ID:          k.ID(),
+			Algorithm:   key.AlgorithmName(k.Algorithm()),
+			CreatedAt:   k.CreatedAt(),
+			CreatedBy:   k.CreatedBy(),
+			Fingerprint: k.Fingerprint(),
+		}
+		if retainUntil := k.RetainUntil(); !retainUntil.IsZero() {
+			response.RetainUntil = &retainUntil
+		}
+		response.LegalHold = k.LegalHold()
+		response.Disabled = k.Disabled()
+		if allowlist := k.Allowlist(); !allowlist.IsZero() {
+			response.Allowlist = &allowlist
+		}
+		response.TokenSigning = k.TokenSigning()
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeRegisterKey(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/register/"
+		MaxBody = 1 * mem.MiB
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Request struct {
+		KMS   string `json:"kms"`
+		KeyID string `json:"key_id"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		retainUntil, err := retentionFromRequest(r)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		if err = config.ReservedPrefixes.verify(r, name, func(r *http.Request) error {
+			return auth.VerifyRequest(r, config.Policies, config.Identities)
+		}); err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		kms, ok := config.ExternalKMS[req.KMS]
+		if !ok {
+			return kes.NewError(http.StatusBadRequest, "external KMS '"+req.KMS+"' does not exist")
+		}
+		k, err := key.NewExternal(key.External{KMS: req.KMS, KeyID: req.KeyID}, auth.Identify(r))
+		if err != nil {
+			return err
+		}
+		if _, err = kms.Key(r.Context(), req.KeyID); err != nil {
+			return err
+		}
+		k.SetRetainUntil(retainUntil)
+		if err = config.Keys.Create(r.Context(), name, k); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventKeyCreated, name)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Name:      name,
-			ID:        key.ID(),
-			Algorithm: key.Algorithm(),
-			CreatedAt: key.CreatedAt(),
-			CreatedBy: key.CreatedBy(),
-		})
 		return nil
 	}
 	return API{
 		Method:  Method,
 		Path:    APIPath,
-		MaxBody: MaxBody,
+		MaxBody: int64(MaxBody),
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(config.ReservedPrefixes.verifier(APIPath, edgeVerifier(config)), handler)))),
 	}
 }
 
@@ -304,34 +439,365 @@ func edgeDescribeKey(config *EdgeRouterConfig) API {
 		}
 	}
 	type Response struct {
-		Name      string           `json:"name"`
-		ID        string           `json:"id,omitempty"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Name      string       `json:"name"`
+		ID        string       `json:"id,omitempty"`
+		Algorithm string       `json:"algorithm,omitempty"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+
+		Fingerprint string        `json:"fingerprint,omitempty"`
+		RetainUntil *time.Time    `json:"retain_until,omitempty"`
+		External    *key.External `json:"external,omitempty"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		k, err := config.Keys.Get(r.Context(), name)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", keyETag(k))
+		w.WriteHeader(http.StatusOK)
+		response := Response{
+			Name:        name,
+			ID:          k.ID(),
+			Algorithm:   key.AlgorithmName(k.Algorithm()),
+			CreatedAt:   k.CreatedAt(),
+			CreatedBy:   k.CreatedBy(),
+			Fingerprint: k.Fingerprint(),
+			External:    k.External(),
+		}
+		if retainUntil := k.RetainUntil(); !retainUntil.IsZero() {
+			response.RetainUntil = &retainUntil
+		}
+		json.NewEncoder(w).Encode(response)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/json",
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func verifyKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/key/verify/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.LoadKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newKeyCheck(name, key))
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    keyCheck{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeVerifyKey(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/v1/key/verify/"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		key, err := config.Keys.Load(r.Context(), name)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newKeyCheck(name, key))
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/json",
+		Response:    keyCheck{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// Integrity states of a stored key reported by
+// the key verify API.
+const (
+	keyIntact     = "intact"     // Stored and computed check value match
+	keyCorrupted  = "corrupted"  // Stored and computed check value differ
+	keyUnverified = "unverified" // Key has been stored without check value
+)
+
+// keyCheck is the response of the key verify API.
+type keyCheck struct {
+	Name             string `json:"name"`
+	ID               string `json:"id"`
+	CheckValue       string `json:"check_value"`
+	StoredCheckValue string `json:"stored_check_value,omitempty"`
+	Status           string `json:"status"`
+}
+
+// newKeyCheck compares the check value of the key
+// with the check value stored along with the key.
+func newKeyCheck(name string, key key.Key) keyCheck {
+	checkValue, storedCheckValue := key.CheckValue(), key.StoredCheckValue()
+
+	status := keyUnverified
+	if len(storedCheckValue) > 0 {
+		status = keyCorrupted
+		if subtle.ConstantTimeCompare(checkValue, storedCheckValue) == 1 {
+			status = keyIntact
+		}
+	}
+	return keyCheck{
+		Name:             name,
+		ID:               key.ID(),
+		CheckValue:       hex.EncodeToString(checkValue),
+		StoredCheckValue: hex.EncodeToString(storedCheckValue),
+		Status:           status,
+	}
+}
+
+func deleteKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodDelete
+		APIPath = "/v1/key/delete/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = enclave.DeleteKey(r.Context(), name); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventKeyDeleted, name)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func holdKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/hold/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		hold := key.LegalHold{
+			CreatedAt: time.Now().UTC(),
+			CreatedBy: auth.Identify(r),
+		}
+		if err = enclave.HoldKey(r.Context(), name, hold); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventKeyHeld, name)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func releaseKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/release/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = enclave.ReleaseKey(r.Context(), name); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventKeyReleased, name)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func disableKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/disable/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = enclave.DisableKey(r.Context(), name); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventKeyDisabled, name)
+		w.WriteHeader(http.StatusOK)
+		return nil
 	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func enableKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/key/enable/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
 			return err
 		}
-		key, err := config.Keys.Get(r.Context(), name)
-		if err != nil {
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = enclave.EnableKey(r.Context(), name); err != nil {
 			return err
 		}
 
-		w.Header().Set("Content-Length", "application/json")
+		config.Events.Publish(r, EventKeyEnabled, name)
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
-			Name:      name,
-			ID:        key.ID(),
-			Algorithm: key.Algorithm(),
-			CreatedAt: key.CreatedAt(),
-			CreatedBy: key.CreatedBy(),
-		})
 		return nil
 	}
 	return API{
@@ -340,38 +806,58 @@ func edgeDescribeKey(config *EdgeRouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
-func deleteKey(config *RouterConfig) API {
+func setKeyAllowlist(config *RouterConfig) API {
 	const (
-		Method  = http.MethodDelete
-		APIPath = "/v1/key/delete/"
-		MaxBody = 0
+		Method  = http.MethodPost
+		APIPath = "/v1/key/allowlist/"
+		MaxBody = int64(1 * mem.MiB)
 		Timeout = 15 * time.Second
 		Verify  = true
 	)
+	type Request struct {
+		Identities []kes.Identity `json:"identities"`
+		Policies   []string       `json:"policies"`
+	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		for _, identity := range req.Identities {
+			if identity.IsUnknown() {
+				return kes.NewError(http.StatusBadRequest, "invalid identity in allowlist")
+			}
+		}
+		for _, policy := range req.Policies {
+			if err = config.Names.verifyName(policy); err != nil {
 				return err
 			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-				return enclave.DeleteKey(r.Context(), name)
-			})
-		}); err != nil {
+		}
+		allowlist := key.Allowlist{
+			Identities: req.Identities,
+			Policies:   req.Policies,
+		}
+		if err = enclave.SetKeyAllowlist(r.Context(), name, allowlist); err != nil {
 			return err
 		}
 
+		config.Events.Publish(r, EventKeyAllowlistSet, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -381,7 +867,8 @@ func deleteKey(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -410,6 +897,7 @@ func edgeDeleteKey(config *EdgeRouterConfig) API {
 			return err
 		}
 
+		config.Events.Publish(r, EventKeyDeleted, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -419,7 +907,7 @@ func edgeDeleteKey(config *EdgeRouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(edgeVerifier(config), handler)))),
 	}
 }
 
@@ -445,21 +933,20 @@ func generateKey(config *RouterConfig) API {
 			return err
 		}
 
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return key.Key{}, err
-			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
-				}
-				return enclave.GetKey(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
 		if err != nil {
 			return err
 		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
 
 		var req Request
 		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -469,11 +956,12 @@ func generateKey(config *RouterConfig) API {
 		if _, err = rand.Read(dataKey); err != nil {
 			return err
 		}
-		ciphertext, err := key.Wrap(dataKey, req.Context)
+		ciphertext, err := wrapKey(r, name, &key, dataKey, req.Context)
 		if err != nil {
 			return err
 		}
 
+		config.Metrics.CountKeyOperation("generate", enclaveName(r), name)
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -483,12 +971,15 @@ func generateKey(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -534,11 +1025,12 @@ func edgeGenerateKey(config *EdgeRouterConfig) API {
 		if _, err = rand.Read(dataKey); err != nil {
 			return err
 		}
-		ciphertext, err := key.Wrap(dataKey, req.Context)
+		ciphertext, err := edgeWrapKey(r, config, name, &key, dataKey, req.Context)
 		if err != nil {
 			return err
 		}
 
+		config.Metrics.CountKeyOperation("generate", "", name)
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -548,12 +1040,15 @@ func edgeGenerateKey(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: int64(MaxBody),
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     int64(MaxBody),
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -579,31 +1074,31 @@ func encryptKey(config *RouterConfig) API {
 			return err
 		}
 
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return key.Key{}, err
-			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
-				}
-				return enclave.GetKey(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
 		if err != nil {
 			return err
 		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
 
 		var req Request
 		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
 			return kes.NewError(http.StatusBadRequest, err.Error())
 		}
-		ciphertext, err := key.Wrap(req.Plaintext, req.Context)
+		ciphertext, err := wrapKey(r, name, &key, req.Plaintext, req.Context)
 		if err != nil {
 			return err
 		}
 
+		config.Metrics.CountKeyOperation("encrypt", enclaveName(r), name)
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -612,12 +1107,15 @@ func encryptKey(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -659,11 +1157,12 @@ func edgeEncryptKey(config *EdgeRouterConfig) API {
 		if err != nil {
 			return err
 		}
-		ciphertext, err := key.Wrap(req.Plaintext, req.Context)
+		ciphertext, err := edgeWrapKey(r, config, name, &key, req.Plaintext, req.Context)
 		if err != nil {
 			return err
 		}
 
+		config.Metrics.CountKeyOperation("encrypt", "", name)
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -672,12 +1171,15 @@ func edgeEncryptKey(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -702,21 +1204,20 @@ func decryptKey(config *RouterConfig) API {
 		if err != nil {
 			return err
 		}
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return key.Key{}, err
-			}
-			return VSync(enclave.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
-				}
-				return enclave.GetKey(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
 		if err != nil {
 			return err
 		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
 
 		var req Request
 		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -727,6 +1228,7 @@ func decryptKey(config *RouterConfig) API {
 			return err
 		}
 
+		config.Metrics.CountKeyOperation("decrypt", enclaveName(r), name)
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -735,12 +1237,15 @@ func decryptKey(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -782,11 +1287,12 @@ func edgeDecryptKey(config *EdgeRouterConfig) API {
 		if err != nil {
 			return err
 		}
-		plaintext, err := key.Unwrap(req.Ciphertext, req.Context)
+		plaintext, err := edgeUnwrapKey(r.Context(), config, &key, req.Ciphertext, req.Context)
 		if err != nil {
 			return err
 		}
 
+		config.Metrics.CountKeyOperation("decrypt", "", name)
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -795,12 +1301,15 @@ func edgeDecryptKey(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -827,21 +1336,20 @@ func bulkDecryptKey(config *RouterConfig) API {
 			return err
 		}
 
-		key, err := VSync(config.Vault.RLocker(), func() (key.Key, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return key.Key{}, err
-			}
-			return VSync(config.Vault.RLocker(), func() (key.Key, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return key.Key{}, err
-				}
-				return enclave.GetKey(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
 		if err != nil {
 			return err
 		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
 
 		var (
 			requests  []Request
@@ -870,12 +1378,164 @@ func bulkDecryptKey(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     []Request{},
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func rewrapKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/rewrap/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+		MaxRequests = 1000 // Limit the number of ciphertexts re-wrapped in a single API call, like bulk decryption.
+	)
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Context    []byte `json:"context"` // optional
+	}
+	type Response struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Format     string `json:"format"` // Format of the original ciphertext
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+
+		var requests []Request
+		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(requests) > MaxRequests {
+			return kes.NewError(http.StatusBadRequest, "too many ciphertexts")
+		}
+		responses := make([]Response, 0, len(requests))
+		for _, req := range requests {
+			ciphertext, format, err := key.Rewrap(name, req.Ciphertext, req.Context)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, Response{
+				Ciphertext: ciphertext,
+				Format:     format,
+			})
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     []Request{},
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func hashKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/key/hash/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+		MaxRequests = 1000 // Limit the number of messages hashed in a single API call, like bulk decryption.
+	)
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		Hash []byte `json:"hash"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+
+		var requests []Request
+		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(requests) > MaxRequests {
+			return kes.NewError(http.StatusBadRequest, "too many messages")
+		}
+		responses := make([]Response, 0, len(requests))
+		for _, req := range requests {
+			hash, err := key.HMAC(req.Message)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, Response{
+				Hash: hash,
+			})
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     []Request{},
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -926,7 +1586,7 @@ func edgeBulkDecryptKey(config *EdgeRouterConfig) API {
 		}
 		responses = make([]Response, 0, len(requests))
 		for _, req := range requests {
-			plaintext, err := key.Unwrap(req.Ciphertext, req.Context)
+			plaintext, err := edgeUnwrapKey(r.Context(), config, &key, req.Ciphertext, req.Context)
 			if err != nil {
 				return err
 			}
@@ -941,12 +1601,15 @@ func edgeBulkDecryptKey(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     []Request{},
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -960,11 +1623,13 @@ func listKey(config *RouterConfig) API {
 		ContentType = "application/x-ndjson"
 	)
 	type Response struct {
-		Name      string           `json:"name,omitempty"`
-		ID        string           `json:"id,omitempty"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Name      string       `json:"name,omitempty"`
+		ID        string       `json:"id,omitempty"`
+		Algorithm string       `json:"algorithm,omitempty"`
+		CreatedAt time.Time    `json:"created_at,omitempty"`
+		CreatedBy kes.Identity `json:"created_by,omitempty"`
+
+		Fingerprint string `json:"fingerprint,omitempty"`
 
 		Err string `json:"error,omitempty"`
 	}
@@ -974,52 +1639,51 @@ func listKey(config *RouterConfig) API {
 			return err
 		}
 
-		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		hasWritten, err := func() (bool, error) {
+			iterator, err := enclave.ListKeys(r.Context())
 			if err != nil {
 				return false, err
 			}
-			return VSync(enclave.RLocker(), func() (bool, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return false, err
-				}
+			defer iterator.Close()
 
-				iterator, err := enclave.ListKeys(r.Context())
+			var hasWritten bool
+			encoder := json.NewEncoder(w)
+			for iterator.Next() {
+				if !matchPattern(pattern, iterator.Name()) || iterator.Name() == "" {
+					continue
+				}
+				k, err := enclave.GetKey(r.Context(), iterator.Name())
 				if err != nil {
-					return false, err
+					return hasWritten, err
 				}
-				defer iterator.Close()
-
-				var hasWritten bool
-				encoder := json.NewEncoder(w)
-				for iterator.Next() {
-					if ok, _ := path.Match(pattern, iterator.Name()); !ok || iterator.Name() == "" {
-						continue
-					}
-					key, err := enclave.GetKey(r.Context(), iterator.Name())
-					if err != nil {
-						return hasWritten, err
-					}
-					if !hasWritten {
-						hasWritten = true
-						w.Header().Set("Content-Type", ContentType)
-						w.WriteHeader(http.StatusOK)
-					}
-
-					err = encoder.Encode(Response{
-						Name:      iterator.Name(),
-						ID:        key.ID(),
-						Algorithm: key.Algorithm(),
-						CreatedAt: key.CreatedAt(),
-						CreatedBy: key.CreatedBy(),
-					})
-					if err != nil {
-						return hasWritten, err
-					}
+				if !hasWritten {
+					hasWritten = true
+					w.Header().Set("Content-Type", ContentType)
+					w.WriteHeader(http.StatusOK)
 				}
-				return hasWritten, iterator.Close()
-			})
-		})
+
+				err = encoder.Encode(Response{
+					Name:        iterator.Name(),
+					ID:          k.ID(),
+					Algorithm:   key.AlgorithmName(k.Algorithm()),
+					CreatedAt:   k.CreatedAt(),
+					CreatedBy:   k.CreatedBy(),
+					Fingerprint: k.Fingerprint(),
+				})
+				if err != nil {
+					return hasWritten, err
+				}
+			}
+			return hasWritten, iterator.Close()
+		}()
 		if err != nil {
 			if hasWritten {
 				json.NewEncoder(w).Encode(Response{Err: err.Error()})
@@ -1033,12 +1697,14 @@ func listKey(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -1069,7 +1735,7 @@ func edgeListKey(config *EdgeRouterConfig) API {
 			return err
 		}
 
-		iterator, err := config.Keys.List(r.Context())
+		iterator, err := config.Keys.ListPrefix(r.Context(), patternPrefix(pattern))
 		if err != nil {
 			return err
 		}
@@ -1084,7 +1750,7 @@ func edgeListKey(config *EdgeRouterConfig) API {
 			if !ok {
 				break
 			}
-			if ok, _ = path.Match(pattern, name); !ok || name == "" {
+			if !matchPattern(pattern, name) || name == "" {
 				continue
 			}
 			if !hasWritten {
@@ -1110,11 +1776,13 @@ func edgeListKey(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
@@ -0,0 +1,44 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/minio/kes-go"
+)
+
+var errTooManyAPIRequests = kes.NewError(http.StatusServiceUnavailable, "service unavailable: too many concurrent requests for API")
+
+// limit sets the max. body size of the API and limits
+// the number of requests it handles concurrently
+// according to the given configuration, if any.
+func limit(a *API, config Config) {
+	if config.MaxBody > 0 {
+		a.MaxBody = config.MaxBody
+	}
+	if config.MaxInFlight > 0 {
+		a.Handler = limitInFlight(config.MaxInFlight, a.Handler)
+	}
+}
+
+// limitInFlight returns a handler that invokes h for at
+// most n requests concurrently. Further requests are
+// rejected with 503 Service Unavailable and a Retry-After
+// header instead of waiting for a request to complete.
+func limitInFlight(n int, h http.Handler) http.Handler {
+	inFlight := make(chan struct{}, n)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case inFlight <- struct{}{}:
+			defer func() { <-inFlight }()
+		default:
+			w.Header().Set("Retry-After", "1")
+			Fail(w, errTooManyAPIRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLimit(t *testing.T) {
+	var (
+		block   = make(chan struct{})
+		started = make(chan struct{})
+	)
+	a := API{
+		Method:  http.MethodPost,
+		Path:    "/v1/key/create/",
+		MaxBody: 1 << 20,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			<-block
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+	limit(&a, Config{})
+	if a.MaxBody != 1<<20 {
+		t.Fatalf("Invalid max. body size: got '%d' - want '%d'", a.MaxBody, 1<<20)
+	}
+
+	limit(&a, Config{MaxBody: 4 << 10, MaxInFlight: 1})
+	if a.MaxBody != 4<<10 {
+		t.Fatalf("Invalid max. body size: got '%d' - want '%d'", a.MaxBody, 4<<10)
+	}
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		a.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	a.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", w.Code, http.StatusServiceUnavailable)
+	}
+	if v := w.Header().Get("Retry-After"); v != "1" {
+		t.Fatalf("Invalid Retry-After header: got '%s' - want '%s'", v, "1")
+	}
+
+	close(block)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", code, http.StatusOK)
+	}
+
+	go func() {
+		w := httptest.NewRecorder()
+		a.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil))
+		done <- w.Code
+	}()
+	<-started
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", code, http.StatusOK)
+	}
+}
@@ -5,9 +5,16 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/log"
@@ -24,13 +31,7 @@ func errorLog(config *RouterConfig) API {
 	)
 
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if err := Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.RLocker(), func() error { return enclave.VerifyRequest(r) })
-		}); err != nil {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
 			Fail(w, err)
 			return
 		}
@@ -45,12 +46,13 @@ func errorLog(config *RouterConfig) API {
 		<-r.Context().Done() // Wait for the client to close the connection
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(handler)),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
 	}
 }
 
@@ -84,16 +86,176 @@ func edgeErrorLog(config *EdgeRouterConfig) API {
 
 		<-r.Context().Done() // Wait for the client to close the connection
 	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
+	}
+}
+
+func logLevel(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/level"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Level log.Level `json:"level"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if auth.Identify(r) != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Level: config.ErrorLog.Level(),
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeLogLevel(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/level"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	type Response struct {
+		Level log.Level `json:"level"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Level: config.ErrorLog.Level(),
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func setLogLevel(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPut
+		APIPath = "/v1/log/level/set/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if auth.Identify(r) != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+
+		level, err := log.ParseLevel(strings.TrimPrefix(r.URL.Path, APIPath))
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		config.ErrorLog.SetLevel(level)
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeSetLogLevel(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodPut
+		APIPath = "/v1/log/level/set/"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+
+		level, err := log.ParseLevel(strings.TrimPrefix(r.URL.Path, APIPath))
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		config.ErrorLog.SetLevel(level)
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
 	return API{
 		Method:  Method,
 		Path:    APIPath,
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(handler)),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
+// AuditQueueSize is the max. number of audit events
+// buffered for an audit log stream subscriber. Once
+// exceeded, the oldest events are dropped.
+const AuditQueueSize = 1000
+
 func auditLog(config *RouterConfig) API {
 	const (
 		Method      = http.MethodGet
@@ -105,33 +267,38 @@ func auditLog(config *RouterConfig) API {
 	)
 
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if err := Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.RLocker(), func() error { return enclave.VerifyRequest(r) })
-		}); err != nil {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
 			Fail(w, err)
 			return
 		}
+		filter, err := audit.ParseFilter(r.URL.Query())
+		if err != nil {
+			Fail(w, kes.NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
 
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 
-		out := https.FlushOnWrite(w)
+		// Audit events are queued per subscriber such that
+		// a slow subscriber cannot slow down other requests.
+		queue := log.NewQueue(https.FlushOnWrite(w), AuditQueueSize, config.Metrics.AuditEventDropped)
+		defer queue.Close()
+
+		out := filter.Writer(queue)
 		config.AuditLog.Add(out)
 		defer config.AuditLog.Remove(out)
 
 		<-r.Context().Done() // Wait for the client to close the connection
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(handler)),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
 	}
 }
 
@@ -154,22 +321,193 @@ func edgeAuditLog(config *EdgeRouterConfig) API {
 			Fail(w, err)
 			return
 		}
+		filter, err := audit.ParseFilter(r.URL.Query())
+		if err != nil {
+			Fail(w, kes.NewError(http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+
+		// Audit events are queued per subscriber such that
+		// a slow subscriber cannot slow down other requests.
+		queue := log.NewQueue(https.FlushOnWrite(w), AuditQueueSize, config.Metrics.AuditEventDropped)
+		defer queue.Close()
+
+		out := filter.Writer(queue)
+		config.AuditLog.Add(out)
+		defer config.AuditLog.Remove(out)
+
+		<-r.Context().Done() // Wait for the client to close the connection
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
+	}
+}
+
+// securityLog streams the security-relevant audit events,
+// like auth failures, denied requests, admin actions and
+// requests sent by the admin identity, to the client.
+func securityLog(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/security/trace"
+		MaxBody     = 0
+		Timeout     = 0 * time.Second // No timeout
+		Verify      = true
+		ContentType = "application/x-ndjson"
+	)
+
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
+			Fail(w, err)
+			return
+		}
+		admin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			Fail(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+
+		queue := log.NewQueue(https.FlushOnWrite(w), AuditQueueSize, config.Metrics.AuditEventDropped)
+		defer queue.Close()
+
+		out := audit.SecurityWriter(queue, admin)
+		config.AuditLog.Add(out)
+		defer config.AuditLog.Remove(out)
+
+		<-r.Context().Done() // Wait for the client to close the connection
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
+	}
+}
+
+func edgeSecurityLog(config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/log/security/trace"
+		MaxBody     int64
+		Timeout     = 0 * time.Second // No timeout
+		Verify      = true
+		ContentType = "application/x-ndjson"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			Fail(w, err)
+			return
+		}
+		admin, err := config.Identities.Admin(r.Context())
+		if err != nil {
+			Fail(w, err)
+			return
+		}
 
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 
-		out := https.FlushOnWrite(w)
+		queue := log.NewQueue(https.FlushOnWrite(w), AuditQueueSize, config.Metrics.AuditEventDropped)
+		defer queue.Close()
+
+		out := audit.SecurityWriter(queue, admin)
 		config.AuditLog.Add(out)
 		defer config.AuditLog.Remove(out)
 
 		<-r.Context().Done() // Wait for the client to close the connection
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(handler)),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(handler)),
+	}
+}
+
+// HeaderRequestID is the HTTP header that carries the ID
+// of a request. The server echos a valid request ID sent
+// by the client and otherwise assigns a random one.
+const HeaderRequestID = "X-Request-Id"
+
+// failureKey is the request context key of the
+// error returned by a HandlerFunc.
+type failureKey struct{}
+
+// logRequest assigns an ID to each request and logs
+// failed requests to logger. It logs server errors
+// with log.LevelError and all other requests with
+// log.LevelDebug.
+func logRequest(logger *log.Logger, h http.Handler) http.Handler {
+	if logger == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			var id [8]byte
+			rand.Read(id[:])
+			requestID = hex.EncodeToString(id[:])
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		var failure error
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), failureKey{}, &failure)))
+
+		level := log.LevelDebug
+		if failure != nil && statusCode(failure) >= http.StatusInternalServerError {
+			level = log.LevelError
+		}
+		if !logger.Enabled(level) {
+			return
+		}
+		fields := log.Fields{
+			Component: "api",
+			RequestID: requestID,
+			Enclave:   r.URL.Query().Get("enclave"),
+			Identity:  auth.Identify(r).String(),
+		}
+		if failure != nil {
+			logger.Logf(level, fields, "%s %s: %v", r.Method, r.URL.Path, failure)
+		} else {
+			logger.Logf(level, fields, "%s %s", r.Method, r.URL.Path)
+		}
+	})
+}
+
+// validRequestID reports whether id is a valid request ID.
+// A valid request ID is a non-empty string of at most 128
+// printable ASCII characters that do not contain spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
 	}
+	return true
 }
@@ -5,10 +5,16 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/metric"
 	"github.com/prometheus/common/expfmt"
 )
 
@@ -21,31 +27,24 @@ func metrics(config *RouterConfig) API {
 		Verify  = true
 	)
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if err := Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.RLocker(), func() error {
-				return enclave.VerifyRequest(r)
-			})
-		}); err != nil {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
 			Fail(w, err)
 			return
 		}
 
-		contentType := expfmt.Negotiate(r.Header)
+		contentType := expfmt.NegotiateIncludingOpenMetrics(r.Header)
 		w.Header().Set("Content-Type", string(contentType))
 		w.WriteHeader(http.StatusOK)
 		config.Metrics.EncodeTo(expfmt.NewEncoder(w, contentType))
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: handler,
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "text/plain",
+		Handler:     handler,
 	}
 }
 
@@ -69,18 +68,210 @@ func edgeMetrics(config *EdgeRouterConfig) API {
 			return
 		}
 
-		contentType := expfmt.Negotiate(r.Header)
+		contentType := expfmt.NegotiateIncludingOpenMetrics(r.Header)
 		w.Header().Set("Content-Type", string(contentType))
 		w.WriteHeader(http.StatusOK)
 
 		config.Metrics.EncodeTo(expfmt.NewEncoder(w, contentType))
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: handler,
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "text/plain",
+		Handler:     handler,
+	}
+}
+
+func topIdentities(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/metrics/identity"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		sysAdmin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if auth.Identify(r) != sysAdmin {
+			return kes.ErrNotAllowed
+		}
+		return writeTopIdentities(w, r, config.Metrics)
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    []topIdentity{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeTopIdentities(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/v1/metrics/identity"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		return writeTopIdentities(w, r, config.Metrics)
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/json",
+		Response:    []topIdentity{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// topIdentity contains the request statistics of one
+// identity returned by the top identities APIs.
+type topIdentity struct {
+	Identity kes.Identity `json:"identity"`
+	Requests uint64       `json:"requests"`
+	Errors   uint64       `json:"errors"`
+	Failures uint64       `json:"failures"`
+}
+
+// writeTopIdentities writes the request statistics of the
+// identities that sent the most requests as JSON to w. The
+// number of identities is specified by the 'n' query parameter
+// and defaults to 10.
+func writeTopIdentities(w http.ResponseWriter, r *http.Request, metrics *metric.Metrics) error {
+
+	n := 10
+	if s := r.URL.Query().Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n <= 0 || n > metric.MaxIdentities {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: n must be between 1 and "+strconv.Itoa(metric.MaxIdentities))
+		}
+	}
+
+	stats := metrics.TopIdentities(n)
+	response := make([]topIdentity, 0, len(stats))
+	for _, stat := range stats {
+		response = append(response, topIdentity{
+			Identity: stat.Identity,
+			Requests: stat.Requests,
+			Errors:   stat.Errors,
+			Failures: stat.Failures,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+	return nil
+}
+
+// MetricsConfig is a structure containing the configuration
+// of a metrics listener that serves the metrics and health
+// APIs separately from all other APIs.
+type MetricsConfig struct {
+	// Metrics are the server metrics.
+	Metrics *metric.Metrics
+
+	// Ready reports whether the server is ready to serve
+	// requests. It returns a non-nil error if the server
+	// cannot serve requests, e.g. because the keystore is
+	// not reachable.
+	Ready func(context.Context) error
+
+	// Token is an optional bearer token. If not empty,
+	// clients have to send the token in the Authorization
+	// header. Otherwise, the metrics listener accepts
+	// requests from arbitrary clients.
+	Token string
+
+	// Replay, if not nil, requires clients to sign requests
+	// with the Token instead of sending it, and rejects
+	// replayed requests. It has no effect without Token.
+	Replay *ReplayGuard
+}
+
+// NewMetricsHandler returns a new http.Handler for a
+// metrics listener. It serves the following APIs without
+// verifying client certificates:
+//
+//	GET /v1/metrics       Prometheus metrics
+//	GET /v1/health/live   Whether the server is running
+//	GET /v1/health/ready  Whether the server can serve requests
+func NewMetricsHandler(config *MetricsConfig) http.Handler {
+	type Response struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
 	}
+	writeHealth := func(w http.ResponseWriter, err error) {
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(Response{Status: "unavailable", Error: err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Status: "ok"})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/metrics", func(w http.ResponseWriter, r *http.Request) {
+		contentType := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		w.Header().Set("Content-Type", string(contentType))
+		w.WriteHeader(http.StatusOK)
+		config.Metrics.EncodeTo(expfmt.NewEncoder(w, contentType))
+	})
+	mux.HandleFunc("/v1/health/live", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, nil)
+	})
+	mux.HandleFunc("/v1/health/ready", func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if config.Ready != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+			defer cancel()
+			err = config.Ready(ctx)
+		}
+		writeHealth(w, err)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		Fail(w, kes.NewError(http.StatusNotFound, "not found"))
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Accept", http.MethodGet)
+			Fail(w, kes.NewError(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed)))
+			return
+		}
+		if config.Token != "" {
+			if err := verifyBearer(r, config.Token, config.Replay); err != nil {
+				if config.Replay == nil {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+				}
+				Fail(w, err)
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
 }
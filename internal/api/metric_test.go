@@ -0,0 +1,53 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes/internal/metric"
+)
+
+var metricsHandlerTests = []struct {
+	Method string
+	Path   string
+	Token  string
+	Ready  error
+	Status int
+}{
+	{Method: http.MethodGet, Path: "/v1/metrics", Status: http.StatusUnauthorized},                                       // 0
+	{Method: http.MethodGet, Path: "/v1/metrics", Token: "secret", Status: http.StatusUnauthorized},                      // 1
+	{Method: http.MethodGet, Path: "/v1/metrics", Token: "Bearer secret", Status: http.StatusOK},                         // 2
+	{Method: http.MethodGet, Path: "/v1/health/live", Token: "Bearer secret", Status: http.StatusOK},                     // 3
+	{Method: http.MethodGet, Path: "/v1/health/ready", Token: "Bearer secret", Status: http.StatusOK},                    // 4
+	{Method: http.MethodGet, Path: "/v1/health/ready", Token: "Bearer secret", Ready: errors.New("sealed"), Status: 503}, // 5
+	{Method: http.MethodGet, Path: "/v1/key/list/", Token: "Bearer secret", Status: http.StatusNotFound},                 // 6
+	{Method: http.MethodPost, Path: "/v1/metrics", Token: "Bearer secret", Status: http.StatusMethodNotAllowed},          // 7
+}
+
+func TestMetricsHandler(t *testing.T) {
+	for i, test := range metricsHandlerTests {
+		ready := test.Ready
+		handler := NewMetricsHandler(&MetricsConfig{
+			Metrics: metric.New(),
+			Ready:   func(context.Context) error { return ready },
+			Token:   "secret",
+		})
+
+		req := httptest.NewRequest(test.Method, test.Path, nil)
+		if test.Token != "" {
+			req.Header.Set("Authorization", test.Token)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		if resp.Code != test.Status {
+			t.Fatalf("Test %d: got status %d - want %d", i, resp.Code, test.Status)
+		}
+	}
+}
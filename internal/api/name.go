@@ -0,0 +1,175 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+const (
+	// DefaultMaxNameLength is the default max. length
+	// of key, policy, secret and other names in bytes.
+	DefaultMaxNameLength = 80
+
+	// MaxNameLength is the upper limit for the max. name
+	// length. Names are used as file names by some key
+	// stores and most file systems limit file names to
+	// 255 bytes.
+	MaxNameLength = 255
+)
+
+// NameRules controls which key, policy, secret and other
+// names the API accepts. The zero value accepts names of
+// up to DefaultMaxNameLength bytes that only contain numbers
+// (0-9), letters (a-z and A-Z) and '-' as well as '_'.
+//
+// Names are hierarchical. They may consist of multiple
+// '/'-separated segments, like "tenants/acme/my-key".
+// No segment must be empty or start with a '.'. Hence,
+// names like "a//b", "a/../b" or ".hidden" are rejected.
+//
+// Names are validated byte by byte. Hence, any non-ASCII
+// character is rejected, including characters that look
+// like a valid one, like the Unicode division slash, or
+// that are equivalent to one after Unicode normalization.
+// Names are never rewritten. Instead, a name that is not
+// in its normal form is rejected. Therefore, two different
+// names never refer to the same key.
+type NameRules struct {
+	// MaxLength is the max. length of names in bytes.
+	// If <= 0, DefaultMaxNameLength is used.
+	MaxLength int
+
+	// Extended enables the extended character set. Names
+	// may also contain '.' characters, like "key.v2" - for
+	// example, to use existing external key IDs.
+	Extended bool
+}
+
+// Validate returns an error if the max. name
+// length is greater than MaxNameLength.
+func (n NameRules) Validate() error {
+	if n.MaxLength > MaxNameLength {
+		return errors.New("api: max. name length must not exceed " + strconv.Itoa(MaxNameLength) + " bytes")
+	}
+	return nil
+}
+
+// verifyName reports whether the name is valid
+// with respect to the rules.
+func (n NameRules) verifyName(name string) error { return n.verify("name", name, false) }
+
+// verifyPattern reports whether the pattern is valid
+// with respect to the rules. In addition to the valid
+// name characters, a pattern may contain '*' characters
+// and may end with a '/' to match all names with the
+// prefix. See matchPattern.
+func (n NameRules) verifyPattern(pattern string) error { return n.verify("pattern", pattern, true) }
+
+func (n NameRules) verify(kind, s string, wildcard bool) error {
+	maxLength := n.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxNameLength
+	}
+
+	if s == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: "+kind+" is empty")
+	}
+	if len(s) > maxLength {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: "+kind+" is too long")
+	}
+	for i := 0; i < len(s); i++ { // Valid characters are: [ 0-9 , A-Z , a-z , - , _ , / ] and '.' or '*', if enabled
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+		case c >= 'A' && c <= 'Z':
+		case c >= 'a' && c <= 'z':
+		case c == '-':
+		case c == '_':
+		case c == '/':
+		case c == '.' && n.Extended:
+		case c == '*' && wildcard:
+		default:
+			return kes.NewError(http.StatusBadRequest, "invalid argument: "+kind+" contains invalid character")
+		}
+	}
+
+	segments := strings.Split(s, "/")
+	for i, segment := range segments {
+		if segment == "" && wildcard && i > 0 && i == len(segments)-1 {
+			continue // A pattern may end with a '/'
+		}
+		if segment == "" || segment[0] == '.' {
+			return kes.NewError(http.StatusBadRequest, "invalid argument: "+kind+" contains invalid path segment")
+		}
+	}
+	return nil
+}
+
+// verifyName reports whether the name is valid
+// with respect to the default NameRules.
+func verifyName(name string) error { return NameRules{}.verifyName(name) }
+
+// verifyPattern reports whether the pattern is valid
+// with respect to the default NameRules.
+func verifyPattern(pattern string) error { return NameRules{}.verifyPattern(pattern) }
+
+// verifyEnclaveName reports whether the name is a valid
+// enclave name. Enclave names are always subject to the
+// default NameRules and must not contain a prefix.
+func verifyEnclaveName(name string) error {
+	if strings.IndexByte(name, '/') >= 0 {
+		return kes.NewError(http.StatusBadRequest, "invalid argument: enclave name contains invalid character")
+	}
+	return verifyName(name)
+}
+
+// matchPattern reports whether the name matches the
+// pattern. A pattern that ends with a '/', like
+// "tenants/acme/", matches all names with this prefix,
+// including names with further segments, like
+// "tenants/acme/dev/my-key". Any other pattern is
+// matched as defined by path.Match. Hence, a '*' does
+// not match a '/'.
+func matchPattern(pattern, name string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(name, pattern)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// patternPrefix returns the literal prefix of the
+// pattern, i.e. everything before the first '*'.
+// Any name that matches the pattern starts with
+// this prefix.
+func patternPrefix(pattern string) string {
+	if i := strings.IndexByte(pattern, '*'); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+type nameRulesKey struct{}
+
+// withNameRules returns a copy of ctx that carries
+// the given rules. Names of requests with such a
+// context are verified using these rules.
+func withNameRules(ctx context.Context, rules NameRules) context.Context {
+	return context.WithValue(ctx, nameRulesKey{}, rules)
+}
+
+// nameRulesFromContext returns the NameRules of ctx, if
+// any. Otherwise, it returns the default NameRules.
+func nameRulesFromContext(ctx context.Context) NameRules {
+	rules, _ := ctx.Value(nameRulesKey{}).(NameRules)
+	return rules
+}
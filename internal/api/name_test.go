@@ -0,0 +1,138 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNameRulesVerifyName(t *testing.T) {
+	for i, test := range nameRulesVerifyNameTests {
+		err := test.Rules.verifyName(test.Name)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: name '%s' is valid but got rejected: %v", i, test.Name, err)
+		}
+	}
+}
+
+func TestNameRulesVerifyPattern(t *testing.T) {
+	for i, test := range nameRulesVerifyPatternTests {
+		err := test.Rules.verifyPattern(test.Pattern)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: pattern '%s' is valid but got rejected: %v", i, test.Pattern, err)
+		}
+	}
+}
+
+func TestNameRulesValidate(t *testing.T) {
+	if err := (NameRules{MaxLength: MaxNameLength, Extended: true}).Validate(); err != nil {
+		t.Fatalf("Valid name rules got rejected: %v", err)
+	}
+	if err := (NameRules{MaxLength: MaxNameLength + 1}).Validate(); err == nil {
+		t.Fatal("Name rules with a max. length greater than MaxNameLength are valid")
+	}
+}
+
+func TestNameFromRequestRules(t *testing.T) {
+	const Name = "tenants/acme/key.v2"
+
+	r := httptest.NewRequest("POST", "/v1/key/create/"+Name, nil)
+	if _, err := nameFromRequest(r, "/v1/key/create/"); err == nil {
+		t.Fatalf("Name '%s' is valid without extended name rules", Name)
+	}
+
+	r = r.WithContext(withNameRules(r.Context(), NameRules{Extended: true}))
+	name, err := nameFromRequest(r, "/v1/key/create/")
+	if err != nil {
+		t.Fatalf("Name '%s' got rejected: %v", Name, err)
+	}
+	if name != Name {
+		t.Fatalf("got '%s' - want '%s'", name, Name)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	for i, test := range matchPatternTests {
+		if ok := matchPattern(test.Pattern, test.Name); ok != test.Match {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, ok, test.Match)
+		}
+		if test.Match && !strings.HasPrefix(test.Name, patternPrefix(test.Pattern)) {
+			t.Fatalf("Test %d: name '%s' does not start with pattern prefix '%s'", i, test.Name, patternPrefix(test.Pattern))
+		}
+	}
+}
+
+var nameRulesVerifyNameTests = []struct {
+	Rules      NameRules
+	Name       string
+	ShouldFail bool
+}{
+	{Rules: NameRules{}, Name: "my-key"},                                                 // 0
+	{Rules: NameRules{}, Name: strings.Repeat("a", DefaultMaxNameLength)},                // 1
+	{Rules: NameRules{}, Name: strings.Repeat("a", 81), ShouldFail: true},                // 2
+	{Rules: NameRules{MaxLength: 200}, Name: strings.Repeat("a", 200)},                   // 3
+	{Rules: NameRules{MaxLength: 200}, Name: strings.Repeat("a", 201), ShouldFail: true}, // 4
+	{Rules: NameRules{MaxLength: 20}, Name: strings.Repeat("a", 21), ShouldFail: true},   // 5
+	{Rules: NameRules{}, Name: "key.v2", ShouldFail: true},                               // 6
+	{Rules: NameRules{}, Name: "a/b/c"},                                                  // 7
+
+	{Rules: NameRules{Extended: true}, Name: "key.v2"},                                       // 8
+	{Rules: NameRules{Extended: true}, Name: "tenants/acme/key-1"},                           // 9
+	{Rules: NameRules{Extended: true}, Name: "arn-aws-kms/eu-west-1/1234.abcd"},              // 10
+	{Rules: NameRules{Extended: true}, Name: "sys/my-key"},                                   // 11
+	{Rules: NameRules{Extended: true}, Name: ".hidden", ShouldFail: true},                    // 12
+	{Rules: NameRules{Extended: true}, Name: "a/.b", ShouldFail: true},                       // 13
+	{Rules: NameRules{Extended: true}, Name: "a/../b", ShouldFail: true},                     // 14
+	{Rules: NameRules{Extended: true}, Name: "a//b", ShouldFail: true},                       // 15
+	{Rules: NameRules{Extended: true}, Name: "/a", ShouldFail: true},                         // 16
+	{Rules: NameRules{Extended: true}, Name: "a/", ShouldFail: true},                         // 17
+	{Rules: NameRules{Extended: true}, Name: "a*", ShouldFail: true},                         // 18
+	{Rules: NameRules{Extended: true}, Name: "a\u2215b", ShouldFail: true},                   // 19 - division slash
+	{Rules: NameRules{Extended: true}, Name: "key\uff0ev2", ShouldFail: true},                // 20 - fullwidth full stop
+	{Rules: NameRules{Extended: true}, Name: "caf\u00e9", ShouldFail: true},                  // 21
+	{Rules: NameRules{Extended: true}, Name: "cafe\u0301", ShouldFail: true},                 // 22 - combining acute accent
+	{Rules: NameRules{Extended: true}, Name: "key\xff", ShouldFail: true},                    // 23 - invalid UTF-8
+	{Rules: NameRules{Extended: true, MaxLength: 10}, Name: "a/b/c/d/e/f", ShouldFail: true}, // 24
+}
+
+var nameRulesVerifyPatternTests = []struct {
+	Rules      NameRules
+	Pattern    string
+	ShouldFail bool
+}{
+	{Rules: NameRules{}, Pattern: "my-*"},                                                // 0
+	{Rules: NameRules{}, Pattern: "*.v2", ShouldFail: true},                              // 1
+	{Rules: NameRules{}, Pattern: "*/*/*"},                                               // 2
+	{Rules: NameRules{Extended: true}, Pattern: "*.v2"},                                  // 3
+	{Rules: NameRules{Extended: true}, Pattern: "tenants/*/*"},                           // 4
+	{Rules: NameRules{Extended: true}, Pattern: "*/.*", ShouldFail: true},                // 5
+	{Rules: NameRules{Extended: true}, Pattern: "*//*", ShouldFail: true},                // 6
+	{Rules: NameRules{Extended: true, MaxLength: 4}, Pattern: "abc-*", ShouldFail: true}, // 7
+}
+
+var matchPatternTests = []struct {
+	Pattern string
+	Name    string
+	Match   bool
+}{
+	{Pattern: "*", Name: "my-key", Match: true},                            // 0
+	{Pattern: "*", Name: "tenants/my-key", Match: false},                   // 1
+	{Pattern: "tenants/*", Name: "tenants/my-key", Match: true},            // 2
+	{Pattern: "tenants/*", Name: "tenants/acme/my-key", Match: false},      // 3
+	{Pattern: "tenants/", Name: "tenants/my-key", Match: true},             // 4
+	{Pattern: "tenants/", Name: "tenants/acme/my-key", Match: true},        // 5
+	{Pattern: "tenants/acme/", Name: "tenants/acme/my-key", Match: true},   // 6
+	{Pattern: "tenants/acme/", Name: "tenants/acme2/my-key", Match: false}, // 7
+	{Pattern: "tenants/", Name: "tenants", Match: false},                   // 8
+	{Pattern: "tenants/*/my-*", Name: "tenants/acme/my-key", Match: true},  // 9
+}
@@ -0,0 +1,396 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/sys"
+)
+
+// openAPIDocument is an OpenAPI 3.1 document.
+type openAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       openAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components openAPIComponents                      `json:"components"`
+	Security   []map[string][]string                  `json:"security"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+
+	MaxBody int64      `json:"x-kes-max-body"`         // Max. request body size in bytes
+	Timeout int64      `json:"x-kes-timeout"`          // Timeout in seconds. 0 means no timeout
+	Sunset  *time.Time `json:"x-kes-sunset,omitempty"` // Time after which the API may be removed
+}
+
+type openAPIParameter struct {
+	Name        string        `json:"name"`
+	In          string        `json:"in"`
+	Description string        `json:"description,omitempty"`
+	Required    bool          `json:"required"`
+	Schema      openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                   `json:"$ref,omitempty"`
+	Type                 string                   `json:"type,omitempty"`
+	Format               string                   `json:"format,omitempty"`
+	Items                *openAPISchema           `json:"items,omitempty"`
+	Properties           map[string]openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema           `json:"additionalProperties,omitempty"`
+	Required             []string                 `json:"required,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]openAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// newOpenAPIDocument returns an OpenAPI document describing
+// the given APIs. If enclaves is true, each API accepts an
+// optional enclave query parameter.
+//
+// APIs with a trailing '/' in their path take a name, or a
+// pattern for list APIs, as last path segment. The request
+// and response body schemas are derived from the API's
+// Request and Response types.
+func newOpenAPIDocument(apis []API, enclaves bool) openAPIDocument {
+	const (
+		SecurityScheme = "mTLS"
+		ErrorSchema    = "Error"
+	)
+	errorResponse := openAPIResponse{
+		Description: "Error response",
+		Content: map[string]openAPIMediaType{
+			"application/json": {Schema: openAPISchema{Ref: "#/components/schemas/" + ErrorSchema}},
+		},
+	}
+
+	doc := openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       "KES API",
+			Description: "The API of the KES server. Clients authenticate with a TLS client certificate.",
+			Version:     sys.BinaryInfo().Version,
+		},
+		Paths: make(map[string]map[string]openAPIOperation, len(apis)),
+		Components: openAPIComponents{
+			Schemas: map[string]openAPISchema{
+				ErrorSchema: {
+					Type: "object",
+					Properties: map[string]openAPISchema{
+						"message": {Type: "string"},
+						"code":    {Type: "string"},
+					},
+					Required: []string{"message"},
+				},
+			},
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				SecurityScheme: {
+					Type:        "mutualTLS",
+					Description: "The client identity is the SHA-256 hash of the client certificate's public key.",
+				},
+			},
+		},
+		Security: []map[string][]string{{SecurityScheme: {}}},
+	}
+	for _, api := range apis {
+		path := api.Path
+		op := openAPIOperation{
+			OperationID: operationID(api),
+			Responses: map[string]openAPIResponse{
+				"200":     successResponse(api),
+				"default": errorResponse,
+			},
+			Deprecated: api.Legacy(),
+			MaxBody:    api.MaxBody,
+			Timeout:    int64(api.Timeout.Truncate(time.Second).Seconds()),
+		}
+		if !api.Sunset.IsZero() {
+			sunset := api.Sunset
+			op.Sunset = &sunset
+		}
+		if !api.Verify {
+			op.Security = []map[string][]string{{}} // No authentication required
+		}
+		if strings.HasSuffix(path, "/") {
+			param := openAPIParameter{
+				Name:        "name",
+				In:          "path",
+				Description: "The name of the resource",
+				Required:    true,
+				Schema:      openAPISchema{Type: "string"},
+			}
+			if strings.HasSuffix(path, "/list/") {
+				param.Name = "pattern"
+				param.Description = "A glob pattern selecting resource names, e.g. '*'"
+			}
+			path += "{" + param.Name + "}"
+			op.Parameters = append(op.Parameters, param)
+		}
+		if enclaves {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:        "enclave",
+				In:          "query",
+				Description: "The enclave name. Defaults to the default enclave",
+				Schema:      openAPISchema{Type: "string"},
+			})
+		}
+		if api.Request != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content: map[string]openAPIMediaType{
+					"application/json": {Schema: schemaOf(reflect.TypeOf(api.Request), nil)},
+				},
+			}
+		}
+
+		if _, ok := doc.Paths[path]; !ok {
+			doc.Paths[path] = map[string]openAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(api.Method)] = op
+	}
+	return doc
+}
+
+// successResponse returns the OpenAPI response of the API
+// if a request succeeds.
+func successResponse(api API) openAPIResponse {
+	if api.ContentType == "" {
+		return openAPIResponse{Description: "Success"}
+	}
+
+	var schema openAPISchema
+	switch {
+	case api.Response != nil:
+		schema = schemaOf(reflect.TypeOf(api.Response), nil)
+	case !strings.Contains(api.ContentType, "json"):
+		schema = openAPISchema{Type: "string"}
+	}
+	description := "Success"
+	if api.ContentType == "application/x-ndjson" || api.ContentType == "text/event-stream" {
+		description = "Success. The response body is a stream of messages"
+	}
+	return openAPIResponse{
+		Description: description,
+		Content: map[string]openAPIMediaType{
+			api.ContentType: {Schema: schema},
+		},
+	}
+}
+
+// schemaOf returns the OpenAPI schema of the JSON encoding
+// of values of type t. It returns an empty schema, matching
+// any value, if the JSON encoding of t is not known.
+//
+// The seen types are the struct types that enclose t. They
+// are used to stop at recursive types.
+func schemaOf(t reflect.Type, seen []reflect.Type) openAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return openAPISchema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return openAPISchema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return openAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return openAPISchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return openAPISchema{Type: "number"}
+	case reflect.String:
+		return openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return openAPISchema{Type: "string", Format: "byte"} // Base64-encoded
+		}
+		items := schemaOf(t.Elem(), seen)
+		return openAPISchema{Type: "array", Items: &items}
+	case reflect.Map:
+		values := schemaOf(t.Elem(), seen)
+		return openAPISchema{Type: "object", AdditionalProperties: &values}
+	case reflect.Struct:
+		for _, s := range seen {
+			if s == t {
+				return openAPISchema{Type: "object"}
+			}
+		}
+		schema := openAPISchema{Type: "object", Properties: map[string]openAPISchema{}}
+		addProperties(&schema, t, append(seen, t))
+		return schema
+	default:
+		return openAPISchema{}
+	}
+}
+
+// addProperties adds the JSON properties of the struct
+// type t to the schema. The fields of embedded structs
+// are added as properties of t, like encoding/json does.
+func addProperties(schema *openAPISchema, t reflect.Type, seen []reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addProperties(schema, embedded, seen)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type, seen)
+		for _, option := range strings.Split(options, ",") {
+			if option == "string" {
+				property = openAPISchema{Type: "string"}
+			}
+		}
+		schema.Properties[name] = property
+	}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// operationID returns a unique operation ID for the API
+// derived from its method and path. For example:
+//
+//	POST /v1/key/create/ => post-key-create
+func operationID(api API) string {
+	path := strings.Trim(strings.TrimPrefix(api.Path, "/v1"), "/")
+	if path == "" {
+		path = "root"
+	}
+	return strings.ToLower(api.Method) + "-" + strings.ReplaceAll(path, "/", "-")
+}
+
+func openAPI(router *Router, config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/openapi.json"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
+			Fail(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newOpenAPIDocument(router.API(), true))
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    openAPIDocument{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeOpenAPI(router *Router, config *EdgeRouterConfig) API {
+	var (
+		Method      = http.MethodGet
+		APIPath     = "/v1/openapi.json"
+		MaxBody     int64
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+		Verify = !c.InsecureSkipAuth
+	}
+	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); Verify && err != nil {
+			Fail(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newOpenAPIDocument(router.API(), false))
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    openAPIDocument{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	apis := []API{
+		{Method: http.MethodGet, Path: "/version", Timeout: 15 * time.Second, Verify: false},
+		{Method: http.MethodPost, Path: "/v1/key/create/", Timeout: 15 * time.Second, Verify: true},
+		{Method: http.MethodGet, Path: "/v1/key/list/", Timeout: 15 * time.Second, Verify: true},
+		{Method: http.MethodPost, Path: "/v1/policy/write/", MaxBody: 1 << 20, Timeout: 15 * time.Second, Verify: true, Request: struct {
+			Allow []string `json:"allow"`
+		}{}},
+	}
+	doc := newOpenAPIDocument(apis, true)
+
+	for i, test := range openAPIDocumentTests {
+		op, ok := doc.Paths[test.Path][test.Method]
+		if !ok {
+			t.Fatalf("Test %d: no operation for '%s %s'", i, test.Method, test.Path)
+		}
+		if op.OperationID != test.OperationID {
+			t.Fatalf("Test %d: got operation ID '%s' - want '%s'", i, op.OperationID, test.OperationID)
+		}
+		if len(op.Parameters) != test.Parameters {
+			t.Fatalf("Test %d: got %d parameters - want %d", i, len(op.Parameters), test.Parameters)
+		}
+		if public := op.Security != nil; public != test.Public {
+			t.Fatalf("Test %d: got public '%v' - want '%v'", i, public, test.Public)
+		}
+		if hasBody := op.RequestBody != nil; hasBody != test.RequestBody {
+			t.Fatalf("Test %d: got request body '%v' - want '%v'", i, hasBody, test.RequestBody)
+		}
+	}
+}
+
+var openAPIDocumentTests = []struct {
+	Method      string
+	Path        string
+	OperationID string
+	Parameters  int
+	Public      bool
+	RequestBody bool
+}{
+	{Method: "get", Path: "/version", OperationID: "get-version", Parameters: 1, Public: true},                            // 0
+	{Method: "post", Path: "/v1/key/create/{name}", OperationID: "post-key-create", Parameters: 2},                        // 1
+	{Method: "get", Path: "/v1/key/list/{pattern}", OperationID: "get-key-list", Parameters: 2},                           // 2
+	{Method: "post", Path: "/v1/policy/write/{name}", OperationID: "post-policy-write", Parameters: 2, RequestBody: true}, // 3
+}
+
+func TestOpenAPISchema(t *testing.T) {
+	type Embedded struct {
+		Fingerprint string `json:"fingerprint"`
+	}
+	type Response struct {
+		Embedded
+		Name      string            `json:"name"`
+		Size      uint64            `json:"size,omitempty"`
+		Plaintext []byte            `json:"plaintext"`
+		CreatedAt time.Time         `json:"created_at"`
+		Tags      map[string]string `json:"tags"`
+		Identity  *kes.Identity     `json:"identity"`
+		Count     int64             `json:"count,string"`
+		Internal  string            `json:"-"`
+		private   string
+	}
+	want := openAPISchema{
+		Type: "object",
+		Properties: map[string]openAPISchema{
+			"fingerprint": {Type: "string"},
+			"name":        {Type: "string"},
+			"size":        {Type: "integer"},
+			"plaintext":   {Type: "string", Format: "byte"},
+			"created_at":  {Type: "string", Format: "date-time"},
+			"tags":        {Type: "object", AdditionalProperties: &openAPISchema{Type: "string"}},
+			"identity":    {Type: "string"},
+			"count":       {Type: "string"},
+		},
+	}
+
+	api := API{Method: http.MethodGet, Path: "/v1/key/list/", ContentType: "application/x-ndjson", Response: Response{}}
+	op := newOpenAPIDocument([]API{api}, false).Paths["/v1/key/list/{pattern}"]["get"]
+	schema := op.Responses["200"].Content[api.ContentType].Schema
+	if !reflect.DeepEqual(schema, want) {
+		t.Fatalf("Invalid schema: got '%+v' - want '%+v'", schema, want)
+	}
+
+	api = API{Method: http.MethodGet, Path: "/v1/api", ContentType: "application/json", Response: []Response{}}
+	op = newOpenAPIDocument([]API{api}, false).Paths["/v1/api"]["get"]
+	schema = op.Responses["200"].Content[api.ContentType].Schema
+	if schema.Type != "array" || schema.Items == nil || !reflect.DeepEqual(*schema.Items, want) {
+		t.Fatalf("Invalid schema: got '%+v' - want array of '%+v'", schema, want)
+	}
+	if schema = schemaOf(reflect.TypeOf(openAPIDocument{}), nil); schema.Type != "object" {
+		t.Fatalf("Invalid schema of recursive type: got '%+v'", schema)
+	}
+}
@@ -6,8 +6,8 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"path"
 	"time"
 
 	"aead.dev/mem"
@@ -25,7 +25,8 @@ func assignPolicy(config *RouterConfig) API {
 		Verify  = true
 	)
 	type Request struct {
-		Identity kes.Identity `json:"identity"`
+		Identity  kes.Identity `json:"identity"`
+		ExpiresAt time.Time    `json:"expires_at"` // optional
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -33,42 +34,51 @@ func assignPolicy(config *RouterConfig) API {
 			return err
 		}
 
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		if err = verifyName(req.Identity.String()); err != nil {
+			return err
+		}
+		if req.Identity.IsUnknown() {
+			return kes.NewError(http.StatusBadRequest, "identity is unknown")
+		}
+		if !req.ExpiresAt.IsZero() && !time.Now().Before(req.ExpiresAt) {
+			return kes.NewError(http.StatusBadRequest, "policy assignment expiry is in the past")
+		}
+		if self := auth.Identify(r); self == req.Identity {
+			return kes.NewError(http.StatusForbidden, "identity cannot assign policy to itself")
+		}
+		admin, err := config.Vault.Admin(r.Context())
+		if err != nil {
+			return err
+		}
+		if admin == req.Identity {
+			return kes.NewError(http.StatusBadRequest, "cannot assign policy to system admin")
+		}
+		enclaveAdmin, err := isEnclaveAdmin(r.Context(), enclave, req.Identity)
+		if err != nil {
+			return err
+		}
+		if enclaveAdmin || name == auth.EnclaveAdminPolicy {
+			if err = verifyEnclaveOwner(config.Vault, enclave, r); err != nil {
 				return err
 			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-
-				var req Request
-				if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-					return err
-				}
-				if err = verifyName(req.Identity.String()); err != nil {
-					return err
-				}
-				if req.Identity.IsUnknown() {
-					return kes.NewError(http.StatusBadRequest, "identity is unknown")
-				}
-				if self := auth.Identify(r); self == req.Identity {
-					return kes.NewError(http.StatusForbidden, "identity cannot assign policy to itself")
-				}
-				admin, err := config.Vault.Admin(r.Context())
-				if err != nil {
-					return err
-				}
-				if admin == req.Identity {
-					return kes.NewError(http.StatusBadRequest, "cannot assign policy to system admin")
-				}
-				return enclave.AssignPolicy(r.Context(), name, req.Identity)
-			})
-		}); err != nil {
+		}
+		if err = enclave.AssignPolicyUntil(r.Context(), name, req.Identity, req.ExpiresAt); err != nil {
 			return err
 		}
 
+		config.Events.Publish(r, EventIdentityAssigned, req.Identity.String())
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -78,7 +88,8 @@ func assignPolicy(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -92,8 +103,9 @@ func describePolicy(config *RouterConfig) API {
 		ContentType = "application/json"
 	)
 	type Response struct {
-		CreatedAt time.Time    `json:"created_at,omitempty"`
-		CreatedBy kes.Identity `json:"created_by,omitempty"`
+		CreatedAt   time.Time    `json:"created_at,omitempty"`
+		CreatedBy   kes.Identity `json:"created_by,omitempty"`
+		RetainUntil *time.Time   `json:"retain_until,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -101,37 +113,40 @@ func describePolicy(config *RouterConfig) API {
 			return err
 		}
 
-		policy, err := VSync(config.Vault.RLocker(), func() (auth.Policy, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return auth.Policy{}, err
-			}
-			return VSync(enclave.RLocker(), func() (auth.Policy, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return auth.Policy{}, err
-				}
-				return enclave.GetPolicy(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		policy, err := enclave.GetPolicy(r.Context(), name)
 		if err != nil {
 			return err
 		}
 
 		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("ETag", policyETag(policy))
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(Response{
+		response := Response{
 			CreatedAt: policy.CreatedAt,
 			CreatedBy: policy.CreatedBy,
-		})
+		}
+		if !policy.RetainUntil.IsZero() {
+			response.RetainUntil = &policy.RetainUntil
+		}
+		json.NewEncoder(w).Encode(response)
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -168,6 +183,7 @@ func edgeDescribePolicy(config *EdgeRouterConfig) API {
 		}
 
 		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("ETag", policyETag(*policy))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
 			CreatedAt: policy.CreatedAt,
@@ -176,12 +192,14 @@ func edgeDescribePolicy(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -199,6 +217,11 @@ func readPolicy(config *RouterConfig) API {
 		Deny      []string     `json:"deny,omitempty"`
 		CreatedAt time.Time    `json:"created_at,omitempty"`
 		CreatedBy kes.Identity `json:"created_by,omitempty"`
+
+		Certificates   map[string]auth.CertificateProfile `json:"certificates,omitempty"`
+		MaxRandomBytes int                                `json:"max_random_bytes,omitempty"`
+		Tokens         *auth.TokenProfile                 `json:"tokens,omitempty"`
+		MaxSSHTTL      string                             `json:"max_ssh_ttl,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -206,39 +229,48 @@ func readPolicy(config *RouterConfig) API {
 			return err
 		}
 
-		policy, err := VSync(config.Vault.RLocker(), func() (auth.Policy, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return auth.Policy{}, err
-			}
-			return VSync(enclave.RLocker(), func() (auth.Policy, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return auth.Policy{}, err
-				}
-				return enclave.GetPolicy(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		policy, err := enclave.GetPolicy(r.Context(), name)
 		if err != nil {
 			return err
 		}
 
+		var maxSSHTTL string
+		if policy.MaxSSHTTL > 0 {
+			maxSSHTTL = policy.MaxSSHTTL.String()
+		}
+
 		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("ETag", policyETag(policy))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
 			Allow:     policy.Allow,
 			Deny:      policy.Deny,
 			CreatedAt: policy.CreatedAt,
 			CreatedBy: policy.CreatedBy,
+
+			Certificates:   policy.Certificates,
+			MaxRandomBytes: policy.MaxRandomBytes,
+			Tokens:         policy.Tokens,
+			MaxSSHTTL:      maxSSHTTL,
 		})
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -277,6 +309,7 @@ func edgeReadPolicy(config *EdgeRouterConfig) API {
 		}
 
 		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("ETag", policyETag(*policy))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
 			Allow:     policy.Allow,
@@ -287,12 +320,14 @@ func edgeReadPolicy(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -307,38 +342,86 @@ func writePolicy(config *RouterConfig) API {
 	type Request struct {
 		Allow []string `json:"allow,omitempty"`
 		Deny  []string `json:"deny,omitempty"`
+
+		Certificates   map[string]auth.CertificateProfile `json:"certificates,omitempty"`
+		MaxRandomBytes int                                `json:"max_random_bytes,omitempty"`
+		Tokens         *auth.TokenProfile                 `json:"tokens,omitempty"`
+		MaxSSHTTL      string                             `json:"max_ssh_ttl,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
 		if err != nil {
 			return err
 		}
+		retainUntil, err := retentionFromRequest(r)
+		if err != nil {
+			return err
+		}
 
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = config.ReservedPrefixes.verify(r, name, enclave.VerifyRequest); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		for profileName, profile := range req.Certificates {
+			if err = config.Names.verifyName(profileName); err != nil {
 				return err
 			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-
-				var req Request
-				if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-					return err
-				}
-				return enclave.SetPolicy(r.Context(), name, auth.Policy{
-					Allow:     req.Allow,
-					Deny:      req.Deny,
-					CreatedAt: time.Now().UTC(),
-					CreatedBy: auth.Identify(r),
-				})
+			if err = config.Names.verifyName(profile.Key); err != nil {
+				return err
+			}
+			if err = profile.Validate(); err != nil {
+				return kes.NewError(http.StatusBadRequest, "invalid certificate profile '"+profileName+"': "+err.Error())
+			}
+		}
+		if req.MaxRandomBytes < 0 || req.MaxRandomBytes > MaxRandomBytes {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid max_random_bytes: must be between 0 and %d", MaxRandomBytes))
+		}
+		if req.Tokens != nil {
+			if err = req.Tokens.Validate(); err != nil {
+				return kes.NewError(http.StatusBadRequest, "invalid token profile: "+err.Error())
+			}
+		}
+		var maxSSHTTL time.Duration
+		if req.MaxSSHTTL != "" {
+			if maxSSHTTL, err = time.ParseDuration(req.MaxSSHTTL); err != nil || maxSSHTTL < 0 {
+				return kes.NewError(http.StatusBadRequest, "invalid max_ssh_ttl '"+req.MaxSSHTTL+"'")
+			}
+		}
+		policy := auth.Policy{
+			Allow:          req.Allow,
+			Deny:           req.Deny,
+			Certificates:   req.Certificates,
+			MaxRandomBytes: req.MaxRandomBytes,
+			Tokens:         req.Tokens,
+			MaxSSHTTL:      maxSSHTTL,
+			CreatedAt:      time.Now().UTC(),
+			CreatedBy:      auth.Identify(r),
+			RetainUntil:    retainUntil,
+		}
+		if r.Header.Get("If-Match") == "" && r.Header.Get("If-None-Match") == "" {
+			err = enclave.SetPolicy(r.Context(), name, policy)
+		} else {
+			err = enclave.SetPolicyIf(r.Context(), name, policy, func(current auth.Policy, exists bool) error {
+				return checkPreconditions(r, policyETag(current), exists)
 			})
-		}); err != nil {
+		}
+		if err != nil {
 			return err
 		}
 
+		w.Header().Set("ETag", policyETag(policy))
+		config.Events.Publish(r, EventPolicyWritten, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -348,7 +431,8 @@ func writePolicy(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(config.ReservedPrefixes.verifier(APIPath, enclaveVerifier(config.Vault)), handler)))),
 	}
 }
 
@@ -366,21 +450,18 @@ func deletePolicy(config *RouterConfig) API {
 			return err
 		}
 
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-				return enclave.DeletePolicy(r.Context(), name)
-			})
-		}); err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = enclave.DeletePolicy(r.Context(), name); err != nil {
 			return err
 		}
 
+		config.Events.Publish(r, EventPolicyDeleted, name)
 		w.WriteHeader(http.StatusOK)
 		return nil
 	}
@@ -390,7 +471,7 @@ func deletePolicy(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -416,49 +497,48 @@ func listPolicy(config *RouterConfig) API {
 			return err
 		}
 
-		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		hasWritten, err := func() (bool, error) {
+			iterator, err := enclave.ListPolicies(r.Context())
 			if err != nil {
 				return false, err
 			}
-			return VSync(enclave.RLocker(), func() (bool, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return false, err
+			defer iterator.Close()
+
+			var hasWritten bool
+			encoder := json.NewEncoder(w)
+			for iterator.Next() {
+				if !matchPattern(pattern, iterator.Name()) {
+					continue
+				}
+				if !hasWritten {
+					hasWritten = true
+					w.Header().Set("Content-Type", ContentType)
+					w.WriteHeader(http.StatusOK)
 				}
-				iterator, err := enclave.ListPolicies(r.Context())
+
+				policy, err := enclave.GetPolicy(r.Context(), iterator.Name())
 				if err != nil {
-					return false, err
+					return hasWritten, err
 				}
-				defer iterator.Close()
-
-				var hasWritten bool
-				encoder := json.NewEncoder(w)
-				for iterator.Next() {
-					if ok, _ := path.Match(pattern, iterator.Name()); !ok {
-						continue
-					}
-					if !hasWritten {
-						hasWritten = true
-						w.Header().Set("Content-Type", ContentType)
-						w.WriteHeader(http.StatusOK)
-					}
-
-					policy, err := enclave.GetPolicy(r.Context(), iterator.Name())
-					if err != nil {
-						return hasWritten, err
-					}
-					err = encoder.Encode(Response{
-						Name:      iterator.Name(),
-						CreatedAt: policy.CreatedAt,
-						CreatedBy: policy.CreatedBy,
-					})
-					if err != nil {
-						return hasWritten, err
-					}
+				err = encoder.Encode(Response{
+					Name:      iterator.Name(),
+					CreatedAt: policy.CreatedAt,
+					CreatedBy: policy.CreatedBy,
+				})
+				if err != nil {
+					return hasWritten, err
 				}
-				return hasWritten, iterator.Close()
-			})
-		})
+			}
+			return hasWritten, iterator.Close()
+		}()
 		if err != nil {
 			if hasWritten {
 				json.NewEncoder(w).Encode(Response{Err: err.Error()})
@@ -472,12 +552,14 @@ func listPolicy(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -521,7 +603,7 @@ func edgeListPolicy(config *EdgeRouterConfig) API {
 		encoder := json.NewEncoder(w)
 		w.Header().Set("Content-Type", ContentType)
 		for iterator.Next() {
-			if ok, _ := path.Match(pattern, iterator.Name()); !ok {
+			if !matchPattern(pattern, iterator.Name()) {
 				continue
 			}
 			if !hasWritten {
@@ -556,11 +638,13 @@ func edgeListPolicy(config *EdgeRouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
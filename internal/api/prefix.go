@@ -0,0 +1,97 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+)
+
+// ReservedPrefixes maps reserved key and policy name prefixes,
+// like "sys/", to the policies whose identities can create keys
+// and policies within them.
+//
+// Reserved prefixes separate the keys and policies of automated
+// systems from those of humans, such that they cannot collide.
+// Only admins and identities assigned to one of the designated
+// policies can create a key or policy whose name starts with a
+// reserved prefix. Using such keys and policies is still subject
+// to the policy checks.
+type ReservedPrefixes map[string][]string
+
+// Validate returns an error if any prefix is not a valid
+// name followed by a '/' or any policy name is invalid.
+func (p ReservedPrefixes) Validate() error {
+	for prefix, policies := range p {
+		name := strings.TrimSuffix(prefix, "/")
+		if len(name) == len(prefix) || strings.Contains(name, "/") || verifyName(name) != nil {
+			return errors.New("api: invalid reserved prefix '" + prefix + "': prefix must be a name followed by a '/'")
+		}
+		if len(policies) == 0 {
+			return errors.New("api: invalid reserved prefix '" + prefix + "': no policy specified")
+		}
+		for _, policy := range policies {
+			if strings.Contains(policy, "/") || verifyName(policy) != nil {
+				return errors.New("api: invalid policy '" + policy + "' for reserved prefix '" + prefix + "'")
+			}
+		}
+	}
+	return nil
+}
+
+// verify returns an error if the name starts with a reserved
+// prefix and the request identity is neither an admin nor
+// assigned to one of the prefix's policies.
+//
+// It determines the identity's policy by verifying a copy of
+// r that records the policy decision. The original request
+// should have been verified before.
+func (p ReservedPrefixes) verify(r *http.Request, name string, verify func(*http.Request) error) error {
+	i := strings.IndexByte(name, '/')
+	if i < 0 {
+		return nil
+	}
+	prefix := name[:i+1]
+	policies, ok := p[prefix]
+	if !ok {
+		return nil
+	}
+
+	req := r.Clone(auth.WithDecisionRecorder(r.Context()))
+	if err := verify(req); err != nil {
+		return err
+	}
+	decision, ok := auth.DecisionFromContext(req.Context())
+	if ok && decision.Admin {
+		return nil
+	}
+	for _, policy := range policies {
+		if ok && decision.Policy == policy {
+			return nil
+		}
+	}
+	return kes.NewError(http.StatusForbidden, "not authorized: name prefix '"+prefix+"' is reserved")
+}
+
+// verifier returns a function that verifies requests with
+// verify and returns an error if the name within the request
+// URL path, following apiPath, starts with a reserved prefix
+// the request identity must not use.
+func (p ReservedPrefixes) verifier(apiPath string, verify func(*http.Request) error) func(*http.Request) error {
+	return func(r *http.Request) error {
+		name, err := nameFromRequest(r, apiPath)
+		if err != nil {
+			return err
+		}
+		if err = verify(r); err != nil {
+			return err
+		}
+		return p.verify(r, name, verify)
+	}
+}
@@ -7,6 +7,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 )
 
@@ -22,3 +23,29 @@ func proxy(proxy *auth.TLSProxy, f http.Handler) http.Handler {
 		f.ServeHTTP(w, r)
 	})
 }
+
+// authorize returns a handler that attaches the authorizer
+// to the request context such that requests, which pass the
+// built-in policy checks, get authorized by it. If authorizer
+// is nil, authorize returns h.
+func authorize(authorizer auth.Authorizer, h http.Handler) http.Handler {
+	if authorizer == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(auth.WithAuthorizer(r.Context(), authorizer)))
+	})
+}
+
+// recordDecisions returns a handler that records the policy
+// decision of requests such that their audit events contain
+// it depending on the given level. If level is
+// audit.DecisionsOff, recordDecisions returns h.
+func recordDecisions(level audit.DecisionLevel, h http.Handler) http.Handler {
+	if level == audit.DecisionsOff {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(audit.WithDecisions(r.Context(), level)))
+	})
+}
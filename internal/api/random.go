@@ -0,0 +1,101 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// MaxRandomBytes is the max. number of random bytes any
+// identity, including admins, can request at once. Policies
+// cannot grant more.
+const MaxRandomBytes = int(64 * mem.KiB)
+
+// defaultRandomBytes is the number of random bytes returned
+// if the client does not request a specific length.
+const defaultRandomBytes = 32
+
+func randomBytes(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/random"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Bytes []byte `json:"bytes"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		length := defaultRandomBytes
+		if s := r.URL.Query().Get("length"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return kes.NewError(http.StatusBadRequest, "invalid length '"+s+"'")
+			}
+			length = n
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		// The max. length is defined by the policy the
+		// requesting identity is assigned to.
+		limit := MaxRandomBytes
+		info, err := enclave.GetIdentity(r.Context(), auth.Identify(r))
+		if err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+			return err
+		}
+		if !info.IsAdmin && info.Policy != "" {
+			policy, err := enclave.GetPolicy(r.Context(), info.Policy)
+			if err != nil {
+				return err
+			}
+			if limit = policy.RandomBytesLimit(); limit > MaxRandomBytes {
+				limit = MaxRandomBytes
+			}
+		}
+		if length > limit {
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid length: must not exceed %d bytes", limit))
+		}
+
+		b := make([]byte, length)
+		if _, err = rand.Read(b); err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Bytes: b})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kesclient"
+)
+
+// DefaultClockSkew is the max. difference between the
+// timestamp of a signed request and the server clock a
+// ReplayGuard tolerates by default.
+const DefaultClockSkew = 5 * time.Minute
+
+var errRequestReplayed = &kesclient.Error{
+	Code:    kesclient.CodeRequestReplayed,
+	Status:  http.StatusUnauthorized,
+	Message: "request has already been processed",
+}
+
+// ReplayConfig is a structure containing the configuration
+// of a ReplayGuard.
+type ReplayConfig struct {
+	// ClockSkew is the max. difference between the timestamp
+	// of a signed request and the server clock. If <= 0,
+	// DefaultClockSkew is used.
+	ClockSkew time.Duration
+}
+
+// NewReplayGuard returns a new ReplayGuard with the given
+// configuration.
+func NewReplayGuard(config *ReplayConfig) *ReplayGuard {
+	g := &ReplayGuard{
+		skew:   config.ClockSkew,
+		nonces: map[int64]map[string]struct{}{},
+	}
+	if g.skew <= 0 {
+		g.skew = DefaultClockSkew
+	}
+	return g
+}
+
+// A ReplayGuard verifies requests signed with a bearer token,
+// as done by kesclient.SignRequest, and rejects replayed ones.
+//
+// A signed request is rejected if its timestamp differs from
+// the server clock by more than the clock skew or if its nonce
+// has been seen before. A nonce is remembered as long as the
+// timestamp of its request is within the clock skew. Hence,
+// the memory is bounded by the number of authentic requests
+// within the clock skew.
+//
+// Only the metrics and SCIM listeners authenticate clients
+// with bearer tokens. The KES API authenticates clients with
+// TLS client certificates, also when using API keys, and TLS
+// already prevents replaying captured requests.
+type ReplayGuard struct {
+	skew time.Duration
+
+	lock    sync.Mutex
+	nonces  map[int64]map[string]struct{} // Timestamp -> nonces of requests signed at it
+	sweptAt int64                         // Unix time of the last removal of expired nonces
+}
+
+// Verify verifies that r is signed with the given bearer
+// token and has not been processed before. It reads the
+// request body and replaces r.Body such that the body
+// can be read again.
+func (g *ReplayGuard) Verify(r *http.Request, token string) error {
+	const MaxNonceLength = 128
+
+	var (
+		timestamp = r.Header.Get(kesclient.HeaderTimestamp)
+		nonce     = r.Header.Get(kesclient.HeaderNonce)
+		signature = r.Header.Get(kesclient.HeaderSignature)
+	)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return kes.NewError(http.StatusUnauthorized, "request is not signed")
+	}
+	if len(nonce) > MaxNonceLength {
+		return kes.NewError(http.StatusUnauthorized, "invalid request nonce: nonce is too long")
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return kes.NewError(http.StatusUnauthorized, "invalid request timestamp")
+	}
+	now := time.Now()
+	if signedAt := time.Unix(sec, 0); signedAt.Before(now.Add(-g.skew)) || signedAt.After(now.Add(g.skew)) {
+		return kes.NewError(http.StatusUnauthorized, "request timestamp is outside the tolerated clock skew")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+				return kes.NewError(http.StatusRequestEntityTooLarge, "request body too large")
+			}
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	want := kesclient.RequestSignature(token, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if subtle.ConstantTimeCompare([]byte(strings.ToLower(signature)), []byte(want)) != 1 {
+		return kes.NewError(http.StatusUnauthorized, "invalid request signature")
+	}
+
+	// Only remember nonces of authentic requests such that
+	// clients without the token cannot flood the guard.
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	// Requests signed before the clock skew are rejected.
+	// Hence, their nonces can be forgotten.
+	if unix := now.Unix(); unix != g.sweptAt {
+		g.sweptAt = unix
+		oldest := now.Add(-g.skew).Unix()
+		for t := range g.nonces {
+			if t < oldest {
+				delete(g.nonces, t)
+			}
+		}
+	}
+
+	// The signature covers the timestamp. Hence, a replayed
+	// request has the same timestamp as the original one and
+	// nonces only have to be unique per timestamp.
+	nonces, ok := g.nonces[sec]
+	if !ok {
+		nonces = map[string]struct{}{}
+		g.nonces[sec] = nonces
+	}
+	if _, ok = nonces[nonce]; ok {
+		return errRequestReplayed
+	}
+	nonces[nonce] = struct{}{}
+	return nil
+}
+
+// verifyBearer verifies that r carries the given bearer
+// token in its Authorization header. If guard is not nil,
+// it verifies that r is signed with the token instead and
+// has not been replayed.
+func verifyBearer(r *http.Request, token string, guard *ReplayGuard) error {
+	if guard != nil {
+		return guard.Verify(r, token)
+	}
+	header := r.Header.Get("Authorization")
+	t := strings.TrimPrefix(header, "Bearer ")
+	if len(t) == len(header) || subtle.ConstantTimeCompare([]byte(t), []byte(token)) != 1 {
+		return kes.NewError(http.StatusUnauthorized, "invalid or missing bearer token")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/kesclient"
+)
+
+func TestReplayGuard(t *testing.T) {
+	const Token = "my-token"
+
+	newRequest := func(body string) *http.Request {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/scim/v2/Users?count=10", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+Token)
+		if err := kesclient.SignRequest(req, Token); err != nil {
+			t.Fatalf("Failed to sign request: %v", err)
+		}
+		if req.Header.Get("Authorization") != "" {
+			t.Fatal("Signed request contains the bearer token")
+		}
+		return req
+	}
+
+	guard := NewReplayGuard(&ReplayConfig{})
+	req := newRequest(`{"userName":"my-app"}`)
+	if err := guard.Verify(req, Token); err != nil {
+		t.Fatalf("Failed to verify signed request: %v", err)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"userName":"my-app"}` {
+		t.Fatalf("Invalid request body: got '%s' - want '%s'", body, `{"userName":"my-app"}`)
+	}
+
+	req.Body = io.NopCloser(strings.NewReader(`{"userName":"my-app"}`))
+	if err := guard.Verify(req, Token); !errors.Is(err, errRequestReplayed) {
+		t.Fatalf("Replayed request: got '%v' - want '%v'", err, errRequestReplayed)
+	}
+
+	req = newRequest(`{"userName":"my-app"}`)
+	req.Body = io.NopCloser(strings.NewReader(`{"userName":"other"}`))
+	if err := guard.Verify(req, Token); err == nil {
+		t.Fatal("Request with modified body has been accepted")
+	}
+	if err := guard.Verify(newRequest(""), "other-token"); err == nil {
+		t.Fatal("Request signed with another token has been accepted")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+Token)
+	if err := guard.Verify(req, Token); err == nil {
+		t.Fatal("Request without signature has been accepted")
+	}
+
+	req = newRequest("")
+	timestamp := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	req.Header.Set(kesclient.HeaderTimestamp, timestamp)
+	req.Header.Set(kesclient.HeaderSignature, kesclient.RequestSignature(Token, req.Method, req.URL.RequestURI(), timestamp, req.Header.Get(kesclient.HeaderNonce), nil))
+	if err := guard.Verify(req, Token); err == nil {
+		t.Fatal("Request outside of the clock skew has been accepted")
+	}
+	if err := NewReplayGuard(&ReplayConfig{ClockSkew: 15 * time.Minute}).Verify(req, Token); err != nil {
+		t.Fatalf("Failed to verify request within the clock skew: %v", err)
+	}
+}
+
+func TestReplayGuardSameSecond(t *testing.T) {
+	const (
+		Token    = "my-token"
+		Requests = 1000
+	)
+
+	sign := func(signedAt time.Time) *http.Request {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/metrics", nil)
+		if err := kesclient.SignRequest(req, Token); err != nil {
+			t.Fatalf("Failed to sign request: %v", err)
+		}
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		req.Header.Set(kesclient.HeaderTimestamp, timestamp)
+		req.Header.Set(kesclient.HeaderSignature, kesclient.RequestSignature(Token, req.Method, req.URL.RequestURI(), timestamp, req.Header.Get(kesclient.HeaderNonce), nil))
+		return req
+	}
+
+	// All distinct requests signed within the same second
+	// or earlier, but within the clock skew, must be accepted.
+	now := time.Now()
+	guard := NewReplayGuard(&ReplayConfig{})
+	requests := make([]*http.Request, 0, Requests)
+	for i := 0; i < Requests; i++ {
+		req := sign(now)
+		if err := guard.Verify(req, Token); err != nil {
+			t.Fatalf("Request %d: failed to verify request: %v", i, err)
+		}
+		requests = append(requests, req)
+	}
+	if err := guard.Verify(sign(now.Add(-time.Minute)), Token); err != nil {
+		t.Fatalf("Failed to verify earlier request: %v", err)
+	}
+	for i, req := range requests {
+		if err := guard.Verify(req, Token); !errors.Is(err, errRequestReplayed) {
+			t.Fatalf("Request %d: replayed request: got '%v' - want '%v'", i, err, errRequestReplayed)
+		}
+	}
+
+	// Nonces of requests signed before the clock skew
+	// must be forgotten.
+	expired := now.Add(-2 * DefaultClockSkew).Unix()
+	guard.lock.Lock()
+	guard.nonces[expired] = map[string]struct{}{"nonce": {}}
+	guard.sweptAt = 0
+	guard.lock.Unlock()
+	if err := guard.Verify(sign(now), Token); err != nil {
+		t.Fatalf("Failed to verify request: %v", err)
+	}
+	if _, ok := guard.nonces[expired]; ok {
+		t.Fatal("Nonces of expired requests have not been forgotten")
+	}
+}
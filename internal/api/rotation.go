@@ -0,0 +1,281 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/dbrotate"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/secret"
+	"github.com/minio/kes/internal/sys"
+)
+
+// MinRotationInterval is the smallest interval between
+// two scheduled rotations of a secret.
+const MinRotationInterval = time.Minute
+
+// defaultRotationClient is the HTTP client used to invoke
+// rotation webhooks if no other client is configured.
+var defaultRotationClient = &http.Client{Timeout: 15 * time.Second}
+
+// SecretRotationConfig is a structure containing the
+// configuration of a SecretRotation.
+type SecretRotationConfig struct {
+	// Client is the HTTP client used to invoke rotation
+	// webhooks. If nil, a client with a 15 second timeout
+	// is used.
+	Client *http.Client
+
+	// Interval is the time period between two checks for
+	// secrets that are due for rotation. If <= 0, defaults
+	// to 1 minute.
+	Interval time.Duration
+}
+
+// SecretRotation periodically rotates all secrets of a
+// Vault with an enabled rotation schedule once they are
+// due for rotation.
+//
+// Within a cluster, only the leader should rotate secrets.
+// Otherwise, multiple nodes may produce new versions of the
+// same secret.
+type SecretRotation struct {
+	vault  *sys.Vault
+	client *http.Client
+
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// NewSecretRotation returns a new SecretRotation that
+// rotates the secrets of the given Vault in the background
+// until the SecretRotation is closed.
+func NewSecretRotation(vault *sys.Vault, config *SecretRotationConfig) *SecretRotation {
+	r := &SecretRotation{
+		vault:  vault,
+		client: config.Client,
+	}
+	if r.client == nil {
+		r.client = defaultRotationClient
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stop = cancel
+	r.done.Add(1)
+	go func() {
+		defer r.done.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := r.rotateAll(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, kes.ErrSealed) {
+				log.Printf("api: failed to rotate secrets: %v", err)
+			}
+		}
+	}()
+	return r
+}
+
+// Close stops the SecretRotation and waits until any
+// in-progress rotation has finished.
+func (r *SecretRotation) Close() error {
+	r.stop()
+	r.done.Wait()
+	return nil
+}
+
+// rotateAll rotates all secrets of all enclaves that are
+// due for rotation. A failed rotation of a secret does not
+// prevent the rotation of other secrets. It gets retried
+// with the next rotateAll call.
+func (r *SecretRotation) rotateAll(ctx context.Context) error {
+	enclaves, err := r.vault.ListEnclaves(ctx)
+	if err != nil {
+		return err
+	}
+	defer enclaves.Close()
+
+	for enclaves.Next() {
+		enclaveName := enclaves.Name()
+		if enclaveName == "" {
+			continue
+		}
+		enclave, err := r.vault.GetEnclave(ctx, enclaveName)
+		if err != nil {
+			return err
+		}
+		if err = r.rotateEnclave(ctx, enclaveName, enclave); err != nil {
+			return err
+		}
+	}
+	return enclaves.Close()
+}
+
+// rotateEnclave rotates all secrets of the enclave that
+// are due for rotation.
+func (r *SecretRotation) rotateEnclave(ctx context.Context, enclaveName string, enclave *sys.Enclave) error {
+	secrets, err := enclave.ListSecrets(ctx)
+	if err != nil {
+		return err
+	}
+	defer secrets.Close()
+
+	now := time.Now()
+	for secrets.Next() {
+		name := secrets.Name()
+		if name == "" {
+			continue
+		}
+		s, err := enclave.GetSecret(ctx, name)
+		if errors.Is(err, kes.ErrSecretNotFound) {
+			continue // Secret has been deleted concurrently
+		}
+		if err != nil {
+			return err
+		}
+		if next := s.NextRotation(); next.IsZero() || now.Before(next) {
+			continue
+		}
+		if _, err = invokeRotation(ctx, r.client, enclaveName, enclave, name, s.Rotation()); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("api: failed to rotate secret '%s' of enclave '%s': %v", name, enclaveName, err)
+		}
+	}
+	return secrets.Close()
+}
+
+// invokeRotation rotates the secret and stores the new value as
+// new version of the secret.
+//
+// If the rotation endpoint is a database URL, the secret value is
+// the password of the database user. The password is changed in
+// the database and stored as new version.
+//
+// Otherwise, the endpoint is a webhook. The webhook receives a
+// JSON object with the enclave, name and current version of the
+// secret. It has to respond with a JSON object containing the
+// new value as base64-encoded "bytes".
+func invokeRotation(ctx context.Context, client *http.Client, enclaveName string, enclave *sys.Enclave, name string, rotation secret.Rotation) (secret.Secret, error) {
+	type Request struct {
+		Enclave string `json:"enclave"`
+		Name    string `json:"name"`
+		Version uint64 `json:"version"`
+	}
+	type Response struct {
+		Bytes []byte `json:"bytes"`
+	}
+
+	s, err := enclave.GetSecret(ctx, name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	if dbrotate.IsDatabase(rotation.Endpoint) {
+		var (
+			rotated   secret.Secret
+			commitErr error
+		)
+		err = dbrotate.Rotate(ctx, rotation.Endpoint, s.Bytes(), func(password []byte) error {
+			rotated, commitErr = enclave.UpdateSecret(ctx, name, password)
+			return commitErr
+		})
+		if commitErr != nil {
+			return secret.Secret{}, commitErr
+		}
+		if err != nil {
+			return secret.Secret{}, errRotationFailed(rotation, err.Error())
+		}
+		return rotated, nil
+	}
+
+	body, err := json.Marshal(Request{
+		Enclave: enclaveName,
+		Name:    name,
+		Version: s.Version(),
+	})
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rotation.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return secret.Secret{}, errRotationFailed(rotation, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return secret.Secret{}, errRotationFailed(rotation, "responded with '"+resp.Status+"'")
+	}
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, 2*secret.MaxValueSize)).Decode(&response); err != nil {
+		return secret.Secret{}, errRotationFailed(rotation, "responded with invalid JSON: "+err.Error())
+	}
+	if len(response.Bytes) == 0 {
+		return secret.Secret{}, errRotationFailed(rotation, "responded with an empty secret")
+	}
+	if len(response.Bytes) > int(secret.MaxValueSize) {
+		return secret.Secret{}, errRotationFailed(rotation, "responded with a secret larger than "+mem.FormatSize(secret.MaxValueSize, 'B', -1))
+	}
+	return enclave.UpdateSecret(ctx, name, response.Bytes)
+}
+
+// errRotationFailed returns an error reporting that the
+// rotation endpoint failed to produce a new secret value.
+func errRotationFailed(rotation secret.Rotation, msg string) error {
+	kind := "webhook"
+	if dbrotate.IsDatabase(rotation.Endpoint) {
+		kind = "database"
+	}
+	return kes.NewError(http.StatusBadGateway, fmt.Sprintf("rotation %s '%s' failed: %s", kind, rotation.Endpoint, msg))
+}
+
+// verifyRotation returns an error if the rotation is neither
+// a zero Rotation nor a valid rotation schedule.
+func verifyRotation(rotation secret.Rotation) error {
+	if rotation == (secret.Rotation{}) {
+		return nil
+	}
+	if rotation.Endpoint == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid rotation: no endpoint specified")
+	}
+	if dbrotate.IsDatabase(rotation.Endpoint) {
+		if err := dbrotate.Verify(rotation.Endpoint); err != nil {
+			return kes.NewError(http.StatusBadRequest, "invalid rotation: "+strings.TrimPrefix(err.Error(), "dbrotate: "))
+		}
+	} else if endpoint, err := url.Parse(rotation.Endpoint); err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return kes.NewError(http.StatusBadRequest, "invalid rotation: endpoint must be an https, postgres or mysql URL")
+	}
+	if rotation.Interval < MinRotationInterval {
+		return kes.NewError(http.StatusBadRequest, "invalid rotation: interval must be at least "+MinRotationInterval.String())
+	}
+	return nil
+}
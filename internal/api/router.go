@@ -5,16 +5,20 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/key"
 	"github.com/minio/kes/internal/log"
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kesclient"
 )
 
 // RouterConfig is a structure containing the
@@ -26,8 +30,55 @@ type RouterConfig struct {
 
 	Proxy *auth.TLSProxy
 
+	Idempotency *IdempotencyCache
+
+	Events *EventStream
+
+	// Forwarder, if not nil, forwards write requests
+	// to the cluster leader. It is set when the server
+	// runs as follower.
+	Forwarder *Forwarder
+
+	// Cluster, if not nil, reports the status of
+	// the server and all other cluster nodes.
+	Cluster *Cluster
+
+	// UI controls whether the router serves
+	// the web console under /ui/.
+	UI bool
+
+	// Admission, if not nil, limits the number and
+	// size of requests handled concurrently.
+	Admission *Admission
+
+	// Authorizer, if not nil, authorizes requests
+	// that have passed the built-in policy checks.
+	Authorizer auth.Authorizer
+
+	// CA controls the lifetimes of certificates
+	// issued by the built-in certificate authority.
+	CA CAConfig
+
+	// KeyPolicy, if not nil, restricts the algorithms
+	// of keys created within any enclave. Enclaves may
+	// restrict them further.
+	KeyPolicy *key.AlgorithmPolicy
+
+	// ReservedPrefixes restricts which identities can
+	// create keys and policies with a reserved name
+	// prefix, like "sys/", within any enclave.
+	ReservedPrefixes ReservedPrefixes
+
+	// Names controls which key, policy, secret and
+	// other names the router accepts.
+	Names NameRules
+
 	AuditLog *log.Logger
 
+	// AuditDecisions controls for which requests audit
+	// events contain the policy decision.
+	AuditDecisions audit.DecisionLevel
+
 	ErrorLog *log.Logger
 }
 
@@ -36,6 +87,10 @@ type RouterConfig struct {
 type EdgeRouterConfig struct {
 	Keys *key.Cache
 
+	// KeyWrapper, if not nil, is the external KMS
+	// that wraps all keys stored at the keystore.
+	KeyWrapper key.Wrapper
+
 	Policies auth.PolicySet
 
 	Identities auth.IdentitySet
@@ -46,9 +101,52 @@ type EdgeRouterConfig struct {
 
 	APIConfig map[string]Config
 
+	Idempotency *IdempotencyCache
+
+	Events *EventStream
+
+	// UI controls whether the router serves
+	// the web console under /ui/.
+	UI bool
+
+	// Admission, if not nil, limits the number and
+	// size of requests handled concurrently.
+	Admission *Admission
+
+	// Authorizer, if not nil, authorizes requests
+	// that have passed the built-in policy checks.
+	Authorizer auth.Authorizer
+
+	// KeyPolicy, if not nil, restricts the algorithms
+	// of keys created by clients.
+	KeyPolicy *key.AlgorithmPolicy
+
+	// ReservedPrefixes restricts which identities can
+	// create keys with a reserved name prefix, like "sys/".
+	ReservedPrefixes ReservedPrefixes
+
+	// Names controls which key, policy and other
+	// names the router accepts.
+	Names NameRules
+
+	// ExternalKMS contains the external KMS, by name,
+	// that hold the keys referenced by external keys.
+	ExternalKMS map[string]key.ExternalKMS
+
 	AuditLog *log.Logger
 
+	// AuditDecisions controls for which requests audit
+	// events contain the policy decision.
+	AuditDecisions audit.DecisionLevel
+
 	ErrorLog *log.Logger
+
+	// Namespaces contains the router configurations of
+	// additional key namespaces. Requests select a namespace
+	// via the enclave query parameter. Requests without one
+	// or for the "default" enclave are handled by this
+	// configuration.
+	Namespaces map[string]*EdgeRouterConfig
 }
 
 // NewRouter returns a new API Router for a KES
@@ -56,24 +154,45 @@ type EdgeRouterConfig struct {
 func NewRouter(config *RouterConfig) *Router {
 	r := &Router{
 		handler: http.NewServeMux(),
+		names:   config.Names,
 	}
 
 	r.api = append(r.api, version(config))
 	r.api = append(r.api, status(config))
 	r.api = append(r.api, metrics(config))
+	r.api = append(r.api, topIdentities(config))
 	r.api = append(r.api, listAPI(r, config))
+	r.api = append(r.api, openAPI(r, config))
 
 	r.api = append(r.api, createKey(config))
 	r.api = append(r.api, importKey(config))
 	r.api = append(r.api, describeKey(config))
+	r.api = append(r.api, verifyKey(config))
 	r.api = append(r.api, listKey(config))
 	r.api = append(r.api, deleteKey(config))
+	r.api = append(r.api, bulkDeleteKey(config))
+	r.api = append(r.api, holdKey(config))
+	r.api = append(r.api, releaseKey(config))
+	r.api = append(r.api, disableKey(config))
+	r.api = append(r.api, enableKey(config))
+	r.api = append(r.api, setKeyAllowlist(config))
 	r.api = append(r.api, encryptKey(config))
 	r.api = append(r.api, generateKey(config))
 	r.api = append(r.api, decryptKey(config))
 	r.api = append(r.api, bulkDecryptKey(config))
+	r.api = append(r.api, rewrapKey(config))
+	r.api = append(r.api, agreeKey(config))
+	r.api = append(r.api, hashKey(config))
+	r.api = append(r.api, fpeEncryptKey(config))
+	r.api = append(r.api, fpeDecryptKey(config))
+	r.api = append(r.api, randomBytes(config))
+	r.api = append(r.api, tokenize(config))
+	r.api = append(r.api, detokenize(config))
 
 	r.api = append(r.api, createSecret(config))
+	r.api = append(r.api, updateSecret(config))
+	r.api = append(r.api, setSecretRotation(config))
+	r.api = append(r.api, rotateSecret(config))
 	r.api = append(r.api, describeSecret(config))
 	r.api = append(r.api, readSecret(config))
 	r.api = append(r.api, deleteSecret(config))
@@ -88,18 +207,61 @@ func NewRouter(config *RouterConfig) *Router {
 
 	r.api = append(r.api, describeIdentity(config))
 	r.api = append(r.api, selfDescribeIdentity(config))
+	r.api = append(r.api, selfIssueCertificate(config))
 	r.api = append(r.api, listIdentity(config))
 	r.api = append(r.api, deleteIdentity(config))
 
+	r.api = append(r.api, requestAccess(config))
+	r.api = append(r.api, approveAccess(config))
+	r.api = append(r.api, denyAccess(config))
+	r.api = append(r.api, listAccess(config))
+
+	r.api = append(r.api, sshPublicKey(config))
+	r.api = append(r.api, sshSign(config))
+
+	r.api = append(r.api, designateTokenKey(config))
+	r.api = append(r.api, signToken(config))
+	r.api = append(r.api, tokenKeySet(config))
+
+	r.api = append(r.api, certCA(config))
+	r.api = append(r.api, certIssue(config))
+
+	r.api = append(r.api, caCertificate(config))
+	r.api = append(r.api, caRevocationList(config))
+	r.api = append(r.api, caIssue(config))
+	r.api = append(r.api, caRevoke(config))
+
 	r.api = append(r.api, createEnclave(config))
 	r.api = append(r.api, describeEnclave(config))
+	r.api = append(r.api, listEnclave(config))
 	r.api = append(r.api, deleteEnclave(config))
 
+	r.api = append(r.api, events(config))
+	r.api = append(r.api, fsck(config))
+	r.api = append(r.api, cacheStats(config))
+	r.api = append(r.api, flushCache(config))
+
 	r.api = append(r.api, errorLog(config))
+	r.api = append(r.api, logLevel(config))
+	r.api = append(r.api, setLogLevel(config))
 	r.api = append(r.api, auditLog(config))
+	r.api = append(r.api, securityLog(config))
+
+	r.api = append(r.api, debugProfile(config))
+
+	if config.Cluster != nil {
+		r.api = append(r.api, clusterStatus(config))
+		r.api = append(r.api, clusterNodes(config))
+	}
+	if config.UI {
+		r.api = append(r.api, ui(config))
+	}
+	if config.Forwarder != nil {
+		forwardWrites(config, r.api)
+	}
 
 	for _, a := range r.api {
-		r.handler.Handle(a.Path, proxy(config.Proxy, a))
+		r.handler.Handle(a.Path, proxy(config.Proxy, trackUsage(config.Metrics, config.ErrorLog, a, logRequest(config.ErrorLog, admit(config.Admission, a, authorize(config.Authorizer, recordDecisions(config.AuditDecisions, negotiate(a))))))))
 	}
 	r.handler.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -113,17 +275,23 @@ func NewRouter(config *RouterConfig) *Router {
 func NewEdgeRouter(config *EdgeRouterConfig) *Router {
 	r := &Router{
 		handler: http.NewServeMux(),
+		names:   config.Names,
 	}
 
 	r.api = append(r.api, edgeVersion(config))
 	r.api = append(r.api, edgeStatus(config))
 	r.api = append(r.api, edgeMetrics(config))
+	r.api = append(r.api, edgeTopIdentities(config))
 	r.api = append(r.api, edgeListAPI(r, config))
+	r.api = append(r.api, edgeOpenAPI(r, config))
 
 	r.api = append(r.api, edgeCreateKey(config))
 	r.api = append(r.api, edgeImportKey(config))
+	r.api = append(r.api, edgeRegisterKey(config))
 	r.api = append(r.api, edgeDescribeKey(config))
+	r.api = append(r.api, edgeVerifyKey(config))
 	r.api = append(r.api, edgeDeleteKey(config))
+	r.api = append(r.api, edgeBulkDeleteKey(config))
 	r.api = append(r.api, edgeListKey(config))
 	r.api = append(r.api, edgeGenerateKey(config))
 	r.api = append(r.api, edgeEncryptKey(config))
@@ -138,16 +306,42 @@ func NewEdgeRouter(config *EdgeRouterConfig) *Router {
 	r.api = append(r.api, edgeSelfDescribeIdentity(config))
 	r.api = append(r.api, edgeListIdentity(config))
 
+	r.api = append(r.api, edgeEvents(config))
+	r.api = append(r.api, edgeCacheStats(config))
+	r.api = append(r.api, edgeFlushCache(config))
+
 	r.api = append(r.api, edgeErrorLog(config))
+	r.api = append(r.api, edgeLogLevel(config))
+	r.api = append(r.api, edgeSetLogLevel(config))
 	r.api = append(r.api, edgeAuditLog(config))
+	r.api = append(r.api, edgeSecurityLog(config))
 
+	r.api = append(r.api, edgeDebugProfile(config))
+
+	if config.UI {
+		r.api = append(r.api, edgeUI(config))
+	}
+
+	for i := range r.api {
+		if c, ok := config.APIConfig[r.api[i].Path]; ok {
+			deprecate(&r.api[i], c)
+			limit(&r.api[i], c)
+		}
+	}
 	for _, a := range r.api {
-		r.handler.Handle(a.Path, proxy(config.Proxy, a))
+		r.handler.Handle(a.Path, proxy(config.Proxy, trackUsage(config.Metrics, config.ErrorLog, a, logRequest(config.ErrorLog, admit(config.Admission, a, authorize(config.Authorizer, recordDecisions(config.AuditDecisions, a)))))))
 	}
 	r.handler.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(10 * time.Second))
 		Fail(w, kes.NewError(http.StatusNotImplemented, "not implemented"))
 	}))
+
+	if len(config.Namespaces) > 0 {
+		r.namespaces = make(map[string]*Router, len(config.Namespaces))
+		for name, nsConfig := range config.Namespaces {
+			r.namespaces[name] = NewEdgeRouter(nsConfig)
+		}
+	}
 	return r
 }
 
@@ -158,14 +352,35 @@ func NewEdgeRouter(config *EdgeRouterConfig) *Router {
 type Router struct {
 	handler *http.ServeMux
 	api     []API
+	names   NameRules
+
+	namespaces map[string]*Router // Edge key namespaces, if any
 }
 
 // ServeHTTP dispatches the request to the API handler whose
 // pattern most matches the request URL.
+//
+// If the Router serves multiple key namespaces, it dispatches
+// requests for another namespace, specified by the enclave
+// query parameter, to the namespace's Router.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if !strings.HasPrefix(req.URL.Path, "/") { // Ensure URL paths start with a '/'
 		req.URL.Path = "/" + req.URL.Path
 	}
+	if r.namespaces != nil {
+		if name := enclaveName(req); name != sys.DefaultEnclaveName {
+			router, ok := r.namespaces[name]
+			if !ok {
+				Fail(w, kes.ErrEnclaveNotFound)
+				return
+			}
+			router.ServeHTTP(w, req)
+			return
+		}
+	}
+	if r.names != (NameRules{}) {
+		req = req.WithContext(withNameRules(req.Context(), r.names))
+	}
 	r.handler.ServeHTTP(w, req)
 }
 
@@ -184,8 +399,24 @@ type HandlerFunc func(http.ResponseWriter, *http.Request) error
 
 // ServeHTTP calls f(w, r). If f returns a non-nil error
 // ServeHTTP sends an error response to the client.
+//
+// If the request deadline has been exceeded, ServeHTTP
+// sends a 504 Gateway Timeout error response, regardless
+// of the error returned by f.
 func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := f(w, r); err != nil {
+		failure := err
+		if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			failure = &kesclient.Error{
+				Code:    errDeadlineExceeded.Code,
+				Status:  errDeadlineExceeded.Status,
+				Message: errDeadlineExceeded.Message + ": " + err.Error(),
+			}
+			err = errDeadlineExceeded
+		}
+		if p, ok := r.Context().Value(failureKey{}).(*error); ok {
+			*p = failure
+		}
 		Fail(w, err)
 	}
 }
@@ -0,0 +1,89 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kes/kv"
+)
+
+var edgeRouterNamespaceTests = []struct {
+	Query       string
+	Status      int
+	Unavailable bool
+}{
+	{Query: "", Status: http.StatusOK, Unavailable: false},                               // 0
+	{Query: "?enclave=default", Status: http.StatusOK, Unavailable: false},               // 1
+	{Query: "?enclave=tenant", Status: http.StatusOK, Unavailable: true},                 // 2
+	{Query: "?enclave=other-tenant", Status: http.StatusNotFound},                        // 3
+	{Query: "?enclave=tenant&enclave=default", Status: http.StatusOK, Unavailable: true}, // 4
+}
+
+func TestEdgeRouterNamespace(t *testing.T) {
+	newConfig := func(store kv.Store[string, []byte]) *EdgeRouterConfig {
+		return &EdgeRouterConfig{
+			Keys:        key.NewCache(key.Store{Conn: store}, &key.CacheConfig{}),
+			Metrics:     metric.New(),
+			Idempotency: NewIdempotencyCache(0),
+			Events:      NewEventStream(),
+			AuditLog:    log.New(io.Discard, "", 0),
+			ErrorLog:    log.New(io.Discard, "", 0),
+			APIConfig: map[string]Config{
+				"/v1/status": {InsecureSkipAuth: true},
+			},
+		}
+	}
+	config := newConfig(&mem.Store{})
+	config.Namespaces = map[string]*EdgeRouterConfig{
+		"tenant": newConfig(offlineStore{&mem.Store{}}),
+	}
+	server := httptest.NewServer(NewEdgeRouter(config))
+	defer server.Close()
+
+	for i, test := range edgeRouterNamespaceTests {
+		resp, err := http.Get(server.URL + "/v1/status" + test.Query)
+		if err != nil {
+			t.Fatalf("Test %d: failed to send request: %v", i, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != test.Status {
+			t.Fatalf("Test %d: invalid status code: got '%d' - want '%d'", i, resp.StatusCode, test.Status)
+		}
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		var status struct {
+			Unavailable bool `json:"keystore_unavailable"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Test %d: failed to decode status: %v", i, err)
+		}
+		if status.Unavailable != test.Unavailable {
+			t.Fatalf("Test %d: keystore unavailable: got '%v' - want '%v'", i, status.Unavailable, test.Unavailable)
+		}
+	}
+}
+
+// offlineStore is a keystore that is never available.
+type offlineStore struct {
+	*mem.Store
+}
+
+func (offlineStore) Status(context.Context) (kv.State, error) {
+	return kv.State{}, errors.New("keystore is offline")
+}
@@ -0,0 +1,1087 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/scim"
+	"github.com/minio/kes/internal/sys"
+)
+
+// SCIMPath is the base path of the SCIM API. Identity
+// providers use https://<host>:<port>/scim/v2 as SCIM
+// endpoint.
+const SCIMPath = "/scim/v2"
+
+// DefaultSCIMPolicy is the policy SCIM users are assigned
+// to while they are not member of any group.
+const DefaultSCIMPolicy = "scim-user"
+
+// SCIMConfig is a structure containing the configuration
+// of a SCIM listener that lets identity providers, like
+// Okta or Azure AD, provision identities and their policy
+// assignments.
+type SCIMConfig struct {
+	Vault *sys.Vault
+
+	// Enclave is the enclave of the provisioned identities.
+	// If empty, identities are provisioned within the
+	// default enclave.
+	Enclave string
+
+	// DefaultPolicy is the policy users are assigned to
+	// while they are not member of any group. If empty,
+	// DefaultSCIMPolicy is used. Users assigned to a policy
+	// that does not exist cannot perform any operation.
+	DefaultPolicy string
+
+	// Token is the bearer token identity providers have
+	// to send in the Authorization header. If empty, all
+	// requests are rejected.
+	Token string
+
+	// Replay, if not nil, requires identity providers
+	// to sign requests with the Token instead of sending
+	// it, and rejects replayed requests.
+	Replay *ReplayGuard
+
+	Events *EventStream
+
+	AuditLog *log.Logger
+}
+
+// NewSCIMHandler returns a new http.Handler for a SCIM
+// listener. It maps SCIM users to identities and SCIM
+// groups to policies of an enclave:
+//
+//	GET    /scim/v2/ServiceProviderConfig
+//	GET    /scim/v2/ResourceTypes
+//	GET    /scim/v2/Users               List identities
+//	POST   /scim/v2/Users               Assign identity to the default policy
+//	GET    /scim/v2/Users/<identity>
+//	PUT    /scim/v2/Users/<identity>    (De)activate identity
+//	PATCH  /scim/v2/Users/<identity>    (De)activate identity
+//	DELETE /scim/v2/Users/<identity>    Delete identity
+//	GET    /scim/v2/Groups              List policies and their identities
+//	POST   /scim/v2/Groups              Link existing policy
+//	GET    /scim/v2/Groups/<policy>
+//	PUT    /scim/v2/Groups/<policy>     Replace the identities of the policy
+//	PATCH  /scim/v2/Groups/<policy>     Add or remove identities of the policy
+//
+// A deactivated user remains assigned to its policy but its
+// assignment has expired. Hence, it cannot perform any operation.
+// Since an identity is assigned to exactly one policy, adding a
+// user to a group removes it from its current group. Removing a
+// user from a group assigns it to the default policy.
+//
+// Policies are not created or deleted via SCIM. The enclave admin
+// policy, admin identities and the default policy are not exposed.
+func NewSCIMHandler(config *SCIMConfig) http.Handler {
+	const (
+		MaxBody = int64(1 * mem.MiB)
+		Timeout = 15 * time.Second
+	)
+	s := &scimServer{
+		vault:         config.Vault,
+		enclave:       config.Enclave,
+		defaultPolicy: config.DefaultPolicy,
+		events:        config.Events,
+	}
+	if s.enclave == "" {
+		s.enclave = sys.DefaultEnclaveName
+	}
+	if s.defaultPolicy == "" {
+		s.defaultPolicy = DefaultSCIMPolicy
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(SCIMPath+"/ServiceProviderConfig", scimMethods{http.MethodGet: s.serviceProviderConfig})
+	mux.Handle(SCIMPath+"/ResourceTypes", scimMethods{http.MethodGet: s.resourceTypes})
+	mux.Handle(SCIMPath+"/Users", scimMethods{
+		http.MethodGet:  s.listUsers,
+		http.MethodPost: s.createUser,
+	})
+	mux.Handle(SCIMPath+"/Users/", scimMethods{
+		http.MethodGet:    s.getUser,
+		http.MethodPut:    s.replaceUser,
+		http.MethodPatch:  s.patchUser,
+		http.MethodDelete: s.deleteUser,
+	})
+	mux.Handle(SCIMPath+"/Groups", scimMethods{
+		http.MethodGet:  s.listGroups,
+		http.MethodPost: s.createGroup,
+	})
+	mux.Handle(SCIMPath+"/Groups/", scimMethods{
+		http.MethodGet:    s.getGroup,
+		http.MethodPut:    s.replaceGroup,
+		http.MethodPatch:  s.patchGroup,
+		http.MethodDelete: s.deleteGroup,
+	})
+	mux.Handle("/", scimMethods{})
+
+	return audit.Log(config.AuditLog, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxBody)
+		if config.Token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeSCIMError(w, scim.Errorf(http.StatusUnauthorized, "", "invalid or missing bearer token"))
+			return
+		}
+		if err := verifyBearer(r, config.Token, config.Replay); err != nil {
+			if config.Replay == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			writeSCIMError(w, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), Timeout)
+		defer cancel()
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
+// scimMethods is an http.Handler that dispatches SCIM
+// requests to the handler of the request method.
+type scimMethods map[string]func(http.ResponseWriter, *http.Request) error
+
+func (m scimMethods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(m) == 0 {
+		writeSCIMError(w, scim.Errorf(http.StatusNotFound, "", "not found"))
+		return
+	}
+	handler, ok := m[r.Method]
+	if !ok {
+		methods := make([]string, 0, len(m))
+		for method := range m {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeSCIMError(w, scim.Errorf(http.StatusMethodNotAllowed, "", http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+	if err := handler(w, r); err != nil {
+		writeSCIMError(w, err)
+	}
+}
+
+type scimServer struct {
+	vault         *sys.Vault
+	enclave       string
+	defaultPolicy string
+	events        *EventStream
+}
+
+// scimIdentity is an identity that can be provisioned via SCIM.
+type scimIdentity struct {
+	Identity kes.Identity
+	Info     auth.IdentityInfo
+}
+
+func (s *scimServer) serviceProviderConfig(w http.ResponseWriter, r *http.Request) error {
+	type Supported struct {
+		Supported bool `json:"supported"`
+	}
+	type Filter struct {
+		Supported  bool `json:"supported"`
+		MaxResults int  `json:"maxResults"`
+	}
+	type Bulk struct {
+		Supported      bool `json:"supported"`
+		MaxOperations  int  `json:"maxOperations"`
+		MaxPayloadSize int  `json:"maxPayloadSize"`
+	}
+	type AuthenticationScheme struct {
+		Type        string `json:"type"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	type Response struct {
+		Schemas               []string               `json:"schemas"`
+		Patch                 Supported              `json:"patch"`
+		Bulk                  Bulk                   `json:"bulk"`
+		Filter                Filter                 `json:"filter"`
+		ChangePassword        Supported              `json:"changePassword"`
+		Sort                  Supported              `json:"sort"`
+		ETag                  Supported              `json:"etag"`
+		AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+		Meta                  scim.Meta              `json:"meta"`
+	}
+	return writeSCIM(w, http.StatusOK, Response{
+		Schemas: []string{scim.ServiceProviderConfigSchema},
+		Patch:   Supported{Supported: true},
+		Filter:  Filter{Supported: true, MaxResults: scimMaxResults},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with a static bearer token",
+		}},
+		Meta: scim.Meta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     scimLocation(r, "/ServiceProviderConfig"),
+		},
+	})
+}
+
+func (s *scimServer) resourceTypes(w http.ResponseWriter, r *http.Request) error {
+	type ResourceType struct {
+		Schemas  []string  `json:"schemas"`
+		ID       string    `json:"id"`
+		Name     string    `json:"name"`
+		Endpoint string    `json:"endpoint"`
+		Schema   string    `json:"schema"`
+		Meta     scim.Meta `json:"meta"`
+	}
+	resourceTypes := []ResourceType{
+		{
+			Schemas:  []string{scim.ResourceTypeSchema},
+			ID:       "User",
+			Name:     "User",
+			Endpoint: "/Users",
+			Schema:   scim.UserSchema,
+			Meta:     scim.Meta{ResourceType: "ResourceType", Location: scimLocation(r, "/ResourceTypes/User")},
+		},
+		{
+			Schemas:  []string{scim.ResourceTypeSchema},
+			ID:       "Group",
+			Name:     "Group",
+			Endpoint: "/Groups",
+			Schema:   scim.GroupSchema,
+			Meta:     scim.Meta{ResourceType: "ResourceType", Location: scimLocation(r, "/ResourceTypes/Group")},
+		},
+	}
+	page, err := scimPage(r, resourceTypes)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, page)
+}
+
+func (s *scimServer) listUsers(w http.ResponseWriter, r *http.Request) error {
+	filter, err := scimFilter(r, "username", "id")
+	if err != nil {
+		return err
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	identities, err := s.listIdentities(r.Context(), enclave)
+	if err != nil {
+		return err
+	}
+
+	users := []scim.User{}
+	for _, identity := range identities {
+		if filter != nil && identity.Identity.String() != filter.Value {
+			continue
+		}
+		users = append(users, s.user(r, identity.Identity, identity.Info))
+	}
+	page, err := scimPage(r, users)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, page)
+}
+
+func (s *scimServer) createUser(w http.ResponseWriter, r *http.Request) error {
+	var user scim.User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid user: %v", err)
+	}
+	identity := kes.Identity(user.UserName)
+	if err := verifyName(user.UserName); err != nil || identity.IsUnknown() {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid userName '%s': userName must be a KES identity", user.UserName)
+	}
+
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	if err = s.verifyAssignable(r.Context(), identity); err != nil {
+		return err
+	}
+	switch _, err = enclave.GetIdentity(r.Context(), identity); {
+	case err == nil:
+		return scim.Errorf(http.StatusConflict, scim.ErrUniqueness, "user '%s' already exists", identity)
+	case !errors.Is(err, kes.ErrIdentityNotFound):
+		return err
+	}
+
+	var expiresAt time.Time
+	if user.Active != nil && !*user.Active {
+		expiresAt = time.Now()
+	}
+	if err = enclave.AssignPolicyUntil(r.Context(), s.defaultPolicy, identity, expiresAt); err != nil {
+		return err
+	}
+	s.events.PublishConfig(s.enclave, EventIdentityAssigned, identity.String())
+
+	info, err := enclave.GetIdentity(r.Context(), identity)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", scimLocation(r, "/Users/"+identity.String()))
+	return writeSCIM(w, http.StatusCreated, s.user(r, identity, info))
+}
+
+func (s *scimServer) getUser(w http.ResponseWriter, r *http.Request) error {
+	identity, err := scimID(r, "/Users/")
+	if err != nil {
+		return err
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	info, err := s.loadUser(r.Context(), enclave, kes.Identity(identity))
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, s.user(r, kes.Identity(identity), info))
+}
+
+func (s *scimServer) replaceUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r, "/Users/")
+	if err != nil {
+		return err
+	}
+	identity := kes.Identity(id)
+
+	var user scim.User
+	if err = json.NewDecoder(r.Body).Decode(&user); err != nil {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid user: %v", err)
+	}
+	if user.UserName != "" && user.UserName != id {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrMutability, "userName cannot be changed")
+	}
+
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	info, err := s.loadUser(r.Context(), enclave, identity)
+	if err != nil {
+		return err
+	}
+	if info, err = s.setActive(r.Context(), enclave, identity, info, user.Active == nil || *user.Active); err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, s.user(r, identity, info))
+}
+
+func (s *scimServer) patchUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r, "/Users/")
+	if err != nil {
+		return err
+	}
+	identity := kes.Identity(id)
+
+	var req scim.PatchRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid patch request: %v", err)
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	info, err := s.loadUser(r.Context(), enclave, identity)
+	if err != nil {
+		return err
+	}
+
+	// Only the active and userName attributes are mapped to
+	// the identity. Changes of other attributes are ignored.
+	active := !info.Expired()
+	setAttribute := func(attr string, value json.RawMessage) error {
+		switch attr {
+		case "active":
+			v, err := scim.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			active = v
+		case "username":
+			var userName string
+			if err := json.Unmarshal(value, &userName); err != nil || userName != id {
+				return scim.Errorf(http.StatusBadRequest, scim.ErrMutability, "userName cannot be changed")
+			}
+		}
+		return nil
+	}
+	for _, op := range req.Operations {
+		attr := strings.ToLower(strings.TrimSpace(op.Path))
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		case "remove":
+			if attr == "active" || attr == "username" {
+				return scim.Errorf(http.StatusBadRequest, scim.ErrMutability, "attribute '%s' cannot be removed", op.Path)
+			}
+			continue
+		default:
+			return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid patch operation '%s'", op.Op)
+		}
+
+		if attr != "" {
+			if err = setAttribute(attr, op.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		var attributes map[string]json.RawMessage
+		if err = json.Unmarshal(op.Value, &attributes); err != nil {
+			return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid patch value: %v", err)
+		}
+		for name, value := range attributes {
+			if err = setAttribute(strings.ToLower(name), value); err != nil {
+				return err
+			}
+		}
+	}
+	if info, err = s.setActive(r.Context(), enclave, identity, info, active); err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, s.user(r, identity, info))
+}
+
+func (s *scimServer) deleteUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := scimID(r, "/Users/")
+	if err != nil {
+		return err
+	}
+	identity := kes.Identity(id)
+
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	if _, err = s.loadUser(r.Context(), enclave, identity); err != nil {
+		return err
+	}
+	if err = enclave.DeleteIdentity(r.Context(), identity); err != nil {
+		return err
+	}
+	s.events.PublishConfig(s.enclave, EventIdentityDeleted, identity.String())
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *scimServer) listGroups(w http.ResponseWriter, r *http.Request) error {
+	filter, err := scimFilter(r, "displayname", "id")
+	if err != nil {
+		return err
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+
+	iter, err := enclave.ListPolicies(r.Context())
+	if err != nil {
+		return err
+	}
+	var names []string
+	for iter.Next() {
+		if name := iter.Name(); s.isGroup(name) && (filter == nil || name == filter.Value) {
+			names = append(names, name)
+		}
+	}
+	if err = iter.Close(); err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var members map[string][]kes.Identity
+	if !scimExcludesMembers(r) {
+		if members, err = s.listMembers(r.Context(), enclave); err != nil {
+			return err
+		}
+	}
+	groups := make([]scim.Group, 0, len(names))
+	for _, name := range names {
+		policy, err := enclave.GetPolicy(r.Context(), name)
+		if errors.Is(err, kes.ErrPolicyNotFound) {
+			continue // Policy has been deleted concurrently
+		}
+		if err != nil {
+			return err
+		}
+		groups = append(groups, s.group(r, name, policy, members[name]))
+	}
+	page, err := scimPage(r, groups)
+	if err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, page)
+}
+
+func (s *scimServer) createGroup(w http.ResponseWriter, r *http.Request) error {
+	var group scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid group: %v", err)
+	}
+	if err := verifyName(group.DisplayName); err != nil || !s.isGroup(group.DisplayName) {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid displayName '%s'", group.DisplayName)
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+
+	// Groups are KES policies that get linked to the groups of
+	// the identity provider by their name. Since policies are
+	// not created via SCIM, creating a group for an existing
+	// policy reports a conflict, such that the identity provider
+	// links the group, and fails otherwise.
+	switch _, err = enclave.GetPolicy(r.Context(), group.DisplayName); {
+	case err == nil:
+		return scim.Errorf(http.StatusConflict, scim.ErrUniqueness, "group '%s' already exists", group.DisplayName)
+	case errors.Is(err, kes.ErrPolicyNotFound):
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "policy '%s' does not exist: groups must refer to an existing policy", group.DisplayName)
+	default:
+		return err
+	}
+}
+
+func (s *scimServer) getGroup(w http.ResponseWriter, r *http.Request) error {
+	name, err := scimID(r, "/Groups/")
+	if err != nil {
+		return err
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	policy, err := s.loadGroup(r.Context(), enclave, name)
+	if err != nil {
+		return err
+	}
+
+	var members map[string][]kes.Identity
+	if !scimExcludesMembers(r) {
+		if members, err = s.listMembers(r.Context(), enclave); err != nil {
+			return err
+		}
+	}
+	return writeSCIM(w, http.StatusOK, s.group(r, name, policy, members[name]))
+}
+
+func (s *scimServer) replaceGroup(w http.ResponseWriter, r *http.Request) error {
+	name, err := scimID(r, "/Groups/")
+	if err != nil {
+		return err
+	}
+
+	var group scim.Group
+	if err = json.NewDecoder(r.Body).Decode(&group); err != nil {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid group: %v", err)
+	}
+	if group.DisplayName != "" && group.DisplayName != name {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrMutability, "displayName cannot be changed")
+	}
+
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	policy, err := s.loadGroup(r.Context(), enclave, name)
+	if err != nil {
+		return err
+	}
+	members, err := s.listMembers(r.Context(), enclave)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[kes.Identity]bool, len(members[name]))
+	for _, identity := range members[name] {
+		current[identity] = true
+	}
+	desired := make(map[kes.Identity]bool, len(group.Members))
+	for _, member := range group.Members {
+		desired[kes.Identity(member.Value)] = true
+	}
+	if err = s.updateMembers(r.Context(), enclave, name, current, desired); err != nil {
+		return err
+	}
+
+	if members, err = s.listMembers(r.Context(), enclave); err != nil {
+		return err
+	}
+	return writeSCIM(w, http.StatusOK, s.group(r, name, policy, members[name]))
+}
+
+func (s *scimServer) patchGroup(w http.ResponseWriter, r *http.Request) error {
+	name, err := scimID(r, "/Groups/")
+	if err != nil {
+		return err
+	}
+
+	var req scim.PatchRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid patch request: %v", err)
+	}
+	enclave, err := s.vault.GetEnclave(r.Context(), s.enclave)
+	if err != nil {
+		return err
+	}
+	if _, err = s.loadGroup(r.Context(), enclave, name); err != nil {
+		return err
+	}
+	members, err := s.listMembers(r.Context(), enclave)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[kes.Identity]bool, len(members[name]))
+	desired := make(map[kes.Identity]bool, len(members[name]))
+	for _, identity := range members[name] {
+		current[identity], desired[identity] = true, true
+	}
+
+	// Only the members and displayName attributes are mapped to
+	// the policy. Changes of other attributes are ignored.
+	setAttribute := func(op, attr string, value json.RawMessage) error {
+		switch attr {
+		case "members":
+			var refs []scim.Member
+			if len(value) > 0 {
+				if err := json.Unmarshal(value, &refs); err != nil {
+					return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid members: %v", err)
+				}
+			}
+			switch op {
+			case "replace":
+				desired = make(map[kes.Identity]bool, len(refs))
+				fallthrough
+			case "add":
+				for _, ref := range refs {
+					desired[kes.Identity(ref.Value)] = true
+				}
+			case "remove":
+				if len(value) == 0 {
+					desired = map[kes.Identity]bool{}
+				}
+				for _, ref := range refs {
+					delete(desired, kes.Identity(ref.Value))
+				}
+			}
+		case "displayname":
+			var displayName string
+			if err := json.Unmarshal(value, &displayName); err != nil || displayName != name || op == "remove" {
+				return scim.Errorf(http.StatusBadRequest, scim.ErrMutability, "displayName cannot be changed")
+			}
+		}
+		return nil
+	}
+	for _, patch := range req.Operations {
+		op := strings.ToLower(patch.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid patch operation '%s'", patch.Op)
+		}
+		attr, filter, err := scim.ParsePath(patch.Path)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case filter != nil:
+			if attr != "members" || op != "remove" || filter.Attribute != "value" {
+				return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidPath, "unsupported path '%s'", patch.Path)
+			}
+			delete(desired, kes.Identity(filter.Value))
+		case attr != "":
+			if err = setAttribute(op, attr, patch.Value); err != nil {
+				return err
+			}
+		default:
+			var attributes map[string]json.RawMessage
+			if err = json.Unmarshal(patch.Value, &attributes); err != nil {
+				return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid patch value: %v", err)
+			}
+			for name, value := range attributes {
+				if err = setAttribute(op, strings.ToLower(name), value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err = s.updateMembers(r.Context(), enclave, name, current, desired); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *scimServer) deleteGroup(w http.ResponseWriter, r *http.Request) error {
+	name, err := scimID(r, "/Groups/")
+	if err != nil {
+		return err
+	}
+	return scim.Errorf(http.StatusForbidden, "", "group '%s' cannot be deleted: groups are KES policies that are not managed via SCIM", name)
+}
+
+// user returns the SCIM user of the identity.
+func (s *scimServer) user(r *http.Request, identity kes.Identity, info auth.IdentityInfo) scim.User {
+	active := !info.Expired()
+	user := scim.User{
+		Schemas:  []string{scim.UserSchema},
+		ID:       identity.String(),
+		UserName: identity.String(),
+		Active:   &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Location:     scimLocation(r, "/Users/"+identity.String()),
+		},
+	}
+	if !info.CreatedAt.IsZero() {
+		user.Meta.Created = &info.CreatedAt
+	}
+	if s.isGroup(info.Policy) {
+		user.Groups = []scim.Member{{
+			Value:   info.Policy,
+			Display: info.Policy,
+			Ref:     scimLocation(r, "/Groups/"+info.Policy),
+		}}
+	}
+	return user
+}
+
+// group returns the SCIM group of the policy.
+func (s *scimServer) group(r *http.Request, name string, policy auth.Policy, members []kes.Identity) scim.Group {
+	group := scim.Group{
+		Schemas:     []string{scim.GroupSchema},
+		ID:          name,
+		DisplayName: name,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Location:     scimLocation(r, "/Groups/"+name),
+		},
+	}
+	if !policy.CreatedAt.IsZero() {
+		group.Meta.Created = &policy.CreatedAt
+	}
+	for _, identity := range members {
+		group.Members = append(group.Members, scim.Member{
+			Value: identity.String(),
+			Ref:   scimLocation(r, "/Users/"+identity.String()),
+		})
+	}
+	return group
+}
+
+// isGroup reports whether the policy is exposed as SCIM group.
+func (s *scimServer) isGroup(policy string) bool {
+	return policy != auth.EnclaveAdminPolicy && policy != s.defaultPolicy
+}
+
+// loadUser returns the identity info of the SCIM user. It returns
+// a 404 SCIM error if the identity does not exist or is an admin.
+func (s *scimServer) loadUser(ctx context.Context, enclave *sys.Enclave, identity kes.Identity) (auth.IdentityInfo, error) {
+	info, err := enclave.GetIdentity(ctx, identity)
+	if errors.Is(err, kes.ErrIdentityNotFound) {
+		return auth.IdentityInfo{}, scim.Errorf(http.StatusNotFound, "", "user '%s' not found", identity)
+	}
+	if err != nil {
+		return auth.IdentityInfo{}, err
+	}
+	if info.IsAdmin || info.Policy == auth.EnclaveAdminPolicy {
+		return auth.IdentityInfo{}, scim.Errorf(http.StatusNotFound, "", "user '%s' not found", identity)
+	}
+	return info, nil
+}
+
+// loadGroup returns the policy of the SCIM group. It returns a
+// 404 SCIM error if the policy does not exist or is not exposed
+// as group.
+func (s *scimServer) loadGroup(ctx context.Context, enclave *sys.Enclave, name string) (auth.Policy, error) {
+	if !s.isGroup(name) {
+		return auth.Policy{}, scim.Errorf(http.StatusNotFound, "", "group '%s' not found", name)
+	}
+	policy, err := enclave.GetPolicy(ctx, name)
+	if errors.Is(err, kes.ErrPolicyNotFound) {
+		return auth.Policy{}, scim.Errorf(http.StatusNotFound, "", "group '%s' not found", name)
+	}
+	return policy, err
+}
+
+// listIdentities returns all identities of the enclave that can
+// be provisioned via SCIM, sorted by identity.
+func (s *scimServer) listIdentities(ctx context.Context, enclave *sys.Enclave) ([]scimIdentity, error) {
+	iter, err := enclave.ListIdentities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var identities []scimIdentity
+	for iter.Next() {
+		info, err := enclave.GetIdentity(ctx, iter.Identity())
+		if errors.Is(err, kes.ErrIdentityNotFound) {
+			continue // Identity has been deleted concurrently
+		}
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		if info.IsAdmin || info.Policy == auth.EnclaveAdminPolicy {
+			continue
+		}
+		identities = append(identities, scimIdentity{Identity: iter.Identity(), Info: info})
+	}
+	if err = iter.Close(); err != nil {
+		return nil, err
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Identity < identities[j].Identity })
+	return identities, nil
+}
+
+// listMembers returns the identities assigned to each policy
+// exposed as SCIM group.
+func (s *scimServer) listMembers(ctx context.Context, enclave *sys.Enclave) (map[string][]kes.Identity, error) {
+	identities, err := s.listIdentities(ctx, enclave)
+	if err != nil {
+		return nil, err
+	}
+	members := map[string][]kes.Identity{}
+	for _, identity := range identities {
+		if s.isGroup(identity.Info.Policy) {
+			members[identity.Info.Policy] = append(members[identity.Info.Policy], identity.Identity)
+		}
+	}
+	return members, nil
+}
+
+// updateMembers assigns all desired but not current members to
+// the policy and all current but not desired members to the
+// default policy.
+func (s *scimServer) updateMembers(ctx context.Context, enclave *sys.Enclave, policy string, current, desired map[kes.Identity]bool) error {
+	var removed, added []kes.Identity
+	for identity := range current {
+		if !desired[identity] {
+			removed = append(removed, identity)
+		}
+	}
+	for identity := range desired {
+		if !current[identity] {
+			added = append(added, identity)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+
+	for _, identity := range added {
+		if err := verifyName(identity.String()); err != nil || identity.IsUnknown() {
+			return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid member '%s': member must be a KES identity", identity)
+		}
+		if err := s.verifyAssignable(ctx, identity); err != nil {
+			return err
+		}
+	}
+	for _, identity := range removed {
+		if err := s.assign(ctx, enclave, policy, s.defaultPolicy, identity); err != nil {
+			return err
+		}
+	}
+	for _, identity := range added {
+		if err := s.assign(ctx, enclave, "", policy, identity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assign assigns the identity to the given policy. If from is not
+// empty, the identity is only reassigned if it is currently assigned
+// to the from policy. An existing deactivated identity remains
+// deactivated.
+func (s *scimServer) assign(ctx context.Context, enclave *sys.Enclave, from, policy string, identity kes.Identity) error {
+	var expiresAt time.Time
+	info, err := enclave.GetIdentity(ctx, identity)
+	switch {
+	case err == nil && (info.IsAdmin || info.Policy == auth.EnclaveAdminPolicy):
+		return scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "cannot change the policy of admin identity '%s'", identity)
+	case err == nil && from != "" && info.Policy != from:
+		return nil // Identity has been assigned to another group concurrently
+	case err == nil && info.Policy == policy:
+		return nil
+	case err == nil:
+		expiresAt = info.ExpiresAt
+	case errors.Is(err, kes.ErrIdentityNotFound):
+		if from != "" {
+			return nil
+		}
+	default:
+		return err
+	}
+
+	if err = enclave.AssignPolicyUntil(ctx, policy, identity, expiresAt); err != nil {
+		return err
+	}
+	s.events.PublishConfig(s.enclave, EventIdentityAssigned, identity.String())
+	return nil
+}
+
+// setActive (de)activates the identity. A deactivated identity
+// remains assigned to its policy but its assignment has expired.
+// Activating an identity removes the expiry of its assignment.
+func (s *scimServer) setActive(ctx context.Context, enclave *sys.Enclave, identity kes.Identity, info auth.IdentityInfo, active bool) (auth.IdentityInfo, error) {
+	if active == !info.Expired() {
+		return info, nil
+	}
+	var expiresAt time.Time
+	if !active {
+		expiresAt = time.Now()
+	}
+	if err := enclave.AssignPolicyUntil(ctx, info.Policy, identity, expiresAt); err != nil {
+		return auth.IdentityInfo{}, err
+	}
+	s.events.PublishConfig(s.enclave, EventIdentityAssigned, identity.String())
+	return enclave.GetIdentity(ctx, identity)
+}
+
+// verifyAssignable returns an error if the identity is the
+// system admin and, therefore, cannot be provisioned.
+func (s *scimServer) verifyAssignable(ctx context.Context, identity kes.Identity) error {
+	admin, err := s.vault.Admin(ctx)
+	if err != nil {
+		return err
+	}
+	if identity == admin {
+		return scim.Errorf(http.StatusConflict, scim.ErrUniqueness, "identity '%s' is the system admin", identity)
+	}
+	return nil
+}
+
+// scimMaxResults is the max. number of resources
+// returned by a single list request.
+const scimMaxResults = 1000
+
+// scimPage returns the page of resources selected by
+// the request's startIndex and count query parameters.
+func scimPage[T any](r *http.Request, resources []T) (scim.ListResponse, error) {
+	startIndex, count := 1, scimMaxResults
+	if s := r.URL.Query().Get("startIndex"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return scim.ListResponse{}, scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid startIndex '%s'", s)
+		}
+		if n > 1 {
+			startIndex = n
+		}
+	}
+	if s := r.URL.Query().Get("count"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return scim.ListResponse{}, scim.Errorf(http.StatusBadRequest, scim.ErrInvalidValue, "invalid count '%s'", s)
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n < count {
+			count = n
+		}
+	}
+
+	start := startIndex - 1
+	if start > len(resources) {
+		start = len(resources)
+	}
+	end := start + count
+	if end > len(resources) {
+		end = len(resources)
+	}
+	page := resources[start:end]
+	if page == nil {
+		page = []T{}
+	}
+	return scim.ListResponse{
+		Schemas:      []string{scim.ListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}, nil
+}
+
+// scimFilter parses the request's filter query parameter, if
+// any. It returns an error if the filter refers to an attribute
+// other than the given ones.
+func scimFilter(r *http.Request, attributes ...string) (*scim.Filter, error) {
+	s := r.URL.Query().Get("filter")
+	if s == "" {
+		return nil, nil
+	}
+	filter, err := scim.ParseFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attributes {
+		if filter.Attribute == attr {
+			return &filter, nil
+		}
+	}
+	return nil, scim.Errorf(http.StatusBadRequest, scim.ErrInvalidFilter, "unsupported filter attribute '%s'", filter.Attribute)
+}
+
+// scimExcludesMembers reports whether the request excludes
+// the members of groups via its excludedAttributes query
+// parameter.
+func scimExcludesMembers(r *http.Request) bool {
+	for _, attr := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+// scimID returns the resource ID of the request URL path
+// following the given resource path, e.g. "/Users/".
+func scimID(r *http.Request, resource string) (string, error) {
+	id := strings.TrimPrefix(r.URL.Path, SCIMPath+resource)
+	if err := verifyName(id); err != nil {
+		return "", scim.Errorf(http.StatusNotFound, "", "resource '%s' not found", id)
+	}
+	return id, nil
+}
+
+// scimLocation returns the URL of the SCIM resource path.
+func scimLocation(r *http.Request, resource string) string {
+	return "https://" + r.Host + SCIMPath + resource
+}
+
+// writeSCIM sends the given status code and v, encoded
+// as JSON, to the client.
+func writeSCIM(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// writeSCIMError sends err as SCIM error response to
+// the client.
+func writeSCIMError(w http.ResponseWriter, err error) {
+	var e *scim.Error
+	if !errors.As(err, &e) {
+		e = &scim.Error{
+			Status: statusCode(err),
+			Detail: err.Error(),
+		}
+	}
+	writeSCIM(w, e.Status, e)
+}
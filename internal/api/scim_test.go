@@ -0,0 +1,128 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/scim"
+	"github.com/minio/kes/internal/sys"
+)
+
+func TestSCIM(t *testing.T) {
+	const (
+		Admin        kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		EnclaveAdmin kes.Identity = "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		User         kes.Identity = "5ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		Token                     = "my-token"
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	vault := sys.NewVault(sys.NewVaultFS(t.TempDir(), rootKey))
+	if _, err = vault.CreateEnclave(ctx, sys.DefaultEnclaveName, EnclaveAdmin, nil); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, sys.DefaultEnclaveName)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if err = enclave.SetPolicy(ctx, "my-app", auth.Policy{Allow: []string{"/v1/key/encrypt/*"}}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	handler := NewSCIMHandler(&SCIMConfig{
+		Vault:    vault,
+		Token:    Token,
+		AuditLog: log.New(io.Discard, "", 0),
+	})
+	send := func(method, path, body string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, SCIMPath+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+Token)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		if resp.Code != status {
+			t.Fatalf("%s %s: got status '%d' - want '%d': %s", method, path, resp.Code, status, resp.Body)
+		}
+		return resp
+	}
+	identity := func(identity kes.Identity) auth.IdentityInfo {
+		t.Helper()
+		info, err := enclave.GetIdentity(ctx, identity)
+		if err != nil {
+			t.Fatalf("Failed to get identity: %v", err)
+		}
+		return info
+	}
+
+	req := httptest.NewRequest(http.MethodGet, SCIMPath+"/Users", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("Request without token: got status '%d' - want '%d'", resp.Code, http.StatusUnauthorized)
+	}
+
+	send(http.MethodPost, "/Users", `{"schemas":["`+scim.UserSchema+`"],"userName":"`+User.String()+`","name":{"givenName":"App"}}`, http.StatusCreated)
+	send(http.MethodPost, "/Users", `{"userName":"`+User.String()+`"}`, http.StatusConflict)
+	send(http.MethodPost, "/Users", `{"userName":"`+Admin.String()+`"}`, http.StatusConflict)
+	if info := identity(User); info.Policy != DefaultSCIMPolicy {
+		t.Fatalf("Invalid policy of new user: got '%s' - want '%s'", info.Policy, DefaultSCIMPolicy)
+	}
+
+	var users scim.ListResponse
+	json.NewDecoder(send(http.MethodGet, `/Users?filter=userName+eq+"`+User.String()+`"`, "", http.StatusOK).Body).Decode(&users)
+	if users.TotalResults != 1 {
+		t.Fatalf("Invalid number of users: got '%d' - want '%d'", users.TotalResults, 1)
+	}
+	send(http.MethodGet, "/Users/"+EnclaveAdmin.String(), "", http.StatusNotFound)
+
+	send(http.MethodPatch, "/Groups/my-app", `{"Operations":[{"op":"add","path":"members","value":[{"value":"`+User.String()+`"}]}]}`, http.StatusNoContent)
+	if info := identity(User); info.Policy != "my-app" {
+		t.Fatalf("Invalid policy of group member: got '%s' - want '%s'", info.Policy, "my-app")
+	}
+
+	send(http.MethodPatch, "/Users/"+User.String(), `{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`, http.StatusOK)
+	if info := identity(User); !info.Expired() || info.Policy != "my-app" {
+		t.Fatalf("Deactivated user: got '%+v' - want expired assignment to '%s'", info, "my-app")
+	}
+
+	send(http.MethodPatch, "/Groups/my-app", `{"Operations":[{"op":"remove","path":"members[value eq \"`+User.String()+`\"]"}]}`, http.StatusNoContent)
+	if info := identity(User); !info.Expired() || info.Policy != DefaultSCIMPolicy {
+		t.Fatalf("Removed group member: got '%+v' - want expired assignment to '%s'", info, DefaultSCIMPolicy)
+	}
+
+	send(http.MethodPatch, "/Users/"+User.String(), `{"Operations":[{"op":"replace","value":{"active":true}}]}`, http.StatusOK)
+	if info := identity(User); info.Expired() {
+		t.Fatal("Activated user is still deactivated")
+	}
+
+	var groups scim.ListResponse
+	json.NewDecoder(send(http.MethodGet, "/Groups", "", http.StatusOK).Body).Decode(&groups)
+	if groups.TotalResults != 1 {
+		t.Fatalf("Invalid number of groups: got '%d' - want '%d'", groups.TotalResults, 1)
+	}
+	send(http.MethodPost, "/Groups", `{"displayName":"my-app"}`, http.StatusConflict)
+	send(http.MethodPost, "/Groups", `{"displayName":"other"}`, http.StatusBadRequest)
+	send(http.MethodGet, "/Groups/"+auth.EnclaveAdminPolicy, "", http.StatusNotFound)
+	send(http.MethodPut, "/Groups/my-app", `{"displayName":"my-app","members":[{"value":"`+EnclaveAdmin.String()+`"}]}`, http.StatusBadRequest)
+	send(http.MethodDelete, "/Groups/my-app", "", http.StatusForbidden)
+
+	send(http.MethodDelete, "/Users/"+User.String(), "", http.StatusNoContent)
+	send(http.MethodGet, "/Users/"+User.String(), "", http.StatusNotFound)
+}
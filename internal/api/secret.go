@@ -7,7 +7,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
-	"path"
+	"strconv"
 	"time"
 
 	"aead.dev/mem"
@@ -17,6 +17,14 @@ import (
 	"github.com/minio/kes/internal/secret"
 )
 
+// errSecretTooLarge is returned when a secret value
+// exceeds secret.MaxValueSize.
+var errSecretTooLarge = kes.NewError(http.StatusRequestEntityTooLarge, "secret value too large: must not exceed "+mem.FormatSize(secret.MaxValueSize, 'B', -1))
+
+// errSecretVersionNotFound is returned when a secret version
+// does not exist or has been discarded.
+var errSecretVersionNotFound = kes.NewError(http.StatusNotFound, "secret version does not exist")
+
 func createSecret(config *RouterConfig) API {
 	const (
 		Method  = http.MethodPost
@@ -35,27 +43,144 @@ func createSecret(config *RouterConfig) API {
 			return err
 		}
 
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
 
-				var req Request
-				if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
-					return err
-				}
-				if req.Type != kes.SecretGeneric { // Currently, we only support generic secrets
-					return kes.NewError(http.StatusBadRequest, "unsupported secret type '"+req.Type.String()+"'")
-				}
-				secret := secret.NewSecret(req.Bytes, auth.Identify(r))
-				return enclave.CreateSecret(r.Context(), name, secret)
-			})
-		}); err != nil {
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		if req.Type != kes.SecretGeneric { // Currently, we only support generic secrets
+			return kes.NewError(http.StatusBadRequest, "unsupported secret type '"+req.Type.String()+"'")
+		}
+		if len(req.Bytes) > int(secret.MaxValueSize) {
+			return errSecretTooLarge
+		}
+		secret := secret.NewSecret(req.Bytes, auth.Identify(r))
+		if err = enclave.CreateSecret(r.Context(), name, secret); err != nil {
+			return err
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func updateSecret(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/secret/update/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Bytes []byte `json:"bytes"`
+	}
+	type Response struct {
+		Version uint64    `json:"version"`
+		ModTime time.Time `json:"mod_time"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		if len(req.Bytes) > int(secret.MaxValueSize) {
+			return errSecretTooLarge
+		}
+		secret, err := enclave.UpdateSecret(r.Context(), name, req.Bytes)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Version: secret.Version(),
+			ModTime: secret.ModTime(),
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func setSecretRotation(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/secret/rotation/"
+		MaxBody = int64(1 * mem.MiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		Endpoint string        `json:"endpoint"`
+		Interval time.Duration `json:"interval"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return err
+		}
+		rotation := secret.Rotation{
+			Endpoint: req.Endpoint,
+			Interval: req.Interval,
+		}
+		if err = verifyRotation(rotation); err != nil {
+			return err
+		}
+		if err = enclave.SetSecretRotation(r.Context(), name, rotation); err != nil {
 			return err
 		}
 
@@ -68,7 +193,66 @@ func createSecret(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func rotateSecret(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/secret/rotate/"
+		MaxBody     = 0
+		Timeout     = 30 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Response struct {
+		Version uint64    `json:"version"`
+		ModTime time.Time `json:"mod_time"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		s, err := enclave.GetSecret(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if !s.Rotation().Enabled() {
+			return kes.NewError(http.StatusBadRequest, "secret has no rotation webhook")
+		}
+		s, err = invokeRotation(r.Context(), defaultRotationClient, enclaveName(r), enclave, name, s.Rotation())
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Version: s.Version(),
+			ModTime: s.ModTime(),
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -81,11 +265,23 @@ func describeSecret(config *RouterConfig) API {
 		Verify      = true
 		ContentType = "application/json"
 	)
+	type Version struct {
+		Version   uint64    `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	type Rotation struct {
+		Endpoint     string        `json:"endpoint"`
+		Interval     time.Duration `json:"interval"`
+		NextRotation time.Time     `json:"next_rotation"`
+	}
 	type Response struct {
 		Type      kes.SecretType `json:"type"`
 		CreatedAt time.Time      `json:"created_at"`
 		ModTime   time.Time      `json:"mod_time"`
 		CreatedBy kes.Identity   `json:"created_by"`
+		Version   uint64         `json:"version"`
+		Versions  []Version      `json:"versions"`
+		Rotation  *Rotation      `json:"rotation,omitempty"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -93,22 +289,31 @@ func describeSecret(config *RouterConfig) API {
 			return err
 		}
 
-		secret, err := VSync(config.Vault.RLocker(), func() (secret.Secret, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return secret.Secret{}, err
-			}
-			return VSync(enclave.RLocker(), func() (secret.Secret, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return secret.Secret{}, err
-				}
-				return enclave.GetSecret(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		secret, err := enclave.GetSecret(r.Context(), name)
 		if err != nil {
 			return err
 		}
 
+		versions := make([]Version, 0, len(secret.Versions()))
+		for _, v := range secret.Versions() {
+			versions = append(versions, Version{Version: v.Version, CreatedAt: v.CreatedAt})
+		}
+		var rotation *Rotation
+		if rot := secret.Rotation(); rot.Enabled() {
+			rotation = &Rotation{
+				Endpoint:     rot.Endpoint,
+				Interval:     rot.Interval,
+				NextRotation: secret.NextRotation(),
+			}
+		}
+
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
@@ -116,16 +321,21 @@ func describeSecret(config *RouterConfig) API {
 			CreatedAt: secret.CreatedAt(),
 			ModTime:   secret.ModTime(),
 			CreatedBy: secret.CreatedBy(),
+			Version:   secret.Version(),
+			Versions:  versions,
+			Rotation:  rotation,
 		})
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -143,6 +353,7 @@ func readSecret(config *RouterConfig) API {
 		CreatedAt time.Time      `json:"created_at"`
 		ModTime   time.Time      `json:"mod_time"`
 		CreatedBy kes.Identity   `json:"created_by"`
+		Version   uint64         `json:"version"`
 	}
 	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
 		name, err := nameFromRequest(r, APIPath)
@@ -150,40 +361,54 @@ func readSecret(config *RouterConfig) API {
 			return err
 		}
 
-		secret, err := VSync(config.Vault.RLocker(), func() (secret.Secret, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return secret.Secret{}, err
-			}
-			return VSync(enclave.RLocker(), func() (secret.Secret, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return secret.Secret{}, err
-				}
-				return enclave.GetSecret(r.Context(), name)
-			})
-		})
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		secret, err := enclave.GetSecret(r.Context(), name)
 		if err != nil {
 			return err
 		}
 
+		// By default, the current version is returned. Clients
+		// may request any version that has not been discarded.
+		current, _ := secret.Lookup(secret.Version())
+		version := current
+		if s := r.URL.Query().Get("version"); s != "" {
+			n, err := strconv.ParseUint(s, 10, 64)
+			if err != nil || n == 0 {
+				return kes.NewError(http.StatusBadRequest, "invalid secret version '"+s+"'")
+			}
+			var ok bool
+			if version, ok = secret.Lookup(n); !ok {
+				return errSecretVersionNotFound
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(Response{
-			Bytes:     secret.Bytes(),
+			Bytes:     version.Bytes,
 			Type:      secret.Type(),
 			CreatedAt: secret.CreatedAt(),
-			ModTime:   secret.ModTime(),
+			ModTime:   version.CreatedAt,
 			CreatedBy: secret.CreatedBy(),
+			Version:   version.Version,
 		})
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "application/json",
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -201,18 +426,14 @@ func deleteSecret(config *RouterConfig) API {
 			return err
 		}
 
-		if err = Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.Locker(), func() error {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return err
-				}
-				return enclave.DeleteSecret(r.Context(), name)
-			})
-		}); err != nil {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		if err = enclave.DeleteSecret(r.Context(), name); err != nil {
 			return err
 		}
 
@@ -225,7 +446,7 @@ func deleteSecret(config *RouterConfig) API {
 		MaxBody: MaxBody,
 		Timeout: Timeout,
 		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
 	}
 }
 
@@ -244,6 +465,7 @@ func listSecret(config *RouterConfig) API {
 		CreatedAt time.Time      `json:"created_at,omitempty"`
 		ModTime   time.Time      `json:"mod_time,omitempty"`
 		CreatedBy kes.Identity   `json:"created_by,omitempty"`
+		Version   uint64         `json:"version,omitempty"`
 
 		Err string `json:"error,omitempty"`
 	}
@@ -253,52 +475,51 @@ func listSecret(config *RouterConfig) API {
 			return err
 		}
 
-		hasWritten, err := VSync(config.Vault.RLocker(), func() (bool, error) {
-			enclave, err := enclaveFromRequest(config.Vault, r)
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		hasWritten, err := func() (bool, error) {
+			iterator, err := enclave.ListSecrets(r.Context())
 			if err != nil {
 				return false, err
 			}
-			return VSync(enclave.RLocker(), func() (bool, error) {
-				if err = enclave.VerifyRequest(r); err != nil {
-					return false, err
-				}
+			defer iterator.Close()
 
-				iterator, err := enclave.ListSecrets(r.Context())
+			var hasWritten bool
+			encoder := json.NewEncoder(w)
+			for iterator.Next() {
+				name := iterator.Name()
+				if !matchPattern(pattern, name) || name == "" {
+					continue
+				}
+				secret, err := enclave.GetSecret(r.Context(), iterator.Name())
 				if err != nil {
-					return false, err
+					return hasWritten, err
 				}
-				defer iterator.Close()
-
-				var hasWritten bool
-				encoder := json.NewEncoder(w)
-				for iterator.Next() {
-					name := iterator.Name()
-					if ok, _ := path.Match(pattern, name); !ok || name == "" {
-						continue
-					}
-					secret, err := enclave.GetSecret(r.Context(), iterator.Name())
-					if err != nil {
-						return hasWritten, err
-					}
-					if !hasWritten {
-						hasWritten = true
-						w.Header().Set("Content-Type", ContentType)
-						w.WriteHeader(http.StatusOK)
-					}
-
-					err = encoder.Encode(Response{
-						Name:      iterator.Name(),
-						CreatedAt: secret.CreatedAt(),
-						ModTime:   secret.ModTime(),
-						CreatedBy: secret.CreatedBy(),
-					})
-					if err != nil {
-						return hasWritten, err
-					}
+				if !hasWritten {
+					hasWritten = true
+					w.Header().Set("Content-Type", ContentType)
+					w.WriteHeader(http.StatusOK)
 				}
-				return hasWritten, iterator.Close()
-			})
-		})
+
+				err = encoder.Encode(Response{
+					Name:      iterator.Name(),
+					CreatedAt: secret.CreatedAt(),
+					ModTime:   secret.ModTime(),
+					CreatedBy: secret.CreatedBy(),
+					Version:   secret.Version(),
+				})
+				if err != nil {
+					return hasWritten, err
+				}
+			}
+			return hasWritten, iterator.Close()
+		}()
 		if err != nil {
 			if hasWritten {
 				json.NewEncoder(w).Encode(Response{Err: err.Error()})
@@ -312,11 +533,13 @@ func listSecret(config *RouterConfig) API {
 		return nil
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
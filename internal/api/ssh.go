@@ -0,0 +1,263 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshSigningKey is the purpose of the signing key
+// derived from a KES key to sign SSH certificates.
+const sshSigningKey = "ssh"
+
+// defaultSSHTTL is the lifetime of SSH certificates if
+// the client does not request a specific lifetime.
+const defaultSSHTTL = 1 * time.Hour
+
+func sshPublicKey(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/v1/ssh/ca/"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "text/plain"
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		signer, err := sshSigner(&key)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func sshSign(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/ssh/sign/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+
+		MaxPrincipals = 256
+	)
+	type Request struct {
+		PublicKey  string        `json:"public_key"` // In authorized_keys format
+		Type       string        `json:"type"`       // "user" or "host"
+		Principals []string      `json:"principals"`
+		TTL        time.Duration `json:"ttl"` // optional
+	}
+	type Response struct {
+		Certificate  string    `json:"certificate"` // In authorized_keys format
+		SerialNumber uint64    `json:"serial_number"`
+		CA           string    `json:"ca"` // Public key of the CA in authorized_keys format
+		ExpiresAt    time.Time `json:"expires_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		var certType uint32
+		switch req.Type {
+		case "user":
+			certType = ssh.UserCert
+		case "host":
+			certType = ssh.HostCert
+		default:
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid certificate type '%s': must be 'user' or 'host'", req.Type))
+		}
+		if len(req.Principals) == 0 {
+			return kes.NewError(http.StatusBadRequest, "no principal specified")
+		}
+		if len(req.Principals) > MaxPrincipals {
+			return kes.NewError(http.StatusBadRequest, "too many principals")
+		}
+		for _, principal := range req.Principals {
+			if principal == "" || principal == "." || principal == ".." || strings.ContainsAny(principal, "/*?[]\\") {
+				return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid principal '%s'", principal))
+			}
+		}
+		if req.TTL < 0 {
+			return kes.NewError(http.StatusBadRequest, "invalid ttl: must not be negative")
+		}
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+		if err != nil {
+			return kes.NewError(http.StatusBadRequest, "invalid public key: "+err.Error())
+		}
+		if _, ok := publicKey.(*ssh.Certificate); ok {
+			return kes.NewError(http.StatusBadRequest, "invalid public key: must not be a certificate")
+		}
+
+		// The policy of the identity has to allow each principal
+		// as path, e.g. /v1/ssh/sign/my-ca/user/alice allows signing
+		// user certificates for alice with the key my-ca.
+		for _, principal := range req.Principals {
+			err = enclave.VerifyPath(r, APIPath+name+"/"+req.Type+"/"+principal)
+			if errors.Is(err, kes.ErrNotAllowed) {
+				return kes.NewError(http.StatusForbidden, fmt.Sprintf("not authorized: principal '%s' is not allowed", principal))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// The max. lifetime is the smaller of the server's
+		// and the one defined by the identity's policy.
+		identity := auth.Identify(r)
+		info, err := enclave.GetIdentity(r.Context(), identity)
+		if err != nil {
+			return err
+		}
+		maxTTL := config.CA.maxSSHTTL()
+		if !info.IsAdmin && info.Policy != "" {
+			policy, err := enclave.GetPolicy(r.Context(), info.Policy)
+			if err != nil {
+				return err
+			}
+			maxTTL = policy.SSHTTLLimit(maxTTL)
+		}
+		if req.TTL > maxTTL {
+			return kes.NewError(http.StatusBadRequest, "invalid ttl: must not exceed "+maxTTL.String())
+		}
+		if req.TTL == 0 {
+			req.TTL = defaultSSHTTL
+			if req.TTL > maxTTL {
+				req.TTL = maxTTL
+			}
+		}
+
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+		signer, err := sshSigner(&key)
+		if err != nil {
+			return err
+		}
+
+		var serial [8]byte
+		if _, err = rand.Read(serial[:]); err != nil {
+			return err
+		}
+		now := time.Now()
+		expiresAt := now.Add(req.TTL)
+		if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(expiresAt) {
+			expiresAt = info.ExpiresAt // The certificate must not outlive the identity's policy assignment
+		}
+		cert := &ssh.Certificate{
+			Key:             publicKey,
+			Serial:          binary.BigEndian.Uint64(serial[:]),
+			CertType:        certType,
+			KeyId:           identity.String(),
+			ValidPrincipals: req.Principals,
+			ValidAfter:      uint64(now.Add(-1 * time.Minute).Unix()),
+			ValidBefore:     uint64(expiresAt.Unix()),
+		}
+		if certType == ssh.UserCert {
+			cert.Permissions.Extensions = map[string]string{
+				"permit-X11-forwarding":   "",
+				"permit-agent-forwarding": "",
+				"permit-port-forwarding":  "",
+				"permit-pty":              "",
+				"permit-user-rc":          "",
+			}
+		}
+		if err = cert.SignCert(rand.Reader, signer); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Certificate:  string(ssh.MarshalAuthorizedKey(cert)),
+			SerialNumber: cert.Serial,
+			CA:           string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+			ExpiresAt:    time.Unix(int64(cert.ValidBefore), 0).UTC(),
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// sshSigner returns the SSH CA signer derived from the key.
+func sshSigner(k *key.Key) (ssh.Signer, error) {
+	priv, err := k.SigningKey(sshSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(priv)
+}
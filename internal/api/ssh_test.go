@@ -0,0 +1,135 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kes/internal/sys"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHSignTTL(t *testing.T) {
+	const (
+		Admin        kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		EnclaveAdmin kes.Identity = "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	vault := sys.NewVault(sys.NewVaultFS(t.TempDir(), rootKey))
+	if _, err = vault.CreateEnclave(ctx, sys.DefaultEnclaveName, EnclaveAdmin, nil); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, sys.DefaultEnclaveName)
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	caKey, err := key.Random(kes.AES256_GCM_SHA256, EnclaveAdmin)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-ca", caKey); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	_, userKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sshKey, err := ssh.NewPublicKey(userKey.Public())
+	if err != nil {
+		t.Fatalf("Failed to create SSH public key: %v", err)
+	}
+	publicKey := string(ssh.MarshalAuthorizedKey(sshKey))
+
+	for i, test := range sshSignTTLTests {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Test %d: failed to generate key: %v", i, err)
+		}
+		policy := auth.Policy{
+			Allow:     []string{"/v1/ssh/sign/my-ca", "/v1/ssh/sign/my-ca/user/alice"},
+			MaxSSHTTL: test.PolicyTTL,
+		}
+		if err = enclave.SetPolicy(ctx, "ssh-users", policy); err != nil {
+			t.Fatalf("Test %d: failed to create policy: %v", i, err)
+		}
+		if err = enclave.AssignPolicy(ctx, "ssh-users", identityOf(priv)); err != nil {
+			t.Fatalf("Test %d: failed to assign policy: %v", i, err)
+		}
+
+		api := sshSign(&RouterConfig{
+			Vault:    vault,
+			CA:       CAConfig{MaxSSHTTL: test.ServerTTL},
+			Metrics:  metric.New(),
+			AuditLog: log.New(io.Discard, "", 0),
+		})
+		body, _ := json.Marshal(map[string]any{
+			"public_key": publicKey,
+			"type":       "user",
+			"principals": []string{"alice"},
+			"ttl":        test.TTL,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/ssh/sign/my-ca", strings.NewReader(string(body)))
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{selfSigned(t, priv)}}
+		resp := httptest.NewRecorder()
+		api.Handler.ServeHTTP(resp, req)
+
+		if test.ShouldFail {
+			if resp.Code != http.StatusBadRequest {
+				t.Fatalf("Test %d: got status '%d' - want '%d'", i, resp.Code, http.StatusBadRequest)
+			}
+			continue
+		}
+		if resp.Code != http.StatusOK {
+			t.Fatalf("Test %d: got status '%d' - want '%d': %s", i, resp.Code, http.StatusOK, resp.Body)
+		}
+		var response struct {
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Test %d: failed to decode response: %v", i, err)
+		}
+		if ttl := time.Until(response.ExpiresAt); ttl > test.Lifetime || ttl < test.Lifetime-time.Minute {
+			t.Fatalf("Test %d: got lifetime '%v' - want '%v'", i, ttl, test.Lifetime)
+		}
+	}
+}
+
+var sshSignTTLTests = []struct {
+	ServerTTL  time.Duration
+	PolicyTTL  time.Duration
+	TTL        time.Duration
+	Lifetime   time.Duration
+	ShouldFail bool
+}{
+	{ServerTTL: 24 * time.Hour, TTL: 8 * time.Hour, Lifetime: 8 * time.Hour},                           // 0
+	{ServerTTL: 24 * time.Hour, PolicyTTL: 30 * time.Minute, Lifetime: 30 * time.Minute},               // 1
+	{ServerTTL: 24 * time.Hour, PolicyTTL: 8 * time.Hour, TTL: 8 * time.Hour, Lifetime: 8 * time.Hour}, // 2
+	{ServerTTL: 24 * time.Hour, PolicyTTL: 8 * time.Hour, TTL: 9 * time.Hour, ShouldFail: true},        // 3
+	{ServerTTL: 2 * time.Hour, PolicyTTL: 8 * time.Hour, TTL: 3 * time.Hour, ShouldFail: true},         // 4
+	{ServerTTL: 2 * time.Hour, PolicyTTL: 8 * time.Hour, TTL: 2 * time.Hour, Lifetime: 2 * time.Hour},  // 5
+	{ServerTTL: 24 * time.Hour, PolicyTTL: 8 * time.Hour, TTL: -time.Hour, ShouldFail: true},           // 6
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/kms"
+	"github.com/minio/kes/kv"
 )
 
 func status(config *RouterConfig) API {
@@ -38,18 +39,13 @@ func status(config *RouterConfig) API {
 		KeyStoreLatency     int64 `json:"keystore_latency"` // In milliseconds
 		KeyStoreUnavailable bool  `json:"keystore_unavailable,omitempty"`
 		KeyStoreUnreachable bool  `json:"keystore_unreachable,omitempty"`
+
+		MinWireVersion int `json:"wire_version_min"` // Min. internal API version supported by the server
+		MaxWireVersion int `json:"wire_version_max"` // Max. internal API version supported by the server
 	}
 	startTime := time.Now().UTC()
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if err := Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.RLocker(), func() error {
-				return enclave.VerifyRequest(r)
-			})
-		}); err != nil {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
 			Fail(w, err)
 			return
 		}
@@ -70,15 +66,20 @@ func status(config *RouterConfig) API {
 			StackAlloc: memStats.StackSys,
 
 			KeyStoreLatency: (1 * time.Millisecond).Milliseconds(), // The keystore is always available - set the min. latency.
+
+			MinWireVersion: MinWireVersion,
+			MaxWireVersion: MaxWireVersion,
 		})
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -97,17 +98,13 @@ func listAPI(router *Router, config *RouterConfig) API {
 		MaxBody int64  `json:"max_body"`
 		Timeout int64  `json:"timeout"`     // Timeout in seconds
 		Verify  bool   `json:"verify_auth"` // Whether the API requires authentication
+
+		Deprecated *time.Time `json:"deprecated,omitempty"`
+		Sunset     *time.Time `json:"sunset,omitempty"`
+		Successor  string     `json:"successor,omitempty"`
 	}
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if err := Sync(config.Vault.RLocker(), func() error {
-			enclave, err := enclaveFromRequest(config.Vault, r)
-			if err != nil {
-				return err
-			}
-			return Sync(enclave.RLocker(), func() error {
-				return enclave.VerifyRequest(r)
-			})
-		}); err != nil {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
 			Fail(w, err)
 			return
 		}
@@ -115,13 +112,23 @@ func listAPI(router *Router, config *RouterConfig) API {
 		apis := router.API()
 		responses := make([]Response, 0, len(apis))
 		for _, api := range apis {
-			responses = append(responses, Response{
-				Method:  api.Method,
-				Path:    api.Path,
-				MaxBody: api.MaxBody,
-				Timeout: int64(api.Timeout.Truncate(time.Second).Seconds()),
-				Verify:  api.Verify,
-			})
+			response := Response{
+				Method:    api.Method,
+				Path:      api.Path,
+				MaxBody:   api.MaxBody,
+				Timeout:   int64(api.Timeout.Truncate(time.Second).Seconds()),
+				Verify:    api.Verify,
+				Successor: api.Successor,
+			}
+			if !api.Deprecated.IsZero() {
+				deprecated := api.Deprecated
+				response.Deprecated = &deprecated
+			}
+			if !api.Sunset.IsZero() {
+				sunset := api.Sunset
+				response.Sunset = &sunset
+			}
+			responses = append(responses, response)
 		}
 
 		w.Header().Set("Content-Type", ContentType)
@@ -129,12 +136,14 @@ func listAPI(router *Router, config *RouterConfig) API {
 		json.NewEncoder(w).Encode(responses)
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -166,6 +175,10 @@ func edgeStatus(config *EdgeRouterConfig) API {
 		KeyStoreLatency     int64 `json:"keystore_latency,omitempty"`
 		KeyStoreUnavailable bool  `json:"keystore_unavailable,omitempty"`
 		KeyStoreUnreachable bool  `json:"keystore_unreachable,omitempty"`
+
+		KeyWrapperLatency     int64 `json:"keywrapper_latency,omitempty"`
+		KeyWrapperUnavailable bool  `json:"keywrapper_unavailable,omitempty"`
+		KeyWrapperUnreachable bool  `json:"keywrapper_unreachable,omitempty"`
 	}
 
 	startTime := time.Now().UTC()
@@ -195,23 +208,30 @@ func edgeStatus(config *EdgeRouterConfig) API {
 			response.KeyStoreUnavailable = true
 			_, response.KeyStoreUnreachable = kms.IsUnreachable(err)
 		} else {
-			latency := state.Latency.Round(time.Millisecond)
-			if latency == 0 { // Make sure we actually send a latency even if the key store respond time is < 1ms.
-				latency = 1 * time.Millisecond
+			response.KeyStoreLatency = latencyMillis(state.Latency)
+		}
+		if config.KeyWrapper != nil {
+			state, err := config.KeyWrapper.Status(r.Context())
+			if err != nil {
+				response.KeyWrapperUnavailable = true
+				_, response.KeyWrapperUnreachable = kv.IsUnreachable(err)
+			} else {
+				response.KeyWrapperLatency = latencyMillis(state.Latency)
 			}
-			response.KeyStoreLatency = latency.Milliseconds()
 		}
 
 		w.Header().Set("Content-Type", ContentType)
 		json.NewEncoder(w).Encode(response)
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Verify:  Verify,
-		Timeout: Timeout,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Timeout:     Timeout,
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
 
@@ -236,6 +256,10 @@ func edgeListAPI(router *Router, config *EdgeRouterConfig) API {
 		MaxBody int64  `json:"max_body"`
 		Timeout int64  `json:"timeout"`     // Timeout in seconds
 		Verify  bool   `json:"verify_auth"` // Whether the API requires authentication
+
+		Deprecated *time.Time `json:"deprecated,omitempty"`
+		Sunset     *time.Time `json:"sunset,omitempty"`
+		Successor  string     `json:"successor,omitempty"`
 	}
 	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
 		if err := auth.VerifyRequest(r, config.Policies, config.Identities); Verify && err != nil {
@@ -246,24 +270,36 @@ func edgeListAPI(router *Router, config *EdgeRouterConfig) API {
 		apis := router.API()
 		responses := make([]Response, 0, len(apis))
 		for _, api := range apis {
-			responses = append(responses, Response{
-				Method:  api.Method,
-				Path:    api.Path,
-				MaxBody: api.MaxBody,
-				Timeout: int64(api.Timeout.Truncate(time.Second).Seconds()),
-				Verify:  api.Verify,
-			})
+			response := Response{
+				Method:    api.Method,
+				Path:      api.Path,
+				MaxBody:   api.MaxBody,
+				Timeout:   int64(api.Timeout.Truncate(time.Second).Seconds()),
+				Verify:    api.Verify,
+				Successor: api.Successor,
+			}
+			if !api.Deprecated.IsZero() {
+				deprecated := api.Deprecated
+				response.Deprecated = &deprecated
+			}
+			if !api.Sunset.IsZero() {
+				sunset := api.Sunset
+				response.Sunset = &sunset
+			}
+			responses = append(responses, response)
 		}
 		w.Header().Set("Content-Type", ContentType)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(responses)
 	}
 	return API{
-		Method:  Method,
-		Path:    APIPath,
-		MaxBody: MaxBody,
-		Timeout: Timeout,
-		Verify:  Verify,
-		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
 	}
 }
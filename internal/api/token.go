@@ -0,0 +1,400 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// tokenSigningKey is the purpose of the signing key
+// derived from a KES key to sign JWTs.
+const tokenSigningKey = "jwt"
+
+// defaultTokenTTL is the lifetime of JWTs if the
+// client does not request a specific lifetime.
+const defaultTokenTTL = 15 * time.Minute
+
+// errNoTokenSigningKey is returned when trying to sign a
+// token with a key that is not designated as JWT signing key.
+var errNoTokenSigningKey = kes.NewError(http.StatusBadRequest, "key is not designated as token signing key")
+
+func designateTokenKey(config *RouterConfig) API {
+	const (
+		Method  = http.MethodPost
+		APIPath = "/v1/token/designate/"
+		MaxBody = int64(1 * mem.KiB)
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	type Request struct {
+		Enabled bool `json:"enabled"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if err = enclave.SetKeyTokenSigning(r.Context(), name, req.Enabled); err != nil {
+			return err
+		}
+
+		config.Events.Publish(r, EventKeyTokenSigningSet, name)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	return API{
+		Method:  Method,
+		Path:    APIPath,
+		MaxBody: MaxBody,
+		Timeout: Timeout,
+		Verify:  Verify,
+		Request: Request{},
+		Handler: config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+func signToken(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/token/sign/"
+		MaxBody     = int64(64 * mem.KiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+	)
+	type Request struct {
+		Claims map[string]any `json:"claims"`
+		TTL    time.Duration  `json:"ttl"` // optional
+	}
+	type Response struct {
+		Token     string    `json:"token"`
+		KeyID     string    `json:"key_id"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		name, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var req Request
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber() // Preserve numeric claims as sent by the client
+		if err = decoder.Decode(&req); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		for _, claim := range []string{"iss", "sub", "iat", "nbf", "exp", "jti"} {
+			if _, ok := req.Claims[claim]; ok {
+				return kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid claims: '%s' is set by the server", claim))
+			}
+		}
+
+		// The token profile of the identity's policy restricts the
+		// audiences, custom claims and the lifetime of the token.
+		identity := auth.Identify(r)
+		info, err := enclave.GetIdentity(r.Context(), identity)
+		if err != nil {
+			return err
+		}
+		var (
+			profile   *auth.TokenProfile
+			maxTTL    = config.CA.maxTokenTTL()
+			unlimited = info.IsAdmin || info.Policy == auth.EnclaveAdminPolicy
+		)
+		if !unlimited && info.Policy != "" {
+			policy, err := enclave.GetPolicy(r.Context(), info.Policy)
+			if err != nil {
+				return err
+			}
+			profile, maxTTL = policy.Tokens, policy.TokenTTLLimit(maxTTL)
+		}
+		if req.TTL < 0 || req.TTL > maxTTL {
+			return kes.NewError(http.StatusBadRequest, "invalid ttl: must not exceed "+maxTTL.String())
+		}
+		if req.TTL == 0 {
+			req.TTL = defaultTokenTTL
+			if req.TTL > maxTTL {
+				req.TTL = maxTTL
+			}
+		}
+
+		key, err := enclave.GetKey(r.Context(), name)
+		if err != nil {
+			return err
+		}
+		if err = verifyKeyAccess(enclave, r, key); err != nil {
+			return err
+		}
+		if !key.TokenSigning() {
+			return errNoTokenSigningKey
+		}
+		priv, err := key.SigningKey(tokenSigningKey)
+		if err != nil {
+			return err
+		}
+
+		var jti [16]byte
+		if _, err = rand.Read(jti[:]); err != nil {
+			return err
+		}
+		now := time.Now()
+		expiresAt := now.Add(req.TTL)
+		if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(expiresAt) {
+			expiresAt = info.ExpiresAt // The token must not outlive the identity's policy assignment
+		}
+
+		// String claims may refer to the requesting identity,
+		// its policy, the enclave and the signing key, e.g.
+		// "tenant": "{{enclave}}".
+		template := strings.NewReplacer(
+			"{{identity}}", identity.String(),
+			"{{policy}}", info.Policy,
+			"{{enclave}}", enclaveName(r),
+			"{{key}}", name,
+		)
+		claims := make(map[string]any, len(req.Claims)+5)
+		for k, v := range req.Claims {
+			claims[k] = expandClaim(template, v)
+		}
+		if !unlimited {
+			if err = verifyTokenClaims(profile, claims); err != nil {
+				return err
+			}
+		}
+		claims["sub"] = identity.String()
+		claims["iat"] = now.Unix()
+		claims["nbf"] = now.Unix()
+		claims["exp"] = expiresAt.Unix()
+		claims["jti"] = hex.EncodeToString(jti[:])
+
+		keyID := jwkThumbprint(priv.Public().(ed25519.PublicKey))
+		token, err := signJWT(priv, keyID, claims)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{
+			Token:     token,
+			KeyID:     keyID,
+			ExpiresAt: time.Unix(expiresAt.Unix(), 0).UTC(),
+		})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     Request{},
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func tokenKeySet(config *RouterConfig) API {
+	const (
+		Method      = http.MethodGet
+		APIPath     = "/.well-known/jwks.json"
+		MaxBody     = 0
+		Timeout     = 15 * time.Second
+		Verify      = false
+		ContentType = "application/jwk-set+json"
+	)
+	type JWK struct {
+		KeyType   string `json:"kty"`
+		Curve     string `json:"crv"`
+		X         string `json:"x"`
+		KeyID     string `json:"kid"`
+		Use       string `json:"use"`
+		Algorithm string `json:"alg"`
+	}
+	type Response struct {
+		Keys []JWK `json:"keys"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		// The key set is served from the enclave's index of
+		// token signing keys. Hence, unauthenticated requests
+		// do not cause a scan of the entire key store.
+		signingKeys, err := enclave.SigningKeys(r.Context())
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(signingKeys))
+		for name := range signingKeys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		keys := make([]JWK, 0, len(names))
+		for _, name := range names {
+			key := signingKeys[name]
+			priv, err := key.SigningKey(tokenSigningKey)
+			if err != nil {
+				return err
+			}
+			pub := priv.Public().(ed25519.PublicKey)
+			keys = append(keys, JWK{
+				KeyType:   "OKP",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+				KeyID:     jwkThumbprint(pub),
+				Use:       "sig",
+				Algorithm: "EdDSA",
+			})
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Response{Keys: keys})
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Response:    Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// verifyTokenClaims returns an error if the claims contain
+// an audience or custom claim not allowed by the profile. A
+// nil profile allows neither audiences nor custom claims.
+func verifyTokenClaims(profile *auth.TokenProfile, claims map[string]any) error {
+	for name, value := range claims {
+		if name != "aud" {
+			if profile == nil || !profile.AllowsClaim(name) {
+				return kes.NewError(http.StatusForbidden, fmt.Sprintf("not authorized: claim '%s' is not allowed", name))
+			}
+			continue
+		}
+
+		// The audience is either a single string
+		// or a list of strings. (RFC 7519 4.1.3)
+		var audiences []string
+		switch v := value.(type) {
+		case string:
+			audiences = []string{v}
+		case []any:
+			for _, a := range v {
+				audience, ok := a.(string)
+				if !ok {
+					return kes.NewError(http.StatusBadRequest, "invalid claims: 'aud' must be a string or a list of strings")
+				}
+				audiences = append(audiences, audience)
+			}
+		default:
+			return kes.NewError(http.StatusBadRequest, "invalid claims: 'aud' must be a string or a list of strings")
+		}
+		for _, audience := range audiences {
+			if profile == nil || !profile.AllowsAudience(audience) {
+				return kes.NewError(http.StatusForbidden, fmt.Sprintf("not authorized: audience '%s' is not allowed", audience))
+			}
+		}
+	}
+	return nil
+}
+
+// expandClaim replaces the template variables within
+// all string values of the claim.
+func expandClaim(template *strings.Replacer, claim any) any {
+	switch v := claim.(type) {
+	case string:
+		return template.Replace(v)
+	case []any:
+		for i := range v {
+			v[i] = expandClaim(template, v[i])
+		}
+		return v
+	case map[string]any:
+		for k := range v {
+			v[k] = expandClaim(template, v[k])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// signJWT returns a compact EdDSA-signed JWT with the
+// given key ID and claims.
+func signJWT(priv ed25519.PrivateKey, keyID string, claims map[string]any) (string, error) {
+	header, err := json.Marshal(struct {
+		Algorithm string `json:"alg"`
+		Type      string `json:"typ"`
+		KeyID     string `json:"kid"`
+	}{
+		Algorithm: "EdDSA",
+		Type:      "JWT",
+		KeyID:     keyID,
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(priv, []byte(token))
+	return token + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// jwkThumbprint returns the RFC 7638 JWK thumbprint of
+// the Ed25519 public key. It is used as JWT key ID.
+func jwkThumbprint(pub ed25519.PublicKey) string {
+	// RFC 7638 requires the required JWK members in
+	// lexicographic order without whitespace.
+	jwk := `{"crv":"Ed25519","kty":"OKP","x":"` + base64.RawURLEncoding.EncodeToString(pub) + `"}`
+	h := sha256.Sum256([]byte(jwk))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
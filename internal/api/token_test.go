@@ -0,0 +1,49 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/minio/kes/internal/auth"
+)
+
+func TestVerifyTokenClaims(t *testing.T) {
+	profile := &auth.TokenProfile{
+		Audiences: []string{"billing", "payments"},
+		Claims:    []string{"tenant"},
+	}
+	for i, test := range verifyTokenClaimsTests {
+		p := profile
+		if test.NoProfile {
+			p = nil
+		}
+		err := verifyTokenClaims(p, test.Claims)
+		if err == nil && test.Status != 0 {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && statusCode(err) != test.Status {
+			t.Fatalf("Test %d: got status '%d' - want '%d': %v", i, statusCode(err), test.Status, err)
+		}
+	}
+}
+
+var verifyTokenClaimsTests = []struct {
+	Claims    map[string]any
+	NoProfile bool
+	Status    int
+}{
+	{Claims: map[string]any{}},                                                                // 0
+	{Claims: map[string]any{"aud": "billing", "tenant": "acme"}},                              // 1
+	{Claims: map[string]any{"aud": []any{"billing", "payments"}}},                             // 2
+	{Claims: map[string]any{}, NoProfile: true},                                               // 3
+	{Claims: map[string]any{"aud": "admin"}, Status: http.StatusForbidden},                    // 4
+	{Claims: map[string]any{"aud": []any{"billing", "admin"}}, Status: http.StatusForbidden},  // 5
+	{Claims: map[string]any{"aud": 42}, Status: http.StatusBadRequest},                        // 6
+	{Claims: map[string]any{"role": "admin"}, Status: http.StatusForbidden},                   // 7
+	{Claims: map[string]any{"aud": "billing"}, NoProfile: true, Status: http.StatusForbidden}, // 8
+	{Claims: map[string]any{"tenant": "acme"}, NoProfile: true, Status: http.StatusForbidden}, // 9
+}
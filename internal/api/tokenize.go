@@ -0,0 +1,191 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/sys"
+)
+
+// tokenize replaces sensitive values by random tokens within
+// a tokenization scope. Tokenizing and detokenizing are two
+// separate APIs. Hence, a policy may allow an identity to
+// tokenize values without being able to reveal them.
+func tokenize(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/tokenize/"
+		MaxBody     = int64(10 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+		MaxRequests = 1000 // Limit the number of values tokenized in a single API call, like bulk decryption.
+	)
+	type Request struct {
+		Value []byte `json:"value"`
+	}
+	type Response struct {
+		Token string `json:"token"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		scope, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var requests []Request
+		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(requests) > MaxRequests {
+			return kes.NewError(http.StatusBadRequest, "too many values")
+		}
+		for _, req := range requests {
+			if len(req.Value) == 0 {
+				return kes.NewError(http.StatusBadRequest, "value must not be empty")
+			}
+			if len(req.Value) > int(sys.MaxTokenValueSize) {
+				return kes.NewError(http.StatusBadRequest, "value is too large")
+			}
+		}
+
+		var (
+			identity  = auth.Identify(r)
+			now       = time.Now().UTC()
+			responses = make([]Response, 0, len(requests))
+		)
+		for _, req := range requests {
+			token, err := enclave.Tokenize(r.Context(), scope, sys.Token{
+				Value:     req.Value,
+				CreatedAt: now,
+				CreatedBy: identity,
+			})
+			if err != nil {
+				return err
+			}
+			responses = append(responses, Response{
+				Token: token,
+			})
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     []Request{},
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, config.Idempotency.Handle(enclaveVerifier(config.Vault), handler)))),
+	}
+}
+
+// detokenize returns the sensitive values replaced by the
+// given tokens within a tokenization scope.
+func detokenize(config *RouterConfig) API {
+	const (
+		Method      = http.MethodPost
+		APIPath     = "/v1/detokenize/"
+		MaxBody     = int64(1 * mem.MiB)
+		Timeout     = 15 * time.Second
+		Verify      = true
+		ContentType = "application/json"
+		MaxRequests = 1000 // Limit the number of tokens detokenized in a single API call, like bulk decryption.
+	)
+	type Request struct {
+		Token string `json:"token"`
+	}
+	type Response struct {
+		Value []byte `json:"value"`
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		scope, err := nameFromRequest(r, APIPath)
+		if err != nil {
+			return err
+		}
+
+		enclave, err := enclaveFromRequest(config.Vault, r)
+		if err != nil {
+			return err
+		}
+		if err = enclave.VerifyRequest(r); err != nil {
+			return err
+		}
+
+		var requests []Request
+		if err = json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			return kes.NewError(http.StatusBadRequest, err.Error())
+		}
+		if len(requests) > MaxRequests {
+			return kes.NewError(http.StatusBadRequest, "too many tokens")
+		}
+		for _, req := range requests {
+			if !validToken(req.Token) {
+				return kes.NewError(http.StatusBadRequest, "invalid token")
+			}
+		}
+
+		responses := make([]Response, 0, len(requests))
+		for _, req := range requests {
+			token, err := enclave.Detokenize(r.Context(), scope, req.Token)
+			if err != nil {
+				return err
+			}
+			responses = append(responses, Response{
+				Value: token.Value,
+			})
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(responses)
+		return nil
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: ContentType,
+		Request:     []Request{},
+		Response:    []Response{},
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+// validToken reports whether token is a well-formed token,
+// i.e. a hex-encoded 128 bit value as generated by
+// Enclave.Tokenize.
+func validToken(token string) bool {
+	if len(token) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	_ "embed"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/audit"
+	"github.com/minio/kes/internal/auth"
+)
+
+// uiIndex is the web console. It is a single, self-contained
+// page that talks to the KES API on behalf of the browser.
+// Hence, it has the same permissions as the client certificate
+// the browser presents.
+//
+//go:embed ui/index.html
+var uiIndex []byte
+
+// serveUI writes the web console page to w if the request
+// refers to the console's index.
+func serveUI(w http.ResponseWriter, r *http.Request, apiPath string) error {
+	const ContentType = "text/html; charset=utf-8"
+
+	if p := r.URL.Path; p != apiPath && p != apiPath+"index.html" {
+		return kes.NewError(http.StatusNotFound, "not found")
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(http.StatusOK)
+	w.Write(uiIndex)
+	return nil
+}
+
+func ui(config *RouterConfig) API {
+	const (
+		Method  = http.MethodGet
+		APIPath = "/ui/"
+		MaxBody = 0
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := verifyEnclaveRequest(config.Vault, r); err != nil {
+			return err
+		}
+		return serveUI(w, r, APIPath)
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "text/html; charset=utf-8",
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
+
+func edgeUI(config *EdgeRouterConfig) API {
+	var (
+		Method  = http.MethodGet
+		APIPath = "/ui/"
+		MaxBody int64
+		Timeout = 15 * time.Second
+		Verify  = true
+	)
+	if c, ok := config.APIConfig[APIPath]; ok {
+		if c.Timeout > 0 {
+			Timeout = c.Timeout
+		}
+	}
+	var handler HandlerFunc = func(w http.ResponseWriter, r *http.Request) error {
+		if err := auth.VerifyRequest(r, config.Policies, config.Identities); err != nil {
+			return err
+		}
+		return serveUI(w, r, APIPath)
+	}
+	return API{
+		Method:      Method,
+		Path:        APIPath,
+		MaxBody:     MaxBody,
+		Timeout:     Timeout,
+		Verify:      Verify,
+		ContentType: "text/html; charset=utf-8",
+		Handler:     config.Metrics.Count(config.Metrics.Latency(audit.Log(config.AuditLog, handler))),
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// Range of internal API wire versions supported by this
+// server. Cluster nodes use the internal API to forward
+// requests and exchange status information.
+//
+// MaxWireVersion must be incremented whenever the
+// internal API changes in an incompatible way, e.g.
+// when the representation of replicated state changes.
+// MinWireVersion must be incremented once a previous
+// wire version is no longer supported.
+const (
+	MinWireVersion = 1
+	MaxWireVersion = 1
+)
+
+// HeaderWireVersion is the HTTP header used by cluster nodes
+// to negotiate the internal API wire version.
+//
+// A node sends the range of wire versions it supports as
+// "<min>-<max>" with every request to another node. The
+// receiving node responds with the highest wire version
+// supported by both nodes or rejects the request if there
+// is no such version.
+const HeaderWireVersion = "Kes-Wire-Version"
+
+// errWireVersion is returned when two cluster
+// nodes do not support a common wire version.
+var errWireVersion = kes.NewError(http.StatusUpgradeRequired, fmt.Sprintf("incompatible wire version: node supports wire versions %d to %d", MinWireVersion, MaxWireVersion))
+
+// wireVersions returns the value of the wire
+// version header sent by this server.
+func wireVersions() string {
+	return strconv.Itoa(MinWireVersion) + "-" + strconv.Itoa(MaxWireVersion)
+}
+
+// negotiateWireVersion returns the highest wire version
+// supported by this server and the range of wire versions
+// within the header value.
+//
+// It returns errWireVersion if there is no common version.
+func negotiateWireVersion(header string) (int, error) {
+	min, max, err := parseWireVersions(header)
+	if err != nil {
+		return 0, err
+	}
+	if max > MaxWireVersion {
+		max = MaxWireVersion
+	}
+	if max < MinWireVersion || max < min {
+		return 0, errWireVersion
+	}
+	return max, nil
+}
+
+// parseWireVersions parses a wire version range of
+// the form "<min>-<max>" or a single wire version.
+func parseWireVersions(s string) (min, max int, err error) {
+	errInvalid := kes.NewError(http.StatusBadRequest, "invalid wire version '"+s+"'")
+
+	minStr, maxStr, ok := strings.Cut(s, "-")
+	if !ok {
+		maxStr = minStr
+	}
+	if min, err = strconv.Atoi(strings.TrimSpace(minStr)); err != nil || min <= 0 {
+		return 0, 0, errInvalid
+	}
+	if max, err = strconv.Atoi(strings.TrimSpace(maxStr)); err != nil || max < min {
+		return 0, 0, errInvalid
+	}
+	return min, max, nil
+}
+
+// verifyWireVersion checks the wire version the response of
+// another cluster node has been sent with. A response without
+// wire version is treated as wire version 1 since it has been
+// sent by a node without support for wire version negotiation.
+func verifyWireVersion(resp *http.Response) error {
+	if resp.StatusCode == http.StatusUpgradeRequired {
+		return kes.NewError(http.StatusUpgradeRequired, "incompatible wire version: cluster node does not support any of the wire versions "+wireVersions())
+	}
+
+	version := 1
+	if header := resp.Header.Get(HeaderWireVersion); header != "" {
+		v, err := strconv.Atoi(header)
+		if err != nil {
+			return errors.New("invalid wire version '" + header + "'")
+		}
+		version = v
+	}
+	if version < MinWireVersion || version > MaxWireVersion {
+		return kes.NewError(http.StatusUpgradeRequired, fmt.Sprintf("incompatible wire version: cluster node responded with wire version %d", version))
+	}
+	return nil
+}
+
+// negotiate negotiates the wire version of requests
+// sent by other cluster nodes. It rejects requests
+// with an incompatible wire version and passes all
+// other requests to h.
+func negotiate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get(HeaderWireVersion); header != "" {
+			version, err := negotiateWireVersion(header)
+			if err != nil {
+				Fail(w, err)
+				return
+			}
+			w.Header().Set(HeaderWireVersion, strconv.Itoa(version))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNegotiateWireVersion(t *testing.T) {
+	for i, test := range negotiateWireVersionTests {
+		version, err := negotiateWireVersion(test.Header)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to negotiate wire version: %v", i, err)
+		}
+		if err == nil && version != test.Version {
+			t.Fatalf("Test %d: got wire version '%d' - want '%d'", i, version, test.Version)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	handler := negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cluster/status", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK || resp.Header().Get(HeaderWireVersion) != "" {
+		t.Fatalf("Request without wire version: got status '%d' and wire version '%s'", resp.Code, resp.Header().Get(HeaderWireVersion))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/cluster/status", nil)
+	req.Header.Set(HeaderWireVersion, wireVersions())
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Request with compatible wire version: got status '%d' - want '%d'", resp.Code, http.StatusOK)
+	}
+	if v := resp.Header().Get(HeaderWireVersion); v != strconv.Itoa(MaxWireVersion) {
+		t.Fatalf("Request with compatible wire version: got wire version '%s' - want '%d'", v, MaxWireVersion)
+	}
+	if err := verifyWireVersion(resp.Result()); err != nil {
+		t.Fatalf("Failed to verify wire version of response: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/cluster/status", nil)
+	req.Header.Set(HeaderWireVersion, strconv.Itoa(MaxWireVersion+1)+"-"+strconv.Itoa(MaxWireVersion+2))
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	if resp.Code != http.StatusUpgradeRequired {
+		t.Fatalf("Request with incompatible wire version: got status '%d' - want '%d'", resp.Code, http.StatusUpgradeRequired)
+	}
+	if err := verifyWireVersion(resp.Result()); err == nil {
+		t.Fatal("Response with incompatible wire version has been accepted")
+	}
+}
+
+var negotiateWireVersionTests = []struct {
+	Header     string
+	Version    int
+	ShouldFail bool
+}{
+	{Header: "1-1", Version: 1},                  // 0
+	{Header: "1", Version: 1},                    // 1
+	{Header: "1-5", Version: MaxWireVersion},     // 2
+	{Header: " 1 - 2 ", Version: MaxWireVersion}, // 3
+	{Header: "100-200", ShouldFail: true},        // 4
+	{Header: "0-1", ShouldFail: true},            // 5
+	{Header: "2-1", ShouldFail: true},            // 6
+	{Header: "v1", ShouldFail: true},             // 7
+	{Header: "", ShouldFail: true},               // 8
+}
@@ -5,10 +5,13 @@
 package audit
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -17,6 +20,55 @@ import (
 	"github.com/minio/kes/internal/log"
 )
 
+// A DecisionLevel controls for which requests audit events
+// contain the policy decision, i.e. the policy and rule that
+// allowed or denied the request.
+type DecisionLevel int
+
+// Policy decision levels.
+const (
+	// DecisionsOff omits the policy decision from all
+	// audit events.
+	DecisionsOff DecisionLevel = iota
+
+	// DecisionsDenied includes the policy decision in
+	// audit events of requests denied by a policy.
+	DecisionsDenied
+
+	// DecisionsAll includes the policy decision in
+	// the audit events of all requests.
+	DecisionsAll
+)
+
+// ParseDecisionLevel parses s as DecisionLevel. Valid
+// values are "off", "denied" and "all".
+func ParseDecisionLevel(s string) (DecisionLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "off":
+		return DecisionsOff, nil
+	case "denied":
+		return DecisionsDenied, nil
+	case "all":
+		return DecisionsAll, nil
+	default:
+		return DecisionsOff, errors.New("audit: invalid decision level '" + s + "'")
+	}
+}
+
+type decisionLevelKey struct{}
+
+// WithDecisions returns a copy of ctx that records the
+// policy decision of requests with such a context. Audit
+// events of these requests contain the policy decision
+// depending on the given level.
+func WithDecisions(ctx context.Context, level DecisionLevel) context.Context {
+	if level == DecisionsOff {
+		return ctx
+	}
+	ctx = auth.WithDecisionRecorder(ctx)
+	return context.WithValue(ctx, decisionLevelKey{}, level)
+}
+
 // Log wraps h with an http.Handler that logs an audit log
 // event to the given logger.
 func Log(logger *log.Logger, h http.Handler) http.Handler {
@@ -33,6 +85,7 @@ func Log(logger *log.Logger, h http.Handler) http.Handler {
 			log:       logger,
 			url:       *r.URL,
 			ip:        ip,
+			ctx:       r.Context(),
 			identity:  auth.Identify(r),
 			timestamp: time.Now(),
 		}
@@ -46,6 +99,7 @@ type responseWriter struct {
 	log       *log.Logger
 	url       url.URL
 	ip        net.IP
+	ctx       context.Context
 	identity  kes.Identity
 	timestamp time.Time
 
@@ -75,12 +129,30 @@ func (w *responseWriter) WriteHeader(status int) {
 		StatusCode int           `json:"code"`
 		Time       time.Duration `json:"time"`
 	}
+	type DecisionInfo struct {
+		Admin   bool   `json:"admin,omitempty"`
+		Policy  string `json:"policy,omitempty"`
+		Rule    string `json:"rule,omitempty"`
+		Allowed bool   `json:"allowed"`
+	}
 	type Response struct {
-		Timestamp time.Time    `json:"time"`
-		Request   RequestInfo  `json:"request"`
-		Response  ResponseInfo `json:"response"`
+		Timestamp time.Time     `json:"time"`
+		Request   RequestInfo   `json:"request"`
+		Response  ResponseInfo  `json:"response"`
+		Decision  *DecisionInfo `json:"decision,omitempty"`
 	}
 
+	var decisionInfo *DecisionInfo
+	if level, _ := w.ctx.Value(decisionLevelKey{}).(DecisionLevel); level != DecisionsOff {
+		if decision, ok := auth.DecisionFromContext(w.ctx); ok && (level == DecisionsAll || !decision.Allowed) {
+			decisionInfo = &DecisionInfo{
+				Admin:   decision.Admin,
+				Policy:  decision.Policy,
+				Rule:    decision.Rule,
+				Allowed: decision.Allowed,
+			}
+		}
+	}
 	json.NewEncoder(w.log.Writer()).Encode(Response{
 		Timestamp: w.timestamp,
 		Request: RequestInfo{
@@ -93,6 +165,7 @@ func (w *responseWriter) WriteHeader(status int) {
 			StatusCode: status,
 			Time:       time.Now().UTC().Sub(w.timestamp.UTC()).Truncate(1 * time.Microsecond),
 		},
+		Decision: decisionInfo,
 	})
 }
 
@@ -0,0 +1,70 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/log"
+)
+
+var logDecisionTests = []struct {
+	Level    DecisionLevel
+	Decision auth.Decision
+	Logged   bool
+}{
+	{Level: DecisionsOff, Decision: auth.Decision{Policy: "my-policy", Rule: "/v1/key/create/*", Allowed: true}, Logged: false},
+	{Level: DecisionsOff, Decision: auth.Decision{Policy: "my-policy"}, Logged: false},
+	{Level: DecisionsDenied, Decision: auth.Decision{Policy: "my-policy", Rule: "/v1/key/create/*", Allowed: true}, Logged: false},
+	{Level: DecisionsDenied, Decision: auth.Decision{Policy: "my-policy", Rule: "/v1/key/*/my-key"}, Logged: true},
+	{Level: DecisionsAll, Decision: auth.Decision{Policy: "my-policy", Rule: "/v1/key/create/*", Allowed: true}, Logged: true},
+	{Level: DecisionsAll, Decision: auth.Decision{Admin: true, Allowed: true}, Logged: true},
+}
+
+func TestLogDecision(t *testing.T) {
+	for i, test := range logDecisionTests {
+		var buffer bytes.Buffer
+		handler := Log(log.New(&buffer, "", 0), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.RecordDecision(r.Context(), test.Decision)
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/key/create/my-key", nil)
+		req = req.WithContext(WithDecisions(req.Context(), test.Level))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		var event struct {
+			Decision *struct {
+				Admin   bool   `json:"admin"`
+				Policy  string `json:"policy"`
+				Rule    string `json:"rule"`
+				Allowed bool   `json:"allowed"`
+			} `json:"decision"`
+		}
+		if err := json.Unmarshal(buffer.Bytes(), &event); err != nil {
+			t.Fatalf("Test %d: failed to parse audit event: %v", i, err)
+		}
+		if logged := event.Decision != nil; logged != test.Logged {
+			t.Fatalf("Test %d: decision logged: got '%v' - want '%v'", i, logged, test.Logged)
+		}
+		if !test.Logged {
+			continue
+		}
+		decision := auth.Decision{
+			Admin:   event.Decision.Admin,
+			Policy:  event.Decision.Policy,
+			Rule:    event.Decision.Rule,
+			Allowed: event.Decision.Allowed,
+		}
+		if decision != test.Decision {
+			t.Fatalf("Test %d: invalid decision: got '%v' - want '%v'", i, decision, test.Decision)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/log"
+)
+
+// AuthFailure describes a client whose TLS certificate
+// has been rejected during the TLS handshake.
+type AuthFailure struct {
+	IP         net.IP       `json:"ip,omitempty"`
+	ServerName string       `json:"server_name,omitempty"`
+	Identity   kes.Identity `json:"identity,omitempty"`
+	Reason     string       `json:"reason"`
+	Error      string       `json:"error"`
+}
+
+// LogAuthFailure logs an audit event to the given logger
+// that records a failed TLS client authentication.
+//
+// Such clients never send a request. Hence, the event
+// contains an "auth_failure" instead of a "request" and
+// "response" field.
+func LogAuthFailure(logger *log.Logger, failure AuthFailure) {
+	type Event struct {
+		Timestamp time.Time   `json:"time"`
+		Failure   AuthFailure `json:"auth_failure"`
+	}
+	json.NewEncoder(logger.Writer()).Encode(Event{
+		Timestamp: time.Now(),
+		Failure:   failure,
+	})
+}
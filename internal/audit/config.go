@@ -0,0 +1,58 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/log"
+)
+
+// ConfigChange describes how the policies and identity
+// assignments of a key namespace have changed when the
+// server config got reloaded.
+type ConfigChange struct {
+	Namespace    string `json:"namespace,omitempty"`
+	AdminChanged bool   `json:"admin_changed,omitempty"`
+
+	AddedPolicies    []string `json:"added_policies,omitempty"`
+	RemovedPolicies  []string `json:"removed_policies,omitempty"`
+	ModifiedPolicies []string `json:"modified_policies,omitempty"`
+
+	AssignedIdentities []kes.Identity `json:"assigned_identities,omitempty"`
+	RemovedIdentities  []kes.Identity `json:"removed_identities,omitempty"`
+}
+
+// LogConfigReload logs an audit event to the given logger
+// that records the fingerprints of the previous and the
+// reloaded server config and how the policies and identity
+// assignments have changed.
+//
+// In contrast to request audit events, the event contains
+// a "config" instead of a "request" and "response" field.
+func LogConfigReload(logger *log.Logger, fingerprint, previous string, changes []ConfigChange) {
+	type ConfigInfo struct {
+		Fingerprint string         `json:"fingerprint"`
+		Previous    string         `json:"previous_fingerprint"`
+		Changes     []ConfigChange `json:"changes"`
+	}
+	type Event struct {
+		Timestamp time.Time  `json:"time"`
+		Config    ConfigInfo `json:"config"`
+	}
+	if changes == nil {
+		changes = []ConfigChange{}
+	}
+	json.NewEncoder(logger.Writer()).Encode(Event{
+		Timestamp: time.Now(),
+		Config: ConfigInfo{
+			Fingerprint: fingerprint,
+			Previous:    previous,
+			Changes:     changes,
+		},
+	})
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// defaultEnclave is the name of the enclave of
+// requests that don't specify an enclave.
+const defaultEnclave = "default"
+
+// A Filter selects audit events. An event is selected
+// if it matches all non-empty filter fields. Hence,
+// the zero Filter selects all events.
+type Filter struct {
+	// Identity selects events of requests
+	// sent by this identity.
+	Identity kes.Identity
+
+	// Path selects events of requests whose
+	// API path starts with Path.
+	Path string
+
+	// Status selects events with this response
+	// status code, like "404", or with a status
+	// code of this class, like "4xx".
+	Status string
+
+	// Enclave selects events of requests
+	// to this enclave.
+	Enclave string
+}
+
+// ParseFilter parses a Filter from the URL query
+// parameters 'identity', 'path', 'status' and
+// 'enclave'.
+func ParseFilter(query url.Values) (Filter, error) {
+	f := Filter{
+		Identity: kes.Identity(query.Get("identity")),
+		Path:     query.Get("path"),
+		Status:   strings.ToLower(query.Get("status")),
+		Enclave:  query.Get("enclave"),
+	}
+	if f.Status != "" {
+		if len(f.Status) != 3 || f.Status[0] < '1' || f.Status[0] > '5' {
+			return Filter{}, errors.New("audit: invalid status filter '" + f.Status + "'")
+		}
+		if f.Status[1:] != "xx" {
+			if _, err := strconv.Atoi(f.Status); err != nil {
+				return Filter{}, errors.New("audit: invalid status filter '" + f.Status + "'")
+			}
+		}
+	}
+	return f, nil
+}
+
+// IsZero reports whether f selects all events.
+func (f *Filter) IsZero() bool { return *f == Filter{} }
+
+// Match reports whether the audit event with the given
+// request identity, API path, enclave and response
+// status code is selected by f.
+func (f *Filter) Match(identity kes.Identity, path, enclave string, status int) bool {
+	if !f.Identity.IsUnknown() && f.Identity != identity {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(path, f.Path) {
+		return false
+	}
+	if f.Enclave != "" {
+		if enclave == "" {
+			enclave = defaultEnclave
+		}
+		if f.Enclave != enclave {
+			return false
+		}
+	}
+	if f.Status != "" {
+		code := strconv.Itoa(status)
+		if strings.HasSuffix(f.Status, "xx") {
+			return code[:1] == f.Status[:1]
+		}
+		return code == f.Status
+	}
+	return true
+}
+
+// Writer returns an io.Writer that writes the audit
+// events selected by f to w and discards all others.
+// Each write to the returned io.Writer must contain
+// exactly one JSON-encoded audit event.
+func (f Filter) Writer(w io.Writer) io.Writer {
+	if f.IsZero() {
+		return w
+	}
+	return &filterWriter{filter: f, w: w}
+}
+
+type filterWriter struct {
+	filter Filter
+	w      io.Writer
+}
+
+func (fw *filterWriter) Write(p []byte) (int, error) {
+	var event struct {
+		Request struct {
+			Enclave  string       `json:"enclave"`
+			APIPath  string       `json:"path"`
+			Identity kes.Identity `json:"identity"`
+		} `json:"request"`
+		Response struct {
+			StatusCode int `json:"code"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(p, &event); err != nil {
+		return len(p), nil // Discard everything that is not an audit event
+	}
+	if !fw.filter.Match(event.Request.Identity, event.Request.APIPath, event.Request.Enclave, event.Response.StatusCode) {
+		return len(p), nil
+	}
+	return fw.w.Write(p)
+}
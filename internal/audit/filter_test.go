@@ -0,0 +1,66 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	const Event = `{"time":"2023-03-24T12:37:33Z","request":{"ip":"10.1.2.3","enclave":"tenant-1","path":"/v1/key/create/my-key","identity":"2ecb8804e7702a6b768e87cfa0c4b7d4ea0fc1f4f3f3b4e7d2a9cd2b5c2e1f6a"},"response":{"code":403,"time":1000}}` + "\n"
+
+	for i, test := range filterTests {
+		filter, err := ParseFilter(test.Query)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse filter: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing should have failed", i)
+		}
+		if err != nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if _, err = filter.Writer(&buf).Write([]byte(Event)); err != nil {
+			t.Fatalf("Test %d: failed to write event: %v", i, err)
+		}
+		if selected := buf.Len() > 0; selected != test.Selected {
+			t.Fatalf("Test %d: got selected '%v' - want '%v'", i, selected, test.Selected)
+		}
+	}
+}
+
+var filterTests = []struct {
+	Query      url.Values
+	Selected   bool
+	ShouldFail bool
+}{
+	{Query: url.Values{}, Selected: true},                                                                                 // 0
+	{Query: url.Values{"path": {"/v1/key/"}}, Selected: true},                                                             // 1
+	{Query: url.Values{"path": {"/v1/policy/"}}, Selected: false},                                                         // 2
+	{Query: url.Values{"status": {"403"}}, Selected: true},                                                                // 3
+	{Query: url.Values{"status": {"4xx"}}, Selected: true},                                                                // 4
+	{Query: url.Values{"status": {"2xx"}}, Selected: false},                                                               // 5
+	{Query: url.Values{"status": {"200"}}, Selected: false},                                                               // 6
+	{Query: url.Values{"enclave": {"tenant-1"}}, Selected: true},                                                          // 7
+	{Query: url.Values{"enclave": {"default"}}, Selected: false},                                                          // 8
+	{Query: url.Values{"identity": {"2ecb8804e7702a6b768e87cfa0c4b7d4ea0fc1f4f3f3b4e7d2a9cd2b5c2e1f6a"}}, Selected: true}, // 9
+	{Query: url.Values{"identity": {"a"}}, Selected: false},                                                               // 10
+	{Query: url.Values{"path": {"/v1/key/"}, "status": {"4xx"}, "enclave": {"tenant-1"}}, Selected: true},                 // 11
+	{Query: url.Values{"path": {"/v1/key/"}, "status": {"5xx"}}, Selected: false},                                         // 12
+	{Query: url.Values{"status": {"abc"}}, ShouldFail: true},                                                              // 13
+	{Query: url.Values{"status": {"4x"}}, ShouldFail: true},                                                               // 14
+	{Query: url.Values{"status": {"9xx"}}, ShouldFail: true},                                                              // 15
+}
+
+func TestFilterDefaultEnclave(t *testing.T) {
+	filter := Filter{Enclave: "default"}
+	if !filter.Match("", "/v1/key/create/my-key", "", 200) {
+		t.Fatal("Events without enclave should be selected by the default enclave filter")
+	}
+}
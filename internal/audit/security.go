@@ -0,0 +1,151 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+)
+
+// Security event types. A security event is an audit
+// event with an additional "security" field that
+// contains one of these types.
+const (
+	// SecurityAuthFailure is the type of events of clients
+	// whose TLS certificate has been rejected.
+	SecurityAuthFailure = "auth_failure"
+
+	// SecurityDenied is the type of events of requests
+	// that have been rejected as unauthenticated or
+	// denied by a policy.
+	SecurityDenied = "denied"
+
+	// SecurityBreakGlass is the type of events of requests
+	// sent by the admin identity. The admin is not subject
+	// to any policy and should only be used in emergencies.
+	SecurityBreakGlass = "break_glass"
+
+	// SecurityAdmin is the type of events of requests that
+	// change policies, identities, enclaves, credentials or
+	// server settings, and of server config reloads.
+	SecurityAdmin = "admin"
+)
+
+// adminAPIs are the API path prefixes of requests that
+// are security-relevant, even if sent by a regular
+// identity that is allowed to do so.
+var adminAPIs = []string{
+	"/v1/enclave/create/",
+	"/v1/enclave/delete/",
+	"/v1/policy/write/",
+	"/v1/policy/assign/",
+	"/v1/policy/delete/",
+	"/v1/identity/delete/",
+	"/v1/access/",
+	"/v1/ca/issue",
+	"/v1/ca/revoke/",
+	"/v1/cert/issue/",
+	"/v1/ssh/sign/",
+	"/v1/log/level/set/",
+	"/v1/admin/",
+}
+
+// SecurityWriter returns an io.Writer that writes only the
+// security-relevant audit events to w and discards all
+// others. Each write to the returned io.Writer must contain
+// exactly one JSON-encoded audit event.
+//
+// Requests sent by the given admin identity are considered
+// break-glass use. Each event written to w contains an
+// additional "security" field with the event type.
+func SecurityWriter(w io.Writer, admin kes.Identity) io.Writer {
+	return &securityWriter{admin: admin, w: w}
+}
+
+type securityWriter struct {
+	admin kes.Identity
+	w     io.Writer
+}
+
+func (sw *securityWriter) Write(p []byte) (int, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(p, &event); err != nil {
+		return len(p), nil // Discard everything that is not an audit event
+	}
+	kind, ok := sw.securityType(event)
+	if !ok {
+		return len(p), nil
+	}
+
+	// Prepend the security field instead of re-encoding
+	// the event such that all other fields keep their order.
+	b := make([]byte, 0, len(p)+len(kind)+16)
+	b = append(b, `{"security":"`...)
+	b = append(b, kind...)
+	b = append(b, `",`...)
+	b = append(b, bytes.TrimSpace(p)[1:]...)
+	if _, err := sw.w.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// securityType returns the security event type of the
+// given audit event and false if the event is not
+// security-relevant.
+func (sw *securityWriter) securityType(event map[string]json.RawMessage) (string, bool) {
+	if _, ok := event["auth_failure"]; ok {
+		return SecurityAuthFailure, true
+	}
+	if _, ok := event["config"]; ok {
+		return SecurityAdmin, true
+	}
+
+	var (
+		request struct {
+			APIPath  string       `json:"path"`
+			Identity kes.Identity `json:"identity"`
+		}
+		response struct {
+			StatusCode int `json:"code"`
+		}
+		decision struct {
+			Admin   bool `json:"admin"`
+			Allowed bool `json:"allowed"`
+		}
+	)
+	if err := json.Unmarshal(event["request"], &request); err != nil {
+		return "", false
+	}
+	if err := json.Unmarshal(event["response"], &response); err != nil {
+		return "", false
+	}
+	if raw, ok := event["decision"]; ok {
+		if err := json.Unmarshal(raw, &decision); err != nil {
+			return "", false
+		}
+		if !decision.Allowed && !decision.Admin {
+			return SecurityDenied, true
+		}
+	}
+
+	switch {
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		return SecurityDenied, true
+	case decision.Admin || (!sw.admin.IsUnknown() && request.Identity == sw.admin):
+		return SecurityBreakGlass, true
+	}
+	for _, prefix := range adminAPIs {
+		if strings.HasPrefix(request.APIPath, prefix) {
+			return SecurityAdmin, true
+		}
+	}
+	return "", false
+}
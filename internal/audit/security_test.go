@@ -0,0 +1,86 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestSecurityWriter(t *testing.T) {
+	const Admin kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+
+	for i, test := range securityWriterTests {
+		var buf bytes.Buffer
+		if _, err := SecurityWriter(&buf, Admin).Write([]byte(test.Event + "\n")); err != nil {
+			t.Fatalf("Test %d: failed to write event: %v", i, err)
+		}
+		if buf.Len() == 0 {
+			if test.Type != "" {
+				t.Fatalf("Test %d: event has been discarded - want type '%s'", i, test.Type)
+			}
+			continue
+		}
+
+		var event struct {
+			Security string `json:"security"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+			t.Fatalf("Test %d: failed to decode event: %v", i, err)
+		}
+		if event.Security != test.Type {
+			t.Fatalf("Test %d: got type '%s' - want '%s'", i, event.Security, test.Type)
+		}
+	}
+}
+
+var securityWriterTests = []struct {
+	Event string
+	Type  string
+}{
+	{ // 0
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/key/encrypt/my-key","identity":"a"},"response":{"code":200,"time":1000}}`,
+		Type:  "",
+	},
+	{ // 1
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/key/encrypt/my-key","identity":"a"},"response":{"code":403,"time":1000}}`,
+		Type:  SecurityDenied,
+	},
+	{ // 2
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/key/encrypt/my-key","identity":"a"},"response":{"code":404,"time":1000},"decision":{"policy":"my-app","allowed":false}}`,
+		Type:  SecurityDenied,
+	},
+	{ // 3
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/key/encrypt/my-key","identity":"3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"},"response":{"code":200,"time":1000}}`,
+		Type:  SecurityBreakGlass,
+	},
+	{ // 4
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/key/encrypt/my-key","identity":"b"},"response":{"code":200,"time":1000},"decision":{"admin":true,"allowed":true}}`,
+		Type:  SecurityBreakGlass,
+	},
+	{ // 5
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/policy/assign/my-app","identity":"a"},"response":{"code":200,"time":1000}}`,
+		Type:  SecurityAdmin,
+	},
+	{ // 6
+		Event: `{"time":"2023-03-24T12:37:33Z","request":{"path":"/v1/policy/describe/my-app","identity":"a"},"response":{"code":200,"time":1000}}`,
+		Type:  "",
+	},
+	{ // 7
+		Event: `{"time":"2023-03-24T12:37:33Z","auth_failure":{"ip":"10.1.2.3","reason":"expired","error":"certificate has expired"}}`,
+		Type:  SecurityAuthFailure,
+	},
+	{ // 8
+		Event: `{"time":"2023-03-24T12:37:33Z","config":{"fingerprint":"a","previous_fingerprint":"b","changes":[]}}`,
+		Type:  SecurityAdmin,
+	},
+	{ // 9
+		Event: `not an audit event`,
+		Type:  "",
+	},
+}
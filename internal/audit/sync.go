@@ -0,0 +1,187 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/log"
+)
+
+// SyncConfig is a structure containing the
+// configuration of a Syncer.
+type SyncConfig struct {
+	// Endpoint is the URL of the audit log service
+	// that receives the audit log files.
+	Endpoint string
+
+	// Client is the HTTP client used to upload audit
+	// log files. If nil, a client with a 1 minute
+	// timeout is used.
+	Client *http.Client
+
+	// Interval is the time period between two sync
+	// attempts. If <= 0, defaults to 5 minutes.
+	Interval time.Duration
+}
+
+// Syncer uploads audit log files to a central audit
+// log service whenever the service is reachable. It
+// allows KES servers with intermittent connectivity
+// to write audit events to a local file and sync them
+// back once connectivity returns.
+//
+// A Syncer periodically rotates the audit log file and
+// uploads all rotated files that have not been synced
+// yet, oldest first. Each file is sent as body of a POST
+// request with the file name in the Kes-Audit-File header.
+// Compressed files are sent with a gzip Content-Encoding.
+//
+// The Syncer keeps track of synced files in a state
+// file next to the audit log file. Rotated files that
+// get removed due to the retention limits of the audit
+// log file before being synced are lost.
+type Syncer struct {
+	file     *log.File
+	endpoint string
+	client   *http.Client
+	state    string
+
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// NewSyncer returns a new Syncer for the given audit log
+// file and starts syncing in the background until the
+// Syncer is closed.
+func NewSyncer(file *log.File, config *SyncConfig) *Syncer {
+	s := &Syncer{
+		file:     file,
+		endpoint: config.Endpoint,
+		client:   config.Client,
+		state:    file.Name() + ".sync",
+	}
+	if s.client == nil {
+		s.client = &http.Client{Timeout: time.Minute}
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Sync(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					log.Printf("audit: failed to sync audit log to '%s': %v", s.endpoint, err)
+				}
+			}
+		}
+	}()
+	return s
+}
+
+// Sync rotates the audit log file and uploads all
+// rotated files that have not been synced yet. It
+// stops at the first file that cannot be uploaded.
+func (s *Syncer) Sync(ctx context.Context) error {
+	if err := s.file.Rotate(); err != nil {
+		return err
+	}
+	files, err := s.file.Rotated()
+	if err != nil {
+		return err
+	}
+
+	synced, err := s.lastSynced()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !file.Time.After(synced) {
+			continue
+		}
+		if err = s.upload(ctx, file.Path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // Removed due to retention limits
+			}
+			return err
+		}
+		if err = os.WriteFile(s.state, []byte(file.Time.Format(time.RFC3339Nano)), 0o600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the Syncer. It does not
+// close the audit log file.
+func (s *Syncer) Close() error {
+	s.stop()
+	s.done.Wait()
+	return nil
+}
+
+// lastSynced returns the rotation time of the
+// last synced file.
+func (s *Syncer) lastSynced() (time.Time, error) {
+	b, err := os.ReadFile(s.state)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid sync state '%s': %v", s.state, err)
+	}
+	return t, nil
+}
+
+func (s *Syncer) upload(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, file)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Kes-Audit-File", filepath.Base(path))
+	if strings.HasSuffix(path, ".gz") {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit log service responded with '%s'", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/minio/kes/internal/log"
+)
+
+func TestSyncer(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		online   bool
+		received []string
+	)
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if !online {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+	}))
+	defer service.Close()
+
+	file, err := log.OpenFile(filepath.Join(t.TempDir(), "audit.log"), log.FileConfig{})
+	if err != nil {
+		t.Fatalf("Failed to open audit log file: %v", err)
+	}
+	defer file.Close()
+
+	syncer := NewSyncer(file, &SyncConfig{Endpoint: service.URL})
+	defer syncer.Close()
+
+	ctx := context.Background()
+	file.Write([]byte("event-1\n"))
+	if err = syncer.Sync(ctx); err == nil {
+		t.Fatal("Synced audit log to unavailable service")
+	}
+
+	lock.Lock()
+	online = true
+	lock.Unlock()
+
+	file.Write([]byte("event-2\n"))
+	if err = syncer.Sync(ctx); err != nil {
+		t.Fatalf("Failed to sync audit log: %v", err)
+	}
+	if err = syncer.Sync(ctx); err != nil {
+		t.Fatalf("Failed to sync audit log: %v", err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Invalid number of synced files: got '%d' - want '%d'", len(received), 2)
+	}
+	if received[0] != "event-1\n" || received[1] != "event-2\n" {
+		t.Fatalf("Invalid synced files: got '%q' - want '%q'", received, []string{"event-1\n", "event-2\n"})
+	}
+}
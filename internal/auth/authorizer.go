@@ -0,0 +1,230 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Authorizer authorizes requests that have passed the
+// built-in policy checks. Hence, an Authorizer can only
+// restrict but not extend the permissions granted by
+// KES policies. For example, it can consult a central
+// entitlement system.
+//
+// An Authorizer is not consulted for requests of admin
+// identities.
+type Authorizer interface {
+	// Authorize returns nil if the identity, assigned to
+	// the given policy, is allowed to perform the request.
+	// It returns kes.ErrNotAllowed if the request has been
+	// denied.
+	Authorize(r *http.Request, identity kes.Identity, policy string) error
+}
+
+type authorizerKey struct{}
+
+// WithAuthorizer returns a copy of ctx that carries
+// the given Authorizer. Requests with such a context
+// get authorized by the Authorizer once they have
+// passed the built-in policy checks.
+func WithAuthorizer(ctx context.Context, authorizer Authorizer) context.Context {
+	return context.WithValue(ctx, authorizerKey{}, authorizer)
+}
+
+// Authorize authorizes the request using the Authorizer
+// of the request context, if any. It returns nil if the
+// request context does not carry an Authorizer.
+func Authorize(r *http.Request, identity kes.Identity, policy string) error {
+	authorizer, ok := r.Context().Value(authorizerKey{}).(Authorizer)
+	if !ok || authorizer == nil {
+		return nil
+	}
+	return authorizer.Authorize(r, identity, policy)
+}
+
+var errAuthorizerUnavailable = kes.NewError(http.StatusServiceUnavailable, "service unavailable: authorizer not available")
+
+// HTTPAuthorizerConfig is a structure containing
+// the HTTPAuthorizer configuration.
+type HTTPAuthorizerConfig struct {
+	// Endpoint is the URL of the authorization
+	// service.
+	Endpoint string
+
+	// Client is the HTTP client used to send requests
+	// to the authorization service. If nil, a client
+	// with a 5 second timeout is used.
+	Client *http.Client
+
+	// CacheExpiry is the time period authorization
+	// decisions are cached. If 0, defaults to 30s.
+	// If < 0, decisions are not cached.
+	CacheExpiry time.Duration
+}
+
+// HTTPAuthorizer is an Authorizer that consults
+// an external authorization service via HTTP.
+//
+// For each request, it sends a POST request with
+// the following JSON body to the service:
+//
+//	{
+//	  "identity": "<identity>",
+//	  "policy":   "<policy>",
+//	  "enclave":  "<enclave>",
+//	  "method":   "<HTTP method>",
+//	  "path":     "<URL path>"
+//	}
+//
+// The service has to respond with 200 OK and the
+// JSON object {"allow": true} to allow the request.
+// The decision is cached per identity, method and
+// path for the configured expiry period.
+//
+// If the service is not reachable or responds with
+// an error, the request is rejected with 503 Service
+// Unavailable.
+type HTTPAuthorizer struct {
+	endpoint string
+	client   *http.Client
+	expiry   time.Duration
+
+	lock  sync.Mutex
+	cache map[authzRequest]authzDecision
+}
+
+// maxAuthzCacheSize is the max. number of
+// decisions cached by an HTTPAuthorizer.
+const maxAuthzCacheSize = 10000
+
+type authzRequest struct {
+	Identity kes.Identity `json:"identity"`
+	Policy   string       `json:"policy"`
+	Enclave  string       `json:"enclave"`
+	Method   string       `json:"method"`
+	Path     string       `json:"path"`
+}
+
+type authzDecision struct {
+	Allow     bool
+	ExpiresAt time.Time
+}
+
+// NewHTTPAuthorizer returns a new HTTPAuthorizer
+// with the given configuration.
+func NewHTTPAuthorizer(config *HTTPAuthorizerConfig) *HTTPAuthorizer {
+	a := &HTTPAuthorizer{
+		endpoint: config.Endpoint,
+		client:   config.Client,
+		expiry:   config.CacheExpiry,
+		cache:    map[authzRequest]authzDecision{},
+	}
+	if a.client == nil {
+		a.client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if a.expiry == 0 {
+		a.expiry = 30 * time.Second
+	}
+	return a
+}
+
+var _ Authorizer = (*HTTPAuthorizer)(nil) // compiler check
+
+// Authorize asks the authorization service whether the
+// identity is allowed to perform the request, unless a
+// decision has been cached.
+func (a *HTTPAuthorizer) Authorize(r *http.Request, identity kes.Identity, policy string) error {
+	req := authzRequest{
+		Identity: identity,
+		Policy:   policy,
+		Enclave:  r.URL.Query().Get("enclave"),
+		Method:   r.Method,
+		Path:     r.URL.Path,
+	}
+
+	now := time.Now()
+	a.lock.Lock()
+	decision, ok := a.cache[req]
+	a.lock.Unlock()
+	if !ok || now.After(decision.ExpiresAt) {
+		allow, err := a.callout(r.Context(), &req)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			log.Printf("auth: failed to authorize request of '%s': %v", identity, err)
+			return errAuthorizerUnavailable
+		}
+		decision = authzDecision{Allow: allow, ExpiresAt: now.Add(a.expiry)}
+		if a.expiry > 0 {
+			a.add(req, decision)
+		}
+	}
+	if !decision.Allow {
+		return kes.ErrNotAllowed
+	}
+	return nil
+}
+
+func (a *HTTPAuthorizer) add(req authzRequest, decision authzDecision) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.cache) >= maxAuthzCacheSize {
+		now := time.Now()
+		for k, v := range a.cache {
+			if now.After(v.ExpiresAt) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= maxAuthzCacheSize {
+			a.cache = map[authzRequest]authzDecision{}
+		}
+	}
+	a.cache[req] = decision
+}
+
+// callout sends the authorization request to the
+// authorization service and returns its decision.
+func (a *HTTPAuthorizer) callout(ctx context.Context, authzReq *authzRequest) (bool, error) {
+	body, err := json.Marshal(authzReq)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("authorizer responded with '%s'", resp.Status)
+	}
+	var response struct {
+		Allow bool `json:"allow"`
+	}
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil {
+		return false, err
+	}
+	return response.Allow, nil
+}
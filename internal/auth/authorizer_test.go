@@ -0,0 +1,62 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestHTTPAuthorizer(t *testing.T) {
+	var callouts int64
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&callouts, 1)
+
+		var req authzRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Policy == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"allow": req.Path == "/v1/key/create/my-key"})
+	}))
+	defer service.Close()
+
+	authorizer := NewHTTPAuthorizer(&HTTPAuthorizerConfig{Endpoint: service.URL})
+	request := func(path string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		return r.WithContext(WithAuthorizer(r.Context(), authorizer))
+	}
+
+	if err := Authorize(request("/v1/key/create/my-key"), "identity", "policy"); err != nil {
+		t.Fatalf("Request has been denied: %v", err)
+	}
+	if err := Authorize(request("/v1/key/create/my-key"), "identity", "policy"); err != nil {
+		t.Fatalf("Request has been denied: %v", err)
+	}
+	if n := atomic.LoadInt64(&callouts); n != 1 {
+		t.Fatalf("Invalid number of callouts: got '%d' - want '%d'", n, 1)
+	}
+	if err := Authorize(request("/v1/key/delete/my-key"), "identity", "policy"); !errors.Is(err, kes.ErrNotAllowed) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+	if err := Authorize(request("/v1/key/create/my-key"), "identity", "broken"); err != errAuthorizerUnavailable {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, errAuthorizerUnavailable)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/key/delete/my-key", nil)
+	if err := Authorize(r, "identity", "policy"); err != nil {
+		t.Fatalf("Request without authorizer has been denied: %v", err)
+	}
+}
@@ -0,0 +1,361 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/singleflight"
+)
+
+// maxAuthCacheSize is the max. number of policies
+// or identities cached by a PolicyCache or an
+// IdentityCache.
+const maxAuthCacheSize = 10000
+
+// PolicyCache is a PolicySet that caches the policies
+// of another PolicySet in memory. Policies are fetched
+// from the underlying PolicySet at most once per expiry
+// period.
+//
+// A PolicyCache is a write-through cache. Set and Delete
+// modify the underlying PolicySet and update the cache.
+// Changes that bypass the PolicyCache become visible once
+// cache entries expire or the cache gets invalidated.
+type PolicyCache struct {
+	policies PolicySet
+	expiry   time.Duration
+
+	lock  sync.Mutex
+	gen   uint64 // Incremented on every write to prevent caching stale policies
+	cache map[string]policyEntry
+
+	// Concurrent fetches of the same policy within the
+	// same generation share a single PolicySet lookup.
+	fetches singleflight.Group[fetchKey[string], *Policy]
+}
+
+// fetchKey identifies an in-flight fetch. Fetches are
+// only shared within the same cache generation such that
+// a fetch started before a write is not shared with a
+// request issued after the write.
+type fetchKey[K comparable] struct {
+	Name K
+	Gen  uint64
+}
+
+type policyEntry struct {
+	Policy    *Policy
+	ExpiresAt time.Time
+}
+
+var _ PolicySet = (*PolicyCache)(nil) // compiler check
+
+// NewPolicyCache returns a new PolicyCache that caches
+// the policies of the given PolicySet for the expiry
+// period. If expiry is 0, it defaults to 1 minute.
+// If expiry < 0, policies are not cached.
+func NewPolicyCache(policies PolicySet, expiry time.Duration) *PolicyCache {
+	if expiry == 0 {
+		expiry = 1 * time.Minute
+	}
+	return &PolicyCache{
+		policies: policies,
+		expiry:   expiry,
+		cache:    map[string]policyEntry{},
+	}
+}
+
+// Set creates or replaces the policy at the given name
+// within the underlying PolicySet and the cache.
+func (c *PolicyCache) Set(ctx context.Context, name string, policy *Policy) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	delete(c.cache, name)
+	if err := c.policies.Set(ctx, name, policy); err != nil {
+		return err
+	}
+	c.add(c.gen, name, policy)
+	return nil
+}
+
+// Get returns the policy with the given name. It fetches
+// the policy from the underlying PolicySet if the policy
+// is not cached or its cache entry has expired.
+//
+// It returns ErrPolicyNotFound if no policy with the
+// given name exists.
+func (c *PolicyCache) Get(ctx context.Context, name string) (*Policy, error) {
+	now := time.Now()
+	c.lock.Lock()
+	entry, ok := c.cache[name]
+	gen := c.gen
+	c.lock.Unlock()
+	if ok && now.Before(entry.ExpiresAt) {
+		return entry.Policy, nil
+	}
+
+	policy, err, shared := c.fetches.Do(fetchKey[string]{name, gen}, func() (*Policy, error) {
+		return c.policies.Get(ctx, name)
+	})
+	if shared && isContextErr(err) && ctx.Err() == nil {
+		policy, err = c.policies.Get(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.add(gen, name, policy)
+	c.lock.Unlock()
+	return policy, nil
+}
+
+// Delete deletes the policy with the given name from
+// the underlying PolicySet and the cache.
+//
+// It returns ErrPolicyNotFound if no policy with the
+// given name exists.
+func (c *PolicyCache) Delete(ctx context.Context, name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	delete(c.cache, name)
+	return c.policies.Delete(ctx, name)
+}
+
+// List returns an iterator over all policies of the
+// underlying PolicySet.
+func (c *PolicyCache) List(ctx context.Context) (PolicyIterator, error) {
+	return c.policies.List(ctx)
+}
+
+// Len returns the number of cached policies.
+func (c *PolicyCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.cache)
+}
+
+// Invalidate removes all policies from the cache.
+func (c *PolicyCache) Invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	c.cache = map[string]policyEntry{}
+}
+
+// add adds the policy to the cache unless the cache has
+// been modified since generation gen. The caller must
+// hold the lock.
+func (c *PolicyCache) add(gen uint64, name string, policy *Policy) {
+	if c.expiry < 0 || gen != c.gen {
+		return
+	}
+	if len(c.cache) >= maxAuthCacheSize {
+		c.cache = map[string]policyEntry{}
+	}
+	c.cache[name] = policyEntry{
+		Policy:    policy,
+		ExpiresAt: time.Now().Add(c.expiry),
+	}
+}
+
+// IdentityCache is an IdentitySet that caches the
+// identities of another IdentitySet in memory.
+// Identities and the admin identity are fetched from
+// the underlying IdentitySet at most once per expiry
+// period.
+//
+// An IdentityCache is a write-through cache. Assign and
+// Delete modify the underlying IdentitySet and update the
+// cache. Changes that bypass the IdentityCache become
+// visible once cache entries expire or the cache gets
+// invalidated.
+type IdentityCache struct {
+	identities IdentitySet
+	expiry     time.Duration
+
+	lock      sync.Mutex
+	gen       uint64 // Incremented on every write to prevent caching stale identities
+	admin     kes.Identity
+	adminTime time.Time
+	cache     map[kes.Identity]identityEntry
+
+	fetches      singleflight.Group[fetchKey[kes.Identity], IdentityInfo]
+	adminFetches singleflight.Group[uint64, kes.Identity]
+}
+
+type identityEntry struct {
+	Info      IdentityInfo
+	ExpiresAt time.Time
+}
+
+var _ IdentitySet = (*IdentityCache)(nil) // compiler check
+
+// NewIdentityCache returns a new IdentityCache that
+// caches the identities of the given IdentitySet for
+// the expiry period. If expiry is 0, it defaults to
+// 1 minute. If expiry < 0, identities are not cached.
+func NewIdentityCache(identities IdentitySet, expiry time.Duration) *IdentityCache {
+	if expiry == 0 {
+		expiry = 1 * time.Minute
+	}
+	return &IdentityCache{
+		identities: identities,
+		expiry:     expiry,
+		cache:      map[kes.Identity]identityEntry{},
+	}
+}
+
+// Admin returns the identity of the admin. It fetches
+// the admin identity from the underlying IdentitySet
+// if it is not cached or its cache entry has expired.
+func (c *IdentityCache) Admin(ctx context.Context) (kes.Identity, error) {
+	now := time.Now()
+	c.lock.Lock()
+	admin, expiresAt, gen := c.admin, c.adminTime, c.gen
+	c.lock.Unlock()
+	if !admin.IsUnknown() && now.Before(expiresAt) {
+		return admin, nil
+	}
+
+	admin, err, shared := c.adminFetches.Do(gen, func() (kes.Identity, error) {
+		return c.identities.Admin(ctx)
+	})
+	if shared && isContextErr(err) && ctx.Err() == nil {
+		admin, err = c.identities.Admin(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.lock.Lock()
+	if c.expiry > 0 && gen == c.gen {
+		c.admin, c.adminTime = admin, time.Now().Add(c.expiry)
+	}
+	c.lock.Unlock()
+	return admin, nil
+}
+
+// Assign assigns the policy to the given identity within
+// the underlying IdentitySet and removes the identity from
+// the cache.
+//
+// It returns an error when the identity is equal to the
+// admin identity.
+func (c *IdentityCache) Assign(ctx context.Context, policy string, identity kes.Identity) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// The IdentityInfo, e.g. its creation time, is
+	// determined by the underlying IdentitySet.
+	c.gen++
+	delete(c.cache, identity)
+	return c.identities.Assign(ctx, policy, identity)
+}
+
+// Get returns the IdentityInfo of an assigned identity.
+// It fetches the IdentityInfo from the underlying
+// IdentitySet if the identity is not cached or its
+// cache entry has expired.
+//
+// It returns ErrIdentityNotFound when there is no
+// IdentityInfo associated to the given identity.
+func (c *IdentityCache) Get(ctx context.Context, identity kes.Identity) (IdentityInfo, error) {
+	now := time.Now()
+	c.lock.Lock()
+	entry, ok := c.cache[identity]
+	gen := c.gen
+	c.lock.Unlock()
+	if ok && now.Before(entry.ExpiresAt) {
+		return entry.Info, nil
+	}
+
+	info, err, shared := c.fetches.Do(fetchKey[kes.Identity]{identity, gen}, func() (IdentityInfo, error) {
+		return c.identities.Get(ctx, identity)
+	})
+	if shared && isContextErr(err) && ctx.Err() == nil {
+		info, err = c.identities.Get(ctx, identity)
+	}
+	if err != nil {
+		return IdentityInfo{}, err
+	}
+	c.lock.Lock()
+	c.add(gen, identity, info)
+	c.lock.Unlock()
+	return info, nil
+}
+
+// Delete deletes the given identity from the underlying
+// IdentitySet and the cache.
+//
+// It returns ErrNotAssigned when the identity is not
+// assigned.
+func (c *IdentityCache) Delete(ctx context.Context, identity kes.Identity) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	delete(c.cache, identity)
+	return c.identities.Delete(ctx, identity)
+}
+
+// List returns an iterator over all assigned identities
+// of the underlying IdentitySet.
+func (c *IdentityCache) List(ctx context.Context) (IdentityIterator, error) {
+	return c.identities.List(ctx)
+}
+
+// Len returns the number of cached identities, not
+// including the admin identity.
+func (c *IdentityCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.cache)
+}
+
+// Invalidate removes all identities, including the
+// admin identity, from the cache.
+func (c *IdentityCache) Invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	c.admin, c.adminTime = "", time.Time{}
+	c.cache = map[kes.Identity]identityEntry{}
+}
+
+// add adds the IdentityInfo to the cache unless the cache
+// has been modified since generation gen. The caller must
+// hold the lock.
+func (c *IdentityCache) add(gen uint64, identity kes.Identity, info IdentityInfo) {
+	if c.expiry < 0 || gen != c.gen {
+		return
+	}
+	if len(c.cache) >= maxAuthCacheSize {
+		c.cache = map[kes.Identity]identityEntry{}
+	}
+	c.cache[identity] = identityEntry{
+		Info:      info,
+		ExpiresAt: time.Now().Add(c.expiry),
+	}
+}
+
+// isContextErr reports whether err is caused by a
+// canceled context or an exceeded context deadline.
+// A shared fetch fails with such an error when the
+// request that started it gets canceled.
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+func TestPolicyCache(t *testing.T) {
+	ctx := context.Background()
+	policies := &countingPolicySet{policies: map[string]*Policy{}}
+	cache := NewPolicyCache(policies, 1*time.Minute)
+
+	policy := &Policy{Allow: []string{"/v1/key/create/*"}}
+	if err := cache.Set(ctx, "my-policy", policy); err != nil {
+		t.Fatalf("Failed to set policy: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		p, err := cache.Get(ctx, "my-policy")
+		if err != nil {
+			t.Fatalf("Failed to get policy: %v", err)
+		}
+		if p != policy {
+			t.Fatalf("Invalid policy: got '%v' - want '%v'", p, policy)
+		}
+	}
+	if policies.gets != 0 {
+		t.Fatalf("Policy has been fetched from the policy set: got %d fetches - want 0", policies.gets)
+	}
+
+	// Policies that bypass the cache are visible once the cache is invalidated.
+	other := &Policy{Allow: []string{"/v1/key/decrypt/*"}}
+	policies.policies["my-policy"] = other
+	if p, _ := cache.Get(ctx, "my-policy"); p != policy {
+		t.Fatalf("Invalid policy: got '%v' - want cached '%v'", p, policy)
+	}
+	cache.Invalidate()
+	if p, _ := cache.Get(ctx, "my-policy"); p != other {
+		t.Fatalf("Invalid policy: got '%v' - want '%v'", p, other)
+	}
+
+	if err := cache.Delete(ctx, "my-policy"); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+	if _, err := cache.Get(ctx, "my-policy"); !errors.Is(err, kes.ErrPolicyNotFound) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kes.ErrPolicyNotFound)
+	}
+
+	// Policies are not cached if the expiry is negative.
+	policies.gets = 0
+	cache = NewPolicyCache(policies, -1)
+	cache.Set(ctx, "my-policy", policy)
+	cache.Get(ctx, "my-policy")
+	cache.Get(ctx, "my-policy")
+	if policies.gets != 2 {
+		t.Fatalf("Policy has been cached: got %d fetches - want 2", policies.gets)
+	}
+}
+
+func TestIdentityCache(t *testing.T) {
+	const (
+		Admin    kes.Identity = "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d"
+		Identity kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	)
+	ctx := context.Background()
+	identities := &countingIdentitySet{admin: Admin, roles: map[kes.Identity]IdentityInfo{}}
+	cache := NewIdentityCache(identities, 1*time.Minute)
+
+	for i := 0; i < 3; i++ {
+		admin, err := cache.Admin(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get admin: %v", err)
+		}
+		if admin != Admin {
+			t.Fatalf("Invalid admin: got '%v' - want '%v'", admin, Admin)
+		}
+	}
+	if identities.admins != 1 {
+		t.Fatalf("Admin has not been cached: got %d fetches - want 1", identities.admins)
+	}
+
+	if err := cache.Assign(ctx, "my-policy", Identity); err != nil {
+		t.Fatalf("Failed to assign identity: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		info, err := cache.Get(ctx, Identity)
+		if err != nil {
+			t.Fatalf("Failed to get identity: %v", err)
+		}
+		if info.Policy != "my-policy" {
+			t.Fatalf("Invalid policy: got '%s' - want '%s'", info.Policy, "my-policy")
+		}
+	}
+	if identities.gets != 1 {
+		t.Fatalf("Identity has not been cached: got %d fetches - want 1", identities.gets)
+	}
+
+	// Assignments through the cache take effect immediately.
+	if err := cache.Assign(ctx, "other-policy", Identity); err != nil {
+		t.Fatalf("Failed to assign identity: %v", err)
+	}
+	if info, _ := cache.Get(ctx, Identity); info.Policy != "other-policy" {
+		t.Fatalf("Invalid policy: got '%s' - want '%s'", info.Policy, "other-policy")
+	}
+	if err := cache.Delete(ctx, Identity); err != nil {
+		t.Fatalf("Failed to delete identity: %v", err)
+	}
+	if _, err := cache.Get(ctx, Identity); !errors.Is(err, kes.ErrIdentityNotFound) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kes.ErrIdentityNotFound)
+	}
+}
+
+type countingPolicySet struct {
+	policies map[string]*Policy
+	gets     int
+}
+
+func (p *countingPolicySet) Set(_ context.Context, name string, policy *Policy) error {
+	p.policies[name] = policy
+	return nil
+}
+
+func (p *countingPolicySet) Get(_ context.Context, name string) (*Policy, error) {
+	p.gets++
+	policy, ok := p.policies[name]
+	if !ok {
+		return nil, kes.ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (p *countingPolicySet) Delete(_ context.Context, name string) error {
+	delete(p.policies, name)
+	return nil
+}
+
+func (p *countingPolicySet) List(context.Context) (PolicyIterator, error) {
+	return nil, errors.New("not implemented")
+}
+
+type countingIdentitySet struct {
+	admin  kes.Identity
+	roles  map[kes.Identity]IdentityInfo
+	admins int
+	gets   int
+}
+
+func (i *countingIdentitySet) Admin(context.Context) (kes.Identity, error) {
+	i.admins++
+	return i.admin, nil
+}
+
+func (i *countingIdentitySet) Assign(_ context.Context, policy string, identity kes.Identity) error {
+	i.roles[identity] = IdentityInfo{Policy: policy}
+	return nil
+}
+
+func (i *countingIdentitySet) Get(_ context.Context, identity kes.Identity) (IdentityInfo, error) {
+	i.gets++
+	info, ok := i.roles[identity]
+	if !ok {
+		return IdentityInfo{}, kes.ErrIdentityNotFound
+	}
+	return info, nil
+}
+
+func (i *countingIdentitySet) Delete(_ context.Context, identity kes.Identity) error {
+	delete(i.roles, identity)
+	return nil
+}
+
+func (i *countingIdentitySet) List(context.Context) (IdentityIterator, error) {
+	return nil, errors.New("not implemented")
+}
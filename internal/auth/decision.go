@@ -0,0 +1,75 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"sync"
+)
+
+// A Decision describes why the built-in policy checks
+// allowed or denied a request.
+type Decision struct {
+	// Admin is true if the request has been sent by an
+	// admin identity. Admin requests are not checked
+	// against any policy.
+	Admin bool
+
+	// Policy is the name of the policy the request
+	// identity is assigned to.
+	Policy string
+
+	// Rule is the allow or deny pattern of the policy
+	// that matched the request path. It is empty if no
+	// pattern matched and the request has been denied
+	// implicitly.
+	Rule string
+
+	// Allowed reports whether the policy allowed the
+	// request.
+	Allowed bool
+}
+
+type decisionKey struct{}
+
+type decisionRecorder struct {
+	lock     sync.Mutex
+	decision Decision
+	ok       bool
+}
+
+// WithDecisionRecorder returns a copy of ctx that records
+// the policy decision of requests with such a context.
+// The decision can be retrieved via DecisionFromContext.
+func WithDecisionRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, decisionKey{}, &decisionRecorder{})
+}
+
+// RecordDecision records the policy decision if ctx
+// has been created by WithDecisionRecorder. Otherwise,
+// RecordDecision does nothing.
+func RecordDecision(ctx context.Context, decision Decision) {
+	recorder, ok := ctx.Value(decisionKey{}).(*decisionRecorder)
+	if !ok {
+		return
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	recorder.decision, recorder.ok = decision, true
+}
+
+// DecisionFromContext returns the most recent policy decision
+// recorded for ctx, if any.
+func DecisionFromContext(ctx context.Context) (Decision, bool) {
+	recorder, ok := ctx.Value(decisionKey{}).(*decisionRecorder)
+	if !ok {
+		return Decision{}, false
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	return recorder.decision, recorder.ok
+}
@@ -19,7 +19,8 @@ import (
 )
 
 // VerifyRequest verifies whether the request's identity is allowed to perform
-// the request based on the given policies.
+// the request based on the given policies. Requests that pass the policy check
+// are authorized by the request's Authorizer, if any.
 func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet) error {
 	if r.TLS == nil {
 		return kes.NewError(http.StatusBadRequest, "insecure connection: TLS required")
@@ -53,6 +54,7 @@ func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet)
 		return err
 	}
 	if identity == admin {
+		RecordDecision(r.Context(), Decision{Admin: true, Allowed: true})
 		return nil
 	}
 
@@ -70,7 +72,12 @@ func VerifyRequest(r *http.Request, policies PolicySet, identities IdentitySet)
 	if err != nil {
 		return err
 	}
-	return policy.Verify(r)
+	allowed, rule := policy.Match(r.URL.Path)
+	RecordDecision(r.Context(), Decision{Policy: info.Policy, Rule: rule, Allowed: allowed})
+	if !allowed {
+		return kes.ErrNotAllowed
+	}
+	return Authorize(r, identity, info.Policy)
 }
 
 // Identify computes the identity of the given HTTP request.
@@ -187,6 +194,18 @@ type IdentityInfo struct {
 	// CreatedBy is the identity that assigned this
 	// identity to its policy.
 	CreatedBy kes.Identity
+
+	// ExpiresAt is the point in time when the policy
+	// assignment lapses. Once expired, the identity is
+	// no longer assigned to its policy. The zero value
+	// means the assignment never expires.
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the policy assignment
+// has expired.
+func (i IdentityInfo) Expired() bool {
+	return !i.ExpiresAt.IsZero() && !time.Now().Before(i.ExpiresAt)
 }
 
 // MarshalBinary returns the IdentityInfo's binary representation.
@@ -196,6 +215,7 @@ func (i IdentityInfo) MarshalBinary() ([]byte, error) {
 		IsAdmin   bool
 		CreatedAt time.Time
 		CreatedBy kes.Identity
+		ExpiresAt time.Time
 	}
 
 	var buffer bytes.Buffer
@@ -212,6 +232,7 @@ func (i *IdentityInfo) UnmarshalBinary(b []byte) error {
 		IsAdmin   bool
 		CreatedAt time.Time
 		CreatedBy kes.Identity
+		ExpiresAt time.Time
 	}
 
 	var value GOB
@@ -222,5 +243,6 @@ func (i *IdentityInfo) UnmarshalBinary(b []byte) error {
 	i.IsAdmin = value.IsAdmin
 	i.CreatedAt = value.CreatedAt
 	i.CreatedBy = value.CreatedBy
+	i.ExpiresAt = value.ExpiresAt
 	return nil
 }
@@ -71,6 +71,39 @@ type PolicyIterator interface {
 	Close() error
 }
 
+// EnclaveAdminPolicy is the name of the built-in policy of
+// enclave admins. Identities assigned to it can manage keys,
+// secrets, policies and identities within their enclave
+// without having system admin privileges.
+//
+// The policy is reserved and cannot be created, modified
+// or deleted.
+const EnclaveAdminPolicy = "enclave-admin"
+
+// EnclaveAdmin returns the built-in policy of enclave admins.
+// It allows all enclave-scoped APIs but no system APIs, like
+// creating or deleting enclaves.
+func EnclaveAdmin() Policy {
+	return Policy{
+		Allow: []string{
+			"/v1/key/*/*",
+			"/v1/key/bulk/*/*",
+			"/v1/key/fpe/*/*",
+			"/v1/policy/*/*",
+			"/v1/identity/*/*",
+			"/v1/secret/*/*",
+			"/v1/random",
+			"/v1/tokenize/*",
+			"/v1/detokenize/*",
+		},
+	}
+}
+
+// DefaultMaxRandomBytes is the max. number of random bytes
+// an identity can request at once if its policy does not
+// specify a limit.
+const DefaultMaxRandomBytes = 1024
+
 // A Policy defines whether an HTTP request is allowed or
 // should be rejected.
 //
@@ -91,8 +124,65 @@ type Policy struct {
 
 	// CreatedBy is the identity that created the policy.
 	CreatedBy kes.Identity
+
+	// RetainUntil is the point in time until the policy
+	// cannot be modified or deleted. It is zero for policies
+	// that have been created without retention period.
+	RetainUntil time.Time
+
+	// Certificates are the certificate profiles, by name,
+	// of identities assigned to the policy.
+	Certificates map[string]CertificateProfile
+
+	// MaxRandomBytes is the max. number of random bytes
+	// identities assigned to the policy can request at
+	// once. If 0, DefaultMaxRandomBytes applies.
+	MaxRandomBytes int
+
+	// Tokens constrains the JWTs identities assigned
+	// to the policy can sign. If nil, tokens must not
+	// contain an audience or custom claims.
+	Tokens *TokenProfile
+
+	// MaxSSHTTL is the max. lifetime of SSH certificates
+	// identities assigned to the policy can obtain. If 0,
+	// the server's max. lifetime applies.
+	MaxSSHTTL time.Duration
+}
+
+// RandomBytesLimit returns the max. number of random bytes
+// identities assigned to the policy can request at once.
+func (p *Policy) RandomBytesLimit() int {
+	if p.MaxRandomBytes > 0 {
+		return p.MaxRandomBytes
+	}
+	return DefaultMaxRandomBytes
+}
+
+// TokenTTLLimit returns the max. lifetime of JWTs identities
+// assigned to the policy can sign. It is the smaller of the
+// policy's and the given server-wide limit.
+func (p *Policy) TokenTTLLimit(max time.Duration) time.Duration {
+	if p.Tokens != nil && p.Tokens.MaxTTL > 0 && p.Tokens.MaxTTL < max {
+		return p.Tokens.MaxTTL
+	}
+	return max
+}
+
+// SSHTTLLimit returns the max. lifetime of SSH certificates
+// identities assigned to the policy can obtain. It is the
+// smaller of the policy's and the given server-wide limit.
+func (p *Policy) SSHTTLLimit(max time.Duration) time.Duration {
+	if p.MaxSSHTTL > 0 && p.MaxSSHTTL < max {
+		return p.MaxSSHTTL
+	}
+	return max
 }
 
+// Immutable reports whether the policy is within its
+// retention period.
+func (p *Policy) Immutable() bool { return time.Now().Before(p.RetainUntil) }
+
 var (
 	_ encoding.BinaryMarshaler   = Policy{}
 	_ encoding.BinaryUnmarshaler = (*Policy)(nil)
@@ -101,10 +191,16 @@ var (
 // MarshalBinary returns the Policy's binary representation.
 func (p Policy) MarshalBinary() ([]byte, error) {
 	type GOB struct {
-		Allow     []string
-		Deny      []string
-		CreatedAt time.Time
-		CreatedBy kes.Identity
+		Allow       []string
+		Deny        []string
+		CreatedAt   time.Time
+		CreatedBy   kes.Identity
+		RetainUntil time.Time
+
+		Certificates   map[string]CertificateProfile
+		MaxRandomBytes int
+		Tokens         *TokenProfile
+		MaxSSHTTL      time.Duration
 	}
 
 	var buffer bytes.Buffer
@@ -117,10 +213,16 @@ func (p Policy) MarshalBinary() ([]byte, error) {
 // UnmarshalBinary unmarshals the Policy's binary representation.
 func (p *Policy) UnmarshalBinary(b []byte) error {
 	type GOB struct {
-		Allow     []string
-		Deny      []string
-		CreatedAt time.Time
-		CreatedBy kes.Identity
+		Allow       []string
+		Deny        []string
+		CreatedAt   time.Time
+		CreatedBy   kes.Identity
+		RetainUntil time.Time
+
+		Certificates   map[string]CertificateProfile
+		MaxRandomBytes int
+		Tokens         *TokenProfile
+		MaxSSHTTL      time.Duration
 	}
 
 	var value GOB
@@ -131,6 +233,11 @@ func (p *Policy) UnmarshalBinary(b []byte) error {
 	p.Deny = value.Deny
 	p.CreatedAt = value.CreatedAt
 	p.CreatedBy = value.CreatedBy
+	p.RetainUntil = value.RetainUntil
+	p.Certificates = value.Certificates
+	p.MaxRandomBytes = value.MaxRandomBytes
+	p.Tokens = value.Tokens
+	p.MaxSSHTTL = value.MaxSSHTTL
 	return nil
 }
 
@@ -142,15 +249,54 @@ func (p *Policy) UnmarshalBinary(b []byte) error {
 //
 // Otherwise, Verify returns ErrNotAllowed.
 func (p *Policy) Verify(r *http.Request) error {
+	if allowed, _ := p.Match(r.URL.Path); !allowed {
+		return kes.ErrNotAllowed
+	}
+	return nil
+}
+
+// Match reports whether the policy allows requests with
+// the given URL path, following the same rules as Verify.
+// It also returns the deny or allow pattern that decided,
+// or the empty string if no pattern matches the path.
+func (p *Policy) Match(urlPath string) (allowed bool, pattern string) {
 	for _, pattern := range p.Deny {
-		if ok, err := path.Match(pattern, r.URL.Path); ok && err == nil {
-			return kes.ErrNotAllowed
+		if ok, err := path.Match(pattern, urlPath); ok && err == nil {
+			return false, pattern
 		}
 	}
 	for _, pattern := range p.Allow {
-		if ok, err := path.Match(pattern, r.URL.Path); ok && err == nil {
-			return nil
+		if ok, err := path.Match(pattern, urlPath); ok && err == nil {
+			return true, pattern
+		}
+	}
+	return false, ""
+}
+
+// EqualPatterns reports whether a and b contain the same
+// path patterns in the same order. A nil and an empty
+// slice are equal.
+func EqualPatterns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IdentityRoles returns the name of the policy, by identity,
+// each identity is assigned to. The identities function
+// returns the identities assigned to a policy.
+func IdentityRoles[P any](policies map[string]P, identities func(P) []kes.Identity) map[kes.Identity]string {
+	roles := map[kes.Identity]string{}
+	for name, policy := range policies {
+		for _, identity := range identities(policy) {
+			roles[identity] = name
 		}
 	}
-	return kes.ErrNotAllowed
+	return roles
 }
@@ -0,0 +1,136 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestPolicyMatch(t *testing.T) {
+	for i, test := range policyMatchTests {
+		allowed, pattern := test.Policy.Match(test.Path)
+		if allowed != test.Allowed {
+			t.Fatalf("Test %d: got allowed '%v' - want '%v'", i, allowed, test.Allowed)
+		}
+		if pattern != test.Pattern {
+			t.Fatalf("Test %d: got pattern '%s' - want '%s'", i, pattern, test.Pattern)
+		}
+
+		err := test.Policy.Verify(httptest.NewRequest("GET", test.Path, nil))
+		if (err == nil) != test.Allowed {
+			t.Fatalf("Test %d: Verify and Match disagree: got error '%v' - want allowed '%v'", i, err, test.Allowed)
+		}
+	}
+}
+
+var policyMatchTests = []struct {
+	Policy  *Policy
+	Path    string
+	Allowed bool
+	Pattern string
+}{
+	{ // 0
+		Policy: &Policy{},
+		Path:   "/v1/key/create/my-key",
+	},
+	{ // 1
+		Policy:  &Policy{Allow: []string{"/v1/key/create/*"}},
+		Path:    "/v1/key/create/my-key",
+		Allowed: true,
+		Pattern: "/v1/key/create/*",
+	},
+	{ // 2
+		Policy:  &Policy{Allow: []string{"/v1/key/create/*"}, Deny: []string{"/v1/key/create/my-*"}},
+		Path:    "/v1/key/create/my-key",
+		Pattern: "/v1/key/create/my-*",
+	},
+	{ // 3
+		Policy:  &Policy{Allow: []string{"/v1/key/*/*"}, Deny: []string{"/v1/key/delete/*"}},
+		Path:    "/v1/key/decrypt/my-key",
+		Allowed: true,
+		Pattern: "/v1/key/*/*",
+	},
+	{ // 4
+		Policy: &Policy{Allow: []string{"/v1/key/*"}},
+		Path:   "/v1/key/create/my-key", // '*' does not match '/'
+	},
+	{ // 5
+		Policy: &Policy{Allow: []string{"/v1/key/[create/*"}}, // Malformed patterns never match
+		Path:   "/v1/key/create/my-key",
+	},
+}
+
+func TestEnclaveAdmin(t *testing.T) {
+	policy := EnclaveAdmin()
+	for _, path := range []string{
+		"/v1/key/create/my-key",
+		"/v1/key/list/",
+		"/v1/key/bulk/decrypt/my-key",
+		"/v1/policy/assign/my-policy",
+		"/v1/identity/self/describe",
+		"/v1/identity/delete/3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+		"/v1/secret/read/my-secret",
+		"/v1/key/hash/my-key",
+		"/v1/key/fpe/encrypt/my-key",
+		"/v1/random",
+		"/v1/tokenize/card-numbers",
+		"/v1/detokenize/card-numbers",
+	} {
+		if allowed, _ := policy.Match(path); !allowed {
+			t.Fatalf("Enclave admin is not allowed to access '%s'", path)
+		}
+	}
+	for _, path := range []string{
+		"/v1/enclave/create/tenant-1",
+		"/v1/enclave/delete/tenant-1",
+		"/v1/log/level/set/DEBUG",
+		"/v1/metrics",
+		"/v1/fsck",
+	} {
+		if allowed, _ := policy.Match(path); allowed {
+			t.Fatalf("Enclave admin is allowed to access '%s'", path)
+		}
+	}
+}
+
+var equalPatternsTests = []struct {
+	A, B  []string
+	Equal bool
+}{
+	{A: nil, B: []string{}, Equal: true},                                             // 0
+	{A: []string{"/v1/key/*"}, B: []string{"/v1/key/*"}, Equal: true},                // 1
+	{A: []string{"/v1/key/*", "/v1/status"}, B: []string{"/v1/status", "/v1/key/*"}}, // 2
+	{A: []string{"/v1/key/*"}, B: []string{"/v1/key/*", "/v1/status"}},               // 3
+}
+
+func TestEqualPatterns(t *testing.T) {
+	for i, test := range equalPatternsTests {
+		if equal := EqualPatterns(test.A, test.B); equal != test.Equal {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, equal, test.Equal)
+		}
+	}
+}
+
+func TestIdentityRoles(t *testing.T) {
+	type policy struct{ Identities []kes.Identity }
+	policies := map[string]policy{
+		"my-app":   {Identities: []kes.Identity{"3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"}},
+		"my-admin": {Identities: []kes.Identity{"5ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"}},
+		"unused":   {},
+	}
+	want := map[kes.Identity]string{
+		"3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22": "my-app",
+		"4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22": "my-app",
+		"5ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22": "my-admin",
+	}
+	roles := IdentityRoles(policies, func(p policy) []kes.Identity { return p.Identities })
+	if !reflect.DeepEqual(roles, want) {
+		t.Fatalf("Invalid roles: got '%v' - want '%v'", roles, want)
+	}
+}
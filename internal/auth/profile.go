@@ -0,0 +1,313 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Certificate usages of a CertificateProfile.
+const (
+	UsageServer = "server"
+	UsageClient = "client"
+)
+
+// A CertificateProfile constrains the X.509 certificates that
+// identities assigned to a policy can obtain from an enclave
+// key acting as certificate authority.
+type CertificateProfile struct {
+	// Key is the name of the enclave key that
+	// signs certificates issued for the profile.
+	Key string
+
+	// DNSNames is a list of DNS name patterns that each
+	// requested DNS name must match. A pattern is either
+	// a DNS name or a DNS name whose leftmost label is
+	// '*', like '*.example.com'. The '*' matches exactly
+	// one label, e.g. 'kes.example.com' but neither
+	// 'example.com' nor 'kes.eu.example.com'.
+	DNSNames []string
+
+	// IPRanges is a list of CIDR ranges that each
+	// requested IP address must be part of.
+	IPRanges []string
+
+	// Usage contains the extended key usages of issued
+	// certificates, UsageServer and/or UsageClient. If
+	// empty, certificates are server certificates.
+	Usage []string
+
+	// MaxTTL is the max. lifetime of issued certificates.
+	// If zero, the server's default applies.
+	MaxTTL time.Duration
+}
+
+// Validate returns an error if the profile contains
+// a malformed DNS name pattern, IP range, or usage.
+func (p *CertificateProfile) Validate() error {
+	if p.Key == "" {
+		return errors.New("no CA key specified")
+	}
+	for _, pattern := range p.DNSNames {
+		if !validDNSPattern(pattern) {
+			return fmt.Errorf("invalid DNS name pattern '%s'", pattern)
+		}
+	}
+	for _, ipRange := range p.IPRanges {
+		if _, _, err := net.ParseCIDR(ipRange); err != nil {
+			return fmt.Errorf("invalid IP range '%s'", ipRange)
+		}
+	}
+	for _, usage := range p.Usage {
+		if usage != UsageServer && usage != UsageClient {
+			return fmt.Errorf("invalid usage '%s': must be '%s' or '%s'", usage, UsageServer, UsageClient)
+		}
+	}
+	if p.MaxTTL < 0 {
+		return errors.New("invalid max. TTL: must not be negative")
+	}
+	return nil
+}
+
+// AllowsDNSName reports whether the profile allows
+// certificates for the given DNS name. It never allows
+// wildcard DNS names, like '*.example.com'.
+func (p *CertificateProfile) AllowsDNSName(name string) bool {
+	if name == "" || strings.Contains(name, "*") {
+		return false
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for _, pattern := range p.DNSNames {
+		if matchDNSName(strings.Split(strings.ToLower(pattern), "."), labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// validDNSPattern reports whether the pattern is a DNS
+// name whose leftmost label may be '*'. The pattern
+// must not consist of the '*' label only.
+func validDNSPattern(pattern string) bool {
+	labels := strings.Split(pattern, ".")
+	if len(labels) < 2 && labels[0] == "*" {
+		return false
+	}
+	for i, label := range labels {
+		if label == "" {
+			return false
+		}
+		if i == 0 && label == "*" {
+			continue
+		}
+		if strings.ContainsAny(label, "*?[]\\/") {
+			return false
+		}
+	}
+	return true
+}
+
+// matchDNSName reports whether the DNS name labels match
+// the pattern labels. A leftmost '*' pattern label matches
+// exactly one label.
+func matchDNSName(pattern, labels []string) bool {
+	if len(pattern) != len(labels) {
+		return false
+	}
+	for i := range pattern {
+		if i == 0 && pattern[i] == "*" {
+			if labels[i] == "" {
+				return false
+			}
+			continue
+		}
+		if pattern[i] != labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsIP reports whether the profile allows
+// certificates for the given IP address.
+func (p *CertificateProfile) AllowsIP(ip net.IP) bool {
+	for _, ipRange := range p.IPRanges {
+		if _, network, err := net.ParseCIDR(ipRange); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON returns the profile's JSON representation.
+// The MaxTTL is encoded as duration string, like "24h".
+func (p CertificateProfile) MarshalJSON() ([]byte, error) {
+	type JSON struct {
+		Key      string   `json:"key"`
+		DNSNames []string `json:"dns_names,omitempty"`
+		IPRanges []string `json:"ip_ranges,omitempty"`
+		Usage    []string `json:"usage,omitempty"`
+		MaxTTL   string   `json:"max_ttl,omitempty"`
+	}
+	var maxTTL string
+	if p.MaxTTL != 0 {
+		maxTTL = p.MaxTTL.String()
+	}
+	return json.Marshal(JSON{
+		Key:      p.Key,
+		DNSNames: p.DNSNames,
+		IPRanges: p.IPRanges,
+		Usage:    p.Usage,
+		MaxTTL:   maxTTL,
+	})
+}
+
+// UnmarshalJSON parses the profile's JSON representation.
+func (p *CertificateProfile) UnmarshalJSON(b []byte) error {
+	type JSON struct {
+		Key      string   `json:"key"`
+		DNSNames []string `json:"dns_names"`
+		IPRanges []string `json:"ip_ranges"`
+		Usage    []string `json:"usage"`
+		MaxTTL   string   `json:"max_ttl"`
+	}
+	var value JSON
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	var maxTTL time.Duration
+	if value.MaxTTL != "" {
+		var err error
+		if maxTTL, err = time.ParseDuration(value.MaxTTL); err != nil {
+			return fmt.Errorf("invalid max. TTL '%s'", value.MaxTTL)
+		}
+	}
+	p.Key = value.Key
+	p.DNSNames = value.DNSNames
+	p.IPRanges = value.IPRanges
+	p.Usage = value.Usage
+	p.MaxTTL = maxTTL
+	return nil
+}
+
+// registeredClaims are the JWT claims that are set by
+// the server and cannot be allowed by a TokenProfile.
+var registeredClaims = []string{"iss", "sub", "aud", "iat", "nbf", "exp", "jti"}
+
+// A TokenProfile constrains the JWTs that identities
+// assigned to a policy can sign with an enclave key
+// designated as token signing key.
+type TokenProfile struct {
+	// Audiences is the list of 'aud' claim values that
+	// identities can request. If empty, tokens must not
+	// contain an audience.
+	Audiences []string
+
+	// Claims is the list of custom claim names that
+	// identities can set. Registered claims, like 'sub'
+	// or 'aud', cannot be listed.
+	Claims []string
+
+	// MaxTTL is the max. lifetime of signed tokens.
+	// If zero, the server's max. lifetime applies.
+	MaxTTL time.Duration
+}
+
+// Validate returns an error if the profile contains an
+// empty audience, an empty or registered claim name, or
+// a negative max. TTL.
+func (p *TokenProfile) Validate() error {
+	for _, audience := range p.Audiences {
+		if audience == "" {
+			return errors.New("empty audience")
+		}
+	}
+	for _, claim := range p.Claims {
+		if claim == "" {
+			return errors.New("empty claim name")
+		}
+		for _, registered := range registeredClaims {
+			if claim == registered {
+				return fmt.Errorf("invalid claim '%s': registered claims cannot be allowed", claim)
+			}
+		}
+	}
+	if p.MaxTTL < 0 {
+		return errors.New("invalid max. TTL: must not be negative")
+	}
+	return nil
+}
+
+// AllowsAudience reports whether the profile allows
+// tokens for the given audience.
+func (p *TokenProfile) AllowsAudience(audience string) bool {
+	for _, a := range p.Audiences {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsClaim reports whether the profile allows
+// tokens with the given custom claim.
+func (p *TokenProfile) AllowsClaim(name string) bool {
+	for _, claim := range p.Claims {
+		if claim == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON returns the profile's JSON representation.
+// The MaxTTL is encoded as duration string, like "1h".
+func (p TokenProfile) MarshalJSON() ([]byte, error) {
+	type JSON struct {
+		Audiences []string `json:"audiences,omitempty"`
+		Claims    []string `json:"claims,omitempty"`
+		MaxTTL    string   `json:"max_ttl,omitempty"`
+	}
+	var maxTTL string
+	if p.MaxTTL != 0 {
+		maxTTL = p.MaxTTL.String()
+	}
+	return json.Marshal(JSON{
+		Audiences: p.Audiences,
+		Claims:    p.Claims,
+		MaxTTL:    maxTTL,
+	})
+}
+
+// UnmarshalJSON parses the profile's JSON representation.
+func (p *TokenProfile) UnmarshalJSON(b []byte) error {
+	type JSON struct {
+		Audiences []string `json:"audiences"`
+		Claims    []string `json:"claims"`
+		MaxTTL    string   `json:"max_ttl"`
+	}
+	var value JSON
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	var maxTTL time.Duration
+	if value.MaxTTL != "" {
+		var err error
+		if maxTTL, err = time.ParseDuration(value.MaxTTL); err != nil {
+			return fmt.Errorf("invalid max. TTL '%s'", value.MaxTTL)
+		}
+	}
+	p.Audiences = value.Audiences
+	p.Claims = value.Claims
+	p.MaxTTL = maxTTL
+	return nil
+}
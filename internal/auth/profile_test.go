@@ -0,0 +1,205 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCertificateProfileValidate(t *testing.T) {
+	for i, test := range certificateProfileValidateTests {
+		err := test.Profile.Validate()
+		if (err != nil) != test.ShouldFail {
+			t.Fatalf("Test %d: got error '%v' - want failure '%v'", i, err, test.ShouldFail)
+		}
+	}
+}
+
+var certificateProfileValidateTests = []struct {
+	Profile    CertificateProfile
+	ShouldFail bool
+}{
+	{Profile: CertificateProfile{Key: "my-ca"}},                                                      // 0
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{"*.svc.cluster.local"}}},           // 1
+	{Profile: CertificateProfile{Key: "my-ca", IPRanges: []string{"10.0.0.0/8", "fd00::/8"}}},        // 2
+	{Profile: CertificateProfile{Key: "my-ca", Usage: []string{UsageServer, UsageClient}}},           // 3
+	{Profile: CertificateProfile{}, ShouldFail: true},                                                // 4
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{"[a-"}}, ShouldFail: true},         // 5
+	{Profile: CertificateProfile{Key: "my-ca", IPRanges: []string{"10.0.0.1"}}, ShouldFail: true},    // 6
+	{Profile: CertificateProfile{Key: "my-ca", Usage: []string{"code-signing"}}, ShouldFail: true},   // 7
+	{Profile: CertificateProfile{Key: "my-ca", MaxTTL: -1 * time.Hour}, ShouldFail: true},            // 8
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{""}}, ShouldFail: true},            // 9
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{"*"}}, ShouldFail: true},           // 10
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{"kes.*.local"}}, ShouldFail: true}, // 11
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{"kes-*.local"}}, ShouldFail: true}, // 12
+	{Profile: CertificateProfile{Key: "my-ca", DNSNames: []string{"*..local"}}, ShouldFail: true},    // 13
+}
+
+func TestCertificateProfileAllows(t *testing.T) {
+	profile := CertificateProfile{
+		Key:      "my-ca",
+		DNSNames: []string{"*.svc.cluster.local", "localhost"},
+		IPRanges: []string{"10.0.0.0/8"},
+	}
+	for _, name := range []string{"kes.svc.cluster.local", "KES.svc.cluster.local", "LOCALHOST"} {
+		if !profile.AllowsDNSName(name) {
+			t.Fatalf("Profile does not allow DNS name '%s'", name)
+		}
+	}
+	for _, name := range []string{
+		"svc.cluster.local",
+		"example.com",
+		"localhost.example.com",
+		"kes.default.svc.cluster.local", // '*' matches exactly one label
+		"*.svc.cluster.local",           // No wildcard certificates
+		"*",
+		"",
+	} {
+		if profile.AllowsDNSName(name) {
+			t.Fatalf("Profile allows DNS name '%s'", name)
+		}
+	}
+	if !profile.AllowsIP(net.ParseIP("10.1.2.3")) {
+		t.Fatal("Profile does not allow IP '10.1.2.3'")
+	}
+	if profile.AllowsIP(net.ParseIP("192.168.0.1")) {
+		t.Fatal("Profile allows IP '192.168.0.1'")
+	}
+}
+
+func TestCertificateProfileJSON(t *testing.T) {
+	const Text = `{"key":"my-ca","dns_names":["*.example.com"],"usage":["server","client"],"max_ttl":"72h0m0s"}`
+
+	var profile CertificateProfile
+	if err := json.Unmarshal([]byte(Text), &profile); err != nil {
+		t.Fatalf("Failed to decode profile: %v", err)
+	}
+	if profile.MaxTTL != 72*time.Hour {
+		t.Fatalf("Invalid max. TTL: got '%v' - want '%v'", profile.MaxTTL, 72*time.Hour)
+	}
+	text, err := json.Marshal(profile)
+	if err != nil {
+		t.Fatalf("Failed to encode profile: %v", err)
+	}
+	if string(text) != Text {
+		t.Fatalf("Invalid profile encoding: got '%s' - want '%s'", text, Text)
+	}
+
+	policy := Policy{Certificates: map[string]CertificateProfile{"web": profile}}
+	binary, err := policy.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode policy: %v", err)
+	}
+	var decoded Policy
+	if err = decoded.UnmarshalBinary(binary); err != nil {
+		t.Fatalf("Failed to decode policy: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Certificates, policy.Certificates) {
+		t.Fatalf("Decoded policy profiles differ: got '%v' - want '%v'", decoded.Certificates, policy.Certificates)
+	}
+
+	if err = json.Unmarshal([]byte(`{"key":"my-ca","max_ttl":"1 day"}`), &profile); err == nil {
+		t.Fatal("Decoded profile with invalid max. TTL")
+	}
+}
+
+func TestTokenProfileValidate(t *testing.T) {
+	for i, test := range tokenProfileValidateTests {
+		err := test.Profile.Validate()
+		if (err != nil) != test.ShouldFail {
+			t.Fatalf("Test %d: got error '%v' - want failure '%v'", i, err, test.ShouldFail)
+		}
+	}
+}
+
+var tokenProfileValidateTests = []struct {
+	Profile    TokenProfile
+	ShouldFail bool
+}{
+	{Profile: TokenProfile{}}, // 0
+	{Profile: TokenProfile{Audiences: []string{"billing"}, Claims: []string{"tenant"}, MaxTTL: time.Hour}}, // 1
+	{Profile: TokenProfile{Audiences: []string{""}}, ShouldFail: true},                                     // 2
+	{Profile: TokenProfile{Claims: []string{""}}, ShouldFail: true},                                        // 3
+	{Profile: TokenProfile{Claims: []string{"sub"}}, ShouldFail: true},                                     // 4
+	{Profile: TokenProfile{Claims: []string{"aud"}}, ShouldFail: true},                                     // 5
+	{Profile: TokenProfile{MaxTTL: -1 * time.Hour}, ShouldFail: true},                                      // 6
+}
+
+func TestPolicyTokenTTLLimit(t *testing.T) {
+	for i, test := range tokenTTLLimitTests {
+		if limit := test.Policy.TokenTTLLimit(test.Max); limit != test.Limit {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, limit, test.Limit)
+		}
+	}
+}
+
+var tokenTTLLimitTests = []struct {
+	Policy Policy
+	Max    time.Duration
+	Limit  time.Duration
+}{
+	{Policy: Policy{}, Max: time.Hour, Limit: time.Hour},                                                     // 0
+	{Policy: Policy{Tokens: &TokenProfile{}}, Max: time.Hour, Limit: time.Hour},                              // 1
+	{Policy: Policy{Tokens: &TokenProfile{MaxTTL: 5 * time.Minute}}, Max: time.Hour, Limit: 5 * time.Minute}, // 2
+	{Policy: Policy{Tokens: &TokenProfile{MaxTTL: 24 * time.Hour}}, Max: time.Hour, Limit: time.Hour},        // 3
+}
+
+func TestPolicySSHTTLLimit(t *testing.T) {
+	for i, test := range sshTTLLimitTests {
+		if limit := test.Policy.SSHTTLLimit(test.Max); limit != test.Limit {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, limit, test.Limit)
+		}
+	}
+}
+
+var sshTTLLimitTests = []struct {
+	Policy Policy
+	Max    time.Duration
+	Limit  time.Duration
+}{
+	{Policy: Policy{}, Max: 24 * time.Hour, Limit: 24 * time.Hour},                          // 0
+	{Policy: Policy{MaxSSHTTL: 8 * time.Hour}, Max: 24 * time.Hour, Limit: 8 * time.Hour},   // 1
+	{Policy: Policy{MaxSSHTTL: 48 * time.Hour}, Max: 24 * time.Hour, Limit: 24 * time.Hour}, // 2
+	{Policy: Policy{MaxSSHTTL: 24 * time.Hour}, Max: 24 * time.Hour, Limit: 24 * time.Hour}, // 3
+}
+
+func TestTokenProfileJSON(t *testing.T) {
+	const Text = `{"audiences":["billing"],"claims":["tenant"],"max_ttl":"5m0s"}`
+
+	var profile TokenProfile
+	if err := json.Unmarshal([]byte(Text), &profile); err != nil {
+		t.Fatalf("Failed to decode profile: %v", err)
+	}
+	if !profile.AllowsAudience("billing") || profile.AllowsAudience("payments") {
+		t.Fatalf("Invalid audiences: got '%v' - want '%v'", profile.Audiences, []string{"billing"})
+	}
+	if !profile.AllowsClaim("tenant") || profile.AllowsClaim("role") {
+		t.Fatalf("Invalid claims: got '%v' - want '%v'", profile.Claims, []string{"tenant"})
+	}
+	text, err := json.Marshal(profile)
+	if err != nil {
+		t.Fatalf("Failed to encode profile: %v", err)
+	}
+	if string(text) != Text {
+		t.Fatalf("Invalid profile encoding: got '%s' - want '%s'", text, Text)
+	}
+
+	policy := Policy{Tokens: &profile}
+	binary, err := policy.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode policy: %v", err)
+	}
+	var decoded Policy
+	if err = decoded.UnmarshalBinary(binary); err != nil {
+		t.Fatalf("Failed to decode policy: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Tokens, policy.Tokens) {
+		t.Fatalf("Decoded token profile differs: got '%v' - want '%v'", decoded.Tokens, policy.Tokens)
+	}
+}
@@ -109,6 +109,13 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 		return kes.NewError(http.StatusBadRequest, "insecure connection: TLS required")
 	}
 
+	// The TLS connection state is shared by all requests sent
+	// over the same connection. Hence, we must not modify it.
+	// Otherwise, subsequent requests of the proxy would carry
+	// the certificate of a previous client.
+	state := *req.TLS
+	req.TLS = &state
+
 	// A TLS proxy may send none, one or multiple peer certificates
 	// as part of the TLS handshake. However, we expect exactly
 	// one client certificate to check whether it is an authentic
@@ -5,6 +5,9 @@
 package auth
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"net/http"
 	"net/url"
 	"testing"
@@ -106,6 +109,35 @@ func TestTLSProxyGetClientCertificate(t *testing.T) {
 	}
 }
 
+func TestTLSProxyVerify(t *testing.T) {
+	block, _ := pem.Decode([]byte(clientCert))
+	proxyCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	proxyCert.IsCA = false
+
+	// Requests sent over the same connection share the TLS connection state.
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{proxyCert}}
+
+	proxy := &TLSProxy{CertHeader: "X-Tls-Client-Cert"}
+	proxy.Add(Identify(&http.Request{TLS: state}))
+
+	req := &http.Request{
+		Header: http.Header{"X-Tls-Client-Cert": []string{url.QueryEscape(clientCert)}},
+		TLS:    state,
+	}
+	if err = proxy.Verify(req); err != nil {
+		t.Fatalf("Failed to verify request: %v", err)
+	}
+	if req.TLS.PeerCertificates[0] == proxyCert {
+		t.Fatal("Verify did not replace the proxy certificate with the client certificate")
+	}
+	if len(state.PeerCertificates) != 1 || state.PeerCertificates[0] != proxyCert {
+		t.Fatal("Verify modified the TLS connection state shared by all requests of the connection")
+	}
+}
+
 const clientCert = `-----BEGIN CERTIFICATE-----
 MIIBETCBxKADAgECAhEAwNfpyTO85V8w7ecjWU8CdDAFBgMrZXAwDzENMAsGA1UE
 AxMEcm9vdDAeFw0xOTEyMTYyMjQ2NDdaFw0yMDAxMTUyMjQ2NDdaMA8xDTALBgNV
@@ -44,6 +44,13 @@ type InitConfig struct {
 		} `yaml:"client"`
 	} `yaml:"tls"`
 
+	Cluster struct {
+		ID     yml.String   `yaml:"id"`
+		Leader yml.String   `yaml:"leader"`
+		CA     yml.String   `yaml:"ca"`
+		Nodes  []yml.String `yaml:"nodes"`
+	} `yaml:"cluster"`
+
 	Unseal struct {
 		Environment struct {
 			Name string `yaml:"name"`
@@ -55,6 +62,12 @@ type InitConfig struct {
 			Identity yml.Identity `yaml:"identity"`
 		} `yaml:"admin"`
 
+		KeyPolicy struct {
+			DefaultAlgorithm string   `yaml:"default_algorithm"`
+			Algorithms       []string `yaml:"algorithms"`
+			MinKeySize       int      `yaml:"min_key_size"`
+		} `yaml:"key_policy"`
+
 		Policy map[string]struct {
 			Allow    []string       `yaml:"allow"`
 			Deny     []string       `yaml:"deny"`
@@ -0,0 +1,239 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package dbrotate rotates the passwords of PostgreSQL
+// and MySQL database users.
+//
+// A database user changes its own password. Hence, the
+// rotation does not require any admin credentials. It
+// only requires the current password of the user.
+package dbrotate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Supported database URL schemes.
+const (
+	SchemePostgres   = "postgres"
+	SchemePostgreSQL = "postgresql"
+	SchemeMySQL      = "mysql"
+)
+
+// PasswordLength is the length of generated passwords.
+const PasswordLength = 32
+
+// dialTimeout is the max. time to establish a database
+// connection.
+const dialTimeout = 10 * time.Second
+
+// IsDatabase reports whether the endpoint refers to a
+// supported database, i.e. whether its URL scheme is
+// one of the supported database URL schemes.
+func IsDatabase(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case SchemePostgres, SchemePostgreSQL, SchemeMySQL:
+		return true
+	default:
+		return false
+	}
+}
+
+// Verify returns an error if the endpoint is not a valid
+// database URL. A database URL specifies the database
+// user but must not contain its password. For example:
+//
+//	postgres://app@db.example.com:5432/app?sslmode=verify-full
+//	mysql://app@db.example.com:3306/app
+func Verify(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("dbrotate: invalid database URL: %v", err)
+	}
+	if !IsDatabase(endpoint) {
+		return fmt.Errorf("dbrotate: unsupported database '%s'", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("dbrotate: invalid database URL: no host specified")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return errors.New("dbrotate: invalid database URL: no user specified")
+	}
+	if _, ok := u.User.Password(); ok {
+		return errors.New("dbrotate: invalid database URL: must not contain a password")
+	}
+	if u.Scheme == SchemeMySQL {
+		if _, err = mysqlConfig(u, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rotate connects to the database at the endpoint as the
+// endpoint's user with the given password and changes the
+// user's password to a new random password. Then it calls
+// commit with the new password, e.g. to store it.
+//
+// If commit fails, Rotate reverts the password change such
+// that the given password remains valid.
+func Rotate(ctx context.Context, endpoint string, password []byte, commit func(password []byte) error) error {
+	if err := Verify(endpoint); err != nil {
+		return err
+	}
+	u, _ := url.Parse(endpoint)
+
+	var db *sql.DB
+	switch u.Scheme {
+	case SchemeMySQL:
+		config, err := mysqlConfig(u, string(password))
+		if err != nil {
+			return err
+		}
+		connector, err := mysql.NewConnector(config)
+		if err != nil {
+			return err
+		}
+		db = sql.OpenDB(connector)
+	default:
+		u.User = url.UserPassword(u.User.Username(), string(password))
+		query := u.Query()
+		if query.Get("connect_timeout") == "" {
+			query.Set("connect_timeout", fmt.Sprint(int(dialTimeout.Seconds())))
+		}
+		u.RawQuery = query.Encode()
+
+		connector, err := pq.NewConnector(u.String())
+		if err != nil {
+			return err
+		}
+		db = sql.OpenDB(connector)
+	}
+	defer db.Close()
+
+	newPassword, err := randomPassword()
+	if err != nil {
+		return err
+	}
+	if u.Scheme == SchemeMySQL {
+		return rotateMySQL(ctx, db, string(password), newPassword, commit)
+	}
+	return rotatePostgres(ctx, db, newPassword, commit)
+}
+
+// rotatePostgres changes the password of the current user.
+//
+// ALTER ROLE is transactional in PostgreSQL. Hence, the
+// password change is only committed once the new password
+// has been committed.
+func rotatePostgres(ctx context.Context, db *sql.DB, newPassword string, commit func([]byte) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "ALTER ROLE CURRENT_USER WITH PASSWORD "+pq.QuoteLiteral(newPassword)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = commit([]byte(newPassword)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("dbrotate: failed to commit password change: %v", err)
+	}
+	return nil
+}
+
+// rotateMySQL changes the password of the current user.
+//
+// MySQL commits ALTER USER implicitly. Hence, the password
+// change gets reverted if the new password cannot be committed.
+// The revert uses the same connection since the new password
+// has not been committed.
+func rotateMySQL(ctx context.Context, db *sql.DB, password, newPassword string, commit func([]byte) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	const AlterUser = "ALTER USER CURRENT_USER() IDENTIFIED BY ?"
+	if _, err = conn.ExecContext(ctx, AlterUser, newPassword); err != nil {
+		return err
+	}
+	if err = commit([]byte(newPassword)); err != nil {
+		if _, rerr := conn.ExecContext(ctx, AlterUser, password); rerr != nil {
+			return fmt.Errorf("dbrotate: failed to revert password change: %v: %v", rerr, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// mysqlConfig returns the MySQL driver configuration for
+// the database URL and password. Connections use TLS unless
+// the URL explicitly specifies another 'tls' mode.
+func mysqlConfig(u *url.URL, password string) (*mysql.Config, error) {
+	config := mysql.NewConfig()
+	config.User = u.User.Username()
+	config.Passwd = password
+	config.Net = "tcp"
+	config.Addr = u.Host
+	if u.Port() == "" {
+		config.Addr = net.JoinHostPort(u.Hostname(), "3306")
+	}
+	config.DBName = strings.TrimPrefix(u.Path, "/")
+	config.Timeout = dialTimeout
+	config.InterpolateParams = true // Required for ALTER USER with parameters
+
+	query := u.Query()
+	switch tls := query.Get("tls"); tls {
+	case "":
+		config.TLSConfig = "true"
+	case "true", "false", "skip-verify", "preferred":
+		config.TLSConfig = tls
+	default:
+		return nil, fmt.Errorf("dbrotate: invalid database URL: invalid tls mode '%s'", tls)
+	}
+	for name := range query {
+		if name != "tls" {
+			return nil, fmt.Errorf("dbrotate: invalid database URL: unsupported parameter '%s'", name)
+		}
+	}
+	return config, nil
+}
+
+// randomPassword returns a new random alphanumeric password
+// of length PasswordLength.
+func randomPassword() (string, error) {
+	const Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	max := big.NewInt(int64(len(Alphabet)))
+	var password strings.Builder
+	for i := 0; i < PasswordLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password.WriteByte(Alphabet[n.Int64()])
+	}
+	return password.String(), nil
+}
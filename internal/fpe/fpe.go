@@ -0,0 +1,397 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package fpe implements the FF1 and FF3-1 format-preserving
+// encryption modes specified in NIST SP 800-38G Rev. 1.
+//
+// A format-preserving encryption maps a string over an alphabet
+// to a string of the same length over the same alphabet. For
+// example, it encrypts a 16 digit credit card number to another
+// 16 digit number.
+package fpe
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+// Mode is a format-preserving encryption mode.
+type Mode string
+
+// Supported format-preserving encryption modes.
+const (
+	FF1  Mode = "FF1"
+	FF31 Mode = "FF3-1"
+)
+
+// Digits is the alphabet of decimal digits.
+const Digits = "0123456789"
+
+// MaxFF1Length is the max. length of strings encrypted with FF1.
+const MaxFF1Length = 256
+
+// FF31TweakSize is the size of FF3-1 tweaks in bytes.
+const FF31TweakSize = 7
+
+// MaxFF1TweakSize is the max. size of FF1 tweaks in bytes.
+const MaxFF1TweakSize = 256
+
+// minDomainSize is the min. number of values a string
+// over the alphabet can represent: radix^minlen >= 1000000.
+const minDomainSize = 1000000
+
+// Alphabet is a set of distinct characters, in the
+// order of their numeral value.
+type Alphabet struct {
+	runes []rune
+	index map[rune]int
+}
+
+// NewAlphabet returns a new Alphabet consisting of the
+// characters of s. It returns an error if s contains
+// less than 2, more than 65536 or duplicate characters.
+func NewAlphabet(s string) (*Alphabet, error) {
+	if !utf8.ValidString(s) {
+		return nil, errors.New("fpe: alphabet is not valid UTF-8")
+	}
+	a := &Alphabet{
+		runes: []rune(s),
+		index: make(map[rune]int, len(s)),
+	}
+	if len(a.runes) < 2 || len(a.runes) > 1<<16 {
+		return nil, errors.New("fpe: alphabet must contain between 2 and 65536 characters")
+	}
+	for i, r := range a.runes {
+		if _, ok := a.index[r]; ok {
+			return nil, fmt.Errorf("fpe: alphabet contains '%c' more than once", r)
+		}
+		a.index[r] = i
+	}
+	return a, nil
+}
+
+// Radix returns the number of characters of the Alphabet.
+func (a *Alphabet) Radix() int { return len(a.runes) }
+
+// Encrypt encrypts the plaintext over the alphabet with the
+// given mode, AES key and tweak. The ciphertext has the same
+// length as the plaintext and consists of alphabet characters.
+//
+// For FF3-1, the tweak must be empty or 7 bytes long. An
+// empty tweak is equivalent to a tweak of 7 zero bytes.
+func Encrypt(mode Mode, key []byte, alphabet *Alphabet, tweak []byte, plaintext string) (string, error) {
+	return crypt(mode, key, alphabet, tweak, plaintext, false)
+}
+
+// Decrypt decrypts the ciphertext over the alphabet with the
+// given mode, AES key and tweak. It is the inverse of Encrypt.
+func Decrypt(mode Mode, key []byte, alphabet *Alphabet, tweak []byte, ciphertext string) (string, error) {
+	return crypt(mode, key, alphabet, tweak, ciphertext, true)
+}
+
+func crypt(mode Mode, key []byte, alphabet *Alphabet, tweak []byte, text string, decrypt bool) (string, error) {
+	x := make([]int, 0, len(text))
+	for _, r := range text {
+		n, ok := alphabet.index[r]
+		if !ok {
+			return "", fmt.Errorf("fpe: '%c' is not part of the alphabet", r)
+		}
+		x = append(x, n)
+	}
+
+	radix := alphabet.Radix()
+	if minLen := minLength(radix); len(x) < minLen {
+		return "", fmt.Errorf("fpe: text must be at least %d characters long", minLen)
+	}
+
+	var (
+		y   []int
+		err error
+	)
+	switch mode {
+	case FF1:
+		if len(x) > MaxFF1Length {
+			return "", fmt.Errorf("fpe: text must be at most %d characters long", MaxFF1Length)
+		}
+		if len(tweak) > MaxFF1TweakSize {
+			return "", fmt.Errorf("fpe: tweak must be at most %d bytes long", MaxFF1TweakSize)
+		}
+		y, err = ff1(key, radix, tweak, x, decrypt)
+	case FF31:
+		if maxLen := maxFF31Length(radix); len(x) > maxLen {
+			return "", fmt.Errorf("fpe: text must be at most %d characters long", maxLen)
+		}
+		switch len(tweak) {
+		case 0:
+			tweak = make([]byte, FF31TweakSize)
+		case FF31TweakSize:
+		default:
+			return "", fmt.Errorf("fpe: tweak must be %d bytes long", FF31TweakSize)
+		}
+		y, err = ff3(key, radix, ff31Tweak(tweak), x, decrypt)
+	default:
+		return "", fmt.Errorf("fpe: unsupported mode '%s'", mode)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	out := make([]rune, 0, len(y))
+	for _, n := range y {
+		out = append(out, alphabet.runes[n])
+	}
+	return string(out), nil
+}
+
+// ff1 implements the FF1 encryption (Algorithm 7) and
+// decryption (Algorithm 8) of the numeral string x.
+func ff1(key []byte, radix int, tweak []byte, x []int, decrypt bool) ([]int, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		n = len(x)
+		t = len(tweak)
+		u = n / 2
+		v = n - u
+		A = append([]int(nil), x[:u]...)
+		B = append([]int(nil), x[u:]...)
+	)
+	b := (bitLen(radix, v) + 7) / 8
+	d := 4*((b+3)/4) + 4
+
+	var P [16]byte
+	P[0], P[1], P[2] = 1, 2, 1
+	P[3], P[4], P[5] = byte(radix>>16), byte(radix>>8), byte(radix)
+	P[6], P[7] = 10, byte(u)
+	binary.BigEndian.PutUint32(P[8:], uint32(n))
+	binary.BigEndian.PutUint32(P[12:], uint32(t))
+
+	pad := (16 - (t+b+1)%16) % 16
+	Q := make([]byte, t+pad+1+b)
+	copy(Q, tweak)
+
+	var (
+		modU = pow(radix, u)
+		modV = pow(radix, v)
+		y    = new(big.Int)
+		c    = new(big.Int)
+		R    = make([]byte, 16)
+		S    = make([]byte, ((d+15)/16)*16)
+		tmp  = make([]byte, 16)
+	)
+	for j := 0; j < 10; j++ {
+		i := j
+		if decrypt {
+			i = 9 - j
+		}
+		m, mod := u, modU
+		if i%2 == 1 {
+			m, mod = v, modV
+		}
+
+		// Q = T || [0]^pad || [i]^1 || [NUM_radix(B)]^b
+		// where B is A during decryption.
+		Q[t+pad] = byte(i)
+		src := B
+		if decrypt {
+			src = A
+		}
+		num(radix, src).FillBytes(Q[t+pad+1:])
+
+		// R = PRF(P || Q) is the AES-CBC-MAC with a zero IV.
+		for k := range R {
+			R[k] = 0
+		}
+		for _, chunk := range [][]byte{P[:], Q} {
+			for k := 0; k < len(chunk); k += 16 {
+				for l := 0; l < 16; l++ {
+					R[l] ^= chunk[k+l]
+				}
+				block.Encrypt(R, R)
+			}
+		}
+
+		// S = R || CIPH(R ⊕ [1]^16) || CIPH(R ⊕ [2]^16) ...
+		copy(S, R)
+		for k := 1; k < len(S)/16; k++ {
+			copy(tmp, R)
+			var ctr [8]byte
+			binary.BigEndian.PutUint64(ctr[:], uint64(k))
+			for l := 0; l < 8; l++ {
+				tmp[8+l] ^= ctr[l]
+			}
+			block.Encrypt(S[16*k:], tmp)
+		}
+		y.SetBytes(S[:d])
+
+		if decrypt {
+			c.Sub(num(radix, B), y)
+			c.Mod(c, mod)
+			B, A = A, str(radix, m, c)
+		} else {
+			c.Add(num(radix, A), y)
+			c.Mod(c, mod)
+			A, B = B, str(radix, m, c)
+		}
+	}
+	return append(A, B...), nil
+}
+
+// ff3 implements the FF3-1 encryption (Algorithm 9) and
+// decryption (Algorithm 10) of the numeral string x with
+// the 64 bit expansion of a 56 bit tweak.
+func ff3(key []byte, radix int, tweak [8]byte, x []int, decrypt bool) ([]int, error) {
+	revKey := make([]byte, len(key))
+	for i := range key {
+		revKey[i] = key[len(key)-1-i]
+	}
+	block, err := aes.NewCipher(revKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		n  = len(x)
+		u  = (n + 1) / 2
+		v  = n - u
+		A  = append([]int(nil), x[:u]...)
+		B  = append([]int(nil), x[u:]...)
+		TL = tweak[:4]
+		TR = tweak[4:]
+	)
+	var (
+		modU = pow(radix, u)
+		modV = pow(radix, v)
+		y    = new(big.Int)
+		c    = new(big.Int)
+		P    = make([]byte, 16)
+		S    = make([]byte, 16)
+	)
+	for j := 0; j < 8; j++ {
+		i := j
+		if decrypt {
+			i = 7 - j
+		}
+		m, mod, W := u, modU, TR
+		if i%2 == 1 {
+			m, mod, W = v, modV, TL
+		}
+
+		// P = W ⊕ [i]^4 || [NUM_radix(REV(B))]^12
+		// where B is A during decryption.
+		copy(P, W)
+		P[3] ^= byte(i)
+		src := B
+		if decrypt {
+			src = A
+		}
+		num(radix, reverse(src)).FillBytes(P[4:])
+
+		// S = REVB(CIPH_REVB(K)(REVB(P)))
+		reverseBytes(P)
+		block.Encrypt(S, P)
+		reverseBytes(S)
+		y.SetBytes(S)
+
+		if decrypt {
+			c.Sub(num(radix, reverse(B)), y)
+			c.Mod(c, mod)
+			B, A = A, reverse(str(radix, m, c))
+		} else {
+			c.Add(num(radix, reverse(A)), y)
+			c.Mod(c, mod)
+			A, B = B, reverse(str(radix, m, c))
+		}
+	}
+	return append(A, B...), nil
+}
+
+// ff31Tweak expands a 56 bit FF3-1 tweak to the
+// 64 bit tweak TL || TR used by FF3.
+func ff31Tweak(t []byte) [8]byte {
+	return [8]byte{
+		t[0], t[1], t[2], t[3] & 0xF0,
+		t[4], t[5], t[6], (t[3] & 0x0F) << 4,
+	}
+}
+
+// minLength returns the min. length of strings over an
+// alphabet with the given radix.
+func minLength(radix int) int {
+	m := 1
+	for n := radix; n < minDomainSize; n *= radix {
+		m++
+	}
+	return m
+}
+
+// maxFF31Length returns the max. length of strings over
+// an alphabet with the given radix that can be encrypted
+// with FF3-1: 2 * floor(log_radix(2^96)).
+func maxFF31Length(radix int) int {
+	limit := new(big.Int).Lsh(big.NewInt(1), 96)
+	m, r := 0, big.NewInt(int64(radix))
+	for p := new(big.Int).Set(r); p.Cmp(limit) <= 0; p.Mul(p, r) {
+		m++
+	}
+	return 2 * m
+}
+
+// num returns the numeral string x as number in base radix.
+func num(radix int, x []int) *big.Int {
+	n, r := new(big.Int), big.NewInt(int64(radix))
+	for _, d := range x {
+		n.Mul(n, r)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	return n
+}
+
+// str returns the m numerals of n in base radix.
+func str(radix, m int, n *big.Int) []int {
+	var (
+		x = make([]int, m)
+		r = big.NewInt(int64(radix))
+		q = new(big.Int).Set(n)
+		d = new(big.Int)
+	)
+	for i := m - 1; i >= 0; i-- {
+		q.DivMod(q, r, d)
+		x[i] = int(d.Int64())
+	}
+	return x
+}
+
+// pow returns radix^m.
+func pow(radix, m int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(radix)), big.NewInt(int64(m)), nil)
+}
+
+// bitLen returns the number of bits required to
+// represent radix^m - 1.
+func bitLen(radix, m int) int {
+	p := pow(radix, m)
+	return p.Sub(p, big.NewInt(1)).BitLen()
+}
+
+func reverse(x []int) []int {
+	r := make([]int, len(x))
+	for i, d := range x {
+		r[len(x)-1-i] = d
+	}
+	return r
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
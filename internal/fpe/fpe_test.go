@@ -0,0 +1,153 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fpe
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+var encryptTests = []struct {
+	Mode       Mode
+	Key        string
+	Tweak      string
+	Alphabet   string
+	Plaintext  string
+	Ciphertext string
+}{
+	{ // 0 - NIST FF1 sample 1
+		Mode:       FF1,
+		Key:        "2B7E151628AED2A6ABF7158809CF4F3C",
+		Alphabet:   Digits,
+		Plaintext:  "0123456789",
+		Ciphertext: "2433477484",
+	},
+	{ // 1 - NIST FF1 sample 2
+		Mode:       FF1,
+		Key:        "2B7E151628AED2A6ABF7158809CF4F3C",
+		Tweak:      "39383736353433323130",
+		Alphabet:   Digits,
+		Plaintext:  "0123456789",
+		Ciphertext: "6124200773",
+	},
+	{ // 2 - NIST FF1 sample 3
+		Mode:       FF1,
+		Key:        "2B7E151628AED2A6ABF7158809CF4F3C",
+		Tweak:      "3737373770717273373737",
+		Alphabet:   "0123456789abcdefghijklmnopqrstuvwxyz",
+		Plaintext:  "0123456789abcdefghi",
+		Ciphertext: "a9tv40mll9kdu509eum",
+	},
+	{ // 3 - NIST FF1 sample 7
+		Mode:       FF1,
+		Key:        "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94",
+		Alphabet:   Digits,
+		Plaintext:  "0123456789",
+		Ciphertext: "6657667009",
+	},
+	{ // 4 - NIST FF1 sample 9
+		Mode:       FF1,
+		Key:        "2B7E151628AED2A6ABF7158809CF4F3CEF4359D8D580AA4F7F036D6F04FC6A94",
+		Tweak:      "3737373770717273373737",
+		Alphabet:   "0123456789abcdefghijklmnopqrstuvwxyz",
+		Plaintext:  "0123456789abcdefghi",
+		Ciphertext: "xs8a0azh2avyalyzuwd",
+	},
+	{ // 5 - FF3-1
+		Mode:       FF31,
+		Key:        "2DE79D232DF5585D68CE47882AE256D6",
+		Tweak:      "CBD09280979564",
+		Alphabet:   Digits,
+		Plaintext:  "3992520240",
+		Ciphertext: "8901801106",
+	},
+}
+
+func TestEncrypt(t *testing.T) {
+	for i, test := range encryptTests {
+		key, _ := hex.DecodeString(test.Key)
+		tweak, _ := hex.DecodeString(test.Tweak)
+		alphabet, err := NewAlphabet(test.Alphabet)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create alphabet: %v", i, err)
+		}
+
+		ciphertext, err := Encrypt(test.Mode, key, alphabet, tweak, test.Plaintext)
+		if err != nil {
+			t.Fatalf("Test %d: failed to encrypt: %v", i, err)
+		}
+		if ciphertext != test.Ciphertext {
+			t.Fatalf("Test %d: invalid ciphertext: got '%s' - want '%s'", i, ciphertext, test.Ciphertext)
+		}
+		plaintext, err := Decrypt(test.Mode, key, alphabet, tweak, ciphertext)
+		if err != nil {
+			t.Fatalf("Test %d: failed to decrypt: %v", i, err)
+		}
+		if plaintext != test.Plaintext {
+			t.Fatalf("Test %d: invalid plaintext: got '%s' - want '%s'", i, plaintext, test.Plaintext)
+		}
+	}
+}
+
+func TestFF3(t *testing.T) {
+	// NIST FF3 sample 1 verifies the FF3 core used by FF3-1.
+	key, _ := hex.DecodeString("EF4359D8D580AA4F7F036D6F04FC6A94")
+	var tweak [8]byte
+	hex.Decode(tweak[:], []byte("D8E7920AFA330A73"))
+
+	const (
+		Plaintext  = "890121234567890000"
+		Ciphertext = "750918814058654607"
+	)
+	x := make([]int, 0, len(Plaintext))
+	for _, r := range Plaintext {
+		x = append(x, int(r-'0'))
+	}
+	y, err := ff3(key, 10, tweak, x, false)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	var ciphertext []byte
+	for _, d := range y {
+		ciphertext = append(ciphertext, byte('0'+d))
+	}
+	if string(ciphertext) != Ciphertext {
+		t.Fatalf("Invalid ciphertext: got '%s' - want '%s'", ciphertext, Ciphertext)
+	}
+}
+
+var invalidTests = []struct {
+	Mode      Mode
+	Alphabet  string
+	Tweak     []byte
+	Plaintext string
+}{
+	{Mode: FF1, Alphabet: Digits, Plaintext: "12345"},                                                        // 0 - too short
+	{Mode: FF1, Alphabet: Digits, Plaintext: "123456789a"},                                                   // 1 - not in alphabet
+	{Mode: FF31, Alphabet: Digits, Tweak: make([]byte, 8), Plaintext: "1234567890"},                          // 2 - invalid tweak
+	{Mode: FF31, Alphabet: Digits, Plaintext: "12345678901234567890123456789012345678901234567890123456789"}, // 3 - too long
+	{Mode: "FF2", Alphabet: Digits, Plaintext: "1234567890"},                                                 // 4 - unsupported mode
+}
+
+func TestEncryptInvalid(t *testing.T) {
+	key := make([]byte, 32)
+	for i, test := range invalidTests {
+		alphabet, err := NewAlphabet(test.Alphabet)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create alphabet: %v", i, err)
+		}
+		if _, err = Encrypt(test.Mode, key, alphabet, test.Tweak, test.Plaintext); err == nil {
+			t.Fatalf("Test %d: should fail but passed", i)
+		}
+	}
+}
+
+func TestNewAlphabet(t *testing.T) {
+	for i, s := range []string{"", "0", "00", "0120"} {
+		if _, err := NewAlphabet(s); err == nil {
+			t.Fatalf("Test %d: should fail but passed", i)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package gcmsiv implements the AES-GCM-SIV AEAD specified
+// in RFC 8452.
+//
+// AES-GCM-SIV is a nonce-misuse resistant AEAD. Encrypting two
+// different messages with the same key and nonce only reveals
+// whether the two messages are equal. In contrast, AES-GCM loses
+// all confidentiality and authenticity guarantees when a nonce
+// gets reused.
+package gcmsiv
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// Sizes of AES-GCM-SIV nonces and tags in bytes.
+const (
+	NonceSize = 12
+	TagSize   = 16
+)
+
+// maxSize is the max. size of plaintexts and associated
+// data in bytes, as specified by RFC 8452.
+const maxSize = 1 << 36
+
+var errOpen = errors.New("gcmsiv: message authentication failed")
+
+// New returns a new AES-GCM-SIV AEAD for the given key-generating
+// key. The key must be either 16 or 32 bytes long to select
+// AES-128-GCM-SIV or AES-256-GCM-SIV.
+func New(key []byte) (cipher.AEAD, error) {
+	if n := len(key); n != 16 && n != 32 {
+		return nil, errors.New("gcmsiv: invalid key size " + strconv.Itoa(n))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aead{block: block, keyLen: len(key)}, nil
+}
+
+type aead struct {
+	block  cipher.Block
+	keyLen int
+}
+
+func (*aead) NonceSize() int { return NonceSize }
+
+func (*aead) Overhead() int { return TagSize }
+
+func (a *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("gcmsiv: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if uint64(len(plaintext)) > maxSize || uint64(len(additionalData)) > maxSize {
+		panic("gcmsiv: message too large for AES-GCM-SIV")
+	}
+	authKey, block := a.deriveKeys(nonce)
+
+	var tag [TagSize]byte
+	a.tag(&tag, block, authKey, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+TagSize)
+	ctr(block, &tag, out[:len(plaintext)], plaintext)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (a *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("gcmsiv: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if len(ciphertext) < TagSize {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > maxSize+TagSize || uint64(len(additionalData)) > maxSize {
+		return nil, errOpen
+	}
+	authKey, block := a.deriveKeys(nonce)
+
+	var tag, expectedTag [TagSize]byte
+	copy(tag[:], ciphertext[len(ciphertext)-TagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-TagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctr(block, &tag, out, ciphertext)
+
+	a.tag(&expectedTag, block, authKey, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(tag[:], expectedTag[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// deriveKeys derives the per-nonce message authentication
+// key and message encryption key from the key-generating
+// key, as specified by RFC 8452, section 4.
+func (a *aead) deriveKeys(nonce []byte) (authKey [16]byte, block cipher.Block) {
+	var (
+		in, out [16]byte
+		encKey  = make([]byte, 0, 32)
+	)
+	copy(in[4:], nonce)
+	for i := uint32(0); i < uint32(2+a.keyLen/8); i++ {
+		binary.LittleEndian.PutUint32(in[:4], i)
+		a.block.Encrypt(out[:], in[:])
+		if i < 2 {
+			copy(authKey[8*i:], out[:8])
+		} else {
+			encKey = append(encKey, out[:8]...)
+		}
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil { // The key has either 16 or 32 bytes
+		panic("gcmsiv: failed to derive message encryption key: " + err.Error())
+	}
+	return authKey, block
+}
+
+// tag computes the AES-GCM-SIV authentication tag
+// of the plaintext and associated data.
+func (a *aead) tag(tag *[TagSize]byte, block cipher.Block, authKey [16]byte, nonce, plaintext, additionalData []byte) {
+	var p polyval
+	p.init(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+	block.Encrypt(tag[:], s[:])
+}
+
+// ctr encrypts or decrypts src to dst in counter mode. The
+// initial counter block is the tag with the most significant
+// bit of the last byte set. The counter is the first 32 bits
+// of the counter block as little-endian integer.
+func ctr(block cipher.Block, tag *[TagSize]byte, dst, src []byte) {
+	var counter, keyStream [16]byte
+	copy(counter[:], tag[:])
+	counter[15] |= 0x80
+
+	for len(src) > 0 {
+		block.Encrypt(keyStream[:], counter[:])
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+
+		n := len(src)
+		if n > len(keyStream) {
+			n = len(keyStream)
+		}
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ keyStream[i]
+		}
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// sliceForAppend extends in by n bytes. It returns the
+// extended slice and a slice of the n appended bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gcmsiv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPolyval(t *testing.T) {
+	// RFC 8452, appendix A
+	var key [16]byte
+	copy(key[:], mustDecodeHex(t, "25629347589242761d31f826ba4b757b"))
+
+	var p polyval
+	p.init(key)
+	p.update(mustDecodeHex(t, "4f4f95668c83dfb6401762bb2d01a262"))
+	p.update(mustDecodeHex(t, "d1a24ddd2721d006bbe45f20d3c9f362"))
+
+	sum := p.sum()
+	if want := "f7a3b47b846119fae5b7866cf5e5b77e"; hex.EncodeToString(sum[:]) != want {
+		t.Fatalf("Invalid POLYVAL: got '%x' - want '%s'", sum, want)
+	}
+}
+
+var sealTests = []struct {
+	Key            string
+	Nonce          string
+	Plaintext      string
+	AssociatedData string
+	Ciphertext     string
+}{
+	{ // 0 - RFC 8452, appendix C.1
+		Key:        "01000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Ciphertext: "dc20e2d83f25705bb49e439eca56de25",
+	},
+	{ // 1 - RFC 8452, appendix C.1
+		Key:        "01000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Plaintext:  "0100000000000000",
+		Ciphertext: "b5d839330ac7b786578782fff6013b815b287c22493a364c",
+	},
+	{ // 2 - RFC 8452, appendix C.2
+		Key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Ciphertext: "07f5f4169bbf55a8400cd47ea6fd400f",
+	},
+	{ // 3 - RFC 8452, appendix C.2
+		Key:        "0100000000000000000000000000000000000000000000000000000000000000",
+		Nonce:      "030000000000000000000000",
+		Plaintext:  "0100000000000000",
+		Ciphertext: "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28",
+	},
+}
+
+func TestSeal(t *testing.T) {
+	for i, test := range sealTests {
+		aead, err := New(mustDecodeHex(t, test.Key))
+		if err != nil {
+			t.Fatalf("Test %d: failed to create AEAD: %v", i, err)
+		}
+		var (
+			nonce          = mustDecodeHex(t, test.Nonce)
+			plaintext      = mustDecodeHex(t, test.Plaintext)
+			associatedData = mustDecodeHex(t, test.AssociatedData)
+		)
+		ciphertext := aead.Seal(nil, nonce, plaintext, associatedData)
+		if hex.EncodeToString(ciphertext) != test.Ciphertext {
+			t.Fatalf("Test %d: invalid ciphertext: got '%x' - want '%s'", i, ciphertext, test.Ciphertext)
+		}
+
+		p, err := aead.Open(nil, nonce, ciphertext, associatedData)
+		if err != nil {
+			t.Fatalf("Test %d: failed to decrypt ciphertext: %v", i, err)
+		}
+		if !bytes.Equal(p, plaintext) {
+			t.Fatalf("Test %d: invalid plaintext: got '%x' - want '%x'", i, p, plaintext)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	aead, err := New(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create AEAD: %v", err)
+	}
+	var (
+		nonce     = make([]byte, NonceSize)
+		plaintext = []byte("Hello World! This message spans more than a single block.")
+	)
+	ciphertext := aead.Seal(nil, nonce, plaintext, []byte("context"))
+
+	if _, err = aead.Open(nil, nonce, ciphertext, nil); err == nil {
+		t.Fatal("Decryption succeeded with wrong associated data")
+	}
+	for i := range ciphertext {
+		tampered := append([]byte{}, ciphertext...)
+		tampered[i] ^= 1
+		if _, err = aead.Open(nil, nonce, tampered, []byte("context")); err == nil {
+			t.Fatalf("Decryption succeeded with modified byte %d", i)
+		}
+	}
+	if _, err = aead.Open(nil, nonce, ciphertext[:TagSize-1], []byte("context")); err == nil {
+		t.Fatal("Decryption succeeded with truncated ciphertext")
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("Failed to decode hex: %v", err)
+	}
+	return b
+}
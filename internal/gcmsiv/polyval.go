@@ -0,0 +1,81 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gcmsiv
+
+import "encoding/binary"
+
+// polyval computes the POLYVAL universal hash function
+// specified in RFC 8452, section 3.
+//
+// Field elements are 128 bit little-endian polynomials
+// represented by their low and high 64 bits.
+type polyval struct {
+	h, s fieldElement
+}
+
+type fieldElement struct {
+	lo, hi uint64
+}
+
+// init initializes p with the hash key and resets its state.
+func (p *polyval) init(key [16]byte) {
+	p.h = fieldElement{
+		lo: binary.LittleEndian.Uint64(key[:8]),
+		hi: binary.LittleEndian.Uint64(key[8:]),
+	}
+	p.s = fieldElement{}
+}
+
+// update adds b to the hash. If b is not a multiple of
+// the block size, it gets padded with zeros.
+func (p *polyval) update(b []byte) {
+	for len(b) > 0 {
+		var block [16]byte
+		n := copy(block[:], b)
+		b = b[n:]
+
+		p.s.lo ^= binary.LittleEndian.Uint64(block[:8])
+		p.s.hi ^= binary.LittleEndian.Uint64(block[8:])
+		p.s = dot(p.s, p.h)
+	}
+}
+
+// sum returns the current hash value.
+func (p *polyval) sum() [16]byte {
+	var s [16]byte
+	binary.LittleEndian.PutUint64(s[:8], p.s.lo)
+	binary.LittleEndian.PutUint64(s[8:], p.s.hi)
+	return s
+}
+
+// dot returns a*b*x^-128 modulo x^128 + x^127 + x^126 + x^121 + 1.
+//
+// It adds b to the result for every set bit of a, from the least
+// to the most significant bit, and multiplies the result by x^-1
+// after every bit. Hence, the i-th bit of a contributes b*x^(i-128).
+// It does not branch on secret values.
+func dot(a, b fieldElement) fieldElement {
+	var r fieldElement
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = (a.lo >> uint(i)) & 1
+		} else {
+			bit = (a.hi >> uint(i-64)) & 1
+		}
+		mask := -bit
+		r.lo ^= b.lo & mask
+		r.hi ^= b.hi & mask
+
+		// Multiply by x^-1: If the constant term is set, add the
+		// modulus, which clears it, and divide by x. Since x^128
+		// is part of the modulus, the top bit gets set.
+		mask = -(r.lo & 1)
+		r.hi ^= mask & (1<<57 | 1<<62 | 1<<63)
+		r.lo = r.lo>>1 | r.hi<<63
+		r.hi = r.hi>>1 | mask&(1<<63)
+	}
+	return r
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repository is a remote Git repository that gets pulled
+// into a local working directory using the git binary.
+type Repository struct {
+	// URL is the URL of the remote repository.
+	URL string
+
+	// Branch is the branch that gets pulled. If
+	// empty, the remote HEAD is pulled.
+	Branch string
+
+	// Dir is the local working directory. It is
+	// initialized as Git repository if it does not
+	// contain one.
+	Dir string
+
+	// AllowedSigners is the path of an SSH allowed
+	// signers file used to verify SSH commit signatures.
+	// If empty, git's configuration is used. GPG commit
+	// signatures are verified with the user's keyring.
+	AllowedSigners string
+
+	// SkipVerify controls whether commit signatures are
+	// verified. By default, Pull fails if the pulled
+	// commit has no valid signature.
+	SkipVerify bool
+}
+
+// Pull fetches the latest commit of the repository branch,
+// verifies its signature and checks it out into the working
+// directory. It returns the ID of the checked out commit.
+//
+// If the commit signature cannot be verified, the working
+// directory remains unchanged.
+//
+// Pull refuses to check out a commit that is not a descendant
+// of the commit checked out previously. Hence, rewinding the
+// branch to an older commit, for example to restore a revoked
+// permission, does not roll back the synced state. To sync a
+// rewritten branch, the working directory must be removed.
+func (r *Repository) Pull(ctx context.Context) (string, error) {
+	if r.URL == "" {
+		return "", errors.New("gitops: no repository URL specified")
+	}
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err = os.MkdirAll(r.Dir, 0o755); err != nil {
+			return "", fmt.Errorf("gitops: failed to create '%s': %v", r.Dir, err)
+		}
+		if _, err = r.git(ctx, "init", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+
+	// The first fetch only fetches the latest commit. Subsequent
+	// fetches fetch all commits since the previous one such that
+	// its ancestry can be checked.
+	previous, _ := r.git(ctx, "rev-parse", "--quiet", "--verify", "HEAD^{commit}")
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	branch := r.Branch
+	if branch == "" {
+		branch = "HEAD"
+	}
+	args := []string{"fetch", "--quiet", "--depth", "1", "--", r.URL, branch}
+	if previous != "" {
+		args = []string{"fetch", "--quiet", "--", r.URL, branch}
+	}
+	if _, err := r.git(ctx, args...); err != nil {
+		return "", err
+	}
+	commit, err := r.git(ctx, "rev-parse", "--verify", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", err
+	}
+	if previous != "" && previous != commit {
+		if _, err = r.git(ctx, "merge-base", "--is-ancestor", previous, commit); err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("gitops: commit '%s' is not a descendant of the previously synced commit '%s'", commit, previous)
+		}
+	}
+	if !r.SkipVerify {
+		args = []string{"verify-commit", commit}
+		if r.AllowedSigners != "" {
+			args = append([]string{"-c", "gpg.ssh.allowedSignersFile=" + r.AllowedSigners}, args...)
+		}
+		if _, err = r.git(ctx, args...); err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("gitops: commit '%s' has no valid signature", commit)
+		}
+	}
+	if _, err = r.git(ctx, "-c", "advice.detachedHead=false", "checkout", "--quiet", "--force", "--detach", commit); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
+
+// git runs the git binary with the given arguments within
+// the working directory and returns its trimmed output.
+func (r *Repository) git(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.Dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("gitops: git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("gitops: git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,440 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package gitops implements a sync of KES policies and
+// identities from a Git repository to a KES server.
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/log"
+	"github.com/minio/kes/internal/sys"
+	"gopkg.in/yaml.v3"
+)
+
+// Server is the set of KES server operations
+// the Syncer performs.
+type Server interface {
+	ListPolicies(ctx context.Context, enclave string) ([]string, error)
+	GetPolicy(ctx context.Context, enclave, name string) (*kes.Policy, error)
+	SetPolicy(ctx context.Context, enclave, name string, policy *kes.Policy) error
+	DeletePolicy(ctx context.Context, enclave, name string) error
+
+	ListIdentities(ctx context.Context, enclave string) ([]kes.IdentityInfo, error)
+	AssignPolicy(ctx context.Context, enclave, policy string, identity kes.Identity) error
+	DeleteIdentity(ctx context.Context, enclave string, identity kes.Identity) error
+}
+
+// NewServer returns a Server that performs
+// operations with the given KES client.
+func NewServer(client *kes.Client) Server { return clientServer{client: client} }
+
+// DriftState describes how a policy or identity on
+// the KES server differs from the Git repository.
+type DriftState string
+
+// Drift states.
+const (
+	// DriftMissing indicates that a policy or identity
+	// of the repository does not exist on the server.
+	DriftMissing DriftState = "missing"
+
+	// DriftModified indicates that a policy differs
+	// from the repository or an identity is assigned
+	// to another policy.
+	DriftModified DriftState = "modified"
+
+	// DriftUnmanaged indicates that a policy or identity
+	// exists on the server but not in the repository.
+	DriftUnmanaged DriftState = "unmanaged"
+)
+
+// Drift is a policy or identity on the KES server
+// that differs from the Git repository.
+type Drift struct {
+	Enclave string     // Enclave of the policy or identity
+	Kind    string     // Either "policy" or "identity"
+	Name    string     // Name of the policy or the identity
+	State   DriftState // How the server differs from the repository
+}
+
+// String returns a string representation of the drift.
+func (d Drift) String() string {
+	return fmt.Sprintf("%s '%s' in enclave '%s' is %s", d.Kind, d.Name, d.Enclave, d.State)
+}
+
+// Report is the outcome of a sync.
+type Report struct {
+	// Commit is the ID of the synced commit.
+	Commit string
+
+	// Drift contains all policies and identities that
+	// differed from the repository before the sync.
+	Drift []Drift
+
+	// Applied is the number of drifts that have been
+	// resolved by modifying the server.
+	Applied int
+}
+
+// A Syncer syncs the policies and identities defined in a
+// Git repository to a KES server.
+//
+// The repository contains YAML files, within Path, that
+// define the policies and identity assignments of one
+// enclave each:
+//
+//	enclave: tenant-1
+//	policy:
+//	  my-app:
+//	    allow:
+//	    - /v1/key/encrypt/my-app*
+//	    identities:
+//	    - 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+//
+// If a file specifies no enclave, it refers to the default
+// enclave. Only enclaves with at least one file are synced.
+type Syncer struct {
+	Repository *Repository
+	Server     Server
+
+	// Path is the directory, relative to the repository
+	// root, that contains the policy files. If empty, the
+	// repository root is used.
+	Path string
+
+	// Prune controls whether unmanaged policies and
+	// identities are deleted from the server. By
+	// default, they are only reported as drift.
+	Prune bool
+
+	// DryRun controls whether the Syncer only reports
+	// drift without modifying the server.
+	DryRun bool
+
+	// ErrorLog is used to log sync errors and drift.
+	// If nil, the default logger is used.
+	ErrorLog *log.Logger
+}
+
+// Run syncs the repository periodically until ctx is
+// canceled. It logs any drift and sync errors.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.Sync(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logf("gitops: %v", err)
+		}
+		if report != nil {
+			for _, drift := range report.Drift {
+				s.logf("gitops: commit %s: %v", shortCommit(report.Commit), drift)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pulls the repository once and syncs its policies
+// and identities to the server. Policies are synced before
+// identities such that policy assignments refer to existing
+// policies.
+//
+// It returns a Report with all drift detected before the
+// sync. If the repository cannot be pulled, e.g. due to an
+// invalid commit signature, or its files are invalid, Sync
+// does not modify the server. Otherwise, it returns a Report
+// even if some drift cannot be resolved.
+func (s *Syncer) Sync(ctx context.Context) (*Report, error) {
+	commit, err := s.Repository.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enclaves, err := readEnclaves(filepath.Join(s.Repository.Dir, s.Path))
+	if err != nil {
+		return nil, fmt.Errorf("gitops: commit %s: %v", shortCommit(commit), err)
+	}
+
+	names := make([]string, 0, len(enclaves))
+	for name := range enclaves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := &Report{Commit: commit}
+	var errs []string
+	for _, name := range names {
+		if err = s.syncEnclave(ctx, name, enclaves[name], report); err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			errs = append(errs, fmt.Sprintf("enclave '%s': %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return report, fmt.Errorf("gitops: commit %s: %s", shortCommit(commit), strings.Join(errs, "; "))
+	}
+	return report, nil
+}
+
+// syncEnclave syncs the policies and identities of one enclave.
+func (s *Syncer) syncEnclave(ctx context.Context, enclave string, policies map[string]policy, report *Report) error {
+	current, err := s.Server.ListPolicies(ctx, enclave)
+	if err != nil {
+		return fmt.Errorf("failed to list policies: %v", err)
+	}
+	identities, err := s.Server.ListIdentities(ctx, enclave)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %v", err)
+	}
+
+	var firstErr error
+	apply := func(drift Drift, f func() error) {
+		report.Drift = append(report.Drift, drift)
+		if s.DryRun || (drift.State == DriftUnmanaged && !s.Prune) {
+			return
+		}
+		if err := f(); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to sync %s '%s': %v", drift.Kind, drift.Name, err)
+			}
+			return
+		}
+		report.Applied++
+	}
+
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := policies[name]
+		state := DriftMissing
+		if existing, err := s.Server.GetPolicy(ctx, enclave, name); err == nil {
+			if auth.EqualPatterns(existing.Allow, p.Allow) && auth.EqualPatterns(existing.Deny, p.Deny) {
+				continue
+			}
+			state = DriftModified
+		} else if !errors.Is(err, kes.ErrPolicyNotFound) {
+			return fmt.Errorf("failed to fetch policy '%s': %v", name, err)
+		}
+		apply(Drift{Enclave: enclave, Kind: "policy", Name: name, State: state}, func() error {
+			return s.Server.SetPolicy(ctx, enclave, name, &kes.Policy{Allow: p.Allow, Deny: p.Deny})
+		})
+	}
+
+	assigned := make(map[kes.Identity]string, len(identities))
+	for _, info := range identities {
+		assigned[info.Identity] = info.Policy
+	}
+	roles := auth.IdentityRoles(policies, func(p policy) []kes.Identity { return p.Identities })
+	ids := make([]kes.Identity, 0, len(roles))
+	for id := range roles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		policy := roles[id]
+		current, ok := assigned[id]
+		if ok && current == policy {
+			continue
+		}
+		state := DriftMissing
+		if ok {
+			state = DriftModified
+		}
+		apply(Drift{Enclave: enclave, Kind: "identity", Name: id.String(), State: state}, func() error {
+			return s.Server.AssignPolicy(ctx, enclave, policy, id)
+		})
+	}
+
+	// Unmanaged identities are removed before unmanaged
+	// policies since policies with assigned identities
+	// may not be deleted.
+	sort.Slice(identities, func(i, j int) bool { return identities[i].Identity < identities[j].Identity })
+	for _, info := range identities {
+		if _, ok := roles[info.Identity]; ok || info.IsAdmin || info.Policy == auth.EnclaveAdminPolicy {
+			continue
+		}
+		id := info.Identity
+		apply(Drift{Enclave: enclave, Kind: "identity", Name: id.String(), State: DriftUnmanaged}, func() error {
+			return s.Server.DeleteIdentity(ctx, enclave, id)
+		})
+	}
+	sort.Strings(current)
+	for _, name := range current {
+		if _, ok := policies[name]; ok || name == auth.EnclaveAdminPolicy {
+			continue
+		}
+		name := name
+		apply(Drift{Enclave: enclave, Kind: "policy", Name: name, State: DriftUnmanaged}, func() error {
+			return s.Server.DeletePolicy(ctx, enclave, name)
+		})
+	}
+	return firstErr
+}
+
+func (s *Syncer) logf(format string, v ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+}
+
+// policy is a policy definition within a policy file.
+type policy struct {
+	Allow      []string       `yaml:"allow"`
+	Deny       []string       `yaml:"deny"`
+	Identities []kes.Identity `yaml:"identities"`
+}
+
+// readEnclaves reads all policy files within dir and
+// returns the policies of each enclave.
+func readEnclaves(dir string) (map[string]map[string]policy, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	enclaves := map[string]map[string]policy{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if ext := filepath.Ext(entry.Name()); ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var content struct {
+			Enclave string            `yaml:"enclave"`
+			Policy  map[string]policy `yaml:"policy"`
+		}
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		err = decoder.Decode(&content)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy file '%s': %v", entry.Name(), err)
+		}
+
+		enclave := content.Enclave
+		if enclave == "" {
+			enclave = sys.DefaultEnclaveName
+		}
+		policies, ok := enclaves[enclave]
+		if !ok {
+			policies = map[string]policy{}
+			enclaves[enclave] = policies
+		}
+		for name, p := range content.Policy {
+			if name == auth.EnclaveAdminPolicy {
+				return nil, fmt.Errorf("invalid policy file '%s': policy '%s' is reserved", entry.Name(), name)
+			}
+			if _, ok := policies[name]; ok {
+				return nil, fmt.Errorf("invalid policy file '%s': policy '%s' in enclave '%s' is already defined", entry.Name(), name, enclave)
+			}
+			policies[name] = p
+		}
+	}
+	for enclave, policies := range enclaves {
+		seen := map[kes.Identity]string{}
+		for name, p := range policies {
+			for _, id := range p.Identities {
+				if id.IsUnknown() {
+					return nil, fmt.Errorf("policy '%s' in enclave '%s' contains an empty identity", name, enclave)
+				}
+				if other, ok := seen[id]; ok && other != name {
+					return nil, fmt.Errorf("identity '%s' in enclave '%s' is assigned to policy '%s' and '%s'", id, enclave, other, name)
+				}
+				seen[id] = name
+			}
+		}
+	}
+	return enclaves, nil
+}
+
+// shortCommit returns the abbreviated commit ID.
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+type clientServer struct {
+	client *kes.Client
+}
+
+func (s clientServer) ListPolicies(ctx context.Context, enclave string) ([]string, error) {
+	iter, err := s.client.Enclave(enclave).ListPolicies(ctx, "*")
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Name())
+	}
+	return names, iter.Close()
+}
+
+func (s clientServer) GetPolicy(ctx context.Context, enclave, name string) (*kes.Policy, error) {
+	return s.client.Enclave(enclave).GetPolicy(ctx, name)
+}
+
+func (s clientServer) SetPolicy(ctx context.Context, enclave, name string, policy *kes.Policy) error {
+	return s.client.Enclave(enclave).SetPolicy(ctx, name, policy)
+}
+
+func (s clientServer) DeletePolicy(ctx context.Context, enclave, name string) error {
+	return s.client.Enclave(enclave).DeletePolicy(ctx, name)
+}
+
+func (s clientServer) ListIdentities(ctx context.Context, enclave string) ([]kes.IdentityInfo, error) {
+	iter, err := s.client.Enclave(enclave).ListIdentities(ctx, "*")
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var identities []kes.IdentityInfo
+	for iter.Next() {
+		identities = append(identities, iter.Value())
+	}
+	return identities, iter.Close()
+}
+
+func (s clientServer) AssignPolicy(ctx context.Context, enclave, policy string, identity kes.Identity) error {
+	return s.client.Enclave(enclave).AssignPolicy(ctx, policy, identity)
+}
+
+func (s clientServer) DeleteIdentity(ctx context.Context, enclave string, identity kes.Identity) error {
+	return s.client.Enclave(enclave).DeleteIdentity(ctx, identity)
+}
@@ -0,0 +1,317 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gitops
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+const policyFile = `
+policy:
+  my-app:
+    allow:
+    - /v1/key/encrypt/my-app*
+    identities:
+    - 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+  my-admin:
+    allow:
+    - /v1/key/*
+`
+
+func TestSync(t *testing.T) {
+	origin := newTestRepository(t, map[string]string{"policies/default.yml": policyFile, "README.md": "# Policies"}, "")
+
+	server := &fakeServer{
+		policies: map[string]*kes.Policy{
+			"default/my-admin": {Allow: []string{"/v1/status"}},
+			"default/old":      {Allow: []string{"/v1/status"}},
+		},
+		identities: map[string]kes.IdentityInfo{
+			"default/4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22": {Identity: "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", Policy: "old"},
+		},
+	}
+	syncer := &Syncer{
+		Repository: &Repository{URL: origin, Dir: filepath.Join(t.TempDir(), "work"), SkipVerify: true},
+		Server:     server,
+		Path:       "policies",
+	}
+
+	report, err := syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	wantDrift := []Drift{
+		{Enclave: "default", Kind: "policy", Name: "my-admin", State: DriftModified},
+		{Enclave: "default", Kind: "policy", Name: "my-app", State: DriftMissing},
+		{Enclave: "default", Kind: "identity", Name: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", State: DriftMissing},
+		{Enclave: "default", Kind: "identity", Name: "4ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", State: DriftUnmanaged},
+		{Enclave: "default", Kind: "policy", Name: "old", State: DriftUnmanaged},
+	}
+	if !reflect.DeepEqual(report.Drift, wantDrift) {
+		t.Fatalf("Invalid drift: got '%v' - want '%v'", report.Drift, wantDrift)
+	}
+	if report.Applied != 3 {
+		t.Fatalf("Invalid number of applied changes: got '%d' - want '%d'", report.Applied, 3)
+	}
+	if _, ok := server.policies["default/old"]; !ok {
+		t.Fatal("Unmanaged policy has been deleted without pruning")
+	}
+
+	syncer.Prune = true
+	if report, err = syncer.Sync(context.Background()); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Drift) != 2 || report.Applied != 2 {
+		t.Fatalf("Invalid report: got '%+v' - want 2 unmanaged and applied drifts", report)
+	}
+	if _, ok := server.policies["default/old"]; ok {
+		t.Fatal("Unmanaged policy has not been deleted")
+	}
+
+	if report, err = syncer.Sync(context.Background()); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	if len(report.Drift) != 0 {
+		t.Fatalf("Invalid drift: got '%v' - want none", report.Drift)
+	}
+}
+
+func TestSyncVerify(t *testing.T) {
+	origin := newTestRepository(t, map[string]string{"default.yml": policyFile}, "")
+
+	server := &fakeServer{policies: map[string]*kes.Policy{}, identities: map[string]kes.IdentityInfo{}}
+	syncer := &Syncer{
+		Repository: &Repository{URL: origin, Dir: filepath.Join(t.TempDir(), "work")},
+		Server:     server,
+	}
+	if _, err := syncer.Sync(context.Background()); err == nil {
+		t.Fatal("Sync of unsigned commit succeeded")
+	}
+	if len(server.policies) != 0 {
+		t.Fatalf("Unsigned commit has been synced: got '%v'", server.policies)
+	}
+
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	dir := t.TempDir()
+	signingKey := filepath.Join(dir, "signing-key")
+	run(t, dir, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "", "-f", signingKey)
+	publicKey, err := os.ReadFile(signingKey + ".pub")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+	allowedSigners := filepath.Join(dir, "allowed-signers")
+	if err = os.WriteFile(allowedSigners, []byte("kes@example.com "+string(publicKey)), 0o644); err != nil {
+		t.Fatalf("Failed to write allowed signers: %v", err)
+	}
+
+	syncer.Repository.URL = newTestRepository(t, map[string]string{"default.yml": policyFile}, signingKey)
+	syncer.Repository.AllowedSigners = allowedSigners
+	if _, err = syncer.Sync(context.Background()); err != nil {
+		t.Fatalf("Failed to sync signed commit: %v", err)
+	}
+	if _, ok := server.policies["default/my-app"]; !ok {
+		t.Fatal("Signed commit has not been synced")
+	}
+}
+
+func TestSyncRollback(t *testing.T) {
+	origin := newTestRepository(t, map[string]string{"default.yml": policyFile}, "")
+
+	server := &fakeServer{policies: map[string]*kes.Policy{}, identities: map[string]kes.IdentityInfo{}}
+	syncer := &Syncer{
+		Repository: &Repository{URL: origin, Dir: filepath.Join(t.TempDir(), "work"), SkipVerify: true},
+		Server:     server,
+		Prune:      true,
+	}
+	if _, err := syncer.Sync(context.Background()); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	revoked := strings.Replace(policyFile, "    identities:\n    - 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22\n", "", 1)
+	if err := os.WriteFile(filepath.Join(origin, "default.yml"), []byte(revoked), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	run(t, origin, "git", "-c", "user.name=KES", "-c", "user.email=kes@example.com", "-c", "commit.gpgSign=false", "commit", "--quiet", "--all", "--message", "Revoke identity")
+	if _, err := syncer.Sync(context.Background()); err != nil {
+		t.Fatalf("Failed to sync descendant commit: %v", err)
+	}
+	if len(server.identities) != 0 {
+		t.Fatalf("Identity has not been removed: got '%v'", server.identities)
+	}
+
+	run(t, origin, "git", "reset", "--quiet", "--hard", "HEAD~1")
+	if _, err := syncer.Sync(context.Background()); err == nil {
+		t.Fatal("Sync of an older commit succeeded")
+	}
+	if len(server.identities) != 0 {
+		t.Fatalf("Older commit has been synced: got '%v'", server.identities)
+	}
+
+	syncer.Repository.URL = newTestRepository(t, map[string]string{"default.yml": policyFile}, "")
+	if _, err := syncer.Sync(context.Background()); err == nil {
+		t.Fatal("Sync of an unrelated commit succeeded")
+	}
+	if len(server.identities) != 0 {
+		t.Fatalf("Unrelated commit has been synced: got '%v'", server.identities)
+	}
+}
+
+var readEnclavesTests = []struct {
+	Files      map[string]string
+	Enclaves   []string
+	ShouldFail bool
+}{
+	{ // 0
+		Files:    map[string]string{"a.yml": policyFile, "b.yaml": "enclave: tenant-1\n" + policyFile, "c.txt": "not a policy file"},
+		Enclaves: []string{"default", "tenant-1"},
+	},
+	{ // 1
+		Files:      map[string]string{"a.yml": policyFile, "b.yml": policyFile},
+		ShouldFail: true, // Policies defined twice
+	},
+	{ // 2
+		Files:      map[string]string{"a.yml": "policy:\n  enclave-admin:\n    allow: []\n"},
+		ShouldFail: true, // Reserved policy
+	},
+	{ // 3
+		Files:      map[string]string{"a.yml": "policy:\n  a:\n    identities: [" + strings.Repeat("f", 64) + "]\n  b:\n    identities: [" + strings.Repeat("f", 64) + "]\n"},
+		ShouldFail: true, // Identity assigned twice
+	},
+	{ // 4
+		Files:      map[string]string{"a.yml": "policies:\n  a:\n    allow: []\n"},
+		ShouldFail: true, // Unknown field
+	},
+}
+
+func TestReadEnclaves(t *testing.T) {
+	for i, test := range readEnclavesTests {
+		dir := t.TempDir()
+		for name, content := range test.Files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatalf("Test %d: failed to write file: %v", i, err)
+			}
+		}
+
+		enclaves, err := readEnclaves(dir)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to read enclaves: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: reading enclaves should have failed", i)
+		}
+		if err == nil && len(enclaves) != len(test.Enclaves) {
+			t.Fatalf("Test %d: got '%d' enclaves - want '%d'", i, len(enclaves), len(test.Enclaves))
+		}
+		for _, name := range test.Enclaves {
+			if _, ok := enclaves[name]; !ok {
+				t.Fatalf("Test %d: enclave '%s' is missing", i, name)
+			}
+		}
+	}
+}
+
+// newTestRepository creates a Git repository with one commit
+// containing the given files and returns its path. If signingKey
+// is not empty, the commit is signed with this SSH key.
+func newTestRepository(t *testing.T, files map[string]string, signingKey string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	run(t, dir, "git", "init", "--quiet")
+	run(t, dir, "git", "add", "--all")
+
+	args := []string{"-c", "user.name=KES", "-c", "user.email=kes@example.com", "-c", "commit.gpgSign=false"}
+	if signingKey != "" {
+		args = append(args, "-c", "gpg.format=ssh", "-c", "user.signingKey="+signingKey)
+	}
+	args = append(args, "commit", "--quiet", "--message", "Add policies")
+	if signingKey != "" {
+		args = append(args, "--gpg-sign")
+	}
+	run(t, dir, "git", args...)
+	return dir
+}
+
+func run(t *testing.T, dir, name string, args ...string) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run '%s': %v: %s", name, err, output)
+	}
+}
+
+type fakeServer struct {
+	policies   map[string]*kes.Policy      // enclave/name
+	identities map[string]kes.IdentityInfo // enclave/identity
+}
+
+func (s *fakeServer) ListPolicies(_ context.Context, enclave string) ([]string, error) {
+	var names []string
+	for name := range s.policies {
+		if strings.HasPrefix(name, enclave+"/") {
+			names = append(names, strings.TrimPrefix(name, enclave+"/"))
+		}
+	}
+	return names, nil
+}
+
+func (s *fakeServer) GetPolicy(_ context.Context, enclave, name string) (*kes.Policy, error) {
+	policy, ok := s.policies[enclave+"/"+name]
+	if !ok {
+		return nil, kes.ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (s *fakeServer) SetPolicy(_ context.Context, enclave, name string, policy *kes.Policy) error {
+	s.policies[enclave+"/"+name] = policy
+	return nil
+}
+
+func (s *fakeServer) DeletePolicy(_ context.Context, enclave, name string) error {
+	delete(s.policies, enclave+"/"+name)
+	return nil
+}
+
+func (s *fakeServer) ListIdentities(_ context.Context, enclave string) ([]kes.IdentityInfo, error) {
+	var identities []kes.IdentityInfo
+	for name, info := range s.identities {
+		if strings.HasPrefix(name, enclave+"/") {
+			identities = append(identities, info)
+		}
+	}
+	return identities, nil
+}
+
+func (s *fakeServer) AssignPolicy(_ context.Context, enclave, policy string, identity kes.Identity) error {
+	s.identities[enclave+"/"+identity.String()] = kes.IdentityInfo{Identity: identity, Policy: policy}
+	return nil
+}
+
+func (s *fakeServer) DeleteIdentity(_ context.Context, enclave string, identity kes.Identity) error {
+	delete(s.identities, enclave+"/"+identity.String())
+	return nil
+}
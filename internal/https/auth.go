@@ -0,0 +1,151 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// Reasons why a client certificate has been rejected.
+const (
+	ReasonNoCertificate    = "no_certificate"    // The client did not send a certificate
+	ReasonExpired          = "expired"           // The certificate has expired or is not valid yet
+	ReasonUnknownAuthority = "unknown_authority" // The certificate has not been issued by a trusted CA
+	ReasonRevoked          = "revoked"           // The certificate has been revoked
+	ReasonInvalid          = "invalid"           // The certificate has been rejected for any other reason
+)
+
+// ErrCertificateRevoked is returned by certificate verification
+// functions, like tls.Config.VerifyPeerCertificate, when a client
+// certificate has been revoked.
+var ErrCertificateRevoked = errors.New("tls: client certificate has been revoked")
+
+// errNoCertificate is returned when a client does not send
+// a certificate but the server requires one.
+var errNoCertificate = errors.New("tls: client did not provide a certificate")
+
+// AuthFailure describes a TLS handshake that failed because
+// the server rejected the client certificate.
+type AuthFailure struct {
+	RemoteAddr net.Addr     // The client address
+	ServerName string       // The server name requested by the client, if any
+	Identity   kes.Identity // The identity of the client certificate, if any
+	Reason     string       // Why the certificate has been rejected, e.g. ReasonExpired
+	Err        error        // The verification error
+}
+
+// authFailureReason returns the reason why the client
+// certificate has been rejected with the given error.
+func authFailureReason(err error) string {
+	var (
+		invalidErr   x509.CertificateInvalidError
+		authorityErr x509.UnknownAuthorityError
+	)
+	switch {
+	case errors.Is(err, errNoCertificate):
+		return ReasonNoCertificate
+	case errors.Is(err, ErrCertificateRevoked):
+		return ReasonRevoked
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return ReasonExpired
+	case errors.As(err, &authorityErr):
+		return ReasonUnknownAuthority
+	default:
+		return ReasonInvalid
+	}
+}
+
+// reportAuthFailures returns a copy of the TLS config that verifies
+// client certificates as specified by the config and calls onFailure
+// whenever a client certificate gets rejected.
+//
+// The built-in certificate verification of crypto/tls does not
+// expose rejected certificates. Therefore, the returned config
+// only requests client certificates and verifies them, including
+// any VerifyPeerCertificate and VerifyConnection functions of
+// the config, itself. Verification takes place on resumed TLS
+// sessions, too.
+func reportAuthFailures(config *tls.Config, addr net.Addr, onFailure func(AuthFailure)) *tls.Config {
+	var (
+		clientAuth = config.ClientAuth
+		verifyPeer = config.VerifyPeerCertificate
+		verifyConn = config.VerifyConnection
+	)
+	config = config.Clone()
+	config.ClientAuth = tls.RequestClientCert
+	config.VerifyPeerCertificate = nil
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		err := verifyClientCertificate(config, clientAuth, cs.PeerCertificates, verifyPeer)
+		if err == nil && verifyConn != nil {
+			err = verifyConn(cs)
+		}
+		if err != nil {
+			var identity kes.Identity
+			if len(cs.PeerCertificates) > 0 {
+				h := sha256.Sum256(cs.PeerCertificates[0].RawSubjectPublicKeyInfo)
+				identity = kes.Identity(hex.EncodeToString(h[:]))
+			}
+			onFailure(AuthFailure{
+				RemoteAddr: addr,
+				ServerName: cs.ServerName,
+				Identity:   identity,
+				Reason:     authFailureReason(err),
+				Err:        err,
+			})
+		}
+		return err
+	}
+	return config
+}
+
+// verifyClientCertificate verifies the client certificates as
+// crypto/tls would verify them for the given ClientAuth type.
+// Then it calls verifyPeer, if not nil, with the certificates
+// and the verified chains.
+func verifyClientCertificate(config *tls.Config, clientAuth tls.ClientAuthType, certs []*x509.Certificate, verifyPeer func([][]byte, [][]*x509.Certificate) error) error {
+	if len(certs) == 0 && (clientAuth == tls.RequireAnyClientCert || clientAuth == tls.RequireAndVerifyClientCert) {
+		return errNoCertificate
+	}
+
+	var chains [][]*x509.Certificate
+	if len(certs) > 0 && (clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert) {
+		now := time.Now()
+		if config.Time != nil {
+			now = config.Time()
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		var err error
+		chains, err = certs[0].Verify(x509.VerifyOptions{
+			Roots:         config.ClientCAs,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return fmt.Errorf("tls: failed to verify certificate: %w", err)
+		}
+	}
+	if verifyPeer != nil {
+		rawCerts := make([][]byte, 0, len(certs))
+		for _, cert := range certs {
+			rawCerts = append(rawCerts, cert.Raw)
+		}
+		return verifyPeer(rawCerts, chains)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerAuthFailure(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler()) // Only used for its server certificate
+	ts.Close()
+
+	ca := newTestCertificate(t, nil, true, time.Now().Add(time.Hour))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.Leaf)
+
+	var authFailureTests = []struct {
+		Certificate *tls.Certificate
+		Reason      string
+	}{
+		{Certificate: newTestCertificate(t, ca, false, time.Now().Add(time.Hour)), Reason: ""},                      // 0
+		{Certificate: nil, Reason: ReasonNoCertificate},                                                             // 1
+		{Certificate: newTestCertificate(t, nil, false, time.Now().Add(time.Hour)), Reason: ReasonUnknownAuthority}, // 2
+		{Certificate: newTestCertificate(t, ca, false, time.Now().Add(-time.Hour)), Reason: ReasonExpired},          // 3
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failures := make(chan AuthFailure, 1)
+	server := NewServer(&Config{
+		Addr:    "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: &tls.Config{
+			Certificates: ts.TLS.Certificates,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		},
+		OnAuthFailure: func(failure AuthFailure) { failures <- failure },
+	})
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Start(ctx) }()
+	select {
+	case <-server.Listening():
+	case err := <-errCh:
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	server.lock.RLock()
+	addr := server.listener.Addr().String()
+	server.lock.RUnlock()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ts.Certificate())
+	for i, test := range authFailureTests {
+		config := &tls.Config{RootCAs: rootCAs}
+		if test.Certificate != nil {
+			config.Certificates = []tls.Certificate{*test.Certificate}
+		}
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := client.Get("https://" + addr)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if test.Reason == "" {
+			if err != nil {
+				t.Fatalf("Test %d: request failed: %v", i, err)
+			}
+			continue
+		}
+
+		select {
+		case failure := <-failures:
+			if failure.Reason != test.Reason {
+				t.Fatalf("Test %d: got reason '%s' - want '%s': %v", i, failure.Reason, test.Reason, failure.Err)
+			}
+			if test.Certificate != nil && failure.Identity.IsUnknown() {
+				t.Fatalf("Test %d: auth failure does not contain client identity", i)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Test %d: no auth failure reported", i)
+		}
+	}
+}
+
+// newTestCertificate returns a new certificate that expires at
+// notAfter. It is signed by the parent or self-signed if parent
+// is nil.
+func newTestCertificate(t *testing.T, parent *tls.Certificate, isCA bool, notAfter time.Time) *tls.Certificate {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             notAfter.Add(-24 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	issuer, signer := template, any(priv)
+	if parent != nil {
+		issuer, signer = parent.Leaf, parent.PrivateKey
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, issuer, &priv.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{raw},
+		PrivateKey:  priv,
+		Leaf:        cert,
+	}
+}
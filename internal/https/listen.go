@@ -0,0 +1,52 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvListeners is the env. variable that contains the
+// listening sockets a server process has inherited from
+// its parent process, e.g. during a binary upgrade.
+//
+// It contains a comma-separated list of <address>=<fd>
+// pairs. For example:
+//
+//	KES_LISTENERS=0.0.0.0:7373=3,127.0.0.1:7380=4
+const EnvListeners = "KES_LISTENERS"
+
+// inheritedListener returns the inherited listening socket
+// for the given address, if any. It returns nil if no
+// socket for the address has been inherited.
+func inheritedListener(addr string) (net.Listener, error) {
+	env := os.Getenv(EnvListeners)
+	if env == "" {
+		return nil, nil
+	}
+	for _, entry := range strings.Split(env, ",") {
+		i := strings.LastIndexByte(entry, '=')
+		if i < 0 || entry[:i] != addr {
+			continue
+		}
+		fd, err := strconv.Atoi(entry[i+1:])
+		if err != nil || fd < 3 {
+			return nil, fmt.Errorf("https: invalid inherited listener '%s'", entry)
+		}
+		f := os.NewFile(uintptr(fd), addr)
+		defer f.Close()
+
+		listener, err := net.FileListener(f)
+		if err != nil {
+			return nil, fmt.Errorf("https: invalid inherited listener '%s': %v", entry, err)
+		}
+		return listener, nil
+	}
+	return nil, nil
+}
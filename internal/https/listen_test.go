@@ -0,0 +1,53 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package https
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestInheritedListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	f, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get listener file: %v", err)
+	}
+	defer f.Close()
+
+	fd, err := syscall.Dup(int(f.Fd())) // inheritedListener takes ownership of fd
+	if err != nil {
+		t.Fatalf("Failed to duplicate file descriptor: %v", err)
+	}
+
+	addr := listener.Addr().String()
+	t.Setenv(EnvListeners, "127.0.0.1:1=1000,"+addr+"="+strconv.Itoa(fd))
+
+	inherited, err := inheritedListener(addr)
+	if err != nil {
+		t.Fatalf("Failed to inherit listener: %v", err)
+	}
+	if inherited == nil {
+		t.Fatalf("Listener for '%s' has not been inherited", addr)
+	}
+	defer inherited.Close()
+	if inherited.Addr().String() != addr {
+		t.Fatalf("Invalid listener address: got '%s' - want '%s'", inherited.Addr(), addr)
+	}
+
+	if inherited, err = inheritedListener("127.0.0.1:2"); inherited != nil || err != nil {
+		t.Fatalf("Listener for '127.0.0.1:2' has been inherited: %v", err)
+	}
+}
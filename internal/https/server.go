@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -29,11 +30,26 @@ type Config struct {
 	// See net.Dial for details of the address format.
 	Addr string
 
+	// Network is the network of the listener. It must be
+	// "tcp", "tcp4" or "tcp6". If empty, "tcp" is used.
+	//
+	// If the address is unspecified, e.g. "0.0.0.0" or "[::]",
+	// a "tcp" listener accepts IPv4 and IPv6 connections
+	// (dual-stack) while a "tcp4" listener only accepts IPv4
+	// and a "tcp6" listener only IPv6 connections.
+	Network string
+
 	// Handler handles incoming requests.
 	Handler http.Handler
 
 	// TLSConfig provides the TLS configuration.
+	// If nil, the server serves plaintext HTTP.
 	TLSConfig *tls.Config
+
+	// OnAuthFailure, if not nil, is called whenever a TLS
+	// handshake fails because the client certificate has
+	// been rejected, e.g. since it has expired.
+	OnAuthFailure func(AuthFailure)
 }
 
 // NewServer returns a new HTTPS server from
@@ -41,7 +57,11 @@ type Config struct {
 func NewServer(config *Config) *Server {
 	srv := &Server{
 		addr:      config.Addr,
-		tlsConfig: config.TLSConfig,
+		network:   network(config.Network),
+		tlsConfig: withALPN(config.TLSConfig),
+		listening: make(chan struct{}),
+
+		onAuthFailure: config.OnAuthFailure,
 	}
 
 	srv.handler = &muxHandler{
@@ -54,12 +74,21 @@ func NewServer(config *Config) *Server {
 // Server is a HTTPS server.
 type Server struct {
 	addr      string
+	network   string
 	handler   *muxHandler
 	tlsConfig *tls.Config
 
-	lock sync.RWMutex
+	onAuthFailure func(AuthFailure)
+
+	lock            sync.RWMutex
+	listener        net.Listener // The TCP listener. Set by Start
+	listening       chan struct{}
+	shutdownTimeout time.Duration
 }
 
+// Addr returns the Server's address.
+func (s *Server) Addr() string { return s.addr }
+
 // Update updates the Server's configuration or
 // returns a non-nil error explaining why the
 // server configuration couldn't be updated.
@@ -70,8 +99,12 @@ func (s *Server) Update(config *Config) error {
 	if config.Addr != s.addr {
 		return fmt.Errorf("https: failed to update server: '%s' does match existing server address", config.Addr)
 	}
+	if network(config.Network) != s.network {
+		return fmt.Errorf("https: failed to update server: '%s' does match existing server network", config.Network)
+	}
 
-	s.tlsConfig = config.TLSConfig.Clone()
+	s.tlsConfig = withALPN(config.TLSConfig)
+	s.onAuthFailure = config.OnAuthFailure
 	s.handler.Handler = config.Handler
 	if s.handler.Handler == nil {
 		s.handler.Handler = http.NewServeMux()
@@ -86,48 +119,139 @@ func (s *Server) UpdateTLS(config *tls.Config) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	s.tlsConfig = config.Clone()
+	s.tlsConfig = withALPN(config)
 	return nil
 }
 
+// withALPN returns a copy of the TLS config that offers HTTP/2
+// and HTTP/1.1 to clients via ALPN, unless the config specifies
+// its own protocols already. The TLS config of a connection
+// replaces the listener's config. Without application protocols,
+// clients would always fall back to HTTP/1.1.
+func withALPN(config *tls.Config) *tls.Config {
+	if config == nil {
+		return nil
+	}
+	config = config.Clone()
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{"h2", "http/1.1"} // Prefer HTTP/2 but also support HTTP/1.1
+	}
+	return config
+}
+
+// Listening returns a channel that is closed once
+// the Server listens for incoming connections.
+func (s *Server) Listening() <-chan struct{} { return s.listening }
+
+// ListenerFile returns a copy of the Server's listening
+// socket as file. The file can be passed to another process
+// that accepts connections on the same socket, e.g. a new
+// server process during a binary upgrade. Closing the file
+// does not affect the Server.
+//
+// It returns an error if the Server does not listen yet.
+func (s *Server) ListenerFile() (*os.File, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.listener == nil {
+		return nil, errors.New("https: server is not listening")
+	}
+	l, ok := s.listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, errors.New("https: listener does not support file descriptors")
+	}
+	return l.File()
+}
+
+// SetShutdownTimeout sets the max. amount of time the Server
+// waits for active connections to become idle when shutting
+// down. Afterwards, it closes all remaining connections. The
+// default shutdown timeout is 1 second.
+func (s *Server) SetShutdownTimeout(timeout time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.shutdownTimeout = timeout
+}
+
 // Start starts the HTTPS server by listening on the
 // Server's address.
 //
-// If the server address is empty, ":https" is used.
+// If the server address is empty, ":https" is used or,
+// for plaintext servers, ":http".
+//
+// If the process has inherited a listening socket for the
+// Server's address, via the EnvListeners env. variable, the
+// Server accepts connections on the inherited socket instead.
 //
 // Start blocks until the given ctx.Done() channel returns.
 // It always returns a non-nil error. Once ctx.Done()
 // returns, the Server gets closed and, if gracefully
 // shutdown, Start returns http.ErrServerClosed.
 func (s *Server) Start(ctx context.Context) error {
-	addr := s.addr
-	if addr == "" {
-		addr = ":https"
-	}
-	listener, err := tls.Listen("tcp", addr, &tls.Config{
-		MinVersion:       tls.VersionTLS12,
-		CipherSuites:     fips.TLSCiphers(),
-		CurvePreferences: fips.TLSCurveIDs(),
-
-		NextProtos: []string{"h2", "http/1.1"}, // Prefer HTTP/2 but also support HTTP/1.1
-		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
-			s.lock.RLock()
-			defer s.lock.RUnlock()
-			return s.tlsConfig, nil
-		},
-	})
+	s.lock.RLock()
+	addr, network, plaintext := s.addr, s.network, s.tlsConfig == nil
+	s.lock.RUnlock()
+
+	listener, err := inheritedListener(addr)
 	if err != nil {
 		return err
 	}
+	if listener == nil {
+		switch {
+		case addr != "":
+		case plaintext:
+			addr = ":http"
+		default:
+			addr = ":https"
+		}
+		if listener, err = net.Listen(network, listenAddr(addr)); err != nil {
+			return err
+		}
+	}
+	s.lock.Lock()
+	s.listener = listener
+	s.lock.Unlock()
+	close(s.listening)
+
+	if !plaintext {
+		listener = tls.NewListener(listener, &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CipherSuites:     fips.TLSCiphers(),
+			CurvePreferences: fips.TLSCurveIDs(),
+
+			NextProtos: []string{"h2", "http/1.1"}, // Prefer HTTP/2 but also support HTTP/1.1
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				s.lock.RLock()
+				config, onAuthFailure := s.tlsConfig, s.onAuthFailure
+				s.lock.RUnlock()
+
+				if onAuthFailure == nil || config.ClientAuth == tls.NoClientCert {
+					return config, nil
+				}
+				return reportAuthFailures(config, hello.Conn.RemoteAddr(), onAuthFailure), nil
+			},
+		})
+	}
+
+	// Connections and requests must not be canceled when ctx.Done()
+	// returns but only once the graceful shutdown has completed.
+	// Otherwise, active requests and TLS handshakes fail immediately.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 
 	srv := &http.Server{
 		Handler:           s.handler,
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      0 * time.Second, // explicitly set no write timeout - see timeout handler.
 		IdleTimeout:       90 * time.Second,
-		BaseContext:       func(net.Listener) context.Context { return ctx },
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
 		ErrorLog:          log.Default().Log(),
 	}
+	conns := newConnTracker()
+	srv.ConnState = conns.track
+
 	srvCh := make(chan error, 1)
 	go func() { srvCh <- srv.Serve(listener) }()
 
@@ -135,10 +259,27 @@ func (s *Server) Start(ctx context.Context) error {
 	case err := <-srvCh:
 		return err
 	case <-ctx.Done():
-		graceCtx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		s.lock.RLock()
+		timeout := s.shutdownTimeout
+		s.lock.RUnlock()
+		if timeout <= 0 {
+			timeout = 1 * time.Second
+		}
+
+		graceCtx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
+		// The http.Server drops requests of connections that have
+		// been accepted but not read before the shutdown started.
+		// Hence, we stop accepting connections first and wait until
+		// all accepted connections have sent their first request.
+		listener.Close()
+		conns.waitNew(graceCtx)
+
 		err := srv.Shutdown(graceCtx)
+		if errors.Is(err, net.ErrClosed) {
+			err = nil
+		}
 		if errors.Is(err, context.DeadlineExceeded) {
 			err = srv.Close()
 		}
@@ -161,3 +302,75 @@ func (m *muxHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	handler.ServeHTTP(w, req)
 }
+
+// connTracker tracks the connections of an http.Server
+// that have been accepted but not used to send a request
+// yet.
+type connTracker struct {
+	lock  sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{conns: map[net.Conn]struct{}{}}
+}
+
+// track updates the tracked connections. It can be
+// used as http.Server.ConnState hook.
+func (t *connTracker) track(conn net.Conn, state http.ConnState) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if state == http.StateNew {
+		t.conns[conn] = struct{}{}
+	} else {
+		delete(t.conns, conn)
+	}
+}
+
+// waitNew waits until no tracked connection is in
+// the http.StateNew state or until ctx.Done() returns.
+func (t *connTracker) waitNew(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		t.lock.Lock()
+		n := len(t.conns)
+		t.lock.Unlock()
+		if n == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// network returns the network of a listener. It
+// returns "tcp" if n is empty.
+func network(n string) string {
+	if n == "" {
+		return "tcp"
+	}
+	return n
+}
+
+// listenAddr returns the address a listener should listen
+// on. It removes an unspecified IP, like "0.0.0.0" or "::",
+// such that the listener listens on all IPv4 and/or IPv6
+// addresses, depending on its network. Otherwise, a "tcp6"
+// listener cannot listen on "0.0.0.0".
+func listenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return net.JoinHostPort("", port)
+	}
+	return addr
+}
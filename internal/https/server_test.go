@@ -0,0 +1,116 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var serverNetworkTests = []struct {
+	Network string
+	Addr    string
+	IPv4    bool
+	IPv6    bool
+}{
+	{Network: "", Addr: "0.0.0.0:0", IPv4: true, IPv6: true},      // 0
+	{Network: "tcp", Addr: "[::]:0", IPv4: true, IPv6: true},      // 1
+	{Network: "tcp4", Addr: "[::]:0", IPv4: true, IPv6: false},    // 2
+	{Network: "tcp6", Addr: "0.0.0.0:0", IPv4: false, IPv6: true}, // 3
+	{Network: "tcp6", Addr: ":0", IPv4: false, IPv6: true},        // 4
+	{Network: "tcp6", Addr: "[::1]:0", IPv4: false, IPv6: true},   // 5
+}
+
+func TestServerNetwork(t *testing.T) {
+	if l, err := net.Listen("tcp6", "[::1]:0"); err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	} else {
+		l.Close()
+	}
+
+	for i, test := range serverNetworkTests {
+		ctx, cancel := context.WithCancel(context.Background())
+		server := NewServer(&Config{
+			Addr:    test.Addr,
+			Network: test.Network,
+		})
+		errCh := make(chan error, 1)
+		go func() { errCh <- server.Start(ctx) }()
+
+		select {
+		case <-server.Listening():
+		case err := <-errCh:
+			cancel()
+			t.Fatalf("Test %d: failed to start server: %v", i, err)
+		}
+		server.lock.RLock()
+		_, port, _ := net.SplitHostPort(server.listener.Addr().String())
+		server.lock.RUnlock()
+
+		if ok := canDial("127.0.0.1:" + port); ok != test.IPv4 {
+			t.Errorf("Test %d: IPv4 connection: got '%v' - want '%v'", i, ok, test.IPv4)
+		}
+		if ok := canDial("[::1]:" + port); ok != test.IPv6 {
+			t.Errorf("Test %d: IPv6 connection: got '%v' - want '%v'", i, ok, test.IPv6)
+		}
+		cancel()
+		<-errCh
+	}
+}
+
+func TestServerHTTP2(t *testing.T) {
+	ts := httptest.NewTLSServer(http.NotFoundHandler()) // Only used for its certificate
+	ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := NewServer(&Config{
+		Addr:      "127.0.0.1:0",
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: &tls.Config{Certificates: ts.TLS.Certificates},
+	})
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Start(ctx) }()
+	select {
+	case <-server.Listening():
+	case err := <-errCh:
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	server.lock.RLock()
+	addr := server.listener.Addr().String()
+	server.lock.RUnlock()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ts.Certificate())
+	client := http.Client{
+		Transport: &http.Transport{
+			ForceAttemptHTTP2: true,
+			TLSClientConfig:   &tls.Config{RootCAs: rootCAs},
+		},
+	}
+	resp, err := client.Get("https://" + addr)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Invalid protocol: got '%s' - want HTTP/2", resp.Proto)
+	}
+}
+
+func canDial(addr string) bool {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
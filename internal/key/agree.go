@@ -0,0 +1,140 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+	"net/http"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/fips"
+	"golang.org/x/crypto/curve25519"
+)
+
+// Key agreement curves.
+const (
+	X25519 = "X25519"
+	P256   = "P-256"
+)
+
+// ErrInvalidPublicKey is returned when a key agreement
+// public key is not a valid point on the curve.
+var ErrInvalidPublicKey = kes.NewError(http.StatusBadRequest, "invalid public key")
+
+// DefaultCurve returns the default key agreement curve.
+// It is P-256 in FIPS mode and X25519 otherwise.
+func DefaultCurve() string {
+	if fips.Enabled {
+		return P256
+	}
+	return X25519
+}
+
+// AgreementPublicKey returns the public key of the static
+// key agreement key pair for the given curve. The private
+// key is derived from the key material. Hence, the same key
+// always has the same public key for a curve.
+//
+// X25519 public keys are 32 bytes long. P-256 public keys
+// are uncompressed points of 65 bytes.
+//
+// It returns ErrDisabled if the key is disabled.
+func (k *Key) AgreementPublicKey(curve string) ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external != nil {
+		return nil, ErrExternal
+	}
+	switch curve {
+	case X25519:
+		if fips.Enabled {
+			return nil, errUnsupportedCurve(curve)
+		}
+		return curve25519.X25519(k.x25519PrivateKey(), curve25519.Basepoint)
+	case P256:
+		x, y := elliptic.P256().ScalarBaseMult(k.p256PrivateKey())
+		return elliptic.Marshal(elliptic.P256(), x, y), nil
+	default:
+		return nil, errUnsupportedCurve(curve)
+	}
+}
+
+// Agree performs an (EC)DH key agreement between the static
+// key agreement key pair for the curve and the peer's public
+// key. It returns the raw shared secret that should be passed
+// through a key derivation function, like HKDF, before use.
+//
+// It returns ErrInvalidPublicKey if the peer's public key
+// is not a valid point on the curve and ErrDisabled if the
+// key is disabled.
+func (k *Key) Agree(curve string, publicKey []byte) ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external != nil {
+		return nil, ErrExternal
+	}
+	switch curve {
+	case X25519:
+		if fips.Enabled {
+			return nil, errUnsupportedCurve(curve)
+		}
+		if len(publicKey) != curve25519.PointSize {
+			return nil, ErrInvalidPublicKey
+		}
+		secret, err := curve25519.X25519(k.x25519PrivateKey(), publicKey)
+		if err != nil { // Low-order points produce an all-zero secret
+			return nil, ErrInvalidPublicKey
+		}
+		return secret, nil
+	case P256:
+		c := elliptic.P256()
+		x, y := elliptic.Unmarshal(c, publicKey) // Verifies that the point is on the curve
+		if x == nil {
+			return nil, ErrInvalidPublicKey
+		}
+		x, _ = c.ScalarMult(x, y, k.p256PrivateKey())
+
+		secret := make([]byte, (c.Params().BitSize+7)/8)
+		return x.FillBytes(secret), nil
+	default:
+		return nil, errUnsupportedCurve(curve)
+	}
+}
+
+// x25519PrivateKey derives the X25519 private key
+// from the key material.
+func (k *Key) x25519PrivateKey() []byte {
+	mac := hmac.New(sha256.New, k.bytes)
+	mac.Write([]byte("KES key agreement key"))
+	mac.Write([]byte(X25519))
+	return mac.Sum(nil)
+}
+
+// p256PrivateKey derives the P-256 private scalar from the
+// key material. It rejects candidates that are not within
+// [1, N-1] and derives the next one, like RFC 6979 does.
+func (k *Key) p256PrivateKey() []byte {
+	N := elliptic.P256().Params().N
+	for counter := byte(0); ; counter++ {
+		mac := hmac.New(sha256.New, k.bytes)
+		mac.Write([]byte("KES key agreement key"))
+		mac.Write([]byte(P256))
+		mac.Write([]byte{counter})
+		candidate := mac.Sum(nil)
+
+		if d := new(big.Int).SetBytes(candidate); d.Sign() > 0 && d.Cmp(N) < 0 {
+			return candidate
+		}
+	}
+}
+
+func errUnsupportedCurve(curve string) error {
+	return kes.NewError(http.StatusBadRequest, "unsupported curve '"+curve+"'")
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestKeyAgreeX25519(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	publicKey, err := key.AgreementPublicKey(X25519)
+	if err != nil {
+		t.Fatalf("Failed to compute public key: %v", err)
+	}
+
+	peerPrivateKey := make([]byte, curve25519.ScalarSize)
+	if _, err = rand.Read(peerPrivateKey); err != nil {
+		t.Fatalf("Failed to generate peer private key: %v", err)
+	}
+	peerPublicKey, err := curve25519.X25519(peerPrivateKey, curve25519.Basepoint)
+	if err != nil {
+		t.Fatalf("Failed to compute peer public key: %v", err)
+	}
+
+	secret, err := key.Agree(X25519, peerPublicKey)
+	if err != nil {
+		t.Fatalf("Failed to perform key agreement: %v", err)
+	}
+	peerSecret, err := curve25519.X25519(peerPrivateKey, publicKey)
+	if err != nil {
+		t.Fatalf("Failed to perform peer key agreement: %v", err)
+	}
+	if !bytes.Equal(secret, peerSecret) {
+		t.Fatalf("Shared secrets do not match: got '%x' - want '%x'", secret, peerSecret)
+	}
+
+	if _, err = key.Agree(X25519, make([]byte, curve25519.PointSize)); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("Agreement with low-order point: got '%v' - want '%v'", err, ErrInvalidPublicKey)
+	}
+	if _, err = key.Agree(X25519, peerPublicKey[:16]); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("Agreement with truncated public key: got '%v' - want '%v'", err, ErrInvalidPublicKey)
+	}
+}
+
+func TestKeyAgreeP256(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	publicKey, err := key.AgreementPublicKey(P256)
+	if err != nil {
+		t.Fatalf("Failed to compute public key: %v", err)
+	}
+
+	curve := elliptic.P256()
+	peerPrivateKey, x, y, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate peer key: %v", err)
+	}
+	secret, err := key.Agree(P256, elliptic.Marshal(curve, x, y))
+	if err != nil {
+		t.Fatalf("Failed to perform key agreement: %v", err)
+	}
+
+	x, y = elliptic.Unmarshal(curve, publicKey)
+	if x == nil {
+		t.Fatalf("Invalid public key: '%x'", publicKey)
+	}
+	x, _ = curve.ScalarMult(x, y, peerPrivateKey)
+	if peerSecret := x.FillBytes(make([]byte, 32)); !bytes.Equal(secret, peerSecret) {
+		t.Fatalf("Shared secrets do not match: got '%x' - want '%x'", secret, peerSecret)
+	}
+
+	invalid := append([]byte{}, publicKey...)
+	invalid[len(invalid)-1] ^= 1 // Not on the curve
+	if _, err = key.Agree(P256, invalid); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("Agreement with invalid point: got '%v' - want '%v'", err, ErrInvalidPublicKey)
+	}
+}
+
+func TestKeyAgreementPublicKey(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	a, err := key.AgreementPublicKey(X25519)
+	if err != nil {
+		t.Fatalf("Failed to compute public key: %v", err)
+	}
+	b, err := key.AgreementPublicKey(X25519)
+	if err != nil {
+		t.Fatalf("Failed to compute public key: %v", err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatalf("Public keys differ: '%x' and '%x'", a, b)
+	}
+	if _, err = key.AgreementPublicKey("P-384"); err == nil {
+		t.Fatal("Computing public key for unsupported curve succeeded")
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/cpu"
+	"github.com/minio/kes/internal/fips"
+)
+
+// AES256_GCM_SIV is the AES-256-GCM-SIV algorithm specified in
+// RFC 8452. In contrast to AES-256-GCM, it is resistant against
+// nonce reuse. The kes.KeyAlgorithm enum does not define it.
+// Hence, use AlgorithmName and ParseAlgorithm instead of its
+// String, MarshalText and UnmarshalText methods.
+const AES256_GCM_SIV kes.KeyAlgorithm = kes.XCHACHA20_POLY1305 + 1
+
+// Text representations of the key algorithms.
+const (
+	nameAES256GCMSHA256   = "AES256-GCM_SHA256"
+	nameXChaCha20Poly1305 = "XCHACHA20-POLY1305"
+	nameAES256GCMSIV      = "AES256-GCM-SIV"
+)
+
+// DefaultAlgorithm returns the algorithm of new keys
+// if none is specified. It is AES256_GCM_SHA256 in FIPS
+// mode or if the CPU provides AES-GCM instructions and
+// XCHACHA20_POLY1305 otherwise.
+func DefaultAlgorithm() kes.KeyAlgorithm {
+	if fips.Enabled || cpu.HasAESGCM() {
+		return kes.AES256_GCM_SHA256
+	}
+	return kes.XCHACHA20_POLY1305
+}
+
+// AlgorithmName returns the text representation of the algorithm.
+// Like kes.KeyAlgorithm.MarshalText, it returns an empty string
+// for kes.KeyAlgorithmUndefined.
+func AlgorithmName(a kes.KeyAlgorithm) string {
+	switch a {
+	case kes.KeyAlgorithmUndefined:
+		return ""
+	case kes.AES256_GCM_SHA256:
+		return nameAES256GCMSHA256
+	case kes.XCHACHA20_POLY1305:
+		return nameXChaCha20Poly1305
+	case AES256_GCM_SIV:
+		return nameAES256GCMSIV
+	default:
+		return a.String()
+	}
+}
+
+// ParseAlgorithm parses s as text representation of an algorithm.
+// It accepts all text representations returned by AlgorithmName.
+func ParseAlgorithm(s string) (kes.KeyAlgorithm, error) {
+	switch s {
+	case "", "undefined":
+		return kes.KeyAlgorithmUndefined, nil
+	case nameAES256GCMSHA256:
+		return kes.AES256_GCM_SHA256, nil
+	case nameXChaCha20Poly1305:
+		return kes.XCHACHA20_POLY1305, nil
+	case nameAES256GCMSIV:
+		return AES256_GCM_SIV, nil
+	default:
+		return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "invalid algorithm '"+s+"'")
+	}
+}
+
+// Supported reports whether keys for the algorithm can be
+// created. In FIPS mode, only AES256_GCM_SHA256 is supported.
+func Supported(a kes.KeyAlgorithm) bool {
+	switch a {
+	case kes.AES256_GCM_SHA256:
+		return true
+	case kes.XCHACHA20_POLY1305, AES256_GCM_SIV:
+		return !fips.Enabled
+	default:
+		return false
+	}
+}
+
+// An AlgorithmPolicy restricts the algorithms of new keys.
+// The zero value allows all supported algorithms.
+type AlgorithmPolicy struct {
+	// Default is the algorithm of new keys created without
+	// an explicit algorithm. If undefined, DefaultAlgorithm
+	// applies if the policy allows it.
+	Default kes.KeyAlgorithm
+
+	// Algorithms are the algorithms of new keys allowed by
+	// the policy. If empty, all algorithms are allowed.
+	Algorithms []kes.KeyAlgorithm
+
+	// MinKeySize is the min. key size in bits.
+	MinKeySize int
+}
+
+// IsZero reports whether the policy does not restrict
+// any algorithm.
+func (p *AlgorithmPolicy) IsZero() bool {
+	return p == nil || (p.Default == kes.KeyAlgorithmUndefined && len(p.Algorithms) == 0 && p.MinKeySize == 0)
+}
+
+// Verify returns an error if the policy does not allow
+// new keys for the given algorithm. A nil policy allows
+// all algorithms.
+func (p *AlgorithmPolicy) Verify(a kes.KeyAlgorithm) error {
+	if p == nil {
+		return nil
+	}
+	if len(p.Algorithms) > 0 {
+		var allowed bool
+		for _, algorithm := range p.Algorithms {
+			if algorithm == a {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			names := make([]string, 0, len(p.Algorithms))
+			for _, algorithm := range p.Algorithms {
+				names = append(names, AlgorithmName(algorithm))
+			}
+			return kes.NewError(http.StatusBadRequest, fmt.Sprintf("algorithm '%s' violates the key policy: allowed algorithms are %s", algorithmText(a), strings.Join(names, ", ")))
+		}
+	}
+	if size := Len(a) * 8; size < p.MinKeySize {
+		return kes.NewError(http.StatusBadRequest, fmt.Sprintf("algorithm '%s' violates the key policy: key size of %d bits is less than %d bits", algorithmText(a), size, p.MinKeySize))
+	}
+	return nil
+}
+
+// Validate returns an error if the policy is invalid, e.g.
+// if its default algorithm is not allowed by the policy
+// itself or unsupported.
+func (p *AlgorithmPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MinKeySize < 0 {
+		return kes.NewError(http.StatusBadRequest, "invalid key policy: min. key size is negative")
+	}
+	for _, a := range p.Algorithms {
+		if !Supported(a) {
+			return kes.NewError(http.StatusBadRequest, "invalid key policy: unsupported algorithm '"+algorithmText(a)+"'")
+		}
+	}
+	if p.Default != kes.KeyAlgorithmUndefined {
+		if !Supported(p.Default) {
+			return kes.NewError(http.StatusBadRequest, "invalid key policy: unsupported algorithm '"+algorithmText(p.Default)+"'")
+		}
+		if err := p.Verify(p.Default); err != nil {
+			return kes.NewError(http.StatusBadRequest, "invalid key policy: default algorithm is not allowed")
+		}
+	}
+	return nil
+}
+
+// MarshalJSON returns the policy's JSON representation.
+func (p AlgorithmPolicy) MarshalJSON() ([]byte, error) {
+	type JSON struct {
+		Default    string   `json:"default_algorithm,omitempty"`
+		Algorithms []string `json:"algorithms,omitempty"`
+		MinKeySize int      `json:"min_key_size,omitempty"`
+	}
+	var algorithms []string
+	for _, a := range p.Algorithms {
+		algorithms = append(algorithms, AlgorithmName(a))
+	}
+	return json.Marshal(JSON{
+		Default:    AlgorithmName(p.Default),
+		Algorithms: algorithms,
+		MinKeySize: p.MinKeySize,
+	})
+}
+
+// UnmarshalJSON parses the policy's JSON representation.
+func (p *AlgorithmPolicy) UnmarshalJSON(b []byte) error {
+	type JSON struct {
+		Default    string   `json:"default_algorithm"`
+		Algorithms []string `json:"algorithms"`
+		MinKeySize int      `json:"min_key_size"`
+	}
+	var value JSON
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	policy, err := ParseAlgorithmPolicy(value.Default, value.Algorithms, value.MinKeySize)
+	if err != nil {
+		return err
+	}
+	*p = *policy
+	return nil
+}
+
+// ParseAlgorithmPolicy parses the text representations of
+// the default and allowed algorithms and returns a new
+// AlgorithmPolicy. It does not validate the policy.
+func ParseAlgorithmPolicy(defaultAlgorithm string, algorithms []string, minKeySize int) (*AlgorithmPolicy, error) {
+	policy := &AlgorithmPolicy{MinKeySize: minKeySize}
+
+	var err error
+	if policy.Default, err = ParseAlgorithm(defaultAlgorithm); err != nil {
+		return nil, err
+	}
+	for _, s := range algorithms {
+		a, err := ParseAlgorithm(s)
+		if err != nil {
+			return nil, err
+		}
+		if a == kes.KeyAlgorithmUndefined {
+			return nil, kes.NewError(http.StatusBadRequest, "invalid algorithm '"+s+"'")
+		}
+		policy.Algorithms = append(policy.Algorithms, a)
+	}
+	return policy, nil
+}
+
+// SelectAlgorithm returns the algorithm of a new key that
+// complies with all policies. If a is undefined, it selects
+// the default algorithm of the first policy that has one,
+// or otherwise the first supported algorithm, preferring
+// DefaultAlgorithm, that all policies allow.
+//
+// It returns an error if a is not supported or any policy
+// does not allow it.
+func SelectAlgorithm(a kes.KeyAlgorithm, policies ...*AlgorithmPolicy) (kes.KeyAlgorithm, error) {
+	if a == kes.KeyAlgorithmUndefined {
+		for _, policy := range policies {
+			if policy != nil && policy.Default != kes.KeyAlgorithmUndefined {
+				a = policy.Default
+				break
+			}
+		}
+	}
+	if a == kes.KeyAlgorithmUndefined {
+		candidates := []kes.KeyAlgorithm{DefaultAlgorithm(), kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305, AES256_GCM_SIV}
+		for _, candidate := range candidates {
+			if Supported(candidate) && verifyAll(candidate, policies) == nil {
+				return candidate, nil
+			}
+		}
+		return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "the key policy does not allow any supported algorithm")
+	}
+
+	if !Supported(a) {
+		return kes.KeyAlgorithmUndefined, kes.NewError(http.StatusBadRequest, "unsupported algorithm '"+algorithmText(a)+"'")
+	}
+	if err := verifyAll(a, policies); err != nil {
+		return kes.KeyAlgorithmUndefined, err
+	}
+	return a, nil
+}
+
+func verifyAll(a kes.KeyAlgorithm, policies []*AlgorithmPolicy) error {
+	for _, policy := range policies {
+		if err := policy.Verify(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// algorithmText returns the text representation of a
+// for error messages. In contrast to AlgorithmName, it
+// is never empty.
+func algorithmText(a kes.KeyAlgorithm) string {
+	if a == kes.KeyAlgorithmUndefined {
+		return "undefined"
+	}
+	return AlgorithmName(a)
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/fips"
+)
+
+var selectAlgorithmTests = []struct {
+	Algorithm kes.KeyAlgorithm
+	Policies  []*AlgorithmPolicy
+	Result    kes.KeyAlgorithm
+
+	ShouldFail bool
+}{
+	{ // 0
+		Algorithm: kes.KeyAlgorithmUndefined,
+		Result:    DefaultAlgorithm(),
+	},
+	{ // 1
+		Algorithm: AES256_GCM_SIV,
+		Policies:  []*AlgorithmPolicy{nil, {}},
+		Result:    AES256_GCM_SIV,
+	},
+	{ // 2
+		Algorithm: kes.KeyAlgorithmUndefined,
+		Policies:  []*AlgorithmPolicy{{Algorithms: []kes.KeyAlgorithm{AES256_GCM_SIV}}},
+		Result:    AES256_GCM_SIV,
+	},
+	{ // 3
+		Algorithm: kes.KeyAlgorithmUndefined,
+		Policies: []*AlgorithmPolicy{
+			nil,
+			{Default: kes.XCHACHA20_POLY1305},
+			{Default: AES256_GCM_SIV},
+		},
+		Result: kes.XCHACHA20_POLY1305,
+	},
+	{ // 4
+		Algorithm:  kes.XCHACHA20_POLY1305,
+		Policies:   []*AlgorithmPolicy{{Algorithms: []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, AES256_GCM_SIV}}},
+		ShouldFail: true,
+	},
+	{ // 5
+		Algorithm: kes.AES256_GCM_SHA256,
+		Policies: []*AlgorithmPolicy{
+			{Algorithms: []kes.KeyAlgorithm{kes.AES256_GCM_SHA256}},
+			{Algorithms: []kes.KeyAlgorithm{AES256_GCM_SIV}},
+		},
+		ShouldFail: true,
+	},
+	{ // 6
+		Algorithm: kes.KeyAlgorithmUndefined,
+		Policies: []*AlgorithmPolicy{
+			{Algorithms: []kes.KeyAlgorithm{kes.AES256_GCM_SHA256}},
+			{Algorithms: []kes.KeyAlgorithm{AES256_GCM_SIV}},
+		},
+		ShouldFail: true,
+	},
+	{ // 7
+		Algorithm:  kes.AES256_GCM_SHA256,
+		Policies:   []*AlgorithmPolicy{{MinKeySize: 512}},
+		ShouldFail: true,
+	},
+	{ // 8
+		Algorithm:  AES256_GCM_SIV + 1,
+		ShouldFail: true,
+	},
+}
+
+func TestSelectAlgorithm(t *testing.T) {
+	if fips.Enabled {
+		t.Skip("Skipping test: only AES256-GCM_SHA256 is supported in FIPS mode")
+	}
+	for i, test := range selectAlgorithmTests {
+		a, err := SelectAlgorithm(test.Algorithm, test.Policies...)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to select algorithm: %v", i, err)
+		}
+		if !test.ShouldFail && a != test.Result {
+			t.Fatalf("Test %d: invalid algorithm: got '%s' - want '%s'", i, AlgorithmName(a), AlgorithmName(test.Result))
+		}
+	}
+}
+
+var algorithmPolicyJSONTests = []struct {
+	JSON   string
+	Policy AlgorithmPolicy
+
+	ShouldFail bool
+}{
+	{ // 0
+		JSON:   `{}`,
+		Policy: AlgorithmPolicy{},
+	},
+	{ // 1
+		JSON: `{"default_algorithm":"AES256-GCM-SIV","algorithms":["AES256-GCM_SHA256","AES256-GCM-SIV"],"min_key_size":256}`,
+		Policy: AlgorithmPolicy{
+			Default:    AES256_GCM_SIV,
+			Algorithms: []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, AES256_GCM_SIV},
+			MinKeySize: 256,
+		},
+	},
+	{ // 2
+		JSON:       `{"algorithms":["AES128-GCM"]}`,
+		ShouldFail: true,
+	},
+	{ // 3
+		JSON:       `{"algorithms":[""]}`,
+		ShouldFail: true,
+	},
+}
+
+func TestAlgorithmPolicyJSON(t *testing.T) {
+	for i, test := range algorithmPolicyJSONTests {
+		var policy AlgorithmPolicy
+		err := json.Unmarshal([]byte(test.JSON), &policy)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to unmarshal policy: %v", i, err)
+		}
+		if test.ShouldFail {
+			continue
+		}
+		if policy.Default != test.Policy.Default || policy.MinKeySize != test.Policy.MinKeySize || len(policy.Algorithms) != len(test.Policy.Algorithms) {
+			t.Fatalf("Test %d: invalid policy: got '%+v' - want '%+v'", i, policy, test.Policy)
+		}
+		for j := range policy.Algorithms {
+			if policy.Algorithms[j] != test.Policy.Algorithms[j] {
+				t.Fatalf("Test %d: invalid policy: got '%+v' - want '%+v'", i, policy, test.Policy)
+			}
+		}
+
+		b, err := json.Marshal(policy)
+		if err != nil {
+			t.Fatalf("Test %d: failed to marshal policy: %v", i, err)
+		}
+		if string(b) != test.JSON {
+			t.Fatalf("Test %d: invalid JSON: got '%s' - want '%s'", i, b, test.JSON)
+		}
+	}
+}
+
+var validateAlgorithmPolicyTests = []struct {
+	Policy     *AlgorithmPolicy
+	ShouldFail bool
+}{
+	{Policy: nil},                // 0
+	{Policy: &AlgorithmPolicy{}}, // 1
+	{ // 2
+		Policy: &AlgorithmPolicy{Default: AES256_GCM_SIV, Algorithms: []kes.KeyAlgorithm{AES256_GCM_SIV}},
+	},
+	{ // 3
+		Policy:     &AlgorithmPolicy{Default: kes.XCHACHA20_POLY1305, Algorithms: []kes.KeyAlgorithm{AES256_GCM_SIV}},
+		ShouldFail: true,
+	},
+	{ // 4
+		Policy:     &AlgorithmPolicy{Default: kes.AES256_GCM_SHA256, MinKeySize: 512},
+		ShouldFail: true,
+	},
+	{ // 5
+		Policy:     &AlgorithmPolicy{MinKeySize: -1},
+		ShouldFail: true,
+	},
+	{ // 6
+		Policy:     &AlgorithmPolicy{Algorithms: []kes.KeyAlgorithm{AES256_GCM_SIV + 1}},
+		ShouldFail: true,
+	},
+}
+
+func TestAlgorithmPolicyValidate(t *testing.T) {
+	if fips.Enabled {
+		t.Skip("Skipping test: only AES256-GCM_SHA256 is supported in FIPS mode")
+	}
+	for i, test := range validateAlgorithmPolicyTests {
+		err := test.Policy.Validate()
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to validate policy: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// BreakerConfig is a structure containing
+// Breaker configuration options.
+type BreakerConfig struct {
+	// Threshold is the number of consecutive failed
+	// operations after which the Breaker opens.
+	// If <= 0, defaults to 5.
+	Threshold int
+
+	// Cooldown is the time period the Breaker remains
+	// open before it probes whether the underlying
+	// store has recovered. If <= 0, defaults to 30s.
+	Cooldown time.Duration
+}
+
+// Breaker is a circuit breaker around a kv.Store.
+//
+// A Breaker forwards all operations to the underlying
+// store as long as it is closed. Once Threshold consecutive
+// operations have failed, the Breaker opens and rejects all
+// operations immediately with a 503 Service Unavailable
+// error. Hence, requests do not pile up while the store is
+// not responding.
+//
+// After the Cooldown period, the Breaker forwards a single
+// operation to probe whether the store has recovered. If the
+// probe succeeds, the Breaker closes again. Otherwise, it
+// remains open for another Cooldown period.
+//
+// Status requests are always forwarded to the underlying
+// store and do not affect the Breaker.
+type Breaker struct {
+	conn      kv.Store[string, []byte]
+	threshold int
+	cooldown  time.Duration
+
+	lock      sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerProbing
+)
+
+// NewBreaker returns a new Breaker around the
+// given store.
+func NewBreaker(conn kv.Store[string, []byte], config *BreakerConfig) *Breaker {
+	b := &Breaker{
+		conn:      conn,
+		threshold: 5,
+		cooldown:  30 * time.Second,
+	}
+	if config != nil && config.Threshold > 0 {
+		b.threshold = config.Threshold
+	}
+	if config != nil && config.Cooldown > 0 {
+		b.cooldown = config.Cooldown
+	}
+	return b
+}
+
+var _ kv.Store[string, []byte] = (*Breaker)(nil) // compiler check
+
+// Status returns the current state of the underlying store.
+func (b *Breaker) Status(ctx context.Context) (kv.State, error) {
+	return b.conn.Status(ctx)
+}
+
+// Create creates a new entry at the underlying store
+// if the Breaker is closed.
+func (b *Breaker) Create(ctx context.Context, name string, value []byte) error {
+	probe, err := b.acquire()
+	if err != nil {
+		return err
+	}
+	err = b.conn.Create(ctx, name, value)
+	b.release(probe, err)
+	return err
+}
+
+// Set writes the entry to the underlying store
+// if the Breaker is closed.
+func (b *Breaker) Set(ctx context.Context, name string, value []byte) error {
+	probe, err := b.acquire()
+	if err != nil {
+		return err
+	}
+	err = b.conn.Set(ctx, name, value)
+	b.release(probe, err)
+	return err
+}
+
+// Get returns the value associated with the given name
+// from the underlying store if the Breaker is closed.
+func (b *Breaker) Get(ctx context.Context, name string) ([]byte, error) {
+	probe, err := b.acquire()
+	if err != nil {
+		return nil, err
+	}
+	value, err := b.conn.Get(ctx, name)
+	b.release(probe, err)
+	return value, err
+}
+
+// Delete deletes the entry from the underlying store
+// if the Breaker is closed.
+func (b *Breaker) Delete(ctx context.Context, name string) error {
+	probe, err := b.acquire()
+	if err != nil {
+		return err
+	}
+	err = b.conn.Delete(ctx, name)
+	b.release(probe, err)
+	return err
+}
+
+// List returns an iterator over the entries of the
+// underlying store if the Breaker is closed.
+func (b *Breaker) List(ctx context.Context) (kv.Iter[string], error) {
+	probe, err := b.acquire()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := b.conn.List(ctx)
+	b.release(probe, err)
+	return iter, err
+}
+
+// ListPrefix returns an iterator over the entries of the
+// underlying store whose names start with the prefix if
+// the Breaker is closed.
+func (b *Breaker) ListPrefix(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	probe, err := b.acquire()
+	if err != nil {
+		return nil, err
+	}
+	iter, err := kv.ListPrefix(ctx, b.conn, prefix)
+	b.release(probe, err)
+	return iter, err
+}
+
+// acquire returns an error if the Breaker is open. It reports
+// whether the operation is the probe of a Breaker whose cooldown
+// period has passed.
+func (b *Breaker) acquire() (probe bool, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now := time.Now(); now.Before(b.openUntil) {
+			return false, &breakerOpenError{retryAfter: b.openUntil.Sub(now)}
+		}
+		b.state = breakerProbing
+		return true, nil
+	case breakerProbing:
+		return false, &breakerOpenError{retryAfter: time.Second}
+	default:
+		return false, nil
+	}
+}
+
+// release records the outcome of an operation.
+func (b *Breaker) release(probe bool, err error) {
+	failed := isBackendFailure(err)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch {
+	case probe && b.state == breakerProbing:
+		if failed {
+			b.state, b.openUntil = breakerOpen, time.Now().Add(b.cooldown)
+			return
+		}
+		b.state, b.failures = breakerClosed, 0
+		log.Print("key: keystore has recovered: closing circuit breaker")
+	case !probe && b.state == breakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		if b.failures++; b.failures >= b.threshold {
+			b.state, b.openUntil = breakerOpen, time.Now().Add(b.cooldown)
+			log.Printf("key: keystore failed %d consecutive times: opening circuit breaker for %v: %v", b.failures, b.cooldown, err)
+		}
+	}
+}
+
+// isBackendFailure reports whether err indicates that the
+// store failed to process an operation. Errors caused by
+// the operation itself, like a non-existing key, or by a
+// canceled request are not failures of the store.
+func isBackendFailure(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, kes.ErrKeyNotFound), errors.Is(err, kes.ErrKeyExists):
+		return false
+	case errors.Is(err, kv.ErrNotExists), errors.Is(err, kv.ErrExists):
+		return false
+	case errors.Is(err, context.Canceled):
+		return false
+	default:
+		return true
+	}
+}
+
+// breakerOpenError is returned by a Breaker
+// that rejects an operation.
+type breakerOpenError struct {
+	retryAfter time.Duration
+}
+
+// isBreakerOpen reports whether err has been
+// returned by an open Breaker.
+func isBreakerOpen(err error) bool {
+	var e *breakerOpenError
+	return errors.As(err, &e)
+}
+
+func (e *breakerOpenError) Error() string {
+	return "service unavailable: keystore is not available"
+}
+
+// Status returns the HTTP 503 status code.
+func (e *breakerOpenError) Status() int { return http.StatusServiceUnavailable }
+
+// Header returns the Retry-After header with the
+// number of seconds until the Breaker probes the
+// underlying store again.
+func (e *breakerOpenError) Header() http.Header {
+	seconds := int((e.retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return http.Header{"Retry-After": []string{strconv.Itoa(seconds)}}
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestBreaker(t *testing.T) {
+	const (
+		Threshold = 3
+		Cooldown  = 50 * time.Millisecond
+	)
+	ctx := context.Background()
+	store := &failingStore{Store: &mem.Store{}}
+	breaker := NewBreaker(store, &BreakerConfig{
+		Threshold: Threshold,
+		Cooldown:  Cooldown,
+	})
+
+	if _, err := breaker.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Failed to get key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	store.fail = true
+	for i := 0; i < Threshold; i++ {
+		if _, err := breaker.Get(ctx, "my-key"); !errors.Is(err, errStoreFailure) {
+			t.Fatalf("Test %d: got error '%v' - want '%v'", i, err, errStoreFailure)
+		}
+	}
+
+	_, err := breaker.Get(ctx, "my-key")
+	if !isBreakerOpen(err) {
+		t.Fatalf("Breaker is closed: got error '%v'", err)
+	}
+	if s, ok := err.(interface{ Status() int }); !ok || s.Status() != http.StatusServiceUnavailable {
+		t.Fatalf("Breaker error does not have status code %d", http.StatusServiceUnavailable)
+	}
+	if h := err.(interface{ Header() http.Header }).Header().Get("Retry-After"); h != "1" {
+		t.Fatalf("Invalid Retry-After header: got '%s' - want '1'", h)
+	}
+
+	time.Sleep(Cooldown)
+	if _, err = breaker.Get(ctx, "my-key"); !errors.Is(err, errStoreFailure) { // Failed probe
+		t.Fatalf("Breaker did not probe the store: got error '%v'", err)
+	}
+	if _, err = breaker.Get(ctx, "my-key"); !isBreakerOpen(err) {
+		t.Fatalf("Breaker is closed after failed probe: got error '%v'", err)
+	}
+
+	store.fail = false
+	time.Sleep(Cooldown)
+	if _, err = breaker.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) { // Successful probe
+		t.Fatalf("Breaker did not probe the store: got error '%v'", err)
+	}
+	if err = breaker.Create(ctx, "my-key", []byte("value")); err != nil {
+		t.Fatalf("Breaker is open after successful probe: got error '%v'", err)
+	}
+}
+
+var errStoreFailure = errors.New("store failure")
+
+type failingStore struct {
+	*mem.Store
+
+	fail bool
+}
+
+func (s *failingStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if s.fail {
+		return nil, errStoreFailure
+	}
+	return s.Store.Get(ctx, name)
+}
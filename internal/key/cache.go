@@ -7,12 +7,16 @@ package key
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"path"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/singleflight"
 	"github.com/minio/kes/kv"
 )
 
@@ -71,6 +75,7 @@ func NewCache(store Store, config *CacheConfig) *Cache {
 	c.gcUnused(config.ExpiryUnused)
 
 	if config.ExpiryOffline > 0 {
+		c.offline = true
 		c.gcOffline(config.ExpiryOffline)
 		c.watchOfflineStatus(10 * time.Second)
 	}
@@ -80,6 +85,11 @@ func NewCache(store Store, config *CacheConfig) *Cache {
 // Cache is a Store that caches keys from an underlying
 // Store in memory.
 type Cache struct {
+	// Number of Get calls served from and missing
+	// the cache. Must be 64-bit aligned for atomic
+	// access on 32-bit platforms.
+	hits, misses uint64
+
 	Store Store
 
 	lock         sync.RWMutex
@@ -94,6 +104,12 @@ type Cache struct {
 	// status.
 	// By default, not in use
 	useOfflineCache uint32
+	offline         bool // Whether the offline cache is enabled
+
+	// Deduplicates concurrent Store fetches for the same
+	// key such that a cold cache does not turn many
+	// requests for one key into many identical reads.
+	fetches singleflight.Group[string, Key]
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -121,6 +137,8 @@ func (c *Cache) Create(ctx context.Context, name string, key Key) error {
 		return nil
 	case errors.Is(err, kes.ErrKeyExists):
 		return kes.ErrKeyExists
+	case isBreakerOpen(err):
+		return err
 	default:
 		return errCreateKey
 	}
@@ -130,18 +148,130 @@ func (c *Cache) Create(ctx context.Context, name string, key Key) error {
 // If noc such entry exists, Get returns kes.ErrKeyNotFound.
 func (c *Cache) Get(ctx context.Context, name string) (Key, error) {
 	if key, ok := c.lookup(c.cache, name); ok {
+		atomic.AddUint64(&c.hits, 1)
 		return key, nil
 	}
+	atomic.AddUint64(&c.misses, 1)
 	if atomic.LoadUint32(&c.useOfflineCache) == 1 {
 		if key, ok := c.lookup(c.offlineCache, name); ok {
 			return key, nil
 		}
 	}
-	switch key, err := c.Store.Get(ctx, name); {
+	switch key, err := c.fetch(ctx, name); {
 	case err == nil:
+		return key, nil
+	case errors.Is(err, kes.ErrKeyNotFound):
+		return Key{}, kes.ErrKeyNotFound
+	default:
+		// The offline cache may contain restored keys before
+		// the Store has been detected as unavailable.
+		if key, ok := c.lookup(c.offlineCache, name); ok {
+			return key, nil
+		}
+		if isBreakerOpen(err) {
+			return Key{}, err
+		}
+		return Key{}, errGetKey
+	}
+}
+
+// fetch fetches the key with the given name from the
+// Store and adds it to the cache. Concurrent fetches for
+// the same key share a single Store read.
+func (c *Cache) fetch(ctx context.Context, name string) (Key, error) {
+	key, err, shared := c.fetches.Do(name, func() (Key, error) {
+		key, err := c.Store.Get(ctx, name)
+		if err != nil {
+			return Key{}, err
+		}
 		return c.insertOrRefresh(c.cache, name, key), nil
+	})
+	// The shared fetch may have been canceled by another
+	// request. Then, fetch the key using our own context.
+	if shared && err != nil && isContextErr(err) && ctx.Err() == nil {
+		if key, err = c.Store.Get(ctx, name); err == nil {
+			key = c.insertOrRefresh(c.cache, name, key)
+		}
+	}
+	return key, err
+}
+
+// isContextErr reports whether err is caused by a
+// canceled context or an exceeded context deadline.
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// CacheStats describes the state of a Cache.
+type CacheStats struct {
+	Entries        int    // Number of cached keys
+	OfflineEntries int    // Number of keys in the offline cache
+	Hits           uint64 // Number of keys served from the cache
+	Misses         uint64 // Number of keys not found in the cache
+}
+
+// Stats returns the current CacheStats.
+func (c *Cache) Stats() CacheStats {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return CacheStats{
+		Entries:        len(c.cache),
+		OfflineEntries: len(c.offlineCache),
+		Hits:           atomic.LoadUint64(&c.hits),
+		Misses:         atomic.LoadUint64(&c.misses),
+	}
+}
+
+// Flush removes all keys whose names match the glob
+// pattern from the cache and the offline cache. If
+// pattern is empty, it removes all keys. Subsequent
+// requests fetch flushed keys from the Store again.
+//
+// It returns the number of removed keys.
+func (c *Cache) Flush(pattern string) (int, error) {
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return 0, kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid key pattern '%s': %v", pattern, err))
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if pattern == "" {
+		n := len(c.cache)
+		c.cache = map[string]*cacheEntry{}
+		c.offlineCache = map[string]*cacheEntry{}
+		return n, nil
+	}
+	var n int
+	for name := range c.cache {
+		if ok, _ := path.Match(pattern, name); ok {
+			delete(c.cache, name)
+			n++
+		}
+	}
+	for name := range c.offlineCache {
+		if ok, _ := path.Match(pattern, name); ok {
+			delete(c.offlineCache, name)
+		}
+	}
+	return n, nil
+}
+
+// Load loads the key associated with the given name from
+// the Store. In contrast to Get, it bypasses the cache and
+// does not modify it.
+// If no such entry exists, Load returns kes.ErrKeyNotFound.
+func (c *Cache) Load(ctx context.Context, name string) (Key, error) {
+	switch key, err := c.Store.Get(ctx, name); {
+	case err == nil:
+		return key, nil
 	case errors.Is(err, kes.ErrKeyNotFound):
 		return Key{}, kes.ErrKeyNotFound
+	case isBreakerOpen(err):
+		return Key{}, err
 	default:
 		return Key{}, errGetKey
 	}
@@ -150,6 +280,9 @@ func (c *Cache) Get(ctx context.Context, name string) (Key, error) {
 // Delete deletes the key associated with the given name.
 func (c *Cache) Delete(ctx context.Context, name string) error {
 	if err := c.Store.Delete(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+		if isBreakerOpen(err) || errors.Is(err, ErrImmutable) || errors.Is(err, ErrLegalHold) {
+			return err
+		}
 		return errDeleteKey
 	}
 
@@ -163,12 +296,123 @@ func (c *Cache) Delete(ctx context.Context, name string) error {
 // List returns a new Iterator over the Store.
 func (c *Cache) List(ctx context.Context) (kv.Iter[string], error) {
 	i, err := c.Store.List(ctx)
+	if isBreakerOpen(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errListKey
+	}
+	return i, nil
+}
+
+// ListPrefix returns a new Iterator over all
+// names in the Store that start with the prefix.
+func (c *Cache) ListPrefix(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	i, err := c.Store.ListPrefix(ctx, prefix)
+	if isBreakerOpen(err) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, errListKey
 	}
 	return i, nil
 }
 
+// Warmup fetches all keys that match at least one of
+// the given names or glob patterns, like "my-app-*",
+// from the Store and adds them to the cache. It returns
+// the number of cached keys.
+//
+// Warmup only lists the Store if at least one pattern
+// is not a plain key name. It fetches all keys before
+// returning the first error, if any.
+func (c *Cache) Warmup(ctx context.Context, patterns []string) (int, error) {
+	var (
+		names    = make(map[string]struct{}, len(patterns))
+		wildcard []string
+	)
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[\\") {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return 0, fmt.Errorf("invalid key pattern '%s': %v", pattern, err)
+			}
+			wildcard = append(wildcard, pattern)
+		} else {
+			names[pattern] = struct{}{}
+		}
+	}
+	if len(wildcard) > 0 {
+		iter, err := c.List(ctx)
+		if err != nil {
+			return 0, err
+		}
+		for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+			for _, pattern := range wildcard {
+				if ok, _ := path.Match(pattern, name); ok {
+					names[name] = struct{}{}
+					break
+				}
+			}
+		}
+		if err = iter.Close(); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		n        int
+		firstErr error
+	)
+	for name := range names {
+		if _, err := c.Get(ctx, name); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to fetch key '%s': %v", name, err)
+			}
+			continue
+		}
+		n++
+	}
+	return n, firstErr
+}
+
+// Snapshot returns all keys in the cache
+// and the offline cache.
+func (c *Cache) Snapshot() map[string]Key {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys := make(map[string]Key, len(c.cache)+len(c.offlineCache))
+	for name, entry := range c.offlineCache {
+		keys[name] = entry.Key
+	}
+	for name, entry := range c.cache {
+		keys[name] = entry.Key
+	}
+	return keys
+}
+
+// Restore adds the given keys to the offline cache,
+// for example keys of a Snapshot taken before a
+// restart. Restored keys are only used while the
+// Store is not available and expire like any other
+// offline cache entry.
+//
+// Restore does nothing if the offline cache is
+// disabled.
+func (c *Cache) Restore(keys map[string]Key) {
+	if !c.offline {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for name, key := range keys {
+		if _, ok := c.offlineCache[name]; !ok {
+			c.offlineCache[name] = &cacheEntry{Key: key}
+		}
+	}
+}
+
 // Stop stops all background tasks performed by the
 // Cache.
 func (c *Cache) Stop() { c.cancel() }
@@ -339,7 +583,10 @@ func (c *Cache) watchOfflineStatus(t time.Duration) {
 				if err != nil {
 					if atomic.CompareAndSwapUint32(&c.useOfflineCache, Online, Offline) {
 						c.lock.Lock()
-						c.offlineCache, c.cache = c.cache, map[string]*cacheEntry{}
+						for name, entry := range c.cache { // Keep restored keys
+							c.offlineCache[name] = entry
+						}
+						c.cache = map[string]*cacheEntry{}
 						c.lock.Unlock()
 
 					}
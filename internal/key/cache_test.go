@@ -0,0 +1,157 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestCacheWarmup(t *testing.T) {
+	ctx := context.Background()
+	store := Store{Conn: &mem.Store{}}
+	for _, name := range []string{"my-key", "app-1", "app-2", "other"} {
+		key, err := Random(kes.AES256_GCM_SHA256, "")
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if err = store.Create(ctx, name, key); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+
+	cache := NewCache(store, &CacheConfig{})
+	defer cache.Stop()
+
+	n, err := cache.Warmup(ctx, []string{"my-key", "app-*"})
+	if err != nil {
+		t.Fatalf("Failed to warm up cache: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("Invalid number of cached keys: got '%d' - want '%d'", n, 3)
+	}
+	for _, name := range []string{"my-key", "app-1", "app-2"} {
+		if _, ok := cache.lookup(cache.cache, name); !ok {
+			t.Fatalf("Key '%s' has not been cached", name)
+		}
+	}
+	if _, ok := cache.lookup(cache.cache, "other"); ok {
+		t.Fatalf("Key '%s' has been cached", "other")
+	}
+
+	if _, err = cache.Warmup(ctx, []string{"missing"}); err == nil {
+		t.Fatal("Warming up missing key succeeded")
+	}
+	if _, err = cache.Warmup(ctx, []string{"app-["}); err == nil {
+		t.Fatal("Warming up invalid pattern succeeded")
+	}
+}
+
+func TestCacheGetConcurrent(t *testing.T) {
+	ctx := context.Background()
+	conn := &countingStore{Store: &mem.Store{}}
+	store := Store{Conn: conn}
+
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", key); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	cache := NewCache(store, &CacheConfig{})
+	defer cache.Stop()
+
+	const N = 100
+	var wg sync.WaitGroup
+	errs := make(chan error, N)
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Get(ctx, "my-key"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if n := atomic.LoadUint32(&conn.gets); n != 1 {
+		t.Fatalf("Invalid number of store reads: got '%d' - want '%d'", n, 1)
+	}
+}
+
+// countingStore counts the number of Get calls and delays
+// each of them such that concurrent calls overlap.
+type countingStore struct {
+	*mem.Store
+
+	gets uint32
+}
+
+func (s *countingStore) Get(ctx context.Context, name string) ([]byte, error) {
+	atomic.AddUint32(&s.gets, 1)
+	time.Sleep(50 * time.Millisecond)
+	return s.Store.Get(ctx, name)
+}
+
+func TestCacheFlush(t *testing.T) {
+	ctx := context.Background()
+	store := Store{Conn: &mem.Store{}}
+	for _, name := range []string{"my-key", "app-1", "app-2"} {
+		key, err := Random(kes.AES256_GCM_SHA256, "")
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if err = store.Create(ctx, name, key); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+
+	cache := NewCache(store, &CacheConfig{})
+	defer cache.Stop()
+
+	if _, err := cache.Warmup(ctx, []string{"*"}); err != nil {
+		t.Fatalf("Failed to warm up cache: %v", err)
+	}
+	cache.Get(ctx, "my-key")
+	if stats := cache.Stats(); stats.Entries != 3 || stats.Hits != 1 || stats.Misses != 3 {
+		t.Fatalf("Invalid cache stats: got '%+v' - want 3 entries, 1 hit and 3 misses", stats)
+	}
+
+	n, err := cache.Flush("app-*")
+	if err != nil {
+		t.Fatalf("Failed to flush cache: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("Invalid number of flushed keys: got '%d' - want '%d'", n, 2)
+	}
+	if _, ok := cache.lookup(cache.cache, "my-key"); !ok {
+		t.Fatalf("Key '%s' has been flushed", "my-key")
+	}
+
+	if n, err = cache.Flush(""); err != nil {
+		t.Fatalf("Failed to flush cache: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("Invalid number of flushed keys: got '%d' - want '%d'", n, 1)
+	}
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Fatalf("Invalid number of cached keys: got '%d' - want '%d'", stats.Entries, 0)
+	}
+	if _, err = cache.Flush("app-["); err == nil {
+		t.Fatal("Flushing invalid pattern succeeded")
+	}
+}
@@ -11,6 +11,42 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
+// Ciphertext formats.
+const (
+	// FormatJSON is the JSON ciphertext format of
+	// early KES releases.
+	FormatJSON = "json"
+
+	// FormatBinary is the MessagePack array format
+	// returned by Key.Wrap.
+	FormatBinary = "binary"
+
+	// FormatEnvelope is the versioned ciphertext
+	// envelope format returned by Key.WrapEnvelope.
+	FormatEnvelope = "envelope"
+)
+
+// CiphertextFormat returns the format of the given
+// ciphertext, e.g. FormatJSON for legacy ciphertexts.
+// It returns ErrDecrypt if the bytes are not a
+// ciphertext of any supported format.
+func CiphertextFormat(bytes []byte) (string, error) {
+	var c ciphertext
+	switch {
+	case isEnvelope(bytes):
+		if err := c.unmarshalEnvelope(bytes); err != nil {
+			return "", kes.ErrDecrypt
+		}
+		return FormatEnvelope, nil
+	case c.UnmarshalBinary(bytes) == nil:
+		return FormatBinary, nil
+	case c.UnmarshalJSON(bytes) == nil:
+		return FormatJSON, nil
+	default:
+		return "", kes.ErrDecrypt
+	}
+}
+
 // decodeCiphertext parses the given bytes as
 // ciphertext. If it fails to unmarshal the
 // given bytes, decodeCiphertext returns
@@ -21,6 +57,12 @@ func decodeCiphertext(bytes []byte) (ciphertext, error) {
 	}
 
 	var c ciphertext
+	if isEnvelope(bytes) {
+		if err := c.unmarshalEnvelope(bytes); err != nil {
+			return ciphertext{}, kes.ErrDecrypt
+		}
+		return c, nil
+	}
 	switch bytes[0] {
 	case 0x95: // msgp first byte
 		if err := c.UnmarshalBinary(bytes); err != nil {
@@ -43,11 +85,17 @@ func decodeCiphertext(bytes []byte) (ciphertext, error) {
 // ciphertext is a structure that contains the encrypted
 // bytes and all relevant information to decrypt these
 // bytes again with a cryptographic key.
+//
+// Version, Name and AADHash are only present for
+// ciphertext envelopes.
 type ciphertext struct {
+	Version   int
+	Name      string
 	Algorithm kes.KeyAlgorithm
 	ID        string
 	IV        []byte
 	Nonce     []byte
+	AADHash   []byte
 	Bytes     []byte
 }
 
@@ -59,7 +107,7 @@ func (c *ciphertext) MarshalBinary() ([]byte, error) {
 
 	var b []byte
 	b = msgp.AppendArrayHeader(b, Items)
-	b = msgp.AppendString(b, c.Algorithm.String())
+	b = msgp.AppendString(b, AlgorithmName(c.Algorithm))
 	b = msgp.AppendString(b, c.ID)
 	b = msgp.AppendBytes(b, c.IV)
 	b = msgp.AppendBytes(b, c.Nonce)
@@ -108,8 +156,8 @@ func (c *ciphertext) UnmarshalBinary(b []byte) error {
 		return kes.ErrDecrypt
 	}
 
-	var alg kes.KeyAlgorithm
-	if err = alg.UnmarshalText([]byte(algorithm)); err != nil {
+	alg, err := ParseAlgorithm(algorithm)
+	if err != nil {
 		return kes.ErrDecrypt
 	}
 
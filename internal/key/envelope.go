@@ -0,0 +1,149 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"crypto/sha256"
+
+	"github.com/minio/kes-go"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// EnvelopeVersion is the current version of the
+// ciphertext envelope format.
+const EnvelopeVersion = 1
+
+// A ciphertext envelope is a MessagePack map that contains
+// the following entries:
+//
+//	"v"     int     The envelope version. Currently 1.
+//	"key"   string  The name of the key.
+//	"kid"   string  The key ID of the key material version.
+//	"alg"   string  The AEAD algorithm, e.g. "AES256-GCM_SHA256".
+//	"iv"    bin     The 16 byte IV used to derive the AEAD key.
+//	"nonce" bin     The AEAD nonce.
+//	"aad"   bin     The SHA-256 hash of the associated data.
+//	"ct"    bin     The AEAD ciphertext, including the tag.
+//
+// The entries may appear in any order but no other entries
+// are allowed. Any change of the format requires a new
+// version. Hence, clients in any language can parse an
+// envelope with a generic MessagePack decoder.
+//
+// The AEAD key is HMAC-SHA256(key, iv) for AES256-GCM_SHA256
+// and HChaCha20(key, iv) for XCHACHA20-POLY1305.
+const (
+	envelopeVersion   = "v"
+	envelopeKey       = "key"
+	envelopeKeyID     = "kid"
+	envelopeAlgorithm = "alg"
+	envelopeIV        = "iv"
+	envelopeNonce     = "nonce"
+	envelopeAADHash   = "aad"
+	envelopeBytes     = "ct"
+)
+
+// isEnvelope reports whether b may be a ciphertext envelope,
+// i.e. whether b starts with a MessagePack map header.
+func isEnvelope(b []byte) bool {
+	return len(b) > 0 && (b[0]&0xf0 == 0x80 || b[0] == 0xde || b[0] == 0xdf)
+}
+
+// marshalEnvelope returns the ciphertext envelope of c.
+func (c *ciphertext) marshalEnvelope() ([]byte, error) {
+	const Items = 8
+
+	var b []byte
+	b = msgp.AppendMapHeader(b, Items)
+	b = msgp.AppendString(b, envelopeVersion)
+	b = msgp.AppendInt(b, EnvelopeVersion)
+	b = msgp.AppendString(b, envelopeKey)
+	b = msgp.AppendString(b, c.Name)
+	b = msgp.AppendString(b, envelopeKeyID)
+	b = msgp.AppendString(b, c.ID)
+	b = msgp.AppendString(b, envelopeAlgorithm)
+	b = msgp.AppendString(b, AlgorithmName(c.Algorithm))
+	b = msgp.AppendString(b, envelopeIV)
+	b = msgp.AppendBytes(b, c.IV)
+	b = msgp.AppendString(b, envelopeNonce)
+	b = msgp.AppendBytes(b, c.Nonce)
+	b = msgp.AppendString(b, envelopeAADHash)
+	b = msgp.AppendBytes(b, c.AADHash)
+	b = msgp.AppendString(b, envelopeBytes)
+	b = msgp.AppendBytes(b, c.Bytes)
+	return b, nil
+}
+
+// unmarshalEnvelope parses b as ciphertext envelope.
+func (c *ciphertext) unmarshalEnvelope(b []byte) error {
+	const (
+		Items     = 8
+		IVSize    = 16
+		NonceSize = 12
+	)
+
+	items, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil || items != Items {
+		return kes.ErrDecrypt
+	}
+
+	var (
+		value ciphertext
+		seen  = make(map[string]bool, Items)
+	)
+	for i := uint32(0); i < items; i++ {
+		var field string
+		if field, b, err = msgp.ReadStringBytes(b); err != nil {
+			return kes.ErrDecrypt
+		}
+		if seen[field] {
+			return kes.ErrDecrypt
+		}
+		seen[field] = true
+
+		switch field {
+		case envelopeVersion:
+			var version int
+			if version, b, err = msgp.ReadIntBytes(b); err != nil {
+				return kes.ErrDecrypt
+			}
+			if version != EnvelopeVersion {
+				return kes.ErrDecrypt
+			}
+			value.Version = version
+		case envelopeKey:
+			value.Name, b, err = msgp.ReadStringBytes(b)
+		case envelopeKeyID:
+			value.ID, b, err = msgp.ReadStringBytes(b)
+		case envelopeAlgorithm:
+			var algorithm string
+			if algorithm, b, err = msgp.ReadStringBytes(b); err != nil {
+				return kes.ErrDecrypt
+			}
+			value.Algorithm, err = ParseAlgorithm(algorithm)
+		case envelopeIV:
+			value.IV, b, err = msgp.ReadBytesBytes(b, nil)
+		case envelopeNonce:
+			value.Nonce, b, err = msgp.ReadBytesBytes(b, nil)
+		case envelopeAADHash:
+			value.AADHash, b, err = msgp.ReadBytesBytes(b, nil)
+		case envelopeBytes:
+			value.Bytes, b, err = msgp.ReadBytesBytes(b, nil)
+		default:
+			return kes.ErrDecrypt
+		}
+		if err != nil {
+			return kes.ErrDecrypt
+		}
+	}
+	if len(b) != 0 {
+		return kes.ErrDecrypt
+	}
+	if len(value.IV) != IVSize || len(value.Nonce) != NonceSize || len(value.AADHash) != sha256.Size {
+		return kes.ErrDecrypt
+	}
+	*c = value
+	return nil
+}
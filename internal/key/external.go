@@ -0,0 +1,178 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// External references a key held by an external KMS, like
+// a cloud KMS or an HSM. A Key that references an external
+// key does not contain any key material. Instead, it encrypts
+// data with random data keys wrapped by the external key.
+type External struct {
+	KMS   string `json:"kms"`    // Name of the external KMS
+	KeyID string `json:"key_id"` // ID of the key at the external KMS
+}
+
+// An ExternalKMS provides access to keys held by an
+// external KMS.
+type ExternalKMS interface {
+	// Key returns a Wrapper that en/decrypts data keys
+	// with the key with the given ID at the external KMS.
+	Key(ctx context.Context, keyID string) (Wrapper, error)
+}
+
+// ErrExternal is returned when an operation requires the
+// key material of a key held by an external KMS.
+var ErrExternal = kes.NewError(http.StatusBadRequest, "key is held by an external KMS: operation not supported")
+
+// NewExternal returns a new Key that references the
+// external key. The key has no key material and only
+// supports WrapExternal and UnwrapExternal.
+func NewExternal(ext External, owner kes.Identity) (Key, error) {
+	if ext.KMS == "" {
+		return Key{}, kes.NewError(http.StatusBadRequest, "invalid external key: no KMS specified")
+	}
+	if ext.KeyID == "" {
+		return Key{}, kes.NewError(http.StatusBadRequest, "invalid external key: no key ID specified")
+	}
+	return Key{
+		external:  &ext,
+		createdAt: time.Now().UTC(),
+		createdBy: owner,
+	}, nil
+}
+
+// External returns the external key referenced by
+// the key, or nil if the key contains key material.
+func (k *Key) External() *External {
+	if k.external == nil {
+		return nil
+	}
+	ext := *k.external
+	return &ext
+}
+
+// externalCiphertext is the encoding of ciphertexts
+// produced by WrapExternal.
+type externalCiphertext struct {
+	Version int    `json:"external"`
+	ID      string `json:"id"`
+	DataKey []byte `json:"data_key"`
+	Nonce   []byte `json:"nonce"`
+	Bytes   []byte `json:"bytes"`
+}
+
+const externalVersion = 1
+
+// WrapExternal encrypts the plaintext with a new random data
+// key and binds the associatedData to the returned ciphertext.
+// The data key gets wrapped by the external key, via w, and is
+// stored as part of the ciphertext.
+//
+// It returns ErrDisabled if the key is disabled and ErrExternal
+// if the key does not reference an external key.
+func (k *Key) WrapExternal(ctx context.Context, w Wrapper, plaintext, associatedData []byte) ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external == nil {
+		return nil, ErrExternal
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newWrappedAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := w.Wrap(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("key: failed to wrap data key with external key: %w", err)
+	}
+	return json.Marshal(externalCiphertext{
+		Version: externalVersion,
+		ID:      k.ID(),
+		DataKey: wrappedKey,
+		Nonce:   nonce,
+		Bytes:   aead.Seal(nil, nonce, plaintext, associatedData),
+	})
+}
+
+// UnwrapExternal decrypts a ciphertext produced by WrapExternal.
+// It unwraps the data key with the external key, via w, and
+// verifies that the associatedData matches the value used when
+// the ciphertext has been generated.
+//
+// It returns ErrDisabled if the key is disabled and ErrExternal
+// if the key does not reference an external key.
+func (k *Key) UnwrapExternal(ctx context.Context, w Wrapper, ciphertext, associatedData []byte) ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external == nil {
+		return nil, ErrExternal
+	}
+
+	var text externalCiphertext
+	if err := json.Unmarshal(ciphertext, &text); err != nil {
+		return nil, kes.ErrDecrypt
+	}
+	if text.Version != externalVersion || text.ID != k.ID() {
+		return nil, kes.ErrDecrypt
+	}
+	dataKey, err := w.Unwrap(ctx, text.DataKey)
+	if err != nil {
+		if _, ok := kv.IsUnreachable(err); ok {
+			return nil, err
+		}
+		if _, ok := kv.IsUnavailable(err); ok {
+			return nil, err
+		}
+		return nil, kes.ErrDecrypt
+	}
+	aead, err := newWrappedAEAD(dataKey)
+	if err != nil {
+		return nil, kes.ErrDecrypt
+	}
+	if len(text.Nonce) != aead.NonceSize() {
+		return nil, kes.ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, text.Nonce, text.Bytes, associatedData)
+	if err != nil {
+		return nil, kes.ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// externalID returns the key ID of an external key. It
+// is derived from the name of the external KMS and the
+// ID of the key at the external KMS.
+func externalID(ext *External) string {
+	const Size = 128 / 8
+	h := sha256.New()
+	h.Write([]byte("KES external key"))
+	h.Write([]byte(ext.KMS))
+	h.Write([]byte{0})
+	h.Write([]byte(ext.KeyID))
+	return hex.EncodeToString(h.Sum(nil)[:Size])
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+var newExternalTests = []struct {
+	External   External
+	ShouldFail bool
+}{
+	{External: External{KMS: "aws", KeyID: "alias/my-key"}},          // 0
+	{External: External{KMS: "", KeyID: "my-key"}, ShouldFail: true}, // 1
+	{External: External{KMS: "aws", KeyID: ""}, ShouldFail: true},    // 2
+}
+
+func TestNewExternal(t *testing.T) {
+	for i, test := range newExternalTests {
+		k, err := NewExternal(test.External, "")
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to create external key: %v", i, err)
+		}
+		if test.ShouldFail {
+			continue
+		}
+		if ext := k.External(); ext == nil || *ext != test.External {
+			t.Fatalf("Test %d: invalid external key: got '%v' - want '%v'", i, ext, test.External)
+		}
+	}
+}
+
+func TestKeyWrapExternal(t *testing.T) {
+	ctx := context.Background()
+	wrapper := newTestWrapper(t)
+
+	k, err := NewExternal(External{KMS: "hsm", KeyID: "my-key"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create external key: %v", err)
+	}
+	plaintext, associatedData := []byte("Hello World"), []byte("my-context")
+	ciphertext, err := k.WrapExternal(ctx, wrapper, plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("Failed to encrypt plaintext: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("Ciphertext contains plaintext")
+	}
+
+	p, err := k.UnwrapExternal(ctx, wrapper, ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("Failed to decrypt ciphertext: %v", err)
+	}
+	if !bytes.Equal(p, plaintext) {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", p, plaintext)
+	}
+	if _, err = k.UnwrapExternal(ctx, wrapper, ciphertext, nil); err != kes.ErrDecrypt {
+		t.Fatalf("Decryption with invalid associated data: got '%v' - want '%v'", err, kes.ErrDecrypt)
+	}
+	if _, err = k.UnwrapExternal(ctx, newTestWrapper(t), ciphertext, associatedData); err != kes.ErrDecrypt {
+		t.Fatalf("Decryption with a different external key: got '%v' - want '%v'", err, kes.ErrDecrypt)
+	}
+
+	// Ciphertexts must not be exchangeable between external keys.
+	other, err := NewExternal(External{KMS: "hsm", KeyID: "other-key"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create external key: %v", err)
+	}
+	if _, err = other.UnwrapExternal(ctx, wrapper, ciphertext, associatedData); err != kes.ErrDecrypt {
+		t.Fatalf("Decryption with a different key: got '%v' - want '%v'", err, kes.ErrDecrypt)
+	}
+}
+
+func TestKeyExternalUnsupported(t *testing.T) {
+	k, err := NewExternal(External{KMS: "hsm", KeyID: "my-key"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create external key: %v", err)
+	}
+	if _, err = k.Wrap([]byte("Hello World"), nil); err != ErrExternal {
+		t.Fatalf("Invalid wrap error: got '%v' - want '%v'", err, ErrExternal)
+	}
+	if _, err = k.HMAC([]byte("Hello World")); err != ErrExternal {
+		t.Fatalf("Invalid HMAC error: got '%v' - want '%v'", err, ErrExternal)
+	}
+	if _, err = k.SigningKey("test"); err != ErrExternal {
+		t.Fatalf("Invalid signing key error: got '%v' - want '%v'", err, ErrExternal)
+	}
+
+	key, err := New(DefaultAlgorithm(), make([]byte, Len(DefaultAlgorithm())), "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = key.WrapExternal(context.Background(), newTestWrapper(t), nil, nil); err != ErrExternal {
+		t.Fatalf("Invalid external wrap error: got '%v' - want '%v'", err, ErrExternal)
+	}
+}
+
+func TestKeyExternalEncoding(t *testing.T) {
+	k, err := NewExternal(External{KMS: "hsm", KeyID: "my-key"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create external key: %v", err)
+	}
+
+	text, err := k.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	parsed, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	if !parsed.Equal(k) || parsed.ID() != k.ID() {
+		t.Fatalf("Invalid key: got '%s' - want '%s'", parsed.ID(), k.ID())
+	}
+
+	binary, err := k.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var decoded Key
+	if err = decoded.UnmarshalBinary(binary); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	if !decoded.Equal(k) || decoded.ID() != k.ID() {
+		t.Fatalf("Invalid key: got '%s' - want '%s'", decoded.ID(), k.ID())
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -17,11 +18,14 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/minio/kes-go"
-	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/fips"
+	"github.com/minio/kes/internal/gcmsiv"
+	"github.com/minio/kes/kesclient"
 	"golang.org/x/crypto/chacha20"
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -34,6 +38,14 @@ const (
 	Size = 256 / 8
 )
 
+// ErrDisabled is returned when a disabled key is used
+// for a cryptographic operation.
+var ErrDisabled = &kesclient.Error{
+	Code:    kesclient.CodeKeyDisabled,
+	Status:  http.StatusConflict,
+	Message: "key is disabled",
+}
+
 // Parse parses b as encoded Key.
 func Parse(b []byte) (Key, error) {
 	var key Key
@@ -50,6 +62,8 @@ func Len(a kes.KeyAlgorithm) int {
 		return 256 / 8
 	case kes.XCHACHA20_POLY1305:
 		return 256 / 8
+	case AES256_GCM_SIV:
+		return 256 / 8
 	case kes.KeyAlgorithmUndefined:
 		return 256 / 8 // For generic/unknown keys, return 256 bit.
 	default:
@@ -97,6 +111,98 @@ type Key struct {
 	algorithm kes.KeyAlgorithm
 	createdAt time.Time
 	createdBy kes.Identity
+
+	// checkValue is the key check value that has been
+	// stored along with the key. It is empty for keys
+	// that have been stored without check value.
+	checkValue []byte
+
+	// retainUntil is the point in time until the key
+	// cannot be deleted. It is zero for keys that have
+	// been created without retention period.
+	retainUntil time.Time
+
+	// legalHold is the legal hold placed on the key,
+	// if any.
+	legalHold *LegalHold
+
+	// disabled reports whether the key has been
+	// disabled and cannot be used for cryptographic
+	// operations.
+	disabled bool
+
+	// allowlist restricts the identities that can
+	// use the key for cryptographic operations.
+	allowlist Allowlist
+
+	// tokenSigning reports whether the key has been
+	// designated as JWT signing key.
+	tokenSigning bool
+
+	// external is the key held by an external KMS that
+	// the key references. It is nil for keys that contain
+	// key material.
+	external *External
+}
+
+// An Allowlist restricts the identities that can use a key
+// for cryptographic operations. It is checked in addition to
+// the policy of the requesting identity.
+//
+// The zero Allowlist does not restrict any identity.
+type Allowlist struct {
+	// Identities that can use the key.
+	Identities []kes.Identity `json:"identities,omitempty"`
+
+	// Policies whose identities can use the key.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// IsZero reports whether the Allowlist is empty and,
+// therefore, does not restrict any identity.
+func (a Allowlist) IsZero() bool { return len(a.Identities) == 0 && len(a.Policies) == 0 }
+
+// Allows reports whether the Allowlist allows the given
+// identity, which is assigned to the given policy, to use
+// the key. The zero Allowlist allows any identity.
+func (a Allowlist) Allows(identity kes.Identity, policy string) bool {
+	if a.IsZero() {
+		return true
+	}
+	for _, id := range a.Identities {
+		if id == identity {
+			return true
+		}
+	}
+	if policy == "" {
+		return false
+	}
+	for _, p := range a.Policies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Allowlist) clone() Allowlist {
+	return Allowlist{
+		Identities: append([]kes.Identity(nil), a.Identities...),
+		Policies:   append([]string(nil), a.Policies...),
+	}
+}
+
+// A LegalHold prevents the deletion of a key until
+// the hold is released. In contrast to a retention
+// period, a legal hold has no fixed end.
+type LegalHold struct {
+	// CreatedAt is the point in time when the
+	// hold has been placed on the key.
+	CreatedAt time.Time `json:"created_at"`
+
+	// CreatedBy is the identity that placed the
+	// hold on the key.
+	CreatedBy kes.Identity `json:"created_by"`
 }
 
 var (
@@ -117,20 +223,139 @@ func (k *Key) CreatedAt() time.Time { return k.createdAt }
 // CreatedBy returns the identity that created the key.
 func (k *Key) CreatedBy() kes.Identity { return k.createdBy }
 
+// RetainUntil returns the point in time until the key is
+// immutable. It returns the zero time if the key has been
+// created without retention period.
+func (k *Key) RetainUntil() time.Time { return k.retainUntil }
+
+// SetRetainUntil makes the key immutable until the given
+// point in time. An immutable key cannot be deleted - not
+// even by an admin - before its retention period ends.
+func (k *Key) SetRetainUntil(t time.Time) { k.retainUntil = t.UTC() }
+
+// Immutable reports whether the key is within its
+// retention period.
+func (k *Key) Immutable() bool { return time.Now().Before(k.retainUntil) }
+
+// LegalHold returns the legal hold placed on the key
+// or nil if the key is not under legal hold.
+func (k *Key) LegalHold() *LegalHold {
+	if k.legalHold == nil {
+		return nil
+	}
+	hold := *k.legalHold
+	return &hold
+}
+
+// SetLegalHold places the legal hold on the key. A nil
+// hold releases any legal hold placed on the key.
+func (k *Key) SetLegalHold(hold *LegalHold) {
+	if hold == nil {
+		k.legalHold = nil
+		return
+	}
+	h := *hold
+	k.legalHold = &h
+}
+
+// Disabled reports whether the key has been disabled.
+// A disabled key cannot be used for cryptographic
+// operations until it is enabled again.
+func (k *Key) Disabled() bool { return k.disabled }
+
+// SetDisabled disables or enables the key.
+func (k *Key) SetDisabled(disabled bool) { k.disabled = disabled }
+
+// Allowlist returns the allowlist of identities that
+// can use the key for cryptographic operations.
+func (k *Key) Allowlist() Allowlist { return k.allowlist.clone() }
+
+// SetAllowlist replaces the allowlist of the key. The
+// zero Allowlist removes any restriction.
+func (k *Key) SetAllowlist(a Allowlist) { k.allowlist = a.clone() }
+
+// TokenSigning reports whether the key has been designated
+// as JWT signing key. Only designated keys can sign tokens
+// and are published as part of the enclave's JWKS.
+func (k *Key) TokenSigning() bool { return k.tokenSigning }
+
+// SetTokenSigning designates the key as JWT signing key
+// or revokes the designation.
+func (k *Key) SetTokenSigning(enabled bool) { k.tokenSigning = enabled }
+
 // ID returns the k's key ID.
 func (k *Key) ID() string {
+	if k.external != nil {
+		return externalID(k.external)
+	}
+
 	const Size = 128 / 8
 	h := sha256.Sum256(k.bytes)
 	return hex.EncodeToString(h[:Size])
 }
 
+// CheckValue returns the k's key check value (KCV).
+//
+// The KCV is an HMAC fingerprint of the key material
+// and algorithm. In contrast to the key ID, it does
+// not reveal a hash of the key material itself.
+func (k *Key) CheckValue() []byte {
+	const Size = 128 / 8
+	mac := hmac.New(sha256.New, k.bytes)
+	mac.Write([]byte("KES key check value"))
+	mac.Write([]byte(k.algorithm.String()))
+	if k.external != nil {
+		mac.Write([]byte(k.ID()))
+	}
+	return mac.Sum(nil)[:Size]
+}
+
+// Fingerprint returns k's public fingerprint as "sha256:"
+// followed by a hex-encoded SHA-256 hash.
+//
+// The fingerprint is a hash of the key algorithm, the creation
+// time, the identity that created the key and the key check
+// value. Hence, it does not reveal the key material but changes
+// whenever a key gets recreated, e.g. under the same name.
+// It is stable otherwise. In particular, it does not change
+// when the key gets disabled, held or modified otherwise.
+func (k *Key) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte("KES key fingerprint"))
+	h.Write([]byte{0})
+	h.Write([]byte(k.algorithm.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(k.createdAt.Unix(), 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(k.createdBy))
+	h.Write([]byte{0})
+	h.Write(k.CheckValue())
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// StoredCheckValue returns the key check value that has
+// been stored along with the key. It returns nil if the
+// key has been stored without check value.
+//
+// A StoredCheckValue that differs from the CheckValue
+// indicates that the stored key has been corrupted.
+func (k *Key) StoredCheckValue() []byte { return clone(k.checkValue...) }
+
 // Clone returns a deep copy of the key.
 func (k *Key) Clone() Key {
 	return Key{
-		bytes:     clone(k.bytes...),
-		algorithm: k.Algorithm(),
-		createdAt: k.CreatedAt(),
-		createdBy: k.CreatedBy(),
+		bytes:       clone(k.bytes...),
+		algorithm:   k.Algorithm(),
+		createdAt:   k.CreatedAt(),
+		createdBy:   k.CreatedBy(),
+		checkValue:  clone(k.checkValue...),
+		retainUntil: k.retainUntil,
+		legalHold:   k.LegalHold(),
+		disabled:    k.disabled,
+		allowlist:   k.Allowlist(),
+		external:    k.External(),
+
+		tokenSigning: k.tokenSigning,
 	}
 }
 
@@ -140,64 +365,135 @@ func (k *Key) Equal(other Key) bool {
 	if k.Algorithm() != other.Algorithm() {
 		return false
 	}
+	if (k.external == nil) != (other.external == nil) {
+		return false
+	}
+	if k.external != nil && *k.external != *other.external {
+		return false
+	}
 	return subtle.ConstantTimeCompare(k.bytes, other.bytes) == 1
 }
 
 // MarshalText returns the key's text representation.
+//
+// The text representation contains the key's stored
+// check value or, if not present, its check value.
 func (k Key) MarshalText() ([]byte, error) {
 	type JSON struct {
-		Version   version          `json:"version"`
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm,omitempty"`
-		CreatedAt time.Time        `json:"created_at,omitempty"`
-		CreatedBy kes.Identity     `json:"created_by,omitempty"`
+		Version     version      `json:"version"`
+		Bytes       []byte       `json:"bytes"`
+		Algorithm   string       `json:"algorithm,omitempty"`
+		CreatedAt   time.Time    `json:"created_at,omitempty"`
+		CreatedBy   kes.Identity `json:"created_by,omitempty"`
+		CheckValue  []byte       `json:"check_value,omitempty"`
+		RetainUntil *time.Time   `json:"retain_until,omitempty"`
+		LegalHold   *LegalHold   `json:"legal_hold,omitempty"`
+		Disabled    bool         `json:"disabled,omitempty"`
+		Allowlist   *Allowlist   `json:"allowlist,omitempty"`
+		External    *External    `json:"external,omitempty"`
+
+		TokenSigning bool `json:"token_signing,omitempty"`
+	}
+	var allowlist *Allowlist
+	if !k.allowlist.IsZero() {
+		allowlist = &k.allowlist
+	}
+	var retainUntil *time.Time
+	if !k.retainUntil.IsZero() {
+		retainUntil = &k.retainUntil
 	}
 	return json.Marshal(JSON{
-		Version:   v1,
-		Bytes:     k.bytes,
-		Algorithm: k.Algorithm(),
-		CreatedAt: k.CreatedAt(),
-		CreatedBy: k.CreatedBy(),
+		Version:     v1,
+		Bytes:       k.bytes,
+		Algorithm:   AlgorithmName(k.Algorithm()),
+		CreatedAt:   k.CreatedAt(),
+		CreatedBy:   k.CreatedBy(),
+		CheckValue:  k.marshalCheckValue(),
+		RetainUntil: retainUntil,
+		LegalHold:   k.legalHold,
+		Disabled:    k.disabled,
+		Allowlist:   allowlist,
+		External:    k.external,
+
+		TokenSigning: k.tokenSigning,
 	})
 }
 
 // UnmarshalText parses and decodes text as encoded key.
 func (k *Key) UnmarshalText(text []byte) error {
 	type JSON struct {
-		Version   version          `json:"version"`
-		Bytes     []byte           `json:"bytes"`
-		Algorithm kes.KeyAlgorithm `json:"algorithm"`
-		CreatedAt time.Time        `json:"created_at"`
-		CreatedBy kes.Identity     `json:"created_by"`
+		Version     version      `json:"version"`
+		Bytes       []byte       `json:"bytes"`
+		Algorithm   string       `json:"algorithm"`
+		CreatedAt   time.Time    `json:"created_at"`
+		CreatedBy   kes.Identity `json:"created_by"`
+		CheckValue  []byte       `json:"check_value"`
+		RetainUntil time.Time    `json:"retain_until"`
+		LegalHold   *LegalHold   `json:"legal_hold"`
+		Disabled    bool         `json:"disabled"`
+		Allowlist   Allowlist    `json:"allowlist"`
+		External    *External    `json:"external"`
+
+		TokenSigning bool `json:"token_signing"`
 	}
 	var value JSON
 	if err := json.Unmarshal(text, &value); err != nil {
 		return err
 	}
+	algorithm, err := ParseAlgorithm(value.Algorithm)
+	if err != nil {
+		return err
+	}
 	k.bytes = value.Bytes
-	k.algorithm = value.Algorithm
+	k.algorithm = algorithm
 	k.createdAt = value.CreatedAt
 	k.createdBy = value.CreatedBy
+	k.checkValue = value.CheckValue
+	k.retainUntil = value.RetainUntil
+	k.legalHold = value.LegalHold
+	k.disabled = value.Disabled
+	k.allowlist = value.Allowlist
+	k.tokenSigning = value.TokenSigning
+	k.external = value.External
 	return nil
 }
 
 // MarshalBinary returns the Key's binary representation.
+//
+// Like MarshalText, the binary representation contains
+// the key's stored check value or its check value.
 func (k Key) MarshalBinary() ([]byte, error) {
 	type GOB struct {
-		Version   version
-		Bytes     []byte
-		Algorithm kes.KeyAlgorithm
-		CreatedAt time.Time
-		CreatedBy kes.Identity
+		Version     version
+		Bytes       []byte
+		Algorithm   kes.KeyAlgorithm
+		CreatedAt   time.Time
+		CreatedBy   kes.Identity
+		CheckValue  []byte
+		RetainUntil time.Time
+		LegalHold   *LegalHold
+		Disabled    bool
+		Allowlist   Allowlist
+		External    *External
+
+		TokenSigning bool
 	}
 
 	var buffer bytes.Buffer
 	err := gob.NewEncoder(&buffer).Encode(GOB{
-		Version:   v1,
-		Bytes:     k.bytes,
-		Algorithm: k.Algorithm(),
-		CreatedAt: k.CreatedAt(),
-		CreatedBy: k.CreatedBy(),
+		Version:     v1,
+		Bytes:       k.bytes,
+		Algorithm:   k.Algorithm(),
+		CreatedAt:   k.CreatedAt(),
+		CreatedBy:   k.CreatedBy(),
+		CheckValue:  k.marshalCheckValue(),
+		RetainUntil: k.retainUntil,
+		LegalHold:   k.legalHold,
+		Disabled:    k.disabled,
+		Allowlist:   k.allowlist,
+		External:    k.external,
+
+		TokenSigning: k.tokenSigning,
 	})
 	return buffer.Bytes(), err
 }
@@ -205,11 +501,19 @@ func (k Key) MarshalBinary() ([]byte, error) {
 // UnmarshalBinary unmarshals the Key's binary representation.
 func (k *Key) UnmarshalBinary(b []byte) error {
 	type GOB struct {
-		Version   version
-		Bytes     []byte
-		Algorithm kes.KeyAlgorithm
-		CreatedAt time.Time
-		CreatedBy kes.Identity
+		Version     version
+		Bytes       []byte
+		Algorithm   kes.KeyAlgorithm
+		CreatedAt   time.Time
+		CreatedBy   kes.Identity
+		CheckValue  []byte
+		RetainUntil time.Time
+		LegalHold   *LegalHold
+		Disabled    bool
+		Allowlist   Allowlist
+		External    *External
+
+		TokenSigning bool
 	}
 
 	var value GOB
@@ -220,15 +524,71 @@ func (k *Key) UnmarshalBinary(b []byte) error {
 	k.algorithm = value.Algorithm
 	k.createdAt = value.CreatedAt
 	k.createdBy = value.CreatedBy
+	k.checkValue = value.CheckValue
+	k.retainUntil = value.RetainUntil
+	k.legalHold = value.LegalHold
+	k.disabled = value.Disabled
+	k.allowlist = value.Allowlist
+	k.tokenSigning = value.TokenSigning
+	k.external = value.External
 	return nil
 }
 
+// marshalCheckValue returns the check value that gets
+// stored along with the key. A stored check value is
+// preserved such that re-encoding a corrupted key does
+// not hide the corruption.
+func (k *Key) marshalCheckValue() []byte {
+	if len(k.checkValue) > 0 {
+		return k.checkValue
+	}
+	return k.CheckValue()
+}
+
 // Wrap encrypts the given plaintext and binds
 // the associatedData to the returned ciphertext.
 //
 // To unwrap the ciphertext the same associatedData
 // has to be provided again.
+//
+// It returns ErrDisabled if the key is disabled.
 func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
+	ciphertext, err := k.seal(plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return ciphertext.MarshalBinary()
+}
+
+// WrapEnvelope encrypts the given plaintext, like Wrap,
+// but returns a versioned ciphertext envelope that contains
+// the key name, key ID, algorithm, nonce and a hash of the
+// associatedData. Unwrap accepts both, ciphertext envelopes
+// and ciphertexts returned by Wrap.
+//
+// It returns ErrDisabled if the key is disabled.
+func (k *Key) WrapEnvelope(name string, plaintext, associatedData []byte) ([]byte, error) {
+	ciphertext, err := k.seal(plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	aadHash := sha256.Sum256(associatedData)
+
+	ciphertext.Version = EnvelopeVersion
+	ciphertext.Name = name
+	ciphertext.AADHash = aadHash[:]
+	return ciphertext.marshalEnvelope()
+}
+
+// seal encrypts the plaintext with a new random IV
+// and nonce.
+func (k *Key) seal(plaintext, associatedData []byte) (*ciphertext, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external != nil {
+		return nil, ErrExternal
+	}
 	iv, err := randomBytes(16)
 	if err != nil {
 		return nil, err
@@ -236,11 +596,7 @@ func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
 
 	algorithm := k.Algorithm()
 	if algorithm == kes.KeyAlgorithmUndefined {
-		if fips.Enabled || cpu.HasAESGCM() {
-			algorithm = kes.AES256_GCM_SHA256
-		} else {
-			algorithm = kes.XCHACHA20_POLY1305
-		}
+		algorithm = DefaultAlgorithm()
 	}
 	cipher, err := newAEAD(algorithm, k.bytes, iv)
 	if err != nil {
@@ -251,14 +607,70 @@ func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	ciphertext := ciphertext{
+	return &ciphertext{
 		Algorithm: algorithm,
 		ID:        k.ID(),
 		IV:        iv,
 		Nonce:     nonce,
 		Bytes:     cipher.Seal(nil, nonce, plaintext, associatedData),
+	}, nil
+}
+
+// SigningKey derives an Ed25519 private key for the given
+// purpose from the key material. The same key and purpose
+// always derive the same private key while different
+// purposes derive independent private keys.
+//
+// It returns ErrDisabled if the key is disabled.
+func (k *Key) SigningKey(purpose string) (ed25519.PrivateKey, error) {
+	if k.disabled {
+		return nil, ErrDisabled
 	}
-	return ciphertext.MarshalBinary()
+	if k.external != nil {
+		return nil, ErrExternal
+	}
+	mac := hmac.New(sha256.New, k.bytes)
+	mac.Write([]byte("KES signing key"))
+	mac.Write([]byte(purpose))
+	return ed25519.NewKeyFromSeed(mac.Sum(nil)), nil
+}
+
+// HMAC returns the HMAC-SHA256 of the message computed
+// with a hashing key derived from the key material. The
+// same key and message always produce the same hash while
+// the hash does not reveal the message or the key.
+//
+// It returns ErrDisabled if the key is disabled.
+func (k *Key) HMAC(message []byte) ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external != nil {
+		return nil, ErrExternal
+	}
+	mac := hmac.New(sha256.New, k.bytes)
+	mac.Write([]byte("KES hashing key"))
+
+	mac = hmac.New(sha256.New, mac.Sum(nil))
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+// FPEKey derives an AES-256 key for format-preserving
+// encryption from the key material. The same key always
+// derives the same FPE key, independent of its algorithm.
+//
+// It returns ErrDisabled if the key is disabled.
+func (k *Key) FPEKey() ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external != nil {
+		return nil, ErrExternal
+	}
+	mac := hmac.New(sha256.New, k.bytes)
+	mac.Write([]byte("KES format-preserving encryption key"))
+	return mac.Sum(nil), nil
 }
 
 // Unwrap decrypts the ciphertext and returns the
@@ -266,7 +678,15 @@ func (k *Key) Wrap(plaintext, associatedData []byte) ([]byte, error) {
 //
 // It verifies that the associatedData matches the
 // value used when the ciphertext has been generated.
+//
+// It returns ErrDisabled if the key is disabled.
 func (k *Key) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
+	if k.disabled {
+		return nil, ErrDisabled
+	}
+	if k.external != nil {
+		return nil, ErrExternal
+	}
 	text, err := decodeCiphertext(ciphertext)
 	if err != nil {
 		return nil, kes.ErrDecrypt
@@ -278,6 +698,12 @@ func (k *Key) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
 	if k.algorithm != kes.KeyAlgorithmUndefined && text.Algorithm != k.Algorithm() {
 		return nil, kes.ErrDecrypt
 	}
+	if text.Version > 0 {
+		aadHash := sha256.Sum256(associatedData)
+		if subtle.ConstantTimeCompare(text.AADHash, aadHash[:]) != 1 {
+			return nil, kes.ErrDecrypt
+		}
+	}
 
 	cipher, err := newAEAD(text.Algorithm, k.bytes, text.IV)
 	if err != nil {
@@ -290,6 +716,28 @@ func (k *Key) Unwrap(ciphertext, associatedData []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// Rewrap decrypts the ciphertext, which may have any supported
+// format, and encrypts the plaintext again as ciphertext envelope
+// for the named key. It returns the new ciphertext envelope and
+// the format of the given ciphertext.
+//
+// It returns ErrDisabled if the key is disabled.
+func (k *Key) Rewrap(name string, ciphertext, associatedData []byte) ([]byte, string, error) {
+	format, err := CiphertextFormat(ciphertext)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := k.Unwrap(ciphertext, associatedData)
+	if err != nil {
+		return nil, "", err
+	}
+	envelope, err := k.WrapEnvelope(name, plaintext, associatedData)
+	if err != nil {
+		return nil, "", err
+	}
+	return envelope, format, nil
+}
+
 // newAEAD returns a new AEAD cipher that implements the given
 // algorithm and is initialized with the given key and iv.
 func newAEAD(algorithm kes.KeyAlgorithm, Key, IV []byte) (cipher.AEAD, error) {
@@ -313,6 +761,13 @@ func newAEAD(algorithm kes.KeyAlgorithm, Key, IV []byte) (cipher.AEAD, error) {
 			return nil, err
 		}
 		return chacha20poly1305.New(sealingKey)
+	case AES256_GCM_SIV:
+		if fips.Enabled {
+			return nil, kes.ErrDecrypt
+		}
+		mac := hmac.New(sha256.New, Key)
+		mac.Write(IV)
+		return gcmsiv.New(mac.Sum(nil))
 	default:
 		return nil, kes.ErrDecrypt
 	}
@@ -6,12 +6,15 @@ package key
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/tinylib/msgp/msgp"
 )
 
 var parseTests = []struct {
@@ -42,11 +45,19 @@ var parseTests = []struct {
 		CreatedAt: mustDecodeTime("2009-11-10T23:00:00Z"),
 		CreatedBy: "189d9de5331e3ee8abe9e4bd40d474ad621d79ccf83a711f6ac68050eb15a52a",
 	},
+	{
+		Raw:       `{"bytes":"9ew6BCae3+13sniOUwttEJ62amg98YXc0OW0WBhNiCY=","algorithm":"AES256-GCM-SIV","created_at":"2009-11-10T23:00:00Z","created_by":"189d9de5331e3ee8abe9e4bd40d474ad621d79ccf83a711f6ac68050eb15a52a"}`,
+		Bytes:     mustDecodeHex("f5ec3a04269edfed77b2788e530b6d109eb66a683df185dcd0e5b458184d8826"),
+		Algorithm: AES256_GCM_SIV,
+		CreatedAt: mustDecodeTime("2009-11-10T23:00:00Z"),
+		CreatedBy: "189d9de5331e3ee8abe9e4bd40d474ad621d79ccf83a711f6ac68050eb15a52a",
+	},
 
 	{Raw: `"bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, ShouldFail: true}, // Missing: {
 	{Raw: `{bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, ShouldFail: true}, // Missing first: "
 	{Raw: `{"bytes""AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}`, ShouldFail: true}, // Missing: :
 	{Raw: `"bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="`, ShouldFail: true},  // Missing final }
+	{Raw: `{"bytes":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=","algorithm":"AES128-GCM"}`, ShouldFail: true},
 }
 
 func TestParse(t *testing.T) {
@@ -89,7 +100,7 @@ var keyWrapTests = []struct {
 }
 
 func TestKeyWrap(t *testing.T) {
-	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305}
+	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305, AES256_GCM_SIV}
 	for _, a := range algorithms {
 		key, err := Random(a, "")
 		if err != nil {
@@ -230,6 +241,247 @@ func TestKeyUnwrap(t *testing.T) {
 	}
 }
 
+func TestKeyWrapEnvelope(t *testing.T) {
+	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305, AES256_GCM_SIV}
+	for _, a := range algorithms {
+		key, err := Random(a, "")
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		for i, test := range keyWrapTests {
+			data := make([]byte, test.KeyLen)
+			envelope, err := key.WrapEnvelope("my-key", data, test.AssociatedData)
+			if err != nil {
+				t.Fatalf("Test %d: Failed to wrap data: %v", i, err)
+			}
+			plaintext, err := key.Unwrap(envelope, test.AssociatedData)
+			if err != nil {
+				t.Fatalf("Test %d: Failed to unwrap data: %v", i, err)
+			}
+			if !bytes.Equal(data, plaintext) {
+				t.Fatalf("Test %d: Original plaintext does not match unwrapped plaintext", i)
+			}
+			if _, err = key.Unwrap(envelope, append(test.AssociatedData, 0)); err != kes.ErrDecrypt {
+				t.Fatalf("Test %d: Unwrapping with wrong associated data: got '%v' - want '%v'", i, err, kes.ErrDecrypt)
+			}
+
+			// The envelope must be a plain MessagePack map such
+			// that other clients can parse it.
+			value, _, err := msgp.ReadMapStrIntfBytes(envelope, nil)
+			if err != nil {
+				t.Fatalf("Test %d: Failed to parse envelope: %v", i, err)
+			}
+			if v, ok := value["v"].(int64); !ok || v != EnvelopeVersion {
+				t.Fatalf("Test %d: Invalid envelope version: got '%v' - want '%d'", i, value["v"], EnvelopeVersion)
+			}
+			if name := value["key"]; name != "my-key" {
+				t.Fatalf("Test %d: Invalid key name: got '%v' - want '%s'", i, name, "my-key")
+			}
+			if id := value["kid"]; id != key.ID() {
+				t.Fatalf("Test %d: Invalid key ID: got '%v' - want '%s'", i, id, key.ID())
+			}
+			if alg := value["alg"]; alg != AlgorithmName(a) {
+				t.Fatalf("Test %d: Invalid algorithm: got '%v' - want '%v'", i, alg, a)
+			}
+			if hash := sha256.Sum256(test.AssociatedData); !bytes.Equal(value["aad"].([]byte), hash[:]) {
+				t.Fatalf("Test %d: Invalid associated data hash: got '%x' - want '%x'", i, value["aad"], hash)
+			}
+		}
+	}
+}
+
+func TestKeyUnwrapAlgorithm(t *testing.T) {
+	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305, AES256_GCM_SIV}
+	secret := make([]byte, 32)
+	for _, a := range algorithms {
+		key, err := New(a, secret, "")
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		ciphertext, err := key.Wrap([]byte("Hello World"), nil)
+		if err != nil {
+			t.Fatalf("Failed to wrap data: %v", err)
+		}
+		for _, b := range algorithms {
+			other, err := New(b, secret, "")
+			if err != nil {
+				t.Fatalf("Failed to create key: %v", err)
+			}
+			_, err = other.Unwrap(ciphertext, nil)
+			if a == b && err != nil {
+				t.Fatalf("Failed to unwrap %s ciphertext: %v", AlgorithmName(a), err)
+			}
+			if a != b && err != kes.ErrDecrypt {
+				t.Fatalf("Unwrapping %s ciphertext with %s key: got '%v' - want '%v'", AlgorithmName(a), AlgorithmName(b), err, kes.ErrDecrypt)
+			}
+		}
+	}
+}
+
+var ciphertextFormatTests = []struct {
+	Ciphertext []byte
+	Format     string
+	ShouldFail bool
+}{
+	{ // 0
+		Ciphertext: []byte(`{"aead":"AES-256-GCM-HMAC-SHA-256","iv":"xLxIN3tSCkg2xMafuvwUwg==","nonce":"gu0mGwUkwcvMEoi5","bytes":"WVgRjeIJm3w50C/l+y7y2i6mbNg5NCAqN1zvOYWZKmc="}`),
+		Format:     FormatJSON,
+	},
+	{ // 1
+		Ciphertext: mustDecodeB64("lbFBRVMyNTYtR0NNX1NIQTI1NtkgNjY2ODdhYWRmODYyYmQ3NzZjOGZjMThiOGU5ZjhlMjDEEExv7LAd4oz0SaHZrX5LBufEDEKME1ow1CDfUFrqv8QgJuy7Sw+jVqz99TK1HV851LT3K4mwwDv46TB2ngWkAJQ="),
+		Format:     FormatBinary,
+	},
+	{ // 2
+		Ciphertext: mustDecodeB64("iKF2AaNrZXmkZW52a6NraWTZIDhiMmNkNzFjNzY4YThiZGMxMWI0NjU1YzdlODYwMzNho2FsZ7FBRVMyNTYtR0NNX1NIQTI1NqJpdsQQZcvhxukqdwYwTGxdonJgf6Vub25jZcQMvknALoRihrhuVqpko2FhZMQgLPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCSiY3TEMB0Dtz1CrlR8Zur0hhXpuYgH3kuyAxZQTvy3cHhKhCLFlIOejQSZO/SBl2jTFnAKaQ=="),
+		Format:     FormatEnvelope,
+	},
+	{ // 3
+		Ciphertext: []byte(`{"aead":"AES-256-GCM-HMAC-SHA-256"}`),
+		ShouldFail: true,
+	},
+	{ // 4
+		Ciphertext: nil,
+		ShouldFail: true,
+	},
+}
+
+func TestCiphertextFormat(t *testing.T) {
+	for i, test := range ciphertextFormatTests {
+		format, err := CiphertextFormat(test.Ciphertext)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but passed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to detect ciphertext format: %v", i, err)
+		}
+		if format != test.Format {
+			t.Fatalf("Test %d: invalid format: got '%s' - want '%s'", i, format, test.Format)
+		}
+	}
+}
+
+func TestKeyRewrap(t *testing.T) {
+	Plaintext := make([]byte, 16)
+	for i, test := range keyUnwrapTests {
+		if test.ShouldFail {
+			continue
+		}
+		key, err := New(test.Algorithm, make([]byte, Len(test.Algorithm)), "")
+		if err != nil {
+			t.Fatalf("Test %d: Failed to create key: %v", i, err)
+		}
+		envelope, format, err := key.Rewrap("my-key", []byte(test.Ciphertext), test.AssociatedData)
+		if err != nil {
+			t.Fatalf("Test %d: Failed to rewrap ciphertext: %v", i, err)
+		}
+		if want, _ := CiphertextFormat([]byte(test.Ciphertext)); format != want {
+			t.Fatalf("Test %d: Invalid format: got '%s' - want '%s'", i, format, want)
+		}
+		if format, _ = CiphertextFormat(envelope); format != FormatEnvelope {
+			t.Fatalf("Test %d: Invalid format: got '%s' - want '%s'", i, format, FormatEnvelope)
+		}
+		plaintext, err := key.Unwrap(envelope, test.AssociatedData)
+		if err != nil {
+			t.Fatalf("Test %d: Failed to unwrap envelope: %v", i, err)
+		}
+		if !bytes.Equal(plaintext, Plaintext) {
+			t.Fatalf("Test %d: Plaintext mismatch: got %x - want %x", i, plaintext, Plaintext)
+		}
+	}
+}
+
+func TestKeyCheckValue(t *testing.T) {
+	algorithms := []kes.KeyAlgorithm{kes.AES256_GCM_SHA256, kes.XCHACHA20_POLY1305}
+	for _, a := range algorithms {
+		key, err := Random(a, "")
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		if len(key.StoredCheckValue()) != 0 {
+			t.Fatalf("Algorithm %v: new key has a stored check value", a)
+		}
+
+		text, err := key.MarshalText()
+		if err != nil {
+			t.Fatalf("Algorithm %v: failed to encode key: %v", a, err)
+		}
+		var textKey Key
+		if err = textKey.UnmarshalText(text); err != nil {
+			t.Fatalf("Algorithm %v: failed to decode key: %v", a, err)
+		}
+		if !bytes.Equal(textKey.StoredCheckValue(), key.CheckValue()) {
+			t.Fatalf("Algorithm %v: stored check value mismatch: got %x - want %x", a, textKey.StoredCheckValue(), key.CheckValue())
+		}
+
+		binary, err := key.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Algorithm %v: failed to encode key: %v", a, err)
+		}
+		var binaryKey Key
+		if err = binaryKey.UnmarshalBinary(binary); err != nil {
+			t.Fatalf("Algorithm %v: failed to decode key: %v", a, err)
+		}
+		if !bytes.Equal(binaryKey.StoredCheckValue(), key.CheckValue()) {
+			t.Fatalf("Algorithm %v: stored check value mismatch: got %x - want %x", a, binaryKey.StoredCheckValue(), key.CheckValue())
+		}
+
+		// Corrupt the key material and verify that the stored
+		// check value is preserved when re-encoding the key.
+		binaryKey.bytes[0] ^= 1
+		if bytes.Equal(binaryKey.StoredCheckValue(), binaryKey.CheckValue()) {
+			t.Fatalf("Algorithm %v: check value of corrupted key matches stored check value", a)
+		}
+		text, err = binaryKey.MarshalText()
+		if err != nil {
+			t.Fatalf("Algorithm %v: failed to encode key: %v", a, err)
+		}
+		if err = textKey.UnmarshalText(text); err != nil {
+			t.Fatalf("Algorithm %v: failed to decode key: %v", a, err)
+		}
+		if !bytes.Equal(textKey.StoredCheckValue(), key.CheckValue()) {
+			t.Fatalf("Algorithm %v: stored check value has not been preserved", a)
+		}
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	fingerprint := key.Fingerprint()
+	if !strings.HasPrefix(fingerprint, "sha256:") || len(fingerprint) != len("sha256:")+2*sha256.Size {
+		t.Fatalf("Invalid fingerprint '%s'", fingerprint)
+	}
+
+	text, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var textKey Key
+	if err = textKey.UnmarshalText(text); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	if textKey.Fingerprint() != fingerprint {
+		t.Fatalf("Fingerprint changed after decoding: got '%s' - want '%s'", textKey.Fingerprint(), fingerprint)
+	}
+	textKey.SetDisabled(true)
+	if textKey.Fingerprint() != fingerprint {
+		t.Fatalf("Fingerprint changed after disabling: got '%s' - want '%s'", textKey.Fingerprint(), fingerprint)
+	}
+
+	// A key with the same metadata but different key
+	// material must have a different fingerprint.
+	recreated, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	recreated.createdAt = key.createdAt
+	if recreated.Fingerprint() == fingerprint {
+		t.Fatal("Recreated key has the same fingerprint")
+	}
+}
+
 func mustDecodeTime(s string) time.Time {
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
@@ -253,3 +505,189 @@ func mustDecodeB64(s string) []byte {
 	}
 	return b
 }
+
+func TestKeyDisabled(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	ciphertext, err := key.Wrap([]byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	key.SetDisabled(true)
+	if _, err = key.Wrap([]byte("Hello World"), nil); err != ErrDisabled {
+		t.Fatalf("Encrypting with disabled key: got '%v' - want '%v'", err, ErrDisabled)
+	}
+	if _, err = key.Unwrap(ciphertext, nil); err != ErrDisabled {
+		t.Fatalf("Decrypting with disabled key: got '%v' - want '%v'", err, ErrDisabled)
+	}
+
+	text, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var textKey Key
+	if err = textKey.UnmarshalText(text); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	binary, err := key.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var binaryKey Key
+	if err = binaryKey.UnmarshalBinary(binary); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	if !textKey.Disabled() || !binaryKey.Disabled() {
+		t.Fatal("Decoded key is not disabled")
+	}
+
+	binaryKey.SetDisabled(false)
+	if _, err = binaryKey.Unwrap(ciphertext, nil); err != nil {
+		t.Fatalf("Failed to decrypt with enabled key: %v", err)
+	}
+}
+
+var allowlistTests = []struct {
+	Allowlist Allowlist
+	Identity  kes.Identity
+	Policy    string
+	Allowed   bool
+}{
+	{Allowlist: Allowlist{}, Identity: "a", Policy: "", Allowed: true},                                                                    // 0
+	{Allowlist: Allowlist{Identities: []kes.Identity{"a"}}, Identity: "a", Policy: "", Allowed: true},                                     // 1
+	{Allowlist: Allowlist{Identities: []kes.Identity{"a"}}, Identity: "b", Policy: "my-app", Allowed: false},                              // 2
+	{Allowlist: Allowlist{Policies: []string{"my-app"}}, Identity: "b", Policy: "my-app", Allowed: true},                                  // 3
+	{Allowlist: Allowlist{Policies: []string{"my-app"}}, Identity: "b", Policy: "", Allowed: false},                                       // 4
+	{Allowlist: Allowlist{Identities: []kes.Identity{"a"}, Policies: []string{"my-app"}}, Identity: "b", Policy: "other", Allowed: false}, // 5
+}
+
+func TestAllowlist(t *testing.T) {
+	for i, test := range allowlistTests {
+		if allowed := test.Allowlist.Allows(test.Identity, test.Policy); allowed != test.Allowed {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, allowed, test.Allowed)
+		}
+	}
+
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	key.SetAllowlist(Allowlist{Identities: []kes.Identity{"a"}, Policies: []string{"my-app"}})
+
+	text, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var textKey Key
+	if err = textKey.UnmarshalText(text); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	binary, err := key.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var binaryKey Key
+	if err = binaryKey.UnmarshalBinary(binary); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	for _, k := range []Key{textKey, binaryKey, key.Clone()} {
+		if allowlist := k.Allowlist(); !allowlist.Allows("a", "") || !allowlist.Allows("b", "my-app") || allowlist.Allows("b", "") {
+			t.Fatalf("Decoded allowlist does not match: got '%v'", allowlist)
+		}
+	}
+}
+
+func TestKeySigningKey(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	priv, err := key.SigningKey("ssh")
+	if err != nil {
+		t.Fatalf("Failed to derive signing key: %v", err)
+	}
+	again, err := key.SigningKey("ssh")
+	if err != nil {
+		t.Fatalf("Failed to derive signing key: %v", err)
+	}
+	if !priv.Equal(again) {
+		t.Fatal("Signing keys for the same purpose are not equal")
+	}
+	other, err := key.SigningKey("jwt")
+	if err != nil {
+		t.Fatalf("Failed to derive signing key: %v", err)
+	}
+	if priv.Equal(other) {
+		t.Fatal("Signing keys for different purposes are equal")
+	}
+
+	key.SetDisabled(true)
+	if _, err = key.SigningKey("ssh"); err != ErrDisabled {
+		t.Fatalf("Deriving signing key from disabled key: got '%v' - want '%v'", err, ErrDisabled)
+	}
+}
+
+func TestKeyHMAC(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	hash, err := key.HMAC([]byte("Hello World"))
+	if err != nil {
+		t.Fatalf("Failed to compute HMAC: %v", err)
+	}
+	again, err := key.HMAC([]byte("Hello World"))
+	if err != nil {
+		t.Fatalf("Failed to compute HMAC: %v", err)
+	}
+	if !bytes.Equal(hash, again) {
+		t.Fatal("HMACs of the same message are not equal")
+	}
+
+	other, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if otherHash, _ := other.HMAC([]byte("Hello World")); bytes.Equal(hash, otherHash) {
+		t.Fatal("HMACs of different keys are equal")
+	}
+
+	key.SetDisabled(true)
+	if _, err = key.HMAC([]byte("Hello World")); err != ErrDisabled {
+		t.Fatalf("Computing HMAC with disabled key: got '%v' - want '%v'", err, ErrDisabled)
+	}
+}
+
+func TestKeyTokenSigning(t *testing.T) {
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if key.TokenSigning() {
+		t.Fatal("New key is designated as token signing key")
+	}
+	key.SetTokenSigning(true)
+
+	text, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var textKey Key
+	if err = textKey.UnmarshalText(text); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	binary, err := key.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	var binaryKey Key
+	if err = binaryKey.UnmarshalBinary(binary); err != nil {
+		t.Fatalf("Failed to decode key: %v", err)
+	}
+	if clone := key.Clone(); !textKey.TokenSigning() || !binaryKey.TokenSigning() || !clone.TokenSigning() {
+		t.Fatal("Decoded key is not designated as token signing key")
+	}
+}
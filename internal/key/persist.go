@@ -0,0 +1,177 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/log"
+)
+
+// Persister writes the keys of a Cache encrypted to a
+// file on disk such that they can be restored after a
+// restart, even if the Store is not available.
+//
+// The file is encrypted with AES-256-GCM using the
+// 256 bit key of the Persister.
+type Persister struct {
+	path string
+	aead cipher.AEAD
+
+	lock  sync.Mutex
+	cache *Cache
+}
+
+// NewPersister returns a new Persister that writes
+// the cache keys to the given path encrypted with
+// the given 256 bit key.
+func NewPersister(path string, key []byte) (*Persister, error) {
+	if len(key) != 32 {
+		return nil, errors.New("key: invalid cache persistence key: key must be 256 bits long")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Persister{
+		path: path,
+		aead: aead,
+	}, nil
+}
+
+type persistedCache struct {
+	Version    string    `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// SetCache sets the Cache that gets persisted.
+func (p *Persister) SetCache(c *Cache) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.cache = c
+}
+
+// Persist writes all keys of the cache to the file.
+// It does nothing if the cache is empty such that
+// a previously written file is not overwritten by
+// an empty cache.
+func (p *Persister) Persist() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.cache == nil {
+		return nil
+	}
+	keys := p.cache.Snapshot()
+	if len(keys) == 0 {
+		return nil
+	}
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	file := persistedCache{
+		Version:   "v1",
+		CreatedAt: time.Now().UTC(),
+		Nonce:     make([]byte, p.aead.NonceSize()),
+	}
+	if _, err = rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	associatedData, err := file.CreatedAt.MarshalText()
+	if err != nil {
+		return err
+	}
+	file.Ciphertext = p.aead.Seal(nil, file.Nonce, plaintext, associatedData)
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p.path), 0o700); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// Load reads and decrypts the keys from the file.
+// It returns no keys if the file does not exist or
+// has been written more than maxAge ago.
+func (p *Persister) Load(maxAge time.Duration) (map[string]Key, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file persistedCache
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("key: invalid persisted cache '%s': %v", p.path, err)
+	}
+	if file.Version != "v1" {
+		return nil, fmt.Errorf("key: invalid persisted cache '%s': unsupported version '%s'", p.path, file.Version)
+	}
+	if len(file.Nonce) != p.aead.NonceSize() {
+		return nil, fmt.Errorf("key: invalid persisted cache '%s': invalid nonce", p.path)
+	}
+	associatedData, err := file.CreatedAt.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := p.aead.Open(nil, file.Nonce, file.Ciphertext, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("key: failed to decrypt persisted cache '%s': invalid key", p.path)
+	}
+	if time.Since(file.CreatedAt) > maxAge {
+		return nil, nil
+	}
+
+	var keys map[string]Key
+	if err = json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, fmt.Errorf("key: invalid persisted cache '%s': %v", p.path, err)
+	}
+	return keys, nil
+}
+
+// Run persists the cache in the given interval
+// until ctx is canceled.
+func (p *Persister) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Persist(); err != nil {
+				log.Printf("failed to persist key cache: %v", err)
+			}
+		}
+	}
+}
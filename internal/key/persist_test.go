@@ -0,0 +1,73 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestPersister(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache")
+	sealKey := make([]byte, 32)
+
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	store := Store{Conn: &mem.Store{}}
+	if err = store.Create(ctx, "my-key", key); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	cache := NewCache(store, &CacheConfig{ExpiryOffline: time.Hour})
+	defer cache.Stop()
+	if _, err = cache.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+
+	persister, err := NewPersister(path, sealKey)
+	if err != nil {
+		t.Fatalf("Failed to create persister: %v", err)
+	}
+	persister.SetCache(cache)
+	if err = persister.Persist(); err != nil {
+		t.Fatalf("Failed to persist cache: %v", err)
+	}
+
+	keys, err := persister.Load(time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	restored, ok := keys["my-key"]
+	if !ok || !restored.Equal(key) {
+		t.Fatalf("Invalid restored key: got '%v' - want '%v'", restored, key)
+	}
+	if expired, _ := persister.Load(0); len(expired) != 0 {
+		t.Fatalf("Loaded expired cache with '%d' keys", len(expired))
+	}
+
+	// Restart while the store is not available.
+	offline := NewCache(Store{Conn: &failingStore{Store: &mem.Store{}, fail: true}}, &CacheConfig{ExpiryOffline: time.Hour})
+	defer offline.Stop()
+	offline.Restore(keys)
+	if _, err = offline.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to fetch restored key: %v", err)
+	}
+
+	sealKey[0] = 1
+	other, err := NewPersister(path, sealKey)
+	if err != nil {
+		t.Fatalf("Failed to create persister: %v", err)
+	}
+	if _, err = other.Load(time.Hour); err == nil {
+		t.Fatal("Loaded persisted cache with invalid key")
+	}
+}
@@ -8,11 +8,20 @@ import (
 	"context"
 	"errors"
 	"log"
+	"net/http"
 
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/kv"
 )
 
+// ErrImmutable is returned when trying to delete a key
+// within its retention period.
+var ErrImmutable = kes.NewError(http.StatusForbidden, "key is immutable")
+
+// ErrLegalHold is returned when trying to delete a key
+// that is under legal hold.
+var ErrLegalHold = kes.NewError(http.StatusForbidden, "key is under legal hold")
+
 // Store is a key store that reads/writes
 // keys from/to a KMS via a kms.Conn.
 type Store struct {
@@ -45,7 +54,7 @@ func (s *Store) Create(ctx context.Context, name string, key Key) error {
 	}
 
 	err = s.Conn.Create(ctx, name, b)
-	if err != nil && !errors.Is(err, kes.ErrKeyExists) {
+	if err != nil && !errors.Is(err, kes.ErrKeyExists) && !isBreakerOpen(err) {
 		logln(s.ErrorLog, err)
 	}
 	return err
@@ -60,7 +69,7 @@ func (s *Store) Create(ctx context.Context, name string, key Key) error {
 func (s *Store) Get(ctx context.Context, name string) (Key, error) {
 	b, err := s.Conn.Get(ctx, name)
 	switch {
-	case errors.Is(err, kes.ErrKeyNotFound):
+	case errors.Is(err, kes.ErrKeyNotFound), isBreakerOpen(err):
 		return Key{}, err
 	case err != nil:
 		logln(s.ErrorLog, err)
@@ -77,10 +86,26 @@ func (s *Store) Get(ctx context.Context, name string) (Key, error) {
 // Delete deletes the specified entry at the KMS.
 //
 // If no entry for the given name exists, Delete
-// returns kes.ErrKeyNotFound.
+// returns kes.ErrKeyNotFound. If the key is within
+// its retention period or under legal hold, Delete
+// returns ErrImmutable or ErrLegalHold.
 func (s *Store) Delete(ctx context.Context, name string) error {
-	err := s.Conn.Delete(ctx, name)
-	if err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+	// A key that cannot be parsed, e.g. because it is
+	// corrupted, has no retention period and can be deleted.
+	b, err := s.Conn.Get(ctx, name)
+	if err == nil {
+		if key, err := Parse(b); err == nil {
+			if key.Immutable() {
+				return ErrImmutable
+			}
+			if key.LegalHold() != nil {
+				return ErrLegalHold
+			}
+		}
+	}
+
+	err = s.Conn.Delete(ctx, name)
+	if err != nil && !errors.Is(err, kes.ErrKeyNotFound) && !isBreakerOpen(err) {
 		logln(s.ErrorLog, err)
 	}
 	return err
@@ -93,7 +118,20 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 // from the KMS once ctx.Done() returns.
 func (s *Store) List(ctx context.Context) (kv.Iter[string], error) {
 	iter, err := s.Conn.List(ctx)
-	if err != nil {
+	if err != nil && !isBreakerOpen(err) {
+		logln(s.ErrorLog, err)
+	}
+	return iter, err
+}
+
+// ListPrefix returns an iterator over the entries
+// at the KMS whose names start with the prefix.
+//
+// The returned Iter stops fetching entries
+// from the KMS once ctx.Done() returns.
+func (s *Store) ListPrefix(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	iter, err := kv.ListPrefix(ctx, s.Conn, prefix)
+	if err != nil && !isBreakerOpen(err) {
 		logln(s.ErrorLog, err)
 	}
 	return iter, err
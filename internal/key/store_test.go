@@ -0,0 +1,83 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestStoreDeleteImmutable(t *testing.T) {
+	ctx := context.Background()
+	store := Store{Conn: &mem.Store{}}
+
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	retainUntil := time.Now().Add(time.Hour)
+	key.SetRetainUntil(retainUntil)
+	if err = store.Create(ctx, "my-key", key); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	stored, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if !stored.RetainUntil().Equal(retainUntil) {
+		t.Fatalf("Invalid retention: got '%v' - want '%v'", stored.RetainUntil(), retainUntil)
+	}
+	if err = store.Delete(ctx, "my-key"); !errors.Is(err, ErrImmutable) {
+		t.Fatalf("Deleting immutable key: got '%v' - want '%v'", err, ErrImmutable)
+	}
+
+	key.SetRetainUntil(time.Now().Add(-time.Hour))
+	if err = store.Create(ctx, "expired-key", key); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = store.Delete(ctx, "expired-key"); err != nil {
+		t.Fatalf("Failed to delete key after retention period: %v", err)
+	}
+}
+
+func TestStoreListPrefix(t *testing.T) {
+	ctx := context.Background()
+	store := Store{Conn: &mem.Store{}}
+
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	for _, name := range []string{"my-key", "tenants/acme/key-1", "tenants/acme/dev/key-2", "tenants/acme2/key-3"} {
+		if err = store.Create(ctx, name, key); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+
+	iter, err := store.ListPrefix(ctx, "tenants/acme/")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	names := map[string]bool{}
+	for {
+		name, ok := iter.Next()
+		if !ok {
+			break
+		}
+		names[name] = true
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(names) != 2 || !names["tenants/acme/key-1"] || !names["tenants/acme/dev/key-2"] {
+		t.Fatalf("Invalid keys: got '%v' - want 'tenants/acme/key-1' and 'tenants/acme/dev/key-2'", names)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/minio/kes/kv"
+)
+
+// A Wrapper en/decrypts data keys with a key stored
+// at an external KMS, e.g. AWS-KMS or an HSM. The key
+// never leaves the external KMS.
+type Wrapper interface {
+	// Status returns the current state of the
+	// external KMS.
+	Status(context.Context) (kv.State, error)
+
+	// Wrap encrypts the given data key with
+	// the key at the external KMS.
+	Wrap(ctx context.Context, plaintext []byte) ([]byte, error)
+
+	// Unwrap decrypts the given wrapped data
+	// key with the key at the external KMS.
+	Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// WrappedStore is a kv.Store that encrypts all values
+// before storing them at the underlying store.
+//
+// Each value is encrypted with a unique data key using
+// AES-256-GCM. The data key is wrapped by the Wrapper
+// and stored alongside the encrypted value. Hence, an
+// attacker with access to the underlying store cannot
+// recover any value without access to the external KMS
+// as well - and vice versa.
+//
+// The name of an entry is bound to its encrypted value.
+// Therefore, values cannot be swapped between entries.
+//
+// Entries stored before wrapping has been enabled are not
+// wrapped. By default, a WrappedStore rejects them. With
+// AllowUnwrapped, it returns them as they are. New entries
+// are always wrapped.
+type WrappedStore struct {
+	conn           kv.Store[string, []byte]
+	wrapper        Wrapper
+	allowUnwrapped bool
+}
+
+var _ kv.Store[string, []byte] = (*WrappedStore)(nil) // compiler check
+
+// WrappedStoreConfig is a structure containing
+// the WrappedStore configuration.
+type WrappedStoreConfig struct {
+	// AllowUnwrapped controls whether entries that have
+	// not been wrapped, e.g. since they have been stored
+	// before wrapping has been enabled, are returned as
+	// they are. Otherwise, fetching them fails.
+	//
+	// Anyone with write access to the underlying store
+	// can add unwrapped entries. Hence, it should only
+	// be enabled while unwrapped entries are in use.
+	AllowUnwrapped bool
+}
+
+// NewWrappedStore returns a new WrappedStore that
+// stores values, encrypted with data keys wrapped
+// by the given wrapper, at the given store.
+//
+// If config is nil, the WrappedStore uses the
+// default configuration.
+func NewWrappedStore(conn kv.Store[string, []byte], wrapper Wrapper, config *WrappedStoreConfig) *WrappedStore {
+	s := &WrappedStore{
+		conn:    conn,
+		wrapper: wrapper,
+	}
+	if config != nil {
+		s.allowUnwrapped = config.AllowUnwrapped
+	}
+	return s
+}
+
+// wrappedValue is the encoding of a value stored
+// by a WrappedStore at the underlying store.
+type wrappedValue struct {
+	Version    string `json:"version"`
+	DataKey    []byte `json:"data_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+const wrappedVersion = "v1"
+
+// Status returns the current state of the underlying
+// store. If the store is healthy, it returns an error
+// if the external KMS is not healthy. The returned
+// latency is the larger of both latencies.
+func (s *WrappedStore) Status(ctx context.Context) (kv.State, error) {
+	state, err := s.conn.Status(ctx)
+	if err != nil {
+		return kv.State{}, err
+	}
+	wrapperState, err := s.wrapper.Status(ctx)
+	if err != nil {
+		if _, ok := kv.IsUnreachable(err); ok {
+			return kv.State{}, err
+		}
+		return kv.State{}, &kv.Unavailable{Err: err}
+	}
+	if wrapperState.Latency > state.Latency {
+		state.Latency = wrapperState.Latency
+	}
+	return state, nil
+}
+
+// Create encrypts the value with a new data key and
+// stores it at the underlying store if and only if
+// no entry with the given name exists.
+func (s *WrappedStore) Create(ctx context.Context, name string, value []byte) error {
+	ciphertext, err := s.seal(ctx, name, value)
+	if err != nil {
+		return err
+	}
+	return s.conn.Create(ctx, name, ciphertext)
+}
+
+// Set encrypts the value with a new data key and
+// stores it at the underlying store.
+func (s *WrappedStore) Set(ctx context.Context, name string, value []byte) error {
+	ciphertext, err := s.seal(ctx, name, value)
+	if err != nil {
+		return err
+	}
+	return s.conn.Set(ctx, name, ciphertext)
+}
+
+// Get returns the decrypted value associated with
+// the given name.
+//
+// It returns an error if the value has not been
+// stored by a WrappedStore, unless the WrappedStore
+// allows unwrapped entries. Then, it returns the
+// value as it is.
+func (s *WrappedStore) Get(ctx context.Context, name string) ([]byte, error) {
+	ciphertext, err := s.conn.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if s.allowUnwrapped && !isWrapped(ciphertext) {
+		return ciphertext, nil
+	}
+	return s.open(ctx, name, ciphertext)
+}
+
+// Delete removes the entry with the given name
+// from the underlying store.
+func (s *WrappedStore) Delete(ctx context.Context, name string) error {
+	return s.conn.Delete(ctx, name)
+}
+
+// List returns an iterator over the names of all
+// entries at the underlying store.
+func (s *WrappedStore) List(ctx context.Context) (kv.Iter[string], error) {
+	return s.conn.List(ctx)
+}
+
+// ListPrefix returns an iterator over the names of all
+// entries at the underlying store that start with the
+// prefix.
+func (s *WrappedStore) ListPrefix(ctx context.Context, prefix string) (kv.Iter[string], error) {
+	return kv.ListPrefix(ctx, s.conn, prefix)
+}
+
+func (s *WrappedStore) seal(ctx context.Context, name string, plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newWrappedAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := s.wrapper.Wrap(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("key: failed to wrap data key: %w", err)
+	}
+	return json.Marshal(wrappedValue{
+		Version:    wrappedVersion,
+		DataKey:    wrappedKey,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(name)),
+	})
+}
+
+func (s *WrappedStore) open(ctx context.Context, name string, ciphertext []byte) ([]byte, error) {
+	var value wrappedValue
+	if err := json.Unmarshal(ciphertext, &value); err != nil || value.Version == "" {
+		return nil, fmt.Errorf("key: entry '%s' is not wrapped by the external KMS", name)
+	}
+	if value.Version != wrappedVersion {
+		return nil, fmt.Errorf("key: entry '%s' has an unsupported wrapping version '%s'", name, value.Version)
+	}
+
+	dataKey, err := s.wrapper.Unwrap(ctx, value.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("key: failed to unwrap data key of '%s': %w", name, err)
+	}
+	aead, err := newWrappedAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(value.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("key: entry '%s' has an invalid nonce", name)
+	}
+	plaintext, err := aead.Open(nil, value.Nonce, value.Ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("key: failed to decrypt entry '%s': %w", name, err)
+	}
+	return plaintext, nil
+}
+
+// isWrapped reports whether the value has been
+// stored by a WrappedStore. Values stored before
+// wrapping has been enabled, like JSON-encoded keys,
+// lack a wrapped data key and nonce.
+func isWrapped(value []byte) bool {
+	var v wrappedValue
+	if err := json.Unmarshal(value, &v); err != nil {
+		return false
+	}
+	return v.Version != "" && len(v.DataKey) > 0 && len(v.Nonce) > 0
+}
+
+func newWrappedAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("key: invalid data key size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package key
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+	"github.com/minio/kes/kv"
+)
+
+func TestWrappedStore(t *testing.T) {
+	ctx := context.Background()
+	backend := &mem.Store{}
+	wrapper := newTestWrapper(t)
+	store := NewWrappedStore(backend, wrapper, nil)
+
+	value := []byte("Hello World")
+	if err := store.Create(ctx, "my-key", value); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	if err := store.Create(ctx, "other-key", value); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+
+	stored, err := backend.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch entry from backend: %v", err)
+	}
+	if bytes.Contains(stored, value) {
+		t.Fatal("Backend contains plaintext value")
+	}
+	plaintext, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch entry: %v", err)
+	}
+	if !bytes.Equal(plaintext, value) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", plaintext, value)
+	}
+
+	// Values must not be swappable between entries.
+	if err = backend.Delete(ctx, "other-key"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if err = backend.Create(ctx, "other-key", stored); err != nil {
+		t.Fatalf("Failed to overwrite entry: %v", err)
+	}
+	if _, err = store.Get(ctx, "other-key"); err == nil {
+		t.Fatal("Fetching swapped entry succeeded")
+	}
+
+	// Values cannot be decrypted without the external KMS.
+	if _, err = NewWrappedStore(backend, newTestWrapper(t), nil).Get(ctx, "my-key"); err == nil {
+		t.Fatal("Fetching entry with a different wrapping key succeeded")
+	}
+
+	// Values that have not been wrapped are rejected.
+	if err = backend.Create(ctx, "plain-key", value); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	if _, err = store.Get(ctx, "plain-key"); err == nil {
+		t.Fatal("Fetching unwrapped entry succeeded")
+	}
+
+	wrapper.err = errors.New("KMS is offline")
+	if _, err = store.Status(ctx); err == nil {
+		t.Fatal("Status succeeded while the external KMS is not healthy")
+	}
+	if _, ok := kv.IsUnavailable(err); !ok {
+		t.Fatalf("Invalid status error: got '%v' - want kv.Unavailable", err)
+	}
+}
+
+func TestWrappedStoreAllowUnwrapped(t *testing.T) {
+	ctx := context.Background()
+	backend := &mem.Store{}
+	wrapper := newTestWrapper(t)
+
+	// Entries stored before wrapping has been enabled.
+	key, err := Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyValue, err := key.MarshalText()
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	unwrapped := map[string][]byte{
+		"my-key":    keyValue,
+		"plain-key": []byte("Hello World"),
+	}
+	for name, value := range unwrapped {
+		if err = backend.Create(ctx, name, value); err != nil {
+			t.Fatalf("Failed to create entry: %v", err)
+		}
+	}
+
+	store := NewWrappedStore(backend, wrapper, &WrappedStoreConfig{AllowUnwrapped: true})
+	for name, value := range unwrapped {
+		plaintext, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to fetch unwrapped entry '%s': %v", name, err)
+		}
+		if !bytes.Equal(plaintext, value) {
+			t.Fatalf("Invalid value of '%s': got '%s' - want '%s'", name, plaintext, value)
+		}
+	}
+
+	// New entries are wrapped nevertheless.
+	value := []byte("Hello World")
+	if err = store.Create(ctx, "new-key", value); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	stored, err := backend.Get(ctx, "new-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch entry from backend: %v", err)
+	}
+	if bytes.Contains(stored, value) {
+		t.Fatal("Backend contains plaintext value")
+	}
+	plaintext, err := NewWrappedStore(backend, wrapper, nil).Get(ctx, "new-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch entry: %v", err)
+	}
+	if !bytes.Equal(plaintext, value) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", plaintext, value)
+	}
+
+	// Wrapped entries that cannot be unwrapped are
+	// not returned as they are.
+	if _, err = NewWrappedStore(backend, newTestWrapper(t), &WrappedStoreConfig{AllowUnwrapped: true}).Get(ctx, "new-key"); err == nil {
+		t.Fatal("Fetching entry with a different wrapping key succeeded")
+	}
+}
+
+type testWrapper struct {
+	aead cipher.AEAD
+	err  error
+}
+
+func newTestWrapper(t *testing.T) *testWrapper {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("Failed to generate wrapping key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create wrapping key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create wrapping key: %v", err)
+	}
+	return &testWrapper{aead: aead}
+}
+
+func (w *testWrapper) Status(context.Context) (kv.State, error) { return kv.State{}, w.err }
+
+func (w *testWrapper) Wrap(_ context.Context, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return w.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (w *testWrapper) Unwrap(_ context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < w.aead.NonceSize() {
+		return nil, errors.New("invalid ciphertext")
+	}
+	nonce, ciphertext := ciphertext[:w.aead.NonceSize()], ciphertext[w.aead.NonceSize():]
+	return w.aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awskms "github.com/aws/aws-sdk-go/service/kms"
+	"github.com/minio/kes/kv"
+)
+
+// KMSConfig is a structure containing configuration
+// options for wrapping keys with an AWS-KMS key.
+type KMSConfig struct {
+	// Addr is the HTTP address of AWS-KMS. In
+	// general, the address has the following form:
+	//  kms.<region>.amazonaws.com
+	//
+	// If empty, the address is derived from the
+	// region.
+	Addr string
+
+	// Region is the AWS region.
+	Region string
+
+	// KeyID is the ID, ARN or alias of the AWS-KMS
+	// key that wraps the keys.
+	KeyID string
+
+	// Login contains the AWS credentials (access/secret key).
+	Login Credentials
+}
+
+// KMS wraps keys with an AWS-KMS key.
+type KMS struct {
+	config KMSConfig
+	client *awskms.KMS
+}
+
+// ConnectKMS establishes and returns a KMS that wraps
+// keys with the AWS-KMS key specified by the config.
+func ConnectKMS(ctx context.Context, config *KMSConfig) (*KMS, error) {
+	if config.KeyID == "" {
+		return nil, errors.New("aws: no KMS key ID provided")
+	}
+	credentials := credentials.NewStaticCredentials(
+		config.Login.AccessKey,
+		config.Login.SecretKey,
+		config.Login.SessionToken,
+	)
+	if config.Login.AccessKey == "" && config.Login.SecretKey == "" && config.Login.SessionToken == "" {
+		credentials = nil // Fetch credentials from the environment. See: Connect
+	}
+
+	awsConfig := aws.Config{
+		Region:      aws.String(config.Region),
+		Credentials: credentials,
+	}
+	if config.Addr != "" {
+		awsConfig.Endpoint = aws.String(config.Addr)
+	}
+	session, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsConfig,
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	k := &KMS{
+		config: *config,
+		client: awskms.New(session),
+	}
+	if _, err = k.Status(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Status returns the current state of the AWS-KMS key.
+// It returns an error if the key cannot be used to
+// wrap keys, e.g. because it has been disabled.
+func (k *KMS) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	output, err := k.client.DescribeKeyWithContext(ctx, &awskms.DescribeKeyInput{
+		KeyId: aws.String(k.config.KeyID),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return kv.State{}, err
+		}
+		var nErr net.Error
+		if errors.As(err, &nErr) {
+			return kv.State{}, &kv.Unreachable{Err: err}
+		}
+		return kv.State{}, fmt.Errorf("aws: failed to describe KMS key '%s': %v", k.config.KeyID, err)
+	}
+	if state := aws.StringValue(output.KeyMetadata.KeyState); state != awskms.KeyStateEnabled {
+		return kv.State{}, fmt.Errorf("aws: KMS key '%s' is not enabled: key state '%s'", k.config.KeyID, state)
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Wrap encrypts the plaintext with the AWS-KMS key.
+func (k *KMS) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	output, err := k.client.EncryptWithContext(ctx, &awskms.EncryptInput{
+		KeyId:     aws.String(k.config.KeyID),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("aws: failed to encrypt with KMS key '%s': %v", k.config.KeyID, err)
+	}
+	return output.CiphertextBlob, nil
+}
+
+// Unwrap decrypts the ciphertext with the AWS-KMS key.
+func (k *KMS) Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	output, err := k.client.DecryptWithContext(ctx, &awskms.DecryptInput{
+		KeyId:          aws.String(k.config.KeyID),
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("aws: failed to decrypt with KMS key '%s': %v", k.config.KeyID, err)
+	}
+	return output.Plaintext, nil
+}
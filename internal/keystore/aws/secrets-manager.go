@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -195,16 +196,35 @@ func (c *Conn) Delete(ctx context.Context, name string) error {
 
 // List returns a new Iterator over the names of
 // all stored keys.
-func (c *Conn) List(ctx context.Context) (kms.Iter, error) {
+func (c *Conn) List(ctx context.Context) (kms.Iter, error) { return c.ListPrefix(ctx, "") }
+
+// ListPrefix returns a new Iterator over the names of
+// all stored keys that start with the prefix. Only
+// secrets whose names start with the prefix are fetched
+// from AWS SecretsManager.
+func (c *Conn) ListPrefix(ctx context.Context, prefix string) (kms.Iter, error) {
+	input := &secretsmanager.ListSecretsInput{}
+	if prefix != "" {
+		input.Filters = []*secretsmanager.Filter{{
+			Key:    aws.String(secretsmanager.FilterNameStringTypeName),
+			Values: []*string{aws.String(prefix)},
+		}}
+	}
+
 	values := make(chan string, 10)
 	iterator := &iterator{
 		values: values,
 	}
 	go func() {
 		defer close(values)
-		err := c.client.ListSecretsPagesWithContext(ctx, &secretsmanager.ListSecretsInput{}, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
+		err := c.client.ListSecretsPagesWithContext(ctx, input, func(page *secretsmanager.ListSecretsOutput, lastPage bool) bool {
 			for _, secret := range page.SecretList {
-				values <- *secret.Name
+				// The name filter of SecretsManager is not
+				// case-sensitive. Hence, we have to check
+				// the prefix again.
+				if strings.HasPrefix(*secret.Name, prefix) {
+					values <- *secret.Name
+				}
 			}
 
 			// The pagination is stopped once we return false.
@@ -7,6 +7,7 @@ package azure
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -410,6 +411,124 @@ func (c *client) ListSecrets(ctx context.Context, nextLink string) ([]string, st
 	}, nil
 }
 
+// GetKeyAttributes returns whether the KeyVault key with
+// the given name is enabled.
+func (c *client) GetKeyAttributes(ctx context.Context, name string) (bool, status, error) {
+	type Response struct {
+		Attr struct {
+			Enabled bool `json:"enabled"`
+		} `json:"attributes"`
+	}
+
+	uri := endpoint(c.Endpoint, "keys", name) + "?api-version=7.2"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return false, status{}, err
+	}
+	req, err = autorest.CreatePreparer(c.Authorizer.WithAuthorization()).Prepare(req)
+	if err != nil {
+		return false, status{}, err
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return false, status{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		response, err := parseErrorResponse(resp)
+		if err != nil {
+			return false, status{}, err
+		}
+		return false, status{
+			StatusCode: resp.StatusCode,
+			ErrorCode:  response.Error.Inner.Code,
+			Message:    response.Error.Message,
+		}, nil
+	}
+
+	const MaxSize = 1 * mem.MiB
+	limit := mem.Size(resp.ContentLength)
+	if limit < 0 || limit > MaxSize {
+		limit = MaxSize
+	}
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, limit)).Decode(&response); err != nil {
+		return false, status{}, err
+	}
+	return response.Attr.Enabled, status{
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// KeyOperation performs the wrapkey or unwrapkey operation
+// with the given version of the KeyVault key. If version is
+// empty then KeyVault uses the latest version of the key.
+//
+// It returns the result and the version of the key that
+// performed the operation.
+func (c *client) KeyOperation(ctx context.Context, name, version, operation string, value []byte) ([]byte, string, status, error) {
+	type Request struct {
+		Algorithm string `json:"alg"`
+		Value     string `json:"value"`
+	}
+	type Response struct {
+		KeyID string `json:"kid"`
+		Value string `json:"value"`
+	}
+	body, err := json.Marshal(Request{
+		Algorithm: "RSA-OAEP-256",
+		Value:     base64.RawURLEncoding.EncodeToString(value),
+	})
+	if err != nil {
+		return nil, "", status{}, err
+	}
+
+	uri := endpoint(c.Endpoint, "keys", name, version, operation) + "?api-version=7.2"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return nil, "", status{}, err
+	}
+	req, err = autorest.CreatePreparer(c.Authorizer.WithAuthorization()).Prepare(req)
+	if err != nil {
+		return nil, "", status{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = int64(len(body))
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, "", status{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		response, err := parseErrorResponse(resp)
+		if err != nil {
+			return nil, "", status{}, err
+		}
+		return nil, "", status{
+			StatusCode: resp.StatusCode,
+			ErrorCode:  response.Error.Inner.Code,
+			Message:    response.Error.Message,
+		}, nil
+	}
+
+	const MaxSize = 1 * mem.MiB
+	limit := mem.Size(resp.ContentLength)
+	if limit < 0 || limit > MaxSize {
+		limit = MaxSize
+	}
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, limit)).Decode(&response); err != nil {
+		return nil, "", status{}, err
+	}
+	result, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(response.Value, "="))
+	if err != nil {
+		return nil, "", status{}, err
+	}
+	return result, path.Base(response.KeyID), status{
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
 // endpoint returns an endpoint URL starting with the
 // given endpoint followed by the path elements.
 //
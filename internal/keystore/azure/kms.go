@@ -0,0 +1,147 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/minio/kes/kv"
+)
+
+// KMS wraps keys with an RSA key stored at Azure KeyVault
+// using RSA-OAEP-256.
+type KMS struct {
+	key    string
+	client client
+}
+
+// ConnectKMSWithCredentials returns a KMS that wraps keys with the
+// named KeyVault key using Azure client credentials.
+func ConnectKMSWithCredentials(ctx context.Context, endpoint, key string, creds Credentials) (*KMS, error) {
+	const Scope = "https://vault.azure.net"
+
+	c := auth.NewClientCredentialsConfig(creds.ClientID, creds.Secret, creds.TenantID)
+	c.Resource = Scope
+	token, err := c.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to obtain ServicePrincipalToken from client credentials: %v", err)
+	}
+	return connectKMS(ctx, endpoint, key, autorest.NewBearerAuthorizer(token))
+}
+
+// ConnectKMSWithIdentity returns a KMS that wraps keys with the
+// named KeyVault key using an Azure managed identity.
+func ConnectKMSWithIdentity(ctx context.Context, endpoint, key string, msi ManagedIdentity) (*KMS, error) {
+	const Scope = "https://vault.azure.net"
+
+	c := auth.NewMSIConfig()
+	c.Resource = Scope
+	c.ClientID = msi.ClientID
+	token, err := c.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to obtain ServicePrincipalToken from managed identity: %v", err)
+	}
+	return connectKMS(ctx, endpoint, key, autorest.NewBearerAuthorizer(token))
+}
+
+func connectKMS(ctx context.Context, endpoint, key string, authorizer autorest.Authorizer) (*KMS, error) {
+	if key == "" {
+		return nil, errors.New("azure: no KeyVault key name provided")
+	}
+	k := &KMS{
+		key: key,
+		client: client{
+			Endpoint:   endpoint,
+			Authorizer: authorizer,
+		},
+	}
+	if _, err := k.Status(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// wrappedKey is the encoding of a key wrapped by
+// a KeyVault key. Since the latest version of the
+// KeyVault key may change, the version used for
+// wrapping is stored alongside the wrapped key.
+type wrappedKey struct {
+	Version string `json:"version"`
+	Value   []byte `json:"value"`
+}
+
+// Status returns the current state of the KeyVault key.
+// It returns an error if the key cannot be used to wrap
+// keys, e.g. because it has been disabled.
+func (k *KMS) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	enabled, stat, err := k.client.GetKeyAttributes(ctx, k.key)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return kv.State{}, err
+		}
+		var nErr net.Error
+		if errors.As(err, &nErr) {
+			return kv.State{}, &kv.Unreachable{Err: err}
+		}
+		return kv.State{}, fmt.Errorf("azure: failed to fetch KeyVault key '%s': %v", k.key, err)
+	}
+	if stat.StatusCode != http.StatusOK {
+		return kv.State{}, fmt.Errorf("azure: failed to fetch KeyVault key '%s': %s (%s)", k.key, stat.Message, stat.ErrorCode)
+	}
+	if !enabled {
+		return kv.State{}, fmt.Errorf("azure: KeyVault key '%s' is disabled", k.key)
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Wrap encrypts the plaintext with the latest version
+// of the KeyVault key.
+func (k *KMS) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	value, version, stat, err := k.client.KeyOperation(ctx, k.key, "", "wrapkey", plaintext)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("azure: failed to wrap with KeyVault key '%s': %v", k.key, err)
+	}
+	if stat.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("azure: failed to wrap with KeyVault key '%s': %s (%s)", k.key, stat.Message, stat.ErrorCode)
+	}
+	return json.Marshal(wrappedKey{
+		Version: version,
+		Value:   value,
+	})
+}
+
+// Unwrap decrypts the ciphertext with the version of
+// the KeyVault key that has wrapped it.
+func (k *KMS) Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var wrapped wrappedKey
+	if err := json.Unmarshal(ciphertext, &wrapped); err != nil {
+		return nil, fmt.Errorf("azure: invalid wrapped key: %v", err)
+	}
+	plaintext, _, stat, err := k.client.KeyOperation(ctx, k.key, wrapped.Version, "unwrapkey", wrapped.Value)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("azure: failed to unwrap with KeyVault key '%s': %v", k.key, err)
+	}
+	if stat.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("azure: failed to unwrap with KeyVault key '%s': %s (%s)", k.key, stat.Message, stat.ErrorCode)
+	}
+	return plaintext, nil
+}
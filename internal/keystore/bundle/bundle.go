@@ -0,0 +1,207 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package bundle implements signed and encrypted key
+// bundles that provision KES servers operating offline.
+//
+// A bundle contains a set of keys and policies. It is
+// encrypted with a 256 bit bundle key using AES-256-GCM
+// and signed with an Ed25519 private key. A KES server
+// verifies the signature before decrypting the bundle.
+package bundle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// Version is the current bundle format version.
+const Version = "v1"
+
+// KeySize is the size of a bundle key in bytes.
+const KeySize = 32
+
+// Policy is a policy contained in a Bundle.
+type Policy struct {
+	Allow      []string       `json:"allow,omitempty"`
+	Deny       []string       `json:"deny,omitempty"`
+	Identities []kes.Identity `json:"identities,omitempty"`
+}
+
+// Bundle is a set of keys and policies.
+type Bundle struct {
+	// CreatedAt is the point in time when
+	// the bundle has been created.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Keys contains the keystore entries,
+	// i.e. encoded keys, by their names.
+	Keys map[string][]byte `json:"keys,omitempty"`
+
+	// Policies contains the policies by
+	// their names.
+	Policies map[string]Policy `json:"policies,omitempty"`
+}
+
+type envelope struct {
+	Version    string `json:"version"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+	Signature  []byte `json:"signature"`
+}
+
+// Seal encrypts the bundle with the given bundle key,
+// signs the ciphertext with the private key and returns
+// the encoded bundle.
+func Seal(bundle *Bundle, key []byte, signer ed25519.PrivateKey) ([]byte, error) {
+	if len(signer) != ed25519.PrivateKeySize {
+		return nil, errors.New("bundle: invalid signing key")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, []byte(Version))
+	return json.Marshal(envelope{
+		Version:    Version,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+		Signature:  ed25519.Sign(signer, signedMessage(nonce, ciphertext)),
+	})
+}
+
+// Open verifies the signature of the encoded bundle
+// using the public key and decrypts it with the given
+// bundle key.
+func Open(data, key []byte, verifier ed25519.PublicKey) (*Bundle, error) {
+	if len(verifier) != ed25519.PublicKeySize {
+		return nil, errors.New("bundle: invalid verification key")
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("bundle: invalid bundle: %v", err)
+	}
+	if env.Version != Version {
+		return nil, fmt.Errorf("bundle: unsupported bundle version '%s'", env.Version)
+	}
+	if !ed25519.Verify(verifier, signedMessage(env.Nonce, env.Ciphertext), env.Signature) {
+		return nil, errors.New("bundle: invalid bundle signature")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, errors.New("bundle: invalid bundle nonce")
+	}
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, []byte(env.Version))
+	if err != nil {
+		return nil, errors.New("bundle: failed to decrypt bundle: invalid bundle key")
+	}
+	var bundle Bundle
+	if err = json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("bundle: invalid bundle: %v", err)
+	}
+	return &bundle, nil
+}
+
+// ParseKey parses a hex-encoded bundle key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("bundle: invalid bundle key: must be %d hex-encoded bytes", KeySize)
+	}
+	return key, nil
+}
+
+// ReadPrivateKey reads a PEM-encoded Ed25519
+// private key from the given file.
+func ReadPrivateKey(filename string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(filename, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: invalid private key '%s': %v", filename, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("bundle: invalid private key '%s': not an Ed25519 key", filename)
+	}
+	return privateKey, nil
+}
+
+// ReadPublicKey reads a PEM-encoded Ed25519
+// public key from the given file.
+func ReadPublicKey(filename string) (ed25519.PublicKey, error) {
+	der, err := readPEM(filename, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: invalid public key '%s': %v", filename, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("bundle: invalid public key '%s': not an Ed25519 key", filename)
+	}
+	return publicKey, nil
+}
+
+func readPEM(filename, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("bundle: '%s' does not contain a PEM-encoded %s", filename, strings.ToLower(blockType))
+	}
+	return block.Bytes, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("bundle: invalid bundle key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// signedMessage returns the message that gets signed,
+// i.e. the version, nonce and ciphertext.
+func signedMessage(nonce, ciphertext []byte) []byte {
+	msg := make([]byte, 0, len(Version)+len(nonce)+len(ciphertext))
+	msg = append(msg, Version...)
+	msg = append(msg, nonce...)
+	return append(msg, ciphertext...)
+}
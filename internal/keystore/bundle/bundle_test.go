@@ -0,0 +1,82 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+func TestSealOpen(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate signing key: %v", err)
+	}
+	key := make([]byte, KeySize)
+	if _, err = rand.Read(key); err != nil {
+		t.Fatalf("Failed to generate bundle key: %v", err)
+	}
+
+	bundle := &Bundle{
+		CreatedAt: time.Now().UTC(),
+		Keys:      map[string][]byte{"my-key": []byte("my-value")},
+		Policies: map[string]Policy{
+			"my-policy": {Allow: []string{"/v1/key/decrypt/my-key"}},
+		},
+	}
+	data, err := Seal(bundle, key, privateKey)
+	if err != nil {
+		t.Fatalf("Failed to seal bundle: %v", err)
+	}
+
+	opened, err := Open(data, key, publicKey)
+	if err != nil {
+		t.Fatalf("Failed to open bundle: %v", err)
+	}
+	if !bytes.Equal(opened.Keys["my-key"], bundle.Keys["my-key"]) {
+		t.Fatalf("Invalid key: got '%s' - want '%s'", opened.Keys["my-key"], bundle.Keys["my-key"])
+	}
+	if _, ok := opened.Policies["my-policy"]; !ok {
+		t.Fatalf("Invalid bundle: policy '%s' not found", "my-policy")
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err = Open(data, key, otherKey); err == nil {
+		t.Fatal("Opened bundle with invalid verification key")
+	}
+	tampered := bytes.Replace(data, []byte(`"version":"v1"`), []byte(`"version":"v2"`), 1)
+	if _, err = Open(tampered, key, publicKey); err == nil {
+		t.Fatal("Opened bundle with unsupported version")
+	}
+	key[0] ^= 1
+	if _, err = Open(data, key, publicKey); err == nil {
+		t.Fatal("Opened bundle with invalid bundle key")
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(&Bundle{Keys: map[string][]byte{"my-key": []byte("my-value")}})
+
+	if _, err := store.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if _, err := store.Get(ctx, "other-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err := store.Create(ctx, "other-key", nil); err != errReadOnly {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, errReadOnly)
+	}
+	if err := store.Delete(ctx, "my-key"); err != errReadOnly {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, errReadOnly)
+	}
+}
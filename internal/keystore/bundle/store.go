@@ -0,0 +1,89 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// errReadOnly is returned when trying to modify
+// the keys of a bundle.
+var errReadOnly = kes.NewError(http.StatusNotImplemented, "keystore is read-only: keys are provisioned by an offline key bundle")
+
+// Store is a read-only key-value store that serves
+// the keys of a Bundle. It does not require any
+// network connectivity.
+type Store struct {
+	keys map[string][]byte
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil) // compiler check
+
+// NewStore returns a new Store that serves the
+// keys of the given bundle.
+func NewStore(bundle *Bundle) *Store {
+	keys := make(map[string][]byte, len(bundle.Keys))
+	for name, value := range bundle.Keys {
+		keys[name] = value
+	}
+	return &Store{keys: keys}
+}
+
+// Status returns the state of the store which
+// is always healthy.
+func (s *Store) Status(context.Context) (kv.State, error) {
+	return kv.State{}, nil
+}
+
+// Create returns an error since the store is read-only.
+func (s *Store) Create(context.Context, string, []byte) error { return errReadOnly }
+
+// Set returns an error since the store is read-only.
+func (s *Store) Set(context.Context, string, []byte) error { return errReadOnly }
+
+// Delete returns an error since the store is read-only.
+func (s *Store) Delete(context.Context, string) error { return errReadOnly }
+
+// Get returns the key associated with the given name.
+// If no entry for this name exists it returns
+// kes.ErrKeyNotFound.
+func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
+	value, ok := s.keys[name]
+	if !ok {
+		return nil, kes.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// List returns a new iterator over the names of
+// all keys in the bundle.
+func (s *Store) List(context.Context) (kv.Iter[string], error) {
+	names := make([]string, 0, len(s.keys))
+	for name := range s.keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &iterator{names: names}, nil
+}
+
+type iterator struct {
+	names []string
+}
+
+func (i *iterator) Next() (string, bool) {
+	if len(i.names) == 0 {
+		return "", false
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return name, true
+}
+
+func (*iterator) Close() error { return nil }
@@ -84,7 +84,7 @@ func (c *Conn) Create(_ context.Context, name string, value []byte) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	filename := filepath.Join(c.dir, name)
+	filename := filepath.Join(c.dir, fileName(name))
 	switch err := c.create(filename, value); {
 	case errors.Is(err, os.ErrExist):
 		return kes.ErrKeyExists
@@ -107,7 +107,7 @@ func (c *Conn) Get(_ context.Context, name string) ([]byte, error) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	file, err := os.Open(filepath.Join(c.dir, name))
+	file, err := os.Open(filepath.Join(c.dir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return nil, kes.ErrKeyNotFound
 	}
@@ -133,7 +133,7 @@ func (c *Conn) Delete(_ context.Context, name string) error {
 	if err := validName(name); err != nil {
 		return err
 	}
-	switch err := os.Remove(filepath.Join(c.dir, name)); {
+	switch err := os.Remove(filepath.Join(c.dir, fileName(name))); {
 	case errors.Is(err, os.ErrNotExist):
 		return kes.ErrKeyNotFound
 	default:
@@ -205,7 +205,7 @@ func (i *Iter) Next() bool {
 	if i.closed || i.err != nil {
 		return false
 	}
-	if len(i.names) > 0 {
+	if len(i.names) > 1 {
 		i.names = i.names[1:]
 		return true
 	}
@@ -239,7 +239,7 @@ func (i *Iter) Next() bool {
 // entries or once the Iter has encountered an error.
 func (i *Iter) Name() string {
 	if len(i.names) > 0 && !i.closed && i.err == nil {
-		return i.names[0].Name()
+		return entryName(i.names[0].Name())
 	}
 	return ""
 }
@@ -262,9 +262,25 @@ func (i *Iter) Close() error {
 
 func validName(name string) error {
 	if name == "" || strings.IndexFunc(name, func(c rune) bool {
-		return c == '/' || c == '\\' || c == '.'
+		return c == '\\' || c == '%'
 	}) >= 0 {
 		return errors.New("fs: key name contains invalid character")
 	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment[0] == '.' {
+			return errors.New("fs: key name contains invalid path segment")
+		}
+	}
 	return nil
 }
+
+// fileName returns the name of the file that stores the
+// named key. Key names may start with a prefix, like
+// "sys/my-key". Since '/' is a path separator, it is
+// replaced by '%' - a character that is not allowed
+// for key names.
+func fileName(name string) string { return strings.ReplaceAll(name, "/", "%") }
+
+// entryName returns the name of the key stored in the
+// file with the given name. It reverses fileName.
+func entryName(filename string) string { return strings.ReplaceAll(filename, "%", "/") }
@@ -4,7 +4,12 @@
 
 package fs
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 var validNameTests = []struct {
 	Name  string
@@ -14,14 +19,20 @@ var validNameTests = []struct {
 	{Name: ".", Valid: false},
 	{Name: "..", Valid: false},
 	{Name: ".my-key", Valid: false},
-	{Name: "my.key", Valid: false},
+	{Name: "my.key", Valid: true},
 	{Name: "/my-key", Valid: false},
 	{Name: "\\my-key", Valid: false},
 	{Name: "my-key/", Valid: false},
-	{Name: "my/key", Valid: false},
+	{Name: "my/key", Valid: true},
+	{Name: "my/key/", Valid: false},
+	{Name: "my//key", Valid: false},
+	{Name: "my%key", Valid: false},
 	{Name: "./my-key", Valid: false},
 	{Name: "./../my-key", Valid: false},
 	{Name: "my-key", Valid: true},
+	{Name: "my/key/v1.2", Valid: true},
+	{Name: "my/.key", Valid: false},
+	{Name: "my/../key", Valid: false},
 }
 
 func TestValidName(t *testing.T) {
@@ -31,3 +42,28 @@ func TestValidName(t *testing.T) {
 		}
 	}
 }
+
+func TestIter(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"key-1", "key-2"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	file, err := os.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to open directory: %v", err)
+	}
+
+	iter := NewIter(context.Background(), file)
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Name())
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list directory: %v", err)
+	}
+	if len(names) != 2 || names[0] == "" || names[1] == "" {
+		t.Fatalf("Invalid names: got '%q' - want '%d' names", names, 2)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/kv"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// KMSConfig is a structure containing configuration
+// options for wrapping keys with a GCP Cloud KMS key.
+type KMSConfig struct {
+	// Endpoint is the GCP Cloud KMS endpoint.
+	// If empty, the default endpoint is used.
+	Endpoint string
+
+	// Key is the resource name of the Cloud KMS
+	// key that wraps the keys. It has the form:
+	//  projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>
+	Key string
+
+	// Credentials are the GCP service account credentials.
+	// If empty, the credentials are fetched from the
+	// environment.
+	Credentials Credentials
+
+	// Scopes are GCP OAuth2 scopes for accessing
+	// Cloud KMS. If empty, the cloudkms scope is
+	// used.
+	Scopes []string
+}
+
+// KMS wraps keys with a GCP Cloud KMS key.
+type KMS struct {
+	key     string
+	service *cloudkms.ProjectsLocationsKeyRingsCryptoKeysService
+}
+
+// ConnectKMS establishes and returns a KMS that wraps
+// keys with the Cloud KMS key specified by the config.
+func ConnectKMS(ctx context.Context, config *KMSConfig) (*KMS, error) {
+	elems := strings.Split(config.Key, "/")
+	if len(elems) != 8 || elems[0] != "projects" || elems[2] != "locations" || elems[4] != "keyRings" || elems[6] != "cryptoKeys" {
+		return nil, fmt.Errorf("gcp: invalid Cloud KMS key name '%s'", config.Key)
+	}
+
+	var options []option.ClientOption
+	if config.Endpoint != "" {
+		options = append(options, option.WithEndpoint(config.Endpoint))
+	}
+	if creds := config.Credentials; creds != (Credentials{}) {
+		creds.projectID = elems[1]
+		if creds.Client == "" {
+			return nil, errors.New("gcp: no client email provided")
+		}
+		if creds.ClientID == "" {
+			return nil, errors.New("gcp: no client ID provided")
+		}
+		if creds.Key == "" {
+			return nil, errors.New("gcp: no client private key provided")
+		}
+		if creds.KeyID == "" {
+			return nil, errors.New("gcp: no client private key ID provided")
+		}
+		credentialsJSON, err := creds.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option.WithCredentialsJSON(credentialsJSON))
+	}
+	if len(config.Scopes) != 0 {
+		options = append(options, option.WithScopes(config.Scopes...))
+	} else {
+		options = append(options, option.WithScopes(cloudkms.CloudkmsScope))
+	}
+
+	service, err := cloudkms.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	k := &KMS{
+		key:     config.Key,
+		service: cloudkms.NewProjectsLocationsKeyRingsCryptoKeysService(service),
+	}
+	if _, err = k.Status(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Status returns the current state of the Cloud KMS key.
+// It returns an error if the key cannot be used to wrap
+// keys, e.g. because its primary version has been disabled.
+func (k *KMS) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	key, err := k.service.Get(k.key).Context(ctx).Do()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return kv.State{}, err
+		}
+		var nErr net.Error
+		if errors.As(err, &nErr) {
+			return kv.State{}, &kv.Unreachable{Err: err}
+		}
+		return kv.State{}, fmt.Errorf("gcp: failed to fetch Cloud KMS key '%s': %v", k.key, err)
+	}
+	if key.Primary == nil || key.Primary.State != "ENABLED" {
+		return kv.State{}, fmt.Errorf("gcp: Cloud KMS key '%s' has no enabled primary version", k.key)
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Wrap encrypts the plaintext with the Cloud KMS key.
+func (k *KMS) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	resp, err := k.service.Encrypt(k.key, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(plaintext),
+	}).Context(ctx).Do()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("gcp: failed to encrypt with Cloud KMS key '%s': %v", k.key, err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Ciphertext)
+}
+
+// Unwrap decrypts the ciphertext with the Cloud KMS key.
+func (k *KMS) Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	resp, err := k.service.Decrypt(k.key, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}).Context(ctx).Do()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("gcp: failed to decrypt with Cloud KMS key '%s': %v", k.key, err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
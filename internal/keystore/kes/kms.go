@@ -0,0 +1,70 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+)
+
+// KMS wraps keys with a key stored at a KES server,
+// e.g. a KES server that uses a hardware security
+// module as keystore.
+type KMS struct {
+	conn *Conn
+	key  string
+}
+
+// ConnectKMS connects to a KES server with the given
+// configuration and returns a KMS that wraps keys with
+// the named key.
+func ConnectKMS(ctx context.Context, config *Config, key string) (*KMS, error) {
+	if key == "" {
+		return nil, errors.New("kes: no key name provided")
+	}
+	conn, err := Connect(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	k := &KMS{
+		conn: conn,
+		key:  key,
+	}
+	if _, err = k.Status(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Status returns the current state of the KES server.
+// It returns an error if the key does not exist.
+func (k *KMS) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	_, err := k.conn.client.Enclave(k.conn.enclave).DescribeKey(ctx, k.key)
+	if connErr, ok := kes.IsConnError(err); ok {
+		return kv.State{}, &kv.Unreachable{Err: connErr}
+	}
+	if err != nil {
+		return kv.State{}, fmt.Errorf("kes: failed to describe key '%s': %w", k.key, err)
+	}
+	return kv.State{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Wrap encrypts the plaintext with the key at the KES server.
+func (k *KMS) Wrap(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return k.conn.client.Enclave(k.conn.enclave).Encrypt(ctx, k.key, plaintext, nil)
+}
+
+// Unwrap decrypts the ciphertext with the key at the KES server.
+func (k *KMS) Unwrap(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return k.conn.client.Enclave(k.conn.enclave).Decrypt(ctx, k.key, ciphertext, nil)
+}
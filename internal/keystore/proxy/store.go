@@ -0,0 +1,102 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package proxy implements a keystore that delegates
+// all cryptographic operations to keys at an external
+// KMS, like AWS KMS, GCP Cloud KMS or Azure KeyVault.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/kv"
+)
+
+// errReadOnly is returned when trying to modify
+// the keys of an external KMS.
+var errReadOnly = kes.NewError(http.StatusNotImplemented, "keystore is read-only: keys are managed by the external KMS")
+
+// Store is a read-only key-value store that serves
+// references to the keys of an external KMS. It does
+// not hold any key material. Instead, each key name
+// refers to the key at the external KMS with the
+// same name, optionally prefixed.
+//
+// Keys served by a Store are external keys. Hence,
+// all cryptographic operations are delegated to the
+// external KMS.
+type Store struct {
+	kms    string
+	prefix string
+	conn   key.ExternalKMS
+}
+
+var _ kv.Store[string, []byte] = (*Store)(nil) // compiler check
+
+// NewStore returns a new Store that serves the keys of
+// the external KMS with the given name. The key ID at
+// the external KMS is the key name prefixed with prefix.
+func NewStore(kms, prefix string, conn key.ExternalKMS) *Store {
+	return &Store{
+		kms:    kms,
+		prefix: prefix,
+		conn:   conn,
+	}
+}
+
+// Status returns the state of the store which is
+// always healthy. The external KMS is reached on
+// first use of a key.
+func (s *Store) Status(context.Context) (kv.State, error) {
+	return kv.State{}, nil
+}
+
+// Create returns an error since the store is read-only.
+func (s *Store) Create(context.Context, string, []byte) error { return errReadOnly }
+
+// Set returns an error since the store is read-only.
+func (s *Store) Set(context.Context, string, []byte) error { return errReadOnly }
+
+// Delete returns an error since the store is read-only.
+func (s *Store) Delete(context.Context, string) error { return errReadOnly }
+
+// Get returns a reference to the key at the external
+// KMS with the given name. It verifies that the key
+// exists and can be used.
+//
+// If the external KMS reports that no such key exists,
+// Get returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	ext := key.External{
+		KMS:   s.kms,
+		KeyID: s.prefix + name,
+	}
+	if _, err := s.conn.Key(ctx, ext.KeyID); err != nil {
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			return nil, kes.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	k, err := key.NewExternal(ext, "")
+	if err != nil {
+		return nil, err
+	}
+	return k.MarshalText()
+}
+
+// List returns an empty iterator since the keys
+// of an external KMS cannot be listed.
+func (s *Store) List(context.Context) (kv.Iter[string], error) {
+	return iterator{}, nil
+}
+
+type iterator struct{}
+
+func (iterator) Next() (string, bool) { return "", false }
+
+func (iterator) Close() error { return nil }
@@ -5,6 +5,7 @@
 package vault
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -12,25 +13,47 @@ import (
 )
 
 type iterator struct {
-	values []interface{}
+	ctx    context.Context
+	list   func(context.Context, string) ([]interface{}, error)
+	prefix string
+
+	dir    string        // The path of the current values
+	values []interface{} // Key names and sub-paths within dir
+	dirs   []string      // Sub-paths that have not been listed yet
 	last   string
+	err    error
 }
 
 var _ kms.Iter = (*iterator)(nil)
 
 func (i *iterator) Next() bool {
-	for len(i.values) > 0 {
-		v := fmt.Sprint(i.values[0])
-		i.values = i.values[1:]
+	for {
+		for len(i.values) > 0 {
+			v := i.dir + fmt.Sprint(i.values[0])
+			i.values = i.values[1:]
+
+			if strings.HasSuffix(v, "/") { // Only descend into sub-paths that may contain keys with the prefix
+				if strings.HasPrefix(v, i.prefix) || strings.HasPrefix(i.prefix, v) {
+					i.dirs = append(i.dirs, v)
+				}
+				continue
+			}
+			if strings.HasPrefix(v, i.prefix) {
+				i.last = v
+				return true
+			}
+		}
+		if len(i.dirs) == 0 || i.list == nil || i.err != nil {
+			return false
+		}
 
-		if !strings.HasSuffix(v, "/") { // Ignore prefixes; only iterator over actual entries
-			i.last = v
-			return true
+		i.dir, i.dirs = i.dirs[0], i.dirs[1:]
+		if i.values, i.err = i.list(i.ctx, i.dir); i.err != nil {
+			return false
 		}
 	}
-	return false
 }
 
 func (i *iterator) Name() string { return i.last }
 
-func (*iterator) Close() error { return nil }
+func (i *iterator) Close() error { return i.err }
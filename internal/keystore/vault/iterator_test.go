@@ -0,0 +1,84 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIteratorPrefix(t *testing.T) {
+	dirs := map[string][]interface{}{
+		"":                  {"my-key", "tenants/"},
+		"tenants/":          {"acme/", "acme2/", "other/", "key-0"},
+		"tenants/acme/":     {"key-1", "dev/"},
+		"tenants/acme/dev/": {"key-2"},
+		"tenants/acme2/":    {"key-3"},
+		"tenants/other/":    {"key-4"},
+	}
+	var listed []string
+	list := func(_ context.Context, dir string) ([]interface{}, error) {
+		listed = append(listed, dir)
+		return dirs[dir], nil
+	}
+
+	for i, test := range iteratorPrefixTests {
+		listed = listed[:0]
+		dir := test.Prefix[:strings.LastIndexByte(test.Prefix, '/')+1]
+		iter := &iterator{
+			list:   list,
+			prefix: test.Prefix,
+			dir:    dir,
+			values: dirs[dir],
+		}
+
+		var names []string
+		for iter.Next() {
+			names = append(names, iter.Name())
+		}
+		if err := iter.Close(); err != nil {
+			t.Fatalf("Test %d: failed to list: %v", i, err)
+		}
+		if len(names) != len(test.Names) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, names, test.Names)
+		}
+		for j := range names {
+			if names[j] != test.Names[j] {
+				t.Fatalf("Test %d: got '%v' - want '%v'", i, names, test.Names)
+			}
+		}
+		if len(listed) != test.Listed {
+			t.Fatalf("Test %d: listed %d paths - want %d: %v", i, len(listed), test.Listed, listed)
+		}
+	}
+}
+
+var iteratorPrefixTests = []struct {
+	Prefix string
+	Names  []string
+	Listed int // Number of sub-paths listed by the iterator
+}{
+	{ // 0
+		Prefix: "",
+		Names:  []string{"my-key", "tenants/key-0", "tenants/acme/key-1", "tenants/acme2/key-3", "tenants/other/key-4", "tenants/acme/dev/key-2"},
+		Listed: 5,
+	},
+	{ // 1
+		Prefix: "tenants/acme/",
+		Names:  []string{"tenants/acme/key-1", "tenants/acme/dev/key-2"},
+		Listed: 1,
+	},
+	{ // 2
+		Prefix: "tenants/acme",
+		Names:  []string{"tenants/acme/key-1", "tenants/acme2/key-3", "tenants/acme/dev/key-2"},
+		Listed: 3,
+	},
+	{ // 3
+		Prefix: "tenants/k",
+		Names:  []string{"tenants/key-0"},
+		Listed: 0,
+	},
+}
@@ -19,6 +19,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"aead.dev/mem"
@@ -220,7 +221,7 @@ func (s *Conn) Create(ctx context.Context, name string, value []byte) error {
 	// But when the client returns an error it does not mean that
 	// the entry does not exist but that some other error (e.g.
 	// network error) occurred.
-	switch secret, err := s.client.Logical().Read(location); {
+	switch secret, err := s.client.Logical().ReadWithContext(ctx, location); {
 	case err == nil && secret != nil && s.config.APIVersion != APIv2:
 		if _, ok := secret.Data[name]; !ok {
 			return fmt.Errorf("vault: entry exist but failed to read '%s': invalid K/V v1 format", location)
@@ -240,6 +241,8 @@ func (s *Conn) Create(ctx context.Context, name string, value []byte) error {
 			return fmt.Errorf("vault: failed to read '%s': entry exists but no secret key is present", location)
 		}
 		return kes.ErrKeyExists
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return err
 	case err != nil:
 		return fmt.Errorf("vault: failed to create '%s': %v", location, err)
 	}
@@ -278,6 +281,9 @@ func (s *Conn) Create(ctx context.Context, name string, value []byte) error {
 		return fmt.Errorf("vault: failed to create '%s': %v", location, err)
 	}
 	resp, err := s.client.Client.RawRequestWithContext(ctx, req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("vault: failed to create '%s': %v", location, err)
 	}
@@ -298,7 +304,7 @@ func (s *Conn) Create(ctx context.Context, name string, value []byte) error {
 
 // Get returns the value associated with the given key.
 // If no entry for the key exists it returns kes.ErrKeyNotFound.
-func (s *Conn) Get(_ context.Context, name string) ([]byte, error) {
+func (s *Conn) Get(ctx context.Context, name string) ([]byte, error) {
 	if s.client.Sealed() {
 		return nil, errSealed
 	}
@@ -311,7 +317,10 @@ func (s *Conn) Get(_ context.Context, name string) ([]byte, error) {
 		// See: https://www.vaultproject.io/api/secret/kv/kv-v1#read-secret
 		location = path.Join(s.config.Engine, s.config.Prefix, name) // /<engine>/<location>/<name>
 	}
-	entry, err := s.client.Logical().Read(location)
+	entry, err := s.client.Logical().ReadWithContext(ctx, location)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
 	if err != nil || entry == nil {
 		// Vault will not return an error if e.g. the key existed but has
 		// been deleted. However, it will return (nil, nil) in this case.
@@ -370,6 +379,9 @@ func (s *Conn) Delete(ctx context.Context, name string) error {
 	// error.
 	req := s.client.Client.NewRequest(http.MethodDelete, "/v1/"+location)
 	resp, err := s.client.Client.RawRequestWithContext(ctx, req)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("vault: failed to delete '%s': %v", location, err)
 	}
@@ -387,11 +399,40 @@ func (s *Conn) Delete(ctx context.Context, name string) error {
 
 // List returns a new Iterator over the names of
 // all stored keys.
-func (s *Conn) List(ctx context.Context) (kms.Iter, error) {
+func (s *Conn) List(ctx context.Context) (kms.Iter, error) { return s.ListPrefix(ctx, "") }
+
+// ListPrefix returns a new Iterator over the names of
+// all stored keys that start with the prefix.
+//
+// Vault stores keys with a '/'-separated name, like
+// "tenants/acme/my-key", hierarchically. ListPrefix
+// only lists the paths that may contain keys with the
+// prefix and descends into sub-paths as needed.
+func (s *Conn) ListPrefix(ctx context.Context, prefix string) (kms.Iter, error) {
 	if s.client.Sealed() {
 		return nil, errSealed
 	}
 
+	var dir string // The path containing all keys with the prefix
+	if i := strings.LastIndexByte(prefix, '/'); i >= 0 {
+		dir = prefix[:i+1]
+	}
+	values, err := s.list(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return kms.FuseIter(&iterator{
+		ctx:    ctx,
+		list:   s.list,
+		prefix: prefix,
+		dir:    dir,
+		values: values,
+	}), nil
+}
+
+// list returns the names of all keys and sub-paths,
+// ending with a '/', within the given directory.
+func (s *Conn) list(ctx context.Context, dir string) ([]interface{}, error) {
 	// We don't use the Vault SDK vault.Logical.List(string) API
 	// here since the SDK does not allow us to specify a context.
 	// However, if the client closes the connection (or a timeout
@@ -401,16 +442,19 @@ func (s *Conn) List(ctx context.Context) (kms.Iter, error) {
 	var location string
 	if s.config.APIVersion == APIv2 {
 		// See: https://www.vaultproject.io/api/secret/kv/kv-v2#list-secrets
-		location = path.Join(s.config.Engine, "metadata", s.config.Prefix)
+		location = path.Join(s.config.Engine, "metadata", s.config.Prefix, dir)
 	} else {
 		// See: https://www.vaultproject.io/api/secret/kv/kv-v1#list-secrets
-		location = path.Join(s.config.Engine, s.config.Prefix)
+		location = path.Join(s.config.Engine, s.config.Prefix, dir)
 	}
 
 	r := s.client.NewRequest("LIST", "/v1/"+location)
 	r.Params.Set("list", "true")
 
 	resp, err := s.client.RawRequestWithContext(ctx, r)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("vault: failed to list '%s': %v", location, err)
 	}
@@ -426,7 +470,7 @@ func (s *Conn) List(ctx context.Context) (kms.Iter, error) {
 		return nil, fmt.Errorf("vault: failed to list '%s': %v", location, err)
 	}
 	if secret == nil { // The secret may be nil even when there was no error.
-		return &iterator{}, nil // We return an empty iterator in this case.
+		return nil, nil // We return an empty listing in this case.
 	}
 
 	// Vault returns a generic map that should contain
@@ -437,7 +481,5 @@ func (s *Conn) List(ctx context.Context) (kms.Iter, error) {
 	if !ok {
 		return nil, fmt.Errorf("vault: failed to list '%s': invalid key listing format", location)
 	}
-	return kms.FuseIter(&iterator{
-		values: values,
-	}), nil
+	return values, nil
 }
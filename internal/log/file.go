@@ -0,0 +1,344 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotationTimeFormat is the time format of the
+// timestamp that is appended to rotated files.
+const rotationTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileConfig is a structure containing the rotation
+// and retention configuration of a File.
+type FileConfig struct {
+	// MaxSize is the max. size of the log file in bytes.
+	// The file is rotated before a write would exceed
+	// MaxSize. If <= 0, the file is not rotated based
+	// on its size.
+	MaxSize int64
+
+	// Interval is the max. amount of time a log file is
+	// written to. The file is rotated on the first write
+	// once Interval has passed since the file has been
+	// opened. If <= 0, the file is not rotated based on
+	// time.
+	Interval time.Duration
+
+	// MaxFiles is the max. number of rotated files to
+	// retain. The oldest rotated files are removed once
+	// there are more than MaxFiles. If <= 0, rotated files
+	// are not removed based on their number.
+	MaxFiles int
+
+	// MaxAge is the max. amount of time rotated files are
+	// retained. If <= 0, rotated files are not removed based
+	// on their age.
+	MaxAge time.Duration
+
+	// Compress determines whether rotated files are
+	// gzip compressed.
+	Compress bool
+}
+
+// File is an io.Writer that writes to a log file on disk.
+// It rotates the log file once it exceeds the configured
+// max. size or age. Rotated files are renamed by inserting
+// the rotation time between the file name and extension.
+// For example, "audit.log" becomes:
+//
+//	audit-2023-01-02T15-04-05.000.log
+//
+// Rotated files are compressed and removed in the background
+// according to the FileConfig.
+//
+// A File may be used concurrently by multiple goroutines.
+type File struct {
+	path   string
+	config FileConfig
+
+	lock     sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	cleanup     sync.WaitGroup
+	cleanupLock sync.Mutex
+}
+
+// OpenFile opens the log file at the given path, creating it
+// if it does not exist. New log data is appended to an existing
+// log file.
+func OpenFile(path string, config FileConfig) (*File, error) {
+	if path == "" {
+		return nil, errors.New("log: empty file path")
+	}
+	f := &File{
+		path:   path,
+		config: config,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Name returns the path of the log file.
+func (f *File) Name() string { return f.path }
+
+// Write writes p to the log file. It rotates the
+// log file before writing if required.
+func (f *File) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.rotationRequired(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current log file, renames it and
+// opens a new log file. It does nothing if the current
+// log file is empty.
+func (f *File) Rotate() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.size == 0 {
+		return nil
+	}
+	return f.rotate()
+}
+
+// Close closes the log file. It waits until all
+// rotated files have been compressed and removed.
+func (f *File) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	err := f.file.Close()
+	f.cleanup.Wait()
+	return err
+}
+
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = stat.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+func (f *File) rotationRequired(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxSize > 0 && f.size+n > f.config.MaxSize {
+		return true
+	}
+	return f.config.Interval > 0 && time.Since(f.openedAt) >= f.config.Interval
+}
+
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	dir, prefix, ext := f.splitPath()
+	now := time.Now().UTC()
+	rotated := filepath.Join(dir, prefix+now.Format(rotationTimeFormat)+ext)
+	for fileExists(rotated) || fileExists(rotated+".gz") { // Multiple rotations within the same millisecond
+		now = now.Add(time.Millisecond)
+		rotated = filepath.Join(dir, prefix+now.Format(rotationTimeFormat)+ext)
+	}
+	if err := os.Rename(f.path, rotated); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+
+		f.cleanupLock.Lock()
+		defer f.cleanupLock.Unlock()
+
+		if f.config.Compress {
+			if err := compressFile(rotated); err != nil {
+				Printf("failed to compress rotated log file '%s': %v", rotated, err)
+			}
+		}
+		if err := f.removeExpired(); err != nil {
+			Printf("failed to remove rotated log files: %v", err)
+		}
+	}()
+	return nil
+}
+
+// RotatedFile is a rotated log file.
+type RotatedFile struct {
+	// Path is the path of the rotated file.
+	Path string
+
+	// Time is the point in time when the
+	// file has been rotated.
+	Time time.Time
+}
+
+// Rotated returns all rotated files of the log
+// file sorted by their rotation time, oldest
+// first.
+//
+// While a rotated file is being compressed, Rotated
+// returns the uncompressed file.
+func (f *File) Rotated() ([]RotatedFile, error) {
+	dir, prefix, ext := f.splitPath()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []RotatedFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		timestamp := strings.TrimPrefix(name, prefix)
+		timestamp = strings.TrimSuffix(timestamp, ".gz")
+		if !strings.HasSuffix(timestamp, ext) {
+			continue
+		}
+		t, err := time.Parse(rotationTimeFormat, strings.TrimSuffix(timestamp, ext))
+		if err != nil {
+			continue
+		}
+		if n := len(files); n > 0 && files[n-1].Time.Equal(t) {
+			continue // Entries are sorted by name: "<file>" is listed before "<file>.gz"
+		}
+		files = append(files, RotatedFile{Path: filepath.Join(dir, name), Time: t})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Time.Before(files[j].Time) })
+	return files, nil
+}
+
+// removeExpired removes all rotated files that exceed
+// the retention limits.
+func (f *File) removeExpired() error {
+	if f.config.MaxFiles <= 0 && f.config.MaxAge <= 0 {
+		return nil
+	}
+
+	files, err := f.Rotated()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range files {
+		file := files[len(files)-1-i] // Newest first
+		expired := f.config.MaxFiles > 0 && i >= f.config.MaxFiles
+		if f.config.MaxAge > 0 && now.Sub(file.Time) > f.config.MaxAge {
+			expired = true
+		}
+		if !expired {
+			continue
+		}
+		if err = os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitPath splits the log file path into its directory,
+// the prefix of rotated files and the file extension.
+func (f *File) splitPath() (dir, prefix, ext string) {
+	dir, name := filepath.Split(f.path)
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile gzip compresses the file at path
+// into path + ".gz" and removes the original file.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
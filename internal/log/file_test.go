@@ -0,0 +1,101 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileRotate(t *testing.T) {
+	const Line = "Hello World\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	file, err := OpenFile(path, FileConfig{
+		MaxSize:  2 * int64(len(Line)),
+		MaxFiles: 2,
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err = io.WriteString(file, Line); err != nil {
+			t.Fatalf("Failed to write to log file: %v", err)
+		}
+	}
+	if err = file.Close(); err != nil {
+		t.Fatalf("Failed to close log file: %v", err)
+	}
+	if _, err = io.WriteString(file, Line); err != os.ErrClosed {
+		t.Fatalf("Write to closed log file: got error '%v' - want '%v'", err, os.ErrClosed)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if s := string(content); s != strings.Repeat(Line, 2) {
+		t.Fatalf("Log file content mismatch: got '%s' - want '%s'", s, strings.Repeat(Line, 2))
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(dir, "audit-*.log.gz"))
+	if err != nil {
+		t.Fatalf("Failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("Rotated file count mismatch: got %d - want 2", len(rotated))
+	}
+	if uncompressed, _ := filepath.Glob(filepath.Join(dir, "audit-*.log")); len(uncompressed) != 0 {
+		t.Fatalf("Rotated files have not been compressed: %v", uncompressed)
+	}
+	for _, name := range rotated {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatalf("Failed to open rotated file: %v", err)
+		}
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Failed to decompress rotated file: %v", err)
+		}
+		content, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Failed to decompress rotated file: %v", err)
+		}
+		if s := string(content); s != strings.Repeat(Line, 2) {
+			t.Fatalf("Rotated file content mismatch: got '%s' - want '%s'", s, strings.Repeat(Line, 2))
+		}
+	}
+}
+
+func TestFileAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		file, err := OpenFile(path, FileConfig{})
+		if err != nil {
+			t.Fatalf("Failed to open log file: %v", err)
+		}
+		if _, err = io.WriteString(file, "Hello World\n"); err != nil {
+			t.Fatalf("Failed to write to log file: %v", err)
+		}
+		if err = file.Close(); err != nil {
+			t.Fatalf("Failed to close log file: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if s := string(content); s != "Hello World\nHello World\n" {
+		t.Fatalf("Log file content mismatch: got '%s'", s)
+	}
+}
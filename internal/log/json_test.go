@@ -40,6 +40,14 @@ var jsonWriterWriteTests = []struct {
 		Content: "Hello \t World \r" + "\n",
 		Output:  `{"message":"Hello \t World \r"}` + "\n",
 	},
+	{
+		Content: `{"time":"2023-01-01T00:00:00Z","level":"ERROR","message":"Hello World","request_id":"1234"}` + "\n",
+		Output:  `{"time":"2023-01-01T00:00:00Z","level":"ERROR","message":"Hello World","request_id":"1234"}` + "\n",
+	},
+	{
+		Content: `{ Hello World }`,
+		Output:  `{"message":"{ Hello World }"}` + "\n",
+	},
 }
 
 func TestErrEncoderWrite(t *testing.T) {
@@ -0,0 +1,98 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Level is the importance or severity of a log entry.
+// The higher the level, the more important or severe
+// the entry.
+type Level int32
+
+// Supported log levels.
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// ParseLevel parses s as log level. It
+// ignores leading and trailing whitespaces
+// and is case-insensitive.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "INFO":
+		return LevelInfo, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	default:
+		return 0, errors.New("log: invalid log level '" + s + "'")
+	}
+}
+
+// String returns the level's string representation.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// MarshalText returns the level's text representation.
+func (l Level) MarshalText() ([]byte, error) { return []byte(l.String()), nil }
+
+// UnmarshalText parses text as log level.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// Fields are structured context attached
+// to a log entry. Empty fields are omitted.
+type Fields struct {
+	Component string // The server component, e.g. "api" or "keystore"
+	RequestID string // The ID of the request that caused the entry
+	Enclave   string // The enclave of the request
+	Identity  string // The identity that sent the request
+}
+
+// appendText appends the non-empty fields as
+// space-separated key=value pairs to b.
+func (f *Fields) appendText(b []byte) []byte {
+	appendField := func(key, value string) {
+		if value != "" {
+			b = append(b, ' ')
+			b = append(b, key...)
+			b = append(b, '=')
+			b = append(b, value...)
+		}
+	}
+	appendField("component", f.Component)
+	appendField("request_id", f.RequestID)
+	appendField("enclave", f.Enclave)
+	appendField("identity", f.Identity)
+	return b
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var parseLevelTests = []struct {
+	String     string
+	Level      Level
+	ShouldFail bool
+}{
+	{String: "debug", Level: LevelDebug},   // 0
+	{String: "INFO", Level: LevelInfo},     // 1
+	{String: " Warn ", Level: LevelWarn},   // 2
+	{String: "warning", Level: LevelWarn},  // 3
+	{String: "error", Level: LevelError},   // 4
+	{String: "", ShouldFail: true},         // 5
+	{String: "fatal", ShouldFail: true},    // 6
+	{String: "LEVEL(8)", ShouldFail: true}, // 7
+}
+
+func TestParseLevel(t *testing.T) {
+	for i, test := range parseLevelTests {
+		level, err := ParseLevel(test.String)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse level: %v", i, err)
+		}
+		if level != test.Level {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, level, test.Level)
+		}
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New(&buffer, "Error: ", 0)
+
+	logger.Logf(LevelDebug, Fields{}, "debug")
+	if buffer.Len() != 0 {
+		t.Fatalf("Logger wrote entry below its level: %s", buffer.String())
+	}
+	logger.SetLevel(LevelDebug)
+	logger.Logf(LevelDebug, Fields{Component: "api", RequestID: "1234"}, "debug")
+	if s := buffer.String(); !strings.HasSuffix(s, "DEBUG: debug component=api request_id=1234\n") {
+		t.Fatalf("Entry mismatch: got '%s'", s)
+	}
+
+	buffer.Reset()
+	logger.SetLevel(LevelError)
+	logger.Logf(LevelWarn, Fields{}, "warn")
+	logger.Printf("error %d", 1)
+	if s := buffer.String(); s != "Error: error 1\n" {
+		t.Fatalf("Entry mismatch: got '%s' - want '%s'", s, "Error: error 1\n")
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	type Entry struct {
+		Level     Level  `json:"level"`
+		Message   string `json:"message"`
+		Component string `json:"component"`
+		RequestID string `json:"request_id"`
+		Enclave   string `json:"enclave"`
+		Identity  string `json:"identity"`
+	}
+
+	var buffer bytes.Buffer
+	logger := New(&buffer, "Error: ", Ldate|Ltime)
+	logger.SetJSON(true)
+
+	fields := Fields{Component: "api", RequestID: "1234", Enclave: "tenant-1", Identity: "foo"}
+	logger.Logf(LevelError, fields, "Hello %s", "World")
+
+	var entry Entry
+	if err := json.Unmarshal(buffer.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode entry '%s': %v", buffer.String(), err)
+	}
+	want := Entry{Level: LevelError, Message: "Hello World", Component: "api", RequestID: "1234", Enclave: "tenant-1", Identity: "foo"}
+	if entry != want {
+		t.Fatalf("Entry mismatch: got '%+v' - want '%+v'", entry, want)
+	}
+
+	buffer.Reset()
+	logger.Log().Print("Hello World")
+	if err := json.Unmarshal(buffer.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode entry '%s': %v", buffer.String(), err)
+	}
+	if entry.Level != LevelError || entry.Message != "Hello World" {
+		t.Fatalf("Entry mismatch: got '%+v'", entry)
+	}
+}
@@ -6,10 +6,13 @@ package log
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // These flags define how a Logger generates text for each log entry.
@@ -80,25 +83,118 @@ func New(out io.Writer, prefix string, flags int) *Logger {
 // single call to the Writer's Write method. A Logger can be used
 // simultaneously from multiple goroutines; it guarantees to serialize
 // access to the Writer.
+//
+// Each log entry has a Level. A Logger only writes entries
+// with a level equal to or higher than its own level. The
+// Print, Printf and Println methods write entries with
+// LevelError.
+//
+// By default, a Logger writes log entries as text lines.
+// Once JSON is enabled, it writes each entry as JSON object
+// containing the time, level, message and all non-empty
+// Fields.
 type Logger struct {
 	log *log.Logger
 	out *multiWriter
+
+	level int32  // atomic Level
+	json  uint32 // atomic bool
 }
 
 // Print writes to the standard logger.
 // Arguments are handled in the manner
 // of fmt.Print.
-func (l *Logger) Print(v ...any) { l.log.Print(v...) }
+func (l *Logger) Print(v ...any) { l.output(LevelError, Fields{}, fmt.Sprint(v...)) }
 
 // Printf writes to the standard logger.
 // Arguments are handled in the manner
 // of fmt.Printf.
-func (l *Logger) Printf(format string, v ...any) { l.log.Printf(format, v...) }
+func (l *Logger) Printf(format string, v ...any) {
+	l.output(LevelError, Fields{}, fmt.Sprintf(format, v...))
+}
 
 // Println writes to the standard logger.
 // Arguments are handled in the manner
 // of fmt.Println.
-func (l *Logger) Println(v ...any) { l.log.Println(v...) }
+func (l *Logger) Println(v ...any) { l.output(LevelError, Fields{}, fmt.Sprintln(v...)) }
+
+// Logf writes a log entry with the given level and fields
+// if the level is enabled. Arguments are handled in the
+// manner of fmt.Printf.
+func (l *Logger) Logf(level Level, fields Fields, format string, v ...any) {
+	if l.Enabled(level) {
+		l.output(level, fields, fmt.Sprintf(format, v...))
+	}
+}
+
+// Level returns the logger's level.
+func (l *Logger) Level() Level { return Level(atomic.LoadInt32(&l.level)) }
+
+// SetLevel sets the logger's level. The logger only writes
+// log entries with a level equal to or higher than level.
+func (l *Logger) SetLevel(level Level) { atomic.StoreInt32(&l.level, int32(level)) }
+
+// Enabled reports whether the logger writes
+// log entries with the given level.
+func (l *Logger) Enabled(level Level) bool { return level >= l.Level() }
+
+// JSON reports whether the logger writes
+// log entries as JSON objects.
+func (l *Logger) JSON() bool { return atomic.LoadUint32(&l.json) == 1 }
+
+// SetJSON controls whether the logger writes
+// log entries as JSON objects or as text lines.
+func (l *Logger) SetJSON(enable bool) {
+	if enable {
+		atomic.StoreUint32(&l.json, 1)
+	} else {
+		atomic.StoreUint32(&l.json, 0)
+	}
+}
+
+// output writes the log entry if its level is enabled.
+func (l *Logger) output(level Level, fields Fields, msg string) {
+	if !l.Enabled(level) {
+		return
+	}
+	msg = strings.TrimSuffix(msg, "\n")
+
+	if l.JSON() {
+		type Entry struct {
+			Time      time.Time `json:"time"`
+			Level     Level     `json:"level"`
+			Message   string    `json:"message"`
+			Component string    `json:"component,omitempty"`
+			RequestID string    `json:"request_id,omitempty"`
+			Enclave   string    `json:"enclave,omitempty"`
+			Identity  string    `json:"identity,omitempty"`
+		}
+		b, err := json.Marshal(Entry{
+			Time:      time.Now().UTC(),
+			Level:     level,
+			Message:   msg,
+			Component: fields.Component,
+			RequestID: fields.RequestID,
+			Enclave:   fields.Enclave,
+			Identity:  fields.Identity,
+		})
+		if err == nil {
+			l.out.Write(append(b, '\n'))
+		}
+		return
+	}
+
+	msg = string(fields.appendText([]byte(msg)))
+	if level >= LevelError {
+		l.log.Output(3, msg) // Error entries use the logger's prefix and flags
+		return
+	}
+	b := time.Now().AppendFormat(nil, "2006/01/02 15:04:05 ")
+	b = append(b, level.String()...)
+	b = append(b, ": "...)
+	b = append(b, msg...)
+	l.out.Write(append(b, '\n'))
+}
 
 // Add adds one or multiple io.Writer as output to
 // the logger.
@@ -108,9 +204,18 @@ func (l *Logger) Add(out ...io.Writer) { l.out.Add(out...) }
 // logging output.
 func (l *Logger) Remove(out ...io.Writer) { l.out.Remove(out...) }
 
-// Log returns a new standard library log.Logger with
-// logger's output, prefix and flags.
-func (l *Logger) Log() *log.Logger { return log.New(l.log.Writer(), l.log.Prefix(), l.log.Flags()) }
+// Log returns a new standard library log.Logger that
+// writes log entries with LevelError to the logger.
+func (l *Logger) Log() *log.Logger { return log.New(errorWriter{l}, "", 0) }
+
+// errorWriter is an io.Writer that writes each
+// message as log entry with LevelError.
+type errorWriter struct{ l *Logger }
+
+func (w errorWriter) Write(p []byte) (int, error) {
+	w.l.output(LevelError, Fields{}, string(p))
+	return len(p), nil
+}
 
 // Writer returns the output destination for the logger.
 func (l *Logger) Writer() io.Writer { return l.out }
@@ -157,6 +262,16 @@ func (w *ErrEncoder) WriteString(s string) (int, error) {
 	// it's not part of the actual error message.
 	s = strings.TrimSuffix(s, "\n")
 
+	// Structured log entries already are JSON objects
+	// that contain a message field.
+	if strings.HasPrefix(s, "{") && json.Valid([]byte(s)) {
+		var entry json.RawMessage = []byte(s)
+		if err := w.encoder.Encode(entry); err != nil {
+			return 0, err
+		}
+		return len(s), nil
+	}
+
 	err := w.encoder.Encode(Response{
 		Message: s,
 	})
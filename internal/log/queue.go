@@ -0,0 +1,141 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"io"
+	"sync"
+)
+
+// Queue is an io.Writer that decouples writers from a
+// potentially slow io.Writer. It buffers up to a fixed
+// number of writes and writes them to the underlying
+// io.Writer in the background.
+//
+// Once the buffer is full, a Queue drops the oldest
+// buffered write to make room for the newest. Hence,
+// writing to a Queue never blocks on the underlying
+// io.Writer. For example, a slow audit log subscriber
+// cannot slow down request handlers.
+type Queue struct {
+	out    io.Writer
+	size   int
+	onDrop func()
+
+	lock    sync.Mutex
+	entries [][]byte
+	dropped uint64
+	closed  bool
+
+	notify chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewQueue returns a new Queue that buffers up to size
+// writes and writes them to out until it is closed. If
+// size <= 0, the queue buffers up to 1000 writes.
+//
+// The onDrop function, if not nil, is called whenever
+// the queue drops a write.
+func NewQueue(out io.Writer, size int, onDrop func()) *Queue {
+	if size <= 0 {
+		size = 1000
+	}
+	q := &Queue{
+		out:    out,
+		size:   size,
+		onDrop: onDrop,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// Write buffers a copy of p. It drops the oldest
+// buffered write if the queue is full. Write does
+// not return an error. Writes to a closed queue are
+// discarded.
+func (q *Queue) Write(p []byte) (int, error) {
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return len(p), nil
+	}
+	var dropped bool
+	if len(q.entries) >= q.size {
+		q.entries[0] = nil
+		q.entries = q.entries[1:]
+		q.dropped++
+		dropped = true
+	}
+	q.entries = append(q.entries, entry)
+	q.lock.Unlock()
+
+	if dropped && q.onDrop != nil {
+		q.onDrop()
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of writes the queue has
+// dropped because it was full.
+func (q *Queue) Dropped() uint64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.dropped
+}
+
+// Close stops writing to the underlying io.Writer and
+// discards all buffered writes. It waits until any
+// in-progress write to the underlying io.Writer returns.
+func (q *Queue) Close() error {
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.entries = nil
+	q.lock.Unlock()
+
+	close(q.done)
+	q.wg.Wait()
+	return nil
+}
+
+func (q *Queue) run() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.notify:
+		}
+
+		for {
+			q.lock.Lock()
+			if q.closed || len(q.entries) == 0 {
+				q.lock.Unlock()
+				break
+			}
+			entry := q.entries[0]
+			q.entries[0] = nil
+			q.entries = q.entries[1:]
+			q.lock.Unlock()
+
+			q.out.Write(entry)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks all writes until it is released.
+type blockingWriter struct {
+	release chan struct{}
+
+	lock  sync.Mutex
+	lines []string
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func (w *blockingWriter) Lines() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]string(nil), w.lines...)
+}
+
+func TestQueue(t *testing.T) {
+	const Size = 4
+
+	out := &blockingWriter{release: make(chan struct{})}
+	var dropped int
+	queue := NewQueue(out, Size, func() { dropped++ })
+
+	// The first write is picked up by the background
+	// writer which blocks. Wait until the queue is empty
+	// again such that the following writes get buffered.
+	queue.Write([]byte("0"))
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		queue.lock.Lock()
+		n := len(queue.entries)
+		queue.lock.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Queue has not started writing")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	for i := 1; i <= 10; i++ {
+		queue.Write([]byte(strconv.Itoa(i)))
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Writing to a full queue blocked")
+	}
+	if n := queue.Dropped(); n != 10-Size {
+		t.Fatalf("Invalid number of dropped writes: got '%d' - want '%d'", n, 10-Size)
+	}
+	if dropped != 10-Size {
+		t.Fatalf("Invalid number of drop callbacks: got '%d' - want '%d'", dropped, 10-Size)
+	}
+
+	close(out.release)
+	want := []string{"0", "7", "8", "9", "10"} // Oldest writes have been dropped
+	for deadline := time.Now().Add(5 * time.Second); len(out.Lines()) < len(want); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Queue has not written all entries: got '%v' - want '%v'", out.Lines(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lines := out.Lines()
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("Invalid queue output: got '%v' - want '%v'", lines, want)
+		}
+	}
+
+	if err := queue.Close(); err != nil {
+		t.Fatalf("Failed to close queue: %v", err)
+	}
+	queue.Write([]byte("11"))
+	if n := len(out.Lines()); n != len(want) {
+		t.Fatalf("Write to closed queue: got '%d' lines - want '%d'", n, len(want))
+	}
+}
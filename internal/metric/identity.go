@@ -0,0 +1,104 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/minio/kes-go"
+)
+
+// MaxIdentities is the max. number of identities
+// for which Metrics track request statistics.
+const MaxIdentities = 1000
+
+// IdentityStat contains the request statistics
+// of one identity.
+type IdentityStat struct {
+	Identity kes.Identity
+
+	Requests uint64 // Number of requests
+	Errors   uint64 // Number of requests that failed with a 4xx status code
+	Failures uint64 // Number of requests that failed with a 5xx status code
+}
+
+// identityStats tracks the request statistics of the identities
+// that send the most requests. Its memory usage is bounded since
+// it tracks at most capacity identities.
+//
+// Once full, it replaces the identity with the fewest requests
+// whenever a new identity sends a request. The new identity
+// inherits the statistics of the replaced one. Hence, the
+// statistics of an identity may overestimate its requests by
+// at most the requests of the replaced identity but identities
+// that send many requests are never replaced by identities that
+// send few requests.
+type identityStats struct {
+	lock     sync.Mutex
+	capacity int
+	stats    map[kes.Identity]*IdentityStat
+}
+
+func newIdentityStats(capacity int) *identityStats {
+	return &identityStats{
+		capacity: capacity,
+		stats:    make(map[kes.Identity]*IdentityStat, capacity),
+	}
+}
+
+// Add adds a request of the identity with
+// the given response status code.
+func (s *identityStats) Add(identity kes.Identity, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stat, ok := s.stats[identity]
+	if !ok {
+		if len(s.stats) < s.capacity {
+			stat = &IdentityStat{}
+		} else {
+			var min kes.Identity
+			for id, st := range s.stats {
+				if stat == nil || st.Requests < stat.Requests {
+					min, stat = id, st
+				}
+			}
+			delete(s.stats, min)
+		}
+		stat.Identity = identity
+		s.stats[identity] = stat
+	}
+
+	stat.Requests++
+	switch {
+	case status >= 400 && status < 500:
+		stat.Errors++
+	case status >= 500:
+		stat.Failures++
+	}
+}
+
+// Top returns the statistics of up to n identities
+// that sent the most requests in descending order.
+func (s *identityStats) Top(n int) []IdentityStat {
+	s.lock.Lock()
+	stats := make([]IdentityStat, 0, len(s.stats))
+	for _, stat := range s.stats {
+		stats = append(stats, *stat)
+	}
+	s.lock.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Requests != stats[j].Requests {
+			return stats[i].Requests > stats[j].Requests
+		}
+		return stats[i].Identity < stats[j].Identity
+	})
+	if n >= 0 && n < len(stats) {
+		stats = stats[:n]
+	}
+	return stats
+}
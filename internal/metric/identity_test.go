@@ -0,0 +1,40 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestIdentityStats(t *testing.T) {
+	stats := newIdentityStats(2)
+	for i := 0; i < 3; i++ {
+		stats.Add("a", http.StatusOK)
+	}
+	stats.Add("b", http.StatusNotFound)
+	stats.Add("b", http.StatusInternalServerError)
+	stats.Add("c", http.StatusOK) // Replaces "b"
+
+	top := stats.Top(-1)
+	want := []IdentityStat{
+		{Identity: "a", Requests: 3},
+		{Identity: "c", Requests: 3, Errors: 1, Failures: 1},
+	}
+	if len(top) != len(want) {
+		t.Fatalf("Identity count mismatch: got %d - want %d", len(top), len(want))
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Fatalf("Test %d: got '%+v' - want '%+v'", i, top[i], want[i])
+		}
+	}
+
+	if top = stats.Top(1); len(top) != 1 || top[0].Identity != kes.Identity("a") {
+		t.Fatalf("Top identity mismatch: got '%+v' - want identity 'a'", top)
+	}
+}
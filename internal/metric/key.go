@@ -0,0 +1,141 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MaxKeyPrefixes is the max. number of key name
+// prefixes that can be exported as metric label.
+const MaxKeyPrefixes = 100
+
+// KeyLabels controls the optional labels of the key
+// operation metrics. The zero value exports the key
+// operations only with an 'operation' label.
+//
+// Each label multiplies the metric cardinality. Hence,
+// there should be few enclaves and key prefixes.
+type KeyLabels struct {
+	// Enclave adds an 'enclave' label with the name
+	// of the enclave the key belongs to.
+	Enclave bool
+
+	// Prefixes, if not empty, adds a 'key_prefix'
+	// label with the longest of the prefixes the key
+	// name starts with. The label of keys that do not
+	// start with any of the prefixes is empty.
+	Prefixes []string
+}
+
+// Validate returns an error if there are more than
+// MaxKeyPrefixes prefixes or if any prefix is empty
+// or specified multiple times.
+func (l KeyLabels) Validate() error {
+	if len(l.Prefixes) > MaxKeyPrefixes {
+		return errors.New("metric: too many key prefixes: at most " + strconv.Itoa(MaxKeyPrefixes) + " are supported")
+	}
+	seen := make(map[string]bool, len(l.Prefixes))
+	for _, prefix := range l.Prefixes {
+		if prefix == "" {
+			return errors.New("metric: key prefix is empty")
+		}
+		if seen[prefix] {
+			return errors.New("metric: key prefix '" + prefix + "' is specified multiple times")
+		}
+		seen[prefix] = true
+	}
+	return nil
+}
+
+// keyOperations is a prometheus.Collector that collects
+// the key operations of the current keyCounter.
+//
+// It is an unchecked collector since its labels change
+// when the KeyLabels change. A registry does not allow
+// to register a metric with the same name but different
+// labels, even after unregistering the previous one.
+type keyOperations struct {
+	atomic.Value // *keyCounter
+}
+
+var _ prometheus.Collector = (*keyOperations)(nil)
+
+// Describe does not send any descriptor such
+// that the registry treats the collector as
+// unchecked collector.
+func (*keyOperations) Describe(chan<- *prometheus.Desc) {}
+
+// Collect sends the key operation metrics to ch.
+func (k *keyOperations) Collect(ch chan<- prometheus.Metric) {
+	k.Load().(*keyCounter).vec.Collect(ch)
+}
+
+// keyCounter counts key operations with the
+// labels specified by KeyLabels.
+type keyCounter struct {
+	vec      *prometheus.CounterVec
+	enclave  bool
+	prefixes []string // Sorted by length in descending order
+}
+
+func newKeyCounter(labels KeyLabels) *keyCounter {
+	names := []string{"operation"}
+	if labels.Enclave {
+		names = append(names, "enclave")
+	}
+
+	var prefixes []string
+	if len(labels.Prefixes) > 0 {
+		names = append(names, "key_prefix")
+
+		prefixes = make([]string, len(labels.Prefixes))
+		copy(prefixes, labels.Prefixes)
+		sort.SliceStable(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	}
+
+	return &keyCounter{
+		vec: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "key",
+			Name:      "operation",
+			Help:      "Number of successful key operations, like generate, encrypt and decrypt.",
+		}, names),
+		enclave:  labels.Enclave,
+		prefixes: prefixes,
+	}
+}
+
+// Inc increments the number of operations
+// on the key of the given enclave.
+func (c *keyCounter) Inc(operation, enclave, name string) {
+	values := make([]string, 1, 3)
+	values[0] = operation
+	if c.enclave {
+		values = append(values, enclave)
+	}
+	if c.prefixes != nil {
+		values = append(values, c.bucket(name))
+	}
+	c.vec.WithLabelValues(values...).Inc()
+}
+
+// bucket returns the longest prefix the name
+// starts with or the empty string if there is
+// no such prefix.
+func (c *keyCounter) bucket(name string) string {
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestKeyLabelsValidate(t *testing.T) {
+	for i, test := range keyLabelsValidateTests {
+		err := test.Labels.Validate()
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to validate labels: %v", i, err)
+		}
+	}
+}
+
+func TestCountKeyOperation(t *testing.T) {
+	metrics := New()
+	if err := metrics.SetKeyLabels(KeyLabels{Enclave: true, Prefixes: []string{"tenants/", "tenants/acme/"}}); err != nil {
+		t.Fatalf("Failed to set key labels: %v", err)
+	}
+	metrics.CountKeyOperation("encrypt", "default", "tenants/acme/my-key")
+	metrics.CountKeyOperation("encrypt", "default", "tenants/acme/my-key")
+	metrics.CountKeyOperation("encrypt", "default", "tenants/umbrella/my-key")
+	metrics.CountKeyOperation("decrypt", "tenant-1", "my-key")
+
+	type Labels struct{ Operation, Enclave, KeyPrefix string }
+	want := map[Labels]float64{
+		{Operation: "encrypt", Enclave: "default", KeyPrefix: "tenants/acme/"}: 2,
+		{Operation: "encrypt", Enclave: "default", KeyPrefix: "tenants/"}:      1,
+		{Operation: "decrypt", Enclave: "tenant-1", KeyPrefix: ""}:             1,
+	}
+	families, err := metrics.gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	var found bool
+	for _, family := range families {
+		if family.GetName() != "kes_key_operation" {
+			continue
+		}
+		found = true
+
+		if n := len(family.GetMetric()); n != len(want) {
+			t.Fatalf("Invalid number of key operation metrics: got %d - want %d", n, len(want))
+		}
+		for _, m := range family.GetMetric() {
+			var labels Labels
+			for _, label := range m.GetLabel() {
+				switch label.GetName() {
+				case "operation":
+					labels.Operation = label.GetValue()
+				case "enclave":
+					labels.Enclave = label.GetValue()
+				case "key_prefix":
+					labels.KeyPrefix = label.GetValue()
+				default:
+					t.Fatalf("Unexpected label '%s'", label.GetName())
+				}
+			}
+			count, ok := want[labels]
+			if !ok {
+				t.Fatalf("Unexpected key operation metric with labels '%+v'", labels)
+			}
+			if v := m.GetCounter().GetValue(); v != count {
+				t.Fatalf("Labels '%+v': got '%v' - want '%v'", labels, v, count)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Key operation metric not found")
+	}
+
+	if err = metrics.SetKeyLabels(KeyLabels{}); err != nil {
+		t.Fatalf("Failed to reset key labels: %v", err)
+	}
+	metrics.CountKeyOperation("generate", "default", "tenants/acme/my-key")
+	if families, err = metrics.gather(); err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "kes_key_operation" {
+			continue
+		}
+		if n := len(family.GetMetric()); n != 1 {
+			t.Fatalf("Invalid number of key operation metrics: got %d - want %d", n, 1)
+		}
+		if n := len(family.GetMetric()[0].GetLabel()); n != 1 {
+			t.Fatalf("Invalid number of labels: got %d - want %d", n, 1)
+		}
+	}
+}
+
+var keyLabelsValidateTests = []struct {
+	Labels     KeyLabels
+	ShouldFail bool
+}{
+	{Labels: KeyLabels{}}, // 0
+	{Labels: KeyLabels{Enclave: true, Prefixes: []string{"tenants/", "app-"}}},        // 1
+	{Labels: KeyLabels{Prefixes: []string{""}}, ShouldFail: true},                     // 2
+	{Labels: KeyLabels{Prefixes: []string{"app-", "app-"}}, ShouldFail: true},         // 3
+	{Labels: KeyLabels{Prefixes: manyPrefixes(MaxKeyPrefixes)}},                       // 4
+	{Labels: KeyLabels{Prefixes: manyPrefixes(MaxKeyPrefixes + 1)}, ShouldFail: true}, // 5
+}
+
+func manyPrefixes(n int) []string {
+	prefixes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		prefixes = append(prefixes, "app-"+strconv.Itoa(i)+"/")
+	}
+	return prefixes
+}
@@ -9,9 +9,12 @@ import (
 	"net/http"
 	"runtime"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/minio/kes/internal/auth"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
 )
 
@@ -19,6 +22,8 @@ import (
 // metrics about the application.
 func New() *Metrics {
 	requestStatusLabels := []string{"code"}
+	identityLabels := []string{"identity"}
+	apiLabels := []string{"api", "version"}
 
 	metrics := &Metrics{
 		registry: prometheus.NewRegistry(),
@@ -52,8 +57,57 @@ func New() *Metrics {
 			Name:      "response_time",
 			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1.0, 1.5, 3.0, 5.0, 10.0}, // from 10ms to 10s
 			Help:      "Histogram of request response times spawning from 10ms to 10s.",
+
+			// Scrapers that accept the protobuf exposition format
+			// also receive a native histogram with exponential
+			// buckets with a growth factor of 1.1.
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  160,
+			NativeHistogramMinResetDuration: 1 * time.Hour,
 		}),
 
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "api_request",
+			Help:      "Number of requests per API and API version.",
+		}, apiLabels),
+		apiDeprecatedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "api_request_deprecated",
+			Help:      "Number of requests to deprecated APIs that will be removed in a future release.",
+		}, apiLabels),
+
+		identities: newIdentityStats(MaxIdentities),
+		identityRequests: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "identity_request",
+			Help:      "Number of requests sent by the identities that sent the most requests.",
+		}, identityLabels),
+		identityErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "identity_request_error",
+			Help:      "Number of requests, sent by the identities that sent the most requests, that failed due to some error. (HTTP 4xx status code)",
+		}, identityLabels),
+		identityFailures: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "identity_request_failure",
+			Help:      "Number of requests, sent by the identities that sent the most requests, that failed due to some internal failure. (HTTP 5xx status code)",
+		}, identityLabels),
+
+		keyOperations: new(keyOperations),
+
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "tls",
+			Name:      "auth_failure",
+			Help:      "Number of TLS handshakes that failed since the client certificate has been rejected.",
+		}, []string{"reason"}),
+
 		errorLogEvents: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: "kes",
 			Subsystem: "log",
@@ -66,6 +120,12 @@ func New() *Metrics {
 			Name:      "audit_events",
 			Help:      "Number of audit log events written to the audit log targets.",
 		}),
+		auditLogDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "log",
+			Name:      "audit_events_dropped",
+			Help:      "Number of audit log events dropped because an audit log stream subscriber was too slow.",
+		}),
 
 		startTime: time.Now(),
 		upTimeInSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
@@ -119,8 +179,15 @@ func New() *Metrics {
 	metrics.registry.MustRegister(metrics.requestFailed)
 	metrics.registry.MustRegister(metrics.requestActive)
 	metrics.registry.MustRegister(metrics.requestLatency)
+	metrics.registry.MustRegister(metrics.apiRequests)
+	metrics.registry.MustRegister(metrics.apiDeprecatedRequests)
+	metrics.registry.MustRegister(metrics.identityRequests)
+	metrics.registry.MustRegister(metrics.identityErrors)
+	metrics.registry.MustRegister(metrics.identityFailures)
+	metrics.registry.MustRegister(metrics.authFailures)
 	metrics.registry.MustRegister(metrics.errorLogEvents)
 	metrics.registry.MustRegister(metrics.auditLogEvents)
+	metrics.registry.MustRegister(metrics.auditLogDropped)
 	metrics.registry.MustRegister(metrics.upTimeInSeconds)
 	metrics.registry.MustRegister(metrics.numCPUs)
 	metrics.registry.MustRegister(metrics.numUsableCPUs)
@@ -129,6 +196,9 @@ func New() *Metrics {
 	metrics.registry.MustRegister(metrics.memHeapObjects)
 	metrics.registry.MustRegister(metrics.memStackUsed)
 
+	metrics.keyOperations.Store(newKeyCounter(KeyLabels{}))
+	metrics.registry.MustRegister(metrics.keyOperations)
+
 	return metrics
 }
 
@@ -143,8 +213,22 @@ type Metrics struct {
 	requestActive    prometheus.Gauge
 	requestLatency   prometheus.Histogram
 
-	errorLogEvents prometheus.Counter
-	auditLogEvents prometheus.Counter
+	apiRequests           *prometheus.CounterVec
+	apiDeprecatedRequests *prometheus.CounterVec
+
+	identities       *identityStats
+	identityLabels   int32 // atomic - number of top identities exposed as metric labels
+	identityRequests *prometheus.GaugeVec
+	identityErrors   *prometheus.GaugeVec
+	identityFailures *prometheus.GaugeVec
+
+	authFailures *prometheus.CounterVec
+
+	keyOperations *keyOperations
+
+	errorLogEvents  prometheus.Counter
+	auditLogEvents  prometheus.Counter
+	auditLogDropped prometheus.Counter
 
 	startTime       time.Time // Used to compute the up time as upTime = now - startTime
 	upTimeInSeconds prometheus.Gauge
@@ -160,6 +244,24 @@ type Metrics struct {
 // EncodeTo collects all outstanding metrics information
 // about the application and writes it to encoder.
 func (m *Metrics) EncodeTo(encoder expfmt.Encoder) error {
+	metrics, err := m.gather()
+	if err != nil {
+		return err
+	}
+	for _, metric := range metrics {
+		if err := encoder.Encode(metric); err != nil {
+			return err
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		return closer.Close() // Required by the OpenMetrics format
+	}
+	return nil
+}
+
+// gather updates the runtime and per-identity metrics
+// and collects all metrics of the registry.
+func (m *Metrics) gather() ([]*dto.MetricFamily, error) {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
@@ -171,25 +273,47 @@ func (m *Metrics) EncodeTo(encoder expfmt.Encoder) error {
 	m.memHeapObjects.Set(float64(memStats.HeapObjects))
 	m.memStackUsed.Set(float64(memStats.StackSys))
 
-	metrics, err := m.registry.Gather()
-	if err != nil {
-		return err
-	}
-	for _, metric := range metrics {
-		if err := encoder.Encode(metric); err != nil {
-			return err
+	m.identityRequests.Reset()
+	m.identityErrors.Reset()
+	m.identityFailures.Reset()
+	if n := atomic.LoadInt32(&m.identityLabels); n > 0 {
+		for _, stat := range m.identities.Top(int(n)) {
+			m.identityRequests.WithLabelValues(stat.Identity.String()).Set(float64(stat.Requests))
+			m.identityErrors.WithLabelValues(stat.Identity.String()).Set(float64(stat.Errors))
+			m.identityFailures.WithLabelValues(stat.Identity.String()).Set(float64(stat.Failures))
 		}
 	}
-	return nil
+
+	return m.registry.Gather()
 }
 
+// TopIdentities returns the request statistics of up to n
+// identities that sent the most requests, in descending
+// order. If n < 0, it returns the statistics of all tracked
+// identities.
+//
+// Metrics track at most MaxIdentities identities. Hence, the
+// statistics of an identity may overestimate its requests
+// if it replaced an identity that sent fewer requests.
+func (m *Metrics) TopIdentities(n int) []IdentityStat { return m.identities.Top(n) }
+
+// SetIdentityLabels sets the number of identities that sent
+// the most requests whose request statistics are exposed as
+// metrics with an identity label. If n <= 0, no per-identity
+// metrics are exposed.
+//
+// The number of identities determines the metric cardinality
+// and should be small.
+func (m *Metrics) SetIdentityLabels(n int) { atomic.StoreInt32(&m.identityLabels, int32(n)) }
+
 // Count returns a HandlerFunc that wraps h and counts the
 // how many requests succeeded (HTTP 200 OK) and how many
 // failed.
 //
 // Count distingushes requests that fail with some sort of
 // well-defined error (HTTP 4xx) and requests that fail due
-// to some internal error (HTTP 5xx).
+// to some internal error (HTTP 5xx). It also counts the
+// requests per identity.
 func (m *Metrics) Count(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		m.requestActive.Inc()
@@ -205,9 +329,26 @@ func (m *Metrics) Count(h http.Handler) http.Handler {
 			rw.flusher = flusher
 		}
 		h.ServeHTTP(&rw, r)
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.identities.Add(auth.Identify(r), status)
 	})
 }
 
+// CountAPI increments the number of requests to the API
+// with the given path and version. If deprecated is true,
+// it also increments the number of requests to deprecated
+// APIs.
+func (m *Metrics) CountAPI(path, version string, deprecated bool) {
+	m.apiRequests.WithLabelValues(path, version).Inc()
+	if deprecated {
+		m.apiDeprecatedRequests.WithLabelValues(path, version).Inc()
+	}
+}
+
 // Latency returns a HandlerFunc that wraps h and measures the
 // internal request-response latency.
 //
@@ -215,6 +356,10 @@ func (m *Metrics) Count(h http.Handler) http.Handler {
 // application takes to generate and send a response after
 // receiving a request. It basically shows how many request
 // the application can handle.
+//
+// If the request carries a W3C traceparent header of a sampled
+// trace, the latency is recorded with the trace ID as exemplar.
+// Hence, slow requests can be looked up in the tracing system.
 func (m *Metrics) Latency(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rw := latencyResponseWriter{
@@ -222,6 +367,9 @@ func (m *Metrics) Latency(h http.Handler) http.Handler {
 			start:          time.Now(),
 			histogram:      m.requestLatency,
 		}
+		if traceID, ok := traceIDFromRequest(r); ok {
+			rw.exemplar = prometheus.Labels{"trace_id": traceID}
+		}
 		if flusher, ok := w.(http.Flusher); ok {
 			rw.flusher = flusher
 		}
@@ -229,6 +377,33 @@ func (m *Metrics) Latency(h http.Handler) http.Handler {
 	})
 }
 
+// SetKeyLabels sets the optional labels of the key operation
+// metrics. It resets the number of key operations counted so
+// far. Hence, it should be called before serving requests.
+//
+// It returns an error if the labels are not valid.
+func (m *Metrics) SetKeyLabels(labels KeyLabels) error {
+	if err := labels.Validate(); err != nil {
+		return err
+	}
+	m.keyOperations.Store(newKeyCounter(labels))
+	return nil
+}
+
+// CountKeyOperation increments the number of successful
+// operations, like "encrypt", on the key with the given
+// name within the enclave. Whether the metric contains
+// the enclave and a key prefix depends on the KeyLabels.
+func (m *Metrics) CountKeyOperation(operation, enclave, name string) {
+	m.keyOperations.Load().(*keyCounter).Inc(operation, enclave, name)
+}
+
+// CountAuthFailure increments the number of TLS client
+// authentication failures with the given reason.
+func (m *Metrics) CountAuthFailure(reason string) {
+	m.authFailures.WithLabelValues(reason).Inc()
+}
+
 // ErrorEventCounter returns an io.Writer that increments
 // the error event log counter on each write call.
 //
@@ -245,6 +420,11 @@ func (m *Metrics) AuditEventCounter() io.Writer {
 	return eventCounter{metric: m.auditLogEvents}
 }
 
+// AuditEventDropped increments the counter of audit
+// log events that have been dropped because an audit
+// log stream subscriber was too slow.
+func (m *Metrics) AuditEventDropped() { m.auditLogDropped.Inc() }
+
 type eventCounter struct {
 	metric prometheus.Counter
 }
@@ -262,6 +442,7 @@ type latencyResponseWriter struct {
 
 	start     time.Time            // The point in time when the request was received
 	histogram prometheus.Histogram // The latency histogram
+	exemplar  prometheus.Labels    // The exemplar labels, if any
 	written   bool                 // Inidicates whether the HTTP headers have been written
 }
 
@@ -273,7 +454,12 @@ var (
 func (w *latencyResponseWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 	if !w.written {
-		w.histogram.Observe(time.Since(w.start).Seconds())
+		latency := time.Since(w.start).Seconds()
+		if observer, ok := w.histogram.(prometheus.ExemplarObserver); ok && w.exemplar != nil {
+			observer.ObserveWithExemplar(latency, w.exemplar)
+		} else {
+			w.histogram.Observe(latency)
+		}
 		w.written = true
 	}
 }
@@ -304,6 +490,7 @@ type countResponseWriter struct {
 	failed    *prometheus.CounterVec
 	prometheus.Metric
 	written bool // Inidicates whether the HTTP headers have been written
+	status  int  // The response status code
 }
 
 var (
@@ -314,6 +501,7 @@ var (
 func (w *countResponseWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 	if !w.written {
+		w.status = status
 		switch {
 		case status >= 200 && status < 300:
 			w.succeeded.WithLabelValues(strconv.Itoa(status)).Inc()
@@ -0,0 +1,227 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// StatsDConfig is a structure containing the
+// configuration of a StatsD exporter.
+type StatsDConfig struct {
+	// Endpoint is the UDP address of the StatsD or
+	// DogStatsD agent. For example: localhost:8125
+	Endpoint string
+
+	// Prefix is prepended to all metric names. For
+	// example, a prefix "prod." turns the metric
+	// kes_http_request_active into
+	// prod.kes_http_request_active.
+	Prefix string
+
+	// Interval is the time period between two pushes.
+	// If <= 0, defaults to 10 seconds.
+	Interval time.Duration
+
+	// DogStatsD enables the DogStatsD protocol extension
+	// such that metric labels are sent as tags. Plain
+	// StatsD has no notion of tags. Hence, label values
+	// get appended to the metric name instead.
+	DogStatsD bool
+
+	// Tags is a list of "key:value" tags attached to all
+	// metrics. It is ignored if DogStatsD is false.
+	Tags []string
+}
+
+// maxStatsDPacketSize is the max. size of a single StatsD
+// UDP packet. It avoids IP fragmentation on common networks
+// with an MTU of 1500 bytes.
+const maxStatsDPacketSize = 1432
+
+// StatsD periodically pushes metrics to a StatsD or
+// DogStatsD agent via UDP.
+//
+// Counters and the count and sum of histograms are sent
+// as StatsD counters containing the increase since the
+// previous push. Gauges are sent as StatsD gauges.
+type StatsD struct {
+	metrics *Metrics
+	conn    net.Conn
+	config  StatsDConfig
+
+	counters map[string]float64 // Most recent value of each counter
+
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// NewStatsD returns a new StatsD exporter that pushes
+// the given metrics to the configured StatsD agent in
+// the background until it is closed.
+func NewStatsD(metrics *Metrics, config *StatsDConfig) (*StatsD, error) {
+	conn, err := net.Dial("udp", config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s := &StatsD{
+		metrics:  metrics,
+		conn:     conn,
+		config:   *config,
+		counters: map[string]float64{},
+	}
+	if s.config.Interval <= 0 {
+		s.config.Interval = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	s.done.Add(1)
+	go func() {
+		defer s.done.Done()
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.push() // push is best effort. StatsD over UDP drops metrics anyway.
+			}
+		}
+	}()
+	return s, nil
+}
+
+// push gathers all metrics and sends them to the
+// StatsD agent.
+func (s *StatsD) push() error {
+	families, err := s.metrics.gather()
+	if err != nil {
+		return err
+	}
+
+	var packet bytes.Buffer
+	for _, line := range s.lines(families) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacketSize {
+			if _, err = s.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		_, err = s.conn.Write(packet.Bytes())
+	}
+	return err
+}
+
+// Close stops pushing metrics and closes the
+// connection to the StatsD agent.
+func (s *StatsD) Close() error {
+	s.stop()
+	s.done.Wait()
+	return s.conn.Close()
+}
+
+// lines converts the metric families into StatsD
+// lines.
+func (s *StatsD) lines(families []*dto.MetricFamily) []string {
+	var lines []string
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name, tags := s.name(family.GetName(), metric.GetLabel())
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				lines = s.appendCounter(lines, name, tags, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				lines = append(lines, s.line(name, metric.GetGauge().GetValue(), "g", tags))
+			case dto.MetricType_UNTYPED:
+				lines = append(lines, s.line(name, metric.GetUntyped().GetValue(), "g", tags))
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				lines = s.appendCounter(lines, name+"_count", tags, float64(histogram.GetSampleCount()))
+				lines = s.appendCounter(lines, name+"_sum", tags, histogram.GetSampleSum())
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				lines = s.appendCounter(lines, name+"_count", tags, float64(summary.GetSampleCount()))
+				lines = s.appendCounter(lines, name+"_sum", tags, summary.GetSampleSum())
+			}
+		}
+	}
+	return lines
+}
+
+// appendCounter appends a StatsD counter line with the
+// increase of the counter since the previous push to
+// lines. A counter that has been reset, e.g. due to a
+// restart, is treated as starting from zero.
+func (s *StatsD) appendCounter(lines []string, name, tags string, value float64) []string {
+	key := name + "|" + tags
+	delta := value
+	if prev, ok := s.counters[key]; ok && prev <= value {
+		delta = value - prev
+	}
+	s.counters[key] = value
+	if delta == 0 {
+		return lines
+	}
+	return append(lines, s.line(name, delta, "c", tags))
+}
+
+// name returns the StatsD metric name and the DogStatsD
+// tags of a metric with the given labels.
+func (s *StatsD) name(name string, labels []*dto.LabelPair) (string, string) {
+	name = s.config.Prefix + name
+	if !s.config.DogStatsD {
+		for _, label := range labels {
+			name += "." + sanitizeStatsD(label.GetValue())
+		}
+		return name, ""
+	}
+
+	tags := make([]string, 0, len(s.config.Tags)+len(labels))
+	tags = append(tags, s.config.Tags...)
+	for _, label := range labels {
+		tags = append(tags, sanitizeStatsD(label.GetName())+":"+sanitizeStatsD(label.GetValue()))
+	}
+	sort.Strings(tags[len(s.config.Tags):])
+	return name, strings.Join(tags, ",")
+}
+
+func (s *StatsD) line(name string, value float64, typ, tags string) string {
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + typ
+	if tags != "" {
+		line += "|#" + tags
+	}
+	return line
+}
+
+// sanitizeStatsD replaces all characters with a special
+// meaning in the StatsD line protocol with an underscore.
+func sanitizeStatsD(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', ',', '#', '@', '\n', ' ':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
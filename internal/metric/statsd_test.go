@@ -0,0 +1,107 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var statsDTests = []struct {
+	Config StatsDConfig
+	Lines  []string
+}{
+	{
+		Config: StatsDConfig{},
+		Lines: []string{
+			"kes_http_request_success.200:1|c",
+			"kes_http_response_time_count:1|c",
+			"kes_http_request_active:0|g",
+		},
+	},
+	{
+		Config: StatsDConfig{Prefix: "prod.", DogStatsD: true, Tags: []string{"env:prod"}},
+		Lines: []string{
+			"prod.kes_http_request_success:1|c|#env:prod,code:200",
+			"prod.kes_http_response_time_count:1|c|#env:prod",
+			"prod.kes_http_request_active:0|g|#env:prod",
+		},
+	},
+}
+
+func TestStatsD(t *testing.T) {
+	for i, test := range statsDTests {
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Test %d: failed to listen: %v", i, err)
+		}
+		defer listener.Close()
+
+		metrics := New()
+		config := test.Config
+		config.Endpoint = listener.LocalAddr().String()
+		config.Interval = time.Hour
+		statsd, err := NewStatsD(metrics, &config)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create StatsD exporter: %v", i, err)
+		}
+		defer statsd.Close()
+
+		handler := metrics.Count(metrics.Latency(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/status", nil))
+
+		lines := readStatsD(t, statsd, listener)
+		for _, line := range test.Lines {
+			if !contains(lines, line) {
+				t.Fatalf("Test %d: line '%s' not found: got '%v'", i, line, lines)
+			}
+		}
+
+		// Counters are sent as increase since the previous
+		// push. Hence, unchanged counters must not be sent.
+		lines = readStatsD(t, statsd, listener)
+		for _, line := range lines {
+			if strings.Contains(line, "|c") {
+				t.Fatalf("Test %d: unchanged counter has been sent: '%s'", i, line)
+			}
+		}
+	}
+}
+
+func readStatsD(t *testing.T, statsd *StatsD, listener net.PacketConn) []string {
+	if err := statsd.push(); err != nil {
+		t.Fatalf("Failed to push metrics: %v", err)
+	}
+
+	var lines []string
+	buffer := make([]byte, 64*1024)
+	for {
+		listener.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := listener.ReadFrom(buffer)
+		if err != nil {
+			break
+		}
+		if n > maxStatsDPacketSize {
+			t.Fatalf("StatsD packet is too large: got '%d' - want <= '%d'", n, maxStatsDPacketSize)
+		}
+		lines = append(lines, strings.Split(string(buffer[:n]), "\n")...)
+	}
+	return lines
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// traceIDFromRequest returns the trace ID of the W3C
+// traceparent header of the request, if present. It
+// only returns trace IDs of sampled traces since other
+// traces are not recorded by the tracing system.
+//
+// See: https://www.w3.org/TR/trace-context/#traceparent-header
+func traceIDFromRequest(r *http.Request) (string, bool) {
+	// The traceparent header has the form:
+	//   <version>-<trace-id>-<parent-id>-<trace-flags>
+	// for example:
+	//   00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	fields := strings.Split(strings.TrimSpace(r.Header.Get("Traceparent")), "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" {
+		return "", false
+	}
+	traceID, parentID, flags := fields[1], fields[2], fields[3]
+	if len(traceID) != 32 || len(parentID) != 16 || len(flags) != 2 {
+		return "", false
+	}
+	if fields[0] == "00" && len(fields) != 4 {
+		return "", false
+	}
+
+	var b [16]byte
+	if _, err := hex.Decode(b[:], []byte(traceID)); err != nil || b == [16]byte{} {
+		return "", false
+	}
+	if _, err := hex.Decode(b[:1], []byte(flags)); err != nil || b[0]&0x01 == 0 {
+		return "", false
+	}
+	return strings.ToLower(traceID), true
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+var traceIDFromRequestTests = []struct {
+	Header  string
+	TraceID string
+	OK      bool
+}{
+	{Header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", OK: true},
+	{Header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-future", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", OK: true},
+	{Header: "", OK: false},
+	{Header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", OK: false},       // not sampled
+	{Header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", OK: false},       // invalid trace ID
+	{Header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", OK: false},       // invalid version
+	{Header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", OK: false}, // too many fields
+	{Header: "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", OK: false},         // trace ID too short
+	{Header: "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01", OK: false},       // invalid hex
+}
+
+func TestTraceIDFromRequest(t *testing.T) {
+	for i, test := range traceIDFromRequestTests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		if test.Header != "" {
+			req.Header.Set("Traceparent", test.Header)
+		}
+		traceID, ok := traceIDFromRequest(req)
+		if ok != test.OK {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, ok, test.OK)
+		}
+		if traceID != test.TraceID {
+			t.Fatalf("Test %d: invalid trace ID: got '%s' - want '%s'", i, traceID, test.TraceID)
+		}
+	}
+}
+
+func TestLatencyExemplar(t *testing.T) {
+	metrics := New()
+	handler := metrics.Latency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var buffer bytes.Buffer
+	if err := metrics.EncodeTo(expfmt.NewEncoder(&buffer, expfmt.FmtOpenMetrics)); err != nil {
+		t.Fatalf("Failed to encode metrics: %v", err)
+	}
+	if !strings.Contains(buffer.String(), `trace_id="4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Fatal("Latency histogram does not contain exemplar")
+	}
+	if !strings.HasSuffix(buffer.String(), "# EOF\n") {
+		t.Fatal("OpenMetrics output is not terminated by '# EOF'")
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package operator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"aead.dev/mem"
+)
+
+// Paths of the service account files mounted into each Pod.
+const (
+	serviceAccountToken     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Kube is a minimal client for the Kubernetes API
+// that reads and updates KES custom resources.
+type Kube struct {
+	// URL is the Kubernetes API server URL.
+	URL string
+
+	// TokenFile is the path of the file containing the
+	// bearer token. It is read on every request since
+	// service account tokens get rotated. If empty,
+	// requests are not authenticated, e.g. when talking
+	// to a 'kubectl proxy'.
+	TokenFile string
+
+	// HTTPClient is the HTTP client used to send requests.
+	HTTPClient http.Client
+}
+
+// InCluster returns a Kube client for the Kubernetes API server
+// of the cluster the process runs in and the namespace of the
+// Pod's service account.
+func InCluster() (*Kube, string, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, "", errors.New("operator: not running in a Kubernetes cluster")
+	}
+	caCert, err := os.ReadFile(serviceAccountCA)
+	if err != nil {
+		return nil, "", err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caCert) {
+		return nil, "", fmt.Errorf("operator: no CA certificate found in '%s'", serviceAccountCA)
+	}
+	namespace, err := os.ReadFile(serviceAccountNamespace)
+	if err != nil {
+		return nil, "", err
+	}
+	return &Kube{
+		URL:       "https://" + net.JoinHostPort(host, port),
+		TokenFile: serviceAccountToken,
+		HTTPClient: http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12},
+			},
+		},
+	}, strings.TrimSpace(string(namespace)), nil
+}
+
+// List returns all custom resources of the given plural
+// resource name within the namespace.
+func (k *Kube) List(ctx context.Context, namespace, resource string) ([]Object, error) {
+	type Response struct {
+		Items []Object `json:"items"`
+	}
+	resp, err := k.send(ctx, http.MethodGet, k.path(namespace, resource, "", ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, 64*mem.MiB)).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+// Patch applies the JSON merge patch to the named custom resource.
+// If subresource is not empty, e.g. "status", it patches the
+// subresource instead.
+func (k *Kube) Patch(ctx context.Context, namespace, resource, name, subresource string, patch any) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	resp, err := k.send(ctx, http.MethodPatch, k.path(namespace, resource, name, subresource), body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (k *Kube) path(namespace, resource, name, subresource string) string {
+	path := fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", Group, Version, url.PathEscape(namespace), resource)
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	if subresource != "" {
+		path += "/" + subresource
+	}
+	return path
+}
+
+func (k *Kube) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(k.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if method == http.MethodPatch {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	if k.TokenFile != "" {
+		token, err := os.ReadFile(k.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+
+		type Response struct {
+			Message string `json:"message"`
+		}
+		var response Response
+		if err = json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil || response.Message == "" {
+			response.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, &StatusError{Status: resp.StatusCode, Message: response.Message}
+	}
+	return resp, nil
+}
+
+// StatusError is an error returned by the Kubernetes API server.
+type StatusError struct {
+	Status  int    // The HTTP response status code
+	Message string // The error message
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("kubernetes: %s (%d)", e.Message, e.Status)
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package operator reconciles KES keys, policies and identities
+// declared as Kubernetes custom resources against a KES server.
+package operator
+
+import (
+	_ "embed"
+	"encoding/json"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// Kubernetes API group and version of the KES custom resources.
+const (
+	Group   = "kes.min.io"
+	Version = "v1alpha1"
+)
+
+// Finalizer is the finalizer the operator adds to custom resources
+// such that it can remove the corresponding KES resource before
+// Kubernetes deletes the custom resource.
+const Finalizer = Group + "/finalizer"
+
+// Plural resource names of the KES custom resources.
+const (
+	ResourceKey      = "keskeys"
+	ResourcePolicy   = "kespolicies"
+	ResourceIdentity = "kesidentities"
+)
+
+// Object is a KES custom resource.
+type Object struct {
+	Kind     string          `json:"kind"`
+	Metadata ObjectMeta      `json:"metadata"`
+	Spec     json.RawMessage `json:"spec"`
+	Status   Status          `json:"status"`
+}
+
+// ObjectMeta is the subset of the Kubernetes object
+// metadata the operator uses.
+type ObjectMeta struct {
+	Name              string     `json:"name"`
+	Namespace         string     `json:"namespace"`
+	Generation        int64      `json:"generation"`
+	ResourceVersion   string     `json:"resourceVersion"`
+	DeletionTimestamp *time.Time `json:"deletionTimestamp,omitempty"`
+	Finalizers        []string   `json:"finalizers,omitempty"`
+}
+
+// Status is the status of a KES custom resource.
+type Status struct {
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+// Condition is a Kubernetes status condition.
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"` // "True", "False" or "Unknown"
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+	ObservedGeneration int64     `json:"observedGeneration,omitempty"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// KeySpec is the spec of a KESKey.
+type KeySpec struct {
+	// Name is the key name. If empty, the
+	// custom resource name is used.
+	Name string `json:"name,omitempty"`
+
+	// Enclave is the enclave of the key. If empty,
+	// the default enclave is used.
+	Enclave string `json:"enclave,omitempty"`
+
+	// DeletionPolicy controls whether the key is deleted
+	// when the custom resource gets deleted. It is either
+	// "Retain" (default) or "Delete".
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// PolicySpec is the spec of a KESPolicy.
+type PolicySpec struct {
+	// Name is the policy name. If empty, the
+	// custom resource name is used.
+	Name string `json:"name,omitempty"`
+
+	// Enclave is the enclave of the policy. If empty,
+	// the default enclave is used.
+	Enclave string `json:"enclave,omitempty"`
+
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// IdentitySpec is the spec of a KESIdentity.
+type IdentitySpec struct {
+	// Identity is the KES identity.
+	Identity kes.Identity `json:"identity"`
+
+	// Policy is the name of the policy
+	// assigned to the identity.
+	Policy string `json:"policy"`
+
+	// Enclave is the enclave of the identity. If empty,
+	// the default enclave is used.
+	Enclave string `json:"enclave,omitempty"`
+}
+
+// hasFinalizer reports whether the object has the operator's finalizer.
+func (o *Object) hasFinalizer() bool {
+	for _, f := range o.Metadata.Finalizers {
+		if f == Finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// readyCondition returns the object's Ready condition, if any.
+func (o *Object) readyCondition() (Condition, bool) {
+	for _, c := range o.Status.Conditions {
+		if c.Type == "Ready" {
+			return c, true
+		}
+	}
+	return Condition{}, false
+}
+
+// CRDs contains the Kubernetes custom resource definitions
+// of KESKey, KESPolicy and KESIdentity as YAML.
+//
+//go:embed crds.yaml
+var CRDs []byte
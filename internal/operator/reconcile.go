@@ -0,0 +1,339 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/log"
+)
+
+// Server is the set of KES server operations
+// the Reconciler performs.
+type Server interface {
+	CreateKey(ctx context.Context, enclave, name string) error
+	DeleteKey(ctx context.Context, enclave, name string) error
+
+	GetPolicy(ctx context.Context, enclave, name string) (*kes.Policy, error)
+	SetPolicy(ctx context.Context, enclave, name string, policy *kes.Policy) error
+	DeletePolicy(ctx context.Context, enclave, name string) error
+
+	DescribeIdentity(ctx context.Context, enclave string, identity kes.Identity) (*kes.IdentityInfo, error)
+	AssignPolicy(ctx context.Context, enclave, policy string, identity kes.Identity) error
+	DeleteIdentity(ctx context.Context, enclave string, identity kes.Identity) error
+}
+
+// NewServer returns a Server that performs
+// operations with the given KES client.
+func NewServer(client *kes.Client) Server { return clientServer{client: client} }
+
+// A Reconciler reconciles KES custom resources within a
+// Kubernetes namespace against a KES server.
+//
+// It creates, updates and deletes KES keys, policies and
+// identities such that they match the custom resources
+// and reports the outcome as Ready status condition of
+// each custom resource.
+type Reconciler struct {
+	Kube      *Kube
+	Server    Server
+	Namespace string
+
+	// ErrorLog is used to log reconciliation errors.
+	// If nil, the default logger is used.
+	ErrorLog *log.Logger
+}
+
+// Run reconciles all custom resources periodically
+// until ctx is canceled.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Reconcile(ctx); err != nil {
+			r.logf("operator: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile reconciles all custom resources once. Policies are
+// reconciled before identities such that policy assignments
+// refer to existing policies.
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	for _, resource := range []string{ResourceKey, ResourcePolicy, ResourceIdentity} {
+		objects, err := r.Kube.List(ctx, r.Namespace, resource)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %v", resource, err)
+		}
+		sort.Slice(objects, func(i, j int) bool { return objects[i].Metadata.Name < objects[j].Metadata.Name })
+
+		for i := range objects {
+			if err = r.reconcile(ctx, resource, &objects[i]); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				r.logf("operator: failed to reconcile %s '%s': %v", resource, objects[i].Metadata.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reconcile reconciles a single custom resource. It returns
+// an error if it fails to update the custom resource. Errors
+// of the KES server are reported as status condition.
+func (r *Reconciler) reconcile(ctx context.Context, resource string, obj *Object) error {
+	if obj.Metadata.DeletionTimestamp != nil {
+		if !obj.hasFinalizer() {
+			return nil
+		}
+		if err := r.delete(ctx, resource, obj); err != nil {
+			return r.setReady(ctx, resource, obj, err)
+		}
+
+		finalizers := make([]string, 0, len(obj.Metadata.Finalizers))
+		for _, f := range obj.Metadata.Finalizers {
+			if f != Finalizer {
+				finalizers = append(finalizers, f)
+			}
+		}
+		return r.patchFinalizers(ctx, resource, obj, finalizers)
+	}
+
+	if !obj.hasFinalizer() {
+		if err := r.patchFinalizers(ctx, resource, obj, append(obj.Metadata.Finalizers, Finalizer)); err != nil {
+			return err
+		}
+	}
+	return r.setReady(ctx, resource, obj, r.apply(ctx, resource, obj))
+}
+
+// apply creates or updates the KES resource of obj.
+func (r *Reconciler) apply(ctx context.Context, resource string, obj *Object) error {
+	switch resource {
+	case ResourceKey:
+		var spec KeySpec
+		if err := json.Unmarshal(obj.Spec, &spec); err != nil {
+			return err
+		}
+		if spec.Name == "" {
+			spec.Name = obj.Metadata.Name
+		}
+		if err := r.Server.CreateKey(ctx, spec.Enclave, spec.Name); err != nil && !errors.Is(err, kes.ErrKeyExists) {
+			return err
+		}
+		return nil
+	case ResourcePolicy:
+		var spec PolicySpec
+		if err := json.Unmarshal(obj.Spec, &spec); err != nil {
+			return err
+		}
+		if spec.Name == "" {
+			spec.Name = obj.Metadata.Name
+		}
+		current, err := r.Server.GetPolicy(ctx, spec.Enclave, spec.Name)
+		if err != nil && !errors.Is(err, kes.ErrPolicyNotFound) {
+			return err
+		}
+		if err == nil && auth.EqualPatterns(current.Allow, spec.Allow) && auth.EqualPatterns(current.Deny, spec.Deny) {
+			return nil
+		}
+		return r.Server.SetPolicy(ctx, spec.Enclave, spec.Name, &kes.Policy{
+			Allow: spec.Allow,
+			Deny:  spec.Deny,
+		})
+	case ResourceIdentity:
+		var spec IdentitySpec
+		if err := json.Unmarshal(obj.Spec, &spec); err != nil {
+			return err
+		}
+		if spec.Identity.IsUnknown() {
+			return errors.New("identity is empty")
+		}
+		info, err := r.Server.DescribeIdentity(ctx, spec.Enclave, spec.Identity)
+		if err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+			return err
+		}
+		if err == nil && info.Policy == spec.Policy {
+			return nil
+		}
+		return r.Server.AssignPolicy(ctx, spec.Enclave, spec.Policy, spec.Identity)
+	default:
+		return fmt.Errorf("unknown resource '%s'", resource)
+	}
+}
+
+// delete deletes the KES resource of obj. Keys are only deleted
+// if their deletion policy is "Delete".
+func (r *Reconciler) delete(ctx context.Context, resource string, obj *Object) error {
+	switch resource {
+	case ResourceKey:
+		var spec KeySpec
+		if err := json.Unmarshal(obj.Spec, &spec); err != nil {
+			return err
+		}
+		if spec.Name == "" {
+			spec.Name = obj.Metadata.Name
+		}
+		if spec.DeletionPolicy != "Delete" {
+			return nil
+		}
+		if err := r.Server.DeleteKey(ctx, spec.Enclave, spec.Name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+		return nil
+	case ResourcePolicy:
+		var spec PolicySpec
+		if err := json.Unmarshal(obj.Spec, &spec); err != nil {
+			return err
+		}
+		if spec.Name == "" {
+			spec.Name = obj.Metadata.Name
+		}
+		if err := r.Server.DeletePolicy(ctx, spec.Enclave, spec.Name); err != nil && !errors.Is(err, kes.ErrPolicyNotFound) {
+			return err
+		}
+		return nil
+	case ResourceIdentity:
+		var spec IdentitySpec
+		if err := json.Unmarshal(obj.Spec, &spec); err != nil {
+			return err
+		}
+		if spec.Identity.IsUnknown() {
+			return nil
+		}
+		if err := r.Server.DeleteIdentity(ctx, spec.Enclave, spec.Identity); err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown resource '%s'", resource)
+	}
+}
+
+// setReady updates the Ready status condition of obj
+// if it has changed.
+func (r *Reconciler) setReady(ctx context.Context, resource string, obj *Object, reconcileErr error) error {
+	ready := Condition{
+		Type:               "Ready",
+		Status:             "True",
+		Reason:             "Reconciled",
+		Message:            "resource is in sync with the KES server",
+		ObservedGeneration: obj.Metadata.Generation,
+		LastTransitionTime: time.Now().UTC().Truncate(time.Second),
+	}
+	if reconcileErr != nil {
+		ready.Status = "False"
+		ready.Reason = "ReconcileFailed"
+		ready.Message = reconcileErr.Error()
+	}
+
+	if current, ok := obj.readyCondition(); ok {
+		if current.Status == ready.Status && current.Reason == ready.Reason && current.Message == ready.Message && current.ObservedGeneration == ready.ObservedGeneration {
+			return nil
+		}
+		if current.Status == ready.Status {
+			ready.LastTransitionTime = current.LastTransitionTime
+		}
+	}
+
+	type Patch struct {
+		Status Status `json:"status"`
+	}
+	status := Status{
+		ObservedGeneration: obj.Metadata.Generation,
+		Conditions:         []Condition{ready},
+	}
+	if err := r.Kube.Patch(ctx, r.Namespace, resource, obj.Metadata.Name, "status", Patch{Status: status}); err != nil {
+		return err
+	}
+	obj.Status = status
+	return nil
+}
+
+// patchFinalizers replaces the finalizers of obj. The patch
+// fails if obj has been modified concurrently.
+func (r *Reconciler) patchFinalizers(ctx context.Context, resource string, obj *Object, finalizers []string) error {
+	type Metadata struct {
+		ResourceVersion string   `json:"resourceVersion"`
+		Finalizers      []string `json:"finalizers"`
+	}
+	type Patch struct {
+		Metadata Metadata `json:"metadata"`
+	}
+	err := r.Kube.Patch(ctx, r.Namespace, resource, obj.Metadata.Name, "", Patch{
+		Metadata: Metadata{
+			ResourceVersion: obj.Metadata.ResourceVersion,
+			Finalizers:      finalizers,
+		},
+	})
+	if err != nil {
+		var sErr *StatusError
+		if errors.As(err, &sErr) && sErr.Status == http.StatusConflict {
+			return nil // Object has been modified. Retry during the next run.
+		}
+		return err
+	}
+	obj.Metadata.Finalizers = finalizers
+	return nil
+}
+
+func (r *Reconciler) logf(format string, v ...any) {
+	if r.ErrorLog != nil {
+		r.ErrorLog.Printf(format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+}
+
+type clientServer struct {
+	client *kes.Client
+}
+
+func (s clientServer) CreateKey(ctx context.Context, enclave, name string) error {
+	return s.client.Enclave(enclave).CreateKey(ctx, name)
+}
+
+func (s clientServer) DeleteKey(ctx context.Context, enclave, name string) error {
+	return s.client.Enclave(enclave).DeleteKey(ctx, name)
+}
+
+func (s clientServer) GetPolicy(ctx context.Context, enclave, name string) (*kes.Policy, error) {
+	return s.client.Enclave(enclave).GetPolicy(ctx, name)
+}
+
+func (s clientServer) SetPolicy(ctx context.Context, enclave, name string, policy *kes.Policy) error {
+	return s.client.Enclave(enclave).SetPolicy(ctx, name, policy)
+}
+
+func (s clientServer) DeletePolicy(ctx context.Context, enclave, name string) error {
+	return s.client.Enclave(enclave).DeletePolicy(ctx, name)
+}
+
+func (s clientServer) DescribeIdentity(ctx context.Context, enclave string, identity kes.Identity) (*kes.IdentityInfo, error) {
+	return s.client.Enclave(enclave).DescribeIdentity(ctx, identity)
+}
+
+func (s clientServer) AssignPolicy(ctx context.Context, enclave, policy string, identity kes.Identity) error {
+	return s.client.Enclave(enclave).AssignPolicy(ctx, policy, identity)
+}
+
+func (s clientServer) DeleteIdentity(ctx context.Context, enclave string, identity kes.Identity) error {
+	return s.client.Enclave(enclave).DeleteIdentity(ctx, identity)
+}
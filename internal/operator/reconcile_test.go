@@ -0,0 +1,218 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+func TestReconcile(t *testing.T) {
+	deleted := time.Now()
+	kube := newFakeKube(map[string][]Object{
+		ResourceKey: {
+			{Metadata: ObjectMeta{Name: "my-key", Generation: 1}, Spec: json.RawMessage(`{}`)},
+			{Metadata: ObjectMeta{Name: "old-key", Generation: 1, Finalizers: []string{Finalizer}, DeletionTimestamp: &deleted}, Spec: json.RawMessage(`{"deletionPolicy":"Delete"}`)},
+		},
+		ResourcePolicy: {
+			{Metadata: ObjectMeta{Name: "my-app", Generation: 2, Finalizers: []string{Finalizer}}, Spec: json.RawMessage(`{"allow":["/v1/key/*"]}`)},
+		},
+		ResourceIdentity: {
+			{Metadata: ObjectMeta{Name: "my-app", Generation: 1}, Spec: json.RawMessage(`{"identity":"3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22","policy":"my-app"}`)},
+			{Metadata: ObjectMeta{Name: "invalid", Generation: 1}, Spec: json.RawMessage(`{"policy":"my-app"}`)},
+		},
+	})
+	defer kube.Close()
+
+	server := &fakeServer{
+		keys:       map[string]bool{"old-key": true},
+		policies:   map[string]*kes.Policy{"my-app": {Allow: []string{"/v1/status"}}},
+		identities: map[kes.Identity]string{},
+	}
+	reconciler := &Reconciler{
+		Kube:      &Kube{URL: kube.URL},
+		Server:    server,
+		Namespace: "default",
+	}
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+
+	if !server.keys["my-key"] {
+		t.Fatal("Key 'my-key' has not been created")
+	}
+	if server.keys["old-key"] {
+		t.Fatal("Key 'old-key' has not been deleted")
+	}
+	if policy := server.policies["my-app"]; len(policy.Allow) != 1 || policy.Allow[0] != "/v1/key/*" {
+		t.Fatalf("Policy 'my-app' has not been updated: got '%v'", policy.Allow)
+	}
+	if policy := server.identities["3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"]; policy != "my-app" {
+		t.Fatalf("Identity got policy '%s' - want 'my-app'", policy)
+	}
+
+	for i, test := range reconcileStatusTests {
+		status, ok := kube.Status(test.Resource, test.Name)
+		if !ok {
+			t.Fatalf("Test %d: no status for %s '%s'", i, test.Resource, test.Name)
+		}
+		if len(status.Conditions) != 1 || status.Conditions[0].Status != test.Ready {
+			t.Fatalf("Test %d: got conditions '%+v' - want Ready '%s'", i, status.Conditions, test.Ready)
+		}
+		if status.ObservedGeneration != test.Generation {
+			t.Fatalf("Test %d: got observed generation %d - want %d", i, status.ObservedGeneration, test.Generation)
+		}
+	}
+	if finalizers := kube.Finalizers(ResourceKey, "old-key"); finalizers == nil || len(finalizers) != 0 {
+		t.Fatalf("Finalizer of 'old-key' has not been removed: got '%v'", finalizers)
+	}
+	if finalizers := kube.Finalizers(ResourceKey, "my-key"); len(finalizers) != 1 || finalizers[0] != Finalizer {
+		t.Fatalf("Finalizer of 'my-key' has not been added: got '%v'", finalizers)
+	}
+}
+
+var reconcileStatusTests = []struct {
+	Resource   string
+	Name       string
+	Ready      string
+	Generation int64
+}{
+	{Resource: ResourceKey, Name: "my-key", Ready: "True", Generation: 1},        // 0
+	{Resource: ResourcePolicy, Name: "my-app", Ready: "True", Generation: 2},     // 1
+	{Resource: ResourceIdentity, Name: "my-app", Ready: "True", Generation: 1},   // 2
+	{Resource: ResourceIdentity, Name: "invalid", Ready: "False", Generation: 1}, // 3
+}
+
+// fakeKube is a fake Kubernetes API server that serves a
+// fixed set of objects and records patches.
+type fakeKube struct {
+	*httptest.Server
+
+	lock       sync.Mutex
+	status     map[string]Status
+	finalizers map[string][]string
+}
+
+func newFakeKube(objects map[string][]Object) *fakeKube {
+	k := &fakeKube{
+		status:     map[string]Status{},
+		finalizers: map[string][]string{},
+	}
+	k.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const Prefix = "/apis/" + Group + "/" + Version + "/namespaces/default/"
+
+		path := strings.TrimPrefix(r.URL.Path, Prefix)
+		resource, name, _ := strings.Cut(path, "/")
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{"items": objects[resource]})
+		case http.MethodPatch:
+			body, _ := io.ReadAll(r.Body)
+
+			k.lock.Lock()
+			defer k.lock.Unlock()
+			if name, ok := strings.CutSuffix(name, "/status"); ok {
+				var patch struct{ Status Status }
+				json.Unmarshal(body, &patch)
+				k.status[resource+"/"+name] = patch.Status
+			} else {
+				var patch struct{ Metadata ObjectMeta }
+				json.Unmarshal(body, &patch)
+				k.finalizers[resource+"/"+name] = append([]string{}, patch.Metadata.Finalizers...)
+			}
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, "{}")
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	return k
+}
+
+func (k *fakeKube) Status(resource, name string) (Status, bool) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	s, ok := k.status[resource+"/"+name]
+	return s, ok
+}
+
+func (k *fakeKube) Finalizers(resource, name string) []string {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return k.finalizers[resource+"/"+name]
+}
+
+// fakeServer is an in-memory KES server.
+type fakeServer struct {
+	keys       map[string]bool
+	policies   map[string]*kes.Policy
+	identities map[kes.Identity]string
+}
+
+func (s *fakeServer) CreateKey(_ context.Context, _, name string) error {
+	if s.keys[name] {
+		return kes.ErrKeyExists
+	}
+	s.keys[name] = true
+	return nil
+}
+
+func (s *fakeServer) DeleteKey(_ context.Context, _, name string) error {
+	if !s.keys[name] {
+		return kes.ErrKeyNotFound
+	}
+	delete(s.keys, name)
+	return nil
+}
+
+func (s *fakeServer) GetPolicy(_ context.Context, _, name string) (*kes.Policy, error) {
+	p, ok := s.policies[name]
+	if !ok {
+		return nil, kes.ErrPolicyNotFound
+	}
+	return p, nil
+}
+
+func (s *fakeServer) SetPolicy(_ context.Context, _, name string, policy *kes.Policy) error {
+	s.policies[name] = policy
+	return nil
+}
+
+func (s *fakeServer) DeletePolicy(_ context.Context, _, name string) error {
+	delete(s.policies, name)
+	return nil
+}
+
+func (s *fakeServer) DescribeIdentity(_ context.Context, _ string, identity kes.Identity) (*kes.IdentityInfo, error) {
+	policy, ok := s.identities[identity]
+	if !ok {
+		return nil, kes.ErrIdentityNotFound
+	}
+	return &kes.IdentityInfo{Identity: identity, Policy: policy}, nil
+}
+
+func (s *fakeServer) AssignPolicy(_ context.Context, _, policy string, identity kes.Identity) error {
+	if _, ok := s.policies[policy]; !ok {
+		return kes.ErrPolicyNotFound
+	}
+	s.identities[identity] = policy
+	return nil
+}
+
+func (s *fakeServer) DeleteIdentity(_ context.Context, _ string, identity kes.Identity) error {
+	delete(s.identities, identity)
+	return nil
+}
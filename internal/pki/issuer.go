@@ -0,0 +1,378 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package pki implements a certificate authority that issues
+// X.509 certificates for KES servers and clients.
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/gob"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// clockSkew is subtracted from the NotBefore time of
+// issued certificates to tolerate clients whose clocks
+// are slightly behind.
+const clockSkew = 1 * time.Minute
+
+// An Issuer is a certificate authority that signs server and
+// client certificates and publishes the certificates it has
+// revoked as certificate revocation list (CRL).
+//
+// An Issuer is immutable. Revoking a certificate returns a
+// new Issuer.
+type Issuer struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+
+	revoked   []pkix.RevokedCertificate
+	crlNumber int64
+}
+
+// GenerateIssuer generates a new self-signed Issuer with the
+// given common name that is valid for the given lifetime.
+func GenerateIssuer(commonName string, lifetime time.Duration) (*Issuer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              now.Add(lifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Issuer{
+		cert: cert,
+		key:  key,
+	}, nil
+}
+
+// DeriveIssuer returns a self-signed Issuer with the given
+// common name for the Ed25519 key. The CA certificate does
+// not expire.
+//
+// Since Ed25519 signatures are deterministic, the same common
+// name, key and notBefore always result in the same CA
+// certificate. Hence, an Issuer can be derived on demand
+// without storing its certificate.
+func DeriveIssuer(commonName string, key ed25519.PrivateKey, notBefore time.Time) (*Issuer, error) {
+	// The serial number is derived from the public key
+	// such that it does not change. Clearing the top bit
+	// keeps it positive, as required by RFC 5280.
+	h := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	h[0] &= 0x7f
+	serial := new(big.Int).SetBytes(h[:16])
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             notBefore.UTC().Truncate(time.Second),
+		NotAfter:              time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC), // RFC 5280, 4.1.2.5
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Issuer{
+		cert: cert,
+		key:  key,
+	}, nil
+}
+
+// Certificate returns the Issuer's CA certificate.
+func (i *Issuer) Certificate() *x509.Certificate { return i.cert }
+
+// IssueClientCertificate issues a new client certificate for
+// the public key and subject of the certificate request. The
+// certificate expires at notAfter or once the Issuer's CA
+// certificate expires, whatever happens first.
+func (i *Issuer) IssueClientCertificate(csr *x509.CertificateRequest, notAfter time.Time) (*x509.Certificate, error) {
+	return i.issue(csr, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: csr.Subject.CommonName},
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+}
+
+// IssueServerCertificate issues a new server certificate for
+// the public key and subject of the certificate request that
+// is valid for the given DNS names and IP addresses. At least
+// one DNS name or IP address is required.
+//
+// The certificate can also be used as client certificate, e.g.
+// by cluster nodes that send requests to each other. It expires
+// at notAfter or once the Issuer's CA certificate expires,
+// whatever happens first.
+func (i *Issuer) IssueServerCertificate(csr *x509.CertificateRequest, dnsNames []string, ipAddresses []net.IP, notAfter time.Time) (*x509.Certificate, error) {
+	if len(dnsNames) == 0 && len(ipAddresses) == 0 {
+		return nil, errors.New("pki: server certificate requires at least one DNS name or IP address")
+	}
+	return i.issue(csr, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: csr.Subject.CommonName},
+		NotAfter:    notAfter,
+		DNSNames:    dnsNames,
+		IPAddresses: ipAddresses,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	})
+}
+
+// IssueCertificate issues a new certificate for the public key
+// of the certificate request that is valid for the given DNS names,
+// IP addresses and extended key usages. At least one DNS name or
+// IP address is required. The first one becomes the subject's
+// common name.
+//
+// The certificate expires at notAfter or once the Issuer's CA
+// certificate expires, whatever happens first.
+func (i *Issuer) IssueCertificate(csr *x509.CertificateRequest, dnsNames []string, ipAddresses []net.IP, usage []x509.ExtKeyUsage, notAfter time.Time) (*x509.Certificate, error) {
+	var commonName string
+	switch {
+	case len(dnsNames) > 0:
+		commonName = dnsNames[0]
+	case len(ipAddresses) > 0:
+		commonName = ipAddresses[0].String()
+	default:
+		return nil, errors.New("pki: certificate requires at least one DNS name or IP address")
+	}
+	return i.issue(csr, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		NotAfter:    notAfter,
+		DNSNames:    dnsNames,
+		IPAddresses: ipAddresses,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: usage,
+	})
+}
+
+// issue signs a certificate for the public key of the
+// certificate request based on the given template.
+func (i *Issuer) issue(csr *x509.CertificateRequest, template *x509.Certificate) (*x509.Certificate, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+	if template.NotAfter.After(i.cert.NotAfter) {
+		template.NotAfter = i.cert.NotAfter
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-clockSkew)
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, i.cert, csr.PublicKey, i.key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(raw)
+}
+
+// Revoke returns a new Issuer that has revoked the certificate
+// with the given serial number at the given point in time. If
+// the certificate has been revoked already, Revoke returns the
+// Issuer itself.
+func (i *Issuer) Revoke(serialNumber *big.Int, revokedAt time.Time) *Issuer {
+	if i.isRevoked(serialNumber) {
+		return i
+	}
+
+	revoked := make([]pkix.RevokedCertificate, 0, len(i.revoked)+1)
+	revoked = append(revoked, i.revoked...)
+	revoked = append(revoked, pkix.RevokedCertificate{
+		SerialNumber:   new(big.Int).Set(serialNumber),
+		RevocationTime: revokedAt.UTC(),
+	})
+	return &Issuer{
+		cert:      i.cert,
+		key:       i.key,
+		revoked:   revoked,
+		crlNumber: i.crlNumber + 1,
+	}
+}
+
+// IsRevoked reports whether the certificate has been issued
+// and revoked by the Issuer.
+func (i *Issuer) IsRevoked(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, i.cert.RawSubject) && i.isRevoked(cert.SerialNumber)
+}
+
+func (i *Issuer) isRevoked(serialNumber *big.Int) bool {
+	for _, r := range i.revoked {
+		if r.SerialNumber.Cmp(serialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RevocationList returns a DER-encoded certificate revocation
+// list (CRL), signed by the Issuer, that contains all revoked
+// certificates. Relying parties should fetch a new CRL before
+// nextUpdate.
+func (i *Issuer) RevocationList(thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	return x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificates: i.revoked,
+		Number:              big.NewInt(i.crlNumber),
+		ThisUpdate:          thisUpdate.UTC(),
+		NextUpdate:          nextUpdate.UTC(),
+	}, i.cert, i.key)
+}
+
+// MarshalBinary returns the Issuer's binary representation.
+func (i *Issuer) MarshalBinary() ([]byte, error) {
+	type Revocation struct {
+		SerialNumber []byte
+		RevokedAt    time.Time
+	}
+	type GOB struct {
+		Certificate []byte
+		PrivateKey  []byte
+		Revoked     []Revocation
+		CRLNumber   int64
+	}
+
+	key, err := x509.MarshalPKCS8PrivateKey(i.key)
+	if err != nil {
+		return nil, err
+	}
+	revoked := make([]Revocation, 0, len(i.revoked))
+	for _, r := range i.revoked {
+		revoked = append(revoked, Revocation{
+			SerialNumber: r.SerialNumber.Bytes(),
+			RevokedAt:    r.RevocationTime,
+		})
+	}
+
+	var buffer bytes.Buffer
+	if err = gob.NewEncoder(&buffer).Encode(GOB{
+		Certificate: i.cert.Raw,
+		PrivateKey:  key,
+		Revoked:     revoked,
+		CRLNumber:   i.crlNumber,
+	}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary unmarshals the Issuer's binary representation.
+func (i *Issuer) UnmarshalBinary(b []byte) error {
+	type Revocation struct {
+		SerialNumber []byte
+		RevokedAt    time.Time
+	}
+	type GOB struct {
+		Certificate []byte
+		PrivateKey  []byte
+		Revoked     []Revocation
+		CRLNumber   int64
+	}
+
+	var value GOB
+	if bytes.HasPrefix(b, []byte("-----BEGIN")) {
+		// Issuers without revoked certificates used to be
+		// stored as PEM-encoded certificate and private key.
+		certBlock, rest := pem.Decode(b)
+		if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+			return errors.New("pki: invalid issuer: no CA certificate")
+		}
+		keyBlock, _ := pem.Decode(rest)
+		if keyBlock == nil || keyBlock.Type != "PRIVATE KEY" {
+			return errors.New("pki: invalid issuer: no private key")
+		}
+		value.Certificate, value.PrivateKey = certBlock.Bytes, keyBlock.Bytes
+	} else if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&value); err != nil {
+		return fmt.Errorf("pki: invalid issuer: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(value.Certificate)
+	if err != nil {
+		return fmt.Errorf("pki: invalid issuer: %v", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(value.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("pki: invalid issuer: %v", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return errors.New("pki: invalid issuer: private key is not a signing key")
+	}
+	revoked := make([]pkix.RevokedCertificate, 0, len(value.Revoked))
+	for _, r := range value.Revoked {
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   new(big.Int).SetBytes(r.SerialNumber),
+			RevocationTime: r.RevokedAt,
+		})
+	}
+
+	i.cert, i.key = cert, signer
+	i.revoked, i.crlNumber = revoked, value.CRLNumber
+	return nil
+}
+
+// ParseCertificateRequest parses a PEM-encoded certificate
+// request and verifies its signature.
+func ParseCertificateRequest(pemBytes []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("pki: invalid certificate request: no PEM-encoded certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("pki: invalid certificate request: %v", err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("pki: invalid certificate request: %v", err)
+	}
+	return csr, nil
+}
+
+// serialNumber returns a random 128 bit certificate
+// serial number.
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
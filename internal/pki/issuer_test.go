@@ -0,0 +1,203 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package pki
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIssueClientCertificate(t *testing.T) {
+	issuer, err := GenerateIssuer("Test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to generate issuer: %v", err)
+	}
+	b, err := issuer.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal issuer: %v", err)
+	}
+	issuer = new(Issuer)
+	if err = issuer.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Failed to unmarshal issuer: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	rawCSR, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "client"},
+	}, priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate request: %v", err)
+	}
+	csr, err := ParseCertificateRequest(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: rawCSR}))
+	if err != nil {
+		t.Fatalf("Failed to parse certificate request: %v", err)
+	}
+
+	// The certificate must not outlive the issuer.
+	cert, err := issuer.IssueClientCertificate(csr, time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	if cert.NotAfter.After(issuer.Certificate().NotAfter) {
+		t.Fatalf("Certificate outlives its issuer: got '%v' - want '%v'", cert.NotAfter, issuer.Certificate().NotAfter)
+	}
+	if cert.Subject.CommonName != "client" {
+		t.Fatalf("Invalid subject: got '%s' - want '%s'", cert.Subject.CommonName, "client")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(issuer.Certificate())
+	if _, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Fatalf("Failed to verify certificate: %v", err)
+	}
+}
+
+func TestParseCertificateRequest(t *testing.T) {
+	if _, err := ParseCertificateRequest([]byte("not a CSR")); err == nil {
+		t.Fatal("Parsing invalid certificate request should have failed")
+	}
+	if _, err := ParseCertificateRequest(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})); err == nil {
+		t.Fatal("Parsing certificate as certificate request should have failed")
+	}
+}
+
+func TestIssuerRevoke(t *testing.T) {
+	issuer, err := GenerateIssuer("Test CA", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to generate issuer: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	rawCSR, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "kes-1"},
+	}, priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate request: %v", err)
+	}
+	csr, err := x509.ParseCertificateRequest(rawCSR)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate request: %v", err)
+	}
+	if _, err = issuer.IssueServerCertificate(csr, nil, nil, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("Issuing server certificate without SAN should have failed")
+	}
+	cert, err := issuer.IssueServerCertificate(csr, []string{"kes-1.local"}, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	if issuer.IsRevoked(cert) {
+		t.Fatal("Certificate should not be revoked")
+	}
+
+	revoked := issuer.Revoke(cert.SerialNumber, time.Now())
+	if !revoked.IsRevoked(cert) {
+		t.Fatal("Certificate should be revoked")
+	}
+	if issuer.IsRevoked(cert) {
+		t.Fatal("Revoking a certificate must not modify the original issuer")
+	}
+	if revoked.Revoke(cert.SerialNumber, time.Now()) != revoked {
+		t.Fatal("Revoking a certificate twice should return the same issuer")
+	}
+
+	b, err := revoked.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal issuer: %v", err)
+	}
+	issuer = new(Issuer)
+	if err = issuer.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Failed to unmarshal issuer: %v", err)
+	}
+	if !issuer.IsRevoked(cert) {
+		t.Fatal("Certificate should be revoked after unmarshaling the issuer")
+	}
+
+	now := time.Now()
+	raw, err := issuer.RevocationList(now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create revocation list: %v", err)
+	}
+	crl, err := x509.ParseCRL(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse revocation list: %v", err)
+	}
+	if err = issuer.Certificate().CheckCRLSignature(crl); err != nil {
+		t.Fatalf("Failed to verify revocation list: %v", err)
+	}
+	if n := len(crl.TBSCertList.RevokedCertificates); n != 1 {
+		t.Fatalf("Invalid number of revoked certificates: got '%d' - want '%d'", n, 1)
+	}
+	if serial := crl.TBSCertList.RevokedCertificates[0].SerialNumber; serial.Cmp(cert.SerialNumber) != 0 {
+		t.Fatalf("Invalid serial number: got '%x' - want '%x'", serial, cert.SerialNumber)
+	}
+}
+
+func TestDeriveIssuer(t *testing.T) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	createdAt := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	issuer, err := DeriveIssuer("Test CA", caKey, createdAt)
+	if err != nil {
+		t.Fatalf("Failed to derive issuer: %v", err)
+	}
+	again, err := DeriveIssuer("Test CA", caKey, createdAt)
+	if err != nil {
+		t.Fatalf("Failed to derive issuer: %v", err)
+	}
+	if !bytes.Equal(issuer.Certificate().Raw, again.Certificate().Raw) {
+		t.Fatal("Derived CA certificates are not equal")
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	rawCSR, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "ignored"},
+	}, priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate request: %v", err)
+	}
+	csr, err := ParseCertificateRequest(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: rawCSR}))
+	if err != nil {
+		t.Fatalf("Failed to parse certificate request: %v", err)
+	}
+	if _, err = issuer.IssueCertificate(csr, nil, nil, nil, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("Issuing certificate without DNS names and IP addresses should have failed")
+	}
+
+	usage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	cert, err := issuer.IssueCertificate(csr, []string{"kes.example.com"}, []net.IP{net.ParseIP("10.1.2.3")}, usage, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	if cert.Subject.CommonName != "kes.example.com" {
+		t.Fatalf("Invalid subject: got '%s' - want '%s'", cert.Subject.CommonName, "kes.example.com")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(again.Certificate())
+	if _, err = cert.Verify(x509.VerifyOptions{DNSName: "kes.example.com", Roots: roots, KeyUsages: usage}); err != nil {
+		t.Fatalf("Failed to verify certificate: %v", err)
+	}
+	if _, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err == nil {
+		t.Fatal("Server certificate is valid for client authentication")
+	}
+}
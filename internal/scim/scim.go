@@ -0,0 +1,217 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package scim implements the resource and message types of the
+// SCIM 2.0 protocol (RFC 7643 and RFC 7644) used by identity
+// providers to provision users and groups.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of SCIM messages.
+const ContentType = "application/scim+json"
+
+// SCIM schema URIs.
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// SCIM error types, sent as scimType of an Error.
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidValue  = "invalidValue"
+	ErrMutability    = "mutability"
+	ErrUniqueness    = "uniqueness"
+)
+
+// Meta contains the metadata of a resource.
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// User is a SCIM user resource.
+//
+// Attributes of the core user schema that are not
+// listed, like name or emails, are ignored.
+type User struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id,omitempty"`
+	UserName string   `json:"userName"`
+	Active   *bool    `json:"active,omitempty"` // Absent means active
+	Groups   []Member `json:"groups,omitempty"`
+	Meta     *Meta    `json:"meta,omitempty"`
+}
+
+// Group is a SCIM group resource.
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Member is a reference to a user, as member of a group,
+// or to a group the user is member of.
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// ListResponse is the response to a query of resources.
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    any      `json:"Resources"`
+}
+
+// PatchRequest is a request to modify a resource.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is a single modification of a PatchRequest.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Error is a SCIM error response.
+type Error struct {
+	Status int    // The HTTP status code
+	Type   string // The SCIM error type, if any
+	Detail string // A human-readable description
+}
+
+// Errorf returns a new Error with the given status code,
+// SCIM error type and formatted description.
+func Errorf(status int, typ, format string, args ...any) *Error {
+	return &Error{
+		Status: status,
+		Type:   typ,
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
+
+// Error returns the description of the error.
+func (e *Error) Error() string { return e.Detail }
+
+// MarshalJSON returns the JSON representation of the
+// error as specified by RFC 7644, section 3.12.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	type JSON struct {
+		Schemas []string `json:"schemas"`
+		Status  string   `json:"status"`
+		Type    string   `json:"scimType,omitempty"`
+		Detail  string   `json:"detail,omitempty"`
+	}
+	return json.Marshal(JSON{
+		Schemas: []string{ErrorSchema},
+		Status:  strconv.Itoa(e.Status),
+		Type:    e.Type,
+		Detail:  e.Detail,
+	})
+}
+
+// Filter is a SCIM filter that selects all resources
+// whose attribute is equal to a value.
+//
+// Other filter operators, like 'co' or 'and', are
+// not supported.
+type Filter struct {
+	Attribute string // The attribute name in lower case
+	Value     string
+}
+
+// ParseFilter parses s as filter expression of the form:
+//
+//	<attribute> eq "<value>"
+//
+// Attribute names and the operator are case-insensitive.
+func ParseFilter(s string) (Filter, error) {
+	attr, rest, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return Filter{}, Errorf(http.StatusBadRequest, ErrInvalidFilter, "invalid filter '%s'", s)
+	}
+	op, value, ok := strings.Cut(strings.TrimSpace(rest), " ")
+	if !ok {
+		return Filter{}, Errorf(http.StatusBadRequest, ErrInvalidFilter, "invalid filter '%s'", s)
+	}
+	if !strings.EqualFold(op, "eq") {
+		return Filter{}, Errorf(http.StatusBadRequest, ErrInvalidFilter, "unsupported filter operator '%s'", op)
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(value))
+	if err != nil {
+		return Filter{}, Errorf(http.StatusBadRequest, ErrInvalidFilter, "invalid filter value in '%s'", s)
+	}
+	return Filter{
+		Attribute: strings.ToLower(attr),
+		Value:     value,
+	}, nil
+}
+
+// ParsePath parses s as attribute path of a PatchOperation,
+// either a plain attribute name or an attribute with a value
+// filter, like:
+//
+//	members[value eq "<value>"]
+//
+// It returns the attribute name in lower case and the filter,
+// if any.
+func ParsePath(s string) (string, *Filter, error) {
+	attr, expr, ok := strings.Cut(s, "[")
+	if !ok {
+		return strings.ToLower(strings.TrimSpace(s)), nil, nil
+	}
+	expr = strings.TrimSpace(expr)
+	if !strings.HasSuffix(expr, "]") {
+		return "", nil, Errorf(http.StatusBadRequest, ErrInvalidPath, "invalid path '%s'", s)
+	}
+	filter, err := ParseFilter(strings.TrimSuffix(expr, "]"))
+	if err != nil {
+		return "", nil, Errorf(http.StatusBadRequest, ErrInvalidPath, "invalid path '%s'", s)
+	}
+	return strings.ToLower(strings.TrimSpace(attr)), &filter, nil
+}
+
+// ParseBool parses the raw JSON value as boolean. Since some
+// identity providers send booleans as strings, it accepts
+// "true" and "false" strings as well.
+func ParseBool(raw json.RawMessage) (bool, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return false, Errorf(http.StatusBadRequest, ErrInvalidValue, "invalid boolean '%s'", raw)
+	}
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, Errorf(http.StatusBadRequest, ErrInvalidValue, "invalid boolean '%s'", raw)
+}
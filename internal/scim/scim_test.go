@@ -0,0 +1,113 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var parseFilterTests = []struct {
+	Filter     string
+	Want       Filter
+	ShouldFail bool
+}{
+	{Filter: `userName eq "my-app"`, Want: Filter{Attribute: "username", Value: "my-app"}},             // 0
+	{Filter: `displayName EQ "my policy"`, Want: Filter{Attribute: "displayname", Value: "my policy"}}, // 1
+	{Filter: `  id eq "a\"b"  `, Want: Filter{Attribute: "id", Value: `a"b`}},                          // 2
+	{Filter: `userName co "my-app"`, ShouldFail: true},                                                 // 3
+	{Filter: `userName eq my-app`, ShouldFail: true},                                                   // 4
+	{Filter: `userName`, ShouldFail: true},                                                             // 5
+	{Filter: `userName eq "a" and id eq "b"`, ShouldFail: true},                                        // 6
+}
+
+func TestParseFilter(t *testing.T) {
+	for i, test := range parseFilterTests {
+		filter, err := ParseFilter(test.Filter)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse filter: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing filter should have failed", i)
+		}
+		if err == nil && filter != test.Want {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, filter, test.Want)
+		}
+	}
+}
+
+var parsePathTests = []struct {
+	Path       string
+	Attribute  string
+	Filter     *Filter
+	ShouldFail bool
+}{
+	{Path: "active", Attribute: "active"},   // 0
+	{Path: "members", Attribute: "members"}, // 1
+	{Path: `members[value eq "abc"]`, Attribute: "members", Filter: &Filter{Attribute: "value", Value: "abc"}}, // 2
+	{Path: `members[value eq "abc"`, ShouldFail: true},                                                         // 3
+	{Path: `members[value]`, ShouldFail: true},                                                                 // 4
+}
+
+func TestParsePath(t *testing.T) {
+	for i, test := range parsePathTests {
+		attr, filter, err := ParsePath(test.Path)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse path: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing path should have failed", i)
+		}
+		if err != nil {
+			continue
+		}
+		if attr != test.Attribute {
+			t.Fatalf("Test %d: got attribute '%s' - want '%s'", i, attr, test.Attribute)
+		}
+		if (filter == nil) != (test.Filter == nil) || (filter != nil && *filter != *test.Filter) {
+			t.Fatalf("Test %d: got filter '%v' - want '%v'", i, filter, test.Filter)
+		}
+	}
+}
+
+var parseBoolTests = []struct {
+	Value      string
+	Want       bool
+	ShouldFail bool
+}{
+	{Value: `true`, Want: true},        // 0
+	{Value: `false`, Want: false},      // 1
+	{Value: `"False"`, Want: false},    // 2
+	{Value: `"True"`, Want: true},      // 3
+	{Value: `"yes"`, ShouldFail: true}, // 4
+	{Value: `1`, ShouldFail: true},     // 5
+}
+
+func TestParseBool(t *testing.T) {
+	for i, test := range parseBoolTests {
+		b, err := ParseBool(json.RawMessage(test.Value))
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse bool: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing bool should have failed", i)
+		}
+		if err == nil && b != test.Want {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, b, test.Want)
+		}
+	}
+}
+
+func TestErrorMarshalJSON(t *testing.T) {
+	const Want = `{"schemas":["urn:ietf:params:scim:api:messages:2.0:Error"],"status":"409","scimType":"uniqueness","detail":"user already exists"}`
+
+	b, err := json.Marshal(Errorf(409, ErrUniqueness, "user already exists"))
+	if err != nil {
+		t.Fatalf("Failed to marshal error: %v", err)
+	}
+	if string(b) != Want {
+		t.Fatalf("Invalid error: got '%s' - want '%s'", b, Want)
+	}
+}
@@ -16,6 +16,36 @@ import (
 // MaxSize is the maximum size of a secret.
 const MaxSize = 1 * mem.MiB
 
+// MaxValueSize is the maximum size of a secret value.
+// Secrets are meant for small credentials, like passwords
+// or API tokens, not for arbitrary data.
+const MaxValueSize = 64 * mem.KiB
+
+// MaxVersions is the maximum number of versions kept
+// per secret, including the current version. Older
+// versions are discarded once a secret gets updated.
+const MaxVersions = 10
+
+// A Version is a particular value of a secret.
+type Version struct {
+	Version   uint64    // Version number, starting at 1
+	CreatedAt time.Time // Point in time when the version has been created
+	Bytes     []byte    // Secret value
+}
+
+// Rotation describes how a secret gets rotated on a schedule.
+//
+// Once the Interval has passed since the secret has been
+// modified last, KES invokes the Endpoint to produce a new
+// value and stores it as new version of the secret.
+type Rotation struct {
+	Endpoint string        // URL of the rotation webhook
+	Interval time.Duration // Time between two rotations
+}
+
+// Enabled reports whether the rotation is enabled.
+func (r Rotation) Enabled() bool { return r.Endpoint != "" && r.Interval > 0 }
+
 // Secret is a generic secret, like a password,
 // API key, or private key.
 type Secret struct {
@@ -23,6 +53,9 @@ type Secret struct {
 	createdAt time.Time
 	modTime   time.Time
 	createdBy kes.Identity
+	version   uint64
+	previous  []Version // Newest first
+	rotation  Rotation
 
 	bytes []byte
 }
@@ -40,6 +73,36 @@ func NewSecret(value []byte, owner kes.Identity) Secret {
 		createdAt: now,
 		modTime:   now,
 		createdBy: owner,
+		version:   1,
+	}
+}
+
+// Update returns a new version of the Secret with the
+// given value. Its version is incremented by one and its
+// ModTime is time.Now. The current value is kept as
+// previous version, up to MaxVersions versions.
+func (s *Secret) Update(value []byte) Secret {
+	previous := make([]Version, 0, MaxVersions-1)
+	previous = append(previous, Version{
+		Version:   s.version,
+		CreatedAt: s.modTime,
+		Bytes:     s.bytes,
+	})
+	for _, v := range s.previous {
+		if len(previous) == MaxVersions-1 {
+			break
+		}
+		previous = append(previous, v)
+	}
+	return Secret{
+		bytes:     clone(value),
+		kind:      s.kind,
+		createdAt: s.createdAt,
+		modTime:   time.Now().UTC(),
+		createdBy: s.createdBy,
+		version:   s.version + 1,
+		previous:  previous,
+		rotation:  s.rotation,
 	}
 }
 
@@ -59,9 +122,56 @@ func (s *Secret) ModTime() time.Time { return s.modTime }
 // CreatedBy returns the identity that created the secret.
 func (s *Secret) CreatedBy() kes.Identity { return s.createdBy }
 
+// Version returns the version of the secret. A new secret
+// has version 1. Each update increments its version by one.
+func (s *Secret) Version() uint64 { return s.version }
+
 // Bytes returns the Secret value.
 func (s *Secret) Bytes() []byte { return clone(s.bytes) }
 
+// Versions returns all versions of the secret, newest first.
+// The returned versions do not contain the secret values.
+// Use Lookup to get the value of a particular version.
+func (s *Secret) Versions() []Version {
+	versions := make([]Version, 0, 1+len(s.previous))
+	versions = append(versions, Version{Version: s.version, CreatedAt: s.modTime})
+	for _, v := range s.previous {
+		versions = append(versions, Version{Version: v.Version, CreatedAt: v.CreatedAt})
+	}
+	return versions
+}
+
+// Lookup returns the given version of the secret. It
+// reports whether such a version exists.
+func (s *Secret) Lookup(version uint64) (Version, bool) {
+	if version == s.version {
+		return Version{Version: s.version, CreatedAt: s.modTime, Bytes: clone(s.bytes)}, true
+	}
+	for _, v := range s.previous {
+		if v.Version == version {
+			return Version{Version: v.Version, CreatedAt: v.CreatedAt, Bytes: clone(v.Bytes)}, true
+		}
+	}
+	return Version{}, false
+}
+
+// Rotation returns the rotation schedule of the secret.
+func (s *Secret) Rotation() Rotation { return s.rotation }
+
+// SetRotation sets the rotation schedule of the secret.
+// A zero Rotation disables the rotation.
+func (s *Secret) SetRotation(r Rotation) { s.rotation = r }
+
+// NextRotation returns the point in time at which the
+// secret should be rotated next. It returns the zero
+// time if the rotation is not enabled.
+func (s *Secret) NextRotation() time.Time {
+	if !s.rotation.Enabled() {
+		return time.Time{}
+	}
+	return s.modTime.Add(s.rotation.Interval)
+}
+
 // MarshalBinary returns the Secret's binary representation.
 func (s *Secret) MarshalBinary() ([]byte, error) {
 	type GOB struct {
@@ -69,6 +179,9 @@ func (s *Secret) MarshalBinary() ([]byte, error) {
 		CreatedAt time.Time
 		ModTime   time.Time
 		CreatedBy kes.Identity
+		Version   uint64
+		Previous  []Version
+		Rotation  Rotation
 		Bytes     []byte
 	}
 
@@ -79,6 +192,9 @@ func (s *Secret) MarshalBinary() ([]byte, error) {
 		CreatedAt: s.CreatedAt(),
 		ModTime:   s.modTime,
 		CreatedBy: s.CreatedBy(),
+		Version:   s.version,
+		Previous:  s.previous,
+		Rotation:  s.rotation,
 	})
 	return buffer.Bytes(), err
 }
@@ -90,6 +206,9 @@ func (s *Secret) UnmarshalBinary(data []byte) error {
 		CreatedAt time.Time
 		ModTime   time.Time
 		CreatedBy kes.Identity
+		Version   uint64
+		Previous  []Version
+		Rotation  Rotation
 		Bytes     []byte
 	}
 
@@ -103,6 +222,12 @@ func (s *Secret) UnmarshalBinary(data []byte) error {
 	s.createdAt = value.CreatedAt
 	s.modTime = value.ModTime
 	s.createdBy = value.CreatedBy
+	s.version = value.Version
+	s.previous = value.Previous
+	s.rotation = value.Rotation
+	if s.version == 0 { // Secrets created before versioning
+		s.version = 1
+	}
 	return nil
 }
 
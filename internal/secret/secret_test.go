@@ -0,0 +1,76 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package secret
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSecretUpdate(t *testing.T) {
+	s := NewSecret([]byte("v1"), "")
+	s.SetRotation(Rotation{Endpoint: "https://127.0.0.1:8443/rotate", Interval: time.Hour})
+	for i := 2; i <= MaxVersions+5; i++ {
+		s = s.Update([]byte("v" + strconv.Itoa(i)))
+	}
+
+	if v := s.Version(); v != MaxVersions+5 {
+		t.Fatalf("Invalid version: got '%d' - want '%d'", v, MaxVersions+5)
+	}
+	versions := s.Versions()
+	if len(versions) != MaxVersions {
+		t.Fatalf("Invalid number of versions: got '%d' - want '%d'", len(versions), MaxVersions)
+	}
+	for i, v := range versions {
+		if want := s.Version() - uint64(i); v.Version != want {
+			t.Fatalf("Version %d: got version '%d' - want '%d'", i, v.Version, want)
+		}
+		if v.Bytes != nil {
+			t.Fatalf("Version %d: contains secret value", i)
+		}
+	}
+
+	v, ok := s.Lookup(7)
+	if !ok {
+		t.Fatal("Version 7 does not exist")
+	}
+	if string(v.Bytes) != "v7" {
+		t.Fatalf("Invalid value of version 7: got '%s' - want '%s'", v.Bytes, "v7")
+	}
+	if _, ok = s.Lookup(5); ok {
+		t.Fatal("Version 5 has not been discarded")
+	}
+	if !s.Rotation().Enabled() {
+		t.Fatal("Rotation has been discarded by update")
+	}
+}
+
+func TestSecretMarshalBinary(t *testing.T) {
+	s := NewSecret([]byte("v1"), "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22")
+	s.SetRotation(Rotation{Endpoint: "https://127.0.0.1:8443/rotate", Interval: time.Hour})
+	s = s.Update([]byte("v2"))
+
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode secret: %v", err)
+	}
+	var decoded Secret
+	if err = decoded.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Failed to decode secret: %v", err)
+	}
+	if decoded.Version() != 2 || string(decoded.Bytes()) != "v2" {
+		t.Fatalf("Invalid secret: got version '%d' with value '%s' - want version '%d' with value '%s'", decoded.Version(), decoded.Bytes(), 2, "v2")
+	}
+	if v, ok := decoded.Lookup(1); !ok || string(v.Bytes) != "v1" {
+		t.Fatalf("Invalid previous version: got '%s' - want '%s'", v.Bytes, "v1")
+	}
+	if r := decoded.Rotation(); r != s.Rotation() {
+		t.Fatalf("Invalid rotation: got '%v' - want '%v'", r, s.Rotation())
+	}
+	if next := decoded.NextRotation(); !next.Equal(s.ModTime().Add(time.Hour)) {
+		t.Fatalf("Invalid next rotation: got '%v' - want '%v'", next, s.ModTime().Add(time.Hour))
+	}
+}
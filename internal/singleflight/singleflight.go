@@ -0,0 +1,68 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package singleflight deduplicates concurrent function
+// calls for the same key.
+package singleflight
+
+import "sync"
+
+// Group deduplicates concurrent calls for the same key.
+// While a call for a key is in flight, subsequent calls
+// for the same key wait for the in-flight call and share
+// its result.
+//
+// The zero value is ready to use. A Group must not be
+// copied after first use.
+type Group[K comparable, V any] struct {
+	lock  sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	dups  int // Number of calls waiting for this call
+	value V
+	err   error
+}
+
+// Do executes fn for the given key unless a call for
+// the same key is already in flight. In this case, Do
+// waits for the in-flight call and returns its result.
+// The returned bool reports whether the result has been
+// shared with, or produced by, another call.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (V, error, bool) {
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*call[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.lock.Unlock()
+		<-c.done
+		return c.value, c.err, true
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.lock.Unlock()
+
+	defer func() {
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err, false
+}
+
+// Forget forgets the in-flight call for the key, if any.
+// Subsequent calls for the key execute their function
+// instead of waiting for the in-flight call.
+func (g *Group[K, V]) Forget(key K) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	delete(g.calls, key)
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGroupDo(t *testing.T) {
+	var (
+		group   Group[string, int]
+		calls   uint32
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+	fn := func() (int, error) {
+		if atomic.AddUint32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return 42, nil
+	}
+
+	const N = 10
+	var (
+		wg     sync.WaitGroup
+		shared uint32
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if v, err, _ := group.Do("key", fn); v != 42 || err != nil {
+			t.Errorf("Invalid result: got '%d, %v' - want '%d, %v'", v, err, 42, nil)
+		}
+	}()
+	<-started
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, ok := group.Do("key", fn)
+			if v != 42 || err != nil {
+				t.Errorf("Invalid result: got '%d, %v' - want '%d, %v'", v, err, 42, nil)
+			}
+			if ok {
+				atomic.AddUint32(&shared, 1)
+			}
+		}()
+	}
+	for waiting(&group, "key") < N {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("Invalid number of calls: got '%d' - want '%d'", calls, 1)
+	}
+	if shared != N {
+		t.Fatalf("Invalid number of shared results: got '%d' - want '%d'", shared, N)
+	}
+
+	// Calls that start after a call has completed are not deduplicated.
+	errCall := errors.New("call failed")
+	if _, err, ok := group.Do("key", func() (int, error) { return 0, errCall }); err != errCall || ok {
+		t.Fatalf("Invalid result: got '%v, %v' - want '%v, %v'", err, ok, errCall, false)
+	}
+}
+
+func waiting[K comparable, V any](g *Group[K, V], key K) int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		return c.dups
+	}
+	return 0
+}
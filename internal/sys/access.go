@@ -0,0 +1,240 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+const (
+	// AccessRequestTimeout is the time after which a pending
+	// access request that has neither been approved nor
+	// denied is discarded.
+	AccessRequestTimeout = 24 * time.Hour
+
+	// MaxAccessRequests is the max. number of pending access
+	// requests per Enclave.
+	MaxAccessRequests = 1000
+
+	// MaxAccessDuration is the max. duration an identity can
+	// request to be assigned to a policy.
+	MaxAccessDuration = 24 * time.Hour
+)
+
+// ErrAccessRequestNotFound is returned when an access request
+// does not exist, has already been approved or denied, or has
+// timed out.
+var ErrAccessRequestNotFound = kes.NewError(http.StatusNotFound, "access request does not exist")
+
+// An AccessRequest is a request of an identity to be
+// assigned to a policy for a limited duration.
+type AccessRequest struct {
+	// ID uniquely identifies the access request.
+	ID string
+
+	// Identity is the identity requesting access.
+	Identity kes.Identity
+
+	// Policy is the requested policy.
+	Policy string
+
+	// Duration is the time the identity remains assigned
+	// to the policy once the request has been approved.
+	Duration time.Duration
+
+	// Reason is an optional justification provided by
+	// the requesting identity.
+	Reason string
+
+	// RequestedAt is the point in time when the access
+	// has been requested.
+	RequestedAt time.Time
+}
+
+// Expired reports whether the access request has
+// timed out without being approved or denied.
+func (r *AccessRequest) Expired() bool {
+	return time.Since(r.RequestedAt) >= AccessRequestTimeout
+}
+
+// RequestAccess files a new access request of the identity for
+// the policy. Any pending request of the same identity is
+// replaced.
+//
+// Identities that are assigned to a policy cannot request
+// access since an approved request would replace their
+// existing policy assignment. The requested duration must
+// not exceed MaxAccessDuration.
+//
+// Pending access requests are kept in memory only. They do not
+// survive a restart of the server or sealing of the Vault and
+// have to be filed again.
+func (e *Enclave) RequestAccess(ctx context.Context, identity kes.Identity, policy string, duration time.Duration, reason string) (AccessRequest, error) {
+	if identity.IsUnknown() {
+		return AccessRequest{}, kes.NewError(http.StatusBadRequest, "identity is unknown")
+	}
+	if duration <= 0 {
+		return AccessRequest{}, kes.NewError(http.StatusBadRequest, "access duration must be positive")
+	}
+	if duration > MaxAccessDuration {
+		return AccessRequest{}, kes.NewError(http.StatusBadRequest, "access duration must not exceed "+MaxAccessDuration.String())
+	}
+	admin, err := e.Admin(ctx)
+	if err != nil {
+		return AccessRequest{}, err
+	}
+	if identity == admin {
+		return AccessRequest{}, kes.NewError(http.StatusBadRequest, "admin cannot request access")
+	}
+	if err = e.verifyUnassigned(ctx, identity); err != nil {
+		return AccessRequest{}, err
+	}
+
+	var id [16]byte
+	if _, err = rand.Read(id[:]); err != nil {
+		return AccessRequest{}, err
+	}
+	req := AccessRequest{
+		ID:          hex.EncodeToString(id[:]),
+		Identity:    identity,
+		Policy:      policy,
+		Duration:    duration,
+		Reason:      reason,
+		RequestedAt: time.Now().UTC(),
+	}
+
+	e.accessLock.Lock()
+	defer e.accessLock.Unlock()
+
+	if e.accessRequests == nil {
+		e.accessRequests = map[string]AccessRequest{}
+	}
+	for k, v := range e.accessRequests {
+		if v.Identity == identity || v.Expired() {
+			delete(e.accessRequests, k)
+		}
+	}
+	if len(e.accessRequests) >= MaxAccessRequests {
+		return AccessRequest{}, kes.NewError(http.StatusTooManyRequests, "too many pending access requests")
+	}
+	e.accessRequests[req.ID] = req
+	return req, nil
+}
+
+// GetAccessRequest returns the pending access request
+// with the given ID.
+//
+// It returns ErrAccessRequestNotFound if no such
+// request is pending.
+func (e *Enclave) GetAccessRequest(id string) (AccessRequest, error) {
+	e.accessLock.Lock()
+	defer e.accessLock.Unlock()
+
+	req, ok := e.accessRequests[id]
+	if !ok || req.Expired() {
+		return AccessRequest{}, ErrAccessRequestNotFound
+	}
+	return req, nil
+}
+
+// ListAccessRequests returns all pending access requests
+// sorted by the time they have been requested.
+func (e *Enclave) ListAccessRequests() []AccessRequest {
+	e.accessLock.Lock()
+	defer e.accessLock.Unlock()
+
+	requests := make([]AccessRequest, 0, len(e.accessRequests))
+	for _, req := range e.accessRequests {
+		if !req.Expired() {
+			requests = append(requests, req)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+	})
+	return requests
+}
+
+// ApproveAccessRequest approves the pending access request
+// with the given ID on behalf of the approver. It assigns the
+// requested policy to the requesting identity until the
+// requested duration has passed.
+//
+// An identity cannot approve its own access request. A request
+// cannot be approved if the requesting identity has been assigned
+// to a policy since filing the request.
+//
+// It returns ErrAccessRequestNotFound if no such request
+// is pending.
+func (e *Enclave) ApproveAccessRequest(ctx context.Context, id string, approver kes.Identity) (AccessRequest, error) {
+	req, err := e.removeAccessRequest(id, func(req AccessRequest) error {
+		if req.Identity == approver {
+			return kes.NewError(http.StatusForbidden, "identity cannot approve its own access request")
+		}
+		return nil
+	})
+	if err != nil {
+		return AccessRequest{}, err
+	}
+	if err = e.verifyUnassigned(ctx, req.Identity); err != nil {
+		return AccessRequest{}, err
+	}
+	if err = e.AssignPolicyUntil(ctx, req.Policy, req.Identity, time.Now().Add(req.Duration)); err != nil {
+		return AccessRequest{}, err
+	}
+	return req, nil
+}
+
+// DenyAccessRequest discards the pending access request
+// with the given ID.
+//
+// It returns ErrAccessRequestNotFound if no such request
+// is pending.
+func (e *Enclave) DenyAccessRequest(id string) (AccessRequest, error) {
+	return e.removeAccessRequest(id, func(AccessRequest) error { return nil })
+}
+
+// verifyUnassigned returns an error if the identity is
+// currently assigned to a policy. Expired assignments
+// are ignored.
+func (e *Enclave) verifyUnassigned(ctx context.Context, identity kes.Identity) error {
+	info, err := e.GetIdentity(ctx, identity)
+	if errors.Is(err, kes.ErrIdentityNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.Expired() {
+		return kes.NewError(http.StatusConflict, "identity is already assigned to policy '"+info.Policy+"'")
+	}
+	return nil
+}
+
+// removeAccessRequest removes the pending access request with
+// the given ID if and only if verify does not return an error.
+func (e *Enclave) removeAccessRequest(id string, verify func(AccessRequest) error) (AccessRequest, error) {
+	e.accessLock.Lock()
+	defer e.accessLock.Unlock()
+
+	req, ok := e.accessRequests[id]
+	if !ok || req.Expired() {
+		delete(e.accessRequests, id)
+		return AccessRequest{}, ErrAccessRequestNotFound
+	}
+	if err := verify(req); err != nil {
+		return AccessRequest{}, err
+	}
+	delete(e.accessRequests, id)
+	return req, nil
+}
@@ -7,13 +7,16 @@ package sys
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/gob"
 	"encoding/hex"
 	"errors"
 	"net/http"
+	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/kes-go"
@@ -55,6 +58,10 @@ type EnclaveInfo struct {
 
 	// CreatedBy is the identity that created the Enclave.
 	CreatedBy kes.Identity
+
+	// KeyPolicy, if not nil, restricts the algorithms
+	// of keys created within the Enclave.
+	KeyPolicy *key.AlgorithmPolicy
 }
 
 // MarshalBinary returns the EnclaveInfo's binary representation.
@@ -67,6 +74,7 @@ func (e EnclaveInfo) MarshalBinary() ([]byte, error) {
 		IdentityKey key.Key
 		CreatedAt   time.Time
 		CreatedBy   kes.Identity
+		KeyPolicy   *key.AlgorithmPolicy
 	}
 
 	var buffer bytes.Buffer
@@ -86,6 +94,7 @@ func (e *EnclaveInfo) UnmarshalBinary(b []byte) error {
 		IdentityKey key.Key
 		CreatedAt   time.Time
 		CreatedBy   kes.Identity
+		KeyPolicy   *key.AlgorithmPolicy
 	}
 
 	var value GOB
@@ -99,15 +108,18 @@ func (e *EnclaveInfo) UnmarshalBinary(b []byte) error {
 	e.IdentityKey = value.IdentityKey
 	e.CreatedAt = value.CreatedAt
 	e.CreatedBy = value.CreatedBy
+	e.KeyPolicy = value.KeyPolicy
 	return nil
 }
 
-// NewEnclave returns a new Enclave with the
-// given key store, policy set and identity set.
-func NewEnclave(keys KeyFS, secrets SecretFS, policies PolicyFS, identities IdentityFS) *Enclave {
+// NewEnclave returns a new Enclave with the given
+// key store, secret store, token store, policy set
+// and identity set.
+func NewEnclave(keys KeyFS, secrets SecretFS, tokens TokenFS, policies PolicyFS, identities IdentityFS) *Enclave {
 	return &Enclave{
 		keys:       keys,
 		secrets:    secrets,
+		tokens:     tokens,
 		policies:   policies,
 		identities: identities,
 
@@ -118,28 +130,84 @@ func NewEnclave(keys KeyFS, secrets SecretFS, policies PolicyFS, identities Iden
 	}
 }
 
+// errReservedPolicy is returned when trying to modify the
+// built-in enclave admin policy.
+var errReservedPolicy = kes.NewError(http.StatusBadRequest, "policy '"+auth.EnclaveAdminPolicy+"' is reserved")
+
+// errImmutablePolicy is returned when trying to overwrite
+// or delete a policy within its retention period.
+var errImmutablePolicy = kes.NewError(http.StatusForbidden, "policy is immutable")
+
 // An Enclave is a shielded environment within a Vault that
 // stores keys, policies and identities.
+//
+// An Enclave is safe for concurrent use. Its caches are
+// guarded by a lock that is never held while accessing the
+// underlying storage. Instead, operations on the same entry,
+// e.g. the same key, are serialized while operations on
+// distinct entries proceed concurrently.
 type Enclave struct {
+	// Number of GetKey calls served from and missing
+	// the key cache. Must be 64-bit aligned for atomic
+	// access on 32-bit platforms.
+	keyHits, keyMisses uint64
+
 	keys       KeyFS
 	secrets    SecretFS
+	tokens     TokenFS
 	policies   PolicyFS
 	identities IdentityFS
-	lock       sync.RWMutex
 
-	cacheLock     sync.Mutex
+	cacheLock     sync.RWMutex // Protects admin and the caches
 	admin         kes.Identity
+	adminGen      uint64 // Incremented whenever the admin changes
 	keyCache      map[string]key.Key
 	secretCache   map[string]secret.Secret
 	policyCache   map[string]auth.Policy
 	identityCache map[kes.Identity]auth.IdentityInfo
+
+	// signingKeys is the index of enabled token signing
+	// keys, by name, or nil if it has to be rebuilt. The
+	// signingKeysGen is incremented whenever the index
+	// changes such that a concurrent rebuild can detect
+	// that its result is stale. Both are protected by
+	// the cacheLock.
+	signingKeys     map[string]key.Key
+	signingKeysGen  uint64
+	signingKeysLock sync.Mutex // Serializes index rebuilds
+
+	keyLocks      entryLocks
+	secretLocks   entryLocks
+	policyLocks   entryLocks
+	identityLocks entryLocks
+
+	accessLock     sync.Mutex // Protects accessRequests
+	accessRequests map[string]AccessRequest
+
+	keyPolicy *key.AlgorithmPolicy
+
+	// writes is shared with the Vault the Enclave
+	// belongs to. It is held exclusively while the
+	// Vault checks its state such that the check
+	// sees a consistent snapshot.
+	writes *sync.RWMutex
 }
 
-// Locker returns a sync.Locker that locks the Enclave for writes.
-func (e *Enclave) Locker() sync.Locker { return &e.lock }
+// beginWrite waits until no Vault state check is
+// in progress and returns a function that ends the
+// write.
+func (e *Enclave) beginWrite() (end func()) {
+	if e.writes == nil {
+		return func() {}
+	}
+	e.writes.RLock()
+	return e.writes.RUnlock
+}
 
-// RLocker returns a sync.Locker that locks the Enclave for reads.
-func (e *Enclave) RLocker() sync.Locker { return e.lock.RLocker() }
+// KeyPolicy returns the policy that restricts the algorithms
+// of keys created within the Enclave. It returns nil if the
+// Enclave has no key policy.
+func (e *Enclave) KeyPolicy() *key.AlgorithmPolicy { return e.keyPolicy }
 
 // Status returns the current state of the key store.
 //
@@ -154,40 +222,351 @@ func (e *Enclave) Status(context.Context) (kms.State, error) { return kms.State{
 //
 // It returns kes.ErrKeyExists if such an entry exists.
 func (e *Enclave) CreateKey(ctx context.Context, name string, key key.Key) error {
-	if _, ok := e.keyCache[name]; ok {
+	defer e.beginWrite()()
+
+	if _, ok := lookup(&e.cacheLock, e.keyCache, name); ok {
 		return kes.ErrKeyExists
 	}
-	return e.keys.CreateKey(ctx, name, key)
+
+	unlock := e.keyLocks.Lock(name)
+	defer unlock()
+
+	if err := e.keys.CreateKey(ctx, name, key); err != nil {
+		return err
+	}
+	e.indexSigningKey(name, key)
+	return nil
 }
 
 // DeleteKey deletes the key associated with the given name.
+//
+// It returns key.ErrImmutable if the key is within its
+// retention period and key.ErrLegalHold if the key is
+// under legal hold.
 func (e *Enclave) DeleteKey(ctx context.Context, name string) error {
-	delete(e.keyCache, name)
-	return e.keys.DeleteKey(ctx, name)
+	defer e.beginWrite()()
+
+	unlock := e.keyLocks.Lock(name)
+	defer unlock()
+
+	if k, err := e.keys.GetKey(ctx, name); err == nil {
+		if k.Immutable() {
+			return key.ErrImmutable
+		}
+		if k.LegalHold() != nil {
+			return key.ErrLegalHold
+		}
+	}
+	evict(&e.cacheLock, e.keyCache, name)
+	if err := e.keys.DeleteKey(ctx, name); err != nil {
+		return err
+	}
+	e.indexSigningKey(name, key.Key{})
+	return nil
+}
+
+// HoldKey places the legal hold on the key associated
+// with the given name. The key cannot be deleted until
+// the hold is released. HoldKey keeps any legal hold
+// already placed on the key.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) HoldKey(ctx context.Context, name string, hold key.LegalHold) error {
+	return e.updateKey(ctx, name, func(k *key.Key) bool {
+		if k.LegalHold() != nil {
+			return false
+		}
+		k.SetLegalHold(&hold)
+		return true
+	})
+}
+
+// ReleaseKey releases the legal hold placed on the
+// key associated with the given name, if any.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) ReleaseKey(ctx context.Context, name string) error {
+	return e.updateKey(ctx, name, func(k *key.Key) bool {
+		if k.LegalHold() == nil {
+			return false
+		}
+		k.SetLegalHold(nil)
+		return true
+	})
+}
+
+// DisableKey disables the key associated with the given
+// name. A disabled key is not deleted but cannot be used
+// for cryptographic operations until it is enabled again.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) DisableKey(ctx context.Context, name string) error {
+	return e.updateKey(ctx, name, func(k *key.Key) bool {
+		if k.Disabled() {
+			return false
+		}
+		k.SetDisabled(true)
+		return true
+	})
+}
+
+// EnableKey enables the key associated with the given
+// name if it has been disabled.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) EnableKey(ctx context.Context, name string) error {
+	return e.updateKey(ctx, name, func(k *key.Key) bool {
+		if !k.Disabled() {
+			return false
+		}
+		k.SetDisabled(false)
+		return true
+	})
+}
+
+// SetKeyAllowlist replaces the allowlist of the key associated
+// with the given name. The zero Allowlist removes any restriction
+// such that the key can be used by any identity with a matching
+// policy.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) SetKeyAllowlist(ctx context.Context, name string, allowlist key.Allowlist) error {
+	return e.updateKey(ctx, name, func(k *key.Key) bool {
+		k.SetAllowlist(allowlist)
+		return true
+	})
+}
+
+// SetKeyTokenSigning designates the key associated with the
+// given name as JWT signing key or revokes the designation.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) SetKeyTokenSigning(ctx context.Context, name string, enabled bool) error {
+	return e.updateKey(ctx, name, func(k *key.Key) bool {
+		if k.TokenSigning() == enabled {
+			return false
+		}
+		k.SetTokenSigning(enabled)
+		return true
+	})
+}
+
+// updateKey reads the key associated with the given name
+// from the underlying storage, applies the update and
+// writes the key back if update returns true.
+func (e *Enclave) updateKey(ctx context.Context, name string, update func(*key.Key) bool) error {
+	defer e.beginWrite()()
+
+	unlock := e.keyLocks.Lock(name)
+	defer unlock()
+
+	k, err := e.keys.GetKey(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !update(&k) {
+		return nil
+	}
+
+	evict(&e.cacheLock, e.keyCache, name)
+	if err = e.keys.SetKey(ctx, name, k); err != nil {
+		return err
+	}
+	e.indexSigningKey(name, k)
+	return nil
+}
+
+// SigningKeys returns the enabled token signing keys, by
+// name, within the Enclave. The returned map must not be
+// modified.
+//
+// SigningKeys lists all keys only if its index has been
+// invalidated, e.g. by FlushCache. Otherwise, it serves
+// the keys from the index that is updated whenever a key
+// gets created, modified or deleted.
+func (e *Enclave) SigningKeys(ctx context.Context) (map[string]key.Key, error) {
+	e.cacheLock.RLock()
+	keys := e.signingKeys
+	e.cacheLock.RUnlock()
+	if keys != nil {
+		return keys, nil
+	}
+
+	e.signingKeysLock.Lock()
+	defer e.signingKeysLock.Unlock()
+
+	e.cacheLock.RLock()
+	keys, gen := e.signingKeys, e.signingKeysGen
+	e.cacheLock.RUnlock()
+	if keys != nil {
+		return keys, nil
+	}
+
+	iter, err := e.keys.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	keys = map[string]key.Key{}
+	for iter.Next() {
+		if iter.Name() == "" {
+			continue
+		}
+		k, err := e.GetKey(ctx, iter.Name())
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			continue // Deleted while listing
+		}
+		if err != nil {
+			return nil, err
+		}
+		if k.TokenSigning() && !k.Disabled() {
+			keys[iter.Name()] = k
+		}
+	}
+	if err = iter.Close(); err != nil {
+		return nil, err
+	}
+
+	e.cacheLock.Lock()
+	if e.signingKeysGen == gen {
+		e.signingKeys = keys
+	}
+	e.cacheLock.Unlock()
+	return keys, nil
+}
+
+// indexSigningKey updates the token signing key index
+// after the key with the given name has been written.
+// A zero key removes the name from the index.
+func (e *Enclave) indexSigningKey(name string, k key.Key) {
+	e.cacheLock.Lock()
+	defer e.cacheLock.Unlock()
+
+	e.signingKeysGen++
+	if e.signingKeys == nil {
+		return
+	}
+	_, indexed := e.signingKeys[name]
+	signing := k.TokenSigning() && !k.Disabled()
+	if !indexed && !signing {
+		return
+	}
+
+	// The index is shared with callers of SigningKeys.
+	// Hence, it is copied instead of modified in place.
+	keys := make(map[string]key.Key, len(e.signingKeys)+1)
+	for n, v := range e.signingKeys {
+		keys[n] = v
+	}
+	if signing {
+		keys[name] = k
+	} else {
+		delete(keys, name)
+	}
+	e.signingKeys = keys
 }
 
 // GetKey returns the key associated with the given name.
 //
 // It returns kes.ErrKeyNotFound if no such entry exists.
 func (e *Enclave) GetKey(ctx context.Context, name string) (key.Key, error) {
-	if k, ok := e.keyCache[name]; ok {
+	if k, ok := lookup(&e.cacheLock, e.keyCache, name); ok {
+		atomic.AddUint64(&e.keyHits, 1)
 		return k, nil
 	}
+	atomic.AddUint64(&e.keyMisses, 1)
 
-	e.cacheLock.Lock()
-	defer e.cacheLock.Unlock()
+	unlock := e.keyLocks.Lock(name)
+	defer unlock()
 
-	if k, ok := e.keyCache[name]; ok {
+	if k, ok := lookup(&e.cacheLock, e.keyCache, name); ok {
 		return k, nil
 	}
 	k, err := e.keys.GetKey(ctx, name)
 	if err != nil {
 		return key.Key{}, err
 	}
-	e.keyCache[name] = k
+	store(&e.cacheLock, e.keyCache, name, k)
 	return k, nil
 }
 
+// CacheStats describes the cache state of an Enclave
+// or of all enclaves of a Vault.
+type CacheStats struct {
+	Enclaves   int // Number of cached enclaves
+	Keys       int // Number of cached keys
+	Secrets    int // Number of cached secrets
+	Policies   int // Number of cached policies
+	Identities int // Number of cached identities
+
+	KeyHits   uint64 // Number of keys served from the cache
+	KeyMisses uint64 // Number of keys not found in the cache
+}
+
+// CacheStats returns the current CacheStats of the Enclave.
+func (e *Enclave) CacheStats() CacheStats {
+	e.cacheLock.RLock()
+	defer e.cacheLock.RUnlock()
+
+	return CacheStats{
+		Enclaves:   1,
+		Keys:       len(e.keyCache),
+		Secrets:    len(e.secretCache),
+		Policies:   len(e.policyCache),
+		Identities: len(e.identityCache),
+		KeyHits:    atomic.LoadUint64(&e.keyHits),
+		KeyMisses:  atomic.LoadUint64(&e.keyMisses),
+	}
+}
+
+// FlushCache removes all keys whose names match the glob
+// pattern from the key cache and invalidates the token
+// signing key index. If pattern is empty, it removes all
+// entries, including the admin identity, from all caches
+// of the Enclave. Subsequent requests fetch flushed
+// entries from the underlying storage.
+//
+// It returns the number of removed keys.
+func (e *Enclave) FlushCache(pattern string) int {
+	e.cacheLock.Lock()
+	defer e.cacheLock.Unlock()
+
+	// The token signing key index is rebuilt from the
+	// underlying storage, as well.
+	e.signingKeys = nil
+	e.signingKeysGen++
+
+	n := len(e.keyCache)
+	if pattern == "" {
+		e.admin = ""
+		e.adminGen++
+		e.keyCache = map[string]key.Key{}
+		e.secretCache = map[string]secret.Secret{}
+		e.policyCache = map[string]auth.Policy{}
+		e.identityCache = map[kes.Identity]auth.IdentityInfo{}
+		return n
+	}
+	for name := range e.keyCache {
+		if ok, _ := path.Match(pattern, name); ok {
+			delete(e.keyCache, name)
+		}
+	}
+	return n - len(e.keyCache)
+}
+
+// LoadKey loads the key associated with the given name from
+// the underlying storage. In contrast to GetKey, it bypasses
+// the key cache and does not modify it.
+//
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (e *Enclave) LoadKey(ctx context.Context, name string) (key.Key, error) {
+	unlock := e.keyLocks.Lock(name)
+	defer unlock()
+
+	return e.keys.GetKey(ctx, name)
+}
+
 // ListKeys returns a new iterator over all keys within the
 // Enclave.
 //
@@ -203,9 +582,15 @@ func (e *Enclave) ListKeys(ctx context.Context) (kms.Iter, error) {
 //
 // It returns kes.ErrSecretExists if such an entry exists.
 func (e *Enclave) CreateSecret(ctx context.Context, name string, secret secret.Secret) error {
-	if _, ok := e.secretCache[name]; ok {
+	defer e.beginWrite()()
+
+	if _, ok := lookup(&e.cacheLock, e.secretCache, name); ok {
 		return kes.ErrSecretExists
 	}
+
+	unlock := e.secretLocks.Lock(name)
+	defer unlock()
+
 	return e.secrets.CreateSecret(ctx, name, secret)
 }
 
@@ -213,21 +598,61 @@ func (e *Enclave) CreateSecret(ctx context.Context, name string, secret secret.S
 //
 // It returns kes.ErrSecretNotFound if no such entry exists.
 func (e *Enclave) GetSecret(ctx context.Context, name string) (secret.Secret, error) {
-	if s, ok := e.secretCache[name]; ok {
+	if s, ok := lookup(&e.cacheLock, e.secretCache, name); ok {
 		return s, nil
 	}
 
-	e.cacheLock.Lock()
-	defer e.cacheLock.Unlock()
+	unlock := e.secretLocks.Lock(name)
+	defer unlock()
 
-	if s, ok := e.secretCache[name]; ok {
+	if s, ok := lookup(&e.cacheLock, e.secretCache, name); ok {
 		return s, nil
 	}
 	s, err := e.secrets.GetSecret(ctx, name)
 	if err != nil {
 		return secret.Secret{}, err
 	}
-	e.secretCache[name] = s
+	store(&e.cacheLock, e.secretCache, name, s)
+	return s, nil
+}
+
+// UpdateSecret replaces the value of the secret associated with
+// the given name and returns the new version of the secret.
+//
+// It returns kes.ErrSecretNotFound if no such entry exists.
+func (e *Enclave) UpdateSecret(ctx context.Context, name string, value []byte) (secret.Secret, error) {
+	return e.updateSecret(ctx, name, func(s *secret.Secret) { *s = s.Update(value) })
+}
+
+// SetSecretRotation replaces the rotation schedule of the secret
+// associated with the given name. A zero rotation disables the
+// rotation of the secret.
+//
+// It returns kes.ErrSecretNotFound if no such entry exists.
+func (e *Enclave) SetSecretRotation(ctx context.Context, name string, rotation secret.Rotation) error {
+	_, err := e.updateSecret(ctx, name, func(s *secret.Secret) { s.SetRotation(rotation) })
+	return err
+}
+
+// updateSecret applies the update function to the secret
+// associated with the given name, stores the modified
+// secret and returns it.
+func (e *Enclave) updateSecret(ctx context.Context, name string, update func(*secret.Secret)) (secret.Secret, error) {
+	defer e.beginWrite()()
+
+	unlock := e.secretLocks.Lock(name)
+	defer unlock()
+
+	s, err := e.secrets.GetSecret(ctx, name)
+	if err != nil {
+		return secret.Secret{}, err
+	}
+	update(&s)
+
+	evict(&e.cacheLock, e.secretCache, name)
+	if err = e.secrets.SetSecret(ctx, name, s); err != nil {
+		return secret.Secret{}, err
+	}
 	return s, nil
 }
 
@@ -235,7 +660,12 @@ func (e *Enclave) GetSecret(ctx context.Context, name string) (secret.Secret, er
 //
 // It returns kes.ErrSecretNotFound if no such entry exists.
 func (e *Enclave) DeleteSecret(ctx context.Context, name string) error {
-	delete(e.secretCache, name)
+	defer e.beginWrite()()
+
+	unlock := e.secretLocks.Lock(name)
+	defer unlock()
+
+	evict(&e.cacheLock, e.secretCache, name)
 	return e.secrets.DeleteSecret(ctx, name)
 }
 
@@ -249,37 +679,140 @@ func (e *Enclave) ListSecrets(ctx context.Context) (secret.Iter, error) {
 	return e.secrets.ListSecrets(ctx)
 }
 
+// Tokenize replaces the value by a new random token within
+// the tokenization scope and returns the token. Tokenizing
+// the same value twice returns two different tokens.
+func (e *Enclave) Tokenize(ctx context.Context, scope string, value Token) (string, error) {
+	defer e.beginWrite()()
+
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	if err := e.tokens.CreateToken(ctx, scope, token, value); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Detokenize returns the value replaced by the token within
+// the tokenization scope.
+//
+// It returns ErrTokenNotFound if no such token exists.
+func (e *Enclave) Detokenize(ctx context.Context, scope, token string) (Token, error) {
+	return e.tokens.GetToken(ctx, scope, token)
+}
+
 // SetPolicy creates or overwrites the policy with the given name.
+//
+// It returns an error if the policy exists and is within its
+// retention period.
 func (e *Enclave) SetPolicy(ctx context.Context, name string, policy auth.Policy) error {
-	delete(e.policyCache, name)
+	if name == auth.EnclaveAdminPolicy {
+		return errReservedPolicy
+	}
+	defer e.beginWrite()()
+
+	unlock := e.policyLocks.Lock(name)
+	defer unlock()
+
+	if err := e.verifyPolicyMutable(ctx, name); err != nil {
+		return err
+	}
+	evict(&e.cacheLock, e.policyCache, name)
+	return e.policies.SetPolicy(ctx, name, policy)
+}
+
+// SetPolicyIf creates or overwrites the policy with the given name
+// if and only if the precondition holds for the current policy.
+// The precondition is called with the current policy and whether
+// such a policy exists. If it returns an error, SetPolicyIf does
+// not modify the policy and returns this error.
+//
+// No other policy modification with the same name happens
+// between evaluating the precondition and writing the policy.
+func (e *Enclave) SetPolicyIf(ctx context.Context, name string, policy auth.Policy, precondition func(current auth.Policy, exists bool) error) error {
+	if name == auth.EnclaveAdminPolicy {
+		return errReservedPolicy
+	}
+	defer e.beginWrite()()
+
+	unlock := e.policyLocks.Lock(name)
+	defer unlock()
+
+	current, err := e.policies.GetPolicy(ctx, name)
+	if err != nil && !errors.Is(err, kes.ErrPolicyNotFound) {
+		return err
+	}
+	if err = precondition(current, err == nil); err != nil {
+		return err
+	}
+	if current.Immutable() {
+		return errImmutablePolicy
+	}
+
+	evict(&e.cacheLock, e.policyCache, name)
 	return e.policies.SetPolicy(ctx, name, policy)
 }
 
 // DeletePolicy deletes the policy associated with the given name.
 func (e *Enclave) DeletePolicy(ctx context.Context, name string) error {
-	delete(e.policyCache, name)
+	if name == auth.EnclaveAdminPolicy {
+		return errReservedPolicy
+	}
+	defer e.beginWrite()()
+
+	unlock := e.policyLocks.Lock(name)
+	defer unlock()
+
+	if err := e.verifyPolicyMutable(ctx, name); err != nil {
+		return err
+	}
+	evict(&e.cacheLock, e.policyCache, name)
 	return e.policies.DeletePolicy(ctx, name)
 }
 
+// verifyPolicyMutable returns errImmutablePolicy if the policy
+// with the given name exists and is within its retention period.
+// The caller must hold the policy lock.
+func (e *Enclave) verifyPolicyMutable(ctx context.Context, name string) error {
+	current, err := e.policies.GetPolicy(ctx, name)
+	if errors.Is(err, kes.ErrPolicyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.Immutable() {
+		return errImmutablePolicy
+	}
+	return nil
+}
+
 // GetPolicy returns the policy associated with the given name.
 //
 // It returns kes.ErrPolicyNotFound when no such entry exists.
+// The built-in auth.EnclaveAdminPolicy always exists.
 func (e *Enclave) GetPolicy(ctx context.Context, name string) (auth.Policy, error) {
-	if policy, ok := e.policyCache[name]; ok {
+	if name == auth.EnclaveAdminPolicy {
+		return auth.EnclaveAdmin(), nil
+	}
+	if policy, ok := lookup(&e.cacheLock, e.policyCache, name); ok {
 		return policy, nil
 	}
 
-	e.cacheLock.Lock()
-	defer e.cacheLock.Unlock()
+	unlock := e.policyLocks.Lock(name)
+	defer unlock()
 
-	if policy, ok := e.policyCache[name]; ok {
+	if policy, ok := lookup(&e.cacheLock, e.policyCache, name); ok {
 		return policy, nil
 	}
 	policy, err := e.policies.GetPolicy(ctx, name)
 	if err != nil {
 		return auth.Policy{}, err
 	}
-	e.policyCache[name] = policy
+	store(&e.cacheLock, e.policyCache, name, policy)
 	return policy, nil
 }
 
@@ -295,33 +828,46 @@ func (e *Enclave) ListPolicies(ctx context.Context) (auth.PolicyIterator, error)
 
 // Admin returns the current Enclave admin identity.
 func (e *Enclave) Admin(ctx context.Context) (kes.Identity, error) {
-	if !e.admin.IsUnknown() {
-		return e.admin, nil
+	e.cacheLock.RLock()
+	admin, gen := e.admin, e.adminGen
+	e.cacheLock.RUnlock()
+	if !admin.IsUnknown() {
+		return admin, nil
 	}
 
-	e.cacheLock.Lock()
-	defer e.cacheLock.Unlock()
-
-	if !e.admin.IsUnknown() {
-		return e.admin, nil
-	}
 	admin, err := e.identities.Admin(ctx)
 	if err != nil {
 		return "", err
 	}
-	e.admin = admin
-	return e.admin, nil
+
+	// A concurrent SetAdmin may have replaced the admin
+	// after it has been read from the storage. Caching the
+	// stale admin would keep the previous admin authorized.
+	e.cacheLock.Lock()
+	if e.adminGen == gen {
+		e.admin = admin
+	}
+	e.cacheLock.Unlock()
+	return admin, nil
 }
 
 // SetAdmin sets the Enclave admin to the given identity. The
 // new admin identity must not be an existing identity that is
 // already assigned to a policy.
 func (e *Enclave) SetAdmin(ctx context.Context, admin kes.Identity) error {
-	if admin == e.admin {
+	defer e.beginWrite()()
+
+	e.cacheLock.RLock()
+	current := e.admin
+	e.cacheLock.RUnlock()
+	if admin == current {
 		return nil
 	}
 
-	_, err := e.GetIdentity(ctx, admin)
+	unlock := e.identityLocks.Lock(admin.String())
+	defer unlock()
+
+	_, err := e.identities.GetIdentity(ctx, admin)
 	if err == nil {
 		return kes.NewError(http.StatusConflict, "identity already exists")
 	}
@@ -332,12 +878,29 @@ func (e *Enclave) SetAdmin(ctx context.Context, admin kes.Identity) error {
 	if err := e.identities.SetAdmin(ctx, admin); err != nil {
 		return err
 	}
+
+	e.cacheLock.Lock()
+	defer e.cacheLock.Unlock()
+
 	e.admin = ""
+	e.adminGen++
+	delete(e.identityCache, current)
+	delete(e.identityCache, admin)
 	return nil
 }
 
 // AssignPolicy assigns the policy to the identity.
 func (e *Enclave) AssignPolicy(ctx context.Context, policy string, identity kes.Identity) error {
+	return e.AssignPolicyUntil(ctx, policy, identity, time.Time{})
+}
+
+// AssignPolicyUntil assigns the policy to the identity until
+// the given point in time. Once the assignment has expired,
+// the identity is no longer allowed to perform any operation.
+// If expiresAt is zero, the assignment never expires.
+func (e *Enclave) AssignPolicyUntil(ctx context.Context, policy string, identity kes.Identity, expiresAt time.Time) error {
+	defer e.beginWrite()()
+
 	admin, err := e.Admin(ctx)
 	if err != nil {
 		return err
@@ -346,12 +909,17 @@ func (e *Enclave) AssignPolicy(ctx context.Context, policy string, identity kes.
 		return kes.NewError(http.StatusBadRequest, "cannot assign policy to admin")
 	}
 
-	delete(e.identityCache, identity)
-	return e.identities.AssignPolicy(ctx, policy, identity)
+	unlock := e.identityLocks.Lock(identity.String())
+	defer unlock()
+
+	evict(&e.cacheLock, e.identityCache, identity)
+	return e.identities.AssignPolicy(ctx, policy, identity, expiresAt)
 }
 
 // DeleteIdentity deletes the given identity.
 func (e *Enclave) DeleteIdentity(ctx context.Context, identity kes.Identity) error {
+	defer e.beginWrite()()
+
 	admin, err := e.Admin(ctx)
 	if err != nil {
 		return err
@@ -360,27 +928,30 @@ func (e *Enclave) DeleteIdentity(ctx context.Context, identity kes.Identity) err
 		return kes.NewError(http.StatusBadRequest, "cannot delete admin")
 	}
 
-	delete(e.identityCache, identity)
+	unlock := e.identityLocks.Lock(identity.String())
+	defer unlock()
+
+	evict(&e.cacheLock, e.identityCache, identity)
 	return e.identities.DeleteIdentity(ctx, identity)
 }
 
 // GetIdentity returns metadata about the given identity.
 func (e *Enclave) GetIdentity(ctx context.Context, identity kes.Identity) (auth.IdentityInfo, error) {
-	if info, ok := e.identityCache[identity]; ok {
+	if info, ok := lookup(&e.cacheLock, e.identityCache, identity); ok {
 		return info, nil
 	}
 
-	e.cacheLock.Lock()
-	defer e.cacheLock.Unlock()
+	unlock := e.identityLocks.Lock(identity.String())
+	defer unlock()
 
-	if info, ok := e.identityCache[identity]; ok {
+	if info, ok := lookup(&e.cacheLock, e.identityCache, identity); ok {
 		return info, nil
 	}
 	info, err := e.identities.GetIdentity(ctx, identity)
 	if err != nil {
 		return auth.IdentityInfo{}, err
 	}
-	e.identityCache[identity] = info
+	store(&e.cacheLock, e.identityCache, identity, info)
 	return info, nil
 }
 
@@ -394,8 +965,25 @@ func (e *Enclave) ListIdentities(ctx context.Context) (auth.IdentityIterator, er
 	return e.identities.ListIdentities(ctx)
 }
 
+// VerifyPath is like VerifyRequest but verifies whether the
+// identity that sent the request may access the given URL
+// path instead of the request's URL path. It is used to
+// check permissions, like the principals of SSH certificates,
+// that are expressed as policy paths.
+func (e *Enclave) VerifyPath(r *http.Request, urlPath string) error {
+	u := *r.URL
+	u.Path, u.RawPath = urlPath, ""
+
+	req := r.WithContext(r.Context())
+	req.URL = &u
+	return e.VerifyRequest(req)
+}
+
 // VerifyRequest verifies the given request is allowed
 // based on the policies and identities within the Enclave.
+// Requests of non-admin identities that pass the policy
+// check are authorized by the request's auth.Authorizer,
+// if any.
 func (e *Enclave) VerifyRequest(r *http.Request) error {
 	if r.TLS == nil {
 		return kes.NewError(http.StatusBadRequest, "insecure connection: TLS required")
@@ -432,8 +1020,12 @@ func (e *Enclave) VerifyRequest(r *http.Request) error {
 		return err
 	}
 	if info.IsAdmin {
+		auth.RecordDecision(r.Context(), auth.Decision{Admin: true, Allowed: true})
 		return nil
 	}
+	if info.Expired() {
+		return kes.ErrNotAllowed
+	}
 
 	policy, err := e.GetPolicy(r.Context(), info.Policy)
 	if errors.Is(err, kes.ErrPolicyNotFound) {
@@ -442,5 +1034,10 @@ func (e *Enclave) VerifyRequest(r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	return policy.Verify(r)
+	allowed, rule := policy.Match(r.URL.Path)
+	auth.RecordDecision(r.Context(), auth.Decision{Policy: info.Policy, Rule: rule, Allowed: allowed})
+	if !allowed {
+		return kes.ErrNotAllowed
+	}
+	return auth.Authorize(r, identity, info.Policy)
 }
@@ -0,0 +1,599 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/secret"
+	"github.com/minio/kes/kms"
+)
+
+func TestEnclaveLegalHold(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	enclave := NewEnclave(NewKeyFS(t.TempDir(), rootKey), nil, nil, nil, nil)
+
+	k, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = enclave.CreateKey(ctx, "my-key", k); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	hold := key.LegalHold{CreatedAt: time.Now().UTC(), CreatedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"}
+	if err = enclave.HoldKey(ctx, "my-key", hold); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+	held, err := enclave.GetKey(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if h := held.LegalHold(); h == nil || h.CreatedBy != hold.CreatedBy {
+		t.Fatalf("Invalid legal hold: got '%v' - want '%v'", h, hold)
+	}
+	if !held.Equal(k) {
+		t.Fatal("Key has been modified by legal hold")
+	}
+	if err = enclave.DeleteKey(ctx, "my-key"); !errors.Is(err, key.ErrLegalHold) {
+		t.Fatalf("Deleting key under legal hold: got '%v' - want '%v'", err, key.ErrLegalHold)
+	}
+
+	if err = enclave.ReleaseKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to release legal hold: %v", err)
+	}
+	if err = enclave.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete released key: %v", err)
+	}
+	if err = enclave.HoldKey(ctx, "my-key", hold); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Placing legal hold on missing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+}
+
+func TestEnclaveAssignPolicyUntil(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	identities := NewIdentityFS(t.TempDir(), rootKey)
+	enclave := NewEnclave(nil, nil, nil, NewPolicyFS(t.TempDir(), rootKey), identities)
+	if err = identities.SetAdmin(ctx, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+	if err = enclave.SetPolicy(ctx, "my-policy", auth.Policy{Allow: []string{"/v1/key/create/*"}}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	cert := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("my-identity")}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	identity := kes.Identity(hex.EncodeToString(sum[:]))
+
+	req := httptest.NewRequest("POST", "/v1/key/create/my-key", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	if err = enclave.AssignPolicyUntil(ctx, "my-policy", identity, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	if err = enclave.VerifyRequest(req); err != nil {
+		t.Fatalf("Failed to verify request before expiry: %v", err)
+	}
+
+	if err = enclave.AssignPolicyUntil(ctx, "my-policy", identity, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	info, err := enclave.GetIdentity(ctx, identity)
+	if err != nil {
+		t.Fatalf("Failed to fetch identity: %v", err)
+	}
+	if !info.Expired() {
+		t.Fatalf("Policy assignment has not expired: expires at '%v'", info.ExpiresAt)
+	}
+	if err = enclave.VerifyRequest(req); err != kes.ErrNotAllowed {
+		t.Fatalf("Verifying request after expiry: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+}
+
+func TestEnclaveAccessRequest(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	identities := NewIdentityFS(t.TempDir(), rootKey)
+	enclave := NewEnclave(nil, nil, nil, NewPolicyFS(t.TempDir(), rootKey), identities)
+	if err = identities.SetAdmin(ctx, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+
+	const (
+		Requester kes.Identity = "8c49c5aa6d8fb1ab0ec0d9fb0d6e1b7c5a9a3d2b9b1d1c8f0d2a6e3f4b5c6d7e"
+		Approver  kes.Identity = "1d2c3b4a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c1b2a3f4e5d6c7b8a9f0e1d2c"
+	)
+	if _, err = enclave.RequestAccess(ctx, Requester, "my-policy", 0, ""); err == nil {
+		t.Fatal("Requesting access without duration succeeded")
+	}
+	if _, err = enclave.RequestAccess(ctx, Requester, "my-policy", MaxAccessDuration+time.Second, ""); err == nil {
+		t.Fatal("Requesting access beyond max. duration succeeded")
+	}
+	if _, err = enclave.RequestAccess(ctx, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", "my-policy", time.Hour, ""); err == nil {
+		t.Fatal("Requesting access as admin succeeded")
+	}
+
+	first, err := enclave.RequestAccess(ctx, Requester, "other-policy", time.Hour, "")
+	if err != nil {
+		t.Fatalf("Failed to request access: %v", err)
+	}
+	req, err := enclave.RequestAccess(ctx, Requester, "my-policy", time.Hour, "INC-1234")
+	if err != nil {
+		t.Fatalf("Failed to request access: %v", err)
+	}
+	if _, err = enclave.GetAccessRequest(first.ID); err != ErrAccessRequestNotFound {
+		t.Fatalf("Fetching replaced access request: got '%v' - want '%v'", err, ErrAccessRequestNotFound)
+	}
+	if requests := enclave.ListAccessRequests(); len(requests) != 1 || requests[0].ID != req.ID {
+		t.Fatalf("Invalid pending access requests: got '%v' - want '%v'", requests, []AccessRequest{req})
+	}
+
+	if _, err = enclave.ApproveAccessRequest(ctx, req.ID, Requester); err == nil {
+		t.Fatal("Approving own access request succeeded")
+	}
+	if _, err = enclave.ApproveAccessRequest(ctx, req.ID, Approver); err != nil {
+		t.Fatalf("Failed to approve access request: %v", err)
+	}
+	if _, err = enclave.ApproveAccessRequest(ctx, req.ID, Approver); err != ErrAccessRequestNotFound {
+		t.Fatalf("Approving access request twice: got '%v' - want '%v'", err, ErrAccessRequestNotFound)
+	}
+	if _, err = enclave.RequestAccess(ctx, Requester, "other-policy", time.Hour, ""); err == nil {
+		t.Fatal("Requesting access while being assigned to a policy succeeded")
+	}
+
+	info, err := enclave.GetIdentity(ctx, Requester)
+	if err != nil {
+		t.Fatalf("Failed to fetch identity: %v", err)
+	}
+	if info.Policy != "my-policy" {
+		t.Fatalf("Invalid policy: got '%s' - want '%s'", info.Policy, "my-policy")
+	}
+	if info.ExpiresAt.IsZero() || info.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("Invalid policy assignment expiry: got '%v' - want at most '%v'", info.ExpiresAt, time.Now().Add(time.Hour))
+	}
+
+	if req, err = enclave.RequestAccess(ctx, Approver, "my-policy", time.Hour, ""); err != nil {
+		t.Fatalf("Failed to request access: %v", err)
+	}
+	if _, err = enclave.DenyAccessRequest(req.ID); err != nil {
+		t.Fatalf("Failed to deny access request: %v", err)
+	}
+	if requests := enclave.ListAccessRequests(); len(requests) != 0 {
+		t.Fatalf("Invalid pending access requests: got '%v' - want none", requests)
+	}
+}
+
+func TestEnclaveAccessRequestAssigned(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	identities := NewIdentityFS(t.TempDir(), rootKey)
+	enclave := NewEnclave(nil, nil, nil, NewPolicyFS(t.TempDir(), rootKey), identities)
+	if err = identities.SetAdmin(ctx, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+
+	const (
+		Requester kes.Identity = "8c49c5aa6d8fb1ab0ec0d9fb0d6e1b7c5a9a3d2b9b1d1c8f0d2a6e3f4b5c6d7e"
+		Approver  kes.Identity = "1d2c3b4a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c1b2a3f4e5d6c7b8a9f0e1d2c"
+	)
+	if err = enclave.AssignPolicy(ctx, "base-policy", Requester); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	if _, err = enclave.RequestAccess(ctx, Requester, "my-policy", time.Hour, ""); err == nil {
+		t.Fatal("Requesting access while being assigned to a policy succeeded")
+	}
+
+	// Requests filed before the identity got assigned to
+	// a policy must not replace the assignment either.
+	if err = enclave.DeleteIdentity(ctx, Requester); err != nil {
+		t.Fatalf("Failed to delete identity: %v", err)
+	}
+	req, err := enclave.RequestAccess(ctx, Requester, "my-policy", time.Hour, "")
+	if err != nil {
+		t.Fatalf("Failed to request access: %v", err)
+	}
+	if err = enclave.AssignPolicy(ctx, "base-policy", Requester); err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	if _, err = enclave.ApproveAccessRequest(ctx, req.ID, Approver); err == nil {
+		t.Fatal("Approving access request of an assigned identity succeeded")
+	}
+
+	info, err := enclave.GetIdentity(ctx, Requester)
+	if err != nil {
+		t.Fatalf("Failed to fetch identity: %v", err)
+	}
+	if info.Policy != "base-policy" || !info.ExpiresAt.IsZero() {
+		t.Fatalf("Invalid policy assignment: got '%s' until '%v' - want '%s' without expiry", info.Policy, info.ExpiresAt, "base-policy")
+	}
+}
+
+func TestEnclaveUpdateSecret(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	enclave := NewEnclave(nil, NewSecretFS(t.TempDir(), rootKey), nil, nil, nil)
+
+	if _, err = enclave.UpdateSecret(ctx, "my-secret", []byte("v2")); !errors.Is(err, kes.ErrSecretNotFound) {
+		t.Fatalf("Updating non-existing secret: got '%v' - want '%v'", err, kes.ErrSecretNotFound)
+	}
+	if err = enclave.CreateSecret(ctx, "my-secret", secret.NewSecret([]byte("v1"), "")); err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if _, err = enclave.GetSecret(ctx, "my-secret"); err != nil { // Populate the cache
+		t.Fatalf("Failed to fetch secret: %v", err)
+	}
+
+	updated, err := enclave.UpdateSecret(ctx, "my-secret", []byte("v2"))
+	if err != nil {
+		t.Fatalf("Failed to update secret: %v", err)
+	}
+	if v := updated.Version(); v != 2 {
+		t.Fatalf("Invalid secret version: got '%d' - want '%d'", v, 2)
+	}
+	s, err := enclave.GetSecret(ctx, "my-secret")
+	if err != nil {
+		t.Fatalf("Failed to fetch secret: %v", err)
+	}
+	if v := string(s.Bytes()); v != "v2" {
+		t.Fatalf("Invalid secret value: got '%s' - want '%s'", v, "v2")
+	}
+	if !s.CreatedAt().Equal(updated.CreatedAt()) || s.Version() != 2 {
+		t.Fatalf("Invalid secret: got version '%d' created at '%v' - want version '%d' created at '%v'", s.Version(), s.CreatedAt(), 2, updated.CreatedAt())
+	}
+
+	iter, err := enclave.ListSecrets(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	var names []string
+	for iter.Next() {
+		if name := iter.Name(); name != "" {
+			names = append(names, name)
+		}
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	if len(names) != 1 || names[0] != "my-secret" {
+		t.Fatalf("Invalid secret list: got '%v' - want '%v'", names, []string{"my-secret"})
+	}
+}
+
+func TestEnclaveTokenize(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	enclave := NewEnclave(nil, nil, NewTokenFS(t.TempDir(), rootKey), nil, nil)
+
+	value := Token{Value: []byte("4111111111111111"), CreatedAt: time.Now().UTC()}
+	token1, err := enclave.Tokenize(ctx, "card-numbers", value)
+	if err != nil {
+		t.Fatalf("Failed to tokenize value: %v", err)
+	}
+	token2, err := enclave.Tokenize(ctx, "card-numbers", value)
+	if err != nil {
+		t.Fatalf("Failed to tokenize value: %v", err)
+	}
+	if token1 == token2 {
+		t.Fatalf("Tokenizing the same value twice returned the same token: '%s'", token1)
+	}
+
+	detokenized, err := enclave.Detokenize(ctx, "card-numbers", token1)
+	if err != nil {
+		t.Fatalf("Failed to detokenize token: %v", err)
+	}
+	if !bytes.Equal(detokenized.Value, value.Value) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", detokenized.Value, value.Value)
+	}
+	if !detokenized.CreatedAt.Equal(value.CreatedAt) {
+		t.Fatalf("Invalid creation time: got '%v' - want '%v'", detokenized.CreatedAt, value.CreatedAt)
+	}
+	if _, err = enclave.Detokenize(ctx, "account-numbers", token1); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("Detokenizing token of another scope: got '%v' - want '%v'", err, ErrTokenNotFound)
+	}
+}
+
+func TestEnclaveSigningKeys(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	keys := &listCountFS{KeyFS: NewKeyFS(t.TempDir(), rootKey)}
+	enclave := NewEnclave(keys, nil, nil, nil, nil)
+
+	for _, name := range []string{"my-key", "my-jwt-key"} {
+		k, err := key.Random(kes.AES256_GCM_SHA256, "")
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if err = enclave.CreateKey(ctx, name, k); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err = enclave.SetKeyTokenSigning(ctx, "my-jwt-key", true); err != nil {
+		t.Fatalf("Failed to designate key: %v", err)
+	}
+
+	signingKeys, err := enclave.SigningKeys(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list signing keys: %v", err)
+	}
+	if _, ok := signingKeys["my-jwt-key"]; !ok || len(signingKeys) != 1 {
+		t.Fatalf("Invalid signing keys: got '%v' - want 'my-jwt-key'", signingKeys)
+	}
+
+	// Subsequent writes update the index instead of invalidating it.
+	if err = enclave.SetKeyTokenSigning(ctx, "my-key", true); err != nil {
+		t.Fatalf("Failed to designate key: %v", err)
+	}
+	if err = enclave.DisableKey(ctx, "my-jwt-key"); err != nil {
+		t.Fatalf("Failed to disable key: %v", err)
+	}
+	if signingKeys, err = enclave.SigningKeys(ctx); err != nil {
+		t.Fatalf("Failed to list signing keys: %v", err)
+	}
+	if _, ok := signingKeys["my-key"]; !ok || len(signingKeys) != 1 {
+		t.Fatalf("Invalid signing keys: got '%v' - want 'my-key'", signingKeys)
+	}
+	if err = enclave.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if signingKeys, err = enclave.SigningKeys(ctx); err != nil {
+		t.Fatalf("Failed to list signing keys: %v", err)
+	}
+	if len(signingKeys) != 0 {
+		t.Fatalf("Invalid signing keys: got '%v' - want none", signingKeys)
+	}
+	if keys.lists != 1 {
+		t.Fatalf("Invalid number of key listings: got '%d' - want '%d'", keys.lists, 1)
+	}
+
+	enclave.FlushCache("")
+	if err = enclave.EnableKey(ctx, "my-jwt-key"); err != nil {
+		t.Fatalf("Failed to enable key: %v", err)
+	}
+	if signingKeys, err = enclave.SigningKeys(ctx); err != nil {
+		t.Fatalf("Failed to list signing keys: %v", err)
+	}
+	if _, ok := signingKeys["my-jwt-key"]; !ok || len(signingKeys) != 1 {
+		t.Fatalf("Invalid signing keys: got '%v' - want 'my-jwt-key'", signingKeys)
+	}
+	if keys.lists != 2 {
+		t.Fatalf("Invalid number of key listings: got '%d' - want '%d'", keys.lists, 2)
+	}
+}
+
+// listCountFS is a KeyFS that counts
+// the number of ListKeys calls.
+type listCountFS struct {
+	KeyFS
+	lists int
+}
+
+func (fs *listCountFS) ListKeys(ctx context.Context) (kms.Iter, error) {
+	fs.lists++
+	return fs.KeyFS.ListKeys(ctx)
+}
+
+// blockingIdentityFS is an IdentityFS that blocks the
+// first Admin call after reading the admin from the
+// underlying IdentityFS until proceed is closed.
+type blockingIdentityFS struct {
+	IdentityFS
+
+	once    sync.Once
+	loaded  chan struct{}
+	proceed chan struct{}
+}
+
+func (fs *blockingIdentityFS) Admin(ctx context.Context) (kes.Identity, error) {
+	admin, err := fs.IdentityFS.Admin(ctx)
+	fs.once.Do(func() {
+		close(fs.loaded)
+		<-fs.proceed
+	})
+	return admin, err
+}
+
+func TestEnclaveAdminRace(t *testing.T) {
+	const (
+		OldAdmin kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		NewAdmin kes.Identity = "1a3160b0987e2a2726e76293e83ab867388084dccc4795af718a06a93e00a007"
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	identities := &blockingIdentityFS{
+		IdentityFS: NewIdentityFS(t.TempDir(), rootKey),
+		loaded:     make(chan struct{}),
+		proceed:    make(chan struct{}),
+	}
+	if err = identities.IdentityFS.SetAdmin(ctx, OldAdmin); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+	enclave := NewEnclave(nil, nil, nil, nil, identities)
+
+	// Load the old admin, then replace it before the
+	// loaded admin gets cached.
+	done := make(chan error, 1)
+	go func() {
+		_, err := enclave.Admin(ctx)
+		done <- err
+	}()
+	<-identities.loaded
+	if err = enclave.SetAdmin(ctx, NewAdmin); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+	close(identities.proceed)
+	if err = <-done; err != nil {
+		t.Fatalf("Failed to load admin: %v", err)
+	}
+
+	admin, err := enclave.Admin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load admin: %v", err)
+	}
+	if admin != NewAdmin {
+		t.Fatalf("Stale admin has been cached: got '%v' - want '%v'", admin, NewAdmin)
+	}
+}
+
+func TestEnclaveConcurrentAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	identities := NewIdentityFS(t.TempDir(), rootKey)
+	if err = identities.SetAdmin(ctx, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+	enclave := NewEnclave(nil, nil, nil, nil, identities)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sum := sha256.Sum256([]byte{byte(i), byte(j)})
+				if err := enclave.SetAdmin(ctx, kes.Identity(hex.EncodeToString(sum[:]))); err != nil {
+					t.Errorf("Failed to set admin: %v", err)
+					return
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := enclave.Admin(ctx); err != nil {
+					t.Errorf("Failed to load admin: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The cached admin must be the admin that has been
+	// persisted last.
+	cached, err := enclave.Admin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load admin: %v", err)
+	}
+	stored, err := identities.Admin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load admin: %v", err)
+	}
+	if cached != stored {
+		t.Fatalf("Cached admin differs from stored admin: got '%v' - want '%v'", cached, stored)
+	}
+}
+
+func TestEnclaveConcurrentIdentities(t *testing.T) {
+	const Identity kes.Identity = "1a3160b0987e2a2726e76293e83ab867388084dccc4795af718a06a93e00a007"
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	identities := NewIdentityFS(t.TempDir(), rootKey)
+	enclave := NewEnclave(nil, nil, nil, NewPolicyFS(t.TempDir(), rootKey), identities)
+	if err = identities.SetAdmin(ctx, "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"); err != nil {
+		t.Fatalf("Failed to set admin: %v", err)
+	}
+	for _, name := range []string{"policy-0", "policy-1"} {
+		if err = enclave.SetPolicy(ctx, name, auth.Policy{Allow: []string{"/v1/key/create/*"}}); err != nil {
+			t.Fatalf("Failed to create policy: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var err error
+				if (i+j)%3 == 0 {
+					err = enclave.DeleteIdentity(ctx, Identity)
+				} else {
+					err = enclave.AssignPolicy(ctx, "policy-"+strconv.Itoa((i+j)%2), Identity)
+				}
+				if err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+					t.Errorf("Failed to modify identity: %v", err)
+					return
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := enclave.GetIdentity(ctx, Identity); err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+					t.Errorf("Failed to fetch identity: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The cached identity must match the identity that
+	// has been persisted last.
+	cached, cachedErr := enclave.GetIdentity(ctx, Identity)
+	stored, storedErr := identities.GetIdentity(ctx, Identity)
+	if (cachedErr == nil) != (storedErr == nil) {
+		t.Fatalf("Cached identity differs from stored identity: got '%v' - want '%v'", cachedErr, storedErr)
+	}
+	if cached.Policy != stored.Policy {
+		t.Fatalf("Cached policy differs from stored policy: got '%s' - want '%s'", cached.Policy, stored.Policy)
+	}
+}
@@ -11,11 +11,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/auth"
 	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/pki"
 	"github.com/minio/kes/internal/secret"
 	"github.com/minio/kes/kms"
 )
@@ -34,10 +37,11 @@ type VaultFS interface {
 	Admin(ctx context.Context) (kes.Identity, error)
 
 	// CreateEnclave creates a new enclave with the given identity
-	// as enclave admin.
+	// as enclave admin. The key policy, if not nil, restricts the
+	// algorithms of keys created within the enclave.
 	//
 	// It returns ErrEnclaveExists if such an enclave already exists.
-	CreateEnclave(ctx context.Context, name string, admin kes.Identity) (EnclaveInfo, error)
+	CreateEnclave(ctx context.Context, name string, admin kes.Identity, keyPolicy *key.AlgorithmPolicy) (EnclaveInfo, error)
 
 	// GetEnclave returns the requested enclave.
 	//
@@ -53,6 +57,24 @@ type VaultFS interface {
 	//
 	// It returns ErrEnclaveNotFound if no such enclave exists.
 	DeleteEnclave(ctx context.Context, name string) error
+
+	// ListEnclaves returns an iterator over the names
+	// of all enclaves.
+	ListEnclaves(ctx context.Context) (kms.Iter, error)
+
+	// GetIssuer returns the certificate authority that issues
+	// server and client certificates. It generates a new one if none
+	// exists yet.
+	GetIssuer(ctx context.Context) (*pki.Issuer, error)
+
+	// SetIssuer replaces the certificate authority, e.g.
+	// once it has revoked a certificate.
+	SetIssuer(ctx context.Context, issuer *pki.Issuer) error
+
+	// Check verifies the integrity of all enclaves and
+	// their entries. If quarantine is true, it moves
+	// corrupted entries into a quarantine area.
+	Check(ctx context.Context, quarantine bool) (CheckReport, error)
 }
 
 // KeyFS provides access to cryptographic keys within a particular
@@ -69,6 +91,12 @@ type KeyFS interface {
 	// It returns ErrKeyNotFound if no such key exists.
 	GetKey(ctx context.Context, name string) (key.Key, error)
 
+	// SetKey replaces the existing entry of the given key,
+	// e.g. to update its metadata.
+	//
+	// It returns ErrKeyNotFound if no such key exists.
+	SetKey(ctx context.Context, name string, key key.Key) error
+
 	// DeleteKey deletes the specified key.
 	//
 	// It returns ErrKeyNotFound if no such key exists.
@@ -92,6 +120,12 @@ type SecretFS interface {
 	// It returns ErrSecretNotFound if no such secret exists.
 	GetSecret(ctx context.Context, name string) (secret.Secret, error)
 
+	// SetSecret replaces the existing entry of the given
+	// secret, e.g. to store a new version.
+	//
+	// It returns ErrSecretNotFound if no such secret exists.
+	SetSecret(ctx context.Context, name string, secret secret.Secret) error
+
 	// DeleteSecret deletes the specified secret.
 	//
 	// It returns ErrSecretNotFound if no such secret exists.
@@ -134,9 +168,11 @@ type IdentityFS interface {
 	SetAdmin(ctx context.Context, admin kes.Identity) error
 
 	// AssignPolicy assigns the policy to the given identity.
+	// If expiresAt is not zero, the assignment lapses at
+	// this point in time.
 	//
 	// No policy must be assigned to the admin identity.
-	AssignPolicy(ctx context.Context, policy string, identity kes.Identity) error
+	AssignPolicy(ctx context.Context, policy string, identity kes.Identity, expiresAt time.Time) error
 
 	// GetIdentity returns identity information for the given identity,
 	// including the admin identity information.
@@ -155,12 +191,32 @@ type IdentityFS interface {
 	ListIdentities(ctx context.Context) (auth.IdentityIterator, error)
 }
 
+// fileName returns the name of the file that stores the key,
+// policy or secret with the given name. Such names may start
+// with a prefix, like "sys/my-key". Since '/' is a path
+// separator, it is replaced by '%' - a character that is
+// not allowed for names.
+func fileName(name string) string { return strings.ReplaceAll(name, "/", "%") }
+
+// entryName returns the name of the key, policy or secret
+// stored in the file with the given name. It reverses
+// fileName.
+func entryName(filename string) string { return strings.ReplaceAll(filename, "%", "/") }
+
+// valid returns an error if name is not a valid file name
+// for an enclave, key, policy, secret or identity. It must
+// not contain a path separator and must not start with a '.'.
+// Hence, it cannot refer to a parent directory or collide
+// with internal files, like ".key.tmp" or ".admin".
 func valid(name string) error {
 	for _, c := range name {
-		if c == '.' || c == '\\' || c == '/' {
+		if c == '\\' || c == '/' {
 			return fmt.Errorf("sys: path contains invalid character %c", c)
 		}
 	}
+	if strings.HasPrefix(name, ".") {
+		return errors.New("sys: path must not start with '.'")
+	}
 	return nil
 }
 
@@ -45,6 +45,22 @@ type InitConfig struct {
 	ProxyIdentities []yml.Identity
 
 	ProxyClientCert yml.String
+
+	// Leader is the endpoint of the cluster leader. If set,
+	// the server runs as follower and forwards write requests
+	// to the leader.
+	Leader yml.String
+
+	// LeaderCA is the path to a file containing the CA
+	// certificates used to verify the certificates of
+	// the leader and all other cluster nodes.
+	LeaderCA yml.String
+
+	// NodeID is the ID of the server within the cluster.
+	NodeID yml.String
+
+	// Nodes are the endpoints of the other cluster nodes.
+	Nodes []yml.String
 }
 
 // ReadInitConfig reads and parses the InitConfig YAML representation
@@ -75,6 +91,13 @@ func ReadInitConfig(filename string) (*InitConfig, error) {
 				VerifyCerts yml.Bool `yaml:"verify_cert"`
 			} `yaml:"client"`
 		} `yaml:"tls"`
+
+		Cluster struct {
+			ID     yml.String   `yaml:"id"`
+			Leader yml.String   `yaml:"leader"`
+			CA     yml.String   `yaml:"ca"`
+			Nodes  []yml.String `yaml:"nodes"`
+		} `yaml:"cluster"`
 	}
 	var config YAML
 	if err := yaml.NewDecoder(f).Decode(&config); err != nil {
@@ -94,6 +117,10 @@ func ReadInitConfig(filename string) (*InitConfig, error) {
 		VerifyClientCerts: config.TLS.Client.VerifyCerts,
 		ProxyIdentities:   config.TLS.Proxy.Identity,
 		ProxyClientCert:   config.TLS.Proxy.Header.ClientCert,
+		Leader:            config.Cluster.Leader,
+		LeaderCA:          config.Cluster.CA,
+		NodeID:            config.Cluster.ID,
+		Nodes:             config.Cluster.Nodes,
 	}, nil
 }
 
@@ -125,6 +152,13 @@ func WriteInitConfig(filename string, config *InitConfig) error {
 				VerifyCerts yml.Bool `yaml:"verify_cert"`
 			} `yaml:"client"`
 		} `yaml:"tls"`
+
+		Cluster struct {
+			ID     yml.String   `yaml:"id,omitempty"`
+			Leader yml.String   `yaml:"leader,omitempty"`
+			CA     yml.String   `yaml:"ca,omitempty"`
+			Nodes  []yml.String `yaml:"nodes,omitempty"`
+		} `yaml:"cluster,omitempty"`
 	}
 
 	c := YAML{
@@ -137,15 +171,24 @@ func WriteInitConfig(filename string, config *InitConfig) error {
 	c.TLS.Client.VerifyCerts = config.VerifyClientCerts
 	c.TLS.Proxy.Identity = config.ProxyIdentities
 	c.TLS.Proxy.Header.ClientCert = config.ProxyClientCert
+	c.Cluster.ID = config.NodeID
+	c.Cluster.Leader = config.Leader
+	c.Cluster.CA = config.LeaderCA
+	c.Cluster.Nodes = config.Nodes
 	return yaml.NewEncoder(f).Encode(c)
 }
 
 // Init initializes a stateful KES deployment within the given
-// path using the InitConfig and SealConfig.
+// path using the SealConfig.
 //
 // It returns an initialized Vault and a set of UnsealKeys to
 // unseal the Vault in the future.
-func Init(path string, init *InitConfig, seal *SealConfig) (*sys.Vault, []sys.UnsealKey, error) {
+//
+// Init does not write the InitConfig. Callers should write it,
+// using WriteInitConfig, once the Vault has been provisioned
+// completely. Hence, a path without InitConfig file contains
+// at most a partially initialized Vault.
+func Init(path string, seal *SealConfig) (*sys.Vault, []sys.UnsealKey, error) {
 	algorithm := kes.AES256_GCM_SHA256
 	if !fips.Enabled && !cpu.HasAESGCM() {
 		algorithm = kes.XCHACHA20_POLY1305
@@ -158,9 +201,6 @@ func Init(path string, init *InitConfig, seal *SealConfig) (*sys.Vault, []sys.Un
 	if err := initFS(path); err != nil {
 		return nil, nil, err
 	}
-	if err := WriteInitConfig(filepath.Join(path, ".init"), init); err != nil {
-		return nil, nil, err
-	}
 	unsealKeys, err := initSeal(path, rootKey, seal.Sealer)
 	if err != nil {
 		return nil, nil, err
@@ -190,12 +230,92 @@ func Open(path string) (*sys.Vault, error) {
 	return sys.NewVault(sys.NewVaultFS(path, rootKey)), nil
 }
 
+// Bootstrap initializes a stateful KES deployment within
+// the given path by calling init, unless the path contains
+// an InitConfig file already.
+//
+// Bootstrap marks the path before calling init and removes
+// the mark once init has succeeded. If a previous bootstrap
+// has been interrupted, Bootstrap removes its remains and
+// starts over. It never removes anything from a path without
+// mark. Instead, it returns os.ErrExist if such a path is not
+// empty, e.g. because its InitConfig file has been removed.
+func Bootstrap(path string, init func() error) error {
+	const (
+		InitFile      = ".init"
+		BootstrapFile = ".bootstrap"
+	)
+	marker := filepath.Join(path, BootstrapFile)
+
+	switch _, err := os.Stat(filepath.Join(path, InitFile)); {
+	case err == nil:
+		// A previous bootstrap may have been interrupted
+		// after writing the InitConfig file.
+		if err = os.Remove(marker); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	switch _, err := os.Stat(marker); {
+	case err == nil:
+		// The path has been empty when the mark has been
+		// written. Hence, all other entries are remains of
+		// the interrupted bootstrap.
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Name() == BootstrapFile {
+				continue
+			}
+			if err = os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if err = initFS(path); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(marker, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_SYNC, 0o600)
+		if err != nil {
+			return err
+		}
+		if err = file.Close(); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if err := init(); err != nil {
+		return err
+	}
+	return os.Remove(marker)
+}
+
+// initFS creates the directory at path. It accepts an
+// existing but empty directory, e.g. a mounted volume,
+// and a directory that only contains the mark of an
+// ongoing bootstrap.
 func initFS(path string) error {
 	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
-		if err == nil {
-			return os.ErrExist
+		if err != nil {
+			return err
 		}
-		return err
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Name() != ".bootstrap" {
+				return os.ErrExist
+			}
+		}
+		return nil
 	}
 	return os.MkdirAll(path, 0o755)
 }
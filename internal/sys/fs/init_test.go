@@ -0,0 +1,83 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBootstrap(t *testing.T) {
+	path := t.TempDir()
+
+	// An initialized path whose '.init' file is missing
+	// must not be bootstrapped. Otherwise, all enclaves
+	// would be deleted.
+	enclave := filepath.Join(path, "enclave", "my-enclave")
+	if err := os.MkdirAll(enclave, 0o755); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(path, ".unseal"), []byte("unseal"), 0o600); err != nil {
+		t.Fatalf("Failed to write unseal file: %v", err)
+	}
+	var called bool
+	init := func() error { called = true; return nil }
+	if err := Bootstrap(path, init); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Bootstrapping non-empty path: got '%v' - want '%v'", err, os.ErrExist)
+	}
+	if called {
+		t.Fatal("Bootstrap of non-empty path called init")
+	}
+	for _, name := range []string{enclave, filepath.Join(path, ".unseal")} {
+		if _, err := os.Stat(name); err != nil {
+			t.Fatalf("Bootstrap of non-empty path removed '%s': %v", name, err)
+		}
+	}
+
+	// An initialized path must not be bootstrapped again.
+	if err := os.WriteFile(filepath.Join(path, ".init"), nil, 0o600); err != nil {
+		t.Fatalf("Failed to write init file: %v", err)
+	}
+	if err := Bootstrap(path, init); err != nil {
+		t.Fatalf("Failed to bootstrap initialized path: %v", err)
+	}
+	if called {
+		t.Fatal("Bootstrap of initialized path called init")
+	}
+}
+
+func TestBootstrapInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kes")
+
+	errInterrupted := errors.New("interrupted")
+	err := Bootstrap(path, func() error {
+		if err := os.MkdirAll(filepath.Join(path, "enclave", "my-enclave"), 0o755); err != nil {
+			return err
+		}
+		return errInterrupted
+	})
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("Interrupted bootstrap: got '%v' - want '%v'", err, errInterrupted)
+	}
+
+	// A bootstrap that has been interrupted must start over.
+	err = Bootstrap(path, func() error {
+		if _, err := os.Stat(filepath.Join(path, "enclave")); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("Remains of interrupted bootstrap have not been removed: %v", err)
+		}
+		if err := initFS(path); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(path, ".init"), nil, 0o600)
+	})
+	if err != nil {
+		t.Fatalf("Failed to bootstrap: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(path, ".bootstrap")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Bootstrap mark has not been removed: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// ModTime returns the most recent modification time of any
+// file or directory within the given path.
+//
+// Deleting a file changes the modification time of its parent
+// directory. Hence, ModTime reflects any change of a stateful
+// KES deployment and can be used to compare how up-to-date
+// replicas of the same deployment are.
+func ModTime(path string) (time.Time, error) {
+	var modTime time.Time
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Deleted concurrently
+		}
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Deleted concurrently
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return modTime.UTC(), nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/secret"
+)
+
+// QuarantineDir is the directory, relative to the state
+// directory, that contains quarantined entries. Each check
+// quarantines entries into a new subdirectory named after
+// the point in time when the check started.
+const QuarantineDir = "quarantine"
+
+// Kinds of entries within the Vault state.
+const (
+	EntryEnclave  = "enclave"
+	EntryKey      = "key"
+	EntrySecret   = "secret"
+	EntryPolicy   = "policy"
+	EntryIdentity = "identity"
+)
+
+// CheckReport is the result of checking the Vault state.
+type CheckReport struct {
+	// Number of intact entries per kind.
+	Enclaves   int
+	Keys       int
+	Secrets    int
+	Policies   int
+	Identities int
+
+	// Corrupted contains all entries that
+	// failed the integrity check.
+	Corrupted []CorruptEntry
+}
+
+// CorruptEntry describes an entry that failed the
+// integrity check.
+type CorruptEntry struct {
+	// Enclave is the name of the enclave
+	// containing the entry.
+	Enclave string
+
+	// Kind is the kind of entry, e.g. EntryKey.
+	Kind string
+
+	// Name is the name of the entry.
+	Name string
+
+	// Path is the path of the entry relative
+	// to the state directory.
+	Path string
+
+	// Err describes why the entry is corrupted.
+	Err error
+
+	// Quarantine is the path of the quarantined entry
+	// relative to the state directory. It is empty if
+	// the entry has not been quarantined.
+	Quarantine string
+}
+
+// Check verifies that every enclave, key, secret, policy
+// and identity can be decrypted and decoded. For keys, it
+// also compares the key check value with the stored one.
+//
+// An enclave that cannot be decrypted is quarantined as
+// a whole since none of its entries are accessible.
+func (v *vaultFS) Check(ctx context.Context, quarantine bool) (CheckReport, error) {
+	c := &checker{
+		rootDir:    v.rootDir,
+		quarantine: quarantine,
+		timestamp:  time.Now().UTC().Format("20060102T150405Z"),
+	}
+
+	names, err := readDirNames(filepath.Join(v.rootDir, "enclave"))
+	if err != nil {
+		return CheckReport{}, err
+	}
+	for _, name := range names {
+		if err = ctx.Err(); err != nil {
+			return c.report, err
+		}
+		if valid(name) != nil {
+			continue
+		}
+
+		enclavePath := path.Join("enclave", name)
+		plaintext, err := readFile(filepath.Join(v.rootDir, filepath.FromSlash(enclavePath), ".enclave"), v.rootKey, 1*mem.MiB, []byte(name))
+		var info EnclaveInfo
+		if err == nil {
+			err = info.UnmarshalBinary(plaintext)
+		}
+		if err != nil {
+			c.corrupt(name, EntryEnclave, name, enclavePath, err)
+			continue
+		}
+		c.report.Enclaves++
+
+		err = c.checkDir(ctx, name, EntryKey, path.Join(enclavePath, "key"), "", info.KeyStoreKey, key.MaxSize, checkKey)
+		if err != nil {
+			return c.report, err
+		}
+		err = c.checkDir(ctx, name, EntrySecret, path.Join(enclavePath, "secret"), "", info.SecretKey, secret.MaxSize, func(b []byte) error {
+			var s secret.Secret
+			return s.UnmarshalBinary(b)
+		})
+		if err != nil {
+			return c.report, err
+		}
+		err = c.checkDir(ctx, name, EntryPolicy, path.Join(enclavePath, "policy"), "", info.PolicyKey, 1*mem.MiB, func(b []byte) error {
+			var p auth.Policy
+			return p.UnmarshalBinary(b)
+		})
+		if err != nil {
+			return c.report, err
+		}
+
+		checkIdentity := func(b []byte) error {
+			var i auth.IdentityInfo
+			return i.UnmarshalBinary(b)
+		}
+		identityPath := path.Join(enclavePath, "identity")
+		if err = c.checkDir(ctx, name, EntryIdentity, path.Join(identityPath, ".admin"), ".admin", info.IdentityKey, 1*mem.MiB, checkIdentity); err != nil {
+			return c.report, err
+		}
+		if err = c.checkDir(ctx, name, EntryIdentity, identityPath, "", info.IdentityKey, 1*mem.MiB, checkIdentity); err != nil {
+			return c.report, err
+		}
+	}
+	return c.report, nil
+}
+
+// checkKey decodes b as key and verifies that
+// the key matches its stored check value.
+func checkKey(b []byte) error {
+	var k key.Key
+	if err := k.UnmarshalBinary(b); err != nil {
+		return err
+	}
+	stored := k.StoredCheckValue()
+	if len(stored) > 0 && subtle.ConstantTimeCompare(stored, k.CheckValue()) != 1 {
+		return errors.New("key check value mismatch")
+	}
+	return nil
+}
+
+// checker checks the entries within a state
+// directory and collects the results.
+type checker struct {
+	rootDir    string
+	quarantine bool
+	timestamp  string
+
+	report CheckReport
+}
+
+// checkDir checks all entries within the directory dir.
+// Each entry is decrypted with the given key using the
+// entry name, prefixed with adPrefix, as associated data
+// and then passed to decode.
+func (c *checker) checkDir(ctx context.Context, enclave, kind, dir, adPrefix string, key key.Key, limit mem.Size, decode func([]byte) error) error {
+	names, err := readDirNames(filepath.Join(c.rootDir, filepath.FromSlash(dir)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if valid(name) != nil { // Temporary files and the .admin directory
+			continue
+		}
+
+		entryPath := path.Join(dir, name)
+		name = entryName(name)
+		plaintext, err := readFile(filepath.Join(c.rootDir, filepath.FromSlash(entryPath)), key, limit, []byte(path.Join(adPrefix, name)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			err = decode(plaintext)
+		}
+		if err != nil {
+			c.corrupt(enclave, kind, name, entryPath, err)
+			continue
+		}
+
+		switch kind {
+		case EntryKey:
+			c.report.Keys++
+		case EntrySecret:
+			c.report.Secrets++
+		case EntryPolicy:
+			c.report.Policies++
+		case EntryIdentity:
+			c.report.Identities++
+		}
+	}
+	return nil
+}
+
+// corrupt adds the entry to the report and
+// quarantines it if requested.
+func (c *checker) corrupt(enclave, kind, name, entryPath string, err error) {
+	entry := CorruptEntry{
+		Enclave: enclave,
+		Kind:    kind,
+		Name:    name,
+		Path:    entryPath,
+		Err:     err,
+	}
+	if c.quarantine {
+		quarantinePath := path.Join(QuarantineDir, c.timestamp, entryPath)
+		dst := filepath.Join(c.rootDir, filepath.FromSlash(quarantinePath))
+		if qErr := os.MkdirAll(filepath.Dir(dst), 0o755); qErr != nil {
+			entry.Err = errors.New(err.Error() + " - failed to quarantine entry: " + qErr.Error())
+		} else if qErr = os.Rename(filepath.Join(c.rootDir, filepath.FromSlash(entryPath)), dst); qErr != nil {
+			entry.Err = errors.New(err.Error() + " - failed to quarantine entry: " + qErr.Error())
+		} else {
+			entry.Quarantine = quarantinePath
+		}
+	}
+	c.report.Corrupted = append(c.report.Corrupted, entry)
+}
+
+// readDirNames returns the sorted names
+// of all directory entries within dir.
+func readDirNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/auth"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/secret"
+)
+
+func TestVaultCheck(t *testing.T) {
+	const (
+		Admin        kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+		EnclaveAdmin kes.Identity = "b4eafa7e3a5a8fee1ce1c6a78ebafb8ec5ad67aa3926a7f42d6feef70a0d3d21"
+	)
+	ctx := context.Background()
+
+	rootDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(rootDir, "enclave"), 0o755); err != nil {
+		t.Fatalf("Failed to create enclave directory: %v", err)
+	}
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	vault := NewVault(NewVaultFS(rootDir, rootKey))
+	if _, err = vault.CreateEnclave(ctx, "tenant-1", EnclaveAdmin, nil); err != nil {
+		t.Fatalf("Failed to create enclave: %v", err)
+	}
+	enclave, err := vault.GetEnclave(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	for _, name := range []string{"key-1", "key-2"} {
+		k, err := key.Random(kes.AES256_GCM_SHA256, EnclaveAdmin)
+		if err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+		if err = enclave.CreateKey(ctx, name, k); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err = enclave.CreateSecret(ctx, "secret-1", secret.NewSecret([]byte("Hello World"), EnclaveAdmin)); err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if err = enclave.SetPolicy(ctx, "policy-1", auth.Policy{Allow: []string{"/v1/key/create/*"}, CreatedBy: EnclaveAdmin}); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	report, err := vault.Check(ctx, false)
+	if err != nil {
+		t.Fatalf("Failed to check vault: %v", err)
+	}
+	if len(report.Corrupted) != 0 {
+		t.Fatalf("Vault contains corrupted entries: %v", report.Corrupted[0].Err)
+	}
+	if report.Enclaves != 1 || report.Keys != 2 || report.Secrets != 1 || report.Policies != 1 || report.Identities != 1 {
+		t.Fatalf("Entry count mismatch: got %+v", report)
+	}
+
+	// Corrupt one key by flipping a bit of its ciphertext.
+	keyFile := filepath.Join(rootDir, "enclave", "tenant-1", "key", "key-2")
+	ciphertext, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatalf("Failed to read key file: %v", err)
+	}
+	ciphertext[len(ciphertext)/2] ^= 1
+	if err = os.WriteFile(keyFile, ciphertext, 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	report, err = vault.Check(ctx, true)
+	if err != nil {
+		t.Fatalf("Failed to check vault: %v", err)
+	}
+	if len(report.Corrupted) != 1 {
+		t.Fatalf("Corrupted entry count mismatch: got %d - want 1", len(report.Corrupted))
+	}
+	if entry := report.Corrupted[0]; entry.Kind != EntryKey || entry.Name != "key-2" || entry.Enclave != "tenant-1" {
+		t.Fatalf("Corrupted entry mismatch: got %s '%s' in enclave '%s'", entry.Kind, entry.Name, entry.Enclave)
+	}
+	if report.Corrupted[0].Quarantine == "" {
+		t.Fatalf("Corrupted entry has not been quarantined: %v", report.Corrupted[0].Err)
+	}
+	if _, err = os.Stat(filepath.Join(rootDir, filepath.FromSlash(report.Corrupted[0].Quarantine))); err != nil {
+		t.Fatalf("Quarantined entry does not exist: %v", err)
+	}
+
+	enclave, err = vault.GetEnclave(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("Failed to get enclave: %v", err)
+	}
+	if _, err = enclave.GetKey(ctx, "key-2"); err != kes.ErrKeyNotFound {
+		t.Fatalf("Quarantined key is still accessible: got error '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if report, err = vault.Check(ctx, false); err != nil || len(report.Corrupted) != 0 {
+		t.Fatalf("Vault still contains corrupted entries: %v", err)
+	}
+}
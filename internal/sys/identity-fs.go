@@ -13,6 +13,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"aead.dev/mem"
@@ -37,6 +38,8 @@ var _ IdentityFS = (*identityFS)(nil)
 type identityFS struct {
 	rootDir string
 	rootKey key.Key
+
+	lock sync.Mutex // Serializes writes to the temporary files
 }
 
 func (fs *identityFS) Admin(_ context.Context) (kes.Identity, error) {
@@ -111,6 +114,9 @@ func (fs *identityFS) SetAdmin(_ context.Context, admin kes.Identity) error {
 		AdminDir = ".admin"
 		TmpFile  = ".admin.tmp"
 	)
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
 	filename := filepath.Join(fs.rootDir, AdminDir, TmpFile)
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
 	if errors.Is(err, os.ErrNotExist) {
@@ -166,7 +172,7 @@ func (fs *identityFS) SetAdmin(_ context.Context, admin kes.Identity) error {
 	return nil
 }
 
-func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity kes.Identity) error {
+func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity kes.Identity, expiresAt time.Time) error {
 	if err := valid(identity.String()); err != nil {
 		return err
 	}
@@ -179,6 +185,9 @@ func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity ke
 	// file in one "atomic" operation. This avoids partial/broken
 	// files in case of a write error.
 	const TmpFile = ".identity.tmp"
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
 	filename := filepath.Join(fs.rootDir, TmpFile)
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
 	if err != nil {
@@ -192,6 +201,9 @@ func (fs *identityFS) AssignPolicy(_ context.Context, policy string, identity ke
 		CreatedAt: time.Now().UTC(),
 		CreatedBy: "", // TODO
 	}
+	if !expiresAt.IsZero() {
+		info.ExpiresAt = expiresAt.UTC()
+	}
 	plaintext, err := info.MarshalBinary()
 	if err != nil {
 		return err
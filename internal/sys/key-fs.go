@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
@@ -32,10 +33,12 @@ func NewKeyFS(filename string, key key.Key) KeyFS {
 type keyFS struct {
 	rootDir string
 	rootKey key.Key
+
+	lock sync.Mutex // Serializes writes to the temporary file
 }
 
 func (fs *keyFS) CreateKey(_ context.Context, name string, key key.Key) error {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return err
 	}
 
@@ -44,51 +47,92 @@ func (fs *keyFS) CreateKey(_ context.Context, name string, key key.Key) error {
 	// that is not allowed for client-specified key names.
 	// Therefore, clients cannot create a key with the
 	// same name.
-	// Then we rename this temporary file to the actual
-	// key file in one "atomic" operation.
+	// Then we link this temporary file to the actual
+	// key file in one "atomic" operation that fails if
+	// the key file exists already.
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	filename, err := fs.writeTmp(name, key)
+	if err != nil {
+		return err
+	}
+	err = os.Link(filename, filepath.Join(fs.rootDir, fileName(name)))
+	os.Remove(filename)
+	if errors.Is(err, os.ErrExist) {
+		return kes.ErrKeyExists
+	}
+	return err
+}
+
+func (fs *keyFS) SetKey(_ context.Context, name string, key key.Key) error {
+	if err := valid(fileName(name)); err != nil {
+		return err
+	}
+
+	// Like CreateKey, we write the key to a temporary
+	// file first. Then we rename it to the existing
+	// key file.
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	target := filepath.Join(fs.rootDir, fileName(name))
+	if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) {
+		return kes.ErrKeyNotFound
+	}
+	filename, err := fs.writeTmp(name, key)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(filename, target); err != nil {
+		os.Remove(filename)
+		return err
+	}
+	return nil
+}
+
+// writeTmp writes the encrypted key to the temporary
+// key file and returns its path. The caller must hold
+// the fs.lock.
+func (fs *keyFS) writeTmp(name string, key key.Key) (string, error) {
 	const TmpFile = ".key.tmp"
 	filename := filepath.Join(fs.rootDir, TmpFile)
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer file.Close()
 
 	plaintext, err := key.MarshalBinary()
 	if err != nil {
-		return err
+		return "", err
 	}
 	ciphertext, err := fs.rootKey.Wrap(plaintext, []byte(name))
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	n, err := file.Write(ciphertext)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if n != len(ciphertext) {
-		return io.ErrShortWrite
+		return "", io.ErrShortWrite
 	}
 	if err = file.Sync(); err != nil {
-		return err
+		return "", err
 	}
 	if err = file.Close(); err != nil {
-		return err
-	}
-
-	if err = os.Rename(filename, filepath.Join(fs.rootDir, name)); err != nil {
-		os.Remove(filename)
-		return err
+		return "", err
 	}
-	return nil
+	return filename, nil
 }
 
 func (fs *keyFS) GetKey(_ context.Context, name string) (key.Key, error) {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return key.Key{}, err
 	}
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	file, err := os.Open(filename)
 	if errors.Is(err, os.ErrNotExist) {
 		return key.Key{}, kes.ErrKeyNotFound
@@ -115,10 +159,10 @@ func (fs *keyFS) GetKey(_ context.Context, name string) (key.Key, error) {
 }
 
 func (fs *keyFS) DeleteKey(_ context.Context, name string) error {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return err
 	}
-	err := os.Remove(filepath.Join(fs.rootDir, name))
+	err := os.Remove(filepath.Join(fs.rootDir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return kes.ErrKeyNotFound
 	}
@@ -184,7 +228,7 @@ func (i *keyIterator) Next() bool {
 	return false
 }
 
-func (i *keyIterator) Name() string { return i.next }
+func (i *keyIterator) Name() string { return entryName(i.next) }
 
 func (i *keyIterator) Close() error {
 	if err := i.dir.Close(); i.err == nil || i.err == io.EOF {
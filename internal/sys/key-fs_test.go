@@ -0,0 +1,47 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestKeyFSCreateKey(t *testing.T) {
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	fs := NewKeyFS(t.TempDir(), rootKey)
+
+	first, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = fs.CreateKey(ctx, "my-key", first); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	second, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	if err = fs.CreateKey(ctx, "my-key", second); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	stored, err := fs.GetKey(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if !stored.Equal(first) {
+		t.Fatal("Existing key has been overwritten")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import "sync"
+
+// entryLocks is a set of mutexes, one per entry name.
+//
+// It allows operations on distinct entries, like two
+// different keys, to proceed concurrently while operations
+// on the same entry are serialized. A mutex only exists
+// while at least one goroutine holds or waits for it.
+//
+// The zero value is ready to use.
+type entryLocks struct {
+	lock  sync.Mutex
+	locks map[string]*entryLock
+}
+
+type entryLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks the entry with the given name and returns
+// a function that unlocks it again.
+func (l *entryLocks) Lock(name string) (unlock func()) {
+	l.lock.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*entryLock{}
+	}
+	e, ok := l.locks[name]
+	if !ok {
+		e = &entryLock{}
+		l.locks[name] = e
+	}
+	e.refs++
+	l.lock.Unlock()
+
+	e.Lock()
+	return func() {
+		e.Unlock()
+
+		l.lock.Lock()
+		if e.refs--; e.refs == 0 {
+			delete(l.locks, name)
+		}
+		l.lock.Unlock()
+	}
+}
+
+// lookup returns the value associated with key from
+// the given cache while holding the lock for reads.
+func lookup[K comparable, V any](lock *sync.RWMutex, cache map[K]V, key K) (V, bool) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	v, ok := cache[key]
+	return v, ok
+}
+
+// store adds the key-value pair to the given cache
+// while holding the lock for writes.
+func store[K comparable, V any](lock *sync.RWMutex, cache map[K]V, key K, value V) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	cache[key] = value
+}
+
+// evict removes the key from the given cache while
+// holding the lock for writes.
+func evict[K comparable, V any](lock *sync.RWMutex, cache map[K]V, key K) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	delete(cache, key)
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestEntryLocks(t *testing.T) {
+	const (
+		Entries    = 4
+		Goroutines = 16
+		Iterations = 100
+	)
+
+	var (
+		locks    entryLocks
+		counters [Entries]int // Each one is only protected by its entry lock
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < Goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < Iterations; j++ {
+				n := (i + j) % Entries
+				unlock := locks.Lock(strconv.Itoa(n))
+				counters[n]++
+				unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var total int
+	for _, n := range counters {
+		total += n
+	}
+	if total != Goroutines*Iterations {
+		t.Fatalf("Lost updates: got '%d' - want '%d'", total, Goroutines*Iterations)
+	}
+	if n := len(locks.locks); n != 0 {
+		t.Fatalf("Unlocked entries are not removed: got '%d' - want '0'", n)
+	}
+}
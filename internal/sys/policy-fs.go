@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"aead.dev/mem"
 	"github.com/minio/kes-go"
@@ -32,21 +33,25 @@ func NewPolicyFS(filename string, key key.Key) PolicyFS {
 type policyFS struct {
 	rootDir string
 	rootKey key.Key
+
+	lock sync.Mutex // Serializes writes to the temporary file
 }
 
 func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy) error {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return err
 	}
 
 	// First, we write the policy to a temporary file.
-	// The tmp file name contains a character ('.')
-	// that is not allowed for client-specified policy names.
-	// Therefore, clients cannot create a policy with the
-	// same name.
+	// The tmp file name starts with a '.' and policy
+	// names must not start with a '.'. Therefore, clients
+	// cannot create a policy with the same name.
 	// Then we rename this temporary file to the actual
 	// policy file in one "atomic" operation.
-	const TmpFile = "policy.tmp"
+	const TmpFile = ".policy.tmp"
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
 	filename := filepath.Join(fs.rootDir, TmpFile)
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
 	if err != nil {
@@ -77,7 +82,7 @@ func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy
 		return err
 	}
 
-	if err = os.Rename(filename, filepath.Join(fs.rootDir, name)); err != nil {
+	if err = os.Rename(filename, filepath.Join(fs.rootDir, fileName(name))); err != nil {
 		os.Remove(filename)
 		return err
 	}
@@ -85,11 +90,11 @@ func (fs *policyFS) SetPolicy(_ context.Context, name string, policy auth.Policy
 }
 
 func (fs *policyFS) GetPolicy(_ context.Context, name string) (auth.Policy, error) {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return auth.Policy{}, err
 	}
 
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	file, err := os.Open(filename)
 	if errors.Is(err, os.ErrNotExist) {
 		return auth.Policy{}, kes.ErrPolicyNotFound
@@ -117,11 +122,11 @@ func (fs *policyFS) GetPolicy(_ context.Context, name string) (auth.Policy, erro
 }
 
 func (fs *policyFS) DeletePolicy(_ context.Context, name string) error {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return err
 	}
 
-	err := os.Remove(filepath.Join(fs.rootDir, name))
+	err := os.Remove(filepath.Join(fs.rootDir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return kes.ErrPolicyNotFound
 	}
@@ -175,7 +180,7 @@ func (i *policyIterator) Next() bool {
 	return true
 }
 
-func (i *policyIterator) Name() string { return i.next }
+func (i *policyIterator) Name() string { return entryName(i.next) }
 
 func (i *policyIterator) Close() error {
 	if err := i.dir.Close(); i.err == nil || i.err == io.EOF {
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/minio/kes-go"
 	"github.com/minio/kes/internal/key"
@@ -29,10 +30,17 @@ func NewSecretFS(filename string, key key.Key) SecretFS {
 type secretFS struct {
 	rootDir string
 	rootKey key.Key
+
+	lock sync.Mutex // Serializes writes to the temporary file
 }
 
+// tmpSecretFile is the name of the temporary file
+// used to replace secrets. It contains a character
+// ('.') that is not allowed for secret names.
+const tmpSecretFile = ".secret.tmp"
+
 func (fs *secretFS) CreateSecret(_ context.Context, name string, secret secret.Secret) error {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return err
 	}
 	plaintext, err := secret.MarshalBinary()
@@ -40,7 +48,7 @@ func (fs *secretFS) CreateSecret(_ context.Context, name string, secret secret.S
 		return err
 	}
 
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	err = createFile(filename, fs.rootKey, plaintext, []byte(name))
 	if errors.Is(err, os.ErrExist) {
 		return kes.ErrSecretExists
@@ -52,11 +60,11 @@ func (fs *secretFS) CreateSecret(_ context.Context, name string, secret secret.S
 }
 
 func (fs *secretFS) GetSecret(_ context.Context, name string) (sec secret.Secret, err error) {
-	if err = valid(name); err != nil {
+	if err = valid(fileName(name)); err != nil {
 		return sec, err
 	}
 
-	filename := filepath.Join(fs.rootDir, name)
+	filename := filepath.Join(fs.rootDir, fileName(name))
 	plaintext, err := readFile(filename, fs.rootKey, secret.MaxSize, []byte(name))
 	if errors.Is(err, os.ErrNotExist) {
 		return sec, kes.ErrSecretNotFound
@@ -71,12 +79,46 @@ func (fs *secretFS) GetSecret(_ context.Context, name string) (sec secret.Secret
 	return sec, nil
 }
 
+func (fs *secretFS) SetSecret(_ context.Context, name string, secret secret.Secret) error {
+	if err := valid(fileName(name)); err != nil {
+		return err
+	}
+	plaintext, err := secret.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	// We write the secret to a temporary file first.
+	// Then we rename it to the existing secret file
+	// such that readers never observe a partial write.
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	target := filepath.Join(fs.rootDir, fileName(name))
+	if _, err = os.Stat(target); errors.Is(err, os.ErrNotExist) {
+		return kes.ErrSecretNotFound
+	}
+	filename := filepath.Join(fs.rootDir, tmpSecretFile)
+	if err = os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err = createFile(filename, fs.rootKey, plaintext, []byte(name)); err != nil {
+		os.Remove(filename)
+		return err
+	}
+	if err = os.Rename(filename, target); err != nil {
+		os.Remove(filename)
+		return err
+	}
+	return nil
+}
+
 func (fs *secretFS) DeleteSecret(_ context.Context, name string) error {
-	if err := valid(name); err != nil {
+	if err := valid(fileName(name)); err != nil {
 		return err
 	}
 
-	err := os.Remove(filepath.Join(fs.rootDir, name))
+	err := os.Remove(filepath.Join(fs.rootDir, fileName(name)))
 	if errors.Is(err, os.ErrNotExist) {
 		return kes.ErrSecretNotFound
 	}
@@ -88,8 +130,27 @@ func (fs *secretFS) ListSecrets(ctx context.Context) (secret.Iter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &iter{
-		ctx:  ctx,
-		file: file,
+	return secretIter{
+		iter: &iter{
+			ctx:  ctx,
+			file: file,
+		},
 	}, nil
 }
+
+// secretIter is an iterator over secrets that
+// skips the temporary secret file.
+type secretIter struct {
+	*iter
+}
+
+func (i secretIter) Next() bool {
+	for i.iter.Next() {
+		if i.Name() != tmpSecretFile {
+			return true
+		}
+	}
+	return false
+}
+
+func (i secretIter) Name() string { return entryName(i.iter.Name()) }
@@ -0,0 +1,165 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+// ErrTokenNotFound is returned when a token does not
+// exist within a tokenization scope.
+var ErrTokenNotFound = kes.NewError(http.StatusNotFound, "token does not exist")
+
+// MaxTokenValueSize is the max. size of a tokenized value.
+const MaxTokenValueSize = 4 * mem.KiB
+
+// A Token is a sensitive value that has been replaced
+// by a random token.
+type Token struct {
+	// Value is the sensitive value.
+	Value []byte
+
+	// CreatedAt is the point in time when the
+	// value has been tokenized.
+	CreatedAt time.Time
+
+	// CreatedBy is the identity that tokenized
+	// the value.
+	CreatedBy kes.Identity
+}
+
+// MarshalBinary returns the Token's binary representation.
+func (t Token) MarshalBinary() ([]byte, error) {
+	type GOB struct {
+		Value     []byte
+		CreatedAt time.Time
+		CreatedBy kes.Identity
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(GOB(t)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary unmarshals the Token's binary representation.
+func (t *Token) UnmarshalBinary(b []byte) error {
+	type GOB struct {
+		Value     []byte
+		CreatedAt time.Time
+		CreatedBy kes.Identity
+	}
+
+	var value GOB
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&value); err != nil {
+		return err
+	}
+	t.Value = value.Value
+	t.CreatedAt = value.CreatedAt
+	t.CreatedBy = value.CreatedBy
+	return nil
+}
+
+// TokenFS provides access to tokens within a particular
+// Enclave. Tokens are grouped into scopes, e.g. one scope
+// for credit card numbers and one for account numbers.
+type TokenFS interface {
+	// CreateToken stores the value of the given token within
+	// the scope if and only if no such token exists already.
+	CreateToken(ctx context.Context, scope, token string, value Token) error
+
+	// GetToken returns the value of the given token within
+	// the scope.
+	//
+	// It returns ErrTokenNotFound if no such token exists.
+	GetToken(ctx context.Context, scope, token string) (Token, error)
+}
+
+// NewTokenFS returns a new TokenFS that reads/writes
+// tokens from/to the given directory path and en/decrypts
+// them with the given encryption key. The directory is
+// created once the first value gets tokenized.
+func NewTokenFS(filename string, key key.Key) TokenFS {
+	return &tokenFS{
+		rootDir: filename,
+		rootKey: key,
+	}
+}
+
+type tokenFS struct {
+	rootDir string
+	rootKey key.Key
+}
+
+func (fs *tokenFS) CreateToken(_ context.Context, scope, token string, value Token) error {
+	if err := valid(fileName(scope)); err != nil {
+		return err
+	}
+	if err := valid(token); err != nil {
+		return err
+	}
+	plaintext, err := value.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(fs.rootDir, fileName(scope))
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, token)
+	if err = createFile(filename, fs.rootKey, plaintext, tokenAssociatedData(scope, token)); err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			os.Remove(filename)
+		}
+		return err
+	}
+	return nil
+}
+
+func (fs *tokenFS) GetToken(_ context.Context, scope, token string) (Token, error) {
+	if err := valid(fileName(scope)); err != nil {
+		return Token{}, err
+	}
+	if err := valid(token); err != nil {
+		return Token{}, err
+	}
+
+	const MaxSize = 2 * MaxTokenValueSize
+	filename := filepath.Join(fs.rootDir, fileName(scope), token)
+	plaintext, err := readFile(filename, fs.rootKey, MaxSize, tokenAssociatedData(scope, token))
+	if errors.Is(err, os.ErrNotExist) {
+		return Token{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return Token{}, err
+	}
+
+	var value Token
+	if err = value.UnmarshalBinary(plaintext); err != nil {
+		return Token{}, err
+	}
+	return value, nil
+}
+
+// tokenAssociatedData returns the associated data that binds
+// an encrypted token file to its scope and token. It differs
+// from the associated data of any secret such that token files
+// cannot be swapped with secret files.
+func tokenAssociatedData(scope, token string) []byte {
+	return []byte("token/" + scope + "/" + token)
+}
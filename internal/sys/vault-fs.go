@@ -18,6 +18,8 @@ import (
 	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/pki"
+	"github.com/minio/kes/kms"
 )
 
 // NewVaultFS returns a new VaultFS that
@@ -74,10 +76,16 @@ func (v *vaultFS) Admin(context.Context) (kes.Identity, error) {
 	return v.rootKey.CreatedBy(), nil
 }
 
-func (v *vaultFS) CreateEnclave(ctx context.Context, name string, admin kes.Identity) (EnclaveInfo, error) {
+func (v *vaultFS) CreateEnclave(ctx context.Context, name string, admin kes.Identity, keyPolicy *key.AlgorithmPolicy) (EnclaveInfo, error) {
 	if err := valid(name); err != nil {
 		return EnclaveInfo{}, err
 	}
+	if err := keyPolicy.Validate(); err != nil {
+		return EnclaveInfo{}, err
+	}
+	if keyPolicy.IsZero() {
+		keyPolicy = nil
+	}
 
 	enclavePath := filepath.Join(v.rootDir, "enclave", name)
 	_, err := os.Stat(enclavePath)
@@ -138,6 +146,7 @@ func (v *vaultFS) CreateEnclave(ctx context.Context, name string, admin kes.Iden
 		IdentityKey: identityKey,
 		CreatedAt:   time.Now().UTC(),
 		CreatedBy:   v.rootKey.CreatedBy(),
+		KeyPolicy:   keyPolicy,
 	}
 	plaintext, err := info.MarshalBinary()
 	if err != nil {
@@ -184,9 +193,13 @@ func (v *vaultFS) GetEnclave(_ context.Context, name string) (*Enclave, error) {
 
 	keyFS := NewKeyFS(filepath.Join(enclavePath, "key"), info.KeyStoreKey)
 	secretFS := NewSecretFS(filepath.Join(enclavePath, "secret"), info.SecretKey)
+	tokenFS := NewTokenFS(filepath.Join(enclavePath, "token"), info.SecretKey) // Tokens are sensitive values, like secrets
 	policyFS := NewPolicyFS(filepath.Join(enclavePath, "policy"), info.PolicyKey)
 	identityFS := NewIdentityFS(filepath.Join(enclavePath, "identity"), info.IdentityKey)
-	return NewEnclave(keyFS, secretFS, policyFS, identityFS), nil
+
+	enclave := NewEnclave(keyFS, secretFS, tokenFS, policyFS, identityFS)
+	enclave.keyPolicy = info.KeyPolicy
+	return enclave, nil
 }
 
 func (v *vaultFS) GetEnclaveInfo(_ context.Context, name string) (EnclaveInfo, error) {
@@ -220,9 +233,110 @@ func (v *vaultFS) GetEnclaveInfo(_ context.Context, name string) (EnclaveInfo, e
 	return info, nil
 }
 
+func (v *vaultFS) ListEnclaves(ctx context.Context) (kms.Iter, error) {
+	file, err := os.Open(filepath.Join(v.rootDir, "enclave"))
+	if err != nil {
+		return nil, err
+	}
+	return &iter{
+		ctx:  ctx,
+		file: file,
+	}, nil
+}
+
 func (v *vaultFS) DeleteEnclave(_ context.Context, name string) error {
 	if err := valid(name); err != nil {
 		return err
 	}
 	return os.RemoveAll(filepath.Join(v.rootDir, "enclave", name))
 }
+
+// issuerLifetime is the lifetime of the generated certificate
+// authority that issues server and client certificates.
+const issuerLifetime = 10 * 365 * 24 * time.Hour
+
+func (v *vaultFS) GetIssuer(context.Context) (*pki.Issuer, error) {
+	const MaxSize = 1 * mem.MiB
+	filename := filepath.Join(v.rootDir, ".issuer")
+
+	for {
+		file, err := os.Open(filename)
+		if err == nil {
+			defer file.Close()
+
+			var ciphertext bytes.Buffer
+			if _, err = io.Copy(&ciphertext, mem.LimitReader(file, MaxSize)); err != nil {
+				return nil, err
+			}
+			plaintext, err := v.rootKey.Unwrap(ciphertext.Bytes(), []byte(".issuer"))
+			if err != nil {
+				return nil, err
+			}
+			var issuer pki.Issuer
+			if err = issuer.UnmarshalBinary(plaintext); err != nil {
+				return nil, err
+			}
+			return &issuer, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		issuer, err := pki.GenerateIssuer("KES CA", issuerLifetime)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := issuer.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := v.rootKey.Wrap(plaintext, []byte(".issuer"))
+		if err != nil {
+			return nil, err
+		}
+
+		// Another server process may create the issuer
+		// concurrently. Then we use its issuer instead.
+		file, err = os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_SYNC, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err = file.Write(ciphertext); err != nil {
+			file.Close()
+			os.Remove(filename)
+			return nil, err
+		}
+		if err = file.Close(); err != nil {
+			os.Remove(filename)
+			return nil, err
+		}
+		return issuer, nil
+	}
+}
+
+func (v *vaultFS) SetIssuer(_ context.Context, issuer *pki.Issuer) error {
+	plaintext, err := issuer.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	ciphertext, err := v.rootKey.Wrap(plaintext, []byte(".issuer"))
+	if err != nil {
+		return err
+	}
+
+	// Replace the issuer atomically such that a crash
+	// does not leave a partially written issuer behind.
+	filename := filepath.Join(v.rootDir, ".issuer")
+	if err = os.WriteFile(filename+".tmp", ciphertext, 0o600); err != nil {
+		os.Remove(filename + ".tmp")
+		return err
+	}
+	if err = os.Rename(filename+".tmp", filename); err != nil {
+		os.Remove(filename + ".tmp")
+		return err
+	}
+	return nil
+}
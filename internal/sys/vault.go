@@ -6,10 +6,17 @@ package sys
 
 import (
 	"context"
+	"fmt"
+	"math/big"
 	"net/http"
+	"path"
 	"sync"
+	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+	"github.com/minio/kes/internal/pki"
+	"github.com/minio/kes/kms"
 )
 
 // NewVault returns a new Vault that uses the given
@@ -24,25 +31,37 @@ func NewVault(fs VaultFS) *Vault {
 // A Vault manages a set of enclaves. It is either in a
 // sealed or unsealed state. When sealed, any Vault operation,
 // except unsealing, returns ErrSealed.
+//
+// A Vault is safe for concurrent use. It never holds its
+// internal lock while accessing the VaultFS, except when
+// sealing or unsealing. Instead, operations on the same
+// enclave are serialized while operations on distinct
+// enclaves proceed concurrently.
 type Vault struct {
-	fs   VaultFS
-	lock sync.RWMutex
+	fs VaultFS
 
-	cacheLock sync.Mutex
-	admin     kes.Identity
-	sealed    bool
-	enclaves  map[string]*Enclave
-}
+	lock     sync.RWMutex // Protects admin, sealed, enclaves and issuer
+	admin    kes.Identity
+	sealed   bool
+	enclaves map[string]*Enclave
+	issuer   *pki.Issuer
+	issuerAt time.Time // Point in time when the issuer has been loaded
 
-// Locker returns a sync.Locker that locks the Vault for writes.
-func (v *Vault) Locker() sync.Locker { return &v.lock }
+	enclaveLocks entryLocks
+	issuerLock   sync.Mutex // Serializes certificate revocations
 
-// RLocker returns a sync.Locker that locks the Vault for reads.
-func (v *Vault) RLocker() sync.Locker { return v.lock.RLocker() }
+	// writes is held exclusively while checking the
+	// Vault state and shared by all write operations,
+	// including writes within enclaves.
+	writes sync.RWMutex
+}
 
 // Seal seals the Vault. Once sealed, any subsequent Vault operation,
 // returns ErrSealed until the Vault gets unsealed again.
 func (v *Vault) Seal(ctx context.Context) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
 	if v.sealed {
 		return kes.ErrSealed
 	}
@@ -51,6 +70,7 @@ func (v *Vault) Seal(ctx context.Context) error {
 	}
 	v.admin = ""
 	v.enclaves = map[string]*Enclave{}
+	v.issuer = nil
 	v.sealed = true
 	return nil
 }
@@ -58,6 +78,9 @@ func (v *Vault) Seal(ctx context.Context) error {
 // Unseal unseals the Vault. In case of an unsealed Vault,
 // Unseal is a no-op.
 func (v *Vault) Unseal(ctx context.Context, keys ...UnsealKey) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
 	if !v.sealed {
 		return nil
 	}
@@ -76,8 +99,11 @@ func (v *Vault) Unseal(ctx context.Context, keys ...UnsealKey) error {
 
 // Admin returns the current Vault admin identity.
 func (v *Vault) Admin(ctx context.Context) (kes.Identity, error) {
-	if !v.admin.IsUnknown() {
-		return v.admin, nil
+	v.lock.RLock()
+	admin := v.admin
+	v.lock.RUnlock()
+	if !admin.IsUnknown() {
+		return admin, nil
 	}
 
 	admin, err := v.fs.Admin(ctx)
@@ -85,33 +111,43 @@ func (v *Vault) Admin(ctx context.Context) (kes.Identity, error) {
 		return "", err
 	}
 
-	v.cacheLock.Lock()
+	v.lock.Lock()
 	v.admin = admin
-	v.cacheLock.Unlock()
-	return v.admin, nil
+	v.lock.Unlock()
+	return admin, nil
 }
 
 // CreateEnclave creates a new enclave with the given name and
-// enclave admin identity.
+// enclave admin identity. The key policy, if not nil, restricts
+// the algorithms of keys created within the enclave.
 //
 // It returns ErrEnclaveExists if such an enclave already exists.
-func (v *Vault) CreateEnclave(ctx context.Context, name string, admin kes.Identity) (EnclaveInfo, error) {
+func (v *Vault) CreateEnclave(ctx context.Context, name string, admin kes.Identity, keyPolicy *key.AlgorithmPolicy) (EnclaveInfo, error) {
 	if name == "" {
 		name = DefaultEnclaveName
 	}
-
-	if v.sealed {
-		return EnclaveInfo{}, kes.ErrSealed
-	}
 	if admin.IsUnknown() {
 		return EnclaveInfo{}, kes.NewError(http.StatusBadRequest, "admin cannot be empty")
 	}
-	if admin == v.admin {
+
+	v.lock.RLock()
+	sealed, sysAdmin := v.sealed, v.admin
+	v.lock.RUnlock()
+	if sealed {
+		return EnclaveInfo{}, kes.ErrSealed
+	}
+	if admin == sysAdmin {
 		return EnclaveInfo{}, kes.NewError(http.StatusBadRequest, "admin cannot be the system admin")
 	}
 
-	delete(v.enclaves, name)
-	return v.fs.CreateEnclave(ctx, name, admin)
+	v.writes.RLock()
+	defer v.writes.RUnlock()
+
+	unlock := v.enclaveLocks.Lock(name)
+	defer unlock()
+
+	v.evictEnclave(name)
+	return v.fs.CreateEnclave(ctx, name, admin, keyPolicy)
 }
 
 // GetEnclave returns the Enclave with the given name.
@@ -121,24 +157,29 @@ func (v *Vault) GetEnclave(ctx context.Context, name string) (*Enclave, error) {
 	if name == "" {
 		name = DefaultEnclaveName
 	}
-
-	if v.sealed {
-		return nil, kes.ErrSealed
-	}
-	if enclave, ok := v.enclaves[name]; ok {
-		return enclave, nil
+	if enclave, err := v.cachedEnclave(name); enclave != nil || err != nil {
+		return enclave, err
 	}
 
-	v.cacheLock.Lock()
-	defer v.cacheLock.Unlock()
+	unlock := v.enclaveLocks.Lock(name)
+	defer unlock()
 
-	if enclave, ok := v.enclaves[name]; ok {
-		return enclave, nil
+	// Another request for the same enclave may have
+	// loaded it while we were waiting for the lock.
+	if enclave, err := v.cachedEnclave(name); enclave != nil || err != nil {
+		return enclave, err
 	}
 	enclave, err := v.fs.GetEnclave(ctx, name)
 	if err != nil {
 		return nil, err
 	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.sealed {
+		return nil, kes.ErrSealed
+	}
+	enclave.writes = &v.writes
 	v.enclaves[name] = enclave
 	return enclave, nil
 }
@@ -150,7 +191,11 @@ func (v *Vault) GetEnclaveInfo(ctx context.Context, name string) (EnclaveInfo, e
 	if name == "" {
 		name = DefaultEnclaveName
 	}
-	if v.sealed {
+
+	v.lock.RLock()
+	sealed := v.sealed
+	v.lock.RUnlock()
+	if sealed {
 		return EnclaveInfo{}, kes.ErrSealed
 	}
 	return v.fs.GetEnclaveInfo(ctx, name)
@@ -164,9 +209,201 @@ func (v *Vault) DeleteEnclave(ctx context.Context, name string) error {
 		name = DefaultEnclaveName
 	}
 
+	v.lock.RLock()
+	sealed := v.sealed
+	v.lock.RUnlock()
+	if sealed {
+		return kes.ErrSealed
+	}
+
+	v.writes.RLock()
+	defer v.writes.RUnlock()
+
+	unlock := v.enclaveLocks.Lock(name)
+	defer unlock()
+
+	v.evictEnclave(name)
+	return v.fs.DeleteEnclave(ctx, name)
+}
+
+// ListEnclaves returns an iterator over the names of
+// all enclaves.
+func (v *Vault) ListEnclaves(ctx context.Context) (kms.Iter, error) {
+	v.lock.RLock()
+	sealed := v.sealed
+	v.lock.RUnlock()
+	if sealed {
+		return nil, kes.ErrSealed
+	}
+	return v.fs.ListEnclaves(ctx)
+}
+
+// Issuer returns the certificate authority that issues server
+// and client certificates. Its private key is encrypted with the
+// Vault's root key and only accessible while the Vault is unsealed.
+//
+// The issuer is generated when requested for the first time.
+func (v *Vault) Issuer(ctx context.Context) (*pki.Issuer, error) {
+	// Cluster nodes replicate the issuer of the leader.
+	// Hence, we reload it periodically to pick up any
+	// certificates revoked by the leader.
+	const CacheTTL = 1 * time.Minute
+
+	v.lock.RLock()
+	sealed, issuer, issuerAt := v.sealed, v.issuer, v.issuerAt
+	v.lock.RUnlock()
+	if sealed {
+		return nil, kes.ErrSealed
+	}
+	if issuer != nil && time.Since(issuerAt) < CacheTTL {
+		return issuer, nil
+	}
+
+	v.writes.RLock()
+	defer v.writes.RUnlock()
+
+	issuer, err := v.fs.GetIssuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if v.sealed {
+		return nil, kes.ErrSealed
+	}
+	v.issuer, v.issuerAt = issuer, time.Now()
+	return issuer, nil
+}
+
+// RevokeCertificate revokes the certificate with the given serial
+// number issued by the Vault's certificate authority. Revoked
+// certificates are listed by the issuer's revocation list.
+func (v *Vault) RevokeCertificate(ctx context.Context, serialNumber *big.Int) error {
+	v.issuerLock.Lock()
+	defer v.issuerLock.Unlock()
+
+	issuer, err := v.Issuer(ctx)
+	if err != nil {
+		return err
+	}
+	revoked := issuer.Revoke(serialNumber, time.Now())
+	if revoked == issuer {
+		return nil
+	}
+
+	v.writes.RLock()
+	defer v.writes.RUnlock()
+
+	if err = v.fs.SetIssuer(ctx, revoked); err != nil {
+		return err
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
 	if v.sealed {
 		return kes.ErrSealed
 	}
+	v.issuer, v.issuerAt = revoked, time.Now()
+	return nil
+}
+
+// Check verifies the integrity of all entries within the
+// Vault. If quarantine is true, it moves corrupted entries
+// out of the way such that they are no longer accessible.
+//
+// Check blocks all write operations until it returns. Hence,
+// it checks a consistent snapshot of the Vault state.
+func (v *Vault) Check(ctx context.Context, quarantine bool) (CheckReport, error) {
+	v.lock.RLock()
+	sealed := v.sealed
+	v.lock.RUnlock()
+	if sealed {
+		return CheckReport{}, kes.ErrSealed
+	}
+
+	v.writes.Lock()
+	defer v.writes.Unlock()
+
+	report, err := v.fs.Check(ctx, quarantine)
+	if quarantine && len(report.Corrupted) > 0 {
+		// Drop all enclaves, and therefore all cached
+		// entries, since cached entries may have been
+		// quarantined.
+		v.lock.Lock()
+		v.enclaves = map[string]*Enclave{}
+		v.lock.Unlock()
+	}
+	return report, err
+}
+
+// CacheStats returns the accumulated CacheStats of all
+// cached enclaves.
+func (v *Vault) CacheStats() (CacheStats, error) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	if v.sealed {
+		return CacheStats{}, kes.ErrSealed
+	}
+	var stats CacheStats
+	for _, enclave := range v.enclaves {
+		s := enclave.CacheStats()
+		stats.Enclaves += s.Enclaves
+		stats.Keys += s.Keys
+		stats.Secrets += s.Secrets
+		stats.Policies += s.Policies
+		stats.Identities += s.Identities
+		stats.KeyHits += s.KeyHits
+		stats.KeyMisses += s.KeyMisses
+	}
+	return stats, nil
+}
+
+// FlushCache removes all keys whose names match the glob
+// pattern from the caches of all enclaves. If pattern is
+// empty, it removes all cached entries of all enclaves.
+// Subsequent requests fetch flushed entries from the
+// VaultFS.
+//
+// It returns the number of removed keys.
+func (v *Vault) FlushCache(pattern string) (int, error) {
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return 0, kes.NewError(http.StatusBadRequest, fmt.Sprintf("invalid key pattern '%s': %v", pattern, err))
+		}
+	}
+
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	if v.sealed {
+		return 0, kes.ErrSealed
+	}
+	var n int
+	for _, enclave := range v.enclaves {
+		n += enclave.FlushCache(pattern)
+	}
+	return n, nil
+}
+
+// cachedEnclave returns the cached Enclave with the given
+// name, if any. It returns ErrSealed if the Vault is sealed.
+func (v *Vault) cachedEnclave(name string) (*Enclave, error) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	if v.sealed {
+		return nil, kes.ErrSealed
+	}
+	return v.enclaves[name], nil
+}
+
+// evictEnclave removes the Enclave with the given name
+// from the cache.
+func (v *Vault) evictEnclave(name string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
 	delete(v.enclaves, name)
-	return v.fs.DeleteEnclave(ctx, name)
 }
@@ -0,0 +1,115 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/key"
+)
+
+func TestVaultIssuer(t *testing.T) {
+	const Admin kes.Identity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	ctx := context.Background()
+
+	rootDir := t.TempDir()
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, Admin)
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	issuer, err := NewVault(NewVaultFS(rootDir, rootKey)).Issuer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get issuer: %v", err)
+	}
+
+	// Another vault on the same directory must
+	// load the same issuer.
+	vault := NewVault(NewVaultFS(rootDir, rootKey))
+	loaded, err := vault.Issuer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load issuer: %v", err)
+	}
+	if !bytes.Equal(issuer.Certificate().Raw, loaded.Certificate().Raw) {
+		t.Fatal("Loaded issuer is not equal to generated issuer")
+	}
+
+	// A revoked serial number must be persisted such that
+	// other vaults on the same directory see it, too.
+	if err = vault.RevokeCertificate(ctx, issuer.Certificate().SerialNumber); err != nil {
+		t.Fatalf("Failed to revoke certificate: %v", err)
+	}
+	loaded, err = NewVault(NewVaultFS(rootDir, rootKey)).Issuer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load issuer: %v", err)
+	}
+	if !loaded.IsRevoked(issuer.Certificate()) {
+		t.Fatal("Revoked certificate is not revoked by loaded issuer")
+	}
+
+	if err = vault.Seal(ctx); err != nil {
+		t.Fatalf("Failed to seal vault: %v", err)
+	}
+	if _, err = vault.Issuer(ctx); !errors.Is(err, kes.ErrSealed) {
+		t.Fatalf("Issuer of sealed vault: got '%v' - want '%v'", err, kes.ErrSealed)
+	}
+}
+
+func TestVaultConcurrentEnclaves(t *testing.T) {
+	const (
+		Name  = "my-enclave"
+		Admin = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+	)
+	ctx := context.Background()
+
+	rootKey, err := key.Random(kes.AES256_GCM_SHA256, "")
+	if err != nil {
+		t.Fatalf("Failed to create root key: %v", err)
+	}
+	fs := NewVaultFS(t.TempDir(), rootKey)
+	vault := NewVault(fs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				var err error
+				if (i+j)%2 == 0 {
+					_, err = vault.CreateEnclave(ctx, Name, Admin, nil)
+				} else {
+					err = vault.DeleteEnclave(ctx, Name)
+				}
+				if err != nil && !errors.Is(err, kes.ErrEnclaveExists) && !errors.Is(err, kes.ErrEnclaveNotFound) {
+					t.Errorf("Failed to modify enclave: %v", err)
+					return
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if _, err := vault.GetEnclave(ctx, Name); err != nil && !errors.Is(err, kes.ErrEnclaveNotFound) {
+					t.Errorf("Failed to fetch enclave: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The cached enclave must exist if and only if
+	// the enclave exists in the underlying storage.
+	_, cachedErr := vault.GetEnclave(ctx, Name)
+	_, storedErr := fs.GetEnclave(ctx, Name)
+	if (cachedErr == nil) != (storedErr == nil) {
+		t.Fatalf("Cached enclave differs from stored enclave: got '%v' - want '%v'", cachedErr, storedErr)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package watchdog
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// residentMemory returns the resident set size (RSS)
+// of the process in bytes.
+func residentMemory() (int64, error) {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, err
+	}
+
+	// The second field is the number of resident pages.
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0, errors.New("watchdog: invalid /proc/self/statm")
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * int64(os.Getpagesize()), nil
+}
@@ -0,0 +1,20 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package watchdog
+
+import "runtime"
+
+// residentMemory returns the memory obtained from
+// the OS by the Go runtime. It is an approximation
+// of the resident set size (RSS) on platforms that
+// do not expose the RSS of the process.
+func residentMemory() (int64, error) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return int64(memStats.Sys), nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Target is a location diagnostics dumps are written to.
+type Target interface {
+	// Write writes a dump file with the given
+	// name and content to the target.
+	Write(ctx context.Context, name string, data []byte) error
+
+	// String returns a description of the target,
+	// like its path or URL.
+	String() string
+}
+
+// ParseTarget parses s as dump target. It is either
+// the path of a local directory or an S3 URL of the
+// form:
+//
+//	s3://<BUCKET>[/<PREFIX>][?region=<REGION>&endpoint=<URL>]
+//
+// The S3 credentials are read from the AWS environment
+// variables or, on EC2, from the instance metadata. The
+// endpoint is only required for S3-compatible object
+// stores, like MinIO.
+func ParseTarget(s string) (Target, error) {
+	if s == "" {
+		return nil, errors.New("watchdog: target is empty")
+	}
+	if !strings.HasPrefix(s, "s3://") {
+		return Dir(s), nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("watchdog: invalid S3 URL: %v", err)
+	}
+	if u.Host == "" {
+		return nil, errors.New("watchdog: invalid S3 URL: no bucket specified")
+	}
+	query := u.Query()
+	for key := range query {
+		if key != "region" && key != "endpoint" {
+			return nil, fmt.Errorf("watchdog: invalid S3 URL: unknown parameter '%s'", key)
+		}
+	}
+	region := query.Get("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := query.Get("endpoint")
+
+	config := aws.Config{
+		Region: aws.String(region),
+	}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	session, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("watchdog: failed to create S3 session: %v", err)
+	}
+	return &S3{
+		url:    s,
+		bucket: u.Host,
+		prefix: strings.TrimPrefix(u.Path, "/"),
+		client: s3.New(session),
+	}, nil
+}
+
+// Dir is a local directory diagnostics dumps are
+// written to. It is created, if it does not exist.
+type Dir string
+
+var _ Target = Dir("")
+
+// Write writes a file with the given name and content
+// to the directory. Only the owner can read the file.
+func (d Dir) Write(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(string(d), name), data, 0o600)
+}
+
+// String returns the directory path.
+func (d Dir) String() string { return string(d) }
+
+// S3 is an S3 bucket diagnostics dumps are
+// written to.
+type S3 struct {
+	url    string
+	bucket string
+	prefix string
+	client *s3.S3
+}
+
+var _ Target = (*S3)(nil)
+
+// Write uploads an object with the given name, within
+// the URL prefix, and content to the bucket.
+func (s *S3) Write(ctx context.Context, name string, data []byte) error {
+	key := name
+	if s.prefix != "" {
+		key = strings.TrimSuffix(s.prefix, "/") + "/" + name
+	}
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// String returns the S3 URL.
+func (s *S3) String() string { return s.url }
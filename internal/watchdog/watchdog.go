@@ -0,0 +1,219 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package watchdog monitors the goroutines, the memory
+// usage and the blocked request handlers of the server
+// and writes a diagnostics dump once a threshold has
+// been crossed.
+//
+// A diagnostics dump consists of a goroutine dump, a
+// heap profile and a summary of why the dump has been
+// written. It makes a post-incident analysis of leaks
+// possible even if the server has been restarted since.
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/log"
+)
+
+const (
+	// DefaultInterval is the default interval in
+	// which the watchdog checks the thresholds.
+	DefaultInterval = 10 * time.Second
+
+	// DefaultCooldown is the default min. time between
+	// two diagnostics dumps. It prevents the watchdog
+	// from filling up the target while the server stays
+	// above a threshold.
+	DefaultCooldown = 15 * time.Minute
+)
+
+// Config is a structure for configuring a watchdog.
+// At least one threshold has to be set.
+type Config struct {
+	// Target is where diagnostics dumps are written to.
+	Target Target
+
+	// MaxGoroutines is the number of goroutines above
+	// which a dump is written. If <= 0, the number of
+	// goroutines is not monitored.
+	MaxGoroutines int
+
+	// MaxMemory is the resident set size (RSS), in bytes,
+	// above which a dump is written. If <= 0, the memory
+	// usage is not monitored.
+	MaxMemory int64
+
+	// MaxBlocked is the number of blocked request handlers
+	// at or above which a dump is written. If <= 0, blocked
+	// handlers are not monitored.
+	MaxBlocked int
+
+	// BlockedHandlers returns the number of blocked
+	// request handlers. It must not be nil if MaxBlocked
+	// is set.
+	BlockedHandlers func() int
+
+	// Interval is the interval in which the thresholds
+	// are checked. If <= 0, DefaultInterval is used.
+	Interval time.Duration
+
+	// Cooldown is the min. time between two dumps.
+	// If <= 0, DefaultCooldown is used.
+	Cooldown time.Duration
+}
+
+// Validate returns an error if the config does not
+// contain a target or any threshold, or if a threshold
+// is negative.
+func (c *Config) Validate() error {
+	if c.Target == nil {
+		return errors.New("watchdog: no dump target specified")
+	}
+	if c.MaxGoroutines < 0 || c.MaxMemory < 0 || c.MaxBlocked < 0 {
+		return errors.New("watchdog: thresholds must not be negative")
+	}
+	if c.MaxGoroutines == 0 && c.MaxMemory == 0 && c.MaxBlocked == 0 {
+		return errors.New("watchdog: no threshold specified")
+	}
+	if c.MaxBlocked > 0 && c.BlockedHandlers == nil {
+		return errors.New("watchdog: no blocked handler source specified")
+	}
+	return nil
+}
+
+// Run checks the thresholds in the configured interval
+// until ctx is canceled. Once a threshold is crossed, it
+// writes a diagnostics dump to the target, unless it has
+// written one within the cooldown period.
+//
+// Run logs failures to write a dump to the error log.
+func Run(ctx context.Context, config *Config) {
+	interval, cooldown := config.Interval, config.Cooldown
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastDump time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := readStats(config)
+		reasons := stats.exceeded(config)
+		if len(reasons) == 0 || (!lastDump.IsZero() && time.Since(lastDump) < cooldown) {
+			continue
+		}
+
+		lastDump = time.Now()
+		name, err := dump(ctx, config.Target, lastDump, stats, reasons)
+		if err != nil {
+			log.Printf("watchdog: failed to write diagnostics dump to '%s': %v", config.Target, err)
+			continue
+		}
+		log.Printf("watchdog: %s: wrote diagnostics dump '%s' to '%s'", strings.Join(reasons, ", "), name, config.Target)
+	}
+}
+
+// stats are the monitored runtime statistics.
+type stats struct {
+	Goroutines int
+	Memory     int64 // RSS in bytes or 0 if unknown
+	Blocked    int
+}
+
+func readStats(config *Config) stats {
+	s := stats{
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if config.MaxMemory > 0 {
+		if rss, err := residentMemory(); err == nil {
+			s.Memory = rss
+		}
+	}
+	if config.BlockedHandlers != nil {
+		s.Blocked = config.BlockedHandlers()
+	}
+	return s
+}
+
+// exceeded returns a description of each
+// threshold that has been crossed, if any.
+func (s stats) exceeded(config *Config) []string {
+	var reasons []string
+	if config.MaxGoroutines > 0 && s.Goroutines > config.MaxGoroutines {
+		reasons = append(reasons, fmt.Sprintf("%d goroutines exceed the threshold of %d", s.Goroutines, config.MaxGoroutines))
+	}
+	if config.MaxMemory > 0 && s.Memory > config.MaxMemory {
+		reasons = append(reasons, fmt.Sprintf("memory usage of %d bytes exceeds the threshold of %d bytes", s.Memory, config.MaxMemory))
+	}
+	if config.MaxBlocked > 0 && s.Blocked >= config.MaxBlocked {
+		reasons = append(reasons, fmt.Sprintf("%d blocked request handlers reach the threshold of %d", s.Blocked, config.MaxBlocked))
+	}
+	return reasons
+}
+
+// dump writes a diagnostics dump to the target and
+// returns the common name prefix of the dump files:
+//   - <NAME>-summary.txt: why the dump has been written
+//   - <NAME>-goroutine.txt: the stack traces of all goroutines
+//   - <NAME>-heap.pprof: the heap profile in the pprof format
+func dump(ctx context.Context, target Target, now time.Time, s stats, reasons []string) (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	name := "kes-" + hostname + "-" + now.UTC().Format("20060102T150405Z")
+
+	var summary bytes.Buffer
+	fmt.Fprintf(&summary, "Time:       %s\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&summary, "Host:       %s\n", hostname)
+	fmt.Fprintf(&summary, "Goroutines: %d\n", s.Goroutines)
+	if s.Memory > 0 {
+		fmt.Fprintf(&summary, "Memory:     %d bytes\n", s.Memory)
+	}
+	fmt.Fprintf(&summary, "Blocked:    %d request handlers\n", s.Blocked)
+	fmt.Fprintln(&summary)
+	for _, reason := range reasons {
+		fmt.Fprintln(&summary, reason)
+	}
+
+	var goroutines, heap bytes.Buffer
+	if err = pprof.Lookup("goroutine").WriteTo(&goroutines, 2); err != nil {
+		return "", err
+	}
+	if err = pprof.Lookup("heap").WriteTo(&heap, 0); err != nil {
+		return "", err
+	}
+
+	if err = target.Write(ctx, name+"-summary.txt", summary.Bytes()); err != nil {
+		return "", err
+	}
+	if err = target.Write(ctx, name+"-goroutine.txt", goroutines.Bytes()); err != nil {
+		return "", err
+	}
+	if err = target.Write(ctx, name+"-heap.pprof", heap.Bytes()); err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package watchdog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for i, test := range validateTests {
+		err := test.Config.Validate()
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to validate config: %v", i, err)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for i, test := range parseTargetTests {
+		target, err := ParseTarget(test.Target)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse target: %v", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		switch target := target.(type) {
+		case Dir:
+			if test.Bucket != "" {
+				t.Fatalf("Test %d: got directory '%s' - want S3 bucket '%s'", i, target, test.Bucket)
+			}
+		case *S3:
+			if target.bucket != test.Bucket || target.prefix != test.Prefix {
+				t.Fatalf("Test %d: got bucket '%s' and prefix '%s' - want '%s' and '%s'", i, target.bucket, target.prefix, test.Bucket, test.Prefix)
+			}
+		}
+		if target.String() != test.Target {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, target.String(), test.Target)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := Dir(filepath.Join(t.TempDir(), "dumps"))
+	target := &countTarget{Target: dir}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(ctx, &Config{
+			Target:          target,
+			MaxBlocked:      1,
+			BlockedHandlers: func() int { return 1 },
+			Interval:        10 * time.Millisecond,
+			Cooldown:        time.Hour,
+		})
+	}()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	<-done
+
+	if n := target.Count(); n != 3 {
+		t.Fatalf("Invalid number of dump files: got %d - want %d", n, 3)
+	}
+	files, err := os.ReadDir(string(dir))
+	if err != nil {
+		t.Fatalf("Failed to read dump directory: %v", err)
+	}
+	for _, file := range files {
+		switch {
+		case strings.HasSuffix(file.Name(), "-summary.txt"):
+			summary, err := os.ReadFile(filepath.Join(string(dir), file.Name()))
+			if err != nil {
+				t.Fatalf("Failed to read summary: %v", err)
+			}
+			if !strings.Contains(string(summary), "1 blocked request handlers") {
+				t.Fatalf("Summary does not contain reason:\n%s", summary)
+			}
+		case strings.HasSuffix(file.Name(), "-goroutine.txt"):
+		case strings.HasSuffix(file.Name(), "-heap.pprof"):
+		default:
+			t.Fatalf("Unexpected dump file '%s'", file.Name())
+		}
+		info, err := file.Info()
+		if err != nil {
+			t.Fatalf("Failed to stat dump file '%s': %v", file.Name(), err)
+		}
+		if info.Size() == 0 {
+			t.Fatalf("Dump file '%s' is empty", file.Name())
+		}
+	}
+}
+
+// countTarget is a Target that counts
+// the number of files written.
+type countTarget struct {
+	Target
+
+	lock sync.Mutex
+	n    int
+}
+
+func (t *countTarget) Write(ctx context.Context, name string, data []byte) error {
+	t.lock.Lock()
+	t.n++
+	t.lock.Unlock()
+	return t.Target.Write(ctx, name, data)
+}
+
+func (t *countTarget) Count() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.n
+}
+
+var validateTests = []struct {
+	Config     Config
+	ShouldFail bool
+}{
+	{Config: Config{Target: Dir("dumps"), MaxGoroutines: 10000}},                               // 0
+	{Config: Config{Target: Dir("dumps"), MaxMemory: 1 << 30}},                                 // 1
+	{Config: Config{Target: Dir("dumps"), MaxBlocked: 10, BlockedHandlers: noBlockedHandlers}}, // 2
+	{Config: Config{MaxGoroutines: 10000}, ShouldFail: true},                                   // 3
+	{Config: Config{Target: Dir("dumps")}, ShouldFail: true},                                   // 4
+	{Config: Config{Target: Dir("dumps"), MaxGoroutines: -1}, ShouldFail: true},                // 5
+	{Config: Config{Target: Dir("dumps"), MaxBlocked: 10}, ShouldFail: true},                   // 6
+	{Config: Config{Target: Dir("dumps"), MaxMemory: -1, MaxGoroutines: 1}, ShouldFail: true},  // 7
+}
+
+func noBlockedHandlers() int { return 0 }
+
+var parseTargetTests = []struct {
+	Target     string
+	Bucket     string
+	Prefix     string
+	ShouldFail bool
+}{
+	{Target: "/var/lib/kes/dumps"},                                                          // 0
+	{Target: "s3://diagnostics", Bucket: "diagnostics"},                                     // 1
+	{Target: "s3://diagnostics/kes/prod/", Bucket: "diagnostics", Prefix: "kes/prod/"},      // 2
+	{Target: "s3://diagnostics/kes?region=eu-west-1", Bucket: "diagnostics", Prefix: "kes"}, // 3
+	{Target: "s3://diagnostics?endpoint=https://minio.local:9000", Bucket: "diagnostics"},   // 4
+	{Target: "", ShouldFail: true},                                                          // 5
+	{Target: "s3:///kes", ShouldFail: true},                                                 // 6
+	{Target: "s3://diagnostics?secret=abc", ShouldFail: true},                               // 7
+}
@@ -83,6 +83,11 @@ func (s *String) Value() string { return s.value }
 // Set sets the String value.
 func (s *String) Set(value string) { s.value = value }
 
+// IsZero reports whether the String has no YAML
+// representation. It allows omitting empty Strings
+// when marshaling.
+func (s String) IsZero() bool { return s.raw == "" }
+
 // MarshalYAML returns the String's YAML representation.
 func (s String) MarshalYAML() (any, error) { return s.raw, nil }
 
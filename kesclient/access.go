@@ -0,0 +1,103 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// AccessRequest is a pending request of an identity
+// to be assigned to a policy for a limited duration.
+type AccessRequest struct {
+	ID          string        `json:"id"`
+	Identity    kes.Identity  `json:"identity"`
+	Policy      string        `json:"policy"`
+	Duration    time.Duration `json:"duration"`
+	Reason      string        `json:"reason,omitempty"`
+	RequestedAt time.Time     `json:"requested_at"`
+}
+
+// RequestAccess requests to be assigned to the named policy
+// within the enclave for the given duration. It returns the
+// ID of the access request.
+//
+// The access request has no effect until another identity
+// approves it. Once approved, the policy assignment expires
+// automatically after the requested duration.
+func RequestAccess(ctx context.Context, client *kes.Client, enclave, policy string, duration time.Duration, reason string) (string, error) {
+	type Request struct {
+		Duration time.Duration `json:"duration"`
+		Reason   string        `json:"reason,omitempty"`
+	}
+	type Response struct {
+		ID string `json:"id"`
+	}
+	body, err := json.Marshal(Request{
+		Duration: duration,
+		Reason:   reason,
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/access/request/"+url.PathEscape(policy)+enclaveQuery(enclave), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.KiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return "", err
+	}
+	return response.ID, nil
+}
+
+// ApproveAccess approves the pending access request with
+// the given ID within the enclave. An identity cannot
+// approve its own access request.
+func ApproveAccess(ctx context.Context, client *kes.Client, enclave, id string) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/access/approve/"+url.PathEscape(id)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DenyAccess discards the pending access request with
+// the given ID within the enclave.
+func DenyAccess(ctx context.Context, client *kes.Client, enclave, id string) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/access/deny/"+url.PathEscape(id)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListAccessRequests returns all pending access requests
+// within the enclave.
+func ListAccessRequests(ctx context.Context, client *kes.Client, enclave string) ([]AccessRequest, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/access/list"+enclaveQuery(enclave), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 10 * mem.MiB
+	var requests []AccessRequest
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
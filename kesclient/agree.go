@@ -0,0 +1,91 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Key agreement curves.
+const (
+	X25519 = "X25519"
+	P256   = "P-256"
+)
+
+// KeyAgreementParams are the parameters of a key agreement.
+type KeyAgreementParams struct {
+	// Curve is the key agreement curve, X25519 or P-256.
+	// If empty, the server uses X25519, or P-256 in FIPS
+	// mode.
+	Curve string `json:"curve,omitempty"`
+
+	// Salt is the optional HKDF salt.
+	Salt []byte `json:"salt,omitempty"`
+
+	// Info is the optional HKDF info, e.g. a protocol
+	// or session identifier.
+	Info []byte `json:"info,omitempty"`
+
+	// Length is the length of the derived shared secret.
+	// If 0, it defaults to 32 bytes. At most 64 bytes.
+	Length int `json:"length,omitempty"`
+}
+
+// AgreeKey performs an (EC)DH key agreement between the static
+// key pair of the named key within the enclave and the given
+// public key. It returns the server's public key and the shared
+// secret derived from the agreement via HKDF-SHA256.
+//
+// X25519 public keys are 32 bytes long. P-256 public keys are
+// uncompressed points.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func AgreeKey(ctx context.Context, client *kes.Client, enclave, name string, publicKey []byte, params *KeyAgreementParams) (serverPublicKey, sharedSecret []byte, err error) {
+	type Request struct {
+		KeyAgreementParams
+		PublicKey []byte `json:"public_key,omitempty"`
+	}
+	type Response struct {
+		PublicKey    []byte `json:"public_key"`
+		SharedSecret []byte `json:"shared_secret"`
+	}
+	req := Request{PublicKey: publicKey}
+	if params != nil {
+		req.KeyAgreementParams = *params
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/agree/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, nil, err
+	}
+	return response.PublicKey, response.SharedSecret, nil
+}
+
+// AgreementPublicKey returns the public key of the static key
+// agreement key pair of the named key within the enclave for
+// the given curve. If curve is empty, the server's default
+// curve is used.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func AgreementPublicKey(ctx context.Context, client *kes.Client, enclave, name, curve string) ([]byte, error) {
+	publicKey, _, err := AgreeKey(ctx, client, enclave, name, nil, &KeyAgreementParams{Curve: curve})
+	return publicKey, err
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/kes-go"
+)
+
+// AuditFilter selects the audit events a server sends
+// to a client. An event is selected if it matches all
+// non-empty fields. The zero AuditFilter selects all
+// events.
+type AuditFilter struct {
+	// Identity selects events of requests
+	// sent by this identity.
+	Identity kes.Identity
+
+	// Path selects events of requests whose
+	// API path starts with Path, e.g. "/v1/key/".
+	Path string
+
+	// Status selects events with this response
+	// status code, like "404", or with a status
+	// code of this class, like "4xx".
+	Status string
+
+	// Enclave selects events of requests
+	// to this enclave.
+	Enclave string
+}
+
+// AuditLog returns a stream of the audit events selected
+// by filter. The server applies the filter such that it
+// only sends matching events.
+//
+// The stream does not contain any events that happened
+// in the past.
+func AuditLog(ctx context.Context, client *kes.Client, filter AuditFilter) (*kes.AuditStream, error) {
+	query := url.Values{}
+	if !filter.Identity.IsUnknown() {
+		query.Set("identity", filter.Identity.String())
+	}
+	if filter.Path != "" {
+		query.Set("path", filter.Path)
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Enclave != "" {
+		query.Set("enclave", filter.Enclave)
+	}
+
+	path := "/v1/log/audit"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := send(ctx, client, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return kes.NewAuditStream(resp.Body), nil
+}
+
+// SecurityLog returns a stream of the security-relevant
+// audit events, like auth failures, denied requests, admin
+// actions and requests of the admin identity. The stream
+// contains one JSON-encoded event per line. Each event has
+// a "security" field with the event type.
+//
+// The stream does not contain any events that happened
+// in the past. The caller must close the returned stream.
+func SecurityLog(ctx context.Context, client *kes.Client) (io.ReadCloser, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/log/security/trace", nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,737 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// NewClient returns a new KES client that distributes requests
+// over the given server endpoints using a Balancer. The TLS
+// config is used for connections to all endpoints.
+//
+// The client uses HTTP/2, if supported by the servers, and the
+// default TransportConfig. It connects to hosts with IPv4 and
+// IPv6 addresses using a happy-eyeballs Dialer.
+//
+// Endpoints like 'https+srv://example.com' refer to all servers
+// listed by the DNS SRV record '_kes._tcp.example.com'.
+//
+// If only one endpoint is given, the client sends all requests
+// to it without a Balancer. Otherwise, the client's Endpoints
+// only contain the first endpoint since requests are routed by
+// the Balancer, which is the client's transport.
+func NewClient(endpoints []string, config *tls.Config) (*kes.Client, error) {
+	return NewClientWithTransport(endpoints, config, nil)
+}
+
+// NewClientWithTransport is like NewClient but uses a transport
+// tuned by the given TransportConfig. If transport is nil, the
+// default TransportConfig is used.
+func NewClientWithTransport(endpoints []string, config *tls.Config, transport *TransportConfig) (*kes.Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("kesclient: no server endpoint")
+	}
+	t, err := NewTransport(config, transport)
+	if err != nil {
+		return nil, err
+	}
+	client := kes.NewClientWithConfig(endpoints[0], config)
+	client.HTTPClient.Transport = t
+	if len(endpoints) == 1 && !IsDiscoveryEndpoint(endpoints[0]) {
+		return client, nil
+	}
+
+	balancer, err := NewBalancer(endpoints, t)
+	if err != nil {
+		return nil, err
+	}
+	client.HTTPClient.Transport = balancer
+	return client, nil
+}
+
+// Health check and fail-over parameters of a Balancer.
+const (
+	// minBackoff is the time an endpoint is considered
+	// unhealthy after its first failure. It doubles with
+	// every consecutive failure up to maxBackoff.
+	minBackoff = 1 * time.Second
+	maxBackoff = 1 * time.Minute
+
+	// healthCheckPath is the API path used to probe
+	// whether an endpoint is responsive.
+	healthCheckPath = "/v1/status"
+)
+
+// A Balancer is an http.RoundTripper that distributes requests
+// over multiple KES server endpoints.
+//
+// It sends requests to healthy endpoints in a round-robin
+// fashion. An endpoint becomes unhealthy once it fails to
+// respond or responds with 502 Bad Gateway, 503 Service
+// Unavailable or 504 Gateway Timeout. Unhealthy endpoints
+// are only used again after an exponentially increasing
+// backoff or once an active health check succeeds.
+//
+// Endpoints like 'https+srv://example.com' refer to the servers
+// listed by the DNS SRV record '_kes._tcp.example.com'. The
+// Balancer resolves such endpoints periodically and adds or
+// removes servers whenever the record changes. Hence, servers
+// can be added or removed without reconfiguring the clients.
+//
+// Idempotent requests are retried on the next endpoint when
+// the current one fails. Requests are idempotent if their
+// method is idempotent, they only read or use keys without
+// modifying server state, like encrypt or decrypt, or they
+// carry an Idempotency-Key header. Any other request is only
+// retried if the connection to the endpoint could not be
+// established. Retries are limited by the RetryBudget.
+//
+// Read-only requests, like GET requests or encrypt and decrypt
+// requests, may be hedged: if an endpoint has not responded
+// within the HedgeDelay, the request is sent to another endpoint
+// as well and the first successful response is used.
+type Balancer struct {
+	// HedgeDelay is the time after which a read-only request
+	// that has not been answered yet is sent to a second
+	// endpoint. The slower request gets canceled once one
+	// endpoint responds. Hedging cuts the tail latency caused
+	// by slow endpoints at the cost of additional requests.
+	// If HedgeDelay <= 0, requests are not hedged.
+	//
+	// HedgeDelay must not be modified once the Balancer
+	// is in use.
+	HedgeDelay time.Duration
+
+	// RetryBudget limits the number of retries and hedged
+	// requests. Retries of requests that could not reach an
+	// endpoint because the connection failed are not limited.
+	// If nil, failed requests are retried on all endpoints.
+	//
+	// NewBalancer sets a RetryBudget with DefaultRetryRatio
+	// and DefaultMinRetries. RetryBudget must not be modified
+	// once the Balancer is in use.
+	RetryBudget *RetryBudget
+
+	transport http.RoundTripper
+	static    []*url.URL // Endpoints not discovered via DNS
+	services  []*url.URL // DNS SRV endpoints, like https+srv://example.com
+
+	lock        sync.Mutex
+	next        int
+	endpoints   []*endpoint
+	discovered  map[string][]string // Last endpoints discovered per DNS SRV endpoint
+	discoverAt  time.Time           // Point in time when DNS SRV endpoints are resolved again
+	discovering bool
+}
+
+// NewBalancer returns a new Balancer that sends requests to the
+// given endpoints using the transport. If transport is nil,
+// http.DefaultTransport is used.
+func NewBalancer(endpoints []string, transport http.RoundTripper) (*Balancer, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("kesclient: no server endpoint")
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	b := &Balancer{
+		RetryBudget: NewRetryBudget(DefaultRetryRatio, DefaultMinRetries),
+		transport:   transport,
+		endpoints:   make([]*endpoint, 0, len(endpoints)),
+		discovered:  map[string][]string{},
+	}
+	for _, e := range endpoints {
+		if IsDiscoveryEndpoint(e) {
+			u, err := parseDiscoveryEndpoint(e)
+			if err != nil {
+				return nil, err
+			}
+			b.services = append(b.services, u)
+			continue
+		}
+
+		u, err := url.Parse(e)
+		if err != nil {
+			return nil, fmt.Errorf("kesclient: invalid endpoint '%s': %v", e, err)
+		}
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return nil, fmt.Errorf("kesclient: invalid endpoint '%s': unsupported scheme '%s'", e, u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("kesclient: invalid endpoint '%s': no host", e)
+		}
+		if u.Path != "" && u.Path != "/" {
+			return nil, fmt.Errorf("kesclient: invalid endpoint '%s': must not contain a path", e)
+		}
+		b.static = append(b.static, u)
+		b.endpoints = append(b.endpoints, &endpoint{url: u})
+	}
+
+	if len(b.services) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+		defer cancel()
+
+		if err := b.Discover(ctx); err != nil && len(b.endpoints) == 0 {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Discover resolves the DNS SRV records of all discovery
+// endpoints, like 'https+srv://example.com', and updates
+// the Balancer's endpoints.
+//
+// Endpoints that are still listed keep their health state.
+// If the endpoints change, idle connections are closed such
+// that subsequent requests are distributed over the new set
+// of endpoints. If a lookup fails, the endpoints previously
+// discovered for the record are kept.
+//
+// A Balancer calls Discover automatically once its DNS SRV
+// records are older than one minute.
+func (b *Balancer) Discover(ctx context.Context) error {
+	var (
+		discovered = make(map[string][]string, len(b.services))
+		errs       []error
+	)
+	for _, service := range b.services {
+		endpoints, err := lookupEndpoints(ctx, service)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		discovered[service.String()] = endpoints
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.discovering = false
+	b.discoverAt = time.Now().Add(discoveryInterval)
+	for service, endpoints := range discovered {
+		b.discovered[service] = endpoints
+	}
+
+	var urls []*url.URL
+	urls = append(urls, b.static...)
+	for _, service := range b.services {
+		for _, e := range b.discovered[service.String()] {
+			u, err := url.Parse(e)
+			if err != nil {
+				continue
+			}
+			urls = append(urls, u)
+		}
+	}
+	if b.setEndpoints(urls) {
+		if t, ok := b.transport.(interface{ CloseIdleConnections() }); ok {
+			t.CloseIdleConnections()
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// setEndpoints replaces the Balancer's endpoints with the given
+// URLs and reports whether the endpoints have changed. Endpoints
+// that are already present keep their health state. It must be
+// called while holding the Balancer's lock.
+func (b *Balancer) setEndpoints(urls []*url.URL) bool {
+	current := make(map[string]*endpoint, len(b.endpoints))
+	for _, e := range b.endpoints {
+		current[e.url.String()] = e
+	}
+
+	endpoints := make([]*endpoint, 0, len(urls))
+	seen := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		key := u.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if e, ok := current[key]; ok {
+			endpoints = append(endpoints, e)
+		} else {
+			endpoints = append(endpoints, &endpoint{url: u})
+		}
+	}
+	if len(endpoints) == 0 {
+		return false // Never remove all endpoints
+	}
+
+	changed := len(endpoints) != len(b.endpoints)
+	for i := 0; !changed && i < len(endpoints); i++ {
+		changed = endpoints[i] != b.endpoints[i]
+	}
+	b.endpoints = endpoints
+	return changed
+}
+
+// Transport returns the underlying transport
+// used to send requests to the endpoints.
+func (b *Balancer) Transport() http.RoundTripper { return b.transport }
+
+// EndpointStatus describes the health of a Balancer endpoint.
+type EndpointStatus struct {
+	Endpoint string    // The endpoint URL
+	Healthy  bool      // Whether requests are sent to the endpoint
+	Failures int       // Number of consecutive failures
+	RetryAt  time.Time // Point in time when an unhealthy endpoint is used again
+	Err      error     // The last error, if any
+}
+
+// Status returns the health of all endpoints
+// in the order they have been specified.
+func (b *Balancer) Status() []EndpointStatus {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	status := make([]EndpointStatus, 0, len(b.endpoints))
+	for _, e := range b.endpoints {
+		status = append(status, EndpointStatus{
+			Endpoint: e.url.String(),
+			Healthy:  e.healthy(now),
+			Failures: e.failures,
+			RetryAt:  e.retryAt,
+			Err:      e.err,
+		})
+	}
+	return status
+}
+
+// CheckHealth probes all endpoints concurrently and updates
+// their health. An endpoint is healthy if it responds to a
+// status request with a status code other than 502, 503 or
+// 504. For example, an endpoint that denies the request with
+// 403 Forbidden is considered healthy.
+func (b *Balancer) CheckHealth(ctx context.Context) {
+	b.lock.Lock()
+	endpoints := append([]*endpoint(nil), b.endpoints...)
+	b.lock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url.Scheme+"://"+e.url.Host+healthCheckPath, nil)
+			if err != nil {
+				b.markDown(e, err)
+				return
+			}
+
+			resp, err := b.transport.RoundTrip(req)
+			if err != nil {
+				if ctx.Err() == nil {
+					b.markDown(e, err)
+				}
+				return
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+			resp.Body.Close()
+
+			if unavailable(resp.StatusCode) {
+				b.markDown(e, errors.New(resp.Status))
+			} else {
+				b.markUp(e)
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// HealthCheck calls CheckHealth periodically until
+// the context is canceled. It is usually run in a
+// separate goroutine.
+func (b *Balancer) HealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RoundTrip sends the request to a healthy endpoint. If the
+// endpoint fails and the request is idempotent, RoundTrip
+// retries the request on the remaining endpoints as long as
+// the RetryBudget permits. Read-only requests are hedged if
+// the Balancer has a HedgeDelay.
+func (b *Balancer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		ctx       = req.Context()
+		endpoints = b.order()
+		replay    = req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		retry     = replay && isIdempotent(req)
+	)
+	b.RetryBudget.Deposit()
+	if b.HedgeDelay > 0 && replay && len(endpoints) > 1 && isReadOnly(req) {
+		return b.hedge(req, endpoints)
+	}
+
+	for i, e := range endpoints {
+		r, err := b.newRequest(req, e, i > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := b.transport.RoundTrip(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			b.markDown(e, err)
+			if i == len(endpoints)-1 {
+				return nil, err
+			}
+			if replay && isDialError(err) { // The request has not been sent
+				continue
+			}
+			if !retry || !b.RetryBudget.Withdraw() {
+				return nil, err
+			}
+			continue
+		}
+		if unavailable(resp.StatusCode) {
+			b.markDown(e, errors.New(resp.Status))
+			if retry && i < len(endpoints)-1 && b.RetryBudget.Withdraw() {
+				io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+				resp.Body.Close()
+				continue
+			}
+			return resp, nil
+		}
+		b.markUp(e)
+		return resp, nil
+	}
+	return nil, errors.New("kesclient: no server endpoint")
+}
+
+// hedge sends the request to the first endpoint and, if it
+// has not responded within the HedgeDelay, to the second
+// endpoint as well. It returns the first response that is
+// neither an error nor indicates that the endpoint is
+// unavailable and cancels the other request.
+//
+// Failed requests are retried on the remaining endpoints
+// as long as the RetryBudget permits. Hedged requests
+// consume the RetryBudget, too.
+func (b *Balancer) hedge(req *http.Request, endpoints []*endpoint) (*http.Response, error) {
+	type Result struct {
+		Endpoint *endpoint
+		Response *http.Response
+		Err      error
+	}
+	var (
+		ctx      = req.Context()
+		results  = make(chan Result, len(endpoints))
+		cancels  []context.CancelFunc
+		next     int
+		inFlight int
+	)
+	send := func() error {
+		e := endpoints[next]
+		r, err := b.newRequest(req, e, next > 0)
+		if err != nil {
+			return err
+		}
+		rctx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		next++
+		inFlight++
+
+		go func() {
+			resp, err := b.transport.RoundTrip(r.WithContext(rctx))
+			if err != nil {
+				cancel()
+			} else {
+				resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			}
+			results <- Result{Endpoint: e, Response: resp, Err: err}
+		}()
+		return nil
+	}
+	// abort cancels all requests in flight and closes
+	// their responses once they arrive.
+	abort := func(inFlight int) {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		go func() {
+			for ; inFlight > 0; inFlight-- {
+				if result := <-results; result.Response != nil {
+					result.Response.Body.Close()
+				}
+			}
+		}()
+	}
+
+	if err := send(); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(b.HedgeDelay)
+	defer timer.Stop()
+
+	var (
+		resp *http.Response // The last response of an unavailable endpoint
+		err  error          // The last error
+	)
+	for inFlight > 0 {
+		select {
+		case <-timer.C:
+			if inFlight == 1 && next < len(endpoints) && b.RetryBudget.Withdraw() {
+				if err := send(); err != nil {
+					abort(inFlight)
+					return nil, err
+				}
+			}
+		case result := <-results:
+			inFlight--
+			if result.Err == nil && !unavailable(result.Response.StatusCode) {
+				b.markUp(result.Endpoint)
+				abort(inFlight)
+				if resp != nil {
+					resp.Body.Close()
+				}
+				// Only cancel the requests that lost. The
+				// context of the successful one is canceled
+				// when its response body gets closed.
+				return result.Response, nil
+			}
+
+			if result.Err != nil {
+				if ctx.Err() != nil {
+					abort(inFlight)
+					if resp != nil {
+						resp.Body.Close()
+					}
+					return nil, result.Err
+				}
+				b.markDown(result.Endpoint, result.Err)
+				err = result.Err
+			} else {
+				b.markDown(result.Endpoint, errors.New(result.Response.Status))
+				if resp != nil {
+					io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+					resp.Body.Close()
+				}
+				resp, err = result.Response, nil
+			}
+			if inFlight == 0 && next < len(endpoints) && (isDialError(result.Err) || b.RetryBudget.Withdraw()) {
+				if err := send(); err != nil {
+					if resp != nil {
+						resp.Body.Close()
+					}
+					return nil, err
+				}
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(b.HedgeDelay)
+			}
+		}
+	}
+	if resp != nil {
+		return resp, nil
+	}
+	return nil, err
+}
+
+// newRequest returns a copy of the request that is sent to the
+// endpoint. If replay is true, the request body is replaced by
+// a new copy of the original request body.
+func (b *Balancer) newRequest(req *http.Request, e *endpoint, replay bool) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = e.url.Scheme
+	r.URL.Host = e.url.Host
+	r.Host = ""
+	if replay && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// order returns all endpoints in the order in which a request
+// should try them. Healthy endpoints come first, rotated in a
+// round-robin fashion, followed by the unhealthy endpoints
+// sorted by their backoff.
+func (b *Balancer) order() []*endpoint {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var (
+		now       = time.Now()
+		healthy   = make([]*endpoint, 0, len(b.endpoints))
+		unhealthy []*endpoint
+	)
+	if len(b.services) > 0 && !b.discovering && !now.Before(b.discoverAt) {
+		b.discovering = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+			defer cancel()
+			b.Discover(ctx)
+		}()
+	}
+	for i := range b.endpoints {
+		e := b.endpoints[(b.next+i)%len(b.endpoints)]
+		if e.healthy(now) {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	b.next = (b.next + 1) % len(b.endpoints)
+
+	sort.SliceStable(unhealthy, func(i, j int) bool {
+		return unhealthy[i].retryAt.Before(unhealthy[j].retryAt)
+	})
+	return append(healthy, unhealthy...)
+}
+
+// markDown marks the endpoint as unhealthy
+// and increases its backoff.
+func (b *Balancer) markDown(e *endpoint, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	backoff := minBackoff << e.failures
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	} else {
+		e.failures++
+	}
+	e.retryAt = time.Now().Add(backoff)
+	e.err = err
+}
+
+// markUp marks the endpoint as healthy.
+func (b *Balancer) markUp(e *endpoint) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	e.failures = 0
+	e.retryAt = time.Time{}
+	e.err = nil
+}
+
+// endpoint is a Balancer endpoint and its health.
+// Its fields are guarded by the Balancer's lock.
+type endpoint struct {
+	url *url.URL
+
+	failures int
+	retryAt  time.Time
+	err      error
+}
+
+// healthy reports whether the endpoint should
+// receive requests at the given point in time.
+func (e *endpoint) healthy(now time.Time) bool { return !now.Before(e.retryAt) }
+
+// unavailable reports whether the HTTP status code
+// indicates that the server cannot handle requests.
+func unavailable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// isDialError reports whether err occurred while connecting
+// to the endpoint. Then the request has not been sent and can
+// be retried even if it is not idempotent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isIdempotent reports whether the request can be sent
+// more than once without changing the server state twice.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	for _, prefix := range readOnlyAPIs {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadOnly reports whether the request does not modify the
+// server state. Read-only requests can be sent to multiple
+// endpoints concurrently.
+func isReadOnly(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		for _, prefix := range readOnlyAPIs {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cancelBody is a response body that cancels the
+// request context once it is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// readOnlyAPIs are the API paths of POST requests
+// that use but don't modify server state.
+var readOnlyAPIs = []string{
+	"/v1/key/encrypt/",
+	"/v1/key/decrypt/",
+	"/v1/key/generate/",
+	"/v1/key/bulk/decrypt/",
+}
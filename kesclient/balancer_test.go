@@ -0,0 +1,320 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBalancerRoundRobin(t *testing.T) {
+	var (
+		hits      [3]int32
+		endpoints []string
+	)
+	for i := range hits {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&hits[i], 1)
+		}))
+		defer server.Close()
+		endpoints = append(endpoints, server.URL)
+	}
+
+	balancer, err := NewBalancer(endpoints, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	client := http.Client{Transport: balancer}
+	for i := 0; i < 3*len(endpoints); i++ {
+		resp, err := client.Get("http://kes.local/v1/status")
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	for i := range hits {
+		if n := atomic.LoadInt32(&hits[i]); n != 3 {
+			t.Fatalf("Endpoint %d: got %d requests - want %d", i, n, 3)
+		}
+	}
+}
+
+func TestBalancerFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	down.Close()
+
+	var hits int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer up.Close()
+
+	balancer, err := NewBalancer([]string{down.URL, up.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	client := http.Client{Transport: balancer}
+	for i := 0; i < 4; i++ {
+		resp, err := client.Post("http://kes.local/v1/key/encrypt/my-key", "application/json", strings.NewReader(`{"plaintext":""}`))
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	if n := atomic.LoadInt32(&hits); n != 4 {
+		t.Fatalf("Invalid number of requests: got %d - want %d", n, 4)
+	}
+
+	status := balancer.Status()
+	if status[0].Healthy || status[0].Failures != 1 || status[0].Err == nil {
+		t.Fatalf("Endpoint '%s' should be unhealthy: %+v", down.URL, status[0])
+	}
+	if !status[1].Healthy {
+		t.Fatalf("Endpoint '%s' should be healthy: %+v", up.URL, status[1])
+	}
+}
+
+func TestBalancerNoRetry(t *testing.T) {
+	var hits [2]int32
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits[0], 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits[1], 1)
+	}))
+	defer available.Close()
+
+	balancer, err := NewBalancer([]string{unavailable.URL, available.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	client := http.Client{Transport: balancer}
+	resp, err := client.Post("http://kes.local/v1/key/create/my-key", "", nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got %d - want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if n := atomic.LoadInt32(&hits[1]); n != 0 {
+		t.Fatalf("Non-idempotent request has been retried %d times", n)
+	}
+	if status := balancer.Status(); status[0].Healthy {
+		t.Fatalf("Endpoint '%s' should be unhealthy: %+v", unavailable.URL, status[0])
+	}
+}
+
+func TestBalancerDialError(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	down.Close()
+
+	var hits int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer up.Close()
+
+	balancer, err := NewBalancer([]string{down.URL, up.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	client := http.Client{Transport: balancer}
+	resp, err := client.Post("http://kes.local/v1/key/create/my-key", "", nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("Invalid number of requests: got %d - want %d", n, 1)
+	}
+}
+
+func TestBalancerHedge(t *testing.T) {
+	canceled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body) // The server detects canceled requests once the body has been read
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+
+	balancer, err := NewBalancer([]string{slow.URL, fast.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	balancer.HedgeDelay = 10 * time.Millisecond
+
+	client := http.Client{Transport: balancer}
+	resp, err := client.Post("http://kes.local/v1/key/decrypt/my-key", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "fast" {
+		t.Fatalf("Invalid response: got '%s' - want '%s'", body, "fast")
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Slow request has not been canceled")
+	}
+}
+
+func TestBalancerRetryBudget(t *testing.T) {
+	var hits [2]int32
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits[0], 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits[1], 1)
+	}))
+	defer available.Close()
+
+	balancer, err := NewBalancer([]string{unavailable.URL, available.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	balancer.RetryBudget = NewRetryBudget(0, 0)
+
+	client := http.Client{Transport: balancer}
+	resp, err := client.Get("http://kes.local/v1/status")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Invalid status code: got %d - want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if n := atomic.LoadInt32(&hits[1]); n != 0 {
+		t.Fatalf("Request has been retried %d times despite exhausted retry budget", n)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(0.5, 1)
+	if !budget.Withdraw() {
+		t.Fatal("Retry within the min. retries per second has been rejected")
+	}
+	if budget.Withdraw() {
+		t.Fatal("Retry of exhausted budget has been allowed")
+	}
+
+	budget.Deposit()
+	budget.Deposit()
+	if !budget.Withdraw() {
+		t.Fatal("Retry of deposited budget has been rejected")
+	}
+	if budget.Withdraw() {
+		t.Fatal("Retry of exhausted budget has been allowed")
+	}
+
+	var unlimited *RetryBudget
+	if !unlimited.Withdraw() {
+		t.Fatal("Retry of nil budget has been rejected")
+	}
+}
+
+func TestBalancerCheckHealth(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	down.Close()
+	forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/status" {
+			t.Errorf("Invalid health check path: got '%s' - want '/v1/status'", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer forbidden.Close()
+
+	balancer, err := NewBalancer([]string{down.URL, forbidden.URL}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	balancer.CheckHealth(context.Background())
+
+	status := balancer.Status()
+	if status[0].Healthy {
+		t.Fatalf("Endpoint '%s' should be unhealthy: %+v", down.URL, status[0])
+	}
+	if !status[1].Healthy {
+		t.Fatalf("Endpoint '%s' should be healthy: %+v", forbidden.URL, status[1])
+	}
+}
+
+func TestNewBalancer(t *testing.T) {
+	for i, test := range newBalancerTests {
+		_, err := NewBalancer(test.Endpoints, nil)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to create balancer: %v", i, err)
+		}
+	}
+}
+
+var newBalancerTests = []struct {
+	Endpoints  []string
+	ShouldFail bool
+}{
+	{Endpoints: []string{"https://127.0.0.1:7373", "https://127.0.0.1:7374/"}},          // 0
+	{Endpoints: []string{"https://kes-1.local:7373", "https://kes-2.local:7373"}},       // 1
+	{Endpoints: []string{}, ShouldFail: true},                                           // 2
+	{Endpoints: []string{"https://127.0.0.1:7373", "127.0.0.1:7374"}, ShouldFail: true}, // 3
+	{Endpoints: []string{"https://127.0.0.1:7373", "https://"}, ShouldFail: true},       // 4
+	{Endpoints: []string{"https://127.0.0.1:7373/kes"}, ShouldFail: true},               // 5
+}
+
+func TestIsIdempotent(t *testing.T) {
+	for i, test := range isIdempotentTests {
+		req, err := http.NewRequest(test.Method, "https://127.0.0.1:7373"+test.Path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+		if test.IdempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", test.IdempotencyKey)
+		}
+		if idempotent := isIdempotent(req); idempotent != test.Idempotent {
+			t.Fatalf("Test %d: got %v - want %v", i, idempotent, test.Idempotent)
+		}
+	}
+}
+
+var isIdempotentTests = []struct {
+	Method         string
+	Path           string
+	IdempotencyKey string
+	Idempotent     bool
+}{
+	{Method: http.MethodGet, Path: "/v1/status", Idempotent: true},                                    // 0
+	{Method: http.MethodDelete, Path: "/v1/key/delete/my-key", Idempotent: true},                      // 1
+	{Method: http.MethodPost, Path: "/v1/key/decrypt/my-key", Idempotent: true},                       // 2
+	{Method: http.MethodPost, Path: "/v1/key/create/my-key", Idempotent: false},                       // 3
+	{Method: http.MethodPost, Path: "/v1/key/create/my-key", IdempotencyKey: "abc", Idempotent: true}, // 4
+	{Method: http.MethodPost, Path: "/v1/policy/assign/my-policy", Idempotent: false},                 // 5
+}
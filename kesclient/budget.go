@@ -0,0 +1,105 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"sync"
+	"time"
+)
+
+// Default retry budget parameters of a Balancer.
+const (
+	// DefaultRetryRatio is the fraction of requests
+	// that may be retried or hedged.
+	DefaultRetryRatio = 0.1
+
+	// DefaultMinRetries is the number of retries per
+	// second that are allowed regardless of the ratio.
+	DefaultMinRetries = 10
+)
+
+// maxRetryTokens limits the number of retries that can be
+// accumulated while requests succeed. It bounds the burst
+// of retries once endpoints start to fail.
+const maxRetryTokens = 100
+
+// A RetryBudget limits the number of retries and hedged
+// requests relative to the number of requests sent.
+//
+// Every request adds a fraction of a token to the budget
+// and every retry or hedged request consumes one token.
+// Once the budget is exhausted, failed requests are not
+// retried anymore. Hence, clients don't multiply the load
+// on servers that are overloaded or partially unavailable.
+// A minimum number of retries per second is always allowed
+// such that clients sending few requests can still retry.
+//
+// A RetryBudget is safe for concurrent use and may be
+// shared by multiple Balancers to limit the retries of
+// all of them.
+type RetryBudget struct {
+	ratio      float64
+	minRetries int
+
+	lock    sync.Mutex
+	tokens  float64
+	reserve int       // Retries left in the current second
+	resetAt time.Time // Point in time when the reserve gets refilled
+}
+
+// NewRetryBudget returns a new RetryBudget that allows
+// retrying the given fraction of requests, e.g. 0.1 for
+// 10%, plus minRetries retries per second.
+func NewRetryBudget(ratio float64, minRetries int) *RetryBudget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if minRetries < 0 {
+		minRetries = 0
+	}
+	return &RetryBudget{
+		ratio:      ratio,
+		minRetries: minRetries,
+	}
+}
+
+// Deposit adds the budget of one request. It is
+// a no-op if b is nil.
+func (b *RetryBudget) Deposit() {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.tokens += b.ratio; b.tokens > maxRetryTokens {
+		b.tokens = maxRetryTokens
+	}
+}
+
+// Withdraw consumes the budget of one retry and reports
+// whether the retry is allowed. A nil RetryBudget allows
+// any retry.
+func (b *RetryBudget) Withdraw() bool {
+	if b == nil {
+		return true
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if now := time.Now(); !now.Before(b.resetAt) {
+		b.reserve = b.minRetries
+		b.resetAt = now.Add(1 * time.Second)
+	}
+	if b.reserve > 0 {
+		b.reserve--
+		return true
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// CertificateRequest is a request for a certificate
+// issued by the built-in CA of a KES server.
+type CertificateRequest struct {
+	CSR         []byte        // PEM-encoded certificate request
+	Server      bool          // Issue a server instead of a client certificate
+	DNSNames    []string      // DNS names of a server certificate
+	IPAddresses []string      // IP addresses of a server certificate
+	TTL         time.Duration // Lifetime of the certificate. If zero, the server picks a default
+}
+
+// CACertificate returns the PEM-encoded certificate of the
+// built-in CA of the KES server.
+func CACertificate(ctx context.Context, client *kes.Client) ([]byte, error) {
+	const MaxSize = 64 * mem.KiB
+	return readAll(ctx, client, "/v1/ca/cert", MaxSize)
+}
+
+// RevocationList returns the DER-encoded certificate revocation
+// list (CRL) of the built-in CA of the KES server.
+func RevocationList(ctx context.Context, client *kes.Client) ([]byte, error) {
+	const MaxSize = 16 * mem.MiB
+	return readAll(ctx, client, "/v1/ca/crl", MaxSize)
+}
+
+// IssueCACertificate issues a new server or client certificate
+// signed by the built-in CA of the KES server. Only the system
+// admin can issue certificates.
+func IssueCACertificate(ctx context.Context, client *kes.Client, req *CertificateRequest) (*IssuedCertificate, error) {
+	type Request struct {
+		CSR         string        `json:"csr"`
+		Type        string        `json:"type"`
+		DNSNames    []string      `json:"dns_names,omitempty"`
+		IPAddresses []string      `json:"ip_addresses,omitempty"`
+		TTL         time.Duration `json:"ttl,omitempty"`
+	}
+	certType := "client"
+	if req.Server {
+		certType = "server"
+	}
+	body, err := json.Marshal(Request{
+		CSR:         string(req.CSR),
+		Type:        certType,
+		DNSNames:    req.DNSNames,
+		IPAddresses: req.IPAddresses,
+		TTL:         req.TTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/ca/issue", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 64 * mem.KiB
+	var cert IssuedCertificate
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&cert); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// RevokeCertificate revokes the certificate with the given serial
+// number issued by the built-in CA of the KES server. Only the
+// system admin can revoke certificates.
+func RevokeCertificate(ctx context.Context, client *kes.Client, serialNumber *big.Int) error {
+	resp, err := send(ctx, client, http.MethodPost, fmt.Sprintf("/v1/ca/revoke/%x", serialNumber), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func readAll(ctx context.Context, client *kes.Client, path string, maxSize mem.Size) ([]byte, error) {
+	resp, err := send(ctx, client, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(mem.LimitReader(resp.Body, maxSize))
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// CacheStats describes the state of the caches of a
+// KES server.
+type CacheStats struct {
+	// Enclaves is the number of cached enclaves.
+	// It is zero for KES edge servers.
+	Enclaves int `json:"enclaves,omitempty"`
+
+	Keys       int `json:"keys"`              // Number of cached keys
+	Secrets    int `json:"secrets,omitempty"` // Number of cached secrets
+	Policies   int `json:"policies"`          // Number of cached policies
+	Identities int `json:"identities"`        // Number of cached identities
+
+	// OfflineKeys is the number of keys in the offline
+	// cache of a KES edge server.
+	OfflineKeys int `json:"offline_keys,omitempty"`
+
+	KeyHits   uint64 `json:"key_hits"`   // Number of keys served from the cache
+	KeyMisses uint64 `json:"key_misses"` // Number of keys fetched from the keystore
+}
+
+// ReadCacheStats returns the current CacheStats of the
+// KES server. The enclave selects a key namespace of a
+// KES edge server. A stateful KES server reports the
+// accumulated CacheStats of all its enclaves.
+func ReadCacheStats(ctx context.Context, client *kes.Client, enclave string) (*CacheStats, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/admin/cache/stats"+enclaveQuery(enclave), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var stats CacheStats
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// FlushCache removes all cached keys whose names match
+// the glob pattern from the server's caches. If pattern
+// is empty, it removes all cached keys, policies and
+// identities.
+//
+// The enclave selects a key namespace of a KES edge
+// server. A stateful KES server flushes the caches of
+// all its enclaves.
+//
+// Subsequent requests fetch flushed entries from the
+// server's keystore again. FlushCache returns the number
+// of removed keys.
+func FlushCache(ctx context.Context, client *kes.Client, enclave, pattern string) (int, error) {
+	query := url.Values{}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	if pattern != "" {
+		query.Set("key", pattern)
+	}
+	path := "/v1/admin/cache/flush"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := send(ctx, client, http.MethodPost, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.KiB
+	var response struct {
+		Keys int `json:"keys"`
+	}
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return 0, err
+	}
+	return response.Keys, nil
+}
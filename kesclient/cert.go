@@ -0,0 +1,65 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// ProfileCertificateRequest is a request for a certificate
+// issued for a certificate profile of the client's policy.
+type ProfileCertificateRequest struct {
+	CSR         []byte        // PEM-encoded certificate request
+	DNSNames    []string      // DNS names of the certificate
+	IPAddresses []string      // IP addresses of the certificate
+	TTL         time.Duration // Lifetime of the certificate. If zero, the server picks a default
+}
+
+// ProfileCA returns the PEM-encoded CA certificate derived
+// from the given key.
+func ProfileCA(ctx context.Context, client *kes.Client, enclave, name string) ([]byte, error) {
+	const MaxSize = 64 * mem.KiB
+	return readAll(ctx, client, "/v1/cert/ca/"+name+enclaveQuery(enclave), MaxSize)
+}
+
+// IssueProfileCertificate issues a new certificate for the named
+// certificate profile. The profile is defined by the policy of
+// the requesting identity and constrains the DNS names, IP
+// addresses and lifetime of the certificate.
+func IssueProfileCertificate(ctx context.Context, client *kes.Client, enclave, profile string, req *ProfileCertificateRequest) (*IssuedCertificate, error) {
+	type Request struct {
+		CSR         string        `json:"csr"`
+		DNSNames    []string      `json:"dns_names,omitempty"`
+		IPAddresses []string      `json:"ip_addresses,omitempty"`
+		TTL         time.Duration `json:"ttl,omitempty"`
+	}
+	body, err := json.Marshal(Request{
+		CSR:         string(req.CSR),
+		DNSNames:    req.DNSNames,
+		IPAddresses: req.IPAddresses,
+		TTL:         req.TTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/cert/issue/"+profile+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 64 * mem.KiB
+	var cert IssuedCertificate
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&cert); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Roles of a KES server within a cluster.
+const (
+	RoleStandalone = "standalone" // Server is not part of a cluster
+	RoleLeader     = "leader"     // Server is the cluster leader
+	RoleFollower   = "follower"   // Server forwards write requests to the leader
+)
+
+// NodeStatus describes the state of a KES cluster node.
+type NodeStatus struct {
+	ID       string        `json:"id,omitempty"`       // ID of the node
+	Endpoint string        `json:"endpoint,omitempty"` // Endpoint of the node
+	Role     string        `json:"role,omitempty"`     // Role of the node within the cluster
+	Leader   string        `json:"leader,omitempty"`   // Endpoint of the leader. Empty for the leader itself
+	Version  string        `json:"version,omitempty"`  // KES version of the node
+	Commit   string        `json:"commit,omitempty"`   // Commit ID of the node's KES binary
+	UpTime   time.Duration `json:"uptime,omitempty"`   // Time since the node has been started
+	ModTime  time.Time     `json:"modified_at"`        // Point in time when the node state has been modified most recently
+
+	MinWireVersion int `json:"wire_version_min,omitempty"` // Min. internal API version supported by the node
+	MaxWireVersion int `json:"wire_version_max,omitempty"` // Max. internal API version supported by the node
+
+	// ReplicationLag is the time the node state lags behind
+	// the leader state. It is negative if unknown, e.g.
+	// because the leader cannot be reached.
+	ReplicationLag time.Duration `json:"replication_lag"`
+
+	// Err is the error that occurred when fetching the
+	// status of the node. It is empty if the node has
+	// been reached.
+	Err string `json:"error,omitempty"`
+}
+
+// ClusterStatus returns the status of the KES server within
+// its cluster. Only the system admin can fetch the cluster
+// status.
+func ClusterStatus(ctx context.Context, client *kes.Client) (*NodeStatus, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/cluster/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response nodeStatusResponse
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, err
+	}
+	status := response.NodeStatus()
+	return &status, nil
+}
+
+// ClusterNodes returns the status of all nodes of the KES
+// server's cluster. The first NodeStatus is the status of
+// the server itself. Only the system admin can fetch the
+// cluster nodes.
+//
+// Nodes that cannot be reached by the server are reported
+// with a non-empty Err.
+func ClusterNodes(ctx context.Context, client *kes.Client) ([]NodeStatus, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/cluster/nodes", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 10 * mem.MiB
+	var responses []nodeStatusResponse
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&responses); err != nil {
+		return nil, err
+	}
+	nodes := make([]NodeStatus, 0, len(responses))
+	for _, response := range responses {
+		nodes = append(nodes, response.NodeStatus())
+	}
+	return nodes, nil
+}
+
+// nodeStatusResponse is the JSON representation
+// of a NodeStatus sent by the server.
+type nodeStatusResponse struct {
+	ID             string         `json:"id"`
+	Endpoint       string         `json:"endpoint"`
+	Role           string         `json:"role"`
+	Leader         string         `json:"leader"`
+	Version        string         `json:"version"`
+	Commit         string         `json:"commit"`
+	UpTime         time.Duration  `json:"uptime"`
+	ModTime        time.Time      `json:"modified_at"`
+	MinWireVersion int            `json:"wire_version_min"`
+	MaxWireVersion int            `json:"wire_version_max"`
+	ReplicationLag *time.Duration `json:"replication_lag"`
+	Err            string         `json:"error"`
+}
+
+func (r *nodeStatusResponse) NodeStatus() NodeStatus {
+	lag := time.Duration(-1)
+	if r.ReplicationLag != nil {
+		lag = *r.ReplicationLag
+	}
+	return NodeStatus{
+		ID:             r.ID,
+		Endpoint:       r.Endpoint,
+		Role:           r.Role,
+		Leader:         r.Leader,
+		Version:        r.Version,
+		Commit:         r.Commit,
+		UpTime:         r.UpTime,
+		ModTime:        r.ModTime,
+		MinWireVersion: r.MinWireVersion,
+		MaxWireVersion: r.MaxWireVersion,
+		ReplicationLag: lag,
+		Err:            r.Err,
+	}
+}
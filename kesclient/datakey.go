@@ -0,0 +1,344 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// DataKeyCacheConfig is a structure containing
+// DataKeyCache configuration options.
+type DataKeyCacheConfig struct {
+	// MaxKeys is the maximum number of data keys kept
+	// in the cache, separately for encryption and
+	// decryption. If <= 0, it defaults to 1000.
+	MaxKeys int
+
+	// TTL is the time period a data key is used for
+	// encryption and kept for decryption after it
+	// has been generated or decrypted. If <= 0, it
+	// defaults to 5 minutes.
+	TTL time.Duration
+
+	// MaxUses is the maximum number of messages
+	// encrypted with the same data key. If 0, it
+	// defaults to 1 << 20. It must not exceed
+	// 1 << 32 since every message uses a random
+	// nonce.
+	MaxUses uint64
+}
+
+// NewDataKeyCache returns a new DataKeyCache that generates
+// and decrypts data keys with the given enclave.
+func NewDataKeyCache(enclave *kes.Enclave, config *DataKeyCacheConfig) *DataKeyCache {
+	const (
+		DefaultMaxKeys = 1000
+		DefaultTTL     = 5 * time.Minute
+		DefaultMaxUses = 1 << 20
+		MaxUses        = 1 << 32
+	)
+	c := &DataKeyCache{
+		enclave:   enclave,
+		maxKeys:   DefaultMaxKeys,
+		ttl:       DefaultTTL,
+		maxUses:   DefaultMaxUses,
+		encrypted: map[string]*dataKey{},
+		decrypted: map[string]*dataKey{},
+	}
+	if config != nil {
+		if config.MaxKeys > 0 {
+			c.maxKeys = config.MaxKeys
+		}
+		if config.TTL > 0 {
+			c.ttl = config.TTL
+		}
+		if config.MaxUses > 0 {
+			c.maxUses = config.MaxUses
+		}
+		if c.maxUses > MaxUses {
+			c.maxUses = MaxUses
+		}
+	}
+	return c
+}
+
+// A DataKeyCache performs envelope encryption with data keys
+// generated by a KES server and caches the data keys such
+// that not every Encrypt or Decrypt call requires a request
+// to the server.
+//
+// Encrypt uses the same data key for all messages encrypted
+// with the same KES key and context until the data key expires
+// or has been used MaxUses times. Decrypt caches the decrypted
+// data keys. The cache only keeps the AES-GCM ciphers derived
+// from data keys, not the plaintext keys themselves.
+//
+// The context is cryptographically bound to the data key and
+// the message. Hence, the exact same context must be provided
+// when decrypting a message. Applications that encrypt messages
+// for different tenants or purposes should use distinct contexts
+// to avoid that data keys are shared across them.
+//
+// A DataKeyCache is safe for concurrent use.
+type DataKeyCache struct {
+	enclave *kes.Enclave
+	maxKeys int
+	ttl     time.Duration
+	maxUses uint64
+
+	lock      sync.Mutex
+	encrypted map[string]*dataKey // data keys used for encryption
+	decrypted map[string]*dataKey // data keys used for decryption
+}
+
+// dataKey is a cached data key.
+type dataKey struct {
+	aead      cipher.AEAD
+	header    []byte // Envelope header containing the KES key name and wrapped data key
+	uses      uint64
+	expiresAt time.Time
+}
+
+// Format of an envelope:
+//
+//	magic      [7]byte  "KESENV" || version
+//	name       uint16 length || KES key name
+//	wrapped    uint16 length || wrapped data key
+//	nonce      [12]byte random nonce
+//	ciphertext AES-256-GCM sealed message
+//
+// The associated data of the ciphertext is the header,
+// from magic to the wrapped data key, followed by the
+// context.
+const envelopeVersion = 1
+
+var envelopeMagic = [6]byte{'K', 'E', 'S', 'E', 'N', 'V'}
+
+// Errors returned when decrypting envelopes.
+var (
+	ErrEnvelopeFormat = errors.New("kesclient: invalid envelope")
+	ErrEnvelopeAuth   = errors.New("kesclient: envelope is not authentic")
+)
+
+// Encrypt encrypts the plaintext with a data key generated by
+// the named KES key and returns the resulting envelope. The
+// optional context is bound to the envelope and must be
+// provided again when decrypting it.
+func (c *DataKeyCache) Encrypt(ctx context.Context, name string, plaintext, context []byte) ([]byte, error) {
+	id := cacheID(name, context)
+
+	c.lock.Lock()
+	key, ok := c.encrypted[id]
+	if ok && (key.uses >= c.maxUses || !time.Now().Before(key.expiresAt)) {
+		delete(c.encrypted, id)
+		ok = false
+	}
+	if ok {
+		key.uses++
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		dek, err := c.enclave.GenerateKey(ctx, name, context)
+		if err != nil {
+			return nil, err
+		}
+		header, err := marshalEnvelopeHeader(name, dek.Ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = newDataKey(dek.Plaintext, header, c.ttl); err != nil {
+			return nil, err
+		}
+		key.uses = 1
+
+		c.lock.Lock()
+		c.insert(c.encrypted, id, key)
+		c.lock.Unlock()
+	}
+
+	envelope := make([]byte, 0, len(key.header)+12+len(plaintext)+key.aead.Overhead())
+	envelope = append(envelope, key.header...)
+	envelope = append(envelope, make([]byte, 12)...)
+	nonce := envelope[len(key.header):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	associatedData := append(key.header[:len(key.header):len(key.header)], context...)
+	return key.aead.Seal(envelope, nonce, plaintext, associatedData), nil
+}
+
+// Decrypt decrypts an envelope returned by Encrypt. The
+// context must be equal to the context used to encrypt
+// the envelope.
+//
+// It returns ErrEnvelopeFormat if the envelope is malformed
+// and ErrEnvelopeAuth if the envelope has been modified or
+// the context is not correct.
+func (c *DataKeyCache) Decrypt(ctx context.Context, envelope, context []byte) ([]byte, error) {
+	name, wrappedKey, n, err := parseEnvelopeHeader(envelope)
+	if err != nil {
+		return nil, err
+	}
+	header, body := envelope[:n], envelope[n:]
+	if len(body) < 12 {
+		return nil, ErrEnvelopeFormat
+	}
+	id := cacheID(string(header), context)
+
+	c.lock.Lock()
+	key, ok := c.decrypted[id]
+	if ok && !time.Now().Before(key.expiresAt) {
+		delete(c.decrypted, id)
+		ok = false
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		plaintext, err := c.enclave.Decrypt(ctx, name, wrappedKey, context)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = newDataKey(plaintext, header, c.ttl); err != nil {
+			return nil, err
+		}
+
+		c.lock.Lock()
+		c.insert(c.decrypted, id, key)
+		c.lock.Unlock()
+	}
+
+	associatedData := append(header[:len(header):len(header)], context...)
+	plaintext, err := key.aead.Open(nil, body[:12], body[12:], associatedData)
+	if err != nil {
+		return nil, ErrEnvelopeAuth
+	}
+	return plaintext, nil
+}
+
+// Purge removes all data keys from the cache.
+func (c *DataKeyCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.encrypted = map[string]*dataKey{}
+	c.decrypted = map[string]*dataKey{}
+}
+
+// insert adds the data key to the cache. If the cache is full,
+// it removes all expired data keys. If it is still full, it
+// removes the data key that expires next.
+//
+// The caller must hold the cache lock.
+func (c *DataKeyCache) insert(cache map[string]*dataKey, id string, key *dataKey) {
+	if _, ok := cache[id]; !ok && len(cache) >= c.maxKeys {
+		var (
+			now    = time.Now()
+			oldest string
+		)
+		for k, v := range cache {
+			if !now.Before(v.expiresAt) {
+				delete(cache, k)
+				continue
+			}
+			if oldest == "" || v.expiresAt.Before(cache[oldest].expiresAt) {
+				oldest = k
+			}
+		}
+		if len(cache) >= c.maxKeys && oldest != "" {
+			delete(cache, oldest)
+		}
+	}
+	cache[id] = key
+}
+
+// newDataKey returns a new data key with an AES-256-GCM
+// cipher derived from the 256 bit plaintext key.
+func newDataKey(plaintext, header []byte, ttl time.Duration) (*dataKey, error) {
+	if len(plaintext) != 32 {
+		return nil, errors.New("kesclient: invalid data key length")
+	}
+	block, err := aes.NewCipher(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &dataKey{
+		aead:      aead,
+		header:    header,
+		expiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// cacheID returns a unique cache ID for
+// the given value and context.
+func cacheID(value string, context []byte) string {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(value)))
+	return string(size[:]) + value + string(context)
+}
+
+// marshalEnvelopeHeader returns the envelope header
+// for the KES key name and wrapped data key.
+func marshalEnvelopeHeader(name string, wrappedKey []byte) ([]byte, error) {
+	if len(name) > 0xFFFF || len(wrappedKey) > 0xFFFF {
+		return nil, errors.New("kesclient: envelope header too large")
+	}
+	b := make([]byte, 0, len(envelopeMagic)+1+2+len(name)+2+len(wrappedKey))
+	b = append(b, envelopeMagic[:]...)
+	b = append(b, envelopeVersion)
+	b = append(b, byte(len(name)>>8), byte(len(name)))
+	b = append(b, name...)
+	b = append(b, byte(len(wrappedKey)>>8), byte(len(wrappedKey)))
+	b = append(b, wrappedKey...)
+	return b, nil
+}
+
+// parseEnvelopeHeader parses the envelope header and returns
+// the KES key name, the wrapped data key and the length of
+// the header.
+func parseEnvelopeHeader(envelope []byte) (string, []byte, int, error) {
+	const MagicLen = len(envelopeMagic) + 1
+	if len(envelope) < MagicLen || !bytes.Equal(envelope[:len(envelopeMagic)], envelopeMagic[:]) || envelope[len(envelopeMagic)] != envelopeVersion {
+		return "", nil, 0, ErrEnvelopeFormat
+	}
+
+	n := MagicLen
+	readField := func() ([]byte, bool) {
+		if len(envelope)-n < 2 {
+			return nil, false
+		}
+		size := int(binary.BigEndian.Uint16(envelope[n:]))
+		n += 2
+		if len(envelope)-n < size {
+			return nil, false
+		}
+		field := envelope[n : n+size]
+		n += size
+		return field, true
+	}
+	name, ok := readField()
+	if !ok {
+		return "", nil, 0, ErrEnvelopeFormat
+	}
+	wrappedKey, ok := readField()
+	if !ok {
+		return "", nil, 0, ErrEnvelopeFormat
+	}
+	return string(name), wrappedKey, n, nil
+}
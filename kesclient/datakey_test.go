@@ -0,0 +1,166 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestDataKeyCache(t *testing.T) {
+	server := newDataKeyServer(t)
+	defer server.Close()
+
+	cache := NewDataKeyCache(server.Enclave(), &DataKeyCacheConfig{MaxUses: 2})
+	ctx := context.Background()
+
+	var envelopes [][]byte
+	for i := 0; i < 3; i++ {
+		envelope, err := cache.Encrypt(ctx, "my-key", []byte("Hello World"), []byte("tenant-1"))
+		if err != nil {
+			t.Fatalf("Failed to encrypt message %d: %v", i, err)
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	if n := server.Generated(); n != 2 {
+		t.Fatalf("Invalid number of generated data keys: got %d - want %d", n, 2)
+	}
+	if bytes.Equal(envelopes[0], envelopes[1]) {
+		t.Fatal("Envelopes encrypted with the same data key are equal")
+	}
+
+	for i, envelope := range envelopes {
+		plaintext, err := cache.Decrypt(ctx, envelope, []byte("tenant-1"))
+		if err != nil {
+			t.Fatalf("Failed to decrypt message %d: %v", i, err)
+		}
+		if string(plaintext) != "Hello World" {
+			t.Fatalf("Invalid plaintext of message %d: got '%s' - want '%s'", i, plaintext, "Hello World")
+		}
+	}
+	if n := server.Decrypted(); n != 2 {
+		t.Fatalf("Invalid number of decrypted data keys: got %d - want %d", n, 2)
+	}
+
+	if _, err := cache.Encrypt(ctx, "my-key", []byte("Hello World"), []byte("tenant-2")); err != nil {
+		t.Fatalf("Failed to encrypt message: %v", err)
+	}
+	if n := server.Generated(); n != 3 {
+		t.Fatalf("Data key has been shared across contexts: got %d data keys - want %d", n, 3)
+	}
+}
+
+func TestDataKeyCacheDecrypt(t *testing.T) {
+	server := newDataKeyServer(t)
+	defer server.Close()
+
+	cache := NewDataKeyCache(server.Enclave(), nil)
+	ctx := context.Background()
+
+	envelope, err := cache.Encrypt(ctx, "my-key", []byte("Hello World"), []byte("tenant-1"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt message: %v", err)
+	}
+
+	if _, err = cache.Decrypt(ctx, envelope, []byte("tenant-2")); !errors.Is(err, ErrEnvelopeAuth) {
+		t.Fatalf("Decrypting with wrong context: got '%v' - want '%v'", err, ErrEnvelopeAuth)
+	}
+
+	modified := append([]byte{}, envelope...)
+	modified[len(modified)-1] ^= 1
+	if _, err = cache.Decrypt(ctx, modified, []byte("tenant-1")); !errors.Is(err, ErrEnvelopeAuth) {
+		t.Fatalf("Decrypting modified envelope: got '%v' - want '%v'", err, ErrEnvelopeAuth)
+	}
+
+	for _, n := range []int{0, 5, len(envelopeMagic) + 2, len(envelopeMagic) + 5} {
+		if _, err = cache.Decrypt(ctx, envelope[:n], []byte("tenant-1")); !errors.Is(err, ErrEnvelopeFormat) {
+			t.Fatalf("Decrypting truncated envelope of %d bytes: got '%v' - want '%v'", n, err, ErrEnvelopeFormat)
+		}
+	}
+}
+
+// dataKeyServer is a fake KES server that generates
+// and decrypts data keys.
+type dataKeyServer struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	keys      map[string][]byte // wrapped key -> plaintext key
+	generated int
+	decrypted int
+}
+
+func newDataKeyServer(t *testing.T) *dataKeyServer {
+	s := &dataKeyServer{keys: map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/key/generate/my-key":
+			plaintext, ciphertext := make([]byte, 32), make([]byte, 64)
+			rand.Read(plaintext)
+			rand.Read(ciphertext)
+			s.keys[string(ciphertext)] = plaintext
+			s.generated++
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(struct {
+				Plaintext  []byte `json:"plaintext"`
+				Ciphertext []byte `json:"ciphertext"`
+			}{Plaintext: plaintext, Ciphertext: ciphertext})
+		case "/v1/key/decrypt/my-key":
+			var req struct {
+				Ciphertext []byte `json:"ciphertext"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("Invalid decrypt request: %v", err)
+			}
+			plaintext, ok := s.keys[string(req.Ciphertext)]
+			if !ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"message":"decryption failed: ciphertext is not authentic"}`))
+				return
+			}
+			s.decrypted++
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(struct {
+				Plaintext []byte `json:"plaintext"`
+			}{Plaintext: plaintext})
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func (s *dataKeyServer) Enclave() *kes.Enclave {
+	client := &kes.Client{Endpoints: []string{s.URL}, HTTPClient: *s.Client()}
+	return client.Enclave("")
+}
+
+func (s *dataKeyServer) Generated() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.generated
+}
+
+func (s *dataKeyServer) Decrypted() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.decrypted
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DefaultAttemptDelay is the default time a Dialer waits
+// for a connection attempt before it starts the next one,
+// as recommended by RFC 8305.
+const DefaultAttemptDelay = 250 * time.Millisecond
+
+// A Dialer connects to hosts with multiple IPv4 and IPv6
+// addresses using the Happy Eyeballs algorithm (RFC 8305).
+//
+// It resolves all A and AAAA records of a host and tries to
+// connect to its addresses alternating between IPv6 and IPv4,
+// starting with the preferred address family. It starts the
+// next connection attempt once the previous one has failed
+// or has not completed within the AttemptDelay and uses the
+// first connection that is established. Hence, an address
+// family that is not reachable, e.g. IPv4 at an IPv6-only
+// site, only delays the connection by the AttemptDelay.
+type Dialer struct {
+	// Dialer establishes the connection to each address.
+	// Its Timeout limits each connection attempt.
+	net.Dialer
+
+	// AttemptDelay is the time to wait for a connection
+	// attempt before starting the next attempt. If <= 0,
+	// DefaultAttemptDelay is used.
+	AttemptDelay time.Duration
+
+	lookup func(context.Context, string) ([]net.IPAddr, error) // For testing. If nil, the Dialer's resolver is used.
+}
+
+// DialContext connects to the address on the named network
+// using the provided context. The network must be "tcp",
+// "tcp4" or "tcp6".
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return d.Dialer.DialContext(ctx, network, address)
+	}
+
+	lookup := d.lookup
+	if lookup == nil {
+		resolver := d.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		lookup = resolver.LookupIPAddr
+	}
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs = interleave(filterAddrs(addrs, network))
+	if len(addrs) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+	if len(addrs) == 1 {
+		return d.Dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].String(), port))
+	}
+
+	delay := d.AttemptDelay
+	if delay <= 0 {
+		delay = DefaultAttemptDelay
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type Result struct {
+		Conn net.Conn
+		Err  error
+	}
+	results := make(chan Result, len(addrs))
+	var next, pending int
+	attempt := func() {
+		addr := net.JoinHostPort(addrs[next].String(), port)
+		next++
+		pending++
+		go func() {
+			conn, err := d.Dialer.DialContext(ctx, network, addr)
+			results <- Result{Conn: conn, Err: err}
+		}()
+	}
+
+	var firstErr error
+	for attempt(); pending > 0; {
+		var timeout <-chan time.Time
+		if next < len(addrs) {
+			timeout = time.After(delay)
+		}
+
+		select {
+		case result := <-results:
+			pending--
+			if result.Err == nil {
+				go func(n int) { // Close connections of slower attempts
+					for ; n > 0; n-- {
+						if r := <-results; r.Conn != nil {
+							r.Conn.Close()
+						}
+					}
+				}(pending)
+				return result.Conn, nil
+			}
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			if next < len(addrs) { // Don't wait for the delay if an attempt fails
+				attempt()
+			}
+		case <-timeout:
+			attempt()
+		}
+	}
+	return nil, firstErr
+}
+
+// filterAddrs returns the addresses of the network's IP
+// version.
+func filterAddrs(addrs []net.IPAddr, network string) []net.IPAddr {
+	if network != "tcp4" && network != "tcp6" {
+		return addrs
+	}
+	filtered := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		if isIPv4 := addr.IP.To4() != nil; isIPv4 == (network == "tcp4") {
+			filtered = append(filtered, addr)
+		}
+	}
+	return filtered
+}
+
+// interleave returns the addresses alternating between the
+// address family of the first, preferred, address and the
+// other address family. It preserves the order of addresses
+// within an address family.
+func interleave(addrs []net.IPAddr) []net.IPAddr {
+	if len(addrs) < 2 {
+		return addrs
+	}
+
+	var primary, secondary []net.IPAddr
+	isIPv4 := addrs[0].IP.To4() != nil
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == isIPv4 {
+			primary = append(primary, addr)
+		} else {
+			secondary = append(secondary, addr)
+		}
+	}
+
+	interleaved := make([]net.IPAddr, 0, len(addrs))
+	for len(primary) > 0 || len(secondary) > 0 {
+		if len(primary) > 0 {
+			interleaved = append(interleaved, primary[0])
+			primary = primary[1:]
+		}
+		if len(secondary) > 0 {
+			interleaved = append(interleaved, secondary[0])
+			secondary = secondary[1:]
+		}
+	}
+	return interleaved
+}
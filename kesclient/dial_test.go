@@ -0,0 +1,84 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+var interleaveTests = []struct {
+	Addrs []string
+	Want  []string
+}{
+	{Addrs: []string{}, Want: []string{}},           // 0
+	{Addrs: []string{"::1"}, Want: []string{"::1"}}, // 1
+	{Addrs: []string{"::1", "::2", "10.0.0.1", "10.0.0.2"}, Want: []string{"::1", "10.0.0.1", "::2", "10.0.0.2"}}, // 2
+	{Addrs: []string{"10.0.0.1", "::1", "::2", "::3"}, Want: []string{"10.0.0.1", "::1", "::2", "::3"}},           // 3
+	{Addrs: []string{"10.0.0.1", "10.0.0.2", "::1"}, Want: []string{"10.0.0.1", "::1", "10.0.0.2"}},               // 4
+}
+
+func TestInterleave(t *testing.T) {
+	for i, test := range interleaveTests {
+		addrs := make([]net.IPAddr, 0, len(test.Addrs))
+		for _, a := range test.Addrs {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+		}
+
+		got := interleave(addrs)
+		if len(got) != len(test.Want) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, got, test.Want)
+		}
+		for j := range got {
+			if got[j].String() != test.Want[j] {
+				t.Fatalf("Test %d: got '%v' - want '%v'", i, got, test.Want)
+			}
+		}
+	}
+}
+
+func TestDialerFallback(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	// The preferred IPv6 address is not reachable since nothing
+	// listens on [::1]:port. Hence, the dialer has to fall back
+	// to the IPv4 address.
+	dialer := &Dialer{
+		lookup: func(context.Context, string) ([]net.IPAddr, error) {
+			return []net.IPAddr{
+				{IP: net.ParseIP("::1")},
+				{IP: net.ParseIP("127.0.0.1")},
+			}, nil
+		},
+	}
+	conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("kes.local", port))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if addr := conn.RemoteAddr().String(); addr != listener.Addr().String() {
+		t.Fatalf("Invalid remote address: got '%s' - want '%s'", addr, listener.Addr())
+	}
+
+	if _, err = dialer.DialContext(context.Background(), "tcp6", net.JoinHostPort("kes.local", port)); err == nil {
+		t.Fatalf("Dialing IPv6-only succeeded but should have failed")
+	}
+}
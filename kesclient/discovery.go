@@ -0,0 +1,112 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Endpoints with one of the following schemes refer to a DNS SRV
+// record instead of a single server. For example, the endpoint
+// 'https+srv://example.com' refers to all servers listed by the
+// SRV record '_kes._tcp.example.com'.
+const (
+	srvSchemeHTTPS = "https+srv"
+	srvSchemeHTTP  = "http+srv"
+)
+
+// DNS SRV service and protocol of KES servers.
+const (
+	srvService = "kes"
+	srvProto   = "tcp"
+)
+
+const (
+	// discoveryInterval is the time after which a Balancer
+	// resolves its DNS SRV endpoints again.
+	discoveryInterval = 1 * time.Minute
+
+	// discoveryTimeout limits the time a Balancer waits
+	// for DNS SRV lookups.
+	discoveryTimeout = 10 * time.Second
+)
+
+// lookupSRV looks up DNS SRV records. It can be replaced
+// by tests.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// IsDiscoveryEndpoint reports whether the endpoint refers to a
+// DNS SRV record, like 'https+srv://example.com', instead of a
+// single server.
+func IsDiscoveryEndpoint(endpoint string) bool {
+	scheme, _, ok := strings.Cut(endpoint, "://")
+	return ok && (scheme == srvSchemeHTTPS || scheme == srvSchemeHTTP)
+}
+
+// LookupEndpoints resolves the DNS SRV record of the given
+// discovery endpoint and returns the server endpoints it lists
+// ordered by their priority and weight.
+//
+// For example, the endpoint 'https+srv://example.com' refers to
+// the SRV record '_kes._tcp.example.com'. If it lists the target
+// 'kes-1.example.com.' with port 7373, the returned endpoints
+// contain 'https://kes-1.example.com:7373'.
+func LookupEndpoints(ctx context.Context, endpoint string) ([]string, error) {
+	u, err := parseDiscoveryEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return lookupEndpoints(ctx, u)
+}
+
+// parseDiscoveryEndpoint parses the discovery endpoint.
+func parseDiscoveryEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("kesclient: invalid endpoint '%s': %v", endpoint, err)
+	}
+	if u.Scheme != srvSchemeHTTPS && u.Scheme != srvSchemeHTTP {
+		return nil, fmt.Errorf("kesclient: invalid endpoint '%s': unsupported scheme '%s'", endpoint, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("kesclient: invalid endpoint '%s': no host", endpoint)
+	}
+	if u.Port() != "" {
+		return nil, fmt.Errorf("kesclient: invalid endpoint '%s': must not contain a port", endpoint)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("kesclient: invalid endpoint '%s': must not contain a path", endpoint)
+	}
+	return u, nil
+}
+
+// lookupEndpoints resolves the DNS SRV record of the discovery
+// endpoint u.
+func lookupEndpoints(ctx context.Context, u *url.URL) ([]string, error) {
+	_, records, err := lookupSRV(ctx, srvService, srvProto, u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("kesclient: failed to discover servers of '%s': %v", u, err)
+	}
+
+	scheme := strings.TrimSuffix(u.Scheme, "+srv")
+	endpoints := make([]string, 0, len(records))
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		if target == "" { // RFC 2782: A target of "." means the service is not available
+			continue
+		}
+		endpoints = append(endpoints, scheme+"://"+net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("kesclient: failed to discover servers of '%s': no server found", u)
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+var lookupEndpointsTests = []struct {
+	Endpoint   string
+	Records    []*net.SRV
+	Endpoints  []string
+	ShouldFail bool
+}{
+	{ // 0
+		Endpoint:  "https+srv://example.com",
+		Records:   []*net.SRV{{Target: "kes-1.example.com.", Port: 7373}, {Target: "kes-2.example.com.", Port: 7374}},
+		Endpoints: []string{"https://kes-1.example.com:7373", "https://kes-2.example.com:7374"},
+	},
+	{ // 1
+		Endpoint:  "http+srv://example.com",
+		Records:   []*net.SRV{{Target: "127.0.0.1", Port: 7373}},
+		Endpoints: []string{"http://127.0.0.1:7373"},
+	},
+	{ // 2
+		Endpoint:   "https+srv://example.com",
+		Records:    []*net.SRV{{Target: ".", Port: 0}},
+		ShouldFail: true,
+	},
+	{ // 3
+		Endpoint:   "https+srv://example.com:7373",
+		ShouldFail: true,
+	},
+	{ // 4
+		Endpoint:   "https://example.com",
+		ShouldFail: true,
+	},
+}
+
+func TestLookupEndpoints(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+
+	for i, test := range lookupEndpointsTests {
+		records := test.Records
+		lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			if service != "kes" || proto != "tcp" || name != "example.com" {
+				t.Fatalf("Test %d: invalid SRV lookup: got '_%s._%s.%s' - want '_kes._tcp.example.com'", i, service, proto, name)
+			}
+			return "", records, nil
+		}
+
+		endpoints, err := LookupEndpoints(context.Background(), test.Endpoint)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: lookup should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to lookup endpoints: %v", i, err)
+		}
+		if len(endpoints) != len(test.Endpoints) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, endpoints, test.Endpoints)
+		}
+		for j := range endpoints {
+			if endpoints[j] != test.Endpoints[j] {
+				t.Fatalf("Test %d: got '%v' - want '%v'", i, endpoints, test.Endpoints)
+			}
+		}
+	}
+}
+
+func TestBalancerDiscover(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+
+	var records []*net.SRV
+	for i := 0; i < 3; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer server.Close()
+
+		u, _ := url.Parse(server.URL)
+		port, _ := strconv.Atoi(u.Port())
+		records = append(records, &net.SRV{Target: u.Hostname(), Port: uint16(port)})
+	}
+
+	lookupSRV = func(context.Context, string, string, string) (string, []*net.SRV, error) {
+		return "", records[:2], nil
+	}
+	balancer, err := NewBalancer([]string{"http+srv://example.com"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create balancer: %v", err)
+	}
+	if n := len(balancer.Status()); n != 2 {
+		t.Fatalf("Invalid number of endpoints: got '%d' - want '%d'", n, 2)
+	}
+
+	// Mark the first endpoint as unhealthy. Its health must be
+	// preserved once the endpoints are discovered again.
+	balancer.markDown(balancer.endpoints[0], errors.New("unavailable"))
+
+	lookupSRV = func(context.Context, string, string, string) (string, []*net.SRV, error) {
+		return "", records, nil
+	}
+	if err = balancer.Discover(context.Background()); err != nil {
+		t.Fatalf("Failed to discover endpoints: %v", err)
+	}
+	status := balancer.Status()
+	if len(status) != 3 {
+		t.Fatalf("Invalid number of endpoints: got '%d' - want '%d'", len(status), 3)
+	}
+	if status[0].Healthy {
+		t.Fatalf("Endpoint '%s' should still be unhealthy", status[0].Endpoint)
+	}
+
+	// A failed lookup must not remove any endpoint.
+	lookupSRV = func(context.Context, string, string, string) (string, []*net.SRV, error) {
+		return "", nil, errors.New("no such host")
+	}
+	if err = balancer.Discover(context.Background()); err == nil {
+		t.Fatalf("Discovery should have failed")
+	}
+	if n := len(balancer.Status()); n != 3 {
+		t.Fatalf("Invalid number of endpoints: got '%d' - want '%d'", n, 3)
+	}
+
+	lookupSRV = func(context.Context, string, string, string) (string, []*net.SRV, error) {
+		return "", records[2:], nil
+	}
+	if err = balancer.Discover(context.Background()); err != nil {
+		t.Fatalf("Failed to discover endpoints: %v", err)
+	}
+	client := http.Client{Transport: balancer}
+	resp, err := client.Get("http://kes.local/v1/status")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if status = balancer.Status(); len(status) != 1 || status[0].Endpoint != "http://"+net.JoinHostPort(records[2].Target, strconv.Itoa(int(records[2].Port))) {
+		t.Fatalf("Invalid endpoints: got '%v'", status)
+	}
+}
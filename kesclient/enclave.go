@@ -0,0 +1,150 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// EnclaveInfo describes a KES enclave.
+//
+// In contrast to kes.EnclaveInfo, it contains the
+// enclave admin identity.
+type EnclaveInfo struct {
+	Name      string       `json:"name"`                 // Enclave name
+	Admin     kes.Identity `json:"admin,omitempty"`      // Enclave admin identity. Empty when listing enclaves
+	KeyPolicy *KeyPolicy   `json:"key_policy,omitempty"` // Algorithm policy for new keys. Nil if none
+	CreatedAt time.Time    `json:"created_at"`           // Point in time when the enclave has been created
+	CreatedBy kes.Identity `json:"created_by"`           // Identity that created the enclave
+}
+
+// KeyPolicy restricts the algorithms of new keys within
+// an enclave. Requests to create or import keys that
+// violate the policy are rejected.
+type KeyPolicy struct {
+	// DefaultAlgorithm is the algorithm of new keys created
+	// without an explicit algorithm. If empty, the server
+	// picks an algorithm allowed by the policy.
+	DefaultAlgorithm string `json:"default_algorithm,omitempty"`
+
+	// Algorithms are the algorithms allowed for new keys,
+	// e.g. AES256_GCM_SHA256. If empty, all algorithms
+	// supported by the server are allowed.
+	Algorithms []string `json:"algorithms,omitempty"`
+
+	// MinKeySize is the min. size of new keys in bits.
+	MinKeySize int `json:"min_key_size,omitempty"`
+}
+
+// CreateEnclaveOptions are options for CreateEnclave.
+type CreateEnclaveOptions struct {
+	// KeyPolicy, if not nil, restricts the algorithms
+	// of new keys within the enclave.
+	KeyPolicy *KeyPolicy
+}
+
+// CreateEnclave creates a new enclave with the given name and
+// admin identity. In contrast to kes.Client.CreateEnclave, it
+// can assign a key policy to the enclave. Only the system admin
+// can create enclaves.
+//
+// It returns kes.ErrEnclaveExists if such an enclave already exists.
+func CreateEnclave(ctx context.Context, client *kes.Client, name string, admin kes.Identity, opts *CreateEnclaveOptions) error {
+	type Request struct {
+		Admin     kes.Identity `json:"admin"`
+		KeyPolicy *KeyPolicy   `json:"key_policy,omitempty"`
+	}
+	req := Request{Admin: admin}
+	if opts != nil {
+		req.KeyPolicy = opts.KeyPolicy
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := send(ctx, client, http.MethodPost, "/v1/enclave/create/"+url.PathEscape(name), body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DescribeEnclave returns information about the named enclave,
+// including its admin identity. Only the system admin can
+// describe enclaves.
+//
+// It returns kes.ErrEnclaveNotFound if no such enclave exists.
+func DescribeEnclave(ctx context.Context, client *kes.Client, name string) (*EnclaveInfo, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/enclave/describe/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var info EnclaveInfo
+	if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// send sends a request to the first of the client's endpoints
+// that can be reached and returns the response. It returns an
+// *Error if the server responds with an error status code.
+func send(ctx context.Context, client *kes.Client, method, path string, body []byte) (*http.Response, error) {
+	return sendWithHeader(ctx, client, method, path, nil, body)
+}
+
+// sendWithHeader is like send but adds the given
+// header fields to the request.
+func sendWithHeader(ctx context.Context, client *kes.Client, method, path string, header http.Header, body []byte) (*http.Response, error) {
+	if len(client.Endpoints) == 0 {
+		return nil, errors.New("kesclient: no server endpoint")
+	}
+
+	var err error
+	for _, endpoint := range client.Endpoints {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, method, strings.TrimSuffix(endpoint, "/")+path, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		var resp *http.Response
+		if resp, err = client.HTTPClient.Do(req); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue // Try the next endpoint
+		}
+		if err = ParseErrorResponse(resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+	return nil, err
+}
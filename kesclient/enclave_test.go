@@ -0,0 +1,82 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestListEnclaves(t *testing.T) {
+	for i, test := range listEnclavesTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/enclave/list/*" {
+				t.Errorf("Test %d: got path '%s' - want '/v1/enclave/list/*'", i, r.URL.Path)
+			}
+			w.Header().Set("Content-Type", test.ContentType)
+			w.WriteHeader(test.Status)
+			io.WriteString(w, test.Body)
+		}))
+
+		client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+		var enclaves []EnclaveInfo
+		iter, err := ListEnclaves(context.Background(), client, "*")
+		if err == nil {
+			for iter.Next() {
+				enclaves = append(enclaves, iter.Value())
+			}
+			err = iter.Close()
+		}
+		server.Close()
+
+		if test.Err != nil {
+			if !errors.Is(err, test.Err) {
+				t.Fatalf("Test %d: got error '%v' - want '%v'", i, err, test.Err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: failed to list enclaves: %v", i, err)
+		}
+		if len(enclaves) != len(test.Names) {
+			t.Fatalf("Test %d: got %d enclaves - want %d", i, len(enclaves), len(test.Names))
+		}
+		for j, enclave := range enclaves {
+			if enclave.Name != test.Names[j] {
+				t.Fatalf("Test %d: got enclave '%s' - want '%s'", i, enclave.Name, test.Names[j])
+			}
+		}
+	}
+}
+
+var listEnclavesTests = []struct {
+	Status      int
+	ContentType string
+	Body        string
+	Names       []string
+	Err         error
+}{
+	{ // 0
+		Status: http.StatusOK,
+	},
+	{ // 1
+		Status:      http.StatusOK,
+		ContentType: "application/x-ndjson",
+		Body:        "{\"name\":\"default\"}\n{\"name\":\"tenant-1\"}\n",
+		Names:       []string{"default", "tenant-1"},
+	},
+	{ // 2
+		Status:      http.StatusForbidden,
+		ContentType: "application/json",
+		Body:        `{"message":"not authorized: insufficient permissions","code":"ErrNotAllowed"}`,
+		Err:         kes.ErrNotAllowed,
+	},
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// EnvelopeVersion is the ciphertext envelope version
+// understood by ParseEnvelope.
+const EnvelopeVersion = 1
+
+// An Envelope is a versioned ciphertext envelope returned by
+// EncryptEnvelope and GenerateKeyEnvelope. It is a MessagePack
+// map with the entries "v", "key", "kid", "alg", "iv", "nonce",
+// "aad" and "ct". KES servers accept envelopes as ciphertext
+// when decrypting.
+type Envelope struct {
+	Version    int    // The envelope version
+	Key        string // The name of the key
+	KeyID      string // The key ID of the key material version
+	Algorithm  string // The AEAD algorithm
+	IV         []byte // The IV used to derive the AEAD key
+	Nonce      []byte // The AEAD nonce
+	AADHash    []byte // The SHA-256 hash of the associated data
+	Ciphertext []byte // The AEAD ciphertext, including the tag
+}
+
+// ParseEnvelope parses b as ciphertext envelope. It returns
+// an error if b is not an envelope, e.g. a ciphertext returned
+// by kes.Enclave.Encrypt, or if the envelope version is not
+// supported.
+func ParseEnvelope(b []byte) (*Envelope, error) {
+	value, rest, err := msgp.ReadMapStrIntfBytes(b, nil)
+	if err != nil || len(rest) != 0 {
+		return nil, errors.New("kes: invalid ciphertext envelope")
+	}
+	if v, ok := value["v"].(int64); !ok || v != EnvelopeVersion {
+		return nil, errors.New("kes: unsupported ciphertext envelope version")
+	}
+
+	var (
+		e  = &Envelope{Version: EnvelopeVersion}
+		ok = len(value) == 8
+	)
+	for field, v := range value {
+		var valid bool
+		switch field {
+		case "v":
+			valid = true
+		case "key":
+			e.Key, valid = v.(string)
+		case "kid":
+			e.KeyID, valid = v.(string)
+		case "alg":
+			e.Algorithm, valid = v.(string)
+		case "iv":
+			e.IV, valid = v.([]byte)
+		case "nonce":
+			e.Nonce, valid = v.([]byte)
+		case "aad":
+			e.AADHash, valid = v.([]byte)
+		case "ct":
+			e.Ciphertext, valid = v.([]byte)
+		}
+		ok = ok && valid
+	}
+	if !ok {
+		return nil, errors.New("kes: invalid ciphertext envelope")
+	}
+	return e, nil
+}
+
+// EncryptEnvelope encrypts the plaintext with the named key
+// within the enclave, like kes.Enclave.Encrypt, but returns
+// a versioned ciphertext envelope.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func EncryptEnvelope(ctx context.Context, client *kes.Client, enclave, name string, plaintext, context []byte) ([]byte, error) {
+	type Request struct {
+		Plaintext []byte `json:"plaintext"`
+		Context   []byte `json:"context,omitempty"`
+	}
+	type Response struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	body, err := json.Marshal(Request{Plaintext: plaintext, Context: context})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/encrypt/"+url.PathEscape(name)+envelopeQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Ciphertext, nil
+}
+
+// GenerateKeyEnvelope generates a new data encryption key with
+// the named key within the enclave, like kes.Enclave.GenerateKey,
+// but returns the encrypted data key as ciphertext envelope.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func GenerateKeyEnvelope(ctx context.Context, client *kes.Client, enclave, name string, context []byte) (kes.DEK, error) {
+	type Request struct {
+		Context []byte `json:"context,omitempty"`
+	}
+	type Response struct {
+		Plaintext  []byte `json:"plaintext"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	body, err := json.Marshal(Request{Context: context})
+	if err != nil {
+		return kes.DEK{}, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/generate/"+url.PathEscape(name)+envelopeQuery(enclave), body)
+	if err != nil {
+		return kes.DEK{}, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return kes.DEK{}, err
+	}
+	return kes.DEK{
+		Plaintext:  response.Plaintext,
+		Ciphertext: response.Ciphertext,
+	}, nil
+}
+
+// envelopeQuery returns the URL query selecting the
+// enclave and requesting a ciphertext envelope.
+func envelopeQuery(enclave string) string {
+	query := url.Values{"envelope": {"true"}}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	return "?" + query.Encode()
+}
+
+// A RewrapResult is a ciphertext re-wrapped by RewrapKey.
+type RewrapResult struct {
+	// Ciphertext is the new ciphertext envelope.
+	Ciphertext []byte
+
+	// Format is the format of the original ciphertext,
+	// either "json", "binary" or "envelope".
+	Format string
+}
+
+// RewrapKey decrypts the ciphertexts, which may use any format
+// supported by the server, including legacy formats, with the
+// named key within the enclave and encrypts them again as
+// ciphertext envelopes. The i-th result belongs to the i-th
+// ciphertext. The server re-wraps at most 1000 ciphertexts at
+// once.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func RewrapKey(ctx context.Context, client *kes.Client, enclave, name string, ciphertexts []kes.CCP) ([]RewrapResult, error) {
+	type Request struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Context    []byte `json:"context,omitempty"`
+	}
+	type Response struct {
+		Ciphertext []byte `json:"ciphertext"`
+		Format     string `json:"format"`
+	}
+	requests := make([]Request, 0, len(ciphertexts))
+	for _, c := range ciphertexts {
+		requests = append(requests, Request{Ciphertext: c.Ciphertext, Context: c.Context})
+	}
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/rewrap/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 10 * mem.MiB
+	var responses []Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&responses); err != nil {
+		return nil, err
+	}
+	results := make([]RewrapResult, 0, len(responses))
+	for _, response := range responses {
+		results = append(results, RewrapResult{
+			Ciphertext: response.Ciphertext,
+			Format:     response.Format,
+		})
+	}
+	return results, nil
+}
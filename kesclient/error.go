@@ -0,0 +1,239 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package kesclient provides client-side extensions for the
+// KES Go SDK (github.com/minio/kes-go).
+package kesclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// An ErrorCode is a stable, machine-readable identifier
+// of a KES server API error. The KES server sends it as
+// part of each error response:
+//
+//	{"message":"key does not exist","code":"ErrKeyNotFound"}
+//
+// In contrast to error messages, error codes do not
+// change between KES server versions. Clients should
+// branch on error codes instead of error messages.
+type ErrorCode string
+
+// Error codes of well-known KES server API errors.
+const (
+	CodeSealed           ErrorCode = "ErrSealed"
+	CodeNotAllowed       ErrorCode = "ErrNotAllowed"
+	CodeKeyNotFound      ErrorCode = "ErrKeyNotFound"
+	CodeKeyExists        ErrorCode = "ErrKeyExists"
+	CodeSecretNotFound   ErrorCode = "ErrSecretNotFound"
+	CodeSecretExists     ErrorCode = "ErrSecretExists"
+	CodePolicyNotFound   ErrorCode = "ErrPolicyNotFound"
+	CodeIdentityNotFound ErrorCode = "ErrIdentityNotFound"
+	CodeDecrypt          ErrorCode = "ErrDecrypt"
+	CodeEnclaveExists    ErrorCode = "ErrEnclaveExists"
+	CodeEnclaveNotFound  ErrorCode = "ErrEnclaveNotFound"
+	CodeKeyDisabled      ErrorCode = "ErrKeyDisabled"
+
+	CodeIdempotencyKeyReused ErrorCode = "ErrIdempotencyKeyReused"
+	CodeIdempotencyKeyInUse  ErrorCode = "ErrIdempotencyKeyInUse"
+	CodePreconditionFailed   ErrorCode = "ErrPreconditionFailed"
+	CodeDeadlineExceeded     ErrorCode = "ErrDeadlineExceeded"
+	CodeRequestReplayed      ErrorCode = "ErrRequestReplayed"
+)
+
+// Generic error codes of KES server API errors that are
+// not well-known errors. They correspond to the HTTP
+// response status code.
+const (
+	CodeBadRequest       ErrorCode = "ErrBadRequest"
+	CodeUnauthorized     ErrorCode = "ErrUnauthorized"
+	CodeForbidden        ErrorCode = "ErrForbidden"
+	CodeNotFound         ErrorCode = "ErrNotFound"
+	CodeMethodNotAllowed ErrorCode = "ErrMethodNotAllowed"
+	CodeConflict         ErrorCode = "ErrConflict"
+	CodeRequestTooLarge  ErrorCode = "ErrRequestTooLarge"
+	CodeUpgradeRequired  ErrorCode = "ErrUpgradeRequired"
+	CodeTooManyRequests  ErrorCode = "ErrTooManyRequests"
+	CodeInternal         ErrorCode = "ErrInternal"
+	CodeNotImplemented   ErrorCode = "ErrNotImplemented"
+	CodeBadGateway       ErrorCode = "ErrBadGateway"
+	CodeUnavailable      ErrorCode = "ErrUnavailable"
+	CodeTimeout          ErrorCode = "ErrTimeout"
+)
+
+// wellKnownErrors maps the KES server API errors
+// defined by the SDK to their error codes.
+var wellKnownErrors = []struct {
+	Err  kes.Error
+	Code ErrorCode
+}{
+	{Err: kes.ErrSealed, Code: CodeSealed},
+	{Err: kes.ErrNotAllowed, Code: CodeNotAllowed},
+	{Err: kes.ErrKeyNotFound, Code: CodeKeyNotFound},
+	{Err: kes.ErrKeyExists, Code: CodeKeyExists},
+	{Err: kes.ErrSecretNotFound, Code: CodeSecretNotFound},
+	{Err: kes.ErrSecretExists, Code: CodeSecretExists},
+	{Err: kes.ErrPolicyNotFound, Code: CodePolicyNotFound},
+	{Err: kes.ErrIdentityNotFound, Code: CodeIdentityNotFound},
+	{Err: kes.ErrDecrypt, Code: CodeDecrypt},
+	{Err: kes.ErrEnclaveExists, Code: CodeEnclaveExists},
+	{Err: kes.ErrEnclaveNotFound, Code: CodeEnclaveNotFound},
+}
+
+// Error is a KES server API error with an error code.
+type Error struct {
+	Code    ErrorCode // The error code
+	Status  int       // The HTTP response status code
+	Message string    // The error message
+}
+
+// Error returns the error message.
+func (e *Error) Error() string { return e.Message }
+
+// Is reports whether target is a kes.Error with the same
+// status code and message as e. Hence, errors.Is(err,
+// kes.ErrKeyNotFound) keeps working for an *Error err.
+func (e *Error) Is(target error) bool {
+	if t, ok := target.(kes.Error); ok {
+		return t == kes.NewError(e.Status, e.Message)
+	}
+	return false
+}
+
+// Code returns the ErrorCode of err.
+//
+// It returns the code of an *Error as is. For errors returned
+// by the SDK, it returns the code of the well-known KES server
+// API error or, otherwise, the generic code corresponding to
+// the error's HTTP status code. It returns CodeInternal for
+// errors without a status code and the empty ErrorCode if err
+// is nil.
+func Code(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+
+	var e *Error
+	if errors.As(err, &e) && e.Code != "" {
+		return e.Code
+	}
+	var l *ListError
+	if errors.As(err, &l) && l.Code != "" {
+		return l.Code
+	}
+	for _, known := range wellKnownErrors {
+		if errors.Is(err, known.Err) {
+			return known.Code
+		}
+	}
+
+	var s interface{ Status() int }
+	if errors.As(err, &s) {
+		return StatusCode(s.Status())
+	}
+	return CodeInternal
+}
+
+// StatusCode returns the generic ErrorCode
+// corresponding to the given HTTP status code.
+func StatusCode(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodeRequestTooLarge
+	case http.StatusUpgradeRequired:
+		return CodeUpgradeRequired
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusBadGateway:
+		return CodeBadGateway
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
+
+// ParseErrorResponse returns an *Error containing the status
+// code, error code and error message of the response if it is
+// an error response - i.e. status code >= 400. If the response
+// does not contain an error code, e.g. because the server does
+// not send error codes, the error code is derived from the error
+// message and status code, like Code does.
+//
+// If the response status code is < 400, ParseErrorResponse
+// returns nil and does not read or close the response body.
+// Otherwise, it reads and closes the response body.
+func ParseErrorResponse(resp *http.Response) error {
+	if resp == nil || resp.StatusCode < 400 {
+		return nil
+	}
+	if resp.Body == nil {
+		return newError(resp.StatusCode, "", "")
+	}
+	defer resp.Body.Close()
+
+	const MaxBodySize = 1 * mem.MiB
+	size := mem.Size(resp.ContentLength)
+	if size < 0 || size > MaxBodySize {
+		size = MaxBodySize
+	}
+
+	contentType := strings.TrimSpace(resp.Header.Get("Content-Type"))
+	if strings.HasPrefix(contentType, "application/json") {
+		type Response struct {
+			Message string    `json:"message"`
+			Code    ErrorCode `json:"code"`
+		}
+		var response Response
+		if err := json.NewDecoder(mem.LimitReader(resp.Body, size)).Decode(&response); err != nil {
+			return err
+		}
+		return newError(resp.StatusCode, response.Code, response.Message)
+	}
+
+	var sb strings.Builder
+	if _, err := io.Copy(&sb, mem.LimitReader(resp.Body, size)); err != nil {
+		return err
+	}
+	return newError(resp.StatusCode, "", sb.String())
+}
+
+func newError(status int, code ErrorCode, msg string) *Error {
+	if code == "" {
+		code = Code(kes.NewError(status, msg))
+	}
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: msg,
+	}
+}
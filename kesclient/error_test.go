@@ -0,0 +1,109 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestCode(t *testing.T) {
+	for i, test := range codeTests {
+		if code := Code(test.Err); code != test.Code {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, code, test.Code)
+		}
+	}
+}
+
+func TestParseErrorResponse(t *testing.T) {
+	for i, test := range parseErrorResponseTests {
+		resp := &http.Response{
+			StatusCode:    test.Status,
+			ContentLength: -1,
+			Header:        http.Header{"Content-Type": []string{test.ContentType}},
+			Body:          io.NopCloser(strings.NewReader(test.Body)),
+		}
+		err := ParseErrorResponse(resp)
+		if err == nil {
+			t.Fatalf("Test %d: expected an error", i)
+		}
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Fatalf("Test %d: got error of type %T - want %T", i, err, e)
+		}
+		if e.Code != test.Code {
+			t.Fatalf("Test %d: got code '%s' - want '%s'", i, e.Code, test.Code)
+		}
+		if e.Message != test.Message {
+			t.Fatalf("Test %d: got message '%s' - want '%s'", i, e.Message, test.Message)
+		}
+		if test.Is != nil && !errors.Is(err, test.Is) {
+			t.Fatalf("Test %d: error '%v' does not match '%v'", i, err, test.Is)
+		}
+	}
+}
+
+var codeTests = []struct {
+	Err  error
+	Code ErrorCode
+}{
+	{Err: nil, Code: ""},                             // 0
+	{Err: kes.ErrKeyNotFound, Code: CodeKeyNotFound}, // 1
+	{Err: fmt.Errorf("failed to encrypt: %w", kes.ErrKeyNotFound), Code: CodeKeyNotFound},                   // 2
+	{Err: kes.ErrNotAllowed, Code: CodeNotAllowed},                                                          // 3
+	{Err: kes.NewError(http.StatusBadRequest, "invalid key size"), Code: CodeBadRequest},                    // 4
+	{Err: kes.NewError(http.StatusBadGateway, "bad gateway"), Code: CodeBadGateway},                         // 5
+	{Err: kes.NewError(http.StatusTeapot, "I'm a teapot"), Code: CodeBadRequest},                            // 6
+	{Err: errors.New("internal error"), Code: CodeInternal},                                                 // 7
+	{Err: &Error{Code: "ErrCustom", Status: http.StatusConflict}, Code: "ErrCustom"},                        // 8
+	{Err: kes.NewError(http.StatusUpgradeRequired, "incompatible wire version"), Code: CodeUpgradeRequired}, // 9
+}
+
+var parseErrorResponseTests = []struct {
+	Status      int
+	ContentType string
+	Body        string
+	Code        ErrorCode
+	Message     string
+	Is          error
+}{
+	{ // 0
+		Status:      http.StatusNotFound,
+		ContentType: "application/json",
+		Body:        `{"message":"key does not exist","code":"ErrKeyNotFound"}`,
+		Code:        CodeKeyNotFound,
+		Message:     "key does not exist",
+		Is:          kes.ErrKeyNotFound,
+	},
+	{ // 1 - older servers don't send error codes
+		Status:      http.StatusNotFound,
+		ContentType: "application/json",
+		Body:        `{"message":"key does not exist"}`,
+		Code:        CodeKeyNotFound,
+		Message:     "key does not exist",
+		Is:          kes.ErrKeyNotFound,
+	},
+	{ // 2
+		Status:      http.StatusBadRequest,
+		ContentType: "application/json; charset=utf-8",
+		Body:        `{"message":"invalid key size","code":"ErrBadRequest"}`,
+		Code:        CodeBadRequest,
+		Message:     "invalid key size",
+	},
+	{ // 3
+		Status:      http.StatusServiceUnavailable,
+		ContentType: "text/plain",
+		Body:        "service unavailable",
+		Code:        CodeUnavailable,
+		Message:     "service unavailable",
+	},
+}
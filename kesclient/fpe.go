@@ -0,0 +1,106 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Format-preserving encryption modes.
+const (
+	FF1  = "FF1"
+	FF31 = "FF3-1"
+)
+
+// FPEParams are the parameters of a format-preserving
+// encryption. The same parameters must be used to decrypt
+// a ciphertext.
+type FPEParams struct {
+	// Mode is the FPE mode, FF1 or FF3-1. If empty,
+	// the server uses FF1.
+	Mode string `json:"mode,omitempty"`
+
+	// Alphabet are the characters of plaintexts and
+	// ciphertexts. If empty, the alphabet consists of
+	// the first Radix digits and lower case letters.
+	Alphabet string `json:"alphabet,omitempty"`
+
+	// Radix is the number of characters of the alphabet.
+	// If both, Radix and Alphabet, are empty the server
+	// uses the decimal digits.
+	Radix int `json:"radix,omitempty"`
+
+	// Tweak is an optional, non-secret value that changes
+	// the ciphertext, like a context. FF3-1 tweaks must be
+	// 7 bytes long.
+	Tweak []byte `json:"tweak,omitempty"`
+}
+
+// FPEEncrypt encrypts the plaintext with the named key within
+// the enclave such that the ciphertext has the same length and
+// alphabet as the plaintext.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func FPEEncrypt(ctx context.Context, client *kes.Client, enclave, name, plaintext string, params *FPEParams) (string, error) {
+	type Request struct {
+		FPEParams
+		Plaintext string `json:"plaintext"`
+	}
+	type Response struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	body, err := json.Marshal(Request{FPEParams: *params, Plaintext: plaintext})
+	if err != nil {
+		return "", err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/fpe/encrypt/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return "", err
+	}
+	return response.Ciphertext, nil
+}
+
+// FPEDecrypt decrypts the ciphertext, produced by FPEEncrypt with
+// the same parameters, with the named key within the enclave.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func FPEDecrypt(ctx context.Context, client *kes.Client, enclave, name, ciphertext string, params *FPEParams) (string, error) {
+	type Request struct {
+		FPEParams
+		Ciphertext string `json:"ciphertext"`
+	}
+	type Response struct {
+		Plaintext string `json:"plaintext"`
+	}
+	body, err := json.Marshal(Request{FPEParams: *params, Ciphertext: ciphertext})
+	if err != nil {
+		return "", err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/fpe/decrypt/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return "", err
+	}
+	return response.Plaintext, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// FsckReport is the result of checking the integrity
+// of the state of a stateful KES server.
+type FsckReport struct {
+	Enclaves   int `json:"enclaves"`   // Number of intact enclaves
+	Keys       int `json:"keys"`       // Number of intact keys
+	Secrets    int `json:"secrets"`    // Number of intact secrets
+	Policies   int `json:"policies"`   // Number of intact policies
+	Identities int `json:"identities"` // Number of intact identities
+
+	// Corrupted contains all entries that failed
+	// the integrity check.
+	Corrupted []CorruptEntry `json:"corrupted,omitempty"`
+}
+
+// CorruptEntry describes an entry of a stateful KES
+// server that failed the integrity check.
+type CorruptEntry struct {
+	Enclave string `json:"enclave"` // Enclave containing the entry
+	Kind    string `json:"kind"`    // Kind of entry, e.g. "key" or "policy"
+	Name    string `json:"name"`    // Name of the entry
+	Path    string `json:"path"`    // Path of the entry relative to the server's state directory
+	Err     string `json:"error"`   // Reason why the entry is corrupted
+
+	// Quarantine is the path of the quarantined entry
+	// relative to the server's state directory. It is
+	// empty if the entry has not been quarantined.
+	Quarantine string `json:"quarantine,omitempty"`
+}
+
+// Fsck checks the integrity of all enclaves, keys, secrets,
+// policies and identities of a stateful KES server. If
+// quarantine is true, the server moves corrupted entries
+// into a quarantine directory.
+//
+// The server blocks all write requests while checking its
+// state. Only the system admin can check the server state.
+func Fsck(ctx context.Context, client *kes.Client, quarantine bool) (*FsckReport, error) {
+	path := "/v1/fsck"
+	if quarantine {
+		path += "?quarantine=true"
+	}
+	resp, err := send(ctx, client, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 10 * mem.MiB
+	var report FsckReport
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// IssuedCertificate is a certificate issued by the
+// built-in CA of the KES server.
+type IssuedCertificate struct {
+	Identity     kes.Identity `json:"identity"`      // Identity of the certificate's public key
+	SerialNumber string       `json:"serial_number"` // Hex-encoded serial number of the certificate
+	Certificate  string       `json:"certificate"`   // PEM-encoded certificate
+	CA           string       `json:"ca"`            // PEM-encoded certificate of the issuing CA
+	ExpiresAt    time.Time    `json:"expires_at"`    // Point in time when the certificate expires
+}
+
+// IssueCertificate submits the PEM-encoded certificate request
+// to the server which issues a short-lived client certificate
+// for it. The certificate expires after ttl but not after the
+// policy assignment of the requesting identity. If ttl is zero,
+// the server picks a default.
+//
+// The certificate request must contain the public key of the
+// requesting identity. Hence, the identity does not change and
+// clients can renew their certificates without an external PKI.
+func IssueCertificate(ctx context.Context, client *kes.Client, enclave string, csr []byte, ttl time.Duration) (*IssuedCertificate, error) {
+	type Request struct {
+		CSR string        `json:"csr"`
+		TTL time.Duration `json:"ttl,omitempty"`
+	}
+	body, err := json.Marshal(Request{
+		CSR: string(csr),
+		TTL: ttl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/identity/self/csr"+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 64 * mem.KiB
+	var cert IssuedCertificate
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&cert); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
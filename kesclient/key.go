@@ -0,0 +1,348 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Integrity states of a stored key.
+const (
+	KeyIntact     = "intact"     // Key matches its stored check value
+	KeyCorrupted  = "corrupted"  // Key does not match its stored check value
+	KeyUnverified = "unverified" // Key has been stored without check value
+)
+
+// KeyCheck describes the integrity of a stored key.
+type KeyCheck struct {
+	Name string `json:"name"` // Name of the key
+	ID   string `json:"id"`   // ID of the key
+
+	// CheckValue is the hex-encoded key check value (KCV)
+	// of the key material read from the key store.
+	CheckValue string `json:"check_value"`
+
+	// StoredCheckValue is the hex-encoded key check value
+	// stored along with the key when it has been created.
+	// It is empty for keys stored without check value.
+	StoredCheckValue string `json:"stored_check_value,omitempty"`
+
+	// Status is the integrity state of the key. It is
+	// KeyCorrupted if the check values differ.
+	Status string `json:"status"`
+}
+
+// VerifyKey reads the named key within the enclave from
+// the key store, bypassing any server cache, and compares
+// its key check value with the check value stored along
+// with the key.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func VerifyKey(ctx context.Context, client *kes.Client, enclave, name string) (*KeyCheck, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/key/verify/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var check KeyCheck
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&check); err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// Key algorithms supported by KES servers. In contrast to
+// kes.KeyAlgorithm, they include algorithms that recent KES
+// servers support, like AES256_GCM_SIV.
+const (
+	AES256_GCM_SHA256  = "AES256-GCM_SHA256"
+	XCHACHA20_POLY1305 = "XCHACHA20-POLY1305"
+	AES256_GCM_SIV     = "AES256-GCM-SIV" // Nonce-misuse resistant AES-GCM (RFC 8452)
+)
+
+// KeyInfo describes a cryptographic key. In contrast to
+// kes.KeyInfo, it represents the key algorithm as string
+// such that it can describe keys of any algorithm.
+type KeyInfo struct {
+	Name      string       `json:"name"`                 // Name of the key
+	ID        string       `json:"id,omitempty"`         // ID of the key
+	Algorithm string       `json:"algorithm,omitempty"`  // Algorithm the key can be used with
+	CreatedAt time.Time    `json:"created_at,omitempty"` // Point in time when the key was created
+	CreatedBy kes.Identity `json:"created_by,omitempty"` // Identity that created the key
+	External  *ExternalKey `json:"external,omitempty"`   // External key referenced by the key. Nil if none
+
+	// Fingerprint is a stable, public fingerprint of the key,
+	// e.g. "sha256:<hex>". It changes when the key gets
+	// recreated, even under the same name, but does not
+	// reveal the key material.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// ExternalKey references a key held by an external KMS,
+// like a cloud KMS or an HSM.
+type ExternalKey struct {
+	KMS   string `json:"kms"`    // Name of the external KMS, as configured at the server
+	KeyID string `json:"key_id"` // ID of the key at the external KMS
+}
+
+// DescribeKey returns the KeyInfo for the named key
+// within the enclave.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func DescribeKey(ctx context.Context, client *kes.Client, enclave, name string) (*KeyInfo, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/key/describe/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	info := KeyInfo{Name: name}
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// CreateKeyOptions are optional parameters of CreateKey.
+type CreateKeyOptions struct {
+	// Algorithm is the algorithm of the new key, e.g.
+	// AES256_GCM_SIV. If empty, the server chooses one.
+	Algorithm string
+
+	// Retention makes the key immutable for the retention
+	// period, like CreateImmutableKey.
+	Retention time.Duration
+}
+
+// CreateKey creates a new key with the given name within
+// the enclave. In contrast to kes.Enclave.CreateKey, the
+// key algorithm can be chosen. The server rejects algorithms
+// it does not support, e.g. all but AES256_GCM_SHA256 in
+// FIPS mode.
+//
+// It returns kes.ErrKeyExists if such a key already exists.
+func CreateKey(ctx context.Context, client *kes.Client, enclave, name string, opts *CreateKeyOptions) error {
+	query := url.Values{}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	if opts != nil && opts.Algorithm != "" {
+		query.Set("algorithm", opts.Algorithm)
+	}
+	if opts != nil && opts.Retention > 0 {
+		query.Set("retention", opts.Retention.String())
+	}
+
+	path := "/v1/key/create/" + url.PathEscape(name)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := send(ctx, client, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RegisterKey creates a new key with the given name within
+// the enclave that references the external key. The key
+// material never leaves the external KMS. Instead, the
+// server encrypts data with data keys wrapped by the
+// external key.
+//
+// It returns kes.ErrKeyExists if such a key already exists.
+func RegisterKey(ctx context.Context, client *kes.Client, enclave, name string, ext ExternalKey) error {
+	body, err := json.Marshal(ext)
+	if err != nil {
+		return err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/register/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// CreateImmutableKey creates a new key with the given name
+// within the enclave and makes it immutable for the retention
+// period. An immutable key cannot be deleted, not even by an
+// admin, before the retention period ends.
+//
+// It returns kes.ErrKeyExists if such a key already exists.
+func CreateImmutableKey(ctx context.Context, client *kes.Client, enclave, name string, retention time.Duration) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/create/"+url.PathEscape(name)+retentionQuery(enclave, retention), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// HoldKey places a legal hold on the named key within the
+// enclave. A key under legal hold cannot be deleted until
+// the hold is released.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func HoldKey(ctx context.Context, client *kes.Client, enclave, name string) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/hold/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ReleaseKey releases the legal hold placed on the named
+// key within the enclave, if any.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func ReleaseKey(ctx context.Context, client *kes.Client, enclave, name string) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/release/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DisableKey disables the named key within the enclave.
+// A disabled key cannot be used for cryptographic operations
+// until it is enabled again. Such operations fail with an
+// *Error with CodeKeyDisabled.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func DisableKey(ctx context.Context, client *kes.Client, enclave, name string) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/disable/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SetKeyAllowlist restricts the identities that can use the
+// named key within the enclave for cryptographic operations to
+// the given identities and the identities assigned to one of the
+// given policies. The allowlist is checked in addition to the
+// policy of the requesting identity.
+//
+// Setting an empty allowlist removes any restriction.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func SetKeyAllowlist(ctx context.Context, client *kes.Client, enclave, name string, identities []kes.Identity, policies []string) error {
+	type Request struct {
+		Identities []kes.Identity `json:"identities"`
+		Policies   []string       `json:"policies"`
+	}
+	body, err := json.Marshal(Request{
+		Identities: identities,
+		Policies:   policies,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/allowlist/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// EnableKey enables the named key within the enclave.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func EnableKey(ctx context.Context, client *kes.Client, enclave, name string) error {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/enable/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// DeleteKeysResult is the result of deleting all keys
+// that match a pattern.
+type DeleteKeysResult struct {
+	// DryRun reports whether no key has been deleted
+	// since the result is a preview.
+	DryRun bool `json:"dry_run"`
+
+	// Confirm is the token that has to be passed to
+	// DeleteKeys to delete the keys of a preview.
+	// It is empty if DryRun is false.
+	Confirm string `json:"confirm,omitempty"`
+
+	// Keys contains the per-key results, sorted by name.
+	Keys []DeleteKeyResult `json:"keys"`
+}
+
+// DeleteKeyResult is the result of deleting a single key
+// that matches a pattern.
+type DeleteKeyResult struct {
+	Name        string `json:"name"`                  // Name of the key
+	Fingerprint string `json:"fingerprint,omitempty"` // Fingerprint of the key
+	Deleted     bool   `json:"deleted"`               // Whether the key has been deleted
+
+	// Err describes why the key cannot be or has not
+	// been deleted, e.g. because it is immutable.
+	Err string `json:"error,omitempty"`
+}
+
+// PreviewDeleteKeys returns which keys within the enclave
+// that match the pattern DeleteKeys would delete without
+// deleting any key. The returned result contains the
+// token required to confirm the deletion.
+func PreviewDeleteKeys(ctx context.Context, client *kes.Client, enclave, pattern string) (*DeleteKeysResult, error) {
+	query := url.Values{"dry-run": {"true"}}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	return deleteKeys(ctx, client, "/v1/key/delete-bulk/"+url.PathEscape(pattern)+"?"+query.Encode())
+}
+
+// DeleteKeys deletes all keys within the enclave that match
+// the pattern and returns the per-key results. The confirm
+// token must be the token of a preceding PreviewDeleteKeys
+// call with the same pattern.
+//
+// It returns an *Error with CodePreconditionFailed, without
+// deleting any key, if the keys that match the pattern have
+// changed since the preview, e.g. because a key has been
+// created or recreated.
+func DeleteKeys(ctx context.Context, client *kes.Client, enclave, pattern, confirm string) (*DeleteKeysResult, error) {
+	query := url.Values{"confirm": {confirm}}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	return deleteKeys(ctx, client, "/v1/key/delete-bulk/"+url.PathEscape(pattern)+"?"+query.Encode())
+}
+
+func deleteKeys(ctx context.Context, client *kes.Client, path string) (*DeleteKeysResult, error) {
+	resp, err := send(ctx, client, http.MethodDelete, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var result DeleteKeysResult
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
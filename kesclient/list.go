@@ -0,0 +1,226 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+// A ListError is an error the KES server encountered while
+// sending a list response. The server reports such errors
+// as error record within the response stream once it has
+// sent some results. Hence, the list is incomplete.
+type ListError struct {
+	Code    ErrorCode // The error code. CodeInternal if not sent by the server
+	Message string    // The error message
+}
+
+// Error returns the error message.
+func (e *ListError) Error() string { return e.Message }
+
+// An Iter iterates over a stream of list results sent by a
+// KES server. It fetches results from the server as Next is
+// called, and does not load the entire list into memory.
+//
+// An Iter must be closed to release the underlying
+// connection. Close can be called before the iteration
+// is complete to cancel the list request early.
+//
+//	iter, err := kesclient.ListKeys(ctx, client, "", "*")
+//	if err != nil {
+//		return err
+//	}
+//	defer iter.Close()
+//
+//	for iter.Next() {
+//		fmt.Println(iter.Value().Name)
+//	}
+//	return iter.Close()
+type Iter[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	body   io.ReadCloser
+
+	decoder *json.Decoder
+	decode  func(json.RawMessage) (T, error)
+
+	value  T
+	err    error
+	closed bool
+}
+
+// Next fetches the next result from the stream. It returns
+// false when there are no more results, once it encounters
+// an error or once the Iter has been closed.
+//
+// Once Next returns false, it returns false on any
+// subsequent Next call.
+func (i *Iter[T]) Next() bool {
+	if i.closed || i.err != nil {
+		return false
+	}
+
+	var (
+		raw    json.RawMessage
+		record struct {
+			Err  string    `json:"error"`
+			Code ErrorCode `json:"code"`
+		}
+	)
+	if err := i.decoder.Decode(&raw); err != nil {
+		switch {
+		case errors.Is(err, io.EOF):
+		case i.ctx.Err() != nil:
+			i.err = i.ctx.Err()
+		default:
+			i.err = err
+		}
+		i.release()
+		return false
+	}
+	if err := json.Unmarshal(raw, &record); err != nil {
+		i.err = err
+		i.release()
+		return false
+	}
+	if record.Err != "" {
+		if record.Code == "" {
+			record.Code = CodeInternal
+		}
+		i.err = &ListError{Code: record.Code, Message: record.Err}
+		i.release()
+		return false
+	}
+
+	value, err := i.decode(raw)
+	if err != nil {
+		i.err = err
+		i.release()
+		return false
+	}
+	i.value = value
+	return true
+}
+
+// Value returns the current result. It returns the
+// same result until Next is called again.
+func (i *Iter[T]) Value() T { return i.value }
+
+// Err returns the first error encountered while iterating,
+// if any. It returns a *ListError if the server reported an
+// error within the stream and the context error if the
+// context has been canceled or its deadline exceeded.
+//
+// Err returns nil if the iteration completed or the
+// Iter has been closed early.
+func (i *Iter[T]) Err() error { return i.err }
+
+// Close closes the Iter and releases the underlying
+// connection. It cancels the list request if the
+// iteration is not complete. It returns the same
+// error as Err.
+func (i *Iter[T]) Close() error {
+	i.release()
+	return i.err
+}
+
+// release cancels the list request
+// and closes the response body.
+func (i *Iter[T]) release() {
+	if !i.closed {
+		i.closed = true
+		i.cancel()
+		i.body.Close()
+	}
+}
+
+// list sends a list request to the given API path and
+// returns an Iter that decodes the results with decode.
+func list[T any](ctx context.Context, client *kes.Client, path string, decode func(json.RawMessage) (T, error)) (*Iter[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	resp, err := send(ctx, client, http.MethodGet, path, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Iter[T]{
+		ctx:     ctx,
+		cancel:  cancel,
+		body:    resp.Body,
+		decoder: json.NewDecoder(resp.Body),
+		decode:  decode,
+	}, nil
+}
+
+// listPath returns the API path for listing all elements
+// matching pattern within the enclave.
+func listPath(apiPath, enclave, pattern string) string {
+	if pattern == "" { // The empty pattern never matches anything
+		pattern = "*"
+	}
+	return apiPath + url.PathEscape(pattern) + enclaveQuery(enclave)
+}
+
+// unmarshal is a decode function for types
+// that can be decoded with json.Unmarshal.
+func unmarshal[T any](raw json.RawMessage) (T, error) {
+	var v T
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+// ListKeys returns an Iter over all keys within the enclave
+// whose names match the glob pattern. A pattern ending with
+// a '/', like "tenants/acme/", matches all keys with this
+// prefix.
+func ListKeys(ctx context.Context, client *kes.Client, enclave, pattern string) (*Iter[KeyInfo], error) {
+	return list(ctx, client, listPath("/v1/key/list/", enclave, pattern), unmarshal[KeyInfo])
+}
+
+// ListSecrets returns an Iter over all secrets within the
+// enclave whose names match the glob pattern.
+func ListSecrets(ctx context.Context, client *kes.Client, enclave, pattern string) (*Iter[kes.SecretInfo], error) {
+	return list(ctx, client, listPath("/v1/secret/list/", enclave, pattern), unmarshal[kes.SecretInfo])
+}
+
+// ListPolicies returns an Iter over all policies within the
+// enclave whose names match the glob pattern.
+func ListPolicies(ctx context.Context, client *kes.Client, enclave, pattern string) (*Iter[kes.PolicyInfo], error) {
+	return list(ctx, client, listPath("/v1/policy/list/", enclave, pattern), unmarshal[kes.PolicyInfo])
+}
+
+// ListIdentities returns an Iter over all identities within
+// the enclave that match the glob pattern.
+func ListIdentities(ctx context.Context, client *kes.Client, enclave, pattern string) (*Iter[kes.IdentityInfo], error) {
+	type Response struct {
+		Identity  kes.Identity `json:"identity"`
+		IsAdmin   bool         `json:"admin"`
+		Policy    string       `json:"policy"`
+		CreatedAt time.Time    `json:"created_at"`
+		CreatedBy kes.Identity `json:"created_by"`
+	}
+	return list(ctx, client, listPath("/v1/identity/list/", enclave, pattern), func(raw json.RawMessage) (kes.IdentityInfo, error) {
+		var resp Response
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return kes.IdentityInfo{}, err
+		}
+		return kes.IdentityInfo(resp), nil
+	})
+}
+
+// ListEnclaves returns an Iter over all enclaves whose names
+// match the glob pattern. Only the system admin can list
+// enclaves.
+func ListEnclaves(ctx context.Context, client *kes.Client, pattern string) (*Iter[EnclaveInfo], error) {
+	return list(ctx, client, listPath("/v1/enclave/list/", "", pattern), unmarshal[EnclaveInfo])
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minio/kes-go"
+)
+
+func TestListKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/key/list/my-*" {
+			t.Errorf("got path '%s' - want '/v1/key/list/my-*'", r.URL.Path)
+		}
+		if enclave := r.URL.Query().Get("enclave"); enclave != "tenant-1" {
+			t.Errorf("got enclave '%s' - want 'tenant-1'", enclave)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"name\":\"my-key-1\",\"algorithm\":\"AES256-GCM_SHA256\"}\n{\"name\":\"my-key-2\"}\n")
+		io.WriteString(w, "{\"error\":\"bad gateway: failed to list keys\",\"code\":\"ErrBadGateway\"}\n")
+	}))
+	defer server.Close()
+
+	client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+	iter, err := ListKeys(context.Background(), client, "tenant-1", "my-*")
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	defer iter.Close()
+
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Value().Name)
+	}
+	if len(names) != 2 || names[0] != "my-key-1" || names[1] != "my-key-2" {
+		t.Fatalf("Invalid keys: got '%v' - want '%v'", names, []string{"my-key-1", "my-key-2"})
+	}
+
+	var listErr *ListError
+	if err = iter.Close(); !errors.As(err, &listErr) {
+		t.Fatalf("Invalid error: got '%v' - want a *ListError", err)
+	}
+	if listErr.Message != "bad gateway: failed to list keys" {
+		t.Fatalf("Invalid error message: got '%s' - want '%s'", listErr.Message, "bad gateway: failed to list keys")
+	}
+	if code := Code(err); code != CodeBadGateway {
+		t.Fatalf("Invalid error code: got '%s' - want '%s'", code, CodeBadGateway)
+	}
+	if iter.Next() {
+		t.Fatal("Next returned true after the iteration failed")
+	}
+}
+
+func TestListIdentities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"identity\":\"a\",\"admin\":true}\n{\"identity\":\"b\",\"policy\":\"my-policy\"}\n")
+	}))
+	defer server.Close()
+
+	client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+	iter, err := ListIdentities(context.Background(), client, "", "*")
+	if err != nil {
+		t.Fatalf("Failed to list identities: %v", err)
+	}
+	defer iter.Close()
+
+	var identities []kes.IdentityInfo
+	for iter.Next() {
+		identities = append(identities, iter.Value())
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list identities: %v", err)
+	}
+	if len(identities) != 2 {
+		t.Fatalf("Invalid number of identities: got %d - want %d", len(identities), 2)
+	}
+	if !identities[0].IsAdmin || identities[1].Policy != "my-policy" {
+		t.Fatalf("Invalid identities: got '%v'", identities)
+	}
+}
+
+func TestIterClose(t *testing.T) {
+	canceled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"name\":\"my-policy\"}\n")
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done() // Block until the client cancels the request
+		close(canceled)
+	}))
+	defer server.Close()
+
+	client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+	iter, err := ListPolicies(context.Background(), client, "", "*")
+	if err != nil {
+		t.Fatalf("Failed to list policies: %v", err)
+	}
+	if !iter.Next() || iter.Value().Name != "my-policy" {
+		t.Fatalf("Failed to fetch first policy: %v", iter.Err())
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Closing iterator early returned error: %v", err)
+	}
+	if iter.Next() {
+		t.Fatal("Next returned true after the iterator has been closed")
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Closing the iterator did not cancel the list request")
+	}
+}
+
+func TestIterCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"name\":\"my-secret\"}\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+	iter, err := ListSecrets(ctx, client, "", "*")
+	if err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	defer iter.Close()
+
+	if !iter.Next() {
+		t.Fatalf("Failed to fetch first secret: %v", iter.Err())
+	}
+	cancel()
+	if iter.Next() {
+		t.Fatal("Next returned true after the context has been canceled")
+	}
+	if err = iter.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, context.Canceled)
+	}
+}
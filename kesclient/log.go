@@ -0,0 +1,50 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// LogLevel returns the level of the server's error log,
+// e.g. "INFO". The server only logs entries with a level
+// equal to or higher than its log level.
+func LogLevel(ctx context.Context, client *kes.Client) (string, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/log/level", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		Level string `json:"level"`
+	}
+	const MaxSize = 1 * mem.KiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return "", err
+	}
+	return response.Level, nil
+}
+
+// SetLogLevel sets the level of the server's error log.
+// Valid levels are "DEBUG", "INFO", "WARN" and "ERROR".
+//
+// The level is not persisted. It is reset when the server
+// restarts or reloads its configuration.
+func SetLogLevel(ctx context.Context, client *kes.Client, level string) error {
+	resp, err := send(ctx, client, http.MethodPut, "/v1/log/level/set/"+url.PathEscape(level), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
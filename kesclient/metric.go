@@ -0,0 +1,46 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// IdentityStat contains the request statistics of
+// an identity.
+type IdentityStat struct {
+	Identity kes.Identity `json:"identity"`
+	Requests uint64       `json:"requests"` // Number of requests
+	Errors   uint64       `json:"errors"`   // Number of requests that failed with a 4xx status code
+	Failures uint64       `json:"failures"` // Number of requests that failed with a 5xx status code
+}
+
+// TopIdentities returns the request statistics of up to n
+// identities that sent the most requests to the server, in
+// descending order.
+//
+// The server tracks a bounded number of identities. Hence,
+// the statistics are approximate once many distinct
+// identities have sent requests.
+func TopIdentities(ctx context.Context, client *kes.Client, n int) ([]IdentityStat, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/metrics/identity?n="+strconv.Itoa(n), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var stats []IdentityStat
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
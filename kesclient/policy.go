@@ -0,0 +1,197 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// A Precondition makes a write request conditional on
+// the current state of the resource. The server rejects
+// the request with CodePreconditionFailed if the
+// precondition does not hold.
+type Precondition struct {
+	// IfMatch is the entity tag the current resource
+	// must have, or "*" if the resource must exist.
+	IfMatch string
+
+	// IfNoneMatch is "*" if the resource must
+	// not exist.
+	IfNoneMatch string
+}
+
+// Policy is a KES policy. In contrast to kes.Policy, it
+// contains the certificate and token profiles and the random
+// bytes limit of the policy.
+type Policy struct {
+	Allow []string `json:"allow,omitempty"` // Set of allow patterns
+	Deny  []string `json:"deny,omitempty"`  // Set of deny patterns
+
+	// Certificates are the certificate profiles, by name,
+	// of identities assigned to the policy.
+	Certificates map[string]CertificateProfile `json:"certificates,omitempty"`
+
+	// MaxRandomBytes is the max. number of random bytes
+	// identities assigned to the policy can request at
+	// once. If 0, the server default applies.
+	MaxRandomBytes int `json:"max_random_bytes,omitempty"`
+
+	// Tokens constrains the JWTs identities assigned to
+	// the policy can sign. If nil, tokens must not contain
+	// an audience or custom claims.
+	Tokens *TokenProfile `json:"tokens,omitempty"`
+
+	// MaxSSHTTL is the max. lifetime of SSH certificates
+	// identities assigned to the policy can obtain, like
+	// "8h". If empty, the server's max. lifetime applies.
+	MaxSSHTTL string `json:"max_ssh_ttl,omitempty"`
+
+	CreatedAt time.Time    `json:"created_at,omitempty"`
+	CreatedBy kes.Identity `json:"created_by,omitempty"`
+}
+
+// A CertificateProfile constrains the X.509 certificates that
+// identities can obtain from an enclave key acting as CA.
+type CertificateProfile struct {
+	Key      string   `json:"key" yaml:"key"`                                 // Name of the CA key
+	DNSNames []string `json:"dns_names,omitempty" yaml:"dns_names,omitempty"` // Allowed DNS names, like "*.example.com"
+	IPRanges []string `json:"ip_ranges,omitempty" yaml:"ip_ranges,omitempty"` // CIDR ranges of allowed IP addresses
+	Usage    []string `json:"usage,omitempty" yaml:"usage,omitempty"`         // "server" and/or "client"
+	MaxTTL   string   `json:"max_ttl,omitempty" yaml:"max_ttl,omitempty"`     // Max. lifetime, like "72h"
+}
+
+// A TokenProfile constrains the JWTs that identities can
+// sign with an enclave key designated as token signing key.
+type TokenProfile struct {
+	Audiences []string `json:"audiences,omitempty" yaml:"audiences,omitempty"` // Allowed 'aud' claim values
+	Claims    []string `json:"claims,omitempty" yaml:"claims,omitempty"`       // Allowed custom claim names
+	MaxTTL    string   `json:"max_ttl,omitempty" yaml:"max_ttl,omitempty"`     // Max. lifetime, like "1h"
+}
+
+// ReadPolicy returns the named policy within the enclave
+// and its entity tag. The entity tag can be passed to
+// WritePolicy to detect concurrent modifications.
+//
+// It returns kes.ErrPolicyNotFound if no such policy exists.
+func ReadPolicy(ctx context.Context, client *kes.Client, enclave, name string) (*Policy, string, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/policy/read/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var policy Policy
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&policy); err != nil {
+		return nil, "", err
+	}
+	return &policy, resp.Header.Get("ETag"), nil
+}
+
+// WritePolicy creates or replaces the named policy within
+// the enclave if the precondition holds and returns the
+// entity tag of the new policy. The zero Precondition
+// always holds.
+func WritePolicy(ctx context.Context, client *kes.Client, enclave, name string, policy *Policy, cond Precondition) (string, error) {
+	return writePolicy(ctx, client, "/v1/policy/write/"+url.PathEscape(name)+enclaveQuery(enclave), policy, cond)
+}
+
+// WriteImmutablePolicy creates the named policy within the
+// enclave and makes it immutable for the retention period.
+// An immutable policy cannot be replaced or deleted, not
+// even by an admin, before the retention period ends.
+func WriteImmutablePolicy(ctx context.Context, client *kes.Client, enclave, name string, policy *Policy, retention time.Duration) error {
+	_, err := writePolicy(ctx, client, "/v1/policy/write/"+url.PathEscape(name)+retentionQuery(enclave, retention), policy, Precondition{})
+	return err
+}
+
+// AssignPolicyUntil assigns the named policy within the enclave
+// to the identity until the given point in time. Once the
+// assignment has expired, the identity is no longer allowed
+// to perform any operation.
+func AssignPolicyUntil(ctx context.Context, client *kes.Client, enclave, policy string, identity kes.Identity, expiresAt time.Time) error {
+	type Request struct {
+		Identity  kes.Identity `json:"identity"`
+		ExpiresAt time.Time    `json:"expires_at"`
+	}
+	body, err := json.Marshal(Request{
+		Identity:  identity,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/policy/assign/"+url.PathEscape(policy)+enclaveQuery(enclave), body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func writePolicy(ctx context.Context, client *kes.Client, path string, policy *Policy, cond Precondition) (string, error) {
+	type Request struct {
+		Allow []string `json:"allow"`
+		Deny  []string `json:"deny"`
+
+		Certificates   map[string]CertificateProfile `json:"certificates,omitempty"`
+		MaxRandomBytes int                           `json:"max_random_bytes,omitempty"`
+		Tokens         *TokenProfile                 `json:"tokens,omitempty"`
+		MaxSSHTTL      string                        `json:"max_ssh_ttl,omitempty"`
+	}
+	body, err := json.Marshal(Request{
+		Allow:          policy.Allow,
+		Deny:           policy.Deny,
+		Certificates:   policy.Certificates,
+		MaxRandomBytes: policy.MaxRandomBytes,
+		Tokens:         policy.Tokens,
+		MaxSSHTTL:      policy.MaxSSHTTL,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	header := http.Header{}
+	if cond.IfMatch != "" {
+		header.Set("If-Match", cond.IfMatch)
+	}
+	if cond.IfNoneMatch != "" {
+		header.Set("If-None-Match", cond.IfNoneMatch)
+	}
+	resp, err := sendWithHeader(ctx, client, http.MethodPost, path, header, body)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// enclaveQuery returns the URL query selecting
+// the enclave, or the empty string for the empty
+// enclave name.
+func enclaveQuery(enclave string) string {
+	if enclave == "" {
+		return ""
+	}
+	return "?" + url.Values{"enclave": {enclave}}.Encode()
+}
+
+// retentionQuery returns the URL query selecting
+// the enclave and specifying the retention period
+// of an immutable key or policy.
+func retentionQuery(enclave string, retention time.Duration) string {
+	query := url.Values{"retention": {retention.String()}}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	return "?" + query.Encode()
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/kes-go"
+)
+
+func TestReadPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/policy/read/my-policy" {
+			t.Errorf("got path '%s' - want '/v1/policy/read/my-policy'", r.URL.Path)
+		}
+		if enclave := r.URL.Query().Get("enclave"); enclave != "tenant-1" {
+			t.Errorf("got enclave '%s' - want 'tenant-1'", enclave)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"0123456789abcdef"`)
+		io.WriteString(w, `{"allow":["/v1/key/create/*"],"deny":["/v1/key/delete/*"],"created_by":"a"}`)
+	}))
+	defer server.Close()
+
+	client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+	policy, etag, err := ReadPolicy(context.Background(), client, "tenant-1", "my-policy")
+	if err != nil {
+		t.Fatalf("Failed to read policy: %v", err)
+	}
+	if etag != `"0123456789abcdef"` {
+		t.Fatalf("Invalid ETag: got '%s' - want '%s'", etag, `"0123456789abcdef"`)
+	}
+	if len(policy.Allow) != 1 || policy.Allow[0] != "/v1/key/create/*" {
+		t.Fatalf("Invalid allow rules: got '%v' - want '%v'", policy.Allow, []string{"/v1/key/create/*"})
+	}
+	if len(policy.Deny) != 1 || policy.Deny[0] != "/v1/key/delete/*" {
+		t.Fatalf("Invalid deny rules: got '%v' - want '%v'", policy.Deny, []string{"/v1/key/delete/*"})
+	}
+}
+
+func TestWritePolicy(t *testing.T) {
+	for i, test := range writePolicyTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("Test %d: got method '%s' - want '%s'", i, r.Method, http.MethodPost)
+			}
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != test.Cond.IfMatch {
+				t.Errorf("Test %d: got If-Match '%s' - want '%s'", i, ifMatch, test.Cond.IfMatch)
+			}
+			if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != test.Cond.IfNoneMatch {
+				t.Errorf("Test %d: got If-None-Match '%s' - want '%s'", i, ifNoneMatch, test.Cond.IfNoneMatch)
+			}
+			if test.Status != http.StatusOK {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.Status)
+				io.WriteString(w, test.Body)
+				return
+			}
+			w.Header().Set("ETag", test.ETag)
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		client := &kes.Client{Endpoints: []string{server.URL}, HTTPClient: *server.Client()}
+		etag, err := WritePolicy(context.Background(), client, "", "my-policy", &Policy{Allow: []string{"/v1/status"}}, test.Cond)
+		server.Close()
+
+		if code := Code(err); code != test.Code {
+			t.Fatalf("Test %d: got error code '%s' - want '%s'", i, code, test.Code)
+		}
+		if err == nil && etag != test.ETag {
+			t.Fatalf("Test %d: got ETag '%s' - want '%s'", i, etag, test.ETag)
+		}
+	}
+}
+
+var writePolicyTests = []struct {
+	Cond   Precondition
+	Status int
+	Body   string
+	ETag   string
+	Code   ErrorCode
+}{
+	{ // 0
+		Status: http.StatusOK,
+		ETag:   `"0123456789abcdef"`,
+	},
+	{ // 1
+		Cond:   Precondition{IfMatch: `"0123456789abcdef"`},
+		Status: http.StatusOK,
+		ETag:   `"fedcba9876543210"`,
+	},
+	{ // 2
+		Cond:   Precondition{IfNoneMatch: "*"},
+		Status: http.StatusOK,
+		ETag:   `"0123456789abcdef"`,
+	},
+	{ // 3
+		Cond:   Precondition{IfMatch: `"0123456789abcdef"`},
+		Status: http.StatusPreconditionFailed,
+		Body:   `{"message":"precondition failed","code":"ErrPreconditionFailed"}`,
+		Code:   CodePreconditionFailed,
+	},
+}
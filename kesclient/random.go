@@ -0,0 +1,80 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// RandomBytes returns length cryptographically secure random
+// bytes generated by the server. The policy of the requesting
+// identity within the enclave limits the length.
+func RandomBytes(ctx context.Context, client *kes.Client, enclave string, length int) ([]byte, error) {
+	type Response struct {
+		Bytes []byte `json:"bytes"`
+	}
+	query := url.Values{"length": {strconv.Itoa(length)}}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	resp, err := send(ctx, client, http.MethodGet, "/v1/random?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Bytes, nil
+}
+
+// HashKey returns the keyed hashes (HMAC-SHA256) of the
+// messages computed with the named key within the enclave.
+// The same key and message always produce the same hash.
+// The i-th hash belongs to the i-th message.
+//
+// It returns kes.ErrKeyNotFound if no such key exists.
+func HashKey(ctx context.Context, client *kes.Client, enclave, name string, messages [][]byte) ([][]byte, error) {
+	type Request struct {
+		Message []byte `json:"message"`
+	}
+	type Response struct {
+		Hash []byte `json:"hash"`
+	}
+	requests := make([]Request, 0, len(messages))
+	for _, message := range messages {
+		requests = append(requests, Request{Message: message})
+	}
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/key/hash/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var responses []Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&responses); err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, 0, len(responses))
+	for _, response := range responses {
+		hashes = append(hashes, response.Hash)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// SecretInfo describes a secret at a KES server.
+type SecretInfo struct {
+	Name      string         `json:"name"`       // Name of the secret
+	Type      kes.SecretType `json:"type"`       // Type of the secret
+	CreatedAt time.Time      `json:"created_at"` // Point in time when the secret has been created
+	ModTime   time.Time      `json:"mod_time"`   // Point in time when the secret has been modified last
+	CreatedBy kes.Identity   `json:"created_by"` // Identity that created the secret
+	Version   uint64         `json:"version"`    // Version of the secret, starting at 1
+
+	// Versions are all versions of the secret that have
+	// not been discarded, newest first. Only populated by
+	// DescribeSecret.
+	Versions []SecretVersion `json:"versions,omitempty"`
+
+	// Rotation is the rotation schedule of the secret. It
+	// is nil if the secret is not rotated automatically.
+	Rotation *SecretRotation `json:"rotation,omitempty"`
+}
+
+// SecretVersion describes a version of a secret.
+type SecretVersion struct {
+	Version   uint64    `json:"version"`    // Version number
+	CreatedAt time.Time `json:"created_at"` // Point in time when the version has been created
+}
+
+// SecretRotation describes the rotation schedule of a secret.
+type SecretRotation struct {
+	Endpoint     string        `json:"endpoint"`      // URL of the rotation webhook or database
+	Interval     time.Duration `json:"interval"`      // Time between two rotations
+	NextRotation time.Time     `json:"next_rotation"` // Point in time of the next rotation
+}
+
+// DescribeSecret returns the SecretInfo of the named secret
+// within the enclave.
+//
+// It returns kes.ErrSecretNotFound if no such secret exists.
+func DescribeSecret(ctx context.Context, client *kes.Client, enclave, name string) (*SecretInfo, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/secret/describe/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var info SecretInfo
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&info); err != nil {
+		return nil, err
+	}
+	info.Name = name
+	return &info, nil
+}
+
+// ReadSecret returns the value and the SecretInfo of the
+// named secret within the enclave.
+//
+// It returns kes.ErrSecretNotFound if no such secret exists.
+func ReadSecret(ctx context.Context, client *kes.Client, enclave, name string) ([]byte, *SecretInfo, error) {
+	return ReadSecretVersion(ctx, client, enclave, name, 0)
+}
+
+// ReadSecretVersion is like ReadSecret but returns the given
+// version of the secret. If version is 0, it returns the
+// current version.
+//
+// It returns an error with status 404 if the version does
+// not exist or has been discarded.
+func ReadSecretVersion(ctx context.Context, client *kes.Client, enclave, name string, version uint64) ([]byte, *SecretInfo, error) {
+	query := url.Values{}
+	if enclave != "" {
+		query.Set("enclave", enclave)
+	}
+	if version > 0 {
+		query.Set("version", strconv.FormatUint(version, 10))
+	}
+	path := "/v1/secret/read/" + url.PathEscape(name)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := send(ctx, client, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		Bytes []byte `json:"bytes"`
+		SecretInfo
+	}
+	const MaxSize = 2 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, nil, err
+	}
+	response.SecretInfo.Name = name
+	return response.Bytes, &response.SecretInfo, nil
+}
+
+// UpdateSecret replaces the value of the named secret within
+// the enclave and returns the new version of the secret.
+//
+// It returns kes.ErrSecretNotFound if no such secret exists.
+func UpdateSecret(ctx context.Context, client *kes.Client, enclave, name string, value []byte) (uint64, error) {
+	type Request struct {
+		Bytes []byte `json:"bytes"`
+	}
+	body, err := json.Marshal(Request{Bytes: value})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/secret/update/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		Version uint64 `json:"version"`
+	}
+	const MaxSize = 1 * mem.KiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return 0, err
+	}
+	return response.Version, nil
+}
+
+// SetSecretRotation sets the rotation schedule of the named
+// secret within the enclave. Once the interval has passed
+// since the secret has been modified last, the server invokes
+// the webhook endpoint to produce a new version of the secret.
+// An empty endpoint disables the rotation.
+//
+// It returns kes.ErrSecretNotFound if no such secret exists.
+func SetSecretRotation(ctx context.Context, client *kes.Client, enclave, name, endpoint string, interval time.Duration) error {
+	type Request struct {
+		Endpoint string        `json:"endpoint"`
+		Interval time.Duration `json:"interval"`
+	}
+	body, err := json.Marshal(Request{Endpoint: endpoint, Interval: interval})
+	if err != nil {
+		return err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/secret/rotation/"+url.PathEscape(name)+enclaveQuery(enclave), body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RotateSecret rotates the named secret within the enclave
+// immediately by invoking its rotation webhook and returns
+// the new version of the secret.
+//
+// It returns kes.ErrSecretNotFound if no such secret exists.
+func RotateSecret(ctx context.Context, client *kes.Client, enclave, name string) (uint64, error) {
+	resp, err := send(ctx, client, http.MethodPost, "/v1/secret/rotate/"+url.PathEscape(name)+enclaveQuery(enclave), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		Version uint64 `json:"version"`
+	}
+	const MaxSize = 1 * mem.KiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return 0, err
+	}
+	return response.Version, nil
+}
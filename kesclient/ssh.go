@@ -0,0 +1,77 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// SSHCertificateRequest is a request to sign an SSH
+// public key with an SSH CA key of a KES server.
+type SSHCertificateRequest struct {
+	PublicKey  []byte        // SSH public key in authorized_keys format
+	Host       bool          // Sign a host instead of a user certificate
+	Principals []string      // User or host names the certificate is valid for
+	TTL        time.Duration // Lifetime of the certificate. If zero, the server picks a default
+}
+
+// SSHCertificate is an SSH certificate signed by
+// a KES server.
+type SSHCertificate struct {
+	Certificate  string    `json:"certificate"`   // SSH certificate in authorized_keys format
+	SerialNumber uint64    `json:"serial_number"` // Serial number of the certificate
+	CA           string    `json:"ca"`            // SSH CA public key in authorized_keys format
+	ExpiresAt    time.Time `json:"expires_at"`    // Point in time when the certificate expires
+}
+
+// SSHPublicKey returns the public key, in authorized_keys
+// format, of the SSH CA derived from the given key.
+func SSHPublicKey(ctx context.Context, client *kes.Client, enclave, name string) ([]byte, error) {
+	const MaxSize = 16 * mem.KiB
+	return readAll(ctx, client, "/v1/ssh/ca/"+name+enclaveQuery(enclave), MaxSize)
+}
+
+// SignSSHKey signs the SSH public key of the request with the SSH
+// CA derived from the given key. The policy of the requesting
+// identity must allow all requested principals.
+func SignSSHKey(ctx context.Context, client *kes.Client, enclave, name string, req *SSHCertificateRequest) (*SSHCertificate, error) {
+	type Request struct {
+		PublicKey  string        `json:"public_key"`
+		Type       string        `json:"type"`
+		Principals []string      `json:"principals"`
+		TTL        time.Duration `json:"ttl,omitempty"`
+	}
+	certType := "user"
+	if req.Host {
+		certType = "host"
+	}
+	body, err := json.Marshal(Request{
+		PublicKey:  string(req.PublicKey),
+		Type:       certType,
+		Principals: req.Principals,
+		TTL:        req.TTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/ssh/sign/"+name+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 64 * mem.KiB
+	var cert SSHCertificate
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&cert); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// ServerStatus describes the state of a KES server,
+// including the state of its key store backend.
+type ServerStatus struct {
+	kes.State
+
+	KeyStoreLatency     time.Duration // Latency of the key store. Zero if unknown
+	KeyStoreUnavailable bool          // Whether the key store responded with an error
+	KeyStoreUnreachable bool          // Whether the key store could not be reached
+
+	// MinWireVersion and MaxWireVersion are the range of
+	// internal API versions the server supports when
+	// communicating with other cluster nodes. Both are
+	// zero if the server did not report them.
+	MinWireVersion int
+	MaxWireVersion int
+
+	// Date is the point in time when the server sent
+	// the status response, as reported by the server.
+	// It has a resolution of one second and is zero if
+	// the server did not report it.
+	Date time.Time
+}
+
+// Status returns the current state of the KES server
+// and its key store backend.
+func Status(ctx context.Context, client *kes.Client) (*ServerStatus, error) {
+	resp, err := send(ctx, client, http.MethodGet, "/v1/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		Version    string        `json:"version"`
+		OS         string        `json:"os"`
+		Arch       string        `json:"arch"`
+		UpTime     time.Duration `json:"uptime"`
+		CPUs       int           `json:"num_cpu"`
+		UsableCPUs int           `json:"num_cpu_used"`
+		HeapAlloc  uint64        `json:"mem_heap_used"`
+		StackAlloc uint64        `json:"mem_stack_used"`
+
+		KeyStoreLatency     int64 `json:"keystore_latency"` // In milliseconds
+		KeyStoreUnavailable bool  `json:"keystore_unavailable"`
+		KeyStoreUnreachable bool  `json:"keystore_unreachable"`
+
+		MinWireVersion int `json:"wire_version_min"`
+		MaxWireVersion int `json:"wire_version_max"`
+	}
+	const MaxSize = 1 * mem.MiB
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&response); err != nil {
+		return nil, err
+	}
+	date, _ := http.ParseTime(resp.Header.Get("Date"))
+	return &ServerStatus{
+		State: kes.State{
+			Version:    response.Version,
+			OS:         response.OS,
+			Arch:       response.Arch,
+			UpTime:     response.UpTime,
+			CPUs:       response.CPUs,
+			UsableCPUs: response.UsableCPUs,
+			HeapAlloc:  response.HeapAlloc,
+			StackAlloc: response.StackAlloc,
+		},
+		KeyStoreLatency:     time.Duration(response.KeyStoreLatency) * time.Millisecond,
+		KeyStoreUnavailable: response.KeyStoreUnavailable,
+		KeyStoreUnreachable: response.KeyStoreUnreachable,
+		MinWireVersion:      response.MinWireVersion,
+		MaxWireVersion:      response.MaxWireVersion,
+		Date:                date,
+	}, nil
+}
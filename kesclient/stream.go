@@ -0,0 +1,254 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// A StreamHeader is the header of a data stream encrypted
+// with EncryptStream. It contains the name of the KES key
+// and the data encryption key wrapped by this KES key.
+//
+// The header is stored in plaintext in front of the
+// encrypted data. It is authenticated, but not encrypted.
+type StreamHeader struct {
+	KeyName    string // Name of the KES key that wrapped the data key
+	WrappedKey []byte // Data key encrypted by the KES key
+}
+
+// Format of an encrypted data stream:
+//
+//	magic      [8]byte  "KESFILE" || version
+//	name       uint16 length || KES key name
+//	wrapped    uint16 length || wrapped data key
+//	segment... AES-256-GCM sealed plaintext segments
+//
+// The plaintext is split into segments of streamSegmentSize
+// bytes. Each segment is sealed with a nonce containing the
+// segment sequence number and a flag marking the final
+// segment. Hence, reordered, removed or truncated segments
+// are detected. The header is the associated data of each
+// segment.
+const (
+	streamVersion     = 1
+	streamSegmentSize = 64 * 1024
+	streamOverhead    = 16
+)
+
+var streamMagic = [7]byte{'K', 'E', 'S', 'F', 'I', 'L', 'E'}
+
+// Errors returned when decrypting data streams.
+var (
+	ErrStreamFormat = errors.New("kesclient: invalid encrypted data stream")
+	ErrStreamAuth   = errors.New("kesclient: encrypted data stream is not authentic")
+)
+
+// MarshalBinary returns the binary representation of h.
+func (h *StreamHeader) MarshalBinary() ([]byte, error) {
+	if len(h.KeyName) > 0xFFFF || len(h.WrappedKey) > 0xFFFF {
+		return nil, errors.New("kesclient: stream header too large")
+	}
+	b := make([]byte, 0, len(streamMagic)+1+2+len(h.KeyName)+2+len(h.WrappedKey))
+	b = append(b, streamMagic[:]...)
+	b = append(b, streamVersion)
+	b = append(b, byte(len(h.KeyName)>>8), byte(len(h.KeyName)))
+	b = append(b, h.KeyName...)
+	b = append(b, byte(len(h.WrappedKey)>>8), byte(len(h.WrappedKey)))
+	b = append(b, h.WrappedKey...)
+	return b, nil
+}
+
+// ReadStreamHeader reads the header of an encrypted data
+// stream from r. It returns ErrStreamFormat if r does not
+// start with a valid header.
+func ReadStreamHeader(r io.Reader) (StreamHeader, error) {
+	var magic [len(streamMagic) + 1]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return StreamHeader{}, ErrStreamFormat
+		}
+		return StreamHeader{}, err
+	}
+	if !bytes.Equal(magic[:len(streamMagic)], streamMagic[:]) || magic[len(streamMagic)] != streamVersion {
+		return StreamHeader{}, ErrStreamFormat
+	}
+
+	readField := func() ([]byte, error) {
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, ErrStreamFormat
+			}
+			return nil, err
+		}
+		field := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(r, field); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, ErrStreamFormat
+			}
+			return nil, err
+		}
+		return field, nil
+	}
+	name, err := readField()
+	if err != nil {
+		return StreamHeader{}, err
+	}
+	wrappedKey, err := readField()
+	if err != nil {
+		return StreamHeader{}, err
+	}
+	return StreamHeader{
+		KeyName:    string(name),
+		WrappedKey: wrappedKey,
+	}, nil
+}
+
+// EncryptStream writes the header followed by the
+// plaintext read from src, encrypted with the 256 bit
+// data key, to dst. It returns the number of plaintext
+// bytes encrypted.
+//
+// The key must be the plaintext of the data key
+// wrapped in the header.
+func EncryptStream(dst io.Writer, src io.Reader, header StreamHeader, key []byte) (int64, error) {
+	associatedData, err := header.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	aead, err := newStreamCipher(key)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = dst.Write(associatedData); err != nil {
+		return 0, err
+	}
+
+	var (
+		n       int64
+		seq     uint64
+		nonce   [12]byte
+		buf     = make([]byte, streamSegmentSize+streamOverhead)
+		segment = buf[:streamSegmentSize]
+		reader  = bufio.NewReaderSize(src, streamSegmentSize)
+	)
+	for {
+		m, err := io.ReadFull(reader, segment)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return n, err
+		}
+		n += int64(m)
+
+		// The segment is the final one if there is no more
+		// plaintext. An empty plaintext produces one empty
+		// final segment.
+		final := err != nil
+		if !final {
+			if _, err = reader.Peek(1); err != nil {
+				if !errors.Is(err, io.EOF) {
+					return n, err
+				}
+				final = true
+			}
+		}
+		streamNonce(&nonce, seq, final)
+		if _, err = dst.Write(aead.Seal(buf[:0], nonce[:], segment[:m], associatedData)); err != nil {
+			return n, err
+		}
+		if final {
+			return n, nil
+		}
+		seq++
+	}
+}
+
+// DecryptStream decrypts the encrypted data read from src
+// with the 256 bit data key and writes the plaintext to
+// dst. It returns the number of plaintext bytes written.
+//
+// The src must be positioned right after the header,
+// e.g. by reading the header with ReadStreamHeader.
+// DecryptStream returns ErrStreamAuth if the encrypted
+// data has been modified or truncated. In this case,
+// the plaintext written to dst so far must be discarded.
+func DecryptStream(dst io.Writer, src io.Reader, header StreamHeader, key []byte) (int64, error) {
+	associatedData, err := header.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	aead, err := newStreamCipher(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		n      int64
+		seq    uint64
+		nonce  [12]byte
+		buf    = make([]byte, streamSegmentSize+streamOverhead)
+		reader = bufio.NewReaderSize(src, streamSegmentSize+streamOverhead)
+	)
+	for {
+		m, err := io.ReadFull(reader, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return n, err
+		}
+		if m < streamOverhead {
+			return n, ErrStreamAuth
+		}
+
+		final := err != nil
+		if !final {
+			if _, err = reader.Peek(1); err != nil {
+				if !errors.Is(err, io.EOF) {
+					return n, err
+				}
+				final = true
+			}
+		}
+		streamNonce(&nonce, seq, final)
+		plaintext, err := aead.Open(buf[:0], nonce[:], buf[:m], associatedData)
+		if err != nil {
+			return n, ErrStreamAuth
+		}
+		if _, err = dst.Write(plaintext); err != nil {
+			return n, err
+		}
+		n += int64(len(plaintext))
+		if final {
+			return n, nil
+		}
+		seq++
+	}
+}
+
+func newStreamCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("kesclient: invalid data key length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// streamNonce sets the nonce of the seq-th segment.
+// Each data key is only used for one stream, such
+// that the nonce does not need a random part.
+func streamNonce(nonce *[12]byte, seq uint64, final bool) {
+	binary.BigEndian.PutUint64(nonce[:8], seq)
+	nonce[8], nonce[9], nonce[10], nonce[11] = 0, 0, 0, 0
+	if final {
+		nonce[11] = 1
+	}
+}
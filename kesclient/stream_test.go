@@ -0,0 +1,115 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	key := make([]byte, 32)
+	header := StreamHeader{KeyName: "my-key", WrappedKey: []byte("wrapped-data-key")}
+
+	for i, size := range streamTests {
+		plaintext := make([]byte, size)
+		if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+			t.Fatalf("Test %d: failed to generate plaintext: %v", i, err)
+		}
+
+		var ciphertext bytes.Buffer
+		n, err := EncryptStream(&ciphertext, bytes.NewReader(plaintext), header, key)
+		if err != nil {
+			t.Fatalf("Test %d: failed to encrypt: %v", i, err)
+		}
+		if n != int64(size) {
+			t.Fatalf("Test %d: got %d encrypted bytes - want %d", i, n, size)
+		}
+
+		src := bytes.NewReader(ciphertext.Bytes())
+		h, err := ReadStreamHeader(src)
+		if err != nil {
+			t.Fatalf("Test %d: failed to read header: %v", i, err)
+		}
+		if h.KeyName != header.KeyName || !bytes.Equal(h.WrappedKey, header.WrappedKey) {
+			t.Fatalf("Test %d: got header '%v' - want '%v'", i, h, header)
+		}
+		var decrypted bytes.Buffer
+		if _, err = DecryptStream(&decrypted, src, h, key); err != nil {
+			t.Fatalf("Test %d: failed to decrypt: %v", i, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plaintext) {
+			t.Fatalf("Test %d: decrypted plaintext does not match original plaintext", i)
+		}
+	}
+}
+
+var streamTests = []int{
+	0,                         // 0
+	1,                         // 1
+	streamSegmentSize - 1,     // 2
+	streamSegmentSize,         // 3
+	streamSegmentSize + 1,     // 4
+	3 * streamSegmentSize,     // 5
+	3*streamSegmentSize + 100, // 6
+}
+
+func TestStreamNotAuthentic(t *testing.T) {
+	key := make([]byte, 32)
+	header := StreamHeader{KeyName: "my-key", WrappedKey: []byte("wrapped-data-key")}
+	plaintext := make([]byte, 2*streamSegmentSize+100)
+
+	var buf bytes.Buffer
+	if _, err := EncryptStream(&buf, bytes.NewReader(plaintext), header, key); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	ciphertext := buf.Bytes()
+	headerSize := len(ciphertext) - len(plaintext) - 3*streamOverhead
+	segmentSize := streamSegmentSize + streamOverhead
+
+	modified := append([]byte{}, ciphertext...)
+	modified[headerSize+10] ^= 1
+	reordered := append([]byte{}, ciphertext[:headerSize]...)
+	reordered = append(reordered, ciphertext[headerSize+segmentSize:headerSize+2*segmentSize]...)
+	reordered = append(reordered, ciphertext[headerSize:headerSize+segmentSize]...)
+	reordered = append(reordered, ciphertext[headerSize+2*segmentSize:]...)
+	renamed := append([]byte{}, ciphertext...)
+	renamed[len(streamMagic)+1+2] = 'M'
+
+	for i, test := range [][]byte{
+		modified,                                 // 0 - modified segment
+		reordered,                                // 1 - reordered segments
+		ciphertext[:headerSize+2*segmentSize],    // 2 - final segment removed
+		ciphertext[:len(ciphertext)-1],           // 3 - final segment truncated
+		renamed,                                  // 4 - modified header
+		ciphertext[:headerSize+streamOverhead-1], // 5 - incomplete segment
+	} {
+		src := bytes.NewReader(test)
+		h, err := ReadStreamHeader(src)
+		if err != nil {
+			t.Fatalf("Test %d: failed to read header: %v", i, err)
+		}
+		if _, err = DecryptStream(io.Discard, src, h, key); !errors.Is(err, ErrStreamAuth) {
+			t.Fatalf("Test %d: got error '%v' - want '%v'", i, err, ErrStreamAuth)
+		}
+	}
+}
+
+func TestReadStreamHeader(t *testing.T) {
+	for i, test := range [][]byte{
+		nil,                             // 0
+		[]byte("KESFILE"),               // 1
+		[]byte("KESFILE\x02"),           // 2
+		[]byte("NOTAFILE\x00\x00"),      // 3
+		[]byte("KESFILE\x01\x00\x05my"), // 4
+	} {
+		if _, err := ReadStreamHeader(bytes.NewReader(test)); !errors.Is(err, ErrStreamFormat) {
+			t.Fatalf("Test %d: got error '%v' - want '%v'", i, err, ErrStreamFormat)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Token is a JWT signed by a KES server.
+type Token struct {
+	Token     string    `json:"token"`      // JWT in compact serialization
+	KeyID     string    `json:"key_id"`     // JWK thumbprint of the signing key
+	ExpiresAt time.Time `json:"expires_at"` // Point in time when the token expires
+}
+
+// DesignateTokenKey designates the given key as JWT signing
+// key or, if enabled is false, revokes the designation.
+func DesignateTokenKey(ctx context.Context, client *kes.Client, enclave, name string, enabled bool) error {
+	type Request struct {
+		Enabled bool `json:"enabled"`
+	}
+	body, err := json.Marshal(Request{Enabled: enabled})
+	if err != nil {
+		return err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/token/designate/"+name+enclaveQuery(enclave), body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// SignToken returns a JWT containing the given claims signed
+// by the given key. String claims may contain the template
+// variables {{identity}}, {{policy}}, {{enclave}} and {{key}}.
+// If ttl is zero, the server picks a default lifetime.
+func SignToken(ctx context.Context, client *kes.Client, enclave, name string, claims map[string]any, ttl time.Duration) (*Token, error) {
+	type Request struct {
+		Claims map[string]any `json:"claims"`
+		TTL    time.Duration  `json:"ttl,omitempty"`
+	}
+	body, err := json.Marshal(Request{
+		Claims: claims,
+		TTL:    ttl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/token/sign/"+name+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 128 * mem.KiB
+	var token Token
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TokenKeySet returns the JSON Web Key Set containing the
+// public keys of all JWT signing keys of the enclave.
+func TokenKeySet(ctx context.Context, client *kes.Client, enclave string) ([]byte, error) {
+	const MaxSize = 1 * mem.MiB
+	return readAll(ctx, client, "/.well-known/jwks.json"+enclaveQuery(enclave), MaxSize)
+}
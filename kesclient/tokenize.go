@@ -0,0 +1,92 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"aead.dev/mem"
+	"github.com/minio/kes-go"
+)
+
+// Tokenize replaces the values by random tokens within the
+// tokenization scope of the enclave. The i-th token belongs
+// to the i-th value. Tokenizing the same value twice returns
+// two different tokens.
+func Tokenize(ctx context.Context, client *kes.Client, enclave, scope string, values [][]byte) ([]string, error) {
+	type Request struct {
+		Value []byte `json:"value"`
+	}
+	type Response struct {
+		Token string `json:"token"`
+	}
+	requests := make([]Request, 0, len(values))
+	for _, value := range values {
+		requests = append(requests, Request{Value: value})
+	}
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/tokenize/"+url.PathEscape(scope)+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 1 * mem.MiB
+	var responses []Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&responses); err != nil {
+		return nil, err
+	}
+	tokens := make([]string, 0, len(responses))
+	for _, response := range responses {
+		tokens = append(tokens, response.Token)
+	}
+	return tokens, nil
+}
+
+// Detokenize returns the values replaced by the tokens within
+// the tokenization scope of the enclave. The i-th value belongs
+// to the i-th token.
+//
+// Detokenizing requires a separate permission. An identity that
+// is allowed to tokenize values may not be able to detokenize
+// them.
+func Detokenize(ctx context.Context, client *kes.Client, enclave, scope string, tokens []string) ([][]byte, error) {
+	type Request struct {
+		Token string `json:"token"`
+	}
+	type Response struct {
+		Value []byte `json:"value"`
+	}
+	requests := make([]Request, 0, len(tokens))
+	for _, token := range tokens {
+		requests = append(requests, Request{Token: token})
+	}
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(ctx, client, http.MethodPost, "/v1/detokenize/"+url.PathEscape(scope)+enclaveQuery(enclave), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	const MaxSize = 10 * mem.MiB
+	var responses []Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxSize)).Decode(&responses); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, len(responses))
+	for _, response := range responses {
+		values = append(values, response.Value)
+	}
+	return values, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Default transport parameters used by NewClient and NewTransport.
+const (
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultMaxIdleConnsPerHost = 32
+	DefaultKeepAlive           = 30 * time.Second
+	DefaultPingInterval        = 30 * time.Second
+	DefaultPingTimeout         = 15 * time.Second
+)
+
+// TransportConfig controls how the HTTP transport returned
+// by NewTransport reuses connections and detects broken ones.
+//
+// Establishing a connection requires at least two round trips,
+// one for TCP and one for the TLS handshake. Hence, clients far
+// away from the servers should keep their connections open and
+// send requests concurrently over the same HTTP/2 connection.
+// Zero values select the corresponding default.
+type TransportConfig struct {
+	// IdleConnTimeout is the time an idle connection is
+	// kept open before it is closed. If negative, idle
+	// connections are kept open until the server closes
+	// them.
+	IdleConnTimeout time.Duration
+
+	// MaxIdleConnsPerHost is the max. number of idle HTTP/1.1
+	// connections kept open per server endpoint. HTTP/2 uses
+	// one connection per endpoint for concurrent requests.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost limits the number of connections per
+	// server endpoint, including connections in use. Requests
+	// wait once the limit is reached. If <= 0, the number
+	// of connections is not limited.
+	MaxConnsPerHost int
+
+	// StrictMaxConcurrentStreams controls whether the max.
+	// number of concurrent HTTP/2 streams announced by a
+	// server limits the concurrent requests per endpoint.
+	// If true, requests wait for a free stream. Otherwise,
+	// additional connections are opened once all streams
+	// of a connection are in use.
+	StrictMaxConcurrentStreams bool
+
+	// KeepAlive is the interval between TCP keep-alive
+	// probes. If negative, TCP keep-alives are disabled.
+	KeepAlive time.Duration
+
+	// PingInterval is the time after which an HTTP/2
+	// connection that has not received any frame is
+	// checked by sending a ping. If negative, no pings
+	// are sent.
+	//
+	// Pings detect connections broken by, for example,
+	// NAT gateways or load balancers much faster than
+	// TCP. Such connections would otherwise cause
+	// requests to hang until they time out.
+	PingInterval time.Duration
+
+	// PingTimeout is the time after which a connection
+	// is closed if a ping has not been answered.
+	PingTimeout time.Duration
+}
+
+// NewTransport returns a new HTTP transport that connects
+// to servers using the TLS config and prefers HTTP/2 over
+// HTTP/1.1. If config is nil, the defaults of TransportConfig
+// are used.
+//
+// The transport connects to hosts with IPv4 and IPv6 addresses
+// using a happy-eyeballs Dialer. The TLS config is not modified.
+func NewTransport(tlsConfig *tls.Config, config *TransportConfig) (*http.Transport, error) {
+	if config == nil {
+		config = &TransportConfig{}
+	}
+	var (
+		idleConnTimeout     = DefaultIdleConnTimeout
+		maxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+		keepAlive           = DefaultKeepAlive
+		pingInterval        = DefaultPingInterval
+		pingTimeout         = DefaultPingTimeout
+	)
+	switch {
+	case config.IdleConnTimeout > 0:
+		idleConnTimeout = config.IdleConnTimeout
+	case config.IdleConnTimeout < 0:
+		idleConnTimeout = 0
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		maxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.KeepAlive != 0 {
+		keepAlive = config.KeepAlive
+	}
+	switch {
+	case config.PingInterval > 0:
+		pingInterval = config.PingInterval
+	case config.PingInterval < 0:
+		pingInterval = 0
+	}
+	if config.PingTimeout > 0 {
+		pingTimeout = config.PingTimeout
+	}
+
+	dialer := &Dialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: keepAlive,
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig.Clone(),
+	}
+	h2, err := http2.ConfigureTransports(transport)
+	if err != nil {
+		return nil, err
+	}
+	h2.ReadIdleTimeout = pingInterval
+	h2.PingTimeout = pingTimeout
+	h2.StrictMaxConcurrentStreams = config.StrictMaxConcurrentStreams
+	return transport, nil
+}
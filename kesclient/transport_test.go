@@ -0,0 +1,44 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewTransportHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	config := &tls.Config{RootCAs: rootCAs}
+
+	transport, err := NewTransport(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create transport: %v", err)
+	}
+	if len(config.NextProtos) != 0 {
+		t.Fatalf("TLS config has been modified: got NextProtos '%v' - want none", config.NextProtos)
+	}
+
+	client := http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Fatalf("Request %d: got protocol '%s' - want HTTP/2", i, resp.Proto)
+		}
+	}
+}
@@ -114,13 +114,15 @@ func (g *Gateway) start() {
 
 	serverCert := issueCertificate("kestest: gateway", g.caCertificate, g.caPrivateKey, x509.ExtKeyUsageServerAuth)
 	g.server = httptest.NewUnstartedServer(api.NewEdgeRouter(&api.EdgeRouterConfig{
-		Keys:       store,
-		Policies:   g.policies.policySet(),
-		Identities: g.policies.identitySet(),
-		Proxy:      nil,
-		AuditLog:   auditLog,
-		ErrorLog:   errorLog,
-		Metrics:    metrics,
+		Keys:        store,
+		Policies:    g.policies.policySet(),
+		Identities:  g.policies.identitySet(),
+		Proxy:       nil,
+		Idempotency: api.NewIdempotencyCache(0),
+		Events:      api.NewEventStream(),
+		AuditLog:    auditLog,
+		ErrorLog:    errorLog,
+		Metrics:     metrics,
 	}))
 	g.server.TLS = &tls.Config{
 		RootCAs:      rootCAs,
@@ -10,6 +10,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/minio/kes-go"
+	"github.com/minio/kes/kesclient"
 	"github.com/minio/kes/kestest"
 )
 
@@ -26,16 +28,21 @@ var gatewayAPIs = map[string]struct {
 	MaxBody int64
 	Timeout time.Duration
 }{
-	"/version":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-	"/v1/status":  {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-	"/v1/metrics": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-	"/v1/api":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/version":             {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/status":           {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/metrics":          {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/metrics/identity": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/api":              {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/openapi.json":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 
 	"/v1/key/create/":       {Method: http.MethodPost, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/import/":       {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
+	"/v1/key/register/":     {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
 	"/v1/key/describe/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/verify/":       {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/list/":         {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/key/delete/":       {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/key/delete-bulk/":  {Method: http.MethodDelete, MaxBody: 0, Timeout: 60 * time.Second},
 	"/v1/key/generate/":     {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
 	"/v1/key/encrypt/":      {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
 	"/v1/key/decrypt/":      {Method: http.MethodPost, MaxBody: 1 << 20, Timeout: 15 * time.Second},
@@ -49,8 +56,17 @@ var gatewayAPIs = map[string]struct {
 	"/v1/identity/self/describe": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	"/v1/identity/list/":         {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 
-	"/v1/log/error": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
-	"/v1/log/audit": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+	"/v1/events":            {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+	"/v1/admin/cache/stats": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/admin/cache/flush": {Method: http.MethodPost, MaxBody: 0, Timeout: 15 * time.Second},
+
+	"/v1/log/error":          {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+	"/v1/log/audit":          {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+	"/v1/log/security/trace": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+	"/v1/log/level":          {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+	"/v1/log/level/set/":     {Method: http.MethodPut, MaxBody: 0, Timeout: 15 * time.Second},
+
+	"/v1/debug/pprof/": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
 }
 
 func TestMetrics(t *testing.T) {
@@ -182,6 +198,33 @@ func TestImportKey(t *testing.T) {
 	}
 }
 
+func TestVerifyKey(t *testing.T) {
+	ctx, cancel := testingContext(t)
+	defer cancel()
+
+	server := kestest.NewGateway()
+	defer server.Close()
+
+	client := server.Client()
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	check, err := kesclient.VerifyKey(ctx, client, "", "my-key")
+	if err != nil {
+		t.Fatalf("Failed to verify key: %v", err)
+	}
+	if check.Status != kesclient.KeyIntact {
+		t.Fatalf("Key status mismatch: got '%s' - want '%s'", check.Status, kesclient.KeyIntact)
+	}
+	if check.CheckValue == "" || check.CheckValue != check.StoredCheckValue {
+		t.Fatalf("Key check value mismatch: got '%s' - want '%s'", check.CheckValue, check.StoredCheckValue)
+	}
+
+	if _, err = kesclient.VerifyKey(ctx, client, "", "missing-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Verifying a non-existing key: got error '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+}
+
 var generateKeyTests = []struct {
 	Context    []byte
 	ShouldFail bool
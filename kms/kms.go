@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -122,6 +123,55 @@ func (f *fuseIter) Close() error {
 	return f.err
 }
 
+// PrefixLister is an optional interface that a Conn
+// can implement to list entries whose names start
+// with a given prefix efficiently - for example,
+// by fetching only these entries from the KMS.
+type PrefixLister interface {
+	// ListPrefix returns an iterator over the entries
+	// at the KMS whose names start with the prefix.
+	// An empty prefix matches all entries.
+	//
+	// The returned Iter stops fetching entries
+	// from the KMS once ctx.Done() returns.
+	ListPrefix(ctx context.Context, prefix string) (Iter, error)
+}
+
+// ListPrefix returns an iterator over the entries at the
+// KMS whose names start with the prefix.
+//
+// If conn implements PrefixLister, ListPrefix calls its
+// ListPrefix method. Otherwise, it lists all entries
+// and skips entries that don't start with the prefix.
+func ListPrefix(ctx context.Context, conn Conn, prefix string) (Iter, error) {
+	if lister, ok := conn.(PrefixLister); ok {
+		return lister.ListPrefix(ctx, prefix)
+	}
+	iter, err := conn.List(ctx)
+	if err != nil || prefix == "" {
+		return iter, err
+	}
+	return &prefixIter{iter: iter, prefix: prefix}, nil
+}
+
+type prefixIter struct {
+	iter   Iter
+	prefix string
+}
+
+func (p *prefixIter) Next() bool {
+	for p.iter.Next() {
+		if strings.HasPrefix(p.iter.Name(), p.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *prefixIter) Name() string { return p.iter.Name() }
+
+func (p *prefixIter) Close() error { return p.iter.Close() }
+
 // State is a structure describing the state of
 // a KMS Conn.
 type State struct {
@@ -0,0 +1,279 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/kv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config is a structure containing the plugin
+// configuration.
+type Config struct {
+	// Path is the path of the plugin executable.
+	// If not empty, Connect starts the plugin
+	// process and connects to it.
+	Path string
+
+	// Args are the command line arguments passed
+	// to the plugin executable.
+	Args []string
+
+	// Socket is the path of the Unix socket of an
+	// already running plugin. It is ignored when
+	// Path is not empty.
+	Socket string
+}
+
+// connectTimeout is the max. amount of time Connect waits
+// for a plugin to accept connections.
+const connectTimeout = 10 * time.Second
+
+// Connect connects to the plugin using the given
+// configuration.
+//
+// If the configuration specifies a plugin executable,
+// Connect starts the plugin process, unless a plugin
+// process with the same executable and arguments is
+// already running. All clients share the same process.
+func Connect(ctx context.Context, config *Config) (*Client, error) {
+	if config == nil || (config.Path == "" && config.Socket == "") {
+		return nil, errors.New("plugin: no plugin executable or socket specified")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	socket := config.Socket
+	if config.Path != "" {
+		p, err := startProcess(ctx, config.Path, config.Args)
+		if err != nil {
+			return nil, err
+		}
+		socket = p.socket
+	}
+
+	conn, err := grpc.DialContext(ctx, "passthrough:///"+socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("plugin: failed to connect to '%s': %v", socket, err)
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Client is a connection to a keystore plugin.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+var _ kv.Store[string, []byte] = (*Client)(nil) // compiler check
+
+// Status returns the current state of the plugin.
+//
+// It returns a kv.Unreachable error if the plugin
+// is not reachable and a kv.Unavailable error if
+// the plugin cannot reach its keystore.
+func (c *Client) Status(ctx context.Context) (kv.State, error) {
+	start := time.Now()
+	if err := c.conn.Invoke(ctx, fullMethod("Status"), &emptyMessage{}, &emptyMessage{}); err != nil {
+		if c.conn.GetState() != connectivity.Ready {
+			return kv.State{}, &kv.Unreachable{Err: err}
+		}
+		return kv.State{}, fromStatus(err)
+	}
+	return kv.State{Latency: time.Since(start)}, nil
+}
+
+// Create creates a new entry with the given name if and
+// only if no such entry exists. Otherwise, it returns
+// kes.ErrKeyExists.
+func (c *Client) Create(ctx context.Context, name string, value []byte) error {
+	err := c.conn.Invoke(ctx, fullMethod("Create"), &entryMessage{Name: name, Value: value}, &emptyMessage{})
+	return fromStatus(err)
+}
+
+// Set writes the entry with the given name.
+func (c *Client) Set(ctx context.Context, name string, value []byte) error {
+	err := c.conn.Invoke(ctx, fullMethod("Set"), &entryMessage{Name: name, Value: value}, &emptyMessage{})
+	return fromStatus(err)
+}
+
+// Get returns the value of the entry with the given name.
+// It returns kes.ErrKeyNotFound if no such entry exists.
+func (c *Client) Get(ctx context.Context, name string) ([]byte, error) {
+	var resp valueMessage
+	if err := c.conn.Invoke(ctx, fullMethod("Get"), &entryMessage{Name: name}, &resp); err != nil {
+		return nil, fromStatus(err)
+	}
+	return resp.Value, nil
+}
+
+// Delete deletes the entry with the given name. It
+// returns kes.ErrKeyNotFound if no such entry exists.
+func (c *Client) Delete(ctx context.Context, name string) error {
+	err := c.conn.Invoke(ctx, fullMethod("Delete"), &entryMessage{Name: name}, &emptyMessage{})
+	return fromStatus(err)
+}
+
+// List returns an iterator over the names of
+// all entries.
+func (c *Client) List(ctx context.Context) (kv.Iter[string], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], fullMethod("List"))
+	if err != nil {
+		cancel()
+		return nil, fromStatus(err)
+	}
+	if err = stream.SendMsg(&emptyMessage{}); err != nil {
+		cancel()
+		return nil, fromStatus(err)
+	}
+	if err = stream.CloseSend(); err != nil {
+		cancel()
+		return nil, fromStatus(err)
+	}
+	return &iter{stream: stream, cancel: cancel}, nil
+}
+
+// Close closes the connection to the plugin. It
+// does not stop the plugin process.
+func (c *Client) Close() error { return c.conn.Close() }
+
+// fullMethod returns the full gRPC method name
+// of the KeyStore method with the given name.
+func fullMethod(name string) string { return "/" + ServiceName + "/" + name }
+
+type iter struct {
+	stream grpc.ClientStream
+	cancel context.CancelFunc
+
+	names []string
+	done  bool
+	err   error
+}
+
+func (i *iter) Next() (string, bool) {
+	for len(i.names) == 0 {
+		if i.done {
+			return "", false
+		}
+		var resp namesMessage
+		if err := i.stream.RecvMsg(&resp); err != nil {
+			if err != io.EOF {
+				i.err = fromStatus(err)
+			}
+			i.done = true
+			return "", false
+		}
+		i.names = resp.Names
+	}
+	name := i.names[0]
+	i.names = i.names[1:]
+	return name, true
+}
+
+func (i *iter) Close() error {
+	i.cancel()
+	i.done = true
+	return i.err
+}
+
+var (
+	processLock sync.Mutex
+	processes   = map[string]*process{} // Running plugin processes by command line
+)
+
+// process is a running plugin process.
+type process struct {
+	socket string
+	exited chan struct{}
+	err    error // Set once exited is closed
+}
+
+// startProcess starts the plugin executable with the given
+// arguments, unless it is running already, and waits until
+// the plugin accepts connections.
+func startProcess(ctx context.Context, path string, args []string) (*process, error) {
+	processLock.Lock()
+	defer processLock.Unlock()
+
+	command := strings.Join(append([]string{path}, args...), " ")
+	if p, ok := processes[command]; ok {
+		select {
+		case <-p.exited:
+		default:
+			return p, nil
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "kes-plugin-")
+	if err != nil {
+		return nil, err
+	}
+	p := &process{
+		socket: filepath.Join(dir, "plugin.sock"),
+		exited: make(chan struct{}),
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(), EnvSocket+"="+p.socket)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if _, err = cmd.StdinPipe(); err != nil { // The plugin exits once KES exits and the pipe gets closed
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("plugin: failed to start '%s': %v", path, err)
+	}
+	go func() {
+		p.err = cmd.Wait()
+		os.RemoveAll(dir)
+		close(p.exited)
+	}()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if conn, err := net.Dial("unix", p.socket); err == nil {
+			conn.Close()
+			processes[command] = p
+			return p, nil
+		}
+
+		select {
+		case <-p.exited:
+			if p.err != nil {
+				return nil, fmt.Errorf("plugin: '%s' exited before accepting connections: %v", path, p.err)
+			}
+			return nil, fmt.Errorf("plugin: '%s' exited before accepting connections", path)
+		case <-ctx.Done():
+			cmd.Process.Kill()
+			return nil, fmt.Errorf("plugin: '%s' does not accept connections: %v", path, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package plugin implements the KES keystore plugin
+// interface.
+//
+// A keystore plugin is a separate process that serves
+// the gRPC KeyStore service, defined in keystore.proto,
+// on a Unix socket. Hence, out-of-tree keystores, like
+// proprietary HSMs or internal secret stores, can be
+// used by KES without modifying the server.
+//
+// Plugins written in Go can implement the kv.Store
+// interface and call Main. Plugins written in other
+// languages can generate the gRPC service from
+// keystore.proto.
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kv"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// EnvSocket is the env. variable that contains the path
+// of the Unix socket a plugin process started by KES
+// listens on.
+const EnvSocket = "KES_PLUGIN_SOCKET"
+
+// ServiceName is the gRPC name of the KeyStore service.
+const ServiceName = "kes.keystore.v1.KeyStore"
+
+// toStatus converts an error returned by a kv.Store
+// to a gRPC status error.
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case isStatus(err):
+		return err
+	case errors.Is(err, kv.ErrExists), errors.Is(err, kes.ErrKeyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, kv.ErrNotExists), errors.Is(err, kes.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	if _, ok := kv.IsUnavailable(err); ok {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if _, ok := kv.IsUnreachable(err); ok {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// isStatus reports whether err is a gRPC status error.
+func isStatus(err error) bool {
+	_, ok := err.(interface{ GRPCStatus() *status.Status })
+	return ok
+}
+
+// fromStatus converts a gRPC status error returned
+// by a plugin to a kv.Store error.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.AlreadyExists:
+		return kes.ErrKeyExists
+	case codes.NotFound:
+		return kes.ErrKeyNotFound
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Unavailable:
+		return &kv.Unavailable{Err: errors.New(s.Message())}
+	default:
+		return fmt.Errorf("plugin: %s", s.Message())
+	}
+}
+
+// codec is a gRPC codec that encodes the KeyStore
+// service messages in the protobuf wire format.
+type codec struct{}
+
+func (codec) Name() string { return "proto" }
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("plugin: invalid message type '%T'", v)
+	}
+	return m.marshal(), nil
+}
+
+func (codec) Unmarshal(b []byte, v any) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("plugin: invalid message type '%T'", v)
+	}
+	return m.unmarshal(b)
+}
+
+// message is a KeyStore service message.
+type message interface {
+	marshal() []byte
+	unmarshal([]byte) error
+}
+
+// emptyMessage represents all messages without fields,
+// like StatusRequest or CreateResponse.
+type emptyMessage struct{}
+
+func (*emptyMessage) marshal() []byte { return nil }
+
+func (*emptyMessage) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(protowire.Number, []byte) {})
+}
+
+// entryMessage represents the CreateRequest, SetRequest,
+// GetRequest and DeleteRequest messages.
+type entryMessage struct {
+	Name  string // Field 1
+	Value []byte // Field 2
+}
+
+func (m *entryMessage) marshal() []byte {
+	var b []byte
+	if m.Name != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, m.Name)
+	}
+	if len(m.Value) > 0 {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Value)
+	}
+	return b
+}
+
+func (m *entryMessage) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, v []byte) {
+		switch num {
+		case 1:
+			m.Name = string(v)
+		case 2:
+			m.Value = append([]byte(nil), v...)
+		}
+	})
+}
+
+// valueMessage represents the GetResponse message.
+type valueMessage struct {
+	Value []byte // Field 1
+}
+
+func (m *valueMessage) marshal() []byte {
+	if len(m.Value) == 0 {
+		return nil
+	}
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, m.Value)
+}
+
+func (m *valueMessage) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, v []byte) {
+		if num == 1 {
+			m.Value = append([]byte(nil), v...)
+		}
+	})
+}
+
+// namesMessage represents the ListResponse message.
+type namesMessage struct {
+	Names []string // Field 1
+}
+
+func (m *namesMessage) marshal() []byte {
+	var b []byte
+	for _, name := range m.Names {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, name)
+	}
+	return b
+}
+
+func (m *namesMessage) unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, v []byte) {
+		if num == 1 {
+			m.Names = append(m.Names, string(v))
+		}
+	})
+}
+
+// unmarshalFields calls field for each length-delimited
+// field in b. It skips all other fields since the KeyStore
+// messages only contain strings and bytes.
+func unmarshalFields(b []byte, field func(protowire.Number, []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		field(num, v)
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/keystore/mem"
+)
+
+func TestPlugin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen on '%s': %v", socket, err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, listener, &mem.Store{}) }()
+
+	client, err := Connect(ctx, &Config{Socket: socket})
+	if err != nil {
+		t.Fatalf("Failed to connect to plugin: %v", err)
+	}
+	defer client.Close()
+
+	if _, err = client.Status(ctx); err != nil {
+		t.Fatalf("Failed to fetch plugin status: %v", err)
+	}
+	if err = client.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = client.Create(ctx, "my-key", []byte("my-value")); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	value, err := client.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if !bytes.Equal(value, []byte("my-value")) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+	if err = client.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = client.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	const N = 2*listBatchSize + 1
+	for i := 0; i < N; i++ {
+		if err = client.Create(ctx, "key-"+strconv.Itoa(i), []byte{1}); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+	iter, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	var names []string
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		names = append(names, name)
+	}
+	if err = iter.Close(); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(names) != N {
+		t.Fatalf("Invalid number of keys: got '%d' - want '%d'", len(names), N)
+	}
+	sort.Strings(names)
+	if names[0] != "key-0" {
+		t.Fatalf("Invalid key: got '%s' - want '%s'", names[0], "key-0")
+	}
+
+	cancel()
+	if err = <-served; err != nil {
+		t.Fatalf("Failed to serve plugin: %v", err)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	entry := &entryMessage{Name: "my-key", Value: []byte("my-value")}
+	var decoded entryMessage
+	if err := decoded.unmarshal(entry.marshal()); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if decoded.Name != entry.Name || !bytes.Equal(decoded.Value, entry.Value) {
+		t.Fatalf("Invalid message: got '%+v' - want '%+v'", decoded, *entry)
+	}
+
+	// Unknown fields, like a varint field 3, must be skipped.
+	b := append(entry.marshal(), 3<<3, 42)
+	if err := decoded.unmarshal(b); err != nil {
+		t.Fatalf("Failed to decode message with unknown field: %v", err)
+	}
+	if err := decoded.unmarshal([]byte{1<<3 | 2, 10}); err == nil {
+		t.Fatal("Decoding truncated message succeeded")
+	}
+}
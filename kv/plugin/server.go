@@ -0,0 +1,169 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/minio/kes/kv"
+	"google.golang.org/grpc"
+)
+
+// Main runs a plugin process started by KES. It serves
+// the KeyStore service for the given store on the Unix
+// socket specified by the EnvSocket env. variable.
+//
+// Main returns once KES closes the plugin's stdin or
+// the process receives SIGINT or SIGTERM. It exits the
+// process if the plugin fails to serve requests.
+func Main(store kv.Store[string, []byte]) {
+	socket := os.Getenv(EnvSocket)
+	if socket == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s is not set: plugin has not been started by KES\n", EnvSocket)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	go func() {
+		io.Copy(io.Discard, os.Stdin) // KES closes the plugin's stdin when it exits
+		cancel()
+	}()
+	if err := ListenAndServe(ctx, socket, store); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// ListenAndServe listens on the given Unix socket and
+// serves the KeyStore service for the given store until
+// ctx.Done() returns.
+func ListenAndServe(ctx context.Context, socket string, store kv.Store[string, []byte]) error {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, listener, store)
+}
+
+// Serve serves the KeyStore service for the given store
+// on the listener until ctx.Done() returns. Once ctx.Done()
+// returns, Serve waits for active requests to complete.
+func Serve(ctx context.Context, listener net.Listener, store kv.Store[string, []byte]) error {
+	server := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+	server.RegisterService(&serviceDesc, &service{store: store})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			server.GracefulStop()
+		case <-done:
+		}
+	}()
+	return server.Serve(listener)
+}
+
+// listBatchSize is the max. number of names
+// sent in a single ListResponse message.
+const listBatchSize = 1000
+
+// service implements the KeyStore service
+// on top of a kv.Store.
+type service struct {
+	store kv.Store[string, []byte]
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		method("Status", func() message { return &emptyMessage{} }, func(s *service, ctx context.Context, _ message) (message, error) {
+			_, err := s.store.Status(ctx)
+			return &emptyMessage{}, err
+		}),
+		method("Create", func() message { return &entryMessage{} }, func(s *service, ctx context.Context, req message) (message, error) {
+			entry := req.(*entryMessage)
+			return &emptyMessage{}, s.store.Create(ctx, entry.Name, entry.Value)
+		}),
+		method("Set", func() message { return &entryMessage{} }, func(s *service, ctx context.Context, req message) (message, error) {
+			entry := req.(*entryMessage)
+			return &emptyMessage{}, s.store.Set(ctx, entry.Name, entry.Value)
+		}),
+		method("Get", func() message { return &entryMessage{} }, func(s *service, ctx context.Context, req message) (message, error) {
+			value, err := s.store.Get(ctx, req.(*entryMessage).Name)
+			return &valueMessage{Value: value}, err
+		}),
+		method("Delete", func() message { return &entryMessage{} }, func(s *service, ctx context.Context, req message) (message, error) {
+			return &emptyMessage{}, s.store.Delete(ctx, req.(*entryMessage).Name)
+		}),
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			ServerStreams: true,
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				if err := stream.RecvMsg(&emptyMessage{}); err != nil {
+					return err
+				}
+				return toStatus(srv.(*service).list(stream))
+			},
+		},
+	},
+	Metadata: "keystore.proto",
+}
+
+// method returns the descriptor of a unary KeyStore method.
+// The request message is created by req and processed by
+// handle.
+func method(name string, req func() message, handle func(*service, context.Context, message) (message, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			in := req()
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			out, err := handle(srv.(*service), ctx, in)
+			if err != nil {
+				return nil, toStatus(err)
+			}
+			return out, nil
+		},
+	}
+}
+
+func (s *service) list(stream grpc.ServerStream) error {
+	iter, err := s.store.List(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	batch := &namesMessage{Names: make([]string, 0, listBatchSize)}
+	for name, ok := iter.Next(); ok; name, ok = iter.Next() {
+		if batch.Names = append(batch.Names, name); len(batch.Names) == listBatchSize {
+			if err = stream.SendMsg(batch); err != nil {
+				iter.Close()
+				return err
+			}
+			batch.Names = batch.Names[:0]
+		}
+	}
+	if err = iter.Close(); err != nil {
+		return err
+	}
+	if len(batch.Names) > 0 {
+		return stream.SendMsg(batch)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ package kv
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -77,6 +78,52 @@ type Store[K comparable, V any] interface {
 	List(context.Context) (Iter[K], error)
 }
 
+// PrefixLister is an optional interface that a Store
+// with string keys can implement to list entries whose
+// keys start with a given prefix efficiently.
+type PrefixLister interface {
+	// ListPrefix returns an Iter enumerating the stored
+	// entries whose keys start with the prefix. An empty
+	// prefix matches all entries.
+	ListPrefix(ctx context.Context, prefix string) (Iter[string], error)
+}
+
+// ListPrefix returns an Iter enumerating the entries of
+// the Store whose keys start with the prefix.
+//
+// If s implements PrefixLister, ListPrefix calls its
+// ListPrefix method. Otherwise, it lists all entries
+// and skips entries that don't start with the prefix.
+func ListPrefix[V any](ctx context.Context, s Store[string, V], prefix string) (Iter[string], error) {
+	if lister, ok := s.(PrefixLister); ok {
+		return lister.ListPrefix(ctx, prefix)
+	}
+	iter, err := s.List(ctx)
+	if err != nil || prefix == "" {
+		return iter, err
+	}
+	return &prefixIter{iter: iter, prefix: prefix}, nil
+}
+
+type prefixIter struct {
+	iter   Iter[string]
+	prefix string
+}
+
+func (p *prefixIter) Next() (string, bool) {
+	for {
+		key, ok := p.iter.Next()
+		if !ok {
+			return "", false
+		}
+		if strings.HasPrefix(key, p.prefix) {
+			return key, true
+		}
+	}
+}
+
+func (p *prefixIter) Close() error { return p.iter.Close() }
+
 // Iter iterates over a list of keys.
 //
 // Its Next method returns the next key
@@ -0,0 +1,198 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kesclient"
+)
+
+func newEnclaveResource() resource.Resource { return &enclaveResource{} }
+
+// enclaveResource manages a KES enclave. Enclaves cannot be
+// modified. Hence, any change replaces the enclave, which
+// deletes all keys, policies and identities within it.
+type enclaveResource struct {
+	client *kes.Client
+}
+
+type enclaveModel struct {
+	Name      types.String    `tfsdk:"name"`
+	Admin     types.String    `tfsdk:"admin"`
+	KeyPolicy *keyPolicyModel `tfsdk:"key_policy"`
+	CreatedAt types.String    `tfsdk:"created_at"`
+	CreatedBy types.String    `tfsdk:"created_by"`
+}
+
+type keyPolicyModel struct {
+	DefaultAlgorithm types.String `tfsdk:"default_algorithm"`
+	Algorithms       []string     `tfsdk:"algorithms"`
+	MinKeySize       types.Int64  `tfsdk:"min_key_size"`
+}
+
+var (
+	_ resource.ResourceWithConfigure   = (*enclaveResource)(nil)
+	_ resource.ResourceWithImportState = (*enclaveResource)(nil)
+)
+
+func (r *enclaveResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_enclave"
+}
+
+func (r *enclaveResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A KES enclave. Changing any argument replaces the enclave and deletes everything within it.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Description:   "The enclave name.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"admin": schema.StringAttribute{
+				Description:   "The identity of the enclave admin.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"key_policy": schema.SingleNestedAttribute{
+				Description:   "Restricts the algorithms of new keys within the enclave.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.Object{objectplanmodifier.RequiresReplace()},
+				Attributes: map[string]schema.Attribute{
+					"default_algorithm": schema.StringAttribute{
+						Description: "The algorithm of keys created without an explicit algorithm.",
+						Optional:    true,
+					},
+					"algorithms": schema.ListAttribute{
+						Description: "The algorithms allowed for new keys. If empty, all algorithms are allowed.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"min_key_size": schema.Int64Attribute{
+						Description: "The min. size of new keys in bits.",
+						Optional:    true,
+					},
+				},
+			},
+			"created_at": schema.StringAttribute{
+				Description:   "The point in time when the enclave has been created.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"created_by": schema.StringAttribute{
+				Description:   "The identity that created the enclave.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *enclaveResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	client, err := clientOf(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid provider configuration", err.Error())
+		return
+	}
+	r.client = client
+}
+
+func (r *enclaveResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan enclaveModel
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	var opts kesclient.CreateEnclaveOptions
+	if p := plan.KeyPolicy; p != nil {
+		opts.KeyPolicy = &kesclient.KeyPolicy{
+			DefaultAlgorithm: p.DefaultAlgorithm.ValueString(),
+			Algorithms:       p.Algorithms,
+			MinKeySize:       int(p.MinKeySize.ValueInt64()),
+		}
+	}
+	name := plan.Name.ValueString()
+	if err := kesclient.CreateEnclave(ctx, r.client, name, kes.Identity(plan.Admin.ValueString()), &opts); err != nil {
+		resp.Diagnostics.AddError("Failed to create enclave '"+name+"'", err.Error())
+		return
+	}
+	info, err := kesclient.DescribeEnclave(ctx, r.client, name)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read enclave '"+name+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, newEnclaveModel(info))...)
+}
+
+func (r *enclaveResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state enclaveModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.Name.ValueString()
+	info, err := kesclient.DescribeEnclave(ctx, r.client, name)
+	if errors.Is(err, kes.ErrEnclaveNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read enclave '"+name+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, newEnclaveModel(info))...)
+}
+
+func (r *enclaveResource) Update(_ context.Context, _ resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// All arguments require a replacement. Hence, Terraform
+	// never updates an enclave in place.
+	resp.Diagnostics.AddError("Enclaves cannot be updated", "Changing an enclave requires replacing it.")
+}
+
+func (r *enclaveResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state enclaveModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.Name.ValueString()
+	if err := r.client.DeleteEnclave(ctx, name); err != nil && !errors.Is(err, kes.ErrEnclaveNotFound) {
+		resp.Diagnostics.AddError("Failed to delete enclave '"+name+"'", err.Error())
+	}
+}
+
+func (r *enclaveResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+}
+
+// newEnclaveModel returns the Terraform state of the enclave.
+func newEnclaveModel(info *kesclient.EnclaveInfo) *enclaveModel {
+	model := &enclaveModel{
+		Name:      types.StringValue(info.Name),
+		Admin:     types.StringValue(info.Admin.String()),
+		CreatedAt: timeValue(info.CreatedAt),
+		CreatedBy: stringOrNull(info.CreatedBy.String()),
+	}
+	if p := info.KeyPolicy; p != nil {
+		model.KeyPolicy = &keyPolicyModel{
+			DefaultAlgorithm: stringOrNull(p.DefaultAlgorithm),
+			Algorithms:       p.Algorithms,
+			MinKeySize:       types.Int64Null(),
+		}
+		if p.MinKeySize > 0 {
+			model.KeyPolicy.MinKeySize = types.Int64Value(int64(p.MinKeySize))
+		}
+	}
+	return model
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/minio/kes-go"
+)
+
+func newIdentityResource() resource.Resource { return &identityResource{} }
+
+// identityResource manages the policy assignment of a KES
+// identity. Deleting the resource deletes the identity such
+// that it can no longer access the enclave.
+type identityResource struct {
+	client *kes.Client
+}
+
+type identityModel struct {
+	Identity  types.String `tfsdk:"identity"`
+	Enclave   types.String `tfsdk:"enclave"`
+	Policy    types.String `tfsdk:"policy"`
+	CreatedAt types.String `tfsdk:"created_at"`
+	CreatedBy types.String `tfsdk:"created_by"`
+}
+
+var (
+	_ resource.ResourceWithConfigure   = (*identityResource)(nil)
+	_ resource.ResourceWithImportState = (*identityResource)(nil)
+)
+
+func (r *identityResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_identity"
+}
+
+func (r *identityResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Assigns a KES identity to a policy.",
+		Attributes: map[string]schema.Attribute{
+			"identity": schema.StringAttribute{
+				Description:   "The identity, i.e. the hex-encoded SHA-256 hash of the client certificate's public key.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"enclave": schema.StringAttribute{
+				Description:   "The enclave of the identity. Defaults to the default enclave.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"policy": schema.StringAttribute{
+				Description: "The name of the policy the identity is assigned to.",
+				Required:    true,
+			},
+			"created_at": schema.StringAttribute{
+				Description: "The point in time when the identity has been assigned to the policy.",
+				Computed:    true,
+			},
+			"created_by": schema.StringAttribute{
+				Description: "The identity that assigned the identity to the policy.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *identityResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	client, err := clientOf(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid provider configuration", err.Error())
+		return
+	}
+	r.client = client
+}
+
+func (r *identityResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan identityModel
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	state, err := r.assign(ctx, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to assign identity '"+plan.Identity.ValueString()+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *identityResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state identityModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	identity := kes.Identity(state.Identity.ValueString())
+	info, err := r.client.Enclave(state.Enclave.ValueString()).DescribeIdentity(ctx, identity)
+	if errors.Is(err, kes.ErrIdentityNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read identity '"+identity.String()+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, newIdentityModel(state.Enclave, info))...)
+}
+
+func (r *identityResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan identityModel
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	state, err := r.assign(ctx, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to assign identity '"+plan.Identity.ValueString()+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *identityResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state identityModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	identity := kes.Identity(state.Identity.ValueString())
+	err := r.client.Enclave(state.Enclave.ValueString()).DeleteIdentity(ctx, identity)
+	if err != nil && !errors.Is(err, kes.ErrIdentityNotFound) {
+		resp.Diagnostics.AddError("Failed to delete identity '"+identity.String()+"'", err.Error())
+	}
+}
+
+// ImportState imports an identity by its '[<enclave>/]<identity>' ID.
+func (r *identityResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	enclave, identity, err := parseID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("identity"), identity)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("enclave"), stringOrNull(enclave))...)
+}
+
+// assign assigns the planned identity to its policy and
+// returns the identity state as read back from the server.
+func (r *identityResource) assign(ctx context.Context, plan *identityModel) (*identityModel, error) {
+	enclave := r.client.Enclave(plan.Enclave.ValueString())
+	identity := kes.Identity(plan.Identity.ValueString())
+	if err := enclave.AssignPolicy(ctx, plan.Policy.ValueString(), identity); err != nil {
+		return nil, err
+	}
+	info, err := enclave.DescribeIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	return newIdentityModel(plan.Enclave, info), nil
+}
+
+// newIdentityModel returns the Terraform state of the
+// identity within the given enclave.
+func newIdentityModel(enclave types.String, info *kes.IdentityInfo) *identityModel {
+	return &identityModel{
+		Identity:  types.StringValue(info.Identity.String()),
+		Enclave:   enclave,
+		Policy:    types.StringValue(info.Policy),
+		CreatedAt: timeValue(info.CreatedAt),
+		CreatedBy: stringOrNull(info.CreatedBy.String()),
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kesclient"
+)
+
+func newKeyResource() resource.Resource { return &keyResource{} }
+
+// keyResource manages a KES key. The key material never
+// leaves the KES server and is not part of the Terraform
+// state. Keys cannot be modified. Hence, any change replaces
+// the key, which makes data encrypted with it unreadable.
+type keyResource struct {
+	client *kes.Client
+}
+
+type keyModel struct {
+	Name        types.String `tfsdk:"name"`
+	Enclave     types.String `tfsdk:"enclave"`
+	Algorithm   types.String `tfsdk:"algorithm"`
+	KeyID       types.String `tfsdk:"key_id"`
+	Fingerprint types.String `tfsdk:"fingerprint"`
+	CreatedAt   types.String `tfsdk:"created_at"`
+	CreatedBy   types.String `tfsdk:"created_by"`
+}
+
+var (
+	_ resource.ResourceWithConfigure   = (*keyResource)(nil)
+	_ resource.ResourceWithImportState = (*keyResource)(nil)
+)
+
+func (r *keyResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_key"
+}
+
+func (r *keyResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A KES key. Changing any argument replaces the key. Data encrypted with the old key can no longer be decrypted.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Description:   "The key name.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"enclave": schema.StringAttribute{
+				Description:   "The enclave of the key. Defaults to the default enclave.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"algorithm": schema.StringAttribute{
+				Description: "The key algorithm, e.g. AES256-GCM_SHA256. If not set, the server chooses one.",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIfConfigured(),
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"key_id": schema.StringAttribute{
+				Description:   "The ID of the key.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"fingerprint": schema.StringAttribute{
+				Description:   "The public fingerprint of the key. It changes when the key is recreated.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"created_at": schema.StringAttribute{
+				Description:   "The point in time when the key has been created.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"created_by": schema.StringAttribute{
+				Description:   "The identity that created the key.",
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *keyResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	client, err := clientOf(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid provider configuration", err.Error())
+		return
+	}
+	r.client = client
+}
+
+func (r *keyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan keyModel
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	enclave, name := plan.Enclave.ValueString(), plan.Name.ValueString()
+	opts := &kesclient.CreateKeyOptions{Algorithm: plan.Algorithm.ValueString()}
+	if err := kesclient.CreateKey(ctx, r.client, enclave, name, opts); err != nil {
+		resp.Diagnostics.AddError("Failed to create key '"+name+"'", err.Error())
+		return
+	}
+	info, err := kesclient.DescribeKey(ctx, r.client, enclave, name)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read key '"+name+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, newKeyModel(plan.Enclave, info))...)
+}
+
+func (r *keyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state keyModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	enclave, name := state.Enclave.ValueString(), state.Name.ValueString()
+	info, err := kesclient.DescribeKey(ctx, r.client, enclave, name)
+	if errors.Is(err, kes.ErrKeyNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read key '"+name+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, newKeyModel(state.Enclave, info))...)
+}
+
+func (r *keyResource) Update(_ context.Context, _ resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// All arguments require a replacement. Hence, Terraform
+	// never updates a key in place.
+	resp.Diagnostics.AddError("Keys cannot be updated", "Changing a key requires replacing it.")
+}
+
+func (r *keyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state keyModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.Name.ValueString()
+	err := r.client.Enclave(state.Enclave.ValueString()).DeleteKey(ctx, name)
+	if err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+		resp.Diagnostics.AddError("Failed to delete key '"+name+"'", err.Error())
+	}
+}
+
+// ImportState imports a key by its '[<enclave>/]<name>' ID.
+func (r *keyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	enclave, name, err := parseID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("enclave"), stringOrNull(enclave))...)
+}
+
+// newKeyModel returns the Terraform state of the key
+// within the given enclave.
+func newKeyModel(enclave types.String, info *kesclient.KeyInfo) *keyModel {
+	return &keyModel{
+		Name:        types.StringValue(info.Name),
+		Enclave:     enclave,
+		Algorithm:   stringOrNull(info.Algorithm),
+		KeyID:       stringOrNull(info.ID),
+		Fingerprint: stringOrNull(info.Fingerprint),
+		CreatedAt:   timeValue(info.CreatedAt),
+		CreatedBy:   stringOrNull(info.CreatedBy.String()),
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/kesclient"
+)
+
+func newPolicyResource() resource.Resource { return &policyResource{} }
+
+// policyResource manages a KES policy. In contrast to keys
+// and enclaves, policies are updated in place.
+type policyResource struct {
+	client *kes.Client
+}
+
+type policyModel struct {
+	Name           types.String                `tfsdk:"name"`
+	Enclave        types.String                `tfsdk:"enclave"`
+	Allow          []string                    `tfsdk:"allow"`
+	Deny           []string                    `tfsdk:"deny"`
+	Certificates   map[string]certificateModel `tfsdk:"certificates"`
+	MaxRandomBytes types.Int64                 `tfsdk:"max_random_bytes"`
+	Tokens         *tokenModel                 `tfsdk:"tokens"`
+	MaxSSHTTL      types.String                `tfsdk:"max_ssh_ttl"`
+	CreatedAt      types.String                `tfsdk:"created_at"`
+	CreatedBy      types.String                `tfsdk:"created_by"`
+}
+
+type certificateModel struct {
+	Key      types.String `tfsdk:"key"`
+	DNSNames []string     `tfsdk:"dns_names"`
+	IPRanges []string     `tfsdk:"ip_ranges"`
+	Usage    []string     `tfsdk:"usage"`
+	MaxTTL   types.String `tfsdk:"max_ttl"`
+}
+
+type tokenModel struct {
+	Audiences []string     `tfsdk:"audiences"`
+	Claims    []string     `tfsdk:"claims"`
+	MaxTTL    types.String `tfsdk:"max_ttl"`
+}
+
+var (
+	_ resource.ResourceWithConfigure   = (*policyResource)(nil)
+	_ resource.ResourceWithImportState = (*policyResource)(nil)
+)
+
+func (r *policyResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_policy"
+}
+
+func (r *policyResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A KES policy. Identities are assigned to it with the kes_identity resource.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Description:   "The policy name.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"enclave": schema.StringAttribute{
+				Description:   "The enclave of the policy. Defaults to the default enclave.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"allow": schema.ListAttribute{
+				Description: "The API path patterns that are allowed, e.g. /v1/key/encrypt/my-app*.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"deny": schema.ListAttribute{
+				Description: "The API path patterns that are denied. Deny patterns take precedence over allow patterns.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"certificates": schema.MapNestedAttribute{
+				Description: "The certificate profiles, by name, of identities assigned to the policy.",
+				Optional:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"key": schema.StringAttribute{
+							Description: "The name of the CA key.",
+							Required:    true,
+						},
+						"dns_names": schema.ListAttribute{
+							Description: "The allowed DNS names, like *.example.com.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"ip_ranges": schema.ListAttribute{
+							Description: "The CIDR ranges of allowed IP addresses.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"usage": schema.ListAttribute{
+							Description: "The certificate usage: server and/or client.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"max_ttl": schema.StringAttribute{
+							Description: "The max. certificate lifetime, like 72h.",
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"max_random_bytes": schema.Int64Attribute{
+				Description: "The max. number of random bytes assigned identities can request at once. If not set, the server default applies.",
+				Optional:    true,
+			},
+			"tokens": schema.SingleNestedAttribute{
+				Description: "Constrains the JWTs assigned identities can sign. If not set, tokens must not contain an audience or custom claims.",
+				Optional:    true,
+				Attributes: map[string]schema.Attribute{
+					"audiences": schema.ListAttribute{
+						Description: "The allowed 'aud' claim values.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"claims": schema.ListAttribute{
+						Description: "The allowed custom claim names.",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"max_ttl": schema.StringAttribute{
+						Description: "The max. token lifetime, like 1h.",
+						Optional:    true,
+					},
+				},
+			},
+			"max_ssh_ttl": schema.StringAttribute{
+				Description: "The max. lifetime of SSH certificates assigned identities can obtain, like 8h. If not set, the server's max. lifetime applies.",
+				Optional:    true,
+			},
+			"created_at": schema.StringAttribute{
+				Description: "The point in time when the policy has been written.",
+				Computed:    true,
+			},
+			"created_by": schema.StringAttribute{
+				Description: "The identity that wrote the policy.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *policyResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	client, err := clientOf(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid provider configuration", err.Error())
+		return
+	}
+	r.client = client
+}
+
+func (r *policyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan policyModel
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The policy must not exist yet. Otherwise, Terraform
+	// would silently take over a policy it does not manage.
+	state, err := r.write(ctx, &plan, kesclient.Precondition{IfNoneMatch: "*"})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create policy '"+plan.Name.ValueString()+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *policyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state policyModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.Name.ValueString()
+	policy, _, err := kesclient.ReadPolicy(ctx, r.client, state.Enclave.ValueString(), name)
+	if errors.Is(err, kes.ErrPolicyNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read policy '"+name+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, newPolicyModel(&state, policy))...)
+}
+
+func (r *policyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan policyModel
+	if resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	state, err := r.write(ctx, &plan, kesclient.Precondition{IfMatch: "*"})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to update policy '"+plan.Name.ValueString()+"'", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *policyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state policyModel
+	if resp.Diagnostics.Append(req.State.Get(ctx, &state)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.Name.ValueString()
+	err := r.client.Enclave(state.Enclave.ValueString()).DeletePolicy(ctx, name)
+	if err != nil && !errors.Is(err, kes.ErrPolicyNotFound) {
+		resp.Diagnostics.AddError("Failed to delete policy '"+name+"'", err.Error())
+	}
+}
+
+// ImportState imports a policy by its '[<enclave>/]<name>' ID.
+func (r *policyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	enclave, name, err := parseID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("enclave"), stringOrNull(enclave))...)
+}
+
+// write writes the planned policy if the precondition holds
+// and returns the policy state as read back from the server.
+func (r *policyResource) write(ctx context.Context, plan *policyModel, cond kesclient.Precondition) (*policyModel, error) {
+	policy := &kesclient.Policy{
+		Allow:          plan.Allow,
+		Deny:           plan.Deny,
+		MaxRandomBytes: int(plan.MaxRandomBytes.ValueInt64()),
+		MaxSSHTTL:      plan.MaxSSHTTL.ValueString(),
+	}
+	if len(plan.Certificates) > 0 {
+		policy.Certificates = make(map[string]kesclient.CertificateProfile, len(plan.Certificates))
+		for name, c := range plan.Certificates {
+			policy.Certificates[name] = kesclient.CertificateProfile{
+				Key:      c.Key.ValueString(),
+				DNSNames: c.DNSNames,
+				IPRanges: c.IPRanges,
+				Usage:    c.Usage,
+				MaxTTL:   c.MaxTTL.ValueString(),
+			}
+		}
+	}
+	if t := plan.Tokens; t != nil {
+		policy.Tokens = &kesclient.TokenProfile{
+			Audiences: t.Audiences,
+			Claims:    t.Claims,
+			MaxTTL:    t.MaxTTL.ValueString(),
+		}
+	}
+
+	enclave, name := plan.Enclave.ValueString(), plan.Name.ValueString()
+	if _, err := kesclient.WritePolicy(ctx, r.client, enclave, name, policy, cond); err != nil {
+		return nil, err
+	}
+	written, _, err := kesclient.ReadPolicy(ctx, r.client, enclave, name)
+	if err != nil {
+		return nil, err
+	}
+	return newPolicyModel(plan, written), nil
+}
+
+// newPolicyModel returns the Terraform state of the policy.
+// The name and enclave are taken from the prior state or plan.
+//
+// The server normalizes durations, e.g. "1h" to "1h0m0s".
+// Durations equal to the prior ones keep their prior value.
+func newPolicyModel(prior *policyModel, policy *kesclient.Policy) *policyModel {
+	model := &policyModel{
+		Name:           prior.Name,
+		Enclave:        prior.Enclave,
+		Allow:          policy.Allow,
+		Deny:           policy.Deny,
+		MaxRandomBytes: types.Int64Null(),
+		MaxSSHTTL:      durationValue(prior.MaxSSHTTL, policy.MaxSSHTTL),
+		CreatedAt:      timeValue(policy.CreatedAt),
+		CreatedBy:      stringOrNull(policy.CreatedBy.String()),
+	}
+	if policy.MaxRandomBytes > 0 {
+		model.MaxRandomBytes = types.Int64Value(int64(policy.MaxRandomBytes))
+	}
+	if len(policy.Certificates) > 0 {
+		model.Certificates = make(map[string]certificateModel, len(policy.Certificates))
+		for name, c := range policy.Certificates {
+			model.Certificates[name] = certificateModel{
+				Key:      types.StringValue(c.Key),
+				DNSNames: c.DNSNames,
+				IPRanges: c.IPRanges,
+				Usage:    c.Usage,
+				MaxTTL:   durationValue(prior.Certificates[name].MaxTTL, c.MaxTTL),
+			}
+		}
+	}
+	if t := policy.Tokens; t != nil {
+		priorTTL := types.StringNull()
+		if prior.Tokens != nil {
+			priorTTL = prior.Tokens.MaxTTL
+		}
+		model.Tokens = &tokenModel{
+			Audiences: t.Audiences,
+			Claims:    t.Claims,
+			MaxTTL:    durationValue(priorTTL, t.MaxTTL),
+		}
+	}
+	return model
+}
+
+// durationValue returns the duration s as Terraform string,
+// or null if s is empty. It returns prior if prior is the
+// same duration as s.
+func durationValue(prior types.String, s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+	if prior.IsNull() || prior.IsUnknown() {
+		return types.StringValue(s)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return types.StringValue(s)
+	}
+	if p, err := time.ParseDuration(prior.ValueString()); err == nil && p == d {
+		return prior
+	}
+	return types.StringValue(s)
+}
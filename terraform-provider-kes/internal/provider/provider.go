@@ -0,0 +1,259 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package provider implements the KES Terraform provider.
+//
+// It manages enclaves, keys, policies and identity
+// assignments through the KES management API. Each
+// resource is created, read back and deleted via its
+// API such that Terraform detects drift.
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/minio/kes-go"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/kesclient"
+)
+
+// Environment variables used when the provider
+// configuration does not specify a value. They
+// match the ones of the kes CLI.
+const (
+	EnvServer     = "KES_SERVER"
+	EnvAPIKey     = "KES_API_KEY"
+	EnvClientCert = "KES_CLIENT_CERT"
+	EnvClientKey  = "KES_CLIENT_KEY"
+)
+
+// DefaultServer is the server endpoint used if neither
+// the provider configuration nor KES_SERVER specify one.
+const DefaultServer = "https://127.0.0.1:7373"
+
+// New returns a function that returns a new KES
+// provider with the given version.
+func New(version string) func() provider.Provider {
+	return func() provider.Provider { return &kesProvider{version: version} }
+}
+
+type kesProvider struct {
+	version string
+}
+
+type providerModel struct {
+	Endpoints  []string     `tfsdk:"endpoints"`
+	APIKey     types.String `tfsdk:"api_key"`
+	ClientCert types.String `tfsdk:"client_cert"`
+	ClientKey  types.String `tfsdk:"client_key"`
+	Insecure   types.Bool   `tfsdk:"insecure"`
+}
+
+var _ provider.Provider = (*kesProvider)(nil)
+
+func (p *kesProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "kes"
+	resp.Version = p.version
+}
+
+func (p *kesProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages KES enclaves, keys, policies and identities.",
+		Attributes: map[string]schema.Attribute{
+			"endpoints": schema.ListAttribute{
+				Description: "KES server endpoints. Defaults to the comma-separated endpoints of KES_SERVER or " + DefaultServer + ".",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"api_key": schema.StringAttribute{
+				Description: "KES API key used to authenticate. Defaults to KES_API_KEY. Conflicts with client_cert and client_key.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"client_cert": schema.StringAttribute{
+				Description: "Path to the TLS client certificate used to authenticate. Defaults to KES_CLIENT_CERT.",
+				Optional:    true,
+			},
+			"client_key": schema.StringAttribute{
+				Description: "Path to the TLS private key of the client certificate. Defaults to KES_CLIENT_KEY.",
+				Optional:    true,
+			},
+			"insecure": schema.BoolAttribute{
+				Description: "Skip TLS certificate validation of the KES server.",
+				Optional:    true,
+			},
+		},
+	}
+}
+
+func (p *kesProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config providerModel
+	if resp.Diagnostics.Append(req.Config.Get(ctx, &config)...); resp.Diagnostics.HasError() {
+		return
+	}
+
+	endpoints := config.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = serverEndpoints()
+	}
+	apiKey := valueOrEnv(config.APIKey, EnvAPIKey)
+	certPath := valueOrEnv(config.ClientCert, EnvClientCert)
+	keyPath := valueOrEnv(config.ClientKey, EnvClientKey)
+
+	var (
+		cert tls.Certificate
+		err  error
+	)
+	switch {
+	case apiKey != "" && (certPath != "" || keyPath != ""):
+		resp.Diagnostics.AddAttributeError(path.Root("api_key"), "Conflicting credentials", "Specify either an API key or a client certificate and private key, not both.")
+		return
+	case apiKey != "":
+		var key kes.APIKey
+		if key, err = kes.ParseAPIKey(apiKey); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("api_key"), "Invalid API key", err.Error())
+			return
+		}
+		if cert, err = kes.GenerateCertificate(key); err != nil {
+			resp.Diagnostics.AddError("Failed to generate client certificate from API key", err.Error())
+			return
+		}
+	case certPath == "" || keyPath == "":
+		resp.Diagnostics.AddError("No client credentials", fmt.Sprintf("Specify an API key or a client certificate and private key, e.g. via '%s' or '%s' and '%s'.", EnvAPIKey, EnvClientCert, EnvClientKey))
+		return
+	default:
+		if cert, err = loadCertificate(certPath, keyPath); err != nil {
+			resp.Diagnostics.AddError("Failed to load client certificate", err.Error())
+			return
+		}
+	}
+
+	client, err := kesclient.NewClient(endpoints, &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: config.Insecure.ValueBool(),
+	})
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("endpoints"), "Invalid KES server endpoints", err.Error())
+		return
+	}
+	resp.ResourceData = client
+	resp.DataSourceData = client
+}
+
+func (p *kesProvider) Resources(context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newEnclaveResource,
+		newKeyResource,
+		newPolicyResource,
+		newIdentityResource,
+	}
+}
+
+func (p *kesProvider) DataSources(context.Context) []func() datasource.DataSource { return nil }
+
+// serverEndpoints returns the comma-separated server
+// endpoints of the KES_SERVER environment variable or
+// the default endpoint if KES_SERVER is not set.
+func serverEndpoints() []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(os.Getenv(EnvServer), ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		return []string{DefaultServer}
+	}
+	return endpoints
+}
+
+// valueOrEnv returns the value of s, if set, or the
+// value of the environment variable otherwise.
+func valueOrEnv(s types.String, env string) string {
+	if !s.IsNull() && !s.IsUnknown() {
+		return strings.TrimSpace(s.ValueString())
+	}
+	return strings.TrimSpace(os.Getenv(env))
+}
+
+// loadCertificate loads a TLS certificate and its
+// private key from the given files. Encrypted private
+// keys are not supported.
+func loadCertificate(certPath, keyPath string) (tls.Certificate, error) {
+	certPem, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certPem, err = https.FilterPEM(certPem, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPem, err := os.ReadFile(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPem, keyPem)
+}
+
+// clientOf returns the KES client passed by the provider
+// to a resource. It returns nil if the provider has not
+// been configured yet.
+func clientOf(providerData any) (*kes.Client, error) {
+	if providerData == nil {
+		return nil, nil
+	}
+	client, ok := providerData.(*kes.Client)
+	if !ok {
+		return nil, fmt.Errorf("unexpected provider data type '%T'", providerData)
+	}
+	return client, nil
+}
+
+// parseID parses a resource import ID of the form
+// '[<enclave>/]<name>'. The enclave is empty if the
+// ID refers to the default enclave.
+func parseID(id string) (enclave, name string, err error) {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		enclave, name = id[:i], id[i+1:]
+		if enclave == "" {
+			return "", "", fmt.Errorf("invalid import ID '%s': enclave is empty", id)
+		}
+	} else {
+		name = id
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("invalid import ID '%s': name is empty", id)
+	}
+	return enclave, name, nil
+}
+
+// timeValue returns the RFC 3339 representation of
+// t, or null if t is the zero time.
+func timeValue(t time.Time) types.String {
+	if t.IsZero() {
+		return types.StringNull()
+	}
+	return types.StringValue(t.UTC().Format(time.RFC3339))
+}
+
+// stringOrNull returns s as Terraform string, or
+// null if s is empty.
+func stringOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(s)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+func TestProviderSchema(t *testing.T) {
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatalf("Failed to create provider server: %v", err)
+	}
+	resp, err := server.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get provider schema: %v", err)
+	}
+	for _, diag := range resp.Diagnostics {
+		if diag.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("Invalid provider schema: %s: %s", diag.Summary, diag.Detail)
+		}
+	}
+	for _, name := range []string{"kes_enclave", "kes_key", "kes_policy", "kes_identity"} {
+		if _, ok := resp.ResourceSchemas[name]; !ok {
+			t.Fatalf("Provider has no resource '%s'", name)
+		}
+	}
+}
+
+var parseIDTests = []struct {
+	ID         string
+	Enclave    string
+	Name       string
+	ShouldFail bool
+}{
+	{ID: "my-key", Name: "my-key"},                               // 0
+	{ID: "tenant-1/my-key", Enclave: "tenant-1", Name: "my-key"}, // 1
+	{ID: "", ShouldFail: true},                                   // 2
+	{ID: "tenant-1/", ShouldFail: true},                          // 3
+	{ID: "/my-key", ShouldFail: true},                            // 4
+}
+
+func TestParseID(t *testing.T) {
+	for i, test := range parseIDTests {
+		enclave, name, err := parseID(test.ID)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should fail but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse ID: %v", i, err)
+		}
+		if enclave != test.Enclave {
+			t.Fatalf("Test %d: got enclave '%s' - want '%s'", i, enclave, test.Enclave)
+		}
+		if name != test.Name {
+			t.Fatalf("Test %d: got name '%s' - want '%s'", i, name, test.Name)
+		}
+	}
+}
+
+var durationValueTests = []struct {
+	Prior types.String
+	Value string
+	Want  types.String
+}{
+	{Prior: types.StringNull(), Value: "", Want: types.StringNull()},                     // 0
+	{Prior: types.StringNull(), Value: "1h0m0s", Want: types.StringValue("1h0m0s")},      // 1
+	{Prior: types.StringValue("1h"), Value: "1h0m0s", Want: types.StringValue("1h")},     // 2
+	{Prior: types.StringValue("60m"), Value: "1h0m0s", Want: types.StringValue("60m")},   // 3
+	{Prior: types.StringValue("2h"), Value: "1h0m0s", Want: types.StringValue("1h0m0s")}, // 4
+	{Prior: types.StringValue("1h"), Value: "", Want: types.StringNull()},                // 5
+}
+
+func TestDurationValue(t *testing.T) {
+	for i, test := range durationValueTests {
+		if v := durationValue(test.Prior, test.Value); !v.Equal(test.Want) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, v, test.Want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Command terraform-provider-kes is a Terraform provider that
+// manages KES enclaves, keys, policies and identities.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/minio/kes/terraform-provider-kes/internal/provider"
+)
+
+// version is the provider version. It is set at build time.
+var version = "dev"
+
+func main() {
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "Start the provider with support for debuggers like delve")
+	flag.Parse()
+
+	err := providerserver.Serve(context.Background(), provider.New(version), providerserver.ServeOpts{
+		Address: "registry.terraform.io/minio/kes",
+		Debug:   debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}